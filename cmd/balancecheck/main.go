@@ -0,0 +1,105 @@
+// Command balancecheck recomputes the balance of every user from the
+// transactions, wallets included, and reports the ones that drifted from the
+// stored balance. With -repair, drifted balances are set to the recomputed
+// ones, and audited when the audit log is enabled. It connects with the same
+// DB_* environment variables as the service and exits with status 1 when
+// drifts are left unrepaired.
+//
+//	balancecheck [-repair] [-actor name] [-json]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"transaction-service/internal/adapters/database"
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/config"
+	"transaction-service/internal/domain/entities"
+)
+
+func main() {
+	repair := flag.Bool("repair", false, "set drifted balances to the recomputed ones")
+	actor := flag.String("actor", os.Getenv("USER"), "operator the repairs are audited on behalf of (default $USER)")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	timeout := flag.Duration("timeout", 30*time.Minute, "time limit for the whole run")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		fail(err)
+	}
+	if cfg.Storage != "postgres" || cfg.Database.Driver != "postgres" {
+		fail(errors.New("balances can only be recomputed in PostgreSQL"))
+	}
+
+	db, err := database.NewConnection(cfg.Database)
+	if err != nil {
+		fail(err)
+	}
+	defer db.Close()
+
+	var opts []services.BalanceCheckServiceOption
+	if cfg.AuditLog {
+		auditService := services.NewAuditService(database.NewAuditLogRepository(db))
+		opts = append(opts, services.WithBalanceCheckAuditRecorder(auditService))
+	}
+	service := services.NewBalanceCheckService(
+		database.NewUnitOfWork(db),
+		database.NewUserRepository(db),
+		database.NewWalletRepository(db),
+		database.NewBalanceCheckRepository(db),
+		opts...,
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	check := service.CheckBalances
+	if *repair {
+		check = func(ctx context.Context) (*entities.BalanceCheckReport, error) {
+			return service.RepairBalances(ctx, *actor)
+		}
+	}
+	report, err := check(ctx)
+	if err != nil {
+		fail(err)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(report)
+	} else {
+		for _, drift := range report.Drifts {
+			balance := "balance"
+			if drift.Currency != "" {
+				balance = drift.Currency + " wallet"
+			}
+			status := "DRIFT   "
+			if drift.Repaired {
+				status = "REPAIRED"
+			}
+			fmt.Printf("%s user %d %s: stored %s, recomputed %s\n",
+				status, drift.UserID, balance, drift.StoredBalance.StringFixed(2), drift.RecomputedBalance.StringFixed(2))
+		}
+		fmt.Printf("%d balances checked, %d unverifiable, %d drifted, %d repaired\n",
+			report.Checked, report.Unverifiable, len(report.Drifts), report.Repaired)
+	}
+
+	if report.Repaired < len(report.Drifts) {
+		cancel()
+		db.Close()
+		os.Exit(1)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "balancecheck:", err)
+	os.Exit(1)
+}
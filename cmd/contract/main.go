@@ -0,0 +1,55 @@
+// Command contract verifies a deployment of the transaction service against
+// its documented REST contract and exits non-zero on any deviation.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"transaction-service/pkg/contract"
+)
+
+func main() {
+	var cfg contract.Config
+	flag.StringVar(&cfg.BaseURL, "base-url", "http://localhost:8080", "base URL of the REST API")
+	flag.Uint64Var(&cfg.UserID, "user-id", 1, "existing test user; its balance is moved and restored")
+	flag.Uint64Var(&cfg.UnknownUserID, "unknown-user-id", 0, "user ID that must not exist (default: largest ID)")
+	flag.StringVar(&cfg.SourceType, "source-type", "game", "Source-Type the checks submit transactions with")
+	flag.StringVar(&cfg.APIKey, "api-key", os.Getenv("CONTRACT_API_KEY"), "X-API-Key to send (default $CONTRACT_API_KEY)")
+	flag.StringVar(&cfg.BearerToken, "token", os.Getenv("CONTRACT_BEARER_TOKEN"), "bearer token to send (default $CONTRACT_BEARER_TOKEN)")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	timeout := flag.Duration("timeout", time.Minute, "time limit for the whole run")
+	flag.Parse()
+
+	verifier, err := contract.NewVerifier(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "contract:", err)
+		os.Exit(2)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	report := verifier.Run(ctx)
+	cancel()
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(report)
+	} else {
+		for _, result := range report.Results {
+			if result.Passed {
+				fmt.Printf("PASS  %s (%s)\n", result.Name, result.Duration.Round(time.Millisecond))
+				continue
+			}
+			fmt.Printf("FAIL  %s: %s\n", result.Name, result.Error)
+		}
+	}
+
+	if !report.Passed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,114 @@
+// Command migrate applies or reverts the versioned database migrations of the
+// transaction service. It connects with the same DB_* environment variables as
+// the service.
+//
+//	migrate [-schema name] up | down [n] | goto <version> | force <version> | version
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"transaction-service/internal/adapters/database"
+	"transaction-service/internal/config"
+)
+
+func main() {
+	schema := flag.String("schema", "", "schema to migrate instead of DB_SCHEMA, e.g. the sandbox schema")
+	timeout := flag.Duration("timeout", 10*time.Minute, "time limit for the whole run")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: migrate [flags] up | down [n] | goto <version> | force <version> | version")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fail(err)
+	}
+	dbConfig := cfg.Database
+	if *schema != "" {
+		dbConfig.Schema = *schema
+	}
+
+	if dbConfig.Schema != "" && dbConfig.Driver != "postgres" {
+		fail(fmt.Errorf("schemas are not supported by the %s database driver", dbConfig.Driver))
+	}
+
+	db, err := database.NewConnection(dbConfig)
+	if err != nil {
+		fail(err)
+	}
+	defer db.Close()
+
+	if dbConfig.Schema != "" {
+		if err := database.CreateSchema(context.Background(), db, dbConfig.Schema); err != nil {
+			fail(err)
+		}
+	}
+
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		fail(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	switch command, args := flag.Arg(0), flag.Args()[1:]; command {
+	case "up":
+		err = migrator.Up(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 0 {
+			steps = parseVersion(args[0])
+		}
+		err = migrator.Down(ctx, steps)
+	case "goto":
+		err = migrator.Goto(ctx, parseVersion(requireArg(args)))
+	case "force":
+		err = migrator.Force(ctx, parseVersion(requireArg(args)))
+	case "version":
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fail(err)
+	}
+
+	version, err := migrator.Version(ctx)
+	if err != nil {
+		fail(err)
+	}
+	fmt.Printf("schema version %d (latest migration %d)\n", version, migrator.Latest())
+}
+
+func requireArg(args []string) string {
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	return args[0]
+}
+
+func parseVersion(value string) int {
+	version, err := strconv.Atoi(value)
+	if err != nil {
+		fail(fmt.Errorf("%q is not a number", value))
+	}
+	return version
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "migrate:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,20 @@
+// Command openapi prints the OpenAPI 3 specification of the REST API, as
+// served on /openapi.json, for publishing it or generating clients.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"transaction-service/internal/adapters/handlers"
+)
+
+func main() {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(handlers.OpenAPISpec()); err != nil {
+		fmt.Fprintln(os.Stderr, "openapi:", err)
+		os.Exit(1)
+	}
+}
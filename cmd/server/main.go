@@ -0,0 +1,13 @@
+// Command server serves the HTTP and gRPC APIs of the transaction service,
+// without the periodic background workers run by cmd/worker.
+package main
+
+import (
+	"os"
+
+	"transaction-service/internal/app"
+)
+
+func main() {
+	os.Exit(app.Run(app.Server))
+}
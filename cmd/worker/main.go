@@ -0,0 +1,13 @@
+// Command worker runs the periodic background workers of the transaction
+// service. It serves the health and metrics endpoints on PORT only.
+package main
+
+import (
+	"os"
+
+	"transaction-service/internal/app"
+)
+
+func main() {
+	os.Exit(app.Run(app.Workers))
+}
@@ -0,0 +1,34 @@
+package alerting
+
+import (
+	"context"
+
+	"transaction-service/internal/application/services"
+
+	"github.com/rs/zerolog"
+)
+
+// LogNotifier writes alerts to the application log
+type LogNotifier struct {
+	logger zerolog.Logger
+}
+
+// NewLogNotifier creates a new LogNotifier
+func NewLogNotifier(logger zerolog.Logger) *LogNotifier {
+	return &LogNotifier{logger: logger}
+}
+
+// NotifyBalanceAlert logs a tripped balance guard
+func (n *LogNotifier) NotifyBalanceAlert(ctx context.Context, alert services.BalanceAlert) error {
+	n.logger.Warn().
+		Str("alert", "balance_guard").
+		Uint64("user_id", alert.UserID).
+		Str("transaction_id", alert.TransactionID).
+		Str("change", alert.Change.StringFixed(2)).
+		Dur("window", alert.Window).
+		Str("action", string(alert.Action)).
+		Bool("frozen", alert.Frozen).
+		Str("reason", alert.Reason).
+		Msg("balance guard tripped")
+	return nil
+}
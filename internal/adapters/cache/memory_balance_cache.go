@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"context"
+	"sync"
+
+	"transaction-service/internal/application/services"
+)
+
+// MemoryBalanceCache is a process-local BalanceCache
+type MemoryBalanceCache struct {
+	mu       sync.RWMutex
+	balances map[uint64]services.CachedBalance
+}
+
+// NewMemoryBalanceCache creates a new MemoryBalanceCache
+func NewMemoryBalanceCache() *MemoryBalanceCache {
+	return &MemoryBalanceCache{
+		balances: make(map[uint64]services.CachedBalance),
+	}
+}
+
+// Get returns the cached balance for a user
+func (c *MemoryBalanceCache) Get(ctx context.Context, userID uint64) (services.CachedBalance, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	balance, ok := c.balances[userID]
+	return balance, ok
+}
+
+// Set stores the balance for a user, keeping the most recent value
+func (c *MemoryBalanceCache) Set(ctx context.Context, userID uint64, balance services.CachedBalance) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if existing, ok := c.balances[userID]; ok && existing.CachedAt.After(balance.CachedAt) {
+		return
+	}
+	c.balances[userID] = balance
+}
+
+// Delete drops the cached balance for a user
+func (c *MemoryBalanceCache) Delete(ctx context.Context, userID uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.balances, userID)
+}
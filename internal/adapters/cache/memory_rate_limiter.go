@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"transaction-service/internal/application/services"
+)
+
+// idleBucketSweepInterval is how often buckets that refilled completely are dropped
+const idleBucketSweepInterval = time.Minute
+
+// tokenBucket is the state of a single bucket
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+	rule    services.RateLimitRule
+}
+
+// refill adds the tokens accrued since the last update
+func (b *tokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(b.updated).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(b.rule.Burst), b.tokens+elapsed*b.rule.Rate)
+		b.updated = now
+	}
+}
+
+// MemoryRateLimiter is a process-local RateLimiter. Every replica limits on
+// its own, so use the Redis limiter when several replicas share the traffic.
+type MemoryRateLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryRateLimiter creates a new MemoryRateLimiter
+func NewMemoryRateLimiter() *MemoryRateLimiter {
+	return &MemoryRateLimiter{
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+// Allow takes a token from the bucket named key
+func (l *MemoryRateLimiter) Allow(ctx context.Context, key string, rule services.RateLimitRule) (services.RateLimitDecision, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(rule.Burst), updated: now, rule: rule}
+		l.buckets[key] = bucket
+	}
+	bucket.rule = rule
+	bucket.refill(now)
+
+	var decision services.RateLimitDecision
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = secondsToDuration((1 - bucket.tokens) / rule.Rate)
+	}
+	decision.Remaining = int(bucket.tokens)
+	decision.ResetAfter = secondsToDuration((float64(rule.Burst) - bucket.tokens) / rule.Rate)
+	return decision, nil
+}
+
+// secondsToDuration rounds seconds up to a whole nanosecond
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(math.Ceil(seconds * float64(time.Second)))
+}
+
+// sweep drops buckets that have refilled completely; they behave exactly like
+// new ones
+func (l *MemoryRateLimiter) sweep(now time.Time) {
+	if l.lastSweep.IsZero() {
+		l.lastSweep = now
+	}
+	if now.Sub(l.lastSweep) < idleBucketSweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, bucket := range l.buckets {
+		bucket.refill(now)
+		if bucket.tokens >= float64(bucket.rule.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
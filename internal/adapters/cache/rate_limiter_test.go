@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"transaction-service/internal/application/services"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryRateLimiter(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewMemoryRateLimiter()
+	limiter.now = func() time.Time { return now }
+	rule := services.RateLimitRule{Rate: 2, Burst: 3}
+
+	// The burst is available immediately
+	for i := 0; i < 3; i++ {
+		decision, err := limiter.Allow(ctx, "user:1", rule)
+		require.NoError(t, err)
+		assert.True(t, decision.Allowed)
+		assert.Equal(t, 2-i, decision.Remaining)
+	}
+
+	decision, err := limiter.Allow(ctx, "user:1", rule)
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, 500*time.Millisecond, decision.RetryAfter)
+	assert.Equal(t, 0, decision.Remaining)
+	assert.Equal(t, 1500*time.Millisecond, decision.ResetAfter)
+
+	// Other buckets are independent
+	decision, err = limiter.Allow(ctx, "user:2", rule)
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+
+	// Tokens refill at the configured rate
+	now = now.Add(500 * time.Millisecond)
+	decision, err = limiter.Allow(ctx, "user:1", rule)
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+
+	// Full buckets are swept once idle
+	now = now.Add(2 * idleBucketSweepInterval)
+	_, err = limiter.Allow(ctx, "user:3", rule)
+	require.NoError(t, err)
+	assert.Len(t, limiter.buckets, 1)
+}
+
+func TestRedisRateLimiter(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	limiter := NewRedisRateLimiter(client, "ratelimit:")
+	rule := services.RateLimitRule{Rate: 1, Burst: 2}
+
+	for i := 0; i < 2; i++ {
+		decision, err := limiter.Allow(ctx, "user:1", rule)
+		require.NoError(t, err)
+		assert.True(t, decision.Allowed)
+		assert.Equal(t, 1-i, decision.Remaining)
+	}
+
+	decision, err := limiter.Allow(ctx, "user:1", rule)
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Greater(t, decision.RetryAfter, time.Duration(0))
+	assert.LessOrEqual(t, decision.RetryAfter, time.Second)
+	assert.Equal(t, 0, decision.Remaining)
+	assert.Greater(t, decision.ResetAfter, time.Second)
+	assert.LessOrEqual(t, decision.ResetAfter, 2*time.Second)
+
+	assert.True(t, server.Exists("ratelimit:user:1"))
+}
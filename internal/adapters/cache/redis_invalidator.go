@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+)
+
+// RedisInvalidator keeps the process-local balance caches of all replicas
+// consistent by announcing balance updates on a Redis pub/sub channel. Each
+// replica drops its cached entry when another replica updates the balance.
+type RedisInvalidator struct {
+	client  *redis.Client
+	channel string
+	cache   *MemoryBalanceCache
+	// origin identifies this replica so it ignores its own announcements
+	origin string
+	logger zerolog.Logger
+}
+
+// NewRedisInvalidator creates a new RedisInvalidator for the given cache
+func NewRedisInvalidator(
+	client *redis.Client,
+	channel string,
+	cache *MemoryBalanceCache,
+	logger zerolog.Logger,
+) *RedisInvalidator {
+	return &RedisInvalidator{
+		client:  client,
+		channel: channel,
+		cache:   cache,
+		origin:  newOrigin(),
+		logger:  logger,
+	}
+}
+
+// Invalidate announces that the user's balance changed on this replica
+func (i *RedisInvalidator) Invalidate(ctx context.Context, userID uint64) error {
+	return i.client.Publish(ctx, i.channel, i.origin+":"+strconv.FormatUint(userID, 10)).Err()
+}
+
+// Run listens for invalidations from other replicas until ctx is cancelled
+func (i *RedisInvalidator) Run(ctx context.Context) {
+	pubsub := i.client.Subscribe(ctx, i.channel)
+	defer pubsub.Close()
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			if err := i.handle(ctx, msg.Payload); err != nil {
+				i.logger.Warn().Err(err).Str("payload", msg.Payload).Msg("ignoring balance invalidation")
+			}
+		}
+	}
+}
+
+// handle applies a single "origin:userID" invalidation message
+func (i *RedisInvalidator) handle(ctx context.Context, payload string) error {
+	origin, rawUserID, ok := strings.Cut(payload, ":")
+	if !ok {
+		return fmt.Errorf("malformed message")
+	}
+	userID, err := strconv.ParseUint(rawUserID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid user ID: %w", err)
+	}
+	if origin == i.origin {
+		return nil
+	}
+
+	i.cache.Delete(ctx, userID)
+	return nil
+}
+
+func newOrigin() string {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
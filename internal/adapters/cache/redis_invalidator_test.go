@@ -0,0 +1,37 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"transaction-service/internal/application/services"
+
+	"github.com/rs/zerolog"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisInvalidator_Handle(t *testing.T) {
+	ctx := context.Background()
+	balanceCache := NewMemoryBalanceCache()
+	for _, userID := range []uint64{1, 2} {
+		balanceCache.Set(ctx, userID, services.CachedBalance{Balance: decimal.NewFromInt(10), CachedAt: time.Now()})
+	}
+	invalidator := NewRedisInvalidator(nil, "balance-invalidations", balanceCache, zerolog.Nop())
+
+	// Announcements from this replica keep the freshly written entry
+	assert.NoError(t, invalidator.handle(ctx, invalidator.origin+":1"))
+	_, ok := balanceCache.Get(ctx, 1)
+	assert.True(t, ok)
+
+	// Announcements from other replicas drop it
+	assert.NoError(t, invalidator.handle(ctx, "other:1"))
+	_, ok = balanceCache.Get(ctx, 1)
+	assert.False(t, ok)
+
+	assert.Error(t, invalidator.handle(ctx, "malformed"))
+	assert.Error(t, invalidator.handle(ctx, "other:abc"))
+	_, ok = balanceCache.Get(ctx, 2)
+	assert.True(t, ok)
+}
@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"transaction-service/internal/application/services"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucketScript takes a token from the bucket in KEYS[1] atomically. It
+// uses the Redis server clock so replicas with skewed clocks agree. ARGV is
+// the rate per second and the burst; it returns whether the token was taken,
+// otherwise the milliseconds until one is available, the whole tokens left
+// and the milliseconds until the bucket is full again.
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(state[1]) or burst
+local updated = tonumber(state[2]) or now
+if now > updated then
+	tokens = math.min(burst, tokens + (now - updated) / 1000 * rate)
+end
+
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) / rate * 1000)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updated', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+return {allowed, retry, math.floor(tokens), math.ceil((burst - tokens) / rate * 1000)}
+`)
+
+// RedisRateLimiter is a RateLimiter whose buckets are shared by all replicas
+// through Redis
+type RedisRateLimiter struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisRateLimiter creates a limiter storing its buckets under prefix
+func NewRedisRateLimiter(client *redis.Client, prefix string) *RedisRateLimiter {
+	return &RedisRateLimiter{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// Allow takes a token from the bucket named key
+func (l *RedisRateLimiter) Allow(ctx context.Context, key string, rule services.RateLimitRule) (services.RateLimitDecision, error) {
+	result, err := tokenBucketScript.Run(ctx, l.client, []string{l.prefix + key}, rule.Rate, rule.Burst).Int64Slice()
+	if err != nil {
+		return services.RateLimitDecision{}, fmt.Errorf("failed to take rate limit token: %w", err)
+	}
+	if len(result) != 4 {
+		return services.RateLimitDecision{}, fmt.Errorf("unexpected rate limit script result %v", result)
+	}
+
+	return services.RateLimitDecision{
+		Allowed:    result[0] == 1,
+		RetryAfter: time.Duration(result[1]) * time.Millisecond,
+		Remaining:  int(result[2]),
+		ResetAfter: time.Duration(result[3]) * time.Millisecond,
+	}, nil
+}
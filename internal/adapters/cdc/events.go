@@ -0,0 +1,68 @@
+package cdc
+
+import (
+	"encoding/json"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+)
+
+// entityNames names the entities of the replicated tables in the event types
+var entityNames = map[string]string{
+	"transactions": "transaction",
+	"users":        "user",
+}
+
+// keyColumns hold the user ID keying the events of a table, so that the
+// events of a user keep their order
+var keyColumns = map[string]string{
+	"transactions": "user_id",
+	"users":        "id",
+}
+
+var verbs = map[string]string{
+	operationInsert: "created",
+	operationUpdate: "updated",
+	operationDelete: "deleted",
+}
+
+// transaction collects the events of a database transaction until it commits
+type transaction struct {
+	commitTime time.Time
+	events     []*entities.OutboxEvent
+}
+
+// add records the event of a change read at lsn. Events are identified by
+// the position of their WAL record, plus their index for records holding
+// several rows, which stays below the position of the next record.
+func (t *transaction) add(lsn uint64, c change) {
+	id := lsn
+	if n := len(t.events); n > 0 && id <= t.events[n-1].ID {
+		id = t.events[n-1].ID + 1
+	}
+
+	entity, ok := entityNames[c.table]
+	if !ok {
+		entity = c.table
+	}
+	var key string
+	if value := c.row[keyColumns[c.table]]; value != nil {
+		key = *value
+	}
+	// A map of strings always encodes
+	payload, _ := json.Marshal(entities.ChangeEvent{
+		Table:       c.table,
+		Operation:   c.operation,
+		Row:         c.row,
+		LSN:         formatLSN(lsn),
+		CommittedAt: t.commitTime,
+	})
+
+	t.events = append(t.events, &entities.OutboxEvent{
+		ID:        id,
+		Type:      entity + "." + verbs[c.operation],
+		Key:       key,
+		Payload:   payload,
+		CreatedAt: t.commitTime,
+	})
+}
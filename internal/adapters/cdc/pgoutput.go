@@ -0,0 +1,233 @@
+package cdc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// pgEpoch is the origin of the timestamps of the replication protocol
+var pgEpoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+var errMalformed = errors.New("malformed pgoutput message")
+
+// Operations of the changes
+const (
+	operationInsert = "insert"
+	operationUpdate = "update"
+	operationDelete = "delete"
+)
+
+// relation is a replicated table as described by the server before its
+// first change
+type relation struct {
+	name    string
+	columns []string
+}
+
+// change is a row written by a database transaction
+type change struct {
+	table     string
+	operation string
+	row       map[string]*string
+}
+
+// message is a decoded pgoutput message. Only the messages a publisher acts
+// on are decoded; the others come back as nil.
+type message any
+
+type beginMessage struct {
+	commitTime time.Time
+}
+
+type commitMessage struct {
+	// endLSN is the position right after the transaction
+	endLSN uint64
+}
+
+// decoder decodes the messages of the pgoutput plugin, version 1. The server
+// describes every table before the first change to it on a connection, so a
+// decoder lives as long as its connection.
+type decoder struct {
+	relations map[uint32]relation
+}
+
+func newDecoder() *decoder {
+	return &decoder{relations: make(map[uint32]relation)}
+}
+
+// decode decodes a message, remembering the tables it describes
+func (d *decoder) decode(data []byte) (message, error) {
+	if len(data) == 0 {
+		return nil, errMalformed
+	}
+	r := &reader{buf: data[1:]}
+
+	var msg message
+	switch data[0] {
+	case 'B':
+		r.uint64() // final LSN
+		msg = beginMessage{commitTime: r.time()}
+		r.uint32() // transaction ID
+	case 'C':
+		r.uint8()  // flags
+		r.uint64() // commit LSN
+		msg = commitMessage{endLSN: r.uint64()}
+		r.uint64() // commit time
+	case 'R':
+		d.decodeRelation(r)
+	case 'I':
+		msg = d.decodeInsert(r)
+	case 'U':
+		msg = d.decodeUpdate(r)
+	case 'D':
+		msg = d.decodeDelete(r)
+	}
+
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to decode %q message: %w", data[0], r.err)
+	}
+	return msg, nil
+}
+
+func (d *decoder) decodeRelation(r *reader) {
+	id := r.uint32()
+	r.string() // namespace
+	rel := relation{name: r.string()}
+	r.uint8() // replica identity
+	columns := int(r.uint16())
+	for i := 0; i < columns && r.err == nil; i++ {
+		r.uint8() // flags
+		rel.columns = append(rel.columns, r.string())
+		r.uint32() // type
+		r.uint32() // type modifier
+	}
+	if r.err == nil {
+		d.relations[id] = rel
+	}
+}
+
+func (d *decoder) decodeInsert(r *reader) message {
+	rel := d.relation(r)
+	if r.expect('N') {
+		return change{table: rel.name, operation: operationInsert, row: r.tuple(rel)}
+	}
+	return nil
+}
+
+func (d *decoder) decodeUpdate(r *reader) message {
+	rel := d.relation(r)
+	// The old key or row comes first when the update changed the key
+	if len(r.buf) > 0 && (r.buf[0] == 'K' || r.buf[0] == 'O') {
+		r.uint8()
+		r.tuple(rel)
+	}
+	if r.expect('N') {
+		return change{table: rel.name, operation: operationUpdate, row: r.tuple(rel)}
+	}
+	return nil
+}
+
+func (d *decoder) decodeDelete(r *reader) message {
+	rel := d.relation(r)
+	kind := r.uint8()
+	if kind != 'K' && kind != 'O' {
+		r.fail()
+		return nil
+	}
+	return change{table: rel.name, operation: operationDelete, row: r.tuple(rel)}
+}
+
+func (d *decoder) relation(r *reader) relation {
+	id := r.uint32()
+	rel, ok := d.relations[id]
+	if !ok && r.err == nil {
+		r.err = fmt.Errorf("change to undescribed relation %d", id)
+	}
+	return rel
+}
+
+// reader reads the fields of a message, remembering the first error
+type reader struct {
+	buf []byte
+	err error
+}
+
+func (r *reader) fail() {
+	if r.err == nil {
+		r.err = errMalformed
+	}
+	r.buf = nil
+}
+
+func (r *reader) next(n int) []byte {
+	if r.err != nil || len(r.buf) < n {
+		r.fail()
+		// Zeros for the fixed-size fields, whatever length was claimed
+		return make([]byte, min(n, 8))
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *reader) uint8() byte     { return r.next(1)[0] }
+func (r *reader) uint16() uint16  { return binary.BigEndian.Uint16(r.next(2)) }
+func (r *reader) uint32() uint32  { return binary.BigEndian.Uint32(r.next(4)) }
+func (r *reader) uint64() uint64  { return binary.BigEndian.Uint64(r.next(8)) }
+func (r *reader) time() time.Time { return pgTime(r.uint64()) }
+func (r *reader) expect(b byte) bool {
+	if r.uint8() != b {
+		r.fail()
+		return false
+	}
+	return true
+}
+
+// string reads a NUL-terminated string
+func (r *reader) string() string {
+	end := bytes.IndexByte(r.buf, 0)
+	if r.err != nil || end < 0 {
+		r.fail()
+		return ""
+	}
+	s := string(r.buf[:end])
+	r.buf = r.buf[end+1:]
+	return s
+}
+
+// tuple reads the column values of a row of rel. Unchanged TOASTed values
+// are left out, since the server does not send them.
+func (r *reader) tuple(rel relation) map[string]*string {
+	columns := int(r.uint16())
+	if r.err == nil && columns > len(rel.columns) {
+		r.fail()
+	}
+	row := make(map[string]*string, columns)
+	for i := 0; i < columns && r.err == nil; i++ {
+		switch r.uint8() {
+		case 'n':
+			row[rel.columns[i]] = nil
+		case 'u':
+		case 't':
+			value := string(r.next(int(r.uint32())))
+			row[rel.columns[i]] = &value
+		default:
+			r.fail()
+		}
+	}
+	return row
+}
+
+// pgTime converts a timestamp of the replication protocol, in microseconds
+// since 2000-01-01
+func pgTime(micros uint64) time.Time {
+	return pgEpoch.Add(time.Duration(int64(micros)) * time.Microsecond)
+}
+
+// formatLSN formats a WAL position the way PostgreSQL does, e.g. 16/B374D848
+func formatLSN(lsn uint64) string {
+	return fmt.Sprintf("%X/%X", lsn>>32, uint32(lsn))
+}
@@ -0,0 +1,130 @@
+package cdc
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// messageBuilder writes pgoutput messages
+type messageBuilder []byte
+
+func (b messageBuilder) byte(v byte) messageBuilder { return append(b, v) }
+func (b messageBuilder) uint16(v uint16) messageBuilder {
+	return binary.BigEndian.AppendUint16(b, v)
+}
+func (b messageBuilder) uint32(v uint32) messageBuilder {
+	return binary.BigEndian.AppendUint32(b, v)
+}
+func (b messageBuilder) uint64(v uint64) messageBuilder {
+	return binary.BigEndian.AppendUint64(b, v)
+}
+func (b messageBuilder) string(v string) messageBuilder { return append(append(b, v...), 0) }
+
+// tuple writes column values; nil values are NULL
+func (b messageBuilder) tuple(values ...*string) messageBuilder {
+	b = b.uint16(uint16(len(values)))
+	for _, value := range values {
+		if value == nil {
+			b = b.byte('n')
+			continue
+		}
+		b = b.byte('t').uint32(uint32(len(*value)))
+		b = append(b, *value...)
+	}
+	return b
+}
+
+func relationMessage(id uint32, table string, columns ...string) []byte {
+	b := messageBuilder{'R'}.uint32(id).string("public").string(table).byte('d').uint16(uint16(len(columns)))
+	for _, column := range columns {
+		b = b.byte(0).string(column).uint32(25).uint32(0xFFFFFFFF)
+	}
+	return b
+}
+
+func text(value string) *string {
+	return &value
+}
+
+func TestDecoder(t *testing.T) {
+	d := newDecoder()
+	commitTime := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	micros := uint64(commitTime.Sub(pgEpoch).Microseconds())
+
+	msg, err := d.decode(messageBuilder{'B'}.uint64(0x10).uint64(micros).uint32(7))
+	require.NoError(t, err)
+	assert.Equal(t, beginMessage{commitTime: commitTime}, msg)
+
+	msg, err = d.decode(relationMessage(1, "transactions", "id", "user_id", "round_id"))
+	require.NoError(t, err)
+	assert.Nil(t, msg)
+
+	msg, err = d.decode(messageBuilder{'I'}.uint32(1).byte('N').tuple(text("7"), text("3"), nil))
+	require.NoError(t, err)
+	assert.Equal(t, change{
+		table:     "transactions",
+		operation: operationInsert,
+		row:       map[string]*string{"id": text("7"), "user_id": text("3"), "round_id": nil},
+	}, msg)
+
+	// The old key of an update is skipped
+	msg, err = d.decode(messageBuilder{'U'}.uint32(1).byte('K').tuple(text("6")).byte('N').tuple(text("7"), text("3"), text("r-1")))
+	require.NoError(t, err)
+	assert.Equal(t, operationUpdate, msg.(change).operation)
+	assert.Equal(t, "r-1", *msg.(change).row["round_id"])
+
+	msg, err = d.decode(messageBuilder{'D'}.uint32(1).byte('K').tuple(text("7")))
+	require.NoError(t, err)
+	assert.Equal(t, change{table: "transactions", operation: operationDelete, row: map[string]*string{"id": text("7")}}, msg)
+
+	msg, err = d.decode(messageBuilder{'C'}.byte(0).uint64(0x10).uint64(0x20).uint64(micros))
+	require.NoError(t, err)
+	assert.Equal(t, commitMessage{endLSN: 0x20}, msg)
+
+	_, err = d.decode(messageBuilder{'I'}.uint32(2).byte('N').tuple(text("1")))
+	assert.ErrorContains(t, err, "undescribed relation 2")
+
+	_, err = d.decode(messageBuilder{'I'}.uint32(1).byte('N').uint16(1).byte('t').uint32(100))
+	assert.ErrorIs(t, err, errMalformed)
+}
+
+func TestTransaction_Add(t *testing.T) {
+	commitTime := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	tx := &transaction{commitTime: commitTime}
+
+	tx.add(0x16B374D848, change{table: "transactions", operation: operationInsert, row: map[string]*string{"id": text("7"), "user_id": text("3")}})
+	tx.add(0x16B374D848, change{table: "transactions", operation: operationInsert, row: map[string]*string{"id": text("8"), "user_id": text("4")}})
+	tx.add(0x16B374D900, change{table: "users", operation: operationUpdate, row: map[string]*string{"id": text("3")}})
+
+	require.Len(t, tx.events, 3)
+	assert.Equal(t, uint64(0x16B374D848), tx.events[0].ID)
+	// Rows of the same WAL record get their own IDs
+	assert.Equal(t, uint64(0x16B374D849), tx.events[1].ID)
+	assert.Equal(t, "transaction.created", tx.events[0].Type)
+	assert.Equal(t, "4", tx.events[1].Key)
+	assert.Equal(t, "user.updated", tx.events[2].Type)
+	assert.Equal(t, "3", tx.events[2].Key)
+
+	var payload entities.ChangeEvent
+	require.NoError(t, json.Unmarshal(tx.events[0].Payload, &payload))
+	assert.Equal(t, "16/B374D848", payload.LSN)
+	assert.Equal(t, "7", *payload.Row["id"])
+	assert.True(t, commitTime.Equal(payload.CommittedAt))
+}
+
+func TestParseLSN(t *testing.T) {
+	lsn, err := parseLSN("16/B374D848")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0x16B374D848), lsn)
+	assert.Equal(t, "16/B374D848", formatLSN(lsn))
+
+	_, err = parseLSN("B374D848")
+	assert.Error(t, err)
+}
@@ -0,0 +1,274 @@
+// Package cdc publishes the changes of the transactions and users tables as
+// events, read from a PostgreSQL logical replication slot. The slot keeps the
+// changes until they were published, so a change is published whichever code
+// path made it, even one bypassing the outbox.
+package cdc
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"transaction-service/internal/adapters/database"
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/config"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgproto3"
+	"github.com/rs/zerolog"
+)
+
+// closeTimeout bounds closing the replication connection
+const closeTimeout = 5 * time.Second
+
+// Stream publishes the changes read from a logical replication slot. The
+// position of the slot only moves past a database transaction once all of its
+// changes were published, so changes are published at least once, in commit
+// order.
+type Stream struct {
+	dsn            string
+	slot           string
+	publication    string
+	statusInterval time.Duration
+	publisher      services.EventPublisher
+	logger         zerolog.Logger
+}
+
+// NewStream creates a Stream reading from the database of databaseCfg
+func NewStream(
+	databaseCfg config.DatabaseConfig,
+	cfg config.CDCConfig,
+	publisher services.EventPublisher,
+	logger zerolog.Logger,
+) *Stream {
+	return &Stream{
+		dsn:            database.PostgresDSN(databaseCfg) + " replication=database",
+		slot:           cfg.Slot,
+		publication:    cfg.Publication,
+		statusInterval: cfg.StatusInterval,
+		publisher:      publisher,
+		logger:         logger,
+	}
+}
+
+// Run creates the publication and the slot when missing and publishes the
+// changes until ctx is cancelled or the stream fails. Progress is called
+// whenever changes were published or the position reported. Run returns nil
+// right away while another process streams from the slot.
+func (s *Stream) Run(ctx context.Context, progress func()) error {
+	conn, err := pgconn.Connect(ctx, s.dsn)
+	if err != nil {
+		return fmt.Errorf("failed to open replication connection: %w", err)
+	}
+	defer func() {
+		closeCtx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+		defer cancel()
+		_ = conn.Close(closeCtx)
+	}()
+
+	flushed, err := s.setUp(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if err := s.start(ctx, conn); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "55006" {
+			s.logger.Debug().Str("slot", s.slot).Msg("replication slot is in use by another process")
+			return nil
+		}
+		return err
+	}
+	s.logger.Info().Str("slot", s.slot).Str("lsn", formatLSN(flushed)).Msg("change stream started")
+
+	return s.stream(ctx, conn, flushed, progress)
+}
+
+// setUp creates the publication and the slot when missing, and returns the
+// position up to which the changes were published
+func (s *Stream) setUp(ctx context.Context, conn *pgconn.PgConn) (uint64, error) {
+	// The names were checked to be plain identifiers by the configuration
+	rows, err := query(ctx, conn, "SELECT 1 FROM pg_publication WHERE pubname = '"+s.publication+"'")
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up publication: %w", err)
+	}
+	if len(rows) == 0 {
+		// Truncations are left out, as they carry no rows
+		err := conn.Exec(ctx, "CREATE PUBLICATION "+s.publication+
+			" FOR TABLE transactions, users WITH (publish = 'insert, update, delete')").Close()
+		if err != nil {
+			return 0, fmt.Errorf("failed to create publication: %w", err)
+		}
+		s.logger.Info().Str("publication", s.publication).Msg("publication created")
+	}
+
+	rows, err = query(ctx, conn, "SELECT confirmed_flush_lsn FROM pg_replication_slots WHERE slot_name = '"+s.slot+"'")
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up replication slot: %w", err)
+	}
+	if len(rows) == 0 {
+		// The slot keeps the changes from its consistent point on
+		rows, err = query(ctx, conn, "CREATE_REPLICATION_SLOT "+s.slot+" LOGICAL pgoutput")
+		if err != nil {
+			return 0, fmt.Errorf("failed to create replication slot: %w", err)
+		}
+		if len(rows) == 0 || len(rows[0]) < 2 {
+			return 0, fmt.Errorf("failed to create replication slot: no consistent point")
+		}
+		s.logger.Info().Str("slot", s.slot).Msg("replication slot created")
+		return parseLSN(string(rows[0][1]))
+	}
+	return parseLSN(string(rows[0][0]))
+}
+
+// start starts streaming from the slot
+func (s *Stream) start(ctx context.Context, conn *pgconn.PgConn) error {
+	conn.Frontend().Send(&pgproto3.Query{String: fmt.Sprintf(
+		"START_REPLICATION SLOT %s LOGICAL 0/0 (proto_version '1', publication_names '%s')",
+		s.slot, s.publication,
+	)})
+	if err := conn.Frontend().Flush(); err != nil {
+		return fmt.Errorf("failed to start replication: %w", err)
+	}
+
+	for {
+		msg, err := conn.ReceiveMessage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to start replication: %w", err)
+		}
+		switch msg := msg.(type) {
+		case *pgproto3.CopyBothResponse:
+			return nil
+		case *pgproto3.ErrorResponse:
+			return fmt.Errorf("failed to start replication: %w", pgconn.ErrorResponseToPgError(msg))
+		}
+	}
+}
+
+// stream publishes the changes of every committed database transaction and
+// reports the published position every status interval
+func (s *Stream) stream(ctx context.Context, conn *pgconn.PgConn, flushed uint64, progress func()) error {
+	decoder := newDecoder()
+	// pending collects the changes of the database transaction being read
+	var pending *transaction
+	nextStatus := time.Now()
+
+	for {
+		if !time.Now().Before(nextStatus) {
+			if err := sendStatus(conn, flushed); err != nil {
+				return fmt.Errorf("failed to report replication status: %w", err)
+			}
+			progress()
+			nextStatus = time.Now().Add(s.statusInterval)
+		}
+
+		receiveCtx, cancel := context.WithDeadline(ctx, nextStatus)
+		msg, err := conn.ReceiveMessage(receiveCtx)
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if pgconn.Timeout(err) {
+				continue
+			}
+			return fmt.Errorf("failed to receive replication message: %w", err)
+		}
+
+		var data []byte
+		switch msg := msg.(type) {
+		case *pgproto3.CopyData:
+			data = msg.Data
+		case *pgproto3.ErrorResponse:
+			return fmt.Errorf("replication failed: %w", pgconn.ErrorResponseToPgError(msg))
+		default:
+			continue
+		}
+
+		switch {
+		case len(data) >= 18 && data[0] == 'k':
+			// Between database transactions nothing is in flight, so the
+			// slot may release the WAL written for other tables
+			if walEnd := binary.BigEndian.Uint64(data[1:9]); pending == nil && walEnd > flushed {
+				flushed = walEnd
+			}
+			if data[17] == 1 {
+				nextStatus = time.Now()
+			}
+
+		case len(data) >= 25 && data[0] == 'w':
+			decoded, err := decoder.decode(data[25:])
+			if err != nil {
+				return err
+			}
+			switch decoded := decoded.(type) {
+			case beginMessage:
+				pending = &transaction{commitTime: decoded.commitTime}
+			case change:
+				if pending == nil {
+					return fmt.Errorf("change outside of a transaction")
+				}
+				pending.add(binary.BigEndian.Uint64(data[1:9]), decoded)
+			case commitMessage:
+				if pending == nil {
+					return fmt.Errorf("commit outside of a transaction")
+				}
+				if len(pending.events) > 0 {
+					if err := s.publisher.Publish(ctx, pending.events); err != nil {
+						return fmt.Errorf("failed to publish changes: %w", err)
+					}
+					progress()
+				}
+				flushed, pending = decoded.endLSN, nil
+			}
+		}
+	}
+}
+
+// sendStatus reports that the changes before lsn were published
+func sendStatus(conn *pgconn.PgConn, lsn uint64) error {
+	data := []byte{'r'}
+	data = binary.BigEndian.AppendUint64(data, lsn) // written
+	data = binary.BigEndian.AppendUint64(data, lsn) // flushed
+	data = binary.BigEndian.AppendUint64(data, lsn) // applied
+	data = binary.BigEndian.AppendUint64(data, uint64(time.Since(pgEpoch).Microseconds()))
+	data = append(data, 0) // no reply requested
+
+	msg, err := (&pgproto3.CopyData{Data: data}).Encode(nil)
+	if err != nil {
+		return err
+	}
+	return conn.Frontend().SendUnbufferedEncodedCopyData(msg)
+}
+
+// query runs a statement on a replication connection and returns its rows
+func query(ctx context.Context, conn *pgconn.PgConn, sql string) ([][][]byte, error) {
+	results, err := conn.Exec(ctx, sql).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, nil
+	}
+	return results[0].Rows, nil
+}
+
+// parseLSN parses a WAL position formatted by PostgreSQL, e.g. 16/B374D848
+func parseLSN(value string) (uint64, error) {
+	high, low, ok := strings.Cut(value, "/")
+	if !ok {
+		return 0, fmt.Errorf("invalid LSN %q", value)
+	}
+	h, err := strconv.ParseUint(high, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid LSN %q", value)
+	}
+	l, err := strconv.ParseUint(low, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid LSN %q", value)
+	}
+	return h<<32 | l, nil
+}
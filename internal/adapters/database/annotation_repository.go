@@ -0,0 +1,110 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"transaction-service/internal/domain/entities"
+)
+
+// AnnotationRepository implements the annotation repository interface
+type AnnotationRepository struct {
+	db *sql.DB
+}
+
+// NewAnnotationRepository creates a new annotation repository
+func NewAnnotationRepository(db *sql.DB) *AnnotationRepository {
+	return &AnnotationRepository{db: db}
+}
+
+// Create stores a new annotation. A zero ID is allocated from the sequence.
+func (r *AnnotationRepository) Create(ctx context.Context, annotation *entities.Annotation) error {
+	query := `
+		INSERT INTO annotations (id, target_type, target_id, author, note, created_at)
+		VALUES (COALESCE($1::BIGINT, nextval(pg_get_serial_sequence('annotations', 'id'))), $2, $3, $4, $5, $6)
+		RETURNING id
+	`
+
+	err := Executor(ctx, r.db).QueryRowContext(
+		ctx,
+		query,
+		explicitID(annotation.ID),
+		annotation.TargetType,
+		annotation.TargetID,
+		annotation.Author,
+		annotation.Note,
+		annotation.CreatedAt,
+	).Scan(&annotation.ID)
+
+	if err != nil {
+		return fmt.Errorf("failed to create annotation: %w", classify(err))
+	}
+
+	return nil
+}
+
+// ListByTarget returns the annotations on a record, oldest first
+func (r *AnnotationRepository) ListByTarget(
+	ctx context.Context,
+	targetType entities.AnnotationTarget,
+	targetID string,
+) ([]*entities.Annotation, error) {
+	query := `
+		SELECT id, target_type, target_id, author, note, created_at
+		FROM annotations
+		WHERE target_type = $1 AND target_id = $2
+		ORDER BY created_at, id
+	`
+
+	return queryAnnotations(ctx, r.db, query, targetType, targetID)
+}
+
+// ListByUser returns the annotations on a user and on the user's
+// transactions, oldest first
+func (r *AnnotationRepository) ListByUser(ctx context.Context, userID uint64) ([]*entities.Annotation, error) {
+	query := `
+		SELECT id, target_type, target_id, author, note, created_at
+		FROM annotations
+		WHERE (target_type = 'user' AND target_id = $1)
+		   OR (target_type = 'transaction' AND target_id IN (
+				SELECT transaction_id FROM transactions WHERE user_id = $2
+		   ))
+		ORDER BY created_at, id
+	`
+
+	return queryAnnotations(ctx, r.db, query, strconv.FormatUint(userID, 10), userID)
+}
+
+// queryAnnotations runs a query selecting the columns of annotations
+func queryAnnotations(ctx context.Context, db *sql.DB, query string, args ...any) ([]*entities.Annotation, error) {
+	rows, err := Executor(ctx, db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list annotations: %w", classify(err))
+	}
+	defer rows.Close()
+
+	var annotations []*entities.Annotation
+	for rows.Next() {
+		var annotation entities.Annotation
+		err := rows.Scan(
+			&annotation.ID,
+			&annotation.TargetType,
+			&annotation.TargetID,
+			&annotation.Author,
+			&annotation.Note,
+			&annotation.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan annotation: %w", classify(err))
+		}
+		annotations = append(annotations, &annotation)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate annotations: %w", classify(err))
+	}
+
+	return annotations, nil
+}
@@ -0,0 +1,119 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+)
+
+// AuditLogRepository implements the audit log repository interface
+type AuditLogRepository struct {
+	db *sql.DB
+}
+
+// NewAuditLogRepository creates a new audit log repository
+func NewAuditLogRepository(db *sql.DB) *AuditLogRepository {
+	return &AuditLogRepository{db: db}
+}
+
+// Append adds an entry to the audit log
+func (r *AuditLogRepository) Append(ctx context.Context, entry *entities.AuditEntry) error {
+	query := `
+		INSERT INTO audit_log (user_id, operation, transaction_id, change, currency, balance_before, balance_after, actor, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id
+	`
+
+	err := Executor(ctx, r.db).QueryRowContext(
+		ctx,
+		query,
+		entry.UserID,
+		entry.Operation,
+		entry.TransactionID,
+		entry.Change,
+		entry.Currency,
+		entry.BalanceBefore,
+		entry.BalanceAfter,
+		entry.Actor,
+		entry.CreatedAt,
+	).Scan(&entry.ID)
+
+	if err != nil {
+		return fmt.Errorf("failed to append audit entry: %w", classify(err))
+	}
+
+	return nil
+}
+
+// List returns a page of the entries matching the filter, newest first
+func (r *AuditLogRepository) List(ctx context.Context, filter repositories.AuditLogFilter) ([]*entities.AuditEntry, int, error) {
+	var conditions []string
+	var args []any
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.UserID != 0 {
+		add("user_id = $%d", filter.UserID)
+	}
+	if filter.From != nil {
+		add("created_at >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		add("created_at < $%d", *filter.To)
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	countQuery := "SELECT COUNT(*) FROM audit_log" + where
+	if err := Executor(ctx, r.db).QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit entries: %w", classify(err))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, user_id, operation, transaction_id, change, currency, balance_before, balance_after, actor, created_at
+		FROM audit_log%s
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d OFFSET $%d
+	`, where, len(args)+1, len(args)+2)
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list audit entries: %w", classify(err))
+	}
+	defer rows.Close()
+
+	entries := []*entities.AuditEntry{}
+	for rows.Next() {
+		var entry entities.AuditEntry
+		err := rows.Scan(
+			&entry.ID,
+			&entry.UserID,
+			&entry.Operation,
+			&entry.TransactionID,
+			&entry.Change,
+			&entry.Currency,
+			&entry.BalanceBefore,
+			&entry.BalanceAfter,
+			&entry.Actor,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan audit entry: %w", classify(err))
+		}
+		entries = append(entries, &entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating audit entries: %w", classify(err))
+	}
+
+	return entries, total, nil
+}
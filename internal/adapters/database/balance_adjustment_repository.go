@@ -0,0 +1,134 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+)
+
+// BalanceAdjustmentRepository implements the balance adjustment repository
+// interface
+type BalanceAdjustmentRepository struct {
+	db *sql.DB
+}
+
+// NewBalanceAdjustmentRepository creates a new balance adjustment repository
+func NewBalanceAdjustmentRepository(db *sql.DB) *BalanceAdjustmentRepository {
+	return &BalanceAdjustmentRepository{db: db}
+}
+
+// balanceAdjustmentColumns are the columns scanned by scanBalanceAdjustment
+const balanceAdjustmentColumns = `id, adjustment_id, user_id, transaction_id, direction, amount, currency, reason, actor,
+	balance_before, balance_after, created_at`
+
+// Create stores a new adjustment. A zero ID is allocated from the sequence.
+func (r *BalanceAdjustmentRepository) Create(ctx context.Context, adjustment *entities.BalanceAdjustment) error {
+	query := `
+		INSERT INTO balance_adjustments (id, adjustment_id, user_id, transaction_id, direction, amount, currency, reason,
+			actor, balance_before, balance_after, created_at)
+		VALUES (COALESCE($1::BIGINT, nextval(pg_get_serial_sequence('balance_adjustments', 'id'))),
+			$2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id
+	`
+
+	err := Executor(ctx, r.db).QueryRowContext(
+		ctx,
+		query,
+		explicitID(adjustment.ID),
+		adjustment.AdjustmentID,
+		adjustment.UserID,
+		adjustment.TransactionID,
+		adjustment.Direction,
+		adjustment.Amount,
+		adjustment.Currency,
+		adjustment.Reason,
+		adjustment.Actor,
+		adjustment.BalanceBefore,
+		adjustment.BalanceAfter,
+		adjustment.CreatedAt,
+	).Scan(&adjustment.ID)
+
+	if err != nil {
+		return fmt.Errorf("failed to create balance adjustment: %w", classify(err))
+	}
+
+	return nil
+}
+
+// GetByAdjustmentID retrieves an adjustment by its external ID
+func (r *BalanceAdjustmentRepository) GetByAdjustmentID(
+	ctx context.Context,
+	adjustmentID string,
+) (*entities.BalanceAdjustment, error) {
+	query := `
+		SELECT ` + balanceAdjustmentColumns + `
+		FROM balance_adjustments
+		WHERE adjustment_id = $1
+	`
+
+	adjustment, err := scanBalanceAdjustment(Executor(ctx, r.db).QueryRowContext(ctx, query, adjustmentID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("balance adjustment %s: %w", adjustmentID, repositories.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get balance adjustment: %w", classify(err))
+	}
+
+	return adjustment, nil
+}
+
+// ListByUser returns the adjustments of the user, newest first
+func (r *BalanceAdjustmentRepository) ListByUser(ctx context.Context, userID uint64) ([]*entities.BalanceAdjustment, error) {
+	query := `
+		SELECT ` + balanceAdjustmentColumns + `
+		FROM balance_adjustments
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+	`
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list balance adjustments: %w", classify(err))
+	}
+	defer rows.Close()
+
+	var adjustments []*entities.BalanceAdjustment
+	for rows.Next() {
+		adjustment, err := scanBalanceAdjustment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan balance adjustment: %w", classify(err))
+		}
+		adjustments = append(adjustments, adjustment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate balance adjustments: %w", classify(err))
+	}
+
+	return adjustments, nil
+}
+
+func scanBalanceAdjustment(row rowScanner) (*entities.BalanceAdjustment, error) {
+	var adjustment entities.BalanceAdjustment
+	err := row.Scan(
+		&adjustment.ID,
+		&adjustment.AdjustmentID,
+		&adjustment.UserID,
+		&adjustment.TransactionID,
+		&adjustment.Direction,
+		&adjustment.Amount,
+		&adjustment.Currency,
+		&adjustment.Reason,
+		&adjustment.Actor,
+		&adjustment.BalanceBefore,
+		&adjustment.BalanceAfter,
+		&adjustment.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &adjustment, nil
+}
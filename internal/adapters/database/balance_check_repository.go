@@ -0,0 +1,135 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/shopspring/decimal"
+)
+
+// BalanceCheckRepository implements the balance check repository interface
+type BalanceCheckRepository struct {
+	db *sql.DB
+}
+
+// NewBalanceCheckRepository creates a new balance check repository
+func NewBalanceCheckRepository(db *sql.DB) *BalanceCheckRepository {
+	return &BalanceCheckRepository{db: db}
+}
+
+// Recompute returns the balances of up to limit users with an ID above
+// afterID, each base currency balance followed by the user's wallets
+func (r *BalanceCheckRepository) Recompute(ctx context.Context, afterID uint64, limit int) ([]repositories.BalanceRecomputation, error) {
+	// The opening balance is implied by the first transaction, whose
+	// balance after is unaffected by its later cancellation. Transactions
+	// recorded as pending did not move the balance they stored, and move it
+	// only once settled.
+	query := `
+		SELECT u.id, u.balance, first_transaction.id IS NOT NULL,
+			first_transaction.balance_after - first_transaction.change, COALESCE(net.change, 0)
+		FROM (SELECT id, balance FROM users WHERE id > $1 ORDER BY id LIMIT $2) u
+		LEFT JOIN LATERAL (
+			SELECT id, balance_after,
+				CASE WHEN pending OR settled_at IS NOT NULL THEN 0 WHEN state = 'lose' THEN -amount ELSE amount END AS change
+			FROM transactions
+			WHERE user_id = u.id AND currency = ''
+			ORDER BY id
+			LIMIT 1
+		) first_transaction ON TRUE
+		LEFT JOIN LATERAL (
+			SELECT SUM(CASE WHEN state = 'lose' THEN -amount ELSE amount END) AS change
+			FROM transactions
+			WHERE user_id = u.id AND currency = '' AND NOT cancelled AND NOT pending
+		) net ON TRUE
+		ORDER BY u.id
+	`
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recompute balances: %w", classify(err))
+	}
+	defer rows.Close()
+
+	var balances []repositories.BalanceRecomputation
+	for rows.Next() {
+		var balance repositories.BalanceRecomputation
+		var hasTransactions bool
+		var opening decimal.NullDecimal
+		var net decimal.Decimal
+		if err := rows.Scan(&balance.UserID, &balance.Stored, &hasTransactions, &opening, &net); err != nil {
+			return nil, fmt.Errorf("failed to scan recomputed balance: %w", classify(err))
+		}
+		switch {
+		case !hasTransactions:
+			// Nothing moved the opening balance
+			recomputed := balance.Stored
+			balance.Recomputed = &recomputed
+		case opening.Valid:
+			recomputed := opening.Decimal.Add(net)
+			balance.Recomputed = &recomputed
+		}
+		balances = append(balances, balance)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating recomputed balances: %w", classify(err))
+	}
+	if len(balances) == 0 {
+		return balances, nil
+	}
+
+	wallets, err := r.recomputeWallets(ctx, balances[0].UserID, balances[len(balances)-1].UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Both lists are in user ID order
+	merged := make([]repositories.BalanceRecomputation, 0, len(balances)+len(wallets))
+	for _, balance := range balances {
+		merged = append(merged, balance)
+		for len(wallets) > 0 && wallets[0].UserID == balance.UserID {
+			merged = append(merged, wallets[0])
+			wallets = wallets[1:]
+		}
+	}
+
+	return merged, nil
+}
+
+// recomputeWallets returns the wallets of the users with an ID in [fromID,
+// toID], which start empty, in user ID order
+func (r *BalanceCheckRepository) recomputeWallets(ctx context.Context, fromID, toID uint64) ([]repositories.BalanceRecomputation, error) {
+	query := `
+		SELECT w.user_id, w.currency, w.balance,
+			COALESCE(SUM(CASE WHEN t.state = 'lose' THEN -t.amount ELSE t.amount END), 0)
+		FROM wallets w
+		LEFT JOIN transactions t ON t.user_id = w.user_id AND t.currency = w.currency AND NOT t.cancelled
+		WHERE w.user_id BETWEEN $1 AND $2
+		GROUP BY w.user_id, w.currency, w.balance
+		ORDER BY w.user_id, w.currency
+	`
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, fromID, toID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recompute wallet balances: %w", classify(err))
+	}
+	defer rows.Close()
+
+	var wallets []repositories.BalanceRecomputation
+	for rows.Next() {
+		var wallet repositories.BalanceRecomputation
+		var recomputed decimal.Decimal
+		if err := rows.Scan(&wallet.UserID, &wallet.Currency, &wallet.Stored, &recomputed); err != nil {
+			return nil, fmt.Errorf("failed to scan recomputed wallet balance: %w", classify(err))
+		}
+		wallet.Recomputed = &recomputed
+		wallets = append(wallets, wallet)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating recomputed wallet balances: %w", classify(err))
+	}
+
+	return wallets, nil
+}
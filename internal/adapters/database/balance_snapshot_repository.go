@@ -0,0 +1,133 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+)
+
+// BalanceSnapshotRepository implements the balance snapshot repository
+// interface
+type BalanceSnapshotRepository struct {
+	db *sql.DB
+}
+
+// NewBalanceSnapshotRepository creates a new balance snapshot repository
+func NewBalanceSnapshotRepository(db *sql.DB) *BalanceSnapshotRepository {
+	return &BalanceSnapshotRepository{db: db}
+}
+
+// Create stores a snapshot unless the user's day is already snapshotted
+func (r *BalanceSnapshotRepository) Create(ctx context.Context, snapshot *entities.BalanceSnapshot) error {
+	query := `
+		INSERT INTO balance_snapshots (user_id, day, balance, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, day) DO NOTHING
+	`
+
+	_, err := Executor(ctx, r.db).ExecContext(ctx, query, snapshot.UserID, snapshot.Day, snapshot.Balance, snapshot.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create balance snapshot: %w", classify(err))
+	}
+
+	return nil
+}
+
+// ListUnsnapshotted returns the IDs of up to limit users without a snapshot of
+// day, lowest first
+func (r *BalanceSnapshotRepository) ListUnsnapshotted(ctx context.Context, day time.Time, limit int) ([]uint64, error) {
+	query := `
+		SELECT u.id
+		FROM users u
+		WHERE NOT EXISTS (SELECT 1 FROM balance_snapshots s WHERE s.user_id = u.id AND s.day = $1)
+		ORDER BY u.id
+		LIMIT $2
+	`
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, day, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unsnapshotted users: %w", classify(err))
+	}
+	defer rows.Close()
+
+	var userIDs []uint64
+	for rows.Next() {
+		var userID uint64
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("failed to scan user ID: %w", classify(err))
+		}
+		userIDs = append(userIDs, userID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list unsnapshotted users: %w", classify(err))
+	}
+
+	return userIDs, nil
+}
+
+// ListByUser returns the user's snapshots of the days in [from, to), oldest
+// first. from and to are midnight UTC.
+func (r *BalanceSnapshotRepository) ListByUser(
+	ctx context.Context,
+	userID uint64,
+	from, to time.Time,
+) ([]*entities.BalanceSnapshot, error) {
+	query := `
+		SELECT user_id, day, balance, created_at
+		FROM balance_snapshots
+		WHERE user_id = $1 AND day >= $2 AND day < $3
+		ORDER BY day
+	`
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list balance snapshots: %w", classify(err))
+	}
+	defer rows.Close()
+
+	snapshots := []*entities.BalanceSnapshot{}
+	for rows.Next() {
+		var snapshot entities.BalanceSnapshot
+		if err := rows.Scan(&snapshot.UserID, &snapshot.Day, &snapshot.Balance, &snapshot.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan balance snapshot: %w", classify(err))
+		}
+		snapshots = append(snapshots, &snapshot)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list balance snapshots: %w", classify(err))
+	}
+
+	return snapshots, nil
+}
+
+// GetLatestBefore returns the user's snapshot of the latest day before
+// before, which is midnight UTC
+func (r *BalanceSnapshotRepository) GetLatestBefore(
+	ctx context.Context,
+	userID uint64,
+	before time.Time,
+) (*entities.BalanceSnapshot, error) {
+	query := `
+		SELECT user_id, day, balance, created_at
+		FROM balance_snapshots
+		WHERE user_id = $1 AND day < $2
+		ORDER BY day DESC
+		LIMIT 1
+	`
+
+	var snapshot entities.BalanceSnapshot
+	err := Executor(ctx, r.db).QueryRowContext(ctx, query, userID, before).
+		Scan(&snapshot.UserID, &snapshot.Day, &snapshot.Balance, &snapshot.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("balance snapshot of user %d before %s: %w", userID, before.Format(time.DateOnly), repositories.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get balance snapshot: %w", classify(err))
+	}
+
+	return &snapshot, nil
+}
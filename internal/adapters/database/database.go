@@ -1,44 +1,174 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
-	"os"
+	"strconv"
+	"time"
 
-	_ "github.com/lib/pq"
-)
+	"transaction-service/internal/config"
 
-// NewPostgresConnection creates a new PostgreSQL database connection
-func NewPostgresConnection() (*sql.DB, error) {
-	host := getEnvOrDefault("DB_HOST", "localhost")
-	port := getEnvOrDefault("DB_PORT", "5432")
-	user := getEnvOrDefault("DB_USER", "postgres")
-	password := getEnvOrDefault("DB_PASSWORD", "password")
-	dbname := getEnvOrDefault("DB_NAME", "transaction_db")
-	sslmode := getEnvOrDefault("DB_SSLMODE", "disable")
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+)
 
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		host, port, user, password, dbname, sslmode)
+// NewPostgresConnection creates a new PostgreSQL database connection. The
+// connections are managed by a pgx pool sized by cfg.Pool, which is closed
+// with the returned database. The server cancels statements running longer
+// than cfg.StatementTimeout, and statements are abandoned once they ran for
+// cfg.QueryTimeout; zero disables either.
+func NewPostgresConnection(cfg config.DatabaseConfig) (*sql.DB, error) {
+	poolConfig, err := postgresPoolConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
 
-	db, err := sql.Open("postgres", dsn)
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database connection: %w", err)
 	}
 
+	// The pool keeps the idle connections, so database/sql only bounds how
+	// many are in use
+	db := sql.OpenDB(poolConnector{
+		Connector:    stdlib.GetPoolConnector(pool),
+		pool:         pool,
+		queryTimeout: cfg.QueryTimeout,
+	})
+	db.SetMaxIdleConns(0)
+	db.SetMaxOpenConns(int(poolConfig.MaxConns))
+
 	if err := db.Ping(); err != nil {
+		_ = db.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	// Set connection pool settings
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
-
 	return db, nil
 }
 
-func getEnvOrDefault(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// postgresPoolConfig returns the configuration of the pgx pool of cfg
+func postgresPoolConfig(cfg config.DatabaseConfig) (*pgxpool.Config, error) {
+	poolConfig, err := pgxpool.ParseConfig(PostgresDSN(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse database configuration: %w", err)
+	}
+	// Unset sizes keep the defaults of pgx
+	if cfg.Pool.MaxConns > 0 {
+		poolConfig.MaxConns = cfg.Pool.MaxConns
+	}
+	if cfg.Pool.MaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = cfg.Pool.MaxConnLifetime
+	}
+	if cfg.Pool.MaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = cfg.Pool.MaxConnIdleTime
+	}
+	poolConfig.MinConns = min(cfg.Pool.MinConns, poolConfig.MaxConns)
+	poolConfig.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10)
+	// Timestamps are stored as UTC wall times without a zone. Sessions in
+	// another TimeZone would shift NOW() defaults and zone conversions.
+	poolConfig.ConnConfig.RuntimeParams["timezone"] = "UTC"
+	return poolConfig, nil
+}
+
+// PostgresDSN returns the connection string of the PostgreSQL database of cfg
+func PostgresDSN(cfg config.DatabaseConfig) string {
+	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.Name, cfg.SSLMode)
+	if cfg.Schema != "" {
+		// Unqualified table names resolve to the schema, so the repositories
+		// and migrations work unchanged against it
+		dsn += " search_path=" + cfg.Schema
+	}
+	return dsn
+}
+
+// poolConnector hands the connections of a pgx pool to database/sql and
+// closes the pool when the database is closed
+type poolConnector struct {
+	driver.Connector
+	pool         *pgxpool.Pool
+	queryTimeout time.Duration
+}
+
+// Connect implements driver.Connector
+func (c poolConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil || c.queryTimeout <= 0 {
+		return conn, err
+	}
+	return &timeoutConn{Conn: conn.(*stdlib.Conn), timeout: c.queryTimeout}, nil
+}
+
+// Close implements io.Closer, which sql.DB.Close calls on its connector
+func (c poolConnector) Close() error {
+	c.pool.Close()
+	return nil
+}
+
+// CheckWritable returns an error while the database is a read-only replica
+func CheckWritable(ctx context.Context, db *sql.DB) error {
+	switch driverOf(db) {
+	case DriverSQLite:
+		// A database file has no replicas
+		return nil
+	case DriverMySQL:
+		var readOnly bool
+		if err := db.QueryRowContext(ctx, "SELECT @@global.read_only").Scan(&readOnly); err != nil {
+			return fmt.Errorf("failed to check read-only status: %w", err)
+		}
+		if readOnly {
+			return fmt.Errorf("database is a read-only replica")
+		}
+		return nil
+	}
+
+	var inRecovery bool
+	if err := db.QueryRowContext(ctx, "SELECT pg_is_in_recovery()").Scan(&inRecovery); err != nil {
+		return fmt.Errorf("failed to check recovery status: %w", err)
+	}
+	if inRecovery {
+		return fmt.Errorf("database is a replica in recovery")
+	}
+	return nil
+}
+
+// warmupQueries look up the hottest tables by key, so that a new connection
+// has their metadata and plans loaded before the first request needs them
+var warmupQueries = []string{
+	"SELECT balance FROM users WHERE id = 0",
+	"SELECT id FROM transactions WHERE transaction_id = ''",
+}
+
+// WarmConnections opens n connections to db at once, at most as many as the
+// pool may open, and runs the warm-up queries on each, then returns them to
+// the pool. The pool keeps as many of them as it keeps idle connections.
+func WarmConnections(ctx context.Context, db *sql.DB, n int) error {
+	if limit := db.Stats().MaxOpenConnections; limit > 0 && n > limit {
+		n = limit
+	}
+	conns := make([]*sql.Conn, 0, n)
+	defer func() {
+		for _, conn := range conns {
+			_ = conn.Close()
+		}
+	}()
+
+	for range n {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to open connection: %w", classify(err))
+		}
+		conns = append(conns, conn)
+
+		for _, query := range warmupQueries {
+			rows, err := conn.QueryContext(ctx, query)
+			if err != nil {
+				return fmt.Errorf("failed to warm up connection: %w", classify(err))
+			}
+			_ = rows.Close()
+		}
 	}
-	return defaultValue
+	return nil
 }
@@ -0,0 +1,81 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"transaction-service/internal/config"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Database drivers, named as in DB_DRIVER. PostgreSQL backs every feature;
+// MySQL and SQLite back the users, wallets, transactions and annotations.
+const (
+	DriverPostgres = "postgres"
+	DriverMySQL    = "mysql"
+	DriverSQLite   = "sqlite"
+)
+
+// NewConnection connects to the database of the configured driver
+func NewConnection(cfg config.DatabaseConfig) (*sql.DB, error) {
+	switch cfg.Driver {
+	case DriverMySQL:
+		return NewMySQLConnection(cfg)
+	case DriverSQLite:
+		return NewSQLiteConnection(cfg)
+	case DriverPostgres:
+		return NewPostgresConnection(cfg)
+	default:
+		return nil, fmt.Errorf("unknown database driver %q", cfg.Driver)
+	}
+}
+
+// driverOf returns the driver db connects with
+func driverOf(db *sql.DB) string {
+	switch db.Driver().(type) {
+	case *mysql.MySQLDriver:
+		return DriverMySQL
+	case *sqlite3.SQLiteDriver:
+		return DriverSQLite
+	default:
+		return DriverPostgres
+	}
+}
+
+// Repositories are the repositories every driver implements
+type Repositories struct {
+	Users        repositories.UserRepository
+	Transactions repositories.TransactionRepository
+	Wallets      repositories.WalletRepository
+	Annotations  repositories.AnnotationRepository
+}
+
+// NewRepositories creates the repositories of the driver db connects with
+func NewRepositories(db *sql.DB) Repositories {
+	switch driverOf(db) {
+	case DriverMySQL:
+		return Repositories{
+			Users:        NewMySQLUserRepository(db),
+			Transactions: NewMySQLTransactionRepository(db),
+			Wallets:      NewMySQLWalletRepository(db),
+			Annotations:  NewMySQLAnnotationRepository(db),
+		}
+	case DriverSQLite:
+		return Repositories{
+			Users:        NewSQLiteUserRepository(db),
+			Transactions: NewSQLiteTransactionRepository(db),
+			Wallets:      NewSQLiteWalletRepository(db),
+			Annotations:  NewSQLiteAnnotationRepository(db),
+		}
+	default:
+		return Repositories{
+			Users:        NewUserRepository(db),
+			Transactions: NewTransactionRepository(db),
+			Wallets:      NewWalletRepository(db),
+			Annotations:  NewAnnotationRepository(db),
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// classify tags driver errors with the repository error they amount to,
+// keeping err wrapped so that its cause can still be inspected. Errors that
+// are neither transient, a statement timeout nor a unique violation are
+// returned as they are.
+func classify(err error) error {
+	if IsTransient(err) {
+		return fmt.Errorf("%w: %w", repositories.ErrUnavailable, err)
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation
+		return fmt.Errorf("%w: %w", repositories.ErrConflict, err)
+	}
+	// A statement cancelled by the statement timeout is not retried, since
+	// it would likely time out again, but the database is overloaded rather
+	// than the request wrong
+	if errors.As(err, &pgErr) && pgErr.Code == "57014" { // query_canceled
+		return fmt.Errorf("%w: %w", repositories.ErrUnavailable, err)
+	}
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+		return fmt.Errorf("%w: %w", repositories.ErrConflict, err)
+	}
+	if isSQLiteUniqueViolation(err) {
+		return fmt.Errorf("%w: %w", repositories.ErrConflict, err)
+	}
+
+	return err
+}
@@ -0,0 +1,55 @@
+package database
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassify(t *testing.T) {
+	deadlock := &pgconn.PgError{Code: "40P01"}
+	uniqueViolation := &pgconn.PgError{Code: "23505"}
+	checkViolation := &pgconn.PgError{Code: "23514"}
+
+	tests := []struct {
+		name    string
+		err     error
+		wantErr error
+	}{
+		{name: "deadlocks are unavailable", err: deadlock, wantErr: repositories.ErrUnavailable},
+		{name: "lost connections are unavailable", err: driver.ErrBadConn, wantErr: repositories.ErrUnavailable},
+		{name: "unique violations conflict", err: uniqueViolation, wantErr: repositories.ErrConflict},
+		{name: "statement timeouts are unavailable", err: &pgconn.PgError{Code: "57014"}, wantErr: repositories.ErrUnavailable},
+		{name: "mysql deadlocks are unavailable", err: &mysql.MySQLError{Number: 1213}, wantErr: repositories.ErrUnavailable},
+		{name: "mysql duplicate entries conflict", err: &mysql.MySQLError{Number: 1062}, wantErr: repositories.ErrConflict},
+		{name: "other errors are kept", err: checkViolation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classify(tt.err)
+
+			// The cause stays inspectable, so classified errors are still retried
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, IsTransient(tt.err), IsTransient(err))
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.Equal(t, tt.err, err)
+			}
+			assert.False(t, errors.Is(err, repositories.ErrNotFound))
+		})
+	}
+
+	t.Run("wrapped driver errors are classified", func(t *testing.T) {
+		err := classify(fmt.Errorf("read: %w", deadlock))
+		assert.ErrorIs(t, err, repositories.ErrUnavailable)
+	})
+}
@@ -0,0 +1,152 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+)
+
+// FeeRuleRepository implements the fee rule repository interface
+type FeeRuleRepository struct {
+	db *sql.DB
+}
+
+// NewFeeRuleRepository creates a new fee rule repository
+func NewFeeRuleRepository(db *sql.DB) *FeeRuleRepository {
+	return &FeeRuleRepository{db: db}
+}
+
+// feeRuleColumns are the columns scanned by scanFeeRule
+const feeRuleColumns = "source_type, state, kind, rate, amount, tiers, updated_at"
+
+func scanFeeRule(row rowScanner) (*entities.FeeRule, error) {
+	var rule entities.FeeRule
+	var tiers []byte
+	err := row.Scan(
+		&rule.SourceType,
+		&rule.State,
+		&rule.Kind,
+		&rule.Rate,
+		&rule.Amount,
+		&tiers,
+		&rule.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(tiers, &rule.Tiers); err != nil {
+		return nil, fmt.Errorf("invalid tiers: %w", err)
+	}
+	if len(rule.Tiers) == 0 {
+		rule.Tiers = nil
+	}
+	return &rule, nil
+}
+
+// Get retrieves the fee rule of a source type and state. It runs in the
+// ambient unit of work, when there is one.
+func (r *FeeRuleRepository) Get(
+	ctx context.Context,
+	sourceType entities.SourceType,
+	state entities.TransactionState,
+) (*entities.FeeRule, error) {
+	query := `SELECT ` + feeRuleColumns + ` FROM fee_rules WHERE source_type = $1 AND state = $2`
+
+	rule, err := scanFeeRule(Executor(ctx, r.db).QueryRowContext(ctx, query, sourceType, state))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("fee rule %s/%s: %w", sourceType, state, repositories.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get fee rule: %w", classify(err))
+	}
+
+	return rule, nil
+}
+
+// List returns the fee rules ordered by source type and state
+func (r *FeeRuleRepository) List(ctx context.Context) ([]*entities.FeeRule, error) {
+	query := `SELECT ` + feeRuleColumns + ` FROM fee_rules ORDER BY source_type, state`
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list fee rules: %w", classify(err))
+	}
+	defer rows.Close()
+
+	rules := []*entities.FeeRule{}
+	for rows.Next() {
+		rule, err := scanFeeRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan fee rule: %w", classify(err))
+		}
+		rules = append(rules, rule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list fee rules: %w", classify(err))
+	}
+
+	return rules, nil
+}
+
+// Set creates the fee rule of its source type and state, or replaces it
+func (r *FeeRuleRepository) Set(ctx context.Context, rule *entities.FeeRule) error {
+	query := `
+		INSERT INTO fee_rules (source_type, state, kind, rate, amount, tiers, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (source_type, state) DO UPDATE
+		SET kind = EXCLUDED.kind, rate = EXCLUDED.rate, amount = EXCLUDED.amount,
+			tiers = EXCLUDED.tiers, updated_at = EXCLUDED.updated_at
+	`
+
+	tiers := rule.Tiers
+	if tiers == nil {
+		tiers = []entities.FeeTier{}
+	}
+	// Tiers of decimals always encode
+	encoded, _ := json.Marshal(tiers)
+
+	_, err := Executor(ctx, r.db).ExecContext(
+		ctx,
+		query,
+		rule.SourceType,
+		rule.State,
+		rule.Kind,
+		rule.Rate,
+		rule.Amount,
+		encoded,
+		rule.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set fee rule: %w", classify(err))
+	}
+
+	return nil
+}
+
+// Delete removes the fee rule of a source type and state
+func (r *FeeRuleRepository) Delete(
+	ctx context.Context,
+	sourceType entities.SourceType,
+	state entities.TransactionState,
+) error {
+	query := `DELETE FROM fee_rules WHERE source_type = $1 AND state = $2`
+
+	result, err := Executor(ctx, r.db).ExecContext(ctx, query, sourceType, state)
+	if err != nil {
+		return fmt.Errorf("failed to delete fee rule: %w", classify(err))
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", classify(err))
+	}
+	if deleted == 0 {
+		return fmt.Errorf("fee rule %s/%s: %w", sourceType, state, repositories.ErrNotFound)
+	}
+
+	return nil
+}
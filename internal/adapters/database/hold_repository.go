@@ -0,0 +1,181 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/shopspring/decimal"
+)
+
+// HoldRepository implements the hold repository interface
+type HoldRepository struct {
+	db *sql.DB
+}
+
+// NewHoldRepository creates a new hold repository
+func NewHoldRepository(db *sql.DB) *HoldRepository {
+	return &HoldRepository{db: db}
+}
+
+// Create stores a new hold. A zero ID is allocated from the sequence.
+func (r *HoldRepository) Create(ctx context.Context, hold *entities.Hold) error {
+	query := `
+		INSERT INTO holds (id, hold_id, user_id, amount, status, created_at, expires_at)
+		VALUES (COALESCE($1::BIGINT, nextval(pg_get_serial_sequence('holds', 'id'))), $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`
+
+	err := Executor(ctx, r.db).QueryRowContext(
+		ctx,
+		query,
+		explicitID(hold.ID),
+		hold.HoldID,
+		hold.UserID,
+		hold.Amount,
+		hold.Status,
+		hold.CreatedAt,
+		hold.ExpiresAt,
+	).Scan(&hold.ID)
+
+	if err != nil {
+		return fmt.Errorf("failed to create hold: %w", classify(err))
+	}
+
+	return nil
+}
+
+// GetByHoldIDForUpdate retrieves a hold by its external ID and locks it until
+// the ambient transaction ends
+func (r *HoldRepository) GetByHoldIDForUpdate(ctx context.Context, holdID string) (*entities.Hold, error) {
+	query := `
+		SELECT id, hold_id, user_id, amount, status, captured_amount, created_at, expires_at, settled_at
+		FROM holds
+		WHERE hold_id = $1
+		FOR UPDATE
+	`
+
+	var hold entities.Hold
+	var capturedAmount decimal.NullDecimal
+	var settledAt sql.NullTime
+
+	err := Executor(ctx, r.db).QueryRowContext(ctx, query, holdID).Scan(
+		&hold.ID,
+		&hold.HoldID,
+		&hold.UserID,
+		&hold.Amount,
+		&hold.Status,
+		&capturedAmount,
+		&hold.CreatedAt,
+		&hold.ExpiresAt,
+		&settledAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("hold %s: %w", holdID, repositories.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get hold: %w", classify(err))
+	}
+
+	if capturedAmount.Valid {
+		hold.CapturedAmount = &capturedAmount.Decimal
+	}
+	if settledAt.Valid {
+		hold.SettledAt = &settledAt.Time
+	}
+
+	return &hold, nil
+}
+
+// SumActive returns the total of the user's holds that are held and have not
+// expired at now
+func (r *HoldRepository) SumActive(ctx context.Context, userID uint64, now time.Time) (decimal.Decimal, error) {
+	query := `
+		SELECT COALESCE(SUM(amount), 0)
+		FROM holds
+		WHERE user_id = $1 AND status = 'held' AND expires_at > $2
+	`
+
+	var sum decimal.Decimal
+	if err := Executor(ctx, r.db).QueryRowContext(ctx, query, userID, now).Scan(&sum); err != nil {
+		return decimal.Zero, fmt.Errorf("failed to sum active holds: %w", classify(err))
+	}
+
+	return sum, nil
+}
+
+// Settle records the final status of a held hold
+func (r *HoldRepository) Settle(
+	ctx context.Context,
+	id uint64,
+	status entities.HoldStatus,
+	capturedAmount *decimal.Decimal,
+	settledAt time.Time,
+) error {
+	query := `
+		UPDATE holds SET status = $1, captured_amount = $2, settled_at = $3
+		WHERE id = $4 AND status = 'held'
+	`
+
+	var captured decimal.NullDecimal
+	if capturedAmount != nil {
+		captured = decimal.NullDecimal{Decimal: *capturedAmount, Valid: true}
+	}
+
+	result, err := Executor(ctx, r.db).ExecContext(ctx, query, status, captured, settledAt, id)
+	if err != nil {
+		return fmt.Errorf("failed to settle hold: %w", classify(err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", classify(err))
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("held hold %d: %w", id, repositories.ErrNotFound)
+	}
+
+	return nil
+}
+
+// Expire marks up to limit holds that are held but expired at now as expired
+// and returns their external IDs. Holds locked by a capture or release in
+// progress are skipped.
+func (r *HoldRepository) Expire(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	query := `
+		UPDATE holds SET status = 'expired', settled_at = $1
+		WHERE id IN (
+			SELECT id FROM holds
+			WHERE status = 'held' AND expires_at <= $1
+			ORDER BY expires_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING hold_id
+	`
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire holds: %w", classify(err))
+	}
+	defer rows.Close()
+
+	var holdIDs []string
+	for rows.Next() {
+		var holdID string
+		if err := rows.Scan(&holdID); err != nil {
+			return nil, fmt.Errorf("failed to scan hold: %w", classify(err))
+		}
+		holdIDs = append(holdIDs, holdID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to expire holds: %w", classify(err))
+	}
+
+	return holdIDs, nil
+}
@@ -1,109 +1,411 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"embed"
+	"errors"
 	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
 
-	"github.com/shopspring/decimal"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
-// RunMigrations runs all database migrations
-func RunMigrations(db *sql.DB) error {
-	// Create users table
-	if err := createUsersTable(db); err != nil {
-		return fmt.Errorf("failed to create users table: %w", err)
+// Migrations are versioned SQL files named <version>_<name>.up.sql and
+// <version>_<name>.down.sql, following the golang-migrate layout. Versions
+// start at 1 and have no gaps. Add a migration as a new pair of files with the
+// next version; never edit a migration once it has been released.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// mysqlMigrationFiles are the migrations of the MySQL schema, which holds the
+// users, wallets, transactions and annotations tables only. They follow the
+// same layout and rules, numbered on their own.
+//
+//go:embed mysql/migrations/*.sql
+var mysqlMigrationFiles embed.FS
+
+// sqliteMigrationFiles are the migrations of the SQLite schema, holding the
+// same tables as the MySQL one
+//
+//go:embed sqlite/migrations/*.sql
+var sqliteMigrationFiles embed.FS
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// migrationLockID is the key of the advisory lock that serializes migrations
+// across instances starting at the same time
+const migrationLockID = 8_431_776_104
+
+var (
+	// ErrUnknownSchemaVersion is returned when the schema was migrated by a
+	// newer release, whose migrations this release does not know
+	ErrUnknownSchemaVersion    = errors.New("schema version is newer than the latest known migration")
+	ErrInvalidMigrationVersion = errors.New("invalid migration version")
+)
+
+// migration is a pair of up and down migration files
+type migration struct {
+	version int
+	name    string
+	up      string
+	down    string
+}
+
+// loadMigrations reads the embedded migrations, ordered by version
+func loadMigrations(files fs.FS) ([]migration, error) {
+	entries, err := fs.ReadDir(files, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
 	}
 
-	// Create transactions table
-	if err := createTransactionsTable(db); err != nil {
-		return fmt.Errorf("failed to create transactions table: %w", err)
+	byVersion := make(map[int]*migration)
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file %s", entry.Name())
+		}
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, fmt.Errorf("unexpected migration file %s: %w", entry.Name(), err)
+		}
+		content, err := fs.ReadFile(files, "migrations/"+entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{version: version, name: match[2]}
+			byVersion[version] = m
+		}
+		if m.name != match[2] {
+			return nil, fmt.Errorf("migration %d is named both %s and %s", version, m.name, match[2])
+		}
+		if match[3] == "up" {
+			m.up = string(content)
+		} else {
+			m.down = string(content)
+		}
 	}
 
-	// Insert predefined users
-	if err := insertPredefinedUsers(db); err != nil {
-		return fmt.Errorf("failed to insert predefined users: %w", err)
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		migrations = append(migrations, *m)
 	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
 
-	return nil
+	for i, m := range migrations {
+		if m.version != i+1 {
+			return nil, fmt.Errorf("migration %d is missing", i+1)
+		}
+		if m.up == "" || m.down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", m.version, m.name)
+		}
+	}
+	return migrations, nil
 }
 
-func createUsersTable(db *sql.DB) error {
-	query := `
-		CREATE TABLE IF NOT EXISTS users (
-			id BIGSERIAL PRIMARY KEY,
-			balance DECIMAL(15,2) NOT NULL DEFAULT 0.00,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+// migrationDialect holds the statements of a Migrator that differ between
+// PostgreSQL and MySQL
+type migrationDialect struct {
+	files              fs.FS
+	lock               string
+	unlock             string
+	createVersionTable string
+	writeVersion       string
+	// noTimeout lifts the statement timeout of the session, which
+	// resetTimeout restores
+	noTimeout    string
+	resetTimeout string
+}
+
+var postgresMigrations = migrationDialect{
+	files:  migrationFiles,
+	lock:   fmt.Sprintf("SELECT pg_advisory_lock(%d)", migrationLockID),
+	unlock: fmt.Sprintf("SELECT pg_advisory_unlock(%d)", migrationLockID),
+	createVersionTable: `
+		CREATE TABLE IF NOT EXISTS schema_version (
+			id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+			version INTEGER NOT NULL,
+			migrated_at TIMESTAMP NOT NULL
+		);
+	`,
+	writeVersion: `
+		INSERT INTO schema_version (id, version, migrated_at) VALUES (TRUE, $1, NOW())
+		ON CONFLICT (id) DO UPDATE SET version = EXCLUDED.version, migrated_at = EXCLUDED.migrated_at
+	`,
+	noTimeout:    "SET statement_timeout = 0",
+	resetTimeout: "RESET statement_timeout",
+}
+
+// MySQL named locks are held by the session, like PostgreSQL advisory locks.
+// MySQL commits DDL statements implicitly, so unlike on PostgreSQL a failed
+// migration may leave its earlier statements applied.
+var mysqlMigrations = migrationDialect{
+	files:  mustSub(mysqlMigrationFiles, "mysql"),
+	lock:   "SELECT GET_LOCK('transaction_service_migrations', -1)",
+	unlock: "SELECT RELEASE_LOCK('transaction_service_migrations')",
+	createVersionTable: `
+		CREATE TABLE IF NOT EXISTS schema_version (
+			id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id = TRUE),
+			version INTEGER NOT NULL,
+			migrated_at DATETIME(6) NOT NULL
 		);
-		
-		CREATE INDEX IF NOT EXISTS idx_users_id ON users(id);
-	`
-	_, err := db.Exec(query)
-	return err
-}
-
-func createTransactionsTable(db *sql.DB) error {
-	query := `
-		CREATE TABLE IF NOT EXISTS transactions (
-			id BIGSERIAL PRIMARY KEY,
-			user_id BIGINT NOT NULL REFERENCES users(id),
-			transaction_id VARCHAR(255) NOT NULL UNIQUE,
-			state VARCHAR(10) NOT NULL CHECK (state IN ('win', 'lose')),
-			amount DECIMAL(15,2) NOT NULL,
-			source_type VARCHAR(20) NOT NULL CHECK (source_type IN ('game', 'server', 'payment')),
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	`,
+	writeVersion: `
+		INSERT INTO schema_version (id, version, migrated_at) VALUES (TRUE, ?, UTC_TIMESTAMP(6))
+		ON DUPLICATE KEY UPDATE version = VALUES(version), migrated_at = VALUES(migrated_at)
+	`,
+}
+
+// SQLite transactions take the database write lock when they begin, which
+// serializes migrations without a lock of their own
+var sqliteMigrations = migrationDialect{
+	files: mustSub(sqliteMigrationFiles, "sqlite"),
+	createVersionTable: `
+		CREATE TABLE IF NOT EXISTS schema_version (
+			id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id = TRUE),
+			version INTEGER NOT NULL,
+			migrated_at TIMESTAMP NOT NULL
 		);
-		
-		CREATE INDEX IF NOT EXISTS idx_transactions_user_id ON transactions(user_id);
-		CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_transaction_id ON transactions(transaction_id);
-		CREATE INDEX IF NOT EXISTS idx_transactions_created_at ON transactions(created_at);
-	`
-	_, err := db.Exec(query)
-	return err
-}
-
-func insertPredefinedUsers(db *sql.DB) error {
-	// Check if users already exist
-	var count int
-	err := db.QueryRow("SELECT COUNT(*) FROM users WHERE id IN (1, 2, 3)").Scan(&count)
+	`,
+	writeVersion: `
+		INSERT INTO schema_version (id, version, migrated_at) VALUES (TRUE, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT (id) DO UPDATE SET version = excluded.version, migrated_at = excluded.migrated_at
+	`,
+}
+
+// mustSub returns the subtree of embedded files rooted at dir
+func mustSub(files embed.FS, dir string) fs.FS {
+	sub, err := fs.Sub(files, dir)
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
+
+// Migrator applies the embedded migrations and records the version the schema
+// was migrated to in the schema_version table
+type Migrator struct {
+	db         *sql.DB
+	dialect    migrationDialect
+	migrations []migration
+}
+
+// NewMigrator creates a new Migrator for the schema db resolves tables to,
+// applying the migrations of the driver db connects with
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	dialect := postgresMigrations
+	switch driverOf(db) {
+	case DriverMySQL:
+		dialect = mysqlMigrations
+	case DriverSQLite:
+		dialect = sqliteMigrations
+	}
+
+	migrations, err := loadMigrations(dialect.files)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		db:         db,
+		dialect:    dialect,
+		migrations: migrations,
+	}, nil
+}
+
+// RunMigrations migrates the schema up to the latest migration
+func RunMigrations(db *sql.DB) error {
+	migrator, err := NewMigrator(db)
 	if err != nil {
 		return err
 	}
+	return migrator.Up(context.Background())
+}
 
-	// If users already exist, skip insertion
-	if count >= 3 {
-		return nil
+// Latest returns the version of the latest migration, the schema version this
+// release expects
+func (m *Migrator) Latest() int {
+	return len(m.migrations)
+}
+
+// Version returns the version the schema was migrated to, 0 when it was never
+// migrated
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	return readSchemaVersion(ctx, m.db)
+}
+
+// Check fails unless the schema has been migrated at least to the latest
+// migration, e.g. while the active region still runs an older release
+func (m *Migrator) Check(ctx context.Context) error {
+	version, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+	if version < m.Latest() {
+		return fmt.Errorf("schema version %d is behind %d", version, m.Latest())
+	}
+	return nil
+}
+
+// Up applies every migration not applied yet. The schema is left alone when
+// a newer release has already migrated it further.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.migrate(ctx, func(current int) int {
+		return max(current, m.Latest())
+	})
+}
+
+// Down reverts the last steps migrations applied
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	if steps < 1 {
+		return fmt.Errorf("%w: at least one migration must be reverted", ErrInvalidMigrationVersion)
 	}
+	return m.migrate(ctx, func(current int) int {
+		return max(current-steps, 0)
+	})
+}
 
-	// Insert predefined users with initial balance
-	initialBalance := decimal.NewFromFloat(100.00) // Starting with 100.00 balance
+// Goto migrates the schema up or down to version
+func (m *Migrator) Goto(ctx context.Context, version int) error {
+	if version < 0 || version > m.Latest() {
+		return fmt.Errorf("%w: %d is not between 0 and %d", ErrInvalidMigrationVersion, version, m.Latest())
+	}
+	return m.migrate(ctx, func(int) int {
+		return version
+	})
+}
 
-	users := []struct {
-		id      uint64
-		balance decimal.Decimal
-	}{
-		{1, initialBalance},
-		{2, initialBalance},
-		{3, initialBalance},
+// Force records version as the schema version without running any migration,
+// e.g. after a failed migration was completed or reverted by hand
+func (m *Migrator) Force(ctx context.Context, version int) error {
+	if version < 0 || version > m.Latest() {
+		return fmt.Errorf("%w: %d is not between 0 and %d", ErrInvalidMigrationVersion, version, m.Latest())
 	}
+	return m.locked(ctx, func(conn *sql.Conn) error {
+		return m.writeSchemaVersion(ctx, conn, version)
+	})
+}
 
-	for _, user := range users {
-		query := `
-			INSERT INTO users (id, balance) 
-			VALUES ($1, $2)
-			ON CONFLICT (id) DO NOTHING
-		`
-		_, err := db.Exec(query, user.id, user.balance)
+// migrate moves the schema from its current version to the version target
+// returns for it, one migration at a time. Each migration runs in its own
+// transaction together with recording its version, so a failed migration
+// leaves the schema at the previous version.
+func (m *Migrator) migrate(ctx context.Context, target func(current int) int) error {
+	return m.locked(ctx, func(conn *sql.Conn) error {
+		current, err := readSchemaVersion(ctx, conn)
 		if err != nil {
-			return fmt.Errorf("failed to insert user %d: %w", user.id, err)
+			return err
+		}
+		to := target(current)
+		if current == to {
+			return nil
 		}
+		if current > m.Latest() {
+			return fmt.Errorf("%w: %d is after %d", ErrUnknownSchemaVersion, current, m.Latest())
+		}
+
+		for current < to {
+			next := m.migrations[current]
+			if err := m.applyMigration(ctx, conn, next.up, next.version); err != nil {
+				return fmt.Errorf("failed to apply migration %d_%s: %w", next.version, next.name, err)
+			}
+			current++
+		}
+		for current > to {
+			last := m.migrations[current-1]
+			if err := m.applyMigration(ctx, conn, last.down, last.version-1); err != nil {
+				return fmt.Errorf("failed to revert migration %d_%s: %w", last.version, last.name, err)
+			}
+			current--
+		}
+		return nil
+	})
+}
+
+// locked runs fn on a connection holding the migration lock, after creating
+// the schema_version table if needed. Migrations and the wait for the lock
+// may take longer than the statement timeout, so it is lifted until fn
+// returns.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	ctx = withoutQueryTimeout(ctx)
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get a connection: %w", err)
+	}
+	defer conn.Close()
+
+	if m.dialect.noTimeout != "" {
+		if _, err := conn.ExecContext(ctx, m.dialect.noTimeout); err != nil {
+			return fmt.Errorf("failed to lift the statement timeout: %w", err)
+		}
+		defer conn.ExecContext(context.Background(), m.dialect.resetTimeout)
+	}
+
+	if m.dialect.lock != "" {
+		if _, err := conn.ExecContext(ctx, m.dialect.lock); err != nil {
+			return fmt.Errorf("failed to take the migration lock: %w", err)
+		}
+		defer conn.ExecContext(context.Background(), m.dialect.unlock)
+	}
+
+	if _, err := conn.ExecContext(ctx, m.dialect.createVersionTable); err != nil {
+		return fmt.Errorf("failed to create schema_version table: %w", err)
 	}
 
-	// Reset the sequence to start from 4 for future auto-generated IDs
-	_, err = db.Exec("SELECT setval('users_id_seq', 3, true)")
+	return fn(conn)
+}
+
+// applyMigration runs the statements of a migration file and records version
+// in one transaction
+func (m *Migrator) applyMigration(ctx context.Context, conn *sql.Conn, statements string, version int) error {
+	tx, err := conn.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to reset user sequence: %w", err)
+		return err
 	}
+	defer tx.Rollback()
 
+	if _, err := tx.ExecContext(ctx, statements); err != nil {
+		return err
+	}
+	if err := m.writeSchemaVersion(ctx, tx, version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// readSchemaVersion returns the recorded schema version, 0 when there is none
+func readSchemaVersion(ctx context.Context, q Querier) (int, error) {
+	var version int
+	err := q.QueryRowContext(ctx, "SELECT version FROM schema_version").Scan(&version)
+	var pgErr *pgconn.PgError
+	var mysqlErr *mysql.MySQLError
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return 0, nil
+	case errors.As(err, &pgErr) && pgErr.Code == "42P01": // undefined_table
+		return 0, nil
+	case errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlNoSuchTable:
+		return 0, nil
+	case isSQLiteNoSuchTable(err):
+		return 0, nil
+	case err != nil:
+		return 0, fmt.Errorf("failed to get schema version: %w", err)
+	}
+	return version, nil
+}
+
+func (m *Migrator) writeSchemaVersion(ctx context.Context, q Querier, version int) error {
+	if _, err := q.ExecContext(ctx, m.dialect.writeVersion, version); err != nil {
+		return fmt.Errorf("failed to record schema version %d: %w", version, err)
+	}
 	return nil
 }
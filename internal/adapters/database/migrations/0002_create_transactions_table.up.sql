@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS transactions (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    transaction_id VARCHAR(255) NOT NULL UNIQUE,
+    state VARCHAR(10) NOT NULL CHECK (state IN ('win', 'lose')),
+    amount DECIMAL(15,2) NOT NULL,
+    source_type VARCHAR(20) NOT NULL CHECK (source_type IN ('game', 'server', 'payment')),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_user_id ON transactions(user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_transaction_id ON transactions(transaction_id);
+CREATE INDEX IF NOT EXISTS idx_transactions_created_at ON transactions(created_at);
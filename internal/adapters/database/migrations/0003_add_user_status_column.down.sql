@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_transactions_user_id_created_at;
+
+ALTER TABLE users DROP COLUMN IF EXISTS status;
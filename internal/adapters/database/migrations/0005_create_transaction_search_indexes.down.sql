@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_transactions_amount_created_at;
+DROP INDEX IF EXISTS idx_transactions_source_type_state_created_at;
+DROP INDEX IF EXISTS idx_transactions_transaction_id_pattern;
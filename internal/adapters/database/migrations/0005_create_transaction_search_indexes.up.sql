@@ -0,0 +1,6 @@
+CREATE INDEX IF NOT EXISTS idx_transactions_transaction_id_pattern
+    ON transactions(transaction_id text_pattern_ops);
+CREATE INDEX IF NOT EXISTS idx_transactions_source_type_state_created_at
+    ON transactions(source_type, state, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_transactions_amount_created_at
+    ON transactions(amount, created_at DESC);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_transactions_odd_uncancelled;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS cancelled_at;
+ALTER TABLE transactions DROP COLUMN IF EXISTS cancelled;
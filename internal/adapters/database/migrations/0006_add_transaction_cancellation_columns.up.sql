@@ -0,0 +1,5 @@
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS cancelled BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS cancelled_at TIMESTAMP NULL;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_odd_uncancelled
+    ON transactions(id DESC) WHERE cancelled = FALSE AND id % 2 = 1;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_transactions_receipt;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS receipt;
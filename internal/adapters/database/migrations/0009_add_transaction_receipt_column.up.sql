@@ -0,0 +1,3 @@
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS receipt VARCHAR(64) NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_receipt ON transactions(receipt);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS wallets (
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    currency VARCHAR(3) NOT NULL,
+    balance DECIMAL(15,2) NOT NULL DEFAULT 0.00 CHECK (balance >= 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, currency)
+);
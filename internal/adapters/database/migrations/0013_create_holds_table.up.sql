@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS holds (
+    id BIGSERIAL PRIMARY KEY,
+    hold_id VARCHAR(255) NOT NULL UNIQUE,
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    status VARCHAR(10) NOT NULL DEFAULT 'held'
+        CHECK (status IN ('held', 'captured', 'released', 'expired')),
+    captured_amount DECIMAL(15,2) NULL,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    settled_at TIMESTAMP NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_holds_held_user_id ON holds(user_id) WHERE status = 'held';
+CREATE INDEX IF NOT EXISTS idx_holds_held_expires_at ON holds(expires_at) WHERE status = 'held';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_transactions_user_round;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS round_id;
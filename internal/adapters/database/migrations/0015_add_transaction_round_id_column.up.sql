@@ -0,0 +1,3 @@
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS round_id VARCHAR(255) NULL;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_user_round ON transactions(user_id, round_id) WHERE round_id IS NOT NULL;
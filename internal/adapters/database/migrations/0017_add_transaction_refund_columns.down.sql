@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_transactions_reverses;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS reversed_by;
+ALTER TABLE transactions DROP COLUMN IF EXISTS reverses;
+ALTER TABLE transactions DROP COLUMN IF EXISTS type;
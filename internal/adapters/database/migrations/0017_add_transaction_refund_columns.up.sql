@@ -0,0 +1,5 @@
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS type VARCHAR(20) NOT NULL DEFAULT 'transaction';
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS reverses VARCHAR(255) NULL;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS reversed_by VARCHAR(255) NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_reverses ON transactions(reverses) WHERE reverses IS NOT NULL;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS restore_markers (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    schema_version INTEGER NOT NULL,
+    wal_position VARCHAR(32) NOT NULL,
+    fingerprint JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS rejections (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    transaction_id VARCHAR(255) NOT NULL,
+    source_type VARCHAR(20) NOT NULL,
+    state VARCHAR(20) NOT NULL,
+    amount VARCHAR(40) NOT NULL,
+    currency VARCHAR(16) NULL,
+    reason VARCHAR(40) NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_rejections_created_at ON rejections(created_at);
+CREATE INDEX IF NOT EXISTS idx_rejections_source_type_created_at ON rejections(source_type, created_at);
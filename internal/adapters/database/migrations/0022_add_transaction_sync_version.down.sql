@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_transactions_sync_version;
+
+DROP TRIGGER IF EXISTS transactions_sync_version ON transactions;
+DROP FUNCTION IF EXISTS set_transaction_sync_version();
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS sync_version;
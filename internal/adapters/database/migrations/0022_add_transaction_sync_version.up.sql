@@ -0,0 +1,18 @@
+-- sync_version is the ID of the database transaction that last inserted or
+-- updated the row, so that changes can be read in the order the database
+-- transactions began. Rows written before it existed come first.
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS sync_version BIGINT NOT NULL DEFAULT 0;
+
+CREATE OR REPLACE FUNCTION set_transaction_sync_version() RETURNS trigger AS $$
+BEGIN
+    NEW.sync_version := pg_current_xact_id()::TEXT::BIGINT;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS transactions_sync_version ON transactions;
+CREATE TRIGGER transactions_sync_version
+    BEFORE INSERT OR UPDATE ON transactions
+    FOR EACH ROW EXECUTE FUNCTION set_transaction_sync_version();
+
+CREATE INDEX IF NOT EXISTS idx_transactions_sync_version ON transactions(sync_version, id);
@@ -0,0 +1,5 @@
+CREATE INDEX IF NOT EXISTS idx_transactions_created_at ON transactions(created_at);
+CREATE INDEX IF NOT EXISTS idx_transactions_user_id_created_at ON transactions(user_id, created_at);
+
+DROP INDEX IF EXISTS idx_transactions_user_id_created_at_id;
+DROP INDEX IF EXISTS idx_transactions_created_at_id;
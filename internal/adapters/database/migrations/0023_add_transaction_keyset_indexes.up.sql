@@ -0,0 +1,6 @@
+CREATE INDEX IF NOT EXISTS idx_transactions_created_at_id ON transactions(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_transactions_user_id_created_at_id ON transactions(user_id, created_at, id);
+
+-- Superseded by the indexes above
+DROP INDEX IF EXISTS idx_transactions_created_at;
+DROP INDEX IF EXISTS idx_transactions_user_id_created_at;
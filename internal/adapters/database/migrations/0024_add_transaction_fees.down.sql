@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS fee_rules;
+
+DROP INDEX IF EXISTS idx_transactions_charged_for;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS charged_for;
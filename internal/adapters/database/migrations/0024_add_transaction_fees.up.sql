@@ -0,0 +1,14 @@
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS charged_for VARCHAR(255) NULL;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_charged_for ON transactions(charged_for) WHERE charged_for IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS fee_rules (
+    source_type VARCHAR(20) NOT NULL,
+    state VARCHAR(10) NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    rate DECIMAL(7,4) NOT NULL DEFAULT 0,
+    amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+    tiers JSONB NOT NULL DEFAULT '[]',
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (source_type, state)
+);
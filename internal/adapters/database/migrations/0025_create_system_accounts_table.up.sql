@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS system_accounts (
+    kind VARCHAR(20) PRIMARY KEY,
+    user_id BIGINT NOT NULL UNIQUE REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS balance_adjustments (
+    id BIGSERIAL PRIMARY KEY,
+    adjustment_id VARCHAR(255) NOT NULL UNIQUE,
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    transaction_id VARCHAR(255) NOT NULL,
+    direction VARCHAR(10) NOT NULL CHECK (direction IN ('credit', 'debit')),
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    currency VARCHAR(3) NOT NULL DEFAULT '',
+    reason TEXT NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    balance_before DECIMAL(15,2) NOT NULL,
+    balance_after DECIMAL(15,2) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_balance_adjustments_user_created_at ON balance_adjustments(user_id, created_at);
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS credit_limit DECIMAL(15,2) NOT NULL DEFAULT 0.00;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_transactions_pending_created_at;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS settled_at;
+ALTER TABLE transactions DROP COLUMN IF EXISTS pending;
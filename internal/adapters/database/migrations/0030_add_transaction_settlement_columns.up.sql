@@ -0,0 +1,4 @@
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS pending BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS settled_at TIMESTAMP NULL;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_pending_created_at ON transactions(created_at) WHERE pending;
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS scheduled_transactions (
+    id BIGSERIAL PRIMARY KEY,
+    schedule_id VARCHAR(255) NOT NULL UNIQUE,
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    state VARCHAR(10) NOT NULL CHECK (state IN ('win', 'lose')),
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    source_type VARCHAR(20) NOT NULL CHECK (source_type IN ('game', 'server', 'payment')),
+    currency VARCHAR(3) NULL,
+    metadata JSONB NULL,
+    cron VARCHAR(255) NULL,
+    max_runs INTEGER NOT NULL DEFAULT 0,
+    runs INTEGER NOT NULL DEFAULT 0,
+    status VARCHAR(10) NOT NULL DEFAULT 'active'
+        CHECK (status IN ('active', 'completed', 'cancelled')),
+    next_run_at TIMESTAMP NULL,
+    last_run_at TIMESTAMP NULL,
+    last_transaction_id VARCHAR(255) NULL,
+    last_error VARCHAR(64) NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_transactions_user_id ON scheduled_transactions(user_id);
+CREATE INDEX IF NOT EXISTS idx_scheduled_transactions_active_next_run_at
+    ON scheduled_transactions(next_run_at) WHERE status = 'active';
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS balance_snapshots (
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    day DATE NOT NULL,
+    balance DECIMAL(15,2) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, day)
+);
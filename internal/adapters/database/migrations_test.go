@@ -0,0 +1,115 @@
+package database
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMigrations_Embedded(t *testing.T) {
+	migrations, err := loadMigrations(migrationFiles)
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	assert.Equal(t, "create_users_table", migrations[0].name)
+	for i, m := range migrations {
+		assert.Equal(t, i+1, m.version)
+		assert.NotEmpty(t, m.up, m.name)
+		assert.NotEmpty(t, m.down, m.name)
+	}
+}
+
+func TestLoadMigrations_MySQL(t *testing.T) {
+	migrations, err := loadMigrations(mysqlMigrations.files)
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(migrations))
+	for _, m := range migrations {
+		names = append(names, m.name)
+	}
+	assert.Equal(t, []string{
+		"create_users_table",
+		"create_transactions_table",
+		"create_annotations_table",
+		"create_wallets_table",
+		"add_transaction_charged_for_column",
+		"add_transaction_metadata_column",
+		"add_user_credit_limit_column",
+		"add_transaction_settlement_columns",
+	}, names)
+}
+
+func TestLoadMigrations_SQLite(t *testing.T) {
+	migrations, err := loadMigrations(sqliteMigrations.files)
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(migrations))
+	for _, m := range migrations {
+		names = append(names, m.name)
+	}
+	assert.Equal(t, []string{
+		"create_users_table",
+		"create_transactions_table",
+		"create_annotations_table",
+		"create_wallets_table",
+		"add_transaction_charged_for_column",
+		"add_transaction_metadata_column",
+		"add_user_credit_limit_column",
+		"add_transaction_settlement_columns",
+	}, names)
+}
+
+func TestLoadMigrations_Invalid(t *testing.T) {
+	file := func(content string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(content)}
+	}
+
+	tests := []struct {
+		name    string
+		files   fstest.MapFS
+		wantErr string
+	}{
+		{
+			name: "gaps between versions",
+			files: fstest.MapFS{
+				"migrations/0001_a.up.sql":   file("SELECT 1;"),
+				"migrations/0001_a.down.sql": file("SELECT 1;"),
+				"migrations/0003_c.up.sql":   file("SELECT 1;"),
+				"migrations/0003_c.down.sql": file("SELECT 1;"),
+			},
+			wantErr: "migration 2 is missing",
+		},
+		{
+			name: "missing down files",
+			files: fstest.MapFS{
+				"migrations/0001_a.up.sql": file("SELECT 1;"),
+			},
+			wantErr: "needs both an up and a down file",
+		},
+		{
+			name: "versions with two names",
+			files: fstest.MapFS{
+				"migrations/0001_a.up.sql":   file("SELECT 1;"),
+				"migrations/0001_b.down.sql": file("SELECT 1;"),
+			},
+			wantErr: "named both",
+		},
+		{
+			name: "unexpected files",
+			files: fstest.MapFS{
+				"migrations/users.sql": file("SELECT 1;"),
+			},
+			wantErr: "unexpected migration file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadMigrations(tt.files)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS transactions (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    transaction_id VARCHAR(255) NOT NULL,
+    state VARCHAR(10) NOT NULL CHECK (state IN ('win', 'lose')),
+    amount DECIMAL(15,2) NOT NULL,
+    source_type VARCHAR(20) NOT NULL CHECK (source_type IN ('game', 'server', 'payment')),
+    occurred_at DATETIME(6) NULL,
+    created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    cancelled BOOLEAN NOT NULL DEFAULT FALSE,
+    cancelled_at DATETIME(6) NULL,
+    balance_after DECIMAL(15,2) NULL,
+    receipt VARCHAR(64) NULL,
+    currency VARCHAR(3) NULL,
+    round_id VARCHAR(255) NULL,
+    type VARCHAR(20) NOT NULL DEFAULT 'transaction',
+    reverses VARCHAR(255) NULL,
+    reversed_by VARCHAR(255) NULL,
+    transfer_id VARCHAR(255) NULL,
+    CONSTRAINT fk_transactions_user_id FOREIGN KEY (user_id) REFERENCES users(id),
+    UNIQUE INDEX idx_transactions_transaction_id (transaction_id),
+    UNIQUE INDEX idx_transactions_receipt (receipt),
+    UNIQUE INDEX idx_transactions_reverses (reverses),
+    INDEX idx_transactions_created_at (created_at),
+    INDEX idx_transactions_user_id_created_at (user_id, created_at),
+    INDEX idx_transactions_source_type_state_created_at (source_type, state, created_at),
+    INDEX idx_transactions_amount_created_at (amount, created_at),
+    INDEX idx_transactions_user_round (user_id, round_id),
+    INDEX idx_transactions_transfer_id (transfer_id)
+);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS annotations (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    target_type VARCHAR(20) NOT NULL CHECK (target_type IN ('user', 'transaction')),
+    target_id VARCHAR(255) NOT NULL,
+    author VARCHAR(255) NOT NULL,
+    note TEXT NOT NULL,
+    created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    INDEX idx_annotations_target (target_type, target_id, created_at)
+);
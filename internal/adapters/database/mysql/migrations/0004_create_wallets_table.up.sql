@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS wallets (
+    user_id BIGINT NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    balance DECIMAL(15,2) NOT NULL DEFAULT 0.00 CHECK (balance >= 0),
+    created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    PRIMARY KEY (user_id, currency),
+    CONSTRAINT fk_wallets_user_id FOREIGN KEY (user_id) REFERENCES users(id)
+);
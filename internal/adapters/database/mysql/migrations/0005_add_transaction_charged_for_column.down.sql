@@ -0,0 +1,3 @@
+ALTER TABLE transactions
+    DROP INDEX idx_transactions_charged_for,
+    DROP COLUMN charged_for;
@@ -0,0 +1,3 @@
+ALTER TABLE transactions
+    ADD COLUMN charged_for VARCHAR(255) NULL,
+    ADD INDEX idx_transactions_charged_for (charged_for);
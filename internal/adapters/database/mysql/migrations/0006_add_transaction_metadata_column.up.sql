@@ -0,0 +1 @@
+ALTER TABLE transactions ADD COLUMN metadata JSON NULL;
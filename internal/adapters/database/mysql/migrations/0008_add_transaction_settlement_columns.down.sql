@@ -0,0 +1,4 @@
+ALTER TABLE transactions
+    DROP INDEX idx_transactions_pending_created_at,
+    DROP COLUMN settled_at,
+    DROP COLUMN pending;
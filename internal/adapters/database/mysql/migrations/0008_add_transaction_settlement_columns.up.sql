@@ -0,0 +1,4 @@
+ALTER TABLE transactions
+    ADD COLUMN pending BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN settled_at DATETIME(6) NULL,
+    ADD INDEX idx_transactions_pending_created_at (pending, created_at);
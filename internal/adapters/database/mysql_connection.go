@@ -0,0 +1,71 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"net"
+	"time"
+
+	"transaction-service/internal/config"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// NewMySQLConnection creates a new MySQL database connection. Times are read
+// and written in UTC, updates report the rows they matched rather than changed
+// as PostgreSQL does, and multiple statements per query are allowed so that
+// migration files run as a whole.
+func NewMySQLConnection(cfg config.DatabaseConfig) (*sql.DB, error) {
+	mysqlCfg := mysql.NewConfig()
+	mysqlCfg.User = cfg.User
+	mysqlCfg.Passwd = cfg.Password
+	mysqlCfg.Net = "tcp"
+	mysqlCfg.Addr = net.JoinHostPort(cfg.Host, cfg.Port)
+	mysqlCfg.DBName = cfg.Name
+	mysqlCfg.ParseTime = true
+	mysqlCfg.Loc = time.UTC
+	mysqlCfg.ClientFoundRows = true
+	mysqlCfg.MultiStatements = true
+	mysqlCfg.TLSConfig = mysqlTLS(cfg.SSLMode)
+
+	db, err := sql.Open("mysql", mysqlCfg.FormatDSN())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database connection: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	// Set connection pool settings. MySQL closes connections idle for
+	// wait_timeout, 8 hours by default.
+	db.SetMaxOpenConns(25)
+	db.SetMaxIdleConns(5)
+	db.SetConnMaxLifetime(time.Hour)
+
+	return db, nil
+}
+
+// mysqlTLS maps a PostgreSQL sslmode onto the TLS setting of the MySQL driver
+func mysqlTLS(sslMode string) string {
+	switch sslMode {
+	case "disable":
+		return "false"
+	case "allow", "prefer":
+		return "preferred"
+	case "require":
+		return "skip-verify"
+	default:
+		return "true"
+	}
+}
+
+// MySQL server error numbers
+const (
+	mysqlTooManyConnections = 1040
+	mysqlServerShutdown     = 1053
+	mysqlDuplicateEntry     = 1062
+	mysqlNoSuchTable        = 1146
+	mysqlLockWaitTimeout    = 1205
+	mysqlDeadlock           = 1213
+)
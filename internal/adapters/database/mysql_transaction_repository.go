@@ -0,0 +1,541 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/shopspring/decimal"
+)
+
+// MySQLTransactionRepository implements the transaction repository interface
+// on MySQL
+type MySQLTransactionRepository struct {
+	db *sql.DB
+}
+
+// NewMySQLTransactionRepository creates a new MySQL transaction repository
+func NewMySQLTransactionRepository(db *sql.DB) *MySQLTransactionRepository {
+	return &MySQLTransactionRepository{db: db}
+}
+
+// mysqlTransactionColumns is the column list matching scanTransactions
+const mysqlTransactionColumns = "id, user_id, transaction_id, state, amount, source_type, occurred_at, created_at, cancelled, cancelled_at, balance_after, COALESCE(receipt, CAST(id AS CHAR)), COALESCE(currency, ''), COALESCE(round_id, ''), type, COALESCE(reverses, ''), COALESCE(reversed_by, ''), COALESCE(transfer_id, ''), COALESCE(charged_for, ''), metadata, pending, settled_at"
+
+// Create creates a new transaction. A zero ID is allocated by AUTO_INCREMENT,
+// and an empty receipt defaults to the decimal ID. An allocated ID is only
+// known once the row is inserted, so its default receipt is set by a second
+// statement, in the same unit of work as the insert.
+func (r *MySQLTransactionRepository) Create(ctx context.Context, transaction *entities.Transaction) error {
+	query := `
+		INSERT INTO transactions (id, user_id, transaction_id, state, amount, source_type, occurred_at, created_at, balance_after, receipt, currency, round_id, type, reverses, transfer_id, charged_for, metadata, pending)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''),
+			COALESCE(NULLIF(?, ''), 'transaction'), NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), ?, ?)
+	`
+
+	receipt := transaction.Receipt
+	if receipt == "" && transaction.ID != 0 {
+		receipt = strconv.FormatUint(transaction.ID, 10)
+	}
+
+	result, err := Executor(ctx, r.db).ExecContext(
+		ctx,
+		query,
+		explicitID(transaction.ID),
+		transaction.UserID,
+		transaction.TransactionID,
+		transaction.State,
+		transaction.Amount,
+		transaction.SourceType,
+		transaction.OccurredAt,
+		transaction.CreatedAt,
+		transaction.BalanceAfter,
+		receipt,
+		transaction.Currency,
+		transaction.RoundID,
+		transaction.Type,
+		transaction.Reverses,
+		transaction.TransferID,
+		transaction.ChargedFor,
+		encodeMetadata(transaction.Metadata),
+		transaction.Pending,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create transaction: %w", classify(err))
+	}
+
+	if transaction.ID == 0 {
+		id, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to get transaction ID: %w", classify(err))
+		}
+		transaction.ID = uint64(id)
+	}
+	if receipt == "" {
+		receipt = strconv.FormatUint(transaction.ID, 10)
+		query := "UPDATE transactions SET receipt = ? WHERE id = ?"
+		if _, err := Executor(ctx, r.db).ExecContext(ctx, query, receipt, transaction.ID); err != nil {
+			return fmt.Errorf("failed to set transaction receipt: %w", classify(err))
+		}
+	}
+	transaction.Receipt = receipt
+
+	return nil
+}
+
+// ExistsByTransactionID checks if a transaction with the given ID exists
+func (r *MySQLTransactionRepository) ExistsByTransactionID(ctx context.Context, transactionID string) (bool, error) {
+	query := "SELECT EXISTS(SELECT 1 FROM transactions WHERE transaction_id = ?)"
+
+	var exists bool
+	err := Executor(ctx, r.db).QueryRowContext(ctx, query, transactionID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check transaction existence: %w", classify(err))
+	}
+
+	return exists, nil
+}
+
+// GetByTransactionID retrieves a transaction by its external ID
+func (r *MySQLTransactionRepository) GetByTransactionID(ctx context.Context, transactionID string) (*entities.Transaction, error) {
+	query := "SELECT " + mysqlTransactionColumns + " FROM transactions WHERE transaction_id = ?"
+	return r.getTransaction(ctx, query, transactionID)
+}
+
+// GetByTransactionIDForUpdate retrieves a transaction by its external ID and
+// locks it until the ambient unit of work ends
+func (r *MySQLTransactionRepository) GetByTransactionIDForUpdate(ctx context.Context, transactionID string) (*entities.Transaction, error) {
+	query := "SELECT " + mysqlTransactionColumns + " FROM transactions WHERE transaction_id = ? FOR UPDATE"
+	return r.getTransaction(ctx, query, transactionID)
+}
+
+func (r *MySQLTransactionRepository) getTransaction(ctx context.Context, query, transactionID string) (*entities.Transaction, error) {
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction: %w", classify(err))
+	}
+	defer rows.Close()
+
+	transactions, err := scanTransactions(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(transactions) == 0 {
+		return nil, fmt.Errorf("transaction %q: %w", transactionID, repositories.ErrNotFound)
+	}
+
+	return transactions[0], nil
+}
+
+// GetByUserID retrieves all transactions for a user
+func (r *MySQLTransactionRepository) GetByUserID(ctx context.Context, userID uint64) ([]*entities.Transaction, error) {
+	query := `
+		SELECT ` + mysqlTransactionColumns + `
+		FROM transactions
+		WHERE user_id = ?
+		ORDER BY created_at DESC
+	`
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transactions: %w", classify(err))
+	}
+	defer rows.Close()
+
+	return scanTransactions(rows)
+}
+
+// mysqlMetadata extracts the value of a metadata key by its JSON path
+func mysqlMetadata(key string) (string, any) {
+	return "JSON_UNQUOTE(JSON_EXTRACT(metadata, %s))", metadataPath(key)
+}
+
+// Search returns a page of transactions matching the filter, newest first
+func (r *MySQLTransactionRepository) Search(
+	ctx context.Context,
+	filter repositories.TransactionFilter,
+) ([]*entities.Transaction, int, error) {
+	// Backslash is the default LIKE escape character of MySQL
+	countWhere, countArgs := compileTransactionFilter(countFilter(filter), func(int) string { return "?" }, "LIKE %s", mysqlMetadata)
+	where, args := compileTransactionFilter(filter, func(int) string { return "?" }, "LIKE %s", mysqlMetadata)
+
+	var total int
+	countQuery := "SELECT COUNT(*) FROM transactions" + countWhere
+	if err := Executor(ctx, r.db).QueryRowContext(ctx, countQuery, countArgs...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count transactions: %w", classify(err))
+	}
+
+	query := `
+		SELECT ` + mysqlTransactionColumns + `
+		FROM transactions` + where + `
+		ORDER BY created_at DESC, id DESC
+		LIMIT ? OFFSET ?
+	`
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search transactions: %w", classify(err))
+	}
+	defer rows.Close()
+
+	transactions, err := scanTransactions(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return transactions, total, nil
+}
+
+// NetChangeSince returns how much a user's base currency transactions moved
+// the balance at or after since, counting settled transactions from when they
+// were settled and taking cancelled ones back out from when they were
+// cancelled
+func (r *MySQLTransactionRepository) NetChangeSince(ctx context.Context, userID uint64, since time.Time) (decimal.Decimal, error) {
+	query := `
+		SELECT COALESCE(SUM(CASE WHEN state = 'win' THEN amount ELSE -amount END * (
+			CASE WHEN COALESCE(settled_at, created_at) >= ? THEN 1 ELSE 0 END -
+			CASE WHEN cancelled AND COALESCE(cancelled_at, settled_at, created_at) >= ? THEN 1 ELSE 0 END
+		)), 0)
+		FROM transactions
+		WHERE user_id = ? AND pending = FALSE AND currency IS NULL
+			AND (COALESCE(settled_at, created_at) >= ? OR cancelled_at >= ?)
+	`
+
+	var net decimal.Decimal
+	err := Executor(ctx, r.db).QueryRowContext(ctx, query, since, since, userID, since, since).Scan(&net)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("failed to get net change: %w", classify(err))
+	}
+
+	return net, nil
+}
+
+// VelocitySince totals a user's uncancelled standard transactions created at
+// or after since
+func (r *MySQLTransactionRepository) VelocitySince(ctx context.Context, userID uint64, since time.Time) (repositories.Velocity, error) {
+	query := `
+		SELECT
+			COUNT(*),
+			COALESCE(SUM(CASE WHEN state = 'lose' AND currency IS NULL THEN amount END), 0)
+		FROM transactions
+		WHERE user_id = ? AND created_at >= ? AND cancelled = FALSE AND type = 'transaction'
+	`
+
+	var velocity repositories.Velocity
+	err := Executor(ctx, r.db).QueryRowContext(ctx, query, userID, since).Scan(&velocity.Transactions, &velocity.Losses)
+	if err != nil {
+		return repositories.Velocity{}, fmt.Errorf("failed to get velocity: %w", classify(err))
+	}
+
+	return velocity, nil
+}
+
+// MostActiveUsers returns the IDs of up to limit users with the most
+// transactions created at or after since, most active first
+func (r *MySQLTransactionRepository) MostActiveUsers(ctx context.Context, since time.Time, limit int) ([]uint64, error) {
+	query := `
+		SELECT user_id
+		FROM transactions
+		WHERE created_at >= ?
+		GROUP BY user_id
+		ORDER BY COUNT(*) DESC, user_id
+		LIMIT ?
+	`
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get most active users: %w", classify(err))
+	}
+	defer rows.Close()
+
+	var userIDs []uint64
+	for rows.Next() {
+		var userID uint64
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("failed to scan user ID: %w", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate users: %w", classify(err))
+	}
+
+	return userIDs, nil
+}
+
+// SummarizeRound totals the user's transactions of a round in a wallet
+// currency, empty for the base currency
+func (r *MySQLTransactionRepository) SummarizeRound(
+	ctx context.Context,
+	userID uint64,
+	roundID, currency string,
+) (repositories.RoundTotals, error) {
+	query := `
+		SELECT
+			COUNT(CASE WHEN cancelled = FALSE AND type <> 'fee' THEN 1 END),
+			COUNT(CASE WHEN cancelled = TRUE THEN 1 END),
+			COALESCE(SUM(CASE WHEN cancelled = FALSE AND type <> 'fee' AND state = 'lose' THEN amount END), 0),
+			COALESCE(SUM(CASE WHEN cancelled = FALSE AND type <> 'fee' AND state = 'win' THEN amount END), 0),
+			COALESCE(SUM(CASE WHEN cancelled = FALSE AND type = 'fee' THEN amount END), 0)
+		FROM transactions
+		WHERE user_id = ? AND round_id = ? AND currency <=> NULLIF(?, '')
+	`
+
+	var totals repositories.RoundTotals
+	err := Executor(ctx, r.db).QueryRowContext(ctx, query, userID, roundID, currency).
+		Scan(&totals.Transactions, &totals.Cancelled, &totals.Bets, &totals.Wins, &totals.Fees)
+	if err != nil {
+		return repositories.RoundTotals{}, fmt.Errorf("failed to summarize round: %w", classify(err))
+	}
+
+	return totals, nil
+}
+
+// SummarizeDays totals the user's uncancelled non-fee transactions in a wallet
+// currency that occurred in [from, to) by UTC day, source type and state.
+// Transactions without a business time count when they were created.
+func (r *MySQLTransactionRepository) SummarizeDays(
+	ctx context.Context,
+	userID uint64,
+	currency string,
+	from, to time.Time,
+) ([]repositories.DayTotals, error) {
+	query := `
+		SELECT DATE(COALESCE(occurred_at, created_at)), source_type, state, COUNT(*), COALESCE(SUM(amount), 0)
+		FROM transactions
+		WHERE user_id = ? AND currency <=> NULLIF(?, '')
+			AND COALESCE(occurred_at, created_at) >= ? AND COALESCE(occurred_at, created_at) < ?
+			AND cancelled = FALSE AND type <> 'fee'
+		GROUP BY 1, 2, 3
+		ORDER BY 1, 2, 3
+	`
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, userID, currency, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize days: %w", classify(err))
+	}
+	defer rows.Close()
+
+	var days []repositories.DayTotals
+	for rows.Next() {
+		var day repositories.DayTotals
+		if err := rows.Scan(&day.Day, &day.SourceType, &day.State, &day.Count, &day.Amount); err != nil {
+			return nil, fmt.Errorf("failed to scan day totals: %w", err)
+		}
+		day.Day = time.Date(day.Day.Year(), day.Day.Month(), day.Day.Day(), 0, 0, 0, 0, time.UTC)
+		days = append(days, day)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate day totals: %w", classify(err))
+	}
+
+	return days, nil
+}
+
+// LockLatestOddUncancelled locks and returns the newest uncancelled odd-ID
+// transactions that are neither refunds, refunded, transfer legs, fees,
+// adjustments nor pending
+func (r *MySQLTransactionRepository) LockLatestOddUncancelled(ctx context.Context, limit int) ([]*entities.Transaction, error) {
+	query := `
+		SELECT ` + mysqlTransactionColumns + `
+		FROM transactions
+		WHERE cancelled = FALSE AND pending = FALSE AND id % 2 = 1 AND type NOT IN ('fee', 'adjustment') AND reverses IS NULL AND reversed_by IS NULL AND transfer_id IS NULL
+		ORDER BY id DESC
+		LIMIT ?
+		FOR UPDATE SKIP LOCKED
+	`
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest odd transactions: %w", classify(err))
+	}
+	defer rows.Close()
+
+	return scanTransactions(rows)
+}
+
+// CheckUniqueIDs checks the transactions created in [from, to) for transaction
+// IDs recorded more than once, and whether a unique index on transaction IDs
+// alone enforces them
+func (r *MySQLTransactionRepository) CheckUniqueIDs(ctx context.Context, from, to time.Time, limit int) (*repositories.UniquenessCheck, error) {
+	var check repositories.UniquenessCheck
+
+	err := Executor(ctx, r.db).QueryRowContext(ctx, `
+		SELECT COUNT(*), COUNT(DISTINCT transaction_id)
+		FROM transactions
+		WHERE created_at >= ? AND created_at < ?
+	`, from, to).Scan(&check.Transactions, &check.DistinctIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count transactions: %w", classify(err))
+	}
+
+	err = Executor(ctx, r.db).QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1
+			FROM information_schema.statistics
+			WHERE table_schema = DATABASE() AND table_name = 'transactions' AND non_unique = 0
+			GROUP BY index_name
+			HAVING COUNT(*) = 1 AND MAX(column_name) = 'transaction_id'
+		)
+	`).Scan(&check.Enforced)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check the transaction ID index: %w", classify(err))
+	}
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, `
+		SELECT transaction_id, COUNT(*)
+		FROM transactions
+		WHERE transaction_id IN (
+			SELECT transaction_id FROM transactions WHERE created_at >= ? AND created_at < ?
+		)
+		GROUP BY transaction_id
+		HAVING COUNT(*) > 1
+		ORDER BY transaction_id
+		LIMIT ?
+	`, from, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find duplicate transaction IDs: %w", classify(err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var duplicate entities.DuplicateTransactionID
+		if err := rows.Scan(&duplicate.TransactionID, &duplicate.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan duplicate transaction ID: %w", classify(err))
+		}
+		check.Duplicates = append(check.Duplicates, duplicate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating duplicate transaction IDs: %w", classify(err))
+	}
+
+	return &check, nil
+}
+
+// MarkCancelled flags a transaction as cancelled
+func (r *MySQLTransactionRepository) MarkCancelled(ctx context.Context, id uint64, cancelledAt time.Time) error {
+	query := "UPDATE transactions SET cancelled = TRUE, cancelled_at = ? WHERE id = ? AND cancelled = FALSE"
+
+	result, err := Executor(ctx, r.db).ExecContext(ctx, query, cancelledAt, id)
+	if err != nil {
+		return fmt.Errorf("failed to cancel transaction: %w", classify(err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", classify(err))
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("transaction %d: %w", id, repositories.ErrNotFound)
+	}
+
+	return nil
+}
+
+// MarkReversed links a transaction to the refund reversing it
+func (r *MySQLTransactionRepository) MarkReversed(ctx context.Context, id uint64, reversedBy string) error {
+	query := "UPDATE transactions SET reversed_by = ? WHERE id = ? AND reversed_by IS NULL"
+
+	result, err := Executor(ctx, r.db).ExecContext(ctx, query, reversedBy, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark transaction reversed: %w", classify(err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", classify(err))
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("transaction %d: %w", id, repositories.ErrNotFound)
+	}
+
+	return nil
+}
+
+// SumPending returns the total of a user's uncancelled base currency
+// transactions awaiting settlement
+func (r *MySQLTransactionRepository) SumPending(ctx context.Context, userID uint64) (decimal.Decimal, error) {
+	query := `
+		SELECT COALESCE(SUM(amount), 0)
+		FROM transactions
+		WHERE user_id = ? AND pending = TRUE AND cancelled = FALSE AND currency IS NULL
+	`
+
+	var pending decimal.Decimal
+	err := Executor(ctx, r.db).QueryRowContext(ctx, query, userID).Scan(&pending)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("failed to get pending total: %w", classify(err))
+	}
+
+	return pending, nil
+}
+
+// ListPendingBefore returns up to limit of the oldest pending transactions
+// created before before, without locking them
+func (r *MySQLTransactionRepository) ListPendingBefore(ctx context.Context, before time.Time, limit int) ([]*entities.Transaction, error) {
+	query := `
+		SELECT ` + mysqlTransactionColumns + `
+		FROM transactions
+		WHERE pending = TRUE AND cancelled = FALSE AND created_at < ?
+		ORDER BY created_at, id
+		LIMIT ?
+	`
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending transactions: %w", classify(err))
+	}
+	defer rows.Close()
+
+	return scanTransactions(rows)
+}
+
+// LockPendingBefore locks and returns up to limit of the oldest pending
+// transactions created before before
+func (r *MySQLTransactionRepository) LockPendingBefore(ctx context.Context, before time.Time, limit int) ([]*entities.Transaction, error) {
+	query := `
+		SELECT ` + mysqlTransactionColumns + `
+		FROM transactions
+		WHERE pending = TRUE AND cancelled = FALSE AND created_at < ?
+		ORDER BY created_at, id
+		LIMIT ?
+		FOR UPDATE SKIP LOCKED
+	`
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending transactions: %w", classify(err))
+	}
+	defer rows.Close()
+
+	return scanTransactions(rows)
+}
+
+// MarkSettled records the settlement of a pending transaction
+func (r *MySQLTransactionRepository) MarkSettled(ctx context.Context, id uint64, settledAt time.Time) error {
+	query := "UPDATE transactions SET pending = FALSE, settled_at = ? WHERE id = ? AND pending = TRUE"
+
+	result, err := Executor(ctx, r.db).ExecContext(ctx, query, settledAt, id)
+	if err != nil {
+		return fmt.Errorf("failed to settle transaction: %w", classify(err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", classify(err))
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("transaction %d: %w", id, repositories.ErrNotFound)
+	}
+
+	return nil
+}
@@ -0,0 +1,183 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/shopspring/decimal"
+)
+
+// MySQLUserRepository implements the user repository interface on MySQL
+type MySQLUserRepository struct {
+	db *sql.DB
+}
+
+// NewMySQLUserRepository creates a new MySQLUserRepository instance
+func NewMySQLUserRepository(db *sql.DB) *MySQLUserRepository {
+	return &MySQLUserRepository{db: db}
+}
+
+// GetByID retrieves a user by their ID
+func (r *MySQLUserRepository) GetByID(ctx context.Context, userID uint64) (*entities.User, error) {
+	return r.getUser(ctx, "SELECT "+userColumns+" FROM users WHERE id = ?", userID)
+}
+
+// GetByIDForUpdate retrieves a user by their ID and locks the row until the
+// ambient transaction ends, serializing concurrent balance updates
+func (r *MySQLUserRepository) GetByIDForUpdate(ctx context.Context, userID uint64) (*entities.User, error) {
+	return r.getUser(ctx, "SELECT "+userColumns+" FROM users WHERE id = ? FOR UPDATE", userID)
+}
+
+func (r *MySQLUserRepository) getUser(ctx context.Context, query string, userID uint64) (*entities.User, error) {
+	user, err := scanUser(Executor(ctx, r.db).QueryRowContext(ctx, query, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("user with ID %d: %w", userID, repositories.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
+
+// UpdateBalance updates the user's balance
+func (r *MySQLUserRepository) UpdateBalance(ctx context.Context, userID uint64, newBalance decimal.Decimal) error {
+	query := "UPDATE users SET balance = ?, updated_at = UTC_TIMESTAMP(6) WHERE id = ?"
+	return r.update(ctx, "balance", query, newBalance, userID)
+}
+
+// Create creates a new user. A zero ID is allocated by AUTO_INCREMENT.
+func (r *MySQLUserRepository) Create(ctx context.Context, user *entities.User) error {
+	query := "INSERT INTO users (id, balance) VALUES (?, ?)"
+
+	result, err := Executor(ctx, r.db).ExecContext(ctx, query, explicitID(user.ID), user.Balance)
+	if err != nil {
+		return fmt.Errorf("failed to create user: %w", classify(err))
+	}
+	if user.ID == 0 {
+		id, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to get user ID: %w", classify(err))
+		}
+		user.ID = uint64(id)
+	}
+	user.Status = entities.UserStatusActive
+
+	return nil
+}
+
+// List retrieves a page of users ordered by ID
+func (r *MySQLUserRepository) List(ctx context.Context, limit, offset int) ([]*entities.User, int, error) {
+	var total int
+	if err := Executor(ctx, r.db).QueryRowContext(ctx, "SELECT COUNT(*) FROM users").Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %w", classify(err))
+	}
+
+	query := "SELECT " + userColumns + " FROM users ORDER BY id LIMIT ? OFFSET ?"
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list users: %w", classify(err))
+	}
+	defer rows.Close()
+
+	users := make([]*entities.User, 0, limit)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list users: %w", classify(err))
+	}
+
+	return users, total, nil
+}
+
+// UpdateStatus updates the user's account status
+func (r *MySQLUserRepository) UpdateStatus(ctx context.Context, userID uint64, status entities.UserStatus) error {
+	query := "UPDATE users SET status = ?, updated_at = UTC_TIMESTAMP(6) WHERE id = ?"
+	return r.update(ctx, "status", query, status, userID)
+}
+
+// UpdateJurisdiction sets the user's jurisdiction; an empty value clears it
+func (r *MySQLUserRepository) UpdateJurisdiction(ctx context.Context, userID uint64, jurisdiction string) error {
+	query := "UPDATE users SET jurisdiction = NULLIF(?, ''), updated_at = UTC_TIMESTAMP(6) WHERE id = ?"
+	return r.update(ctx, "jurisdiction", query, jurisdiction, userID)
+}
+
+// UpdateCreditLimit sets how far below zero the user's balance may go
+func (r *MySQLUserRepository) UpdateCreditLimit(ctx context.Context, userID uint64, creditLimit decimal.Decimal) error {
+	query := "UPDATE users SET credit_limit = ?, updated_at = UTC_TIMESTAMP(6) WHERE id = ?"
+	return r.update(ctx, "credit limit", query, creditLimit, userID)
+}
+
+// LockIdleSince locks and returns up to limit active users that are not
+// dormant, were created before since and have no transaction created at or
+// after since, skipping users locked by others
+func (r *MySQLUserRepository) LockIdleSince(ctx context.Context, since time.Time, limit int) ([]*entities.User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users u
+		WHERE u.status = 'active' AND u.dormant_at IS NULL AND u.created_at < ?
+			AND NOT EXISTS (
+				SELECT 1 FROM transactions t
+				WHERE t.user_id = u.id AND t.created_at >= ?
+			)
+		ORDER BY u.id
+		LIMIT ?
+		FOR UPDATE OF u SKIP LOCKED
+	`
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, since, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get idle users: %w", classify(err))
+	}
+	defer rows.Close()
+
+	var users []*entities.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get idle users: %w", classify(err))
+	}
+
+	return users, nil
+}
+
+// UpdateDormancy flags the user as dormant at dormantAt; nil clears the flag
+func (r *MySQLUserRepository) UpdateDormancy(ctx context.Context, userID uint64, dormantAt *time.Time) error {
+	query := "UPDATE users SET dormant_at = ?, updated_at = UTC_TIMESTAMP(6) WHERE id = ?"
+	return r.update(ctx, "dormancy", query, dormantAt, userID)
+}
+
+// update runs an update of a single user
+func (r *MySQLUserRepository) update(ctx context.Context, field, query string, value any, userID uint64) error {
+	result, err := Executor(ctx, r.db).ExecContext(ctx, query, value, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", field, classify(err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", classify(err))
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("user with ID %d: %w", userID, repositories.ErrNotFound)
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+)
+
+// OutboxRepository implements the outbox repository interface
+type OutboxRepository struct {
+	db *sql.DB
+}
+
+// NewOutboxRepository creates a new outbox repository
+func NewOutboxRepository(db *sql.DB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// Append records an event in the ambient unit of work
+func (r *OutboxRepository) Append(ctx context.Context, event *entities.OutboxEvent) error {
+	query := `
+		INSERT INTO outbox (event_type, event_key, payload, created_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`
+
+	err := Executor(ctx, r.db).QueryRowContext(
+		ctx,
+		query,
+		event.Type,
+		event.Key,
+		event.Payload,
+		event.CreatedAt,
+	).Scan(&event.ID)
+
+	if err != nil {
+		return fmt.Errorf("failed to append outbox event: %w", classify(err))
+	}
+
+	return nil
+}
+
+// LockUnpublished locks and returns up to limit of the oldest unpublished
+// events. Rows locked by another relay are waited for rather than skipped, so
+// that a later event is never published before an earlier one.
+func (r *OutboxRepository) LockUnpublished(ctx context.Context, limit int) ([]*entities.OutboxEvent, error) {
+	query := `
+		SELECT id, event_type, event_key, payload, created_at
+		FROM outbox
+		WHERE published_at IS NULL
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE
+	`
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock outbox events: %w", classify(err))
+	}
+	defer rows.Close()
+
+	var events []*entities.OutboxEvent
+	for rows.Next() {
+		var event entities.OutboxEvent
+		err := rows.Scan(
+			&event.ID,
+			&event.Type,
+			&event.Key,
+			&event.Payload,
+			&event.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan outbox event: %w", classify(err))
+		}
+		events = append(events, &event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate outbox events: %w", classify(err))
+	}
+
+	return events, nil
+}
+
+// MarkPublished records that the events were published
+func (r *OutboxRepository) MarkPublished(ctx context.Context, ids []uint64, publishedAt time.Time) error {
+	query := `UPDATE outbox SET published_at = $1 WHERE id = ANY($2)`
+
+	args := make([]int64, len(ids))
+	for i, id := range ids {
+		args[i] = int64(id)
+	}
+
+	if _, err := Executor(ctx, r.db).ExecContext(ctx, query, publishedAt, args); err != nil {
+		return fmt.Errorf("failed to mark outbox events published: %w", classify(err))
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"transaction-service/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresPoolConfig(t *testing.T) {
+	poolConfig, err := postgresPoolConfig(config.DatabaseConfig{
+		Host: "localhost", Port: "5432", User: "app", Name: "ledger", SSLMode: "disable",
+		StatementTimeout: 5 * time.Second,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "UTC", poolConfig.ConnConfig.RuntimeParams["timezone"], "sessions run in UTC")
+	assert.Equal(t, "5000", poolConfig.ConnConfig.RuntimeParams["statement_timeout"])
+}
+
+// openTestPostgres connects to the PostgreSQL database of TEST_POSTGRES_DSN
+// on a single connection, so that temporary tables shadowing the real ones
+// are seen by every query. The test is skipped without it.
+func openTestPostgres(t *testing.T, params string) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+	db, err := sql.Open("pgx", dsn+" "+params)
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func TestTransactionRepository_SummarizeDaysInUTC(t *testing.T) {
+	ctx := context.Background()
+	// Sessions east of UTC would move late transactions to the next day
+	db := openTestPostgres(t, "timezone=Asia/Tokyo")
+	_, err := db.ExecContext(ctx, `
+		CREATE TEMPORARY TABLE transactions (
+			user_id BIGINT, state VARCHAR(10), amount DECIMAL(15,2), source_type VARCHAR(20),
+			type VARCHAR(20) DEFAULT 'standard', currency VARCHAR(3), cancelled BOOLEAN DEFAULT FALSE,
+			created_at TIMESTAMP, occurred_at TIMESTAMP
+		)
+	`)
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO transactions (user_id, state, amount, source_type, created_at, occurred_at) VALUES
+			(1, 'win', 10, 'game', '2025-03-01 23:30:00', NULL),
+			(1, 'lose', 4, 'game', '2025-03-02 00:30:00', NULL),
+			(1, 'lose', 1, 'game', '2025-03-03 00:10:00', '2025-03-02 23:50:00')
+	`)
+	require.NoError(t, err)
+
+	days, err := NewTransactionRepository(db).SummarizeDays(ctx, 1, "",
+		time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Len(t, days, 2)
+	assert.Equal(t, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), days[0].Day)
+	assert.Equal(t, "10", days[0].Amount.String())
+	assert.Equal(t, time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC), days[1].Day)
+	// The late arrival counts on the day it occurred
+	assert.Equal(t, 2, days[1].Count)
+	assert.Equal(t, "5", days[1].Amount.String())
+}
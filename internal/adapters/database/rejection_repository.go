@@ -0,0 +1,185 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+)
+
+// RejectionRepository implements the rejection repository interface
+type RejectionRepository struct {
+	db *sql.DB
+}
+
+// NewRejectionRepository creates a new rejection repository
+func NewRejectionRepository(db *sql.DB) *RejectionRepository {
+	return &RejectionRepository{db: db}
+}
+
+// Create records a rejected transaction attempt
+func (r *RejectionRepository) Create(ctx context.Context, rejection *entities.Rejection) error {
+	query := `
+		INSERT INTO rejections (user_id, transaction_id, source_type, state, amount, currency, reason, created_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8)
+		RETURNING id
+	`
+
+	err := Executor(ctx, r.db).QueryRowContext(
+		ctx,
+		query,
+		rejection.UserID,
+		rejection.TransactionID,
+		rejection.SourceType,
+		rejection.State,
+		rejection.Amount,
+		rejection.Currency,
+		rejection.Reason,
+		rejection.CreatedAt,
+	).Scan(&rejection.ID)
+
+	if err != nil {
+		return fmt.Errorf("failed to create rejection: %w", classify(err))
+	}
+
+	return nil
+}
+
+// CountByReason counts the rejections created in [from, to) by source type
+// and reason
+func (r *RejectionRepository) CountByReason(ctx context.Context, from, to time.Time) ([]repositories.RejectionCount, error) {
+	query := `
+		SELECT source_type, reason, COUNT(*)
+		FROM rejections
+		WHERE created_at >= $1 AND created_at < $2
+		GROUP BY source_type, reason
+		ORDER BY source_type, reason
+	`
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count rejections: %w", classify(err))
+	}
+	defer rows.Close()
+
+	var counts []repositories.RejectionCount
+	for rows.Next() {
+		var count repositories.RejectionCount
+		if err := rows.Scan(&count.SourceType, &count.Reason, &count.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan rejection count: %w", classify(err))
+		}
+		counts = append(counts, count)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rejection counts: %w", classify(err))
+	}
+
+	return counts, nil
+}
+
+// CountAccepted counts the standard transactions created in [from, to) by
+// source type
+func (r *RejectionRepository) CountAccepted(ctx context.Context, from, to time.Time) (map[entities.SourceType]int, error) {
+	query := `
+		SELECT source_type, COUNT(*)
+		FROM transactions
+		WHERE created_at >= $1 AND created_at < $2 AND type = 'transaction'
+		GROUP BY source_type
+	`
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count accepted transactions: %w", classify(err))
+	}
+	defer rows.Close()
+
+	counts := make(map[entities.SourceType]int)
+	for rows.Next() {
+		var sourceType entities.SourceType
+		var count int
+		if err := rows.Scan(&sourceType, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan accepted transaction count: %w", classify(err))
+		}
+		counts[sourceType] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating accepted transaction counts: %w", classify(err))
+	}
+
+	return counts, nil
+}
+
+// List returns a page of the rejections matching the filter, newest first
+func (r *RejectionRepository) List(ctx context.Context, filter repositories.RejectionFilter) ([]*entities.Rejection, int, error) {
+	var conditions []string
+	var args []any
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.SourceType != "" {
+		add("source_type = $%d", filter.SourceType)
+	}
+	if filter.Reason != "" {
+		add("reason = $%d", filter.Reason)
+	}
+	if filter.From != nil {
+		add("created_at >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		add("created_at < $%d", *filter.To)
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	countQuery := "SELECT COUNT(*) FROM rejections" + where
+	if err := Executor(ctx, r.db).QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count rejections: %w", classify(err))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, user_id, transaction_id, source_type, state, amount, COALESCE(currency, ''), reason, created_at
+		FROM rejections%s
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d OFFSET $%d
+	`, where, len(args)+1, len(args)+2)
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list rejections: %w", classify(err))
+	}
+	defer rows.Close()
+
+	rejections := []*entities.Rejection{}
+	for rows.Next() {
+		var rejection entities.Rejection
+		err := rows.Scan(
+			&rejection.ID,
+			&rejection.UserID,
+			&rejection.TransactionID,
+			&rejection.SourceType,
+			&rejection.State,
+			&rejection.Amount,
+			&rejection.Currency,
+			&rejection.Reason,
+			&rejection.CreatedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan rejection: %w", classify(err))
+		}
+		rejections = append(rejections, &rejection)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating rejections: %w", classify(err))
+	}
+
+	return rejections, total, nil
+}
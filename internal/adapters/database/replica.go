@@ -0,0 +1,199 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"transaction-service/internal/consistency"
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+)
+
+// ReplicaReads routes the reads of requests marked with
+// consistency.WithReplicaRead to a read replica, once the replica has
+// replayed the write-ahead log up to the position the request asks for.
+// Reads fall back to the primary while the replica lags behind or is
+// unavailable, and reads within a unit of work always use the primary.
+type ReplicaReads struct {
+	// position returns the log position the replica has replayed
+	position func(ctx context.Context) (consistency.Token, error)
+	// replayed is the highest position seen, which the replica never goes
+	// back from
+	replayed atomic.Uint64
+}
+
+// NewReplicaReads creates a new ReplicaReads for the replica database
+func NewReplicaReads(replica *sql.DB) *ReplicaReads {
+	return &ReplicaReads{
+		position: func(ctx context.Context) (consistency.Token, error) {
+			return replayPosition(ctx, replica)
+		},
+	}
+}
+
+// CurrentPosition returns the current write-ahead log position of the
+// primary, handed out as the consistency token of a write
+func CurrentPosition(ctx context.Context, db *sql.DB) (consistency.Token, error) {
+	var position string
+	if err := db.QueryRowContext(ctx, "SELECT pg_current_wal_lsn()::text").Scan(&position); err != nil {
+		return 0, fmt.Errorf("failed to get WAL position: %w", err)
+	}
+	return consistency.ParseToken(position)
+}
+
+// replayPosition returns the write-ahead log position a replica has replayed.
+// A database that is not in recovery has everything it wrote.
+func replayPosition(ctx context.Context, db *sql.DB) (consistency.Token, error) {
+	var position string
+	query := "SELECT COALESCE(pg_last_wal_replay_lsn(), pg_current_wal_lsn())::text"
+	if err := db.QueryRowContext(ctx, query).Scan(&position); err != nil {
+		return 0, fmt.Errorf("failed to get replayed WAL position: %w", err)
+	}
+	return consistency.ParseToken(position)
+}
+
+// useReplica reports whether a read made with ctx can be served by the
+// replica. The replica is asked for its position only while the highest
+// position seen is behind the one required.
+func (r *ReplicaReads) useReplica(ctx context.Context) bool {
+	if _, ok := TxFromContext(ctx); ok {
+		return false
+	}
+	after, ok := consistency.ReplicaRead(ctx)
+	if !ok {
+		return false
+	}
+	if consistency.Token(r.replayed.Load()) >= after {
+		return true
+	}
+
+	position, err := r.position(ctx)
+	if err != nil {
+		return false
+	}
+	for {
+		seen := r.replayed.Load()
+		if uint64(position) <= seen || r.replayed.CompareAndSwap(seen, uint64(position)) {
+			break
+		}
+	}
+	return position >= after
+}
+
+// routeRead runs replica when the read can be served by the replica, and
+// primary otherwise or when the replica is unavailable
+func routeRead[T any](ctx context.Context, r *ReplicaReads, replica, primary func() (T, error)) (T, error) {
+	if r.useReplica(ctx) {
+		result, err := replica()
+		if !errors.Is(err, repositories.ErrUnavailable) && !IsTransient(err) {
+			return result, err
+		}
+	}
+	return primary()
+}
+
+// UserRepository routes the user lookups and listings of primary to replica
+func (r *ReplicaReads) UserRepository(primary, replica repositories.UserRepository) repositories.UserRepository {
+	return &replicaUserRepository{UserRepository: primary, replica: replica, reads: r}
+}
+
+type replicaUserRepository struct {
+	repositories.UserRepository
+	replica repositories.UserRepository
+	reads   *ReplicaReads
+}
+
+func (u *replicaUserRepository) GetByID(ctx context.Context, userID uint64) (*entities.User, error) {
+	return routeRead(ctx, u.reads, func() (*entities.User, error) {
+		return u.replica.GetByID(ctx, userID)
+	}, func() (*entities.User, error) {
+		return u.UserRepository.GetByID(ctx, userID)
+	})
+}
+
+// userPage is a page of users along with their total
+type userPage struct {
+	users []*entities.User
+	total int
+}
+
+func (u *replicaUserRepository) List(ctx context.Context, limit, offset int) ([]*entities.User, int, error) {
+	page, err := routeRead(ctx, u.reads, func() (userPage, error) {
+		users, total, err := u.replica.List(ctx, limit, offset)
+		return userPage{users, total}, err
+	}, func() (userPage, error) {
+		users, total, err := u.UserRepository.List(ctx, limit, offset)
+		return userPage{users, total}, err
+	})
+	return page.users, page.total, err
+}
+
+// TransactionRepository routes the transaction lookups, histories, searches
+// and round summaries of primary to replica
+func (r *ReplicaReads) TransactionRepository(primary, replica repositories.TransactionRepository) repositories.TransactionRepository {
+	return &replicaTransactionRepository{TransactionRepository: primary, replica: replica, reads: r}
+}
+
+type replicaTransactionRepository struct {
+	repositories.TransactionRepository
+	replica repositories.TransactionRepository
+	reads   *ReplicaReads
+}
+
+func (t *replicaTransactionRepository) GetByTransactionID(ctx context.Context, transactionID string) (*entities.Transaction, error) {
+	return routeRead(ctx, t.reads, func() (*entities.Transaction, error) {
+		return t.replica.GetByTransactionID(ctx, transactionID)
+	}, func() (*entities.Transaction, error) {
+		return t.TransactionRepository.GetByTransactionID(ctx, transactionID)
+	})
+}
+
+func (t *replicaTransactionRepository) GetByUserID(ctx context.Context, userID uint64) ([]*entities.Transaction, error) {
+	return routeRead(ctx, t.reads, func() ([]*entities.Transaction, error) {
+		return t.replica.GetByUserID(ctx, userID)
+	}, func() ([]*entities.Transaction, error) {
+		return t.TransactionRepository.GetByUserID(ctx, userID)
+	})
+}
+
+// transactionPage is a page of transactions along with their total
+type transactionPage struct {
+	transactions []*entities.Transaction
+	total        int
+}
+
+func (t *replicaTransactionRepository) Search(ctx context.Context, filter repositories.TransactionFilter) ([]*entities.Transaction, int, error) {
+	page, err := routeRead(ctx, t.reads, func() (transactionPage, error) {
+		transactions, total, err := t.replica.Search(ctx, filter)
+		return transactionPage{transactions, total}, err
+	}, func() (transactionPage, error) {
+		transactions, total, err := t.TransactionRepository.Search(ctx, filter)
+		return transactionPage{transactions, total}, err
+	})
+	return page.transactions, page.total, err
+}
+
+func (t *replicaTransactionRepository) SummarizeRound(ctx context.Context, userID uint64, roundID, currency string) (repositories.RoundTotals, error) {
+	return routeRead(ctx, t.reads, func() (repositories.RoundTotals, error) {
+		return t.replica.SummarizeRound(ctx, userID, roundID, currency)
+	}, func() (repositories.RoundTotals, error) {
+		return t.TransactionRepository.SummarizeRound(ctx, userID, roundID, currency)
+	})
+}
+
+func (t *replicaTransactionRepository) SummarizeDays(
+	ctx context.Context,
+	userID uint64,
+	currency string,
+	from, to time.Time,
+) ([]repositories.DayTotals, error) {
+	return routeRead(ctx, t.reads, func() ([]repositories.DayTotals, error) {
+		return t.replica.SummarizeDays(ctx, userID, currency, from, to)
+	}, func() ([]repositories.DayTotals, error) {
+		return t.TransactionRepository.SummarizeDays(ctx, userID, currency, from, to)
+	})
+}
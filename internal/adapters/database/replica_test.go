@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"transaction-service/internal/consistency"
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// namedUserRepository answers lookups with a user named after the store
+type namedUserRepository struct {
+	repositories.UserRepository
+	jurisdiction string
+	err          error
+}
+
+func (r *namedUserRepository) GetByID(ctx context.Context, userID uint64) (*entities.User, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	return &entities.User{ID: userID, Jurisdiction: r.jurisdiction}, nil
+}
+
+func TestReplicaReads_UserRepository(t *testing.T) {
+	var position consistency.Token = 100
+	var lookups int
+	reads := &ReplicaReads{position: func(context.Context) (consistency.Token, error) {
+		lookups++
+		return position, nil
+	}}
+	replica := &namedUserRepository{jurisdiction: "replica"}
+	repo := reads.UserRepository(&namedUserRepository{jurisdiction: "primary"}, replica)
+
+	readFrom := func(ctx context.Context) string {
+		user, err := repo.GetByID(ctx, 1)
+		require.NoError(t, err)
+		return user.Jurisdiction
+	}
+	background := context.Background()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{name: "unmarked reads use the primary", ctx: background, want: "primary"},
+		{name: "reads without a token use the replica", ctx: consistency.WithReplicaRead(background, 0), want: "replica"},
+		{name: "caught up replicas serve reads", ctx: consistency.WithReplicaRead(background, 100), want: "replica"},
+		{name: "lagging replicas fall back to the primary", ctx: consistency.WithReplicaRead(background, 101), want: "primary"},
+		{
+			name: "reads within a unit of work use the primary",
+			ctx:  context.WithValue(consistency.WithReplicaRead(background, 0), txContextKey{}, &sql.Tx{}),
+			want: "primary",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, readFrom(tt.ctx))
+		})
+	}
+
+	t.Run("the highest position seen is remembered", func(t *testing.T) {
+		lookups = 0
+		position = 200
+		assert.Equal(t, "replica", readFrom(consistency.WithReplicaRead(background, 150)))
+		assert.Equal(t, "replica", readFrom(consistency.WithReplicaRead(background, 200)))
+		assert.Equal(t, 1, lookups)
+	})
+
+	t.Run("unavailable replicas fall back to the primary", func(t *testing.T) {
+		replica.err = repositories.ErrUnavailable
+		assert.Equal(t, "primary", readFrom(consistency.WithReplicaRead(background, 0)))
+
+		replica.err = repositories.ErrNotFound
+		_, err := repo.GetByID(consistency.WithReplicaRead(background, 0), 1)
+		assert.True(t, errors.Is(err, repositories.ErrNotFound))
+	})
+}
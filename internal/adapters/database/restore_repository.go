@@ -0,0 +1,212 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+)
+
+// fingerprintTables lists the tables a fingerprint covers, with the columns
+// each row is checksummed by. Columns tracking the progress of event
+// publication, webhook deliveries and scheduled transactions are left out, so
+// that background work does not break verification.
+var fingerprintTables = []struct {
+	name string
+	row  string
+}{
+	{"users", "id::TEXT || ':' || balance::TEXT || ':' || status || ':' || COALESCE(jurisdiction, '') || ':' || COALESCE(dormant_at::TEXT, '')"},
+	{"wallets", "user_id::TEXT || ':' || currency || ':' || balance::TEXT"},
+	{"transactions", "id::TEXT || ':' || user_id::TEXT || ':' || transaction_id || ':' || state || ':' || amount::TEXT || ':' || " +
+		"COALESCE(currency, '') || ':' || cancelled::TEXT || ':' || COALESCE(reversed_by, '') || ':' || COALESCE(transfer_id, '') || ':' || COALESCE(charged_for, '')"},
+	{"holds", "id::TEXT || ':' || hold_id || ':' || user_id::TEXT || ':' || amount::TEXT || ':' || status"},
+	{"scheduled_transactions", "id::TEXT || ':' || schedule_id || ':' || user_id::TEXT || ':' || state || ':' || amount::TEXT || ':' || COALESCE(cron, '')"},
+	{"balance_snapshots", "user_id::TEXT || ':' || day::TEXT || ':' || balance::TEXT"},
+	{"annotations", "id::TEXT || ':' || target_type || ':' || target_id || ':' || note"},
+	{"outbox", "id::TEXT || ':' || event_type || ':' || event_key"},
+	{"webhooks", "id::TEXT || ':' || url || ':' || active::TEXT"},
+	{"webhook_deliveries", "id::TEXT || ':' || webhook_id::TEXT || ':' || event_type"},
+	{"fee_rules", "source_type || ':' || state || ':' || kind || ':' || rate::TEXT || ':' || amount::TEXT || ':' || tiers::TEXT"},
+	{"system_accounts", "kind || ':' || user_id::TEXT"},
+	{"balance_adjustments", "id::TEXT || ':' || adjustment_id || ':' || user_id::TEXT || ':' || direction || ':' || amount::TEXT || ':' || currency"},
+	{"audit_log", "id::TEXT || ':' || user_id::TEXT || ':' || operation || ':' || transaction_id || ':' || change::TEXT || ':' || currency"},
+}
+
+// fingerprintQuery computes every table fingerprint and the balance
+// consistency in a single statement, so that they all see the same snapshot.
+// Checksums add up the first 60 bits of each row's MD5, which does not
+// depend on the order rows are read in.
+var fingerprintQuery = func() string {
+	var columns []string
+	for _, table := range fingerprintTables {
+		columns = append(columns,
+			fmt.Sprintf("(SELECT COUNT(*) FROM %s)", table.name),
+			fmt.Sprintf("(SELECT COALESCE(SUM(('x' || LEFT(md5(%s), 15))::BIT(60)::BIGINT), 0)::TEXT FROM %s)", table.row, table.name),
+		)
+	}
+	columns = append(columns,
+		"COUNT(*) FILTER (WHERE b.balance < 0)",
+		`COUNT(*) FILTER (WHERE latest.balance_after <> b.balance AND NOT EXISTS (
+			SELECT 1 FROM transactions c
+			WHERE c.user_id = b.user_id AND COALESCE(c.currency, '') = b.currency AND c.cancelled
+		))`,
+	)
+
+	return `
+		WITH balances AS (
+			SELECT id AS user_id, '' AS currency, balance FROM users
+			UNION ALL
+			SELECT user_id, currency, balance FROM wallets
+		)
+		SELECT ` + strings.Join(columns, ",\n\t\t\t") + `
+		FROM balances b
+		LEFT JOIN LATERAL (
+			SELECT t.balance_after FROM transactions t
+			WHERE t.user_id = b.user_id AND COALESCE(t.currency, '') = b.currency
+			ORDER BY t.id DESC
+			LIMIT 1
+		) latest ON TRUE
+	`
+}()
+
+// RestoreRepository implements the restore repository interface
+type RestoreRepository struct {
+	db *sql.DB
+}
+
+// NewRestoreRepository creates a new restore repository
+func NewRestoreRepository(db *sql.DB) *RestoreRepository {
+	return &RestoreRepository{db: db}
+}
+
+// SchemaVersion returns the schema version recorded by the migrations
+func (r *RestoreRepository) SchemaVersion(ctx context.Context) (int, error) {
+	var version int
+	err := Executor(ctx, r.db).QueryRowContext(ctx, "SELECT version FROM schema_version").Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get schema version: %w", classify(err))
+	}
+
+	return version, nil
+}
+
+// Fingerprint summarizes the current contents of the database
+func (r *RestoreRepository) Fingerprint(ctx context.Context) (*entities.DatabaseFingerprint, error) {
+	fingerprint := &entities.DatabaseFingerprint{
+		Tables: make([]entities.TableFingerprint, len(fingerprintTables)),
+	}
+
+	dest := make([]any, 0, 2*len(fingerprintTables)+2)
+	for i, table := range fingerprintTables {
+		fingerprint.Tables[i].Table = table.name
+		dest = append(dest, &fingerprint.Tables[i].Rows, &fingerprint.Tables[i].Checksum)
+	}
+	dest = append(dest, &fingerprint.Balances.Negative, &fingerprint.Balances.LedgerMismatches)
+
+	if err := Executor(ctx, r.db).QueryRowContext(ctx, fingerprintQuery).Scan(dest...); err != nil {
+		return nil, fmt.Errorf("failed to fingerprint database: %w", classify(err))
+	}
+
+	return fingerprint, nil
+}
+
+// CreateMarker stores a restore marker at the current WAL position
+func (r *RestoreRepository) CreateMarker(ctx context.Context, marker *entities.RestoreMarker) error {
+	query := `
+		INSERT INTO restore_markers (name, schema_version, wal_position, fingerprint, created_at)
+		VALUES ($1, $2, pg_current_wal_lsn()::TEXT, $3, $4)
+		RETURNING wal_position
+	`
+
+	fingerprint, err := json.Marshal(marker.Fingerprint)
+	if err != nil {
+		return fmt.Errorf("failed to encode fingerprint: %w", err)
+	}
+
+	err = Executor(ctx, r.db).QueryRowContext(
+		ctx,
+		query,
+		marker.Name,
+		marker.SchemaVersion,
+		string(fingerprint),
+		marker.CreatedAt,
+	).Scan(&marker.WALPosition)
+
+	if err != nil {
+		return fmt.Errorf("failed to create restore marker: %w", classify(err))
+	}
+
+	return nil
+}
+
+// restoreMarkerColumns is the column list matching scanRestoreMarkers
+const restoreMarkerColumns = "name, schema_version, wal_position, fingerprint, created_at"
+
+// GetMarker retrieves a restore marker by name
+func (r *RestoreRepository) GetMarker(ctx context.Context, name string) (*entities.RestoreMarker, error) {
+	query := "SELECT " + restoreMarkerColumns + " FROM restore_markers WHERE name = $1"
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get restore marker: %w", classify(err))
+	}
+	defer rows.Close()
+
+	markers, err := scanRestoreMarkers(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(markers) == 0 {
+		return nil, fmt.Errorf("restore marker %q: %w", name, repositories.ErrNotFound)
+	}
+
+	return markers[0], nil
+}
+
+// ListMarkers returns the restore markers, newest first
+func (r *RestoreRepository) ListMarkers(ctx context.Context) ([]*entities.RestoreMarker, error) {
+	query := "SELECT " + restoreMarkerColumns + " FROM restore_markers ORDER BY id DESC"
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list restore markers: %w", classify(err))
+	}
+	defer rows.Close()
+
+	return scanRestoreMarkers(rows)
+}
+
+// scanRestoreMarkers reads all rows selected with restoreMarkerColumns
+func scanRestoreMarkers(rows *sql.Rows) ([]*entities.RestoreMarker, error) {
+	var markers []*entities.RestoreMarker
+	for rows.Next() {
+		var marker entities.RestoreMarker
+		var fingerprint []byte
+
+		err := rows.Scan(
+			&marker.Name,
+			&marker.SchemaVersion,
+			&marker.WALPosition,
+			&fingerprint,
+			&marker.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan restore marker: %w", classify(err))
+		}
+		if err := json.Unmarshal(fingerprint, &marker.Fingerprint); err != nil {
+			return nil, fmt.Errorf("failed to decode fingerprint of restore marker %q: %w", marker.Name, err)
+		}
+
+		markers = append(markers, &marker)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating restore markers: %w", classify(err))
+	}
+
+	return markers, nil
+}
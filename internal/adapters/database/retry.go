@@ -0,0 +1,449 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"strings"
+	"syscall"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+	"transaction-service/internal/logging"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rs/zerolog"
+	"github.com/shopspring/decimal"
+)
+
+// ErrCommitFailed is returned when committing a unit of work fails. The
+// outcome of the transaction is unknown, so it is never retried.
+var ErrCommitFailed = errors.New("failed to commit transaction")
+
+// RetryPolicy bounds the retries of transient database errors
+type RetryPolicy struct {
+	// MaxAttempts includes the first attempt; 1 disables retries
+	MaxAttempts int
+	// SerializationMaxAttempts bounds the attempts of units of work failing
+	// to serialize, which contention makes more frequent than other
+	// transient errors; zero uses MaxAttempts
+	SerializationMaxAttempts int
+	BaseDelay                time.Duration
+	MaxDelay                 time.Duration
+}
+
+// RetryMetrics counts the retries of a Retrier
+type RetryMetrics interface {
+	// DatabaseRetry counts a retry of operation; serialization is set when
+	// it failed to serialize
+	DatabaseRetry(operation string, serialization bool)
+}
+
+type noopRetryMetrics struct{}
+
+func (noopRetryMetrics) DatabaseRetry(string, bool) {}
+
+// IsTransient reports whether err is a database error that may succeed when
+// retried: a lost or refused connection, a deadlock, a lock wait timeout, a
+// busy SQLite database, a serialization failure or a server that is shutting
+// down or out of connections. Everything else, including constraint
+// violations and cancelled contexts, is permanent.
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "40001", // serialization_failure
+			"40P01", // deadlock_detected
+			"53300", // too_many_connections
+			"57P01", // admin_shutdown
+			"57P02", // crash_shutdown
+			"57P03": // cannot_connect_now
+			return true
+		}
+		// Class 08 is connection exceptions
+		return strings.HasPrefix(pgErr.Code, "08")
+	}
+	// pgx reports failures to connect, and failures before the statement
+	// reached the server, with errors of its own
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) || pgconn.SafeToRetry(err) {
+		return true
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case mysqlDeadlock, mysqlLockWaitTimeout, mysqlTooManyConnections, mysqlServerShutdown:
+			return true
+		}
+		return false
+	}
+
+	if isSQLiteTransient(err) {
+		return true
+	}
+
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, mysql.ErrInvalidConn) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// IsSerializationFailure reports whether err is a PostgreSQL serialization
+// failure, which a transaction under SERIALIZABLE isolation fails with when
+// it conflicts with a concurrent one
+func IsSerializationFailure(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "40001" // serialization_failure
+}
+
+// Retrier decorates repositories so that transient errors are retried with
+// exponential backoff. Units of work are retried as a whole, since a failed
+// statement aborts its transaction; repository calls are retried on their own
+// only outside a unit of work, and only when repeating them is safe.
+type Retrier struct {
+	policy  RetryPolicy
+	logger  zerolog.Logger
+	metrics RetryMetrics
+}
+
+// NewRetrier creates a new Retrier; metrics may be nil
+func NewRetrier(policy RetryPolicy, logger zerolog.Logger, metrics RetryMetrics) *Retrier {
+	if metrics == nil {
+		metrics = noopRetryMetrics{}
+	}
+	return &Retrier{policy: policy, logger: logger, metrics: metrics}
+}
+
+// do runs fn until it succeeds, fails permanently or runs out of attempts
+func (r *Retrier) do(ctx context.Context, operation string, fn func() error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		serialization := IsSerializationFailure(err)
+		maxAttempts := r.policy.MaxAttempts
+		if serialization && r.policy.SerializationMaxAttempts > 0 {
+			maxAttempts = r.policy.SerializationMaxAttempts
+		}
+		if attempt >= maxAttempts || !IsTransient(err) || errors.Is(err, ErrCommitFailed) {
+			return err
+		}
+
+		logging.FromContext(ctx, &r.logger).Warn().Err(err).
+			Str("operation", operation).
+			Int("attempt", attempt).
+			Msg("retrying transient database error")
+		r.metrics.DatabaseRetry(operation, serialization)
+
+		if err := sleep(ctx, r.backoff(attempt)); err != nil {
+			return err
+		}
+	}
+}
+
+// backoff returns the delay before the given retry
+func (r *Retrier) backoff(attempt int) time.Duration {
+	delay := r.policy.BaseDelay << (attempt - 1)
+	if delay <= 0 || delay > r.policy.MaxDelay {
+		delay = r.policy.MaxDelay
+	}
+	// Full jitter spreads out retries of requests that failed together
+	if delay > 0 {
+		delay = time.Duration(rand.Int64N(int64(delay))) + 1
+	}
+	return delay
+}
+
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// retryOutside retries fn unless ctx carries a unit of work, which is
+// retried as a whole instead
+func retryOutside[T any](ctx context.Context, r *Retrier, operation string, fn func() (T, error)) (T, error) {
+	if _, ok := TxFromContext(ctx); ok {
+		return fn()
+	}
+
+	var result T
+	err := r.do(ctx, operation, func() error {
+		var err error
+		result, err = fn()
+		return err
+	})
+	return result, err
+}
+
+// UnitOfWork retries uow. A nested unit of work joins the outer transaction,
+// so only the outermost one is retried.
+func (r *Retrier) UnitOfWork(uow repositories.UnitOfWork) repositories.UnitOfWork {
+	return &retryingUnitOfWork{UnitOfWork: uow, retrier: r}
+}
+
+type retryingUnitOfWork struct {
+	repositories.UnitOfWork
+	retrier *Retrier
+}
+
+func (u *retryingUnitOfWork) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	_, err := retryOutside(ctx, u.retrier, "unit of work", func() (struct{}, error) {
+		return struct{}{}, u.UnitOfWork.WithinTransaction(ctx, fn)
+	})
+	return err
+}
+
+// UserRepository retries reads and status changes of repo. Creating users
+// is not idempotent and is never retried.
+func (r *Retrier) UserRepository(repo repositories.UserRepository) repositories.UserRepository {
+	return &retryingUserRepository{UserRepository: repo, retrier: r}
+}
+
+type retryingUserRepository struct {
+	repositories.UserRepository
+	retrier *Retrier
+}
+
+func (u *retryingUserRepository) GetByID(ctx context.Context, userID uint64) (*entities.User, error) {
+	return retryOutside(ctx, u.retrier, "get user", func() (*entities.User, error) {
+		return u.UserRepository.GetByID(ctx, userID)
+	})
+}
+
+func (u *retryingUserRepository) List(ctx context.Context, limit, offset int) ([]*entities.User, int, error) {
+	var total int
+	users, err := retryOutside(ctx, u.retrier, "list users", func() ([]*entities.User, error) {
+		var users []*entities.User
+		var err error
+		users, total, err = u.UserRepository.List(ctx, limit, offset)
+		return users, err
+	})
+	return users, total, err
+}
+
+func (u *retryingUserRepository) UpdateStatus(ctx context.Context, userID uint64, status entities.UserStatus) error {
+	_, err := retryOutside(ctx, u.retrier, "update user status", func() (struct{}, error) {
+		return struct{}{}, u.UserRepository.UpdateStatus(ctx, userID, status)
+	})
+	return err
+}
+
+func (u *retryingUserRepository) UpdateJurisdiction(ctx context.Context, userID uint64, jurisdiction string) error {
+	_, err := retryOutside(ctx, u.retrier, "update user jurisdiction", func() (struct{}, error) {
+		return struct{}{}, u.UserRepository.UpdateJurisdiction(ctx, userID, jurisdiction)
+	})
+	return err
+}
+
+func (u *retryingUserRepository) UpdateCreditLimit(ctx context.Context, userID uint64, creditLimit decimal.Decimal) error {
+	_, err := retryOutside(ctx, u.retrier, "update user credit limit", func() (struct{}, error) {
+		return struct{}{}, u.UserRepository.UpdateCreditLimit(ctx, userID, creditLimit)
+	})
+	return err
+}
+
+// WalletRepository retries the reads of repo
+func (r *Retrier) WalletRepository(repo repositories.WalletRepository) repositories.WalletRepository {
+	return &retryingWalletRepository{WalletRepository: repo, retrier: r}
+}
+
+type retryingWalletRepository struct {
+	repositories.WalletRepository
+	retrier *Retrier
+}
+
+func (w *retryingWalletRepository) ListByUser(ctx context.Context, userID uint64) ([]*entities.Wallet, error) {
+	return retryOutside(ctx, w.retrier, "list wallets", func() ([]*entities.Wallet, error) {
+		return w.WalletRepository.ListByUser(ctx, userID)
+	})
+}
+
+// TransactionRepository retries the reads of repo. Transactions are only
+// written within a unit of work.
+func (r *Retrier) TransactionRepository(repo repositories.TransactionRepository) repositories.TransactionRepository {
+	return &retryingTransactionRepository{TransactionRepository: repo, retrier: r}
+}
+
+type retryingTransactionRepository struct {
+	repositories.TransactionRepository
+	retrier *Retrier
+}
+
+func (t *retryingTransactionRepository) ExistsByTransactionID(ctx context.Context, transactionID string) (bool, error) {
+	return retryOutside(ctx, t.retrier, "check transaction existence", func() (bool, error) {
+		return t.TransactionRepository.ExistsByTransactionID(ctx, transactionID)
+	})
+}
+
+func (t *retryingTransactionRepository) GetByTransactionID(ctx context.Context, transactionID string) (*entities.Transaction, error) {
+	return retryOutside(ctx, t.retrier, "get transaction", func() (*entities.Transaction, error) {
+		return t.TransactionRepository.GetByTransactionID(ctx, transactionID)
+	})
+}
+
+func (t *retryingTransactionRepository) GetByUserID(ctx context.Context, userID uint64) ([]*entities.Transaction, error) {
+	return retryOutside(ctx, t.retrier, "get user transactions", func() ([]*entities.Transaction, error) {
+		return t.TransactionRepository.GetByUserID(ctx, userID)
+	})
+}
+
+func (t *retryingTransactionRepository) NetChangeSince(ctx context.Context, userID uint64, since time.Time) (decimal.Decimal, error) {
+	return retryOutside(ctx, t.retrier, "sum balance changes", func() (decimal.Decimal, error) {
+		return t.TransactionRepository.NetChangeSince(ctx, userID, since)
+	})
+}
+
+func (t *retryingTransactionRepository) VelocitySince(ctx context.Context, userID uint64, since time.Time) (repositories.Velocity, error) {
+	return retryOutside(ctx, t.retrier, "get velocity", func() (repositories.Velocity, error) {
+		return t.TransactionRepository.VelocitySince(ctx, userID, since)
+	})
+}
+
+func (t *retryingTransactionRepository) MostActiveUsers(ctx context.Context, since time.Time, limit int) ([]uint64, error) {
+	return retryOutside(ctx, t.retrier, "get most active users", func() ([]uint64, error) {
+		return t.TransactionRepository.MostActiveUsers(ctx, since, limit)
+	})
+}
+
+func (t *retryingTransactionRepository) Search(
+	ctx context.Context,
+	filter repositories.TransactionFilter,
+) ([]*entities.Transaction, int, error) {
+	var total int
+	transactions, err := retryOutside(ctx, t.retrier, "search transactions", func() ([]*entities.Transaction, error) {
+		var transactions []*entities.Transaction
+		var err error
+		transactions, total, err = t.TransactionRepository.Search(ctx, filter)
+		return transactions, err
+	})
+	return transactions, total, err
+}
+
+func (t *retryingTransactionRepository) SummarizeDays(
+	ctx context.Context,
+	userID uint64,
+	currency string,
+	from, to time.Time,
+) ([]repositories.DayTotals, error) {
+	return retryOutside(ctx, t.retrier, "summarize days", func() ([]repositories.DayTotals, error) {
+		return t.TransactionRepository.SummarizeDays(ctx, userID, currency, from, to)
+	})
+}
+
+func (t *retryingTransactionRepository) CheckUniqueIDs(
+	ctx context.Context,
+	from, to time.Time,
+	limit int,
+) (*repositories.UniquenessCheck, error) {
+	return retryOutside(ctx, t.retrier, "check transaction IDs", func() (*repositories.UniquenessCheck, error) {
+		return t.TransactionRepository.CheckUniqueIDs(ctx, from, to, limit)
+	})
+}
+
+func (t *retryingTransactionRepository) SumPending(ctx context.Context, userID uint64) (decimal.Decimal, error) {
+	return retryOutside(ctx, t.retrier, "sum pending transactions", func() (decimal.Decimal, error) {
+		return t.TransactionRepository.SumPending(ctx, userID)
+	})
+}
+
+// HoldRepository retries the reads of repo and the expiry of holds, which
+// only ever settles holds that have expired
+func (r *Retrier) HoldRepository(repo repositories.HoldRepository) repositories.HoldRepository {
+	return &retryingHoldRepository{HoldRepository: repo, retrier: r}
+}
+
+type retryingHoldRepository struct {
+	repositories.HoldRepository
+	retrier *Retrier
+}
+
+func (h *retryingHoldRepository) SumActive(ctx context.Context, userID uint64, now time.Time) (decimal.Decimal, error) {
+	return retryOutside(ctx, h.retrier, "sum active holds", func() (decimal.Decimal, error) {
+		return h.HoldRepository.SumActive(ctx, userID, now)
+	})
+}
+
+func (h *retryingHoldRepository) Expire(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	return retryOutside(ctx, h.retrier, "expire holds", func() ([]string, error) {
+		return h.HoldRepository.Expire(ctx, now, limit)
+	})
+}
+
+// ScheduledTransactionRepository retries the reads of repo
+func (r *Retrier) ScheduledTransactionRepository(
+	repo repositories.ScheduledTransactionRepository,
+) repositories.ScheduledTransactionRepository {
+	return &retryingScheduledTransactionRepository{ScheduledTransactionRepository: repo, retrier: r}
+}
+
+type retryingScheduledTransactionRepository struct {
+	repositories.ScheduledTransactionRepository
+	retrier *Retrier
+}
+
+func (s *retryingScheduledTransactionRepository) ListByUser(
+	ctx context.Context,
+	userID uint64,
+) ([]*entities.ScheduledTransaction, error) {
+	return retryOutside(ctx, s.retrier, "list scheduled transactions", func() ([]*entities.ScheduledTransaction, error) {
+		return s.ScheduledTransactionRepository.ListByUser(ctx, userID)
+	})
+}
+
+func (s *retryingScheduledTransactionRepository) ListDue(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	return retryOutside(ctx, s.retrier, "list due scheduled transactions", func() ([]string, error) {
+		return s.ScheduledTransactionRepository.ListDue(ctx, now, limit)
+	})
+}
+
+// BalanceSnapshotRepository retries the reads of repo
+func (r *Retrier) BalanceSnapshotRepository(repo repositories.BalanceSnapshotRepository) repositories.BalanceSnapshotRepository {
+	return &retryingBalanceSnapshotRepository{BalanceSnapshotRepository: repo, retrier: r}
+}
+
+type retryingBalanceSnapshotRepository struct {
+	repositories.BalanceSnapshotRepository
+	retrier *Retrier
+}
+
+func (s *retryingBalanceSnapshotRepository) ListUnsnapshotted(ctx context.Context, day time.Time, limit int) ([]uint64, error) {
+	return retryOutside(ctx, s.retrier, "list unsnapshotted users", func() ([]uint64, error) {
+		return s.BalanceSnapshotRepository.ListUnsnapshotted(ctx, day, limit)
+	})
+}
+
+func (s *retryingBalanceSnapshotRepository) ListByUser(
+	ctx context.Context,
+	userID uint64,
+	from, to time.Time,
+) ([]*entities.BalanceSnapshot, error) {
+	return retryOutside(ctx, s.retrier, "list balance snapshots", func() ([]*entities.BalanceSnapshot, error) {
+		return s.BalanceSnapshotRepository.ListByUser(ctx, userID, from, to)
+	})
+}
+
+func (s *retryingBalanceSnapshotRepository) GetLatestBefore(
+	ctx context.Context,
+	userID uint64,
+	before time.Time,
+) (*entities.BalanceSnapshot, error) {
+	return retryOutside(ctx, s.retrier, "get balance snapshot", func() (*entities.BalanceSnapshot, error) {
+		return s.BalanceSnapshotRepository.GetLatestBefore(ctx, userID, before)
+	})
+}
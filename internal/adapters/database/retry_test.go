@@ -0,0 +1,241 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "serialization failure", err: &pgconn.PgError{Code: "40001"}, want: true},
+		{name: "deadlock", err: fmt.Errorf("failed to update balance: %w", &pgconn.PgError{Code: "40P01"}), want: true},
+		{name: "connection failure", err: &pgconn.PgError{Code: "08006"}, want: true},
+		{name: "server shutting down", err: &pgconn.PgError{Code: "57P01"}, want: true},
+		{name: "unique violation", err: &pgconn.PgError{Code: "23505"}, want: false},
+		{name: "failed to connect", err: &pgconn.ConnectError{}, want: true},
+		{name: "authentication failure", err: fmt.Errorf("connect: %w", &pgconn.PgError{Code: "28P01"}), want: false},
+		{name: "mysql deadlock", err: &mysql.MySQLError{Number: 1213}, want: true},
+		{name: "mysql lock wait timeout", err: &mysql.MySQLError{Number: 1205}, want: true},
+		{name: "mysql duplicate entry", err: &mysql.MySQLError{Number: 1062}, want: false},
+		{name: "mysql invalid connection", err: mysql.ErrInvalidConn, want: true},
+		{name: "bad connection", err: driver.ErrBadConn, want: true},
+		{name: "connection reset", err: fmt.Errorf("read: %w", syscall.ECONNRESET), want: true},
+		{name: "not found", err: repositories.ErrNotFound, want: false},
+		{name: "cancelled context", err: context.Canceled, want: false},
+		{name: "other errors", err: errors.New("boom"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsTransient(tt.err))
+		})
+	}
+}
+
+// countingUnitOfWork fails with the given errors before succeeding
+type countingUnitOfWork struct {
+	errs  []error
+	calls int
+}
+
+func (u *countingUnitOfWork) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	u.calls++
+	if u.calls <= len(u.errs) {
+		return u.errs[u.calls-1]
+	}
+	return fn(ctx)
+}
+
+func newTestRetrier(maxAttempts int) *Retrier {
+	return NewRetrier(RetryPolicy{
+		MaxAttempts: maxAttempts,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    time.Millisecond,
+	}, zerolog.Nop(), nil)
+}
+
+func TestRetrier_UnitOfWork(t *testing.T) {
+	deadlock := &pgconn.PgError{Code: "40P01"}
+	uniqueViolation := &pgconn.PgError{Code: "23505"}
+	noop := func(context.Context) error { return nil }
+
+	tests := []struct {
+		name        string
+		errs        []error
+		ctx         context.Context
+		wantCalls   int
+		wantErr     error
+		maxAttempts int
+	}{
+		{
+			name:        "transient errors are retried",
+			errs:        []error{deadlock, driver.ErrBadConn},
+			wantCalls:   3,
+			maxAttempts: 3,
+		},
+		{
+			name:        "attempts are bounded",
+			errs:        []error{deadlock, deadlock, deadlock},
+			wantCalls:   2,
+			wantErr:     deadlock,
+			maxAttempts: 2,
+		},
+		{
+			name:        "permanent errors are not retried",
+			errs:        []error{uniqueViolation},
+			wantCalls:   1,
+			wantErr:     uniqueViolation,
+			maxAttempts: 3,
+		},
+		{
+			name:        "failed commits are not retried",
+			errs:        []error{fmt.Errorf("%w: %w", ErrCommitFailed, driver.ErrBadConn)},
+			wantCalls:   1,
+			wantErr:     ErrCommitFailed,
+			maxAttempts: 3,
+		},
+		{
+			name:        "nested units of work are retried by the outermost",
+			errs:        []error{deadlock},
+			ctx:         context.WithValue(context.Background(), txContextKey{}, &sql.Tx{}),
+			wantCalls:   1,
+			wantErr:     deadlock,
+			maxAttempts: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			inner := &countingUnitOfWork{errs: tt.errs}
+			uow := newTestRetrier(tt.maxAttempts).UnitOfWork(inner)
+
+			err := uow.WithinTransaction(ctx, noop)
+
+			assert.Equal(t, tt.wantCalls, inner.calls)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+// retryCounts counts the retries reported by a Retrier
+type retryCounts struct {
+	transient, serialization int
+}
+
+func (c *retryCounts) DatabaseRetry(operation string, serialization bool) {
+	if serialization {
+		c.serialization++
+	} else {
+		c.transient++
+	}
+}
+
+func TestRetrier_SerializationFailures(t *testing.T) {
+	serializationFailure := &pgconn.PgError{Code: "40001"}
+	deadlock := &pgconn.PgError{Code: "40P01"}
+	noop := func(context.Context) error { return nil }
+	policy := RetryPolicy{
+		MaxAttempts:              2,
+		SerializationMaxAttempts: 4,
+		BaseDelay:                time.Millisecond,
+		MaxDelay:                 time.Millisecond,
+	}
+
+	t.Run("serialization failures have their own attempts", func(t *testing.T) {
+		counts := &retryCounts{}
+		inner := &countingUnitOfWork{errs: []error{serializationFailure, serializationFailure, serializationFailure, serializationFailure}}
+
+		err := NewRetrier(policy, zerolog.Nop(), counts).UnitOfWork(inner).WithinTransaction(context.Background(), noop)
+
+		assert.ErrorIs(t, err, serializationFailure)
+		assert.Equal(t, 4, inner.calls)
+		assert.Equal(t, &retryCounts{serialization: 3}, counts)
+	})
+
+	t.Run("retries are counted by reason", func(t *testing.T) {
+		counts := &retryCounts{}
+		inner := &countingUnitOfWork{errs: []error{serializationFailure, deadlock}}
+		policy := policy
+		policy.MaxAttempts = 3
+
+		err := NewRetrier(policy, zerolog.Nop(), counts).UnitOfWork(inner).WithinTransaction(context.Background(), noop)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, inner.calls)
+		assert.Equal(t, &retryCounts{transient: 1, serialization: 1}, counts)
+	})
+
+	t.Run("serialization failures at commit are retried", func(t *testing.T) {
+		assert.True(t, IsTransient(fmt.Errorf("failed to commit transaction: %w", classify(serializationFailure))))
+		assert.False(t, IsSerializationFailure(deadlock))
+	})
+}
+
+// flakyUserRepository fails GetByID with err the first failures times
+type flakyUserRepository struct {
+	repositories.UserRepository
+	err      error
+	failures int
+	calls    int
+}
+
+func (r *flakyUserRepository) GetByID(ctx context.Context, userID uint64) (*entities.User, error) {
+	r.calls++
+	if r.calls <= r.failures {
+		return nil, r.err
+	}
+	return &entities.User{ID: userID}, nil
+}
+
+func TestRetrier_Reads(t *testing.T) {
+	t.Run("reads are retried", func(t *testing.T) {
+		inner := &flakyUserRepository{err: driver.ErrBadConn, failures: 2}
+		repo := newTestRetrier(3).UserRepository(inner)
+
+		user, err := repo.GetByID(context.Background(), 7)
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(7), user.ID)
+		assert.Equal(t, 3, inner.calls)
+	})
+
+	t.Run("cancelled contexts stop retries", func(t *testing.T) {
+		inner := &flakyUserRepository{err: driver.ErrBadConn, failures: 3}
+		retrier := NewRetrier(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour, MaxDelay: time.Hour}, zerolog.Nop(), nil)
+		repo := retrier.UserRepository(inner)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err := repo.GetByID(ctx, 7)
+
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, 1, inner.calls)
+	})
+}
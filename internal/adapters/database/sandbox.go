@@ -0,0 +1,51 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CreateSchema creates the schema if it does not exist yet
+func CreateSchema(ctx context.Context, db *sql.DB, schema string) error {
+	if _, err := db.ExecContext(ctx, "CREATE SCHEMA IF NOT EXISTS "+pgx.Identifier{schema}.Sanitize()); err != nil {
+		return fmt.Errorf("failed to create schema %s: %w", schema, err)
+	}
+	return nil
+}
+
+// ResetSchema deletes every user, wallet, transaction and annotation in schema and
+// seeds users again, in a single transaction. It refuses to run unless db resolves tables to schema,
+// so a misconfigured pool can never wipe the live tables.
+func ResetSchema(ctx context.Context, db *sql.DB, schema string, users []SeedUser) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin reset transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var current sql.NullString
+	if err := tx.QueryRowContext(ctx, "SELECT current_schema()").Scan(&current); err != nil {
+		return fmt.Errorf("failed to read current schema: %w", err)
+	}
+	if current.String != schema {
+		return fmt.Errorf("refusing to reset schema %q: connection uses %q", schema, current.String)
+	}
+
+	query := "TRUNCATE webhook_deliveries, webhooks, outbox, annotations, holds, transactions, wallets, users RESTART IDENTITY CASCADE"
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to truncate schema %s: %w", schema, err)
+	}
+
+	if err := seedUsers(ctx, tx, users); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit reset transaction: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,218 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+)
+
+// ScheduledTransactionRepository implements the scheduled transaction
+// repository interface
+type ScheduledTransactionRepository struct {
+	db *sql.DB
+}
+
+// NewScheduledTransactionRepository creates a new scheduled transaction
+// repository
+func NewScheduledTransactionRepository(db *sql.DB) *ScheduledTransactionRepository {
+	return &ScheduledTransactionRepository{db: db}
+}
+
+const scheduledTransactionColumns = "id, schedule_id, user_id, state, amount, source_type, COALESCE(currency, ''), metadata, " +
+	"COALESCE(cron, ''), max_runs, runs, status, next_run_at, last_run_at, COALESCE(last_transaction_id, ''), " +
+	"COALESCE(last_error, ''), created_at"
+
+// Create stores a new scheduled transaction
+func (r *ScheduledTransactionRepository) Create(ctx context.Context, schedule *entities.ScheduledTransaction) error {
+	query := `
+		INSERT INTO scheduled_transactions (schedule_id, user_id, state, amount, source_type, currency, metadata, cron,
+			max_runs, status, next_run_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, NULLIF($8, ''), $9, $10, $11, $12)
+		RETURNING id
+	`
+
+	err := Executor(ctx, r.db).QueryRowContext(
+		ctx,
+		query,
+		schedule.ScheduleID,
+		schedule.UserID,
+		schedule.State,
+		schedule.Amount,
+		schedule.SourceType,
+		schedule.Currency,
+		encodeMetadata(schedule.Metadata),
+		schedule.Cron,
+		schedule.MaxRuns,
+		schedule.Status,
+		schedule.NextRunAt,
+		schedule.CreatedAt,
+	).Scan(&schedule.ID)
+
+	if err != nil {
+		return fmt.Errorf("failed to create scheduled transaction: %w", classify(err))
+	}
+
+	return nil
+}
+
+// GetByScheduleIDForUpdate retrieves a scheduled transaction by its external
+// ID and locks it until the ambient transaction ends
+func (r *ScheduledTransactionRepository) GetByScheduleIDForUpdate(
+	ctx context.Context,
+	scheduleID string,
+) (*entities.ScheduledTransaction, error) {
+	query := "SELECT " + scheduledTransactionColumns + " FROM scheduled_transactions WHERE schedule_id = $1 FOR UPDATE"
+
+	schedule, err := scanScheduledTransaction(Executor(ctx, r.db).QueryRowContext(ctx, query, scheduleID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("scheduled transaction %s: %w", scheduleID, repositories.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get scheduled transaction: %w", classify(err))
+	}
+
+	return schedule, nil
+}
+
+// ListByUser returns the scheduled transactions of a user, newest first
+func (r *ScheduledTransactionRepository) ListByUser(ctx context.Context, userID uint64) ([]*entities.ScheduledTransaction, error) {
+	query := "SELECT " + scheduledTransactionColumns + " FROM scheduled_transactions WHERE user_id = $1 ORDER BY id DESC"
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scheduled transactions: %w", classify(err))
+	}
+	defer rows.Close()
+
+	schedules := []*entities.ScheduledTransaction{}
+	for rows.Next() {
+		schedule, err := scanScheduledTransaction(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan scheduled transaction: %w", classify(err))
+		}
+		schedules = append(schedules, schedule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list scheduled transactions: %w", classify(err))
+	}
+
+	return schedules, nil
+}
+
+// ListDue returns the external IDs of up to limit active scheduled
+// transactions due at now, the longest due first
+func (r *ScheduledTransactionRepository) ListDue(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	query := `
+		SELECT schedule_id
+		FROM scheduled_transactions
+		WHERE status = 'active' AND next_run_at <= $1
+		ORDER BY next_run_at
+		LIMIT $2
+	`
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list due scheduled transactions: %w", classify(err))
+	}
+	defer rows.Close()
+
+	var scheduleIDs []string
+	for rows.Next() {
+		var scheduleID string
+		if err := rows.Scan(&scheduleID); err != nil {
+			return nil, fmt.Errorf("failed to scan scheduled transaction: %w", classify(err))
+		}
+		scheduleIDs = append(scheduleIDs, scheduleID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list due scheduled transactions: %w", classify(err))
+	}
+
+	return scheduleIDs, nil
+}
+
+// Update records the runs, the next run, the outcome of the last run and the
+// status of a scheduled transaction
+func (r *ScheduledTransactionRepository) Update(ctx context.Context, schedule *entities.ScheduledTransaction) error {
+	query := `
+		UPDATE scheduled_transactions
+		SET runs = $1, status = $2, next_run_at = $3, last_run_at = $4, last_transaction_id = NULLIF($5, ''),
+			last_error = NULLIF($6, '')
+		WHERE id = $7
+	`
+
+	result, err := Executor(ctx, r.db).ExecContext(
+		ctx,
+		query,
+		schedule.Runs,
+		schedule.Status,
+		schedule.NextRunAt,
+		schedule.LastRunAt,
+		schedule.LastTransactionID,
+		schedule.LastError,
+		schedule.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update scheduled transaction: %w", classify(err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", classify(err))
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("scheduled transaction %d: %w", schedule.ID, repositories.ErrNotFound)
+	}
+
+	return nil
+}
+
+// scanScheduledTransaction scans a row of scheduledTransactionColumns
+func scanScheduledTransaction(row rowScanner) (*entities.ScheduledTransaction, error) {
+	var schedule entities.ScheduledTransaction
+	var metadata sql.NullString
+	var nextRunAt, lastRunAt sql.NullTime
+
+	err := row.Scan(
+		&schedule.ID,
+		&schedule.ScheduleID,
+		&schedule.UserID,
+		&schedule.State,
+		&schedule.Amount,
+		&schedule.SourceType,
+		&schedule.Currency,
+		&metadata,
+		&schedule.Cron,
+		&schedule.MaxRuns,
+		&schedule.Runs,
+		&schedule.Status,
+		&nextRunAt,
+		&lastRunAt,
+		&schedule.LastTransactionID,
+		&schedule.LastError,
+		&schedule.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if metadata.Valid {
+		if err := json.Unmarshal([]byte(metadata.String), &schedule.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to decode metadata: %w", err)
+		}
+	}
+	if nextRunAt.Valid {
+		schedule.NextRunAt = &nextRunAt.Time
+	}
+	if lastRunAt.Valid {
+		schedule.LastRunAt = &lastRunAt.Time
+	}
+
+	return &schedule, nil
+}
@@ -0,0 +1,137 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// seedLockKey identifies the advisory lock held while seeding users, so that
+// replicas booting at the same time do not interleave their seed statements
+const seedLockKey = 7_325_001
+
+// SeedUser describes a user that must exist after seeding
+type SeedUser struct {
+	ID      uint64
+	Balance decimal.Decimal
+}
+
+// SeedUsers idempotently inserts the given users. Existing users are left
+// untouched, and the users ID sequence is advanced past the highest ID so
+// that later auto-generated IDs never collide with seeded ones.
+func SeedUsers(ctx context.Context, db *sql.DB, users []SeedUser) error {
+	if len(users) == 0 {
+		return nil
+	}
+	switch driverOf(db) {
+	case DriverMySQL:
+		return seedMySQLUsers(ctx, db, users)
+	case DriverSQLite:
+		return seedSQLiteUsers(ctx, db, users)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin seed transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Serialize seeding across replicas; released automatically on commit
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", seedLockKey); err != nil {
+		return fmt.Errorf("failed to acquire seed lock: %w", err)
+	}
+
+	if err := seedUsers(ctx, tx, users); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit seed transaction: %w", err)
+	}
+
+	return nil
+}
+
+// seedUsers inserts users within tx and advances the users ID sequence
+func seedUsers(ctx context.Context, tx *sql.Tx, users []SeedUser) error {
+	for _, user := range users {
+		query := `
+			INSERT INTO users (id, balance)
+			VALUES ($1, $2)
+			ON CONFLICT (id) DO NOTHING
+		`
+		if _, err := tx.ExecContext(ctx, query, user.ID, user.Balance); err != nil {
+			return fmt.Errorf("failed to insert user %d: %w", user.ID, err)
+		}
+	}
+
+	// Never move the sequence backwards, even if other users were created since
+	query := "SELECT setval('users_id_seq', GREATEST((SELECT COALESCE(MAX(id), 1) FROM users), 1), true)"
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to advance user sequence: %w", err)
+	}
+
+	return nil
+}
+
+// seedMySQLUsers inserts users on MySQL, holding a named lock for the same
+// reason as the advisory lock on PostgreSQL. AUTO_INCREMENT moves past
+// explicitly inserted IDs on its own.
+func seedMySQLUsers(ctx context.Context, db *sql.DB, users []SeedUser) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get a connection: %w", err)
+	}
+	defer conn.Close()
+
+	// Named locks belong to the session rather than the transaction
+	if _, err := conn.ExecContext(ctx, "SELECT GET_LOCK('transaction_service_seed', -1)"); err != nil {
+		return fmt.Errorf("failed to acquire seed lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK('transaction_service_seed')")
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin seed transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, user := range users {
+		query := "INSERT INTO users (id, balance) VALUES (?, ?) ON DUPLICATE KEY UPDATE id = id"
+		if _, err := tx.ExecContext(ctx, query, user.ID, user.Balance); err != nil {
+			return fmt.Errorf("failed to insert user %d: %w", user.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit seed transaction: %w", err)
+	}
+
+	return nil
+}
+
+// seedSQLiteUsers inserts users on SQLite. Transactions take the database
+// write lock when they begin, which serializes seeding, and AUTOINCREMENT
+// moves past explicitly inserted IDs on its own.
+func seedSQLiteUsers(ctx context.Context, db *sql.DB, users []SeedUser) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin seed transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, user := range users {
+		query := "INSERT INTO users (id, balance) VALUES (?, ?) ON CONFLICT (id) DO NOTHING"
+		if _, err := tx.ExecContext(ctx, query, user.ID, user.Balance); err != nil {
+			return fmt.Errorf("failed to insert user %d: %w", user.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit seed transaction: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// sequenceTables are the tables whose IDs are allocated from a sequence
+var sequenceTables = []string{"users", "transactions", "holds", "annotations"}
+
+// explicitID returns id as a query argument, or NULL for a zero ID so that
+// the insert allocates one from the sequence
+func explicitID(id uint64) sql.NullInt64 {
+	if id == 0 {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(id), Valid: true}
+}
+
+// AdvanceSequences moves every ID sequence past the highest ID in its table.
+// Rows inserted with explicit IDs, such as those replayed by a storage
+// migration, do not advance the sequences, which must be advanced before the
+// database allocates IDs of its own. Sequences are never moved backwards.
+func AdvanceSequences(ctx context.Context, db *sql.DB) error {
+	for _, table := range sequenceTables {
+		query := fmt.Sprintf(`
+			SELECT setval(seq, GREATEST((SELECT COALESCE(MAX(id), 1) FROM %[1]s), pg_sequence_last_value(seq)), true)
+			FROM (SELECT pg_get_serial_sequence('%[1]s', 'id')::REGCLASS AS seq) AS sequence
+		`, table)
+		if _, err := db.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("failed to advance %s sequence: %w", table, classify(err))
+		}
+	}
+	return nil
+}
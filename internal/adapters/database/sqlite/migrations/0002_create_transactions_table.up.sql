@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS transactions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    transaction_id VARCHAR(255) NOT NULL,
+    state VARCHAR(10) NOT NULL CHECK (state IN ('win', 'lose')),
+    amount DECIMAL(15,2) NOT NULL,
+    source_type VARCHAR(20) NOT NULL CHECK (source_type IN ('game', 'server', 'payment')),
+    occurred_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    cancelled BOOLEAN NOT NULL DEFAULT FALSE,
+    cancelled_at TIMESTAMP NULL,
+    balance_after DECIMAL(15,2) NULL,
+    receipt VARCHAR(64) NULL,
+    currency VARCHAR(3) NULL,
+    round_id VARCHAR(255) NULL,
+    type VARCHAR(20) NOT NULL DEFAULT 'transaction',
+    reverses VARCHAR(255) NULL,
+    reversed_by VARCHAR(255) NULL,
+    transfer_id VARCHAR(255) NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_transaction_id ON transactions(transaction_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_receipt ON transactions(receipt);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_reverses ON transactions(reverses) WHERE reverses IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_transactions_created_at ON transactions(created_at);
+CREATE INDEX IF NOT EXISTS idx_transactions_user_id_created_at ON transactions(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_transactions_user_round ON transactions(user_id, round_id) WHERE round_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_transactions_transfer_id ON transactions(transfer_id) WHERE transfer_id IS NOT NULL;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS annotations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    target_type VARCHAR(20) NOT NULL CHECK (target_type IN ('user', 'transaction')),
+    target_id VARCHAR(255) NOT NULL,
+    author VARCHAR(255) NOT NULL,
+    note TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_annotations_target
+    ON annotations(target_type, target_id, created_at);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_transactions_charged_for;
+
+ALTER TABLE transactions DROP COLUMN charged_for;
@@ -0,0 +1,3 @@
+ALTER TABLE transactions ADD COLUMN charged_for VARCHAR(255) NULL;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_charged_for ON transactions(charged_for);
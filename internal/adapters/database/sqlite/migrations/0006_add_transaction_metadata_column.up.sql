@@ -0,0 +1 @@
+ALTER TABLE transactions ADD COLUMN metadata TEXT NULL;
@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"transaction-service/internal/domain/entities"
+)
+
+// SQLiteAnnotationRepository implements the annotation repository interface
+// on SQLite
+type SQLiteAnnotationRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteAnnotationRepository creates a new SQLite annotation repository
+func NewSQLiteAnnotationRepository(db *sql.DB) *SQLiteAnnotationRepository {
+	return &SQLiteAnnotationRepository{db: db}
+}
+
+// Create stores a new annotation. A zero ID is allocated by AUTOINCREMENT.
+func (r *SQLiteAnnotationRepository) Create(ctx context.Context, annotation *entities.Annotation) error {
+	query := `
+		INSERT INTO annotations (id, target_type, target_id, author, note, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+
+	result, err := Executor(ctx, r.db).ExecContext(
+		ctx,
+		query,
+		explicitID(annotation.ID),
+		annotation.TargetType,
+		annotation.TargetID,
+		annotation.Author,
+		annotation.Note,
+		sqliteTime(&annotation.CreatedAt),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create annotation: %w", classify(err))
+	}
+
+	if annotation.ID == 0 {
+		id, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to get annotation ID: %w", classify(err))
+		}
+		annotation.ID = uint64(id)
+	}
+
+	return nil
+}
+
+// ListByTarget returns the annotations on a record, oldest first
+func (r *SQLiteAnnotationRepository) ListByTarget(
+	ctx context.Context,
+	targetType entities.AnnotationTarget,
+	targetID string,
+) ([]*entities.Annotation, error) {
+	query := `
+		SELECT id, target_type, target_id, author, note, created_at
+		FROM annotations
+		WHERE target_type = ? AND target_id = ?
+		ORDER BY created_at, id
+	`
+
+	return queryAnnotations(ctx, r.db, query, targetType, targetID)
+}
+
+// ListByUser returns the annotations on a user and on the user's
+// transactions, oldest first
+func (r *SQLiteAnnotationRepository) ListByUser(ctx context.Context, userID uint64) ([]*entities.Annotation, error) {
+	query := `
+		SELECT id, target_type, target_id, author, note, created_at
+		FROM annotations
+		WHERE (target_type = 'user' AND target_id = ?)
+		   OR (target_type = 'transaction' AND target_id IN (
+				SELECT transaction_id FROM transactions WHERE user_id = ?
+		   ))
+		ORDER BY created_at, id
+	`
+
+	return queryAnnotations(ctx, r.db, query, strconv.FormatUint(userID, 10), userID)
+}
@@ -0,0 +1,53 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"time"
+
+	"transaction-service/internal/config"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// NewSQLiteConnection opens the SQLite database file at cfg.Path, creating it
+// if needed. Every transaction takes the database write lock when it begins,
+// so units of work are serialized and need no row locks, and connections wait
+// for the lock rather than fail at once. LIKE is case-sensitive, as in
+// PostgreSQL. The driver needs cgo.
+func NewSQLiteConnection(cfg config.DatabaseConfig) (*sql.DB, error) {
+	params := url.Values{
+		"_txlock":       {"immediate"},
+		"_busy_timeout": {"5000"},
+		"_foreign_keys": {"on"},
+		"_journal_mode": {"WAL"},
+		"_cslike":       {"on"},
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+cfg.Path+"?"+params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database connection: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	// Set connection pool settings; writers take turns however many
+	// connections there are
+	db.SetMaxOpenConns(4)
+	db.SetMaxIdleConns(4)
+
+	return db, nil
+}
+
+// sqliteTime returns t in UTC. SQLite stores times as text, which compares in
+// time order only when every time has the same offset.
+func sqliteTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
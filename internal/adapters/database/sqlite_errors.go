@@ -0,0 +1,33 @@
+//go:build cgo
+
+package database
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// isSQLiteTransient reports whether err is an SQLite error raised while
+// another connection held a lock for longer than the busy timeout
+func isSQLiteTransient(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) &&
+		(sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked)
+}
+
+// isSQLiteUniqueViolation reports whether err is an SQLite unique or primary
+// key constraint violation
+func isSQLiteUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) &&
+		(sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique || sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey)
+}
+
+// isSQLiteNoSuchTable reports whether err is an SQLite error about a missing
+// table, which SQLite reports with the generic error code
+func isSQLiteNoSuchTable(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && strings.HasPrefix(sqliteErr.Error(), "no such table")
+}
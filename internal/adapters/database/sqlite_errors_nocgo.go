@@ -0,0 +1,12 @@
+//go:build !cgo
+
+package database
+
+// Without cgo the SQLite driver is a stub that fails to connect, so there are
+// no SQLite errors to inspect
+
+func isSQLiteTransient(error) bool { return false }
+
+func isSQLiteUniqueViolation(error) bool { return false }
+
+func isSQLiteNoSuchTable(error) bool { return false }
@@ -0,0 +1,385 @@
+//go:build cgo
+
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"transaction-service/internal/config"
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteRepositories(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := NewSQLiteConnection(config.DatabaseConfig{Driver: DriverSQLite, Path: filepath.Join(t.TempDir(), "test.db")})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	migrator, err := NewMigrator(db)
+	require.NoError(t, err)
+	require.NoError(t, migrator.Up(ctx))
+	require.NoError(t, migrator.Check(ctx))
+
+	repos := NewRepositories(db)
+	unitOfWork := NewUnitOfWork(db)
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+
+	user := &entities.User{Balance: decimal.RequireFromString("10.50")}
+	require.NoError(t, repos.Users.Create(ctx, user))
+	require.NotZero(t, user.ID)
+
+	t.Run("transactions round-trip", func(t *testing.T) {
+		err := unitOfWork.WithinTransaction(ctx, func(ctx context.Context) error {
+			locked, err := repos.Users.GetByIDForUpdate(ctx, user.ID)
+			if err != nil {
+				return err
+			}
+			for i, state := range []entities.TransactionState{entities.StateWin, entities.StateLose} {
+				transaction := &entities.Transaction{
+					UserID:        locked.ID,
+					TransactionID: []string{"tx-1", "tx-2"}[i],
+					State:         state,
+					Amount:        decimal.RequireFromString([]string{"1.10", "0.20"}[i]),
+					SourceType:    entities.SourceTypeGame,
+					CreatedAt:     now.Add(time.Duration(i) * time.Minute),
+				}
+				if err := repos.Transactions.Create(ctx, transaction); err != nil {
+					return err
+				}
+			}
+			return repos.Users.UpdateBalance(ctx, locked.ID, decimal.RequireFromString("11.40"))
+		})
+		require.NoError(t, err)
+
+		got, err := repos.Users.GetByID(ctx, user.ID)
+		require.NoError(t, err)
+		assert.True(t, decimal.RequireFromString("11.40").Equal(got.Balance))
+
+		transaction, err := repos.Transactions.GetByTransactionID(ctx, "tx-1")
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, transaction.UserID)
+		assert.Equal(t, "1.1", transaction.Amount.String())
+		assert.Equal(t, entities.TransactionTypeStandard, transaction.Type)
+		assert.NotEmpty(t, transaction.Receipt)
+		assert.True(t, now.Equal(transaction.CreatedAt))
+
+		from := now.Add(30 * time.Second)
+		page, total, err := repos.Transactions.Search(ctx, repositories.TransactionFilter{
+			UserID: user.ID, TransactionIDPrefix: "tx-", From: &from, Limit: 10,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 1, total)
+		require.Len(t, page, 1)
+		assert.Equal(t, "tx-2", page[0].TransactionID)
+
+		after := &repositories.TransactionCursor{CreatedAt: page[0].CreatedAt, ID: page[0].ID}
+		page, total, err = repos.Transactions.Search(ctx, repositories.TransactionFilter{
+			UserID: user.ID, After: after, Limit: 10,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 2, total)
+		require.Len(t, page, 1)
+		assert.Equal(t, "tx-1", page[0].TransactionID)
+
+		cancelled := false
+		page, total, err = repos.Transactions.Search(ctx, repositories.TransactionFilter{
+			UserID: user.ID, State: entities.StateLose, SourceType: entities.SourceTypeGame, Cancelled: &cancelled, Limit: 10,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 1, total)
+		require.Len(t, page, 1)
+		assert.Equal(t, "tx-2", page[0].TransactionID)
+
+		change, err := repos.Transactions.NetChangeSince(ctx, user.ID, now.Add(-time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, "0.9", change.String())
+
+		velocity, err := repos.Transactions.VelocitySince(ctx, user.ID, now.Add(-time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, 2, velocity.Transactions)
+		assert.Equal(t, "0.2", velocity.Losses.String())
+
+		_, err = repos.Transactions.GetByTransactionID(ctx, "tx-3")
+		assert.ErrorIs(t, err, repositories.ErrNotFound)
+	})
+
+	t.Run("duplicate transaction IDs conflict", func(t *testing.T) {
+		err := repos.Transactions.Create(ctx, &entities.Transaction{
+			UserID:        user.ID,
+			TransactionID: "tx-1",
+			State:         entities.StateWin,
+			Amount:        decimal.RequireFromString("1.00"),
+			SourceType:    entities.SourceTypeGame,
+			CreatedAt:     now,
+		})
+		assert.ErrorIs(t, err, repositories.ErrConflict)
+
+		// The insert does nothing, so the unit of work goes on and commits
+		var createErr error
+		err = unitOfWork.WithinTransaction(ctx, func(ctx context.Context) error {
+			createErr = repos.Transactions.Create(ctx, &entities.Transaction{
+				UserID:        user.ID,
+				TransactionID: "tx-2",
+				State:         entities.StateWin,
+				Amount:        decimal.RequireFromString("7.00"),
+				SourceType:    entities.SourceTypeGame,
+				CreatedAt:     now,
+			})
+			_, err := repos.Transactions.GetByTransactionID(ctx, "tx-2")
+			return err
+		})
+		require.NoError(t, err)
+		assert.ErrorIs(t, createErr, repositories.ErrConflict)
+
+		original, err := repos.Transactions.GetByTransactionID(ctx, "tx-2")
+		require.NoError(t, err)
+		assert.Equal(t, "0.2", original.Amount.String())
+	})
+
+	t.Run("metadata round-trips and is searchable", func(t *testing.T) {
+		require.NoError(t, repos.Transactions.Create(ctx, &entities.Transaction{
+			UserID:        user.ID,
+			TransactionID: "tx-meta",
+			State:         entities.StateWin,
+			Amount:        decimal.RequireFromString("0.00"),
+			SourceType:    entities.SourceTypeGame,
+			CreatedAt:     now,
+			Metadata:      map[string]string{"table.id": "t-7", "provider": "acme"},
+		}))
+
+		transaction, err := repos.Transactions.GetByTransactionID(ctx, "tx-meta")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"table.id": "t-7", "provider": "acme"}, transaction.Metadata)
+
+		other, err := repos.Transactions.GetByTransactionID(ctx, "tx-1")
+		require.NoError(t, err)
+		assert.Nil(t, other.Metadata)
+
+		tableID, otherTableID := "t-7", "t-8"
+		for name, tt := range map[string]struct {
+			value *string
+			want  int
+		}{
+			"by key":           {want: 1},
+			"by key and value": {value: &tableID, want: 1},
+			"by other value":   {value: &otherTableID, want: 0},
+		} {
+			t.Run(name, func(t *testing.T) {
+				_, total, err := repos.Transactions.Search(ctx, repositories.TransactionFilter{
+					MetadataKey: "table.id", MetadataValue: tt.value, Limit: 10,
+				})
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, total)
+			})
+		}
+	})
+
+	t.Run("wallets are created on first use", func(t *testing.T) {
+		err := unitOfWork.WithinTransaction(ctx, func(ctx context.Context) error {
+			wallet, err := repos.Wallets.GetForUpdate(ctx, user.ID, "EUR")
+			if err != nil {
+				return err
+			}
+			return repos.Wallets.UpdateBalance(ctx, user.ID, "EUR", wallet.Balance.Add(decimal.RequireFromString("2.25")))
+		})
+		require.NoError(t, err)
+
+		wallets, err := repos.Wallets.ListByUser(ctx, user.ID)
+		require.NoError(t, err)
+		require.Len(t, wallets, 1)
+		assert.Equal(t, "EUR", wallets[0].Currency)
+		assert.Equal(t, "2.25", wallets[0].Balance.String())
+	})
+
+	t.Run("credit limits are updated", func(t *testing.T) {
+		require.NoError(t, repos.Users.UpdateCreditLimit(ctx, user.ID, decimal.RequireFromString("150.50")))
+
+		got, err := repos.Users.GetByID(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "150.5", got.CreditLimit.String())
+
+		err = repos.Users.UpdateCreditLimit(ctx, user.ID+1000, decimal.Zero)
+		assert.ErrorIs(t, err, repositories.ErrNotFound)
+	})
+
+	t.Run("pending transactions are settled", func(t *testing.T) {
+		require.NoError(t, repos.Transactions.Create(ctx, &entities.Transaction{
+			UserID:        user.ID,
+			TransactionID: "tx-pending",
+			State:         entities.StateWin,
+			Amount:        decimal.RequireFromString("5.10"),
+			SourceType:    entities.SourceTypePayment,
+			CreatedAt:     now,
+			Pending:       true,
+		}))
+
+		pending, err := repos.Transactions.SumPending(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "5.1", pending.String())
+
+		listed, err := repos.Transactions.ListPendingBefore(ctx, now.Add(time.Second), 10)
+		require.NoError(t, err)
+		require.Len(t, listed, 1)
+		assert.Equal(t, "tx-pending", listed[0].TransactionID)
+		listed, err = repos.Transactions.ListPendingBefore(ctx, now, 10)
+		require.NoError(t, err)
+		assert.Empty(t, listed)
+
+		var due []*entities.Transaction
+		err = unitOfWork.WithinTransaction(ctx, func(ctx context.Context) error {
+			due, err = repos.Transactions.LockPendingBefore(ctx, now.Add(time.Second), 10)
+			return err
+		})
+		require.NoError(t, err)
+		require.Len(t, due, 1)
+		assert.True(t, due[0].Pending)
+
+		require.NoError(t, repos.Transactions.MarkSettled(ctx, due[0].ID, now.Add(time.Hour)))
+		assert.ErrorIs(t, repos.Transactions.MarkSettled(ctx, due[0].ID, now.Add(time.Hour)), repositories.ErrNotFound)
+
+		settled, err := repos.Transactions.GetByTransactionID(ctx, "tx-pending")
+		require.NoError(t, err)
+		assert.False(t, settled.Pending)
+		require.NotNil(t, settled.SettledAt)
+		assert.True(t, now.Add(time.Hour).Equal(*settled.SettledAt))
+
+		pending, err = repos.Transactions.SumPending(ctx, user.ID)
+		require.NoError(t, err)
+		assert.True(t, pending.IsZero())
+	})
+
+	t.Run("balance changes count from settlement and cancellation", func(t *testing.T) {
+		other := &entities.User{Balance: decimal.Zero}
+		require.NoError(t, repos.Users.Create(ctx, other))
+		create := func(transactionID, state, amount string, pending bool) *entities.Transaction {
+			transaction := &entities.Transaction{
+				UserID:        other.ID,
+				TransactionID: transactionID,
+				State:         entities.TransactionState(state),
+				Amount:        decimal.RequireFromString(amount),
+				SourceType:    entities.SourceTypePayment,
+				CreatedAt:     now.Add(-2 * time.Hour),
+				Pending:       pending,
+			}
+			require.NoError(t, repos.Transactions.Create(ctx, transaction))
+			return transaction
+		}
+		settled := create("tx-settled-late", "win", "4.00", true)
+		cancelled := create("tx-cancelled-late", "lose", "1.50", false)
+		create("tx-early", "win", "2.00", false)
+		create("tx-unsettled", "win", "9.00", true)
+		require.NoError(t, repos.Transactions.MarkSettled(ctx, settled.ID, now))
+		require.NoError(t, repos.Transactions.MarkCancelled(ctx, cancelled.ID, now))
+
+		for since, want := range map[time.Duration]string{
+			-3 * time.Hour: "6",
+			-time.Hour:     "5.5",
+			time.Hour:      "0",
+		} {
+			change, err := repos.Transactions.NetChangeSince(ctx, other.ID, now.Add(since))
+			require.NoError(t, err)
+			assert.Equal(t, want, change.String(), "since %s", since)
+		}
+	})
+
+	t.Run("annotations are listed by target and by user", func(t *testing.T) {
+		annotation := &entities.Annotation{
+			TargetType: entities.AnnotationTargetTransaction,
+			TargetID:   "tx-1",
+			Author:     "support",
+			Note:       "checked",
+			CreatedAt:  now,
+		}
+		require.NoError(t, repos.Annotations.Create(ctx, annotation))
+
+		annotations, err := repos.Annotations.ListByTarget(ctx, entities.AnnotationTargetTransaction, "tx-1")
+		require.NoError(t, err)
+		require.Len(t, annotations, 1)
+		assert.Equal(t, "checked", annotations[0].Note)
+
+		require.NoError(t, repos.Annotations.Create(ctx, &entities.Annotation{
+			TargetType: entities.AnnotationTargetUser,
+			TargetID:   strconv.FormatUint(user.ID, 10),
+			Author:     "support",
+			Note:       "called",
+			CreatedAt:  now.Add(time.Minute),
+		}))
+		annotations, err = repos.Annotations.ListByUser(ctx, user.ID)
+		require.NoError(t, err)
+		require.Len(t, annotations, 2)
+		assert.Equal(t, "checked", annotations[0].Note)
+		assert.Equal(t, "called", annotations[1].Note)
+
+		annotations, err = repos.Annotations.ListByUser(ctx, user.ID+100)
+		require.NoError(t, err)
+		assert.Empty(t, annotations)
+	})
+
+	t.Run("most active users", func(t *testing.T) {
+		userIDs, err := repos.Transactions.MostActiveUsers(ctx, now.Add(-time.Hour), 10)
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{user.ID}, userIDs)
+
+		userIDs, err = repos.Transactions.MostActiveUsers(ctx, now.Add(time.Hour), 10)
+		require.NoError(t, err)
+		assert.Empty(t, userIDs)
+	})
+
+	t.Run("days are summarized in UTC", func(t *testing.T) {
+		days, err := repos.Transactions.SummarizeDays(ctx, user.ID, "", now.Add(-time.Hour), now.Add(time.Hour))
+		require.NoError(t, err)
+		require.NotEmpty(t, days)
+		// now is 12:00 CEST, i.e. 10:00 UTC on June 1st
+		assert.Equal(t, time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), days[0].Day)
+		assert.Equal(t, entities.SourceTypeGame, days[0].SourceType)
+		assert.Equal(t, entities.StateLose, days[0].State)
+		assert.Equal(t, 1, days[0].Count)
+		assert.Equal(t, "0.2", days[0].Amount.String())
+
+		days, err = repos.Transactions.SummarizeDays(ctx, user.ID, "", now.Add(time.Hour), now.Add(2*time.Hour))
+		require.NoError(t, err)
+		assert.Empty(t, days)
+	})
+
+	t.Run("days are summarized by business time", func(t *testing.T) {
+		late := &entities.User{Balance: decimal.Zero}
+		require.NoError(t, repos.Users.Create(ctx, late))
+		// Arrived at 10:00 UTC on June 1st, happened at 22:00 UTC the day before
+		occurredAt := now.Add(-12 * time.Hour)
+		require.NoError(t, repos.Transactions.Create(ctx, &entities.Transaction{
+			UserID:        late.ID,
+			TransactionID: "tx-late",
+			State:         entities.StateWin,
+			Amount:        decimal.RequireFromString("3.00"),
+			SourceType:    entities.SourceTypePayment,
+			OccurredAt:    &occurredAt,
+			CreatedAt:     now,
+		}))
+
+		days, err := repos.Transactions.SummarizeDays(ctx, late.ID, "", now.Add(-24*time.Hour), now.Add(time.Hour))
+		require.NoError(t, err)
+		require.Len(t, days, 1)
+		assert.Equal(t, time.Date(2025, 5, 31, 0, 0, 0, 0, time.UTC), days[0].Day)
+		assert.Equal(t, "3", days[0].Amount.String())
+
+		days, err = repos.Transactions.SummarizeDays(ctx, late.ID, "", now.Add(-time.Hour), now.Add(time.Hour))
+		require.NoError(t, err)
+		assert.Empty(t, days, "the range bounds the business time")
+	})
+
+	t.Run("connections are warmed up", func(t *testing.T) {
+		// More connections than the pool may open are not waited for
+		require.NoError(t, WarmConnections(ctx, db, 5))
+		assert.Equal(t, 4, db.Stats().Idle)
+	})
+}
@@ -0,0 +1,554 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/shopspring/decimal"
+)
+
+// SQLiteTransactionRepository implements the transaction repository interface
+// on SQLite
+type SQLiteTransactionRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteTransactionRepository creates a new SQLite transaction repository
+func NewSQLiteTransactionRepository(db *sql.DB) *SQLiteTransactionRepository {
+	return &SQLiteTransactionRepository{db: db}
+}
+
+// sqliteTransactionColumns is the column list matching scanTransactions
+const sqliteTransactionColumns = "id, user_id, transaction_id, state, amount, source_type, occurred_at, created_at, cancelled, cancelled_at, balance_after, COALESCE(receipt, CAST(id AS TEXT)), COALESCE(currency, ''), COALESCE(round_id, ''), type, COALESCE(reverses, ''), COALESCE(reversed_by, ''), COALESCE(transfer_id, ''), COALESCE(charged_for, ''), metadata, pending, settled_at"
+
+// Create creates a new transaction. A zero ID is allocated by AUTOINCREMENT,
+// and an empty receipt defaults to the decimal ID. An allocated ID is only
+// known once the row is inserted, so its default receipt is set by a second
+// statement, in the same unit of work as the insert. A transaction ID already
+// in use is rejected with ErrConflict by the unique index, the insert doing
+// nothing.
+func (r *SQLiteTransactionRepository) Create(ctx context.Context, transaction *entities.Transaction) error {
+	query := `
+		INSERT INTO transactions (id, user_id, transaction_id, state, amount, source_type, occurred_at, created_at, balance_after, receipt, currency, round_id, type, reverses, transfer_id, charged_for, metadata, pending)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''),
+			COALESCE(NULLIF(?, ''), 'transaction'), NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), ?, ?)
+		ON CONFLICT (transaction_id) DO NOTHING
+	`
+
+	receipt := transaction.Receipt
+	if receipt == "" && transaction.ID != 0 {
+		receipt = strconv.FormatUint(transaction.ID, 10)
+	}
+
+	result, err := Executor(ctx, r.db).ExecContext(
+		ctx,
+		query,
+		explicitID(transaction.ID),
+		transaction.UserID,
+		transaction.TransactionID,
+		transaction.State,
+		transaction.Amount,
+		transaction.SourceType,
+		sqliteTime(transaction.OccurredAt),
+		sqliteTime(&transaction.CreatedAt),
+		transaction.BalanceAfter,
+		receipt,
+		transaction.Currency,
+		transaction.RoundID,
+		transaction.Type,
+		transaction.Reverses,
+		transaction.TransferID,
+		transaction.ChargedFor,
+		encodeMetadata(transaction.Metadata),
+		transaction.Pending,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create transaction: %w", classify(err))
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to create transaction: %w", classify(err))
+	}
+	if inserted == 0 {
+		return fmt.Errorf("transaction %q: %w", transaction.TransactionID, repositories.ErrConflict)
+	}
+
+	if transaction.ID == 0 {
+		id, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to get transaction ID: %w", classify(err))
+		}
+		transaction.ID = uint64(id)
+	}
+	if receipt == "" {
+		receipt = strconv.FormatUint(transaction.ID, 10)
+		query := "UPDATE transactions SET receipt = ? WHERE id = ?"
+		if _, err := Executor(ctx, r.db).ExecContext(ctx, query, receipt, transaction.ID); err != nil {
+			return fmt.Errorf("failed to set transaction receipt: %w", classify(err))
+		}
+	}
+	transaction.Receipt = receipt
+
+	return nil
+}
+
+// ExistsByTransactionID checks if a transaction with the given ID exists
+func (r *SQLiteTransactionRepository) ExistsByTransactionID(ctx context.Context, transactionID string) (bool, error) {
+	query := "SELECT EXISTS(SELECT 1 FROM transactions WHERE transaction_id = ?)"
+
+	var exists bool
+	err := Executor(ctx, r.db).QueryRowContext(ctx, query, transactionID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check transaction existence: %w", classify(err))
+	}
+
+	return exists, nil
+}
+
+// GetByTransactionID retrieves a transaction by its external ID
+func (r *SQLiteTransactionRepository) GetByTransactionID(ctx context.Context, transactionID string) (*entities.Transaction, error) {
+	query := "SELECT " + sqliteTransactionColumns + " FROM transactions WHERE transaction_id = ?"
+	return r.getTransaction(ctx, query, transactionID)
+}
+
+// GetByTransactionIDForUpdate retrieves a transaction by its external ID. The
+// ambient unit of work holds the database write lock.
+func (r *SQLiteTransactionRepository) GetByTransactionIDForUpdate(ctx context.Context, transactionID string) (*entities.Transaction, error) {
+	query := "SELECT " + sqliteTransactionColumns + " FROM transactions WHERE transaction_id = ?"
+	return r.getTransaction(ctx, query, transactionID)
+}
+
+func (r *SQLiteTransactionRepository) getTransaction(ctx context.Context, query, transactionID string) (*entities.Transaction, error) {
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction: %w", classify(err))
+	}
+	defer rows.Close()
+
+	transactions, err := scanTransactions(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(transactions) == 0 {
+		return nil, fmt.Errorf("transaction %q: %w", transactionID, repositories.ErrNotFound)
+	}
+
+	return transactions[0], nil
+}
+
+// GetByUserID retrieves all transactions for a user
+func (r *SQLiteTransactionRepository) GetByUserID(ctx context.Context, userID uint64) ([]*entities.Transaction, error) {
+	query := `
+		SELECT ` + sqliteTransactionColumns + `
+		FROM transactions
+		WHERE user_id = ?
+		ORDER BY created_at DESC
+	`
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transactions: %w", classify(err))
+	}
+	defer rows.Close()
+
+	return scanTransactions(rows)
+}
+
+// sqliteMetadata extracts the value of a metadata key by its JSON path
+func sqliteMetadata(key string) (string, any) {
+	return "json_extract(metadata, %s)", metadataPath(key)
+}
+
+// Search returns a page of transactions matching the filter, newest first
+func (r *SQLiteTransactionRepository) Search(
+	ctx context.Context,
+	filter repositories.TransactionFilter,
+) ([]*entities.Transaction, int, error) {
+	filter.From, filter.To = sqliteTime(filter.From), sqliteTime(filter.To)
+	if filter.After != nil {
+		after := *filter.After
+		after.CreatedAt = after.CreatedAt.UTC()
+		filter.After = &after
+	}
+	countWhere, countArgs := compileTransactionFilter(countFilter(filter), func(int) string { return "?" }, `LIKE %s ESCAPE '\'`, sqliteMetadata)
+	where, args := compileTransactionFilter(filter, func(int) string { return "?" }, `LIKE %s ESCAPE '\'`, sqliteMetadata)
+
+	var total int
+	countQuery := "SELECT COUNT(*) FROM transactions" + countWhere
+	if err := Executor(ctx, r.db).QueryRowContext(ctx, countQuery, countArgs...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count transactions: %w", classify(err))
+	}
+
+	query := `
+		SELECT ` + sqliteTransactionColumns + `
+		FROM transactions` + where + `
+		ORDER BY created_at DESC, id DESC
+		LIMIT ? OFFSET ?
+	`
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search transactions: %w", classify(err))
+	}
+	defer rows.Close()
+
+	transactions, err := scanTransactions(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return transactions, total, nil
+}
+
+// NetChangeSince returns how much a user's base currency transactions moved
+// the balance at or after since, counting settled transactions from when they
+// were settled and taking cancelled ones back out from when they were
+// cancelled. SQLite sums decimals as floating point numbers, so the sum is
+// rounded back to cents.
+func (r *SQLiteTransactionRepository) NetChangeSince(ctx context.Context, userID uint64, since time.Time) (decimal.Decimal, error) {
+	query := `
+		SELECT COALESCE(SUM(CASE WHEN state = 'win' THEN amount ELSE -amount END * (
+			CASE WHEN COALESCE(settled_at, created_at) >= ? THEN 1 ELSE 0 END -
+			CASE WHEN cancelled AND COALESCE(cancelled_at, settled_at, created_at) >= ? THEN 1 ELSE 0 END
+		)), 0)
+		FROM transactions
+		WHERE user_id = ? AND pending = FALSE AND currency IS NULL
+			AND (COALESCE(settled_at, created_at) >= ? OR cancelled_at >= ?)
+	`
+
+	at := sqliteTime(&since)
+	var net decimal.Decimal
+	err := Executor(ctx, r.db).QueryRowContext(ctx, query, at, at, userID, at, at).Scan(&net)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("failed to get net change: %w", classify(err))
+	}
+
+	return net.Round(2), nil
+}
+
+// VelocitySince totals a user's uncancelled standard transactions created at
+// or after since. Losses are rounded back to cents like in NetChangeSince.
+func (r *SQLiteTransactionRepository) VelocitySince(ctx context.Context, userID uint64, since time.Time) (repositories.Velocity, error) {
+	query := `
+		SELECT
+			COUNT(*),
+			COALESCE(SUM(CASE WHEN state = 'lose' AND currency IS NULL THEN amount END), 0)
+		FROM transactions
+		WHERE user_id = ? AND created_at >= ? AND cancelled = FALSE AND type = 'transaction'
+	`
+
+	var velocity repositories.Velocity
+	err := Executor(ctx, r.db).QueryRowContext(ctx, query, userID, sqliteTime(&since)).Scan(&velocity.Transactions, &velocity.Losses)
+	if err != nil {
+		return repositories.Velocity{}, fmt.Errorf("failed to get velocity: %w", classify(err))
+	}
+
+	velocity.Losses = velocity.Losses.Round(2)
+
+	return velocity, nil
+}
+
+// MostActiveUsers returns the IDs of up to limit users with the most
+// transactions created at or after since, most active first
+func (r *SQLiteTransactionRepository) MostActiveUsers(ctx context.Context, since time.Time, limit int) ([]uint64, error) {
+	query := `
+		SELECT user_id
+		FROM transactions
+		WHERE created_at >= ?
+		GROUP BY user_id
+		ORDER BY COUNT(*) DESC, user_id
+		LIMIT ?
+	`
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, sqliteTime(&since), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get most active users: %w", classify(err))
+	}
+	defer rows.Close()
+
+	var userIDs []uint64
+	for rows.Next() {
+		var userID uint64
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("failed to scan user ID: %w", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate users: %w", classify(err))
+	}
+
+	return userIDs, nil
+}
+
+// SummarizeRound totals the user's transactions of a round in a wallet
+// currency, empty for the base currency. Totals are rounded back to cents
+// like in NetChangeSince.
+func (r *SQLiteTransactionRepository) SummarizeRound(
+	ctx context.Context,
+	userID uint64,
+	roundID, currency string,
+) (repositories.RoundTotals, error) {
+	query := `
+		SELECT
+			COUNT(CASE WHEN cancelled = FALSE AND type <> 'fee' THEN 1 END),
+			COUNT(CASE WHEN cancelled = TRUE THEN 1 END),
+			COALESCE(SUM(CASE WHEN cancelled = FALSE AND type <> 'fee' AND state = 'lose' THEN amount END), 0),
+			COALESCE(SUM(CASE WHEN cancelled = FALSE AND type <> 'fee' AND state = 'win' THEN amount END), 0),
+			COALESCE(SUM(CASE WHEN cancelled = FALSE AND type = 'fee' THEN amount END), 0)
+		FROM transactions
+		WHERE user_id = ? AND round_id = ? AND currency IS NULLIF(?, '')
+	`
+
+	var totals repositories.RoundTotals
+	err := Executor(ctx, r.db).QueryRowContext(ctx, query, userID, roundID, currency).
+		Scan(&totals.Transactions, &totals.Cancelled, &totals.Bets, &totals.Wins, &totals.Fees)
+	if err != nil {
+		return repositories.RoundTotals{}, fmt.Errorf("failed to summarize round: %w", classify(err))
+	}
+
+	totals.Bets, totals.Wins, totals.Fees = totals.Bets.Round(2), totals.Wins.Round(2), totals.Fees.Round(2)
+
+	return totals, nil
+}
+
+// SummarizeDays totals the user's uncancelled non-fee transactions in a wallet
+// currency that occurred in [from, to) by UTC day, source type and state.
+// Transactions without a business time count when they were created. Days
+// are cut from the stored UTC text and totals rounded back to cents like in
+// SummarizeRound.
+func (r *SQLiteTransactionRepository) SummarizeDays(
+	ctx context.Context,
+	userID uint64,
+	currency string,
+	from, to time.Time,
+) ([]repositories.DayTotals, error) {
+	query := `
+		SELECT substr(COALESCE(occurred_at, created_at), 1, 10), source_type, state, COUNT(*), COALESCE(SUM(amount), 0)
+		FROM transactions
+		WHERE user_id = ? AND currency IS NULLIF(?, '')
+			AND COALESCE(occurred_at, created_at) >= ? AND COALESCE(occurred_at, created_at) < ?
+			AND cancelled = FALSE AND type <> 'fee'
+		GROUP BY 1, 2, 3
+		ORDER BY 1, 2, 3
+	`
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, userID, currency, sqliteTime(&from), sqliteTime(&to))
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize days: %w", classify(err))
+	}
+	defer rows.Close()
+
+	var days []repositories.DayTotals
+	for rows.Next() {
+		var day repositories.DayTotals
+		var date string
+		if err := rows.Scan(&date, &day.SourceType, &day.State, &day.Count, &day.Amount); err != nil {
+			return nil, fmt.Errorf("failed to scan day totals: %w", err)
+		}
+		if day.Day, err = time.Parse(time.DateOnly, date); err != nil {
+			return nil, fmt.Errorf("failed to parse day %q: %w", date, err)
+		}
+		day.Amount = day.Amount.Round(2)
+		days = append(days, day)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate day totals: %w", classify(err))
+	}
+
+	return days, nil
+}
+
+// LockLatestOddUncancelled returns the newest uncancelled odd-ID transactions
+// that are neither refunds, refunded, transfer legs, fees, adjustments nor
+// pending. The ambient unit of work holds the database write lock.
+func (r *SQLiteTransactionRepository) LockLatestOddUncancelled(ctx context.Context, limit int) ([]*entities.Transaction, error) {
+	query := `
+		SELECT ` + sqliteTransactionColumns + `
+		FROM transactions
+		WHERE cancelled = FALSE AND pending = FALSE AND id % 2 = 1 AND type NOT IN ('fee', 'adjustment') AND reverses IS NULL AND reversed_by IS NULL AND transfer_id IS NULL
+		ORDER BY id DESC
+		LIMIT ?
+	`
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest odd transactions: %w", classify(err))
+	}
+	defer rows.Close()
+
+	return scanTransactions(rows)
+}
+
+// CheckUniqueIDs checks the transactions created in [from, to) for transaction
+// IDs recorded more than once, and whether a unique index on transaction IDs
+// alone enforces them
+func (r *SQLiteTransactionRepository) CheckUniqueIDs(ctx context.Context, from, to time.Time, limit int) (*repositories.UniquenessCheck, error) {
+	var check repositories.UniquenessCheck
+
+	err := Executor(ctx, r.db).QueryRowContext(ctx, `
+		SELECT COUNT(*), COUNT(DISTINCT transaction_id)
+		FROM transactions
+		WHERE created_at >= ? AND created_at < ?
+	`, sqliteTime(&from), sqliteTime(&to)).Scan(&check.Transactions, &check.DistinctIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count transactions: %w", classify(err))
+	}
+
+	err = Executor(ctx, r.db).QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1
+			FROM pragma_index_list('transactions') AS l
+			WHERE l."unique" AND NOT l.partial
+				AND (SELECT COUNT(*) FROM pragma_index_info(l.name)) = 1
+				AND (SELECT name FROM pragma_index_info(l.name)) = 'transaction_id'
+		)
+	`).Scan(&check.Enforced)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check the transaction ID index: %w", classify(err))
+	}
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, `
+		SELECT transaction_id, COUNT(*)
+		FROM transactions
+		WHERE transaction_id IN (
+			SELECT transaction_id FROM transactions WHERE created_at >= ? AND created_at < ?
+		)
+		GROUP BY transaction_id
+		HAVING COUNT(*) > 1
+		ORDER BY transaction_id
+		LIMIT ?
+	`, sqliteTime(&from), sqliteTime(&to), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find duplicate transaction IDs: %w", classify(err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var duplicate entities.DuplicateTransactionID
+		if err := rows.Scan(&duplicate.TransactionID, &duplicate.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan duplicate transaction ID: %w", classify(err))
+		}
+		check.Duplicates = append(check.Duplicates, duplicate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating duplicate transaction IDs: %w", classify(err))
+	}
+
+	return &check, nil
+}
+
+// MarkCancelled flags a transaction as cancelled
+func (r *SQLiteTransactionRepository) MarkCancelled(ctx context.Context, id uint64, cancelledAt time.Time) error {
+	query := "UPDATE transactions SET cancelled = TRUE, cancelled_at = ? WHERE id = ? AND cancelled = FALSE"
+
+	result, err := Executor(ctx, r.db).ExecContext(ctx, query, sqliteTime(&cancelledAt), id)
+	if err != nil {
+		return fmt.Errorf("failed to cancel transaction: %w", classify(err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", classify(err))
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("transaction %d: %w", id, repositories.ErrNotFound)
+	}
+
+	return nil
+}
+
+// MarkReversed links a transaction to the refund reversing it
+func (r *SQLiteTransactionRepository) MarkReversed(ctx context.Context, id uint64, reversedBy string) error {
+	query := "UPDATE transactions SET reversed_by = ? WHERE id = ? AND reversed_by IS NULL"
+
+	result, err := Executor(ctx, r.db).ExecContext(ctx, query, reversedBy, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark transaction reversed: %w", classify(err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", classify(err))
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("transaction %d: %w", id, repositories.ErrNotFound)
+	}
+
+	return nil
+}
+
+// SumPending returns the total of a user's uncancelled base currency
+// transactions awaiting settlement, rounded back to cents like in
+// NetChangeSince
+func (r *SQLiteTransactionRepository) SumPending(ctx context.Context, userID uint64) (decimal.Decimal, error) {
+	query := `
+		SELECT COALESCE(SUM(amount), 0)
+		FROM transactions
+		WHERE user_id = ? AND pending = TRUE AND cancelled = FALSE AND currency IS NULL
+	`
+
+	var pending decimal.Decimal
+	err := Executor(ctx, r.db).QueryRowContext(ctx, query, userID).Scan(&pending)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("failed to get pending total: %w", classify(err))
+	}
+
+	return pending.Round(2), nil
+}
+
+// ListPendingBefore returns up to limit of the oldest pending transactions
+// created before before
+func (r *SQLiteTransactionRepository) ListPendingBefore(ctx context.Context, before time.Time, limit int) ([]*entities.Transaction, error) {
+	query := `
+		SELECT ` + sqliteTransactionColumns + `
+		FROM transactions
+		WHERE pending = TRUE AND cancelled = FALSE AND created_at < ?
+		ORDER BY created_at, id
+		LIMIT ?
+	`
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, sqliteTime(&before), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending transactions: %w", classify(err))
+	}
+	defer rows.Close()
+
+	return scanTransactions(rows)
+}
+
+// LockPendingBefore is ListPendingBefore. The ambient unit of work holds the
+// database write lock.
+func (r *SQLiteTransactionRepository) LockPendingBefore(ctx context.Context, before time.Time, limit int) ([]*entities.Transaction, error) {
+	return r.ListPendingBefore(ctx, before, limit)
+}
+
+// MarkSettled records the settlement of a pending transaction
+func (r *SQLiteTransactionRepository) MarkSettled(ctx context.Context, id uint64, settledAt time.Time) error {
+	query := "UPDATE transactions SET pending = FALSE, settled_at = ? WHERE id = ? AND pending = TRUE"
+
+	result, err := Executor(ctx, r.db).ExecContext(ctx, query, sqliteTime(&settledAt), id)
+	if err != nil {
+		return fmt.Errorf("failed to settle transaction: %w", classify(err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", classify(err))
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("transaction %d: %w", id, repositories.ErrNotFound)
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/shopspring/decimal"
+)
+
+// SQLiteWalletRepository implements the wallet repository interface on SQLite
+type SQLiteWalletRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteWalletRepository creates a new SQLite wallet repository
+func NewSQLiteWalletRepository(db *sql.DB) *SQLiteWalletRepository {
+	return &SQLiteWalletRepository{db: db}
+}
+
+// GetForUpdate retrieves the user's wallet in currency, creating an empty one
+// first if needed. The ambient transaction holds the database write lock.
+func (r *SQLiteWalletRepository) GetForUpdate(ctx context.Context, userID uint64, currency string) (*entities.Wallet, error) {
+	insert := "INSERT INTO wallets (user_id, currency) VALUES (?, ?) ON CONFLICT (user_id, currency) DO NOTHING"
+	if _, err := Executor(ctx, r.db).ExecContext(ctx, insert, userID, currency); err != nil {
+		return nil, fmt.Errorf("failed to create wallet: %w", classify(err))
+	}
+
+	query := "SELECT balance FROM wallets WHERE user_id = ? AND currency = ?"
+
+	wallet := entities.Wallet{UserID: userID, Currency: currency}
+	if err := Executor(ctx, r.db).QueryRowContext(ctx, query, userID, currency).Scan(&wallet.Balance); err != nil {
+		return nil, fmt.Errorf("failed to get wallet: %w", classify(err))
+	}
+
+	return &wallet, nil
+}
+
+// UpdateBalance updates the balance of the user's wallet in currency
+func (r *SQLiteWalletRepository) UpdateBalance(ctx context.Context, userID uint64, currency string, newBalance decimal.Decimal) error {
+	query := "UPDATE wallets SET balance = ?, updated_at = CURRENT_TIMESTAMP WHERE user_id = ? AND currency = ?"
+
+	result, err := Executor(ctx, r.db).ExecContext(ctx, query, newBalance, userID, currency)
+	if err != nil {
+		return fmt.Errorf("failed to update wallet balance: %w", classify(err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", classify(err))
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("wallet %s of user %d: %w", currency, userID, repositories.ErrNotFound)
+	}
+
+	return nil
+}
+
+// ListByUser retrieves the user's wallets ordered by currency
+func (r *SQLiteWalletRepository) ListByUser(ctx context.Context, userID uint64) ([]*entities.Wallet, error) {
+	query := "SELECT currency, balance FROM wallets WHERE user_id = ? ORDER BY currency"
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list wallets: %w", classify(err))
+	}
+	defer rows.Close()
+
+	var wallets []*entities.Wallet
+	for rows.Next() {
+		wallet := entities.Wallet{UserID: userID}
+		if err := rows.Scan(&wallet.Currency, &wallet.Balance); err != nil {
+			return nil, fmt.Errorf("failed to scan wallet: %w", classify(err))
+		}
+		wallets = append(wallets, &wallet)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list wallets: %w", classify(err))
+	}
+
+	return wallets, nil
+}
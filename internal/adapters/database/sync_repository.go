@@ -0,0 +1,64 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"transaction-service/internal/domain/repositories"
+)
+
+// SyncRepository implements repositories.SyncRepository on the sync_version
+// column, which a trigger sets to the ID of the database transaction
+// inserting or updating a row
+type SyncRepository struct {
+	db *sql.DB
+}
+
+// NewSyncRepository creates a new SyncRepository
+func NewSyncRepository(db *sql.DB) *SyncRepository {
+	return &SyncRepository{db: db}
+}
+
+// ChangesSince returns up to limit transactions changed after cursor. Only
+// changes made by database transactions older than every one still in
+// progress are returned: a later database transaction gets a higher ID, so
+// no change can appear behind the cursor once it was read.
+func (r *SyncRepository) ChangesSince(
+	ctx context.Context,
+	cursor repositories.SyncCursor,
+	limit int,
+) ([]repositories.TransactionChange, error) {
+	query := `
+		SELECT ` + transactionColumns + `, sync_version
+		FROM transactions
+		WHERE (sync_version, id) > ($1, $2)
+			AND sync_version < pg_snapshot_xmin(pg_current_snapshot())::TEXT::BIGINT
+		ORDER BY sync_version, id
+		LIMIT $3
+	`
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, int64(cursor.Version), int64(cursor.ID), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction changes: %w", classify(err))
+	}
+	defer rows.Close()
+
+	var changes []repositories.TransactionChange
+	for rows.Next() {
+		var version uint64
+		transaction, err := scanTransaction(rows, &version)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, repositories.TransactionChange{
+			Transaction: transaction,
+			Cursor:      repositories.SyncCursor{Version: version, ID: transaction.ID},
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate transaction changes: %w", classify(err))
+	}
+
+	return changes, nil
+}
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"transaction-service/internal/domain/entities"
+)
+
+// EnsureSystemAccounts creates the missing system accounts, each as a user
+// with a zero balance, and returns all of them. It holds the seed lock, so
+// that replicas booting at the same time create every account once and
+// seeded users keep their IDs.
+func EnsureSystemAccounts(ctx context.Context, db *sql.DB) ([]*entities.SystemAccount, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin system account transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Serialize with seeding across replicas; released automatically on commit
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", seedLockKey); err != nil {
+		return nil, fmt.Errorf("failed to acquire seed lock: %w", err)
+	}
+
+	existing, err := listSystemAccounts(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[entities.SystemAccountKind]bool, len(existing))
+	for _, account := range existing {
+		found[account.Kind] = true
+	}
+
+	for _, kind := range entities.SystemAccountKinds {
+		if found[kind] {
+			continue
+		}
+		query := `
+			WITH account AS (INSERT INTO users (balance) VALUES (0) RETURNING id)
+			INSERT INTO system_accounts (kind, user_id) SELECT $1, id FROM account
+		`
+		if _, err := tx.ExecContext(ctx, query, kind); err != nil {
+			return nil, fmt.Errorf("failed to create %s account: %w", kind, classify(err))
+		}
+	}
+
+	accounts, err := listSystemAccounts(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit system account transaction: %w", err)
+	}
+
+	return accounts, nil
+}
+
+// LoadSystemAccounts returns the system accounts without creating missing
+// ones, for the deployments that do not write
+func LoadSystemAccounts(ctx context.Context, db *sql.DB) ([]*entities.SystemAccount, error) {
+	return listSystemAccounts(ctx, db)
+}
+
+// listSystemAccounts returns the system accounts ordered by user ID
+func listSystemAccounts(ctx context.Context, exec Querier) ([]*entities.SystemAccount, error) {
+	rows, err := exec.QueryContext(ctx, `SELECT kind, user_id, created_at FROM system_accounts ORDER BY user_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list system accounts: %w", classify(err))
+	}
+	defer rows.Close()
+
+	var accounts []*entities.SystemAccount
+	for rows.Next() {
+		var account entities.SystemAccount
+		if err := rows.Scan(&account.Kind, &account.UserID, &account.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan system account: %w", err)
+		}
+		accounts = append(accounts, &account)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list system accounts: %w", classify(err))
+	}
+
+	return accounts, nil
+}
@@ -0,0 +1,63 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"time"
+
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+type noQueryTimeoutKey struct{}
+
+// withoutQueryTimeout lets the statements run with ctx take as long as ctx
+// allows, such as the migrations
+func withoutQueryTimeout(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noQueryTimeoutKey{}, true)
+}
+
+// timeoutConn bounds every statement run on a PostgreSQL connection by a
+// deadline of its own, so that a query stays bounded when the server cannot
+// cancel it, such as when the network is down. Reading the rows of a query
+// counts towards its deadline.
+type timeoutConn struct {
+	*stdlib.Conn
+	timeout time.Duration
+}
+
+func (c *timeoutConn) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if skip, _ := ctx.Value(noQueryTimeoutKey{}).(bool); skip {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+// ExecContext implements driver.ExecerContext
+func (c *timeoutConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	return c.Conn.ExecContext(ctx, query, args)
+}
+
+// QueryContext implements driver.QueryerContext
+func (c *timeoutConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	rows, err := c.Conn.QueryContext(ctx, query, args)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &timeoutRows{Rows: rows.(*stdlib.Rows), cancel: cancel}, nil
+}
+
+// timeoutRows releases the deadline of a query once its rows are closed
+type timeoutRows struct {
+	*stdlib.Rows
+	cancel context.CancelFunc
+}
+
+func (r *timeoutRows) Close() error {
+	err := r.Rows.Close()
+	r.cancel()
+	return err
+}
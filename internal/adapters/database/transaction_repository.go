@@ -3,9 +3,14 @@ package database
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
 
 	"github.com/shopspring/decimal"
 )
@@ -20,27 +25,56 @@ func NewTransactionRepository(db *sql.DB) *TransactionRepository {
 	return &TransactionRepository{db: db}
 }
 
-// Create creates a new transaction
+// Create creates a new transaction. A zero ID is allocated from the
+// sequence, and an empty receipt defaults to the decimal ID. A transaction ID
+// already in use is rejected with ErrConflict by the unique index rather than
+// a prior existence check, which concurrent requests could both pass; the
+// insert does nothing instead of failing, so the unit of work stays usable.
 func (r *TransactionRepository) Create(ctx context.Context, transaction *entities.Transaction) error {
 	query := `
-		INSERT INTO transactions (user_id, transaction_id, state, amount, source_type, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id
+		WITH new_id AS (
+			SELECT COALESCE($1::BIGINT, nextval(pg_get_serial_sequence('transactions', 'id'))) AS id
+		)
+		INSERT INTO transactions (id, user_id, transaction_id, state, amount, source_type, occurred_at, created_at, balance_after, receipt, currency, round_id, type, reverses, transfer_id, charged_for, metadata, pending)
+		SELECT id, $2, $3, $4, $5, $6, $7, $8, $9, COALESCE($10, id::TEXT), NULLIF($11, ''), NULLIF($12, ''),
+			COALESCE(NULLIF($13, ''), 'transaction'), NULLIF($14, ''), NULLIF($15, ''), NULLIF($16, ''), $17::JSONB, $18 FROM new_id
+		ON CONFLICT (transaction_id) DO NOTHING
+		RETURNING id, receipt
 	`
 
-	err := r.db.QueryRowContext(
+	var receipt sql.NullString
+	if transaction.Receipt != "" {
+		receipt = sql.NullString{String: transaction.Receipt, Valid: true}
+	}
+
+	err := Executor(ctx, r.db).QueryRowContext(
 		ctx,
 		query,
+		explicitID(transaction.ID),
 		transaction.UserID,
 		transaction.TransactionID,
 		transaction.State,
 		transaction.Amount,
 		transaction.SourceType,
+		transaction.OccurredAt,
 		transaction.CreatedAt,
-	).Scan(&transaction.ID)
+		transaction.BalanceAfter,
+		receipt,
+		transaction.Currency,
+		transaction.RoundID,
+		transaction.Type,
+		transaction.Reverses,
+		transaction.TransferID,
+		transaction.ChargedFor,
+		encodeMetadata(transaction.Metadata),
+		transaction.Pending,
+	).Scan(&transaction.ID, &transaction.Receipt)
 
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("transaction %q: %w", transaction.TransactionID, repositories.ErrConflict)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to create transaction: %w", err)
+		return fmt.Errorf("failed to create transaction: %w", classify(err))
 	}
 
 	return nil
@@ -51,59 +85,647 @@ func (r *TransactionRepository) ExistsByTransactionID(ctx context.Context, trans
 	query := "SELECT EXISTS(SELECT 1 FROM transactions WHERE transaction_id = $1)"
 
 	var exists bool
-	err := r.db.QueryRowContext(ctx, query, transactionID).Scan(&exists)
+	err := Executor(ctx, r.db).QueryRowContext(ctx, query, transactionID).Scan(&exists)
 	if err != nil {
-		return false, fmt.Errorf("failed to check transaction existence: %w", err)
+		return false, fmt.Errorf("failed to check transaction existence: %w", classify(err))
 	}
 
 	return exists, nil
 }
 
+// transactionColumns is the column list matching scanTransactions
+const transactionColumns = "id, user_id, transaction_id, state, amount, source_type, occurred_at, created_at, cancelled, cancelled_at, balance_after, COALESCE(receipt, id::TEXT), COALESCE(currency, ''), COALESCE(round_id, ''), type, COALESCE(reverses, ''), COALESCE(reversed_by, ''), COALESCE(transfer_id, ''), COALESCE(charged_for, ''), metadata, pending, settled_at"
+
+// GetByTransactionID retrieves a transaction by its external ID
+func (r *TransactionRepository) GetByTransactionID(ctx context.Context, transactionID string) (*entities.Transaction, error) {
+	query := "SELECT " + transactionColumns + " FROM transactions WHERE transaction_id = $1"
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction: %w", classify(err))
+	}
+	defer rows.Close()
+
+	transactions, err := scanTransactions(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(transactions) == 0 {
+		return nil, fmt.Errorf("transaction %q: %w", transactionID, repositories.ErrNotFound)
+	}
+
+	return transactions[0], nil
+}
+
+// GetByTransactionIDForUpdate retrieves a transaction by its external ID and
+// locks it until the ambient unit of work ends
+func (r *TransactionRepository) GetByTransactionIDForUpdate(ctx context.Context, transactionID string) (*entities.Transaction, error) {
+	query := "SELECT " + transactionColumns + " FROM transactions WHERE transaction_id = $1 FOR UPDATE"
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction: %w", classify(err))
+	}
+	defer rows.Close()
+
+	transactions, err := scanTransactions(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(transactions) == 0 {
+		return nil, fmt.Errorf("transaction %q: %w", transactionID, repositories.ErrNotFound)
+	}
+
+	return transactions[0], nil
+}
+
 // GetByUserID retrieves all transactions for a user
 func (r *TransactionRepository) GetByUserID(ctx context.Context, userID uint64) ([]*entities.Transaction, error) {
 	query := `
-		SELECT id, user_id, transaction_id, state, amount, source_type, created_at
+		SELECT ` + transactionColumns + `
 		FROM transactions
 		WHERE user_id = $1
 		ORDER BY created_at DESC
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, userID)
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get transactions: %w", err)
+		return nil, fmt.Errorf("failed to get transactions: %w", classify(err))
 	}
 	defer rows.Close()
 
+	return scanTransactions(rows)
+}
+
+// Search returns a page of transactions matching the filter, newest first
+func (r *TransactionRepository) Search(
+	ctx context.Context,
+	filter repositories.TransactionFilter,
+) ([]*entities.Transaction, int, error) {
+	countWhere, countArgs := buildTransactionFilter(countFilter(filter))
+	where, args := buildTransactionFilter(filter)
+
+	var total int
+	countQuery := "SELECT COUNT(*) FROM transactions" + countWhere
+	if err := Executor(ctx, r.db).QueryRowContext(ctx, countQuery, countArgs...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count transactions: %w", classify(err))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM transactions%s
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d OFFSET $%d
+	`, transactionColumns, where, len(args)+1, len(args)+2)
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search transactions: %w", classify(err))
+	}
+	defer rows.Close()
+
+	transactions, err := scanTransactions(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return transactions, total, nil
+}
+
+// countFilter drops the cursor of filter, so that the total of a search
+// counts every match whichever page is read
+func countFilter(filter repositories.TransactionFilter) repositories.TransactionFilter {
+	filter.After = nil
+	return filter
+}
+
+// buildTransactionFilter compiles a filter into a parameterised WHERE clause
+func buildTransactionFilter(filter repositories.TransactionFilter) (string, []any) {
+	return compileTransactionFilter(filter, func(n int) string {
+		return fmt.Sprintf("$%d", n)
+	}, `LIKE %s ESCAPE '\'`, func(key string) (string, any) {
+		return "metadata ->> %s::TEXT", key
+	})
+}
+
+// metadataLookup returns the format of the expression extracting the value of
+// a metadata key, NULL when it is missing, and the argument of the format
+type metadataLookup func(key string) (format string, arg any)
+
+// metadataPath is the JSON path of a metadata key. Keys are restricted to
+// characters that need no escaping between the quotes.
+func metadataPath(key string) string {
+	return `$."` + key + `"`
+}
+
+// compileTransactionFilter compiles a filter into a WHERE clause, writing the
+// placeholder of the nth argument with placeholder, prefix matches with the
+// like format and metadata keys with metadata
+func compileTransactionFilter(
+	filter repositories.TransactionFilter,
+	placeholder func(n int) string,
+	like string,
+	metadata metadataLookup,
+) (string, []any) {
+	var conditions []string
+	var args []any
+
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, placeholder(len(args))))
+	}
+
+	if filter.UserID != 0 {
+		add("user_id = %s", filter.UserID)
+	}
+	if filter.TransactionIDPrefix != "" {
+		add("transaction_id "+like, escapeLike(filter.TransactionIDPrefix)+"%")
+	}
+	if filter.MinAmount != nil {
+		add("amount >= %s", *filter.MinAmount)
+	}
+	if filter.MaxAmount != nil {
+		add("amount <= %s", *filter.MaxAmount)
+	}
+	if filter.SourceType != "" {
+		add("source_type = %s", filter.SourceType)
+	}
+	if filter.State != "" {
+		add("state = %s", filter.State)
+	}
+	if filter.From != nil {
+		add("created_at >= %s", *filter.From)
+	}
+	if filter.To != nil {
+		add("created_at < %s", *filter.To)
+	}
+	if filter.Cancelled != nil {
+		add("cancelled = %s", *filter.Cancelled)
+	}
+	if filter.MetadataKey != "" {
+		format, arg := metadata(filter.MetadataKey)
+		if filter.MetadataValue == nil {
+			add(format+" IS NOT NULL", arg)
+		} else {
+			args = append(args, arg, *filter.MetadataValue)
+			conditions = append(conditions, fmt.Sprintf(format+" = %s", placeholder(len(args)-1), placeholder(len(args))))
+		}
+	}
+	if filter.After != nil {
+		// The bound on created_at alone seeks the index on every database,
+		// unlike a row comparison; the rest only sorts out the ties
+		args = append(args, filter.After.CreatedAt, filter.After.CreatedAt, filter.After.ID)
+		conditions = append(conditions, fmt.Sprintf("created_at <= %s AND (created_at < %s OR id < %s)",
+			placeholder(len(args)-2), placeholder(len(args)-1), placeholder(len(args))))
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// escapeLike escapes the LIKE wildcards in a user-supplied value
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+// scanTransactions reads all rows selected with transactionColumns
+func scanTransactions(rows *sql.Rows) ([]*entities.Transaction, error) {
 	var transactions []*entities.Transaction
 	for rows.Next() {
-		var transaction entities.Transaction
-		var amountStr string
-
-		err := rows.Scan(
-			&transaction.ID,
-			&transaction.UserID,
-			&transaction.TransactionID,
-			&transaction.State,
-			&amountStr,
-			&transaction.SourceType,
-			&transaction.CreatedAt,
-		)
+		transaction, err := scanTransaction(rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+			return nil, err
 		}
+		transactions = append(transactions, transaction)
+	}
 
-		amount, err := decimal.NewFromString(amountStr)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse amount: %w", err)
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating transactions: %w", classify(err))
+	}
+
+	return transactions, nil
+}
+
+// scanTransaction reads the current row, selected with transactionColumns
+// followed by the columns scanned into extra
+func scanTransaction(rows *sql.Rows, extra ...any) (*entities.Transaction, error) {
+	var transaction entities.Transaction
+	var occurredAt, cancelledAt, settledAt sql.NullTime
+	var balanceAfter decimal.NullDecimal
+	var metadata sql.NullString
+
+	dest := []any{
+		&transaction.ID,
+		&transaction.UserID,
+		&transaction.TransactionID,
+		&transaction.State,
+		&transaction.Amount,
+		&transaction.SourceType,
+		&occurredAt,
+		&transaction.CreatedAt,
+		&transaction.Cancelled,
+		&cancelledAt,
+		&balanceAfter,
+		&transaction.Receipt,
+		&transaction.Currency,
+		&transaction.RoundID,
+		&transaction.Type,
+		&transaction.Reverses,
+		&transaction.ReversedBy,
+		&transaction.TransferID,
+		&transaction.ChargedFor,
+		&metadata,
+		&transaction.Pending,
+		&settledAt,
+	}
+	if err := rows.Scan(append(dest, extra...)...); err != nil {
+		return nil, fmt.Errorf("failed to scan transaction: %w", classify(err))
+	}
+
+	if occurredAt.Valid {
+		transaction.OccurredAt = &occurredAt.Time
+	}
+	if cancelledAt.Valid {
+		transaction.CancelledAt = &cancelledAt.Time
+	}
+	if balanceAfter.Valid {
+		transaction.BalanceAfter = &balanceAfter.Decimal
+	}
+	if settledAt.Valid {
+		transaction.SettledAt = &settledAt.Time
+	}
+	if metadata.Valid {
+		if err := json.Unmarshal([]byte(metadata.String), &transaction.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to decode transaction metadata: %w", err)
 		}
-		transaction.Amount = amount
+	}
 
-		transactions = append(transactions, &transaction)
+	return &transaction, nil
+}
+
+// encodeMetadata encodes the metadata of a transaction for its JSON column,
+// NULL when there is none
+func encodeMetadata(metadata map[string]string) sql.NullString {
+	if len(metadata) == 0 {
+		return sql.NullString{}
 	}
+	// A map of strings always encodes
+	encoded, _ := json.Marshal(metadata)
+	return sql.NullString{String: string(encoded), Valid: true}
+}
 
+// NetChangeSince returns how much a user's base currency transactions moved
+// the balance at or after since, counting settled transactions from when they
+// were settled and taking cancelled ones back out from when they were
+// cancelled
+func (r *TransactionRepository) NetChangeSince(ctx context.Context, userID uint64, since time.Time) (decimal.Decimal, error) {
+	query := `
+		SELECT COALESCE(SUM(CASE WHEN state = 'win' THEN amount ELSE -amount END * (
+			CASE WHEN COALESCE(settled_at, created_at) >= $2 THEN 1 ELSE 0 END -
+			CASE WHEN cancelled AND COALESCE(cancelled_at, settled_at, created_at) >= $2 THEN 1 ELSE 0 END
+		)), 0)
+		FROM transactions
+		WHERE user_id = $1 AND pending = FALSE AND currency IS NULL
+			AND (COALESCE(settled_at, created_at) >= $2 OR cancelled_at >= $2)
+	`
+
+	var net decimal.Decimal
+	err := Executor(ctx, r.db).QueryRowContext(ctx, query, userID, since).Scan(&net)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("failed to get net change: %w", classify(err))
+	}
+
+	return net, nil
+}
+
+// VelocitySince totals a user's uncancelled standard transactions created at
+// or after since
+func (r *TransactionRepository) VelocitySince(ctx context.Context, userID uint64, since time.Time) (repositories.Velocity, error) {
+	query := `
+		SELECT
+			COUNT(*),
+			COALESCE(SUM(amount) FILTER (WHERE state = 'lose' AND currency IS NULL), 0)
+		FROM transactions
+		WHERE user_id = $1 AND created_at >= $2 AND cancelled = FALSE AND type = 'transaction'
+	`
+
+	var velocity repositories.Velocity
+	err := Executor(ctx, r.db).QueryRowContext(ctx, query, userID, since).Scan(&velocity.Transactions, &velocity.Losses)
+	if err != nil {
+		return repositories.Velocity{}, fmt.Errorf("failed to get velocity: %w", classify(err))
+	}
+
+	return velocity, nil
+}
+
+// MostActiveUsers returns the IDs of up to limit users with the most
+// transactions created at or after since, most active first
+func (r *TransactionRepository) MostActiveUsers(ctx context.Context, since time.Time, limit int) ([]uint64, error) {
+	query := `
+		SELECT user_id
+		FROM transactions
+		WHERE created_at >= $1
+		GROUP BY user_id
+		ORDER BY COUNT(*) DESC, user_id
+		LIMIT $2
+	`
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get most active users: %w", classify(err))
+	}
+	defer rows.Close()
+
+	var userIDs []uint64
+	for rows.Next() {
+		var userID uint64
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("failed to scan user ID: %w", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating transactions: %w", err)
+		return nil, fmt.Errorf("failed to iterate users: %w", classify(err))
 	}
 
-	return transactions, nil
+	return userIDs, nil
+}
+
+// SummarizeRound totals the user's transactions of a round in a wallet
+// currency, empty for the base currency
+func (r *TransactionRepository) SummarizeRound(
+	ctx context.Context,
+	userID uint64,
+	roundID, currency string,
+) (repositories.RoundTotals, error) {
+	query := `
+		SELECT
+			COUNT(*) FILTER (WHERE cancelled = FALSE AND type <> 'fee'),
+			COUNT(*) FILTER (WHERE cancelled = TRUE),
+			COALESCE(SUM(amount) FILTER (WHERE cancelled = FALSE AND type <> 'fee' AND state = 'lose'), 0),
+			COALESCE(SUM(amount) FILTER (WHERE cancelled = FALSE AND type <> 'fee' AND state = 'win'), 0),
+			COALESCE(SUM(amount) FILTER (WHERE cancelled = FALSE AND type = 'fee'), 0)
+		FROM transactions
+		WHERE user_id = $1 AND round_id = $2 AND currency IS NOT DISTINCT FROM NULLIF($3, '')
+	`
+
+	var totals repositories.RoundTotals
+	err := Executor(ctx, r.db).QueryRowContext(ctx, query, userID, roundID, currency).
+		Scan(&totals.Transactions, &totals.Cancelled, &totals.Bets, &totals.Wins, &totals.Fees)
+	if err != nil {
+		return repositories.RoundTotals{}, fmt.Errorf("failed to summarize round: %w", classify(err))
+	}
+
+	return totals, nil
+}
+
+// SummarizeDays totals the user's uncancelled non-fee transactions in a wallet
+// currency that occurred in [from, to) by UTC day, source type and state.
+// Transactions without a business time count when they were created. The
+// timestamps hold UTC wall times without a zone, so they are truncated as
+// they are: converting them with AT TIME ZONE would bucket by the session
+// TimeZone.
+func (r *TransactionRepository) SummarizeDays(
+	ctx context.Context,
+	userID uint64,
+	currency string,
+	from, to time.Time,
+) ([]repositories.DayTotals, error) {
+	query := `
+		SELECT date_trunc('day', COALESCE(occurred_at, created_at)), source_type, state, COUNT(*), COALESCE(SUM(amount), 0)
+		FROM transactions
+		WHERE user_id = $1 AND currency IS NOT DISTINCT FROM NULLIF($2, '')
+			AND COALESCE(occurred_at, created_at) >= $3 AND COALESCE(occurred_at, created_at) < $4
+			AND cancelled = FALSE AND type <> 'fee'
+		GROUP BY 1, 2, 3
+		ORDER BY 1, 2, 3
+	`
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, userID, currency, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize days: %w", classify(err))
+	}
+	defer rows.Close()
+
+	var days []repositories.DayTotals
+	for rows.Next() {
+		var day repositories.DayTotals
+		if err := rows.Scan(&day.Day, &day.SourceType, &day.State, &day.Count, &day.Amount); err != nil {
+			return nil, fmt.Errorf("failed to scan day totals: %w", err)
+		}
+		day.Day = time.Date(day.Day.Year(), day.Day.Month(), day.Day.Day(), 0, 0, 0, 0, time.UTC)
+		days = append(days, day)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate day totals: %w", classify(err))
+	}
+
+	return days, nil
+}
+
+// LockLatestOddUncancelled locks and returns the newest uncancelled odd-ID
+// transactions that are neither refunds, refunded, transfer legs, fees,
+// adjustments nor pending
+func (r *TransactionRepository) LockLatestOddUncancelled(ctx context.Context, limit int) ([]*entities.Transaction, error) {
+	query := `
+		SELECT ` + transactionColumns + `
+		FROM transactions
+		WHERE cancelled = FALSE AND pending = FALSE AND id % 2 = 1 AND type NOT IN ('fee', 'adjustment') AND reverses IS NULL AND reversed_by IS NULL AND transfer_id IS NULL
+		ORDER BY id DESC
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest odd transactions: %w", classify(err))
+	}
+	defer rows.Close()
+
+	return scanTransactions(rows)
+}
+
+// CheckUniqueIDs checks the transactions created in [from, to) for transaction
+// IDs recorded more than once. The unique index on transaction IDs normally
+// rules them out; the check guards against it having been dropped or left
+// invalid, e.g. by a failed concurrent rebuild or a partial restore.
+func (r *TransactionRepository) CheckUniqueIDs(ctx context.Context, from, to time.Time, limit int) (*repositories.UniquenessCheck, error) {
+	var check repositories.UniquenessCheck
+
+	err := Executor(ctx, r.db).QueryRowContext(ctx, `
+		SELECT COUNT(*), COUNT(DISTINCT transaction_id)
+		FROM transactions
+		WHERE created_at >= $1 AND created_at < $2
+	`, from, to).Scan(&check.Transactions, &check.DistinctIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count transactions: %w", classify(err))
+	}
+
+	err = Executor(ctx, r.db).QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1
+			FROM pg_index i
+			JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = i.indkey[0]
+			WHERE i.indrelid = 'transactions'::regclass
+				AND i.indisunique AND i.indisvalid AND i.indnatts = 1 AND i.indpred IS NULL
+				AND a.attname = 'transaction_id'
+		)
+	`).Scan(&check.Enforced)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check the transaction ID index: %w", classify(err))
+	}
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, `
+		SELECT transaction_id, COUNT(*)
+		FROM transactions
+		WHERE transaction_id IN (
+			SELECT transaction_id FROM transactions WHERE created_at >= $1 AND created_at < $2
+		)
+		GROUP BY transaction_id
+		HAVING COUNT(*) > 1
+		ORDER BY transaction_id
+		LIMIT $3
+	`, from, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find duplicate transaction IDs: %w", classify(err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var duplicate entities.DuplicateTransactionID
+		if err := rows.Scan(&duplicate.TransactionID, &duplicate.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan duplicate transaction ID: %w", classify(err))
+		}
+		check.Duplicates = append(check.Duplicates, duplicate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating duplicate transaction IDs: %w", classify(err))
+	}
+
+	return &check, nil
+}
+
+// MarkCancelled flags a transaction as cancelled
+func (r *TransactionRepository) MarkCancelled(ctx context.Context, id uint64, cancelledAt time.Time) error {
+	query := "UPDATE transactions SET cancelled = TRUE, cancelled_at = $1 WHERE id = $2 AND cancelled = FALSE"
+
+	result, err := Executor(ctx, r.db).ExecContext(ctx, query, cancelledAt, id)
+	if err != nil {
+		return fmt.Errorf("failed to cancel transaction: %w", classify(err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", classify(err))
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("transaction %d: %w", id, repositories.ErrNotFound)
+	}
+
+	return nil
+}
+
+// MarkReversed links a transaction to the refund reversing it
+func (r *TransactionRepository) MarkReversed(ctx context.Context, id uint64, reversedBy string) error {
+	query := "UPDATE transactions SET reversed_by = $1 WHERE id = $2 AND reversed_by IS NULL"
+
+	result, err := Executor(ctx, r.db).ExecContext(ctx, query, reversedBy, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark transaction reversed: %w", classify(err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", classify(err))
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("transaction %d: %w", id, repositories.ErrNotFound)
+	}
+
+	return nil
+}
+
+// SumPending returns the total of a user's uncancelled base currency
+// transactions awaiting settlement
+func (r *TransactionRepository) SumPending(ctx context.Context, userID uint64) (decimal.Decimal, error) {
+	query := `
+		SELECT COALESCE(SUM(amount), 0)
+		FROM transactions
+		WHERE user_id = $1 AND pending = TRUE AND cancelled = FALSE AND currency IS NULL
+	`
+
+	var pending decimal.Decimal
+	err := Executor(ctx, r.db).QueryRowContext(ctx, query, userID).Scan(&pending)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("failed to get pending total: %w", classify(err))
+	}
+
+	return pending, nil
+}
+
+// ListPendingBefore returns up to limit of the oldest pending transactions
+// created before before, without locking them
+func (r *TransactionRepository) ListPendingBefore(ctx context.Context, before time.Time, limit int) ([]*entities.Transaction, error) {
+	query := `
+		SELECT ` + transactionColumns + `
+		FROM transactions
+		WHERE pending = TRUE AND cancelled = FALSE AND created_at < $1
+		ORDER BY created_at, id
+		LIMIT $2
+	`
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending transactions: %w", classify(err))
+	}
+	defer rows.Close()
+
+	return scanTransactions(rows)
+}
+
+// LockPendingBefore locks and returns up to limit of the oldest pending
+// transactions created before before
+func (r *TransactionRepository) LockPendingBefore(ctx context.Context, before time.Time, limit int) ([]*entities.Transaction, error) {
+	query := `
+		SELECT ` + transactionColumns + `
+		FROM transactions
+		WHERE pending = TRUE AND cancelled = FALSE AND created_at < $1
+		ORDER BY created_at, id
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending transactions: %w", classify(err))
+	}
+	defer rows.Close()
+
+	return scanTransactions(rows)
+}
+
+// MarkSettled records the settlement of a pending transaction
+func (r *TransactionRepository) MarkSettled(ctx context.Context, id uint64, settledAt time.Time) error {
+	query := "UPDATE transactions SET pending = FALSE, settled_at = $1 WHERE id = $2 AND pending = TRUE"
+
+	result, err := Executor(ctx, r.db).ExecContext(ctx, query, settledAt, id)
+	if err != nil {
+		return fmt.Errorf("failed to settle transaction: %w", classify(err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", classify(err))
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("transaction %d: %w", id, repositories.ErrNotFound)
+	}
+
+	return nil
 }
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildTransactionFilter(t *testing.T) {
+	minAmount := decimal.NewFromInt(10)
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tableID := "t-7"
+
+	tests := []struct {
+		name      string
+		filter    repositories.TransactionFilter
+		wantWhere string
+		wantArgs  []any
+	}{
+		{
+			name:      "empty filter",
+			filter:    repositories.TransactionFilter{},
+			wantWhere: "",
+			wantArgs:  nil,
+		},
+		{
+			name: "combined criteria are numbered in order",
+			filter: repositories.TransactionFilter{
+				UserID:     42,
+				MinAmount:  &minAmount,
+				SourceType: entities.SourceTypePayment,
+				From:       &from,
+			},
+			wantWhere: " WHERE user_id = $1 AND amount >= $2 AND source_type = $3 AND created_at >= $4",
+			wantArgs:  []any{uint64(42), minAmount, entities.SourceTypePayment, from},
+		},
+		{
+			name:      "prefix wildcards are escaped",
+			filter:    repositories.TransactionFilter{TransactionIDPrefix: "tx_10%"},
+			wantWhere: ` WHERE transaction_id LIKE $1 ESCAPE '\'`,
+			wantArgs:  []any{`tx\_10\%%`},
+		},
+		{
+			name:      "metadata keys",
+			filter:    repositories.TransactionFilter{UserID: 42, MetadataKey: "table.id"},
+			wantWhere: " WHERE user_id = $1 AND metadata ->> $2::TEXT IS NOT NULL",
+			wantArgs:  []any{uint64(42), "table.id"},
+		},
+		{
+			name:      "metadata values",
+			filter:    repositories.TransactionFilter{MetadataKey: "table.id", MetadataValue: &tableID},
+			wantWhere: " WHERE metadata ->> $1::TEXT = $2",
+			wantArgs:  []any{"table.id", "t-7"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, args := buildTransactionFilter(tt.filter)
+			assert.Equal(t, tt.wantWhere, where)
+			assert.Equal(t, tt.wantArgs, args)
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"transaction-service/internal/domain/repositories"
+)
+
+type txContextKey struct{}
+
+// Querier is the set of operations shared by *sql.DB and *sql.Tx
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// UnitOfWork implements the unit of work interface on top of database/sql
+type UnitOfWork struct {
+	db *sql.DB
+}
+
+// NewUnitOfWork creates a new UnitOfWork
+func NewUnitOfWork(db *sql.DB) *UnitOfWork {
+	return &UnitOfWork{db: db}
+}
+
+// WithinTransaction runs fn inside a database transaction carried by its
+// context, under SERIALIZABLE isolation or read-only under REPEATABLE READ
+// isolation when ctx asks for it (see repositories.WithSerializable and
+// repositories.WithSnapshotRead). SQLite ignores both.
+func (u *UnitOfWork) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	// Join the ambient transaction if there is one
+	if _, ok := TxFromContext(ctx); ok {
+		return fn(ctx)
+	}
+
+	var opts *sql.TxOptions
+	switch {
+	case repositories.Serializable(ctx):
+		opts = &sql.TxOptions{Isolation: sql.LevelSerializable}
+	case repositories.SnapshotRead(ctx):
+		opts = &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+	}
+	tx, err := u.db.BeginTx(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", classify(err))
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txContextKey{}, tx)); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		// A transaction failing to serialize is rolled back, so its outcome
+		// is known and it can be retried
+		if IsSerializationFailure(err) {
+			return fmt.Errorf("failed to commit transaction: %w", classify(err))
+		}
+		return fmt.Errorf("%w: %w", ErrCommitFailed, classify(err))
+	}
+
+	return nil
+}
+
+// TxFromContext returns the ambient transaction started by a UnitOfWork.
+// Extensions can use it to write their own rows atomically with the main
+// transaction.
+func TxFromContext(ctx context.Context) (*sql.Tx, bool) {
+	tx, ok := ctx.Value(txContextKey{}).(*sql.Tx)
+	return tx, ok
+}
+
+// Executor returns the ambient transaction if there is one, otherwise db
+func Executor(ctx context.Context, db *sql.DB) Querier {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx
+	}
+	return db
+}
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+)
+
+// userLockNamespace is the first key of the advisory locks taken per user.
+// Two-key advisory locks do not conflict with the single-key locks of seeding
+// and migrations.
+const userLockNamespace = 7_325_002
+
+// LockUser takes the advisory lock of a user within the ambient transaction,
+// waiting for the instances holding it. The lock is released when the
+// transaction ends. User IDs are folded into 32 bits, so users whose IDs
+// differ by a multiple of 2^32 share a lock, which only serializes them
+// needlessly. Extensions writing balances through TxFromContext can use it to
+// serialize with the service.
+func LockUser(ctx context.Context, userID uint64) error {
+	tx, ok := TxFromContext(ctx)
+	if !ok {
+		return fmt.Errorf("failed to lock user %d: no unit of work", userID)
+	}
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1, $2)", userLockNamespace, userLockKey(userID)); err != nil {
+		return fmt.Errorf("failed to lock user: %w", classify(err))
+	}
+	return nil
+}
+
+// userLockKey returns the second key of the advisory lock of a user
+func userLockKey(userID uint64) int32 {
+	return int32(userID)
+}
+
+// advisoryLockingUsers takes the advisory lock of the users locked for update
+type advisoryLockingUsers struct {
+	repositories.UserRepository
+}
+
+// WithUserAdvisoryLocks decorates a PostgreSQL user repository so that
+// locking a user for update first takes its advisory lock (see LockUser).
+// Every unit of work updating a balance locks its user, so balance updates
+// are serialized across instances by the lock, on top of the row lock.
+// Outside of a unit of work, where no lock can be held, users are read
+// without it.
+func WithUserAdvisoryLocks(users repositories.UserRepository) repositories.UserRepository {
+	return advisoryLockingUsers{UserRepository: users}
+}
+
+// GetByIDForUpdate takes the advisory lock of the user before locking its row
+func (r advisoryLockingUsers) GetByIDForUpdate(ctx context.Context, userID uint64) (*entities.User, error) {
+	if _, ok := TxFromContext(ctx); ok {
+		if err := LockUser(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+	return r.UserRepository.GetByIDForUpdate(ctx, userID)
+}
@@ -0,0 +1,40 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lockedUsers records the users locked for update
+type lockedUsers struct {
+	repositories.UserRepository
+	locked []uint64
+}
+
+func (r *lockedUsers) GetByIDForUpdate(ctx context.Context, userID uint64) (*entities.User, error) {
+	r.locked = append(r.locked, userID)
+	return &entities.User{ID: userID}, nil
+}
+
+func TestWithUserAdvisoryLocks_OutsideUnitOfWork(t *testing.T) {
+	users := &lockedUsers{}
+
+	user, err := WithUserAdvisoryLocks(users).GetByIDForUpdate(context.Background(), 7)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7), user.ID)
+	assert.Equal(t, []uint64{7}, users.locked)
+
+	assert.ErrorContains(t, LockUser(context.Background(), 7), "no unit of work")
+}
+
+func TestUserLockKey(t *testing.T) {
+	assert.Equal(t, int32(7), userLockKey(7))
+	assert.Equal(t, userLockKey(7), userLockKey(7+1<<32), "IDs are folded into 32 bits")
+	assert.NotEqual(t, userLockKey(7), userLockKey(8))
+}
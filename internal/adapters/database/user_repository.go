@@ -3,9 +3,12 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"time"
 
 	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
 
 	"github.com/shopspring/decimal"
 )
@@ -19,26 +22,43 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
+// userColumns are the columns scanned by scanUser
+const userColumns = "id, balance, status, credit_limit, COALESCE(jurisdiction, ''), dormant_at"
+
 // GetByID retrieves a user by their ID
 func (r *UserRepository) GetByID(ctx context.Context, userID uint64) (*entities.User, error) {
-	query := "SELECT id, balance FROM users WHERE id = $1"
+	return r.getUser(ctx, "SELECT "+userColumns+" FROM users WHERE id = $1", userID)
+}
 
-	var user entities.User
-	var balanceStr string
+// GetByIDForUpdate retrieves a user by their ID and locks the row until the
+// ambient transaction ends, serializing concurrent balance updates
+func (r *UserRepository) GetByIDForUpdate(ctx context.Context, userID uint64) (*entities.User, error) {
+	return r.getUser(ctx, "SELECT "+userColumns+" FROM users WHERE id = $1 FOR UPDATE", userID)
+}
 
-	err := r.db.QueryRowContext(ctx, query, userID).Scan(&user.ID, &balanceStr)
+func (r *UserRepository) getUser(ctx context.Context, query string, userID uint64) (*entities.User, error) {
+	user, err := scanUser(Executor(ctx, r.db).QueryRowContext(ctx, query, userID))
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("user with ID %d not found", userID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("user with ID %d: %w", userID, repositories.ErrNotFound)
 		}
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
-	balance, err := decimal.NewFromString(balanceStr)
+	return user, nil
+}
+
+// scanUser reads a user selected with userColumns
+func scanUser(row rowScanner) (*entities.User, error) {
+	var user entities.User
+
+	err := row.Scan(&user.ID, &user.Balance, &user.Status, &user.CreditLimit, &user.Jurisdiction, &user.DormantAt)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse balance: %w", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, classify(err)
 	}
-	user.Balance = balance
 
 	return &user, nil
 }
@@ -47,30 +67,187 @@ func (r *UserRepository) GetByID(ctx context.Context, userID uint64) (*entities.
 func (r *UserRepository) UpdateBalance(ctx context.Context, userID uint64, newBalance decimal.Decimal) error {
 	query := "UPDATE users SET balance = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2"
 
-	result, err := r.db.ExecContext(ctx, query, newBalance, userID)
+	result, err := Executor(ctx, r.db).ExecContext(ctx, query, newBalance, userID)
 	if err != nil {
-		return fmt.Errorf("failed to update balance: %w", err)
+		return fmt.Errorf("failed to update balance: %w", classify(err))
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return fmt.Errorf("failed to get rows affected: %w", classify(err))
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("user with ID %d not found", userID)
+		return fmt.Errorf("user with ID %d: %w", userID, repositories.ErrNotFound)
 	}
 
 	return nil
 }
 
-// Create creates a new user
+// Create creates a new user. A zero ID is allocated from the sequence.
 func (r *UserRepository) Create(ctx context.Context, user *entities.User) error {
-	query := "INSERT INTO users (balance) VALUES ($1) RETURNING id"
+	query := `
+		INSERT INTO users (id, balance)
+		VALUES (COALESCE($1::BIGINT, nextval(pg_get_serial_sequence('users', 'id'))), $2)
+		RETURNING id, status
+	`
+
+	err := Executor(ctx, r.db).QueryRowContext(ctx, query, explicitID(user.ID), user.Balance).Scan(&user.ID, &user.Status)
+	if err != nil {
+		return fmt.Errorf("failed to create user: %w", classify(err))
+	}
+
+	return nil
+}
+
+// List retrieves a page of users ordered by ID
+func (r *UserRepository) List(ctx context.Context, limit, offset int) ([]*entities.User, int, error) {
+	var total int
+	if err := Executor(ctx, r.db).QueryRowContext(ctx, "SELECT COUNT(*) FROM users").Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %w", classify(err))
+	}
 
-	err := r.db.QueryRowContext(ctx, query, user.Balance).Scan(&user.ID)
+	query := "SELECT " + userColumns + " FROM users ORDER BY id LIMIT $1 OFFSET $2"
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, limit, offset)
 	if err != nil {
-		return fmt.Errorf("failed to create user: %w", err)
+		return nil, 0, fmt.Errorf("failed to list users: %w", classify(err))
+	}
+	defer rows.Close()
+
+	users := make([]*entities.User, 0, limit)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list users: %w", classify(err))
+	}
+
+	return users, total, nil
+}
+
+// UpdateStatus updates the user's account status
+func (r *UserRepository) UpdateStatus(ctx context.Context, userID uint64, status entities.UserStatus) error {
+	query := "UPDATE users SET status = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2"
+
+	result, err := Executor(ctx, r.db).ExecContext(ctx, query, status, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update status: %w", classify(err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", classify(err))
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("user with ID %d: %w", userID, repositories.ErrNotFound)
+	}
+
+	return nil
+}
+
+// UpdateJurisdiction sets the user's jurisdiction; an empty value clears it
+func (r *UserRepository) UpdateJurisdiction(ctx context.Context, userID uint64, jurisdiction string) error {
+	query := "UPDATE users SET jurisdiction = NULLIF($1, ''), updated_at = CURRENT_TIMESTAMP WHERE id = $2"
+
+	result, err := Executor(ctx, r.db).ExecContext(ctx, query, jurisdiction, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update jurisdiction: %w", classify(err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", classify(err))
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("user with ID %d: %w", userID, repositories.ErrNotFound)
+	}
+
+	return nil
+}
+
+// UpdateCreditLimit sets how far below zero the user's balance may go
+func (r *UserRepository) UpdateCreditLimit(ctx context.Context, userID uint64, creditLimit decimal.Decimal) error {
+	query := "UPDATE users SET credit_limit = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2"
+
+	result, err := Executor(ctx, r.db).ExecContext(ctx, query, creditLimit, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update credit limit: %w", classify(err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", classify(err))
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("user with ID %d: %w", userID, repositories.ErrNotFound)
+	}
+
+	return nil
+}
+
+// LockIdleSince locks and returns up to limit active users that are not
+// dormant, were created before since and have no transaction created at or
+// after since, skipping users locked by others. System accounts are never
+// idle.
+func (r *UserRepository) LockIdleSince(ctx context.Context, since time.Time, limit int) ([]*entities.User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users u
+		WHERE u.status = 'active' AND u.dormant_at IS NULL AND u.created_at < $1
+			AND NOT EXISTS (
+				SELECT 1 FROM transactions t
+				WHERE t.user_id = u.id AND t.created_at >= $1
+			)
+			AND NOT EXISTS (SELECT 1 FROM system_accounts a WHERE a.user_id = u.id)
+		ORDER BY u.id
+		LIMIT $2
+		FOR UPDATE OF u SKIP LOCKED
+	`
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get idle users: %w", classify(err))
+	}
+	defer rows.Close()
+
+	var users []*entities.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get idle users: %w", classify(err))
+	}
+
+	return users, nil
+}
+
+// UpdateDormancy flags the user as dormant at dormantAt; nil clears the flag
+func (r *UserRepository) UpdateDormancy(ctx context.Context, userID uint64, dormantAt *time.Time) error {
+	query := "UPDATE users SET dormant_at = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2"
+
+	result, err := Executor(ctx, r.db).ExecContext(ctx, query, dormantAt, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update dormancy: %w", classify(err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", classify(err))
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("user with ID %d: %w", userID, repositories.ErrNotFound)
 	}
 
 	return nil
@@ -0,0 +1,397 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// WebhookRepository implements the webhook repository interface
+type WebhookRepository struct {
+	db *sql.DB
+}
+
+// NewWebhookRepository creates a new webhook repository
+func NewWebhookRepository(db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+// Create stores a new webhook
+func (r *WebhookRepository) Create(ctx context.Context, webhook *entities.Webhook) error {
+	query := `
+		INSERT INTO webhooks (url, secret, event_types, user_id, source_type, state, active, created_at)
+		VALUES ($1, $2, $3, NULLIF($4::BIGINT, 0), NULLIF($5, ''), NULLIF($6, ''), TRUE, $7)
+		RETURNING id
+	`
+
+	events := webhook.Events
+	if events == nil {
+		events = []string{}
+	}
+	err := Executor(ctx, r.db).QueryRowContext(
+		ctx,
+		query,
+		webhook.URL,
+		webhook.Secret,
+		events,
+		int64(webhook.Filter.UserID),
+		webhook.Filter.SourceType,
+		webhook.Filter.State,
+		webhook.CreatedAt,
+	).Scan(&webhook.ID)
+
+	if err != nil {
+		return fmt.Errorf("failed to create webhook: %w", classify(err))
+	}
+
+	webhook.Events = events
+	webhook.Active = true
+	return nil
+}
+
+// webhookColumns are the columns scanned by scanWebhook
+const webhookColumns = `
+	id, url, event_types, COALESCE(user_id, 0), COALESCE(source_type, ''), COALESCE(state, ''), active, created_at
+`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanWebhook(row rowScanner) (*entities.Webhook, error) {
+	var webhook entities.Webhook
+	var events []string
+	err := row.Scan(
+		&webhook.ID,
+		&webhook.URL,
+		// database/sql hands arrays over as text, which pgx parses
+		pgtype.NewMap().SQLScanner(&events),
+		&webhook.Filter.UserID,
+		&webhook.Filter.SourceType,
+		&webhook.Filter.State,
+		&webhook.Active,
+		&webhook.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	webhook.Events = events
+	return &webhook, nil
+}
+
+// GetByID retrieves an active webhook without its secret
+func (r *WebhookRepository) GetByID(ctx context.Context, id uint64) (*entities.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE id = $1 AND active`
+
+	webhook, err := scanWebhook(Executor(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("webhook %d: %w", id, repositories.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get webhook: %w", classify(err))
+	}
+
+	return webhook, nil
+}
+
+// List returns the active webhooks ordered by ID, without their secrets
+func (r *WebhookRepository) List(ctx context.Context) ([]*entities.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE active ORDER BY id`
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", classify(err))
+	}
+	defer rows.Close()
+
+	webhooks := []*entities.Webhook{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", classify(err))
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", classify(err))
+	}
+
+	return webhooks, nil
+}
+
+// Deactivate stops deliveries to the webhook and fails its pending ones
+func (r *WebhookRepository) Deactivate(ctx context.Context, id uint64) error {
+	query := `
+		WITH deactivated AS (
+			UPDATE webhooks SET active = FALSE WHERE id = $1 AND active RETURNING id
+		), abandoned AS (
+			UPDATE webhook_deliveries d
+			SET status = 'failed', next_attempt_at = NULL, last_error = 'webhook deleted'
+			FROM deactivated
+			WHERE d.webhook_id = deactivated.id AND d.status = 'pending'
+		)
+		SELECT COUNT(*) FROM deactivated
+	`
+
+	var deactivated int
+	if err := Executor(ctx, r.db).QueryRowContext(ctx, query, id).Scan(&deactivated); err != nil {
+		return fmt.Errorf("failed to deactivate webhook: %w", classify(err))
+	}
+	if deactivated == 0 {
+		return fmt.Errorf("webhook %d: %w", id, repositories.ErrNotFound)
+	}
+
+	return nil
+}
+
+// EnqueueDeliveries queues event for every active webhook matching it in the
+// ambient unit of work
+func (r *WebhookRepository) EnqueueDeliveries(ctx context.Context, event repositories.WebhookEvent) (int, error) {
+	query := `
+		INSERT INTO webhook_deliveries (webhook_id, event_type, payload, status, next_attempt_at, created_at)
+		SELECT id, $1, $2, 'pending', $3, $3
+		FROM webhooks
+		WHERE active
+			AND (cardinality(event_types) = 0 OR $1 = ANY(event_types))
+			AND (user_id IS NULL OR user_id = $4)
+			AND (source_type IS NULL OR source_type = $5)
+			AND (state IS NULL OR state = $6)
+	`
+
+	result, err := Executor(ctx, r.db).ExecContext(
+		ctx,
+		query,
+		event.Type,
+		event.Payload,
+		event.CreatedAt,
+		int64(event.UserID),
+		event.SourceType,
+		event.State,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue webhook deliveries: %w", classify(err))
+	}
+
+	queued, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", classify(err))
+	}
+
+	return int(queued), nil
+}
+
+// ClaimDueDeliveries returns up to limit of the pending deliveries due at now
+// and postpones them to leaseUntil. Deliveries claimed by a concurrent caller
+// are skipped.
+func (r *WebhookRepository) ClaimDueDeliveries(
+	ctx context.Context,
+	now, leaseUntil time.Time,
+	limit int,
+) ([]*entities.WebhookDelivery, error) {
+	query := `
+		WITH due AS (
+			SELECT id
+			FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at, id
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = $2
+		FROM due, webhooks w
+		WHERE d.id = due.id AND w.id = d.webhook_id
+		RETURNING d.id, d.webhook_id, d.event_type, d.payload, d.attempts, d.created_at, w.url, w.secret
+	`
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, now, leaseUntil, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", classify(err))
+	}
+	defer rows.Close()
+
+	var deliveries []*entities.WebhookDelivery
+	for rows.Next() {
+		delivery := entities.WebhookDelivery{Status: entities.WebhookDeliveryPending}
+		var payload []byte
+		err := rows.Scan(
+			&delivery.ID,
+			&delivery.WebhookID,
+			&delivery.EventType,
+			&payload,
+			&delivery.Attempts,
+			&delivery.CreatedAt,
+			&delivery.URL,
+			&delivery.Secret,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", classify(err))
+		}
+		delivery.Payload = payload
+		deliveries = append(deliveries, &delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", classify(err))
+	}
+
+	return deliveries, nil
+}
+
+// UpdateDelivery records the outcome of an attempt
+func (r *WebhookRepository) UpdateDelivery(ctx context.Context, delivery *entities.WebhookDelivery) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $1, attempts = $2, next_attempt_at = $3, last_status_code = NULLIF($4, 0),
+			last_error = NULLIF($5, ''), delivered_at = $6
+		WHERE id = $7
+	`
+
+	_, err := Executor(ctx, r.db).ExecContext(
+		ctx,
+		query,
+		delivery.Status,
+		delivery.Attempts,
+		delivery.NextAttemptAt,
+		delivery.LastStatusCode,
+		delivery.LastError,
+		delivery.DeliveredAt,
+		delivery.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", classify(err))
+	}
+
+	return nil
+}
+
+// ListDeliveries returns a page of the webhook's deliveries, newest first
+func (r *WebhookRepository) ListDeliveries(
+	ctx context.Context,
+	webhookID uint64,
+	status entities.WebhookDeliveryStatus,
+	limit, offset int,
+) ([]*entities.WebhookDelivery, int, error) {
+	where := `WHERE webhook_id = $1 AND ($2 = '' OR status = $2)`
+
+	var total int
+	err := Executor(ctx, r.db).QueryRowContext(
+		ctx, `SELECT COUNT(*) FROM webhook_deliveries `+where, webhookID, status,
+	).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count webhook deliveries: %w", classify(err))
+	}
+
+	query := `
+		SELECT id, webhook_id, event_type, payload, status, attempts, next_attempt_at,
+			COALESCE(last_status_code, 0), COALESCE(last_error, ''), created_at, delivered_at
+		FROM webhook_deliveries
+		` + where + `
+		ORDER BY id DESC
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, webhookID, status, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list webhook deliveries: %w", classify(err))
+	}
+	defer rows.Close()
+
+	deliveries := make([]*entities.WebhookDelivery, 0, limit)
+	for rows.Next() {
+		var delivery entities.WebhookDelivery
+		var payload []byte
+		var nextAttemptAt, deliveredAt sql.NullTime
+		err := rows.Scan(
+			&delivery.ID,
+			&delivery.WebhookID,
+			&delivery.EventType,
+			&payload,
+			&delivery.Status,
+			&delivery.Attempts,
+			&nextAttemptAt,
+			&delivery.LastStatusCode,
+			&delivery.LastError,
+			&delivery.CreatedAt,
+			&deliveredAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan webhook delivery: %w", classify(err))
+		}
+		delivery.Payload = payload
+		if nextAttemptAt.Valid {
+			delivery.NextAttemptAt = &nextAttemptAt.Time
+		}
+		if deliveredAt.Valid {
+			delivery.DeliveredAt = &deliveredAt.Time
+		}
+		deliveries = append(deliveries, &delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list webhook deliveries: %w", classify(err))
+	}
+
+	return deliveries, total, nil
+}
+
+// ListDeliveryIDs returns the IDs of the deliveries of active webhooks
+// created in [from, to)
+func (r *WebhookRepository) ListDeliveryIDs(ctx context.Context, webhookID uint64, from, to time.Time) ([]uint64, error) {
+	query := `
+		SELECT d.id
+		FROM webhook_deliveries d
+		JOIN webhooks w ON w.id = d.webhook_id
+		WHERE w.active AND ($1::BIGINT = 0 OR d.webhook_id = $1) AND d.created_at >= $2 AND d.created_at < $3
+		ORDER BY d.id
+	`
+
+	rows, err := Executor(ctx, r.db).QueryContext(ctx, query, int64(webhookID), from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook delivery IDs: %w", classify(err))
+	}
+	defer rows.Close()
+
+	var ids []uint64
+	for rows.Next() {
+		var id uint64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery ID: %w", classify(err))
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list webhook delivery IDs: %w", classify(err))
+	}
+
+	return ids, nil
+}
+
+// RequeueDeliveries makes the deliveries pending again, due at now
+func (r *WebhookRepository) RequeueDeliveries(ctx context.Context, ids []uint64, now time.Time) (int, error) {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = $1, delivered_at = NULL
+		WHERE id = ANY($2)
+	`
+
+	args := make([]int64, len(ids))
+	for i, id := range ids {
+		args[i] = int64(id)
+	}
+
+	result, err := Executor(ctx, r.db).ExecContext(ctx, query, now, args)
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue webhook deliveries: %w", classify(err))
+	}
+
+	requeued, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", classify(err))
+	}
+
+	return int(requeued), nil
+}
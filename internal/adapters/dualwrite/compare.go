@@ -0,0 +1,155 @@
+package dualwrite
+
+import (
+	"context"
+	"errors"
+	"maps"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/shopspring/decimal"
+)
+
+// compareUser compares the user and their wallets between the stores and
+// reports whether they differ
+func (m *Migrator) compareUser(ctx context.Context, primary, shadow *Store, userID uint64) bool {
+	want, err := primary.Users.GetByID(ctx, userID)
+	if err != nil {
+		m.logger.Warn().Err(err).Uint64("user_id", userID).Msg("failed to read user for comparison")
+		return false
+	}
+	got, err := shadow.Users.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			m.logger.Warn().Uint64("user_id", userID).Msg("user is missing from the shadow store")
+			return true
+		}
+		m.logger.Warn().Err(err).Uint64("user_id", userID).Msg("failed to read shadow user for comparison")
+		return false
+	}
+
+	var fields []string
+	if !want.Balance.Equal(got.Balance) {
+		fields = append(fields, "balance")
+	}
+	if want.Status != got.Status {
+		fields = append(fields, "status")
+	}
+	if !want.CreditLimit.Equal(got.CreditLimit) {
+		fields = append(fields, "credit_limit")
+	}
+	if want.Jurisdiction != got.Jurisdiction {
+		fields = append(fields, "jurisdiction")
+	}
+	if (want.DormantAt == nil) != (got.DormantAt == nil) {
+		fields = append(fields, "dormant")
+	}
+
+	if primary.Wallets != nil && shadow.Wallets != nil {
+		wantWallets, err := primary.Wallets.ListByUser(ctx, userID)
+		if err != nil {
+			m.logger.Warn().Err(err).Uint64("user_id", userID).Msg("failed to read wallets for comparison")
+			return false
+		}
+		gotWallets, err := shadow.Wallets.ListByUser(ctx, userID)
+		if err != nil {
+			m.logger.Warn().Err(err).Uint64("user_id", userID).Msg("failed to read shadow wallets for comparison")
+			return false
+		}
+		if !sameWallets(wantWallets, gotWallets) {
+			fields = append(fields, "wallets")
+		}
+	}
+
+	if len(fields) == 0 {
+		return false
+	}
+	m.logger.Warn().Uint64("user_id", userID).Strs("fields", fields).Msg("shadow user differs from primary")
+	return true
+}
+
+// compareTransaction compares the transaction between the stores and reports
+// whether they differ
+func (m *Migrator) compareTransaction(ctx context.Context, primary, shadow *Store, transactionID string) bool {
+	want, err := primary.Transactions.GetByTransactionID(ctx, transactionID)
+	if err != nil {
+		m.logger.Warn().Err(err).Str("transaction_id", transactionID).Msg("failed to read transaction for comparison")
+		return false
+	}
+	got, err := shadow.Transactions.GetByTransactionID(ctx, transactionID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			m.logger.Warn().Str("transaction_id", transactionID).Msg("transaction is missing from the shadow store")
+			return true
+		}
+		m.logger.Warn().Err(err).Str("transaction_id", transactionID).Msg("failed to read shadow transaction for comparison")
+		return false
+	}
+
+	var fields []string
+	if want.ID != got.ID {
+		fields = append(fields, "id")
+	}
+	if want.UserID != got.UserID {
+		fields = append(fields, "userId")
+	}
+	if want.State != got.State || want.SourceType != got.SourceType || want.Currency != got.Currency {
+		fields = append(fields, "kind")
+	}
+	if !want.Amount.Equal(got.Amount) {
+		fields = append(fields, "amount")
+	}
+	if want.Receipt != got.Receipt {
+		fields = append(fields, "receipt")
+	}
+	if want.RoundID != got.RoundID {
+		fields = append(fields, "roundId")
+	}
+	if !maps.Equal(want.Metadata, got.Metadata) {
+		fields = append(fields, "metadata")
+	}
+	if want.Cancelled != got.Cancelled {
+		fields = append(fields, "cancelled")
+	}
+	if want.Pending != got.Pending {
+		fields = append(fields, "pending")
+	}
+	if want.Type != got.Type || want.Reverses != got.Reverses || want.ReversedBy != got.ReversedBy {
+		fields = append(fields, "reversal")
+	}
+	if want.TransferID != got.TransferID {
+		fields = append(fields, "transferId")
+	}
+	if want.ChargedFor != got.ChargedFor {
+		fields = append(fields, "chargedFor")
+	}
+	if !sameDecimal(want.BalanceAfter, got.BalanceAfter) {
+		fields = append(fields, "balanceAfter")
+	}
+
+	if len(fields) == 0 {
+		return false
+	}
+	m.logger.Warn().Str("transaction_id", transactionID).Strs("fields", fields).Msg("shadow transaction differs from primary")
+	return true
+}
+
+func sameWallets(want, got []*entities.Wallet) bool {
+	if len(want) != len(got) {
+		return false
+	}
+	for i := range want {
+		if want[i].Currency != got[i].Currency || !want[i].Balance.Equal(got[i].Balance) {
+			return false
+		}
+	}
+	return true
+}
+
+func sameDecimal(a, b *decimal.Decimal) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,377 @@
+package dualwrite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"transaction-service/internal/domain/repositories"
+	"transaction-service/internal/logging"
+
+	"github.com/rs/zerolog"
+)
+
+// Phase selects which store serves the service while both are written
+type Phase string
+
+const (
+	// PhaseDualWrite serves from the source store and shadows writes to the
+	// target store
+	PhaseDualWrite Phase = "dual_write"
+	// PhaseCutover serves from the target store and shadows writes back to
+	// the source store, so that the migration can still be rolled back
+	PhaseCutover Phase = "cutover"
+)
+
+// IsValid checks if the phase is valid
+func (p Phase) IsValid() bool {
+	return p == PhaseDualWrite || p == PhaseCutover
+}
+
+// ParsePhase converts a configuration value into a Phase
+func ParsePhase(value string) (Phase, error) {
+	phase := Phase(value)
+	if !phase.IsValid() {
+		return "", fmt.Errorf("invalid storage migration phase %q", value)
+	}
+	return phase, nil
+}
+
+// ErrShadowBacklog is returned when switching phases while shadow writes are
+// still waiting to be applied
+var ErrShadowBacklog = errors.New("shadow writes are still pending")
+
+// Store holds the repositories of one storage backend. Holds and Annotations
+// may be nil when the service does not use them.
+type Store struct {
+	UnitOfWork   repositories.UnitOfWork
+	Users        repositories.UserRepository
+	Wallets      repositories.WalletRepository
+	Transactions repositories.TransactionRepository
+	Holds        repositories.HoldRepository
+	Annotations  repositories.AnnotationRepository
+	// Promote readies the store to serve as the primary, e.g. by advancing ID
+	// sequences past the rows replayed into it; may be nil
+	Promote func(ctx context.Context) error
+}
+
+// Status describes the progress of the migration
+type Status struct {
+	Phase Phase `json:"phase"`
+	// Pending is the number of committed writes not yet applied to the shadow
+	Pending int `json:"pending"`
+	// Applied and Failed count the units of work replayed on the shadow
+	Applied uint64 `json:"applied"`
+	Failed  uint64 `json:"failed"`
+	// Dropped counts the units of work not shadowed because the queue was full
+	Dropped uint64 `json:"dropped"`
+	// Compared and Mismatches count the records compared between the stores
+	// after their writes were shadowed
+	Compared   uint64    `json:"compared"`
+	Mismatches uint64    `json:"mismatches"`
+	ChangedAt  time.Time `json:"changedAt"`
+}
+
+// Migrator migrates the service between two storage backends without
+// downtime. The repositories it returns serve reads from the primary store
+// and write to it synchronously; once a write commits, it is replayed on the
+// shadow store in the background, and the records it touched are compared
+// between both stores. Mismatches are logged and counted, but never affect
+// requests.
+//
+// Replays copy the IDs allocated by the primary store, so the shadow store
+// must accept explicit IDs. Data written before the migration started must
+// be copied separately.
+type Migrator struct {
+	source Store
+	target Store
+	queue  chan *journal
+	logger zerolog.Logger
+
+	// switching is held shared by writes from their primary write until
+	// they are queued, and exclusively while the phase changes
+	switching sync.RWMutex
+
+	mu     sync.Mutex
+	status Status
+	// pendingUsers counts the queued units of work per user, so that users
+	// are compared only once all of their writes were shadowed
+	pendingUsers map[uint64]int
+	drainPoll    time.Duration
+}
+
+// NewMigrator creates a new Migrator that queues up to queueSize units of
+// work for the shadow store
+func NewMigrator(source, target Store, phase Phase, queueSize int, logger zerolog.Logger) *Migrator {
+	return &Migrator{
+		source: source,
+		target: target,
+		queue:  make(chan *journal, queueSize),
+		logger: logger,
+		status: Status{
+			Phase:     phase,
+			ChangedAt: time.Now(),
+		},
+		pendingUsers: make(map[uint64]int),
+		drainPoll:    10 * time.Millisecond,
+	}
+}
+
+// Status returns the current status
+func (m *Migrator) Status() Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.status
+}
+
+// Phase returns the current phase
+func (m *Migrator) Phase() Phase {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.status.Phase
+}
+
+// Cutover makes the target store the primary
+func (m *Migrator) Cutover(ctx context.Context) (Status, error) {
+	return m.switchTo(ctx, PhaseCutover)
+}
+
+// Rollback makes the source store the primary again
+func (m *Migrator) Rollback(ctx context.Context) (Status, error) {
+	return m.switchTo(ctx, PhaseDualWrite)
+}
+
+// switchTo pauses writes until the shadow store has caught up, promotes it
+// and makes it the primary. If it does not catch up before ctx is done,
+// ErrShadowBacklog is returned and the phase is kept.
+func (m *Migrator) switchTo(ctx context.Context, phase Phase) (Status, error) {
+	m.switching.Lock()
+	defer m.switching.Unlock()
+
+	if m.Phase() == phase {
+		return m.Status(), nil
+	}
+
+	ticker := time.NewTicker(m.drainPoll)
+	defer ticker.Stop()
+	for m.Status().Pending > 0 {
+		select {
+		case <-ctx.Done():
+			return m.Status(), fmt.Errorf("%w: %v", ErrShadowBacklog, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+
+	_, shadow := m.storesFor(m.Phase())
+	if shadow.Promote != nil {
+		if err := shadow.Promote(ctx); err != nil {
+			return m.Status(), fmt.Errorf("failed to promote store: %w", err)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.status.Phase = phase
+	m.status.ChangedAt = time.Now()
+	return m.status, nil
+}
+
+// storesFor returns the primary and shadow stores of phase
+func (m *Migrator) storesFor(phase Phase) (primary, shadow *Store) {
+	if phase == PhaseCutover {
+		return &m.target, &m.source
+	}
+	return &m.source, &m.target
+}
+
+// stores returns the stores serving ctx. A unit of work keeps the stores it
+// started with.
+func (m *Migrator) stores(ctx context.Context) (primary, shadow *Store) {
+	if j, ok := journalFromContext(ctx); ok {
+		return m.storesFor(j.phase)
+	}
+	return m.storesFor(m.Phase())
+}
+
+// Run replays queued writes on the shadow store until ctx is done, then
+// replays the writes still queued
+func (m *Migrator) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			m.drain(context.WithoutCancel(ctx))
+			return
+		case j := <-m.queue:
+			m.apply(ctx, j)
+		}
+	}
+}
+
+// drain replays the writes queued so far
+func (m *Migrator) drain(ctx context.Context) {
+	for {
+		select {
+		case j := <-m.queue:
+			m.apply(ctx, j)
+		default:
+			return
+		}
+	}
+}
+
+// enqueue queues a committed unit of work for the shadow store, dropping it
+// if the queue is full so that the primary is never slowed down
+func (m *Migrator) enqueue(ctx context.Context, j *journal) {
+	if len(j.ops) == 0 {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	select {
+	case m.queue <- j:
+		m.status.Pending++
+		for userID := range j.users {
+			m.pendingUsers[userID]++
+		}
+	default:
+		m.status.Dropped++
+		logging.FromContext(ctx, &m.logger).Error().
+			Str("phase", string(j.phase)).
+			Msg("shadow write queue is full, dropping write")
+	}
+}
+
+// apply replays j on its shadow store and compares the records it touched
+func (m *Migrator) apply(ctx context.Context, j *journal) {
+	primary, shadow := m.storesFor(j.phase)
+
+	err := shadow.UnitOfWork.WithinTransaction(ctx, func(ctx context.Context) error {
+		for _, op := range j.ops {
+			if err := op(ctx, shadow); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	settled := m.settle(j, err)
+	if err != nil {
+		m.logger.Error().Err(err).Str("phase", string(j.phase)).Msg("failed to apply shadow write")
+		return
+	}
+
+	mismatches := 0
+	compared := 0
+	for _, userID := range settled {
+		compared++
+		if m.compareUser(ctx, primary, shadow, userID) {
+			mismatches++
+		}
+	}
+	for _, transactionID := range j.transactions {
+		compared++
+		if m.compareTransaction(ctx, primary, shadow, transactionID) {
+			mismatches++
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.status.Compared += uint64(compared)
+	m.status.Mismatches += uint64(mismatches)
+}
+
+// settle records the outcome of applying j and returns the users it touched
+// that have no further writes queued
+func (m *Migrator) settle(j *journal, err error) []uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.status.Pending--
+	if err != nil {
+		m.status.Failed++
+	} else {
+		m.status.Applied++
+	}
+
+	var settled []uint64
+	for userID := range j.users {
+		if m.pendingUsers[userID]--; m.pendingUsers[userID] <= 0 {
+			delete(m.pendingUsers, userID)
+			settled = append(settled, userID)
+		}
+	}
+	return settled
+}
+
+// operation is a write that can be run against either store
+type operation func(ctx context.Context, store *Store) error
+
+type journalContextKey struct{}
+
+// journal collects the writes of a unit of work, which are replayed on the
+// shadow store once it commits
+type journal struct {
+	phase Phase
+	ops   []operation
+	// users and transactions are compared once the writes were replayed
+	users        map[uint64]struct{}
+	transactions []string
+}
+
+func newJournal(phase Phase) *journal {
+	return &journal{phase: phase, users: make(map[uint64]struct{})}
+}
+
+func journalFromContext(ctx context.Context) (*journal, bool) {
+	j, ok := ctx.Value(journalContextKey{}).(*journal)
+	return j, ok
+}
+
+// record adds op to j. A zero userID means the write touches no user.
+func (j *journal) record(userID uint64, op operation) {
+	j.ops = append(j.ops, op)
+	if userID != 0 {
+		j.users[userID] = struct{}{}
+	}
+}
+
+// write runs op against the primary store and records it for the shadow
+// store. Outside a unit of work, op is queued as soon as it succeeds.
+func (m *Migrator) write(ctx context.Context, userID uint64, op operation) error {
+	return m.within(ctx, func(ctx context.Context, primary *Store, j *journal) error {
+		if err := op(ctx, primary); err != nil {
+			return err
+		}
+		j.record(userID, op)
+		return nil
+	})
+}
+
+// within runs fn with the primary store and the journal of the ambient unit
+// of work, or with a journal of its own that is queued if fn succeeds
+func (m *Migrator) within(ctx context.Context, fn func(ctx context.Context, primary *Store, j *journal) error) error {
+	if j, ok := journalFromContext(ctx); ok {
+		primary, _ := m.storesFor(j.phase)
+		return fn(ctx, primary, j)
+	}
+
+	m.switching.RLock()
+	defer m.switching.RUnlock()
+
+	j := newJournal(m.Phase())
+	primary, _ := m.storesFor(j.phase)
+	if err := fn(ctx, primary, j); err != nil {
+		return err
+	}
+	m.enqueue(ctx, j)
+	return nil
+}
@@ -0,0 +1,285 @@
+package dualwrite
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/rs/zerolog"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memStore is an in-memory store with users and transactions
+type memStore struct {
+	mu           sync.Mutex
+	users        map[uint64]*entities.User
+	transactions map[string]*entities.Transaction
+	nextID       uint64
+	promoted     bool
+}
+
+func newMemStore(users ...*entities.User) *memStore {
+	s := &memStore{
+		users:        make(map[uint64]*entities.User),
+		transactions: make(map[string]*entities.Transaction),
+		nextID:       100,
+	}
+	for _, user := range users {
+		s.users[user.ID] = user
+	}
+	return s
+}
+
+func (s *memStore) store() Store {
+	return Store{
+		UnitOfWork:   memUnitOfWork{},
+		Users:        &memUsers{s: s},
+		Transactions: &memTransactions{s: s},
+		Promote: func(context.Context) error {
+			s.promoted = true
+			return nil
+		},
+	}
+}
+
+func (s *memStore) user(id uint64) *entities.User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.users[id]
+}
+
+type memUnitOfWork struct{}
+
+func (memUnitOfWork) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+type memUsers struct {
+	repositories.UserRepository
+	s *memStore
+}
+
+func (r *memUsers) GetByID(ctx context.Context, userID uint64) (*entities.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	user, ok := r.s.users[userID]
+	if !ok {
+		return nil, repositories.ErrNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *memUsers) Create(ctx context.Context, user *entities.User) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if user.ID == 0 {
+		r.s.nextID++
+		user.ID = r.s.nextID
+	}
+	user.Status = entities.UserStatusActive
+	copied := *user
+	r.s.users[user.ID] = &copied
+	return nil
+}
+
+func (r *memUsers) UpdateBalance(ctx context.Context, userID uint64, newBalance decimal.Decimal) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	user, ok := r.s.users[userID]
+	if !ok {
+		return repositories.ErrNotFound
+	}
+	user.Balance = newBalance
+	return nil
+}
+
+func (r *memUsers) UpdateStatus(ctx context.Context, userID uint64, status entities.UserStatus) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	user, ok := r.s.users[userID]
+	if !ok {
+		return repositories.ErrNotFound
+	}
+	user.Status = status
+	return nil
+}
+
+type memTransactions struct {
+	repositories.TransactionRepository
+	s *memStore
+}
+
+func (r *memTransactions) Create(ctx context.Context, transaction *entities.Transaction) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if transaction.ID == 0 {
+		r.s.nextID++
+		transaction.ID = r.s.nextID
+	}
+	copied := *transaction
+	r.s.transactions[transaction.TransactionID] = &copied
+	return nil
+}
+
+func (r *memTransactions) GetByTransactionID(ctx context.Context, transactionID string) (*entities.Transaction, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	transaction, ok := r.s.transactions[transactionID]
+	if !ok {
+		return nil, repositories.ErrNotFound
+	}
+	copied := *transaction
+	return &copied, nil
+}
+
+func newTestMigrator(source, target *memStore, phase Phase, queueSize int) *Migrator {
+	m := NewMigrator(source.store(), target.store(), phase, queueSize, zerolog.Nop())
+	m.drainPoll = time.Millisecond
+	return m
+}
+
+func TestMigrator_DualWrite(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("committed writes are shadowed and compared", func(t *testing.T) {
+		source, target := newMemStore(), newMemStore()
+		target.nextID = 500
+		m := newTestMigrator(source, target, PhaseDualWrite, 10)
+
+		var userID uint64
+		err := m.UnitOfWork().WithinTransaction(ctx, func(ctx context.Context) error {
+			user := &entities.User{Balance: decimal.NewFromInt(10)}
+			if err := m.Users().Create(ctx, user); err != nil {
+				return err
+			}
+			userID = user.ID
+			if err := m.Transactions().Create(ctx, &entities.Transaction{
+				UserID: user.ID, TransactionID: "tx-1", Amount: decimal.NewFromInt(5),
+			}); err != nil {
+				return err
+			}
+			return m.Users().UpdateBalance(ctx, user.ID, decimal.NewFromInt(15))
+		})
+		require.NoError(t, err)
+
+		// The primary is written synchronously, the shadow in the background
+		assert.Nil(t, target.user(userID))
+		assert.Equal(t, 1, m.Status().Pending)
+
+		m.drain(ctx)
+
+		shadowed := target.user(userID)
+		require.NotNil(t, shadowed, "replays keep the IDs allocated by the primary")
+		assert.Equal(t, "15", shadowed.Balance.String())
+		assert.Equal(t, source.transactions["tx-1"].ID, target.transactions["tx-1"].ID)
+
+		status := m.Status()
+		assert.Equal(t, 0, status.Pending)
+		assert.Equal(t, uint64(1), status.Applied)
+		assert.Equal(t, uint64(2), status.Compared)
+		assert.Equal(t, uint64(0), status.Mismatches)
+	})
+
+	t.Run("failed units of work are not shadowed", func(t *testing.T) {
+		source, target := newMemStore(), newMemStore()
+		m := newTestMigrator(source, target, PhaseDualWrite, 10)
+
+		err := m.UnitOfWork().WithinTransaction(ctx, func(ctx context.Context) error {
+			if err := m.Users().Create(ctx, &entities.User{}); err != nil {
+				return err
+			}
+			return errors.New("insufficient funds")
+		})
+		require.Error(t, err)
+
+		assert.Equal(t, 0, m.Status().Pending)
+		assert.Empty(t, target.users)
+	})
+
+	t.Run("differences between the stores are reported", func(t *testing.T) {
+		source := newMemStore(&entities.User{ID: 1, Balance: decimal.NewFromInt(10)})
+		target := newMemStore(&entities.User{ID: 1, Balance: decimal.NewFromInt(7)})
+		m := newTestMigrator(source, target, PhaseDualWrite, 10)
+
+		require.NoError(t, m.Users().UpdateStatus(ctx, 1, entities.UserStatusFrozen))
+		m.drain(ctx)
+
+		assert.Equal(t, entities.UserStatusFrozen, target.user(1).Status)
+		assert.Equal(t, uint64(1), m.Status().Mismatches)
+	})
+
+	t.Run("full queues drop writes instead of blocking", func(t *testing.T) {
+		source := newMemStore(&entities.User{ID: 1}, &entities.User{ID: 2})
+		m := newTestMigrator(source, newMemStore(), PhaseDualWrite, 1)
+
+		require.NoError(t, m.Users().UpdateStatus(ctx, 1, entities.UserStatusFrozen))
+		require.NoError(t, m.Users().UpdateStatus(ctx, 2, entities.UserStatusFrozen))
+
+		status := m.Status()
+		assert.Equal(t, 1, status.Pending)
+		assert.Equal(t, uint64(1), status.Dropped)
+	})
+}
+
+func TestMigrator_Cutover(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("cutover serves from the target and shadows to the source", func(t *testing.T) {
+		source := newMemStore(&entities.User{ID: 1, Balance: decimal.NewFromInt(10)})
+		target := newMemStore(&entities.User{ID: 1, Balance: decimal.NewFromInt(10)})
+		m := newTestMigrator(source, target, PhaseDualWrite, 10)
+
+		status, err := m.Cutover(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, PhaseCutover, status.Phase)
+		assert.True(t, target.promoted)
+
+		require.NoError(t, m.Users().UpdateBalance(ctx, 1, decimal.NewFromInt(20)))
+		assert.Equal(t, "20", target.user(1).Balance.String())
+		assert.Equal(t, "10", source.user(1).Balance.String())
+
+		m.drain(ctx)
+		assert.Equal(t, "20", source.user(1).Balance.String())
+
+		status, err = m.Rollback(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, PhaseDualWrite, status.Phase)
+		assert.True(t, source.promoted)
+	})
+
+	t.Run("cutover waits for the shadow to catch up", func(t *testing.T) {
+		source := newMemStore(&entities.User{ID: 1})
+		m := newTestMigrator(source, newMemStore(&entities.User{ID: 1}), PhaseDualWrite, 10)
+		require.NoError(t, m.Users().UpdateStatus(ctx, 1, entities.UserStatusFrozen))
+
+		timeout, cancel := context.WithTimeout(ctx, 5*time.Millisecond)
+		defer cancel()
+		_, err := m.Cutover(timeout)
+		assert.ErrorIs(t, err, ErrShadowBacklog)
+		assert.Equal(t, PhaseDualWrite, m.Phase())
+
+		runCtx, stop := context.WithCancel(ctx)
+		defer stop()
+		go m.Run(runCtx)
+
+		status, err := m.Cutover(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, PhaseCutover, status.Phase)
+		assert.Equal(t, 0, status.Pending)
+	})
+}
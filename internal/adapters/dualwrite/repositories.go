@@ -0,0 +1,397 @@
+package dualwrite
+
+import (
+	"context"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/shopspring/decimal"
+)
+
+// UnitOfWork runs units of work on the primary store and queues their writes
+// for the shadow store once they commit. Writes whose commit failed are not
+// shadowed; the comparison of the next write of the same user reports them.
+func (m *Migrator) UnitOfWork() repositories.UnitOfWork {
+	return &unitOfWork{m: m}
+}
+
+type unitOfWork struct {
+	m *Migrator
+}
+
+func (u *unitOfWork) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := journalFromContext(ctx); ok {
+		return fn(ctx)
+	}
+
+	u.m.switching.RLock()
+	defer u.m.switching.RUnlock()
+
+	phase := u.m.Phase()
+	primary, _ := u.m.storesFor(phase)
+	var j *journal
+	err := primary.UnitOfWork.WithinTransaction(ctx, func(ctx context.Context) error {
+		// A retried unit of work starts over with an empty journal
+		j = newJournal(phase)
+		return fn(context.WithValue(ctx, journalContextKey{}, j))
+	})
+	if err != nil {
+		return err
+	}
+
+	u.m.enqueue(ctx, j)
+	return nil
+}
+
+// Users returns the user repository of the migration
+func (m *Migrator) Users() repositories.UserRepository {
+	return &userRepository{m: m}
+}
+
+type userRepository struct {
+	m *Migrator
+}
+
+func (r *userRepository) GetByID(ctx context.Context, userID uint64) (*entities.User, error) {
+	primary, _ := r.m.stores(ctx)
+	return primary.Users.GetByID(ctx, userID)
+}
+
+func (r *userRepository) GetByIDForUpdate(ctx context.Context, userID uint64) (*entities.User, error) {
+	primary, _ := r.m.stores(ctx)
+	return primary.Users.GetByIDForUpdate(ctx, userID)
+}
+
+func (r *userRepository) List(ctx context.Context, limit, offset int) ([]*entities.User, int, error) {
+	primary, _ := r.m.stores(ctx)
+	return primary.Users.List(ctx, limit, offset)
+}
+
+func (r *userRepository) UpdateBalance(ctx context.Context, userID uint64, newBalance decimal.Decimal) error {
+	return r.m.write(ctx, userID, func(ctx context.Context, store *Store) error {
+		return store.Users.UpdateBalance(ctx, userID, newBalance)
+	})
+}
+
+func (r *userRepository) Create(ctx context.Context, user *entities.User) error {
+	return r.m.within(ctx, func(ctx context.Context, primary *Store, j *journal) error {
+		if err := primary.Users.Create(ctx, user); err != nil {
+			return err
+		}
+		created := *user
+		j.record(user.ID, func(ctx context.Context, store *Store) error {
+			replayed := created
+			return store.Users.Create(ctx, &replayed)
+		})
+		return nil
+	})
+}
+
+func (r *userRepository) UpdateStatus(ctx context.Context, userID uint64, status entities.UserStatus) error {
+	return r.m.write(ctx, userID, func(ctx context.Context, store *Store) error {
+		return store.Users.UpdateStatus(ctx, userID, status)
+	})
+}
+
+func (r *userRepository) UpdateJurisdiction(ctx context.Context, userID uint64, jurisdiction string) error {
+	return r.m.write(ctx, userID, func(ctx context.Context, store *Store) error {
+		return store.Users.UpdateJurisdiction(ctx, userID, jurisdiction)
+	})
+}
+
+func (r *userRepository) UpdateCreditLimit(ctx context.Context, userID uint64, creditLimit decimal.Decimal) error {
+	return r.m.write(ctx, userID, func(ctx context.Context, store *Store) error {
+		return store.Users.UpdateCreditLimit(ctx, userID, creditLimit)
+	})
+}
+
+func (r *userRepository) LockIdleSince(ctx context.Context, since time.Time, limit int) ([]*entities.User, error) {
+	primary, _ := r.m.stores(ctx)
+	return primary.Users.LockIdleSince(ctx, since, limit)
+}
+
+func (r *userRepository) UpdateDormancy(ctx context.Context, userID uint64, dormantAt *time.Time) error {
+	return r.m.write(ctx, userID, func(ctx context.Context, store *Store) error {
+		return store.Users.UpdateDormancy(ctx, userID, dormantAt)
+	})
+}
+
+// Wallets returns the wallet repository of the migration
+func (m *Migrator) Wallets() repositories.WalletRepository {
+	return &walletRepository{m: m}
+}
+
+type walletRepository struct {
+	m *Migrator
+}
+
+func (r *walletRepository) GetForUpdate(ctx context.Context, userID uint64, currency string) (*entities.Wallet, error) {
+	var wallet *entities.Wallet
+	err := r.m.within(ctx, func(ctx context.Context, primary *Store, j *journal) error {
+		var err error
+		if wallet, err = primary.Wallets.GetForUpdate(ctx, userID, currency); err != nil {
+			return err
+		}
+		// Getting a wallet creates it if needed
+		j.record(userID, func(ctx context.Context, store *Store) error {
+			_, err := store.Wallets.GetForUpdate(ctx, userID, currency)
+			return err
+		})
+		return nil
+	})
+	return wallet, err
+}
+
+func (r *walletRepository) UpdateBalance(ctx context.Context, userID uint64, currency string, newBalance decimal.Decimal) error {
+	return r.m.write(ctx, userID, func(ctx context.Context, store *Store) error {
+		return store.Wallets.UpdateBalance(ctx, userID, currency, newBalance)
+	})
+}
+
+func (r *walletRepository) ListByUser(ctx context.Context, userID uint64) ([]*entities.Wallet, error) {
+	primary, _ := r.m.stores(ctx)
+	return primary.Wallets.ListByUser(ctx, userID)
+}
+
+// Transactions returns the transaction repository of the migration
+func (m *Migrator) Transactions() repositories.TransactionRepository {
+	return &transactionRepository{m: m}
+}
+
+type transactionRepository struct {
+	m *Migrator
+}
+
+func (r *transactionRepository) Create(ctx context.Context, transaction *entities.Transaction) error {
+	return r.m.within(ctx, func(ctx context.Context, primary *Store, j *journal) error {
+		if err := primary.Transactions.Create(ctx, transaction); err != nil {
+			return err
+		}
+		created := *transaction
+		j.record(transaction.UserID, func(ctx context.Context, store *Store) error {
+			replayed := created
+			return store.Transactions.Create(ctx, &replayed)
+		})
+		j.transactions = append(j.transactions, transaction.TransactionID)
+		return nil
+	})
+}
+
+func (r *transactionRepository) ExistsByTransactionID(ctx context.Context, transactionID string) (bool, error) {
+	primary, _ := r.m.stores(ctx)
+	return primary.Transactions.ExistsByTransactionID(ctx, transactionID)
+}
+
+func (r *transactionRepository) GetByTransactionID(ctx context.Context, transactionID string) (*entities.Transaction, error) {
+	primary, _ := r.m.stores(ctx)
+	return primary.Transactions.GetByTransactionID(ctx, transactionID)
+}
+
+func (r *transactionRepository) GetByTransactionIDForUpdate(ctx context.Context, transactionID string) (*entities.Transaction, error) {
+	primary, _ := r.m.stores(ctx)
+	return primary.Transactions.GetByTransactionIDForUpdate(ctx, transactionID)
+}
+
+func (r *transactionRepository) GetByUserID(ctx context.Context, userID uint64) ([]*entities.Transaction, error) {
+	primary, _ := r.m.stores(ctx)
+	return primary.Transactions.GetByUserID(ctx, userID)
+}
+
+func (r *transactionRepository) NetChangeSince(ctx context.Context, userID uint64, since time.Time) (decimal.Decimal, error) {
+	primary, _ := r.m.stores(ctx)
+	return primary.Transactions.NetChangeSince(ctx, userID, since)
+}
+
+func (r *transactionRepository) VelocitySince(ctx context.Context, userID uint64, since time.Time) (repositories.Velocity, error) {
+	primary, _ := r.m.stores(ctx)
+	return primary.Transactions.VelocitySince(ctx, userID, since)
+}
+
+func (r *transactionRepository) MostActiveUsers(ctx context.Context, since time.Time, limit int) ([]uint64, error) {
+	primary, _ := r.m.stores(ctx)
+	return primary.Transactions.MostActiveUsers(ctx, since, limit)
+}
+
+func (r *transactionRepository) Search(
+	ctx context.Context,
+	filter repositories.TransactionFilter,
+) ([]*entities.Transaction, int, error) {
+	primary, _ := r.m.stores(ctx)
+	return primary.Transactions.Search(ctx, filter)
+}
+
+func (r *transactionRepository) SummarizeRound(
+	ctx context.Context,
+	userID uint64,
+	roundID, currency string,
+) (repositories.RoundTotals, error) {
+	primary, _ := r.m.stores(ctx)
+	return primary.Transactions.SummarizeRound(ctx, userID, roundID, currency)
+}
+
+func (r *transactionRepository) SummarizeDays(
+	ctx context.Context,
+	userID uint64,
+	currency string,
+	from, to time.Time,
+) ([]repositories.DayTotals, error) {
+	primary, _ := r.m.stores(ctx)
+	return primary.Transactions.SummarizeDays(ctx, userID, currency, from, to)
+}
+
+func (r *transactionRepository) LockLatestOddUncancelled(ctx context.Context, limit int) ([]*entities.Transaction, error) {
+	primary, _ := r.m.stores(ctx)
+	return primary.Transactions.LockLatestOddUncancelled(ctx, limit)
+}
+
+func (r *transactionRepository) MarkCancelled(ctx context.Context, id uint64, cancelledAt time.Time) error {
+	return r.m.write(ctx, 0, func(ctx context.Context, store *Store) error {
+		return store.Transactions.MarkCancelled(ctx, id, cancelledAt)
+	})
+}
+
+func (r *transactionRepository) MarkReversed(ctx context.Context, id uint64, reversedBy string) error {
+	return r.m.write(ctx, 0, func(ctx context.Context, store *Store) error {
+		return store.Transactions.MarkReversed(ctx, id, reversedBy)
+	})
+}
+
+func (r *transactionRepository) CheckUniqueIDs(
+	ctx context.Context,
+	from, to time.Time,
+	limit int,
+) (*repositories.UniquenessCheck, error) {
+	primary, _ := r.m.stores(ctx)
+	return primary.Transactions.CheckUniqueIDs(ctx, from, to, limit)
+}
+
+func (r *transactionRepository) SumPending(ctx context.Context, userID uint64) (decimal.Decimal, error) {
+	primary, _ := r.m.stores(ctx)
+	return primary.Transactions.SumPending(ctx, userID)
+}
+
+func (r *transactionRepository) ListPendingBefore(ctx context.Context, before time.Time, limit int) ([]*entities.Transaction, error) {
+	primary, _ := r.m.stores(ctx)
+	return primary.Transactions.ListPendingBefore(ctx, before, limit)
+}
+
+func (r *transactionRepository) LockPendingBefore(ctx context.Context, before time.Time, limit int) ([]*entities.Transaction, error) {
+	primary, _ := r.m.stores(ctx)
+	return primary.Transactions.LockPendingBefore(ctx, before, limit)
+}
+
+func (r *transactionRepository) MarkSettled(ctx context.Context, id uint64, settledAt time.Time) error {
+	return r.m.write(ctx, 0, func(ctx context.Context, store *Store) error {
+		return store.Transactions.MarkSettled(ctx, id, settledAt)
+	})
+}
+
+// Holds returns the hold repository of the migration
+func (m *Migrator) Holds() repositories.HoldRepository {
+	return &holdRepository{m: m}
+}
+
+type holdRepository struct {
+	m *Migrator
+}
+
+func (r *holdRepository) Create(ctx context.Context, hold *entities.Hold) error {
+	return r.m.within(ctx, func(ctx context.Context, primary *Store, j *journal) error {
+		if err := primary.Holds.Create(ctx, hold); err != nil {
+			return err
+		}
+		created := *hold
+		j.record(hold.UserID, func(ctx context.Context, store *Store) error {
+			replayed := created
+			return store.Holds.Create(ctx, &replayed)
+		})
+		return nil
+	})
+}
+
+func (r *holdRepository) GetByHoldIDForUpdate(ctx context.Context, holdID string) (*entities.Hold, error) {
+	primary, _ := r.m.stores(ctx)
+	return primary.Holds.GetByHoldIDForUpdate(ctx, holdID)
+}
+
+func (r *holdRepository) SumActive(ctx context.Context, userID uint64, now time.Time) (decimal.Decimal, error) {
+	primary, _ := r.m.stores(ctx)
+	return primary.Holds.SumActive(ctx, userID, now)
+}
+
+func (r *holdRepository) Settle(
+	ctx context.Context,
+	id uint64,
+	status entities.HoldStatus,
+	capturedAmount *decimal.Decimal,
+	settledAt time.Time,
+) error {
+	return r.m.write(ctx, 0, func(ctx context.Context, store *Store) error {
+		return store.Holds.Settle(ctx, id, status, capturedAmount, settledAt)
+	})
+}
+
+func (r *holdRepository) Expire(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	var holdIDs []string
+	err := r.m.within(ctx, func(ctx context.Context, primary *Store, j *journal) error {
+		var err error
+		if holdIDs, err = primary.Holds.Expire(ctx, now, limit); err != nil {
+			return err
+		}
+		// Expire exactly the holds the primary expired
+		expired := holdIDs
+		j.record(0, func(ctx context.Context, store *Store) error {
+			for _, holdID := range expired {
+				hold, err := store.Holds.GetByHoldIDForUpdate(ctx, holdID)
+				if err != nil {
+					return err
+				}
+				if err := store.Holds.Settle(ctx, hold.ID, entities.HoldStatusExpired, nil, now); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		return nil
+	})
+	return holdIDs, err
+}
+
+// Annotations returns the annotation repository of the migration
+func (m *Migrator) Annotations() repositories.AnnotationRepository {
+	return &annotationRepository{m: m}
+}
+
+type annotationRepository struct {
+	m *Migrator
+}
+
+func (r *annotationRepository) Create(ctx context.Context, annotation *entities.Annotation) error {
+	return r.m.within(ctx, func(ctx context.Context, primary *Store, j *journal) error {
+		if err := primary.Annotations.Create(ctx, annotation); err != nil {
+			return err
+		}
+		created := *annotation
+		j.record(0, func(ctx context.Context, store *Store) error {
+			replayed := created
+			return store.Annotations.Create(ctx, &replayed)
+		})
+		return nil
+	})
+}
+
+func (r *annotationRepository) ListByTarget(
+	ctx context.Context,
+	targetType entities.AnnotationTarget,
+	targetID string,
+) ([]*entities.Annotation, error) {
+	primary, _ := r.m.stores(ctx)
+	return primary.Annotations.ListByTarget(ctx, targetType, targetID)
+}
+
+func (r *annotationRepository) ListByUser(ctx context.Context, userID uint64) ([]*entities.Annotation, error) {
+	primary, _ := r.m.stores(ctx)
+	return primary.Annotations.ListByUser(ctx, userID)
+}
@@ -0,0 +1,34 @@
+// Package events publishes the balance change events recorded in the outbox.
+package events
+
+import (
+	"context"
+
+	"transaction-service/internal/domain/entities"
+
+	"github.com/rs/zerolog"
+)
+
+// LogPublisher writes events to the application log. It stands in for a
+// broker in development.
+type LogPublisher struct {
+	logger zerolog.Logger
+}
+
+// NewLogPublisher creates a new LogPublisher
+func NewLogPublisher(logger zerolog.Logger) *LogPublisher {
+	return &LogPublisher{logger: logger}
+}
+
+// Publish logs the events
+func (p *LogPublisher) Publish(ctx context.Context, events []*entities.OutboxEvent) error {
+	for _, event := range events {
+		p.logger.Info().
+			Uint64("event_id", event.ID).
+			Str("event_type", event.Type).
+			Str("key", event.Key).
+			RawJSON("payload", event.Payload).
+			Msg("event published")
+	}
+	return nil
+}
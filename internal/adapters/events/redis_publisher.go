@@ -0,0 +1,55 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStreamPublisher appends events to a Redis stream, which consumer
+// groups read with acknowledgements. Each entry holds the event's id, type,
+// key, payload and createdAt fields.
+type RedisStreamPublisher struct {
+	client *redis.Client
+	stream string
+	// maxLen caps the stream approximately; zero keeps every entry
+	maxLen int64
+}
+
+// NewRedisStreamPublisher creates a publisher appending to stream
+func NewRedisStreamPublisher(client *redis.Client, stream string, maxLen int64) *RedisStreamPublisher {
+	return &RedisStreamPublisher{
+		client: client,
+		stream: stream,
+		maxLen: maxLen,
+	}
+}
+
+// Publish appends the events in order in a single round trip
+func (p *RedisStreamPublisher) Publish(ctx context.Context, events []*entities.OutboxEvent) error {
+	pipe := p.client.Pipeline()
+	for _, event := range events {
+		pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: p.stream,
+			MaxLen: p.maxLen,
+			Approx: p.maxLen > 0,
+			Values: map[string]any{
+				"id":        strconv.FormatUint(event.ID, 10),
+				"type":      event.Type,
+				"key":       event.Key,
+				"payload":   string(event.Payload),
+				"createdAt": event.CreatedAt.UTC().Format(time.RFC3339Nano),
+			},
+		})
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to append events to stream %s: %w", p.stream, err)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisStreamPublisher_Publish(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	publisher := NewRedisStreamPublisher(client, "balance-events", 0)
+	createdAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	err := publisher.Publish(ctx, []*entities.OutboxEvent{
+		{ID: 1, Type: entities.EventTransactionProcessed, Key: "7", Payload: []byte(`{"userId":7}`), CreatedAt: createdAt},
+		{ID: 2, Type: entities.EventTransactionCancelled, Key: "7", Payload: []byte(`{"userId":7}`), CreatedAt: createdAt},
+	})
+	require.NoError(t, err)
+
+	entries, err := client.XRange(ctx, "balance-events", "-", "+").Result()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, map[string]any{
+		"id":        "1",
+		"type":      "transaction.processed",
+		"key":       "7",
+		"payload":   `{"userId":7}`,
+		"createdAt": "2026-01-02T03:04:05Z",
+	}, entries[0].Values)
+	assert.Equal(t, "transaction.cancelled", entries[1].Values["type"])
+
+	server.Close()
+	assert.Error(t, publisher.Publish(ctx, []*entities.OutboxEvent{{ID: 3}}))
+}
@@ -0,0 +1,54 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+
+	"transaction-service/internal/auth"
+	"transaction-service/internal/domain/entities"
+
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// apiKeyMetadata carries the integrator's API key, like X-API-Key over REST
+const apiKeyMetadata = "x-api-key"
+
+// APIKeyInterceptor is the gRPC counterpart of the REST API key middleware. It
+// requires an API key from store in the x-api-key metadata and rejects source
+// types the key is not bound to.
+func APIKeyInterceptor(store auth.APIKeyStore) grpclib.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		_ *grpclib.UnaryServerInfo,
+		handler grpclib.UnaryHandler,
+	) (any, error) {
+		var key string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(apiKeyMetadata); len(values) > 0 {
+				key = values[0]
+			}
+		}
+		if key == "" {
+			return nil, status.Error(codes.Unauthenticated, "API key is required")
+		}
+
+		apiKey, err := store.Lookup(ctx, key)
+		if err != nil {
+			if errors.Is(err, auth.ErrUnknownAPIKey) {
+				return nil, status.Error(codes.Unauthenticated, "invalid API key")
+			}
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		if r, ok := req.(interface{ GetSourceType() string }); ok {
+			if sourceType := entities.SourceType(r.GetSourceType()); sourceType.IsValid() && !apiKey.Allows(sourceType) {
+				return nil, status.Error(codes.PermissionDenied, "API key is not allowed to use this source type")
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
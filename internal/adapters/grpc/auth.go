@@ -0,0 +1,45 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+
+	"transaction-service/internal/auth"
+
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// AuthInterceptor is the gRPC counterpart of the REST JWT middleware. It
+// requires a bearer token in the authorization metadata and only lets callers
+// operate on the user named in its subject unless they carry the admin scope.
+func AuthInterceptor(verifier *auth.Verifier) grpclib.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		_ *grpclib.UnaryServerInfo,
+		handler grpclib.UnaryHandler,
+	) (any, error) {
+		var token string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get("authorization"); len(values) > 0 {
+				token, _ = strings.CutPrefix(values[0], "Bearer ")
+			}
+		}
+		if strings.TrimSpace(token) == "" {
+			return nil, status.Error(codes.Unauthenticated, "bearer token is required")
+		}
+
+		claims, err := verifier.Verify(strings.TrimSpace(token))
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, "invalid bearer token")
+		}
+		if r, ok := req.(interface{ GetUserId() uint64 }); ok && !verifier.CanAccessUser(claims, r.GetUserId()) {
+			return nil, status.Error(codes.PermissionDenied, "not allowed to operate on this user")
+		}
+
+		return handler(ctx, req)
+	}
+}
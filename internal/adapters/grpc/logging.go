@@ -0,0 +1,75 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"transaction-service/internal/logging"
+
+	"github.com/rs/zerolog"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// LoggingInterceptor is the gRPC counterpart of the REST request logger. It
+// propagates the caller's x-request-id metadata or assigns a new ID, returns
+// it in the response header, carries a request-scoped logger in the context
+// and logs one structured line per call.
+func LoggingInterceptor(logger zerolog.Logger) grpclib.UnaryServerInterceptor {
+	headerKey := strings.ToLower(logging.RequestIDHeader)
+
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpclib.UnaryServerInfo,
+		handler grpclib.UnaryHandler,
+	) (any, error) {
+		start := time.Now()
+
+		var supplied string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(headerKey); len(values) > 0 {
+				supplied = values[0]
+			}
+		}
+		requestID := logging.RequestID(supplied)
+		_ = grpclib.SetHeader(ctx, metadata.Pairs(headerKey, requestID))
+
+		requestLogger := logger.With().Str("request_id", requestID).Logger()
+		resp, err := handler(requestLogger.WithContext(ctx), req)
+
+		code := status.Code(err)
+		level := zerolog.InfoLevel
+		if err != nil {
+			level = zerolog.WarnLevel
+			if code == codes.Internal {
+				level = zerolog.ErrorLevel
+			}
+		}
+
+		event := requestLogger.WithLevel(level)
+		if r, ok := req.(interface{ GetUserId() uint64 }); ok && r.GetUserId() != 0 {
+			event = event.Uint64("user_id", r.GetUserId())
+		}
+		if r, ok := req.(interface{ GetTransactionId() string }); ok && r.GetTransactionId() != "" {
+			event = event.Str("transaction_id", r.GetTransactionId())
+		}
+		if r, ok := req.(interface{ GetSourceType() string }); ok && r.GetSourceType() != "" {
+			event = event.Str("source_type", r.GetSourceType())
+		}
+		if err != nil {
+			event = event.Err(err)
+		}
+
+		event.
+			Str("method", info.FullMethod).
+			Str("code", code.String()).
+			Dur("duration_ms", time.Since(start)).
+			Msg("call completed")
+
+		return resp, err
+	}
+}
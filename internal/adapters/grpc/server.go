@@ -0,0 +1,183 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"transaction-service/internal/adapters/grpc/transactionpb"
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Server adapts the TransactionService to the gRPC API. It shares the
+// service with the REST handlers, so both APIs apply the same rules.
+type Server struct {
+	transactionpb.UnimplementedTransactionServiceServer
+
+	transactionService *services.TransactionService
+}
+
+// NewServer creates a new gRPC server adapter
+func NewServer(transactionService *services.TransactionService) *Server {
+	return &Server{
+		transactionService: transactionService,
+	}
+}
+
+// ProcessTransaction implements transactionpb.TransactionServiceServer
+func (s *Server) ProcessTransaction(
+	ctx context.Context,
+	req *transactionpb.ProcessTransactionRequest,
+) (*transactionpb.ProcessTransactionResponse, error) {
+	if req.GetUserId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid user ID")
+	}
+	if req.GetState() == "" || req.GetAmount() == "" || req.GetTransactionId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "all fields (state, amount, transaction_id) are required")
+	}
+
+	txReq := entities.TransactionRequest{
+		State:         req.GetState(),
+		Amount:        req.GetAmount(),
+		TransactionID: req.GetTransactionId(),
+	}
+	if req.GetOccurredAt() != nil {
+		occurredAt := req.GetOccurredAt().AsTime()
+		txReq.OccurredAt = &occurredAt
+	}
+
+	result, err := s.transactionService.ProcessTransaction(ctx, req.GetUserId(), txReq, entities.SourceType(req.GetSourceType()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &transactionpb.ProcessTransactionResponse{
+		Balance:  result.Balance,
+		Replayed: result.Replayed,
+		Receipt:  result.Receipt,
+	}, nil
+}
+
+// GetBalance implements transactionpb.TransactionServiceServer
+func (s *Server) GetBalance(
+	ctx context.Context,
+	req *transactionpb.GetBalanceRequest,
+) (*transactionpb.GetBalanceResponse, error) {
+	if req.GetUserId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid user ID")
+	}
+
+	balance, err := s.transactionService.GetUserBalance(ctx, req.GetUserId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &transactionpb.GetBalanceResponse{
+		UserId:  balance.UserID,
+		Balance: balance.Balance,
+		Stale:   balance.Stale,
+		AsOf:    toTimestamp(balance.AsOf),
+	}, nil
+}
+
+// GetTransactions implements transactionpb.TransactionServiceServer
+func (s *Server) GetTransactions(
+	ctx context.Context,
+	req *transactionpb.GetTransactionsRequest,
+) (*transactionpb.GetTransactionsResponse, error) {
+	if req.GetUserId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid user ID")
+	}
+
+	page, err := s.transactionService.GetUserTransactions(ctx, req.GetUserId(), repositories.TransactionFilter{
+		Limit:  int(req.GetLimit()),
+		Offset: int(req.GetOffset()),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	transactions := make([]*transactionpb.Transaction, 0, len(page.Transactions))
+	for _, transaction := range page.Transactions {
+		transactions = append(transactions, toTransaction(transaction))
+	}
+
+	return &transactionpb.GetTransactionsResponse{
+		Transactions: transactions,
+		Total:        int32(page.Total),
+		Limit:        int32(page.Limit),
+		Offset:       int32(page.Offset),
+	}, nil
+}
+
+func toTransaction(transaction *entities.Transaction) *transactionpb.Transaction {
+	return &transactionpb.Transaction{
+		Id:            transaction.ID,
+		UserId:        transaction.UserID,
+		TransactionId: transaction.TransactionID,
+		State:         string(transaction.State),
+		Amount:        transaction.Amount.StringFixed(2),
+		SourceType:    string(transaction.SourceType),
+		OccurredAt:    toTimestamp(transaction.OccurredAt),
+		CreatedAt:     timestamppb.New(transaction.CreatedAt),
+		Cancelled:     transaction.Cancelled,
+		CancelledAt:   toTimestamp(transaction.CancelledAt),
+	}
+}
+
+func toTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+// toStatus maps service errors to gRPC status codes, mirroring the HTTP
+// status codes used by the REST handlers
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		return status.Error(codes.NotFound, "user not found")
+
+	case errors.Is(err, services.ErrDuplicateTransaction):
+		return status.Error(codes.AlreadyExists, "transaction ID already used for a different transaction")
+
+	case errors.Is(err, services.ErrInvalidAmount),
+		errors.Is(err, services.ErrAmountBelowMinimum),
+		errors.Is(err, services.ErrAmountAboveMaximum),
+		errors.Is(err, services.ErrAmountPrecision),
+		errors.Is(err, services.ErrInvalidTransactionState),
+		errors.Is(err, services.ErrInvalidSourceType),
+		errors.Is(err, services.ErrInvalidOccurredAt),
+		errors.Is(err, services.ErrInvalidRoundID),
+		errors.Is(err, services.ErrInvalidMetadata),
+		errors.Is(err, services.ErrInvalidFilter):
+		return status.Error(codes.InvalidArgument, err.Error())
+
+	case errors.Is(err, services.ErrInsufficientFunds),
+		errors.Is(err, services.ErrBalanceChangeLimitExceeded),
+		errors.Is(err, services.ErrLossLimitExceeded):
+		return status.Error(codes.FailedPrecondition, err.Error())
+
+	case errors.Is(err, services.ErrVelocityLimitExceeded):
+		return status.Error(codes.ResourceExhausted, err.Error())
+
+	case errors.Is(err, services.ErrAccountFrozen),
+		errors.Is(err, services.ErrSourceTypeNotAllowed),
+		errors.Is(err, services.ErrSystemAccount):
+		return status.Error(codes.PermissionDenied, err.Error())
+
+	case errors.Is(err, services.ErrRegionStandby),
+		errors.Is(err, services.ErrUnavailable):
+		return status.Error(codes.Unavailable, err.Error())
+
+	default:
+		return status.Error(codes.Internal, "internal server error: "+err.Error())
+	}
+}
@@ -0,0 +1,37 @@
+package grpc
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"transaction-service/internal/application/services"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestToStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want codes.Code
+	}{
+		{err: services.ErrUserNotFound, want: codes.NotFound},
+		{err: services.ErrDuplicateTransaction, want: codes.AlreadyExists},
+		{err: services.ErrInvalidAmount, want: codes.InvalidArgument},
+		{err: services.ErrInvalidFilter, want: codes.InvalidArgument},
+		{err: services.ErrInsufficientFunds, want: codes.FailedPrecondition},
+		{err: services.ErrAccountFrozen, want: codes.PermissionDenied},
+		{err: services.ErrRegionStandby, want: codes.Unavailable},
+		{err: fmt.Errorf("wrapped: %w", services.ErrUserNotFound), want: codes.NotFound},
+		{err: fmt.Errorf("failed to get user: %w", services.ErrUnavailable), want: codes.Unavailable},
+		{err: errors.New("connection refused"), want: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			assert.Equal(t, tt.want, status.Code(toStatus(tt.err)))
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/config"
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+	"transaction-service/internal/export"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminHandler handles administrative HTTP requests
+type AdminHandler struct {
+	cfg                *config.Config
+	transactionService *services.TransactionService
+	annotationService  *services.AnnotationService
+	accountService     *services.AccountService
+}
+
+// NewAdminHandler creates a new admin HTTP handler
+func NewAdminHandler(
+	cfg *config.Config,
+	transactionService *services.TransactionService,
+	annotationService *services.AnnotationService,
+	accountService *services.AccountService,
+) *AdminHandler {
+	return &AdminHandler{
+		cfg:                cfg,
+		transactionService: transactionService,
+		annotationService:  annotationService,
+		accountService:     accountService,
+	}
+}
+
+// SetupRoutes sets up the admin routes
+func (h *AdminHandler) SetupRoutes(router gin.IRouter) {
+	admin := router.Group("/admin")
+
+	// Effective configuration route
+	admin.GET("/config", h.GetConfig)
+
+	// Cross-user transaction search route
+	admin.GET("/transactions", h.SearchTransactions)
+
+	// Export verification route
+	admin.GET("/exports/:name/verify", h.VerifyExport)
+
+	// Account administration routes
+	admin.PUT("/users/:userId/jurisdiction", h.SetUserJurisdiction)
+	admin.PUT("/users/:userId/credit-limit", h.SetUserCreditLimit)
+
+	// Support annotation routes
+	admin.POST("/users/:userId/annotations", h.annotate(entities.AnnotationTargetUser, "userId"))
+	admin.GET("/users/:userId/annotations", h.listAnnotations(entities.AnnotationTargetUser, "userId"))
+	admin.POST("/transactions/:transactionId/annotations", h.annotate(entities.AnnotationTargetTransaction, "transactionId"))
+	admin.GET("/transactions/:transactionId/annotations", h.listAnnotations(entities.AnnotationTargetTransaction, "transactionId"))
+}
+
+// GetConfig handles GET /admin/config
+func (h *AdminHandler) GetConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, h.cfg.Redacted())
+}
+
+// SearchTransactions handles GET /admin/transactions
+func (h *AdminHandler) SearchTransactions(c *gin.Context) {
+	filter, err := parseTransactionFilter(c)
+	if err != nil {
+		respondWithProblem(c, problemInvalidQuery, err.Error())
+		return
+	}
+
+	page, err := h.transactionService.SearchTransactions(c.Request.Context(), filter)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidFilter) {
+			respondWithProblem(c, problemInvalidFilter,
+				"Invalid filter: ranges must be ordered, limit must not exceed 500, offset cannot be combined with cursor and metadataValue requires a valid metadataKey")
+			return
+		}
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// parseTransactionFilter reads the transaction search criteria from the query string
+func parseTransactionFilter(c *gin.Context) (repositories.TransactionFilter, error) {
+	filter, err := parseHistoryFilter(c)
+	if err != nil {
+		return filter, err
+	}
+
+	filter.TransactionIDPrefix = c.Query("transactionIdPrefix")
+	filter.MetadataKey = c.Query("metadataKey")
+	if value, ok := c.GetQuery("metadataValue"); ok {
+		filter.MetadataValue = &value
+	}
+	if filter.UserID, err = queryUint(c, "userId"); err != nil {
+		return filter, err
+	}
+	if filter.MinAmount, err = queryDecimal(c, "minAmount"); err != nil {
+		return filter, err
+	}
+	if filter.MaxAmount, err = queryDecimal(c, "maxAmount"); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+// VerifyExport handles GET /admin/exports/{name}/verify
+func (h *AdminHandler) VerifyExport(c *gin.Context) {
+	report, err := export.Verify(h.cfg.ExportDir, c.Param("name"))
+	if err != nil {
+		switch {
+		case errors.Is(err, export.ErrExportNotFound):
+			respondWithProblem(c, problemExportNotFound, "Export not found")
+
+		case errors.Is(err, export.ErrInvalidExportName):
+			respondWithProblem(c, problemInvalidExportName, "Invalid export name")
+
+		default:
+			respondWithError(c, err)
+		}
+		return
+	}
+
+	// A tampered or incomplete export is reported with its details
+	if !report.Valid {
+		c.JSON(http.StatusUnprocessableEntity, report)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// SetUserJurisdiction handles PUT /admin/users/{userId}/jurisdiction
+func (h *AdminHandler) SetUserJurisdiction(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("userId"), 10, 64)
+	if err != nil || userID == 0 {
+		respondWithProblem(c, problemInvalidUserID, "Invalid user ID. Must be a positive integer.")
+		return
+	}
+
+	var req struct {
+		Jurisdiction string `json:"jurisdiction"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondWithProblem(c, problemInvalidRequestBody, "Invalid request body: "+err.Error())
+		return
+	}
+
+	if err := h.accountService.SetJurisdiction(c.Request.Context(), userID, req.Jurisdiction); err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// SetUserCreditLimit handles PUT /admin/users/{userId}/credit-limit
+func (h *AdminHandler) SetUserCreditLimit(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("userId"), 10, 64)
+	if err != nil || userID == 0 {
+		respondWithProblem(c, problemInvalidUserID, "Invalid user ID. Must be a positive integer.")
+		return
+	}
+
+	var req struct {
+		CreditLimit string `json:"creditLimit" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondWithProblem(c, problemInvalidRequestBody, "Invalid request body: "+err.Error())
+		return
+	}
+
+	if err := h.accountService.SetCreditLimit(c.Request.Context(), userID, req.CreditLimit); err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// annotate handles POST /admin/{users|transactions}/{id}/annotations
+func (h *AdminHandler) annotate(targetType entities.AnnotationTarget, param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req entities.AnnotationRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondWithProblem(c, problemInvalidRequestBody, "Invalid request body: "+err.Error())
+			return
+		}
+
+		annotation, err := h.annotationService.Annotate(c.Request.Context(), targetType, c.Param(param), req)
+		if err != nil {
+			writeAnnotationError(c, targetType, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, annotation)
+	}
+}
+
+// listAnnotations handles GET /admin/{users|transactions}/{id}/annotations
+func (h *AdminHandler) listAnnotations(targetType entities.AnnotationTarget, param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		annotations, err := h.annotationService.ListAnnotations(c.Request.Context(), targetType, c.Param(param))
+		if err != nil {
+			writeAnnotationError(c, targetType, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"annotations": annotations,
+		})
+	}
+}
+
+func writeAnnotationError(c *gin.Context, targetType entities.AnnotationTarget, err error) {
+	if !errors.Is(err, services.ErrAnnotationTargetNotFound) {
+		respondWithError(c, err)
+		return
+	}
+	if targetType == entities.AnnotationTargetUser {
+		respondWithProblem(c, problemUserNotFound, "User not found")
+		return
+	}
+	respondWithProblem(c, problemTransactionNotFound, "Transaction not found")
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"errors"
+
+	"transaction-service/internal/auth"
+	"transaction-service/internal/domain/entities"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+)
+
+// apiKeyKey is the Gin context key holding the caller's resolved API key
+const apiKeyKey = "auth.apiKey"
+
+// APIKeyAuth requires every integrator request to carry an API key from store
+// in the X-API-Key header and rejects Source-Type headers the key is not bound
+// to. publicPaths and the admin routes, which are reserved for operators, are
+// exempt.
+func APIKeyAuth(store auth.APIKeyStore, publicPaths ...string) gin.HandlerFunc {
+	public := make(map[string]bool, len(publicPaths))
+	for _, path := range publicPaths {
+		public[path] = true
+	}
+
+	return func(c *gin.Context) {
+		if public[c.FullPath()] || isAdminRoute(c.FullPath()) {
+			c.Next()
+			return
+		}
+
+		key := c.GetHeader(APIKeyHeader)
+		if key == "" {
+			abortWithProblem(c, problemAPIKeyRequired, "X-API-Key header is required")
+			return
+		}
+		apiKey, err := store.Lookup(c.Request.Context(), key)
+		if err != nil {
+			if errors.Is(err, auth.ErrUnknownAPIKey) {
+				abortWithProblem(c, problemInvalidAPIKey, "Invalid API key")
+				return
+			}
+			abortWithProblem(c, problemInternal, "Internal server error: "+err.Error())
+			return
+		}
+		c.Set(apiKeyKey, apiKey)
+		zerolog.Ctx(c.Request.Context()).UpdateContext(func(l zerolog.Context) zerolog.Context {
+			return l.Str("api_key_id", apiKey.ID)
+		})
+
+		// Unknown source types are left for the handlers to reject
+		if sourceType := entities.SourceType(c.GetHeader("Source-Type")); sourceType.IsValid() && !apiKey.Allows(sourceType) {
+			abortWithProblem(c, problemSourceTypeForbidden, "API key is not allowed to use this Source-Type")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// APIKeyFromContext returns the API key the caller authenticated with, if any
+func APIKeyFromContext(c *gin.Context) (*auth.APIKey, bool) {
+	value, ok := c.Get(apiKeyKey)
+	if !ok {
+		return nil, false
+	}
+	apiKey, ok := value.(*auth.APIKey)
+	return apiKey, ok
+}
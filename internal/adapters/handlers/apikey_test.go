@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"transaction-service/internal/auth"
+	"transaction-service/internal/domain/entities"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newAPIKeyRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(APIKeyAuth(auth.NewStaticAPIKeyStore(map[string][]entities.SourceType{
+		"game-key": {entities.SourceTypeGame},
+	}), "/readyz"))
+
+	ok := func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	}
+	router.GET("/readyz", ok)
+	router.GET("/admin/config", ok)
+	router.POST("/user/:userId/transaction", ok)
+
+	return router
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	router := newAPIKeyRouter()
+
+	tests := []struct {
+		name       string
+		path       string
+		apiKey     string
+		sourceType string
+		wantStatus int
+	}{
+		{
+			name:       "public paths need no key",
+			path:       "/readyz",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "admin routes need no key",
+			path:       "/admin/config",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "missing key",
+			path:       "/user/1/transaction",
+			sourceType: "game",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "unknown key",
+			path:       "/user/1/transaction",
+			apiKey:     "other-key",
+			sourceType: "game",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "bound source type",
+			path:       "/user/1/transaction",
+			apiKey:     "game-key",
+			sourceType: "game",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "mismatched source type",
+			path:       "/user/1/transaction",
+			apiKey:     "game-key",
+			sourceType: "payment",
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := http.MethodPost
+			if tt.path != "/user/1/transaction" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, tt.path, nil)
+			if tt.apiKey != "" {
+				req.Header.Set(APIKeyHeader, tt.apiKey)
+			}
+			if tt.sourceType != "" {
+				req.Header.Set("Source-Type", tt.sourceType)
+			}
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/domain/entities"
+
+	"github.com/gin-gonic/gin"
+)
+
+// preferAsync is the preference (RFC 7240) of the callers asking for their
+// transaction to be processed in the background
+const preferAsync = "respond-async"
+
+// WithAsyncProcessor processes the transactions of the callers sending
+// Prefer: respond-async in the background with processor
+func WithAsyncProcessor(processor *services.AsyncProcessor) HandlerOption {
+	return func(h *Handler) {
+		h.asyncProcessor = processor
+	}
+}
+
+// prefersAsync reports whether the caller asked for asynchronous processing
+func prefersAsync(c *gin.Context) bool {
+	for _, header := range c.Request.Header.Values("Prefer") {
+		for _, preference := range strings.Split(header, ",") {
+			name, _, _ := strings.Cut(preference, ";")
+			if strings.EqualFold(strings.TrimSpace(name), preferAsync) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// submitAsync queues a validated transaction, answering 202 Accepted with the
+// URL its status is reported at
+func (h *Handler) submitAsync(c *gin.Context, userID uint64, req entities.TransactionRequest, sourceType entities.SourceType) {
+	status, err := h.asyncProcessor.Submit(c.Request.Context(), userID, req, sourceType)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	response, err := asyncTransactionResponse(c, status)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+	c.Header("Location", response["statusUrl"].(string))
+	c.Header("Preference-Applied", preferAsync)
+	c.JSON(http.StatusAccepted, response)
+}
+
+// GetTransactionStatus handles GET /transaction/{transactionId}/status,
+// reporting whether a transaction submitted for asynchronous processing is
+// pending, processed or failed. Transactions recorded in the ledger are
+// reported as processed whichever way they were submitted.
+func (h *Handler) GetTransactionStatus(c *gin.Context) {
+	transactionID := c.Param("transactionId")
+	logTransactionID(c, transactionID)
+
+	statusOf := h.transactionService.TransactionStatus
+	if h.asyncProcessor != nil {
+		statusOf = h.asyncProcessor.Status
+	}
+	status, err := statusOf(c.Request.Context(), transactionID)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	response, err := asyncTransactionResponse(c, status)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// asyncTransactionResponse describes the status of a transaction: the result
+// of a processed one, with amounts in minor units for the callers using them,
+// and the problem details a failed one was rejected with
+func asyncTransactionResponse(c *gin.Context, status *entities.AsyncTransaction) (gin.H, error) {
+	response := gin.H{
+		"transactionId": status.TransactionID,
+		"userId":        status.UserID,
+		"status":        status.Status,
+		"statusUrl":     versionPath(c) + transactionPath + "/" + url.PathEscape(status.TransactionID) + "/status",
+	}
+	if status.SubmittedAt != nil {
+		response["submittedAt"] = status.SubmittedAt
+	}
+	if status.FinishedAt != nil {
+		response["finishedAt"] = status.FinishedAt
+	}
+
+	if status.Result != nil {
+		currency, minorUnits := minorUnitsCurrency(c)
+		result, err := transactionResultResponse(status.Result, currency, minorUnits)
+		if err != nil {
+			return nil, err
+		}
+		response["result"] = result
+	}
+	if status.Err != nil {
+		problem, detail := errorProblem(status.Err)
+		response["problem"] = newProblem(c, problem, detail)
+	}
+	return response, nil
+}
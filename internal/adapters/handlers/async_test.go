@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"transaction-service/internal/adapters/memory"
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/processor"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAsyncTransactions(t *testing.T) {
+	newRouter := func(t *testing.T, async bool) *gin.Engine {
+		store := memory.NewStore()
+		memory.SeedUsers(store, []*entities.User{{ID: 1, Balance: decimal.NewFromInt(10)}})
+		service := services.NewTransactionService(
+			memory.NewUnitOfWork(store), memory.NewUserRepository(store), memory.NewTransactionRepository(store),
+			services.WithCurrencies(memory.NewWalletRepository(store), eur.Code),
+		)
+
+		var opts []HandlerOption
+		if async {
+			pool := processor.New(2, 10)
+			ctx, cancel := context.WithCancel(context.Background())
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				pool.Run(ctx)
+			}()
+			t.Cleanup(func() {
+				cancel()
+				wg.Wait()
+			})
+			opts = append(opts, WithAsyncProcessor(services.NewAsyncProcessor(service, pool)))
+		}
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		NewHandler(service, opts...).SetupRoutes(router)
+		return router
+	}
+	submit := func(router *gin.Engine, body string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/user/1/transaction", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Source-Type", "game")
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	poll := func(router *gin.Engine, target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+
+	type statusResponse struct {
+		TransactionID string         `json:"transactionId"`
+		UserID        uint64         `json:"userId"`
+		Status        string         `json:"status"`
+		StatusURL     string         `json:"statusUrl"`
+		Result        map[string]any `json:"result"`
+		Problem       map[string]any `json:"problem"`
+	}
+	// waitFinished polls the status URL until the transaction is no longer
+	// pending
+	waitFinished := func(t *testing.T, router *gin.Engine, target string) statusResponse {
+		var response statusResponse
+		require.Eventually(t, func() bool {
+			w := poll(router, target)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			return response.Status != string(entities.AsyncTransactionPending)
+		}, time.Second, time.Millisecond)
+		return response
+	}
+
+	t.Run("processes transactions in the background when asked to", func(t *testing.T) {
+		router := newRouter(t, true)
+		w := submit(router, `{"state": "win", "amount": "5", "transactionId": "tx-1"}`, "Prefer", "respond-async, wait=10")
+		require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+		assert.Equal(t, "/transaction/tx-1/status", w.Header().Get("Location"))
+		assert.Equal(t, "respond-async", w.Header().Get("Preference-Applied"))
+
+		var accepted statusResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &accepted))
+		assert.Equal(t, "tx-1", accepted.TransactionID)
+		assert.Equal(t, uint64(1), accepted.UserID)
+		assert.Equal(t, "/transaction/tx-1/status", accepted.StatusURL)
+
+		response := waitFinished(t, router, accepted.StatusURL)
+		assert.Equal(t, "processed", response.Status)
+		assert.Equal(t, "15.00", response.Result["balance"])
+		assert.Nil(t, response.Problem)
+	})
+
+	t.Run("failed transactions report their problem", func(t *testing.T) {
+		router := newRouter(t, true)
+		w := submit(router, `{"state": "lose", "amount": "100", "transactionId": "tx-1"}`, "Prefer", "respond-async")
+		require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+
+		response := waitFinished(t, router, "/transaction/tx-1/status")
+		assert.Equal(t, "failed", response.Status)
+		assert.Equal(t, "insufficient_funds", response.Problem["code"])
+		assert.Nil(t, response.Result)
+	})
+
+	t.Run("transactions are processed synchronously otherwise", func(t *testing.T) {
+		for name, tt := range map[string]struct {
+			async  bool
+			header []string
+		}{
+			"without preference":  {async: true},
+			"without a processor": {async: false, header: []string{"Prefer", "respond-async"}},
+		} {
+			t.Run(name, func(t *testing.T) {
+				router := newRouter(t, tt.async)
+				w := submit(router, `{"state": "win", "amount": "5", "transactionId": "tx-1"}`, tt.header...)
+				require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+				assert.Empty(t, w.Header().Get("Preference-Applied"))
+
+				// The status of processed transactions is still reported
+				w = poll(router, "/transaction/tx-1/status")
+				require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+				var response statusResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, "processed", response.Status)
+				assert.Equal(t, "15.00", response.Result["balance"])
+			})
+		}
+	})
+
+	t.Run("invalid transactions are rejected before being queued", func(t *testing.T) {
+		router := newRouter(t, true)
+		w := submit(router, `{"state": "win", "transactionId": "tx-1"}`, "Prefer", "respond-async")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = poll(router, "/transaction/tx-1/status")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, ProblemContentType, w.Header().Get("Content-Type"))
+	})
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditLogHandler handles the audit log requests
+type AuditLogHandler struct {
+	auditService *services.AuditService
+}
+
+// NewAuditLogHandler creates a new audit log HTTP handler
+func NewAuditLogHandler(auditService *services.AuditService) *AuditLogHandler {
+	return &AuditLogHandler{
+		auditService: auditService,
+	}
+}
+
+// SetupRoutes sets up the audit log routes
+func (h *AuditLogHandler) SetupRoutes(router gin.IRouter) {
+	router.GET(adminPathPrefix+"/audit-log", h.ListEntries)
+}
+
+// ListEntries handles GET /admin/audit-log with optional userId, from, to,
+// limit and offset query parameters
+func (h *AuditLogHandler) ListEntries(c *gin.Context) {
+	filter, err := parseAuditLogFilter(c)
+	if err != nil {
+		respondWithProblem(c, problemInvalidQuery, err.Error())
+		return
+	}
+
+	page, err := h.auditService.ListEntries(c.Request.Context(), filter)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidFilter) {
+			respondWithProblem(c, problemInvalidFilter, "Invalid filter: limit must not exceed 500 and from must be before to")
+			return
+		}
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// parseAuditLogFilter reads the audit log criteria from the query string
+func parseAuditLogFilter(c *gin.Context) (repositories.AuditLogFilter, error) {
+	var filter repositories.AuditLogFilter
+
+	if raw := c.Query("userId"); raw != "" {
+		userID, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil || userID == 0 {
+			return filter, errors.New("userId must be a positive integer")
+		}
+		filter.UserID = userID
+	}
+
+	var err error
+	if filter.From, err = queryTime(c, "from"); err != nil {
+		return filter, err
+	}
+	if filter.To, err = queryTime(c, "to"); err != nil {
+		return filter, err
+	}
+	if filter.Limit, err = queryInt(c, "limit"); err != nil {
+		return filter, err
+	}
+	if filter.Offset, err = queryInt(c, "offset"); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+
+	"transaction-service/internal/apiversion"
+	"transaction-service/internal/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// claimsKey is the Gin context key holding the authenticated caller's claims
+const claimsKey = "auth.claims"
+
+// adminPathPrefix marks the routes reserved for callers with the admin scope
+const adminPathPrefix = "/admin"
+
+// webhooksPath is the root of the webhook routes
+const webhooksPath = "/webhooks"
+
+// transactionPath is the root of the routes operating on a transaction of any
+// user
+const transactionPath = "/transaction"
+
+// transfersPath is the root of the routes moving amounts between users
+const transfersPath = "/transfers"
+
+// syncPath is the root of the change feed routes
+const syncPath = "/sync"
+
+// JWTAuth requires a valid bearer token on every route except publicPaths and
+// stores its claims in the Gin context. Callers may only operate on the user
+// named in their token's subject and may not use the admin routes unless
+// they carry the admin scope.
+func JWTAuth(verifier *auth.Verifier, publicPaths ...string) gin.HandlerFunc {
+	public := make(map[string]bool, len(publicPaths))
+	for _, path := range publicPaths {
+		public[path] = true
+	}
+
+	return func(c *gin.Context) {
+		if public[c.FullPath()] {
+			c.Next()
+			return
+		}
+
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || strings.TrimSpace(token) == "" {
+			c.Header("WWW-Authenticate", `Bearer`)
+			abortWithProblem(c, problemBearerTokenRequired, "Bearer token is required")
+			return
+		}
+		claims, err := verifier.Verify(strings.TrimSpace(token))
+		if err != nil {
+			_ = c.Error(err)
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			abortWithProblem(c, problemInvalidBearerToken, "Invalid bearer token")
+			return
+		}
+		c.Set(claimsKey, claims)
+
+		if isAdminRoute(c.FullPath()) && !verifier.IsAdmin(claims) {
+			abortWithProblem(c, problemAdminScopeRequired, "Admin scope is required")
+			return
+		}
+
+		// Malformed user IDs are rejected by the handlers themselves
+		if userID, err := strconv.ParseUint(c.Param("userId"), 10, 64); err == nil && !verifier.CanAccessUser(claims, userID) {
+			abortWithProblem(c, problemUserForbidden, "Not allowed to operate on this user")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireAdminAuth rejects the admin routes unless JWTAuth authenticated the
+// caller with the admin scope. It runs on every route, whatever its route
+// group, so operator routes are never served anonymously: neither when bearer
+// tokens are disabled nor when a route group skips authentication. API keys
+// do not authenticate operators.
+func RequireAdminAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := ClaimsFromContext(c); !ok && isAdminRoute(c.FullPath()) {
+			abortWithProblem(c, problemAdminAuthRequired, "Admin routes require AUTH_ENABLED and a bearer token with the admin scope")
+			return
+		}
+		c.Next()
+	}
+}
+
+// isAdminRoute reports whether route, in any version, needs the admin scope. Creating and
+// listing users, refunds and transfers are not scoped to a single user, and
+// webhooks and the change feed carry the events of every user, so they are
+// admin routes. Freezing accounts is reserved for operators too.
+func isAdminRoute(route string) bool {
+	route = apiversion.Strip(route)
+	switch route {
+	case "/user", "/users", "/user/:userId/freeze", "/user/:userId/unfreeze":
+		return true
+	}
+	for _, prefix := range []string{adminPathPrefix, webhooksPath, transactionPath, transfersPath, syncPath} {
+		if route == prefix || strings.HasPrefix(route, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// ClaimsFromContext returns the claims of the authenticated caller, if any
+func ClaimsFromContext(c *gin.Context) (*auth.Claims, bool) {
+	value, ok := c.Get(claimsKey)
+	if !ok {
+		return nil, false
+	}
+	claims, ok := value.(*auth.Claims)
+	return claims, ok
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"transaction-service/internal/auth"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSigningKey = "test-signing-key"
+
+func newAuthRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(JWTAuth(auth.NewVerifier(testSigningKey, "", "admin"), "/readyz"))
+
+	ok := func(c *gin.Context) {
+		if _, found := ClaimsFromContext(c); !found && c.FullPath() != "/readyz" {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.Status(http.StatusOK)
+	}
+	router.GET("/readyz", ok)
+	router.GET("/user/:userId/balance", ok)
+	router.GET("/admin/config", ok)
+	router.GET("/users", ok)
+	router.Any("/user/:userId/freeze", ok)
+	router.GET("/webhooks/:webhookId", ok)
+	router.GET("/transaction/:transactionId", ok)
+	router.Any("/transaction/:transactionId/refund", ok)
+	router.Any("/transfers", ok)
+	router.GET("/sync/transactions", ok)
+
+	return router
+}
+
+func bearerToken(t *testing.T, subject, scope string) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, auth.Claims{
+		Scope: scope,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}).SignedString([]byte(testSigningKey))
+	require.NoError(t, err)
+	return "Bearer " + token
+}
+
+func TestJWTAuth(t *testing.T) {
+	router := newAuthRouter()
+
+	tests := []struct {
+		name          string
+		path          string
+		authorization string
+		wantStatus    int
+	}{
+		{
+			name:       "public paths need no token",
+			path:       "/readyz",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "missing token",
+			path:       "/user/1/balance",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:          "invalid token",
+			path:          "/user/1/balance",
+			authorization: "Bearer not-a-token",
+			wantStatus:    http.StatusUnauthorized,
+		},
+		{
+			name:          "own user",
+			path:          "/user/1/balance",
+			authorization: bearerToken(t, "1", ""),
+			wantStatus:    http.StatusOK,
+		},
+		{
+			name:          "other user",
+			path:          "/user/2/balance",
+			authorization: bearerToken(t, "1", ""),
+			wantStatus:    http.StatusForbidden,
+		},
+		{
+			name:          "admin on other user",
+			path:          "/user/2/balance",
+			authorization: bearerToken(t, "1", "admin"),
+			wantStatus:    http.StatusOK,
+		},
+		{
+			name:          "admin route without admin scope",
+			path:          "/admin/config",
+			authorization: bearerToken(t, "1", ""),
+			wantStatus:    http.StatusForbidden,
+		},
+		{
+			name:          "admin route with admin scope",
+			path:          "/admin/config",
+			authorization: bearerToken(t, "", "admin"),
+			wantStatus:    http.StatusOK,
+		},
+		{
+			name:          "listing users without admin scope",
+			path:          "/users",
+			authorization: bearerToken(t, "1", ""),
+			wantStatus:    http.StatusForbidden,
+		},
+		{
+			name:          "freezing own account without admin scope",
+			path:          "/user/1/freeze",
+			authorization: bearerToken(t, "1", ""),
+			wantStatus:    http.StatusForbidden,
+		},
+		{
+			name:          "freezing with admin scope",
+			path:          "/user/1/freeze",
+			authorization: bearerToken(t, "", "admin"),
+			wantStatus:    http.StatusOK,
+		},
+		{
+			name:          "webhooks without admin scope",
+			path:          "/webhooks/1",
+			authorization: bearerToken(t, "1", ""),
+			wantStatus:    http.StatusForbidden,
+		},
+		{
+			name:          "transaction lookup without admin scope",
+			path:          "/transaction/tx-1",
+			authorization: bearerToken(t, "1", ""),
+			wantStatus:    http.StatusForbidden,
+		},
+		{
+			name:          "refunds without admin scope",
+			path:          "/transaction/tx-1/refund",
+			authorization: bearerToken(t, "1", ""),
+			wantStatus:    http.StatusForbidden,
+		},
+		{
+			name:          "transfers without admin scope",
+			path:          "/transfers",
+			authorization: bearerToken(t, "1", ""),
+			wantStatus:    http.StatusForbidden,
+		},
+		{
+			name:          "sync without admin scope",
+			path:          "/sync/transactions",
+			authorization: bearerToken(t, "1", ""),
+			wantStatus:    http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
+
+func TestRequireAdminAuth(t *testing.T) {
+	newRouter := func(middleware ...gin.HandlerFunc) *gin.Engine {
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.Use(middleware...)
+		router.Use(RequireAdminAuth())
+		ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+		router.GET("/user/:userId/balance", ok)
+		router.GET("/admin/config", ok)
+		router.POST("/api/v1/transaction/:transactionId/refund", ok)
+		return router
+	}
+	serve := func(router *gin.Engine, method, path, authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Without bearer tokens, only the admin routes are closed
+	router := newRouter()
+	assert.Equal(t, http.StatusOK, serve(router, http.MethodGet, "/user/1/balance", "").Code)
+	w := serve(router, http.MethodGet, "/admin/config", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "admin_auth_required")
+	assert.Equal(t, http.StatusForbidden, serve(router, http.MethodPost, "/api/v1/transaction/tx-1/refund", "").Code)
+
+	router = newRouter(JWTAuth(auth.NewVerifier(testSigningKey, "", "admin")))
+	assert.Equal(t, http.StatusOK, serve(router, http.MethodGet, "/admin/config", bearerToken(t, "ops", "admin")).Code)
+}
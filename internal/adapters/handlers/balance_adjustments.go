@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/domain/entities"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BalanceAdjustmentHandler handles the manual balance adjustment HTTP
+// requests
+type BalanceAdjustmentHandler struct {
+	transactionService *services.TransactionService
+}
+
+// NewBalanceAdjustmentHandler creates a new balance adjustment HTTP handler
+func NewBalanceAdjustmentHandler(transactionService *services.TransactionService) *BalanceAdjustmentHandler {
+	return &BalanceAdjustmentHandler{transactionService: transactionService}
+}
+
+// SetupRoutes sets up the balance adjustment routes
+func (h *BalanceAdjustmentHandler) SetupRoutes(router gin.IRouter) {
+	router.POST(adminPathPrefix+"/users/:userId/adjustments", h.AdjustBalance)
+	router.GET(adminPathPrefix+"/users/:userId/adjustments", h.ListAdjustments)
+}
+
+// AdjustBalance handles POST /admin/users/{userId}/adjustments. The actor is
+// the subject of the caller's token; requests authenticated otherwise name
+// the actor in the body.
+func (h *BalanceAdjustmentHandler) AdjustBalance(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("userId"), 10, 64)
+	if err != nil || userID == 0 {
+		respondWithProblem(c, problemInvalidUserID, "Invalid user ID. Must be a positive integer.")
+		return
+	}
+
+	var req entities.BalanceAdjustmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondWithProblem(c, problemInvalidRequestBody, "Invalid request body: "+err.Error())
+		return
+	}
+	if claims, ok := ClaimsFromContext(c); ok && claims.Subject != "" {
+		req.Actor = claims.Subject
+	}
+
+	adjustment, err := h.transactionService.AdjustBalance(c.Request.Context(), userID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidAdjustment) {
+			respondWithProblem(c, problemInvalidAdjustment, err.Error())
+			return
+		}
+		respondWithError(c, err)
+		return
+	}
+
+	if adjustment.Replayed {
+		c.Header("Idempotent-Replayed", "true")
+		c.JSON(http.StatusOK, adjustment)
+		return
+	}
+	c.JSON(http.StatusCreated, adjustment)
+}
+
+// ListAdjustments handles GET /admin/users/{userId}/adjustments
+func (h *BalanceAdjustmentHandler) ListAdjustments(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("userId"), 10, 64)
+	if err != nil || userID == 0 {
+		respondWithProblem(c, problemInvalidUserID, "Invalid user ID. Must be a positive integer.")
+		return
+	}
+
+	adjustments, err := h.transactionService.ListBalanceAdjustments(c.Request.Context(), userID)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"adjustments": adjustments,
+	})
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+
+	"transaction-service/internal/application/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BalanceCheckHandler handles the balance recomputation requests
+type BalanceCheckHandler struct {
+	balanceCheckService *services.BalanceCheckService
+}
+
+// NewBalanceCheckHandler creates a new balance check HTTP handler
+func NewBalanceCheckHandler(balanceCheckService *services.BalanceCheckService) *BalanceCheckHandler {
+	return &BalanceCheckHandler{
+		balanceCheckService: balanceCheckService,
+	}
+}
+
+// SetupRoutes sets up the balance check routes
+func (h *BalanceCheckHandler) SetupRoutes(router gin.IRouter) {
+	router.GET(adminPathPrefix+"/balances/check", h.CheckBalances)
+	router.POST(adminPathPrefix+"/balances/repair", h.RepairBalances)
+}
+
+// CheckBalances handles GET /admin/balances/check
+func (h *BalanceCheckHandler) CheckBalances(c *gin.Context) {
+	report, err := h.balanceCheckService.CheckBalances(c.Request.Context())
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	// Drifted balances are reported with their details
+	if len(report.Drifts) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, report)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// RepairBalances handles POST /admin/balances/repair. The repairs are
+// audited on behalf of the subject of the caller's token.
+func (h *BalanceCheckHandler) RepairBalances(c *gin.Context) {
+	var actor string
+	if claims, ok := ClaimsFromContext(c); ok {
+		actor = claims.Subject
+	}
+
+	report, err := h.balanceCheckService.RepairBalances(c.Request.Context(), actor)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
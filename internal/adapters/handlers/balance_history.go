@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+
+	"transaction-service/internal/application/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BalanceHistoryHandler handles balance history HTTP requests
+type BalanceHistoryHandler struct {
+	snapshotService *services.BalanceSnapshotService
+}
+
+// NewBalanceHistoryHandler creates a new balance history HTTP handler
+func NewBalanceHistoryHandler(snapshotService *services.BalanceSnapshotService) *BalanceHistoryHandler {
+	return &BalanceHistoryHandler{snapshotService: snapshotService}
+}
+
+// SetupRoutes sets up the balance history routes
+func (h *BalanceHistoryHandler) SetupRoutes(router gin.IRouter) {
+	router.GET("/user/:userId/balance/history", h.GetBalanceHistory)
+}
+
+// GetBalanceHistory handles GET /user/{userId}/balance/history?granularity=&from=&to=
+func (h *BalanceHistoryHandler) GetBalanceHistory(c *gin.Context) {
+	userID, ok := pathUserID(c)
+	if !ok {
+		return
+	}
+
+	from, err := queryTime(c, "from")
+	if err != nil {
+		respondWithProblem(c, problemInvalidQuery, err.Error())
+		return
+	}
+	to, err := queryTime(c, "to")
+	if err != nil {
+		respondWithProblem(c, problemInvalidQuery, err.Error())
+		return
+	}
+
+	history, err := h.snapshotService.GetBalanceHistory(c.Request.Context(), userID, from, to, c.Query("granularity"))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/domain/entities"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BulkJobHandler handles the bulk admin job requests
+type BulkJobHandler struct {
+	bulkJobService *services.BulkJobService
+}
+
+// NewBulkJobHandler creates a new bulk job HTTP handler
+func NewBulkJobHandler(bulkJobService *services.BulkJobService) *BulkJobHandler {
+	return &BulkJobHandler{
+		bulkJobService: bulkJobService,
+	}
+}
+
+// SetupRoutes sets up the bulk job routes
+func (h *BulkJobHandler) SetupRoutes(router gin.IRouter) {
+	jobs := router.Group("/admin/jobs")
+
+	jobs.POST("/freeze-users", submitBulkJob(h.bulkJobService.FreezeUsers))
+	jobs.POST("/adjust-balances", submitBulkJob(h.bulkJobService.AdjustBalances))
+	jobs.POST("/redeliver-webhooks", submitBulkJob(h.bulkJobService.RedeliverWebhooks))
+	jobs.POST("/export-transactions", submitBulkJob(h.bulkJobService.ExportTransactions))
+	jobs.GET("", h.ListJobs)
+	jobs.GET("/:jobId", h.GetJob)
+}
+
+// submitBulkJob handles POST /admin/jobs/{operation}. The job runs in the
+// background; its progress is reported by GET /admin/jobs/{jobId}.
+func submitBulkJob[R any](submit func(context.Context, R) (*entities.BulkJob, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req R
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondWithProblem(c, problemInvalidRequestBody, "Invalid request body: "+err.Error())
+			return
+		}
+
+		job, err := submit(c.Request.Context(), req)
+		if err != nil {
+			respondWithBulkJobError(c, err)
+			return
+		}
+
+		c.Header("Location", versionPath(c)+"/admin/jobs/"+job.ID)
+		c.JSON(http.StatusAccepted, job)
+	}
+}
+
+// ListJobs handles GET /admin/jobs
+func (h *BulkJobHandler) ListJobs(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"jobs": h.bulkJobService.ListJobs(c.Request.Context()),
+	})
+}
+
+// GetJob handles GET /admin/jobs/{jobId}
+func (h *BulkJobHandler) GetJob(c *gin.Context) {
+	job, err := h.bulkJobService.GetJob(c.Request.Context(), c.Param("jobId"))
+	if err != nil {
+		respondWithBulkJobError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+func respondWithBulkJobError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrInvalidBulkJob) {
+		respondWithProblem(c, problemInvalidBulkJob, err.Error())
+		return
+	}
+	respondWithError(c, err)
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"transaction-service/internal/consistency"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+)
+
+// ConsistencyTokens provides read-your-writes consistency while reads are
+// served by a replica. Successful writes respond with the primary's current
+// write-ahead log position, from position, in the X-Consistency-Token
+// header. GET requests may be served by the replica; echoing a token makes
+// sure they only are once the replica has caught up with it.
+func ConsistencyTokens(position func(ctx context.Context) (consistency.Token, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			var after consistency.Token
+			if value := c.GetHeader(consistency.Header); value != "" {
+				var err error
+				if after, err = consistency.ParseToken(value); err != nil {
+					abortWithProblem(c, problemInvalidConsistencyToken, "Invalid "+consistency.Header+" header")
+					return
+				}
+			}
+			c.Request = c.Request.WithContext(consistency.WithReplicaRead(c.Request.Context(), after))
+			c.Next()
+			return
+		}
+
+		writer := &consistencyWriter{ResponseWriter: c.Writer, ctx: c.Request.Context(), position: position}
+		c.Writer = writer
+		c.Next()
+		// Responses without a body have not been written yet
+		if !writer.Written() {
+			writer.stamp()
+		}
+	}
+}
+
+// consistencyWriter adds the consistency token to successful responses right
+// before their headers are written, after the handler committed the write
+type consistencyWriter struct {
+	gin.ResponseWriter
+	ctx      context.Context
+	position func(ctx context.Context) (consistency.Token, error)
+	stamped  bool
+}
+
+func (w *consistencyWriter) stamp() {
+	if w.stamped {
+		return
+	}
+	w.stamped = true
+
+	if w.Status() < http.StatusOK || w.Status() >= http.StatusMultipleChoices {
+		return
+	}
+	token, err := w.position(w.ctx)
+	if err != nil {
+		// The write succeeded, so it is answered without a token
+		zerolog.Ctx(w.ctx).Warn().Err(err).Msg("failed to get consistency token")
+		return
+	}
+	w.Header().Set(consistency.Header, token.String())
+}
+
+func (w *consistencyWriter) WriteHeaderNow() {
+	w.stamp()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *consistencyWriter) Write(data []byte) (int, error) {
+	w.stamp()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *consistencyWriter) WriteString(s string) (int, error) {
+	w.stamp()
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"transaction-service/internal/consistency"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsistencyTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ConsistencyTokens(func(ctx context.Context) (consistency.Token, error) {
+		return 1<<32 | 0x2A, nil
+	}))
+
+	var replicaRead consistency.Token
+	var replicaAllowed bool
+	router.GET("/read", func(c *gin.Context) {
+		replicaRead, replicaAllowed = consistency.ReplicaRead(c.Request.Context())
+		c.JSON(http.StatusOK, gin.H{})
+	})
+	router.POST("/write", func(c *gin.Context) {
+		_, replicaAllowed = consistency.ReplicaRead(c.Request.Context())
+		c.JSON(http.StatusCreated, gin.H{})
+	})
+	router.POST("/fail", func(c *gin.Context) {
+		respondWithProblem(c, problemInvalidRequestBody, "Invalid request body")
+	})
+	router.DELETE("/empty", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	t.Run("successful writes respond with a token", func(t *testing.T) {
+		for _, req := range []*http.Request{
+			httptest.NewRequest(http.MethodPost, "/write", nil),
+			httptest.NewRequest(http.MethodDelete, "/empty", nil),
+		} {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, "1/2A", w.Header().Get(consistency.Header), req.URL.Path)
+		}
+		assert.False(t, replicaAllowed, "writes use the primary")
+	})
+
+	t.Run("failed writes respond without a token", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/fail", nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Empty(t, w.Header().Get(consistency.Header))
+	})
+
+	t.Run("reads may use a replica caught up with the echoed token", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/read", nil))
+		assert.True(t, replicaAllowed)
+		assert.Equal(t, consistency.Token(0), replicaRead)
+
+		req := httptest.NewRequest(http.MethodGet, "/read", nil)
+		req.Header.Set(consistency.Header, "1/2A")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, consistency.Token(1<<32|0x2A), replicaRead)
+	})
+
+	t.Run("invalid tokens are rejected", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/read", nil)
+		req.Header.Set(consistency.Header, "latest")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
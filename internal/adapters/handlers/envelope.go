@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHeader identifies the calling integrator
+const APIKeyHeader = "X-API-Key"
+
+// Envelope status values
+const (
+	envelopeStatusOK    = "ok"
+	envelopeStatusError = "error"
+)
+
+// envelope is the legacy response shape: {"status":"ok","data":{...}}
+type envelope struct {
+	Status string          `json:"status"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// ResponseEnvelope serves callers using one of apiKeys in the legacy envelope
+// mode. Their JSON request bodies may be wrapped in {"data": ...}, which is
+// unwrapped before the handlers see it, and every JSON response is wrapped
+// as {"status":"ok"|"error","data": ...}. Problem details are wrapped in the
+// legacy error shape, {"error": ...,"code": ...}. Other callers are
+// unaffected.
+func ResponseEnvelope(apiKeys []string) gin.HandlerFunc {
+	enveloped := make(map[string]bool, len(apiKeys))
+	for _, key := range apiKeys {
+		enveloped[key] = true
+	}
+
+	return func(c *gin.Context) {
+		if len(enveloped) == 0 || !enveloped[c.GetHeader(APIKeyHeader)] {
+			c.Next()
+			return
+		}
+
+		if err := unwrapRequestBody(c.Request); err != nil {
+			c.Writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+			c.Writer.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(c.Writer).Encode(envelope{
+				Status: envelopeStatusError,
+				Data:   json.RawMessage(`{"error":"Invalid request body","code":"invalid_request_body"}`),
+			})
+			c.Abort()
+			return
+		}
+
+		writer := &envelopeWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		writer.flush()
+	}
+}
+
+// unwrapRequestBody replaces a {"data": ...} JSON body with its data. Bodies
+// that are not enveloped are left untouched.
+func unwrapRequestBody(req *http.Request) error {
+	if req.Body == nil || !strings.HasPrefix(req.Header.Get("Content-Type"), "application/json") {
+		return nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	_ = req.Body.Close()
+
+	var wrapped map[string]json.RawMessage
+	if json.Unmarshal(body, &wrapped) == nil {
+		if data, ok := wrapped["data"]; ok && len(wrapped) == 1 {
+			body = data
+		}
+	}
+
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+	return nil
+}
+
+// envelopeWriter buffers the response body so it can be wrapped once the
+// handler is done
+type envelopeWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *envelopeWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *envelopeWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+// flush writes the buffered body, wrapped if it is JSON
+func (w *envelopeWriter) flush() {
+	body := w.body.Bytes()
+	if len(body) == 0 {
+		w.ResponseWriter.WriteHeaderNow()
+		return
+	}
+
+	if strings.HasPrefix(w.Header().Get("Content-Type"), ProblemContentType) {
+		body = legacyError(body)
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	}
+	if strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") && json.Valid(body) {
+		status := envelopeStatusOK
+		if w.Status() >= http.StatusBadRequest {
+			status = envelopeStatusError
+		}
+		if wrapped, err := json.Marshal(envelope{Status: status, Data: body}); err == nil {
+			body = wrapped
+		}
+	}
+
+	_, _ = w.ResponseWriter.Write(body)
+}
+
+// legacyError turns a problem details body into the error payload legacy
+// callers expect, keeping the code. Bodies that cannot be read are left as
+// they are.
+func legacyError(body []byte) []byte {
+	var problem struct {
+		Title  string `json:"title"`
+		Detail string `json:"detail"`
+		Code   string `json:"code"`
+	}
+	if json.Unmarshal(body, &problem) != nil {
+		return body
+	}
+	message := problem.Detail
+	if message == "" {
+		message = problem.Title
+	}
+	legacy, err := json.Marshal(struct {
+		Error string `json:"error"`
+		Code  string `json:"code"`
+	}{message, problem.Code})
+	if err != nil {
+		return body
+	}
+	return legacy
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newEnvelopeRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ResponseEnvelope([]string{"legacy-key"}))
+
+	router.POST("/echo", func(c *gin.Context) {
+		var req struct {
+			Amount string `json:"amount" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondWithProblem(c, problemInvalidRequestBody, "Invalid request body")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"amount": req.Amount})
+	})
+	router.DELETE("/empty", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	return router
+}
+
+func TestResponseEnvelope(t *testing.T) {
+	router := newEnvelopeRouter()
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		apiKey     string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "legacy callers have requests unwrapped and responses wrapped",
+			method:     http.MethodPost,
+			path:       "/echo",
+			apiKey:     "legacy-key",
+			body:       `{"data":{"amount":"10.00"}}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"status":"ok","data":{"amount":"10.00"}}`,
+		},
+		{
+			name:       "legacy callers may send unwrapped requests",
+			method:     http.MethodPost,
+			path:       "/echo",
+			apiKey:     "legacy-key",
+			body:       `{"amount":"10.00"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"status":"ok","data":{"amount":"10.00"}}`,
+		},
+		{
+			name:       "problems are wrapped in the legacy error shape",
+			method:     http.MethodPost,
+			path:       "/echo",
+			apiKey:     "legacy-key",
+			body:       `{"data":{}}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"status":"error","data":{"error":"Invalid request body","code":"invalid_request_body"}}`,
+		},
+		{
+			name:       "other callers get problem details",
+			method:     http.MethodPost,
+			path:       "/echo",
+			apiKey:     "modern-key",
+			body:       `{}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"type":"/problems/invalid_request_body","code":"invalid_request_body","title":"Invalid request body","status":400,"detail":"Invalid request body"}`,
+		},
+		{
+			name:       "other callers are unaffected",
+			method:     http.MethodPost,
+			path:       "/echo",
+			apiKey:     "modern-key",
+			body:       `{"amount":"10.00"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"amount":"10.00"}`,
+		},
+		{
+			name:       "empty responses stay empty",
+			method:     http.MethodDelete,
+			path:       "/empty",
+			apiKey:     "legacy-key",
+			wantStatus: http.StatusNoContent,
+			wantBody:   "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(APIKeyHeader, tt.apiKey)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantBody == "" {
+				assert.Empty(t, w.Body.String())
+				return
+			}
+			assert.JSONEq(t, tt.wantBody, w.Body.String())
+		})
+	}
+}
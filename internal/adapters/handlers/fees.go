@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"net/http"
+
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/domain/entities"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FeeHandler handles the fee rule HTTP requests
+type FeeHandler struct {
+	feeService *services.FeeService
+}
+
+// NewFeeHandler creates a new fee rule HTTP handler
+func NewFeeHandler(feeService *services.FeeService) *FeeHandler {
+	return &FeeHandler{feeService: feeService}
+}
+
+// SetupRoutes sets up the fee rule routes
+func (h *FeeHandler) SetupRoutes(router gin.IRouter) {
+	fees := router.Group(adminPathPrefix + "/fees")
+	fees.GET("", h.ListRules)
+	fees.PUT("/:sourceType/:state", h.SetRule)
+	fees.DELETE("/:sourceType/:state", h.DeleteRule)
+}
+
+// ListRules handles GET /admin/fees
+func (h *FeeHandler) ListRules(c *gin.Context) {
+	rules, err := h.feeService.ListRules(c.Request.Context())
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rules": rules,
+	})
+}
+
+// SetRule handles PUT /admin/fees/{sourceType}/{state}
+func (h *FeeHandler) SetRule(c *gin.Context) {
+	var req entities.FeeRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondWithProblem(c, problemInvalidRequestBody, "Invalid request body: "+err.Error())
+		return
+	}
+
+	rule, err := h.feeService.SetRule(
+		c.Request.Context(),
+		entities.SourceType(c.Param("sourceType")),
+		entities.TransactionState(c.Param("state")),
+		req,
+	)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeleteRule handles DELETE /admin/fees/{sourceType}/{state}
+func (h *FeeHandler) DeleteRule(c *gin.Context) {
+	err := h.feeService.DeleteRule(
+		c.Request.Context(),
+		entities.SourceType(c.Param("sourceType")),
+		entities.TransactionState(c.Param("state")),
+	)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
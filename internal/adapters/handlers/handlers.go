@@ -7,6 +7,7 @@ import (
 
 	"transaction-service/internal/application/services"
 	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,22 +15,74 @@ import (
 // Handler handles HTTP requests
 type Handler struct {
 	transactionService *services.TransactionService
+	// quotaWarnRatio is optional; when set, successful transactions carry
+	// quota warning headers as users approach their velocity limits
+	quotaWarnRatio float64
+	// sandboxService is optional; when set, it serves sandbox traffic
+	sandboxService *services.TransactionService
+	// asyncProcessor is optional; when set, transactions are processed in the
+	// background for the callers preferring it
+	asyncProcessor *services.AsyncProcessor
+}
+
+// HandlerOption configures optional Handler behavior
+type HandlerOption func(*Handler)
+
+// WithSandbox serves sandbox traffic from service, which must be backed by
+// the isolated sandbox users
+func WithSandbox(service *services.TransactionService) HandlerOption {
+	return func(h *Handler) {
+		h.sandboxService = service
+	}
 }
 
 // NewHandler creates a new HTTP handler
-func NewHandler(transactionService *services.TransactionService) *Handler {
-	return &Handler{
+func NewHandler(
+	transactionService *services.TransactionService,
+	opts ...HandlerOption,
+) *Handler {
+	h := &Handler{
 		transactionService: transactionService,
 	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// service returns the transaction service serving the request
+func (h *Handler) service(c *gin.Context) *services.TransactionService {
+	if h.sandboxService != nil && isSandbox(c) {
+		return h.sandboxService
+	}
+	return h.transactionService
 }
 
 // SetupRoutes sets up the HTTP routes
-func (h *Handler) SetupRoutes(router *gin.Engine) {
+func (h *Handler) SetupRoutes(router gin.IRouter) {
 	// User transaction route
 	router.POST("/user/:userId/transaction", h.ProcessTransaction)
+	router.POST("/user/:userId/transactions/batch", h.ProcessTransactionBatch)
 
 	// User balance route
 	router.GET("/user/:userId/balance", h.GetUserBalance)
+
+	// User transaction history route
+	router.GET("/user/:userId/transactions", h.GetUserTransactions)
+	router.GET("/user/:userId/transactions/export", h.ExportUserTransactions)
+
+	// Game round settlement route
+	router.GET("/user/:userId/rounds/:roundId", h.GetRoundSummary)
+	router.GET("/user/:userId/stats", h.GetUserStats)
+
+	// Transaction lookup, refund and settlement routes, reserved for operators
+	router.GET(transactionPath+"/:transactionId", h.GetTransaction)
+	router.GET(transactionPath+"/:transactionId/status", h.GetTransactionStatus)
+	router.POST(transactionPath+"/:transactionId/refund", h.RefundTransaction)
+	router.POST(transactionPath+"/:transactionId/settle", h.SettleTransaction)
+
+	// Transfer route, reserved for operators
+	router.POST(transfersPath, h.Transfer)
 }
 
 // ProcessTransaction handles POST /user/{userId}/transaction
@@ -38,126 +91,401 @@ func (h *Handler) ProcessTransaction(c *gin.Context) {
 	userIDStr := c.Param("userId")
 	userID, err := strconv.ParseUint(userIDStr, 10, 64)
 	if err != nil || userID == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid user ID",
-		})
+		respondWithProblem(c, problemInvalidUserID, "Invalid user ID")
 		return
 	}
 
 	// Extract source type from header
 	sourceTypeHeader := c.GetHeader("Source-Type")
 	if sourceTypeHeader == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Source-Type header is required",
-		})
+		respondWithProblem(c, problemSourceTypeRequired, "Source-Type header is required")
 		return
 	}
 
 	sourceType := entities.SourceType(sourceTypeHeader)
 	if !sourceType.IsValid() {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid Source-Type header. Must be one of: game, server, payment",
-		})
+		respondWithProblem(c, problemInvalidSourceType, "Invalid Source-Type header. Must be one of: game, server, payment")
 		return
 	}
 
 	// Parse the request body
 	var req entities.TransactionRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request body: " + err.Error(),
-		})
+	currency, minorUnits := minorUnitsCurrency(c)
+	if minorUnits {
+		req, err = bindMinorUnitsRequest(c)
+	} else {
+		err = c.ShouldBindJSON(&req)
+	}
+	if err != nil {
+		if errors.Is(err, errUnsupportedCurrency) {
+			respondWithProblem(c, problemUnsupportedCurrency, "Unsupported currency")
+			return
+		}
+		respondWithProblem(c, problemInvalidRequestBody, "Invalid request body: "+err.Error())
 		return
 	}
 
 	// Validate required fields
 	if req.State == "" || req.Amount == "" || req.TransactionID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "All fields (state, amount, transactionID) are required",
-		})
+		respondWithProblem(c, problemInvalidRequestBody, "All fields (state, amount, transactionID) are required")
+		return
+	}
+
+	logTransactionID(c, req.TransactionID)
+
+	// Sandbox traffic is always processed synchronously
+	if h.asyncProcessor != nil && prefersAsync(c) && !isSandbox(c) {
+		h.submitAsync(c, userID, req, sourceType)
 		return
 	}
 
 	// Process the transaction
-	err = h.transactionService.ProcessTransaction(c.Request.Context(), userID, req, sourceType)
+	result, err := h.service(c).ProcessTransaction(c.Request.Context(), userID, req, sourceType)
 	if err != nil {
-		switch {
-		case errors.Is(err, services.ErrUserNotFound):
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": "User not found",
-			})
-
-		case errors.Is(err, services.ErrInsufficientFunds):
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Insufficient funds",
-			})
-
-		case errors.Is(err, services.ErrDuplicateTransaction):
-			c.JSON(http.StatusConflict, gin.H{
-				"error": "Transaction already processed",
-			})
-
-		case errors.Is(err, services.ErrInvalidAmount):
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Invalid amount format",
-			})
-
-		case errors.Is(err, services.ErrInvalidTransactionState):
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Invalid state. Must be 'win' or 'lose'",
-			})
-
-		case errors.Is(err, services.ErrInvalidSourceType):
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Invalid Source-Type. Must be one of: game, server, payment",
-			})
-
-		default:
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Internal server error: " + err.Error(),
-			})
-		}
-
+		respondWithError(c, err)
 		return
 	}
 
+	// Replays return the original result without quota warnings, which
+	// only concern real users
+	if result.Replayed {
+		c.Header("Idempotent-Replayed", "true")
+	} else if !isSandbox(c) {
+		// Warn well-behaved integrators before they hit hard limits
+		h.setQuotaHeaders(c, userID)
+	}
+
 	// Return success response
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Transaction processed successfully",
-		"status":  "success",
-	})
+	response, err := transactionResultResponse(result, currency, minorUnits)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+	response["message"] = "Transaction processed successfully"
+	response["status"] = "success"
+	c.JSON(http.StatusOK, response)
 }
 
-// GetUserBalance handles GET /user/{userId}/balance
+// transactionResultResponse describes a processed transaction, with amounts
+// in minor units of currency for the callers using them
+func transactionResultResponse(result *entities.TransactionResult, currency entities.Currency, minorUnits bool) (gin.H, error) {
+	response := gin.H{
+		"id":            result.ID,
+		"transactionId": result.TransactionID,
+		"receipt":       result.Receipt,
+		"balance":       result.Balance,
+		"replayed":      result.Replayed,
+	}
+	if result.Currency != "" {
+		response["currency"] = result.Currency
+	}
+	if result.Pending {
+		response["pending"] = true
+	}
+	if result.Fee != "" {
+		response["fee"] = result.Fee
+	}
+	if minorUnits {
+		balance, err := toMinorUnits(currencyOrDefault(result.Currency, currency), result.Balance)
+		if err != nil {
+			return nil, err
+		}
+		response["balance"] = balance
+		response["currency"] = currencyOrDefault(result.Currency, currency).Code
+		if result.Fee != "" {
+			if response["fee"], err = toMinorUnits(currencyOrDefault(result.Currency, currency), result.Fee); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return response, nil
+}
+
+// GetUserBalance handles GET /user/{userId}/balance?at=
 func (h *Handler) GetUserBalance(c *gin.Context) {
 	// Extract user ID from the path
 	userIDStr := c.Param("userId")
 	userID, err := strconv.ParseUint(userIDStr, 10, 64)
 	if err != nil || userID == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid user ID. Must be a positive integer.",
-		})
+		respondWithProblem(c, problemInvalidUserID, "Invalid user ID. Must be a positive integer.")
 		return
 	}
 
-	// Get user balance
-	balance, err := h.transactionService.GetUserBalance(c.Request.Context(), userID)
+	// Get user balance, as of a past moment when at is given
+	at, err := queryTime(c, "at")
+	if err != nil {
+		respondWithProblem(c, problemInvalidQuery, err.Error())
+		return
+	}
+	var balance *entities.BalanceResponse
+	if at != nil {
+		balance, err = h.service(c).GetUserBalanceAt(c.Request.Context(), userID, *at)
+	} else {
+		balance, err = h.service(c).GetUserBalance(c.Request.Context(), userID)
+	}
 	if err != nil {
-		if errors.Is(err, services.ErrUserNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": "User not found",
-			})
+		respondWithError(c, err)
+		return
+	}
+
+	// Mark balances served from cache during a database outage
+	if balance.Stale {
+		c.Header("Warning", `110 - "Response is Stale"`)
+	}
+
+	// Return the balance
+	if currency, ok := minorUnitsCurrency(c); ok {
+		converted, err := newMinorUnitsBalanceResponse(currency, balance)
+		if err != nil {
+			respondWithError(c, err)
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Internal server error: " + err.Error(),
+		c.JSON(http.StatusOK, gin.H{
+			"userId":  userID,
+			"balance": converted,
 		})
 		return
 	}
-
-	// Return the balance
 	c.JSON(http.StatusOK, gin.H{
 		"userId":  userID,
 		"balance": balance,
 	})
 }
+
+// GetUserTransactions handles GET /user/{userId}/transactions
+func (h *Handler) GetUserTransactions(c *gin.Context) {
+	// Extract user ID from the path
+	userIDStr := c.Param("userId")
+	userID, err := strconv.ParseUint(userIDStr, 10, 64)
+	if err != nil || userID == 0 {
+		respondWithProblem(c, problemInvalidUserID, "Invalid user ID. Must be a positive integer.")
+		return
+	}
+
+	// Parse the filter and pagination parameters
+	filter, err := parseHistoryFilter(c)
+	if err != nil {
+		respondWithProblem(c, problemInvalidQuery, err.Error())
+		return
+	}
+
+	// Get the page of transactions
+	page, err := h.service(c).GetUserTransactions(c.Request.Context(), userID, filter)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidFilter) {
+			respondWithProblem(c, problemInvalidFilter,
+				"Invalid filter: from must not be after to, limit must not exceed 500 and offset cannot be combined with cursor")
+			return
+		}
+		respondWithError(c, err)
+		return
+	}
+
+	if currency, ok := minorUnitsCurrency(c); ok {
+		converted, err := newMinorUnitsTransactionPage(currency, page)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, converted)
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// parseHistoryFilter reads the criteria of a transaction history from the
+// query string
+func parseHistoryFilter(c *gin.Context) (repositories.TransactionFilter, error) {
+	filter := repositories.TransactionFilter{
+		SourceType: entities.SourceType(c.Query("sourceType")),
+		State:      entities.TransactionState(c.Query("state")),
+	}
+
+	var err error
+	if filter.From, err = queryTime(c, "from"); err != nil {
+		return filter, err
+	}
+	if filter.To, err = queryTime(c, "to"); err != nil {
+		return filter, err
+	}
+	if filter.Cancelled, err = queryBool(c, "cancelled"); err != nil {
+		return filter, err
+	}
+	if filter.Limit, err = queryInt(c, "limit"); err != nil {
+		return filter, err
+	}
+	if filter.Offset, err = queryInt(c, "offset"); err != nil {
+		return filter, err
+	}
+	if filter.After, err = queryCursor(c, "cursor"); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+// GetRoundSummary handles GET /user/{userId}/rounds/{roundId}
+func (h *Handler) GetRoundSummary(c *gin.Context) {
+	// Extract user ID from the path
+	userIDStr := c.Param("userId")
+	userID, err := strconv.ParseUint(userIDStr, 10, 64)
+	if err != nil || userID == 0 {
+		respondWithProblem(c, problemInvalidUserID, "Invalid user ID. Must be a positive integer.")
+		return
+	}
+
+	// Total the round in the requested currency, the base currency by default
+	summary, err := h.service(c).GetRoundSummary(c.Request.Context(), userID, c.Param("roundId"), c.Query("currency"))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	if currency, ok := minorUnitsCurrency(c); ok {
+		converted, err := newMinorUnitsRoundSummary(currency, summary)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, converted)
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
+
+// GetUserStats handles GET /user/{userId}/stats?from=&to=&currency=
+func (h *Handler) GetUserStats(c *gin.Context) {
+	userID, ok := pathUserID(c)
+	if !ok {
+		return
+	}
+
+	from, err := queryTime(c, "from")
+	if err != nil {
+		respondWithProblem(c, problemInvalidQuery, err.Error())
+		return
+	}
+	to, err := queryTime(c, "to")
+	if err != nil {
+		respondWithProblem(c, problemInvalidQuery, err.Error())
+		return
+	}
+
+	stats, err := h.service(c).GetUserStats(c.Request.Context(), userID, from, to, c.Query("currency"))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	if currency, ok := minorUnitsCurrency(c); ok {
+		converted, err := newMinorUnitsUserStats(currency, stats)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, converted)
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetTransaction handles GET /transaction/{transactionId}. Like the other
+// admin routes it always operates on real users.
+func (h *Handler) GetTransaction(c *gin.Context) {
+	transactionID := c.Param("transactionId")
+	logTransactionID(c, transactionID)
+
+	transaction, err := h.transactionService.GetTransaction(c.Request.Context(), transactionID)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, transaction)
+}
+
+// RefundTransaction handles POST /transaction/{transactionId}/refund. Like the
+// other admin routes it always operates on real users.
+func (h *Handler) RefundTransaction(c *gin.Context) {
+	transactionID := c.Param("transactionId")
+	logTransactionID(c, transactionID)
+
+	result, err := h.transactionService.RefundTransaction(c.Request.Context(), transactionID)
+	if err != nil {
+		if errors.Is(err, services.ErrDuplicateTransaction) {
+			respondWithProblem(c, problemDuplicateTransaction, "Refund transaction ID already used for a different transaction")
+			return
+		}
+		respondWithError(c, err)
+		return
+	}
+
+	response := gin.H{
+		"message":       "Transaction refunded successfully",
+		"status":        "success",
+		"id":            result.ID,
+		"transactionId": result.TransactionID,
+		"reverses":      transactionID,
+		"receipt":       result.Receipt,
+		"balance":       result.Balance,
+	}
+	if result.Currency != "" {
+		response["currency"] = result.Currency
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// SettleTransaction handles POST /transaction/{transactionId}/settle. Like
+// the other admin routes it always operates on real users.
+func (h *Handler) SettleTransaction(c *gin.Context) {
+	transactionID := c.Param("transactionId")
+	logTransactionID(c, transactionID)
+
+	result, err := h.transactionService.SettleTransaction(c.Request.Context(), transactionID)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	if result.Replayed {
+		c.Header("Idempotent-Replayed", "true")
+	}
+	response := gin.H{
+		"message":       "Transaction settled successfully",
+		"status":        "success",
+		"id":            result.ID,
+		"transactionId": result.TransactionID,
+		"receipt":       result.Receipt,
+		"balance":       result.Balance,
+		"replayed":      result.Replayed,
+	}
+	if result.Currency != "" {
+		response["currency"] = result.Currency
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// Transfer handles POST /transfers. Like the other admin routes it always
+// operates on real users.
+func (h *Handler) Transfer(c *gin.Context) {
+	var req entities.TransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondWithProblem(c, problemInvalidRequestBody, "Invalid request body: "+err.Error())
+		return
+	}
+
+	result, err := h.transactionService.Transfer(c.Request.Context(), req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidTransfer) {
+			respondWithProblem(c, problemInvalidTransfer, err.Error())
+			return
+		}
+		respondWithError(c, err)
+		return
+	}
+
+	if result.Replayed {
+		c.Header("Idempotent-Replayed", "true")
+	}
+	c.JSON(http.StatusOK, result)
+}
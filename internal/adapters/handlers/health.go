@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http"
+
+	"transaction-service/internal/health"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HealthHandler handles health, readiness and build information HTTP requests
+type HealthHandler struct {
+	checker   *health.Checker
+	liveness  *health.Checker
+	buildInfo health.BuildInfo
+}
+
+// NewHealthHandler creates a new health HTTP handler. checker holds the
+// readiness checks and liveness the checks of the liveness probe.
+func NewHealthHandler(checker, liveness *health.Checker, buildInfo health.BuildInfo) *HealthHandler {
+	return &HealthHandler{
+		checker:   checker,
+		liveness:  liveness,
+		buildInfo: buildInfo,
+	}
+}
+
+// SetupRoutes sets up the health routes
+func (h *HealthHandler) SetupRoutes(router gin.IRouter) {
+	router.GET("/healthz", h.Liveness)
+	router.GET("/readyz", h.Readiness)
+	router.GET("/version", h.Version)
+}
+
+// Liveness handles GET /healthz. It does not check dependencies, so that an
+// outage of the database does not get healthy replicas restarted.
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	report := h.liveness.Live(c.Request.Context())
+
+	status := http.StatusOK
+	if report.Status == health.StatusStalled {
+		status = http.StatusServiceUnavailable
+	}
+
+	c.JSON(status, report)
+}
+
+// Readiness handles GET /readyz
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	report := h.checker.Check(c.Request.Context())
+
+	// Degraded dependencies are reported but keep the service in rotation
+	status := http.StatusOK
+	if report.Status == health.StatusUnready {
+		status = http.StatusServiceUnavailable
+	}
+
+	c.JSON(status, report)
+}
+
+// Version handles GET /version
+func (h *HealthHandler) Version(c *gin.Context) {
+	c.JSON(http.StatusOK, h.buildInfo)
+}
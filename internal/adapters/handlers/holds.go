@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/domain/entities"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HoldHandler handles payment hold HTTP requests
+type HoldHandler struct {
+	holdService *services.HoldService
+	// sandboxHoldService is optional; when set, it serves sandbox traffic
+	sandboxHoldService *services.HoldService
+}
+
+// NewHoldHandler creates a new hold HTTP handler
+func NewHoldHandler(holdService, sandboxHoldService *services.HoldService) *HoldHandler {
+	return &HoldHandler{
+		holdService:        holdService,
+		sandboxHoldService: sandboxHoldService,
+	}
+}
+
+// SetupRoutes sets up the hold routes. All of them require the payment
+// Source-Type, so API keys bound to other sources cannot use them.
+func (h *HoldHandler) SetupRoutes(router gin.IRouter) {
+	holds := router.Group("/user/:userId/holds", requirePaymentSource)
+	holds.POST("", h.CreateHold)
+	holds.POST("/:holdId/capture", h.CaptureHold)
+	holds.POST("/:holdId/release", h.ReleaseHold)
+}
+
+// service returns the hold service serving the request
+func (h *HoldHandler) service(c *gin.Context) *services.HoldService {
+	if h.sandboxHoldService != nil && isSandbox(c) {
+		return h.sandboxHoldService
+	}
+	return h.holdService
+}
+
+// requirePaymentSource refuses requests without the payment Source-Type
+func requirePaymentSource(c *gin.Context) {
+	if entities.SourceType(c.GetHeader("Source-Type")) != entities.SourceTypePayment {
+		abortWithProblem(c, problemHoldSourceType, "Holds require the payment Source-Type")
+		return
+	}
+	c.Next()
+}
+
+// CreateHold handles POST /user/{userId}/holds
+func (h *HoldHandler) CreateHold(c *gin.Context) {
+	userID, ok := pathUserID(c)
+	if !ok {
+		return
+	}
+
+	var req entities.HoldRequest
+	var err error
+	currency, minorUnits := minorUnitsCurrency(c)
+	if minorUnits {
+		req, err = bindMinorUnitsHoldRequest(c, currency)
+	} else {
+		err = c.ShouldBindJSON(&req)
+	}
+	if err != nil {
+		respondWithHoldBindError(c, currency, err)
+		return
+	}
+
+	hold, err := h.service(c).CreateHold(c.Request.Context(), userID, req, entities.SourceTypePayment)
+	if err != nil {
+		respondWithHoldError(c, err)
+		return
+	}
+
+	status := http.StatusCreated
+	if hold.Replayed {
+		status = http.StatusOK
+	}
+	respondWithHold(c, status, hold)
+}
+
+// CaptureHold handles POST /user/{userId}/holds/{holdId}/capture
+func (h *HoldHandler) CaptureHold(c *gin.Context) {
+	userID, ok := pathUserID(c)
+	if !ok {
+		return
+	}
+
+	// The body is optional; the full hold is captured without it
+	var req entities.CaptureRequest
+	var err error
+	currency, minorUnits := minorUnitsCurrency(c)
+	if c.Request.ContentLength != 0 {
+		if minorUnits {
+			req, err = bindMinorUnitsCaptureRequest(c, currency)
+		} else {
+			err = c.ShouldBindJSON(&req)
+		}
+	}
+	if err != nil {
+		respondWithHoldBindError(c, currency, err)
+		return
+	}
+
+	hold, err := h.service(c).CaptureHold(c.Request.Context(), userID, c.Param("holdId"), req)
+	if err != nil {
+		respondWithHoldError(c, err)
+		return
+	}
+
+	respondWithHold(c, http.StatusOK, hold)
+}
+
+// ReleaseHold handles POST /user/{userId}/holds/{holdId}/release
+func (h *HoldHandler) ReleaseHold(c *gin.Context) {
+	userID, ok := pathUserID(c)
+	if !ok {
+		return
+	}
+
+	hold, err := h.service(c).ReleaseHold(c.Request.Context(), userID, c.Param("holdId"))
+	if err != nil {
+		respondWithHoldError(c, err)
+		return
+	}
+
+	respondWithHold(c, http.StatusOK, hold)
+}
+
+// pathUserID parses the user ID from the path, responding with an error if
+// it is invalid
+func pathUserID(c *gin.Context) (uint64, bool) {
+	userID, err := strconv.ParseUint(c.Param("userId"), 10, 64)
+	if err != nil || userID == 0 {
+		respondWithProblem(c, problemInvalidUserID, "Invalid user ID")
+		return 0, false
+	}
+	return userID, true
+}
+
+func respondWithHoldBindError(c *gin.Context, currency entities.Currency, err error) {
+	if errors.Is(err, errUnsupportedCurrency) {
+		respondWithProblem(c, problemUnsupportedCurrency, "Unsupported currency. Must be "+currency.Code)
+		return
+	}
+	respondWithProblem(c, problemInvalidRequestBody, "Invalid request body: "+err.Error())
+}
+
+func respondWithHoldError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidAmount):
+		respondWithProblem(c, problemInvalidAmount, "Invalid amount. Must be positive with at most two decimal places and may not exceed the hold")
+
+	case errors.Is(err, services.ErrDuplicateTransaction):
+		respondWithProblem(c, problemDuplicateTransaction, "Hold ID already used as the transaction ID of a different transaction")
+
+	default:
+		respondWithError(c, err)
+	}
+}
+
+// respondWithHold writes hold, in minor units when the caller uses them
+func respondWithHold(c *gin.Context, status int, hold *entities.HoldResponse) {
+	if hold.Replayed {
+		c.Header("Idempotent-Replayed", "true")
+	}
+	if currency, ok := minorUnitsCurrency(c); ok {
+		converted, err := newMinorUnitsHold(currency, hold)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(status, converted)
+		return
+	}
+	c.JSON(status, hold)
+}
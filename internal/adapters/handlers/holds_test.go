@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHoldRoutes_RequirePaymentSource(t *testing.T) {
+	h := NewHoldHandler(nil, nil)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	h.SetupRoutes(router)
+
+	for _, path := range []string{"/user/1/holds", "/user/1/holds/h-1/capture", "/user/1/holds/h-1/release"} {
+		for _, sourceType := range []string{"", "game"} {
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{}`))
+			req.Header.Set("Source-Type", sourceType)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code, "%s with %q", path, sourceType)
+			assert.Contains(t, w.Body.String(), "payment Source-Type")
+		}
+	}
+}
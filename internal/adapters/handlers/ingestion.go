@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"transaction-service/internal/application/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IngestionHandler handles the exactly-once ingestion requests
+type IngestionHandler struct {
+	ingestionService *services.IngestionService
+}
+
+// NewIngestionHandler creates a new exactly-once ingestion HTTP handler
+func NewIngestionHandler(ingestionService *services.IngestionService) *IngestionHandler {
+	return &IngestionHandler{
+		ingestionService: ingestionService,
+	}
+}
+
+// SetupRoutes sets up the exactly-once ingestion routes
+func (h *IngestionHandler) SetupRoutes(router gin.IRouter) {
+	router.GET("/admin/ingestion/verify", h.VerifyIngestion)
+}
+
+// VerifyIngestion handles GET /admin/ingestion/verify?from=&to=
+func (h *IngestionHandler) VerifyIngestion(c *gin.Context) {
+	from, err := queryTime(c, "from")
+	if err == nil && from == nil {
+		err = errors.New("from is required")
+	}
+	if err != nil {
+		respondWithProblem(c, problemInvalidQuery, err.Error())
+		return
+	}
+	to, err := queryTime(c, "to")
+	if err == nil && to == nil {
+		err = errors.New("to is required")
+	}
+	if err != nil {
+		respondWithProblem(c, problemInvalidQuery, err.Error())
+		return
+	}
+
+	report, err := h.ingestionService.Verify(c.Request.Context(), *from, *to)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	// Duplicated transaction IDs are reported with their details
+	if !report.Valid {
+		c.JSON(http.StatusUnprocessableEntity, report)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"transaction-service/internal/logging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+)
+
+// RequestLogger assigns every request an ID, propagating the caller's
+// X-Request-ID when present, carries a request-scoped logger in the request
+// context and logs one structured line per request
+func RequestLogger(logger zerolog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		requestID := logging.RequestID(c.GetHeader(logging.RequestIDHeader))
+		c.Header(logging.RequestIDHeader, requestID)
+
+		scoped := logger.With().Str("request_id", requestID).Logger()
+		c.Request = c.Request.WithContext(scoped.WithContext(c.Request.Context()))
+		// Handlers may add fields to the logger stored in the context
+		requestLogger := zerolog.Ctx(c.Request.Context())
+
+		c.Next()
+
+		status := c.Writer.Status()
+		level := zerolog.InfoLevel
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = zerolog.ErrorLevel
+		case status >= http.StatusBadRequest:
+			level = zerolog.WarnLevel
+		}
+
+		event := requestLogger.WithLevel(level)
+		if userID := c.Param("userId"); userID != "" {
+			event = event.Str("user_id", userID)
+		}
+		if transactionID := c.Param("transactionId"); transactionID != "" {
+			event = event.Str("transaction_id", transactionID)
+		}
+		if sourceType := c.GetHeader("Source-Type"); sourceType != "" {
+			event = event.Str("source_type", sourceType)
+		}
+		if len(c.Errors) > 0 {
+			event = event.Str("errors", c.Errors.String())
+		}
+
+		event.
+			Str("method", c.Request.Method).
+			Str("route", c.FullPath()).
+			Str("path", c.Request.URL.Path).
+			Int("status", status).
+			Dur("duration_ms", time.Since(start)).
+			Str("client_ip", c.ClientIP()).
+			Msg("request completed")
+	}
+}
+
+// logTransactionID adds the transaction ID from the request body to the
+// request log line
+func logTransactionID(c *gin.Context, transactionID string) {
+	zerolog.Ctx(c.Request.Context()).UpdateContext(func(l zerolog.Context) zerolog.Context {
+		return l.Str("transaction_id", transactionID)
+	})
+}
@@ -0,0 +1,473 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+)
+
+// minorUnitsKey is the Gin context key holding the currency of callers that
+// exchange amounts in integer minor units
+const minorUnitsKey = "amounts.minorUnits"
+
+// MinorUnits serves callers using one of apiKeys with amounts as integer minor
+// units of currency (e.g. 1050 for 10.50 EUR) plus a currency code instead of
+// decimal strings. Other callers are unaffected.
+func MinorUnits(apiKeys []string, currency entities.Currency) gin.HandlerFunc {
+	minorUnits := make(map[string]bool, len(apiKeys))
+	for _, key := range apiKeys {
+		minorUnits[key] = true
+	}
+
+	return func(c *gin.Context) {
+		if minorUnits[c.GetHeader(APIKeyHeader)] {
+			c.Set(minorUnitsKey, currency)
+		}
+		c.Next()
+	}
+}
+
+// minorUnitsCurrency returns the currency amounts are expressed in when the
+// caller uses minor units
+func minorUnitsCurrency(c *gin.Context) (entities.Currency, bool) {
+	value, ok := c.Get(minorUnitsKey)
+	if !ok {
+		return entities.Currency{}, false
+	}
+	currency, ok := value.(entities.Currency)
+	return currency, ok
+}
+
+// errUnsupportedCurrency is returned for minor units requests in another currency
+var errUnsupportedCurrency = errors.New("unsupported currency")
+
+// minorUnitsTransactionRequest is the minor units form of entities.TransactionRequest
+type minorUnitsTransactionRequest struct {
+	State         string            `json:"state" binding:"required"`
+	Amount        int64             `json:"amount" binding:"required"`
+	Currency      string            `json:"currency" binding:"required"`
+	TransactionID string            `json:"transactionId" binding:"required"`
+	OccurredAt    *time.Time        `json:"occurredAt,omitempty"`
+	RoundID       string            `json:"roundId,omitempty"`
+	Metadata      map[string]string `json:"metadata,omitempty"`
+}
+
+// bindMinorUnitsRequest parses a minor units request body into a transaction
+// request. The amount is scaled by the exponent of the requested currency;
+// whether users may hold it is up to the service.
+func bindMinorUnitsRequest(c *gin.Context) (entities.TransactionRequest, error) {
+	var req minorUnitsTransactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return entities.TransactionRequest{}, err
+	}
+	return req.transactionRequest()
+}
+
+// transactionRequest converts the request to decimal amounts
+func (req minorUnitsTransactionRequest) transactionRequest() (entities.TransactionRequest, error) {
+	currency, ok := entities.LookupCurrency(req.Currency)
+	if !ok {
+		return entities.TransactionRequest{}, fmt.Errorf("%w: %s", errUnsupportedCurrency, req.Currency)
+	}
+
+	return entities.TransactionRequest{
+		State:         req.State,
+		Amount:        currency.FromMinorUnits(req.Amount).String(),
+		TransactionID: req.TransactionID,
+		Currency:      currency.Code,
+		OccurredAt:    req.OccurredAt,
+		RoundID:       req.RoundID,
+		Metadata:      req.Metadata,
+	}, nil
+}
+
+// currencyOrDefault looks up an ISO 4217 code, falling back to fallback for
+// empty or unknown codes
+func currencyOrDefault(code string, fallback entities.Currency) entities.Currency {
+	if currency, ok := entities.LookupCurrency(code); ok {
+		return currency
+	}
+	return fallback
+}
+
+// toMinorUnits converts a decimal amount string to minor units of currency
+func toMinorUnits(currency entities.Currency, amount string) (int64, error) {
+	value, err := decimal.NewFromString(amount)
+	if err != nil {
+		return 0, err
+	}
+	return currency.ToMinorUnits(value)
+}
+
+// minorUnitsBalanceResponse is the minor units form of entities.BalanceResponse
+type minorUnitsBalanceResponse struct {
+	UserID    uint64           `json:"userId"`
+	Balance   int64            `json:"balance"`
+	Currency  string           `json:"currency"`
+	Balances  map[string]int64 `json:"balances,omitempty"`
+	Available *int64           `json:"available,omitempty"`
+	Pending   *int64           `json:"pending,omitempty"`
+	Stale     bool             `json:"stale,omitempty"`
+	AsOf      *time.Time       `json:"asOf,omitempty"`
+}
+
+func newMinorUnitsBalanceResponse(currency entities.Currency, balance *entities.BalanceResponse) (*minorUnitsBalanceResponse, error) {
+	currency = currencyOrDefault(balance.Currency, currency)
+	units, err := toMinorUnits(currency, balance.Balance)
+	if err != nil {
+		return nil, err
+	}
+
+	var balances map[string]int64
+	if len(balance.Balances) > 0 {
+		balances = make(map[string]int64, len(balance.Balances))
+		for code, amount := range balance.Balances {
+			if balances[code], err = toMinorUnits(currencyOrDefault(code, currency), amount); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var available *int64
+	if balance.Available != "" {
+		units, err := toMinorUnits(currency, balance.Available)
+		if err != nil {
+			return nil, err
+		}
+		available = &units
+	}
+
+	var pending *int64
+	if balance.Pending != "" {
+		units, err := toMinorUnits(currency, balance.Pending)
+		if err != nil {
+			return nil, err
+		}
+		pending = &units
+	}
+
+	return &minorUnitsBalanceResponse{
+		UserID:    balance.UserID,
+		Balance:   units,
+		Currency:  currency.Code,
+		Balances:  balances,
+		Available: available,
+		Pending:   pending,
+		Stale:     balance.Stale,
+		AsOf:      balance.AsOf,
+	}, nil
+}
+
+// minorUnitsTransaction shadows the decimal amounts of a transaction with
+// their minor units
+type minorUnitsTransaction struct {
+	*entities.Transaction
+	Amount       int64  `json:"amount"`
+	BalanceAfter *int64 `json:"balanceAfter,omitempty"`
+	Currency     string `json:"currency"`
+}
+
+// minorUnitsTransactionPage is the minor units form of entities.TransactionPage
+type minorUnitsTransactionPage struct {
+	*entities.TransactionPage
+	Transactions []minorUnitsTransaction `json:"transactions"`
+}
+
+func newMinorUnitsTransactionPage(currency entities.Currency, page *entities.TransactionPage) (*minorUnitsTransactionPage, error) {
+	transactions := make([]minorUnitsTransaction, 0, len(page.Transactions))
+	for _, transaction := range page.Transactions {
+		currency := currencyOrDefault(transaction.Currency, currency)
+		amount, err := currency.ToMinorUnits(transaction.Amount)
+		if err != nil {
+			return nil, err
+		}
+		converted := minorUnitsTransaction{
+			Transaction: transaction,
+			Amount:      amount,
+			Currency:    currency.Code,
+		}
+		if transaction.BalanceAfter != nil {
+			balanceAfter, err := currency.ToMinorUnits(*transaction.BalanceAfter)
+			if err != nil {
+				return nil, err
+			}
+			converted.BalanceAfter = &balanceAfter
+		}
+		transactions = append(transactions, converted)
+	}
+
+	return &minorUnitsTransactionPage{
+		TransactionPage: page,
+		Transactions:    transactions,
+	}, nil
+}
+
+// minorUnitsRoundSummary shadows the decimal totals of a round with their
+// minor units
+type minorUnitsRoundSummary struct {
+	*entities.RoundSummary
+	TotalBets int64 `json:"totalBets"`
+	TotalWins int64 `json:"totalWins"`
+	TotalFees int64 `json:"totalFees"`
+	Net       int64 `json:"net"`
+}
+
+func newMinorUnitsRoundSummary(currency entities.Currency, summary *entities.RoundSummary) (*minorUnitsRoundSummary, error) {
+	currency = currencyOrDefault(summary.Currency, currency)
+	bets, err := toMinorUnits(currency, summary.TotalBets)
+	if err != nil {
+		return nil, err
+	}
+	wins, err := toMinorUnits(currency, summary.TotalWins)
+	if err != nil {
+		return nil, err
+	}
+	fees, err := toMinorUnits(currency, summary.TotalFees)
+	if err != nil {
+		return nil, err
+	}
+	net, err := toMinorUnits(currency, summary.Net)
+	if err != nil {
+		return nil, err
+	}
+
+	return &minorUnitsRoundSummary{
+		RoundSummary: summary,
+		TotalBets:    bets,
+		TotalWins:    wins,
+		TotalFees:    fees,
+		Net:          net,
+	}, nil
+}
+
+// minorUnitsStatsTotals is the minor units form of entities.StatsTotals
+type minorUnitsStatsTotals struct {
+	Wins        int   `json:"wins"`
+	Losses      int   `json:"losses"`
+	TotalWins   int64 `json:"totalWins"`
+	TotalLosses int64 `json:"totalLosses"`
+	Net         int64 `json:"net"`
+}
+
+func newMinorUnitsStatsTotals(currency entities.Currency, totals entities.StatsTotals) (minorUnitsStatsTotals, error) {
+	wins, err := toMinorUnits(currency, totals.TotalWins)
+	if err != nil {
+		return minorUnitsStatsTotals{}, err
+	}
+	losses, err := toMinorUnits(currency, totals.TotalLosses)
+	if err != nil {
+		return minorUnitsStatsTotals{}, err
+	}
+	net, err := toMinorUnits(currency, totals.Net)
+	if err != nil {
+		return minorUnitsStatsTotals{}, err
+	}
+	return minorUnitsStatsTotals{
+		Wins:        totals.Wins,
+		Losses:      totals.Losses,
+		TotalWins:   wins,
+		TotalLosses: losses,
+		Net:         net,
+	}, nil
+}
+
+// minorUnitsUserStats shadows the decimal totals of a user's statistics with
+// their minor units
+type minorUnitsUserStats struct {
+	*entities.UserStats
+	minorUnitsStatsTotals
+	BySourceType []minorUnitsSourceTypeStats `json:"bySourceType"`
+	Days         []minorUnitsDayStats        `json:"days"`
+}
+
+type minorUnitsSourceTypeStats struct {
+	SourceType entities.SourceType `json:"sourceType"`
+	minorUnitsStatsTotals
+}
+
+type minorUnitsDayStats struct {
+	Day string `json:"day"`
+	minorUnitsStatsTotals
+}
+
+func newMinorUnitsUserStats(currency entities.Currency, stats *entities.UserStats) (*minorUnitsUserStats, error) {
+	currency = currencyOrDefault(stats.Currency, currency)
+	totals, err := newMinorUnitsStatsTotals(currency, stats.StatsTotals)
+	if err != nil {
+		return nil, err
+	}
+
+	converted := &minorUnitsUserStats{
+		UserStats:             stats,
+		minorUnitsStatsTotals: totals,
+		BySourceType:          make([]minorUnitsSourceTypeStats, 0, len(stats.BySourceType)),
+		Days:                  make([]minorUnitsDayStats, 0, len(stats.Days)),
+	}
+	for _, sourceType := range stats.BySourceType {
+		totals, err := newMinorUnitsStatsTotals(currency, sourceType.StatsTotals)
+		if err != nil {
+			return nil, err
+		}
+		converted.BySourceType = append(converted.BySourceType, minorUnitsSourceTypeStats{sourceType.SourceType, totals})
+	}
+	for _, day := range stats.Days {
+		totals, err := newMinorUnitsStatsTotals(currency, day.StatsTotals)
+		if err != nil {
+			return nil, err
+		}
+		converted.Days = append(converted.Days, minorUnitsDayStats{day.Day, totals})
+	}
+	return converted, nil
+}
+
+// minorUnitsCreateUserRequest is the minor units form of entities.CreateUserRequest
+type minorUnitsCreateUserRequest struct {
+	Balance  *int64 `json:"balance"`
+	Currency string `json:"currency"`
+}
+
+// bindMinorUnitsCreateUserRequest parses a minor units request body into a
+// user creation request. The currency is only required with a balance.
+func bindMinorUnitsCreateUserRequest(c *gin.Context, currency entities.Currency) (entities.CreateUserRequest, error) {
+	var req minorUnitsCreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return entities.CreateUserRequest{}, err
+	}
+	if req.Balance == nil {
+		return entities.CreateUserRequest{}, nil
+	}
+	if !strings.EqualFold(req.Currency, currency.Code) {
+		return entities.CreateUserRequest{}, fmt.Errorf("%w: %s", errUnsupportedCurrency, req.Currency)
+	}
+
+	return entities.CreateUserRequest{
+		Balance: currency.FromMinorUnits(*req.Balance).String(),
+	}, nil
+}
+
+// minorUnitsUser shadows the decimal balance of a user with its minor units
+type minorUnitsUser struct {
+	*entities.UserResponse
+	Balance  int64  `json:"balance"`
+	Currency string `json:"currency"`
+}
+
+func newMinorUnitsUser(currency entities.Currency, user *entities.UserResponse) (*minorUnitsUser, error) {
+	balance, err := toMinorUnits(currency, user.Balance)
+	if err != nil {
+		return nil, err
+	}
+	return &minorUnitsUser{
+		UserResponse: user,
+		Balance:      balance,
+		Currency:     currency.Code,
+	}, nil
+}
+
+// minorUnitsUserPage is the minor units form of entities.UserPage
+type minorUnitsUserPage struct {
+	*entities.UserPage
+	Users []*minorUnitsUser `json:"users"`
+}
+
+func newMinorUnitsUserPage(currency entities.Currency, page *entities.UserPage) (*minorUnitsUserPage, error) {
+	users := make([]*minorUnitsUser, 0, len(page.Users))
+	for _, user := range page.Users {
+		converted, err := newMinorUnitsUser(currency, user)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, converted)
+	}
+
+	return &minorUnitsUserPage{
+		UserPage: page,
+		Users:    users,
+	}, nil
+}
+
+// minorUnitsHoldRequest is the minor units form of entities.HoldRequest
+type minorUnitsHoldRequest struct {
+	HoldID    string `json:"holdId" binding:"required"`
+	Amount    int64  `json:"amount" binding:"required"`
+	Currency  string `json:"currency" binding:"required"`
+	ExpiresIn string `json:"expiresIn,omitempty"`
+}
+
+// bindMinorUnitsHoldRequest parses a minor units request body into a hold
+// request. Holds are placed in the base currency only.
+func bindMinorUnitsHoldRequest(c *gin.Context, currency entities.Currency) (entities.HoldRequest, error) {
+	var req minorUnitsHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return entities.HoldRequest{}, err
+	}
+	if !strings.EqualFold(req.Currency, currency.Code) {
+		return entities.HoldRequest{}, fmt.Errorf("%w: %s", errUnsupportedCurrency, req.Currency)
+	}
+
+	return entities.HoldRequest{
+		HoldID:    req.HoldID,
+		Amount:    currency.FromMinorUnits(req.Amount).String(),
+		ExpiresIn: req.ExpiresIn,
+	}, nil
+}
+
+// minorUnitsCaptureRequest is the minor units form of entities.CaptureRequest
+type minorUnitsCaptureRequest struct {
+	Amount   *int64 `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// bindMinorUnitsCaptureRequest parses a minor units request body into a
+// capture request. The currency is only required with an amount.
+func bindMinorUnitsCaptureRequest(c *gin.Context, currency entities.Currency) (entities.CaptureRequest, error) {
+	var req minorUnitsCaptureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return entities.CaptureRequest{}, err
+	}
+	if req.Amount == nil {
+		return entities.CaptureRequest{}, nil
+	}
+	if !strings.EqualFold(req.Currency, currency.Code) {
+		return entities.CaptureRequest{}, fmt.Errorf("%w: %s", errUnsupportedCurrency, req.Currency)
+	}
+
+	return entities.CaptureRequest{
+		Amount: currency.FromMinorUnits(*req.Amount).String(),
+	}, nil
+}
+
+// minorUnitsHold shadows the decimal amounts of a hold with their minor units
+type minorUnitsHold struct {
+	*entities.HoldResponse
+	Amount         int64  `json:"amount"`
+	CapturedAmount *int64 `json:"capturedAmount,omitempty"`
+	Currency       string `json:"currency"`
+}
+
+func newMinorUnitsHold(currency entities.Currency, hold *entities.HoldResponse) (*minorUnitsHold, error) {
+	amount, err := toMinorUnits(currency, hold.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	converted := &minorUnitsHold{
+		HoldResponse: hold,
+		Amount:       amount,
+		Currency:     currency.Code,
+	}
+	if hold.CapturedAmount != "" {
+		captured, err := toMinorUnits(currency, hold.CapturedAmount)
+		if err != nil {
+			return nil, err
+		}
+		converted.CapturedAmount = &captured
+	}
+	return converted, nil
+}
@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var eur = entities.Currency{Code: "EUR", Exponent: 2}
+
+func TestBindMinorUnitsRequest(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantAmount string
+		wantErr    error
+	}{
+		{
+			name:       "amount in cents",
+			body:       `{"state":"win","amount":1050,"currency":"EUR","transactionId":"tx-1"}`,
+			wantAmount: "10.5",
+		},
+		{
+			name:       "lowercase currency",
+			body:       `{"state":"win","amount":1,"currency":"eur","transactionId":"tx-1"}`,
+			wantAmount: "0.01",
+		},
+		{
+			name:       "other currency",
+			body:       `{"state":"win","amount":1050,"currency":"USD","transactionId":"tx-1"}`,
+			wantAmount: "10.5",
+		},
+		{
+			name:       "currency without minor units",
+			body:       `{"state":"win","amount":1050,"currency":"JPY","transactionId":"tx-1"}`,
+			wantAmount: "1050",
+		},
+		{
+			name:    "unknown currency",
+			body:    `{"state":"win","amount":1050,"currency":"XYZ","transactionId":"tx-1"}`,
+			wantErr: errUnsupportedCurrency,
+		},
+		{
+			name: "decimal string amount",
+			body: `{"state":"win","amount":"10.50","currency":"EUR","transactionId":"tx-1"}`,
+		},
+		{
+			name: "fractional amount",
+			body: `{"state":"win","amount":10.5,"currency":"EUR","transactionId":"tx-1"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPost, "/user/1/transaction", strings.NewReader(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			req, err := bindMinorUnitsRequest(c)
+			switch {
+			case tt.wantErr != nil:
+				assert.ErrorIs(t, err, tt.wantErr)
+			case tt.wantAmount == "":
+				assert.Error(t, err)
+			default:
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantAmount, req.Amount)
+				assert.Equal(t, "tx-1", req.TransactionID)
+				assert.Len(t, req.Currency, 3)
+			}
+		})
+	}
+}
+
+func TestNewMinorUnitsTransactionPage(t *testing.T) {
+	balanceAfter := decimal.RequireFromString("110.50")
+	page := &entities.TransactionPage{
+		Transactions: []*entities.Transaction{{
+			ID:            1,
+			TransactionID: "tx-1",
+			Amount:        decimal.RequireFromString("10.50"),
+			BalanceAfter:  &balanceAfter,
+		}},
+		Total: 1,
+		Limit: 50,
+	}
+
+	converted, err := newMinorUnitsTransactionPage(eur, page)
+	require.NoError(t, err)
+
+	encoded, err := json.Marshal(converted)
+	require.NoError(t, err)
+
+	var decoded struct {
+		Transactions []map[string]any `json:"transactions"`
+		Total        int              `json:"total"`
+	}
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+	require.Len(t, decoded.Transactions, 1)
+	assert.Equal(t, 1, decoded.Total)
+	assert.Equal(t, float64(1050), decoded.Transactions[0]["amount"])
+	assert.Equal(t, float64(11050), decoded.Transactions[0]["balanceAfter"])
+	assert.Equal(t, "EUR", decoded.Transactions[0]["currency"])
+	assert.Equal(t, "tx-1", decoded.Transactions[0]["transactionId"])
+}
+
+func TestNewMinorUnitsUserPage(t *testing.T) {
+	page := &entities.UserPage{
+		Users: []*entities.UserResponse{{ID: 1, Balance: "100.25", Status: entities.UserStatusActive}},
+		Total: 1,
+		Limit: 50,
+	}
+
+	converted, err := newMinorUnitsUserPage(eur, page)
+	require.NoError(t, err)
+
+	encoded, err := json.Marshal(converted)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"users": [{"id": 1, "balance": 10025, "currency": "EUR", "status": "active"}],
+		"total": 1,
+		"limit": 50,
+		"offset": 0
+	}`, string(encoded))
+}
+
+func TestNewMinorUnitsBalanceResponse(t *testing.T) {
+	converted, err := newMinorUnitsBalanceResponse(eur, &entities.BalanceResponse{
+		UserID:    1,
+		Balance:   "100.25",
+		Currency:  "EUR",
+		Balances:  map[string]string{"EUR": "100.25", "JPY": "500.00"},
+		Available: "40.25",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(10025), converted.Balance)
+	require.NotNil(t, converted.Available)
+	assert.Equal(t, int64(4025), *converted.Available)
+	assert.Equal(t, "EUR", converted.Currency)
+	assert.Equal(t, map[string]int64{"EUR": 10025, "JPY": 500}, converted.Balances)
+}
+
+func TestNewMinorUnitsRoundSummary(t *testing.T) {
+	converted, err := newMinorUnitsRoundSummary(eur, &entities.RoundSummary{
+		UserID:       1,
+		RoundID:      "round-1",
+		Currency:     "EUR",
+		Transactions: 2,
+		TotalBets:    "10.00",
+		TotalWins:    "12.50",
+		TotalFees:    "0.50",
+		Net:          "2.00",
+	})
+	require.NoError(t, err)
+
+	encoded, err := json.Marshal(converted)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"userId": 1,
+		"roundId": "round-1",
+		"currency": "EUR",
+		"transactions": 2,
+		"cancelled": 0,
+		"totalBets": 1000,
+		"totalWins": 1250,
+		"totalFees": 50,
+		"net": 200
+	}`, string(encoded))
+}
+
+func TestNewMinorUnitsUserStats(t *testing.T) {
+	totals := entities.StatsTotals{Wins: 1, Losses: 1, TotalWins: "12.50", TotalLosses: "10.00", Net: "2.50"}
+	converted, err := newMinorUnitsUserStats(eur, &entities.UserStats{
+		UserID:       1,
+		Currency:     "EUR",
+		From:         time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+		To:           time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC),
+		StatsTotals:  totals,
+		BySourceType: []entities.SourceTypeStats{{SourceType: entities.SourceTypeGame, StatsTotals: totals}},
+		Days:         []entities.DayStats{{Day: "2025-03-01", StatsTotals: totals}},
+	})
+	require.NoError(t, err)
+
+	encoded, err := json.Marshal(converted)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"userId": 1,
+		"currency": "EUR",
+		"from": "2025-03-01T00:00:00Z",
+		"to": "2025-03-02T00:00:00Z",
+		"wins": 1,
+		"losses": 1,
+		"totalWins": 1250,
+		"totalLosses": 1000,
+		"net": 250,
+		"bySourceType": [{"sourceType": "game", "wins": 1, "losses": 1, "totalWins": 1250, "totalLosses": 1000, "net": 250}],
+		"days": [{"day": "2025-03-01", "wins": 1, "losses": 1, "totalWins": 1250, "totalLosses": 1000, "net": 250}]
+	}`, string(encoded))
+}
+
+func TestMinorUnitsHolds(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/user/1/holds",
+		strings.NewReader(`{"holdId":"h-1","amount":2550,"currency":"eur","expiresIn":"1h"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	req, err := bindMinorUnitsHoldRequest(c, eur)
+	require.NoError(t, err)
+	assert.Equal(t, entities.HoldRequest{HoldID: "h-1", Amount: "25.5", ExpiresIn: "1h"}, req)
+
+	c.Request = httptest.NewRequest(http.MethodPost, "/user/1/holds/h-1/capture",
+		strings.NewReader(`{"amount":1000,"currency":"USD"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	_, err = bindMinorUnitsCaptureRequest(c, eur)
+	assert.ErrorIs(t, err, errUnsupportedCurrency)
+
+	converted, err := newMinorUnitsHold(eur, &entities.HoldResponse{
+		HoldID:         "h-1",
+		Amount:         "25.50",
+		CapturedAmount: "10.00",
+		Status:         entities.HoldStatusCaptured,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2550), converted.Amount)
+	require.NotNil(t, converted.CapturedAmount)
+	assert.Equal(t, int64(1000), *converted.CapturedAmount)
+	assert.Equal(t, "EUR", converted.Currency)
+}
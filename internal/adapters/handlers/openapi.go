@@ -0,0 +1,1212 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"transaction-service/internal/adapters/dualwrite"
+	"transaction-service/internal/adapters/metrics"
+	"transaction-service/internal/apiversion"
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/export"
+	"transaction-service/internal/health"
+	"transaction-service/internal/region"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+)
+
+// swaggerUIVersion pins the Swagger UI release /docs loads
+const swaggerUIVersion = "5.17.14"
+
+// apiOperation documents a route. Request and response are zero values of
+// the types bound and written by the handler; their schemas are generated
+// from the json tags, so the specification follows the types as they change.
+type apiOperation struct {
+	method  string
+	path    string
+	tag     string
+	summary string
+	// description adds the details the summary leaves out
+	description string
+	query       []apiParameter
+	// sourceType is set for the routes that require the Source-Type header
+	sourceType bool
+	request    any
+	// optionalBody is set when the request body may be left out
+	optionalBody bool
+	status       int
+	// response is nil for the routes answering without content
+	response any
+	// accepted is the response of the routes that may answer 202 Accepted
+	// instead, when they process the request in the background
+	accepted any
+	// contentType is set for the routes answering with a text document
+	// instead of JSON
+	contentType string
+	// problems are the route-specific problems; the ones every route can
+	// report are added by the generator
+	problems []problemType
+	// public routes skip authentication
+	public bool
+}
+
+// asyncTransactionDoc documents the status of a transaction processed in the
+// background
+var asyncTransactionDoc = struct {
+	TransactionID string                          `json:"transactionId"`
+	UserID        uint64                          `json:"userId"`
+	Status        entities.AsyncTransactionStatus `json:"status"`
+	StatusURL     string                          `json:"statusUrl"`
+	SubmittedAt   *time.Time                      `json:"submittedAt,omitempty"`
+	FinishedAt    *time.Time                      `json:"finishedAt,omitempty"`
+	Result        *entities.TransactionResult     `json:"result,omitempty"`
+	Problem       map[string]any                  `json:"problem,omitempty"`
+}{}
+
+// apiParameter documents a query parameter
+type apiParameter struct {
+	name        string
+	schema      map[string]any
+	description string
+}
+
+var (
+	stringParam   = map[string]any{"type": "string"}
+	integerParam  = map[string]any{"type": "integer", "minimum": 0}
+	booleanParam  = map[string]any{"type": "boolean"}
+	decimalParam  = map[string]any{"type": "string", "format": "decimal"}
+	dateTimeParam = map[string]any{"type": "string", "format": "date-time"}
+)
+
+var paginationParams = []apiParameter{
+	{"limit", integerParam, "Maximum number of items, at most 500"},
+	{"offset", integerParam, "Number of items to skip"},
+}
+
+var rangeParams = []apiParameter{
+	{"from", dateTimeParam, "Start of the range (RFC 3339)"},
+	{"to", dateTimeParam, "End of the range (RFC 3339)"},
+}
+
+var historyParams = append([]apiParameter{
+	{"sourceType", stringParam, "Only transactions of the source type"},
+	{"state", stringParam, "Only transactions of the state"},
+	rangeParams[0],
+	rangeParams[1],
+	{"cancelled", booleanParam, "Only cancelled or only live transactions"},
+	{"cursor", stringParam, "Cursor of the next page, returned by the previous one; cannot be combined with offset"},
+}, paginationParams...)
+
+var searchParams = append([]apiParameter{
+	{"transactionIdPrefix", stringParam, "Only transaction IDs starting with the prefix"},
+	{"userId", integerParam, "Only transactions of the user"},
+	{"minAmount", decimalParam, "Smallest amount"},
+	{"maxAmount", decimalParam, "Largest amount"},
+	{"metadataKey", stringParam, "Only transactions with the metadata key"},
+	{"metadataValue", stringParam, "Only transactions whose metadataKey has the value"},
+}, historyParams...)
+
+// minorUnitsNote documents the amounts of the API keys using minor units
+const minorUnitsNote = " API keys configured for minor units send and receive amounts as integers in the minor unit of the currency."
+
+// apiOperations documents every route the handlers serve. TestOpenAPI_MatchesRoutes
+// fails when a route is added or removed without updating it.
+var apiOperations = []apiOperation{
+	// Transactions
+	{
+		method: http.MethodPost, path: "/user/:userId/transaction", tag: "Transactions",
+		summary:     "Process a transaction",
+		description: "Applies a win or lose transaction to the balance of the user. Retrying with the same transactionId returns the original result with the Idempotent-Replayed header. When asynchronous processing is enabled, callers sending Prefer: respond-async get 202 Accepted once the transaction is queued, with its status URL in the Location header." + minorUnitsNote,
+		sourceType:  true,
+		request:     entities.TransactionRequest{},
+		status:      http.StatusOK,
+		response: struct {
+			Message       string `json:"message"`
+			Status        string `json:"status"`
+			ID            uint64 `json:"id"`
+			TransactionID string `json:"transactionId"`
+			Receipt       string `json:"receipt"`
+			Balance       string `json:"balance"`
+			Replayed      bool   `json:"replayed"`
+			Currency      string `json:"currency,omitempty"`
+			Fee           string `json:"fee,omitempty"`
+			Pending       bool   `json:"pending,omitempty"`
+		}{},
+		accepted: asyncTransactionDoc,
+		problems: []problemType{
+			problemInvalidUserID, problemSourceTypeRequired, problemInvalidSourceType, problemInvalidRequestBody,
+			problemInvalidState, problemInvalidAmount, problemAmountBelowMinimum, problemAmountAboveMaximum,
+			problemInvalidAmountPrecision, problemInvalidCurrency, problemUnsupportedCurrency,
+			problemInvalidOccurredAt, problemInvalidRoundID, problemInvalidMetadata, problemInsufficientFunds, problemSourceTypeForbidden,
+			problemAccountFrozen, problemSourceTypeNotAllowed, problemSystemAccount, problemUserNotFound,
+			problemDuplicateTransaction, problemBalanceChangeLimit, problemLossLimit, problemVelocityLimit,
+			problemAsyncQueueFull,
+		},
+	},
+	{
+		method: http.MethodPost, path: "/user/:userId/transactions/batch", tag: "Transactions",
+		summary:     "Process a batch of transactions",
+		description: "Processes up to 1000 transactions of the user in order, each on its own. Every entry gets the status, and the result or problem details, it would have been answered with on its own; resubmitting a batch replays the entries already processed." + minorUnitsNote,
+		sourceType:  true,
+		request:     entities.TransactionBatchRequest{},
+		status:      http.StatusOK,
+		response: struct {
+			Results   []TransactionBatchItem `json:"results"`
+			Processed int                    `json:"processed"`
+			Failed    int                    `json:"failed"`
+		}{},
+		problems: []problemType{
+			problemInvalidUserID, problemSourceTypeRequired, problemInvalidSourceType, problemInvalidRequestBody,
+			problemInvalidBatch, problemSourceTypeForbidden, problemUserNotFound,
+		},
+	},
+	{
+		method: http.MethodGet, path: "/user/:userId/balance", tag: "Transactions",
+		summary:     "Get the balance of a user",
+		description: "Balances served from cache during a database outage carry a Warning header." + minorUnitsNote,
+		query:       []apiParameter{{"at", dateTimeParam, "Past moment to reconstruct the base currency balance as of"}},
+		status:      http.StatusOK,
+		response: struct {
+			UserID  uint64                    `json:"userId"`
+			Balance *entities.BalanceResponse `json:"balance"`
+		}{},
+		problems: []problemType{problemInvalidUserID, problemInvalidQuery, problemUserNotFound},
+	},
+	{
+		method: http.MethodGet, path: "/user/:userId/balance/history", tag: "Transactions",
+		summary:     "List the daily closing balances of a user",
+		description: "Served when balance snapshots are enabled. Only the days snapshotted so far are listed.",
+		query: []apiParameter{
+			{"granularity", stringParam, "Granularity of the history; only day"},
+			{"from", dateTimeParam, "Start of the range; its UTC day is included. 30 days before to by default"},
+			{"to", dateTimeParam, "End of the range; its UTC day is left out. Now by default"},
+		},
+		status:   http.StatusOK,
+		response: entities.BalanceHistory{},
+		problems: []problemType{problemInvalidUserID, problemInvalidQuery, problemInvalidRange, problemUserNotFound},
+	},
+	{
+		method: http.MethodGet, path: "/user/:userId/transactions", tag: "Transactions",
+		summary:  "List the transactions of a user",
+		query:    historyParams,
+		status:   http.StatusOK,
+		response: entities.TransactionPage{},
+		problems: []problemType{problemInvalidUserID, problemInvalidQuery, problemInvalidFilter, problemUserNotFound},
+	},
+	{
+		method: http.MethodGet, path: "/user/:userId/transactions/export", tag: "Transactions",
+		summary:     "Export the transactions of a user",
+		description: "Streams the history matching the filters as CSV, newest first. Failures after the first row are reported in the X-Export-Error trailer.",
+		query: append([]apiParameter{
+			{"format", stringParam, "Export format; only csv"},
+		}, historyParams[:5]...),
+		status:      http.StatusOK,
+		contentType: "text/csv",
+		problems:    []problemType{problemInvalidUserID, problemInvalidQuery, problemInvalidFilter, problemUserNotFound},
+	},
+	{
+		method: http.MethodGet, path: "/user/:userId/rounds/:roundId", tag: "Transactions",
+		summary:  "Total the transactions of a game round",
+		query:    []apiParameter{{"currency", stringParam, "Currency to total the round in, the base currency by default"}},
+		status:   http.StatusOK,
+		response: entities.RoundSummary{},
+		problems: []problemType{problemInvalidUserID, problemInvalidCurrency, problemUnsupportedCurrency, problemRoundNotFound},
+	},
+	{
+		method: http.MethodGet, path: "/user/:userId/stats", tag: "Transactions",
+		summary: "Count and total a user's wins and losses by source type and day",
+		query: []apiParameter{
+			{"from", dateTimeParam, "Start of the range of business times, inclusive; 30 days before to by default"},
+			{"to", dateTimeParam, "End of the range, exclusive; now by default"},
+			{"currency", stringParam, "Currency to total, the base currency by default"},
+		},
+		status:   http.StatusOK,
+		response: entities.UserStats{},
+		problems: []problemType{problemInvalidUserID, problemInvalidQuery, problemInvalidRange, problemInvalidCurrency, problemUnsupportedCurrency, problemUserNotFound},
+	},
+	{
+		method: http.MethodGet, path: "/transaction/:transactionId", tag: "Transactions",
+		summary:  "Look up a transaction",
+		status:   http.StatusOK,
+		response: entities.Transaction{},
+		problems: []problemType{problemTransactionNotFound},
+	},
+	{
+		method: http.MethodGet, path: "/transaction/:transactionId/status", tag: "Transactions",
+		summary:     "Get the processing status of a transaction",
+		description: "Reports a transaction submitted for asynchronous processing as pending, processed with its result, or failed with the problem details it was rejected with. Statuses are kept by the instance the transaction was submitted to; transactions recorded in the ledger are reported as processed by every instance.",
+		status:      http.StatusOK,
+		response:    asyncTransactionDoc,
+		problems:    []problemType{problemTransactionNotFound},
+	},
+	{
+		method: http.MethodPost, path: "/transaction/:transactionId/refund", tag: "Transactions",
+		summary: "Refund a transaction",
+		status:  http.StatusOK,
+		response: struct {
+			Message       string `json:"message"`
+			Status        string `json:"status"`
+			ID            uint64 `json:"id"`
+			TransactionID string `json:"transactionId"`
+			Reverses      string `json:"reverses"`
+			Receipt       string `json:"receipt"`
+			Balance       string `json:"balance"`
+			Currency      string `json:"currency,omitempty"`
+		}{},
+		problems: []problemType{
+			problemInsufficientFunds, problemTransactionNotFound, problemDuplicateTransaction,
+			problemAlreadyRefunded, problemNotRefundable,
+		},
+	},
+	{
+		method: http.MethodPost, path: "/transaction/:transactionId/settle", tag: "Transactions",
+		summary:     "Settle a pending transaction",
+		description: "Moves the amount of a pending payment credit to the balance of its user ahead of the settlement job. Settling a settled transaction returns the current balance with the Idempotent-Replayed header.",
+		status:      http.StatusOK,
+		response: struct {
+			Message       string `json:"message"`
+			Status        string `json:"status"`
+			ID            uint64 `json:"id"`
+			TransactionID string `json:"transactionId"`
+			Receipt       string `json:"receipt"`
+			Balance       string `json:"balance"`
+			Replayed      bool   `json:"replayed"`
+			Currency      string `json:"currency,omitempty"`
+		}{},
+		problems: []problemType{problemTransactionNotFound, problemNotPending},
+	},
+	{
+		method: http.MethodPost, path: "/transfers", tag: "Transactions",
+		summary:  "Transfer funds between users",
+		request:  entities.TransferRequest{},
+		status:   http.StatusOK,
+		response: entities.TransferResult{},
+		problems: []problemType{
+			problemInvalidRequestBody, problemInvalidTransfer, problemInvalidAmount, problemInsufficientFunds,
+			problemAccountFrozen, problemUserNotFound, problemDuplicateTransfer,
+		},
+	},
+
+	// Holds
+	{
+		method: http.MethodPost, path: "/user/:userId/holds", tag: "Holds",
+		summary:     "Place a hold on funds",
+		description: "Returns 200 instead of 201 when the hold had already been placed." + minorUnitsNote,
+		sourceType:  true,
+		request:     entities.HoldRequest{},
+		status:      http.StatusCreated,
+		response:    entities.HoldResponse{},
+		problems: []problemType{
+			problemInvalidUserID, problemHoldSourceType, problemInvalidRequestBody, problemInvalidAmount,
+			problemUnsupportedCurrency, problemInvalidHoldExpiry, problemInsufficientFunds, problemAccountFrozen,
+			problemUserNotFound, problemDuplicateHold, problemDuplicateTransaction,
+		},
+	},
+	{
+		method: http.MethodPost, path: "/user/:userId/holds/:holdId/capture", tag: "Holds",
+		summary:      "Capture a hold",
+		description:  "Captures the full hold without a body." + minorUnitsNote,
+		sourceType:   true,
+		request:      entities.CaptureRequest{},
+		optionalBody: true,
+		status:       http.StatusOK,
+		response:     entities.HoldResponse{},
+		problems: []problemType{
+			problemInvalidUserID, problemHoldSourceType, problemInvalidRequestBody, problemInvalidAmount,
+			problemHoldNotFound, problemHoldNotActive,
+		},
+	},
+	{
+		method: http.MethodPost, path: "/user/:userId/holds/:holdId/release", tag: "Holds",
+		summary:    "Release a hold",
+		sourceType: true,
+		status:     http.StatusOK,
+		response:   entities.HoldResponse{},
+		problems:   []problemType{problemInvalidUserID, problemHoldSourceType, problemHoldNotFound, problemHoldNotActive},
+	},
+
+	// Scheduled transactions
+	{
+		method: http.MethodPost, path: "/user/:userId/scheduled-transactions", tag: "Scheduled transactions",
+		summary: "Schedule a transaction",
+		description: "Processes the transaction once at runAt, or on every activation of the cron expression. " +
+			"Returns 200 instead of 201 when the transaction had already been scheduled.",
+		sourceType: true,
+		request:    entities.ScheduleRequest{},
+		status:     http.StatusCreated,
+		response:   entities.ScheduledTransactionResponse{},
+		problems: []problemType{
+			problemInvalidUserID, problemInvalidRequestBody, problemInvalidSchedule, problemInvalidState, problemInvalidAmount,
+			problemAmountBelowMinimum, problemAmountAboveMaximum, problemInvalidAmountPrecision, problemInvalidCurrency,
+			problemUnsupportedCurrency, problemInvalidMetadata, problemAccountFrozen, problemSystemAccount,
+			problemUserNotFound, problemDuplicateSchedule,
+		},
+	},
+	{
+		method: http.MethodGet, path: "/user/:userId/scheduled-transactions", tag: "Scheduled transactions",
+		summary: "List the scheduled transactions of a user",
+		status:  http.StatusOK,
+		response: struct {
+			UserID                uint64                                   `json:"userId"`
+			ScheduledTransactions []*entities.ScheduledTransactionResponse `json:"scheduledTransactions"`
+		}{},
+		problems: []problemType{problemInvalidUserID, problemUserNotFound},
+	},
+	{
+		method: http.MethodPost, path: "/user/:userId/scheduled-transactions/:scheduleId/cancel", tag: "Scheduled transactions",
+		summary:  "Cancel a scheduled transaction",
+		status:   http.StatusOK,
+		response: entities.ScheduledTransactionResponse{},
+		problems: []problemType{problemInvalidUserID, problemScheduleNotFound, problemScheduleNotActive},
+	},
+
+	// Users
+	{
+		method: http.MethodPost, path: "/user", tag: "Users",
+		summary:      "Create a user",
+		description:  "The body is optional; users start with a zero balance without it." + minorUnitsNote,
+		request:      entities.CreateUserRequest{},
+		optionalBody: true,
+		status:       http.StatusCreated,
+		response:     entities.UserResponse{},
+		problems:     []problemType{problemInvalidRequestBody, problemInvalidAmount, problemUnsupportedCurrency},
+	},
+	{
+		method: http.MethodGet, path: "/user/:userId", tag: "Users",
+		summary:  "Get a user",
+		status:   http.StatusOK,
+		response: entities.UserResponse{},
+		problems: []problemType{problemInvalidUserID, problemUserNotFound},
+	},
+	{
+		method: http.MethodGet, path: "/users", tag: "Users",
+		summary:  "List users",
+		query:    paginationParams,
+		status:   http.StatusOK,
+		response: entities.UserPage{},
+		problems: []problemType{problemInvalidQuery, problemInvalidFilter},
+	},
+	{
+		method: http.MethodPost, path: "/user/:userId/freeze", tag: "Users",
+		summary:     "Freeze a user's account",
+		description: "Every transaction of a frozen account is rejected with status 403; balance reads remain available. Freezing a frozen account has no effect.",
+		status:      http.StatusOK,
+		response:    entities.UserResponse{},
+		problems:    []problemType{problemInvalidUserID, problemUserNotFound},
+	},
+	{
+		method: http.MethodPost, path: "/user/:userId/unfreeze", tag: "Users",
+		summary:  "Unfreeze a user's account",
+		status:   http.StatusOK,
+		response: entities.UserResponse{},
+		problems: []problemType{problemInvalidUserID, problemUserNotFound},
+	},
+
+	// Webhooks
+	{
+		method: http.MethodPost, path: "/webhooks", tag: "Webhooks",
+		summary:  "Register a webhook",
+		request:  entities.WebhookRequest{},
+		status:   http.StatusCreated,
+		response: entities.Webhook{},
+		problems: []problemType{problemInvalidRequestBody, problemInvalidWebhook, problemWebhooksDisabled},
+	},
+	{
+		method: http.MethodGet, path: "/webhooks", tag: "Webhooks",
+		summary: "List webhooks",
+		status:  http.StatusOK,
+		response: struct {
+			Webhooks []*entities.Webhook `json:"webhooks"`
+		}{},
+		problems: []problemType{problemWebhooksDisabled},
+	},
+	{
+		method: http.MethodGet, path: "/webhooks/:webhookId", tag: "Webhooks",
+		summary:  "Get a webhook",
+		status:   http.StatusOK,
+		response: entities.Webhook{},
+		problems: []problemType{problemInvalidWebhook, problemWebhooksDisabled, problemWebhookNotFound},
+	},
+	{
+		method: http.MethodDelete, path: "/webhooks/:webhookId", tag: "Webhooks",
+		summary:  "Delete a webhook",
+		status:   http.StatusNoContent,
+		problems: []problemType{problemInvalidWebhook, problemWebhooksDisabled, problemWebhookNotFound},
+	},
+	{
+		method: http.MethodGet, path: "/webhooks/:webhookId/deliveries", tag: "Webhooks",
+		summary:  "List the deliveries of a webhook",
+		query:    append([]apiParameter{{"status", stringParam, "Only deliveries in the status: pending, succeeded or failed"}}, paginationParams...),
+		status:   http.StatusOK,
+		response: entities.WebhookDeliveryPage{},
+		problems: []problemType{problemInvalidWebhook, problemInvalidQuery, problemInvalidFilter, problemWebhooksDisabled, problemWebhookNotFound},
+	},
+
+	// Sync
+	{
+		method: http.MethodGet, path: "/sync/transactions", tag: "Sync",
+		summary: "Read the transactions changed since a cursor",
+		query: []apiParameter{
+			{"since", stringParam, "Cursor returned by the previous sync; from the beginning without it"},
+			{"limit", integerParam, "Maximum number of transactions, at most 500"},
+		},
+		status:   http.StatusOK,
+		response: entities.SyncPage{},
+		problems: []problemType{problemInvalidQuery, problemInvalidFilter, problemInvalidSyncCursor},
+	},
+
+	// Administration
+	{
+		method: http.MethodGet, path: "/admin/config", tag: "Administration",
+		summary:  "Get the running configuration, secrets redacted",
+		status:   http.StatusOK,
+		response: map[string]any{},
+	},
+	{
+		method: http.MethodGet, path: "/admin/transactions", tag: "Administration",
+		summary:  "Search transactions",
+		query:    searchParams,
+		status:   http.StatusOK,
+		response: entities.TransactionPage{},
+		problems: []problemType{problemInvalidQuery, problemInvalidFilter},
+	},
+	{
+		method: http.MethodGet, path: "/admin/exports/:name/verify", tag: "Administration",
+		summary:     "Verify an export against its manifest",
+		description: "Tampered or incomplete exports are reported with status 422.",
+		status:      http.StatusOK,
+		response:    export.VerificationReport{},
+		problems:    []problemType{problemInvalidExportName, problemExportNotFound},
+	},
+	{
+		method: http.MethodPut, path: "/admin/users/:userId/jurisdiction", tag: "Administration",
+		summary: "Set the jurisdiction of a user",
+		request: struct {
+			Jurisdiction string `json:"jurisdiction"`
+		}{},
+		status:   http.StatusNoContent,
+		problems: []problemType{problemInvalidUserID, problemInvalidRequestBody, problemInvalidJurisdiction, problemUserNotFound},
+	},
+	{
+		method: http.MethodPut, path: "/admin/users/:userId/credit-limit", tag: "Administration",
+		summary:     "Set the credit limit of a user",
+		description: "The user's base currency balance may go down to minus the credit limit. Zero removes the credit line.",
+		request: struct {
+			CreditLimit string `json:"creditLimit"`
+		}{},
+		status:   http.StatusNoContent,
+		problems: []problemType{problemInvalidUserID, problemInvalidRequestBody, problemInvalidCreditLimit, problemUserNotFound},
+	},
+	{
+		method: http.MethodPost, path: "/admin/users/:userId/annotations", tag: "Administration",
+		summary:  "Annotate a user",
+		request:  entities.AnnotationRequest{},
+		status:   http.StatusCreated,
+		response: entities.Annotation{},
+		problems: []problemType{problemInvalidRequestBody, problemInvalidAnnotation, problemUserNotFound},
+	},
+	{
+		method: http.MethodGet, path: "/admin/users/:userId/annotations", tag: "Administration",
+		summary: "List the annotations of a user",
+		status:  http.StatusOK,
+		response: struct {
+			Annotations []*entities.Annotation `json:"annotations"`
+		}{},
+		problems: []problemType{problemUserNotFound},
+	},
+	{
+		method: http.MethodPost, path: "/admin/transactions/:transactionId/annotations", tag: "Administration",
+		summary:  "Annotate a transaction",
+		request:  entities.AnnotationRequest{},
+		status:   http.StatusCreated,
+		response: entities.Annotation{},
+		problems: []problemType{problemInvalidRequestBody, problemInvalidAnnotation, problemTransactionNotFound},
+	},
+	{
+		method: http.MethodGet, path: "/admin/transactions/:transactionId/annotations", tag: "Administration",
+		summary: "List the annotations of a transaction",
+		status:  http.StatusOK,
+		response: struct {
+			Annotations []*entities.Annotation `json:"annotations"`
+		}{},
+		problems: []problemType{problemTransactionNotFound},
+	},
+	{
+		method: http.MethodPost, path: "/admin/jobs/freeze-users", tag: "Administration",
+		summary:     "Freeze users in the background",
+		description: "The Location header points at the job.",
+		request:     entities.BulkFreezeRequest{},
+		status:      http.StatusAccepted,
+		response:    entities.BulkJob{},
+		problems:    []problemType{problemInvalidRequestBody, problemInvalidBulkJob, problemBulkJobQueueFull},
+	},
+	{
+		method: http.MethodPost, path: "/admin/jobs/adjust-balances", tag: "Administration",
+		summary:     "Correct balances in the background",
+		description: "The Location header points at the job.",
+		request:     entities.BulkAdjustmentRequest{},
+		status:      http.StatusAccepted,
+		response:    entities.BulkJob{},
+		problems:    []problemType{problemInvalidRequestBody, problemInvalidBulkJob, problemBulkJobQueueFull},
+	},
+	{
+		method: http.MethodPost, path: "/admin/jobs/redeliver-webhooks", tag: "Administration",
+		summary:     "Redeliver webhook deliveries in the background",
+		description: "The Location header points at the job.",
+		request:     entities.BulkRedeliveryRequest{},
+		status:      http.StatusAccepted,
+		response:    entities.BulkJob{},
+		problems:    []problemType{problemInvalidRequestBody, problemInvalidBulkJob, problemBulkJobQueueFull, problemWebhooksDisabled},
+	},
+	{
+		method: http.MethodPost, path: "/admin/jobs/export-transactions", tag: "Administration",
+		summary:     "Export user transaction histories with a manifest in the background",
+		description: "The Location header points at the job. The export is verified with GET /admin/exports/{name}/verify.",
+		request:     entities.BulkExportRequest{},
+		status:      http.StatusAccepted,
+		response:    entities.BulkJob{},
+		problems:    []problemType{problemInvalidRequestBody, problemInvalidBulkJob, problemBulkJobQueueFull, problemExportExists},
+	},
+	{
+		method: http.MethodGet, path: "/admin/jobs", tag: "Administration",
+		summary: "List bulk jobs",
+		status:  http.StatusOK,
+		response: struct {
+			Jobs []*entities.BulkJob `json:"jobs"`
+		}{},
+	},
+	{
+		method: http.MethodGet, path: "/admin/jobs/:jobId", tag: "Administration",
+		summary:  "Get the progress of a bulk job",
+		status:   http.StatusOK,
+		response: entities.BulkJob{},
+		problems: []problemType{problemBulkJobNotFound},
+	},
+	{
+		method: http.MethodGet, path: "/admin/fees", tag: "Administration",
+		summary: "List fee rules",
+		status:  http.StatusOK,
+		response: struct {
+			Rules []*entities.FeeRule `json:"rules"`
+		}{},
+	},
+	{
+		method: http.MethodPut, path: "/admin/fees/:sourceType/:state", tag: "Administration",
+		summary:  "Set the fee rule of a source type and state",
+		request:  entities.FeeRuleRequest{},
+		status:   http.StatusOK,
+		response: entities.FeeRule{},
+		problems: []problemType{problemInvalidRequestBody, problemInvalidFeeRule, problemInvalidSourceType, problemInvalidState},
+	},
+	{
+		method: http.MethodDelete, path: "/admin/fees/:sourceType/:state", tag: "Administration",
+		summary:  "Delete the fee rule of a source type and state",
+		status:   http.StatusNoContent,
+		problems: []problemType{problemInvalidSourceType, problemInvalidState, problemFeeRuleNotFound},
+	},
+	{
+		method: http.MethodGet, path: "/admin/system-accounts", tag: "Administration",
+		summary: "List the system accounts and their balances",
+		status:  http.StatusOK,
+		response: struct {
+			Accounts []*entities.SystemAccount `json:"accounts"`
+		}{},
+	},
+	{
+		method: http.MethodPost, path: "/admin/users/:userId/adjustments", tag: "Administration",
+		summary: "Credit or debit a user by hand",
+		description: "The actor is the subject of the bearer token, or else the actor of the request. " +
+			"Returns 200 instead of 201 when the adjustment had already been made.",
+		request:  entities.BalanceAdjustmentRequest{},
+		status:   http.StatusCreated,
+		response: entities.BalanceAdjustment{},
+		problems: []problemType{
+			problemInvalidUserID, problemInvalidRequestBody, problemInvalidAdjustment, problemInvalidAmount,
+			problemInvalidCurrency, problemUnsupportedCurrency, problemInsufficientFunds, problemSystemAccount,
+			problemUserNotFound, problemDuplicateAdjustment,
+		},
+	},
+	{
+		method: http.MethodGet, path: "/admin/users/:userId/adjustments", tag: "Administration",
+		summary: "List the balance adjustments of a user",
+		status:  http.StatusOK,
+		response: struct {
+			Adjustments []*entities.BalanceAdjustment `json:"adjustments"`
+		}{},
+		problems: []problemType{problemInvalidUserID, problemUserNotFound},
+	},
+	{
+		method: http.MethodGet, path: "/admin/audit-log", tag: "Administration",
+		summary: "List the audit log of balance-affecting operations",
+		query: append([]apiParameter{
+			{"userId", integerParam, "Only entries of the user"},
+			rangeParams[0],
+			rangeParams[1],
+		}, paginationParams...),
+		status:   http.StatusOK,
+		response: entities.AuditLogPage{},
+		problems: []problemType{problemInvalidQuery, problemInvalidFilter},
+	},
+	{
+		method: http.MethodGet, path: "/admin/balances/check", tag: "Administration",
+		summary:     "Recompute the balances from the transactions",
+		description: "Drifted balances are reported with status 422.",
+		status:      http.StatusOK,
+		response:    entities.BalanceCheckReport{},
+	},
+	{
+		method: http.MethodPost, path: "/admin/balances/repair", tag: "Administration",
+		summary:     "Set drifted balances to the ones recomputed from the transactions",
+		description: "Balances recomputed as negative are reported but not repaired.",
+		status:      http.StatusOK,
+		response:    entities.BalanceCheckReport{},
+	},
+	{
+		method: http.MethodGet, path: "/admin/settlement/preview", tag: "Administration",
+		summary: "Preview the next settlement batch",
+		description: "Totals the oldest pending transactions due for settlement by source type, with the fees charged for them and the house account movement, without settling them. " +
+			"The limit defaults to the batch size of the settlement worker.",
+		query: []apiParameter{
+			{"limit", integerParam, "Maximum number of transactions, at most 500"},
+		},
+		status:   http.StatusOK,
+		response: entities.SettlementPreview{},
+		problems: []problemType{problemInvalidQuery, problemInvalidFilter},
+	},
+	{
+		method: http.MethodPost, path: "/admin/reconciliation", tag: "Administration",
+		summary:     "Reconcile the statement of a source system with the ledger",
+		description: "The statement is sent as JSON or, with Content-Type text/csv, as CSV with a header row naming the transactionId, amount and optional state columns. Missing, mismatched and extra records are reported with status 422.",
+		query: append([]apiParameter{
+			{"sourceType", stringParam, "Source type of the statement"},
+		}, rangeParams...),
+		request:  []entities.StatementEntry{},
+		status:   http.StatusOK,
+		response: entities.ReconciliationReport{},
+		problems: []problemType{problemInvalidQuery, problemInvalidStatement, problemInvalidSourceType, problemInvalidRange},
+	},
+	{
+		method: http.MethodGet, path: "/admin/rejections", tag: "Administration",
+		summary: "List rejected transactions",
+		query: append([]apiParameter{
+			{"sourceType", stringParam, "Only rejections of the source type"},
+			{"reason", stringParam, "Only rejections for the reason"},
+			rangeParams[0],
+			rangeParams[1],
+		}, paginationParams...),
+		status:   http.StatusOK,
+		response: entities.RejectionPage{},
+		problems: []problemType{problemInvalidQuery, problemInvalidFilter},
+	},
+	{
+		method: http.MethodGet, path: "/admin/rejections/report", tag: "Administration",
+		summary:  "Report duplicate submissions and rejections by source",
+		query:    rangeParams,
+		status:   http.StatusOK,
+		response: entities.RejectionReport{},
+		problems: []problemType{problemInvalidQuery, problemInvalidRange},
+	},
+	{
+		method: http.MethodGet, path: "/admin/ingestion/verify", tag: "Administration",
+		summary:     "Verify that every ingested event was recorded",
+		description: "Gaps are reported with status 422.",
+		query:       rangeParams,
+		status:      http.StatusOK,
+		response:    entities.IngestionReport{},
+		problems:    []problemType{problemInvalidQuery, problemInvalidRange},
+	},
+	{
+		method: http.MethodPost, path: "/admin/restore-markers", tag: "Administration",
+		summary:  "Record a restore marker",
+		request:  entities.RestoreMarkerRequest{},
+		status:   http.StatusCreated,
+		response: entities.RestoreMarker{},
+		problems: []problemType{problemInvalidRequestBody, problemInvalidRestoreMarker, problemRestoreMarkerExists},
+	},
+	{
+		method: http.MethodGet, path: "/admin/restore-markers", tag: "Administration",
+		summary: "List restore markers",
+		status:  http.StatusOK,
+		response: struct {
+			Markers []*entities.RestoreMarker `json:"markers"`
+		}{},
+	},
+	{
+		method: http.MethodGet, path: "/admin/restore-markers/:name/verify", tag: "Administration",
+		summary:     "Verify a restored database against a marker",
+		description: "Restores that differ from the marker are reported with status 422.",
+		status:      http.StatusOK,
+		response:    entities.RestoreVerificationReport{},
+		problems:    []problemType{problemInvalidRestoreMarker, problemRestoreMarkerNotFound},
+	},
+	{
+		method: http.MethodGet, path: "/admin/slo", tag: "Administration",
+		summary:  "Report the service level objectives and their error budgets",
+		status:   http.StatusOK,
+		response: metrics.SLOReport{},
+	},
+	{
+		method: http.MethodGet, path: "/admin/region", tag: "Administration",
+		summary:  "Get the mode of the region",
+		status:   http.StatusOK,
+		response: region.Status{},
+	},
+	{
+		method: http.MethodPost, path: "/admin/region/promote", tag: "Administration",
+		summary:  "Promote the region to active",
+		status:   http.StatusOK,
+		response: region.Status{},
+		problems: []problemType{problemDatabaseNotWritable},
+	},
+	{
+		method: http.MethodPost, path: "/admin/region/demote", tag: "Administration",
+		summary: "Demote the region to standby",
+		request: struct {
+			ActiveRegionURL string `json:"activeRegionUrl" binding:"required"`
+		}{},
+		status:   http.StatusOK,
+		response: region.Status{},
+		problems: []problemType{problemInvalidRequestBody},
+	},
+	{
+		method: http.MethodGet, path: "/admin/storage-migration", tag: "Administration",
+		summary:  "Get the phase of the storage migration",
+		status:   http.StatusOK,
+		response: dualwrite.Status{},
+	},
+	{
+		method: http.MethodPost, path: "/admin/storage-migration/cutover", tag: "Administration",
+		summary:  "Cut reads and writes over to the new store",
+		status:   http.StatusOK,
+		response: dualwrite.Status{},
+		problems: []problemType{problemShadowBacklog},
+	},
+	{
+		method: http.MethodPost, path: "/admin/storage-migration/rollback", tag: "Administration",
+		summary:  "Roll back to the original store",
+		status:   http.StatusOK,
+		response: dualwrite.Status{},
+		problems: []problemType{problemShadowBacklog},
+	},
+
+	// Sandbox
+	{
+		method: http.MethodPost, path: "/sandbox/reset", tag: "Sandbox",
+		summary: "Reset the sandbox users and clock",
+		status:  http.StatusOK,
+		response: struct {
+			Message string `json:"message"`
+		}{},
+		problems: []problemType{problemSandboxKeyRequired},
+	},
+	{
+		method: http.MethodGet, path: "/sandbox/clock", tag: "Sandbox",
+		summary:  "Get the sandbox clock",
+		status:   http.StatusOK,
+		response: ClockResponse{},
+		problems: []problemType{problemSandboxKeyRequired},
+	},
+	{
+		method: http.MethodPost, path: "/sandbox/clock/advance", tag: "Sandbox",
+		summary:  "Move the sandbox clock forward",
+		request:  AdvanceClockRequest{},
+		status:   http.StatusOK,
+		response: ClockResponse{},
+		problems: []problemType{problemSandboxKeyRequired, problemInvalidRequestBody, problemInvalidDuration},
+	},
+
+	// Operations
+	{
+		method: http.MethodGet, path: "/healthz", tag: "Operations",
+		summary:     "Liveness probe",
+		description: "Answers 503 when a background loop stalled.",
+		status:      http.StatusOK,
+		response:    health.Report{},
+		public:      true,
+	},
+	{
+		method: http.MethodGet, path: "/readyz", tag: "Operations",
+		summary:     "Readiness probe",
+		description: "Answers 503 when a required dependency is down.",
+		status:      http.StatusOK,
+		response:    health.Report{},
+		public:      true,
+	},
+	{
+		method: http.MethodGet, path: "/version", tag: "Operations",
+		summary:  "Build information",
+		status:   http.StatusOK,
+		response: health.BuildInfo{},
+		public:   true,
+	},
+}
+
+// OpenAPISpec generates the OpenAPI 3 specification of the API
+func OpenAPISpec() map[string]any {
+	schemas := newSchemaRegistry()
+	paths := map[string]map[string]any{}
+	for _, op := range apiOperations {
+		// The API is served under /api/v1, the probes at the root
+		route := op.path
+		if !op.public {
+			route = apiversion.Path(apiversion.V1) + route
+		}
+		specPath := openAPIPath(route)
+		if paths[specPath] == nil {
+			paths[specPath] = map[string]any{}
+		}
+		paths[specPath][strings.ToLower(op.method)] = op.spec(schemas)
+	}
+
+	schemas.schemas["Problem"] = problemSchema
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "Transaction Service API",
+			"version": health.Version,
+			"description": "Errors are reported as RFC 7807 problem details; clients branch on their stable code. " +
+				"The routes are also served without the /api/v1 prefix for older clients; those are deprecated.",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas.schemas,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+				"apiKey":     map[string]any{"type": "apiKey", "in": "header", "name": APIKeyHeader},
+			},
+		},
+		// Deployments enable either scheme, or neither
+		"security": []map[string][]string{{"bearerAuth": {}}, {"apiKey": {}}, {}},
+	}
+}
+
+// spec builds the operation object of op
+func (op apiOperation) spec(schemas *schemaRegistry) map[string]any {
+	var parameters []map[string]any
+	for _, segment := range strings.Split(op.path, "/") {
+		name, ok := strings.CutPrefix(segment, ":")
+		if !ok {
+			continue
+		}
+		schema := stringParam
+		if name == "userId" || name == "webhookId" {
+			schema = map[string]any{"type": "integer", "minimum": 1}
+		}
+		parameters = append(parameters, map[string]any{"name": name, "in": "path", "required": true, "schema": schema})
+	}
+	if op.sourceType {
+		parameters = append(parameters, map[string]any{
+			"name": "Source-Type", "in": "header", "required": true,
+			"schema": map[string]any{"type": "string", "enum": []entities.SourceType{
+				entities.SourceTypeGame, entities.SourceTypeServer, entities.SourceTypePayment,
+			}},
+		})
+	}
+	for _, param := range op.query {
+		parameters = append(parameters, map[string]any{
+			"name": param.name, "in": "query", "schema": param.schema, "description": param.description,
+		})
+	}
+
+	success := map[string]any{"description": http.StatusText(op.status)}
+	switch {
+	case op.contentType != "":
+		success["content"] = map[string]any{
+			op.contentType: map[string]any{"schema": map[string]any{"type": "string"}},
+		}
+	case op.response != nil:
+		success["content"] = map[string]any{
+			"application/json": map[string]any{"schema": schemas.of(reflect.TypeOf(op.response))},
+		}
+	}
+	responses := map[string]any{strconv.Itoa(op.status): success}
+	if op.accepted != nil {
+		responses[strconv.Itoa(http.StatusAccepted)] = map[string]any{
+			"description": http.StatusText(http.StatusAccepted),
+			"content": map[string]any{
+				"application/json": map[string]any{"schema": schemas.of(reflect.TypeOf(op.accepted))},
+			},
+		}
+	}
+	for status, problems := range op.allProblems() {
+		codes := make([]string, len(problems))
+		for i, problem := range problems {
+			codes[i] = problem.code
+		}
+		responses[strconv.Itoa(status)] = map[string]any{
+			"description": http.StatusText(status) + ". Codes: " + strings.Join(codes, ", "),
+			"content": map[string]any{
+				ProblemContentType: map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/Problem"}},
+			},
+		}
+	}
+
+	spec := map[string]any{
+		"operationId": operationID(op.method, op.path),
+		"tags":        []string{op.tag},
+		"summary":     op.summary,
+		"responses":   responses,
+	}
+	if op.description != "" {
+		spec["description"] = op.description
+	}
+	if len(parameters) > 0 {
+		spec["parameters"] = parameters
+	}
+	if op.request != nil {
+		spec["requestBody"] = map[string]any{
+			"required": !op.optionalBody,
+			"content": map[string]any{
+				"application/json": map[string]any{"schema": schemas.of(reflect.TypeOf(op.request))},
+			},
+		}
+	}
+	if op.public {
+		spec["security"] = []map[string][]string{}
+	}
+	return spec
+}
+
+// allProblems groups the problems op can report by status, adding the ones
+// the middleware reports
+func (op apiOperation) allProblems() map[int][]problemType {
+	problems := slices.Clone(op.problems)
+	if !op.public {
+		problems = append(problems,
+			problemAPIKeyRequired, problemInvalidAPIKey, problemBearerTokenRequired, problemInvalidBearerToken,
+			problemRateLimited, problemUnavailable)
+		if isAdminRoute(op.path) {
+			problems = append(problems, problemAdminScopeRequired, problemAdminAuthRequired)
+		}
+	}
+	if op.method != http.MethodGet {
+		problems = append(problems, problemRequestTooLarge, problemRegionStandby)
+	}
+	problems = append(problems, problemInternal)
+
+	byStatus := map[int][]problemType{}
+	for _, problem := range problems {
+		byStatus[problem.status] = append(byStatus[problem.status], problem)
+	}
+	return byStatus
+}
+
+// problemSchema describes the problem details error responses
+var problemSchema = map[string]any{
+	"type":     "object",
+	"required": []string{"type", "code", "title", "status"},
+	"properties": map[string]any{
+		"type":      map[string]any{"type": "string", "description": "URI reference identifying the problem, /problems/{code}"},
+		"code":      map[string]any{"type": "string", "description": "Stable machine-readable code"},
+		"title":     map[string]any{"type": "string"},
+		"status":    map[string]any{"type": "integer"},
+		"detail":    map[string]any{"type": "string"},
+		"requestId": map[string]any{"type": "string"},
+	},
+	"additionalProperties": true,
+}
+
+// openAPIPath converts a gin route pattern to an OpenAPI path template
+func openAPIPath(route string) string {
+	segments := strings.Split(route, "/")
+	for i, segment := range segments {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			segments[i] = "{" + name + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// operationID derives a unique operation ID from the method and route
+func operationID(method, route string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(route, func(r rune) bool { return r == '/' || r == '-' || r == ':' }) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+var (
+	decimalType    = reflect.TypeOf(decimal.Decimal{})
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemaRegistry generates JSON schemas from Go types, registering named
+// structs as components referenced by the operations
+type schemaRegistry struct {
+	schemas map[string]any
+	types   map[string]reflect.Type
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{schemas: map[string]any{}, types: map[string]reflect.Type{}}
+}
+
+// of returns the schema of values of type t as encoding/json writes them
+func (r *schemaRegistry) of(t reflect.Type) map[string]any {
+	switch t {
+	case decimalType:
+		return map[string]any{"type": "string", "format": "decimal"}
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case rawMessageType:
+		return map[string]any{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return r.of(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": r.of(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": r.of(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return r.object(t)
+		}
+		name := schemaName(t)
+		if registered, ok := r.types[name]; ok {
+			if registered != t {
+				panic(fmt.Sprintf("openapi: schema name %s used by %s and %s", name, registered, t))
+			}
+		} else {
+			// Register before generating, so recursive types terminate
+			r.types[name] = t
+			r.schemas[name] = r.object(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	default:
+		return map[string]any{}
+	}
+}
+
+// object returns the schema of a struct. Fields bound with
+// binding:"required" are required.
+func (r *schemaRegistry) object(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	var required []string
+	r.addFields(t, properties, &required)
+
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
+
+func (r *schemaRegistry) addFields(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		// Embedded structs without a name are flattened into the parent
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				r.addFields(embedded, properties, required)
+				continue
+			}
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = r.of(field.Type)
+		if slices.Contains(strings.Split(field.Tag.Get("binding"), ","), "required") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// schemaName names the component of a struct, qualifying the types outside
+// the domain and this package with their package
+func schemaName(t reflect.Type) string {
+	switch pkg := path.Base(t.PkgPath()); pkg {
+	case "entities", "handlers":
+		return t.Name()
+	default:
+		return strings.ToUpper(pkg[:1]) + pkg[1:] + t.Name()
+	}
+}
+
+// DocsHandler serves the OpenAPI specification and a Swagger UI to browse it
+type DocsHandler struct {
+	spec []byte
+}
+
+// NewDocsHandler creates a new documentation HTTP handler, generating the
+// specification once
+func NewDocsHandler() *DocsHandler {
+	spec, err := json.Marshal(OpenAPISpec())
+	if err != nil {
+		panic(fmt.Sprintf("openapi: failed to marshal the specification: %v", err))
+	}
+	return &DocsHandler{spec: spec}
+}
+
+// SetupRoutes sets up the documentation routes
+func (h *DocsHandler) SetupRoutes(router gin.IRouter) {
+	router.GET("/openapi.json", h.GetSpec)
+	router.GET("/docs", h.GetDocs)
+}
+
+// GetSpec handles GET /openapi.json
+func (h *DocsHandler) GetSpec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", h.spec)
+}
+
+// GetDocs handles GET /docs
+func (h *DocsHandler) GetDocs(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(docsPage))
+}
+
+// docsPage renders /openapi.json with Swagger UI, loaded from a CDN
+var docsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Transaction Service API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@` + swaggerUIVersion + `/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@` + swaggerUIVersion + `/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.onload = () => {
+      window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" });
+    };
+  </script>
+</body>
+</html>
+`
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"transaction-service/internal/health"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOpenAPI_MatchesRoutes keeps the specification in sync with the routes:
+// every route the handlers set up is documented, and nothing else is
+func TestOpenAPI_MatchesRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	NewHandler(nil).SetupRoutes(router)
+	NewUserHandler(nil, nil).SetupRoutes(router)
+	NewAdminHandler(nil, nil, nil, nil).SetupRoutes(router)
+	NewBulkJobHandler(nil).SetupRoutes(router)
+	NewRegionHandler(nil).SetupRoutes(router)
+	NewRestoreHandler(nil).SetupRoutes(router)
+	NewSyncHandler(nil).SetupRoutes(router)
+	NewIngestionHandler(nil).SetupRoutes(router)
+	NewHoldHandler(nil, nil).SetupRoutes(router)
+	NewScheduleHandler(nil).SetupRoutes(router)
+	NewBalanceHistoryHandler(nil).SetupRoutes(router)
+	NewSandboxHandler(nil, nil).SetupRoutes(router)
+	NewStorageMigrationHandler(nil).SetupRoutes(router)
+	NewWebhookHandler(nil).SetupRoutes(router)
+	NewRejectionHandler(nil).SetupRoutes(router)
+	NewFeeHandler(nil).SetupRoutes(router)
+	NewSystemAccountHandler(nil).SetupRoutes(router)
+	NewBalanceAdjustmentHandler(nil).SetupRoutes(router)
+	NewAuditLogHandler(nil).SetupRoutes(router)
+	NewBalanceCheckHandler(nil).SetupRoutes(router)
+	NewReconciliationHandler(nil).SetupRoutes(router)
+	NewSettlementHandler(nil, 0).SetupRoutes(router)
+	NewSLOHandler(nil).SetupRoutes(router)
+	NewHealthHandler(nil, nil, health.BuildInfo{}).SetupRoutes(router)
+
+	var routes []string
+	for _, route := range router.Routes() {
+		routes = append(routes, route.Method+" "+route.Path)
+	}
+	var documented []string
+	for _, op := range apiOperations {
+		documented = append(documented, op.method+" "+op.path)
+	}
+	assert.ElementsMatch(t, routes, documented)
+}
+
+func TestOpenAPISpec(t *testing.T) {
+	spec := OpenAPISpec()
+
+	body, err := json.Marshal(spec)
+	require.NoError(t, err)
+	var decoded struct {
+		Paths map[string]map[string]struct {
+			OperationID string `json:"operationId"`
+			Parameters  []struct {
+				Name string `json:"name"`
+				In   string `json:"in"`
+			} `json:"parameters"`
+			RequestBody *struct {
+				Content map[string]struct {
+					Schema map[string]any `json:"schema"`
+				} `json:"content"`
+			} `json:"requestBody"`
+			Responses map[string]json.RawMessage `json:"responses"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Required   []string       `json:"required"`
+				Properties map[string]any `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(body, &decoded))
+
+	operationIDs := map[string]bool{}
+	for _, methods := range decoded.Paths {
+		for _, op := range methods {
+			assert.False(t, operationIDs[op.OperationID], "duplicate operation ID %s", op.OperationID)
+			operationIDs[op.OperationID] = true
+		}
+	}
+
+	process := decoded.Paths["/api/v1/user/{userId}/transaction"]["post"]
+	assert.Equal(t, "postUserUserIdTransaction", process.OperationID)
+	var params []string
+	for _, param := range process.Parameters {
+		params = append(params, param.In+":"+param.Name)
+	}
+	assert.Equal(t, []string{"path:userId", "header:Source-Type"}, params)
+	assert.Equal(t, map[string]any{"$ref": "#/components/schemas/TransactionRequest"},
+		process.RequestBody.Content["application/json"].Schema)
+	assert.Contains(t, process.Responses, "200")
+	assert.Contains(t, string(process.Responses["400"]), "insufficient_funds")
+	assert.Contains(t, string(process.Responses["400"]), ProblemContentType)
+
+	// Schemas follow the json and binding tags of the types
+	request := decoded.Components.Schemas["TransactionRequest"]
+	assert.Equal(t, []string{"amount", "state", "transactionId"}, request.Required)
+	assert.Contains(t, request.Properties, "occurredAt")
+	assert.Contains(t, decoded.Components.Schemas, "RegionStatus")
+	assert.Contains(t, decoded.Components.Schemas, "Problem")
+
+	// Probes do not authenticate
+	assert.NotContains(t, decoded.Paths["/healthz"]["get"].Responses, "401")
+}
+
+func TestDocsHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	NewDocsHandler().SetupRoutes(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var spec map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &spec))
+	assert.Equal(t, "3.0.3", spec["openapi"])
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, w.Body.String(), `url: "/openapi.json"`)
+}
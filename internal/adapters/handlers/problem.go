@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProblemContentType is the media type of error responses (RFC 7807)
+const ProblemContentType = "application/problem+json"
+
+// problemTypeBase prefixes the code of a problem to form its type URI
+const problemTypeBase = "/problems/"
+
+// problemType is a kind of error the API reports. Its code is the stable,
+// machine-readable identifier clients branch on; the title summarizes it and
+// the detail of each response explains the occurrence.
+type problemType struct {
+	status int
+	code   string
+	title  string
+}
+
+// The problems the API reports. Codes are part of the API contract: new ones
+// may be added, but existing ones are never renamed or reused.
+var (
+	problemInvalidRequestBody      = problemType{http.StatusBadRequest, "invalid_request_body", "Invalid request body"}
+	problemInvalidQuery            = problemType{http.StatusBadRequest, "invalid_query", "Invalid query parameter"}
+	problemInvalidFilter           = problemType{http.StatusBadRequest, "invalid_filter", "Invalid filter"}
+	problemInvalidUserID           = problemType{http.StatusBadRequest, "invalid_user_id", "Invalid user ID"}
+	problemSourceTypeRequired      = problemType{http.StatusBadRequest, "source_type_required", "Source-Type header required"}
+	problemInvalidSourceType       = problemType{http.StatusBadRequest, "invalid_source_type", "Invalid source type"}
+	problemInvalidState            = problemType{http.StatusBadRequest, "invalid_state", "Invalid transaction state"}
+	problemInvalidAmount           = problemType{http.StatusBadRequest, "invalid_amount", "Invalid amount"}
+	problemAmountBelowMinimum      = problemType{http.StatusBadRequest, "amount_below_minimum", "Amount below minimum"}
+	problemAmountAboveMaximum      = problemType{http.StatusBadRequest, "amount_above_maximum", "Amount above maximum"}
+	problemInvalidAmountPrecision  = problemType{http.StatusBadRequest, "invalid_amount_precision", "Invalid amount precision"}
+	problemInvalidCurrency         = problemType{http.StatusBadRequest, "invalid_currency", "Invalid currency"}
+	problemUnsupportedCurrency     = problemType{http.StatusBadRequest, "unsupported_currency", "Unsupported currency"}
+	problemInvalidOccurredAt       = problemType{http.StatusBadRequest, "invalid_occurred_at", "Invalid occurredAt"}
+	problemInvalidRoundID          = problemType{http.StatusBadRequest, "invalid_round_id", "Invalid round ID"}
+	problemInvalidMetadata         = problemType{http.StatusBadRequest, "invalid_metadata", "Invalid metadata"}
+	problemInvalidTransfer         = problemType{http.StatusBadRequest, "invalid_transfer", "Invalid transfer"}
+	problemInvalidAdjustment       = problemType{http.StatusBadRequest, "invalid_adjustment", "Invalid balance adjustment"}
+	problemInsufficientFunds       = problemType{http.StatusBadRequest, "insufficient_funds", "Insufficient funds"}
+	problemInvalidRange            = problemType{http.StatusBadRequest, "invalid_range", "Invalid range"}
+	problemInvalidStatement        = problemType{http.StatusBadRequest, "invalid_statement", "Invalid statement"}
+	problemInvalidBatch            = problemType{http.StatusBadRequest, "invalid_batch", "Invalid transaction batch"}
+	problemInvalidSyncCursor       = problemType{http.StatusBadRequest, "invalid_sync_cursor", "Invalid sync cursor"}
+	problemInvalidJurisdiction     = problemType{http.StatusBadRequest, "invalid_jurisdiction", "Invalid jurisdiction"}
+	problemInvalidCreditLimit      = problemType{http.StatusBadRequest, "invalid_credit_limit", "Invalid credit limit"}
+	problemInvalidAnnotation       = problemType{http.StatusBadRequest, "invalid_annotation", "Invalid annotation"}
+	problemInvalidExportName       = problemType{http.StatusBadRequest, "invalid_export_name", "Invalid export name"}
+	problemInvalidBulkJob          = problemType{http.StatusBadRequest, "invalid_bulk_job", "Invalid bulk job"}
+	problemWebhooksDisabled        = problemType{http.StatusBadRequest, "webhooks_disabled", "Webhooks not enabled"}
+	problemInvalidWebhook          = problemType{http.StatusBadRequest, "invalid_webhook", "Invalid webhook"}
+	problemInvalidFeeRule          = problemType{http.StatusBadRequest, "invalid_fee_rule", "Invalid fee rule"}
+	problemInvalidHoldExpiry       = problemType{http.StatusBadRequest, "invalid_hold_expiry", "Invalid hold expiry"}
+	problemHoldSourceType          = problemType{http.StatusBadRequest, "hold_source_type", "Holds require the payment source type"}
+	problemInvalidSchedule         = problemType{http.StatusBadRequest, "invalid_schedule", "Invalid schedule"}
+	problemInvalidRestoreMarker    = problemType{http.StatusBadRequest, "invalid_restore_marker", "Invalid restore marker"}
+	problemInvalidDuration         = problemType{http.StatusBadRequest, "invalid_duration", "Invalid duration"}
+	problemInvalidConsistencyToken = problemType{http.StatusBadRequest, "invalid_consistency_token", "Invalid consistency token"}
+	problemAPIKeyRequired          = problemType{http.StatusUnauthorized, "api_key_required", "API key required"}
+	problemInvalidAPIKey           = problemType{http.StatusUnauthorized, "invalid_api_key", "Invalid API key"}
+	problemBearerTokenRequired     = problemType{http.StatusUnauthorized, "bearer_token_required", "Bearer token required"}
+	problemInvalidBearerToken      = problemType{http.StatusUnauthorized, "invalid_bearer_token", "Invalid bearer token"}
+	problemSignatureRequired       = problemType{http.StatusUnauthorized, "signature_required", "Request signature required"}
+	problemSignatureExpired        = problemType{http.StatusUnauthorized, "signature_expired", "Request signature expired"}
+	problemInvalidSignature        = problemType{http.StatusUnauthorized, "invalid_signature", "Invalid request signature"}
+	problemSourceTypeForbidden     = problemType{http.StatusForbidden, "source_type_forbidden", "Source type not allowed for the API key"}
+	problemAdminScopeRequired      = problemType{http.StatusForbidden, "admin_scope_required", "Admin scope required"}
+	problemAdminAuthRequired       = problemType{http.StatusForbidden, "admin_auth_required", "Admin routes require bearer token authentication"}
+	problemUserForbidden           = problemType{http.StatusForbidden, "user_forbidden", "Not allowed to operate on the user"}
+	problemSandboxKeyRequired      = problemType{http.StatusForbidden, "sandbox_key_required", "Sandbox API key required"}
+	problemAccountFrozen           = problemType{http.StatusForbidden, "account_frozen", "Account frozen"}
+	problemSourceTypeNotAllowed    = problemType{http.StatusForbidden, "source_type_not_allowed", "Source type not allowed in the jurisdiction"}
+	problemSystemAccount           = problemType{http.StatusForbidden, "system_account", "Not allowed on a system account"}
+	problemUserNotFound            = problemType{http.StatusNotFound, "user_not_found", "User not found"}
+	problemTransactionNotFound     = problemType{http.StatusNotFound, "transaction_not_found", "Transaction not found"}
+	problemRoundNotFound           = problemType{http.StatusNotFound, "round_not_found", "Round not found"}
+	problemHoldNotFound            = problemType{http.StatusNotFound, "hold_not_found", "Hold not found"}
+	problemScheduleNotFound        = problemType{http.StatusNotFound, "schedule_not_found", "Scheduled transaction not found"}
+	problemBulkJobNotFound         = problemType{http.StatusNotFound, "bulk_job_not_found", "Job not found"}
+	problemWebhookNotFound         = problemType{http.StatusNotFound, "webhook_not_found", "Webhook not found"}
+	problemFeeRuleNotFound         = problemType{http.StatusNotFound, "fee_rule_not_found", "Fee rule not found"}
+	problemExportNotFound          = problemType{http.StatusNotFound, "export_not_found", "Export not found"}
+	problemRestoreMarkerNotFound   = problemType{http.StatusNotFound, "restore_marker_not_found", "Restore marker not found"}
+	problemDuplicateTransaction    = problemType{http.StatusConflict, "duplicate_transaction", "Transaction ID already used"}
+	problemDuplicateTransfer       = problemType{http.StatusConflict, "duplicate_transfer", "Transfer ID already used"}
+	problemDuplicateAdjustment     = problemType{http.StatusConflict, "duplicate_adjustment", "Adjustment ID already used"}
+	problemDuplicateHold           = problemType{http.StatusConflict, "duplicate_hold", "Hold ID already used"}
+	problemHoldNotActive           = problemType{http.StatusConflict, "hold_not_active", "Hold no longer active"}
+	problemDuplicateSchedule       = problemType{http.StatusConflict, "duplicate_schedule", "Schedule ID already used"}
+	problemScheduleNotActive       = problemType{http.StatusConflict, "schedule_not_active", "Scheduled transaction no longer active"}
+	problemAlreadyRefunded         = problemType{http.StatusConflict, "already_refunded", "Transaction already refunded"}
+	problemNotRefundable           = problemType{http.StatusConflict, "not_refundable", "Transaction not refundable"}
+	problemNotPending              = problemType{http.StatusConflict, "transaction_not_pending", "Transaction not pending"}
+	problemRestoreMarkerExists     = problemType{http.StatusConflict, "restore_marker_exists", "Restore marker already exists"}
+	problemExportExists            = problemType{http.StatusConflict, "export_exists", "Export already exists"}
+	problemDatabaseNotWritable     = problemType{http.StatusConflict, "database_not_writable", "Database not writable"}
+	problemShadowBacklog           = problemType{http.StatusConflict, "shadow_backlog", "Shadow writes pending"}
+	problemRequestTooLarge         = problemType{http.StatusRequestEntityTooLarge, "request_too_large", "Request body too large"}
+	problemRegionStandby           = problemType{http.StatusMisdirectedRequest, "region_standby", "Region in standby"}
+	problemBalanceChangeLimit      = problemType{http.StatusUnprocessableEntity, "balance_change_limit", "Balance change limit exceeded"}
+	problemLossLimit               = problemType{http.StatusUnprocessableEntity, "loss_limit", "Loss limit exceeded"}
+	problemRateLimited             = problemType{http.StatusTooManyRequests, "rate_limited", "Rate limit exceeded"}
+	problemVelocityLimit           = problemType{http.StatusTooManyRequests, "velocity_limit", "Velocity limit exceeded"}
+	problemBulkJobQueueFull        = problemType{http.StatusTooManyRequests, "bulk_job_queue_full", "Too many jobs queued"}
+	problemAsyncQueueFull          = problemType{http.StatusTooManyRequests, "async_queue_full", "Too many transactions queued"}
+	problemInternal                = problemType{http.StatusInternalServerError, "internal", "Internal server error"}
+	problemUnavailable             = problemType{http.StatusServiceUnavailable, "unavailable", "Service unavailable"}
+)
+
+// errorProblems maps service errors to the problems reported for them, with
+// the detail used unless a handler has a more specific one
+var errorProblems = []struct {
+	err     error
+	problem problemType
+	detail  string
+}{
+	{services.ErrUserNotFound, problemUserNotFound, "User not found"},
+	{services.ErrInsufficientFunds, problemInsufficientFunds, "Insufficient funds"},
+	{services.ErrDuplicateTransaction, problemDuplicateTransaction, "Transaction ID already used for a different transaction"},
+	{services.ErrInvalidAmount, problemInvalidAmount, "Invalid amount format"},
+	{services.ErrAmountBelowMinimum, problemAmountBelowMinimum, "Amount is below the minimum accepted for the Source-Type"},
+	{services.ErrAmountAboveMaximum, problemAmountAboveMaximum, "Amount is above the maximum accepted for the Source-Type"},
+	{services.ErrAmountPrecision, problemInvalidAmountPrecision, "Amount has more decimal places than accepted"},
+	{services.ErrInvalidTransactionState, problemInvalidState, "Invalid state. Must be 'win' or 'lose'"},
+	{services.ErrInvalidSourceType, problemInvalidSourceType, "Invalid sourceType. Must be one of: game, server, payment"},
+	{services.ErrInvalidOccurredAt, problemInvalidOccurredAt, "occurredAt is outside the accepted clock skew"},
+	{services.ErrAccountFrozen, problemAccountFrozen, "Account is frozen"},
+	{services.ErrBalanceChangeLimitExceeded, problemBalanceChangeLimit, "Balance change limit exceeded, transaction held for review"},
+	{services.ErrSourceTypeNotAllowed, problemSourceTypeNotAllowed, "Source-Type is not allowed in the user's jurisdiction"},
+	{services.ErrSystemAccount, problemSystemAccount, "System accounts only move through fees, transfers and corrections"},
+	{services.ErrLossLimitExceeded, problemLossLimit, "Loss limit for the user's jurisdiction exceeded"},
+	{services.ErrVelocityLimitExceeded, problemVelocityLimit, "Transaction count or loss limit of the user's last transactions exceeded"},
+	{services.ErrInvalidCurrency, problemInvalidCurrency, "Invalid currency. Must be an ISO 4217 code"},
+	{services.ErrUnsupportedCurrency, problemUnsupportedCurrency, "Unsupported currency"},
+	{services.ErrInvalidBatch, problemInvalidBatch, "Invalid batch. Must hold 1 to 1000 transactions"},
+	{services.ErrInvalidRoundID, problemInvalidRoundID, "Invalid roundId. Must be at most 255 characters"},
+	{services.ErrInvalidMetadata, problemInvalidMetadata,
+		"Invalid metadata. Must hold at most 20 keys of letters, digits, '_', '-' and '.' up to 64 characters, with values up to 255 characters"},
+	{services.ErrRoundNotFound, problemRoundNotFound, "Round not found"},
+	{services.ErrTransactionNotFound, problemTransactionNotFound, "Transaction not found"},
+	{services.ErrAlreadyRefunded, problemAlreadyRefunded, "Transaction has already been refunded"},
+	{services.ErrNotRefundable, problemNotRefundable, "Refunds, transfer legs, adjustments, cancelled and pending transactions cannot be refunded"},
+	{services.ErrNotPending, problemNotPending, "Only pending payment credits can be settled"},
+	{services.ErrDuplicateTransfer, problemDuplicateTransfer, "Transfer ID already used for a different transfer"},
+	{services.ErrDuplicateAdjustment, problemDuplicateAdjustment, "Adjustment ID already used for a different adjustment"},
+	{services.ErrRegionStandby, problemRegionStandby, "This region is in standby and does not accept writes"},
+	{services.ErrInvalidJurisdiction, problemInvalidJurisdiction, "Invalid jurisdiction. Must be an ISO 3166-1 alpha-2 country code or empty"},
+	{services.ErrInvalidCreditLimit, problemInvalidCreditLimit, "Invalid credit limit. Must be a non-negative amount with at most 2 decimal places"},
+	{services.ErrInvalidAnnotation, problemInvalidAnnotation, "Invalid annotation: author and note are required and the note must not exceed 2000 characters"},
+	{services.ErrBulkJobNotFound, problemBulkJobNotFound, "Job not found"},
+	{services.ErrBulkJobQueueFull, problemBulkJobQueueFull, "Too many jobs are queued, please retry once some have finished"},
+	{services.ErrAsyncQueueFull, problemAsyncQueueFull, "Too many transactions are queued for asynchronous processing, please retry"},
+	{services.ErrWebhooksDisabled, problemWebhooksDisabled, "Webhooks are not enabled"},
+	{services.ErrExportExists, problemExportExists, "An export with this name already exists"},
+	{services.ErrWebhookNotFound, problemWebhookNotFound, "Webhook not found"},
+	{services.ErrInvalidFeeRule, problemInvalidFeeRule, "Invalid fee rule: percentage rules need a rate, flat rules an amount and tiered rules ascending tiers, and only those"},
+	{services.ErrFeeRuleNotFound, problemFeeRuleNotFound, "Fee rule not found"},
+	{services.ErrHoldNotFound, problemHoldNotFound, "Hold not found"},
+	{services.ErrHoldNotActive, problemHoldNotActive, "Hold is no longer active"},
+	{services.ErrDuplicateHold, problemDuplicateHold, "Hold ID already used for a different hold"},
+	{services.ErrHoldSourceType, problemHoldSourceType, "Holds require the payment Source-Type"},
+	{services.ErrInvalidHoldExpiry, problemInvalidHoldExpiry, "Invalid expiresIn. Must be a positive Go duration within the maximum hold expiry"},
+	{services.ErrInvalidSchedule, problemInvalidSchedule,
+		"Invalid schedule. Needs a scheduleId of at most 200 characters and either a future runAt or a five-field cron expression, with maxRuns only for cron"},
+	{services.ErrScheduleNotFound, problemScheduleNotFound, "Scheduled transaction not found"},
+	{services.ErrDuplicateSchedule, problemDuplicateSchedule, "Schedule ID already used for a different scheduled transaction"},
+	{services.ErrScheduleNotActive, problemScheduleNotActive, "Scheduled transaction has already completed"},
+	{services.ErrInvalidIngestionRange, problemInvalidRange, "Invalid range. from must be before to, at most 31 days apart"},
+	{services.ErrInvalidStatsRange, problemInvalidRange, "Invalid range. from must be before to, at most 366 days apart"},
+	{services.ErrInvalidBalanceHistoryRange, problemInvalidRange, "Invalid range. from must be before to, at most 366 days apart"},
+	{services.ErrInvalidGranularity, problemInvalidQuery, "Invalid granularity. Must be day"},
+	{services.ErrInvalidBalanceTime, problemInvalidQuery, "Invalid at. Must not be in the future"},
+	{services.ErrInvalidRejectionRange, problemInvalidRange, "Invalid range. from must be before to, at most 31 days apart"},
+	{services.ErrInvalidReconciliationRange, problemInvalidRange, "Invalid range. from must be before to, at most 31 days apart"},
+	{services.ErrInvalidRestoreMarker, problemInvalidRestoreMarker, "Invalid name. Must be 1 to 255 letters, digits, '.', '_' or '-'"},
+	{services.ErrRestoreMarkerExists, problemRestoreMarkerExists, "Restore marker already exists"},
+	{services.ErrRestoreMarkerNotFound, problemRestoreMarkerNotFound, "Restore marker not found"},
+	{services.ErrInvalidSyncCursor, problemInvalidSyncCursor, "Invalid since. Must be a cursor returned by a previous sync"},
+	{services.ErrUnavailable, problemUnavailable, "Service temporarily unavailable, please retry"},
+}
+
+// respondWithError reports err as the problem mapped to it. Errors that are
+// not mapped are internal errors; storage outages are mapped to 503 so that
+// clients know to retry, instead of as a missing resource or a generic
+// failure.
+func respondWithError(c *gin.Context, err error) {
+	problem, detail := errorProblem(err)
+	respondWithProblem(c, problem, detail)
+}
+
+// errorProblem returns the problem mapped to err and its detail
+func errorProblem(err error) (problemType, string) {
+	for _, mapped := range errorProblems {
+		if errors.Is(err, mapped.err) {
+			return mapped.problem, mapped.detail
+		}
+	}
+	return problemInternal, "Internal server error: " + err.Error()
+}
+
+// respondWithProblem writes a problem details response
+func respondWithProblem(c *gin.Context, problem problemType, detail string) {
+	writeProblem(c, problem.status, newProblem(c, problem, detail))
+}
+
+// abortWithProblem writes a problem details response and stops the chain
+func abortWithProblem(c *gin.Context, problem problemType, detail string) {
+	c.Abort()
+	respondWithProblem(c, problem, detail)
+}
+
+// newProblem builds the body of a problem details response. Handlers may add
+// extension members before writing it.
+func newProblem(c *gin.Context, problem problemType, detail string) gin.H {
+	body := gin.H{
+		"type":   problemTypeBase + problem.code,
+		"code":   problem.code,
+		"title":  problem.title,
+		"status": problem.status,
+	}
+	if detail != "" {
+		body["detail"] = detail
+	}
+	// The request logger assigns the ID before any handler runs
+	if requestID := c.Writer.Header().Get(logging.RequestIDHeader); requestID != "" {
+		body["requestId"] = requestID
+	}
+	return body
+}
+
+// writeProblem writes body with the problem details media type
+func writeProblem(c *gin.Context, status int, body gin.H) {
+	c.Header("Content-Type", ProblemContentType)
+	c.JSON(status, body)
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"transaction-service/internal/application/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRespondWithError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+		wantDetail string
+	}{
+		{
+			name:       "mapped errors are reported with their code",
+			err:        fmt.Errorf("failed to lock user: %w", services.ErrInsufficientFunds),
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "insufficient_funds",
+			wantDetail: "Insufficient funds",
+		},
+		{
+			name:       "storage outages ask for a retry",
+			err:        fmt.Errorf("failed to get user: %w", services.ErrUnavailable),
+			wantStatus: http.StatusServiceUnavailable,
+			wantCode:   "unavailable",
+			wantDetail: "Service temporarily unavailable, please retry",
+		},
+		{
+			name:       "other errors are internal",
+			err:        errors.New("boom"),
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   "internal",
+			wantDetail: "Internal server error: boom",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(RequestLogger(zerolog.Nop()))
+			router.GET("/fail", func(c *gin.Context) { respondWithError(c, tt.err) })
+
+			req := httptest.NewRequest(http.MethodGet, "/fail", nil)
+			req.Header.Set("X-Request-ID", "req-1")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, ProblemContentType, w.Header().Get("Content-Type"))
+
+			var problem map[string]any
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+			assert.Equal(t, "/problems/"+tt.wantCode, problem["type"])
+			assert.Equal(t, tt.wantCode, problem["code"])
+			assert.Equal(t, float64(tt.wantStatus), problem["status"])
+			assert.Equal(t, tt.wantDetail, problem["detail"])
+			assert.NotEmpty(t, problem["title"])
+			assert.Equal(t, "req-1", problem["requestId"])
+		})
+	}
+}
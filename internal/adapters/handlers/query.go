@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+)
+
+// queryInt parses an optional non-negative integer query parameter
+func queryInt(c *gin.Context, key string) (int, error) {
+	value := c.Query(key)
+	if value == "" {
+		return 0, nil
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer", key)
+	}
+	return parsed, nil
+}
+
+// queryUint parses an optional positive integer query parameter
+func queryUint(c *gin.Context, key string) (uint64, error) {
+	value := c.Query(key)
+	if value == "" {
+		return 0, nil
+	}
+
+	parsed, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || parsed == 0 {
+		return 0, fmt.Errorf("%s must be a positive integer", key)
+	}
+	return parsed, nil
+}
+
+// queryBool parses an optional boolean query parameter
+func queryBool(c *gin.Context, key string) (*bool, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be true or false", key)
+	}
+	return &parsed, nil
+}
+
+// queryDecimal parses an optional decimal query parameter
+func queryDecimal(c *gin.Context, key string) (*decimal.Decimal, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := decimal.NewFromString(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a decimal number", key)
+	}
+	return &parsed, nil
+}
+
+// queryTime parses an optional RFC 3339 timestamp query parameter
+func queryTime(c *gin.Context, key string) (*time.Time, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an RFC 3339 timestamp", key)
+	}
+	return &parsed, nil
+}
+
+// queryCursor parses an optional transaction cursor query parameter
+func queryCursor(c *gin.Context, key string) (*repositories.TransactionCursor, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := services.ParseTransactionCursor(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be the nextCursor of a previous page", key)
+	}
+	return parsed, nil
+}
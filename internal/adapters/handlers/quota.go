@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"github.com/shopspring/decimal"
+)
+
+// WithQuotaWarnings adds the remaining velocity limits of a user to the
+// successful transaction responses once the user has used warnRatio of a
+// limit
+func WithQuotaWarnings(warnRatio float64) HandlerOption {
+	return func(h *Handler) {
+		h.quotaWarnRatio = warnRatio
+	}
+}
+
+// setQuotaHeaders adds the standard remaining-quota headers to the response
+// for each velocity limit the user is near. The limits are the ones enforced
+// by the transaction service, read after the transaction was recorded. When
+// they cannot be read, the headers are left out rather than failing a
+// processed transaction.
+func (h *Handler) setQuotaHeaders(c *gin.Context, userID uint64) {
+	if h.quotaWarnRatio == 0 {
+		return
+	}
+
+	usage, err := h.transactionService.GetVelocityUsage(c.Request.Context(), userID)
+	if err != nil {
+		zerolog.Ctx(c.Request.Context()).Warn().Err(err).Uint64("userId", userID).Msg("failed to read velocity usage for quota headers")
+		return
+	}
+	if usage == nil {
+		return
+	}
+
+	if usage.MaxTransactions > 0 {
+		used := usage.MaxTransactions - usage.RemainingTransactions
+		if float64(used) >= float64(usage.MaxTransactions)*h.quotaWarnRatio {
+			c.Header("X-Quota-Limit-Transactions", strconv.Itoa(usage.MaxTransactions))
+			c.Header("X-Quota-Remaining-Transactions", strconv.Itoa(usage.RemainingTransactions))
+		}
+	}
+
+	if usage.MaxLoss.IsPositive() {
+		used := usage.MaxLoss.Sub(usage.RemainingLoss)
+		if used.GreaterThanOrEqual(usage.MaxLoss.Mul(decimal.NewFromFloat(h.quotaWarnRatio))) {
+			c.Header("X-Quota-Limit-Amount", usage.MaxLoss.StringFixed(2))
+			c.Header("X-Quota-Remaining-Amount", usage.RemainingLoss.StringFixed(2))
+		}
+	}
+}
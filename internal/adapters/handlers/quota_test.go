@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"transaction-service/internal/adapters/memory"
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/domain/entities"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuotaWarnings(t *testing.T) {
+	store := memory.NewStore()
+	memory.SeedUsers(store, []*entities.User{{ID: 1, Balance: decimal.NewFromInt(100)}})
+	service := services.NewTransactionService(
+		memory.NewUnitOfWork(store), memory.NewUserRepository(store), memory.NewTransactionRepository(store),
+		services.WithVelocityLimits(services.VelocityPolicy{
+			MaxTransactions: 4,
+			MaxLoss:         decimal.NewFromInt(50),
+			Window:          24 * time.Hour,
+		}),
+	)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	NewHandler(service, WithQuotaWarnings(0.5)).SetupRoutes(router)
+	process := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/user/1/transaction", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Source-Type", "game")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Below the warn ratio of both limits
+	w := process(`{"state": "lose", "amount": "10.00", "transactionId": "tx-1"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("X-Quota-Remaining-Transactions"))
+	assert.Empty(t, w.Header().Get("X-Quota-Remaining-Amount"))
+
+	w = process(`{"state": "lose", "amount": "20.00", "transactionId": "tx-2"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "4", w.Header().Get("X-Quota-Limit-Transactions"))
+	assert.Equal(t, "2", w.Header().Get("X-Quota-Remaining-Transactions"))
+	assert.Equal(t, "50.00", w.Header().Get("X-Quota-Limit-Amount"))
+	assert.Equal(t, "20.00", w.Header().Get("X-Quota-Remaining-Amount"))
+
+	// Replays carry no warnings
+	w = process(`{"state": "lose", "amount": "20.00", "transactionId": "tx-2"}`)
+	assert.Equal(t, "true", w.Header().Get("Idempotent-Replayed"))
+	assert.Empty(t, w.Header().Get("X-Quota-Remaining-Transactions"))
+}
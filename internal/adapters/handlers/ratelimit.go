@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/domain/entities"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+)
+
+// RateLimit rejects requests made faster than the per-user or
+// per-source-type rate of policy with 429 and a Retry-After header. Routes
+// without a user ID are only limited per source type. When the limiter fails,
+// requests are let through rather than failing the ledger.
+//
+// Every response reports the bucket closest to running out in the
+// X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset headers, so
+// clients can slow down before they are rejected.
+func RateLimit(limiter services.RateLimiter, policy services.RateLimitPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		type bucket struct {
+			key  string
+			rule services.RateLimitRule
+		}
+		// Sandbox traffic is limited separately from real users
+		prefix := ""
+		if isSandbox(c) {
+			prefix = "sandbox:"
+		}
+		var buckets []bucket
+		if userID := c.Param("userId"); userID != "" && !policy.PerUser.IsZero() {
+			buckets = append(buckets, bucket{key: prefix + "user:" + userID, rule: policy.PerUser})
+		}
+		sourceType := entities.SourceType(c.GetHeader("Source-Type"))
+		if rule, ok := policy.PerSource[sourceType]; ok && !rule.IsZero() {
+			buckets = append(buckets, bucket{key: prefix + "source:" + string(sourceType), rule: rule})
+		}
+
+		limited := false
+		var retryAfter time.Duration
+		var tightest *services.RateLimitDecision
+		var tightestRule services.RateLimitRule
+		for _, b := range buckets {
+			decision, err := limiter.Allow(c.Request.Context(), b.key, b.rule)
+			if err != nil {
+				zerolog.Ctx(c.Request.Context()).Warn().Err(err).Str("bucket", b.key).Msg("rate limiter unavailable, allowing request")
+				continue
+			}
+			if !decision.Allowed {
+				limited = true
+				retryAfter = max(retryAfter, decision.RetryAfter)
+			}
+			if tightest == nil || decision.Remaining < tightest.Remaining ||
+				decision.Remaining == tightest.Remaining && decision.ResetAfter > tightest.ResetAfter {
+				tightest, tightestRule = &decision, b.rule
+			}
+		}
+
+		if tightest != nil {
+			// The reset is the Unix time the bucket is full again, rounded up
+			reset := time.Now().Add(tightest.ResetAfter + time.Second - 1).Unix()
+			c.Header("X-RateLimit-Limit", strconv.Itoa(tightestRule.Burst))
+			c.Header("X-RateLimit-Remaining", strconv.Itoa(tightest.Remaining))
+			c.Header("X-RateLimit-Reset", strconv.FormatInt(reset, 10))
+		}
+
+		if limited {
+			c.Header("Retry-After", strconv.Itoa(max(1, int(math.Ceil(retryAfter.Seconds())))))
+			abortWithProblem(c, problemRateLimited, "Rate limit exceeded")
+			return
+		}
+
+		c.Next()
+	}
+}
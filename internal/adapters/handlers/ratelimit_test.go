@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/domain/entities"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingLimiter allows the first limit requests per bucket, each bucket
+// refilling completely in a second
+type countingLimiter struct {
+	limit int
+	taken map[string]int
+	err   error
+}
+
+func (l *countingLimiter) Allow(_ context.Context, key string, _ services.RateLimitRule) (services.RateLimitDecision, error) {
+	if l.err != nil {
+		return services.RateLimitDecision{}, l.err
+	}
+	l.taken[key]++
+	if l.taken[key] > l.limit {
+		return services.RateLimitDecision{RetryAfter: 1500 * time.Millisecond, ResetAfter: time.Second}, nil
+	}
+	return services.RateLimitDecision{Allowed: true, Remaining: l.limit - l.taken[key], ResetAfter: time.Second}, nil
+}
+
+var rateLimitTestPolicy = services.RateLimitPolicy{
+	PerUser: services.RateLimitRule{Rate: 1, Burst: 1},
+	PerSource: map[entities.SourceType]services.RateLimitRule{
+		entities.SourceTypePayment: {Rate: 1, Burst: 1},
+	},
+}
+
+func newRateLimitRouter(limiter services.RateLimiter) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/user/:userId/transaction", RateLimit(limiter, rateLimitTestPolicy), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func submit(router *gin.Engine, userID, sourceType string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/user/"+userID+"/transaction", nil)
+	req.Header.Set("Source-Type", sourceType)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimit(t *testing.T) {
+	limiter := &countingLimiter{limit: 2, taken: map[string]int{}}
+	router := newRateLimitRouter(limiter)
+
+	// Successful responses report what is left of the bucket
+	w := submit(router, "1", "game")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Limit"), "the burst of the user rule")
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Remaining"))
+	reset, err := strconv.ParseInt(w.Header().Get("X-RateLimit-Reset"), 10, 64)
+	require.NoError(t, err)
+	assert.InDelta(t, time.Now().Add(time.Second).Unix(), reset, 1)
+	assert.Equal(t, "0", submit(router, "1", "game").Header().Get("X-RateLimit-Remaining"))
+
+	w = submit(router, "1", "game")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "2", w.Header().Get("Retry-After"))
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+
+	// Other users have their own budget and game has no source rule
+	assert.Equal(t, http.StatusOK, submit(router, "2", "game").Code)
+
+	// Limited source types are shared across users
+	assert.Equal(t, http.StatusOK, submit(router, "3", "payment").Code)
+	w = submit(router, "4", "payment")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"), "the source bucket runs out first")
+	assert.Equal(t, http.StatusTooManyRequests, submit(router, "5", "payment").Code)
+	assert.NotContains(t, limiter.taken, "source:game")
+}
+
+func TestRateLimit_FailsOpen(t *testing.T) {
+	router := newRateLimitRouter(&countingLimiter{err: errors.New("redis down")})
+
+	w := submit(router, "1", "game")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("X-RateLimit-Remaining"))
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/domain/entities"
+
+	"github.com/gin-gonic/gin"
+)
+
+// statementFormats maps the accepted statement content types to their format
+var statementFormats = map[string]services.StatementFormat{
+	"text/csv":         services.StatementFormatCSV,
+	"application/json": services.StatementFormatJSON,
+}
+
+// ReconciliationHandler handles the statement reconciliation requests
+type ReconciliationHandler struct {
+	reconciliationService *services.ReconciliationService
+}
+
+// NewReconciliationHandler creates a new reconciliation HTTP handler
+func NewReconciliationHandler(reconciliationService *services.ReconciliationService) *ReconciliationHandler {
+	return &ReconciliationHandler{
+		reconciliationService: reconciliationService,
+	}
+}
+
+// SetupRoutes sets up the reconciliation routes
+func (h *ReconciliationHandler) SetupRoutes(router gin.IRouter) {
+	router.POST(adminPathPrefix+"/reconciliation", h.Reconcile)
+}
+
+// Reconcile handles POST /admin/reconciliation?sourceType=&from=&to= with a
+// CSV or JSON statement as the body
+func (h *ReconciliationHandler) Reconcile(c *gin.Context) {
+	from, err := queryTime(c, "from")
+	if err == nil && from == nil {
+		err = errors.New("from is required")
+	}
+	if err != nil {
+		respondWithProblem(c, problemInvalidQuery, err.Error())
+		return
+	}
+	to, err := queryTime(c, "to")
+	if err == nil && to == nil {
+		err = errors.New("to is required")
+	}
+	if err != nil {
+		respondWithProblem(c, problemInvalidQuery, err.Error())
+		return
+	}
+
+	format, ok := statementFormats[c.ContentType()]
+	if !ok {
+		respondWithProblem(c, problemInvalidStatement, "Content-Type must be text/csv or application/json")
+		return
+	}
+	entries, err := services.ParseStatement(c.Request.Body, format)
+	if err != nil {
+		respondWithProblem(c, problemInvalidStatement, err.Error())
+		return
+	}
+
+	sourceType := entities.SourceType(c.Query("sourceType"))
+	report, err := h.reconciliationService.Reconcile(c.Request.Context(), sourceType, *from, *to, entries)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	// Missing, mismatched and extra records are reported with their details
+	if !report.Reconciled {
+		c.JSON(http.StatusUnprocessableEntity, report)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"transaction-service/internal/apiversion"
+	"transaction-service/internal/region"
+
+	"github.com/gin-gonic/gin"
+)
+
+// regionAdminPath is exempt from the write guard so a standby can be promoted
+const regionAdminPath = "/admin/region"
+
+// RegionHandler handles the active-passive region administration requests
+type RegionHandler struct {
+	state *region.State
+}
+
+// NewRegionHandler creates a new region HTTP handler
+func NewRegionHandler(state *region.State) *RegionHandler {
+	return &RegionHandler{
+		state: state,
+	}
+}
+
+// SetupRoutes sets up the region administration routes
+func (h *RegionHandler) SetupRoutes(router gin.IRouter) {
+	admin := router.Group(regionAdminPath)
+
+	admin.GET("", h.GetStatus)
+	admin.POST("/promote", h.Promote)
+	admin.POST("/demote", h.Demote)
+}
+
+// WriteGuard refuses state-changing requests while the region is in standby,
+// pointing the client at the same path in the active region
+func (h *RegionHandler) WriteGuard() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		if h.state.AcceptsWrites() || strings.HasPrefix(apiversion.Strip(c.Request.URL.Path), regionAdminPath) {
+			c.Next()
+			return
+		}
+
+		activeRegionURL := h.state.ActiveRegionURL()
+		c.Header("Location", strings.TrimSuffix(activeRegionURL, "/")+c.Request.URL.RequestURI())
+		problem := newProblem(c, problemRegionStandby, "This region is in standby and does not accept writes")
+		problem["activeRegionUrl"] = activeRegionURL
+		c.Abort()
+		writeProblem(c, problemRegionStandby.status, problem)
+	}
+}
+
+// GetStatus handles GET /admin/region
+func (h *RegionHandler) GetStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, h.state.Status())
+}
+
+// Promote handles POST /admin/region/promote
+func (h *RegionHandler) Promote(c *gin.Context) {
+	status, err := h.state.Promote(c.Request.Context())
+	if err != nil {
+		if errors.Is(err, region.ErrNotWritable) {
+			respondWithProblem(c, problemDatabaseNotWritable, "Promote the database before the region: "+err.Error())
+			return
+		}
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// Demote handles POST /admin/region/demote
+func (h *RegionHandler) Demote(c *gin.Context) {
+	var req struct {
+		ActiveRegionURL string `json:"activeRegionUrl" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondWithProblem(c, problemInvalidRequestBody, "Invalid request body: "+err.Error())
+		return
+	}
+
+	status, err := h.state.Demote(req.ActiveRegionURL)
+	if err != nil {
+		respondWithProblem(c, problemInvalidRequestBody, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
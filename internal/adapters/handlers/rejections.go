@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RejectionHandler handles the rejection analytics requests
+type RejectionHandler struct {
+	rejectionService *services.RejectionService
+}
+
+// NewRejectionHandler creates a new rejection analytics HTTP handler
+func NewRejectionHandler(rejectionService *services.RejectionService) *RejectionHandler {
+	return &RejectionHandler{
+		rejectionService: rejectionService,
+	}
+}
+
+// SetupRoutes sets up the rejection analytics routes
+func (h *RejectionHandler) SetupRoutes(router gin.IRouter) {
+	router.GET("/admin/rejections", h.ListRejections)
+	router.GET("/admin/rejections/report", h.Report)
+}
+
+// Report handles GET /admin/rejections/report?from=&to=
+func (h *RejectionHandler) Report(c *gin.Context) {
+	from, err := queryTime(c, "from")
+	if err == nil && from == nil {
+		err = errors.New("from is required")
+	}
+	if err != nil {
+		respondWithProblem(c, problemInvalidQuery, err.Error())
+		return
+	}
+	to, err := queryTime(c, "to")
+	if err == nil && to == nil {
+		err = errors.New("to is required")
+	}
+	if err != nil {
+		respondWithProblem(c, problemInvalidQuery, err.Error())
+		return
+	}
+
+	report, err := h.rejectionService.Report(c.Request.Context(), *from, *to)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// ListRejections handles GET /admin/rejections with optional sourceType,
+// reason, from, to, limit and offset query parameters
+func (h *RejectionHandler) ListRejections(c *gin.Context) {
+	filter, err := parseRejectionFilter(c)
+	if err != nil {
+		respondWithProblem(c, problemInvalidQuery, err.Error())
+		return
+	}
+
+	page, err := h.rejectionService.ListRejections(c.Request.Context(), filter)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidFilter) {
+			respondWithProblem(c, problemInvalidFilter, "Invalid filter: limit must not exceed 500")
+			return
+		}
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// parseRejectionFilter reads the rejection listing criteria from the query string
+func parseRejectionFilter(c *gin.Context) (repositories.RejectionFilter, error) {
+	filter := repositories.RejectionFilter{
+		SourceType: entities.SourceType(c.Query("sourceType")),
+		Reason:     c.Query("reason"),
+	}
+
+	var err error
+	if filter.From, err = queryTime(c, "from"); err != nil {
+		return filter, err
+	}
+	if filter.To, err = queryTime(c, "to"); err != nil {
+		return filter, err
+	}
+	if filter.Limit, err = queryInt(c, "limit"); err != nil {
+		return filter, err
+	}
+	if filter.Offset, err = queryInt(c, "offset"); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
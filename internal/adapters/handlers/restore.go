@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"net/http"
+
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/domain/entities"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RestoreHandler handles the restore drill requests
+type RestoreHandler struct {
+	restoreService *services.RestoreService
+}
+
+// NewRestoreHandler creates a new restore drill HTTP handler
+func NewRestoreHandler(restoreService *services.RestoreService) *RestoreHandler {
+	return &RestoreHandler{
+		restoreService: restoreService,
+	}
+}
+
+// SetupRoutes sets up the restore drill routes
+func (h *RestoreHandler) SetupRoutes(router gin.IRouter) {
+	markers := router.Group("/admin/restore-markers")
+
+	markers.POST("", h.CreateMarker)
+	markers.GET("", h.ListMarkers)
+	markers.GET("/:name/verify", h.VerifyRestore)
+}
+
+// CreateMarker handles POST /admin/restore-markers
+func (h *RestoreHandler) CreateMarker(c *gin.Context) {
+	var req entities.RestoreMarkerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondWithProblem(c, problemInvalidRequestBody, "Invalid request body: "+err.Error())
+		return
+	}
+
+	marker, err := h.restoreService.CreateMarker(c.Request.Context(), req.Name)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, marker)
+}
+
+// ListMarkers handles GET /admin/restore-markers
+func (h *RestoreHandler) ListMarkers(c *gin.Context) {
+	markers, err := h.restoreService.ListMarkers(c.Request.Context())
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"markers": markers,
+	})
+}
+
+// VerifyRestore handles GET /admin/restore-markers/{name}/verify
+func (h *RestoreHandler) VerifyRestore(c *gin.Context) {
+	report, err := h.restoreService.Verify(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	// A restore that differs from the marker is reported with its details
+	if !report.Valid {
+		c.JSON(http.StatusUnprocessableEntity, report)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"transaction-service/internal/apiversion"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RouteGroups decides which middleware the routes run, by the route group
+// they belong to. A route belongs to the group with the longest path prefix
+// of its pattern without its API version, matched on whole segments; routes
+// outside every group and public routes run none of the group middleware.
+type RouteGroups struct {
+	// groups are ordered by decreasing prefix length, so the first match is
+	// the longest
+	groups []routeGroup
+	public map[string]bool
+}
+
+type routeGroup struct {
+	prefix     string
+	middleware []string
+}
+
+// NewRouteGroups creates RouteGroups from the middleware names of each group,
+// keyed by path prefix. Probes and scrapers are served on publicPaths.
+func NewRouteGroups(groups map[string][]string, publicPaths ...string) *RouteGroups {
+	r := &RouteGroups{public: make(map[string]bool, len(publicPaths))}
+	for _, path := range publicPaths {
+		r.public[path] = true
+	}
+	for prefix, middleware := range groups {
+		r.groups = append(r.groups, routeGroup{prefix: strings.TrimSuffix(prefix, "/"), middleware: middleware})
+	}
+	sort.Slice(r.groups, func(i, j int) bool {
+		return len(r.groups[i].prefix) > len(r.groups[j].prefix)
+	})
+	return r
+}
+
+// Runs reports whether the route with the given pattern runs the named
+// middleware
+func (r *RouteGroups) Runs(route, name string) bool {
+	if route == "" || r.public[route] {
+		return false
+	}
+	route = apiversion.Strip(route)
+	for _, group := range r.groups {
+		if route == group.prefix || strings.HasPrefix(route, group.prefix+"/") {
+			return slices.Contains(group.middleware, name)
+		}
+	}
+	return false
+}
+
+// Only runs middleware on the routes whose group runs the named middleware
+// and skips it elsewhere
+func (r *RouteGroups) Only(name string, middleware gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !r.Runs(c.FullPath(), name) {
+			c.Next()
+			return
+		}
+		middleware(c)
+	}
+}
+
+// BodyLimit rejects request bodies larger than limit bytes. Declared sizes
+// are rejected with 413 up front; bodies of unknown size fail to be read
+// once they exceed the limit.
+func BodyLimit(limit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > limit {
+			abortWithProblem(c, problemRequestTooLarge, "Request body is too large")
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+
+		c.Next()
+	}
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouteGroups(t *testing.T) {
+	groups := NewRouteGroups(map[string][]string{
+		"/":                          {"auth"},
+		"/admin/":                    {"auth", "body_limit"},
+		"/user/:userId/transaction":  {"auth", "signing"},
+		"/user/:userId/transactions": {},
+	}, "/healthz")
+
+	tests := []struct {
+		route string
+		name  string
+		want  bool
+	}{
+		{"/user/:userId/balance", "auth", true},
+		{"/user/:userId/balance", "signing", false},
+		{"/admin/transactions", "body_limit", true},
+		{"/admin", "body_limit", true},
+		// Prefixes match whole segments
+		{"/administrators", "body_limit", false},
+		{"/user/:userId/transaction", "signing", true},
+		{"/user/:userId/transactions", "auth", false},
+		// Routes belong to the same group in every version
+		{"/api/v1/user/:userId/transaction", "signing", true},
+		{"/api/v1/admin", "body_limit", true},
+		{"/healthz", "auth", false},
+		// Unmatched requests have no route
+		{"", "auth", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, groups.Runs(tt.route, tt.name), "%s runs %s", tt.route, tt.name)
+	}
+
+	t.Run("skipped middleware passes requests on", func(t *testing.T) {
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		deny := func(c *gin.Context) {
+			c.AbortWithStatus(http.StatusForbidden)
+		}
+		router.Use(groups.Only("signing", deny))
+		for _, path := range []string{"/user/:userId/transaction", "/user/:userId/balance"} {
+			router.POST(path, func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+		}
+
+		for path, want := range map[string]int{
+			"/user/1/transaction": http.StatusForbidden,
+			"/user/1/balance":     http.StatusOK,
+		} {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, nil))
+			assert.Equal(t, want, w.Code, path)
+		}
+	})
+}
+
+func TestBodyLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/echo", BodyLimit(8), func(c *gin.Context) {
+		var body map[string]any
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	send := func(body string, contentLength int64) int {
+		req := httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(body))
+		req.ContentLength = contentLength
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, send(`{"a":1}`, 7))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, send(`{"a":1234}`, 10))
+	// Bodies of unknown size are cut off at the limit
+	assert.Equal(t, http.StatusBadRequest, send(`{"a":1234}`, -1))
+}
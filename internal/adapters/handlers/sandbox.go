@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"transaction-service/internal/clock"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sandboxKey is the Gin context key marking requests served from the sandbox
+const sandboxKey = "sandbox"
+
+// Sandbox marks requests made with one of apiKeys as sandbox traffic. The
+// user routes serve such requests from isolated test users, so integrators
+// can exercise the API without touching real balances.
+func Sandbox(apiKeys []string) gin.HandlerFunc {
+	sandboxed := make(map[string]bool, len(apiKeys))
+	for _, key := range apiKeys {
+		sandboxed[key] = true
+	}
+
+	return func(c *gin.Context) {
+		if sandboxed[c.GetHeader(APIKeyHeader)] {
+			c.Set(sandboxKey, true)
+			c.Header("Sandbox", "true")
+		}
+		c.Next()
+	}
+}
+
+// isSandbox reports whether the request is sandbox traffic
+func isSandbox(c *gin.Context) bool {
+	return c.GetBool(sandboxKey)
+}
+
+// SandboxHandler handles the sandbox management requests
+type SandboxHandler struct {
+	reset func(ctx context.Context) error
+	clock *clock.Virtual
+}
+
+// AdvanceClockRequest is the body of POST /sandbox/clock/advance
+type AdvanceClockRequest struct {
+	Duration string `json:"duration" binding:"required"`
+}
+
+// ClockResponse describes the sandbox clock
+type ClockResponse struct {
+	Now    time.Time `json:"now"`
+	Offset string    `json:"offset"`
+}
+
+// NewSandboxHandler creates a new sandbox HTTP handler. reset restores the
+// sandbox to its deterministic test users and clock is the virtual clock the
+// sandbox services run on.
+func NewSandboxHandler(reset func(ctx context.Context) error, clock *clock.Virtual) *SandboxHandler {
+	return &SandboxHandler{
+		reset: reset,
+		clock: clock,
+	}
+}
+
+// SetupRoutes sets up the sandbox routes
+func (h *SandboxHandler) SetupRoutes(router gin.IRouter) {
+	sandbox := router.Group("/sandbox", h.requireSandbox)
+	sandbox.POST("/reset", h.Reset)
+	sandbox.GET("/clock", h.GetClock)
+	sandbox.POST("/clock/advance", h.AdvanceClock)
+}
+
+// requireSandbox refuses the sandbox management routes to other API keys
+func (h *SandboxHandler) requireSandbox(c *gin.Context) {
+	if !isSandbox(c) {
+		abortWithProblem(c, problemSandboxKeyRequired, "Sandbox management requires a sandbox API key")
+		return
+	}
+	c.Next()
+}
+
+// Reset handles POST /sandbox/reset
+func (h *SandboxHandler) Reset(c *gin.Context) {
+	if err := h.reset(c.Request.Context()); err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Sandbox reset successfully",
+	})
+}
+
+// GetClock handles GET /sandbox/clock
+func (h *SandboxHandler) GetClock(c *gin.Context) {
+	c.JSON(http.StatusOK, h.clockResponse())
+}
+
+// AdvanceClock handles POST /sandbox/clock/advance. The clock only moves
+// forward; reset the sandbox to bring it back to the wall clock.
+func (h *SandboxHandler) AdvanceClock(c *gin.Context) {
+	var req AdvanceClockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondWithProblem(c, problemInvalidRequestBody, "Invalid request body: "+err.Error())
+		return
+	}
+
+	duration, err := time.ParseDuration(req.Duration)
+	if err != nil {
+		respondWithProblem(c, problemInvalidDuration, "Invalid duration. Use a Go duration such as 90m or 24h")
+		return
+	}
+
+	if err := h.clock.Advance(duration); err != nil {
+		respondWithProblem(c, problemInvalidDuration, "Invalid duration. The sandbox clock only moves forward")
+		return
+	}
+
+	c.JSON(http.StatusOK, h.clockResponse())
+}
+
+func (h *SandboxHandler) clockResponse() ClockResponse {
+	return ClockResponse{
+		Now:    h.clock.Now().UTC(),
+		Offset: h.clock.Offset().String(),
+	}
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/clock"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSandboxReset(t *testing.T) {
+	tests := []struct {
+		name        string
+		apiKey      string
+		resetErr    error
+		wantStatus  int
+		wantReset   bool
+		wantSandbox string
+	}{
+		{
+			name:        "sandbox keys reset the sandbox",
+			apiKey:      "sandbox-key",
+			wantStatus:  http.StatusOK,
+			wantReset:   true,
+			wantSandbox: "true",
+		},
+		{
+			name:       "other keys are refused",
+			apiKey:     "live-key",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:        "reset failures are reported",
+			apiKey:      "sandbox-key",
+			resetErr:    errors.New("connection refused"),
+			wantStatus:  http.StatusInternalServerError,
+			wantReset:   true,
+			wantSandbox: "true",
+		},
+		{
+			name:        "storage outages are reported as unavailable",
+			apiKey:      "sandbox-key",
+			resetErr:    fmt.Errorf("failed to reset sandbox: %w", services.ErrUnavailable),
+			wantStatus:  http.StatusServiceUnavailable,
+			wantReset:   true,
+			wantSandbox: "true",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reset := false
+			h := NewSandboxHandler(func(context.Context) error {
+				reset = true
+				return tt.resetErr
+			}, clock.NewVirtual())
+
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(Sandbox([]string{"sandbox-key"}))
+			h.SetupRoutes(router)
+
+			req := httptest.NewRequest(http.MethodPost, "/sandbox/reset", nil)
+			req.Header.Set(APIKeyHeader, tt.apiKey)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantReset, reset)
+			assert.Equal(t, tt.wantSandbox, w.Header().Get("Sandbox"))
+		})
+	}
+}
+
+func TestSandboxClock(t *testing.T) {
+	tests := []struct {
+		name       string
+		apiKey     string
+		body       string
+		wantStatus int
+		wantOffset time.Duration
+	}{
+		{
+			name:       "advances the clock",
+			apiKey:     "sandbox-key",
+			body:       `{"duration":"25h"}`,
+			wantStatus: http.StatusOK,
+			wantOffset: 25 * time.Hour,
+		},
+		{
+			name:       "other keys are refused",
+			apiKey:     "live-key",
+			body:       `{"duration":"25h"}`,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "invalid durations are rejected",
+			apiKey:     "sandbox-key",
+			body:       `{"duration":"tomorrow"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "the clock never moves backwards",
+			apiKey:     "sandbox-key",
+			body:       `{"duration":"-1h"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "missing durations are rejected",
+			apiKey:     "sandbox-key",
+			body:       `{}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			virtual := clock.NewVirtual()
+			h := NewSandboxHandler(func(context.Context) error { return nil }, virtual)
+
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(Sandbox([]string{"sandbox-key"}))
+			h.SetupRoutes(router)
+
+			req := httptest.NewRequest(http.MethodPost, "/sandbox/clock/advance", strings.NewReader(tt.body))
+			req.Header.Set(APIKeyHeader, tt.apiKey)
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantOffset, virtual.Offset())
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var resp ClockResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, tt.wantOffset.String(), resp.Offset)
+			assert.WithinDuration(t, time.Now().Add(tt.wantOffset), resp.Now, time.Minute)
+		})
+	}
+}
+
+func TestRateLimit_SandboxBucketsAreSeparate(t *testing.T) {
+	limiter := &countingLimiter{limit: 1, taken: map[string]int{}}
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Sandbox([]string{"sandbox-key"}))
+	router.POST("/user/:userId/transaction", RateLimit(limiter, rateLimitTestPolicy), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	for _, apiKey := range []string{"live-key", "sandbox-key"} {
+		req := httptest.NewRequest(http.MethodPost, "/user/1/transaction", nil)
+		req.Header.Set("Source-Type", "game")
+		req.Header.Set(APIKeyHeader, apiKey)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code, apiKey)
+	}
+	assert.Equal(t, map[string]int{"user:1": 1, "sandbox:user:1": 1}, limiter.taken)
+}
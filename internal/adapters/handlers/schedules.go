@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"net/http"
+
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/domain/entities"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ScheduleHandler handles scheduled transaction HTTP requests
+type ScheduleHandler struct {
+	scheduleService *services.ScheduleService
+}
+
+// NewScheduleHandler creates a new scheduled transaction HTTP handler
+func NewScheduleHandler(scheduleService *services.ScheduleService) *ScheduleHandler {
+	return &ScheduleHandler{scheduleService: scheduleService}
+}
+
+// SetupRoutes sets up the scheduled transaction routes
+func (h *ScheduleHandler) SetupRoutes(router gin.IRouter) {
+	schedules := router.Group("/user/:userId/scheduled-transactions")
+	schedules.POST("", h.CreateSchedule)
+	schedules.GET("", h.ListSchedules)
+	schedules.POST("/:scheduleId/cancel", h.CancelSchedule)
+}
+
+// CreateSchedule handles POST /user/{userId}/scheduled-transactions
+func (h *ScheduleHandler) CreateSchedule(c *gin.Context) {
+	userID, ok := pathUserID(c)
+	if !ok {
+		return
+	}
+
+	// Runs are processed with the Source-Type the transaction was scheduled
+	// with
+	sourceTypeHeader := c.GetHeader("Source-Type")
+	if sourceTypeHeader == "" {
+		respondWithProblem(c, problemSourceTypeRequired, "Source-Type header is required")
+		return
+	}
+	sourceType := entities.SourceType(sourceTypeHeader)
+	if !sourceType.IsValid() {
+		respondWithProblem(c, problemInvalidSourceType, "Invalid Source-Type header. Must be one of: game, server, payment")
+		return
+	}
+
+	var req entities.ScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondWithProblem(c, problemInvalidRequestBody, "Invalid request body: "+err.Error())
+		return
+	}
+
+	schedule, err := h.scheduleService.CreateSchedule(c.Request.Context(), userID, req, sourceType)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	status := http.StatusCreated
+	if schedule.Replayed {
+		c.Header("Idempotent-Replayed", "true")
+		status = http.StatusOK
+	}
+	c.JSON(status, schedule)
+}
+
+// ListSchedules handles GET /user/{userId}/scheduled-transactions
+func (h *ScheduleHandler) ListSchedules(c *gin.Context) {
+	userID, ok := pathUserID(c)
+	if !ok {
+		return
+	}
+
+	schedules, err := h.scheduleService.ListSchedules(c.Request.Context(), userID)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"userId":                userID,
+		"scheduledTransactions": schedules,
+	})
+}
+
+// CancelSchedule handles POST /user/{userId}/scheduled-transactions/{scheduleId}/cancel
+func (h *ScheduleHandler) CancelSchedule(c *gin.Context) {
+	userID, ok := pathUserID(c)
+	if !ok {
+		return
+	}
+
+	schedule, err := h.scheduleService.CancelSchedule(c.Request.Context(), userID, c.Param("scheduleId"))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	if schedule.Replayed {
+		c.Header("Idempotent-Replayed", "true")
+	}
+	c.JSON(http.StatusOK, schedule)
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"transaction-service/internal/application/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SettlementHandler handles the settlement batch HTTP requests
+type SettlementHandler struct {
+	transactionService *services.TransactionService
+	batchSize          int
+}
+
+// NewSettlementHandler creates a new settlement HTTP handler previewing
+// batches of batchSize transactions by default, the batch size of the
+// settlement worker
+func NewSettlementHandler(transactionService *services.TransactionService, batchSize int) *SettlementHandler {
+	return &SettlementHandler{
+		transactionService: transactionService,
+		batchSize:          min(batchSize, services.MaxPageSize),
+	}
+}
+
+// SetupRoutes sets up the settlement routes
+func (h *SettlementHandler) SetupRoutes(router gin.IRouter) {
+	router.GET(adminPathPrefix+"/settlement/preview", h.PreviewSettlement)
+}
+
+// PreviewSettlement handles GET /admin/settlement/preview with an optional
+// limit query parameter
+func (h *SettlementHandler) PreviewSettlement(c *gin.Context) {
+	limit, err := queryInt(c, "limit")
+	if err != nil {
+		respondWithProblem(c, problemInvalidQuery, err.Error())
+		return
+	}
+	if limit == 0 {
+		limit = h.batchSize
+	}
+
+	preview, err := h.transactionService.PreviewSettlement(c.Request.Context(), limit)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidFilter) {
+			respondWithProblem(c, problemInvalidFilter, "Invalid filter: limit must not exceed 500")
+			return
+		}
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, preview)
+}
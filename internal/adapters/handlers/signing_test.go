@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestSignature(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/user/:userId/transaction", RequestSignature("secret", time.Minute, func() time.Time { return now }),
+		func(c *gin.Context) {
+			// The verified body is still readable
+			body, _ := io.ReadAll(c.Request.Body)
+			c.String(http.StatusOK, string(body))
+		})
+
+	const body = `{"state":"win","amount":"10.00","transactionId":"tx-1"}`
+	send := func(path string, signedAt time.Time, signature string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set(SignatureTimestampHeader, strconv.FormatInt(signedAt.Unix(), 10))
+		req.Header.Set(SignatureHeader, signature)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	sign := func(signedAt time.Time, requestURI string) string {
+		return SignRequest("secret", signedAt, http.MethodPost, requestURI, []byte(body))
+	}
+
+	w := send("/user/1/transaction", now, sign(now, "/user/1/transaction"))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, body, w.Body.String())
+
+	signedAt := now.Add(-30 * time.Second)
+	assert.Equal(t, http.StatusOK, send("/user/1/transaction", signedAt, sign(signedAt, "/user/1/transaction")).Code)
+
+	// Replaying the body for another user fails
+	assert.Equal(t, http.StatusUnauthorized, send("/user/2/transaction", now, sign(now, "/user/1/transaction")).Code)
+
+	signedAt = now.Add(-2 * time.Minute)
+	assert.Equal(t, http.StatusUnauthorized, send("/user/1/transaction", signedAt, sign(signedAt, "/user/1/transaction")).Code)
+
+	req := httptest.NewRequest(http.MethodPost, "/user/1/transaction", strings.NewReader(body))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestSignRequest(t *testing.T) {
+	// pkg/client computes the same signature
+	signedAt := time.Unix(1748779200, 0)
+	assert.Equal(t,
+		"sha256=a2cc2973eb286b250069b69036570543f705d5b1b6a582e29412f25ac7c16c5d",
+		SignRequest("secret", signedAt, http.MethodPost, "/user/1/transaction?x=1", []byte(`{"a":1}`)),
+	)
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"net/http"
+
+	"transaction-service/internal/adapters/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SLOHandler reports the SLOs tracked by this instance
+type SLOHandler struct {
+	tracker *metrics.SLOTracker
+}
+
+// NewSLOHandler creates a new SLO HTTP handler
+func NewSLOHandler(tracker *metrics.SLOTracker) *SLOHandler {
+	return &SLOHandler{tracker: tracker}
+}
+
+// SetupRoutes sets up the SLO routes
+func (h *SLOHandler) SetupRoutes(router gin.IRouter) {
+	router.GET(adminPathPrefix+"/slo", h.GetReport)
+}
+
+// GetReport handles GET /admin/slo
+func (h *SLOHandler) GetReport(c *gin.Context) {
+	c.JSON(http.StatusOK, h.tracker.Report())
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"transaction-service/internal/adapters/dualwrite"
+
+	"github.com/gin-gonic/gin"
+)
+
+// storageMigrationSwitchTimeout bounds how long writes are paused while the
+// shadow store catches up before a phase switch
+const storageMigrationSwitchTimeout = 10 * time.Second
+
+// StorageMigrationHandler handles the storage migration administration requests
+type StorageMigrationHandler struct {
+	migrator *dualwrite.Migrator
+}
+
+// NewStorageMigrationHandler creates a new storage migration HTTP handler
+func NewStorageMigrationHandler(migrator *dualwrite.Migrator) *StorageMigrationHandler {
+	return &StorageMigrationHandler{
+		migrator: migrator,
+	}
+}
+
+// SetupRoutes sets up the storage migration administration routes
+func (h *StorageMigrationHandler) SetupRoutes(router gin.IRouter) {
+	admin := router.Group("/admin/storage-migration")
+
+	admin.GET("", h.GetStatus)
+	admin.POST("/cutover", h.Cutover)
+	admin.POST("/rollback", h.Rollback)
+}
+
+// GetStatus handles GET /admin/storage-migration
+func (h *StorageMigrationHandler) GetStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, h.migrator.Status())
+}
+
+// Cutover handles POST /admin/storage-migration/cutover
+func (h *StorageMigrationHandler) Cutover(c *gin.Context) {
+	h.switchPhase(c, h.migrator.Cutover)
+}
+
+// Rollback handles POST /admin/storage-migration/rollback
+func (h *StorageMigrationHandler) Rollback(c *gin.Context) {
+	h.switchPhase(c, h.migrator.Rollback)
+}
+
+func (h *StorageMigrationHandler) switchPhase(c *gin.Context, switchPhase func(context.Context) (dualwrite.Status, error)) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), storageMigrationSwitchTimeout)
+	defer cancel()
+
+	status, err := switchPhase(ctx)
+	if err != nil {
+		if errors.Is(err, dualwrite.ErrShadowBacklog) {
+			problem := newProblem(c, problemShadowBacklog, "The shadow store did not catch up in time, retry when fewer writes are pending")
+			problem["migration"] = status
+			writeProblem(c, problemShadowBacklog.status, problem)
+			return
+		}
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
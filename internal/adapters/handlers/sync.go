@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"transaction-service/internal/application/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SyncHandler handles the change feed requests of downstream copies
+type SyncHandler struct {
+	syncService *services.SyncService
+}
+
+// NewSyncHandler creates a new sync HTTP handler
+func NewSyncHandler(syncService *services.SyncService) *SyncHandler {
+	return &SyncHandler{
+		syncService: syncService,
+	}
+}
+
+// SetupRoutes sets up the sync routes
+func (h *SyncHandler) SetupRoutes(router gin.IRouter) {
+	router.GET(syncPath+"/transactions", h.Transactions)
+}
+
+// Transactions handles GET /sync/transactions
+func (h *SyncHandler) Transactions(c *gin.Context) {
+	limit, err := queryInt(c, "limit")
+	if err != nil {
+		respondWithProblem(c, problemInvalidQuery, err.Error())
+		return
+	}
+
+	page, err := h.syncService.Changes(c.Request.Context(), c.Query("since"), limit)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidFilter):
+			respondWithProblem(c, problemInvalidFilter, "Invalid filter: limit must not exceed 500")
+
+		default:
+			respondWithError(c, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
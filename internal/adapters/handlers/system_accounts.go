@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http"
+
+	"transaction-service/internal/application/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SystemAccountHandler handles the system account HTTP requests
+type SystemAccountHandler struct {
+	systemAccountService *services.SystemAccountService
+}
+
+// NewSystemAccountHandler creates a new system account HTTP handler
+func NewSystemAccountHandler(systemAccountService *services.SystemAccountService) *SystemAccountHandler {
+	return &SystemAccountHandler{systemAccountService: systemAccountService}
+}
+
+// SetupRoutes sets up the system account routes
+func (h *SystemAccountHandler) SetupRoutes(router gin.IRouter) {
+	router.GET(adminPathPrefix+"/system-accounts", h.ListAccounts)
+}
+
+// ListAccounts handles GET /admin/system-accounts
+func (h *SystemAccountHandler) ListAccounts(c *gin.Context) {
+	accounts, err := h.systemAccountService.List(c.Request.Context())
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"accounts": accounts,
+	})
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/domain/entities"
+
+	"github.com/gin-gonic/gin"
+)
+
+// minorUnitsTransactionBatchRequest is the minor units form of
+// entities.TransactionBatchRequest
+type minorUnitsTransactionBatchRequest struct {
+	Transactions []minorUnitsTransactionRequest `json:"transactions" binding:"required"`
+}
+
+// TransactionBatchItem is the outcome of a transaction of a batch. Status is the
+// one the transaction would have been answered with on its own; the result of
+// a processed transaction is set, the problem details of a rejected one
+// otherwise.
+type TransactionBatchItem struct {
+	Index         int    `json:"index"`
+	TransactionID string `json:"transactionId"`
+	Status        int    `json:"status"`
+	Result        gin.H  `json:"result,omitempty"`
+	Problem       gin.H  `json:"problem,omitempty"`
+}
+
+// ProcessTransactionBatch handles POST /user/{userId}/transactions/batch,
+// processing up to 1000 transactions of the user at once. Transactions are
+// processed in order, each on its own: the response lists the outcome of each
+// of them, while the batch as a whole is only rejected when it is malformed or
+// cannot be processed at all.
+func (h *Handler) ProcessTransactionBatch(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("userId"), 10, 64)
+	if err != nil || userID == 0 {
+		respondWithProblem(c, problemInvalidUserID, "Invalid user ID")
+		return
+	}
+
+	sourceTypeHeader := c.GetHeader("Source-Type")
+	if sourceTypeHeader == "" {
+		respondWithProblem(c, problemSourceTypeRequired, "Source-Type header is required")
+		return
+	}
+	sourceType := entities.SourceType(sourceTypeHeader)
+	if !sourceType.IsValid() {
+		respondWithProblem(c, problemInvalidSourceType, "Invalid Source-Type header. Must be one of: game, server, payment")
+		return
+	}
+
+	// Entries that cannot be converted are rejected on their own, like the
+	// ones the service rejects
+	currency, minorUnits := minorUnitsCurrency(c)
+	var reqs []entities.TransactionRequest
+	var invalid []error
+	if minorUnits {
+		var batch minorUnitsTransactionBatchRequest
+		err = c.ShouldBindJSON(&batch)
+		reqs = make([]entities.TransactionRequest, len(batch.Transactions))
+		invalid = make([]error, len(batch.Transactions))
+		for i, item := range batch.Transactions {
+			reqs[i], invalid[i] = item.transactionRequest()
+			reqs[i].TransactionID = item.TransactionID
+		}
+	} else {
+		var batch entities.TransactionBatchRequest
+		err = c.ShouldBindJSON(&batch)
+		reqs = batch.Transactions
+		invalid = make([]error, len(batch.Transactions))
+	}
+	if err != nil {
+		respondWithProblem(c, problemInvalidRequestBody, "Invalid request body: "+err.Error())
+		return
+	}
+	if len(reqs) == 0 || len(reqs) > services.MaxBatchSize {
+		respondWithError(c, services.ErrInvalidBatch)
+		return
+	}
+
+	// Only the valid entries are submitted, keeping track of their index
+	submitted := make([]entities.TransactionRequest, 0, len(reqs))
+	indexes := make([]int, 0, len(reqs))
+	for i, req := range reqs {
+		if invalid[i] == nil && (req.State == "" || req.Amount == "" || req.TransactionID == "") {
+			invalid[i] = errMissingTransactionFields
+		}
+		if invalid[i] == nil {
+			submitted = append(submitted, req)
+			indexes = append(indexes, i)
+		}
+	}
+
+	items := make([]TransactionBatchItem, len(reqs))
+	for i, req := range reqs {
+		items[i] = TransactionBatchItem{Index: i, TransactionID: req.TransactionID}
+		if invalid[i] != nil {
+			items[i].setProblem(c, invalid[i])
+		}
+	}
+
+	recorded := false
+	if len(submitted) > 0 {
+		outcomes, err := h.service(c).ProcessTransactionBatch(c.Request.Context(), userID, submitted, sourceType)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		for j, outcome := range outcomes {
+			item := &items[indexes[j]]
+			if outcome.Err != nil {
+				item.setProblem(c, outcome.Err)
+				continue
+			}
+			if item.Result, err = transactionResultResponse(outcome.Result, currency, minorUnits); err != nil {
+				item.setProblem(c, err)
+				continue
+			}
+			item.Status = http.StatusOK
+			recorded = recorded || !outcome.Result.Replayed
+		}
+	}
+
+	// As for single transactions, quota warnings are only sent when the batch
+	// recorded a transaction
+	if recorded && !isSandbox(c) {
+		h.setQuotaHeaders(c, userID)
+	}
+
+	processed := 0
+	for _, item := range items {
+		if item.Status == http.StatusOK {
+			processed++
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"results":   items,
+		"processed": processed,
+		"failed":    len(items) - processed,
+	})
+}
+
+// errMissingTransactionFields is reported for batch entries lacking a
+// required field
+var errMissingTransactionFields = errors.New("all fields (state, amount, transactionId) are required")
+
+// setProblem records err as the outcome of the item
+func (item *TransactionBatchItem) setProblem(c *gin.Context, err error) {
+	problem, detail := errorProblem(err)
+	switch {
+	case errors.Is(err, errMissingTransactionFields):
+		problem, detail = problemInvalidRequestBody, "All fields (state, amount, transactionId) are required"
+	case errors.Is(err, errUnsupportedCurrency):
+		problem, detail = problemUnsupportedCurrency, "Unsupported currency"
+	}
+	item.Status = problem.status
+	item.Problem = newProblem(c, problem, detail)
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"transaction-service/internal/adapters/memory"
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/domain/entities"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessTransactionBatch(t *testing.T) {
+	newRouter := func(middleware ...gin.HandlerFunc) *gin.Engine {
+		store := memory.NewStore()
+		memory.SeedUsers(store, []*entities.User{{ID: 1, Balance: decimal.NewFromInt(10)}})
+		service := services.NewTransactionService(
+			memory.NewUnitOfWork(store), memory.NewUserRepository(store), memory.NewTransactionRepository(store),
+			services.WithCurrencies(memory.NewWalletRepository(store), eur.Code),
+		)
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.Use(middleware...)
+		NewHandler(service).SetupRoutes(router)
+		return router
+	}
+	submit := func(router *gin.Engine, target, body string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Source-Type", "game")
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	type batchResponse struct {
+		Results []struct {
+			Index         int            `json:"index"`
+			TransactionID string         `json:"transactionId"`
+			Status        int            `json:"status"`
+			Result        map[string]any `json:"result"`
+			Problem       map[string]any `json:"problem"`
+		} `json:"results"`
+		Processed int `json:"processed"`
+		Failed    int `json:"failed"`
+	}
+
+	t.Run("reports the outcome of every transaction", func(t *testing.T) {
+		router := newRouter()
+		w := submit(router, "/user/1/transactions/batch", `{"transactions": [
+			{"state": "win", "amount": "5", "transactionId": "tx-1"},
+			{"state": "lose", "amount": "100", "transactionId": "tx-2"},
+			{"state": "lose", "transactionId": "tx-3"},
+			{"state": "lose", "amount": "15", "transactionId": "tx-4"}
+		]}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var response batchResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 2, response.Processed)
+		assert.Equal(t, 2, response.Failed)
+		require.Len(t, response.Results, 4)
+
+		assert.Equal(t, http.StatusOK, response.Results[0].Status)
+		assert.Equal(t, "15.00", response.Results[0].Result["balance"])
+		assert.Nil(t, response.Results[0].Problem)
+
+		assert.Equal(t, 1, response.Results[1].Index)
+		assert.Equal(t, "tx-2", response.Results[1].TransactionID)
+		assert.Equal(t, http.StatusBadRequest, response.Results[1].Status)
+		assert.Equal(t, "insufficient_funds", response.Results[1].Problem["code"])
+		assert.Nil(t, response.Results[1].Result)
+
+		assert.Equal(t, http.StatusBadRequest, response.Results[2].Status)
+		assert.Equal(t, "invalid_request_body", response.Results[2].Problem["code"])
+
+		assert.Equal(t, http.StatusOK, response.Results[3].Status)
+		assert.Equal(t, "0.00", response.Results[3].Result["balance"])
+
+		// Resubmitting replays the processed transactions
+		w = submit(router, "/user/1/transactions/batch", `{"transactions": [
+			{"state": "win", "amount": "5", "transactionId": "tx-1"},
+			{"state": "win", "amount": "6", "transactionId": "tx-4"}
+		]}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, true, response.Results[0].Result["replayed"])
+		assert.Equal(t, http.StatusConflict, response.Results[1].Status)
+		assert.Equal(t, "duplicate_transaction", response.Results[1].Problem["code"])
+	})
+
+	t.Run("amounts are in minor units for the callers using them", func(t *testing.T) {
+		router := newRouter(MinorUnits([]string{"cents-key"}, eur))
+		w := submit(router, "/user/1/transactions/batch", `{"transactions": [
+			{"state": "win", "amount": 250, "currency": "EUR", "transactionId": "tx-1"},
+			{"state": "win", "amount": 250, "currency": "XXX", "transactionId": "tx-2"}
+		]}`, APIKeyHeader, "cents-key")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var response batchResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, float64(1250), response.Results[0].Result["balance"])
+		assert.Equal(t, "tx-2", response.Results[1].TransactionID)
+		assert.Equal(t, "unsupported_currency", response.Results[1].Problem["code"])
+	})
+
+	t.Run("batches that cannot be processed are rejected", func(t *testing.T) {
+		entries := make([]string, services.MaxBatchSize+1)
+		for i := range entries {
+			entries[i] = fmt.Sprintf(`{"state": "win", "amount": "1", "transactionId": "tx-%d"}`, i)
+		}
+		one := `{"transactions": [{"state": "win", "amount": "1", "transactionId": "tx-1"}]}`
+
+		tests := []struct {
+			name       string
+			target     string
+			body       string
+			sourceType string
+			wantStatus int
+			wantCode   string
+		}{
+			{"invalid user", "/user/0/transactions/batch", one, "game", http.StatusBadRequest, "invalid_user_id"},
+			{"invalid source type", "/user/1/transactions/batch", one, "casino", http.StatusBadRequest, "invalid_source_type"},
+			{"malformed body", "/user/1/transactions/batch", `{"transactions": {}}`, "game", http.StatusBadRequest, "invalid_request_body"},
+			{"empty batch", "/user/1/transactions/batch", `{"transactions": []}`, "game", http.StatusBadRequest, "invalid_batch"},
+			{"oversized batch", "/user/1/transactions/batch", `{"transactions": [` + strings.Join(entries, ",") + `]}`, "game", http.StatusBadRequest, "invalid_batch"},
+			{"unknown user", "/user/99/transactions/batch", one, "game", http.StatusNotFound, "user_not_found"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				w := submit(newRouter(), tt.target, tt.body, "Source-Type", tt.sourceType)
+				assert.Equal(t, tt.wantStatus, w.Code)
+				assert.Equal(t, ProblemContentType, w.Header().Get("Content-Type"))
+				assert.Contains(t, w.Body.String(), `"code":"`+tt.wantCode+`"`)
+			})
+		}
+	})
+}
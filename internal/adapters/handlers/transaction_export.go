@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/export"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Trailers of a transaction export. A complete export reports its row count,
+// including the header, and SHA-256 checksum, as an export manifest would; a
+// failed one reports the error instead.
+const (
+	exportRowsTrailer   = "X-Export-Rows"
+	exportSHA256Trailer = "X-Export-SHA256"
+	exportErrorTrailer  = "X-Export-Error"
+)
+
+// ExportUserTransactions handles GET /user/{userId}/transactions/export,
+// streaming the user's history matching the sourceType, state, from, to and
+// cancelled query parameters as CSV, newest first. The rows are written
+// through the export package, which counts and hashes them for the
+// X-Export-Rows and X-Export-SHA256 trailers. Failures after the first row
+// are reported in the X-Export-Error trailer.
+func (h *Handler) ExportUserTransactions(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("userId"), 10, 64)
+	if err != nil || userID == 0 {
+		respondWithProblem(c, problemInvalidUserID, "Invalid user ID. Must be a positive integer.")
+		return
+	}
+
+	if format := c.DefaultQuery("format", "csv"); format != "csv" {
+		respondWithProblem(c, problemInvalidQuery, "format must be csv")
+		return
+	}
+	filter, err := parseHistoryFilter(c)
+	if err != nil {
+		respondWithProblem(c, problemInvalidQuery, err.Error())
+		return
+	}
+
+	// Nothing is written before the first row, so that errors up to it are
+	// still reported as problems
+	fileName := fmt.Sprintf("user-%d-transactions.csv", userID)
+	writer := export.NewTransactionCSV(c.Writer)
+	started := false
+	start := func() error {
+		started = true
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, fileName))
+		c.Header("Trailer", strings.Join([]string{exportRowsTrailer, exportSHA256Trailer, exportErrorTrailer}, ", "))
+		c.Status(http.StatusOK)
+		return writer.WriteHeader()
+	}
+
+	err = h.service(c).ExportUserTransactions(c.Request.Context(), userID, filter, func(transaction *entities.Transaction) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		return writer.Write(transaction)
+	})
+	if err == nil && !started {
+		err = start()
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		entry := writer.Entry(fileName)
+		c.Writer.Header().Set(exportRowsTrailer, strconv.FormatInt(entry.Rows, 10))
+		c.Writer.Header().Set(exportSHA256Trailer, entry.SHA256)
+		return
+	}
+
+	if !started {
+		if errors.Is(err, services.ErrInvalidFilter) {
+			respondWithProblem(c, problemInvalidFilter, "Invalid filter: from must not be after to")
+			return
+		}
+		respondWithError(c, err)
+		return
+	}
+	_ = c.Error(err)
+	_ = writer.Flush()
+	c.Writer.Header().Set(exportErrorTrailer, err.Error())
+}
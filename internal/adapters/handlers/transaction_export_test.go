@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"transaction-service/internal/adapters/memory"
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/export"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportUserTransactions(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	memory.SeedUsers(store, []*entities.User{
+		{ID: 1, Balance: decimal.NewFromInt(100)},
+		{ID: 2, Balance: decimal.NewFromInt(100)},
+	})
+	service := services.NewTransactionService(
+		memory.NewUnitOfWork(store), memory.NewUserRepository(store), memory.NewTransactionRepository(store),
+	)
+	for _, req := range []entities.TransactionRequest{
+		{State: "win", Amount: "10", TransactionID: "tx-1"},
+		{State: "lose", Amount: "2.5", TransactionID: "=HYPERLINK(\"x\"),1"},
+	} {
+		_, err := service.ProcessTransaction(ctx, 1, req, entities.SourceTypeGame)
+		require.NoError(t, err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	NewHandler(service).SetupRoutes(router)
+
+	get := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+
+	t.Run("streams the history newest first", func(t *testing.T) {
+		w := get("/user/1/transactions/export?format=csv")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), "user-1-transactions.csv")
+		// The trailers check the file as its manifest entry would
+		sum := sha256.Sum256(w.Body.Bytes())
+		assert.Equal(t, "3", w.Result().Trailer.Get("X-Export-Rows"))
+		assert.Equal(t, hex.EncodeToString(sum[:]), w.Result().Trailer.Get("X-Export-SHA256"))
+
+		records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 3)
+		assert.Equal(t, export.TransactionColumns, records[0])
+
+		row := func(record []string, column string) string {
+			for i, name := range export.TransactionColumns {
+				if name == column {
+					return record[i]
+				}
+			}
+			t.Fatalf("unknown column %s", column)
+			return ""
+		}
+		// Text starting like a formula is quoted, commas and quotes escaped
+		assert.Equal(t, "'=HYPERLINK(\"x\"),1", row(records[1], "transactionId"))
+		assert.Equal(t, "lose", row(records[1], "state"))
+		assert.Equal(t, "2.50", row(records[1], "amount"))
+		assert.Equal(t, "107.50", row(records[1], "balanceAfter"))
+		assert.Equal(t, "tx-1", row(records[2], "transactionId"))
+		assert.Equal(t, "transaction", row(records[2], "type"))
+		assert.Equal(t, "false", row(records[2], "cancelled"))
+	})
+
+	t.Run("filters the history", func(t *testing.T) {
+		w := get("/user/1/transactions/export?state=win")
+		require.Equal(t, http.StatusOK, w.Code)
+		records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+		assert.Equal(t, "tx-1", records[1][2])
+	})
+
+	t.Run("empty histories export the header", func(t *testing.T) {
+		w := get("/user/2/transactions/export")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, strings.Join(export.TransactionColumns, ",")+"\n", w.Body.String())
+	})
+
+	t.Run("failures before the first row are problems", func(t *testing.T) {
+		tests := []struct {
+			target     string
+			wantStatus int
+		}{
+			{"/user/0/transactions/export", http.StatusBadRequest},
+			{"/user/1/transactions/export?format=xlsx", http.StatusBadRequest},
+			{"/user/1/transactions/export?state=draw", http.StatusBadRequest},
+			{"/user/99/transactions/export", http.StatusNotFound},
+		}
+		for _, tt := range tests {
+			w := get(tt.target)
+			assert.Equal(t, tt.wantStatus, w.Code, tt.target)
+			assert.Equal(t, ProblemContentType, w.Header().Get("Content-Type"), tt.target)
+		}
+	})
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/domain/entities"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserHandler handles user account HTTP requests
+type UserHandler struct {
+	accountService *services.AccountService
+	// sandboxAccountService is optional; when set, it serves sandbox traffic
+	sandboxAccountService *services.AccountService
+}
+
+// NewUserHandler creates a new user HTTP handler
+func NewUserHandler(accountService, sandboxAccountService *services.AccountService) *UserHandler {
+	return &UserHandler{
+		accountService:        accountService,
+		sandboxAccountService: sandboxAccountService,
+	}
+}
+
+// SetupRoutes sets up the user account routes
+func (h *UserHandler) SetupRoutes(router gin.IRouter) {
+	router.POST("/user", h.CreateUser)
+	router.GET("/user/:userId", h.GetUser)
+	router.GET("/users", h.ListUsers)
+	router.POST("/user/:userId/freeze", h.setStatus(entities.UserStatusFrozen))
+	router.POST("/user/:userId/unfreeze", h.setStatus(entities.UserStatusActive))
+}
+
+// service returns the account service serving the request
+func (h *UserHandler) service(c *gin.Context) *services.AccountService {
+	if h.sandboxAccountService != nil && isSandbox(c) {
+		return h.sandboxAccountService
+	}
+	return h.accountService
+}
+
+// CreateUser handles POST /user
+func (h *UserHandler) CreateUser(c *gin.Context) {
+	// The body is optional; users start with a zero balance without it
+	var req entities.CreateUserRequest
+	var err error
+	currency, minorUnits := minorUnitsCurrency(c)
+	if c.Request.ContentLength != 0 {
+		if minorUnits {
+			req, err = bindMinorUnitsCreateUserRequest(c, currency)
+		} else {
+			err = c.ShouldBindJSON(&req)
+		}
+	}
+	if err != nil {
+		if errors.Is(err, errUnsupportedCurrency) {
+			respondWithProblem(c, problemUnsupportedCurrency, "Unsupported currency. Must be "+currency.Code)
+			return
+		}
+		respondWithProblem(c, problemInvalidRequestBody, "Invalid request body: "+err.Error())
+		return
+	}
+
+	user, err := h.service(c).CreateUser(c.Request.Context(), req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidAmount) {
+			respondWithProblem(c, problemInvalidAmount, "Invalid balance. Must be a non-negative amount with at most two decimal places")
+			return
+		}
+		respondWithError(c, err)
+		return
+	}
+
+	c.Header("Location", versionPath(c)+"/user/"+strconv.FormatUint(user.ID, 10))
+	h.respondWithUser(c, http.StatusCreated, user)
+}
+
+// GetUser handles GET /user/{userId}
+func (h *UserHandler) GetUser(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("userId"), 10, 64)
+	if err != nil || userID == 0 {
+		respondWithProblem(c, problemInvalidUserID, "Invalid user ID. Must be a positive integer.")
+		return
+	}
+
+	user, err := h.service(c).GetUser(c.Request.Context(), userID)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	h.respondWithUser(c, http.StatusOK, user)
+}
+
+// ListUsers handles GET /users
+func (h *UserHandler) ListUsers(c *gin.Context) {
+	limit, err := queryInt(c, "limit")
+	if err != nil {
+		respondWithProblem(c, problemInvalidQuery, err.Error())
+		return
+	}
+	offset, err := queryInt(c, "offset")
+	if err != nil {
+		respondWithProblem(c, problemInvalidQuery, err.Error())
+		return
+	}
+
+	page, err := h.service(c).ListUsers(c.Request.Context(), limit, offset)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidFilter) {
+			respondWithProblem(c, problemInvalidFilter, "Invalid pagination: limit and offset must not be negative and limit must not exceed 500")
+			return
+		}
+		respondWithError(c, err)
+		return
+	}
+
+	if currency, ok := minorUnitsCurrency(c); ok {
+		converted, err := newMinorUnitsUserPage(currency, page)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, converted)
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// setStatus handles POST /user/{userId}/freeze and /unfreeze, responding with
+// the updated user
+func (h *UserHandler) setStatus(status entities.UserStatus) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := strconv.ParseUint(c.Param("userId"), 10, 64)
+		if err != nil || userID == 0 {
+			respondWithProblem(c, problemInvalidUserID, "Invalid user ID. Must be a positive integer.")
+			return
+		}
+
+		if err := h.service(c).SetStatus(c.Request.Context(), userID, status); err != nil {
+			respondWithError(c, err)
+			return
+		}
+		user, err := h.service(c).GetUser(c.Request.Context(), userID)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		h.respondWithUser(c, http.StatusOK, user)
+	}
+}
+
+// respondWithUser writes user, in minor units when the caller uses them
+func (h *UserHandler) respondWithUser(c *gin.Context, status int, user *entities.UserResponse) {
+	if currency, ok := minorUnitsCurrency(c); ok {
+		converted, err := newMinorUnitsUser(currency, user)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(status, converted)
+		return
+	}
+	c.JSON(status, user)
+}
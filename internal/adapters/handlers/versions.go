@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"transaction-service/internal/apiversion"
+
+	"github.com/gin-gonic/gin"
+)
+
+// legacyRoutesDeprecated is when the unversioned routes were deprecated in
+// favour of /api/v1
+var legacyRoutesDeprecated = time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC)
+
+// RouteSetup is implemented by the handlers setting up API routes, so that
+// the same routes can be served by several versions of the API
+type RouteSetup interface {
+	SetupRoutes(router gin.IRouter)
+}
+
+// APIVersion groups the routes of version of the API under their prefix,
+// e.g. /api/v1. A later version is registered side by side with the ones it
+// replaces, running middleware such as Deprecated on the old ones.
+func APIVersion(router *gin.Engine, version string, middleware ...gin.HandlerFunc) *gin.RouterGroup {
+	return router.Group(apiversion.Path(version), middleware...)
+}
+
+// LegacyRoutes groups the unversioned routes, which serve the first version
+// of the API for the clients predating /api/v1 and are deprecated. sunset,
+// if known, is when they will be removed.
+func LegacyRoutes(router *gin.Engine, sunset time.Time) *gin.RouterGroup {
+	return router.Group("", Deprecated(Deprecation{
+		Since:     legacyRoutesDeprecated,
+		Sunset:    sunset,
+		Successor: apiversion.V1,
+	}))
+}
+
+// Deprecation describes routes that are going away
+type Deprecation struct {
+	// Since is when the routes were deprecated
+	Since time.Time
+	// Sunset is when the routes will be removed, zero while unknown
+	Sunset time.Time
+	// Successor is the version serving the same routes in their place, if any
+	Successor string
+}
+
+// Deprecated announces d on every response of the routes it runs on, with
+// the Deprecation (RFC 9745) and Sunset (RFC 8594) headers, and a Link to
+// the same path in the successor version
+func Deprecated(d Deprecation) gin.HandlerFunc {
+	deprecation := "@" + strconv.FormatInt(d.Since.Unix(), 10)
+	return func(c *gin.Context) {
+		c.Header("Deprecation", deprecation)
+		if !d.Sunset.IsZero() {
+			c.Header("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+		}
+		if d.Successor != "" {
+			successor := apiversion.Path(d.Successor) + apiversion.Strip(c.Request.URL.Path)
+			c.Header("Link", "<"+successor+`>; rel="successor-version"`)
+		}
+
+		c.Next()
+	}
+}
+
+// versionPath returns the prefix of the version of the API serving the
+// request, for building links to other routes of the same version. It is
+// empty on the unversioned routes.
+func versionPath(c *gin.Context) string {
+	version, _ := apiversion.Split(c.FullPath())
+	if version == "" {
+		return ""
+	}
+	return apiversion.Path(version)
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// echoRoutes serves a route answering with the version path of the request
+type echoRoutes struct{}
+
+func (echoRoutes) SetupRoutes(router gin.IRouter) {
+	router.GET("/user/:userId/balance", func(c *gin.Context) {
+		c.Header("Location", versionPath(c)+"/user/"+c.Param("userId"))
+		c.Status(http.StatusOK)
+	})
+}
+
+func TestAPIVersions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	echoRoutes{}.SetupRoutes(APIVersion(router, "v1"))
+	echoRoutes{}.SetupRoutes(LegacyRoutes(router, time.Date(2027, time.April, 1, 0, 0, 0, 0, time.UTC)))
+
+	t.Run("versioned route", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/user/1/balance", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "/api/v1/user/1", w.Header().Get("Location"))
+		assert.Empty(t, w.Header().Get("Deprecation"))
+	})
+
+	t.Run("legacy route", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/user/1/balance", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "/user/1", w.Header().Get("Location"))
+		assert.Equal(t, "@1792108800", w.Header().Get("Deprecation"))
+		assert.Equal(t, "Thu, 01 Apr 2027 00:00:00 GMT", w.Header().Get("Sunset"))
+		assert.Equal(t, `</api/v1/user/1/balance>; rel="successor-version"`, w.Header().Get("Link"))
+	})
+}
+
+func TestIsAdminRoute_Versioned(t *testing.T) {
+	assert.True(t, isAdminRoute("/api/v1/admin/transactions"))
+	assert.True(t, isAdminRoute("/api/v1/users"))
+	assert.False(t, isAdminRoute("/api/v1/user/:userId/balance"))
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/domain/entities"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookHandler handles webhook registration HTTP requests
+type WebhookHandler struct {
+	webhookService *services.WebhookService
+}
+
+// NewWebhookHandler creates a new webhook HTTP handler
+func NewWebhookHandler(webhookService *services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{webhookService: webhookService}
+}
+
+// SetupRoutes sets up the webhook routes. Webhooks receive the events of
+// every user, so the routes require the admin scope.
+func (h *WebhookHandler) SetupRoutes(router gin.IRouter) {
+	webhooks := router.Group(webhooksPath)
+	webhooks.POST("", h.RegisterWebhook)
+	webhooks.GET("", h.ListWebhooks)
+	webhooks.GET("/:webhookId", h.GetWebhook)
+	webhooks.DELETE("/:webhookId", h.DeleteWebhook)
+	webhooks.GET("/:webhookId/deliveries", h.ListDeliveries)
+}
+
+// RegisterWebhook handles POST /webhooks
+func (h *WebhookHandler) RegisterWebhook(c *gin.Context) {
+	var req entities.WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondWithProblem(c, problemInvalidRequestBody, "Invalid request body: "+err.Error())
+		return
+	}
+
+	webhook, err := h.webhookService.Register(c.Request.Context(), req)
+	if err != nil {
+		respondWithWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, webhook)
+}
+
+// ListWebhooks handles GET /webhooks
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	webhooks, err := h.webhookService.ListWebhooks(c.Request.Context())
+	if err != nil {
+		respondWithWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"webhooks": webhooks,
+	})
+}
+
+// GetWebhook handles GET /webhooks/{webhookId}
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	webhookID, ok := webhookIDParam(c)
+	if !ok {
+		return
+	}
+
+	webhook, err := h.webhookService.GetWebhook(c.Request.Context(), webhookID)
+	if err != nil {
+		respondWithWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// DeleteWebhook handles DELETE /webhooks/{webhookId}
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	webhookID, ok := webhookIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.webhookService.DeleteWebhook(c.Request.Context(), webhookID); err != nil {
+		respondWithWebhookError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListDeliveries handles GET /webhooks/{webhookId}/deliveries
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	webhookID, ok := webhookIDParam(c)
+	if !ok {
+		return
+	}
+	limit, err := queryInt(c, "limit")
+	if err != nil {
+		respondWithProblem(c, problemInvalidQuery, err.Error())
+		return
+	}
+	offset, err := queryInt(c, "offset")
+	if err != nil {
+		respondWithProblem(c, problemInvalidQuery, err.Error())
+		return
+	}
+	status := entities.WebhookDeliveryStatus(c.Query("status"))
+
+	page, err := h.webhookService.ListDeliveries(c.Request.Context(), webhookID, status, limit, offset)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidFilter) {
+			respondWithProblem(c, problemInvalidFilter, "Invalid filter: status must be pending, succeeded or failed, limit and offset must not be negative and limit must not exceed 500")
+			return
+		}
+		respondWithWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// webhookIDParam parses the webhook ID from the path, responding with an
+// error if it is invalid
+func webhookIDParam(c *gin.Context) (uint64, bool) {
+	webhookID, err := strconv.ParseUint(c.Param("webhookId"), 10, 64)
+	if err != nil || webhookID == 0 {
+		respondWithProblem(c, problemInvalidWebhook, "Invalid webhook ID")
+		return 0, false
+	}
+	return webhookID, true
+}
+
+func respondWithWebhookError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrInvalidWebhook) {
+		respondWithProblem(c, problemInvalidWebhook, err.Error())
+		return
+	}
+	respondWithError(c, err)
+}
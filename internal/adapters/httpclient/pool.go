@@ -0,0 +1,114 @@
+// Package httpclient provides the shared outbound HTTP clients. Every
+// destination, such as the webhook receivers, gets its own connection pool,
+// timeouts and retry budget, and its requests are measured.
+package httpclient
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"transaction-service/internal/config"
+)
+
+// Metrics observes the outbound requests of a Pool
+type Metrics interface {
+	// OutboundRequest observes an attempt; status is zero when it failed
+	// without a response
+	OutboundRequest(destination string, status int, duration time.Duration)
+	// OutboundRetry counts a failed attempt that was retried, or that was
+	// not because the retry budget ran out
+	OutboundRetry(destination string, allowed bool)
+}
+
+// Pool hands out the HTTP clients of the destinations. The clients of a
+// destination share its transport, so they share its connections and retry
+// budget.
+type Pool struct {
+	defaultPolicy config.HTTPClientPolicy
+	policies      map[string]config.HTTPClientPolicy
+	metrics       Metrics
+
+	mu         sync.Mutex
+	transports map[string]*transport
+}
+
+// NewPool creates a new Pool. Destinations without a policy use
+// defaultPolicy; metrics may be nil.
+func NewPool(
+	defaultPolicy config.HTTPClientPolicy,
+	policies map[string]config.HTTPClientPolicy,
+	metrics Metrics,
+) (*Pool, error) {
+	for destination, policy := range policies {
+		if _, err := proxyFunc(policy.Proxy); err != nil {
+			return nil, fmt.Errorf("invalid proxy of %s: %w", destination, err)
+		}
+	}
+	if _, err := proxyFunc(defaultPolicy.Proxy); err != nil {
+		return nil, fmt.Errorf("invalid default proxy: %w", err)
+	}
+	if metrics == nil {
+		metrics = noopMetrics{}
+	}
+
+	return &Pool{
+		defaultPolicy: defaultPolicy,
+		policies:      policies,
+		metrics:       metrics,
+		transports:    make(map[string]*transport),
+	}, nil
+}
+
+// Client returns a client for destination. Callers may change the returned
+// client, such as its redirect policy, without affecting other clients.
+func (p *Pool) Client(destination string) *http.Client {
+	return &http.Client{Transport: p.transport(destination)}
+}
+
+// CloseIdleConnections closes the idle connections of every destination
+func (p *Pool) CloseIdleConnections() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, t := range p.transports {
+		t.base.CloseIdleConnections()
+	}
+}
+
+func (p *Pool) transport(destination string) *transport {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if t, ok := p.transports[destination]; ok {
+		return t
+	}
+	policy, ok := p.policies[destination]
+	if !ok {
+		policy = p.defaultPolicy
+	}
+	t := newTransport(destination, policy, p.metrics)
+	p.transports[destination] = t
+	return t
+}
+
+// proxyFunc returns the proxy function of a transport using proxy, or the
+// environment when proxy is empty
+func proxyFunc(proxy string) (func(*http.Request) (*url.URL, error), error) {
+	if proxy == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+	u, err := url.Parse(proxy)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		// The URL may hold credentials, so it is left out of the error
+		return nil, fmt.Errorf("proxy must be an absolute URL")
+	}
+	return http.ProxyURL(u), nil
+}
+
+type noopMetrics struct{}
+
+func (noopMetrics) OutboundRequest(string, int, time.Duration) {}
+func (noopMetrics) OutboundRetry(string, bool)                 {}
@@ -0,0 +1,194 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"transaction-service/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingMetrics struct {
+	mu       sync.Mutex
+	statuses []int
+	retries  []bool
+}
+
+func (m *recordingMetrics) OutboundRequest(destination string, status int, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.statuses = append(m.statuses, status)
+}
+
+func (m *recordingMetrics) OutboundRetry(destination string, allowed bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retries = append(m.retries, allowed)
+}
+
+var testPolicy = config.HTTPClientPolicy{
+	Timeout:             time.Second,
+	MaxIdleConnsPerHost: 2,
+	IdleConnTimeout:     time.Minute,
+	Retries:             2,
+	RetryBudget:         0.1,
+}
+
+// failingServer answers 503 to the first failures requests and 200 after,
+// recording the bodies it received
+func failingServer(t *testing.T, failures int32) (*httptest.Server, *atomic.Int32, *[]string) {
+	var attempts atomic.Int32
+	var mu sync.Mutex
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(body))
+		mu.Unlock()
+		if attempts.Add(1) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	return server, &attempts, &bodies
+}
+
+func TestPool_Retries(t *testing.T) {
+	t.Run("idempotent requests are retried", func(t *testing.T) {
+		server, attempts, _ := failingServer(t, 2)
+		metrics := &recordingMetrics{}
+		pool, err := NewPool(testPolicy, nil, metrics)
+		require.NoError(t, err)
+
+		resp, err := pool.Client("provider").Get(server.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, int32(3), attempts.Load())
+		assert.Equal(t, []int{503, 503, 200}, metrics.statuses)
+		assert.Equal(t, []bool{true, true}, metrics.retries)
+	})
+
+	t.Run("replayable posts are sent again with their body", func(t *testing.T) {
+		server, attempts, bodies := failingServer(t, 1)
+		pool, err := NewPool(testPolicy, nil, nil)
+		require.NoError(t, err)
+
+		req, err := http.NewRequestWithContext(WithReplay(context.Background()), http.MethodPost, server.URL, strings.NewReader("payload"))
+		require.NoError(t, err)
+		resp, err := pool.Client("webhooks").Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, int32(2), attempts.Load())
+		assert.Equal(t, []string{"payload", "payload"}, *bodies)
+	})
+
+	t.Run("other posts are sent once", func(t *testing.T) {
+		server, attempts, _ := failingServer(t, 1)
+		pool, err := NewPool(testPolicy, nil, nil)
+		require.NoError(t, err)
+
+		resp, err := pool.Client("provider").Post(server.URL, "text/plain", strings.NewReader("payload"))
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		assert.Equal(t, int32(1), attempts.Load())
+	})
+
+	t.Run("retries stop when the budget runs out", func(t *testing.T) {
+		server, attempts, _ := failingServer(t, 100)
+		metrics := &recordingMetrics{}
+		policy := testPolicy
+		policy.Retries = 1
+		policy.RetryBudget = 0
+		pool, err := NewPool(policy, nil, metrics)
+		require.NoError(t, err)
+
+		client := pool.Client("provider")
+		for range minRetryTokens + 5 {
+			resp, err := client.Get(server.URL)
+			require.NoError(t, err)
+			resp.Body.Close()
+		}
+
+		assert.Equal(t, int32(2*minRetryTokens+5), attempts.Load())
+		assert.Len(t, metrics.retries, minRetryTokens+5)
+		assert.False(t, metrics.retries[len(metrics.retries)-1])
+	})
+}
+
+func TestPool_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	metrics := &recordingMetrics{}
+	policy := testPolicy
+	policy.Timeout = 50 * time.Millisecond
+	policy.Retries = 1
+	pool, err := NewPool(config.HTTPClientPolicy{}, map[string]config.HTTPClientPolicy{"provider": policy}, metrics)
+	require.NoError(t, err)
+
+	_, err = pool.Client("provider").Get(server.URL)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, int32(2), attempts.Load())
+	assert.Equal(t, []int{0, 0}, metrics.statuses)
+}
+
+func TestPool_Proxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer proxy.Close()
+
+	policy := testPolicy
+	policy.Proxy = proxy.URL
+	pool, err := NewPool(testPolicy, map[string]config.HTTPClientPolicy{"provider": policy}, nil)
+	require.NoError(t, err)
+
+	resp, err := pool.Client("provider").Get("http://provider.invalid/status")
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, "http://provider.invalid/status", proxied)
+
+	policy.Proxy = "proxy:3128"
+	_, err = NewPool(testPolicy, map[string]config.HTTPClientPolicy{"provider": policy}, nil)
+	assert.ErrorContains(t, err, "invalid proxy of provider")
+}
+
+func TestPool_Client(t *testing.T) {
+	pool, err := NewPool(testPolicy, map[string]config.HTTPClientPolicy{"webhooks": testPolicy}, nil)
+	require.NoError(t, err)
+
+	// Clients of a destination share its connections and retry budget
+	assert.Same(t, pool.Client("webhooks").Transport, pool.Client("webhooks").Transport)
+	assert.NotSame(t, pool.Client("webhooks").Transport, pool.Client("provider").Transport)
+	assert.Equal(t, testPolicy, pool.Client("provider").Transport.(*transport).policy)
+}
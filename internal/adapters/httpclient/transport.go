@@ -0,0 +1,219 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"transaction-service/internal/config"
+)
+
+// retryBaseDelay is the delay before the first retry, doubled for each
+// further retry and jittered
+const retryBaseDelay = 50 * time.Millisecond
+
+// maxDrainedBody bounds how much of a discarded response body is read so
+// that the connection can be reused
+const maxDrainedBody = 64 << 10
+
+type replayKey struct{}
+
+// WithReplay marks the requests made with ctx as safe to send more than once,
+// such as deliveries their receivers deduplicate. Requests with an idempotent
+// method or an Idempotency-Key header are retried without it.
+func WithReplay(ctx context.Context) context.Context {
+	return context.WithValue(ctx, replayKey{}, true)
+}
+
+// transport sends the requests of a destination, bounding every attempt by
+// the policy timeout and retrying failed attempts of replayable requests
+type transport struct {
+	destination string
+	policy      config.HTTPClientPolicy
+	base        *http.Transport
+	budget      *retryBudget
+	metrics     Metrics
+}
+
+func newTransport(destination string, policy config.HTTPClientPolicy, metrics Metrics) *transport {
+	// The proxy was validated by NewPool
+	proxy, _ := proxyFunc(policy.Proxy)
+
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.Proxy = proxy
+	base.MaxIdleConnsPerHost = policy.MaxIdleConnsPerHost
+	base.MaxConnsPerHost = policy.MaxConnsPerHost
+	base.IdleConnTimeout = policy.IdleConnTimeout
+
+	return &transport{
+		destination: destination,
+		policy:      policy,
+		base:        base,
+		budget:      newRetryBudget(policy.RetryBudget),
+		metrics:     metrics,
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.budget.deposit()
+	replayable := isReplayable(req)
+
+	for attempt := 0; ; attempt++ {
+		resp, err := t.attempt(req)
+		if attempt >= t.policy.Retries || !replayable || !shouldRetry(req.Context(), resp, err) {
+			return resp, err
+		}
+		if !t.budget.withdraw() {
+			t.metrics.OutboundRetry(t.destination, false)
+			return resp, err
+		}
+		t.metrics.OutboundRetry(t.destination, true)
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainedBody))
+			resp.Body.Close()
+		}
+
+		if err := sleep(req.Context(), backoff(attempt)); err != nil {
+			return nil, err
+		}
+		if req, err = rewind(req); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// attempt sends req once, bounded by the policy timeout until the response
+// body is closed
+func (t *transport) attempt(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), t.policy.Timeout)
+	start := time.Now()
+
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		t.metrics.OutboundRequest(t.destination, 0, time.Since(start))
+		return nil, err
+	}
+	t.metrics.OutboundRequest(t.destination, resp.StatusCode, time.Since(start))
+
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// isReplayable reports whether req may be sent again after a failed attempt
+func isReplayable(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	if req.Header.Get("Idempotency-Key") != "" {
+		return true
+	}
+	replay, _ := req.Context().Value(replayKey{}).(bool)
+	return replay
+}
+
+// shouldRetry reports whether a failed attempt is worth retrying: the
+// request failed without a response, or a gateway in front of the
+// destination answered in its place
+func shouldRetry(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// rewind returns a copy of req with a fresh body
+func rewind(req *http.Request) (*http.Request, error) {
+	if req.GetBody == nil {
+		return req, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	next := req.Clone(req.Context())
+	next.Body = body
+	return next, nil
+}
+
+// backoff returns the jittered delay before retry attempt+1
+func backoff(attempt int) time.Duration {
+	delay := retryBaseDelay << attempt
+	return delay/2 + rand.N(delay/2+1)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// cancelOnClose releases the timeout of an attempt once its response body is
+// closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// minRetryTokens is how many retries a destination may bank, so that a few
+// retries are allowed however little traffic it gets
+const minRetryTokens = 10
+
+// retryBudget limits the retries of a destination to a ratio of its
+// requests. Every request deposits ratio tokens, up to minRetryTokens, and
+// every retry withdraws one, so retries cannot multiply the load of a
+// destination that is failing.
+type retryBudget struct {
+	mu     sync.Mutex
+	ratio  float64
+	tokens float64
+}
+
+func newRetryBudget(ratio float64) *retryBudget {
+	return &retryBudget{ratio: ratio, tokens: minRetryTokens}
+}
+
+func (b *retryBudget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens = min(b.tokens+b.ratio, minRetryTokens)
+}
+
+func (b *retryBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,133 @@
+package idgen
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"transaction-service/internal/application/services"
+
+	"github.com/google/uuid"
+)
+
+// Supported ID generation strategies
+const (
+	StrategySequence  = "sequence"
+	StrategyUUIDv7    = "uuidv7"
+	StrategySnowflake = "snowflake"
+)
+
+// New returns the generator for the named strategy. nodeID is only used by
+// the snowflake strategy.
+func New(strategy string, nodeID uint16) (services.IDGenerator, error) {
+	switch strategy {
+	case StrategySequence:
+		return Sequence{}, nil
+	case StrategyUUIDv7:
+		return UUIDv7{}, nil
+	case StrategySnowflake:
+		return NewSnowflake(nodeID)
+	default:
+		return nil, fmt.Errorf("unknown ID strategy %q", strategy)
+	}
+}
+
+// Sequence leaves both the surrogate key and the receipt to the database
+// sequence. It requires coordination through a single primary database.
+type Sequence struct{}
+
+// NextIDs implements services.IDGenerator
+func (Sequence) NextIDs() (services.TransactionIDs, error) {
+	return services.TransactionIDs{}, nil
+}
+
+// UUIDv7 keeps the database sequence for the surrogate key and issues
+// time-ordered UUIDv7 receipts that need no coordination between deployments
+type UUIDv7 struct{}
+
+// NextIDs implements services.IDGenerator
+func (UUIDv7) NextIDs() (services.TransactionIDs, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return services.TransactionIDs{}, fmt.Errorf("failed to generate UUIDv7: %w", err)
+	}
+	return services.TransactionIDs{Receipt: id.String()}, nil
+}
+
+const (
+	nodeBits     = 10
+	sequenceBits = 12
+
+	// MaxNodeID is the largest node ID a snowflake generator accepts
+	MaxNodeID   = 1<<nodeBits - 1
+	maxSequence = 1<<sequenceBits - 1
+)
+
+// snowflakeEpoch is the origin of snowflake timestamps
+var snowflakeEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// ErrClockMovedBackwards is returned when the clock is behind the last
+// issued snowflake ID
+var ErrClockMovedBackwards = errors.New("clock moved backwards")
+
+// Snowflake allocates 63-bit keys made of a millisecond timestamp, a node ID
+// and a per-millisecond sequence. Every instance must use a distinct node ID;
+// no other coordination is needed.
+type Snowflake struct {
+	mu       sync.Mutex
+	nodeID   uint64
+	lastMs   int64
+	sequence uint64
+	now      func() time.Time
+}
+
+// NewSnowflake creates a Snowflake generator for the given node
+func NewSnowflake(nodeID uint16) (*Snowflake, error) {
+	if nodeID > MaxNodeID {
+		return nil, fmt.Errorf("snowflake node ID %d exceeds %d", nodeID, MaxNodeID)
+	}
+	return &Snowflake{nodeID: uint64(nodeID), now: time.Now}, nil
+}
+
+// NextIDs implements services.IDGenerator
+func (s *Snowflake) NextIDs() (services.TransactionIDs, error) {
+	key, err := s.next()
+	if err != nil {
+		return services.TransactionIDs{}, err
+	}
+	return services.TransactionIDs{Key: key, Receipt: strconv.FormatUint(key, 10)}, nil
+}
+
+func (s *Snowflake) next() (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ms := s.now().Sub(snowflakeEpoch).Milliseconds()
+	if ms < s.lastMs {
+		return 0, fmt.Errorf("%w by %dms", ErrClockMovedBackwards, s.lastMs-ms)
+	}
+
+	if ms == s.lastMs {
+		s.sequence = (s.sequence + 1) & maxSequence
+		// Wait for the next millisecond once the sequence is exhausted
+		for s.sequence == 0 && ms <= s.lastMs {
+			time.Sleep(100 * time.Microsecond)
+			ms = s.now().Sub(snowflakeEpoch).Milliseconds()
+		}
+	} else {
+		s.sequence = 0
+	}
+	s.lastMs = ms
+
+	return uint64(ms)<<(nodeBits+sequenceBits) | s.nodeID<<sequenceBits | s.sequence, nil
+}
+
+// Decompose splits a snowflake key into its creation time, node ID and sequence
+func Decompose(key uint64) (time.Time, uint16, uint16) {
+	ms := int64(key >> (nodeBits + sequenceBits))
+	nodeID := uint16(key >> sequenceBits & MaxNodeID)
+	sequence := uint16(key & maxSequence)
+	return snowflakeEpoch.Add(time.Duration(ms) * time.Millisecond), nodeID, sequence
+}
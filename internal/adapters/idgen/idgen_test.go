@@ -0,0 +1,79 @@
+package idgen
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	for _, strategy := range []string{StrategySequence, StrategyUUIDv7, StrategySnowflake} {
+		generator, err := New(strategy, 1)
+		require.NoError(t, err, strategy)
+		assert.NotNil(t, generator)
+	}
+
+	_, err := New("random", 1)
+	assert.Error(t, err)
+	_, err = New(StrategySnowflake, MaxNodeID+1)
+	assert.Error(t, err)
+}
+
+func TestSequence_LeavesAllocationToDatabase(t *testing.T) {
+	ids, err := Sequence{}.NextIDs()
+	require.NoError(t, err)
+	assert.Zero(t, ids.Key)
+	assert.Empty(t, ids.Receipt)
+}
+
+func TestUUIDv7_IssuesReceipts(t *testing.T) {
+	ids, err := UUIDv7{}.NextIDs()
+	require.NoError(t, err)
+	assert.Zero(t, ids.Key)
+
+	parsed, err := uuid.Parse(ids.Receipt)
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Version(7), parsed.Version())
+}
+
+func TestSnowflake_UniqueAndOrdered(t *testing.T) {
+	generator, err := NewSnowflake(42)
+	require.NoError(t, err)
+
+	seen := make(map[uint64]bool)
+	var last uint64
+	for i := 0; i < 10000; i++ {
+		ids, err := generator.NextIDs()
+		require.NoError(t, err)
+		require.Greater(t, ids.Key, last)
+		require.False(t, seen[ids.Key])
+		assert.Equal(t, strconv.FormatUint(ids.Key, 10), ids.Receipt)
+		seen[ids.Key] = true
+		last = ids.Key
+	}
+
+	_, nodeID, _ := Decompose(last)
+	assert.Equal(t, uint16(42), nodeID)
+}
+
+func TestSnowflake_ClockMovedBackwards(t *testing.T) {
+	generator, err := NewSnowflake(1)
+	require.NoError(t, err)
+
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	generator.now = func() time.Time { return now }
+	ids, err := generator.NextIDs()
+	require.NoError(t, err)
+
+	createdAt, _, sequence := Decompose(ids.Key)
+	assert.Equal(t, now, createdAt)
+	assert.Zero(t, sequence)
+
+	now = now.Add(-time.Second)
+	_, err = generator.NextIDs()
+	assert.ErrorIs(t, err, ErrClockMovedBackwards)
+}
@@ -0,0 +1,121 @@
+// Package ingest holds what the adapters ingesting transactions from message
+// brokers share: the transaction event they consume and how the errors of
+// applying it are told apart.
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/domain/entities"
+)
+
+// TransactionProcessor applies transactions, e.g. the TransactionService
+type TransactionProcessor interface {
+	ProcessTransaction(
+		ctx context.Context,
+		userID uint64,
+		req entities.TransactionRequest,
+		sourceType entities.SourceType,
+	) (*entities.TransactionResult, error)
+}
+
+// ErrInvalidEvent is returned for messages that are not transaction events
+var ErrInvalidEvent = errors.New("invalid transaction event")
+
+// TransactionEvent is the payload of a transaction message
+type TransactionEvent struct {
+	UserID        uint64     `json:"userId"`
+	State         string     `json:"state"`
+	Amount        string     `json:"amount"`
+	TransactionID string     `json:"transactionId"`
+	Currency      string     `json:"currency,omitempty"`
+	OccurredAt    *time.Time `json:"occurredAt,omitempty"`
+	RoundID       string     `json:"roundId,omitempty"`
+	// Metadata is attached to the transaction as it is
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// DecodeEvent decodes and validates the payload of a message
+func DecodeEvent(value []byte) (*TransactionEvent, error) {
+	var event TransactionEvent
+	if err := json.Unmarshal(value, &event); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+	}
+	if event.UserID == 0 || event.State == "" || event.Amount == "" || event.TransactionID == "" {
+		return nil, fmt.Errorf("%w: userId, state, amount and transactionId are required", ErrInvalidEvent)
+	}
+	return &event, nil
+}
+
+// Request returns the transaction request of the event
+func (e *TransactionEvent) Request() entities.TransactionRequest {
+	return entities.TransactionRequest{
+		State:         e.State,
+		Amount:        e.Amount,
+		TransactionID: e.TransactionID,
+		Currency:      e.Currency,
+		OccurredAt:    e.OccurredAt,
+		RoundID:       e.RoundID,
+		Metadata:      e.Metadata,
+	}
+}
+
+// rejections are the errors of transactions that can never be applied
+var rejections = []error{
+	services.ErrUserNotFound,
+	services.ErrInsufficientFunds,
+	services.ErrDuplicateTransaction,
+	services.ErrInvalidAmount,
+	services.ErrAmountBelowMinimum,
+	services.ErrAmountAboveMaximum,
+	services.ErrAmountPrecision,
+	services.ErrInvalidTransactionState,
+	services.ErrInvalidSourceType,
+	services.ErrInvalidOccurredAt,
+	services.ErrAccountFrozen,
+	services.ErrBalanceChangeLimitExceeded,
+	services.ErrSourceTypeNotAllowed,
+	services.ErrLossLimitExceeded,
+	services.ErrVelocityLimitExceeded,
+	services.ErrInvalidCurrency,
+	services.ErrUnsupportedCurrency,
+	services.ErrInvalidRoundID,
+	services.ErrInvalidMetadata,
+	services.ErrSystemAccount,
+}
+
+// IsRejection reports whether err means the transaction can never be applied,
+// so its message must be dead-lettered rather than retried
+func IsRejection(err error) bool {
+	for _, rejection := range rejections {
+		if errors.Is(err, rejection) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTransient reports whether err is expected to pass without intervention
+func IsTransient(err error) bool {
+	return errors.Is(err, services.ErrUnavailable) || errors.Is(err, services.ErrRegionStandby)
+}
+
+// Backoff returns the delay before the given retry, doubling from baseDelay
+// up to maxDelay
+func Backoff(attempt int, baseDelay, maxDelay time.Duration) time.Duration {
+	delay := baseDelay << (attempt - 1)
+	if delay <= 0 || delay > maxDelay {
+		delay = maxDelay
+	}
+	// Full jitter spreads out the retries of consumers that failed together
+	if delay > 0 {
+		delay = time.Duration(rand.Int64N(int64(delay))) + 1
+	}
+	return delay
+}
@@ -0,0 +1,41 @@
+package ingest
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"transaction-service/internal/application/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeEvent(t *testing.T) {
+	event, err := DecodeEvent([]byte(`{"userId":1,"state":"win","amount":"5.00","transactionId":"tx-1","roundId":"r-1"}`))
+	require.NoError(t, err)
+	req := event.Request()
+	assert.Equal(t, "tx-1", req.TransactionID)
+	assert.Equal(t, "r-1", req.RoundID)
+
+	_, err = DecodeEvent([]byte("{"))
+	assert.ErrorIs(t, err, ErrInvalidEvent)
+	_, err = DecodeEvent([]byte(`{"userId":1}`))
+	assert.ErrorIs(t, err, ErrInvalidEvent)
+}
+
+func TestErrorClasses(t *testing.T) {
+	assert.True(t, IsRejection(fmt.Errorf("failed: %w", services.ErrInsufficientFunds)))
+	assert.False(t, IsRejection(services.ErrUnavailable))
+	assert.True(t, IsTransient(fmt.Errorf("failed to get user: %w", services.ErrUnavailable)))
+	assert.False(t, IsTransient(services.ErrUserNotFound))
+}
+
+func TestBackoff(t *testing.T) {
+	for attempt := 1; attempt < 100; attempt++ {
+		delay := Backoff(attempt, time.Millisecond, time.Second)
+		assert.Positive(t, delay)
+		assert.LessOrEqual(t, delay, time.Second)
+	}
+	assert.Zero(t, Backoff(1, 0, 0))
+}
@@ -0,0 +1,107 @@
+package kafka
+
+import (
+	"context"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// ClientReader binds a kafka-go Reader consuming a topic as a member of a
+// consumer group to Reader
+type ClientReader struct {
+	reader *kafkago.Reader
+}
+
+// NewClientReader creates a new ClientReader. Offsets are committed
+// synchronously, so a committed message is never redelivered.
+func NewClientReader(brokers []string, topic, groupID string) *ClientReader {
+	return &ClientReader{reader: kafkago.NewReader(kafkago.ReaderConfig{
+		Brokers: brokers,
+		Topic:   topic,
+		GroupID: groupID,
+	})}
+}
+
+// FetchMessage implements Reader
+func (r *ClientReader) FetchMessage(ctx context.Context) (Message, error) {
+	msg, err := r.reader.FetchMessage(ctx)
+	if err != nil {
+		return Message{}, err
+	}
+	return fromClientMessage(msg), nil
+}
+
+// CommitMessages implements Reader
+func (r *ClientReader) CommitMessages(ctx context.Context, msgs ...Message) error {
+	return r.reader.CommitMessages(ctx, toClientMessages(msgs)...)
+}
+
+// Close leaves the consumer group and closes the connections
+func (r *ClientReader) Close() error {
+	return r.reader.Close()
+}
+
+// ClientWriter binds a kafka-go Writer to Writer. Messages are partitioned by
+// key, and written once every in-sync replica acknowledged them. A write is
+// sent right away rather than waiting to fill a larger batch.
+type ClientWriter struct {
+	writer *kafkago.Writer
+}
+
+// NewClientWriter creates a new ClientWriter writing each message to its own
+// topic
+func NewClientWriter(brokers []string) *ClientWriter {
+	return &ClientWriter{writer: &kafkago.Writer{
+		Addr:         kafkago.TCP(brokers...),
+		Balancer:     &kafkago.Hash{},
+		RequiredAcks: kafkago.RequireAll,
+		BatchTimeout: time.Millisecond,
+	}}
+}
+
+// WriteMessages implements Writer
+func (w *ClientWriter) WriteMessages(ctx context.Context, msgs ...Message) error {
+	return w.writer.WriteMessages(ctx, toClientMessages(msgs)...)
+}
+
+// Close flushes the pending messages and closes the connections
+func (w *ClientWriter) Close() error {
+	return w.writer.Close()
+}
+
+func fromClientMessage(msg kafkago.Message) Message {
+	headers := make([]Header, len(msg.Headers))
+	for i, header := range msg.Headers {
+		headers[i] = Header{Key: header.Key, Value: header.Value}
+	}
+	return Message{
+		Topic:     msg.Topic,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Key:       msg.Key,
+		Value:     msg.Value,
+		Headers:   headers,
+		Time:      msg.Time,
+	}
+}
+
+func toClientMessages(msgs []Message) []kafkago.Message {
+	converted := make([]kafkago.Message, len(msgs))
+	for i, msg := range msgs {
+		headers := make([]kafkago.Header, len(msg.Headers))
+		for j, header := range msg.Headers {
+			headers[j] = kafkago.Header{Key: header.Key, Value: header.Value}
+		}
+		converted[i] = kafkago.Message{
+			Topic:     msg.Topic,
+			Partition: msg.Partition,
+			Offset:    msg.Offset,
+			Key:       msg.Key,
+			Value:     msg.Value,
+			Headers:   headers,
+			Time:      msg.Time,
+		}
+	}
+	return converted
+}
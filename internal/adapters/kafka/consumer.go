@@ -0,0 +1,371 @@
+// Package kafka ingests the transactions game servers publish to Kafka and
+// publishes the balance change events of the outbox.
+//
+// The adapters depend on the Reader and Writer ports instead of a client
+// library. They are modelled on kafka-go's Reader and Writer, which
+// ClientReader and ClientWriter bind to them by converting their messages.
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"transaction-service/internal/adapters/ingest"
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/health"
+	"transaction-service/internal/processor"
+
+	"github.com/rs/zerolog"
+)
+
+// Header is a Kafka record header
+type Header struct {
+	Key   string
+	Value []byte
+}
+
+// Message is a Kafka record
+type Message struct {
+	Topic     string
+	Partition int
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Headers   []Header
+	Time      time.Time
+}
+
+// Reader fetches the messages of a consumer group. Offsets advance only when
+// messages are committed.
+type Reader interface {
+	FetchMessage(ctx context.Context) (Message, error)
+	CommitMessages(ctx context.Context, msgs ...Message) error
+}
+
+// Writer produces messages
+type Writer interface {
+	WriteMessages(ctx context.Context, msgs ...Message) error
+}
+
+// DLQ headers describe why and from where a message was dead-lettered
+const (
+	HeaderError     = "dlq-error"
+	HeaderTopic     = "dlq-topic"
+	HeaderPartition = "dlq-partition"
+	HeaderOffset    = "dlq-offset"
+)
+
+// RetryPolicy bounds the retries of messages that fail to process
+type RetryPolicy struct {
+	// MaxAttempts bounds the attempts of messages failing with unexpected
+	// errors before they are dead-lettered. Messages failing because the
+	// database is unavailable are retried until it recovers.
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// Consumer processes transaction messages in order. A message's offset is
+// committed only once its transaction was applied, or once the message was
+// written to the dead letter topic because it can never be applied. Until
+// then the message is retried, holding back the rest of its partition.
+// Redelivered messages are safe, since transactions are idempotent by
+// transaction ID.
+//
+// With a processor pool, the messages of different users are processed
+// concurrently while those of a user keep their order, and a message still
+// being retried only holds back the messages of its worker. Offsets are still
+// committed in order.
+type Consumer struct {
+	reader    Reader
+	dlq       Writer
+	dlqTopic  string
+	processor ingest.TransactionProcessor
+	// sourceType is the source type every message of the topic is applied as
+	sourceType entities.SourceType
+	policy     RetryPolicy
+	// gate pauses the consumer while the region does not accept writes; may be nil
+	gate   services.RegionGate
+	logger zerolog.Logger
+	// pool processes the messages when set, with offsets tracking the
+	// messages in flight
+	pool    *processor.Pool
+	offsets *offsetTracker
+	// heartbeat beats while the consumer makes progress or waits for
+	// messages, at least every idle interval; may be nil
+	heartbeat *health.Heartbeat
+	idle      time.Duration
+
+	mu sync.Mutex
+	// fetchErr is the error of the last attempt to fetch a message, nil once
+	// a fetch succeeded
+	fetchErr error
+}
+
+// ConsumerOption configures a Consumer
+type ConsumerOption func(*Consumer)
+
+// WithPool processes the messages on pool, which must be running for them to
+// be processed
+func WithPool(pool *processor.Pool) ConsumerOption {
+	return func(c *Consumer) {
+		c.pool = pool
+		c.offsets = newOffsetTracker()
+	}
+}
+
+// WithHeartbeat beats heartbeat whenever the consumer handled a message,
+// retried one, or waited idle for messages or for room in the pool, so that
+// a consumer whose loop is stuck fails the liveness probe
+func WithHeartbeat(heartbeat *health.Heartbeat, idle time.Duration) ConsumerOption {
+	return func(c *Consumer) {
+		c.heartbeat = heartbeat
+		c.idle = idle
+	}
+}
+
+// NewConsumer creates a new Consumer
+func NewConsumer(
+	reader Reader,
+	dlq Writer,
+	dlqTopic string,
+	processor ingest.TransactionProcessor,
+	sourceType entities.SourceType,
+	policy RetryPolicy,
+	gate services.RegionGate,
+	logger zerolog.Logger,
+	opts ...ConsumerOption,
+) *Consumer {
+	c := &Consumer{
+		reader:     reader,
+		dlq:        dlq,
+		dlqTopic:   dlqTopic,
+		processor:  processor,
+		sourceType: sourceType,
+		policy:     policy,
+		gate:       gate,
+		logger:     logger.With().Str("consumer", "transactions").Logger(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Run consumes messages until the context is cancelled
+func (c *Consumer) Run(ctx context.Context) {
+	c.logger.Info().Str("source_type", string(c.sourceType)).Msg("consumer started")
+
+	for {
+		c.heartbeat.Beat()
+		if ctx.Err() != nil {
+			c.logger.Info().Msg("consumer stopped")
+			return
+		}
+
+		// Only the active region writes
+		if c.gate != nil && !c.gate.AcceptsWrites() {
+			_ = sleep(ctx, c.policy.MaxDelay)
+			continue
+		}
+
+		// A fetch gives up once idle, so that the consumer beats while no
+		// messages arrive
+		fetchCtx, cancelFetch := c.idleContext(ctx)
+		msg, err := c.reader.FetchMessage(fetchCtx)
+		idle := fetchCtx.Err() != nil
+		cancelFetch()
+		if err != nil {
+			if !idle {
+				c.logger.Error().Err(err).Msg("failed to fetch message")
+				c.setFetchErr(err)
+				_ = sleep(ctx, c.policy.BaseDelay)
+			}
+			continue
+		}
+		c.setFetchErr(nil)
+
+		if c.pool == nil {
+			if c.handle(ctx, msg) {
+				c.commit(ctx, msg)
+			}
+			continue
+		}
+		c.submit(ctx, msg)
+	}
+}
+
+// submit queues msg on the pool, keyed by the user of its event. Messages
+// that are not transaction events are dead-lettered by the worker of user 0.
+func (c *Consumer) submit(ctx context.Context, msg Message) {
+	var userID uint64
+	if event, err := ingest.DecodeEvent(msg.Value); err == nil {
+		userID = event.UserID
+	}
+
+	tracked := c.offsets.track(msg)
+	task := processor.Task{
+		UserID: userID,
+		Run: func(ctx context.Context) {
+			// Messages still queued on shutdown are left uncommitted
+			if ctx.Err() != nil || !c.handle(ctx, msg) {
+				return
+			}
+			if last, ok := c.offsets.done(tracked); ok {
+				c.commit(ctx, last)
+			}
+		},
+	}
+	var err error
+	for {
+		submitCtx, cancelSubmit := c.idleContext(ctx)
+		err = c.pool.Submit(submitCtx, task)
+		idle := submitCtx.Err() != nil && ctx.Err() == nil
+		cancelSubmit()
+		if err == nil || !idle {
+			break
+		}
+		c.heartbeat.Beat()
+	}
+	// The message is redelivered, like the ones in flight, when the consumer
+	// or the pool is shutting down
+	if err != nil && ctx.Err() == nil {
+		c.logger.Warn().Err(err).Msg("failed to queue message")
+	}
+}
+
+// idleContext bounds a wait of the consumer by its idle interval, after
+// which it beats and waits again
+func (c *Consumer) idleContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.idle <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.idle)
+}
+
+// Check fails while the last attempt to fetch a message failed, e.g. because
+// the brokers are unreachable, so the consumer can be registered as a
+// readiness check
+func (c *Consumer) Check(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.fetchErr != nil {
+		return fmt.Errorf("failed to fetch message: %w", c.fetchErr)
+	}
+	return nil
+}
+
+func (c *Consumer) setFetchErr(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fetchErr = err
+}
+
+// messageLogger returns the logger of the lines about msg
+func (c *Consumer) messageLogger(msg Message) zerolog.Logger {
+	return c.logger.With().
+		Str("topic", msg.Topic).
+		Int("partition", msg.Partition).
+		Int64("offset", msg.Offset).
+		Logger()
+}
+
+// handle processes msg until it is applied or dead-lettered, reporting
+// whether it was. Messages still unhandled when ctx is cancelled are not, and
+// must be left uncommitted.
+func (c *Consumer) handle(ctx context.Context, msg Message) bool {
+	logger := c.messageLogger(msg)
+
+	event, err := ingest.DecodeEvent(msg.Value)
+	if err != nil {
+		return c.deadLetter(ctx, &logger, msg, err)
+	}
+	logger = logger.With().Str("transaction_id", event.TransactionID).Logger()
+
+	req := event.Request()
+	for attempt := 1; ; attempt++ {
+		_, err := c.processor.ProcessTransaction(ctx, event.UserID, req, c.sourceType)
+		switch {
+		case err == nil:
+			return true
+		case ctx.Err() != nil:
+			return false
+		case ingest.IsRejection(err):
+			return c.deadLetter(ctx, &logger, msg, err)
+		case !ingest.IsTransient(err) && attempt >= c.policy.MaxAttempts:
+			return c.deadLetter(ctx, &logger, msg, err)
+		}
+
+		logger.Warn().Err(err).Int("attempt", attempt).Msg("retrying message")
+		if sleep(ctx, c.backoff(attempt)) != nil {
+			return false
+		}
+		c.heartbeat.Beat()
+	}
+}
+
+// deadLetter writes msg to the dead letter topic, retrying until it succeeds,
+// and reports whether it was written
+func (c *Consumer) deadLetter(ctx context.Context, logger *zerolog.Logger, msg Message, cause error) bool {
+	dead := Message{
+		Topic: c.dlqTopic,
+		Key:   msg.Key,
+		Value: msg.Value,
+		Headers: append(append([]Header(nil), msg.Headers...),
+			Header{Key: HeaderError, Value: []byte(cause.Error())},
+			Header{Key: HeaderTopic, Value: []byte(msg.Topic)},
+			Header{Key: HeaderPartition, Value: []byte(strconv.Itoa(msg.Partition))},
+			Header{Key: HeaderOffset, Value: []byte(strconv.FormatInt(msg.Offset, 10))},
+		),
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := c.dlq.WriteMessages(ctx, dead)
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+		logger.Error().Err(err).Int("attempt", attempt).Msg("failed to dead-letter message")
+		if sleep(ctx, c.backoff(attempt)) != nil {
+			return false
+		}
+		c.heartbeat.Beat()
+	}
+
+	logger.Warn().Err(cause).Str("dlq_topic", c.dlqTopic).Msg("message dead-lettered")
+	return true
+}
+
+// commit commits msg, and with it the messages before it in its partition. A
+// failed commit only causes the messages to be redelivered, which replays
+// their transactions.
+func (c *Consumer) commit(ctx context.Context, msg Message) {
+	if err := c.reader.CommitMessages(ctx, msg); err != nil {
+		logger := c.messageLogger(msg)
+		logger.Error().Err(err).Msg("failed to commit message")
+	}
+}
+
+// backoff returns the delay before the given retry
+func (c *Consumer) backoff(attempt int) time.Duration {
+	return ingest.Backoff(attempt, c.policy.BaseDelay, c.policy.MaxDelay)
+}
+
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
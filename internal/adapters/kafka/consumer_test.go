@@ -0,0 +1,399 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"transaction-service/internal/adapters/ingest"
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/health"
+	"transaction-service/internal/processor"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeReader fails with its fetch errors, then serves its messages once and
+// cancels the consumer after them
+type fakeReader struct {
+	fetchErrs []error
+	messages  []Message
+	cancel    context.CancelFunc
+
+	mu        sync.Mutex
+	committed []int64
+}
+
+func (r *fakeReader) FetchMessage(ctx context.Context) (Message, error) {
+	if len(r.fetchErrs) > 0 {
+		err := r.fetchErrs[0]
+		r.fetchErrs = r.fetchErrs[1:]
+		return Message{}, err
+	}
+	if len(r.messages) == 0 {
+		r.cancel()
+		<-ctx.Done()
+		return Message{}, ctx.Err()
+	}
+	msg := r.messages[0]
+	r.messages = r.messages[1:]
+	return msg, nil
+}
+
+func (r *fakeReader) CommitMessages(ctx context.Context, msgs ...Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, msg := range msgs {
+		r.committed = append(r.committed, msg.Offset)
+	}
+	return nil
+}
+
+type fakeWriter struct {
+	written []Message
+	errs    []error
+}
+
+func (w *fakeWriter) WriteMessages(ctx context.Context, msgs ...Message) error {
+	if len(w.errs) > 0 {
+		err := w.errs[0]
+		w.errs = w.errs[1:]
+		return err
+	}
+	w.written = append(w.written, msgs...)
+	return nil
+}
+
+// fakeProcessor fails each transaction ID with the queued errors before
+// applying it
+type fakeProcessor struct {
+	errs    map[string][]error
+	calls   map[string]int
+	applied []entities.TransactionRequest
+}
+
+func (p *fakeProcessor) ProcessTransaction(
+	ctx context.Context,
+	userID uint64,
+	req entities.TransactionRequest,
+	sourceType entities.SourceType,
+) (*entities.TransactionResult, error) {
+	p.calls[req.TransactionID]++
+	if errs := p.errs[req.TransactionID]; len(errs) > 0 {
+		p.errs[req.TransactionID] = errs[1:]
+		return nil, errs[0]
+	}
+	p.applied = append(p.applied, req)
+	return &entities.TransactionResult{UserID: userID, TransactionID: req.TransactionID}, nil
+}
+
+func eventMessage(offset int64, transactionID string) Message {
+	value, _ := json.Marshal(ingest.TransactionEvent{UserID: 1, State: "lose", Amount: "5.00", TransactionID: transactionID})
+	return Message{Topic: "transactions", Partition: 2, Offset: offset, Value: value}
+}
+
+func TestConsumer(t *testing.T) {
+	unavailable := fmt.Errorf("failed to get user: %w", services.ErrUnavailable)
+
+	tests := []struct {
+		name          string
+		message       Message
+		errs          []error
+		dlqErrs       []error
+		wantCalls     int
+		wantApplied   bool
+		wantDLQReason string
+	}{
+		{
+			name:        "applied messages are committed",
+			message:     eventMessage(7, "tx-1"),
+			wantCalls:   1,
+			wantApplied: true,
+		},
+		{
+			name:          "undecodable messages are dead-lettered",
+			message:       Message{Topic: "transactions", Partition: 2, Offset: 7, Value: []byte("{")},
+			wantDLQReason: "invalid transaction event",
+		},
+		{
+			name:          "incomplete events are dead-lettered",
+			message:       Message{Topic: "transactions", Partition: 2, Offset: 7, Value: []byte(`{"userId":1}`)},
+			wantDLQReason: "invalid transaction event",
+		},
+		{
+			name:          "rejected transactions are dead-lettered",
+			message:       eventMessage(7, "tx-1"),
+			errs:          []error{services.ErrInsufficientFunds},
+			wantCalls:     1,
+			wantDLQReason: "insufficient funds",
+		},
+		{
+			name:        "outages are retried until they pass",
+			message:     eventMessage(7, "tx-1"),
+			errs:        []error{unavailable, unavailable, unavailable, unavailable},
+			wantCalls:   5,
+			wantApplied: true,
+		},
+		{
+			name:          "unexpected errors are dead-lettered after the last attempt",
+			message:       eventMessage(7, "tx-1"),
+			errs:          []error{errors.New("boom"), errors.New("boom"), errors.New("boom")},
+			wantCalls:     3,
+			wantDLQReason: "boom",
+		},
+		{
+			name:          "dead-lettering is retried",
+			message:       eventMessage(7, "tx-1"),
+			errs:          []error{services.ErrUserNotFound},
+			dlqErrs:       []error{errors.New("leader not available")},
+			wantCalls:     1,
+			wantDLQReason: "user not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			reader := &fakeReader{messages: []Message{tt.message}, cancel: cancel}
+			dlq := &fakeWriter{errs: tt.dlqErrs}
+			processor := &fakeProcessor{
+				errs:  map[string][]error{"tx-1": tt.errs},
+				calls: make(map[string]int),
+			}
+			consumer := NewConsumer(reader, dlq, "transactions-dlq", processor, entities.SourceTypeGame, RetryPolicy{
+				MaxAttempts: 3,
+				BaseDelay:   time.Millisecond,
+				MaxDelay:    time.Millisecond,
+			}, nil, zerolog.Nop())
+
+			consumer.Run(ctx)
+
+			assert.Equal(t, []int64{7}, reader.committed, "the message is committed once handled")
+			assert.Equal(t, tt.wantCalls, processor.calls["tx-1"])
+			assert.Equal(t, tt.wantApplied, len(processor.applied) == 1)
+			if tt.wantDLQReason == "" {
+				assert.Empty(t, dlq.written)
+				return
+			}
+
+			require.Len(t, dlq.written, 1)
+			dead := dlq.written[0]
+			assert.Equal(t, "transactions-dlq", dead.Topic)
+			assert.Equal(t, tt.message.Value, dead.Value)
+			headers := make(map[string]string)
+			for _, header := range dead.Headers {
+				headers[header.Key] = string(header.Value)
+			}
+			assert.Contains(t, headers[HeaderError], tt.wantDLQReason)
+			assert.Equal(t, "transactions", headers[HeaderTopic])
+			assert.Equal(t, "2", headers[HeaderPartition])
+			assert.Equal(t, "7", headers[HeaderOffset])
+		})
+	}
+}
+
+// gatedProcessor applies transactions once their gate, if any, is closed
+type gatedProcessor struct {
+	gates map[string]chan struct{}
+
+	mu      sync.Mutex
+	applied []string
+}
+
+func (p *gatedProcessor) ProcessTransaction(
+	ctx context.Context,
+	userID uint64,
+	req entities.TransactionRequest,
+	sourceType entities.SourceType,
+) (*entities.TransactionResult, error) {
+	if gate, ok := p.gates[req.TransactionID]; ok {
+		<-gate
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.applied = append(p.applied, req.TransactionID)
+	return &entities.TransactionResult{UserID: userID, TransactionID: req.TransactionID}, nil
+}
+
+func (p *gatedProcessor) appliedTransactions() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.applied...)
+}
+
+func TestConsumer_Pool(t *testing.T) {
+	event := func(offset int64, userID uint64, transactionID string) Message {
+		value, _ := json.Marshal(ingest.TransactionEvent{UserID: userID, State: "win", Amount: "5.00", TransactionID: transactionID})
+		return Message{Topic: "transactions", Partition: 2, Offset: offset, Value: value}
+	}
+	committed := func(reader *fakeReader) []int64 {
+		reader.mu.Lock()
+		defer reader.mu.Unlock()
+		return append([]int64(nil), reader.committed...)
+	}
+
+	pool := processor.New(2, 10)
+	poolCtx, stopPool := context.WithCancel(context.Background())
+	poolDone := make(chan struct{})
+	go func() {
+		defer close(poolDone)
+		pool.Run(poolCtx)
+	}()
+	defer func() {
+		stopPool()
+		<-poolDone
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	reader := &fakeReader{cancel: cancel, messages: []Message{
+		event(1, 1, "tx-1"),
+		event(2, 2, "tx-2"),
+		event(3, 1, "tx-3"),
+		{Topic: "transactions", Partition: 2, Offset: 4, Value: []byte("{")},
+	}}
+	dlq := &fakeWriter{}
+	release := make(chan struct{})
+	gated := &gatedProcessor{gates: map[string]chan struct{}{"tx-1": release}}
+	consumer := NewConsumer(reader, dlq, "transactions-dlq", gated, entities.SourceTypeGame, RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    time.Millisecond,
+	}, nil, zerolog.Nop(), WithPool(pool))
+
+	consumer.Run(ctx)
+
+	// Other users are not held back by tx-1, but their offsets are not
+	// committed before it
+	require.Eventually(t, func() bool {
+		return len(gated.appliedTransactions()) == 1
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, []string{"tx-2"}, gated.appliedTransactions())
+	assert.Empty(t, committed(reader))
+
+	close(release)
+	require.Eventually(t, func() bool {
+		offsets := committed(reader)
+		return len(offsets) > 0 && offsets[len(offsets)-1] == 4
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, []string{"tx-2", "tx-1", "tx-3"}, gated.appliedTransactions(), "the transactions of a user keep their order")
+	assert.IsIncreasing(t, committed(reader))
+	assert.Len(t, dlq.written, 1)
+}
+
+func TestOffsetTracker(t *testing.T) {
+	tracker := newOffsetTracker()
+	first := tracker.track(Message{Topic: "transactions", Partition: 1, Offset: 1})
+	second := tracker.track(Message{Topic: "transactions", Partition: 1, Offset: 2})
+	other := tracker.track(Message{Topic: "transactions", Partition: 2, Offset: 1})
+	third := tracker.track(Message{Topic: "transactions", Partition: 1, Offset: 3})
+
+	_, ok := tracker.done(second)
+	assert.False(t, ok, "the first message is still in flight")
+
+	last, ok := tracker.done(other)
+	require.True(t, ok, "partitions are tracked apart")
+	assert.Equal(t, 2, last.Partition)
+
+	last, ok = tracker.done(first)
+	require.True(t, ok)
+	assert.EqualValues(t, 2, last.Offset)
+
+	last, ok = tracker.done(third)
+	require.True(t, ok)
+	assert.EqualValues(t, 3, last.Offset)
+	assert.Empty(t, tracker.partitions)
+}
+
+func TestConsumer_UncommittedOnShutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	reader := &fakeReader{messages: []Message{eventMessage(7, "tx-1")}, cancel: cancel}
+	processor := &fakeProcessor{
+		errs:  map[string][]error{"tx-1": {services.ErrUnavailable}},
+		calls: make(map[string]int),
+	}
+	consumer := NewConsumer(reader, &fakeWriter{}, "transactions-dlq", processor, entities.SourceTypeGame, RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Hour,
+		MaxDelay:    time.Hour,
+	}, nil, zerolog.Nop())
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	consumer.Run(ctx)
+
+	assert.Empty(t, reader.committed, "a message still failing on shutdown is redelivered")
+}
+
+func TestConsumer_Check(t *testing.T) {
+	run := func(reader *fakeReader) *Consumer {
+		ctx, cancel := context.WithCancel(context.Background())
+		reader.cancel = cancel
+		processor := &fakeProcessor{calls: make(map[string]int)}
+		consumer := NewConsumer(reader, &fakeWriter{}, "transactions-dlq", processor, entities.SourceTypeGame, RetryPolicy{
+			MaxAttempts: 3,
+			BaseDelay:   time.Millisecond,
+			MaxDelay:    time.Millisecond,
+		}, nil, zerolog.Nop())
+		consumer.Run(ctx)
+		return consumer
+	}
+
+	t.Run("failing fetches", func(t *testing.T) {
+		consumer := run(&fakeReader{fetchErrs: []error{errors.New("broker unreachable")}})
+		assert.EqualError(t, consumer.Check(context.Background()), "failed to fetch message: broker unreachable")
+	})
+
+	t.Run("recovered fetches", func(t *testing.T) {
+		consumer := run(&fakeReader{
+			fetchErrs: []error{errors.New("broker unreachable")},
+			messages:  []Message{eventMessage(7, "tx-1")},
+		})
+		assert.NoError(t, consumer.Check(context.Background()))
+	})
+}
+
+// idleReader waits for messages that never arrive
+type idleReader struct{}
+
+func (idleReader) FetchMessage(ctx context.Context) (Message, error) {
+	<-ctx.Done()
+	return Message{}, ctx.Err()
+}
+
+func (idleReader) CommitMessages(ctx context.Context, msgs ...Message) error {
+	return nil
+}
+
+func TestConsumer_Heartbeat(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	heartbeat := health.NewHeartbeat(50 * time.Millisecond)
+	consumer := NewConsumer(idleReader{}, &fakeWriter{}, "transactions-dlq", &fakeProcessor{}, entities.SourceTypeGame, RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    time.Millisecond,
+	}, nil, zerolog.Nop(), WithHeartbeat(heartbeat, 5*time.Millisecond))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		consumer.Run(ctx)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	assert.NoError(t, heartbeat.Check(ctx), "an idle consumer beats")
+	assert.NoError(t, consumer.Check(ctx), "idle fetches are not failures")
+	cancel()
+	<-done
+}
@@ -0,0 +1,60 @@
+package kafka
+
+import "sync"
+
+// topicPartition identifies a partition
+type topicPartition struct {
+	topic     string
+	partition int
+}
+
+// trackedMessage is a message in flight
+type trackedMessage struct {
+	msg  Message
+	done bool
+}
+
+// offsetTracker keeps the messages in flight of every partition in the order
+// they were fetched, so that a message handled out of order is only committed
+// once every message before it was handled too
+type offsetTracker struct {
+	mu         sync.Mutex
+	partitions map[topicPartition][]*trackedMessage
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{partitions: make(map[topicPartition][]*trackedMessage)}
+}
+
+// track records msg as in flight
+func (t *offsetTracker) track(msg Message) *trackedMessage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tracked := &trackedMessage{msg: msg}
+	key := topicPartition{topic: msg.Topic, partition: msg.Partition}
+	t.partitions[key] = append(t.partitions[key], tracked)
+	return tracked
+}
+
+// done marks tracked as handled. It returns the last message of its partition
+// every message up to which was handled, which is to be committed; ok is false
+// while an earlier message is still in flight.
+func (t *offsetTracker) done(tracked *trackedMessage) (last Message, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tracked.done = true
+	key := topicPartition{topic: tracked.msg.Topic, partition: tracked.msg.Partition}
+	inFlight := t.partitions[key]
+	for len(inFlight) > 0 && inFlight[0].done {
+		last, ok = inFlight[0].msg, true
+		inFlight = inFlight[1:]
+	}
+	if len(inFlight) == 0 {
+		delete(t.partitions, key)
+	} else {
+		t.partitions[key] = inFlight
+	}
+	return last, ok
+}
@@ -0,0 +1,47 @@
+package kafka
+
+import (
+	"context"
+	"strconv"
+
+	"transaction-service/internal/domain/entities"
+)
+
+// Headers identify published outbox events, so consumers can deduplicate
+// the events a relay publishes more than once
+const (
+	HeaderEventID   = "event-id"
+	HeaderEventType = "event-type"
+)
+
+// Publisher publishes outbox events to a topic, keyed by the event key so the
+// events of a user land on one partition in order. The writer must only
+// return once the brokers acknowledged the messages, e.g. a kafka-go Writer
+// with RequiredAcks set to all.
+type Publisher struct {
+	writer Writer
+	topic  string
+}
+
+// NewPublisher creates a new Publisher
+func NewPublisher(writer Writer, topic string) *Publisher {
+	return &Publisher{writer: writer, topic: topic}
+}
+
+// Publish writes the events as one batch
+func (p *Publisher) Publish(ctx context.Context, events []*entities.OutboxEvent) error {
+	msgs := make([]Message, len(events))
+	for i, event := range events {
+		msgs[i] = Message{
+			Topic: p.topic,
+			Key:   []byte(event.Key),
+			Value: event.Payload,
+			Headers: []Header{
+				{Key: HeaderEventID, Value: []byte(strconv.FormatUint(event.ID, 10))},
+				{Key: HeaderEventType, Value: []byte(event.Type)},
+			},
+			Time: event.CreatedAt,
+		}
+	}
+	return p.writer.WriteMessages(ctx, msgs...)
+}
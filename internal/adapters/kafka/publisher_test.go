@@ -0,0 +1,36 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"transaction-service/internal/domain/entities"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublisher_Publish(t *testing.T) {
+	ctx := context.Background()
+	writer := &fakeWriter{}
+	publisher := NewPublisher(writer, "balance-events")
+
+	err := publisher.Publish(ctx, []*entities.OutboxEvent{
+		{ID: 41, Type: entities.EventTransactionProcessed, Key: "7", Payload: []byte(`{"userId":7}`)},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, writer.written, 1)
+	msg := writer.written[0]
+	assert.Equal(t, "balance-events", msg.Topic)
+	assert.Equal(t, []byte("7"), msg.Key, "events of a user share a partition")
+	assert.Equal(t, []byte(`{"userId":7}`), msg.Value)
+	assert.Equal(t, []Header{
+		{Key: HeaderEventID, Value: []byte("41")},
+		{Key: HeaderEventType, Value: []byte("transaction.processed")},
+	}, msg.Headers)
+
+	writer.errs = []error{errors.New("not enough replicas")}
+	assert.Error(t, publisher.Publish(ctx, []*entities.OutboxEvent{{ID: 42}}))
+}
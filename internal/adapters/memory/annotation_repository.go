@@ -0,0 +1,90 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strconv"
+
+	"transaction-service/internal/domain/entities"
+)
+
+// AnnotationRepository implements the annotation repository interface on top
+// of a Store
+type AnnotationRepository struct {
+	store *Store
+}
+
+// NewAnnotationRepository creates a new AnnotationRepository
+func NewAnnotationRepository(store *Store) *AnnotationRepository {
+	return &AnnotationRepository{store: store}
+}
+
+// Create stores a new annotation. A zero ID is allocated past the highest ID;
+// like a sequence, allocated IDs are not given back by a failed unit of work.
+func (r *AnnotationRepository) Create(ctx context.Context, annotation *entities.Annotation) error {
+	return r.store.write(ctx, func() error {
+		if annotation.ID == 0 {
+			annotation.ID = r.store.lastAnnotationID + 1
+		}
+		r.store.lastAnnotationID = max(r.store.lastAnnotationID, annotation.ID)
+
+		stored := *annotation
+		r.store.annotations = append(r.store.annotations, &stored)
+		r.store.record(ctx, func() {
+			r.store.annotations = slices.DeleteFunc(r.store.annotations, func(a *entities.Annotation) bool {
+				return a == &stored
+			})
+		})
+		return nil
+	})
+}
+
+// ListByTarget returns the annotations on a record, oldest first
+func (r *AnnotationRepository) ListByTarget(
+	ctx context.Context,
+	targetType entities.AnnotationTarget,
+	targetID string,
+) ([]*entities.Annotation, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	return r.list(func(annotation *entities.Annotation) bool {
+		return annotation.TargetType == targetType && annotation.TargetID == targetID
+	}), nil
+}
+
+// ListByUser returns the annotations on a user and on the user's
+// transactions, oldest first
+func (r *AnnotationRepository) ListByUser(ctx context.Context, userID uint64) ([]*entities.Annotation, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	userTargetID := strconv.FormatUint(userID, 10)
+	return r.list(func(annotation *entities.Annotation) bool {
+		switch annotation.TargetType {
+		case entities.AnnotationTargetUser:
+			return annotation.TargetID == userTargetID
+		case entities.AnnotationTargetTransaction:
+			transaction := r.store.byTransactionID[annotation.TargetID]
+			return transaction != nil && transaction.UserID == userID
+		}
+		return false
+	}), nil
+}
+
+// list copies the annotations matching keep, oldest first. The caller holds
+// the store's lock.
+func (r *AnnotationRepository) list(keep func(*entities.Annotation) bool) []*entities.Annotation {
+	var annotations []*entities.Annotation
+	for _, annotation := range r.store.annotations {
+		if keep(annotation) {
+			copied := *annotation
+			annotations = append(annotations, &copied)
+		}
+	}
+	sort.SliceStable(annotations, func(i, j int) bool {
+		return annotations[i].CreatedAt.Before(annotations[j].CreatedAt)
+	})
+	return annotations
+}
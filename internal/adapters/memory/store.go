@@ -0,0 +1,158 @@
+// Package memory implements the user, wallet, transaction and annotation
+// repositories in process memory, so that the service can run without
+// PostgreSQL in CI and local demos. Everything is lost when the process exits
+// and nothing is shared between processes.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+)
+
+type journalContextKey struct{}
+
+// journal collects the undo steps of the writes made in a unit of work
+type journal struct {
+	undo []func()
+}
+
+// Store holds the records shared by the repositories of this package
+type Store struct {
+	// writer serializes units of work, standing in for row locks: a unit of
+	// work sees no concurrent changes to the records it locks
+	writer sync.Mutex
+
+	mu           sync.RWMutex
+	users        map[uint64]*userRecord
+	lastUserID   uint64
+	wallets      map[walletKey]*entities.Wallet
+	transactions map[uint64]*entities.Transaction
+	// byTransactionID indexes transactions by their external ID, which is
+	// unique like in the database
+	byTransactionID   map[string]*entities.Transaction
+	lastTransactionID uint64
+	annotations       []*entities.Annotation
+	lastAnnotationID  uint64
+
+	now func() time.Time
+}
+
+// userRecord is a stored user along with when it was created
+type userRecord struct {
+	user      entities.User
+	createdAt time.Time
+}
+
+type walletKey struct {
+	userID   uint64
+	currency string
+}
+
+// NewStore creates a new empty Store
+func NewStore() *Store {
+	return &Store{
+		users:           make(map[uint64]*userRecord),
+		wallets:         make(map[walletKey]*entities.Wallet),
+		transactions:    make(map[uint64]*entities.Transaction),
+		byTransactionID: make(map[string]*entities.Transaction),
+		now:             time.Now,
+	}
+}
+
+// write runs fn holding s.mu for writing. Writes outside a unit of work wait
+// for the running one, like they would for its row locks.
+func (s *Store) write(ctx context.Context, fn func() error) error {
+	if _, ok := ctx.Value(journalContextKey{}).(*journal); !ok {
+		s.writer.Lock()
+		defer s.writer.Unlock()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return fn()
+}
+
+// record registers how to undo a write made with ctx, for when its unit of
+// work fails. s.mu must be held.
+func (s *Store) record(ctx context.Context, undo func()) {
+	if j, ok := ctx.Value(journalContextKey{}).(*journal); ok {
+		j.undo = append(j.undo, undo)
+	}
+}
+
+// rollback undoes the writes of a failed unit of work, newest first
+func (s *Store) rollback(j *journal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := len(j.undo) - 1; i >= 0; i-- {
+		j.undo[i]()
+	}
+}
+
+// SeedUsers idempotently inserts the given users with their ID and balance.
+// Existing users are left untouched, and later users are given IDs past the
+// highest one.
+func SeedUsers(store *Store, users []*entities.User) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	for _, user := range users {
+		if _, ok := store.users[user.ID]; ok {
+			continue
+		}
+		store.users[user.ID] = &userRecord{
+			user:      entities.User{ID: user.ID, Balance: user.Balance, Status: entities.UserStatusActive},
+			createdAt: store.now(),
+		}
+		store.lastUserID = max(store.lastUserID, user.ID)
+	}
+}
+
+// UnitOfWork implements the unit of work interface on top of a Store. Writes
+// are applied as they are made and undone if the unit of work fails.
+type UnitOfWork struct {
+	store *Store
+}
+
+// NewUnitOfWork creates a new UnitOfWork
+func NewUnitOfWork(store *Store) *UnitOfWork {
+	return &UnitOfWork{store: store}
+}
+
+// WithinTransaction runs fn as a unit of work carried by its context, one at a
+// time
+func (u *UnitOfWork) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	// Join the ambient unit of work if there is one
+	if _, ok := ctx.Value(journalContextKey{}).(*journal); ok {
+		return fn(ctx)
+	}
+
+	u.store.writer.Lock()
+	defer u.store.writer.Unlock()
+
+	j := &journal{}
+	defer func() {
+		if p := recover(); p != nil {
+			u.store.rollback(j)
+			panic(p)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, journalContextKey{}, j)); err != nil {
+		u.store.rollback(j)
+		return err
+	}
+
+	return nil
+}
+
+// conflict reports a write colliding with an existing record
+func conflict(format string, args ...any) error {
+	return fmt.Errorf("%s: %w", fmt.Sprintf(format, args...), repositories.ErrConflict)
+}
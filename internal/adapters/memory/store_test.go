@@ -0,0 +1,194 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnitOfWork_RollsBackFailedUnits(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore()
+	SeedUsers(store, []*entities.User{{ID: 1, Balance: decimal.NewFromInt(10)}})
+	unitOfWork := NewUnitOfWork(store)
+	userRepo := NewUserRepository(store)
+	transactionRepo := NewTransactionRepository(store)
+	walletRepo := NewWalletRepository(store)
+
+	failure := errors.New("failed")
+	err := unitOfWork.WithinTransaction(ctx, func(ctx context.Context) error {
+		require.NoError(t, userRepo.UpdateBalance(ctx, 1, decimal.NewFromInt(20)))
+		require.NoError(t, transactionRepo.Create(ctx, &entities.Transaction{UserID: 1, TransactionID: "tx-1"}))
+		_, err := walletRepo.GetForUpdate(ctx, 1, "USD")
+		require.NoError(t, err)
+		// Nested units of work join the outer one
+		return unitOfWork.WithinTransaction(ctx, func(ctx context.Context) error {
+			require.NoError(t, userRepo.Create(ctx, &entities.User{}))
+			return failure
+		})
+	})
+	require.ErrorIs(t, err, failure)
+
+	user, err := userRepo.GetByID(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "10", user.Balance.String())
+	_, err = transactionRepo.GetByTransactionID(ctx, "tx-1")
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+	wallets, err := walletRepo.ListByUser(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, wallets)
+	_, total, err := userRepo.List(ctx, 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+
+	t.Run("writes outside a unit of work apply at once", func(t *testing.T) {
+		user := &entities.User{Balance: decimal.NewFromInt(5)}
+		require.NoError(t, userRepo.Create(ctx, user))
+		// IDs are not given back by the failed unit of work
+		assert.Equal(t, uint64(3), user.ID)
+		assert.Equal(t, entities.UserStatusActive, user.Status)
+	})
+}
+
+func TestTransactionRepository_RejectsDuplicates(t *testing.T) {
+	ctx := context.Background()
+	repo := NewTransactionRepository(NewStore())
+
+	transaction := &entities.Transaction{UserID: 1, TransactionID: "tx-1", Amount: decimal.NewFromInt(5)}
+	require.NoError(t, repo.Create(ctx, transaction))
+	assert.Equal(t, uint64(1), transaction.ID)
+	assert.Equal(t, "1", transaction.Receipt)
+
+	err := repo.Create(ctx, &entities.Transaction{UserID: 2, TransactionID: "tx-1"})
+	assert.ErrorIs(t, err, repositories.ErrConflict)
+	err = repo.Create(ctx, &entities.Transaction{ID: 1, UserID: 2, TransactionID: "tx-2"})
+	assert.ErrorIs(t, err, repositories.ErrConflict)
+
+	exists, err := repo.ExistsByTransactionID(ctx, "tx-1")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	stored, err := repo.GetByTransactionID(ctx, "tx-1")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), stored.UserID)
+	assert.Equal(t, entities.TransactionTypeStandard, stored.Type)
+}
+
+func TestTransactionRepository_LockLatestOddUncancelled(t *testing.T) {
+	ctx := context.Background()
+	repo := NewTransactionRepository(NewStore())
+	// IDs 1 to 5: a transaction, then an adjustment and its house contra leg
+	// on odd IDs, each followed by a transaction
+	for i, transactionType := range []entities.TransactionType{
+		entities.TransactionTypeStandard,
+		entities.TransactionTypeStandard,
+		entities.TransactionTypeAdjustment,
+		entities.TransactionTypeStandard,
+		entities.TransactionTypeAdjustment,
+	} {
+		require.NoError(t, repo.Create(ctx, &entities.Transaction{
+			UserID:        1,
+			TransactionID: "tx-" + strconv.Itoa(i+1),
+			State:         entities.StateWin,
+			Amount:        decimal.NewFromInt(5),
+			Type:          transactionType,
+		}))
+	}
+
+	transactions, err := repo.LockLatestOddUncancelled(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, transactions, 1)
+	// Adjustments are operator corrections, never cancelled
+	assert.Equal(t, uint64(1), transactions[0].ID)
+}
+
+func TestTransactionRepository_Search(t *testing.T) {
+	ctx := context.Background()
+	repo := NewTransactionRepository(NewStore())
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	for i, amount := range []int64{5, 10, 15, 20} {
+		require.NoError(t, repo.Create(ctx, &entities.Transaction{
+			UserID:        uint64(i%2 + 1),
+			TransactionID: "tx-" + strconv.Itoa(i),
+			State:         entities.StateWin,
+			Amount:        decimal.NewFromInt(amount),
+			SourceType:    entities.SourceTypeGame,
+			CreatedAt:     start.Add(time.Duration(i) * time.Minute),
+		}))
+	}
+
+	minAmount := decimal.NewFromInt(10)
+	to := start.Add(3 * time.Minute)
+	transactions, total, err := repo.Search(ctx, repositories.TransactionFilter{
+		MinAmount: &minAmount,
+		To:        &to,
+		Limit:     10,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+	require.Len(t, transactions, 2)
+	// Newest first
+	assert.Equal(t, "tx-2", transactions[0].TransactionID)
+	assert.Equal(t, "tx-1", transactions[1].TransactionID)
+
+	transactions, total, err = repo.Search(ctx, repositories.TransactionFilter{UserID: 2, Limit: 1, Offset: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+	require.Len(t, transactions, 1)
+	assert.Equal(t, "tx-1", transactions[0].TransactionID)
+
+	// The cursor continues after the transaction it points at
+	after := &repositories.TransactionCursor{CreatedAt: start.Add(2 * time.Minute), ID: 3}
+	transactions, total, err = repo.Search(ctx, repositories.TransactionFilter{After: after, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 4, total)
+	require.Len(t, transactions, 2)
+	assert.Equal(t, "tx-1", transactions[0].TransactionID)
+
+	cancelled := true
+	_, total, err = repo.Search(ctx, repositories.TransactionFilter{Cancelled: &cancelled, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 0, total)
+}
+
+func TestTransactionService_ConcurrentTransactions(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore()
+	SeedUsers(store, []*entities.User{{ID: 1, Balance: decimal.NewFromInt(100)}})
+	service := services.NewTransactionService(
+		NewUnitOfWork(store), NewUserRepository(store), NewTransactionRepository(store),
+	)
+
+	// Every transaction is submitted twice; only the first applies
+	var wg sync.WaitGroup
+	for i := range 50 {
+		for range 2 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, _ = service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+					State: "win", Amount: "1", TransactionID: "tx-" + strconv.Itoa(i),
+				}, entities.SourceTypeGame)
+			}()
+		}
+	}
+	wg.Wait()
+
+	balance, err := service.GetUserBalance(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "150.00", balance.Balance)
+
+	transactions, err := NewTransactionRepository(store).GetByUserID(ctx, 1)
+	require.NoError(t, err)
+	assert.Len(t, transactions, 50)
+}
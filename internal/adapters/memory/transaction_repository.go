@@ -0,0 +1,446 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/shopspring/decimal"
+)
+
+// TransactionRepository implements the transaction repository interface on
+// top of a Store. Transaction IDs are unique like in the database: recording
+// one twice fails with ErrConflict.
+type TransactionRepository struct {
+	store *Store
+}
+
+// NewTransactionRepository creates a new TransactionRepository
+func NewTransactionRepository(store *Store) *TransactionRepository {
+	return &TransactionRepository{store: store}
+}
+
+// Create creates a new transaction. A zero ID is allocated past the highest
+// ID, and an empty receipt defaults to the decimal ID. A transaction ID or ID
+// in use is rejected with ErrConflict.
+func (r *TransactionRepository) Create(ctx context.Context, transaction *entities.Transaction) error {
+	return r.store.write(ctx, func() error {
+		if _, ok := r.store.byTransactionID[transaction.TransactionID]; ok {
+			return conflict("transaction %q", transaction.TransactionID)
+		}
+		id := transaction.ID
+		if id == 0 {
+			id = r.store.lastTransactionID + 1
+		}
+		if _, ok := r.store.transactions[id]; ok {
+			return conflict("transaction with ID %d", id)
+		}
+
+		stored := *transaction
+		stored.ID = id
+		stored.Metadata = maps.Clone(transaction.Metadata)
+		if stored.Receipt == "" {
+			stored.Receipt = strconv.FormatUint(id, 10)
+		}
+		if stored.Type == "" {
+			stored.Type = entities.TransactionTypeStandard
+		}
+		r.store.transactions[id] = &stored
+		r.store.byTransactionID[stored.TransactionID] = &stored
+		r.store.lastTransactionID = max(r.store.lastTransactionID, id)
+		r.store.record(ctx, func() {
+			delete(r.store.transactions, id)
+			delete(r.store.byTransactionID, stored.TransactionID)
+		})
+
+		transaction.ID = stored.ID
+		transaction.Receipt = stored.Receipt
+		return nil
+	})
+}
+
+// ExistsByTransactionID checks if a transaction with the given ID exists
+func (r *TransactionRepository) ExistsByTransactionID(ctx context.Context, transactionID string) (bool, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	_, ok := r.store.byTransactionID[transactionID]
+	return ok, nil
+}
+
+// GetByTransactionID retrieves a transaction by its external ID
+func (r *TransactionRepository) GetByTransactionID(ctx context.Context, transactionID string) (*entities.Transaction, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	transaction, ok := r.store.byTransactionID[transactionID]
+	if !ok {
+		return nil, fmt.Errorf("transaction %q: %w", transactionID, repositories.ErrNotFound)
+	}
+	copied := *transaction
+	return &copied, nil
+}
+
+// GetByTransactionIDForUpdate retrieves a transaction by its external ID.
+// Units of work run one at a time, so the transaction stays as it is until
+// the ambient unit of work ends.
+func (r *TransactionRepository) GetByTransactionIDForUpdate(ctx context.Context, transactionID string) (*entities.Transaction, error) {
+	return r.GetByTransactionID(ctx, transactionID)
+}
+
+// matching returns copies of the transactions accepted by keep, newest
+// first. r.store.mu must be held.
+func (r *TransactionRepository) matching(keep func(transaction *entities.Transaction) bool) []*entities.Transaction {
+	var transactions []*entities.Transaction
+	for _, transaction := range r.store.transactions {
+		if keep(transaction) {
+			copied := *transaction
+			transactions = append(transactions, &copied)
+		}
+	}
+	sort.Slice(transactions, func(i, j int) bool {
+		if !transactions[i].CreatedAt.Equal(transactions[j].CreatedAt) {
+			return transactions[i].CreatedAt.After(transactions[j].CreatedAt)
+		}
+		return transactions[i].ID > transactions[j].ID
+	})
+	return transactions
+}
+
+// GetByUserID retrieves all transactions for a user
+func (r *TransactionRepository) GetByUserID(ctx context.Context, userID uint64) ([]*entities.Transaction, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	return r.matching(func(transaction *entities.Transaction) bool {
+		return transaction.UserID == userID
+	}), nil
+}
+
+// Search returns a page of transactions matching the filter, newest first
+func (r *TransactionRepository) Search(
+	ctx context.Context,
+	filter repositories.TransactionFilter,
+) ([]*entities.Transaction, int, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	matches := r.matching(func(transaction *entities.Transaction) bool {
+		return matchesFilter(transaction, filter)
+	})
+	total := len(matches)
+	start := min(filter.Offset, total)
+	if after := filter.After; after != nil {
+		start = sort.Search(total, func(i int) bool {
+			return matches[i].CreatedAt.Before(after.CreatedAt) ||
+				matches[i].CreatedAt.Equal(after.CreatedAt) && matches[i].ID < after.ID
+		})
+	}
+	return matches[start:min(start+filter.Limit, total)], total, nil
+}
+
+// matchesFilter reports whether transaction meets every criterion of filter
+func matchesFilter(transaction *entities.Transaction, filter repositories.TransactionFilter) bool {
+	switch {
+	case filter.UserID != 0 && transaction.UserID != filter.UserID:
+		return false
+	case !strings.HasPrefix(transaction.TransactionID, filter.TransactionIDPrefix):
+		return false
+	case filter.MinAmount != nil && transaction.Amount.LessThan(*filter.MinAmount):
+		return false
+	case filter.MaxAmount != nil && transaction.Amount.GreaterThan(*filter.MaxAmount):
+		return false
+	case filter.SourceType != "" && transaction.SourceType != filter.SourceType:
+		return false
+	case filter.State != "" && transaction.State != filter.State:
+		return false
+	case filter.From != nil && transaction.CreatedAt.Before(*filter.From):
+		return false
+	case filter.To != nil && !transaction.CreatedAt.Before(*filter.To):
+		return false
+	case filter.Cancelled != nil && transaction.Cancelled != *filter.Cancelled:
+		return false
+	case filter.MetadataKey != "":
+		value, ok := transaction.Metadata[filter.MetadataKey]
+		return ok && (filter.MetadataValue == nil || value == *filter.MetadataValue)
+	}
+	return true
+}
+
+// NetChangeSince returns how much a user's base currency transactions moved
+// the balance at or after since
+func (r *TransactionRepository) NetChangeSince(ctx context.Context, userID uint64, since time.Time) (decimal.Decimal, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	net := decimal.Zero
+	for _, transaction := range r.store.transactions {
+		if transaction.UserID == userID && transaction.Currency == "" {
+			net = net.Add(transaction.BalanceChangeSince(since))
+		}
+	}
+	return net, nil
+}
+
+// VelocitySince totals a user's uncancelled standard transactions created at
+// or after since
+func (r *TransactionRepository) VelocitySince(ctx context.Context, userID uint64, since time.Time) (repositories.Velocity, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	velocity := repositories.Velocity{Losses: decimal.Zero}
+	for _, transaction := range r.store.transactions {
+		if transaction.UserID != userID || transaction.CreatedAt.Before(since) || transaction.Cancelled ||
+			transaction.Type != entities.TransactionTypeStandard {
+			continue
+		}
+		velocity.Transactions++
+		if transaction.State == entities.StateLose && transaction.Currency == "" {
+			velocity.Losses = velocity.Losses.Add(transaction.Amount)
+		}
+	}
+	return velocity, nil
+}
+
+// MostActiveUsers returns the IDs of up to limit users with the most
+// transactions created at or after since, most active first
+func (r *TransactionRepository) MostActiveUsers(ctx context.Context, since time.Time, limit int) ([]uint64, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	counts := make(map[uint64]int)
+	for _, transaction := range r.store.transactions {
+		if !transaction.CreatedAt.Before(since) {
+			counts[transaction.UserID]++
+		}
+	}
+	userIDs := make([]uint64, 0, len(counts))
+	for userID := range counts {
+		userIDs = append(userIDs, userID)
+	}
+	sort.Slice(userIDs, func(i, j int) bool {
+		if counts[userIDs[i]] != counts[userIDs[j]] {
+			return counts[userIDs[i]] > counts[userIDs[j]]
+		}
+		return userIDs[i] < userIDs[j]
+	})
+	if len(userIDs) > limit {
+		userIDs = userIDs[:limit]
+	}
+	return userIDs, nil
+}
+
+// SummarizeRound totals the user's transactions of a round in a wallet
+// currency, empty for the base currency
+func (r *TransactionRepository) SummarizeRound(
+	ctx context.Context,
+	userID uint64,
+	roundID, currency string,
+) (repositories.RoundTotals, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	totals := repositories.RoundTotals{Bets: decimal.Zero, Wins: decimal.Zero, Fees: decimal.Zero}
+	for _, transaction := range r.store.transactions {
+		if transaction.UserID != userID || transaction.RoundID != roundID || transaction.Currency != currency {
+			continue
+		}
+		switch {
+		case transaction.Cancelled:
+			totals.Cancelled++
+			continue
+		case transaction.Type == entities.TransactionTypeFee:
+			totals.Fees = totals.Fees.Add(transaction.Amount)
+			continue
+		case transaction.State == entities.StateWin:
+			totals.Wins = totals.Wins.Add(transaction.Amount)
+		case transaction.State == entities.StateLose:
+			totals.Bets = totals.Bets.Add(transaction.Amount)
+		}
+		totals.Transactions++
+	}
+	return totals, nil
+}
+
+// SummarizeDays totals the user's uncancelled non-fee transactions in a wallet
+// currency that occurred in [from, to) by UTC day, source type and state.
+// Transactions without a business time count when they were created.
+func (r *TransactionRepository) SummarizeDays(
+	ctx context.Context,
+	userID uint64,
+	currency string,
+	from, to time.Time,
+) ([]repositories.DayTotals, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	type key struct {
+		day        time.Time
+		sourceType entities.SourceType
+		state      entities.TransactionState
+	}
+	totals := make(map[key]*repositories.DayTotals)
+	for _, transaction := range r.store.transactions {
+		occurredAt := transaction.BusinessTime().UTC()
+		if transaction.UserID != userID || transaction.Currency != currency || transaction.Cancelled ||
+			transaction.Type == entities.TransactionTypeFee ||
+			occurredAt.Before(from) || !occurredAt.Before(to) {
+			continue
+		}
+		k := key{
+			day:        time.Date(occurredAt.Year(), occurredAt.Month(), occurredAt.Day(), 0, 0, 0, 0, time.UTC),
+			sourceType: transaction.SourceType,
+			state:      transaction.State,
+		}
+		if totals[k] == nil {
+			totals[k] = &repositories.DayTotals{Day: k.day, SourceType: k.sourceType, State: k.state, Amount: decimal.Zero}
+		}
+		totals[k].Count++
+		totals[k].Amount = totals[k].Amount.Add(transaction.Amount)
+	}
+
+	days := make([]repositories.DayTotals, 0, len(totals))
+	for _, day := range totals {
+		days = append(days, *day)
+	}
+	sort.Slice(days, func(i, j int) bool {
+		if !days[i].Day.Equal(days[j].Day) {
+			return days[i].Day.Before(days[j].Day)
+		}
+		if days[i].SourceType != days[j].SourceType {
+			return days[i].SourceType < days[j].SourceType
+		}
+		return days[i].State < days[j].State
+	})
+	return days, nil
+}
+
+// LockLatestOddUncancelled returns the newest uncancelled odd-ID transactions
+// that are neither refunds, refunded, transfer legs, fees, adjustments nor
+// pending
+func (r *TransactionRepository) LockLatestOddUncancelled(ctx context.Context, limit int) ([]*entities.Transaction, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	transactions := r.matching(func(transaction *entities.Transaction) bool {
+		return !transaction.Cancelled && !transaction.Pending && transaction.ID%2 == 1 &&
+			transaction.Type != entities.TransactionTypeFee && transaction.Type != entities.TransactionTypeAdjustment &&
+			transaction.Reverses == "" && transaction.ReversedBy == "" && transaction.TransferID == ""
+	})
+	sort.Slice(transactions, func(i, j int) bool { return transactions[i].ID > transactions[j].ID })
+	return transactions[:min(limit, len(transactions))], nil
+}
+
+// CheckUniqueIDs checks the transactions created in [from, to) for transaction
+// IDs recorded more than once. The store indexes transactions by their
+// transaction ID, so the check always finds it enforced and no duplicates.
+func (r *TransactionRepository) CheckUniqueIDs(ctx context.Context, from, to time.Time, limit int) (*repositories.UniquenessCheck, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	check := &repositories.UniquenessCheck{Enforced: true}
+	for _, transaction := range r.store.transactions {
+		if !transaction.CreatedAt.Before(from) && transaction.CreatedAt.Before(to) {
+			check.Transactions++
+		}
+	}
+	check.DistinctIDs = check.Transactions
+	return check, nil
+}
+
+// update applies change to the transaction with id when it is accepted by
+// eligible, undoing it if the ambient unit of work fails
+func (r *TransactionRepository) update(
+	ctx context.Context,
+	id uint64,
+	eligible func(transaction *entities.Transaction) bool,
+	change func(transaction *entities.Transaction),
+) error {
+	return r.store.write(ctx, func() error {
+		transaction, ok := r.store.transactions[id]
+		if !ok || !eligible(transaction) {
+			return fmt.Errorf("transaction %d: %w", id, repositories.ErrNotFound)
+		}
+		previous := *transaction
+		r.store.record(ctx, func() {
+			*transaction = previous
+		})
+		change(transaction)
+		return nil
+	})
+}
+
+// MarkCancelled flags a transaction as cancelled
+func (r *TransactionRepository) MarkCancelled(ctx context.Context, id uint64, cancelledAt time.Time) error {
+	return r.update(ctx, id, func(transaction *entities.Transaction) bool {
+		return !transaction.Cancelled
+	}, func(transaction *entities.Transaction) {
+		transaction.Cancelled = true
+		transaction.CancelledAt = &cancelledAt
+	})
+}
+
+// MarkReversed links a transaction to the refund reversing it
+func (r *TransactionRepository) MarkReversed(ctx context.Context, id uint64, reversedBy string) error {
+	return r.update(ctx, id, func(transaction *entities.Transaction) bool {
+		return transaction.ReversedBy == ""
+	}, func(transaction *entities.Transaction) {
+		transaction.ReversedBy = reversedBy
+	})
+}
+
+// SumPending returns the total of a user's uncancelled base currency
+// transactions awaiting settlement
+func (r *TransactionRepository) SumPending(ctx context.Context, userID uint64) (decimal.Decimal, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	pending := decimal.Zero
+	for _, transaction := range r.store.transactions {
+		if transaction.UserID == userID && transaction.Pending && !transaction.Cancelled && transaction.Currency == "" {
+			pending = pending.Add(transaction.Amount)
+		}
+	}
+	return pending, nil
+}
+
+// ListPendingBefore returns up to limit of the oldest pending transactions
+// created before before
+func (r *TransactionRepository) ListPendingBefore(ctx context.Context, before time.Time, limit int) ([]*entities.Transaction, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	transactions := r.matching(func(transaction *entities.Transaction) bool {
+		return transaction.Pending && !transaction.Cancelled && transaction.CreatedAt.Before(before)
+	})
+	sort.Slice(transactions, func(i, j int) bool {
+		if !transactions[i].CreatedAt.Equal(transactions[j].CreatedAt) {
+			return transactions[i].CreatedAt.Before(transactions[j].CreatedAt)
+		}
+		return transactions[i].ID < transactions[j].ID
+	})
+	return transactions[:min(limit, len(transactions))], nil
+}
+
+// LockPendingBefore is ListPendingBefore. Units of work run one at a time, so
+// the transactions stay as they are until the ambient unit of work ends.
+func (r *TransactionRepository) LockPendingBefore(ctx context.Context, before time.Time, limit int) ([]*entities.Transaction, error) {
+	return r.ListPendingBefore(ctx, before, limit)
+}
+
+// MarkSettled records the settlement of a pending transaction
+func (r *TransactionRepository) MarkSettled(ctx context.Context, id uint64, settledAt time.Time) error {
+	return r.update(ctx, id, func(transaction *entities.Transaction) bool {
+		return transaction.Pending
+	}, func(transaction *entities.Transaction) {
+		transaction.Pending = false
+		transaction.SettledAt = &settledAt
+	})
+}
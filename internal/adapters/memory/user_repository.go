@@ -0,0 +1,166 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/shopspring/decimal"
+)
+
+// UserRepository implements the user repository interface on top of a Store
+type UserRepository struct {
+	store *Store
+}
+
+// NewUserRepository creates a new UserRepository
+func NewUserRepository(store *Store) *UserRepository {
+	return &UserRepository{store: store}
+}
+
+// GetByID retrieves a user by their ID
+func (r *UserRepository) GetByID(ctx context.Context, userID uint64) (*entities.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	record, ok := r.store.users[userID]
+	if !ok {
+		return nil, fmt.Errorf("user with ID %d: %w", userID, repositories.ErrNotFound)
+	}
+	user := record.user
+	return &user, nil
+}
+
+// GetByIDForUpdate retrieves a user by their ID. Units of work run one at a
+// time, so the user stays as it is until the ambient unit of work ends.
+func (r *UserRepository) GetByIDForUpdate(ctx context.Context, userID uint64) (*entities.User, error) {
+	return r.GetByID(ctx, userID)
+}
+
+// update applies change to the user with userID, undoing it if the ambient
+// unit of work fails
+func (r *UserRepository) update(ctx context.Context, userID uint64, change func(user *entities.User)) error {
+	return r.store.write(ctx, func() error {
+		record, ok := r.store.users[userID]
+		if !ok {
+			return fmt.Errorf("user with ID %d: %w", userID, repositories.ErrNotFound)
+		}
+		previous := record.user
+		r.store.record(ctx, func() {
+			record.user = previous
+		})
+		change(&record.user)
+		return nil
+	})
+}
+
+// UpdateBalance updates the user's balance
+func (r *UserRepository) UpdateBalance(ctx context.Context, userID uint64, newBalance decimal.Decimal) error {
+	return r.update(ctx, userID, func(user *entities.User) {
+		user.Balance = newBalance
+	})
+}
+
+// Create creates a new active user. A zero ID is allocated past the highest
+// ID, and like a sequence is not given back by a failed unit of work. An ID
+// in use is rejected with ErrConflict.
+func (r *UserRepository) Create(ctx context.Context, user *entities.User) error {
+	return r.store.write(ctx, func() error {
+		id := user.ID
+		if id == 0 {
+			id = r.store.lastUserID + 1
+		}
+		if _, ok := r.store.users[id]; ok {
+			return conflict("user with ID %d", id)
+		}
+
+		r.store.users[id] = &userRecord{
+			user:      entities.User{ID: id, Balance: user.Balance, Status: entities.UserStatusActive},
+			createdAt: r.store.now(),
+		}
+		r.store.lastUserID = max(r.store.lastUserID, id)
+		r.store.record(ctx, func() {
+			delete(r.store.users, id)
+		})
+
+		user.ID = id
+		user.Status = entities.UserStatusActive
+		return nil
+	})
+}
+
+// sortedUsers returns copies of the users accepted by keep, ordered by ID.
+// r.store.mu must be held.
+func (r *UserRepository) sortedUsers(keep func(record *userRecord) bool) []*entities.User {
+	users := make([]*entities.User, 0, len(r.store.users))
+	for _, record := range r.store.users {
+		if keep(record) {
+			user := record.user
+			users = append(users, &user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users
+}
+
+// List retrieves a page of users ordered by ID
+func (r *UserRepository) List(ctx context.Context, limit, offset int) ([]*entities.User, int, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	users := r.sortedUsers(func(*userRecord) bool { return true })
+	total := len(users)
+	return users[min(offset, total):min(offset+limit, total)], total, nil
+}
+
+// UpdateStatus updates the user's account status
+func (r *UserRepository) UpdateStatus(ctx context.Context, userID uint64, status entities.UserStatus) error {
+	return r.update(ctx, userID, func(user *entities.User) {
+		user.Status = status
+	})
+}
+
+// UpdateJurisdiction sets the user's jurisdiction; an empty value clears it
+func (r *UserRepository) UpdateJurisdiction(ctx context.Context, userID uint64, jurisdiction string) error {
+	return r.update(ctx, userID, func(user *entities.User) {
+		user.Jurisdiction = jurisdiction
+	})
+}
+
+// UpdateCreditLimit sets how far below zero the user's balance may go
+func (r *UserRepository) UpdateCreditLimit(ctx context.Context, userID uint64, creditLimit decimal.Decimal) error {
+	return r.update(ctx, userID, func(user *entities.User) {
+		user.CreditLimit = creditLimit
+	})
+}
+
+// LockIdleSince returns up to limit active users that are not dormant, were
+// created before since and have no transaction created at or after since
+func (r *UserRepository) LockIdleSince(ctx context.Context, since time.Time, limit int) ([]*entities.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	active := make(map[uint64]bool)
+	for _, transaction := range r.store.transactions {
+		if !transaction.CreatedAt.Before(since) {
+			active[transaction.UserID] = true
+		}
+	}
+
+	users := r.sortedUsers(func(record *userRecord) bool {
+		return record.user.Status == entities.UserStatusActive && record.user.DormantAt == nil &&
+			record.createdAt.Before(since) && !active[record.user.ID]
+	})
+	return users[:min(limit, len(users))], nil
+}
+
+// UpdateDormancy flags the user as dormant at dormantAt; nil clears the flag
+func (r *UserRepository) UpdateDormancy(ctx context.Context, userID uint64, dormantAt *time.Time) error {
+	return r.update(ctx, userID, func(user *entities.User) {
+		user.DormantAt = dormantAt
+	})
+}
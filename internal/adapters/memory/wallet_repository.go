@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/shopspring/decimal"
+)
+
+// WalletRepository implements the wallet repository interface on top of a
+// Store
+type WalletRepository struct {
+	store *Store
+}
+
+// NewWalletRepository creates a new WalletRepository
+func NewWalletRepository(store *Store) *WalletRepository {
+	return &WalletRepository{store: store}
+}
+
+// GetForUpdate retrieves the user's wallet in currency, creating an empty one
+// first if needed
+func (r *WalletRepository) GetForUpdate(ctx context.Context, userID uint64, currency string) (*entities.Wallet, error) {
+	var copied entities.Wallet
+	err := r.store.write(ctx, func() error {
+		key := walletKey{userID: userID, currency: currency}
+		wallet, ok := r.store.wallets[key]
+		if !ok {
+			wallet = &entities.Wallet{UserID: userID, Currency: currency, Balance: decimal.Zero}
+			r.store.wallets[key] = wallet
+			r.store.record(ctx, func() {
+				delete(r.store.wallets, key)
+			})
+		}
+		copied = *wallet
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &copied, nil
+}
+
+// UpdateBalance updates the balance of the user's wallet in currency
+func (r *WalletRepository) UpdateBalance(ctx context.Context, userID uint64, currency string, newBalance decimal.Decimal) error {
+	return r.store.write(ctx, func() error {
+		wallet, ok := r.store.wallets[walletKey{userID: userID, currency: currency}]
+		if !ok {
+			return fmt.Errorf("wallet %s of user %d: %w", currency, userID, repositories.ErrNotFound)
+		}
+		previous := wallet.Balance
+		r.store.record(ctx, func() {
+			wallet.Balance = previous
+		})
+		wallet.Balance = newBalance
+		return nil
+	})
+}
+
+// ListByUser retrieves the user's wallets ordered by currency
+func (r *WalletRepository) ListByUser(ctx context.Context, userID uint64) ([]*entities.Wallet, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var wallets []*entities.Wallet
+	for key, wallet := range r.store.wallets {
+		if key.userID == userID {
+			copied := *wallet
+			wallets = append(wallets, &copied)
+		}
+	}
+	sort.Slice(wallets, func(i, j int) bool { return wallets[i].Currency < wallets[j].Currency })
+	return wallets, nil
+}
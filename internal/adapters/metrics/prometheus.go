@@ -0,0 +1,181 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"transaction-service/internal/apiversion"
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/domain/entities"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Prometheus collects the service metrics in its own registry
+type Prometheus struct {
+	registry *prometheus.Registry
+
+	processed  *prometheus.CounterVec
+	duplicates *prometheus.CounterVec
+	failed     *prometheus.CounterVec
+	latency    *prometheus.HistogramVec
+
+	outboundLatency *prometheus.HistogramVec
+	outboundRetries *prometheus.CounterVec
+
+	shadowRuns *prometheus.CounterVec
+
+	databaseRetries *prometheus.CounterVec
+
+	slos *SLOTracker
+}
+
+// NewPrometheus creates the metrics and registers them, together with Go
+// runtime and database pool metrics for db unless it is nil
+func NewPrometheus(db *sql.DB) *Prometheus {
+	p := &Prometheus{
+		registry: prometheus.NewRegistry(),
+		processed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "transactions_processed_total",
+			Help: "Transactions applied to a user's balance.",
+		}, []string{"source_type", "state"}),
+		duplicates: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "transactions_duplicate_total",
+			Help: "Replayed transactions and rejected reused transaction IDs.",
+		}, []string{"source_type", "state", "outcome"}),
+		failed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "transactions_failed_total",
+			Help: "Transactions that were rejected or failed.",
+		}, []string{"source_type", "state", "reason"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP handler latency.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		outboundLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "outbound_http_request_duration_seconds",
+			Help:    "Latency of outbound HTTP attempts; status is \"error\" when there was no response.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"destination", "status"}),
+		outboundRetries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "outbound_http_retries_total",
+			Help: "Failed outbound HTTP attempts that were retried or denied a retry by the retry budget.",
+		}, []string{"destination", "outcome"}),
+		shadowRuns: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "shadow_runs_total",
+			Help: "Requests also run through the candidate engine, by how its result compared.",
+		}, []string{"operation", "outcome"}),
+		databaseRetries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "database_retries_total",
+			Help: "Database operations retried after a transient error, by whether it was a serialization failure.",
+		}, []string{"operation", "reason"}),
+	}
+
+	p.registry.MustRegister(
+		p.processed,
+		p.duplicates,
+		p.failed,
+		p.latency,
+		p.outboundLatency,
+		p.outboundRetries,
+		p.shadowRuns,
+		p.databaseRetries,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	if db != nil {
+		p.registry.MustRegister(collectors.NewDBStatsCollector(db, "postgres"))
+	}
+
+	return p
+}
+
+// TransactionProcessed implements services.TransactionMetrics
+func (p *Prometheus) TransactionProcessed(sourceType entities.SourceType, state entities.TransactionState) {
+	p.processed.WithLabelValues(string(sourceType), string(state)).Inc()
+}
+
+// TransactionDuplicate implements services.TransactionMetrics
+func (p *Prometheus) TransactionDuplicate(sourceType entities.SourceType, state entities.TransactionState, replayed bool) {
+	outcome := "rejected"
+	if replayed {
+		outcome = "replayed"
+	}
+	p.duplicates.WithLabelValues(string(sourceType), string(state), outcome).Inc()
+}
+
+// TransactionFailed implements services.TransactionMetrics
+func (p *Prometheus) TransactionFailed(sourceType entities.SourceType, state entities.TransactionState, reason string) {
+	p.failed.WithLabelValues(string(sourceType), string(state), reason).Inc()
+}
+
+// OutboundRequest implements httpclient.Metrics
+func (p *Prometheus) OutboundRequest(destination string, status int, duration time.Duration) {
+	label := "error"
+	if status != 0 {
+		label = strconv.Itoa(status)
+	}
+	p.outboundLatency.WithLabelValues(destination, label).Observe(duration.Seconds())
+}
+
+// OutboundRetry implements httpclient.Metrics
+func (p *Prometheus) OutboundRetry(destination string, allowed bool) {
+	outcome := "budget_exhausted"
+	if allowed {
+		outcome = "retried"
+	}
+	p.outboundRetries.WithLabelValues(destination, outcome).Inc()
+}
+
+// ShadowCompared implements services.ShadowMetrics
+func (p *Prometheus) ShadowCompared(operation string, outcome services.ShadowOutcome) {
+	p.shadowRuns.WithLabelValues(operation, string(outcome)).Inc()
+}
+
+// DatabaseRetry implements database.RetryMetrics
+func (p *Prometheus) DatabaseRetry(operation string, serialization bool) {
+	reason := "transient"
+	if serialization {
+		reason = "serialization_failure"
+	}
+	p.databaseRetries.WithLabelValues(operation, reason).Inc()
+}
+
+// TrackSLOs feeds the requests the middleware observes to the tracker and
+// exports its burn rates and error budgets
+func (p *Prometheus) TrackSLOs(tracker *SLOTracker) {
+	p.slos = tracker
+	p.registry.MustRegister(tracker)
+}
+
+// Middleware records the latency of every request by route template, so
+// path parameters such as user IDs do not create new series
+func (p *Prometheus) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		duration := time.Since(start)
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		p.latency.WithLabelValues(
+			c.Request.Method, route, strconv.Itoa(c.Writer.Status()),
+		).Observe(duration.Seconds())
+		// Objectives hold for a route in every version of the API
+		if p.slos != nil {
+			p.slos.Observe(c.Request.Method, apiversion.Strip(route), c.Writer.Status(), duration)
+		}
+	}
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func (p *Prometheus) Handler() http.Handler {
+	return promhttp.HandlerFor(p.registry, promhttp.HandlerOpts{})
+}
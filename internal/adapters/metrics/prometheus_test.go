@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/domain/entities"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrometheus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// sql.Open does not connect, which is enough for the pool stats collector
+	db, err := sql.Open("pgx", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	p := NewPrometheus(db)
+	p.TransactionProcessed(entities.SourceTypeGame, entities.StateWin)
+	p.TransactionProcessed(entities.SourceTypeGame, entities.StateWin)
+	p.TransactionDuplicate(entities.SourceTypePayment, entities.StateLose, true)
+	p.TransactionFailed(entities.SourceTypeGame, entities.StateLose, "insufficient_funds")
+	p.OutboundRequest("webhooks", http.StatusServiceUnavailable, time.Second)
+	p.OutboundRequest("webhooks", 0, time.Second)
+	p.OutboundRetry("webhooks", true)
+	p.ShadowCompared(services.ShadowOperationProcess, services.ShadowMismatch)
+	p.DatabaseRetry("unit of work", true)
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(p.processed.WithLabelValues("game", "win")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(p.duplicates.WithLabelValues("payment", "lose", "replayed")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(p.failed.WithLabelValues("game", "lose", "insufficient_funds")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(p.outboundRetries.WithLabelValues("webhooks", "retried")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(p.shadowRuns.WithLabelValues("process_transaction", "mismatch")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(p.databaseRetries.WithLabelValues("unit of work", "serialization_failure")))
+
+	router := gin.New()
+	router.Use(p.Middleware())
+	router.GET("/user/:userId/balance", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/metrics", gin.WrapH(p.Handler()))
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/user/42/balance", nil))
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := recorder.Body.String()
+
+	assert.Contains(t, body, `http_request_duration_seconds_count{method="GET",route="/user/:userId/balance",status="200"} 1`)
+	assert.Contains(t, body, "go_sql_open_connections")
+	assert.Contains(t, body, `outbound_http_request_duration_seconds_count{destination="webhooks",status="503"} 1`)
+	assert.Contains(t, body, `outbound_http_request_duration_seconds_count{destination="webhooks",status="error"} 1`)
+	assert.False(t, strings.Contains(body, "/user/42/balance"))
+}
@@ -0,0 +1,234 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"transaction-service/internal/config"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// BurnRateWindows are the windows burn rates are reported over: the short
+// ones catch fast burns, the long one slow leaks
+var BurnRateWindows = []struct {
+	Name     string
+	Duration time.Duration
+}{
+	{"5m", 5 * time.Minute},
+	{"1h", time.Hour},
+	{"6h", 6 * time.Hour},
+}
+
+// The SLIs of an objective
+const (
+	SLIAvailability = "availability"
+	SLILatency      = "latency"
+)
+
+// SLOReport is the state of every objective
+type SLOReport struct {
+	Window     string      `json:"window"`
+	Objectives []SLOStatus `json:"objectives"`
+}
+
+// SLOStatus is how an SLI of a route fares against its target over the
+// window. Bad requests are the 5xx responses for availability and the
+// responses slower than Threshold for latency.
+type SLOStatus struct {
+	Route     string  `json:"route"`
+	SLI       string  `json:"sli"`
+	Target    float64 `json:"target"`
+	Threshold string  `json:"threshold,omitempty"`
+	Requests  uint64  `json:"requests"`
+	Bad       uint64  `json:"bad"`
+	// Attainment is the percentage of good requests, 100 without requests
+	Attainment float64 `json:"attainment"`
+	// BudgetRemaining is the share of the error budget left, negative once
+	// it is overspent
+	BudgetRemaining float64 `json:"budgetRemaining"`
+	// BurnRates are how fast the budget is spent over each of the
+	// BurnRateWindows; 1 spends exactly the budget over the window
+	BurnRates    map[string]float64 `json:"burnRates"`
+	WithinBudget bool               `json:"withinBudget"`
+}
+
+// sloBucket counts the requests of a route started in a time slot
+type sloBucket struct {
+	start int64
+	total uint64
+	// errors are 5xx responses, slow the responses over the latency threshold
+	errors uint64
+	slow   uint64
+}
+
+// sloRing keeps the buckets of a span of time, reusing the slots of buckets
+// that fell out of it
+type sloRing struct {
+	width   time.Duration
+	buckets []sloBucket
+}
+
+func newSLORing(width, span time.Duration) *sloRing {
+	return &sloRing{width: width, buckets: make([]sloBucket, int(span/width)+1)}
+}
+
+func (r *sloRing) add(now time.Time, failed, slow bool) {
+	slot := now.Unix() / int64(r.width/time.Second)
+	bucket := &r.buckets[slot%int64(len(r.buckets))]
+	if bucket.start != slot {
+		*bucket = sloBucket{start: slot}
+	}
+	bucket.total++
+	if failed {
+		bucket.errors++
+	}
+	if slow {
+		bucket.slow++
+	}
+}
+
+// sum adds up the buckets of the last span, the current one included
+func (r *sloRing) sum(now time.Time, span time.Duration) sloBucket {
+	width := int64(r.width / time.Second)
+	current := now.Unix() / width
+	oldest := current - int64(span/r.width) + 1
+
+	var total sloBucket
+	for _, bucket := range r.buckets {
+		if bucket.start >= oldest && bucket.start <= current {
+			total.total += bucket.total
+			total.errors += bucket.errors
+			total.slow += bucket.slow
+		}
+	}
+	return total
+}
+
+// routeSLO is the objective of a route and the requests it served: by
+// minute for the burn rates and by hour for the window
+type routeSLO struct {
+	objective config.SLOObjective
+	minutes   *sloRing
+	hours     *sloRing
+}
+
+// SLOTracker evaluates the objectives of the routes from the requests the
+// metrics middleware observes. Counts are kept in memory by each instance.
+type SLOTracker struct {
+	mu     sync.Mutex
+	window time.Duration
+	routes map[string]*routeSLO
+	// order lists the routes in configuration order
+	order []string
+	now   func() time.Time
+
+	budgetDesc   *prometheus.Desc
+	burnRateDesc *prometheus.Desc
+}
+
+// NewSLOTracker creates a tracker of the configured objectives
+func NewSLOTracker(cfg config.SLOConfig) *SLOTracker {
+	t := &SLOTracker{
+		window: cfg.Window,
+		routes: make(map[string]*routeSLO, len(cfg.Objectives)),
+		now:    time.Now,
+		budgetDesc: prometheus.NewDesc("slo_error_budget_remaining",
+			"Share of the error budget left over the SLO window.", []string{"route", "sli"}, nil),
+		burnRateDesc: prometheus.NewDesc("slo_burn_rate",
+			"Rate the error budget is spent at; 1 spends exactly the budget over the SLO window.",
+			[]string{"route", "sli", "window"}, nil),
+	}
+	longest := BurnRateWindows[len(BurnRateWindows)-1].Duration
+	for _, objective := range cfg.Objectives {
+		t.routes[objective.Route] = &routeSLO{
+			objective: objective,
+			minutes:   newSLORing(time.Minute, longest),
+			hours:     newSLORing(time.Hour, cfg.Window),
+		}
+		t.order = append(t.order, objective.Route)
+	}
+	return t
+}
+
+// Observe counts a request to a route template against its objective, if it
+// has one
+func (t *SLOTracker) Observe(method, route string, status int, duration time.Duration) {
+	slo, ok := t.routes[method+" "+route]
+	if !ok {
+		return
+	}
+	failed := status >= 500
+	slow := duration > slo.objective.Latency
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	slo.minutes.add(now, failed, slow)
+	slo.hours.add(now, failed, slow)
+}
+
+// Report evaluates every objective
+func (t *SLOTracker) Report() SLOReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+
+	report := SLOReport{Window: t.window.String(), Objectives: []SLOStatus{}}
+	for _, route := range t.order {
+		slo := t.routes[route]
+		windowed := slo.hours.sum(now, t.window)
+		recent := make(map[string]sloBucket, len(BurnRateWindows))
+		for _, window := range BurnRateWindows {
+			recent[window.Name] = slo.minutes.sum(now, window.Duration)
+		}
+
+		for _, sli := range []string{SLIAvailability, SLILatency} {
+			status := SLOStatus{Route: route, SLI: sli, Target: slo.objective.Availability}
+			bad := func(b sloBucket) uint64 { return b.errors }
+			if sli == SLILatency {
+				status.Target = slo.objective.LatencyTarget
+				status.Threshold = slo.objective.Latency.String()
+				bad = func(b sloBucket) uint64 { return b.slow }
+			}
+			budget := 1 - status.Target/100
+
+			status.Requests, status.Bad = windowed.total, bad(windowed)
+			status.Attainment, status.BudgetRemaining = 100, 1
+			if status.Requests > 0 {
+				badRatio := float64(status.Bad) / float64(status.Requests)
+				status.Attainment = 100 * (1 - badRatio)
+				status.BudgetRemaining = 1 - badRatio/budget
+			}
+			status.WithinBudget = status.BudgetRemaining > 0
+
+			status.BurnRates = make(map[string]float64, len(BurnRateWindows))
+			for _, window := range BurnRateWindows {
+				if b := recent[window.Name]; b.total > 0 {
+					status.BurnRates[window.Name] = float64(bad(b)) / float64(b.total) / budget
+				} else {
+					status.BurnRates[window.Name] = 0
+				}
+			}
+			report.Objectives = append(report.Objectives, status)
+		}
+	}
+	return report
+}
+
+// Describe implements prometheus.Collector
+func (t *SLOTracker) Describe(ch chan<- *prometheus.Desc) {
+	ch <- t.budgetDesc
+	ch <- t.burnRateDesc
+}
+
+// Collect implements prometheus.Collector, evaluating the objectives on
+// every scrape
+func (t *SLOTracker) Collect(ch chan<- prometheus.Metric) {
+	for _, status := range t.Report().Objectives {
+		ch <- prometheus.MustNewConstMetric(t.budgetDesc, prometheus.GaugeValue, status.BudgetRemaining, status.Route, status.SLI)
+		for window, rate := range status.BurnRates {
+			ch <- prometheus.MustNewConstMetric(t.burnRateDesc, prometheus.GaugeValue, rate, status.Route, status.SLI, window)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"transaction-service/internal/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSLOTracker(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	tracker := NewSLOTracker(config.SLOConfig{
+		Window: 24 * time.Hour,
+		Objectives: []config.SLOObjective{{
+			Route: "GET /user/:userId/balance", Availability: 99, Latency: 100 * time.Millisecond, LatencyTarget: 90,
+		}},
+	})
+
+	// 7 hours ago: counted in the window, but by none of the burn rates
+	tracker.now = func() time.Time { return now.Add(-7 * time.Hour) }
+	for i := 0; i < 100; i++ {
+		tracker.Observe(http.MethodGet, "/user/:userId/balance", http.StatusOK, time.Millisecond)
+	}
+	tracker.now = func() time.Time { return now }
+	for i := 0; i < 98; i++ {
+		tracker.Observe(http.MethodGet, "/user/:userId/balance", http.StatusOK, time.Millisecond)
+	}
+	tracker.Observe(http.MethodGet, "/user/:userId/balance", http.StatusInternalServerError, time.Millisecond)
+	tracker.Observe(http.MethodGet, "/user/:userId/balance", http.StatusOK, time.Second)
+	// Routes without an objective are not tracked
+	tracker.Observe(http.MethodPost, "/user/:userId/balance", http.StatusInternalServerError, time.Millisecond)
+
+	report := tracker.Report()
+	assert.Equal(t, "24h0m0s", report.Window)
+	require.Len(t, report.Objectives, 2)
+
+	availability := report.Objectives[0]
+	assert.Equal(t, SLIAvailability, availability.SLI)
+	assert.Equal(t, uint64(200), availability.Requests)
+	assert.Equal(t, uint64(1), availability.Bad)
+	assert.InDelta(t, 99.5, availability.Attainment, 1e-9)
+	assert.InDelta(t, 0.5, availability.BudgetRemaining, 1e-9)
+	assert.InDelta(t, 1.0, availability.BurnRates["5m"], 1e-9)
+	assert.InDelta(t, 1.0, availability.BurnRates["6h"], 1e-9)
+	assert.True(t, availability.WithinBudget)
+
+	latency := report.Objectives[1]
+	assert.Equal(t, SLILatency, latency.SLI)
+	assert.Equal(t, "100ms", latency.Threshold)
+	assert.Equal(t, uint64(1), latency.Bad)
+	assert.InDelta(t, 0.1, latency.BurnRates["1h"], 1e-9)
+
+	// Once the window has passed, the old requests no longer count
+	tracker.now = func() time.Time { return now.Add(18 * time.Hour) }
+	report = tracker.Report()
+	assert.Equal(t, uint64(100), report.Objectives[0].Requests)
+	assert.Zero(t, report.Objectives[0].BurnRates["5m"])
+
+	tracker.now = func() time.Time { return now.Add(25 * time.Hour) }
+	report = tracker.Report()
+	assert.Zero(t, report.Objectives[0].Requests)
+	assert.Equal(t, 100.0, report.Objectives[0].Attainment)
+	assert.Equal(t, 1.0, report.Objectives[0].BudgetRemaining)
+}
+
+func TestSLOTracker_Exhausted(t *testing.T) {
+	tracker := NewSLOTracker(config.SLOConfig{
+		Window: time.Hour,
+		Objectives: []config.SLOObjective{{
+			Route: "GET /ping", Availability: 99.9, Latency: time.Second, LatencyTarget: 99,
+		}},
+	})
+
+	tracker.Observe(http.MethodGet, "/ping", http.StatusOK, time.Millisecond)
+	tracker.Observe(http.MethodGet, "/ping", http.StatusServiceUnavailable, time.Millisecond)
+
+	availability := tracker.Report().Objectives[0]
+	assert.Less(t, availability.BudgetRemaining, 0.0)
+	assert.False(t, availability.WithinBudget)
+	assert.InDelta(t, 500.0, availability.BurnRates["5m"], 1e-6)
+}
+
+func TestPrometheus_TrackSLOs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	p := NewPrometheus(nil)
+	p.TrackSLOs(NewSLOTracker(config.SLOConfig{
+		Window: time.Hour,
+		Objectives: []config.SLOObjective{{
+			Route: "GET /user/:userId/balance", Availability: 99.9, Latency: time.Second, LatencyTarget: 99,
+		}},
+	}))
+
+	router := gin.New()
+	router.Use(p.Middleware())
+	router.GET("/user/:userId/balance", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+	router.GET("/metrics", gin.WrapH(p.Handler()))
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/user/42/balance", nil))
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := recorder.Body.String()
+
+	assert.Contains(t, body, `slo_burn_rate{route="GET /user/:userId/balance",sli="availability",window="5m"} 1000`)
+	assert.Contains(t, body, `slo_error_budget_remaining{route="GET /user/:userId/balance",sli="latency"} 1`)
+}
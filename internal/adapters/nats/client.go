@@ -0,0 +1,141 @@
+package nats
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// Client binds a nats.go connection and its JetStream context to JetStream,
+// and hands out the messages of durable consumers as a MessageSource
+type Client struct {
+	conn *natsgo.Conn
+	js   jetstream.JetStream
+}
+
+// Connect connects to the servers at url. A server that is unreachable at
+// first is retried, like a lost connection, until the client is closed.
+func Connect(url string) (*Client, error) {
+	conn, err := natsgo.Connect(url,
+		natsgo.Name("transaction-service"),
+		natsgo.RetryOnFailedConnect(true),
+		natsgo.MaxReconnects(-1),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to nats: %w", err)
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create jetstream context: %w", err)
+	}
+	return &Client{conn: conn, js: js}, nil
+}
+
+// PublishMsg implements JetStream
+func (c *Client) PublishMsg(ctx context.Context, msg *Message) error {
+	_, err := c.js.PublishMsg(ctx, &natsgo.Msg{
+		Subject: msg.Subject,
+		Header:  natsgo.Header(msg.Header),
+		Data:    msg.Data,
+	})
+	return err
+}
+
+// Messages returns the messages of the durable consumer of stream configured
+// by config. The consumer is created or updated on the stream, which must
+// exist, once messages are first fetched.
+func (c *Client) Messages(stream string, config ConsumerConfig) *ClientMessages {
+	return &ClientMessages{js: c.js, stream: stream, config: jetstream.ConsumerConfig{
+		Durable:        config.Durable,
+		FilterSubjects: config.FilterSubjects,
+		AckPolicy:      jetstream.AckExplicitPolicy,
+		AckWait:        config.AckWait,
+		MaxDeliver:     config.MaxDeliver,
+		MaxAckPending:  config.MaxAckPending,
+	}}
+}
+
+// Close closes the connection. Messages delivered but not acknowledged are
+// redelivered once their ack wait expires.
+func (c *Client) Close() error {
+	c.conn.Close()
+	return nil
+}
+
+// ClientMessages binds the messages of a durable pull consumer to
+// MessageSource. It is not safe for concurrent fetches.
+type ClientMessages struct {
+	js     jetstream.JetStream
+	stream string
+	config jetstream.ConsumerConfig
+
+	// messages is nil until the consumer was created, and again once
+	// fetching from it failed
+	messages jetstream.MessagesContext
+}
+
+// Next implements MessageSource
+func (m *ClientMessages) Next(ctx context.Context) (Msg, error) {
+	if m.messages == nil {
+		consumer, err := m.js.CreateOrUpdateConsumer(ctx, m.stream, m.config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create consumer: %w", err)
+		}
+		messages, err := consumer.Messages()
+		if err != nil {
+			return nil, fmt.Errorf("failed to consume: %w", err)
+		}
+		m.messages = messages
+	}
+
+	msg, err := m.messages.Next(jetstream.NextContext(ctx))
+	if err != nil {
+		// A failed iterator, e.g. of a deleted consumer, is replaced by the
+		// next fetch
+		if ctx.Err() == nil {
+			m.stop()
+		}
+		return nil, err
+	}
+	return clientMsg{msg: msg}, nil
+}
+
+// stop stops fetching messages
+func (m *ClientMessages) stop() {
+	if m.messages != nil {
+		m.messages.Stop()
+		m.messages = nil
+	}
+}
+
+// clientMsg binds a jetstream.Msg to Msg
+type clientMsg struct {
+	msg jetstream.Msg
+}
+
+func (m clientMsg) Subject() string { return m.msg.Subject() }
+func (m clientMsg) Data() []byte    { return m.msg.Data() }
+func (m clientMsg) Headers() Header { return Header(m.msg.Headers()) }
+
+func (m clientMsg) Metadata() (*Metadata, error) {
+	meta, err := m.msg.Metadata()
+	if err != nil {
+		return nil, err
+	}
+	return &Metadata{
+		Stream:         meta.Stream,
+		Consumer:       meta.Consumer,
+		StreamSequence: meta.Sequence.Stream,
+		NumDelivered:   meta.NumDelivered,
+	}, nil
+}
+
+func (m clientMsg) Ack() error                             { return m.msg.Ack() }
+func (m clientMsg) Nak() error                             { return m.msg.Nak() }
+func (m clientMsg) NakWithDelay(delay time.Duration) error { return m.msg.NakWithDelay(delay) }
+func (m clientMsg) Term() error                            { return m.msg.Term() }
+func (m clientMsg) InProgress() error                      { return m.msg.InProgress() }
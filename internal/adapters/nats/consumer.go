@@ -0,0 +1,493 @@
+// Package nats ingests the transaction commands game servers publish to NATS
+// JetStream and publishes the balance change events of the outbox.
+//
+// The adapters depend on the MessageSource, Msg and JetStream ports instead
+// of a client library. They are modelled on the jetstream package of nats.go,
+// which Client binds to them by converting the metadata of its messages.
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"transaction-service/internal/adapters/ingest"
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/health"
+	"transaction-service/internal/processor"
+
+	"github.com/rs/zerolog"
+)
+
+// Header is a NATS message header
+type Header map[string][]string
+
+// Message is a message to publish
+type Message struct {
+	Subject string
+	Header  Header
+	Data    []byte
+}
+
+// Metadata is the JetStream metadata of a delivered message
+type Metadata struct {
+	Stream         string
+	Consumer       string
+	StreamSequence uint64
+	// NumDelivered counts the deliveries of the message, this one included
+	NumDelivered uint64
+}
+
+// Msg is a message delivered by a JetStream consumer with explicit acks
+type Msg interface {
+	Subject() string
+	Data() []byte
+	Headers() Header
+	Metadata() (*Metadata, error)
+	// Ack acknowledges the message, so that it is not redelivered
+	Ack() error
+	// Nak has the message redelivered right away
+	Nak() error
+	// NakWithDelay has the message redelivered after delay
+	NakWithDelay(delay time.Duration) error
+	// Term stops the redelivery of the message
+	Term() error
+	// InProgress resets the ack wait of the message while it is still being
+	// processed
+	InProgress() error
+}
+
+// MessageSource delivers the messages of a durable pull consumer, e.g. the
+// jetstream.MessagesContext of a jetstream.Consumer
+type MessageSource interface {
+	Next(ctx context.Context) (Msg, error)
+}
+
+// JetStream publishes messages, returning only once the stream stored them,
+// e.g. jetstream.JetStream's PublishMsg
+type JetStream interface {
+	PublishMsg(ctx context.Context, msg *Message) error
+}
+
+// ErrUnroutableSubject is returned for messages on subjects without a route
+var ErrUnroutableSubject = errors.New("no route for subject")
+
+// HeaderMsgID deduplicates the messages published to a stream with the same
+// ID within the stream's duplicate window
+const HeaderMsgID = "Nats-Msg-Id"
+
+// DLQ headers describe why and from where a message was dead-lettered
+const (
+	HeaderError          = "Dlq-Error"
+	HeaderSubject        = "Dlq-Subject"
+	HeaderStream         = "Dlq-Stream"
+	HeaderStreamSequence = "Dlq-Stream-Sequence"
+)
+
+// Route applies the commands published on the subjects matching Subject,
+// which may hold the * and > wildcards, as transactions of SourceType
+type Route struct {
+	Subject    string
+	SourceType entities.SourceType
+}
+
+// ConsumerConfig is the configuration of the durable consumer the Consumer
+// reads from. It is created on the stream by the binding, e.g. as a
+// jetstream.ConsumerConfig with the explicit ack policy.
+type ConsumerConfig struct {
+	Durable        string
+	FilterSubjects []string
+	// AckWait is how long a delivered message may go unacknowledged before
+	// it is redelivered
+	AckWait time.Duration
+	// MaxDeliver is unlimited, since the Consumer dead-letters the messages
+	// it gives up on rather than having the server drop them
+	MaxDeliver int
+	// MaxAckPending bounds the messages delivered but not yet acknowledged.
+	// A Consumer without a processor pool needs 1, so that messages are
+	// handled one at a time in stream order, redeliveries included, and the
+	// transactions of a user keep their order.
+	MaxAckPending int
+}
+
+// DurableConsumerConfig returns the configuration of the durable consumer
+// named durable that reads the subjects of routes
+func DurableConsumerConfig(durable string, routes []Route, ackWait time.Duration, maxAckPending int) ConsumerConfig {
+	subjects := make([]string, len(routes))
+	for i, route := range routes {
+		subjects[i] = route.Subject
+	}
+	return ConsumerConfig{
+		Durable:        durable,
+		FilterSubjects: subjects,
+		AckWait:        ackWait,
+		MaxDeliver:     -1,
+		MaxAckPending:  maxAckPending,
+	}
+}
+
+// RetryPolicy bounds the redeliveries of messages that fail to process
+type RetryPolicy struct {
+	// MaxAttempts bounds the deliveries of messages failing with unexpected
+	// errors before they are dead-lettered. Messages failing because the
+	// database is unavailable are redelivered until it recovers.
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// Consumer applies transaction commands. A message is acked once its
+// transaction was applied, and terminated once it was published to the dead
+// letter subject because it can never be applied. Otherwise it is nakked
+// with a backoff, and redelivered by the server. Redelivered messages are
+// safe, since transactions are idempotent by transaction ID.
+//
+// With a processor pool, the messages of different users are processed
+// concurrently while those of a user keep their order. A failing message is
+// then retried in place rather than nakked, holding back only the messages
+// of its worker, and its ack wait is reset between attempts.
+type Consumer struct {
+	messages   MessageSource
+	js         JetStream
+	dlqSubject string
+	routes     []Route
+	processor  ingest.TransactionProcessor
+	policy     RetryPolicy
+	// gate pauses the consumer while the region does not accept writes; may be nil
+	gate   services.RegionGate
+	logger zerolog.Logger
+	// pool processes the messages when set
+	pool *processor.Pool
+	// heartbeat beats while the consumer makes progress or waits for
+	// messages, at least every idle interval; may be nil
+	heartbeat *health.Heartbeat
+	idle      time.Duration
+
+	mu sync.Mutex
+	// fetchErr is the error of the last attempt to fetch a message, nil once
+	// a fetch succeeded
+	fetchErr error
+}
+
+// ConsumerOption configures a Consumer
+type ConsumerOption func(*Consumer)
+
+// WithPool processes the messages on pool, which must be running for them to
+// be processed. The durable consumer should then allow as many pending acks
+// as the pool holds messages.
+func WithPool(pool *processor.Pool) ConsumerOption {
+	return func(c *Consumer) {
+		c.pool = pool
+	}
+}
+
+// WithHeartbeat beats heartbeat whenever the consumer handled a message,
+// retried one, or waited idle for messages or for room in the pool, so that
+// a consumer whose loop is stuck fails the liveness probe
+func WithHeartbeat(heartbeat *health.Heartbeat, idle time.Duration) ConsumerOption {
+	return func(c *Consumer) {
+		c.heartbeat = heartbeat
+		c.idle = idle
+	}
+}
+
+// NewConsumer creates a new Consumer publishing dead letters to dlqSubject
+// with js
+func NewConsumer(
+	messages MessageSource,
+	js JetStream,
+	dlqSubject string,
+	routes []Route,
+	processor ingest.TransactionProcessor,
+	policy RetryPolicy,
+	gate services.RegionGate,
+	logger zerolog.Logger,
+	opts ...ConsumerOption,
+) *Consumer {
+	c := &Consumer{
+		messages:   messages,
+		js:         js,
+		dlqSubject: dlqSubject,
+		routes:     routes,
+		processor:  processor,
+		policy:     policy,
+		gate:       gate,
+		logger:     logger.With().Str("consumer", "nats_transactions").Logger(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Run consumes messages until the context is cancelled
+func (c *Consumer) Run(ctx context.Context) {
+	c.logger.Info().Int("routes", len(c.routes)).Msg("consumer started")
+
+	for {
+		c.heartbeat.Beat()
+		if ctx.Err() != nil {
+			c.logger.Info().Msg("consumer stopped")
+			return
+		}
+
+		// Only the active region writes
+		if c.gate != nil && !c.gate.AcceptsWrites() {
+			_ = sleep(ctx, c.policy.MaxDelay)
+			continue
+		}
+
+		// A fetch gives up once idle, so that the consumer beats while no
+		// messages arrive
+		fetchCtx, cancelFetch := c.idleContext(ctx)
+		msg, err := c.messages.Next(fetchCtx)
+		idle := fetchCtx.Err() != nil
+		cancelFetch()
+		if err != nil {
+			if !idle {
+				c.logger.Error().Err(err).Msg("failed to fetch message")
+				c.setFetchErr(err)
+				_ = sleep(ctx, c.policy.BaseDelay)
+			}
+			continue
+		}
+		c.setFetchErr(nil)
+
+		if c.pool == nil {
+			c.handle(ctx, msg)
+			continue
+		}
+		c.submit(ctx, msg)
+	}
+}
+
+// submit queues msg on the pool, keyed by the user of its event. Messages
+// that are not transaction events are dead-lettered by the worker of user 0.
+func (c *Consumer) submit(ctx context.Context, msg Msg) {
+	var userID uint64
+	if event, err := ingest.DecodeEvent(msg.Data()); err == nil {
+		userID = event.UserID
+	}
+
+	task := processor.Task{
+		UserID: userID,
+		Run: func(ctx context.Context) {
+			// Messages still queued on shutdown are taken over by another
+			// instance right away
+			if ctx.Err() != nil {
+				c.nak(msg)
+				return
+			}
+			c.handle(ctx, msg)
+		},
+	}
+	var err error
+	for {
+		submitCtx, cancelSubmit := c.idleContext(ctx)
+		err = c.pool.Submit(submitCtx, task)
+		idle := submitCtx.Err() != nil && ctx.Err() == nil
+		cancelSubmit()
+		if err == nil || !idle {
+			break
+		}
+		c.heartbeat.Beat()
+	}
+	if err != nil {
+		if ctx.Err() == nil {
+			c.logger.Warn().Err(err).Msg("failed to queue message")
+		}
+		c.nak(msg)
+	}
+}
+
+// idleContext bounds a wait of the consumer by its idle interval, after
+// which it beats and waits again
+func (c *Consumer) idleContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.idle <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.idle)
+}
+
+// Check fails while the last attempt to fetch a message failed, e.g. because
+// the server is unreachable, so the consumer can be registered as a
+// readiness check
+func (c *Consumer) Check(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.fetchErr != nil {
+		return fmt.Errorf("failed to fetch message: %w", c.fetchErr)
+	}
+	return nil
+}
+
+func (c *Consumer) setFetchErr(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fetchErr = err
+}
+
+// handle applies msg, then acks, terminates or nakks it
+func (c *Consumer) handle(ctx context.Context, msg Msg) {
+	logger := c.logger.With().Str("subject", msg.Subject()).Logger()
+	meta, err := msg.Metadata()
+	if err != nil {
+		// Messages delivered outside JetStream count as first deliveries
+		logger.Warn().Err(err).Msg("failed to read message metadata")
+		meta = &Metadata{NumDelivered: 1}
+	}
+	logger = logger.With().
+		Uint64("stream_sequence", meta.StreamSequence).
+		Uint64("delivered", meta.NumDelivered).
+		Logger()
+
+	sourceType, ok := c.route(msg.Subject())
+	if !ok {
+		c.deadLetter(ctx, &logger, msg, meta, ErrUnroutableSubject)
+		return
+	}
+	event, err := ingest.DecodeEvent(msg.Data())
+	if err != nil {
+		c.deadLetter(ctx, &logger, msg, meta, err)
+		return
+	}
+	logger = logger.With().Str("transaction_id", event.TransactionID).Logger()
+
+	req := event.Request()
+	// The attempts of a redelivered message include its earlier deliveries
+	for attempt := meta.NumDelivered; ; attempt++ {
+		_, err := c.processor.ProcessTransaction(ctx, event.UserID, req, sourceType)
+		switch {
+		case err == nil:
+			if err := msg.Ack(); err != nil {
+				// The message is redelivered and replayed
+				logger.Error().Err(err).Msg("failed to ack message")
+			}
+			return
+		case ctx.Err() != nil:
+			// Another instance takes over the message right away
+			c.nak(msg)
+			return
+		case ingest.IsRejection(err):
+			c.deadLetter(ctx, &logger, msg, meta, err)
+			return
+		case !ingest.IsTransient(err) && attempt >= uint64(c.policy.MaxAttempts):
+			c.deadLetter(ctx, &logger, msg, meta, err)
+			return
+		case c.pool == nil:
+			logger.Warn().Err(err).Msg("retrying message")
+			c.redeliver(&logger, msg, meta)
+			return
+		}
+
+		// A redelivery could overtake the later messages of the user, which
+		// the pool already holds
+		logger.Warn().Err(err).Uint64("attempt", attempt).Msg("retrying message")
+		if sleep(ctx, c.backoff(attempt)) != nil {
+			c.nak(msg)
+			return
+		}
+		if err := msg.InProgress(); err != nil {
+			// The message may be redelivered to another instance meanwhile,
+			// which replays it
+			logger.Warn().Err(err).Msg("failed to reset ack wait")
+		}
+		c.heartbeat.Beat()
+	}
+}
+
+// nak has msg redelivered right away
+func (c *Consumer) nak(msg Msg) {
+	if err := msg.Nak(); err != nil {
+		// The message is redelivered once its ack wait expires
+		c.logger.Warn().Err(err).Str("subject", msg.Subject()).Msg("failed to nak message")
+	}
+}
+
+// deadLetter publishes msg to the dead letter subject and terminates it. If
+// publishing fails, msg is redelivered and dead-lettered again.
+func (c *Consumer) deadLetter(ctx context.Context, logger *zerolog.Logger, msg Msg, meta *Metadata, cause error) {
+	header := make(Header, len(msg.Headers())+5)
+	for key, values := range msg.Headers() {
+		header[key] = append([]string(nil), values...)
+	}
+	sequence := strconv.FormatUint(meta.StreamSequence, 10)
+	// The stream drops the dead letter when a redelivered message is
+	// dead-lettered twice
+	header[HeaderMsgID] = []string{meta.Stream + "." + sequence}
+	header[HeaderError] = []string{cause.Error()}
+	header[HeaderSubject] = []string{msg.Subject()}
+	header[HeaderStream] = []string{meta.Stream}
+	header[HeaderStreamSequence] = []string{sequence}
+
+	err := c.js.PublishMsg(ctx, &Message{Subject: c.dlqSubject, Header: header, Data: msg.Data()})
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to dead-letter message")
+		c.redeliver(logger, msg, meta)
+		return
+	}
+
+	logger.Warn().Err(cause).Str("dlq_subject", c.dlqSubject).Msg("message dead-lettered")
+	if err := msg.Term(); err != nil {
+		// The message is redelivered and dead-lettered again
+		logger.Error().Err(err).Msg("failed to terminate message")
+	}
+}
+
+// redeliver nakks msg with the backoff of its delivery
+func (c *Consumer) redeliver(logger *zerolog.Logger, msg Msg, meta *Metadata) {
+	delay := c.backoff(meta.NumDelivered)
+	if err := msg.NakWithDelay(delay); err != nil {
+		// The message is redelivered once its ack wait expires
+		logger.Warn().Err(err).Msg("failed to nak message")
+	}
+}
+
+// backoff returns the delay before the given retry
+func (c *Consumer) backoff(attempt uint64) time.Duration {
+	return ingest.Backoff(int(min(attempt, 32)), c.policy.BaseDelay, c.policy.MaxDelay)
+}
+
+// route returns the source type of the first route matching subject
+func (c *Consumer) route(subject string) (entities.SourceType, bool) {
+	for _, route := range c.routes {
+		if subjectMatches(route.Subject, subject) {
+			return route.SourceType, true
+		}
+	}
+	return "", false
+}
+
+// subjectMatches reports whether subject matches pattern, where * matches a
+// single token and a trailing > one or more
+func subjectMatches(pattern, subject string) bool {
+	patternTokens := strings.Split(pattern, ".")
+	subjectTokens := strings.Split(subject, ".")
+	for i, token := range patternTokens {
+		if token == ">" && i == len(patternTokens)-1 {
+			return len(subjectTokens) > i
+		}
+		if i >= len(subjectTokens) || (token != "*" && token != subjectTokens[i]) {
+			return false
+		}
+	}
+	return len(patternTokens) == len(subjectTokens)
+}
+
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
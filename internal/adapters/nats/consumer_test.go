@@ -0,0 +1,529 @@
+package nats
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"transaction-service/internal/adapters/ingest"
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/health"
+	"transaction-service/internal/processor"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMsg records how it was settled. Nakked messages are redelivered by
+// their source.
+type fakeMsg struct {
+	source  *fakeSource
+	subject string
+	data    []byte
+	header  Header
+	meta    Metadata
+
+	settled    string
+	delay      time.Duration
+	inProgress int
+}
+
+func (m *fakeMsg) Subject() string { return m.subject }
+func (m *fakeMsg) Data() []byte    { return m.data }
+func (m *fakeMsg) Headers() Header { return m.header }
+
+func (m *fakeMsg) Metadata() (*Metadata, error) {
+	meta := m.meta
+	return &meta, nil
+}
+
+func (m *fakeMsg) Ack() error  { return m.settle("ack") }
+func (m *fakeMsg) Nak() error  { return m.settle("nak") }
+func (m *fakeMsg) Term() error { return m.settle("term") }
+
+func (m *fakeMsg) NakWithDelay(delay time.Duration) error {
+	m.delay = delay
+	return m.settle("nak")
+}
+
+func (m *fakeMsg) InProgress() error {
+	m.source.mu.Lock()
+	defer m.source.mu.Unlock()
+	m.inProgress++
+	return nil
+}
+
+func (m *fakeMsg) settle(how string) error {
+	m.source.mu.Lock()
+	defer m.source.mu.Unlock()
+	m.settled = how
+	m.source.deliveries = append(m.source.deliveries, m)
+	if how == "nak" {
+		redelivery := *m
+		redelivery.meta.NumDelivered++
+		redelivery.settled = ""
+		m.source.queue = append(m.source.queue, &redelivery)
+	}
+	return nil
+}
+
+// fakeSource fails with its fetch errors, then serves its queue and cancels
+// the consumer once it is empty
+type fakeSource struct {
+	fetchErrs []error
+	cancel    context.CancelFunc
+
+	mu         sync.Mutex
+	queue      []*fakeMsg
+	deliveries []*fakeMsg
+}
+
+func (s *fakeSource) Next(ctx context.Context) (Msg, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.fetchErrs) > 0 {
+		err := s.fetchErrs[0]
+		s.fetchErrs = s.fetchErrs[1:]
+		return nil, err
+	}
+	if len(s.queue) == 0 {
+		s.cancel()
+		s.mu.Unlock()
+		<-ctx.Done()
+		s.mu.Lock()
+		return nil, ctx.Err()
+	}
+	msg := s.queue[0]
+	s.queue = s.queue[1:]
+	return msg, nil
+}
+
+func (s *fakeSource) push(subject string, data []byte) {
+	s.queue = append(s.queue, &fakeMsg{
+		source:  s,
+		subject: subject,
+		data:    data,
+		header:  Header{"Trace-Id": {"abc"}},
+		meta:    Metadata{Stream: "TRANSACTIONS", StreamSequence: 7, NumDelivered: 1},
+	})
+}
+
+// settlements returns how the delivered messages were settled, by
+// transaction ID or by subject for messages that are not events
+func (s *fakeSource) settlements() map[string][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	settled := make(map[string][]string)
+	for _, delivery := range s.deliveries {
+		key := delivery.subject
+		if event, err := ingest.DecodeEvent(delivery.data); err == nil {
+			key = event.TransactionID
+		}
+		settled[key] = append(settled[key], delivery.settled)
+	}
+	return settled
+}
+
+type fakeJetStream struct {
+	published []*Message
+	errs      []error
+}
+
+func (js *fakeJetStream) PublishMsg(ctx context.Context, msg *Message) error {
+	if len(js.errs) > 0 {
+		err := js.errs[0]
+		js.errs = js.errs[1:]
+		return err
+	}
+	js.published = append(js.published, msg)
+	return nil
+}
+
+// fakeProcessor fails each transaction ID with the queued errors before
+// applying it
+type fakeProcessor struct {
+	errs    map[string][]error
+	calls   map[string]int
+	applied []entities.SourceType
+}
+
+func (p *fakeProcessor) ProcessTransaction(
+	ctx context.Context,
+	userID uint64,
+	req entities.TransactionRequest,
+	sourceType entities.SourceType,
+) (*entities.TransactionResult, error) {
+	p.calls[req.TransactionID]++
+	if errs := p.errs[req.TransactionID]; len(errs) > 0 {
+		p.errs[req.TransactionID] = errs[1:]
+		return nil, errs[0]
+	}
+	p.applied = append(p.applied, sourceType)
+	return &entities.TransactionResult{UserID: userID, TransactionID: req.TransactionID}, nil
+}
+
+func eventData(transactionID string) []byte {
+	data, _ := json.Marshal(ingest.TransactionEvent{UserID: 1, State: "lose", Amount: "5.00", TransactionID: transactionID})
+	return data
+}
+
+var testRoutes = []Route{
+	{Subject: "transactions.game.>", SourceType: entities.SourceTypeGame},
+	{Subject: "transactions.*.payment", SourceType: entities.SourceTypePayment},
+}
+
+var testPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+
+func TestConsumer(t *testing.T) {
+	unavailable := fmt.Errorf("failed to get user: %w", services.ErrUnavailable)
+
+	tests := []struct {
+		name          string
+		subject       string
+		data          []byte
+		errs          []error
+		dlqErrs       []error
+		wantCalls     int
+		wantSettled   []string
+		wantApplied   entities.SourceType
+		wantDLQReason string
+	}{
+		{
+			name:        "applied messages are acked",
+			subject:     "transactions.game.eu",
+			data:        eventData("tx-1"),
+			wantCalls:   1,
+			wantSettled: []string{"ack"},
+			wantApplied: entities.SourceTypeGame,
+		},
+		{
+			name:        "the subject selects the source type",
+			subject:     "transactions.psp.payment",
+			data:        eventData("tx-1"),
+			wantCalls:   1,
+			wantSettled: []string{"ack"},
+			wantApplied: entities.SourceTypePayment,
+		},
+		{
+			name:          "unroutable messages are dead-lettered",
+			subject:       "transactions.server",
+			data:          eventData("tx-1"),
+			wantSettled:   []string{"term"},
+			wantDLQReason: "no route for subject",
+		},
+		{
+			name:          "undecodable messages are dead-lettered",
+			subject:       "transactions.game.eu",
+			data:          []byte("{"),
+			wantSettled:   []string{"term"},
+			wantDLQReason: "invalid transaction event",
+		},
+		{
+			name:          "rejected transactions are dead-lettered",
+			subject:       "transactions.game.eu",
+			data:          eventData("tx-1"),
+			errs:          []error{services.ErrInsufficientFunds},
+			wantCalls:     1,
+			wantSettled:   []string{"term"},
+			wantDLQReason: "insufficient funds",
+		},
+		{
+			name:        "outages are nakked until they pass",
+			subject:     "transactions.game.eu",
+			data:        eventData("tx-1"),
+			errs:        []error{unavailable, unavailable, unavailable, unavailable},
+			wantCalls:   5,
+			wantSettled: []string{"nak", "nak", "nak", "nak", "ack"},
+			wantApplied: entities.SourceTypeGame,
+		},
+		{
+			name:          "unexpected errors are dead-lettered on the last delivery",
+			subject:       "transactions.game.eu",
+			data:          eventData("tx-1"),
+			errs:          []error{errors.New("boom"), errors.New("boom"), errors.New("boom")},
+			wantCalls:     3,
+			wantSettled:   []string{"nak", "nak", "term"},
+			wantDLQReason: "boom",
+		},
+		{
+			name:          "failed dead letters are redelivered",
+			subject:       "transactions.game.eu",
+			data:          eventData("tx-1"),
+			errs:          []error{services.ErrUserNotFound, services.ErrUserNotFound},
+			dlqErrs:       []error{errors.New("no responders")},
+			wantCalls:     2,
+			wantSettled:   []string{"nak", "term"},
+			wantDLQReason: "user not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			source := &fakeSource{cancel: cancel}
+			source.push(tt.subject, tt.data)
+			js := &fakeJetStream{errs: tt.dlqErrs}
+			processor := &fakeProcessor{
+				errs:  map[string][]error{"tx-1": tt.errs},
+				calls: make(map[string]int),
+			}
+			consumer := NewConsumer(source, js, "transactions.dlq", testRoutes, processor, testPolicy, nil, zerolog.Nop())
+
+			consumer.Run(ctx)
+
+			settled := make([]string, len(source.deliveries))
+			for i, delivery := range source.deliveries {
+				settled[i] = delivery.settled
+				assert.EqualValues(t, i+1, delivery.meta.NumDelivered)
+			}
+			assert.Equal(t, tt.wantSettled, settled)
+			assert.Equal(t, tt.wantCalls, processor.calls["tx-1"])
+			if tt.wantApplied == "" {
+				assert.Empty(t, processor.applied)
+			} else {
+				assert.Equal(t, []entities.SourceType{tt.wantApplied}, processor.applied)
+			}
+			if tt.wantDLQReason == "" {
+				assert.Empty(t, js.published)
+				return
+			}
+
+			require.Len(t, js.published, 1)
+			dead := js.published[0]
+			assert.Equal(t, "transactions.dlq", dead.Subject)
+			assert.Equal(t, tt.data, dead.Data)
+			assert.Contains(t, dead.Header[HeaderError][0], tt.wantDLQReason)
+			assert.Equal(t, []string{tt.subject}, dead.Header[HeaderSubject])
+			assert.Equal(t, []string{"TRANSACTIONS"}, dead.Header[HeaderStream])
+			assert.Equal(t, []string{"7"}, dead.Header[HeaderStreamSequence])
+			assert.Equal(t, []string{"TRANSACTIONS.7"}, dead.Header[HeaderMsgID], "dead letters are deduplicated")
+			assert.Equal(t, []string{"abc"}, dead.Header["Trace-Id"])
+		})
+	}
+}
+
+func TestConsumer_NakOnShutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	source := &fakeSource{cancel: cancel}
+	source.push("transactions.game.eu", eventData("tx-1"))
+	processor := &fakeProcessor{
+		errs:  map[string][]error{"tx-1": {context.Canceled}},
+		calls: make(map[string]int),
+	}
+	cancel()
+	consumer := NewConsumer(source, &fakeJetStream{}, "transactions.dlq", testRoutes, processor, testPolicy, nil, zerolog.Nop())
+	consumer.handle(ctx, source.queue[0])
+
+	require.Len(t, source.deliveries, 1)
+	assert.Equal(t, "nak", source.deliveries[0].settled)
+	assert.Zero(t, source.deliveries[0].delay, "another instance takes over right away")
+}
+
+func TestConsumer_Check(t *testing.T) {
+	run := func(source *fakeSource) *Consumer {
+		ctx, cancel := context.WithCancel(context.Background())
+		source.cancel = cancel
+		processor := &fakeProcessor{calls: make(map[string]int)}
+		consumer := NewConsumer(source, &fakeJetStream{}, "transactions.dlq", testRoutes, processor, testPolicy, nil, zerolog.Nop())
+		consumer.Run(ctx)
+		return consumer
+	}
+
+	t.Run("failing fetches", func(t *testing.T) {
+		consumer := run(&fakeSource{fetchErrs: []error{errors.New("nats: connection closed")}})
+		assert.EqualError(t, consumer.Check(context.Background()), "failed to fetch message: nats: connection closed")
+	})
+
+	t.Run("recovered fetches", func(t *testing.T) {
+		source := &fakeSource{fetchErrs: []error{errors.New("nats: connection closed")}}
+		source.push("transactions.game.eu", eventData("tx-1"))
+		consumer := run(source)
+		assert.NoError(t, consumer.Check(context.Background()))
+	})
+}
+
+// gatedProcessor applies transactions once their gate, if any, is closed,
+// after failing them with their queued errors
+type gatedProcessor struct {
+	gates map[string]chan struct{}
+
+	mu      sync.Mutex
+	errs    map[string][]error
+	applied []string
+}
+
+func (p *gatedProcessor) ProcessTransaction(
+	ctx context.Context,
+	userID uint64,
+	req entities.TransactionRequest,
+	sourceType entities.SourceType,
+) (*entities.TransactionResult, error) {
+	if gate, ok := p.gates[req.TransactionID]; ok {
+		<-gate
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if errs := p.errs[req.TransactionID]; len(errs) > 0 {
+		p.errs[req.TransactionID] = errs[1:]
+		return nil, errs[0]
+	}
+	p.applied = append(p.applied, req.TransactionID)
+	return &entities.TransactionResult{UserID: userID, TransactionID: req.TransactionID}, nil
+}
+
+func (p *gatedProcessor) appliedTransactions() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.applied...)
+}
+
+func TestConsumer_Pool(t *testing.T) {
+	event := func(userID uint64, transactionID string) []byte {
+		data, _ := json.Marshal(ingest.TransactionEvent{UserID: userID, State: "win", Amount: "5.00", TransactionID: transactionID})
+		return data
+	}
+
+	pool := processor.New(2, 10)
+	poolCtx, stopPool := context.WithCancel(context.Background())
+	poolDone := make(chan struct{})
+	go func() {
+		defer close(poolDone)
+		pool.Run(poolCtx)
+	}()
+	defer func() {
+		stopPool()
+		<-poolDone
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	source := &fakeSource{cancel: cancel}
+	source.push("transactions.game.eu", event(1, "tx-1"))
+	source.push("transactions.game.eu", event(2, "tx-2"))
+	source.push("transactions.game.eu", event(1, "tx-3"))
+	source.push("transactions.game.eu", []byte("{"))
+	js := &fakeJetStream{}
+	release := make(chan struct{})
+	gated := &gatedProcessor{
+		gates: map[string]chan struct{}{"tx-1": release},
+		errs:  map[string][]error{"tx-1": {fmt.Errorf("failed to get user: %w", services.ErrUnavailable), errors.New("boom")}},
+	}
+	consumer := NewConsumer(source, js, "transactions.dlq", testRoutes, gated, testPolicy, nil, zerolog.Nop(), WithPool(pool))
+
+	consumer.Run(ctx)
+
+	// Other users are not held back by tx-1
+	require.Eventually(t, func() bool {
+		return len(gated.appliedTransactions()) == 1
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, []string{"tx-2"}, gated.appliedTransactions())
+
+	close(release)
+	require.Eventually(t, func() bool {
+		return len(gated.appliedTransactions()) == 3
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, []string{"tx-2", "tx-1", "tx-3"}, gated.appliedTransactions(), "the transactions of a user keep their order")
+	require.Eventually(t, func() bool {
+		return len(source.settlements()) == 4
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, map[string][]string{
+		"tx-1":                 {"ack"},
+		"tx-2":                 {"ack"},
+		"tx-3":                 {"ack"},
+		"transactions.game.eu": {"term"},
+	}, source.settlements(), "failing messages are retried in place rather than nakked")
+	source.mu.Lock()
+	for _, delivery := range source.deliveries {
+		if bytes.Equal(delivery.data, event(1, "tx-1")) {
+			assert.Equal(t, 2, delivery.inProgress, "the ack wait is reset between attempts")
+		}
+	}
+	source.mu.Unlock()
+	assert.Len(t, js.published, 1)
+}
+
+func TestConsumer_PoolNakOnShutdown(t *testing.T) {
+	pool := processor.New(1, 10)
+	poolCtx, stopPool := context.WithCancel(context.Background())
+	stopPool()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	source := &fakeSource{cancel: cancel}
+	source.push("transactions.game.eu", eventData("tx-1"))
+	processor := &fakeProcessor{calls: make(map[string]int)}
+	consumer := NewConsumer(source, &fakeJetStream{}, "transactions.dlq", testRoutes, processor, testPolicy, nil, zerolog.Nop(), WithPool(pool))
+
+	consumer.Run(ctx)
+	pool.Run(poolCtx)
+
+	assert.Equal(t, map[string][]string{"tx-1": {"nak"}}, source.settlements(), "messages queued on shutdown are taken over right away")
+	assert.Zero(t, processor.calls["tx-1"])
+}
+
+// idleSource waits for messages that never arrive
+type idleSource struct{}
+
+func (idleSource) Next(ctx context.Context) (Msg, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestConsumer_Heartbeat(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	heartbeat := health.NewHeartbeat(50 * time.Millisecond)
+	consumer := NewConsumer(idleSource{}, &fakeJetStream{}, "transactions.dlq", testRoutes, &fakeProcessor{}, testPolicy, nil, zerolog.Nop(),
+		WithHeartbeat(heartbeat, 5*time.Millisecond))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		consumer.Run(ctx)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	assert.NoError(t, heartbeat.Check(ctx), "an idle consumer beats")
+	assert.NoError(t, consumer.Check(ctx), "idle fetches are not failures")
+	cancel()
+	<-done
+}
+
+func TestDurableConsumerConfig(t *testing.T) {
+	config := DurableConsumerConfig("transaction-service", testRoutes, 30*time.Second, 100)
+	assert.Equal(t, ConsumerConfig{
+		Durable:        "transaction-service",
+		FilterSubjects: []string{"transactions.game.>", "transactions.*.payment"},
+		AckWait:        30 * time.Second,
+		MaxDeliver:     -1,
+		MaxAckPending:  100,
+	}, config)
+}
+
+func TestSubjectMatches(t *testing.T) {
+	tests := []struct {
+		pattern, subject string
+		want             bool
+	}{
+		{"transactions.game", "transactions.game", true},
+		{"transactions.game", "transactions.payment", false},
+		{"transactions.game", "transactions.game.eu", false},
+		{"transactions.*", "transactions.game", true},
+		{"transactions.*", "transactions.game.eu", false},
+		{"transactions.*.eu", "transactions.game.eu", true},
+		{"transactions.>", "transactions.game.eu", true},
+		{"transactions.>", "transactions", false},
+		{"transactions.game.>", "transactions.game", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, subjectMatches(tt.pattern, tt.subject), "%s against %s", tt.subject, tt.pattern)
+	}
+}
@@ -0,0 +1,51 @@
+package nats
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"transaction-service/internal/domain/entities"
+)
+
+// HeaderEventType carries the type of published outbox events
+const HeaderEventType = "Event-Type"
+
+// Publisher publishes outbox events to the subject
+// <prefix>.<event type>.<event key>, e.g. balance.events.transaction.processed.7,
+// so subscribers can filter by event type and by user. Events are published
+// one at a time in order, each once the stream stored the one before.
+//
+// The outbox event ID is the message ID, so the stream drops the events a
+// relay publishes more than once within its duplicate window.
+type Publisher struct {
+	js            JetStream
+	subjectPrefix string
+}
+
+// NewPublisher creates a new Publisher
+func NewPublisher(js JetStream, subjectPrefix string) *Publisher {
+	return &Publisher{js: js, subjectPrefix: subjectPrefix}
+}
+
+// Publish publishes the events, stopping at the first that fails
+func (p *Publisher) Publish(ctx context.Context, events []*entities.OutboxEvent) error {
+	for _, event := range events {
+		subject := p.subjectPrefix + "." + event.Type
+		if event.Key != "" {
+			subject += "." + event.Key
+		}
+		err := p.js.PublishMsg(ctx, &Message{
+			Subject: subject,
+			Header: Header{
+				HeaderMsgID:     {strconv.FormatUint(event.ID, 10)},
+				HeaderEventType: {event.Type},
+			},
+			Data: event.Payload,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to publish event %d: %w", event.ID, err)
+		}
+	}
+	return nil
+}
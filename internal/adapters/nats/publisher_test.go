@@ -0,0 +1,37 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"transaction-service/internal/domain/entities"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublisher_Publish(t *testing.T) {
+	ctx := context.Background()
+	js := &fakeJetStream{}
+	publisher := NewPublisher(js, "balance.events")
+
+	err := publisher.Publish(ctx, []*entities.OutboxEvent{
+		{ID: 41, Type: entities.EventTransactionProcessed, Key: "7", Payload: []byte(`{"userId":7}`)},
+		{ID: 42, Type: "system.event", Payload: []byte(`{}`)},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, js.published, 2)
+	msg := js.published[0]
+	assert.Equal(t, "balance.events.transaction.processed.7", msg.Subject)
+	assert.Equal(t, []byte(`{"userId":7}`), msg.Data)
+	assert.Equal(t, Header{
+		HeaderMsgID:     {"41"},
+		HeaderEventType: {"transaction.processed"},
+	}, msg.Header)
+	assert.Equal(t, "balance.events.system.event", js.published[1].Subject, "events without a key")
+
+	js.errs = []error{errors.New("nats: no response from stream")}
+	assert.ErrorContains(t, publisher.Publish(ctx, []*entities.OutboxEvent{{ID: 43}}), "failed to publish event 43")
+}
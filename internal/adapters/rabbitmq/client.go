@@ -0,0 +1,121 @@
+package rabbitmq
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Dialer binds an amqp091-go connection to Connector. It dials the broker
+// when a channel is first opened, and again once the connection was lost.
+type Dialer struct {
+	url string
+
+	mu   sync.Mutex
+	conn *amqp.Connection
+}
+
+// NewDialer creates a new Dialer connecting to the AMQP URL url
+func NewDialer(url string) *Dialer {
+	return &Dialer{url: url}
+}
+
+// Channel implements Connector
+func (d *Dialer) Channel(ctx context.Context) (Channel, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.conn == nil || d.conn.IsClosed() {
+		conn, err := amqp.Dial(d.url)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect: %w", err)
+		}
+		d.conn = conn
+	}
+	ch, err := d.conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+	return &clientChannel{ch: ch, closed: make(chan struct{})}, nil
+}
+
+// Close closes the connection, which requeues the messages its channels had
+// not acked
+func (d *Dialer) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.conn == nil || d.conn.IsClosed() {
+		return nil
+	}
+	return d.conn.Close()
+}
+
+// clientChannel binds an amqp091-go channel to Channel
+type clientChannel struct {
+	ch *amqp.Channel
+	// closed stops the conversion of the deliveries once the channel is
+	// closed, even if they are no longer received
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (c *clientChannel) Qos(prefetchCount, prefetchSize int, global bool) error {
+	return c.ch.Qos(prefetchCount, prefetchSize, global)
+}
+
+func (c *clientChannel) ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args Table) error {
+	return c.ch.ExchangeDeclare(name, kind, durable, autoDelete, internal, noWait, amqp.Table(args))
+}
+
+func (c *clientChannel) QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args Table) (Queue, error) {
+	queue, err := c.ch.QueueDeclare(name, durable, autoDelete, exclusive, noWait, amqp.Table(args))
+	if err != nil {
+		return Queue{}, err
+	}
+	return Queue{Name: queue.Name, Messages: queue.Messages, Consumers: queue.Consumers}, nil
+}
+
+func (c *clientChannel) QueueBind(name, key, exchange string, noWait bool, args Table) error {
+	return c.ch.QueueBind(name, key, exchange, noWait, amqp.Table(args))
+}
+
+func (c *clientChannel) Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args Table) (<-chan Delivery, error) {
+	deliveries, err := c.ch.Consume(queue, consumer, autoAck, exclusive, noLocal, noWait, amqp.Table(args))
+	if err != nil {
+		return nil, err
+	}
+
+	converted := make(chan Delivery)
+	go func() {
+		defer close(converted)
+		for delivery := range deliveries {
+			select {
+			case converted <- fromClientDelivery(delivery):
+			case <-c.closed:
+				return
+			}
+		}
+	}()
+	return converted, nil
+}
+
+func (c *clientChannel) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return c.ch.Close()
+}
+
+func fromClientDelivery(delivery amqp.Delivery) Delivery {
+	return Delivery{
+		Acknowledger: delivery.Acknowledger,
+		DeliveryTag:  delivery.DeliveryTag,
+		Redelivered:  delivery.Redelivered,
+		Exchange:     delivery.Exchange,
+		RoutingKey:   delivery.RoutingKey,
+		MessageID:    delivery.MessageId,
+		Headers:      Table(delivery.Headers),
+		Body:         delivery.Body,
+	}
+}
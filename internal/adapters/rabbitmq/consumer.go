@@ -0,0 +1,430 @@
+// Package rabbitmq ingests the transactions the legacy wallet integration
+// publishes over AMQP.
+//
+// The consumer depends on the Connector and Channel ports instead of a client
+// library. They are modelled on amqp091-go's Connection and Channel, which
+// Dialer binds to them by converting their tables and deliveries.
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"transaction-service/internal/adapters/ingest"
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/health"
+	"transaction-service/internal/processor"
+
+	"github.com/rs/zerolog"
+)
+
+// Table holds the arguments and headers of AMQP methods and messages
+type Table map[string]any
+
+// Acknowledger settles deliveries by delivery tag
+type Acknowledger interface {
+	Ack(tag uint64, multiple bool) error
+	Nack(tag uint64, multiple, requeue bool) error
+	Reject(tag uint64, requeue bool) error
+}
+
+// Delivery is a message delivered to a consumer with manual acks
+type Delivery struct {
+	Acknowledger Acknowledger
+	DeliveryTag  uint64
+	// Redelivered is set on messages delivered before without being acked
+	Redelivered bool
+	Exchange    string
+	RoutingKey  string
+	MessageID   string
+	Headers     Table
+	Body        []byte
+}
+
+// Ack acknowledges the delivery
+func (d Delivery) Ack(multiple bool) error {
+	return d.Acknowledger.Ack(d.DeliveryTag, multiple)
+}
+
+// Nack negatively acknowledges the delivery, requeueing it or dead-lettering
+// it to the queue's dead letter exchange
+func (d Delivery) Nack(multiple, requeue bool) error {
+	return d.Acknowledger.Nack(d.DeliveryTag, multiple, requeue)
+}
+
+// Reject rejects the delivery, requeueing it or dead-lettering it to the
+// queue's dead letter exchange
+func (d Delivery) Reject(requeue bool) error {
+	return d.Acknowledger.Reject(d.DeliveryTag, requeue)
+}
+
+// Queue is a declared queue
+type Queue struct {
+	Name      string
+	Messages  int
+	Consumers int
+}
+
+// Channel is an AMQP channel
+type Channel interface {
+	Qos(prefetchCount, prefetchSize int, global bool) error
+	ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args Table) error
+	QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args Table) (Queue, error)
+	QueueBind(name, key, exchange string, noWait bool, args Table) error
+	// Consume delivers the messages of queue until the channel is closed,
+	// e.g. because its connection was lost
+	Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args Table) (<-chan Delivery, error)
+	Close() error
+}
+
+// Connector opens channels, e.g. on a connection it dials and redials once
+// lost
+type Connector interface {
+	Channel(ctx context.Context) (Channel, error)
+}
+
+// RetryPolicy bounds the retries of messages that fail to process
+type RetryPolicy struct {
+	// MaxAttempts bounds the attempts of messages failing with unexpected
+	// errors before they are dead-lettered. Messages failing because the
+	// database is unavailable are requeued until it recovers.
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// errChannelClosed is reported while the consumer reconnects after its
+// deliveries stopped
+var errChannelClosed = errors.New("delivery channel closed")
+
+// Consumer applies transaction messages from a queue in delivery order. A
+// message is acked once its transaction was applied, and rejected to the
+// dead letter exchange once it can never be applied. Messages failing
+// because the database is unavailable are requeued after a backoff, and
+// those failing with unexpected errors are retried in place until the last
+// attempt. Redelivered messages are safe, since transactions are idempotent
+// by transaction ID.
+//
+// With a processor pool, the messages of different users are processed
+// concurrently while those of a user keep their order. A message failing
+// because the database is unavailable is then retried in place rather than
+// requeued, holding back only the messages of its worker.
+type Consumer struct {
+	connector Connector
+	topology  Topology
+	processor ingest.TransactionProcessor
+	// sourceType is the source type every message of the queue is applied as
+	sourceType entities.SourceType
+	policy     RetryPolicy
+	// gate pauses the consumer while the region does not accept writes; may be nil
+	gate   services.RegionGate
+	logger zerolog.Logger
+	// pool processes the messages when set
+	pool *processor.Pool
+	// heartbeat beats while the consumer makes progress or waits for
+	// messages, at least every idle interval; may be nil
+	heartbeat *health.Heartbeat
+	idle      time.Duration
+
+	mu sync.Mutex
+	// consumeErr is the error the consumer last failed to consume with, nil
+	// while it is consuming
+	consumeErr error
+}
+
+// ConsumerOption configures a Consumer
+type ConsumerOption func(*Consumer)
+
+// WithPool processes the messages on pool, which must be running for them to
+// be processed. The prefetch of the topology bounds the messages in flight.
+// Messages still on the pool when their channel is closed are redelivered,
+// and replayed.
+func WithPool(pool *processor.Pool) ConsumerOption {
+	return func(c *Consumer) {
+		c.pool = pool
+	}
+}
+
+// WithHeartbeat beats heartbeat whenever the consumer handled a message,
+// retried one, or waited idle for messages or for room in the pool, so that
+// a consumer whose loop is stuck fails the liveness probe
+func WithHeartbeat(heartbeat *health.Heartbeat, idle time.Duration) ConsumerOption {
+	return func(c *Consumer) {
+		c.heartbeat = heartbeat
+		c.idle = idle
+	}
+}
+
+// NewConsumer creates a new Consumer
+func NewConsumer(
+	connector Connector,
+	topology Topology,
+	processor ingest.TransactionProcessor,
+	sourceType entities.SourceType,
+	policy RetryPolicy,
+	gate services.RegionGate,
+	logger zerolog.Logger,
+	opts ...ConsumerOption,
+) *Consumer {
+	c := &Consumer{
+		connector:  connector,
+		topology:   topology,
+		processor:  processor,
+		sourceType: sourceType,
+		policy:     policy,
+		gate:       gate,
+		logger:     logger.With().Str("consumer", "rabbitmq_transactions").Str("queue", topology.Queue).Logger(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Run consumes messages until the context is cancelled, opening a new channel
+// whenever the current one is closed
+func (c *Consumer) Run(ctx context.Context) {
+	c.logger.Info().Str("source_type", string(c.sourceType)).Msg("consumer started")
+
+	// attempt counts the failures to consume since messages were last
+	// delivered
+	attempt := 0
+	for {
+		c.heartbeat.Beat()
+		if ctx.Err() != nil {
+			c.logger.Info().Msg("consumer stopped")
+			return
+		}
+
+		// Only the active region writes
+		if c.gate != nil && !c.gate.AcceptsWrites() {
+			_ = sleep(ctx, c.policy.MaxDelay)
+			continue
+		}
+
+		consumed, err := c.consume(ctx)
+		if ctx.Err() != nil || errors.Is(err, services.ErrRegionStandby) {
+			continue
+		}
+		if consumed {
+			attempt = 0
+		}
+		attempt++
+		c.logger.Error().Err(err).Int("attempt", attempt).Msg("failed to consume queue")
+		c.setConsumeErr(err)
+		_ = sleep(ctx, ingest.Backoff(attempt, c.policy.BaseDelay, c.policy.MaxDelay))
+	}
+}
+
+// consume opens a channel, declares the topology and handles the deliveries
+// until the channel is closed, the context is cancelled or the region stops
+// accepting writes. It reports whether any message was delivered.
+func (c *Consumer) consume(ctx context.Context) (bool, error) {
+	ch, err := c.connector.Channel(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to open channel: %w", err)
+	}
+	// Closing the channel requeues the messages delivered but not acked
+	defer ch.Close()
+
+	if err := c.topology.declare(ch); err != nil {
+		return false, err
+	}
+	if err := ch.Qos(c.topology.Prefetch, 0, false); err != nil {
+		return false, fmt.Errorf("failed to set prefetch: %w", err)
+	}
+	deliveries, err := ch.Consume(c.topology.Queue, c.topology.ConsumerTag, false, false, false, false, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to consume: %w", err)
+	}
+	c.setConsumeErr(nil)
+
+	// The consumer beats while no messages arrive
+	var idle <-chan time.Time
+	if c.idle > 0 {
+		ticker := time.NewTicker(c.idle)
+		defer ticker.Stop()
+		idle = ticker.C
+	}
+
+	consumed := false
+	for {
+		c.heartbeat.Beat()
+		select {
+		case <-ctx.Done():
+			return consumed, ctx.Err()
+		case <-idle:
+			continue
+		case delivery, ok := <-deliveries:
+			if !ok {
+				return consumed, errChannelClosed
+			}
+			consumed = true
+			if c.pool == nil {
+				c.handle(ctx, delivery)
+			} else {
+				c.submit(ctx, delivery)
+			}
+			// The prefetched messages are requeued for the active region
+			if c.gate != nil && !c.gate.AcceptsWrites() {
+				return consumed, services.ErrRegionStandby
+			}
+		}
+	}
+}
+
+// submit queues delivery on the pool, keyed by the user of its event.
+// Messages that are not transaction events are dead-lettered by the worker of
+// user 0.
+func (c *Consumer) submit(ctx context.Context, delivery Delivery) {
+	var userID uint64
+	if event, err := ingest.DecodeEvent(delivery.Body); err == nil {
+		userID = event.UserID
+	}
+
+	task := processor.Task{
+		UserID: userID,
+		Run: func(ctx context.Context) {
+			// Messages still queued on shutdown are requeued for another
+			// consumer
+			if ctx.Err() != nil {
+				c.requeue(&c.logger, delivery)
+				return
+			}
+			c.handle(ctx, delivery)
+		},
+	}
+	var err error
+	for {
+		submitCtx, cancelSubmit := c.idleContext(ctx)
+		err = c.pool.Submit(submitCtx, task)
+		idle := submitCtx.Err() != nil && ctx.Err() == nil
+		cancelSubmit()
+		if err == nil || !idle {
+			break
+		}
+		c.heartbeat.Beat()
+	}
+	if err != nil {
+		if ctx.Err() == nil {
+			c.logger.Warn().Err(err).Msg("failed to queue message")
+		}
+		c.requeue(&c.logger, delivery)
+	}
+}
+
+// idleContext bounds a wait of the consumer by its idle interval, after
+// which it beats and waits again
+func (c *Consumer) idleContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.idle <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.idle)
+}
+
+// Check fails while the consumer is not consuming, e.g. because the broker is
+// unreachable, so the consumer can be registered as a readiness check
+func (c *Consumer) Check(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.consumeErr != nil {
+		return fmt.Errorf("failed to consume queue: %w", c.consumeErr)
+	}
+	return nil
+}
+
+func (c *Consumer) setConsumeErr(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.consumeErr = err
+}
+
+// handle applies delivery, then acks, requeues or dead-letters it
+func (c *Consumer) handle(ctx context.Context, delivery Delivery) {
+	logger := c.logger.With().
+		Uint64("delivery_tag", delivery.DeliveryTag).
+		Str("routing_key", delivery.RoutingKey).
+		Bool("redelivered", delivery.Redelivered).
+		Logger()
+
+	event, err := ingest.DecodeEvent(delivery.Body)
+	if err != nil {
+		c.deadLetter(&logger, delivery, err)
+		return
+	}
+	logger = logger.With().Str("transaction_id", event.TransactionID).Logger()
+
+	req := event.Request()
+	for attempt := 1; ; attempt++ {
+		_, err := c.processor.ProcessTransaction(ctx, event.UserID, req, c.sourceType)
+		switch {
+		case err == nil:
+			if err := delivery.Ack(false); err != nil {
+				// The message is redelivered and replayed
+				logger.Error().Err(err).Msg("failed to ack message")
+			}
+			return
+		case ctx.Err() != nil:
+			c.requeue(&logger, delivery)
+			return
+		case ingest.IsRejection(err):
+			c.deadLetter(&logger, delivery, err)
+			return
+		case ingest.IsTransient(err) && c.pool == nil:
+			// Requeued messages are delivered again, to this consumer or
+			// another, once the backoff passed
+			logger.Warn().Err(err).Msg("requeueing message")
+			_ = sleep(ctx, ingest.Backoff(attempt, c.policy.BaseDelay, c.policy.MaxDelay))
+			c.requeue(&logger, delivery)
+			return
+		case !ingest.IsTransient(err) && attempt >= c.policy.MaxAttempts:
+			c.deadLetter(&logger, delivery, err)
+			return
+		}
+
+		// With a pool, a requeued message could overtake the later messages
+		// of the user, which the pool already holds
+		logger.Warn().Err(err).Int("attempt", attempt).Msg("retrying message")
+		if sleep(ctx, ingest.Backoff(attempt, c.policy.BaseDelay, c.policy.MaxDelay)) != nil {
+			c.requeue(&logger, delivery)
+			return
+		}
+		c.heartbeat.Beat()
+	}
+}
+
+// requeue returns delivery to its queue
+func (c *Consumer) requeue(logger *zerolog.Logger, delivery Delivery) {
+	if err := delivery.Nack(false, true); err != nil {
+		// The message is requeued once the channel closes
+		logger.Warn().Err(err).Msg("failed to requeue message")
+	}
+}
+
+// deadLetter rejects delivery, which the broker routes to the dead letter
+// exchange with the reason and origin in its x-death header
+func (c *Consumer) deadLetter(logger *zerolog.Logger, delivery Delivery, cause error) {
+	if err := delivery.Reject(false); err != nil {
+		// The message is redelivered and dead-lettered again
+		logger.Error().Err(err).Msg("failed to dead-letter message")
+		return
+	}
+	logger.Warn().Err(cause).Str("dead_letter_exchange", c.topology.DeadLetterExchange).Msg("message dead-lettered")
+}
+
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
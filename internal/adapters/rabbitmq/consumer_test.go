@@ -0,0 +1,438 @@
+package rabbitmq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"transaction-service/internal/adapters/ingest"
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/health"
+	"transaction-service/internal/processor"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBroker hands out channels delivering its messages. Requeued messages
+// are delivered again, and the consumer is cancelled once every message was
+// acked or rejected.
+type fakeBroker struct {
+	channelErrs []error
+	cancel      context.CancelFunc
+
+	mu         sync.Mutex
+	channels   []*fakeChannel
+	bodies     map[uint64][]byte
+	pending    int
+	settled    []string
+	deliveries chan Delivery
+}
+
+func newFakeBroker(cancel context.CancelFunc, bodies ...[]byte) *fakeBroker {
+	b := &fakeBroker{cancel: cancel, bodies: make(map[uint64][]byte), deliveries: make(chan Delivery, 10)}
+	for i, body := range bodies {
+		tag := uint64(i + 1)
+		b.bodies[tag] = body
+		b.pending++
+		b.deliveries <- Delivery{Acknowledger: b, DeliveryTag: tag, RoutingKey: "wallet.credit", Body: body}
+	}
+	return b
+}
+
+func (b *fakeBroker) Channel(ctx context.Context) (Channel, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.channelErrs) > 0 {
+		err := b.channelErrs[0]
+		b.channelErrs = b.channelErrs[1:]
+		return nil, err
+	}
+	ch := &fakeChannel{broker: b}
+	b.channels = append(b.channels, ch)
+	return ch, nil
+}
+
+func (b *fakeBroker) settle(how string, done bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.settled = append(b.settled, how)
+	if done {
+		b.pending--
+		if b.pending == 0 {
+			b.cancel()
+		}
+	}
+}
+
+func (b *fakeBroker) Ack(tag uint64, multiple bool) error {
+	b.settle("ack", true)
+	return nil
+}
+
+func (b *fakeBroker) Nack(tag uint64, multiple, requeue bool) error {
+	if !requeue {
+		b.settle("dead-letter", true)
+		return nil
+	}
+	b.settle("requeue", false)
+	b.deliveries <- Delivery{Acknowledger: b, DeliveryTag: tag, Redelivered: true, RoutingKey: "wallet.credit", Body: b.bodies[tag]}
+	return nil
+}
+
+func (b *fakeBroker) Reject(tag uint64, requeue bool) error {
+	if requeue {
+		return b.Nack(tag, false, true)
+	}
+	b.settle("dead-letter", true)
+	return nil
+}
+
+func (b *fakeBroker) settlements() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.settled...)
+}
+
+type fakeChannel struct {
+	broker *fakeBroker
+
+	declared []string
+	prefetch int
+	closed   bool
+}
+
+func (ch *fakeChannel) Qos(prefetchCount, prefetchSize int, global bool) error {
+	ch.prefetch = prefetchCount
+	return nil
+}
+
+func (ch *fakeChannel) ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args Table) error {
+	ch.declared = append(ch.declared, fmt.Sprintf("exchange %s %s", name, kind))
+	return nil
+}
+
+func (ch *fakeChannel) QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args Table) (Queue, error) {
+	ch.declared = append(ch.declared, fmt.Sprintf("queue %s %v", name, args))
+	return Queue{Name: name}, nil
+}
+
+func (ch *fakeChannel) QueueBind(name, key, exchange string, noWait bool, args Table) error {
+	ch.declared = append(ch.declared, fmt.Sprintf("bind %s %q %s", name, key, exchange))
+	return nil
+}
+
+func (ch *fakeChannel) Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args Table) (<-chan Delivery, error) {
+	return ch.broker.deliveries, nil
+}
+
+func (ch *fakeChannel) Close() error {
+	ch.closed = true
+	return nil
+}
+
+// fakeProcessor fails each transaction ID with the queued errors before
+// applying it
+type fakeProcessor struct {
+	errs    map[string][]error
+	calls   map[string]int
+	applied []entities.TransactionRequest
+}
+
+func (p *fakeProcessor) ProcessTransaction(
+	ctx context.Context,
+	userID uint64,
+	req entities.TransactionRequest,
+	sourceType entities.SourceType,
+) (*entities.TransactionResult, error) {
+	p.calls[req.TransactionID]++
+	if errs := p.errs[req.TransactionID]; len(errs) > 0 {
+		p.errs[req.TransactionID] = errs[1:]
+		return nil, errs[0]
+	}
+	p.applied = append(p.applied, req)
+	return &entities.TransactionResult{UserID: userID, TransactionID: req.TransactionID}, nil
+}
+
+func eventBody(transactionID string) []byte {
+	body, _ := json.Marshal(ingest.TransactionEvent{UserID: 1, State: "win", Amount: "5.00", TransactionID: transactionID})
+	return body
+}
+
+var testTopology = Topology{
+	Exchange:           "wallet",
+	ExchangeKind:       "topic",
+	BindingKeys:        []string{"wallet.credit", "wallet.debit"},
+	Queue:              "wallet-transactions",
+	DeadLetterExchange: "wallet-dlx",
+	DeadLetterQueue:    "wallet-transactions-dlq",
+	Prefetch:           20,
+	ConsumerTag:        "transaction-service",
+}
+
+var testPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+
+func TestConsumer(t *testing.T) {
+	unavailable := fmt.Errorf("failed to get user: %w", services.ErrUnavailable)
+
+	tests := []struct {
+		name        string
+		body        []byte
+		errs        []error
+		wantCalls   int
+		wantSettled []string
+		wantApplied bool
+	}{
+		{
+			name:        "applied messages are acked",
+			body:        eventBody("tx-1"),
+			wantCalls:   1,
+			wantSettled: []string{"ack"},
+			wantApplied: true,
+		},
+		{
+			name:        "undecodable messages are dead-lettered",
+			body:        []byte("{"),
+			wantSettled: []string{"dead-letter"},
+		},
+		{
+			name:        "rejected transactions are dead-lettered",
+			body:        eventBody("tx-1"),
+			errs:        []error{services.ErrInsufficientFunds},
+			wantCalls:   1,
+			wantSettled: []string{"dead-letter"},
+		},
+		{
+			name:        "outages are requeued until they pass",
+			body:        eventBody("tx-1"),
+			errs:        []error{unavailable, unavailable},
+			wantCalls:   3,
+			wantSettled: []string{"requeue", "requeue", "ack"},
+			wantApplied: true,
+		},
+		{
+			name:        "unexpected errors are retried in place until the last attempt",
+			body:        eventBody("tx-1"),
+			errs:        []error{errors.New("boom"), errors.New("boom"), errors.New("boom")},
+			wantCalls:   3,
+			wantSettled: []string{"dead-letter"},
+		},
+		{
+			name:        "unexpected errors may pass on retry",
+			body:        eventBody("tx-1"),
+			errs:        []error{errors.New("boom")},
+			wantCalls:   2,
+			wantSettled: []string{"ack"},
+			wantApplied: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			broker := newFakeBroker(cancel, tt.body)
+			processor := &fakeProcessor{
+				errs:  map[string][]error{"tx-1": tt.errs},
+				calls: make(map[string]int),
+			}
+			consumer := NewConsumer(broker, testTopology, processor, entities.SourceTypePayment, testPolicy, nil, zerolog.Nop())
+
+			consumer.Run(ctx)
+
+			assert.Equal(t, tt.wantSettled, broker.settlements())
+			assert.Equal(t, tt.wantCalls, processor.calls["tx-1"])
+			assert.Equal(t, tt.wantApplied, len(processor.applied) == 1)
+			require.Len(t, broker.channels, 1)
+			assert.True(t, broker.channels[0].closed, "the channel is closed on shutdown")
+		})
+	}
+}
+
+// gatedProcessor applies transactions once their gate, if any, is closed,
+// after failing them with their queued errors
+type gatedProcessor struct {
+	gates map[string]chan struct{}
+
+	mu      sync.Mutex
+	errs    map[string][]error
+	applied []string
+}
+
+func (p *gatedProcessor) ProcessTransaction(
+	ctx context.Context,
+	userID uint64,
+	req entities.TransactionRequest,
+	sourceType entities.SourceType,
+) (*entities.TransactionResult, error) {
+	if gate, ok := p.gates[req.TransactionID]; ok {
+		<-gate
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if errs := p.errs[req.TransactionID]; len(errs) > 0 {
+		p.errs[req.TransactionID] = errs[1:]
+		return nil, errs[0]
+	}
+	p.applied = append(p.applied, req.TransactionID)
+	return &entities.TransactionResult{UserID: userID, TransactionID: req.TransactionID}, nil
+}
+
+func (p *gatedProcessor) appliedTransactions() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.applied...)
+}
+
+func TestConsumer_Pool(t *testing.T) {
+	event := func(userID uint64, transactionID string) []byte {
+		body, _ := json.Marshal(ingest.TransactionEvent{UserID: userID, State: "win", Amount: "5.00", TransactionID: transactionID})
+		return body
+	}
+
+	pool := processor.New(2, 10)
+	poolCtx, stopPool := context.WithCancel(context.Background())
+	poolDone := make(chan struct{})
+	go func() {
+		defer close(poolDone)
+		pool.Run(poolCtx)
+	}()
+	defer func() {
+		stopPool()
+		<-poolDone
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	broker := newFakeBroker(cancel, event(1, "tx-1"), event(2, "tx-2"), event(1, "tx-3"), []byte("{"))
+	release := make(chan struct{})
+	gated := &gatedProcessor{
+		gates: map[string]chan struct{}{"tx-1": release},
+		errs:  map[string][]error{"tx-1": {fmt.Errorf("failed to get user: %w", services.ErrUnavailable)}},
+	}
+	consumer := NewConsumer(broker, testTopology, gated, entities.SourceTypePayment, testPolicy, nil, zerolog.Nop(), WithPool(pool))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		consumer.Run(ctx)
+	}()
+
+	// Other users are not held back by tx-1
+	require.Eventually(t, func() bool {
+		return len(gated.appliedTransactions()) == 1
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, []string{"tx-2"}, gated.appliedTransactions())
+
+	close(release)
+	<-done
+	assert.Equal(t, []string{"tx-2", "tx-1", "tx-3"}, gated.appliedTransactions(), "the transactions of a user keep their order")
+	assert.ElementsMatch(t, []string{"ack", "ack", "ack", "dead-letter"}, broker.settlements(), "outages are retried in place rather than requeued")
+}
+
+func TestConsumer_Heartbeat(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	broker := newFakeBroker(cancel)
+	heartbeat := health.NewHeartbeat(50 * time.Millisecond)
+	processor := &fakeProcessor{calls: make(map[string]int)}
+	consumer := NewConsumer(broker, testTopology, processor, entities.SourceTypePayment, testPolicy, nil, zerolog.Nop(),
+		WithHeartbeat(heartbeat, 5*time.Millisecond))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		consumer.Run(ctx)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	assert.NoError(t, heartbeat.Check(ctx), "an idle consumer beats")
+	assert.NoError(t, consumer.Check(ctx))
+	cancel()
+	<-done
+}
+
+func TestConsumer_Topology(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	broker := newFakeBroker(cancel, eventBody("tx-1"))
+	processor := &fakeProcessor{calls: make(map[string]int)}
+	consumer := NewConsumer(broker, testTopology, processor, entities.SourceTypePayment, testPolicy, nil, zerolog.Nop())
+
+	consumer.Run(ctx)
+
+	require.Len(t, broker.channels, 1)
+	ch := broker.channels[0]
+	assert.Equal(t, 20, ch.prefetch)
+	assert.Equal(t, []string{
+		"exchange wallet-dlx fanout",
+		"queue wallet-transactions-dlq map[]",
+		`bind wallet-transactions-dlq "" wallet-dlx`,
+		"queue wallet-transactions map[x-dead-letter-exchange:wallet-dlx]",
+		"exchange wallet topic",
+		`bind wallet-transactions "wallet.credit" wallet`,
+		`bind wallet-transactions "wallet.debit" wallet`,
+	}, ch.declared)
+}
+
+func TestConsumer_Reconnect(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	broker := newFakeBroker(cancel)
+	broker.channelErrs = []error{errors.New("connection refused")}
+	processor := &fakeProcessor{calls: make(map[string]int)}
+	consumer := NewConsumer(broker, testTopology, processor, entities.SourceTypePayment, testPolicy, nil, zerolog.Nop())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		consumer.Run(ctx)
+	}()
+
+	// The first channel fails to open, the second consumes
+	require.Eventually(t, func() bool {
+		broker.mu.Lock()
+		defer broker.mu.Unlock()
+		return len(broker.channels) == 1
+	}, time.Second, time.Millisecond)
+	require.Eventually(t, func() bool {
+		return consumer.Check(ctx) == nil
+	}, time.Second, time.Millisecond)
+
+	// A closed delivery channel is reported until a new channel consumes
+	close(broker.deliveries)
+	require.Eventually(t, func() bool {
+		broker.mu.Lock()
+		defer broker.mu.Unlock()
+		return len(broker.channels) > 1
+	}, time.Second, time.Millisecond)
+	cancel()
+	<-done
+	assert.True(t, broker.channels[0].closed)
+}
+
+func TestConsumer_Check(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	broker := newFakeBroker(cancel)
+	broker.channelErrs = []error{errors.New("connection refused")}
+	processor := &fakeProcessor{calls: make(map[string]int)}
+	consumer := NewConsumer(broker, testTopology, processor, entities.SourceTypePayment, RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Hour,
+		MaxDelay:    time.Hour,
+	}, nil, zerolog.Nop())
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	consumer.Run(ctx)
+
+	assert.EqualError(t, consumer.Check(context.Background()), "failed to consume queue: failed to open channel: connection refused")
+}
@@ -0,0 +1,56 @@
+package rabbitmq
+
+import "fmt"
+
+// Topology names the exchanges and queues the consumer declares and reads.
+// Declarations are idempotent, but fail when an existing exchange or queue
+// was declared with other settings.
+type Topology struct {
+	// Exchange is the exchange of ExchangeKind the transactions are published
+	// to. The queue is bound to it with each of BindingKeys. When empty, the
+	// transactions are published to the queue through the default exchange.
+	Exchange     string
+	ExchangeKind string
+	BindingKeys  []string
+	// Queue is the durable queue the transactions are consumed from
+	Queue string
+	// DeadLetterExchange receives the messages rejected from Queue, and
+	// routes them to the durable DeadLetterQueue
+	DeadLetterExchange string
+	DeadLetterQueue    string
+	// Prefetch bounds the messages delivered to the consumer before it acked
+	// them
+	Prefetch    int
+	ConsumerTag string
+}
+
+// declare declares the dead letter exchange and queue, the exchange and the
+// queue, and binds them
+func (t Topology) declare(ch Channel) error {
+	if err := ch.ExchangeDeclare(t.DeadLetterExchange, "fanout", true, false, false, false, nil); err != nil {
+		return fmt.Errorf("failed to declare dead letter exchange: %w", err)
+	}
+	if _, err := ch.QueueDeclare(t.DeadLetterQueue, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("failed to declare dead letter queue: %w", err)
+	}
+	if err := ch.QueueBind(t.DeadLetterQueue, "", t.DeadLetterExchange, false, nil); err != nil {
+		return fmt.Errorf("failed to bind dead letter queue: %w", err)
+	}
+
+	args := Table{"x-dead-letter-exchange": t.DeadLetterExchange}
+	if _, err := ch.QueueDeclare(t.Queue, true, false, false, false, args); err != nil {
+		return fmt.Errorf("failed to declare queue: %w", err)
+	}
+	if t.Exchange == "" {
+		return nil
+	}
+	if err := ch.ExchangeDeclare(t.Exchange, t.ExchangeKind, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("failed to declare exchange: %w", err)
+	}
+	for _, key := range t.BindingKeys {
+		if err := ch.QueueBind(t.Queue, key, t.Exchange, false, nil); err != nil {
+			return fmt.Errorf("failed to bind queue with %q: %w", key, err)
+		}
+	}
+	return nil
+}
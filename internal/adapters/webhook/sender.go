@@ -0,0 +1,56 @@
+// Package webhook posts webhook deliveries over HTTP
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"transaction-service/internal/adapters/httpclient"
+)
+
+// maxDrainedBody bounds how much of a response body is read so that the
+// connection can be reused
+const maxDrainedBody = 64 << 10
+
+// HTTPSender posts deliveries with an HTTP client. Attempts are bounded by
+// the deadline of their context.
+type HTTPSender struct {
+	client *http.Client
+}
+
+// NewHTTPSender creates a new HTTPSender posting with a copy of client.
+// Redirects are not followed, so a webhook URL cannot bounce deliveries
+// elsewhere.
+func NewHTTPSender(client *http.Client) *HTTPSender {
+	sender := &HTTPSender{client: new(http.Client)}
+	*sender.client = *client
+	sender.client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return sender
+}
+
+// Send posts body to url with the given headers and returns the response
+// status code. Receivers deduplicate deliveries by their delivery header, so
+// the client may retry a post whose response was lost.
+func (s *HTTPSender) Send(ctx context.Context, url string, headers map[string]string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(httpclient.WithReplay(ctx), http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainedBody))
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,48 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPSender_Send(t *testing.T) {
+	var gotBody, gotSignature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		gotSignature = r.Header.Get("X-Webhook-Signature")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	status, err := NewHTTPSender(http.DefaultClient).Send(context.Background(), server.URL, map[string]string{
+		"X-Webhook-Signature": "sha256=abc",
+	}, []byte(`{"id":1}`))
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, status)
+	assert.Equal(t, `{"id":1}`, gotBody)
+	assert.Equal(t, "sha256=abc", gotSignature)
+}
+
+func TestHTTPSender_DoesNotFollowRedirects(t *testing.T) {
+	redirected := false
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redirected = true
+	}))
+	defer target.Close()
+	server := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	defer server.Close()
+
+	status, err := NewHTTPSender(http.DefaultClient).Send(context.Background(), server.URL, nil, []byte(`{}`))
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusTemporaryRedirect, status)
+	assert.False(t, redirected)
+}
@@ -0,0 +1,55 @@
+// Package apiversion locates the version of the REST API in request paths.
+// Each version is served under its own prefix, e.g. /api/v1/user/1/balance,
+// so that a new version can change the contract while clients of the
+// previous one keep working. Policies such as authentication or SLOs apply
+// to a route in every version, so they look at paths without their version.
+package apiversion
+
+import "strings"
+
+// Prefix is the root of the versioned routes
+const Prefix = "/api"
+
+// The versions of the REST API
+const (
+	V1 = "v1"
+)
+
+// Path returns the prefix of the routes of version
+func Path(version string) string {
+	return Prefix + "/" + version
+}
+
+// Split separates the version from a request path or route template. Paths
+// outside every version are returned unchanged with an empty version.
+func Split(path string) (version, rest string) {
+	trimmed, ok := strings.CutPrefix(path, Prefix+"/")
+	if !ok {
+		return "", path
+	}
+	version, rest, _ = strings.Cut(trimmed, "/")
+	if !isVersion(version) {
+		return "", path
+	}
+	return version, "/" + rest
+}
+
+// Strip returns path without its version
+func Strip(path string) string {
+	_, rest := Split(path)
+	return rest
+}
+
+// isVersion reports whether segment names a version, "v" and a number
+func isVersion(segment string) bool {
+	digits, ok := strings.CutPrefix(segment, "v")
+	if !ok || digits == "" {
+		return false
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
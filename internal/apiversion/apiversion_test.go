@@ -0,0 +1,30 @@
+package apiversion
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		path    string
+		version string
+		rest    string
+	}{
+		{"/api/v1/user/:userId/balance", "v1", "/user/:userId/balance"},
+		{"/api/v2/admin/jobs/1", "v2", "/admin/jobs/1"},
+		{"/api/v1", "v1", "/"},
+		{"/user/1/balance", "", "/user/1/balance"},
+		{"/api/docs", "", "/api/docs"},
+		{"/api/v/user", "", "/api/v/user"},
+		{"/apiv1/user", "", "/apiv1/user"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			version, rest := Split(tt.path)
+			assert.Equal(t, tt.version, version)
+			assert.Equal(t, tt.rest, rest)
+		})
+	}
+}
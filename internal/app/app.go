@@ -0,0 +1,1287 @@
+// Package app wires the transaction service together from its configuration
+// and runs the components a command deploys: the HTTP and gRPC APIs, the
+// background workers, or both.
+package app
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"transaction-service/internal/adapters/alerting"
+	"transaction-service/internal/adapters/cache"
+	"transaction-service/internal/adapters/cdc"
+	"transaction-service/internal/adapters/database"
+	"transaction-service/internal/adapters/dualwrite"
+	"transaction-service/internal/adapters/events"
+	grpcadapter "transaction-service/internal/adapters/grpc"
+	"transaction-service/internal/adapters/grpc/transactionpb"
+	"transaction-service/internal/adapters/handlers"
+	"transaction-service/internal/adapters/httpclient"
+	"transaction-service/internal/adapters/idgen"
+	"transaction-service/internal/adapters/kafka"
+	"transaction-service/internal/adapters/memory"
+	"transaction-service/internal/adapters/metrics"
+	"transaction-service/internal/adapters/nats"
+	"transaction-service/internal/adapters/rabbitmq"
+	"transaction-service/internal/adapters/webhook"
+	"transaction-service/internal/apiversion"
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/auth"
+	"transaction-service/internal/clock"
+	"transaction-service/internal/config"
+	"transaction-service/internal/consistency"
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+	"transaction-service/internal/health"
+	"transaction-service/internal/logging"
+	"transaction-service/internal/processor"
+	"transaction-service/internal/region"
+	"transaction-service/internal/worker"
+
+	"github.com/gin-gonic/gin"
+	"github.com/joho/godotenv"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+	"github.com/shopspring/decimal"
+	"google.golang.org/grpc"
+)
+
+// consumerIdleInterval is how long the broker consumers wait for messages
+// before they beat their heartbeat and wait again
+const consumerIdleInterval = 10 * time.Second
+
+// Component is a part of the service a process runs
+type Component int
+
+const (
+	// Server serves the HTTP and gRPC APIs along with the work requests hand
+	// off to the background, such as bulk jobs and storage migration writes
+	Server Component = 1 << iota
+	// Workers runs the background workers: outbox relay, change data
+	// capture, webhook delivery, hold expiry, scheduled transactions,
+	// balance snapshots, settlement, cancellation, dormancy and the broker
+	// consumers. Only the health and metrics endpoints are served.
+	Workers
+)
+
+// Run runs components until SIGINT or SIGTERM and drains them. It returns the
+// process exit code.
+func Run(components Component) int {
+	// Load environment variables
+	envErr := godotenv.Load()
+
+	// Load the application configuration
+	cfg, err := config.Load()
+	if err != nil {
+		bootstrap := zerolog.New(os.Stderr).With().Timestamp().Logger()
+		bootstrap.Fatal().Err(err).Msg("failed to load configuration")
+	}
+
+	// Initialize the structured logger
+	logger, err := logging.New(cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		bootstrap := zerolog.New(os.Stderr).With().Timestamp().Logger()
+		bootstrap.Fatal().Err(err).Msg("invalid log configuration")
+	}
+	if envErr != nil {
+		logger.Info().Msg("no .env file found, using default environment variables")
+	}
+	logEffectiveConfig(logger, cfg)
+	serveAPI := components&Server != 0
+	runWorkers := components&Workers != 0
+
+	// SIGINT and SIGTERM start a graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Initialize the database; the memory storage backend needs none
+	var db *sql.DB
+	var memoryStore *memory.Store
+	if cfg.Storage == "memory" {
+		memoryStore = memory.NewStore()
+		logger.Warn().Msg("storing data in memory; it is lost on exit")
+	} else {
+		db, err = database.NewConnection(cfg.Database)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to connect to the database")
+		}
+	}
+
+	// Determine whether this region accepts writes
+	regionMode, err := region.ParseMode(cfg.Region.Mode)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("invalid region configuration")
+	}
+	if regionMode == region.ModeStandby && cfg.Region.ActiveURL == "" {
+		logger.Fatal().Msg("REGION_ACTIVE_URL is required in standby mode")
+	}
+	regionState := region.NewState(cfg.Region.Name, regionMode, cfg.Region.ActiveURL, func(ctx context.Context) error {
+		if db == nil {
+			return nil
+		}
+		return database.CheckWritable(ctx, db)
+	})
+
+	// A standby reads from a replica, which receives its schema and users
+	// from the active region
+	var schemaMigrator *database.Migrator
+	if db != nil {
+		schemaMigrator, err = database.NewMigrator(db)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to load migrations")
+		}
+	}
+	switch {
+	case memoryStore != nil:
+		// Seed configured users into the empty store
+		seedUsers := make([]*entities.User, 0, len(cfg.Seed.Users))
+		for _, user := range cfg.Seed.Users {
+			seedUsers = append(seedUsers, &entities.User{ID: user.ID, Balance: user.Balance})
+		}
+		memory.SeedUsers(memoryStore, seedUsers)
+	case regionMode == region.ModeActive:
+		// Run migrations, unless they are run by cmd/migrate
+		if cfg.MigrateOnStart {
+			if err := schemaMigrator.Up(ctx); err != nil {
+				logger.Fatal().Err(err).Msg("failed to run migrations")
+			}
+
+			logger.Info().Int("schema_version", schemaMigrator.Latest()).Msg("database migrations completed successfully")
+		}
+
+		// Seed configured users
+		if err := database.SeedUsers(ctx, db, toSeedUsers(cfg.Seed.Users)); err != nil {
+			logger.Fatal().Err(err).Msg("failed to seed users")
+		}
+	default:
+		logger.Info().Str("active_region_url", cfg.Region.ActiveURL).Msg("starting in standby mode")
+	}
+
+	// Sandbox traffic is served from its own schema with deterministic users
+	var sandboxDB *sql.DB
+	if len(cfg.Sandbox.APIKeys) > 0 {
+		if regionMode == region.ModeActive && cfg.MigrateOnStart {
+			if err := database.CreateSchema(ctx, db, cfg.Sandbox.Schema); err != nil {
+				logger.Fatal().Err(err).Msg("failed to create sandbox schema")
+			}
+		}
+		sandboxDBConfig := cfg.Database
+		sandboxDBConfig.Schema = cfg.Sandbox.Schema
+		sandboxDB, err = database.NewPostgresConnection(sandboxDBConfig)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to connect to the sandbox database")
+		}
+		if regionMode == region.ModeActive {
+			if cfg.MigrateOnStart {
+				if err := database.RunMigrations(sandboxDB); err != nil {
+					logger.Fatal().Err(err).Msg("failed to run sandbox migrations")
+				}
+			}
+			if err := database.SeedUsers(ctx, sandboxDB, toSeedUsers(cfg.Sandbox.Users)); err != nil {
+				logger.Fatal().Err(err).Msg("failed to seed sandbox users")
+			}
+		}
+	}
+
+	prometheusMetrics := metrics.NewPrometheus(db)
+	// Initialize repositories. Transient database errors are retried.
+	retrier := database.NewRetrier(database.RetryPolicy{
+		MaxAttempts:              cfg.DatabaseRetry.MaxAttempts,
+		SerializationMaxAttempts: cfg.DatabaseRetry.SerializationMaxAttempts,
+		BaseDelay:                cfg.DatabaseRetry.BaseDelay,
+		MaxDelay:                 cfg.DatabaseRetry.MaxDelay,
+	}, logger, prometheusMetrics)
+	var userRepo repositories.UserRepository
+	var transactionRepo repositories.TransactionRepository
+	var walletRepo repositories.WalletRepository
+	var annotationRepo repositories.AnnotationRepository
+	var unitOfWork repositories.UnitOfWork
+	if memoryStore != nil {
+		userRepo = memory.NewUserRepository(memoryStore)
+		transactionRepo = memory.NewTransactionRepository(memoryStore)
+		walletRepo = memory.NewWalletRepository(memoryStore)
+		annotationRepo = memory.NewAnnotationRepository(memoryStore)
+		unitOfWork = memory.NewUnitOfWork(memoryStore)
+	} else {
+		repos := database.NewRepositories(db)
+		if cfg.UserAdvisoryLocks {
+			repos.Users = database.WithUserAdvisoryLocks(repos.Users)
+		}
+		userRepo = retrier.UserRepository(repos.Users)
+		transactionRepo = retrier.TransactionRepository(repos.Transactions)
+		walletRepo = retrier.WalletRepository(repos.Wallets)
+		annotationRepo = repos.Annotations
+		unitOfWork = retrier.UnitOfWork(database.NewUnitOfWork(db))
+	}
+	var holdRepo repositories.HoldRepository
+	if cfg.Holds.Enabled {
+		holdRepo = retrier.HoldRepository(database.NewHoldRepository(db))
+	}
+
+	// During a storage migration, writes go to both databases: the primary
+	// serves the service and the other is written in the background
+	var migrationDB *sql.DB
+	var migrator *dualwrite.Migrator
+	if cfg.StorageMigration.Phase != "off" {
+		phase, err := dualwrite.ParsePhase(cfg.StorageMigration.Phase)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("invalid storage migration configuration")
+		}
+		migrationDB, err = database.NewPostgresConnection(cfg.StorageMigration.Target)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to connect to the storage migration target database")
+		}
+		if regionMode == region.ModeActive {
+			if cfg.MigrateOnStart {
+				if err := database.RunMigrations(migrationDB); err != nil {
+					logger.Fatal().Err(err).Msg("failed to run storage migration target migrations")
+				}
+			}
+			// The target may have been promoted by another instance
+			if phase == dualwrite.PhaseCutover {
+				if err := database.AdvanceSequences(ctx, migrationDB); err != nil {
+					logger.Fatal().Err(err).Msg("failed to advance storage migration target sequences")
+				}
+			}
+		}
+
+		source := dualwrite.Store{
+			UnitOfWork:   unitOfWork,
+			Users:        userRepo,
+			Wallets:      walletRepo,
+			Transactions: transactionRepo,
+			Holds:        holdRepo,
+			Annotations:  annotationRepo,
+			Promote: func(ctx context.Context) error {
+				return database.AdvanceSequences(ctx, db)
+			},
+		}
+		target := dualwrite.Store{
+			UnitOfWork:   retrier.UnitOfWork(database.NewUnitOfWork(migrationDB)),
+			Users:        retrier.UserRepository(database.NewUserRepository(migrationDB)),
+			Wallets:      retrier.WalletRepository(database.NewWalletRepository(migrationDB)),
+			Transactions: retrier.TransactionRepository(database.NewTransactionRepository(migrationDB)),
+			Annotations:  database.NewAnnotationRepository(migrationDB),
+			Promote: func(ctx context.Context) error {
+				return database.AdvanceSequences(ctx, migrationDB)
+			},
+		}
+		if cfg.Holds.Enabled {
+			target.Holds = retrier.HoldRepository(database.NewHoldRepository(migrationDB))
+		}
+
+		migrator = dualwrite.NewMigrator(source, target, phase, cfg.StorageMigration.QueueSize, logger)
+		unitOfWork = migrator.UnitOfWork()
+		userRepo = migrator.Users()
+		walletRepo = migrator.Wallets()
+		transactionRepo = migrator.Transactions()
+		annotationRepo = migrator.Annotations()
+		if cfg.Holds.Enabled {
+			holdRepo = migrator.Holds()
+		}
+		logger.Info().Str("phase", string(phase)).Msg("storage migration enabled")
+	}
+
+	// The reads of GET requests may be served by a read replica. Writes hand
+	// out consistency tokens so that clients can still read their writes.
+	var replicaDB *sql.DB
+	if cfg.ReplicaReads.Enabled {
+		replicaDB, err = database.NewPostgresConnection(cfg.ReplicaReads.Replica)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to connect to the read replica")
+		}
+		replicaReads := database.NewReplicaReads(replicaDB)
+		userRepo = replicaReads.UserRepository(userRepo, retrier.UserRepository(database.NewUserRepository(replicaDB)))
+		transactionRepo = replicaReads.TransactionRepository(transactionRepo,
+			retrier.TransactionRepository(database.NewTransactionRepository(replicaDB)))
+		logger.Info().Str("host", cfg.ReplicaReads.Replica.Host).Msg("replica reads enabled")
+	}
+
+	// Initialize services
+	clockSkewPolicy := services.ClockSkewPolicy{
+		Default:   cfg.ClockSkew.Default,
+		PerSource: make(map[entities.SourceType]time.Duration, len(cfg.ClockSkew.PerSource)),
+	}
+	for source, tolerance := range cfg.ClockSkew.PerSource {
+		sourceType := entities.SourceType(source)
+		if !sourceType.IsValid() {
+			logger.Fatal().Str("source_type", source).Msg("invalid source type in clock skew configuration")
+		}
+		clockSkewPolicy.PerSource[sourceType] = tolerance
+	}
+	amountPolicy := services.AmountPolicy{
+		MaxDecimalPlaces: int32(cfg.Amounts.MaxDecimalPlaces),
+		Default:          services.AmountLimits{Min: cfg.Amounts.Min, Max: cfg.Amounts.Max},
+		PerSource:        make(map[entities.SourceType]services.AmountLimits),
+	}
+	for _, perSource := range []map[string]decimal.Decimal{cfg.Amounts.MinPerSource, cfg.Amounts.MaxPerSource} {
+		for source := range perSource {
+			sourceType := entities.SourceType(source)
+			if !sourceType.IsValid() {
+				logger.Fatal().Str("source_type", source).Msg("invalid source type in amount configuration")
+			}
+			limits := amountPolicy.Default
+			if lower, ok := cfg.Amounts.MinPerSource[source]; ok {
+				limits.Min = lower
+			}
+			if upper, ok := cfg.Amounts.MaxPerSource[source]; ok {
+				limits.Max = upper
+			}
+			amountPolicy.PerSource[sourceType] = limits
+		}
+	}
+	velocityPolicy := services.VelocityPolicy{
+		MaxTransactions: cfg.Velocity.MaxTransactions,
+		MaxLoss:         cfg.Velocity.MaxLoss,
+		Window:          cfg.Velocity.Window,
+	}
+	idGenerator, err := idgen.New(cfg.IDs.Strategy, cfg.IDs.NodeID)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("invalid ID generation configuration")
+	}
+	currency, ok := entities.LookupCurrency(cfg.Currency)
+	if !ok {
+		logger.Fatal().Str("currency", cfg.Currency).Msg("unsupported currency")
+	}
+	walletCurrencies := make([]string, 0, len(cfg.Currencies))
+	for _, code := range cfg.Currencies {
+		walletCurrency, ok := entities.LookupCurrency(code)
+		if !ok {
+			logger.Fatal().Str("currency", code).Msg("unsupported currency in CURRENCIES")
+		}
+		walletCurrencies = append(walletCurrencies, walletCurrency.Code)
+	}
+	sloTracker := metrics.NewSLOTracker(cfg.SLO)
+	prometheusMetrics.TrackSLOs(sloTracker)
+	// Outbound HTTP requests share a connection pool per destination
+	httpClients, err := httpclient.NewPool(cfg.HTTPClient.Default, cfg.HTTPClient.Destinations, prometheusMetrics)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("invalid HTTP client configuration")
+	}
+	// Duplicate submissions are also counted per minute for the exactly-once
+	// verification
+	duplicateTracker := services.NewDuplicateTracker()
+	serviceOpts := []services.TransactionServiceOption{
+		services.WithClockSkewPolicy(clockSkewPolicy),
+		services.WithAmountPolicy(amountPolicy),
+		services.WithVelocityLimits(velocityPolicy),
+		services.WithIDGenerator(idGenerator),
+		services.WithRegionGate(regionState),
+		services.WithLogger(logger),
+		services.WithMetrics(prometheusMetrics, duplicateTracker),
+		services.WithCurrencies(walletRepo, currency.Code, walletCurrencies...),
+	}
+	if cfg.Holds.Enabled {
+		serviceOpts = append(serviceOpts, services.WithHolds(holdRepo))
+	}
+	// Payment credits stay pending until settled by the settlement worker or
+	// an operator
+	if cfg.Settlement.Enabled {
+		serviceOpts = append(serviceOpts, services.WithSettlement(cfg.Settlement.Delay))
+	}
+	// Background workers share this context and are drained on shutdown
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	var workers sync.WaitGroup
+	startWorker := func(run func(context.Context)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			run(workerCtx)
+		}()
+	}
+
+	// Periodic workers beat after every run. A worker that made no progress
+	// for its interval plus the stall timeout fails the liveness probe.
+	livenessChecker := health.NewChecker(cfg.Readiness.CheckTimeout, nil)
+	workerHeartbeat := func(name string, interval time.Duration) *health.Heartbeat {
+		heartbeat := health.NewHeartbeat(interval + cfg.Liveness.StallTimeout)
+		livenessChecker.Register(name, health.PolicyRequired, heartbeat.Check)
+		return heartbeat
+	}
+
+	// Redis is shared by cache invalidation and rate limiting
+	var redisClient *redis.Client
+	if cfg.Redis.Addr != "" {
+		redisClient = redis.NewClient(&redis.Options{
+			Addr:     cfg.Redis.Addr,
+			Password: cfg.Redis.Password,
+			DB:       cfg.Redis.DB,
+		})
+	}
+
+	if cfg.StaleBalance.Enabled {
+		balanceCache := cache.NewMemoryBalanceCache()
+		serviceOpts = append(serviceOpts, services.WithStaleBalanceFallback(
+			balanceCache, cfg.StaleBalance.MaxStaleness,
+		))
+		if redisClient != nil {
+			invalidator := cache.NewRedisInvalidator(redisClient, cfg.Redis.InvalidationChannel, balanceCache, logger)
+			startWorker(invalidator.Run)
+			serviceOpts = append(serviceOpts, services.WithBalanceInvalidator(invalidator))
+		}
+	}
+	if cfg.Guard.Enabled {
+		action := services.GuardAction(cfg.Guard.Action)
+		if !action.IsValid() {
+			logger.Fatal().Str("action", cfg.Guard.Action).Msg("invalid balance guard action")
+		}
+		balanceGuard := services.NewBalanceGuard(services.BalanceGuardPolicy{
+			Window:           cfg.Guard.Window,
+			MaxChange:        cfg.Guard.MaxChange,
+			MaxChangePercent: cfg.Guard.MaxChangePercent,
+			Action:           action,
+			FreezeOnTrip:     cfg.Guard.FreezeOnTrip,
+		}, userRepo, transactionRepo, alerting.NewLogNotifier(logger))
+		serviceOpts = append(serviceOpts, services.WithBalanceGuard(balanceGuard))
+	}
+	var jurisdictionRules services.JurisdictionRules
+	if len(cfg.Jurisdictions) > 0 {
+		jurisdictionRules = make(services.JurisdictionRules, len(cfg.Jurisdictions))
+		for code, rule := range cfg.Jurisdictions {
+			disabled := make([]entities.SourceType, 0, len(rule.DisabledSources))
+			for _, source := range rule.DisabledSources {
+				sourceType := entities.SourceType(source)
+				if !sourceType.IsValid() {
+					logger.Fatal().Str("jurisdiction", code).Str("source_type", source).
+						Msg("invalid source type in jurisdiction configuration")
+				}
+				disabled = append(disabled, sourceType)
+			}
+			jurisdictionRules[code] = services.JurisdictionRule{
+				DisabledSources: disabled,
+				LossLimit:       rule.LossLimit,
+				LossWindow:      rule.LossWindow,
+				DormancyFee:     rule.DormancyFee,
+				FreezeDormant:   rule.FreezeDormant,
+			}
+		}
+		serviceOpts = append(serviceOpts, services.WithJurisdictionRules(jurisdictionRules))
+	}
+	// Broker clients connect on first use, and are closed once the workers
+	// stopped. The Kafka consumer and publishers share one writer, and the
+	// NATS consumer and publishers one connection.
+	var brokerClients []io.Closer
+	var kafkaWriter *kafka.ClientWriter
+	if len(cfg.Kafka.Brokers) > 0 {
+		kafkaWriter = kafka.NewClientWriter(cfg.Kafka.Brokers)
+		brokerClients = append(brokerClients, kafkaWriter)
+	}
+	var natsClient *nats.Client
+	if cfg.NATS.URL != "" {
+		natsClient, err = nats.Connect(cfg.NATS.URL)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to connect to NATS")
+		}
+		brokerClients = append(brokerClients, natsClient)
+	}
+	// Balance changes record their events in the outbox, which the relay
+	// publishes to the broker
+	var cancellationOpts []services.CancellationServiceOption
+	dormancyOpts := []services.DormancyServiceOption{services.WithDormancyRules(jurisdictionRules)}
+	if cfg.Outbox.Enabled {
+		if cfg.Outbox.Publisher == "redis" && redisClient == nil {
+			logger.Fatal().Msg("REDIS_ADDR is required for the redis outbox publisher")
+		}
+		if cfg.Outbox.Publisher == "kafka" && kafkaWriter == nil {
+			logger.Fatal().Msg("KAFKA_BROKERS is required for the kafka outbox publisher")
+		}
+		if cfg.Outbox.Publisher == "nats" && natsClient == nil {
+			logger.Fatal().Msg("NATS_URL is required for the nats outbox publisher")
+		}
+		publisher := newEventPublisher(cfg.Outbox.Publisher, cfg.Outbox.Topic, cfg.Outbox.StreamMaxLen, redisClient, kafkaWriter, natsClient, logger)
+		outboxRepo := database.NewOutboxRepository(db)
+		outbox := services.NewOutbox(outboxRepo)
+		serviceOpts = append(serviceOpts, services.WithTransactionHooks(outbox), services.WithSettlementRecorders(outbox))
+		cancellationOpts = append(cancellationOpts, services.WithCancellationRecorders(outbox))
+		dormancyOpts = append(dormancyOpts, services.WithDormancyRecorders(outbox))
+
+		if runWorkers {
+			relay := services.NewOutboxRelay(unitOfWork, outboxRepo, publisher)
+			relayWorker := worker.NewOutboxRelayWorker(
+				relay, cfg.Outbox.RelayInterval, cfg.Outbox.RelayBatchSize, regionState,
+				workerHeartbeat("outbox_relay_worker", cfg.Outbox.RelayInterval), logger,
+			)
+			startWorker(relayWorker.Run)
+		}
+	}
+	// Change data capture publishes every change to the transactions and
+	// users tables from a replication slot, whichever code path made it
+	if cfg.CDC.Enabled && runWorkers {
+		if cfg.CDC.Publisher == "redis" && redisClient == nil {
+			logger.Fatal().Msg("REDIS_ADDR is required for the redis change publisher")
+		}
+		if cfg.CDC.Publisher == "kafka" && kafkaWriter == nil {
+			logger.Fatal().Msg("KAFKA_BROKERS is required for the kafka change publisher")
+		}
+		if cfg.CDC.Publisher == "nats" && natsClient == nil {
+			logger.Fatal().Msg("NATS_URL is required for the nats change publisher")
+		}
+		publisher := newEventPublisher(cfg.CDC.Publisher, cfg.CDC.Topic, cfg.CDC.StreamMaxLen, redisClient, kafkaWriter, natsClient, logger)
+		cdcWorker := worker.NewCDCWorker(
+			cdc.NewStream(cfg.Database, cfg.CDC, publisher, logger), cfg.CDC.StatusInterval, regionState,
+			workerHeartbeat("cdc_worker", cfg.CDC.StatusInterval), logger,
+		)
+		startWorker(cdcWorker.Run)
+	}
+	// Webhooks queue their deliveries with the balance change and receive
+	// them from the delivery worker
+	var webhookService *services.WebhookService
+	if cfg.Webhooks.Enabled {
+		webhookService = services.NewWebhookService(
+			database.NewWebhookRepository(db),
+			webhook.NewHTTPSender(httpClients.Client("webhooks")),
+			services.WebhookPolicy{
+				Timeout:     cfg.Webhooks.Timeout,
+				MaxAttempts: cfg.Webhooks.MaxAttempts,
+				BaseDelay:   cfg.Webhooks.RetryBaseDelay,
+				MaxDelay:    cfg.Webhooks.RetryMaxDelay,
+			},
+		)
+		serviceOpts = append(serviceOpts, services.WithTransactionHooks(webhookService), services.WithSettlementRecorders(webhookService))
+		cancellationOpts = append(cancellationOpts, services.WithCancellationRecorders(webhookService))
+		dormancyOpts = append(dormancyOpts, services.WithDormancyRecorders(webhookService))
+
+		if runWorkers {
+			deliveryWorker := worker.NewWebhookDeliveryWorker(
+				webhookService, cfg.Webhooks.DeliveryInterval, cfg.Webhooks.DeliveryBatchSize, regionState,
+				workerHeartbeat("webhook_delivery_worker", cfg.Webhooks.DeliveryInterval), logger,
+			)
+			startWorker(deliveryWorker.Run)
+		}
+	}
+	// Rejected transaction attempts are recorded for the rejection rate reports
+	var rejectionService *services.RejectionService
+	if cfg.RejectionAnalytics {
+		rejectionService = services.NewRejectionService(database.NewRejectionRepository(db))
+		serviceOpts = append(serviceOpts, services.WithRejectionRecorders(rejectionService))
+	}
+	// Every balance-affecting operation is recorded in the audit log
+	var auditService *services.AuditService
+	if cfg.AuditLog {
+		auditService = services.NewAuditService(database.NewAuditLogRepository(db))
+		serviceOpts = append(serviceOpts, services.WithAuditRecorder(auditService))
+		cancellationOpts = append(cancellationOpts, services.WithCancellationAuditRecorder(auditService))
+	}
+	// Balances are recomputed from the transactions on request
+	var balanceCheckService *services.BalanceCheckService
+	if cfg.BalanceChecks {
+		var balanceCheckOpts []services.BalanceCheckServiceOption
+		if auditService != nil {
+			balanceCheckOpts = append(balanceCheckOpts, services.WithBalanceCheckAuditRecorder(auditService))
+		}
+		balanceCheckService = services.NewBalanceCheckService(
+			unitOfWork, userRepo, walletRepo, database.NewBalanceCheckRepository(db), balanceCheckOpts...,
+		)
+	}
+	// System accounts are the counterparty of fees, sweeps and corrections.
+	// Only the active region creates the missing ones.
+	var systemAccountService *services.SystemAccountService
+	if cfg.SystemAccounts {
+		loadSystemAccounts := database.LoadSystemAccounts
+		if regionMode == region.ModeActive {
+			loadSystemAccounts = database.EnsureSystemAccounts
+		}
+		accounts, err := loadSystemAccounts(ctx, db)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to load system accounts")
+		}
+		systemAccountService = services.NewSystemAccountService(accounts, userRepo)
+		serviceOpts = append(serviceOpts, services.WithSystemAccounts(accounts))
+	}
+	// Fees are charged by the admin-managed fee rules
+	var feeService *services.FeeService
+	if cfg.Fees {
+		feeService = services.NewFeeService(database.NewFeeRuleRepository(db))
+		serviceOpts = append(serviceOpts, services.WithFees(feeService))
+	}
+	// Transactions run under SERIALIZABLE isolation, the retrier retrying
+	// their serialization failures
+	if cfg.SerializableIsolation {
+		serviceOpts = append(serviceOpts, services.WithSerializableProcessing())
+	}
+	// Operators credit and debit users by hand, audited in the balance
+	// adjustments table
+	if cfg.BalanceAdjustments {
+		serviceOpts = append(serviceOpts, services.WithBalanceAdjustments(database.NewBalanceAdjustmentRepository(db)))
+	}
+	// Point-in-time balances are replayed from the daily closing balances
+	var snapshotRepo repositories.BalanceSnapshotRepository
+	if cfg.BalanceSnapshots.Enabled {
+		snapshotRepo = retrier.BalanceSnapshotRepository(database.NewBalanceSnapshotRepository(db))
+		serviceOpts = append(serviceOpts, services.WithBalanceSnapshots(snapshotRepo))
+	}
+	// A sample of the requests also runs through the candidate engine, whose
+	// results are compared and logged but never returned. The candidate is the
+	// current engine until a rewrite under evaluation is wired in here.
+	if cfg.Shadow.Percent > 0 {
+		candidate := services.NewTransactionService(unitOfWork, userRepo, transactionRepo, serviceOpts...)
+		serviceOpts = append(serviceOpts, services.WithShadow(candidate, services.ShadowPolicy{
+			Percent:       cfg.Shadow.Percent,
+			Timeout:       cfg.Shadow.Timeout,
+			MaxConcurrent: cfg.Shadow.MaxConcurrent,
+		}, prometheusMetrics))
+	}
+	transactionService := services.NewTransactionService(unitOfWork, userRepo, transactionRepo, serviceOpts...)
+	annotationService := services.NewAnnotationService(annotationRepo, userRepo, transactionRepo)
+	accountService := services.NewAccountService(userRepo)
+	bulkJobService := services.NewBulkJobService(accountService, transactionService, webhookService,
+		services.WithExportDir(cfg.ExportDir), services.WithAnnotationExport(annotationService))
+	// Bulk jobs are submitted to and run by the server
+	if serveAPI {
+		startWorker(bulkJobService.Run)
+	}
+	// Asynchronous transactions and the broker consumers share one processor
+	// pool, which serializes the transactions of each user
+	var pool *processor.Pool
+	processorPool := func() *processor.Pool {
+		if pool == nil {
+			pool = processor.New(cfg.Processor.Workers, cfg.Processor.QueueSize)
+			startWorker(pool.Run)
+		}
+		return pool
+	}
+	// Asynchronous transactions are queued by the server as well
+	var asyncProcessor *services.AsyncProcessor
+	if cfg.AsyncProcessing && serveAPI {
+		asyncProcessor = services.NewAsyncProcessor(transactionService, processorPool())
+	}
+	// The broker consumers apply the transactions published to them
+	var kafkaConsumer *kafka.Consumer
+	if cfg.Kafka.Enabled && runWorkers {
+		sourceType := entities.SourceType(cfg.Kafka.SourceType)
+		if !sourceType.IsValid() {
+			logger.Fatal().Str("source_type", cfg.Kafka.SourceType).Msg("invalid source type in Kafka configuration")
+		}
+		reader := kafka.NewClientReader(cfg.Kafka.Brokers, cfg.Kafka.Topic, cfg.Kafka.GroupID)
+		brokerClients = append(brokerClients, reader)
+		kafkaConsumer = kafka.NewConsumer(reader, kafkaWriter, cfg.Kafka.DLQTopic, transactionService, sourceType, kafka.RetryPolicy{
+			MaxAttempts: cfg.Ingest.MaxAttempts,
+			BaseDelay:   cfg.Ingest.RetryBaseDelay,
+			MaxDelay:    cfg.Ingest.RetryMaxDelay,
+		}, regionState, logger,
+			kafka.WithPool(processorPool()),
+			kafka.WithHeartbeat(workerHeartbeat("kafka_consumer", consumerIdleInterval), consumerIdleInterval),
+		)
+		startWorker(kafkaConsumer.Run)
+	}
+	var natsConsumer *nats.Consumer
+	if cfg.NATS.Enabled && runWorkers {
+		routes := make([]nats.Route, len(cfg.NATS.Subjects))
+		for i, subject := range cfg.NATS.Subjects {
+			sourceType := entities.SourceType(subject.SourceType)
+			if !sourceType.IsValid() {
+				logger.Fatal().Str("source_type", subject.SourceType).Msg("invalid source type in NATS configuration")
+			}
+			routes[i] = nats.Route{Subject: subject.Subject, SourceType: sourceType}
+		}
+		messages := natsClient.Messages(cfg.NATS.Stream, nats.DurableConsumerConfig(
+			cfg.NATS.Durable, routes, cfg.NATS.AckWait, cfg.NATS.MaxAckPending,
+		))
+		natsConsumer = nats.NewConsumer(messages, natsClient, cfg.NATS.DLQSubject, routes, transactionService, nats.RetryPolicy{
+			MaxAttempts: cfg.Ingest.MaxAttempts,
+			BaseDelay:   cfg.Ingest.RetryBaseDelay,
+			MaxDelay:    cfg.Ingest.RetryMaxDelay,
+		}, regionState, logger,
+			nats.WithPool(processorPool()),
+			nats.WithHeartbeat(workerHeartbeat("nats_consumer", consumerIdleInterval), consumerIdleInterval),
+		)
+		startWorker(natsConsumer.Run)
+	}
+	var rabbitMQConsumer *rabbitmq.Consumer
+	if cfg.RabbitMQ.Enabled && runWorkers {
+		sourceType := entities.SourceType(cfg.RabbitMQ.SourceType)
+		if !sourceType.IsValid() {
+			logger.Fatal().Str("source_type", cfg.RabbitMQ.SourceType).Msg("invalid source type in RabbitMQ configuration")
+		}
+		dialer := rabbitmq.NewDialer(cfg.RabbitMQ.URL)
+		brokerClients = append(brokerClients, dialer)
+		rabbitMQConsumer = rabbitmq.NewConsumer(dialer, rabbitmq.Topology{
+			Exchange:           cfg.RabbitMQ.Exchange,
+			ExchangeKind:       cfg.RabbitMQ.ExchangeKind,
+			BindingKeys:        cfg.RabbitMQ.BindingKeys,
+			Queue:              cfg.RabbitMQ.Queue,
+			DeadLetterExchange: cfg.RabbitMQ.DeadLetterExchange,
+			DeadLetterQueue:    cfg.RabbitMQ.DeadLetterQueue,
+			Prefetch:           cfg.RabbitMQ.Prefetch,
+			ConsumerTag:        "transaction-service",
+		}, transactionService, sourceType, rabbitmq.RetryPolicy{
+			MaxAttempts: cfg.Ingest.MaxAttempts,
+			BaseDelay:   cfg.Ingest.RetryBaseDelay,
+			MaxDelay:    cfg.Ingest.RetryMaxDelay,
+		}, regionState, logger,
+			rabbitmq.WithPool(processorPool()),
+			rabbitmq.WithHeartbeat(workerHeartbeat("rabbitmq_consumer", consumerIdleInterval), consumerIdleInterval),
+		)
+		startWorker(rabbitMQConsumer.Run)
+	}
+
+	// Start background workers. Every process writing to the database applies
+	// its storage migration writes.
+	if migrator != nil {
+		startWorker(migrator.Run)
+	}
+	holdPolicy := services.HoldPolicy{DefaultTTL: cfg.Holds.DefaultTTL, MaxTTL: cfg.Holds.MaxTTL}
+	var holdService *services.HoldService
+	if cfg.Holds.Enabled {
+		holdService = services.NewHoldService(unitOfWork, userRepo, holdRepo, transactionService, holdPolicy)
+		if runWorkers {
+			holdExpiryWorker := worker.NewHoldExpiryWorker(
+				holdService, cfg.Holds.ExpiryInterval, cfg.Holds.ExpiryBatchSize, regionState,
+				workerHeartbeat("hold_expiry_worker", cfg.Holds.ExpiryInterval), logger,
+			)
+			startWorker(holdExpiryWorker.Run)
+		}
+	}
+	var scheduleService *services.ScheduleService
+	if cfg.ScheduledTransactions.Enabled {
+		scheduleRepo := retrier.ScheduledTransactionRepository(database.NewScheduledTransactionRepository(db))
+		scheduleService = services.NewScheduleService(unitOfWork, userRepo, scheduleRepo, transactionService)
+		if runWorkers {
+			scheduleWorker := worker.NewScheduleWorker(
+				scheduleService, cfg.ScheduledTransactions.Interval, cfg.ScheduledTransactions.BatchSize, regionState,
+				workerHeartbeat("schedule_worker", cfg.ScheduledTransactions.Interval), logger,
+			)
+			startWorker(scheduleWorker.Run)
+		}
+	}
+	var balanceSnapshotService *services.BalanceSnapshotService
+	if cfg.BalanceSnapshots.Enabled {
+		balanceSnapshotService = services.NewBalanceSnapshotService(unitOfWork, userRepo, snapshotRepo, transactionService)
+		if runWorkers {
+			balanceSnapshotWorker := worker.NewBalanceSnapshotWorker(
+				balanceSnapshotService, cfg.BalanceSnapshots.Interval, cfg.BalanceSnapshots.BatchSize, regionState,
+				workerHeartbeat("balance_snapshot_worker", cfg.BalanceSnapshots.Interval), logger,
+			)
+			startWorker(balanceSnapshotWorker.Run)
+		}
+	}
+	if cfg.Settlement.Enabled && runWorkers {
+		settlementWorker := worker.NewSettlementWorker(
+			transactionService, cfg.Settlement.Interval, cfg.Settlement.BatchSize, regionState,
+			workerHeartbeat("settlement_worker", cfg.Settlement.Interval), logger,
+		)
+		startWorker(settlementWorker.Run)
+	}
+	if cfg.Cancellation.Enabled && runWorkers {
+		cancellationService := services.NewCancellationService(
+			unitOfWork, userRepo, walletRepo, transactionRepo, cancellationOpts...,
+		)
+		cancellationWorker := worker.NewCancellationWorker(
+			cancellationService, cfg.Cancellation.Interval, cfg.Cancellation.BatchSize, regionState,
+			workerHeartbeat("cancellation_worker", cfg.Cancellation.Interval), logger,
+		)
+		startWorker(cancellationWorker.Run)
+	}
+	if cfg.Dormancy.Enabled && runWorkers {
+		dormancyService := services.NewDormancyService(
+			unitOfWork, userRepo, transactionService, cfg.Dormancy.Period, dormancyOpts...,
+		)
+		dormancyWorker := worker.NewDormancyWorker(
+			dormancyService, cfg.Dormancy.Interval, cfg.Dormancy.BatchSize, regionState,
+			workerHeartbeat("dormancy_worker", cfg.Dormancy.Interval), logger,
+		)
+		startWorker(dormancyWorker.Run)
+	}
+
+	// Initialize readiness checks
+	readinessPolicies := make(map[string]health.Policy, len(cfg.Readiness.Policies))
+	for name, value := range cfg.Readiness.Policies {
+		policy, err := health.ParsePolicy(value)
+		if err != nil {
+			logger.Fatal().Err(err).Str("dependency", name).Msg("invalid readiness policy")
+		}
+		readinessPolicies[name] = policy
+	}
+	healthChecker := health.NewChecker(cfg.Readiness.CheckTimeout, readinessPolicies)
+	if db != nil {
+		healthChecker.Register(cfg.Database.Driver, health.PolicyRequired, db.PingContext)
+		healthChecker.Register("migrations", health.PolicyRequired, schemaMigrator.Check)
+	}
+	if migrationDB != nil {
+		healthChecker.Register("postgres_migration_target", health.PolicyOptional, migrationDB.PingContext)
+	}
+	// Reads fall back to the primary while the replica is down
+	if replicaDB != nil {
+		healthChecker.Register("postgres_replica", health.PolicyOptional, replicaDB.PingContext)
+	}
+	// Transactions wait in the topic, stream or queue while the brokers are
+	// unreachable
+	if kafkaConsumer != nil {
+		healthChecker.Register("kafka", health.PolicyOptional, kafkaConsumer.Check)
+	}
+	if natsConsumer != nil {
+		healthChecker.Register("nats", health.PolicyOptional, natsConsumer.Check)
+	}
+	if rabbitMQConsumer != nil {
+		healthChecker.Register("rabbitmq", health.PolicyOptional, rabbitMQConsumer.Check)
+	}
+	// Cache invalidation, rate limiting and the Redis publishers degrade
+	// gracefully while Redis is down
+	if redisClient != nil {
+		healthChecker.Register("redis", health.PolicyOptional, func(ctx context.Context) error {
+			return redisClient.Ping(ctx).Err()
+		})
+	}
+	// The warm-up opens connections and primes the balances of the most
+	// active users; the API is unready until it is done
+	if cfg.Warmup.Enabled && serveAPI {
+		warmupGate := health.NewGate("warming up")
+		healthChecker.Register("warmup", health.PolicyRequired, warmupGate.Check)
+		startWorker(func(ctx context.Context) {
+			defer warmupGate.Open()
+			warmUp(ctx, cfg.Warmup, db, transactionService, logger)
+		})
+	}
+
+	// Initialize the HTTP handlers
+	apiKeys := make(map[string][]entities.SourceType, len(cfg.APIKeys))
+	for key, sources := range cfg.APIKeys {
+		for _, source := range sources {
+			sourceType := entities.SourceType(source)
+			if !sourceType.IsValid() {
+				logger.Fatal().Str("source_type", source).Msg("invalid source type in API key configuration")
+			}
+			apiKeys[key] = append(apiKeys[key], sourceType)
+		}
+	}
+	apiKeyStore := auth.NewStaticAPIKeyStore(apiKeys)
+	var handlerOpts []handlers.HandlerOption
+	if asyncProcessor != nil {
+		handlerOpts = append(handlerOpts, handlers.WithAsyncProcessor(asyncProcessor))
+	}
+	if cfg.Quota.Enabled {
+		handlerOpts = append(handlerOpts, handlers.WithQuotaWarnings(cfg.Quota.WarnRatio))
+	}
+	var rateLimit gin.HandlerFunc
+	if cfg.RateLimit.Enabled {
+		var limiter services.RateLimiter
+		switch cfg.RateLimit.Backend {
+		case "memory":
+			limiter = cache.NewMemoryRateLimiter()
+		case "redis":
+			if redisClient == nil {
+				logger.Fatal().Msg("REDIS_ADDR is required for the redis rate limit backend")
+			}
+			limiter = cache.NewRedisRateLimiter(redisClient, "ratelimit:")
+		default:
+			logger.Fatal().Str("backend", cfg.RateLimit.Backend).Msg("invalid rate limit backend")
+		}
+
+		policy := services.RateLimitPolicy{
+			PerUser:   services.RateLimitRule{Rate: cfg.RateLimit.UserRate, Burst: cfg.RateLimit.UserBurst},
+			PerSource: make(map[entities.SourceType]services.RateLimitRule, len(cfg.RateLimit.SourceRates)),
+		}
+		for source, rate := range cfg.RateLimit.SourceRates {
+			sourceType := entities.SourceType(source)
+			if !sourceType.IsValid() {
+				logger.Fatal().Str("source_type", source).Msg("invalid source type in rate limit configuration")
+			}
+			// Source types may burst up to one second's worth of transactions
+			policy.PerSource[sourceType] = services.RateLimitRule{Rate: rate, Burst: int(math.Ceil(rate))}
+		}
+		rateLimit = handlers.RateLimit(limiter, policy)
+	}
+	var sandboxHandler *handlers.SandboxHandler
+	var sandboxAccountService *services.AccountService
+	var sandboxHoldService *services.HoldService
+	if sandboxDB != nil {
+		// Sandbox users are isolated from metrics, caches and the balance
+		// guard, which all observe real users. They run on a virtual clock
+		// that integrators can advance to test time-dependent behavior.
+		sandboxClock := clock.NewVirtual()
+		sandboxUnitOfWork := retrier.UnitOfWork(database.NewUnitOfWork(sandboxDB))
+		sandboxUserRepo := retrier.UserRepository(database.NewUserRepository(sandboxDB))
+		sandboxOpts := []services.TransactionServiceOption{
+			services.WithClockSkewPolicy(clockSkewPolicy),
+			services.WithAmountPolicy(amountPolicy),
+			services.WithVelocityLimits(velocityPolicy),
+			services.WithIDGenerator(idGenerator),
+			services.WithRegionGate(regionState),
+			services.WithLogger(logger),
+			services.WithCurrencies(retrier.WalletRepository(database.NewWalletRepository(sandboxDB)), currency.Code, walletCurrencies...),
+			services.WithClock(sandboxClock.Now),
+		}
+		var sandboxHoldRepo repositories.HoldRepository
+		if cfg.Holds.Enabled {
+			sandboxHoldRepo = retrier.HoldRepository(database.NewHoldRepository(sandboxDB))
+			sandboxOpts = append(sandboxOpts, services.WithHolds(sandboxHoldRepo))
+		}
+		sandboxService := services.NewTransactionService(
+			sandboxUnitOfWork,
+			sandboxUserRepo,
+			retrier.TransactionRepository(database.NewTransactionRepository(sandboxDB)),
+			sandboxOpts...,
+		)
+		if cfg.Holds.Enabled {
+			// Sandbox holds expire by the virtual clock; they stop reserving
+			// their amount without the expiry worker
+			sandboxHoldService = services.NewHoldService(sandboxUnitOfWork, sandboxUserRepo, sandboxHoldRepo, sandboxService, holdPolicy)
+		}
+		handlerOpts = append(handlerOpts, handlers.WithSandbox(sandboxService))
+		sandboxAccountService = services.NewAccountService(sandboxUserRepo)
+		sandboxUsers := toSeedUsers(cfg.Sandbox.Users)
+		sandboxHandler = handlers.NewSandboxHandler(func(ctx context.Context) error {
+			if err := database.ResetSchema(ctx, sandboxDB, cfg.Sandbox.Schema, sandboxUsers); err != nil {
+				return err
+			}
+			sandboxClock.Reset()
+			return nil
+		}, sandboxClock)
+	}
+	httpHandler := handlers.NewHandler(transactionService, handlerOpts...)
+	userHandler := handlers.NewUserHandler(accountService, sandboxAccountService)
+	holdHandler := handlers.NewHoldHandler(holdService, sandboxHoldService)
+	healthHandler := handlers.NewHealthHandler(healthChecker, livenessChecker, health.ReadBuildInfo())
+	adminHandler := handlers.NewAdminHandler(cfg, transactionService, annotationService, accountService)
+	regionHandler := handlers.NewRegionHandler(regionState)
+	ingestionHandler := handlers.NewIngestionHandler(services.NewIngestionService(transactionRepo, duplicateTracker))
+
+	// Set up Gin HTTP router
+	router := gin.New()
+
+	// Add middleware for error handling and logging
+	router.Use(handlers.RequestLogger(logger))
+	router.Use(gin.Recovery())
+	router.Use(prometheusMetrics.Middleware())
+
+	// Set up the APIs; worker processes only serve probes and metrics
+	var grpcServer *grpc.Server
+	serverErrors := make(chan error, 2)
+	if serveAPI {
+		router.Use(handlers.ResponseEnvelope(cfg.EnvelopeAPIKeys))
+		router.Use(handlers.MinorUnits(cfg.MinorUnitsAPIKeys, currency))
+		router.Use(handlers.Sandbox(cfg.Sandbox.APIKeys))
+		// Each route group runs the middleware configured for it. Probes and
+		// scrapers do not authenticate, nor do readers of the API documentation.
+		publicPaths := []string{"/healthz", "/readyz", "/version", "/metrics", "/openapi.json", "/docs"}
+		routeGroups := handlers.NewRouteGroups(cfg.Routes.Groups, publicPaths...)
+		var verifier *auth.Verifier
+		if cfg.Auth.Enabled {
+			verifier = auth.NewVerifier(cfg.Auth.SigningKey, cfg.Auth.Issuer, cfg.Auth.AdminScope)
+			router.Use(routeGroups.Only("auth", handlers.JWTAuth(verifier, publicPaths...)))
+		}
+		if len(cfg.APIKeys) > 0 {
+			router.Use(routeGroups.Only("auth", handlers.APIKeyAuth(apiKeyStore, publicPaths...)))
+		}
+		router.Use(handlers.RequireAdminAuth())
+		router.Use(routeGroups.Only("body_limit", handlers.BodyLimit(cfg.Routes.BodyLimit)))
+		if cfg.Routes.SigningSecret != "" {
+			router.Use(routeGroups.Only("signing", handlers.RequestSignature(
+				cfg.Routes.SigningSecret, cfg.Routes.SigningTolerance, time.Now,
+			)))
+		}
+		if rateLimit != nil {
+			router.Use(routeGroups.Only("rate_limit", rateLimit))
+		}
+		router.Use(regionHandler.WriteGuard())
+		if replicaDB != nil {
+			router.Use(handlers.ConsistencyTokens(func(ctx context.Context) (consistency.Token, error) {
+				return database.CurrentPosition(ctx, db)
+			}))
+		}
+
+		// Set up the routes of /api/v1, and the deprecated unversioned routes
+		// serving the same API to the clients predating it
+		apiRoutes := []handlers.RouteSetup{
+			httpHandler,
+			userHandler,
+			adminHandler,
+			handlers.NewBulkJobHandler(bulkJobService),
+			regionHandler,
+		}
+		// Restore drills check PostgreSQL backups, and the change feed relies
+		// on PostgreSQL transaction IDs
+		if db != nil && cfg.Database.Driver == "postgres" {
+			apiRoutes = append(apiRoutes,
+				handlers.NewRestoreHandler(services.NewRestoreService(database.NewRestoreRepository(db))),
+				handlers.NewSyncHandler(services.NewSyncService(database.NewSyncRepository(db))),
+			)
+		}
+		apiRoutes = append(apiRoutes,
+			ingestionHandler,
+			handlers.NewReconciliationHandler(services.NewReconciliationService(transactionRepo)),
+		)
+		if cfg.Settlement.Enabled {
+			apiRoutes = append(apiRoutes, handlers.NewSettlementHandler(transactionService, cfg.Settlement.BatchSize))
+		}
+		if cfg.Holds.Enabled {
+			apiRoutes = append(apiRoutes, holdHandler)
+		}
+		if scheduleService != nil {
+			apiRoutes = append(apiRoutes, handlers.NewScheduleHandler(scheduleService))
+		}
+		if balanceSnapshotService != nil {
+			apiRoutes = append(apiRoutes, handlers.NewBalanceHistoryHandler(balanceSnapshotService))
+		}
+		if sandboxHandler != nil {
+			apiRoutes = append(apiRoutes, sandboxHandler)
+		}
+		if migrator != nil {
+			apiRoutes = append(apiRoutes, handlers.NewStorageMigrationHandler(migrator))
+		}
+		if webhookService != nil {
+			apiRoutes = append(apiRoutes, handlers.NewWebhookHandler(webhookService))
+		}
+		if rejectionService != nil {
+			apiRoutes = append(apiRoutes, handlers.NewRejectionHandler(rejectionService))
+		}
+		if feeService != nil {
+			apiRoutes = append(apiRoutes, handlers.NewFeeHandler(feeService))
+		}
+		if systemAccountService != nil {
+			apiRoutes = append(apiRoutes, handlers.NewSystemAccountHandler(systemAccountService))
+		}
+		if cfg.BalanceAdjustments {
+			apiRoutes = append(apiRoutes, handlers.NewBalanceAdjustmentHandler(transactionService))
+		}
+		if auditService != nil {
+			apiRoutes = append(apiRoutes, handlers.NewAuditLogHandler(auditService))
+		}
+		if balanceCheckService != nil {
+			apiRoutes = append(apiRoutes, handlers.NewBalanceCheckHandler(balanceCheckService))
+		}
+		apiRoutes = append(apiRoutes, handlers.NewSLOHandler(sloTracker))
+
+		v1 := handlers.APIVersion(router, apiversion.V1)
+		for _, routes := range apiRoutes {
+			routes.SetupRoutes(v1)
+		}
+		if cfg.Routes.Legacy {
+			legacy := handlers.LegacyRoutes(router, cfg.Routes.LegacySunset)
+			for _, routes := range apiRoutes {
+				routes.SetupRoutes(legacy)
+			}
+		}
+		handlers.NewDocsHandler().SetupRoutes(router)
+
+		// Set up the gRPC server sharing the same transaction service
+		grpcListener, err := net.Listen("tcp", ":"+cfg.GRPCPort)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to listen on gRPC port")
+		}
+		interceptors := []grpc.UnaryServerInterceptor{grpcadapter.LoggingInterceptor(logger)}
+		if verifier != nil {
+			interceptors = append(interceptors, grpcadapter.AuthInterceptor(verifier))
+		}
+		if len(cfg.APIKeys) > 0 {
+			interceptors = append(interceptors, grpcadapter.APIKeyInterceptor(apiKeyStore))
+		}
+		grpcServer = grpc.NewServer(grpc.ChainUnaryInterceptor(interceptors...))
+		transactionpb.RegisterTransactionServiceServer(grpcServer, grpcadapter.NewServer(transactionService))
+
+		go func() {
+			logger.Info().Str("port", cfg.GRPCPort).Msg("starting gRPC server")
+			if err := grpcServer.Serve(grpcListener); err != nil {
+				serverErrors <- fmt.Errorf("gRPC server: %w", err)
+			}
+		}()
+	}
+	healthHandler.SetupRoutes(router)
+	router.GET("/metrics", gin.WrapH(prometheusMetrics.Handler()))
+
+	httpServer := &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           router,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	// Serve until a signal arrives or either server fails
+	go func() {
+		logger.Info().Str("port", cfg.Port).Msg("starting HTTP server")
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErrors <- fmt.Errorf("HTTP server: %w", err)
+		}
+	}()
+
+	exitCode := 0
+	select {
+	case <-ctx.Done():
+		logger.Info().Msg("shutdown signal received")
+	case err := <-serverErrors:
+		logger.Error().Err(err).Msg("server failed")
+		exitCode = 1
+	}
+	// A second signal terminates immediately
+	stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	// Stop accepting requests and let in-flight ones finish
+	logger.Info().Dur("timeout", cfg.ShutdownTimeout).Msg("draining in-flight requests")
+	grpcDrained := make(chan bool, 1)
+	go func() {
+		grpcDrained <- grpcServer == nil || stopGRPCServer(shutdownCtx, grpcServer)
+	}()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		logger.Error().Err(err).Msg("HTTP server did not drain in time")
+		exitCode = 1
+	}
+	if !<-grpcDrained {
+		logger.Error().Msg("gRPC server did not drain in time")
+		exitCode = 1
+	}
+
+	// Stop background workers once no request can reach them any more
+	stopWorkers()
+	if !waitWithContext(shutdownCtx, &workers) {
+		logger.Error().Msg("background workers did not stop in time")
+		exitCode = 1
+	}
+
+	// Close connections last, once nothing uses them
+	httpClients.CloseIdleConnections()
+	for _, client := range brokerClients {
+		if err := client.Close(); err != nil {
+			logger.Error().Err(err).Msg("failed to close broker client")
+		}
+	}
+	if redisClient != nil {
+		if err := redisClient.Close(); err != nil {
+			logger.Error().Err(err).Msg("failed to close Redis client")
+		}
+	}
+	if migrationDB != nil {
+		if err := migrationDB.Close(); err != nil {
+			logger.Error().Err(err).Msg("failed to close storage migration target database pool")
+			exitCode = 1
+		}
+	}
+	if replicaDB != nil {
+		if err := replicaDB.Close(); err != nil {
+			logger.Error().Err(err).Msg("failed to close read replica database pool")
+			exitCode = 1
+		}
+	}
+	if sandboxDB != nil {
+		if err := sandboxDB.Close(); err != nil {
+			logger.Error().Err(err).Msg("failed to close sandbox database pool")
+			exitCode = 1
+		}
+	}
+	if db != nil {
+		if err := db.Close(); err != nil {
+			logger.Error().Err(err).Msg("failed to close database pool")
+			exitCode = 1
+		}
+	}
+
+	logger.Info().Msg("shutdown complete")
+	return exitCode
+}
+
+// warmUp opens database connections and primes the balances of the most
+// active users within the configured timeout. Failures are logged rather than
+// fatal: a cold service is slower, but works.
+func warmUp(
+	ctx context.Context,
+	cfg config.WarmupConfig,
+	db *sql.DB,
+	transactionService *services.TransactionService,
+	logger zerolog.Logger,
+) {
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+	start := time.Now()
+
+	if db != nil {
+		if err := database.WarmConnections(ctx, db, cfg.Connections); err != nil {
+			logger.Warn().Err(err).Msg("failed to warm up database connections")
+		}
+	}
+	primed, err := transactionService.PrimeBalances(ctx, cfg.Window, cfg.Users)
+	if err != nil {
+		logger.Warn().Err(err).Msg("failed to prime balances")
+	}
+
+	logger.Info().Int("balances", primed).Dur("duration", time.Since(start)).Msg("warm-up completed")
+}
+
+// newEventPublisher creates the publisher of kind, log, redis, kafka or nats,
+// publishing to topic, which prefixes the subjects of the nats publisher
+func newEventPublisher(
+	kind, topic string,
+	streamMaxLen int64,
+	redisClient *redis.Client,
+	kafkaWriter *kafka.ClientWriter,
+	natsClient *nats.Client,
+	logger zerolog.Logger,
+) services.EventPublisher {
+	switch kind {
+	case "redis":
+		return events.NewRedisStreamPublisher(redisClient, topic, streamMaxLen)
+	case "kafka":
+		return kafka.NewPublisher(kafkaWriter, topic)
+	case "nats":
+		return nats.NewPublisher(natsClient, topic)
+	}
+	return events.NewLogPublisher(logger)
+}
+
+// stopGRPCServer waits for in-flight calls to finish, forcing the server to
+// stop when ctx expires first. It reports whether the server drained cleanly.
+func stopGRPCServer(ctx context.Context, server *grpc.Server) bool {
+	done := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		server.Stop()
+		<-done
+		return false
+	}
+}
+
+// waitWithContext waits for wg, giving up when ctx expires. It reports whether
+// every goroutine finished.
+func waitWithContext(ctx context.Context, wg *sync.WaitGroup) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// toSeedUsers converts configured users to the users to seed
+func toSeedUsers(users []config.SeedUserConfig) []database.SeedUser {
+	seedUsers := make([]database.SeedUser, 0, len(users))
+	for _, user := range users {
+		seedUsers = append(seedUsers, database.SeedUser{ID: user.ID, Balance: user.Balance})
+	}
+	return seedUsers
+}
+
+// logEffectiveConfig logs the redacted configuration the process is running with
+func logEffectiveConfig(logger zerolog.Logger, cfg *config.Config) {
+	dump, err := json.Marshal(cfg.Redacted())
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to encode effective configuration")
+		return
+	}
+	logger.Info().RawJSON("config", dump).Msg("starting transaction-service")
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	// ErrInvalidJurisdiction is returned for jurisdictions that are not two-letter country codes
+	ErrInvalidJurisdiction = errors.New("invalid jurisdiction")
+	// ErrInvalidCreditLimit is returned for credit limits that are negative or
+	// have more than two decimal places
+	ErrInvalidCreditLimit = errors.New("invalid credit limit")
+)
+
+// AccountService handles administrative changes to user accounts
+type AccountService struct {
+	userRepo repositories.UserRepository
+}
+
+// NewAccountService creates a new AccountService
+func NewAccountService(userRepo repositories.UserRepository) *AccountService {
+	return &AccountService{userRepo: userRepo}
+}
+
+// CreateUser creates an active user with the requested initial balance, which
+// must not be negative or have more than two decimal places
+func (s *AccountService) CreateUser(ctx context.Context, req entities.CreateUserRequest) (*entities.UserResponse, error) {
+	balance := decimal.Zero
+	if req.Balance != "" {
+		parsed, err := decimal.NewFromString(req.Balance)
+		if err != nil || parsed.IsNegative() || !parsed.Equal(parsed.Round(2)) {
+			return nil, ErrInvalidAmount
+		}
+		balance = parsed
+	}
+
+	user := &entities.User{Balance: balance}
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return newUserResponse(user), nil
+}
+
+// GetUser returns the user's account
+func (s *AccountService) GetUser(ctx context.Context, userID uint64) (*entities.UserResponse, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return newUserResponse(user), nil
+}
+
+// ListUsers returns a page of users ordered by ID
+func (s *AccountService) ListUsers(ctx context.Context, limit, offset int) (*entities.UserPage, error) {
+	if limit < 0 || offset < 0 || limit > MaxPageSize {
+		return nil, ErrInvalidFilter
+	}
+	if limit == 0 {
+		limit = DefaultPageSize
+	}
+
+	users, total, err := s.userRepo.List(ctx, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	page := &entities.UserPage{
+		Users:  make([]*entities.UserResponse, 0, len(users)),
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	}
+	for _, user := range users {
+		page.Users = append(page.Users, newUserResponse(user))
+	}
+
+	return page, nil
+}
+
+func newUserResponse(user *entities.User) *entities.UserResponse {
+	return &entities.UserResponse{
+		ID:           user.ID,
+		Balance:      user.Balance.StringFixed(2),
+		Status:       user.Status,
+		CreditLimit:  creditLimitString(user.CreditLimit),
+		Jurisdiction: user.Jurisdiction,
+		DormantAt:    user.DormantAt,
+	}
+}
+
+// creditLimitString formats a credit limit, leaving out the absent ones
+func creditLimitString(creditLimit decimal.Decimal) string {
+	if !creditLimit.IsPositive() {
+		return ""
+	}
+	return creditLimit.StringFixed(2)
+}
+
+// SetJurisdiction assigns an ISO 3166-1 alpha-2 country code to the user. An
+// empty jurisdiction removes any overlay.
+func (s *AccountService) SetJurisdiction(ctx context.Context, userID uint64, jurisdiction string) error {
+	jurisdiction = strings.ToUpper(strings.TrimSpace(jurisdiction))
+	if !isValidJurisdiction(jurisdiction) {
+		return ErrInvalidJurisdiction
+	}
+
+	if err := s.userRepo.UpdateJurisdiction(ctx, userID, jurisdiction); err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("failed to update jurisdiction: %w", err)
+	}
+
+	return nil
+}
+
+// SetCreditLimit lets the user's base currency balance go down to minus the
+// credit limit. A zero credit limit removes the credit line; balances already
+// below zero are left as they are, but cannot be debited further.
+func (s *AccountService) SetCreditLimit(ctx context.Context, userID uint64, creditLimit string) error {
+	parsed, err := decimal.NewFromString(creditLimit)
+	if err != nil || parsed.IsNegative() || !parsed.Equal(parsed.Round(2)) {
+		return ErrInvalidCreditLimit
+	}
+
+	if err := s.userRepo.UpdateCreditLimit(ctx, userID, parsed); err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("failed to update credit limit: %w", err)
+	}
+
+	return nil
+}
+
+// SetStatus freezes or reactivates the user's account
+func (s *AccountService) SetStatus(ctx context.Context, userID uint64, status entities.UserStatus) error {
+	if !status.IsValid() {
+		return ErrInvalidFilter
+	}
+
+	if err := s.userRepo.UpdateStatus(ctx, userID, status); err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("failed to update status: %w", err)
+	}
+
+	return nil
+}
+
+func isValidJurisdiction(jurisdiction string) bool {
+	if jurisdiction == "" {
+		return true
+	}
+	if len(jurisdiction) != 2 {
+		return false
+	}
+	for _, r := range jurisdiction {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
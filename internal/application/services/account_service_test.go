@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"transaction-service/internal/domain/entities"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountService_CreateUser(t *testing.T) {
+	tests := []struct {
+		name        string
+		balance     string
+		wantBalance string
+		wantErr     error
+	}{
+		{name: "defaults to a zero balance", balance: "", wantBalance: "0.00"},
+		{name: "initial balance", balance: "25.5", wantBalance: "25.50"},
+		{name: "trailing zeros beyond cents", balance: "10.500", wantBalance: "10.50"},
+		{name: "negative balance", balance: "-1", wantErr: ErrInvalidAmount},
+		{name: "fractions of a cent", balance: "0.001", wantErr: ErrInvalidAmount},
+		{name: "malformed balance", balance: "ten", wantErr: ErrInvalidAmount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepo := newFakeUserRepo()
+			service := NewAccountService(userRepo)
+
+			user, err := service.CreateUser(context.Background(), entities.CreateUserRequest{Balance: tt.balance})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, uint64(1), user.ID)
+			assert.Equal(t, tt.wantBalance, user.Balance)
+			assert.Equal(t, entities.UserStatusActive, user.Status)
+		})
+	}
+}
+
+func TestAccountService_GetUser(t *testing.T) {
+	service := NewAccountService(newFakeUserRepo(
+		&entities.User{ID: 1, Balance: decimal.NewFromInt(100), Jurisdiction: "DE"},
+	))
+
+	user, err := service.GetUser(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, &entities.UserResponse{
+		ID:           1,
+		Balance:      "100.00",
+		Status:       entities.UserStatusActive,
+		Jurisdiction: "DE",
+	}, user)
+
+	_, err = service.GetUser(context.Background(), 2)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+func TestAccountService_ListUsers(t *testing.T) {
+	service := NewAccountService(newFakeUserRepo(
+		&entities.User{ID: 3, Balance: decimal.Zero},
+		&entities.User{ID: 1, Balance: decimal.Zero},
+		&entities.User{ID: 2, Balance: decimal.Zero},
+	))
+
+	t.Run("pages are ordered by ID", func(t *testing.T) {
+		page, err := service.ListUsers(context.Background(), 2, 1)
+		require.NoError(t, err)
+
+		assert.Equal(t, 3, page.Total)
+		require.Len(t, page.Users, 2)
+		assert.Equal(t, uint64(2), page.Users[0].ID)
+		assert.Equal(t, uint64(3), page.Users[1].ID)
+	})
+
+	t.Run("default page size", func(t *testing.T) {
+		page, err := service.ListUsers(context.Background(), 0, 0)
+		require.NoError(t, err)
+		assert.Equal(t, DefaultPageSize, page.Limit)
+		assert.Len(t, page.Users, 3)
+	})
+
+	t.Run("invalid pagination", func(t *testing.T) {
+		_, err := service.ListUsers(context.Background(), MaxPageSize+1, 0)
+		assert.ErrorIs(t, err, ErrInvalidFilter)
+	})
+}
+
+func TestAccountService_SetCreditLimit(t *testing.T) {
+	service := NewAccountService(newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.Zero}))
+	ctx := context.Background()
+
+	require.NoError(t, service.SetCreditLimit(ctx, 1, "250.5"))
+	user, err := service.GetUser(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "250.50", user.CreditLimit)
+
+	require.NoError(t, service.SetCreditLimit(ctx, 1, "0"))
+	user, err = service.GetUser(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, user.CreditLimit, "zero credit limits are left out")
+
+	for _, creditLimit := range []string{"-1", "0.001", "ten", ""} {
+		assert.ErrorIs(t, service.SetCreditLimit(ctx, 1, creditLimit), ErrInvalidCreditLimit, creditLimit)
+	}
+	assert.ErrorIs(t, service.SetCreditLimit(ctx, 2, "10"), ErrUserNotFound)
+}
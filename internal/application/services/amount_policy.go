@@ -0,0 +1,53 @@
+package services
+
+import (
+	"errors"
+
+	"transaction-service/internal/domain/entities"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrAmountBelowMinimum = errors.New("amount is below the minimum")
+	ErrAmountAboveMaximum = errors.New("amount is above the maximum")
+	ErrAmountPrecision    = errors.New("amount has too many decimal places")
+)
+
+// AmountLimits bounds the amounts of transactions; zero bounds are disabled
+type AmountLimits struct {
+	Min decimal.Decimal
+	Max decimal.Decimal
+}
+
+// AmountPolicy validates the amounts of transactions on top of them being
+// positive, so that a misbehaving client cannot post implausible amounts.
+// PerSource replaces the default limits for a source type.
+type AmountPolicy struct {
+	MaxDecimalPlaces int32
+	Default          AmountLimits
+	PerSource        map[entities.SourceType]AmountLimits
+}
+
+// Limits returns the limits of the given source type
+func (p AmountPolicy) Limits(sourceType entities.SourceType) AmountLimits {
+	if limits, ok := p.PerSource[sourceType]; ok {
+		return limits
+	}
+	return p.Default
+}
+
+// Validate checks a positive amount of the given source type
+func (p AmountPolicy) Validate(amount decimal.Decimal, sourceType entities.SourceType) error {
+	if !amount.Equal(amount.Truncate(p.MaxDecimalPlaces)) {
+		return ErrAmountPrecision
+	}
+	limits := p.Limits(sourceType)
+	if limits.Min.IsPositive() && amount.LessThan(limits.Min) {
+		return ErrAmountBelowMinimum
+	}
+	if limits.Max.IsPositive() && amount.GreaterThan(limits.Max) {
+		return ErrAmountAboveMaximum
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"transaction-service/internal/domain/entities"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAmountPolicy_Validate(t *testing.T) {
+	policy := AmountPolicy{
+		MaxDecimalPlaces: 2,
+		Default:          AmountLimits{Min: decimal.RequireFromString("0.10"), Max: decimal.NewFromInt(10000)},
+		PerSource: map[entities.SourceType]AmountLimits{
+			entities.SourceTypePayment: {Max: decimal.NewFromInt(50000)},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		amount     string
+		sourceType entities.SourceType
+		wantErr    error
+	}{
+		{name: "within the limits", amount: "25.50", sourceType: entities.SourceTypeGame},
+		{name: "trailing zeros are not decimal places", amount: "25.5000", sourceType: entities.SourceTypeGame},
+		{name: "too many decimal places", amount: "25.505", sourceType: entities.SourceTypeGame, wantErr: ErrAmountPrecision},
+		{name: "below the minimum", amount: "0.05", sourceType: entities.SourceTypeGame, wantErr: ErrAmountBelowMinimum},
+		{name: "the bounds are inclusive", amount: "10000", sourceType: entities.SourceTypeGame},
+		{name: "above the maximum", amount: "10000000", sourceType: entities.SourceTypeGame, wantErr: ErrAmountAboveMaximum},
+		{name: "source types replace the limits", amount: "20000", sourceType: entities.SourceTypePayment},
+		{name: "replaced limits drop the default minimum", amount: "0.05", sourceType: entities.SourceTypePayment},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Validate(decimal.RequireFromString(tt.amount), tt.sourceType)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestTransactionService_ProcessTransaction_AmountPolicy(t *testing.T) {
+	ctx := context.Background()
+	userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(100)})
+	transactionRepo := newFakeTransactionRepo()
+	service := NewTransactionService(&fakeUnitOfWork{}, userRepo, transactionRepo, WithAmountPolicy(AmountPolicy{
+		MaxDecimalPlaces: 2,
+		Default:          AmountLimits{Max: decimal.NewFromInt(1000)},
+	}))
+
+	_, err := service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+		State: "win", Amount: "10000000", TransactionID: "tx-1",
+	}, entities.SourceTypeGame)
+	assert.ErrorIs(t, err, ErrAmountAboveMaximum)
+	assert.Empty(t, transactionRepo.transactions)
+
+	_, err = service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+		State: "win", Amount: "10.00", TransactionID: "tx-1",
+	}, entities.SourceTypeGame)
+	require.NoError(t, err)
+	assert.Len(t, transactionRepo.transactions, 1)
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+)
+
+// MaxAnnotationLength is the maximum length of a support note in characters
+const MaxAnnotationLength = 2000
+
+var (
+	ErrInvalidAnnotation        = errors.New("invalid annotation")
+	ErrAnnotationTargetNotFound = errors.New("annotation target not found")
+)
+
+// AnnotationService manages support notes on users and transactions. Notes
+// are kept apart from financial data and never affect balances.
+type AnnotationService struct {
+	annotationRepo  repositories.AnnotationRepository
+	userRepo        repositories.UserRepository
+	transactionRepo repositories.TransactionRepository
+}
+
+// NewAnnotationService creates a new AnnotationService
+func NewAnnotationService(
+	annotationRepo repositories.AnnotationRepository,
+	userRepo repositories.UserRepository,
+	transactionRepo repositories.TransactionRepository,
+) *AnnotationService {
+	return &AnnotationService{
+		annotationRepo:  annotationRepo,
+		userRepo:        userRepo,
+		transactionRepo: transactionRepo,
+	}
+}
+
+// Annotate attaches a note to an existing user or transaction
+func (s *AnnotationService) Annotate(
+	ctx context.Context,
+	targetType entities.AnnotationTarget,
+	targetID string,
+	req entities.AnnotationRequest,
+) (*entities.Annotation, error) {
+	author := strings.TrimSpace(req.Author)
+	note := strings.TrimSpace(req.Note)
+	if author == "" || note == "" || len([]rune(note)) > MaxAnnotationLength {
+		return nil, ErrInvalidAnnotation
+	}
+
+	if err := s.ensureTargetExists(ctx, targetType, targetID); err != nil {
+		return nil, err
+	}
+
+	annotation := &entities.Annotation{
+		TargetType: targetType,
+		TargetID:   targetID,
+		Author:     author,
+		Note:       note,
+		CreatedAt:  time.Now(),
+	}
+	if err := s.annotationRepo.Create(ctx, annotation); err != nil {
+		return nil, fmt.Errorf("failed to create annotation: %w", err)
+	}
+
+	return annotation, nil
+}
+
+// ListAnnotations returns the notes on an existing user or transaction, oldest first
+func (s *AnnotationService) ListAnnotations(
+	ctx context.Context,
+	targetType entities.AnnotationTarget,
+	targetID string,
+) ([]*entities.Annotation, error) {
+	if err := s.ensureTargetExists(ctx, targetType, targetID); err != nil {
+		return nil, err
+	}
+
+	annotations, err := s.annotationRepo.ListByTarget(ctx, targetType, targetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list annotations: %w", err)
+	}
+	if annotations == nil {
+		annotations = []*entities.Annotation{}
+	}
+
+	return annotations, nil
+}
+
+// ListUserAnnotations returns the notes on an existing user and on the user's
+// transactions, oldest first
+func (s *AnnotationService) ListUserAnnotations(ctx context.Context, userID uint64) ([]*entities.Annotation, error) {
+	if err := s.ensureTargetExists(ctx, entities.AnnotationTargetUser, strconv.FormatUint(userID, 10)); err != nil {
+		return nil, err
+	}
+
+	annotations, err := s.annotationRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list annotations: %w", err)
+	}
+
+	return annotations, nil
+}
+
+func (s *AnnotationService) ensureTargetExists(
+	ctx context.Context,
+	targetType entities.AnnotationTarget,
+	targetID string,
+) error {
+	switch targetType {
+	case entities.AnnotationTargetUser:
+		userID, err := strconv.ParseUint(targetID, 10, 64)
+		if err != nil || userID == 0 {
+			return ErrAnnotationTargetNotFound
+		}
+		if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+			if errors.Is(err, repositories.ErrNotFound) {
+				return ErrAnnotationTargetNotFound
+			}
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+
+	case entities.AnnotationTargetTransaction:
+		exists, err := s.transactionRepo.ExistsByTransactionID(ctx, targetID)
+		if err != nil {
+			return fmt.Errorf("failed to check transaction: %w", err)
+		}
+		if !exists {
+			return ErrAnnotationTargetNotFound
+		}
+
+	default:
+		return ErrInvalidAnnotation
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"transaction-service/internal/domain/entities"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnnotationService(t *testing.T) {
+	ctx := context.Background()
+
+	newService := func(t *testing.T) (*AnnotationService, *fakeAnnotationRepo) {
+		userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(100)})
+		transactionRepo := newFakeTransactionRepo()
+		transactionService := NewTransactionService(&fakeUnitOfWork{}, userRepo, transactionRepo)
+		_, err := transactionService.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "win", Amount: "10.00", TransactionID: "tx-1",
+		}, entities.SourceTypeGame)
+		require.NoError(t, err)
+
+		annotationRepo := &fakeAnnotationRepo{}
+		return NewAnnotationService(annotationRepo, userRepo, transactionRepo), annotationRepo
+	}
+
+	t.Run("notes are attached to users and transactions separately", func(t *testing.T) {
+		service, _ := newService(t)
+
+		_, err := service.Annotate(ctx, entities.AnnotationTargetUser, "1", entities.AnnotationRequest{
+			Author: "alice", Note: "Customer called about a delayed payout",
+		})
+		require.NoError(t, err)
+		_, err = service.Annotate(ctx, entities.AnnotationTargetTransaction, "tx-1", entities.AnnotationRequest{
+			Author: "bob", Note: "Verified with the game provider",
+		})
+		require.NoError(t, err)
+
+		userNotes, err := service.ListAnnotations(ctx, entities.AnnotationTargetUser, "1")
+		require.NoError(t, err)
+		require.Len(t, userNotes, 1)
+		assert.Equal(t, "alice", userNotes[0].Author)
+		assert.False(t, userNotes[0].CreatedAt.IsZero())
+
+		transactionNotes, err := service.ListAnnotations(ctx, entities.AnnotationTargetTransaction, "tx-1")
+		require.NoError(t, err)
+		require.Len(t, transactionNotes, 1)
+		assert.Equal(t, "Verified with the game provider", transactionNotes[0].Note)
+	})
+
+	t.Run("unknown targets are rejected", func(t *testing.T) {
+		service, annotationRepo := newService(t)
+		req := entities.AnnotationRequest{Author: "alice", Note: "note"}
+
+		_, err := service.Annotate(ctx, entities.AnnotationTargetUser, "99", req)
+		assert.ErrorIs(t, err, ErrAnnotationTargetNotFound)
+		_, err = service.Annotate(ctx, entities.AnnotationTargetUser, "abc", req)
+		assert.ErrorIs(t, err, ErrAnnotationTargetNotFound)
+		_, err = service.Annotate(ctx, entities.AnnotationTargetTransaction, "tx-missing", req)
+		assert.ErrorIs(t, err, ErrAnnotationTargetNotFound)
+		assert.Empty(t, annotationRepo.annotations)
+	})
+
+	t.Run("blank or oversized notes are rejected", func(t *testing.T) {
+		service, _ := newService(t)
+
+		_, err := service.Annotate(ctx, entities.AnnotationTargetUser, "1", entities.AnnotationRequest{
+			Author: "alice", Note: "   ",
+		})
+		assert.ErrorIs(t, err, ErrInvalidAnnotation)
+
+		_, err = service.Annotate(ctx, entities.AnnotationTargetUser, "1", entities.AnnotationRequest{
+			Author: "alice", Note: strings.Repeat("x", MaxAnnotationLength+1),
+		})
+		assert.ErrorIs(t, err, ErrInvalidAnnotation)
+	})
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/processor"
+)
+
+var ErrAsyncQueueFull = errors.New("too many transactions are queued")
+
+// asyncRetention is the number of finished transactions whose status is kept
+const asyncRetention = 10000
+
+// AsyncProcessor processes transactions in the background for the source
+// systems that cannot wait for them, on a processor pool serializing the
+// transactions of each user. Statuses live in memory: they are reported by the instance
+// the transaction was submitted to, and lost on restart. Transactions that
+// were processed are found in the ledger by every instance; the others are
+// safe to submit again, since transaction IDs are idempotency keys.
+type AsyncProcessor struct {
+	transactions *TransactionService
+	pool         *processor.Pool
+
+	mu sync.Mutex
+	// statuses holds every retained status; order lists their transaction
+	// IDs oldest first
+	statuses map[string]*entities.AsyncTransaction
+	order    []string
+	now      func() time.Time
+}
+
+// NewAsyncProcessor creates a new AsyncProcessor queueing transactions on
+// pool, which must be running for them to be processed
+func NewAsyncProcessor(transactions *TransactionService, pool *processor.Pool) *AsyncProcessor {
+	return &AsyncProcessor{
+		transactions: transactions,
+		pool:         pool,
+		statuses:     make(map[string]*entities.AsyncTransaction),
+		now:          time.Now,
+	}
+}
+
+// Submit queues a transaction, returning its pending status. Submitting a
+// transaction that is pending or processed again returns its status without
+// queueing it twice; a failed one is queued again. Transaction IDs submitted
+// for another user fail with ErrDuplicateTransaction. It fails with
+// ErrAsyncQueueFull when the queue of the user's worker is full, and with
+// ErrUnavailable once the pool is shutting down.
+func (p *AsyncProcessor) Submit(
+	ctx context.Context,
+	userID uint64,
+	req entities.TransactionRequest,
+	sourceType entities.SourceType,
+) (*entities.AsyncTransaction, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	existing, tracked := p.statuses[req.TransactionID]
+	if tracked && existing.UserID != userID {
+		return nil, ErrDuplicateTransaction
+	}
+	if tracked && existing.Status != entities.AsyncTransactionFailed {
+		return snapshotAsync(existing), nil
+	}
+
+	// The task cannot record its outcome before the status below is stored,
+	// since it waits for p.mu
+	err := p.pool.TrySubmit(processor.Task{
+		UserID: userID,
+		Run: func(ctx context.Context) {
+			// Accepted transactions are processed even while shutting down
+			p.process(context.WithoutCancel(ctx), userID, req, sourceType)
+		},
+	})
+	switch {
+	case errors.Is(err, processor.ErrQueueFull):
+		return nil, ErrAsyncQueueFull
+	case errors.Is(err, processor.ErrClosed):
+		return nil, ErrUnavailable
+	case err != nil:
+		return nil, err
+	}
+	submittedAt := p.now()
+	status := &entities.AsyncTransaction{
+		TransactionID: req.TransactionID,
+		UserID:        userID,
+		Status:        entities.AsyncTransactionPending,
+		SubmittedAt:   &submittedAt,
+	}
+	p.statuses[req.TransactionID] = status
+	if !tracked {
+		p.order = append(p.order, req.TransactionID)
+		p.prune()
+	}
+
+	return snapshotAsync(status), nil
+}
+
+// prune forgets the oldest finished statuses beyond the retention; p.mu must
+// be held
+func (p *AsyncProcessor) prune() {
+	excess := len(p.order) - asyncRetention
+	if excess <= 0 {
+		return
+	}
+	kept := p.order[:0]
+	for _, id := range p.order {
+		if excess > 0 && p.statuses[id].Status != entities.AsyncTransactionPending {
+			delete(p.statuses, id)
+			excess--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	p.order = kept
+}
+
+// Status returns the status of a transaction. Transactions this instance has
+// no status of are looked up in the ledger; ErrTransactionNotFound is
+// returned when they are not there either.
+func (p *AsyncProcessor) Status(ctx context.Context, transactionID string) (*entities.AsyncTransaction, error) {
+	p.mu.Lock()
+	status, ok := p.statuses[transactionID]
+	if ok {
+		status = snapshotAsync(status)
+	}
+	p.mu.Unlock()
+
+	if ok {
+		return status, nil
+	}
+	return p.transactions.TransactionStatus(ctx, transactionID)
+}
+
+// snapshotAsync copies a status; the processor's mutex must be held
+func snapshotAsync(status *entities.AsyncTransaction) *entities.AsyncTransaction {
+	copied := *status
+	return &copied
+}
+
+func (p *AsyncProcessor) process(
+	ctx context.Context,
+	userID uint64,
+	req entities.TransactionRequest,
+	sourceType entities.SourceType,
+) {
+	result, err := p.transactions.ProcessTransaction(ctx, userID, req, sourceType)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	status, ok := p.statuses[req.TransactionID]
+	if !ok {
+		return
+	}
+	finishedAt := p.now()
+	status.FinishedAt = &finishedAt
+	if err != nil {
+		status.Status = entities.AsyncTransactionFailed
+		status.Err = err
+		return
+	}
+	status.Status = entities.AsyncTransactionProcessed
+	status.Result = result
+}
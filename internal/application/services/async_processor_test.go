@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/processor"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAsyncProcessor(t *testing.T) {
+	newProcessor := func(queueSize int) (*AsyncProcessor, *fakeTransactionRepo) {
+		userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(10)})
+		transactionRepo := newFakeTransactionRepo()
+		service := NewTransactionService(&fakeUnitOfWork{}, userRepo, transactionRepo)
+		// A single worker, so that the queue size is the one of the user
+		return NewAsyncProcessor(service, processor.New(1, queueSize)), transactionRepo
+	}
+	start := func(t *testing.T, async *AsyncProcessor) (stop func()) {
+		ctx, cancel := context.WithCancel(context.Background())
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			async.pool.Run(ctx)
+		}()
+		stop = func() {
+			cancel()
+			wg.Wait()
+		}
+		t.Cleanup(stop)
+		return stop
+	}
+	waitFinished := func(t *testing.T, async *AsyncProcessor, transactionID string) *entities.AsyncTransaction {
+		var status *entities.AsyncTransaction
+		require.Eventually(t, func() bool {
+			var err error
+			status, err = async.Status(context.Background(), transactionID)
+			require.NoError(t, err)
+			return status.Status != entities.AsyncTransactionPending
+		}, time.Second, time.Millisecond)
+		return status
+	}
+	ctx := context.Background()
+
+	t.Run("processes submitted transactions in the background", func(t *testing.T) {
+		async, transactionRepo := newProcessor(10)
+
+		status, err := async.Submit(ctx, 1, entities.TransactionRequest{State: "win", Amount: "5", TransactionID: "tx-1"}, entities.SourceTypeGame)
+		require.NoError(t, err)
+		assert.Equal(t, entities.AsyncTransactionPending, status.Status)
+		assert.Equal(t, uint64(1), status.UserID)
+		assert.NotNil(t, status.SubmittedAt)
+		assert.Nil(t, status.FinishedAt)
+		_, err = async.Submit(ctx, 1, entities.TransactionRequest{State: "lose", Amount: "100", TransactionID: "tx-2"}, entities.SourceTypeGame)
+		require.NoError(t, err)
+
+		start(t, async)
+		status = waitFinished(t, async, "tx-1")
+		assert.Equal(t, entities.AsyncTransactionProcessed, status.Status)
+		require.NotNil(t, status.Result)
+		assert.Equal(t, "15.00", status.Result.Balance)
+		assert.NotNil(t, status.FinishedAt)
+
+		status = waitFinished(t, async, "tx-2")
+		assert.Equal(t, entities.AsyncTransactionFailed, status.Status)
+		assert.ErrorIs(t, status.Err, ErrInsufficientFunds)
+		assert.Nil(t, status.Result)
+		assert.Len(t, transactionRepo.transactions, 1)
+	})
+
+	t.Run("resubmitted transactions are not queued twice", func(t *testing.T) {
+		async, _ := newProcessor(1)
+		req := entities.TransactionRequest{State: "win", Amount: "5", TransactionID: "tx-1"}
+
+		_, err := async.Submit(ctx, 1, req, entities.SourceTypeGame)
+		require.NoError(t, err)
+		status, err := async.Submit(ctx, 1, req, entities.SourceTypeGame)
+		require.NoError(t, err, "the queue only holds the first submission")
+		assert.Equal(t, entities.AsyncTransactionPending, status.Status)
+
+		_, err = async.Submit(ctx, 2, req, entities.SourceTypeGame)
+		assert.ErrorIs(t, err, ErrDuplicateTransaction)
+	})
+
+	t.Run("failed transactions are queued again", func(t *testing.T) {
+		async, transactionRepo := newProcessor(10)
+		start(t, async)
+
+		_, err := async.Submit(ctx, 1, entities.TransactionRequest{State: "lose", Amount: "15", TransactionID: "tx-1"}, entities.SourceTypeGame)
+		require.NoError(t, err)
+		assert.Equal(t, entities.AsyncTransactionFailed, waitFinished(t, async, "tx-1").Status)
+
+		status, err := async.Submit(ctx, 1, entities.TransactionRequest{State: "lose", Amount: "5", TransactionID: "tx-1"}, entities.SourceTypeGame)
+		require.NoError(t, err)
+		assert.Equal(t, entities.AsyncTransactionPending, status.Status)
+		status = waitFinished(t, async, "tx-1")
+		assert.Equal(t, entities.AsyncTransactionProcessed, status.Status)
+		assert.NoError(t, status.Err)
+		assert.Len(t, transactionRepo.transactions, 1)
+	})
+
+	t.Run("submissions beyond the queue size are refused", func(t *testing.T) {
+		async, _ := newProcessor(1)
+
+		_, err := async.Submit(ctx, 1, entities.TransactionRequest{State: "win", Amount: "5", TransactionID: "tx-1"}, entities.SourceTypeGame)
+		require.NoError(t, err)
+		_, err = async.Submit(ctx, 1, entities.TransactionRequest{State: "win", Amount: "5", TransactionID: "tx-2"}, entities.SourceTypeGame)
+		assert.ErrorIs(t, err, ErrAsyncQueueFull)
+
+		_, err = async.Status(ctx, "tx-2")
+		assert.ErrorIs(t, err, ErrTransactionNotFound, "refused transactions are not tracked")
+	})
+
+	t.Run("transactions it has no status of are looked up in the ledger", func(t *testing.T) {
+		async, _ := newProcessor(10)
+		_, err := async.transactions.ProcessTransaction(ctx, 1, entities.TransactionRequest{State: "win", Amount: "5", TransactionID: "tx-1"}, entities.SourceTypeGame)
+		require.NoError(t, err)
+
+		status, err := async.Status(ctx, "tx-1")
+		require.NoError(t, err)
+		assert.Equal(t, entities.AsyncTransactionProcessed, status.Status)
+		assert.Equal(t, "15.00", status.Result.Balance)
+
+		_, err = async.Status(ctx, "unknown")
+		assert.ErrorIs(t, err, ErrTransactionNotFound)
+	})
+
+	t.Run("queued transactions are processed before shutting down", func(t *testing.T) {
+		async, transactionRepo := newProcessor(10)
+		for _, id := range []string{"tx-1", "tx-2", "tx-3"} {
+			_, err := async.Submit(ctx, 1, entities.TransactionRequest{State: "win", Amount: "1", TransactionID: id}, entities.SourceTypeGame)
+			require.NoError(t, err)
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		async.pool.Run(ctx)
+
+		assert.Len(t, transactionRepo.transactions, 3)
+		_, err := async.Submit(ctx, 1, entities.TransactionRequest{State: "win", Amount: "1", TransactionID: "tx-4"}, entities.SourceTypeGame)
+		assert.ErrorIs(t, err, ErrUnavailable)
+	})
+}
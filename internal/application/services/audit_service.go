@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/shopspring/decimal"
+)
+
+// AuditRecorder appends entries to the audit log. It runs in the unit of work
+// of the operation, so an entry commits or rolls back with the operation it
+// records; returning an error rolls the operation back.
+type AuditRecorder interface {
+	RecordAudit(ctx context.Context, entry *entities.AuditEntry) error
+}
+
+// WithAuditRecorder records every transaction the service writes in the
+// audit log: processed transactions, refunds, transfer legs, fees and
+// adjustments, contra legs included
+func WithAuditRecorder(recorder AuditRecorder) TransactionServiceOption {
+	return func(s *TransactionService) {
+		s.audit = recorder
+	}
+}
+
+// WithCancellationAuditRecorder records every cancellation in the audit log
+func WithCancellationAuditRecorder(recorder AuditRecorder) CancellationServiceOption {
+	return func(s *CancellationService) {
+		s.audit = recorder
+	}
+}
+
+type auditActorKey struct{}
+
+// withAuditActor names the operator behind the writes made with the returned
+// context in their audit entries
+func withAuditActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, auditActorKey{}, actor)
+}
+
+// auditActor returns the operator set by withAuditActor, if any
+func auditActor(ctx context.Context) string {
+	actor, _ := ctx.Value(auditActorKey{}).(string)
+	return actor
+}
+
+// recordAudit records the transaction of event in the audit log. It runs
+// after the transaction and the balance were written.
+func (s *TransactionService) recordAudit(ctx context.Context, event *TransactionEvent) error {
+	if s.audit == nil {
+		return nil
+	}
+
+	transaction := event.Transaction
+	operation := entities.AuditOperation(transaction.Type)
+	if transaction.Type == "" {
+		operation = entities.AuditOperationTransaction
+	}
+	// Pending transactions move the balance once settled, in an entry of
+	// their own
+	change := transaction.SignedAmount()
+	if transaction.Pending {
+		change = decimal.Zero
+	}
+	return s.audit.RecordAudit(ctx, &entities.AuditEntry{
+		UserID:        transaction.UserID,
+		Operation:     operation,
+		TransactionID: transaction.TransactionID,
+		Change:        change,
+		Currency:      transaction.Currency,
+		BalanceBefore: event.NewBalance.Sub(change),
+		BalanceAfter:  event.NewBalance,
+		Actor:         auditActor(ctx),
+		CreatedAt:     transaction.CreatedAt,
+	})
+}
+
+// AuditService keeps the append-only audit log of the balance-affecting
+// operations and lists its entries
+type AuditService struct {
+	auditRepo repositories.AuditLogRepository
+}
+
+// NewAuditService creates a new AuditService
+func NewAuditService(auditRepo repositories.AuditLogRepository) *AuditService {
+	return &AuditService{
+		auditRepo: auditRepo,
+	}
+}
+
+// RecordAudit implements AuditRecorder
+func (s *AuditService) RecordAudit(ctx context.Context, entry *entities.AuditEntry) error {
+	return s.auditRepo.Append(ctx, entry)
+}
+
+// ListEntries returns a page of the entries matching the filter, newest first
+func (s *AuditService) ListEntries(ctx context.Context, filter repositories.AuditLogFilter) (*entities.AuditLogPage, error) {
+	if filter.Limit < 0 || filter.Offset < 0 || filter.Limit > MaxPageSize {
+		return nil, ErrInvalidFilter
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return nil, ErrInvalidFilter
+	}
+	if filter.Limit == 0 {
+		filter.Limit = DefaultPageSize
+	}
+
+	entries, total, err := s.auditRepo.List(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+
+	return &entities.AuditLogPage{
+		Entries: entries,
+		Total:   total,
+		Limit:   filter.Limit,
+		Offset:  filter.Offset,
+	}, nil
+}
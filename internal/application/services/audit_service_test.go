@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionService_AuditLog(t *testing.T) {
+	ctx := context.Background()
+	userRepo := newFakeUserRepo(
+		&entities.User{ID: 1, Balance: decimal.NewFromInt(100)},
+		&entities.User{ID: 2, Balance: decimal.NewFromInt(50)},
+	)
+	auditRepo := &fakeAuditLogRepo{}
+	service := NewTransactionService(&fakeUnitOfWork{}, userRepo, newFakeTransactionRepo(),
+		WithBalanceAdjustments(&fakeBalanceAdjustmentRepo{}),
+		WithAuditRecorder(NewAuditService(auditRepo)))
+
+	lastEntry := func() *entities.AuditEntry {
+		require.NotEmpty(t, auditRepo.entries)
+		return auditRepo.entries[len(auditRepo.entries)-1]
+	}
+
+	t.Run("processed transactions are audited with the balance before and after", func(t *testing.T) {
+		_, err := service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "lose", Amount: "30.00", TransactionID: "tx-1",
+		}, entities.SourceTypeGame)
+		require.NoError(t, err)
+
+		entry := lastEntry()
+		assert.Equal(t, uint64(1), entry.UserID)
+		assert.Equal(t, entities.AuditOperationTransaction, entry.Operation)
+		assert.Equal(t, "tx-1", entry.TransactionID)
+		assert.Equal(t, "-30", entry.Change.String())
+		assert.Equal(t, "100", entry.BalanceBefore.String())
+		assert.Equal(t, "70", entry.BalanceAfter.String())
+		assert.Empty(t, entry.Actor)
+	})
+
+	t.Run("refunds are audited", func(t *testing.T) {
+		_, err := service.RefundTransaction(ctx, "tx-1")
+		require.NoError(t, err)
+
+		entry := lastEntry()
+		assert.Equal(t, entities.AuditOperationRefund, entry.Operation)
+		assert.Equal(t, "30", entry.Change.String())
+		assert.Equal(t, "100", entry.BalanceAfter.String())
+	})
+
+	t.Run("both legs of transfers are audited", func(t *testing.T) {
+		before := len(auditRepo.entries)
+		_, err := service.Transfer(ctx, entities.TransferRequest{
+			TransferID: "transfer-1", FromUserID: 1, ToUserID: 2, Amount: "10.00",
+		})
+		require.NoError(t, err)
+
+		legs := auditRepo.entries[before:]
+		require.Len(t, legs, 2)
+		changes := map[uint64]string{}
+		for _, leg := range legs {
+			assert.Equal(t, entities.AuditOperationTransfer, leg.Operation)
+			changes[leg.UserID] = leg.Change.String()
+		}
+		assert.Equal(t, map[uint64]string{1: "-10", 2: "10"}, changes)
+	})
+
+	t.Run("adjustments are audited with their actor", func(t *testing.T) {
+		_, err := service.AdjustBalance(ctx, 2, entities.BalanceAdjustmentRequest{
+			AdjustmentID: "adj-1", Direction: entities.AdjustmentCredit, Amount: "5.00",
+			Reason: "goodwill", Actor: "ops@example.com",
+		})
+		require.NoError(t, err)
+
+		entry := lastEntry()
+		assert.Equal(t, entities.AuditOperationAdjustment, entry.Operation)
+		assert.Equal(t, AdjustmentTransactionID("adj-1"), entry.TransactionID)
+		assert.Equal(t, "60", entry.BalanceBefore.String())
+		assert.Equal(t, "65", entry.BalanceAfter.String())
+		assert.Equal(t, "ops@example.com", entry.Actor)
+	})
+
+	t.Run("rejected transactions are not audited", func(t *testing.T) {
+		before := len(auditRepo.entries)
+		_, err := service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "lose", Amount: "1000.00", TransactionID: "tx-2",
+		}, entities.SourceTypeGame)
+		assert.ErrorIs(t, err, ErrInsufficientFunds)
+		assert.Len(t, auditRepo.entries, before)
+	})
+}
+
+func TestCancellationService_AuditLog(t *testing.T) {
+	ctx := context.Background()
+	userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(100)})
+	transactionRepo := newFakeTransactionRepo()
+	transactionService := NewTransactionService(&fakeUnitOfWork{}, userRepo, transactionRepo)
+
+	_, err := transactionService.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+		State: "win", Amount: "10", TransactionID: "tx-1",
+	}, entities.SourceTypeGame)
+	require.NoError(t, err)
+
+	auditRepo := &fakeAuditLogRepo{}
+	service := NewCancellationService(&fakeUnitOfWork{}, userRepo, newFakeWalletRepo(), transactionRepo,
+		WithCancellationAuditRecorder(NewAuditService(auditRepo)))
+
+	result, err := service.CancelLatestOddTransactions(ctx, 10)
+	require.NoError(t, err)
+	require.Equal(t, []string{"tx-1"}, result.Cancelled)
+
+	require.Len(t, auditRepo.entries, 1)
+	entry := auditRepo.entries[0]
+	assert.Equal(t, entities.AuditOperationCancellation, entry.Operation)
+	assert.Equal(t, "tx-1", entry.TransactionID)
+	assert.Equal(t, "-10", entry.Change.String())
+	assert.Equal(t, "110", entry.BalanceBefore.String())
+	assert.Equal(t, "100", entry.BalanceAfter.String())
+}
+
+func TestAuditService_ListEntries(t *testing.T) {
+	ctx := context.Background()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	auditRepo := &fakeAuditLogRepo{}
+	service := NewAuditService(auditRepo)
+	for i, userID := range []uint64{1, 2, 1} {
+		require.NoError(t, service.RecordAudit(ctx, &entities.AuditEntry{
+			UserID: userID, Operation: entities.AuditOperationTransaction,
+			CreatedAt: base.Add(time.Duration(i) * time.Hour),
+		}))
+	}
+
+	t.Run("filters by user, newest first", func(t *testing.T) {
+		page, err := service.ListEntries(ctx, repositories.AuditLogFilter{UserID: 1})
+		require.NoError(t, err)
+		assert.Equal(t, 2, page.Total)
+		assert.Equal(t, DefaultPageSize, page.Limit)
+		require.Len(t, page.Entries, 2)
+		assert.Equal(t, uint64(3), page.Entries[0].ID)
+	})
+
+	t.Run("filters by time range", func(t *testing.T) {
+		from, to := base.Add(time.Hour), base.Add(2*time.Hour)
+		page, err := service.ListEntries(ctx, repositories.AuditLogFilter{From: &from, To: &to})
+		require.NoError(t, err)
+		require.Len(t, page.Entries, 1)
+		assert.Equal(t, uint64(2), page.Entries[0].UserID)
+	})
+
+	t.Run("invalid filters are rejected", func(t *testing.T) {
+		from, to := base.Add(time.Hour), base
+		for _, filter := range []repositories.AuditLogFilter{
+			{Limit: MaxPageSize + 1},
+			{Offset: -1},
+			{From: &from, To: &to},
+		} {
+			_, err := service.ListEntries(ctx, filter)
+			assert.ErrorIs(t, err, ErrInvalidFilter)
+		}
+	})
+}
@@ -0,0 +1,268 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// MaxAdjustmentReasonLength is the maximum length of the reason of a
+	// balance adjustment in characters
+	MaxAdjustmentReasonLength = 1000
+	// MaxAdjustmentActorLength is the maximum length of the actor of a
+	// balance adjustment
+	MaxAdjustmentActorLength = 255
+)
+
+var (
+	ErrInvalidAdjustment   = errors.New("invalid balance adjustment")
+	ErrDuplicateAdjustment = errors.New("adjustment ID already used for a different adjustment")
+)
+
+// WithBalanceAdjustments lets operators credit and debit users by hand,
+// auditing every adjustment in adjustments
+func WithBalanceAdjustments(adjustments repositories.BalanceAdjustmentRepository) TransactionServiceOption {
+	return func(s *TransactionService) {
+		s.adjustments = adjustments
+	}
+}
+
+// AdjustmentTransactionID returns the transaction ID an adjustment is
+// recorded under in the user's history
+func AdjustmentTransactionID(adjustmentID string) string {
+	return "adjustment:" + adjustmentID
+}
+
+// AdjustBalance credits or debits the user by hand. The adjustment is
+// recorded as an adjustment transaction in the user's history, and audited
+// with its reason, its actor and the balance before and after it, all in a
+// single unit of work. With system accounts, the house is the counterparty of
+// base currency adjustments.
+//
+// Adjustments are operator corrections: they are applied to frozen accounts
+// and skip the balance guard and the jurisdiction rules, but debits may
+// neither make the balance negative nor spend held amounts. Replaying an
+// adjustment returns the original record with Replayed set, while reusing an
+// adjustment ID for a different adjustment fails with ErrDuplicateAdjustment.
+func (s *TransactionService) AdjustBalance(
+	ctx context.Context,
+	userID uint64,
+	req entities.BalanceAdjustmentRequest,
+) (*entities.BalanceAdjustment, error) {
+	// Writes belong to the active region
+	if s.region != nil && !s.region.AcceptsWrites() {
+		return nil, ErrRegionStandby
+	}
+
+	reason := strings.TrimSpace(req.Reason)
+	actor := strings.TrimSpace(req.Actor)
+	switch {
+	case req.AdjustmentID == "" || len(req.AdjustmentID) > MaxAdjustmentIDLength:
+		return nil, fmt.Errorf("%w: adjustmentId must be 1 to %d characters", ErrInvalidAdjustment, MaxAdjustmentIDLength)
+	case !req.Direction.IsValid():
+		return nil, fmt.Errorf("%w: direction must be credit or debit", ErrInvalidAdjustment)
+	case reason == "" || len([]rune(reason)) > MaxAdjustmentReasonLength:
+		return nil, fmt.Errorf("%w: reason must be 1 to %d characters", ErrInvalidAdjustment, MaxAdjustmentReasonLength)
+	case actor == "" || len(actor) > MaxAdjustmentActorLength:
+		return nil, fmt.Errorf("%w: actor must be 1 to %d characters", ErrInvalidAdjustment, MaxAdjustmentActorLength)
+	}
+	amount, err := decimal.NewFromString(req.Amount)
+	if err != nil || !amount.IsPositive() {
+		return nil, ErrInvalidAmount
+	}
+	currency, err := s.walletCurrency(req.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	// System accounts only move as the counterparty of corrections
+	if s.isSystemAccount(userID) {
+		return nil, ErrSystemAccount
+	}
+
+	now := s.now()
+	var adjustment *entities.BalanceAdjustment
+
+	ctx = withAuditActor(ctx, actor)
+	err = s.uow.WithinTransaction(ctx, func(ctx context.Context) error {
+		// Base currency adjustments move the house, which is locked first
+		if currency == "" {
+			if err := s.lockHouse(ctx); err != nil {
+				return err
+			}
+		}
+
+		// The lock on the user serializes retries of the same adjustment
+		user, err := s.userRepo.GetByIDForUpdate(ctx, userID)
+		if err != nil {
+			if errors.Is(err, repositories.ErrNotFound) {
+				return ErrUserNotFound
+			}
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+
+		// Replay the original record of an adjustment that was already made
+		existing, err := s.adjustments.GetByAdjustmentID(ctx, req.AdjustmentID)
+		if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+			return fmt.Errorf("failed to check adjustment existence: %w", err)
+		}
+		if existing != nil {
+			if !isAdjustmentReplay(existing, userID, req.Direction, amount, currency) {
+				return ErrDuplicateAdjustment
+			}
+			adjustment = existing
+			adjustment.Replayed = true
+			return nil
+		}
+
+		balance := user.Balance
+		if currency != "" {
+			wallet, err := s.walletRepo.GetForUpdate(ctx, user.ID, currency)
+			if err != nil {
+				return fmt.Errorf("failed to get wallet: %w", err)
+			}
+			balance = wallet.Balance
+		}
+
+		state := req.Direction.State()
+		newBalance := balance.Add(amount)
+		if state == entities.StateLose {
+			newBalance = balance.Sub(amount)
+		}
+		if state == entities.StateLose && newBalance.Add(creditLimit(user, currency)).IsNegative() {
+			return ErrInsufficientFunds
+		}
+
+		// Debits may not spend the amounts reserved by holds
+		if s.holdRepo != nil && currency == "" && state == entities.StateLose {
+			held, err := s.holdRepo.SumActive(ctx, user.ID, now)
+			if err != nil {
+				return err
+			}
+			if newBalance.Add(user.CreditLimit).Sub(held).IsNegative() {
+				return ErrInsufficientFunds
+			}
+		}
+
+		transaction := &entities.Transaction{
+			UserID:        user.ID,
+			TransactionID: AdjustmentTransactionID(req.AdjustmentID),
+			State:         state,
+			Amount:        amount,
+			SourceType:    entities.SourceTypeServer,
+			Currency:      currency,
+			CreatedAt:     now,
+			BalanceAfter:  &newBalance,
+			Type:          entities.TransactionTypeAdjustment,
+		}
+		if err := s.recordLeg(ctx, user, transaction, ErrDuplicateAdjustment); err != nil {
+			return err
+		}
+		if currency == "" {
+			if err := s.adjustHouse(ctx, transaction); err != nil {
+				return err
+			}
+		}
+
+		adjustment = &entities.BalanceAdjustment{
+			AdjustmentID:  req.AdjustmentID,
+			UserID:        user.ID,
+			TransactionID: transaction.TransactionID,
+			Direction:     req.Direction,
+			Amount:        amount,
+			Currency:      currency,
+			Reason:        reason,
+			Actor:         actor,
+			BalanceBefore: balance,
+			BalanceAfter:  newBalance,
+			CreatedAt:     now,
+		}
+		if err := s.adjustments.Create(ctx, adjustment); err != nil {
+			if errors.Is(err, repositories.ErrConflict) {
+				return ErrDuplicateAdjustment
+			}
+			return fmt.Errorf("failed to create balance adjustment: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the base currency balance is cached
+	if !adjustment.Replayed && currency == "" {
+		s.cacheBalance(ctx, userID, adjustment.BalanceAfter, time.Now())
+		s.invalidateBalance(ctx, userID)
+	}
+
+	return adjustment, nil
+}
+
+// ListBalanceAdjustments returns the audit records of the user's
+// adjustments, newest first
+func (s *TransactionService) ListBalanceAdjustments(ctx context.Context, userID uint64) ([]*entities.BalanceAdjustment, error) {
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	adjustments, err := s.adjustments.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list balance adjustments: %w", err)
+	}
+	return adjustments, nil
+}
+
+// adjustHouse records the contra leg of a base currency adjustment, moving
+// the house by the opposite of the adjustment. The house may go negative. It
+// runs in the unit of work of the adjustment, which locked the house with
+// lockHouse before the user, and does nothing without system accounts.
+func (s *TransactionService) adjustHouse(ctx context.Context, transaction *entities.Transaction) error {
+	houseID, ok := s.systemAccount(entities.SystemAccountHouse)
+	if !ok {
+		return nil
+	}
+
+	house, err := s.userRepo.GetByIDForUpdate(ctx, houseID)
+	if err != nil {
+		return fmt.Errorf("failed to get house account: %w", err)
+	}
+
+	contra := &entities.Transaction{
+		UserID:        houseID,
+		TransactionID: ContraTransactionID(transaction.TransactionID),
+		State:         transaction.State.Opposite(),
+		Amount:        transaction.Amount,
+		SourceType:    entities.SourceTypeServer,
+		CreatedAt:     transaction.CreatedAt,
+		Type:          entities.TransactionTypeAdjustment,
+	}
+	balance := house.Balance.Add(contra.SignedAmount())
+	contra.BalanceAfter = &balance
+	return s.recordLeg(ctx, house, contra, ErrDuplicateAdjustment)
+}
+
+// isAdjustmentReplay reports whether an already made adjustment matches the
+// incoming request, so its original record can be returned
+func isAdjustmentReplay(
+	existing *entities.BalanceAdjustment,
+	userID uint64,
+	direction entities.AdjustmentDirection,
+	amount decimal.Decimal,
+	currency string,
+) bool {
+	return existing.UserID == userID &&
+		existing.Direction == direction &&
+		existing.Amount.Equal(amount) &&
+		existing.Currency == currency
+}
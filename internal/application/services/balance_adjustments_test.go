@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionService_AdjustBalance(t *testing.T) {
+	ctx := context.Background()
+	userRepo := newFakeUserRepo(
+		&entities.User{ID: 1, Balance: decimal.NewFromInt(100)},
+		&entities.User{ID: 2, Balance: decimal.NewFromInt(10), Status: entities.UserStatusFrozen},
+	)
+	transactionRepo := newFakeTransactionRepo()
+	adjustmentRepo := &fakeBalanceAdjustmentRepo{}
+	holdRepo := &fakeHoldRepo{}
+	service := NewTransactionService(&fakeUnitOfWork{}, userRepo, transactionRepo,
+		WithBalanceAdjustments(adjustmentRepo), WithHolds(holdRepo))
+
+	adjust := func(userID uint64, id string, direction entities.AdjustmentDirection, amount string) (*entities.BalanceAdjustment, error) {
+		return service.AdjustBalance(ctx, userID, entities.BalanceAdjustmentRequest{
+			AdjustmentID: id, Direction: direction, Amount: amount, Reason: "goodwill", Actor: "ops@example.com",
+		})
+	}
+
+	t.Run("credits are recorded in the history and audited", func(t *testing.T) {
+		adjustment, err := adjust(1, "adj-1", entities.AdjustmentCredit, "25.00")
+		require.NoError(t, err)
+		assert.False(t, adjustment.Replayed)
+		assert.Equal(t, "100", adjustment.BalanceBefore.String())
+		assert.Equal(t, "125", adjustment.BalanceAfter.String())
+		assert.Equal(t, "ops@example.com", adjustment.Actor)
+		assert.Equal(t, "125", userRepo.users[1].Balance.String())
+
+		transaction, err := transactionRepo.GetByTransactionID(ctx, AdjustmentTransactionID("adj-1"))
+		require.NoError(t, err)
+		assert.Equal(t, entities.TransactionTypeAdjustment, transaction.Type)
+		assert.Equal(t, entities.StateWin, transaction.State)
+		assert.Equal(t, entities.SourceTypeServer, transaction.SourceType)
+		assert.Equal(t, adjustment.TransactionID, transaction.TransactionID)
+
+		// Adjustments are corrected by another adjustment
+		_, err = service.RefundTransaction(ctx, transaction.TransactionID)
+		assert.ErrorIs(t, err, ErrNotRefundable)
+	})
+
+	t.Run("debits take the amount", func(t *testing.T) {
+		adjustment, err := adjust(1, "adj-2", entities.AdjustmentDebit, "5.00")
+		require.NoError(t, err)
+		assert.Equal(t, "120", adjustment.BalanceAfter.String())
+		assert.Equal(t, "120", userRepo.users[1].Balance.String())
+	})
+
+	t.Run("debits may not make the balance negative", func(t *testing.T) {
+		_, err := adjust(1, "adj-3", entities.AdjustmentDebit, "500.00")
+		assert.ErrorIs(t, err, ErrInsufficientFunds)
+	})
+
+	t.Run("debits may not spend held amounts", func(t *testing.T) {
+		require.NoError(t, holdRepo.Create(ctx, &entities.Hold{
+			HoldID: "hold-1", UserID: 1, Amount: decimal.NewFromInt(100), Status: entities.HoldStatusHeld,
+			ExpiresAt: time.Now().Add(time.Hour),
+		}))
+		_, err := adjust(1, "adj-4", entities.AdjustmentDebit, "30.00")
+		assert.ErrorIs(t, err, ErrInsufficientFunds)
+		assert.Equal(t, "120", userRepo.users[1].Balance.String())
+	})
+
+	t.Run("replays return the original record", func(t *testing.T) {
+		adjustment, err := adjust(1, "adj-1", entities.AdjustmentCredit, "25.00")
+		require.NoError(t, err)
+		assert.True(t, adjustment.Replayed)
+		assert.Equal(t, "125", adjustment.BalanceAfter.String())
+		assert.Equal(t, "120", userRepo.users[1].Balance.String())
+
+		_, err = adjust(1, "adj-1", entities.AdjustmentDebit, "25.00")
+		assert.ErrorIs(t, err, ErrDuplicateAdjustment)
+	})
+
+	t.Run("frozen accounts are adjusted", func(t *testing.T) {
+		adjustment, err := adjust(2, "adj-5", entities.AdjustmentCredit, "1.50")
+		require.NoError(t, err)
+		assert.Equal(t, "11.5", adjustment.BalanceAfter.String())
+	})
+
+	t.Run("the history lists the newest first", func(t *testing.T) {
+		adjustments, err := service.ListBalanceAdjustments(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, adjustments, 2)
+		assert.Equal(t, "adj-2", adjustments[0].AdjustmentID)
+		assert.Equal(t, "adj-1", adjustments[1].AdjustmentID)
+
+		_, err = service.ListBalanceAdjustments(ctx, 99)
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+
+	t.Run("invalid requests are rejected", func(t *testing.T) {
+		tests := map[string]struct {
+			req  entities.BalanceAdjustmentRequest
+			want error
+		}{
+			"missing reason": {
+				entities.BalanceAdjustmentRequest{AdjustmentID: "a", Direction: "credit", Amount: "1", Actor: "ops"},
+				ErrInvalidAdjustment,
+			},
+			"missing actor": {
+				entities.BalanceAdjustmentRequest{AdjustmentID: "a", Direction: "credit", Amount: "1", Reason: "why"},
+				ErrInvalidAdjustment,
+			},
+			"unknown direction": {
+				entities.BalanceAdjustmentRequest{AdjustmentID: "a", Direction: "up", Amount: "1", Reason: "why", Actor: "ops"},
+				ErrInvalidAdjustment,
+			},
+			"negative amount": {
+				entities.BalanceAdjustmentRequest{AdjustmentID: "a", Direction: "debit", Amount: "-1", Reason: "why", Actor: "ops"},
+				ErrInvalidAmount,
+			},
+		}
+		for name, tt := range tests {
+			_, err := service.AdjustBalance(ctx, 1, tt.req)
+			assert.ErrorIs(t, err, tt.want, name)
+		}
+	})
+}
+
+func TestTransactionService_AdjustBalanceWithSystemAccounts(t *testing.T) {
+	ctx := context.Background()
+	userRepo := newSystemAccountUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(10)})
+	transactionRepo := newFakeTransactionRepo()
+	service := NewTransactionService(&fakeUnitOfWork{}, userRepo, transactionRepo,
+		WithBalanceAdjustments(&fakeBalanceAdjustmentRepo{}), WithSystemAccounts(testSystemAccounts))
+
+	_, err := service.AdjustBalance(ctx, 1, entities.BalanceAdjustmentRequest{
+		AdjustmentID: "adj-1", Direction: entities.AdjustmentCredit, Amount: "15.00", Reason: "goodwill", Actor: "ops",
+	})
+	require.NoError(t, err)
+
+	// The house paid for the credit
+	assert.Equal(t, "-15", userRepo.users[101].Balance.String())
+	contra, err := transactionRepo.GetByTransactionID(ctx, ContraTransactionID(AdjustmentTransactionID("adj-1")))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(101), contra.UserID)
+	assert.Equal(t, entities.StateLose, contra.State)
+	assert.Equal(t, entities.TransactionTypeAdjustment, contra.Type)
+
+	_, err = service.AdjustBalance(ctx, 101, entities.BalanceAdjustmentRequest{
+		AdjustmentID: "adj-2", Direction: entities.AdjustmentCredit, Amount: "15.00", Reason: "goodwill", Actor: "ops",
+	})
+	assert.ErrorIs(t, err, ErrSystemAccount)
+}
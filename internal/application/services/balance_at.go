@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/shopspring/decimal"
+)
+
+// ErrInvalidBalanceTime is returned for point-in-time balances requested for
+// the future
+var ErrInvalidBalanceTime = errors.New("balance time is in the future")
+
+// WithBalanceSnapshots replays point-in-time balances from the closing
+// balance of the last day ended before them, rather than back from the
+// current balance
+func WithBalanceSnapshots(snapshotRepo repositories.BalanceSnapshotRepository) TransactionServiceOption {
+	return func(s *TransactionService) {
+		s.snapshotRepo = snapshotRepo
+	}
+}
+
+// GetUserBalanceAt reconstructs the user's base currency balance as of at,
+// e.g. to resolve disputes with payment providers. The balance changes
+// between the closing balance of the last day ended by at and at are
+// replayed onto that snapshot; without one, the balance changes since at are
+// taken back out of the current balance. Settlements and cancellations count
+// when they happened, and pending transactions are left out until settled,
+// like from the balance itself.
+func (s *TransactionService) GetUserBalanceAt(
+	ctx context.Context,
+	userID uint64,
+	at time.Time,
+) (*entities.BalanceResponse, error) {
+	if at.After(s.now()) {
+		return nil, ErrInvalidBalanceTime
+	}
+
+	var balance decimal.Decimal
+	// The reads share one snapshot, so no transaction lands between reading
+	// the balance and totalling the changes since at, and writers of the
+	// user are not blocked meanwhile
+	err := s.uow.WithinTransaction(repositories.WithSnapshotRead(ctx), func(ctx context.Context) error {
+		user, err := s.userRepo.GetByID(ctx, userID)
+		if err != nil {
+			if errors.Is(err, repositories.ErrNotFound) {
+				return ErrUserNotFound
+			}
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+		sinceAt, err := s.transactionRepo.NetChangeSince(ctx, userID, at)
+		if err != nil {
+			return fmt.Errorf("failed to sum balance changes: %w", err)
+		}
+
+		if s.snapshotRepo != nil {
+			snapshot, err := s.snapshotRepo.GetLatestBefore(ctx, userID, utcDay(at))
+			switch {
+			case err == nil:
+				sinceClose, err := s.transactionRepo.NetChangeSince(ctx, userID, snapshot.Day.AddDate(0, 0, 1))
+				if err != nil {
+					return fmt.Errorf("failed to sum balance changes: %w", err)
+				}
+				balance = snapshot.Balance.Add(sinceClose.Sub(sinceAt))
+				return nil
+			case !errors.Is(err, repositories.ErrNotFound):
+				return fmt.Errorf("failed to get balance snapshot: %w", err)
+			}
+		}
+
+		balance = user.Balance.Sub(sinceAt)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	at = at.UTC()
+	return &entities.BalanceResponse{
+		UserID:   userID,
+		Balance:  balance.StringFixed(2),
+		Currency: s.baseCurrency,
+		AsOf:     &at,
+	}, nil
+}
@@ -0,0 +1,26 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// CachedBalance is a balance remembered from a previous successful read or write
+type CachedBalance struct {
+	Balance  decimal.Decimal
+	CachedAt time.Time
+}
+
+// BalanceCache stores the last known balance per user
+type BalanceCache interface {
+	Get(ctx context.Context, userID uint64) (CachedBalance, bool)
+	Set(ctx context.Context, userID uint64, balance CachedBalance)
+}
+
+// BalanceInvalidator announces balance changes so other replicas can drop
+// their cached copy instead of waiting for it to age out
+type BalanceInvalidator interface {
+	Invalidate(ctx context.Context, userID uint64) error
+}
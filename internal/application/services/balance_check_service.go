@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+)
+
+// balanceCheckBatchSize is the number of users recomputed at a time
+const balanceCheckBatchSize = 500
+
+// BalanceCheckService recomputes the balances from the transactions, reports
+// the ones that drifted and repairs them on request
+type BalanceCheckService struct {
+	uow         repositories.UnitOfWork
+	userRepo    repositories.UserRepository
+	walletRepo  repositories.WalletRepository
+	balanceRepo repositories.BalanceCheckRepository
+	// audit records every repair in the audit log; disabled when nil
+	audit     AuditRecorder
+	batchSize int
+}
+
+// BalanceCheckServiceOption configures optional BalanceCheckService behaviour
+type BalanceCheckServiceOption func(*BalanceCheckService)
+
+// WithBalanceCheckAuditRecorder records every repair in the audit log
+func WithBalanceCheckAuditRecorder(recorder AuditRecorder) BalanceCheckServiceOption {
+	return func(s *BalanceCheckService) {
+		s.audit = recorder
+	}
+}
+
+// NewBalanceCheckService creates a new BalanceCheckService
+func NewBalanceCheckService(
+	uow repositories.UnitOfWork,
+	userRepo repositories.UserRepository,
+	walletRepo repositories.WalletRepository,
+	balanceRepo repositories.BalanceCheckRepository,
+	opts ...BalanceCheckServiceOption,
+) *BalanceCheckService {
+	s := &BalanceCheckService{
+		uow:         uow,
+		userRepo:    userRepo,
+		walletRepo:  walletRepo,
+		balanceRepo: balanceRepo,
+		batchSize:   balanceCheckBatchSize,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// CheckBalances recomputes the balance of every user, wallets included, and
+// reports the ones that differ from the stored balance
+func (s *BalanceCheckService) CheckBalances(ctx context.Context) (*entities.BalanceCheckReport, error) {
+	return s.check(ctx, false, "")
+}
+
+// RepairBalances is CheckBalances setting every drifted balance to the
+// recomputed one, on behalf of actor. Each repair runs in its own unit of
+// work, which recomputes the balance again under the user's lock. Balances
+// recomputed as negative point at a missing transaction rather than at a
+// wrong balance; they are reported but left alone.
+func (s *BalanceCheckService) RepairBalances(ctx context.Context, actor string) (*entities.BalanceCheckReport, error) {
+	return s.check(ctx, true, actor)
+}
+
+func (s *BalanceCheckService) check(ctx context.Context, repair bool, actor string) (*entities.BalanceCheckReport, error) {
+	report := &entities.BalanceCheckReport{
+		CheckedAt: time.Now(),
+		Drifts:    []*entities.BalanceDrift{},
+	}
+
+	var afterID uint64
+	for {
+		balances, err := s.balanceRepo.Recompute(ctx, afterID, s.batchSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to recompute balances: %w", err)
+		}
+
+		users := 0
+		for _, balance := range balances {
+			if balance.Currency == "" {
+				users++
+				afterID = balance.UserID
+			}
+
+			report.Checked++
+			if balance.Recomputed == nil {
+				report.Unverifiable++
+				continue
+			}
+			if balance.Stored.Equal(*balance.Recomputed) {
+				continue
+			}
+
+			drift := newBalanceDrift(balance)
+			if repair && !drift.RecomputedBalance.IsNegative() {
+				if err := s.repair(ctx, drift, actor); err != nil {
+					return nil, err
+				}
+				if drift.Repaired {
+					report.Repaired++
+				}
+			}
+			report.Drifts = append(report.Drifts, drift)
+		}
+
+		if users < s.batchSize {
+			return report, nil
+		}
+	}
+}
+
+// repair sets the drifted balance to the one recomputed under the user's
+// lock, updating drift with it. The balance is left alone if it no longer
+// drifts.
+func (s *BalanceCheckService) repair(ctx context.Context, drift *entities.BalanceDrift, actor string) error {
+	err := s.uow.WithinTransaction(ctx, func(ctx context.Context) error {
+		// Lock the user before its wallet, in the same order as transactions
+		if _, err := s.userRepo.GetByIDForUpdate(ctx, drift.UserID); err != nil {
+			return fmt.Errorf("failed to get user %d: %w", drift.UserID, err)
+		}
+		if drift.Currency != "" {
+			if _, err := s.walletRepo.GetForUpdate(ctx, drift.UserID, drift.Currency); err != nil {
+				return fmt.Errorf("failed to get %s wallet of user %d: %w", drift.Currency, drift.UserID, err)
+			}
+		}
+
+		balances, err := s.balanceRepo.Recompute(ctx, drift.UserID-1, 1)
+		if err != nil {
+			return fmt.Errorf("failed to recompute balances of user %d: %w", drift.UserID, err)
+		}
+		var current *repositories.BalanceRecomputation
+		for i := range balances {
+			if balances[i].UserID == drift.UserID && balances[i].Currency == drift.Currency {
+				current = &balances[i]
+			}
+		}
+		if current == nil || current.Recomputed == nil || current.Recomputed.IsNegative() ||
+			current.Stored.Equal(*current.Recomputed) {
+			return nil
+		}
+		*drift = *newBalanceDrift(*current)
+
+		if drift.Currency != "" {
+			err = s.walletRepo.UpdateBalance(ctx, drift.UserID, drift.Currency, drift.RecomputedBalance)
+		} else {
+			err = s.userRepo.UpdateBalance(ctx, drift.UserID, drift.RecomputedBalance)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to repair balance of user %d: %w", drift.UserID, err)
+		}
+
+		if s.audit != nil {
+			err := s.audit.RecordAudit(ctx, &entities.AuditEntry{
+				UserID:        drift.UserID,
+				Operation:     entities.AuditOperationRepair,
+				Change:        drift.Drift.Neg(),
+				Currency:      drift.Currency,
+				BalanceBefore: drift.StoredBalance,
+				BalanceAfter:  drift.RecomputedBalance,
+				Actor:         actor,
+				CreatedAt:     time.Now(),
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		drift.Repaired = true
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to repair balance: %w", err)
+	}
+
+	return nil
+}
+
+func newBalanceDrift(balance repositories.BalanceRecomputation) *entities.BalanceDrift {
+	return &entities.BalanceDrift{
+		UserID:            balance.UserID,
+		Currency:          balance.Currency,
+		StoredBalance:     balance.Stored,
+		RecomputedBalance: *balance.Recomputed,
+		Drift:             balance.Stored.Sub(*balance.Recomputed),
+	}
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBalanceCheckService(t *testing.T) {
+	ctx := context.Background()
+	userRepo := newFakeUserRepo(
+		&entities.User{ID: 1, Balance: decimal.NewFromInt(100)},
+		&entities.User{ID: 2, Balance: decimal.NewFromInt(50)},
+		&entities.User{ID: 3, Balance: decimal.NewFromInt(20)},
+		&entities.User{ID: 4, Balance: decimal.NewFromInt(10)},
+	)
+	walletRepo := newFakeWalletRepo(
+		&entities.Wallet{UserID: 2, Currency: "EUR", Balance: decimal.NewFromInt(5)},
+		&entities.Wallet{UserID: 3, Currency: "USD", Balance: decimal.Zero},
+	)
+	transactionRepo := newFakeTransactionRepo()
+	transactionService := NewTransactionService(&fakeUnitOfWork{}, userRepo, transactionRepo)
+
+	// User 1 wins 10 and loses 5, then the win is cancelled; user 2 loses 20
+	for _, r := range []struct {
+		userID uint64
+		id     string
+		state  string
+		amount string
+	}{
+		{1, "tx-1", "win", "10"}, {1, "tx-2", "lose", "5"}, {2, "tx-3", "lose", "20"},
+	} {
+		_, err := transactionService.ProcessTransaction(ctx, r.userID, entities.TransactionRequest{
+			State: r.state, Amount: r.amount, TransactionID: r.id,
+		}, entities.SourceTypeGame)
+		require.NoError(t, err)
+	}
+	result, err := NewCancellationService(&fakeUnitOfWork{}, userRepo, walletRepo, transactionRepo).
+		CancelLatestOddTransactions(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"tx-3"}, result.Cancelled)
+
+	// The wallets start empty; user 4's only transaction predates balance after
+	for _, transaction := range []*entities.Transaction{
+		{UserID: 2, TransactionID: "tx-4", State: entities.StateWin, Amount: decimal.NewFromInt(5), Currency: "EUR"},
+		{UserID: 3, TransactionID: "tx-5", State: entities.StateLose, Amount: decimal.NewFromInt(5), Currency: "USD"},
+		{UserID: 4, TransactionID: "tx-6", State: entities.StateWin, Amount: decimal.NewFromInt(10)},
+	} {
+		transaction.CreatedAt = time.Now()
+		require.NoError(t, transactionRepo.Create(ctx, transaction))
+	}
+
+	// Drift user 1 and the EUR wallet of user 2
+	userRepo.users[1].Balance = decimal.NewFromInt(200)
+	walletRepo.wallets[walletKey(2, "EUR")].Balance = decimal.NewFromInt(7)
+
+	auditRepo := &fakeAuditLogRepo{}
+	service := NewBalanceCheckService(&fakeUnitOfWork{}, userRepo, walletRepo,
+		&fakeBalanceCheckRepo{users: userRepo, wallets: walletRepo, transactions: transactionRepo},
+		WithBalanceCheckAuditRecorder(NewAuditService(auditRepo)))
+	service.batchSize = 3
+
+	drifts := func(report *entities.BalanceCheckReport) map[string]string {
+		found := map[string]string{}
+		for _, drift := range report.Drifts {
+			key := walletKey(drift.UserID, drift.Currency)
+			found[key] = drift.StoredBalance.String() + " -> " + drift.RecomputedBalance.String()
+			if drift.Repaired {
+				found[key] += " repaired"
+			}
+		}
+		return found
+	}
+
+	t.Run("drifted balances are reported", func(t *testing.T) {
+		report, err := service.CheckBalances(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 6, report.Checked)
+		assert.Equal(t, 1, report.Unverifiable)
+		assert.Equal(t, 0, report.Repaired)
+		assert.Equal(t, map[string]string{
+			"1/":    "200 -> 105",
+			"2/EUR": "7 -> 5",
+			"3/USD": "0 -> -5",
+		}, drifts(report))
+		assert.Equal(t, "200", userRepo.users[1].Balance.String())
+		assert.Empty(t, auditRepo.entries)
+	})
+
+	t.Run("repairs set the recomputed balance and are audited", func(t *testing.T) {
+		report, err := service.RepairBalances(ctx, "ops@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, 2, report.Repaired)
+		assert.Equal(t, map[string]string{
+			"1/":    "200 -> 105 repaired",
+			"2/EUR": "7 -> 5 repaired",
+			"3/USD": "0 -> -5",
+		}, drifts(report))
+		assert.Equal(t, "105", userRepo.users[1].Balance.String())
+		assert.Equal(t, "5", walletRepo.wallets[walletKey(2, "EUR")].Balance.String())
+
+		require.Len(t, auditRepo.entries, 2)
+		entry := auditRepo.entries[0]
+		assert.Equal(t, entities.AuditOperationRepair, entry.Operation)
+		assert.Equal(t, "-95", entry.Change.String())
+		assert.Equal(t, "200", entry.BalanceBefore.String())
+		assert.Equal(t, "105", entry.BalanceAfter.String())
+		assert.Equal(t, "ops@example.com", entry.Actor)
+
+		report, err = service.CheckBalances(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"3/USD": "0 -> -5"}, drifts(report))
+	})
+}
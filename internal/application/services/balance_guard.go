@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/shopspring/decimal"
+)
+
+// GuardAction determines what happens when the balance guard trips
+type GuardAction string
+
+const (
+	// GuardActionFlag lets the transaction through and only emits an alert
+	GuardActionFlag GuardAction = "flag"
+	// GuardActionBlock rejects the transaction and emits an alert
+	GuardActionBlock GuardAction = "block"
+)
+
+// IsValid checks if the guard action is valid
+func (a GuardAction) IsValid() bool {
+	return a == GuardActionFlag || a == GuardActionBlock
+}
+
+// BalanceGuardPolicy configures the rate-of-change guard. A zero limit disables
+// that particular check.
+type BalanceGuardPolicy struct {
+	Window           time.Duration
+	MaxChange        decimal.Decimal
+	MaxChangePercent decimal.Decimal
+	Action           GuardAction
+	FreezeOnTrip     bool
+}
+
+// BalanceAlert describes a tripped balance guard
+type BalanceAlert struct {
+	UserID        uint64
+	TransactionID string
+	StartBalance  decimal.Decimal
+	Change        decimal.Decimal
+	Window        time.Duration
+	Action        GuardAction
+	Frozen        bool
+	Reason        string
+}
+
+// AlertNotifier delivers balance alerts to operators
+type AlertNotifier interface {
+	NotifyBalanceAlert(ctx context.Context, alert BalanceAlert) error
+}
+
+// BalanceGuard flags or blocks unusually fast balance movements for a user
+type BalanceGuard struct {
+	policy          BalanceGuardPolicy
+	userRepo        repositories.UserRepository
+	transactionRepo repositories.TransactionRepository
+	notifier        AlertNotifier
+}
+
+// NewBalanceGuard creates a new BalanceGuard
+func NewBalanceGuard(
+	policy BalanceGuardPolicy,
+	userRepo repositories.UserRepository,
+	transactionRepo repositories.TransactionRepository,
+	notifier AlertNotifier,
+) *BalanceGuard {
+	return &BalanceGuard{
+		policy:          policy,
+		userRepo:        userRepo,
+		transactionRepo: transactionRepo,
+		notifier:        notifier,
+	}
+}
+
+// Evaluate checks a prospective balance change for the user and returns an
+// alert when a limit is exceeded. It has no side effects; call Enforce once
+// the outcome of the transaction is known.
+func (g *BalanceGuard) Evaluate(
+	ctx context.Context,
+	user *entities.User,
+	transactionID string,
+	delta decimal.Decimal,
+) (*BalanceAlert, error) {
+	netChange, err := g.transactionRepo.NetChangeSince(ctx, user.ID, time.Now().Add(-g.policy.Window))
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate balance guard: %w", err)
+	}
+
+	// Balance at the start of the window, before any of the counted changes
+	startBalance := user.Balance.Sub(netChange)
+	change := netChange.Add(delta)
+
+	reason := g.exceeded(startBalance, change)
+	if reason == "" {
+		return nil, nil
+	}
+
+	return &BalanceAlert{
+		UserID:        user.ID,
+		TransactionID: transactionID,
+		StartBalance:  startBalance,
+		Change:        change,
+		Window:        g.policy.Window,
+		Action:        g.policy.Action,
+		Frozen:        g.policy.FreezeOnTrip,
+		Reason:        reason,
+	}, nil
+}
+
+// Blocks reports whether a tripped guard rejects the transaction
+func (g *BalanceGuard) Blocks() bool {
+	return g.policy.Action == GuardActionBlock
+}
+
+// Enforce freezes the account if configured and notifies operators. It must
+// run outside the transaction being guarded so a blocked transaction's
+// rollback does not undo the freeze.
+func (g *BalanceGuard) Enforce(ctx context.Context, alert *BalanceAlert) error {
+	if alert.Frozen {
+		if err := g.userRepo.UpdateStatus(ctx, alert.UserID, entities.UserStatusFrozen); err != nil {
+			return fmt.Errorf("failed to freeze user: %w", err)
+		}
+	}
+
+	if err := g.notifier.NotifyBalanceAlert(ctx, *alert); err != nil {
+		return fmt.Errorf("failed to send balance alert: %w", err)
+	}
+
+	return nil
+}
+
+// exceeded returns a description of the violated limit, or an empty string
+func (g *BalanceGuard) exceeded(startBalance, change decimal.Decimal) string {
+	absChange := change.Abs()
+
+	if g.policy.MaxChange.IsPositive() && absChange.GreaterThan(g.policy.MaxChange) {
+		return fmt.Sprintf("balance changed by %s within %s, limit is %s",
+			change.StringFixed(2), g.policy.Window, g.policy.MaxChange.StringFixed(2))
+	}
+
+	if g.policy.MaxChangePercent.IsPositive() && startBalance.IsPositive() {
+		percent := absChange.Div(startBalance).Mul(decimal.NewFromInt(100))
+		if percent.GreaterThan(g.policy.MaxChangePercent) {
+			return fmt.Sprintf("balance changed by %s%% within %s, limit is %s%%",
+				percent.StringFixed(2), g.policy.Window, g.policy.MaxChangePercent.String())
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBalanceGuard(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     BalanceGuardPolicy
+		requests   []entities.TransactionRequest
+		wantErr    error
+		wantAlerts int
+		wantStatus entities.UserStatus
+	}{
+		{
+			name: "changes within the absolute limit pass",
+			policy: BalanceGuardPolicy{
+				Window:    time.Hour,
+				MaxChange: decimal.NewFromInt(50),
+				Action:    GuardActionBlock,
+			},
+			requests: []entities.TransactionRequest{
+				{State: "win", Amount: "20.00", TransactionID: "tx-1"},
+				{State: "win", Amount: "30.00", TransactionID: "tx-2"},
+			},
+			wantStatus: entities.UserStatusActive,
+		},
+		{
+			name: "cumulative change over the absolute limit is blocked",
+			policy: BalanceGuardPolicy{
+				Window:    time.Hour,
+				MaxChange: decimal.NewFromInt(50),
+				Action:    GuardActionBlock,
+			},
+			requests: []entities.TransactionRequest{
+				{State: "win", Amount: "40.00", TransactionID: "tx-1"},
+				{State: "win", Amount: "20.00", TransactionID: "tx-2"},
+			},
+			wantErr:    ErrBalanceChangeLimitExceeded,
+			wantAlerts: 1,
+			wantStatus: entities.UserStatusActive,
+		},
+		{
+			name: "losses count towards the limit",
+			policy: BalanceGuardPolicy{
+				Window:           time.Hour,
+				MaxChangePercent: decimal.NewFromInt(50),
+				Action:           GuardActionBlock,
+			},
+			requests: []entities.TransactionRequest{
+				{State: "lose", Amount: "30.00", TransactionID: "tx-1"},
+				{State: "lose", Amount: "30.00", TransactionID: "tx-2"},
+			},
+			wantErr:    ErrBalanceChangeLimitExceeded,
+			wantAlerts: 1,
+			wantStatus: entities.UserStatusActive,
+		},
+		{
+			name: "flag mode lets the transaction through",
+			policy: BalanceGuardPolicy{
+				Window:    time.Hour,
+				MaxChange: decimal.NewFromInt(10),
+				Action:    GuardActionFlag,
+			},
+			requests: []entities.TransactionRequest{
+				{State: "win", Amount: "20.00", TransactionID: "tx-1"},
+			},
+			wantAlerts: 1,
+			wantStatus: entities.UserStatusActive,
+		},
+		{
+			name: "tripping the guard freezes the account",
+			policy: BalanceGuardPolicy{
+				Window:       time.Hour,
+				MaxChange:    decimal.NewFromInt(10),
+				Action:       GuardActionFlag,
+				FreezeOnTrip: true,
+			},
+			requests: []entities.TransactionRequest{
+				{State: "win", Amount: "20.00", TransactionID: "tx-1"},
+				{State: "win", Amount: "1.00", TransactionID: "tx-2"},
+			},
+			wantErr:    ErrAccountFrozen,
+			wantAlerts: 1,
+			wantStatus: entities.UserStatusFrozen,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(100)})
+			transactionRepo := newFakeTransactionRepo()
+			notifier := &recordingNotifier{}
+
+			guard := NewBalanceGuard(tt.policy, userRepo, transactionRepo, notifier)
+			service := NewTransactionService(&fakeUnitOfWork{}, userRepo, transactionRepo, WithBalanceGuard(guard))
+
+			var err error
+			for _, req := range tt.requests {
+				if _, err = service.ProcessTransaction(ctx, 1, req, entities.SourceTypeGame); err != nil {
+					break
+				}
+			}
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Len(t, notifier.alerts, tt.wantAlerts)
+
+			user, err := userRepo.GetByID(ctx, 1)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, user.Status)
+		})
+	}
+}
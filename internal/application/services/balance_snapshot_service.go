@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+)
+
+var (
+	ErrInvalidGranularity         = errors.New("invalid granularity")
+	ErrInvalidBalanceHistoryRange = errors.New("invalid balance history range")
+)
+
+const (
+	// GranularityDay lists one closing balance per UTC day
+	GranularityDay = "day"
+
+	// DefaultBalanceHistoryRange is the range balance histories cover when
+	// from is omitted
+	DefaultBalanceHistoryRange = 30 * 24 * time.Hour
+	// MaxBalanceHistoryRange is the longest range of a balance history
+	MaxBalanceHistoryRange = 366 * 24 * time.Hour
+)
+
+// BalanceSnapshotService records the base currency balance every user closes
+// each UTC day with, so that balances can be charted over time without
+// replaying the transaction history
+type BalanceSnapshotService struct {
+	uow          repositories.UnitOfWork
+	userRepo     repositories.UserRepository
+	snapshotRepo repositories.BalanceSnapshotRepository
+	transactions *TransactionService
+}
+
+// NewBalanceSnapshotService creates a new BalanceSnapshotService. Days close
+// by the clock of transactions.
+func NewBalanceSnapshotService(
+	uow repositories.UnitOfWork,
+	userRepo repositories.UserRepository,
+	snapshotRepo repositories.BalanceSnapshotRepository,
+	transactions *TransactionService,
+) *BalanceSnapshotService {
+	return &BalanceSnapshotService{
+		uow:          uow,
+		userRepo:     userRepo,
+		snapshotRepo: snapshotRepo,
+		transactions: transactions,
+	}
+}
+
+// SnapshotPreviousDay snapshots the closing balance of the last UTC day that
+// ended for up to limit users not snapshotted yet, returning their IDs. A
+// closing balance is the current balance less the balance changes since
+// midnight, so snapshots taken late in the following day are as exact as
+// those taken right after midnight.
+func (s *BalanceSnapshotService) SnapshotPreviousDay(ctx context.Context, limit int) ([]uint64, error) {
+	now := s.transactions.now()
+	end := utcDay(now)
+	day := end.AddDate(0, 0, -1)
+
+	userIDs, err := s.snapshotRepo.ListUnsnapshotted(ctx, day, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unsnapshotted users: %w", err)
+	}
+
+	snapshotted := make([]uint64, 0, len(userIDs))
+	for _, userID := range userIDs {
+		if err := s.snapshot(ctx, userID, day, end, now); err != nil {
+			return snapshotted, fmt.Errorf("failed to snapshot user %d: %w", userID, err)
+		}
+		snapshotted = append(snapshotted, userID)
+	}
+	return snapshotted, nil
+}
+
+// snapshot records the user's balance at end as the closing balance of day.
+// The user is locked so that no transaction lands between reading the
+// balance and totalling the transactions since end.
+func (s *BalanceSnapshotService) snapshot(ctx context.Context, userID uint64, day, end, now time.Time) error {
+	return s.uow.WithinTransaction(ctx, func(ctx context.Context) error {
+		user, err := s.userRepo.GetByIDForUpdate(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+		netChange, err := s.transactions.transactionRepo.NetChangeSince(ctx, userID, end)
+		if err != nil {
+			return fmt.Errorf("failed to sum balance changes: %w", err)
+		}
+
+		return s.snapshotRepo.Create(ctx, &entities.BalanceSnapshot{
+			UserID:    userID,
+			Day:       day,
+			Balance:   user.Balance.Sub(netChange),
+			CreatedAt: now,
+		})
+	})
+}
+
+// GetBalanceHistory lists the user's closing balances at the granularity,
+// only GranularityDay so far, from the UTC day of from up to the UTC day of
+// to, which is left out. to defaults to now and from to
+// DefaultBalanceHistoryRange before to.
+func (s *BalanceSnapshotService) GetBalanceHistory(
+	ctx context.Context,
+	userID uint64,
+	from, to *time.Time,
+	granularity string,
+) (*entities.BalanceHistory, error) {
+	if granularity == "" {
+		granularity = GranularityDay
+	}
+	if granularity != GranularityDay {
+		return nil, ErrInvalidGranularity
+	}
+	end := s.transactions.now()
+	if to != nil {
+		end = *to
+	}
+	start := end.Add(-DefaultBalanceHistoryRange)
+	if from != nil {
+		start = *from
+	}
+	if !start.Before(end) || end.Sub(start) > MaxBalanceHistoryRange {
+		return nil, fmt.Errorf("%w: from must be before to, at most %s apart", ErrInvalidBalanceHistoryRange, MaxBalanceHistoryRange)
+	}
+
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	snapshots, err := s.snapshotRepo.ListByUser(ctx, userID, utcDay(start), utcDay(end))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list balance snapshots: %w", err)
+	}
+
+	history := &entities.BalanceHistory{
+		UserID:      userID,
+		Currency:    s.transactions.currencyCode(""),
+		Granularity: granularity,
+		From:        start.UTC(),
+		To:          end.UTC(),
+		Balances:    make([]entities.ClosingBalance, 0, len(snapshots)),
+	}
+	for _, snapshot := range snapshots {
+		history.Balances = append(history.Balances, entities.ClosingBalance{
+			Day:     snapshot.Day.UTC().Format(time.DateOnly),
+			Balance: snapshot.Balance.StringFixed(2),
+		})
+	}
+	return history, nil
+}
+
+// utcDay returns midnight UTC of the day t falls on in UTC
+func utcDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type snapshotFixture struct {
+	*ledgerFixture
+	service      *BalanceSnapshotService
+	snapshotRepo *fakeSnapshotRepo
+}
+
+func newSnapshotFixture(opts ...TransactionServiceOption) *snapshotFixture {
+	f := &snapshotFixture{
+		ledgerFixture: newLedgerFixture(time.Date(2025, 3, 1, 18, 0, 0, 0, time.UTC), []*entities.User{
+			{ID: 1, Balance: decimal.NewFromInt(100)},
+			{ID: 2, Balance: decimal.NewFromInt(50)},
+		}, opts...),
+		snapshotRepo: &fakeSnapshotRepo{userIDs: []uint64{1, 2}},
+	}
+	f.service = NewBalanceSnapshotService(f.uow, f.userRepo, f.snapshotRepo, f.transactions)
+	return f
+}
+
+func (f *snapshotFixture) process(t *testing.T, transactionID, state, amount string) {
+	t.Helper()
+	_, err := f.transactions.ProcessTransaction(context.Background(), 1, entities.TransactionRequest{
+		TransactionID: transactionID, State: state, Amount: amount,
+	}, entities.SourceTypeGame)
+	require.NoError(t, err)
+}
+
+func TestBalanceSnapshotService_SnapshotPreviousDay(t *testing.T) {
+	ctx := context.Background()
+	f := newSnapshotFixture()
+	f.process(t, "tx-1", "win", "20.00")
+
+	// Transactions after midnight are taken back out of the closing balance
+	f.now = time.Date(2025, 3, 2, 9, 0, 0, 0, time.UTC)
+	f.process(t, "tx-2", "lose", "5.00")
+
+	snapshotted, err := f.service.SnapshotPreviousDay(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{1}, snapshotted)
+	snapshotted, err = f.service.SnapshotPreviousDay(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{2}, snapshotted)
+	snapshotted, err = f.service.SnapshotPreviousDay(ctx, 10)
+	require.NoError(t, err)
+	assert.Empty(t, snapshotted, "every user is snapshotted once a day")
+
+	require.Len(t, f.snapshotRepo.snapshots, 2)
+	snapshot := f.snapshotRepo.snapshots[0]
+	assert.Equal(t, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), snapshot.Day)
+	assert.Equal(t, "120.00", snapshot.Balance.StringFixed(2))
+	assert.Equal(t, f.now, snapshot.CreatedAt)
+	assert.Equal(t, "50.00", f.snapshotRepo.snapshots[1].Balance.StringFixed(2))
+}
+
+func TestBalanceSnapshotService_SnapshotPreviousDaySettledLate(t *testing.T) {
+	ctx := context.Background()
+	f := newSnapshotFixture(WithSettlement(time.Hour))
+	f.now = time.Date(2025, 3, 1, 23, 30, 0, 0, time.UTC)
+	_, err := f.transactions.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+		TransactionID: "tx-1", State: "win", Amount: "10.00",
+	}, entities.SourceTypePayment)
+	require.NoError(t, err)
+
+	// The credit recorded before midnight only moved the balance once
+	// settled after it
+	f.now = time.Date(2025, 3, 2, 0, 30, 0, 0, time.UTC)
+	_, err = f.transactions.SettleTransaction(ctx, "tx-1")
+	require.NoError(t, err)
+
+	f.now = time.Date(2025, 3, 2, 9, 0, 0, 0, time.UTC)
+	_, err = f.service.SnapshotPreviousDay(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, f.snapshotRepo.snapshots, 1)
+	assert.Equal(t, "100.00", f.snapshotRepo.snapshots[0].Balance.StringFixed(2))
+
+	for at, want := range map[time.Time]string{
+		time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC): "100.00",
+		time.Date(2025, 3, 2, 1, 0, 0, 0, time.UTC): "110.00",
+	} {
+		balance, err := f.transactions.GetUserBalanceAt(ctx, 1, at)
+		require.NoError(t, err)
+		assert.Equal(t, want, balance.Balance, "at %s", at)
+	}
+}
+
+func TestBalanceSnapshotService_GetBalanceHistory(t *testing.T) {
+	ctx := context.Background()
+	f := newSnapshotFixture()
+	for day, balance := range []string{"100.00", "120.00", "115.50"} {
+		f.snapshotRepo.snapshots = append(f.snapshotRepo.snapshots, &entities.BalanceSnapshot{
+			UserID:  1,
+			Day:     time.Date(2025, 2, 26+day, 0, 0, 0, 0, time.UTC),
+			Balance: decimal.RequireFromString(balance),
+		})
+	}
+
+	t.Run("lists the closing balances oldest first", func(t *testing.T) {
+		history, err := f.service.GetBalanceHistory(ctx, 1, nil, nil, "")
+		require.NoError(t, err)
+		assert.Equal(t, GranularityDay, history.Granularity)
+		assert.Equal(t, f.now, history.To)
+		assert.Equal(t, []entities.ClosingBalance{
+			{Day: "2025-02-26", Balance: "100.00"},
+			{Day: "2025-02-27", Balance: "120.00"},
+			{Day: "2025-02-28", Balance: "115.50"},
+		}, history.Balances)
+	})
+
+	t.Run("the day of from is included and the day of to left out", func(t *testing.T) {
+		from := time.Date(2025, 2, 27, 12, 0, 0, 0, time.UTC)
+		to := time.Date(2025, 2, 28, 12, 0, 0, 0, time.UTC)
+		history, err := f.service.GetBalanceHistory(ctx, 1, &from, &to, GranularityDay)
+		require.NoError(t, err)
+		assert.Equal(t, []entities.ClosingBalance{{Day: "2025-02-27", Balance: "120.00"}}, history.Balances)
+	})
+
+	t.Run("invalid requests are rejected", func(t *testing.T) {
+		_, err := f.service.GetBalanceHistory(ctx, 1, nil, nil, "hour")
+		assert.ErrorIs(t, err, ErrInvalidGranularity)
+		from := f.now.Add(-367 * 24 * time.Hour)
+		_, err = f.service.GetBalanceHistory(ctx, 1, &from, nil, "")
+		assert.ErrorIs(t, err, ErrInvalidBalanceHistoryRange)
+		_, err = f.service.GetBalanceHistory(ctx, 99, nil, nil, "")
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+}
+
+func TestTransactionService_GetUserBalanceAt(t *testing.T) {
+	ctx := context.Background()
+	f := newSnapshotFixture()
+	f.process(t, "tx-1", "win", "20.00")
+	f.now = time.Date(2025, 3, 2, 9, 0, 0, 0, time.UTC)
+	f.process(t, "tx-2", "lose", "5.00")
+	f.now = time.Date(2025, 3, 2, 11, 0, 0, 0, time.UTC)
+	f.process(t, "tx-3", "win", "1.00")
+	f.now = time.Date(2025, 3, 3, 8, 0, 0, 0, time.UTC)
+
+	balanceAt := func(t *testing.T, transactions *TransactionService, at time.Time) string {
+		t.Helper()
+		balance, err := transactions.GetUserBalanceAt(ctx, 1, at)
+		require.NoError(t, err)
+		require.NotNil(t, balance.AsOf)
+		assert.Equal(t, at, *balance.AsOf)
+		return balance.Balance
+	}
+
+	t.Run("replayed back from the current balance", func(t *testing.T) {
+		assert.Equal(t, "100.00", balanceAt(t, f.transactions, time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)))
+		assert.Equal(t, "120.00", balanceAt(t, f.transactions, time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC)))
+		assert.Equal(t, "115.00", balanceAt(t, f.transactions, time.Date(2025, 3, 2, 10, 0, 0, 0, time.UTC)))
+		assert.Equal(t, "116.00", balanceAt(t, f.transactions, f.now))
+	})
+
+	t.Run("replayed forward from the closing balance", func(t *testing.T) {
+		// The snapshot differs from the transactions, e.g. because one was
+		// cancelled since, and takes precedence
+		f.snapshotRepo.snapshots = append(f.snapshotRepo.snapshots, &entities.BalanceSnapshot{
+			UserID: 1, Day: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), Balance: decimal.NewFromInt(130),
+		})
+		transactions := NewTransactionService(&fakeUnitOfWork{}, f.userRepo, f.transactionRepo,
+			WithClock(func() time.Time { return f.now }), WithBalanceSnapshots(f.snapshotRepo))
+
+		assert.Equal(t, "100.00", balanceAt(t, transactions, time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)), "no snapshot before")
+		assert.Equal(t, "130.00", balanceAt(t, transactions, time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC)))
+		assert.Equal(t, "125.00", balanceAt(t, transactions, time.Date(2025, 3, 2, 10, 0, 0, 0, time.UTC)))
+		assert.Equal(t, "126.00", balanceAt(t, transactions, f.now))
+	})
+
+	t.Run("read from one snapshot without locking the user", func(t *testing.T) {
+		uow := &isolationRecordingUnitOfWork{}
+		transactions := NewTransactionService(uow, f.userRepo, f.transactionRepo,
+			WithClock(func() time.Time { return f.now }))
+
+		assert.Equal(t, "115.00", balanceAt(t, transactions, time.Date(2025, 3, 2, 10, 0, 0, 0, time.UTC)))
+		assert.Equal(t, []bool{true}, uow.snapshotRead)
+		assert.Equal(t, []bool{false}, uow.serializable)
+	})
+
+	t.Run("future times and unknown users are rejected", func(t *testing.T) {
+		_, err := f.transactions.GetUserBalanceAt(ctx, 1, f.now.Add(time.Minute))
+		assert.ErrorIs(t, err, ErrInvalidBalanceTime)
+		_, err = f.transactions.GetUserBalanceAt(ctx, 99, f.now)
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+}
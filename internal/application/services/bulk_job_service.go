@@ -0,0 +1,502 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+	"transaction-service/internal/export"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrInvalidBulkJob   = errors.New("invalid bulk job")
+	ErrBulkJobNotFound  = errors.New("bulk job not found")
+	ErrBulkJobQueueFull = errors.New("too many bulk jobs are queued")
+	ErrWebhooksDisabled = errors.New("webhooks are not enabled")
+	ErrExportExists     = errors.New("export already exists")
+)
+
+const (
+	// MaxBulkJobItems is the largest number of users a bulk job accepts
+	MaxBulkJobItems = 10000
+	// MaxAdjustmentIDLength leaves room for the user ID in the transaction ID
+	// of an adjustment
+	MaxAdjustmentIDLength = 200
+
+	// maxBulkJobErrors bounds the failed items reported by a job
+	maxBulkJobErrors = 100
+	// bulkJobQueueSize bounds the jobs waiting to run
+	bulkJobQueueSize = 16
+	// bulkJobRetention is the number of finished jobs kept for reporting
+	bulkJobRetention = 100
+	// redeliveryBatchSize is the number of deliveries requeued at once
+	redeliveryBatchSize = 500
+)
+
+// BulkJobService runs admin operations on many users or deliveries as
+// background jobs, one at a time, and reports their progress. Jobs live in
+// memory: they are reported only by the instance running them and are lost
+// on restart. Every operation is safe to submit again, so a lost or
+// cancelled job is resumed by resubmitting it.
+type BulkJobService struct {
+	accounts     *AccountService
+	transactions *TransactionService
+	// webhooks may be nil when webhooks are not enabled
+	webhooks *WebhookService
+	// exportDir is where export jobs write; empty refuses them
+	exportDir string
+	// annotations, when set, are exported next to the transactions
+	annotations *AnnotationService
+
+	mu sync.Mutex
+	// jobs holds every retained job; order lists their IDs oldest first
+	jobs  map[string]*bulkJob
+	order []string
+	queue chan *bulkJob
+	now   func() time.Time
+}
+
+// bulkJob is a job with the function that processes its items. run reports
+// the outcome of every item it processes.
+type bulkJob struct {
+	entities.BulkJob
+	run func(ctx context.Context, report func(item string, err error))
+}
+
+// BulkJobServiceOption configures optional BulkJobService behaviour
+type BulkJobServiceOption func(*BulkJobService)
+
+// WithExportDir lets export jobs write their files and manifests into dir
+func WithExportDir(dir string) BulkJobServiceOption {
+	return func(s *BulkJobService) {
+		s.exportDir = dir
+	}
+}
+
+// WithAnnotationExport makes export jobs write the support notes on each
+// user and the user's transactions as well
+func WithAnnotationExport(annotations *AnnotationService) BulkJobServiceOption {
+	return func(s *BulkJobService) {
+		s.annotations = annotations
+	}
+}
+
+// NewBulkJobService creates a new BulkJobService. webhooks may be nil, in
+// which case redelivery jobs are refused.
+func NewBulkJobService(
+	accounts *AccountService,
+	transactions *TransactionService,
+	webhooks *WebhookService,
+	opts ...BulkJobServiceOption,
+) *BulkJobService {
+	s := &BulkJobService{
+		accounts:     accounts,
+		transactions: transactions,
+		webhooks:     webhooks,
+		jobs:         make(map[string]*bulkJob),
+		queue:        make(chan *bulkJob, bulkJobQueueSize),
+		now:          time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// FreezeUsers queues a job freezing the users
+func (s *BulkJobService) FreezeUsers(ctx context.Context, req entities.BulkFreezeRequest) (*entities.BulkJob, error) {
+	userIDs, err := uniqueUserIDs(req.UserIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.submit(entities.BulkJobFreezeUsers, len(userIDs), func(ctx context.Context, report func(string, error)) {
+		for _, userID := range userIDs {
+			if ctx.Err() != nil {
+				return
+			}
+			report(strconv.FormatUint(userID, 10), s.accounts.SetStatus(ctx, userID, entities.UserStatusFrozen))
+		}
+	})
+}
+
+// AdjustBalances queues a job applying the same adjustment to every user as
+// a server transaction, or as a transfer with the counterparty when there
+// are system accounts. Users whose adjustment was already applied are
+// counted as succeeded without being adjusted again.
+func (s *BulkJobService) AdjustBalances(ctx context.Context, req entities.BulkAdjustmentRequest) (*entities.BulkJob, error) {
+	userIDs, err := uniqueUserIDs(req.UserIDs)
+	if err != nil {
+		return nil, err
+	}
+	if req.AdjustmentID == "" || len(req.AdjustmentID) > MaxAdjustmentIDLength {
+		return nil, fmt.Errorf("%w: adjustmentId must be 1 to %d characters", ErrInvalidBulkJob, MaxAdjustmentIDLength)
+	}
+	if !entities.TransactionState(req.State).IsValid() {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidBulkJob, ErrInvalidTransactionState)
+	}
+	if amount, err := decimal.NewFromString(req.Amount); err != nil || !amount.IsPositive() {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidBulkJob, ErrInvalidAmount)
+	}
+	if _, err := s.transactions.walletCurrency(req.Currency); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidBulkJob, err)
+	}
+
+	// With system accounts, adjustments are transfers with a counterparty
+	counterpartyID, err := s.counterparty(req.Counterparty)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.submit(entities.BulkJobAdjustBalances, len(userIDs), func(ctx context.Context, report func(string, error)) {
+		for _, userID := range userIDs {
+			if ctx.Err() != nil {
+				return
+			}
+			if counterpartyID != 0 {
+				transfer := entities.TransferRequest{
+					TransferID: req.AdjustmentID + ":" + strconv.FormatUint(userID, 10),
+					FromUserID: counterpartyID,
+					ToUserID:   userID,
+					Amount:     req.Amount,
+					Currency:   req.Currency,
+				}
+				if entities.TransactionState(req.State) == entities.StateLose {
+					transfer.FromUserID, transfer.ToUserID = userID, counterpartyID
+				}
+				_, err := s.transactions.transfer(ctx, transfer)
+				report(strconv.FormatUint(userID, 10), err)
+				continue
+			}
+			_, err := s.transactions.ProcessTransaction(ctx, userID, entities.TransactionRequest{
+				State:         req.State,
+				Amount:        req.Amount,
+				TransactionID: req.AdjustmentID + ":" + strconv.FormatUint(userID, 10),
+				Currency:      req.Currency,
+			}, entities.SourceTypeServer)
+			report(strconv.FormatUint(userID, 10), err)
+		}
+	})
+}
+
+// counterparty returns the user ID of the system account adjustments are
+// made against, the house unless requested otherwise, and zero without
+// system accounts
+func (s *BulkJobService) counterparty(kind entities.SystemAccountKind) (uint64, error) {
+	if len(s.transactions.systemAccounts) == 0 {
+		if kind != "" {
+			return 0, fmt.Errorf("%w: counterparty requires system accounts", ErrInvalidBulkJob)
+		}
+		return 0, nil
+	}
+	if kind == "" {
+		kind = entities.SystemAccountHouse
+	}
+	userID, ok := s.transactions.systemAccount(kind)
+	if !ok {
+		return 0, fmt.Errorf("%w: counterparty must be one of house, promo, escheat", ErrInvalidBulkJob)
+	}
+	return userID, nil
+}
+
+// RedeliverWebhooks queues a job delivering again the webhook deliveries
+// created in the requested range
+func (s *BulkJobService) RedeliverWebhooks(ctx context.Context, req entities.BulkRedeliveryRequest) (*entities.BulkJob, error) {
+	if s.webhooks == nil {
+		return nil, ErrWebhooksDisabled
+	}
+	if !req.From.Before(req.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidBulkJob)
+	}
+
+	ids, err := s.webhooks.DeliveriesCreatedBetween(ctx, req.WebhookID, req.From, req.To)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.submit(entities.BulkJobRedeliverWebhooks, len(ids), func(ctx context.Context, report func(string, error)) {
+		for batch := range slices.Chunk(ids, redeliveryBatchSize) {
+			if ctx.Err() != nil {
+				return
+			}
+			err := s.webhooks.Redeliver(ctx, batch)
+			for _, id := range batch {
+				report(strconv.FormatUint(id, 10), err)
+			}
+		}
+	})
+}
+
+// ExportTransactions queues a job exporting the history of every user, each
+// to its own CSV file, into the export directory under the requested name.
+// The files are written through the export package, which writes the
+// manifest once every user was exported; it is the job's last item. An
+// export with failed users gets no manifest, so it never verifies as
+// complete and is submitted again under another name.
+func (s *BulkJobService) ExportTransactions(ctx context.Context, req entities.BulkExportRequest) (*entities.BulkJob, error) {
+	if s.exportDir == "" {
+		return nil, fmt.Errorf("%w: exports are not enabled", ErrInvalidBulkJob)
+	}
+	userIDs, err := uniqueUserIDs(req.UserIDs)
+	if err != nil {
+		return nil, err
+	}
+	if req.From != nil && req.To != nil && req.From.After(*req.To) {
+		return nil, fmt.Errorf("%w: from must not be after to", ErrInvalidBulkJob)
+	}
+	exists, err := export.Exists(s.exportDir, req.Name)
+	if err != nil {
+		if errors.Is(err, export.ErrInvalidExportName) {
+			return nil, fmt.Errorf("%w: name must be a single path element not starting with '.'", ErrInvalidBulkJob)
+		}
+		return nil, err
+	}
+	if exists {
+		return nil, ErrExportExists
+	}
+
+	filter := repositories.TransactionFilter{From: req.From, To: req.To}
+	return s.submit(entities.BulkJobExportTransactions, len(userIDs)+1, func(ctx context.Context, report func(string, error)) {
+		writer, err := export.NewWriter(s.exportDir, req.Name)
+		if err != nil {
+			if errors.Is(err, export.ErrExportExists) {
+				err = ErrExportExists
+			}
+			for _, userID := range userIDs {
+				report(strconv.FormatUint(userID, 10), err)
+			}
+			report(export.ManifestFileName, err)
+			return
+		}
+
+		failed := 0
+		for _, userID := range userIDs {
+			if ctx.Err() != nil {
+				return
+			}
+			err := s.exportUser(ctx, writer, userID, filter)
+			if err != nil {
+				failed++
+			}
+			report(strconv.FormatUint(userID, 10), err)
+		}
+		if failed > 0 {
+			report(export.ManifestFileName, fmt.Errorf("not written, %d users failed to export", failed))
+			return
+		}
+		_, err = writer.Finish()
+		report(export.ManifestFileName, err)
+	})
+}
+
+// exportUser writes the user's history matching filter, and the notes on the
+// user when annotations are exported, to the user's files of the export. A
+// file that failed is left out of the export.
+func (s *BulkJobService) exportUser(ctx context.Context, writer *export.Writer, userID uint64, filter repositories.TransactionFilter) error {
+	file, err := writer.Create(fmt.Sprintf("user-%d-transactions.csv", userID))
+	if err != nil {
+		return err
+	}
+
+	rows := export.NewTransactionCSV(file)
+	err = rows.WriteHeader()
+	if err == nil {
+		err = s.transactions.ExportUserTransactions(ctx, userID, filter, rows.Write)
+	}
+	if err == nil {
+		err = rows.Flush()
+	}
+	if err != nil {
+		_ = file.Abort()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	if s.annotations == nil {
+		return nil
+	}
+	return s.exportAnnotations(ctx, writer, userID)
+}
+
+// exportAnnotations writes the notes on the user and the user's transactions
+// to the user's annotation file of the export. Notes are not filtered by the
+// range of the transactions, since a note on a transaction can be written
+// long after it.
+func (s *BulkJobService) exportAnnotations(ctx context.Context, writer *export.Writer, userID uint64) error {
+	annotations, err := s.annotations.ListUserAnnotations(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	file, err := writer.Create(fmt.Sprintf("user-%d-annotations.csv", userID))
+	if err != nil {
+		return err
+	}
+
+	records := [][]string{export.AnnotationColumns}
+	for _, annotation := range annotations {
+		records = append(records, export.AnnotationRow(annotation))
+	}
+	if err := csv.NewWriter(file).WriteAll(records); err != nil {
+		_ = file.Abort()
+		return err
+	}
+	return file.Close()
+}
+
+// uniqueUserIDs validates the user IDs of a job and drops duplicates
+func uniqueUserIDs(userIDs []uint64) ([]uint64, error) {
+	if len(userIDs) == 0 || len(userIDs) > MaxBulkJobItems {
+		return nil, fmt.Errorf("%w: userIds must list 1 to %d users", ErrInvalidBulkJob, MaxBulkJobItems)
+	}
+
+	seen := make(map[uint64]bool, len(userIDs))
+	unique := make([]uint64, 0, len(userIDs))
+	for _, userID := range userIDs {
+		if userID == 0 {
+			return nil, fmt.Errorf("%w: user IDs must be positive", ErrInvalidBulkJob)
+		}
+		if !seen[userID] {
+			seen[userID] = true
+			unique = append(unique, userID)
+		}
+	}
+	return unique, nil
+}
+
+// submit queues a job with total items
+func (s *BulkJobService) submit(
+	jobType entities.BulkJobType,
+	total int,
+	run func(ctx context.Context, report func(item string, err error)),
+) (*entities.BulkJob, error) {
+	job := &bulkJob{
+		BulkJob: entities.BulkJob{
+			ID:        uuid.NewString(),
+			Type:      jobType,
+			Status:    entities.BulkJobQueued,
+			Total:     total,
+			Errors:    []entities.BulkJobError{},
+			CreatedAt: s.now(),
+		},
+		run: run,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case s.queue <- job:
+	default:
+		return nil, ErrBulkJobQueueFull
+	}
+	s.jobs[job.ID] = job
+	s.order = append(s.order, job.ID)
+	s.prune()
+
+	return s.snapshot(job), nil
+}
+
+// prune forgets the oldest finished jobs beyond the retention
+func (s *BulkJobService) prune() {
+	excess := len(s.order) - bulkJobRetention
+	kept := s.order[:0]
+	for _, id := range s.order {
+		job := s.jobs[id]
+		if excess > 0 && job.FinishedAt != nil {
+			delete(s.jobs, id)
+			excess--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	s.order = kept
+}
+
+// GetJob returns the progress of a job
+func (s *BulkJobService) GetJob(ctx context.Context, id string) (*entities.BulkJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrBulkJobNotFound
+	}
+	return s.snapshot(job), nil
+}
+
+// ListJobs returns the progress of the retained jobs, newest first
+func (s *BulkJobService) ListJobs(ctx context.Context) []*entities.BulkJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := make([]*entities.BulkJob, 0, len(s.order))
+	for i := len(s.order) - 1; i >= 0; i-- {
+		jobs = append(jobs, s.snapshot(s.jobs[s.order[i]]))
+	}
+	return jobs
+}
+
+// snapshot copies the progress of a job; s.mu must be held
+func (s *BulkJobService) snapshot(job *bulkJob) *entities.BulkJob {
+	copied := job.BulkJob
+	copied.Errors = slices.Clone(job.Errors)
+	return &copied
+}
+
+// Run runs the queued jobs one at a time until the context is cancelled. A
+// job running when the context is cancelled stops after its current item.
+func (s *BulkJobService) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-s.queue:
+			s.runJob(ctx, job)
+		}
+	}
+}
+
+func (s *BulkJobService) runJob(ctx context.Context, job *bulkJob) {
+	s.mu.Lock()
+	startedAt := s.now()
+	job.Status = entities.BulkJobRunning
+	job.StartedAt = &startedAt
+	s.mu.Unlock()
+
+	job.run(ctx, func(item string, err error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		job.Processed++
+		if err == nil {
+			job.Succeeded++
+			return
+		}
+		job.Failed++
+		if len(job.Errors) < maxBulkJobErrors {
+			job.Errors = append(job.Errors, entities.BulkJobError{Item: item, Error: err.Error()})
+		}
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	finishedAt := s.now()
+	job.FinishedAt = &finishedAt
+	job.Status = entities.BulkJobCompleted
+	if job.Processed < job.Total {
+		job.Status = entities.BulkJobCancelled
+	}
+}
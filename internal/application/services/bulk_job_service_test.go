@@ -0,0 +1,250 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/export"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runQueued runs the next queued job to completion
+func runQueued(ctx context.Context, s *BulkJobService) {
+	s.runJob(ctx, <-s.queue)
+}
+
+func TestBulkJobService_FreezeUsers(t *testing.T) {
+	ctx := context.Background()
+	userRepo := newFakeUserRepo(&entities.User{ID: 1}, &entities.User{ID: 2})
+	service := NewBulkJobService(NewAccountService(userRepo), nil, nil)
+
+	job, err := service.FreezeUsers(ctx, entities.BulkFreezeRequest{UserIDs: []uint64{1, 2, 1, 3}})
+	require.NoError(t, err)
+	assert.Equal(t, entities.BulkJobQueued, job.Status)
+	assert.Equal(t, 3, job.Total, "duplicates are dropped")
+
+	runQueued(ctx, service)
+
+	job, err = service.GetJob(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entities.BulkJobCompleted, job.Status)
+	assert.Equal(t, 3, job.Processed)
+	assert.Equal(t, 2, job.Succeeded)
+	assert.Equal(t, 1, job.Failed)
+	assert.Equal(t, []entities.BulkJobError{{Item: "3", Error: ErrUserNotFound.Error()}}, job.Errors)
+	assert.NotNil(t, job.FinishedAt)
+	assert.Equal(t, entities.UserStatusFrozen, userRepo.users[1].Status)
+	assert.Equal(t, entities.UserStatusFrozen, userRepo.users[2].Status)
+}
+
+func TestBulkJobService_AdjustBalances(t *testing.T) {
+	ctx := context.Background()
+	userRepo := newFakeUserRepo(
+		&entities.User{ID: 1, Balance: decimal.NewFromInt(100)},
+		&entities.User{ID: 2, Balance: decimal.NewFromInt(5)},
+	)
+	transactionRepo := newFakeTransactionRepo()
+	transactions := NewTransactionService(&fakeUnitOfWork{}, userRepo, transactionRepo)
+	service := NewBulkJobService(NewAccountService(userRepo), transactions, nil)
+	req := entities.BulkAdjustmentRequest{
+		AdjustmentID: "incident-42",
+		UserIDs:      []uint64{1, 2},
+		State:        "lose",
+		Amount:       "10.00",
+	}
+
+	// Resubmitting the adjustment does not apply it twice
+	for range 2 {
+		job, err := service.AdjustBalances(ctx, req)
+		require.NoError(t, err)
+		runQueued(ctx, service)
+
+		job, err = service.GetJob(ctx, job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, job.Succeeded)
+		assert.Equal(t, 1, job.Failed)
+		assert.Equal(t, "2", job.Errors[0].Item)
+		assert.Contains(t, job.Errors[0].Error, ErrInsufficientFunds.Error())
+	}
+
+	assert.Equal(t, "90", userRepo.users[1].Balance.String())
+	transaction, err := transactionRepo.GetByTransactionID(ctx, "incident-42:1")
+	require.NoError(t, err)
+	assert.Equal(t, entities.SourceTypeServer, transaction.SourceType)
+}
+
+func TestBulkJobService_InvalidJobs(t *testing.T) {
+	ctx := context.Background()
+	transactions := NewTransactionService(&fakeUnitOfWork{}, newFakeUserRepo(), newFakeTransactionRepo())
+	service := NewBulkJobService(NewAccountService(newFakeUserRepo()), transactions, nil)
+	valid := entities.BulkAdjustmentRequest{AdjustmentID: "a", UserIDs: []uint64{1}, State: "win", Amount: "1"}
+
+	tests := []struct {
+		name   string
+		modify func(req *entities.BulkAdjustmentRequest)
+	}{
+		{name: "no users", modify: func(req *entities.BulkAdjustmentRequest) { req.UserIDs = nil }},
+		{name: "zero user ID", modify: func(req *entities.BulkAdjustmentRequest) { req.UserIDs = []uint64{1, 0} }},
+		{name: "too many users", modify: func(req *entities.BulkAdjustmentRequest) {
+			req.UserIDs = make([]uint64, MaxBulkJobItems+1)
+		}},
+		{name: "missing adjustment ID", modify: func(req *entities.BulkAdjustmentRequest) { req.AdjustmentID = "" }},
+		{name: "invalid state", modify: func(req *entities.BulkAdjustmentRequest) { req.State = "draw" }},
+		{name: "negative amount", modify: func(req *entities.BulkAdjustmentRequest) { req.Amount = "-1" }},
+		{name: "unsupported currency", modify: func(req *entities.BulkAdjustmentRequest) { req.Currency = "JPY" }},
+		{name: "counterparty without system accounts", modify: func(req *entities.BulkAdjustmentRequest) {
+			req.Counterparty = entities.SystemAccountHouse
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid
+			tt.modify(&req)
+			_, err := service.AdjustBalances(ctx, req)
+			assert.ErrorIs(t, err, ErrInvalidBulkJob)
+		})
+	}
+
+	_, err := service.RedeliverWebhooks(ctx, entities.BulkRedeliveryRequest{From: time.Now(), To: time.Now().Add(time.Hour)})
+	assert.ErrorIs(t, err, ErrWebhooksDisabled)
+	_, err = service.GetJob(ctx, "missing")
+	assert.ErrorIs(t, err, ErrBulkJobNotFound)
+}
+
+func TestBulkJobService_RedeliverWebhooks(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	repo := &fakeWebhookRepo{}
+	webhooks := NewWebhookService(repo, &scriptedSender{}, testWebhookPolicy)
+	webhooks.now = func() time.Time { return now }
+	webhook, err := webhooks.Register(ctx, entities.WebhookRequest{URL: "https://example.com/hook"})
+	require.NoError(t, err)
+	for _, offset := range []time.Duration{-2 * time.Hour, -30 * time.Minute, 0} {
+		now = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC).Add(offset)
+		require.NoError(t, webhooks.RecordCancellation(ctx, &entities.Transaction{UserID: 7}, decimal.Zero))
+	}
+	for _, delivery := range repo.deliveries {
+		delivery.Status = entities.WebhookDeliveryFailed
+		delivery.Attempts = 8
+	}
+
+	service := NewBulkJobService(nil, nil, webhooks)
+	from := time.Date(2024, 5, 1, 11, 0, 0, 0, time.UTC)
+	job, err := service.RedeliverWebhooks(ctx, entities.BulkRedeliveryRequest{
+		WebhookID: webhook.ID,
+		From:      from,
+		To:        from.Add(time.Hour),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, job.Total)
+	runQueued(ctx, service)
+
+	assert.Equal(t, entities.WebhookDeliveryFailed, repo.deliveries[0].Status)
+	assert.Equal(t, entities.WebhookDeliveryPending, repo.deliveries[1].Status)
+	assert.Equal(t, 0, repo.deliveries[1].Attempts)
+	assert.Equal(t, entities.WebhookDeliveryFailed, repo.deliveries[2].Status, "the range excludes its end")
+
+	_, err = service.RedeliverWebhooks(ctx, entities.BulkRedeliveryRequest{WebhookID: 9, From: from, To: from.Add(time.Hour)})
+	assert.ErrorIs(t, err, ErrWebhookNotFound)
+}
+
+func TestBulkJobService_ExportTransactions(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	userRepo := newFakeUserRepo(&entities.User{ID: 1}, &entities.User{ID: 2})
+	transactionRepo := newFakeTransactionRepo()
+	for _, id := range []string{"tx-1", "tx-2"} {
+		require.NoError(t, transactionRepo.Create(ctx, &entities.Transaction{
+			UserID: 1, TransactionID: id, State: entities.StateWin, Amount: decimal.NewFromInt(10),
+		}))
+	}
+	transactions := NewTransactionService(&fakeUnitOfWork{}, userRepo, transactionRepo)
+	annotations := NewAnnotationService(&fakeAnnotationRepo{transactions: transactionRepo}, userRepo, transactionRepo)
+	_, err := annotations.Annotate(ctx, entities.AnnotationTargetTransaction, "tx-1", entities.AnnotationRequest{
+		Author: "support", Note: "=chargeback",
+	})
+	require.NoError(t, err)
+	service := NewBulkJobService(NewAccountService(userRepo), transactions, nil,
+		WithExportDir(dir), WithAnnotationExport(annotations))
+
+	job, err := service.ExportTransactions(ctx, entities.BulkExportRequest{Name: "statements", UserIDs: []uint64{1, 2}})
+	require.NoError(t, err)
+	assert.Equal(t, 3, job.Total, "the manifest is the last item")
+	runQueued(ctx, service)
+
+	job, err = service.GetJob(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, job.Succeeded)
+	report, err := export.Verify(dir, "statements")
+	require.NoError(t, err)
+	assert.True(t, report.Valid)
+	require.Len(t, report.Files, 4)
+	assert.Equal(t, "user-1-transactions.csv", report.Files[0].Path)
+	assert.EqualValues(t, 3, report.Files[0].Expected.Rows, "header and two transactions")
+	assert.Equal(t, "user-1-annotations.csv", report.Files[1].Path)
+	assert.EqualValues(t, 2, report.Files[1].Expected.Rows, "header and the note")
+	assert.EqualValues(t, 1, report.Files[3].Expected.Rows, "user 2 has no notes")
+	notes, err := os.ReadFile(filepath.Join(dir, "statements", "user-1-annotations.csv"))
+	require.NoError(t, err)
+	assert.Contains(t, string(notes), "transaction,tx-1,support,'=chargeback")
+
+	_, err = service.ExportTransactions(ctx, entities.BulkExportRequest{Name: "statements", UserIDs: []uint64{1}})
+	assert.ErrorIs(t, err, ErrExportExists)
+	_, err = service.ExportTransactions(ctx, entities.BulkExportRequest{Name: "../statements", UserIDs: []uint64{1}})
+	assert.ErrorIs(t, err, ErrInvalidBulkJob)
+
+	// Without every user, no manifest is written, so the export does not verify
+	job, err = service.ExportTransactions(ctx, entities.BulkExportRequest{Name: "partial", UserIDs: []uint64{1, 3}})
+	require.NoError(t, err)
+	runQueued(ctx, service)
+
+	job, err = service.GetJob(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, job.Succeeded)
+	assert.Equal(t, 2, job.Failed)
+	assert.Equal(t, "3", job.Errors[0].Item)
+	assert.Equal(t, export.ManifestFileName, job.Errors[1].Item)
+	_, err = export.Verify(dir, "partial")
+	assert.Error(t, err)
+
+	_, err = NewBulkJobService(nil, transactions, nil).ExportTransactions(ctx, entities.BulkExportRequest{Name: "off", UserIDs: []uint64{1}})
+	assert.ErrorIs(t, err, ErrInvalidBulkJob, "exports are not enabled")
+}
+
+func TestBulkJobService_Queue(t *testing.T) {
+	ctx := context.Background()
+	service := NewBulkJobService(NewAccountService(newFakeUserRepo(&entities.User{ID: 1})), nil, nil)
+	req := entities.BulkFreezeRequest{UserIDs: []uint64{1}}
+
+	var first *entities.BulkJob
+	for i := range bulkJobQueueSize {
+		job, err := service.FreezeUsers(ctx, req)
+		require.NoError(t, err)
+		if i == 0 {
+			first = job
+		}
+	}
+	_, err := service.FreezeUsers(ctx, req)
+	assert.ErrorIs(t, err, ErrBulkJobQueueFull)
+
+	jobs := service.ListJobs(ctx)
+	require.Len(t, jobs, bulkJobQueueSize)
+	assert.Equal(t, first.ID, jobs[len(jobs)-1].ID, "newest first")
+
+	// Jobs interrupted by a shutdown are reported as cancelled
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	runQueued(cancelled, service)
+	job, err := service.GetJob(ctx, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entities.BulkJobCancelled, job.Status)
+	assert.Equal(t, 0, job.Processed)
+}
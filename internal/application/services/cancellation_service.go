@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/shopspring/decimal"
+)
+
+// CancellationResult summarizes a cancellation run by external transaction ID
+type CancellationResult struct {
+	Cancelled []string
+	// Skipped lists transactions whose reversal would make the balance negative
+	Skipped []string
+}
+
+// CancellationService cancels processed transactions and reverts their effect
+type CancellationService struct {
+	uow             repositories.UnitOfWork
+	userRepo        repositories.UserRepository
+	walletRepo      repositories.WalletRepository
+	transactionRepo repositories.TransactionRepository
+	// recorders are told about every cancellation, e.g. the outbox
+	recorders []CancellationRecorder
+	// audit records every cancellation in the audit log; disabled when nil
+	audit AuditRecorder
+}
+
+// CancellationRecorder records cancellations, e.g. as events. It runs in the
+// unit of work that reverts the transaction; returning an error rolls the
+// cancellation back.
+type CancellationRecorder interface {
+	// RecordCancellation records the cancellation of transaction, whose
+	// reversal left the balance at balance
+	RecordCancellation(ctx context.Context, transaction *entities.Transaction, balance decimal.Decimal) error
+}
+
+// CancellationServiceOption configures optional CancellationService behaviour
+type CancellationServiceOption func(*CancellationService)
+
+// WithCancellationRecorders registers recorders, run in registration order
+// for every cancellation
+func WithCancellationRecorders(recorders ...CancellationRecorder) CancellationServiceOption {
+	return func(s *CancellationService) {
+		s.recorders = append(s.recorders, recorders...)
+	}
+}
+
+// NewCancellationService creates a new CancellationService
+func NewCancellationService(
+	uow repositories.UnitOfWork,
+	userRepo repositories.UserRepository,
+	walletRepo repositories.WalletRepository,
+	transactionRepo repositories.TransactionRepository,
+	opts ...CancellationServiceOption,
+) *CancellationService {
+	s := &CancellationService{
+		uow:             uow,
+		userRepo:        userRepo,
+		walletRepo:      walletRepo,
+		transactionRepo: transactionRepo,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// CancelLatestOddTransactions cancels up to limit of the newest uncancelled
+// transactions with an odd ID and reverts their balance changes, in the
+// wallet of the transaction's currency where it has one. A
+// transaction is skipped if reverting it would make the balance negative.
+func (s *CancellationService) CancelLatestOddTransactions(ctx context.Context, limit int) (*CancellationResult, error) {
+	result := &CancellationResult{}
+
+	err := s.uow.WithinTransaction(ctx, func(ctx context.Context) error {
+		transactions, err := s.transactionRepo.LockLatestOddUncancelled(ctx, limit)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, transaction := range transactions {
+			// Lock the user before its wallet, in the same order as transactions
+			user, err := s.userRepo.GetByIDForUpdate(ctx, transaction.UserID)
+			if err != nil {
+				return fmt.Errorf("failed to get user %d: %w", transaction.UserID, err)
+			}
+			balance := user.Balance
+			if transaction.Currency != "" {
+				wallet, err := s.walletRepo.GetForUpdate(ctx, user.ID, transaction.Currency)
+				if err != nil {
+					return fmt.Errorf("failed to get %s wallet of user %d: %w", transaction.Currency, user.ID, err)
+				}
+				balance = wallet.Balance
+			}
+
+			revertedBalance := balance.Sub(transaction.SignedAmount())
+			if transaction.State == entities.StateWin && revertedBalance.Add(creditLimit(user, transaction.Currency)).IsNegative() {
+				result.Skipped = append(result.Skipped, transaction.TransactionID)
+				continue
+			}
+
+			if err := s.transactionRepo.MarkCancelled(ctx, transaction.ID, now); err != nil {
+				return err
+			}
+			if transaction.Currency != "" {
+				err = s.walletRepo.UpdateBalance(ctx, user.ID, transaction.Currency, revertedBalance)
+			} else {
+				err = s.userRepo.UpdateBalance(ctx, user.ID, revertedBalance)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to revert balance for user %d: %w", user.ID, err)
+			}
+
+			transaction.Cancelled = true
+			transaction.CancelledAt = &now
+			for _, recorder := range s.recorders {
+				if err := recorder.RecordCancellation(ctx, transaction, revertedBalance); err != nil {
+					return err
+				}
+			}
+			if s.audit != nil {
+				err := s.audit.RecordAudit(ctx, &entities.AuditEntry{
+					UserID:        user.ID,
+					Operation:     entities.AuditOperationCancellation,
+					TransactionID: transaction.TransactionID,
+					Change:        transaction.SignedAmount().Neg(),
+					Currency:      transaction.Currency,
+					BalanceBefore: balance,
+					BalanceAfter:  revertedBalance,
+					CreatedAt:     now,
+				})
+				if err != nil {
+					return err
+				}
+			}
+
+			result.Cancelled = append(result.Cancelled, transaction.TransactionID)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel transactions: %w", err)
+	}
+
+	return result, nil
+}
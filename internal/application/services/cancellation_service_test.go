@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"transaction-service/internal/domain/entities"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCancellationService_CancelLatestOddTransactions(t *testing.T) {
+	ctx := context.Background()
+	userRepo := newFakeUserRepo(
+		&entities.User{ID: 1, Balance: decimal.NewFromInt(100)},
+		&entities.User{ID: 2, Balance: decimal.NewFromInt(100)},
+	)
+	transactionRepo := newFakeTransactionRepo()
+	transactionService := NewTransactionService(&fakeUnitOfWork{}, userRepo, transactionRepo)
+
+	// IDs 1..4 for user 1: win 10, lose 5, win 20, lose 5 -> balance 120
+	// IDs 5..6 for user 2: win 150, lose 240 -> balance 10
+	requests := []struct {
+		userID uint64
+		state  string
+		amount string
+	}{
+		{1, "win", "10"}, {1, "lose", "5"}, {1, "win", "20"}, {1, "lose", "5"},
+		{2, "win", "150"}, {2, "lose", "240"},
+	}
+	for i, r := range requests {
+		_, err := transactionService.ProcessTransaction(ctx, r.userID, entities.TransactionRequest{
+			State: r.state, Amount: r.amount, TransactionID: fmt.Sprintf("tx-%d", i+1),
+		}, entities.SourceTypeGame)
+		require.NoError(t, err)
+	}
+
+	service := NewCancellationService(&fakeUnitOfWork{}, userRepo, newFakeWalletRepo(), transactionRepo)
+
+	result, err := service.CancelLatestOddTransactions(ctx, 10)
+	require.NoError(t, err)
+
+	// tx-5 (win 150) cannot be reverted without making user 2 negative
+	assert.Equal(t, []string{"tx-3", "tx-1"}, result.Cancelled)
+	assert.Equal(t, []string{"tx-5"}, result.Skipped)
+
+	user1, err := userRepo.GetByID(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "90.00", user1.Balance.StringFixed(2))
+
+	user2, err := userRepo.GetByID(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "10.00", user2.Balance.StringFixed(2))
+
+	// Cancelled transactions are not picked up again
+	result, err = service.CancelLatestOddTransactions(ctx, 10)
+	require.NoError(t, err)
+	assert.Empty(t, result.Cancelled)
+	assert.Equal(t, []string{"tx-5"}, result.Skipped)
+}
+
+func TestCancellationService_RevertsWalletTransactions(t *testing.T) {
+	ctx := context.Background()
+	userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(100)})
+	walletRepo := newFakeWalletRepo()
+	transactionRepo := newFakeTransactionRepo()
+	transactionService := NewTransactionService(&fakeUnitOfWork{}, userRepo, transactionRepo,
+		WithCurrencies(walletRepo, "EUR", "EUR", "USD"))
+
+	_, err := transactionService.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+		State: "win", Amount: "30", Currency: "USD", TransactionID: "tx-1",
+	}, entities.SourceTypeGame)
+	require.NoError(t, err)
+
+	service := NewCancellationService(&fakeUnitOfWork{}, userRepo, walletRepo, transactionRepo)
+	result, err := service.CancelLatestOddTransactions(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tx-1"}, result.Cancelled)
+
+	wallets, err := walletRepo.ListByUser(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, wallets, 1)
+	assert.True(t, wallets[0].Balance.IsZero())
+
+	user, err := userRepo.GetByID(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "100.00", user.Balance.StringFixed(2))
+}
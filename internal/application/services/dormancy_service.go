@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+)
+
+// DormancyResult summarizes a dormancy sweep by user ID
+type DormancyResult struct {
+	Flagged []uint64
+	// Charged and Frozen list the flagged users that were charged a dormancy
+	// fee and whose accounts were frozen
+	Charged []uint64
+	Frozen  []uint64
+}
+
+// DormancyService flags users that have not transacted for a while as
+// dormant, applying the dormancy rules of their jurisdiction
+type DormancyService struct {
+	uow          repositories.UnitOfWork
+	userRepo     repositories.UserRepository
+	transactions *TransactionService
+	// period is how long users must go without transactions to be dormant
+	period time.Duration
+	// Per-jurisdiction dormancy fees and freezes, keyed by country code
+	rules JurisdictionRules
+	// recorders are told about every user flagged, e.g. the outbox
+	recorders []DormancyRecorder
+}
+
+// DormancyRecorder records users flagged as dormant, e.g. as events. It runs
+// in the unit of work that flags the user; returning an error rolls the sweep
+// back.
+type DormancyRecorder interface {
+	RecordDormancy(ctx context.Context, event *entities.UserDormantEvent) error
+}
+
+// DormancyServiceOption configures optional DormancyService behaviour
+type DormancyServiceOption func(*DormancyService)
+
+// WithDormancyRules applies the dormancy fees and freezes of the users'
+// jurisdictions
+func WithDormancyRules(rules JurisdictionRules) DormancyServiceOption {
+	return func(s *DormancyService) {
+		s.rules = rules
+	}
+}
+
+// WithDormancyRecorders registers recorders, run in registration order for
+// every user flagged
+func WithDormancyRecorders(recorders ...DormancyRecorder) DormancyServiceOption {
+	return func(s *DormancyService) {
+		s.recorders = append(s.recorders, recorders...)
+	}
+}
+
+// NewDormancyService creates a new DormancyService. Fees are charged through
+// transactions, whose clock also dates the sweep.
+func NewDormancyService(
+	uow repositories.UnitOfWork,
+	userRepo repositories.UserRepository,
+	transactions *TransactionService,
+	period time.Duration,
+	opts ...DormancyServiceOption,
+) *DormancyService {
+	s := &DormancyService{
+		uow:          uow,
+		userRepo:     userRepo,
+		transactions: transactions,
+		period:       period,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// FlagIdleUsers flags up to limit active users without transactions for the
+// dormancy period as dormant. Where the user's jurisdiction asks for it, a
+// dormancy fee capped at the available balance is charged and the account is
+// frozen. Users are flagged once: they are flagged again only after
+// transacting, which ends their dormancy, and going idle for another period.
+func (s *DormancyService) FlagIdleUsers(ctx context.Context, limit int) (*DormancyResult, error) {
+	var result *DormancyResult
+	var fees []*entities.Transaction
+
+	err := s.uow.WithinTransaction(ctx, func(ctx context.Context) error {
+		// A retried unit of work starts over
+		result = &DormancyResult{}
+		fees = nil
+
+		// Dormancy fees credit the house, which is locked before the users
+		if err := s.transactions.lockHouse(ctx); err != nil {
+			return err
+		}
+
+		now := s.transactions.now()
+		users, err := s.userRepo.LockIdleSince(ctx, now.Add(-s.period), limit)
+		if err != nil {
+			return err
+		}
+
+		for _, user := range users {
+			if err := s.userRepo.UpdateDormancy(ctx, user.ID, &now); err != nil {
+				return fmt.Errorf("failed to flag user %d as dormant: %w", user.ID, err)
+			}
+			event := &entities.UserDormantEvent{
+				UserID:       user.ID,
+				Jurisdiction: user.Jurisdiction,
+				DormantAt:    now,
+			}
+
+			var rule JurisdictionRule
+			if user.Jurisdiction != "" {
+				rule = s.rules[user.Jurisdiction]
+			}
+
+			if rule.DormancyFee.IsPositive() {
+				fee, err := s.transactions.ChargeFee(ctx, user, dormancyFeeID(user.ID, now), rule.DormancyFee)
+				if err != nil {
+					return fmt.Errorf("failed to charge dormancy fee to user %d: %w", user.ID, err)
+				}
+				// Nothing is charged when nothing is available
+				if fee != nil {
+					event.Fee = fee.Amount.StringFixed(2)
+					event.FeeTransactionID = fee.TransactionID
+					fees = append(fees, fee)
+					result.Charged = append(result.Charged, user.ID)
+				}
+			}
+			event.Balance = user.Balance.StringFixed(2)
+
+			if rule.FreezeDormant {
+				if err := s.userRepo.UpdateStatus(ctx, user.ID, entities.UserStatusFrozen); err != nil {
+					return fmt.Errorf("failed to freeze dormant user %d: %w", user.ID, err)
+				}
+				event.Frozen = true
+				result.Frozen = append(result.Frozen, user.ID)
+			}
+
+			for _, recorder := range s.recorders {
+				if err := recorder.RecordDormancy(ctx, event); err != nil {
+					return err
+				}
+			}
+
+			result.Flagged = append(result.Flagged, user.ID)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to flag dormant users: %w", err)
+	}
+
+	for _, fee := range fees {
+		s.transactions.cacheBalance(ctx, fee.UserID, *fee.BalanceAfter, time.Now())
+		s.transactions.invalidateBalance(ctx, fee.UserID)
+	}
+
+	return result, nil
+}
+
+// dormancyFeeID returns the transaction ID of the dormancy fee charged to a
+// user flagged as dormant at dormantAt
+func dormancyFeeID(userID uint64, dormantAt time.Time) string {
+	return "dormancy-fee:" + strconv.FormatUint(userID, 10) + ":" + strconv.FormatInt(dormantAt.Unix(), 10)
+}
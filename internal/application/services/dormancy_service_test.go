@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDormancyService_FlagIdleUsers(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	userRepo := newFakeUserRepo(
+		&entities.User{ID: 1, Balance: decimal.NewFromInt(100), Jurisdiction: "DE"},
+		&entities.User{ID: 2, Balance: decimal.NewFromInt(3), Jurisdiction: "DE"},
+		&entities.User{ID: 3, Balance: decimal.NewFromInt(100), Jurisdiction: "NL"},
+		&entities.User{ID: 4, Balance: decimal.NewFromInt(100)},
+		&entities.User{ID: 5, Balance: decimal.NewFromInt(100), Status: entities.UserStatusFrozen},
+	)
+	transactionRepo := newFakeTransactionRepo()
+	userRepo.transactions = transactionRepo
+	outboxRepo := &fakeOutboxRepo{}
+	transactionService := NewTransactionService(&fakeUnitOfWork{}, userRepo, transactionRepo,
+		WithClock(func() time.Time { return now }))
+
+	// User 4 transacted within the dormancy period
+	_, err := transactionService.ProcessTransaction(ctx, 4, entities.TransactionRequest{
+		State: "win", Amount: "10", TransactionID: "tx-1",
+	}, entities.SourceTypeGame)
+	require.NoError(t, err)
+
+	rules := JurisdictionRules{
+		"DE": {DormancyFee: decimal.NewFromInt(5)},
+		"NL": {FreezeDormant: true},
+	}
+	service := NewDormancyService(&fakeUnitOfWork{}, userRepo, transactionService, 30*24*time.Hour,
+		WithDormancyRules(rules), WithDormancyRecorders(NewOutbox(outboxRepo)))
+
+	result, err := service.FlagIdleUsers(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{1, 2, 3}, result.Flagged)
+	assert.Equal(t, []uint64{1, 2}, result.Charged)
+	assert.Equal(t, []uint64{3}, result.Frozen)
+
+	t.Run("fees are capped at the balance", func(t *testing.T) {
+		user1, err := userRepo.GetByID(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, "95.00", user1.Balance.StringFixed(2))
+		require.NotNil(t, user1.DormantAt)
+		assert.Equal(t, now, *user1.DormantAt)
+
+		user2, err := userRepo.GetByID(ctx, 2)
+		require.NoError(t, err)
+		assert.True(t, user2.Balance.IsZero())
+
+		fee, err := transactionRepo.GetByTransactionID(ctx, dormancyFeeID(2, now))
+		require.NoError(t, err)
+		assert.Equal(t, entities.TransactionTypeFee, fee.Type)
+		assert.Equal(t, "3.00", fee.Amount.StringFixed(2))
+	})
+
+	t.Run("accounts are frozen per jurisdiction", func(t *testing.T) {
+		user3, err := userRepo.GetByID(ctx, 3)
+		require.NoError(t, err)
+		assert.Equal(t, entities.UserStatusFrozen, user3.Status)
+		assert.Equal(t, "100.00", user3.Balance.StringFixed(2))
+	})
+
+	t.Run("an event is recorded for every flagged user", func(t *testing.T) {
+		require.Len(t, outboxRepo.events, 3)
+		assert.Equal(t, entities.EventUserDormant, outboxRepo.events[0].Type)
+		assert.Equal(t, "1", outboxRepo.events[0].Key)
+
+		var event entities.UserDormantEvent
+		require.NoError(t, json.Unmarshal(outboxRepo.events[0].Payload, &event))
+		assert.Equal(t, "5.00", event.Fee)
+		assert.Equal(t, dormancyFeeID(1, now), event.FeeTransactionID)
+		assert.Equal(t, "95.00", event.Balance)
+		assert.False(t, event.Frozen)
+
+		require.NoError(t, json.Unmarshal(outboxRepo.events[2].Payload, &event))
+		assert.True(t, event.Frozen)
+	})
+
+	t.Run("users are flagged once", func(t *testing.T) {
+		result, err := service.FlagIdleUsers(ctx, 10)
+		require.NoError(t, err)
+		assert.Empty(t, result.Flagged)
+	})
+
+	t.Run("transacting ends dormancy", func(t *testing.T) {
+		_, err := transactionService.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "win", Amount: "10", TransactionID: "tx-2",
+		}, entities.SourceTypeGame)
+		require.NoError(t, err)
+
+		user1, err := userRepo.GetByID(ctx, 1)
+		require.NoError(t, err)
+		assert.Nil(t, user1.DormantAt)
+	})
+}
+
+func TestTransactionService_ChargeFeeSparesHeldAmounts(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	user := &entities.User{ID: 1, Balance: decimal.NewFromInt(10)}
+	userRepo := newFakeUserRepo(user)
+	holdRepo := &fakeHoldRepo{}
+	require.NoError(t, holdRepo.Create(ctx, &entities.Hold{
+		HoldID: "hold-1", UserID: 1, Amount: decimal.NewFromInt(8),
+		Status: entities.HoldStatusHeld, CreatedAt: now, ExpiresAt: now.Add(time.Hour),
+	}))
+	service := NewTransactionService(&fakeUnitOfWork{}, userRepo, newFakeTransactionRepo(), WithHolds(holdRepo))
+
+	fee, err := service.ChargeFee(ctx, user, "fee-1", decimal.NewFromInt(5))
+	require.NoError(t, err)
+	require.NotNil(t, fee)
+	assert.Equal(t, "2.00", fee.Amount.StringFixed(2))
+	assert.Equal(t, "8.00", fee.BalanceAfter.StringFixed(2))
+
+	// Nothing is left to charge
+	fee, err = service.ChargeFee(ctx, user, "fee-2", decimal.NewFromInt(5))
+	require.NoError(t, err)
+	assert.Nil(t, fee)
+}
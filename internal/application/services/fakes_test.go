@@ -0,0 +1,1409 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/shopspring/decimal"
+)
+
+// fakeUnitOfWork runs the function directly and records the outcome
+type fakeUnitOfWork struct {
+	commits   int
+	rollbacks int
+}
+
+func (u *fakeUnitOfWork) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := fn(ctx); err != nil {
+		u.rollbacks++
+		return err
+	}
+	u.commits++
+	return nil
+}
+
+// fakeUserRepo is an in-memory UserRepository for service tests
+type fakeUserRepo struct {
+	mu    sync.Mutex
+	users map[uint64]*entities.User
+	// getErr simulates an unavailable database for reads
+	getErr error
+	// updateErr simulates a failing balance update
+	updateErr error
+	// transactions, when set, tells idle users apart in LockIdleSince
+	transactions *fakeTransactionRepo
+}
+
+func newFakeUserRepo(users ...*entities.User) *fakeUserRepo {
+	repo := &fakeUserRepo{users: make(map[uint64]*entities.User)}
+	for _, user := range users {
+		if user.Status == "" {
+			user.Status = entities.UserStatusActive
+		}
+		repo.users[user.ID] = user
+	}
+	return repo
+}
+
+func (r *fakeUserRepo) GetByID(ctx context.Context, userID uint64) (*entities.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.getErr != nil {
+		return nil, r.getErr
+	}
+
+	user, ok := r.users[userID]
+	if !ok {
+		return nil, repositories.ErrNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *fakeUserRepo) GetByIDForUpdate(ctx context.Context, userID uint64) (*entities.User, error) {
+	return r.GetByID(ctx, userID)
+}
+
+func (r *fakeUserRepo) UpdateBalance(ctx context.Context, userID uint64, newBalance decimal.Decimal) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.updateErr != nil {
+		return r.updateErr
+	}
+
+	user, ok := r.users[userID]
+	if !ok {
+		return errors.New("user not found")
+	}
+	user.Balance = newBalance
+	return nil
+}
+
+func (r *fakeUserRepo) Create(ctx context.Context, user *entities.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user.ID = uint64(len(r.users) + 1)
+	user.Status = entities.UserStatusActive
+	copied := *user
+	r.users[user.ID] = &copied
+	return nil
+}
+
+func (r *fakeUserRepo) List(ctx context.Context, limit, offset int) ([]*entities.User, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	users := make([]*entities.User, 0, len(r.users))
+	for _, user := range r.users {
+		copied := *user
+		users = append(users, &copied)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+
+	total := len(users)
+	users = users[min(offset, total):min(offset+limit, total)]
+	return users, total, nil
+}
+
+func (r *fakeUserRepo) UpdateStatus(ctx context.Context, userID uint64, status entities.UserStatus) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return repositories.ErrNotFound
+	}
+	user.Status = status
+	return nil
+}
+
+func (r *fakeUserRepo) UpdateJurisdiction(ctx context.Context, userID uint64, jurisdiction string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return repositories.ErrNotFound
+	}
+	user.Jurisdiction = jurisdiction
+	return nil
+}
+
+func (r *fakeUserRepo) UpdateCreditLimit(ctx context.Context, userID uint64, creditLimit decimal.Decimal) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return repositories.ErrNotFound
+	}
+	user.CreditLimit = creditLimit
+	return nil
+}
+
+func (r *fakeUserRepo) LockIdleSince(ctx context.Context, since time.Time, limit int) ([]*entities.User, error) {
+	active := make(map[uint64]bool)
+	if r.transactions != nil {
+		r.transactions.mu.Lock()
+		for _, transaction := range r.transactions.transactions {
+			if !transaction.CreatedAt.Before(since) {
+				active[transaction.UserID] = true
+			}
+		}
+		r.transactions.mu.Unlock()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var users []*entities.User
+	for _, user := range r.users {
+		if user.Status == entities.UserStatusActive && user.DormantAt == nil && !active[user.ID] {
+			copied := *user
+			users = append(users, &copied)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users[:min(limit, len(users))], nil
+}
+
+func (r *fakeUserRepo) UpdateDormancy(ctx context.Context, userID uint64, dormantAt *time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return repositories.ErrNotFound
+	}
+	user.DormantAt = dormantAt
+	return nil
+}
+
+// fakeWalletRepo is an in-memory WalletRepository for service tests
+type fakeWalletRepo struct {
+	mu      sync.Mutex
+	wallets map[string]*entities.Wallet
+}
+
+func newFakeWalletRepo(wallets ...*entities.Wallet) *fakeWalletRepo {
+	repo := &fakeWalletRepo{wallets: make(map[string]*entities.Wallet)}
+	for _, wallet := range wallets {
+		repo.wallets[walletKey(wallet.UserID, wallet.Currency)] = wallet
+	}
+	return repo
+}
+
+func walletKey(userID uint64, currency string) string {
+	return strconv.FormatUint(userID, 10) + "/" + currency
+}
+
+func (r *fakeWalletRepo) GetForUpdate(ctx context.Context, userID uint64, currency string) (*entities.Wallet, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := walletKey(userID, currency)
+	if _, ok := r.wallets[key]; !ok {
+		r.wallets[key] = &entities.Wallet{UserID: userID, Currency: currency}
+	}
+	copied := *r.wallets[key]
+	return &copied, nil
+}
+
+func (r *fakeWalletRepo) UpdateBalance(ctx context.Context, userID uint64, currency string, newBalance decimal.Decimal) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	wallet, ok := r.wallets[walletKey(userID, currency)]
+	if !ok {
+		return repositories.ErrNotFound
+	}
+	wallet.Balance = newBalance
+	return nil
+}
+
+func (r *fakeWalletRepo) ListByUser(ctx context.Context, userID uint64) ([]*entities.Wallet, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var wallets []*entities.Wallet
+	for _, wallet := range r.wallets {
+		if wallet.UserID == userID {
+			copied := *wallet
+			wallets = append(wallets, &copied)
+		}
+	}
+	sort.Slice(wallets, func(i, j int) bool { return wallets[i].Currency < wallets[j].Currency })
+	return wallets, nil
+}
+
+// fakeTransactionRepo is an in-memory TransactionRepository for service tests
+type fakeTransactionRepo struct {
+	mu           sync.Mutex
+	transactions []*entities.Transaction
+	// createErr simulates a failing insert
+	createErr error
+}
+
+func newFakeTransactionRepo() *fakeTransactionRepo {
+	return &fakeTransactionRepo{}
+}
+
+func (r *fakeTransactionRepo) Create(ctx context.Context, transaction *entities.Transaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.createErr != nil {
+		return r.createErr
+	}
+	// Transaction IDs are unique like under the unique index
+	for _, existing := range r.transactions {
+		if existing.TransactionID == transaction.TransactionID {
+			return repositories.ErrConflict
+		}
+	}
+	if transaction.ID == 0 {
+		transaction.ID = uint64(len(r.transactions) + 1)
+	}
+	if transaction.Receipt == "" {
+		transaction.Receipt = strconv.FormatUint(transaction.ID, 10)
+	}
+	copied := *transaction
+	r.transactions = append(r.transactions, &copied)
+	return nil
+}
+
+func (r *fakeTransactionRepo) ExistsByTransactionID(ctx context.Context, transactionID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, transaction := range r.transactions {
+		if transaction.TransactionID == transactionID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeTransactionRepo) GetByTransactionID(ctx context.Context, transactionID string) (*entities.Transaction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, transaction := range r.transactions {
+		if transaction.TransactionID == transactionID {
+			copied := *transaction
+			return &copied, nil
+		}
+	}
+	return nil, repositories.ErrNotFound
+}
+
+func (r *fakeTransactionRepo) GetByTransactionIDForUpdate(ctx context.Context, transactionID string) (*entities.Transaction, error) {
+	return r.GetByTransactionID(ctx, transactionID)
+}
+
+func (r *fakeTransactionRepo) GetByUserID(ctx context.Context, userID uint64) ([]*entities.Transaction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var result []*entities.Transaction
+	for i := len(r.transactions) - 1; i >= 0; i-- {
+		if r.transactions[i].UserID == userID {
+			result = append(result, r.transactions[i])
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeTransactionRepo) NetChangeSince(ctx context.Context, userID uint64, since time.Time) (decimal.Decimal, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	net := decimal.Zero
+	for _, transaction := range r.transactions {
+		if transaction.UserID == userID && transaction.Currency == "" {
+			net = net.Add(transaction.BalanceChangeSince(since))
+		}
+	}
+	return net, nil
+}
+
+func (r *fakeTransactionRepo) VelocitySince(ctx context.Context, userID uint64, since time.Time) (repositories.Velocity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	velocity := repositories.Velocity{Losses: decimal.Zero}
+	for _, transaction := range r.transactions {
+		if transaction.UserID != userID || transaction.CreatedAt.Before(since) || transaction.Cancelled ||
+			transaction.Type != entities.TransactionTypeStandard {
+			continue
+		}
+		velocity.Transactions++
+		if transaction.State == entities.StateLose && transaction.Currency == "" {
+			velocity.Losses = velocity.Losses.Add(transaction.Amount)
+		}
+	}
+	return velocity, nil
+}
+
+func (r *fakeTransactionRepo) MostActiveUsers(ctx context.Context, since time.Time, limit int) ([]uint64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	counts := make(map[uint64]int)
+	var userIDs []uint64
+	for _, transaction := range r.transactions {
+		if transaction.CreatedAt.Before(since) {
+			continue
+		}
+		if counts[transaction.UserID] == 0 {
+			userIDs = append(userIDs, transaction.UserID)
+		}
+		counts[transaction.UserID]++
+	}
+	sort.SliceStable(userIDs, func(i, j int) bool {
+		return counts[userIDs[i]] > counts[userIDs[j]]
+	})
+	if len(userIDs) > limit {
+		userIDs = userIDs[:limit]
+	}
+	return userIDs, nil
+}
+
+func (r *fakeTransactionRepo) Search(
+	ctx context.Context,
+	filter repositories.TransactionFilter,
+) ([]*entities.Transaction, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var matches []*entities.Transaction
+	for i := len(r.transactions) - 1; i >= 0; i-- {
+		transaction := r.transactions[i]
+		if filter.UserID != 0 && transaction.UserID != filter.UserID {
+			continue
+		}
+		if filter.SourceType != "" && transaction.SourceType != filter.SourceType {
+			continue
+		}
+		if filter.State != "" && transaction.State != filter.State {
+			continue
+		}
+		if filter.Cancelled != nil && transaction.Cancelled != *filter.Cancelled {
+			continue
+		}
+		copied := *transaction
+		matches = append(matches, &copied)
+	}
+
+	total := len(matches)
+	start := filter.Offset
+	if after := filter.After; after != nil {
+		start = total
+		for i, transaction := range matches {
+			if transaction.CreatedAt.Before(after.CreatedAt) ||
+				transaction.CreatedAt.Equal(after.CreatedAt) && transaction.ID < after.ID {
+				start = i
+				break
+			}
+		}
+	}
+	if start >= total {
+		return nil, total, nil
+	}
+	end := min(start+filter.Limit, total)
+	return matches[start:end], total, nil
+}
+
+func (r *fakeTransactionRepo) SummarizeRound(
+	ctx context.Context,
+	userID uint64,
+	roundID, currency string,
+) (repositories.RoundTotals, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	totals := repositories.RoundTotals{Bets: decimal.Zero, Wins: decimal.Zero, Fees: decimal.Zero}
+	for _, transaction := range r.transactions {
+		if transaction.UserID != userID || transaction.RoundID != roundID || transaction.Currency != currency {
+			continue
+		}
+		switch {
+		case transaction.Cancelled:
+			totals.Cancelled++
+			continue
+		case transaction.Type == entities.TransactionTypeFee:
+			totals.Fees = totals.Fees.Add(transaction.Amount)
+			continue
+		case transaction.State == entities.StateWin:
+			totals.Wins = totals.Wins.Add(transaction.Amount)
+		default:
+			totals.Bets = totals.Bets.Add(transaction.Amount)
+		}
+		totals.Transactions++
+	}
+	return totals, nil
+}
+
+func (r *fakeTransactionRepo) SummarizeDays(
+	ctx context.Context,
+	userID uint64,
+	currency string,
+	from, to time.Time,
+) ([]repositories.DayTotals, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var days []repositories.DayTotals
+	for _, transaction := range r.transactions {
+		occurredAt := transaction.BusinessTime()
+		if transaction.UserID != userID || transaction.Currency != currency || transaction.Cancelled ||
+			transaction.Type == entities.TransactionTypeFee ||
+			occurredAt.Before(from) || !occurredAt.Before(to) {
+			continue
+		}
+		day := occurredAt.UTC().Truncate(24 * time.Hour)
+		i := slices.IndexFunc(days, func(d repositories.DayTotals) bool {
+			return d.Day.Equal(day) && d.SourceType == transaction.SourceType && d.State == transaction.State
+		})
+		if i < 0 {
+			days = append(days, repositories.DayTotals{Day: day, SourceType: transaction.SourceType, State: transaction.State, Amount: decimal.Zero})
+			i = len(days) - 1
+		}
+		days[i].Count++
+		days[i].Amount = days[i].Amount.Add(transaction.Amount)
+	}
+	sort.Slice(days, func(i, j int) bool {
+		if !days[i].Day.Equal(days[j].Day) {
+			return days[i].Day.Before(days[j].Day)
+		}
+		if days[i].SourceType != days[j].SourceType {
+			return days[i].SourceType < days[j].SourceType
+		}
+		return days[i].State < days[j].State
+	})
+	return days, nil
+}
+
+func (r *fakeTransactionRepo) LockLatestOddUncancelled(ctx context.Context, limit int) ([]*entities.Transaction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var result []*entities.Transaction
+	for i := len(r.transactions) - 1; i >= 0 && len(result) < limit; i-- {
+		transaction := r.transactions[i]
+		if transaction.ID%2 == 1 && !transaction.Cancelled && transaction.Reverses == "" && transaction.ReversedBy == "" && transaction.TransferID == "" &&
+			transaction.Type != entities.TransactionTypeFee && transaction.Type != entities.TransactionTypeAdjustment && !transaction.Pending {
+			copied := *transaction
+			result = append(result, &copied)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeTransactionRepo) CheckUniqueIDs(
+	ctx context.Context,
+	from, to time.Time,
+	limit int,
+) (*repositories.UniquenessCheck, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	check := &repositories.UniquenessCheck{Enforced: true}
+	counts := make(map[string]int)
+	inRange := make(map[string]bool)
+	for _, transaction := range r.transactions {
+		counts[transaction.TransactionID]++
+		if !transaction.CreatedAt.Before(from) && transaction.CreatedAt.Before(to) {
+			check.Transactions++
+			inRange[transaction.TransactionID] = true
+		}
+	}
+	check.DistinctIDs = len(inRange)
+
+	ids := slices.Sorted(maps.Keys(inRange))
+	for _, id := range ids {
+		if counts[id] > 1 && len(check.Duplicates) < limit {
+			check.Duplicates = append(check.Duplicates, entities.DuplicateTransactionID{TransactionID: id, Count: counts[id]})
+		}
+	}
+	return check, nil
+}
+
+func (r *fakeTransactionRepo) MarkCancelled(ctx context.Context, id uint64, cancelledAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, transaction := range r.transactions {
+		if transaction.ID == id && !transaction.Cancelled {
+			transaction.Cancelled = true
+			transaction.CancelledAt = &cancelledAt
+			return nil
+		}
+	}
+	return repositories.ErrNotFound
+}
+
+func (r *fakeTransactionRepo) MarkReversed(ctx context.Context, id uint64, reversedBy string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, transaction := range r.transactions {
+		if transaction.ID == id && transaction.ReversedBy == "" {
+			transaction.ReversedBy = reversedBy
+			return nil
+		}
+	}
+	return repositories.ErrNotFound
+}
+
+func (r *fakeTransactionRepo) SumPending(ctx context.Context, userID uint64) (decimal.Decimal, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pending := decimal.Zero
+	for _, transaction := range r.transactions {
+		if transaction.UserID == userID && transaction.Pending && !transaction.Cancelled && transaction.Currency == "" {
+			pending = pending.Add(transaction.Amount)
+		}
+	}
+	return pending, nil
+}
+
+func (r *fakeTransactionRepo) LockPendingBefore(ctx context.Context, before time.Time, limit int) ([]*entities.Transaction, error) {
+	return r.ListPendingBefore(ctx, before, limit)
+}
+
+func (r *fakeTransactionRepo) ListPendingBefore(ctx context.Context, before time.Time, limit int) ([]*entities.Transaction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var result []*entities.Transaction
+	for _, transaction := range r.transactions {
+		if len(result) < limit && transaction.Pending && !transaction.Cancelled && transaction.CreatedAt.Before(before) {
+			copied := *transaction
+			result = append(result, &copied)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeTransactionRepo) MarkSettled(ctx context.Context, id uint64, settledAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, transaction := range r.transactions {
+		if transaction.ID == id && transaction.Pending {
+			transaction.Pending = false
+			transaction.SettledAt = &settledAt
+			return nil
+		}
+	}
+	return repositories.ErrNotFound
+}
+
+// fakeHoldRepo is an in-memory HoldRepository for service tests
+type fakeHoldRepo struct {
+	mu    sync.Mutex
+	holds []*entities.Hold
+}
+
+func (r *fakeHoldRepo) Create(ctx context.Context, hold *entities.Hold) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	hold.ID = uint64(len(r.holds) + 1)
+	copied := *hold
+	r.holds = append(r.holds, &copied)
+	return nil
+}
+
+func (r *fakeHoldRepo) GetByHoldIDForUpdate(ctx context.Context, holdID string) (*entities.Hold, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, hold := range r.holds {
+		if hold.HoldID == holdID {
+			copied := *hold
+			return &copied, nil
+		}
+	}
+	return nil, repositories.ErrNotFound
+}
+
+func (r *fakeHoldRepo) SumActive(ctx context.Context, userID uint64, now time.Time) (decimal.Decimal, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sum := decimal.Zero
+	for _, hold := range r.holds {
+		if hold.UserID == userID && hold.IsActive(now) {
+			sum = sum.Add(hold.Amount)
+		}
+	}
+	return sum, nil
+}
+
+func (r *fakeHoldRepo) Settle(
+	ctx context.Context,
+	id uint64,
+	status entities.HoldStatus,
+	capturedAmount *decimal.Decimal,
+	settledAt time.Time,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, hold := range r.holds {
+		if hold.ID == id && hold.Status == entities.HoldStatusHeld {
+			hold.Status = status
+			hold.CapturedAmount = capturedAmount
+			hold.SettledAt = &settledAt
+			return nil
+		}
+	}
+	return repositories.ErrNotFound
+}
+
+func (r *fakeHoldRepo) Expire(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var holdIDs []string
+	for _, hold := range r.holds {
+		if len(holdIDs) < limit && hold.Status == entities.HoldStatusHeld && !now.Before(hold.ExpiresAt) {
+			hold.Status = entities.HoldStatusExpired
+			hold.SettledAt = &now
+			holdIDs = append(holdIDs, hold.HoldID)
+		}
+	}
+	return holdIDs, nil
+}
+
+// fakeScheduleRepo is an in-memory ScheduledTransactionRepository for
+// service tests
+type fakeScheduleRepo struct {
+	mu        sync.Mutex
+	schedules []*entities.ScheduledTransaction
+}
+
+func (r *fakeScheduleRepo) Create(ctx context.Context, schedule *entities.ScheduledTransaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.schedules {
+		if existing.ScheduleID == schedule.ScheduleID {
+			return repositories.ErrConflict
+		}
+	}
+	schedule.ID = uint64(len(r.schedules) + 1)
+	copied := *schedule
+	r.schedules = append(r.schedules, &copied)
+	return nil
+}
+
+func (r *fakeScheduleRepo) GetByScheduleIDForUpdate(
+	ctx context.Context,
+	scheduleID string,
+) (*entities.ScheduledTransaction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, schedule := range r.schedules {
+		if schedule.ScheduleID == scheduleID {
+			copied := *schedule
+			return &copied, nil
+		}
+	}
+	return nil, repositories.ErrNotFound
+}
+
+func (r *fakeScheduleRepo) ListByUser(ctx context.Context, userID uint64) ([]*entities.ScheduledTransaction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var schedules []*entities.ScheduledTransaction
+	for i := len(r.schedules) - 1; i >= 0; i-- {
+		if r.schedules[i].UserID == userID {
+			copied := *r.schedules[i]
+			schedules = append(schedules, &copied)
+		}
+	}
+	return schedules, nil
+}
+
+func (r *fakeScheduleRepo) ListDue(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var scheduleIDs []string
+	for _, schedule := range r.schedules {
+		if len(scheduleIDs) < limit && schedule.Status == entities.ScheduleStatusActive && !schedule.NextRunAt.After(now) {
+			scheduleIDs = append(scheduleIDs, schedule.ScheduleID)
+		}
+	}
+	return scheduleIDs, nil
+}
+
+func (r *fakeScheduleRepo) Update(ctx context.Context, schedule *entities.ScheduledTransaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, existing := range r.schedules {
+		if existing.ID == schedule.ID {
+			copied := *schedule
+			r.schedules[i] = &copied
+			return nil
+		}
+	}
+	return repositories.ErrNotFound
+}
+
+// recordingNotifier captures the alerts it receives
+type recordingNotifier struct {
+	alerts []BalanceAlert
+}
+
+func (n *recordingNotifier) NotifyBalanceAlert(ctx context.Context, alert BalanceAlert) error {
+	n.alerts = append(n.alerts, alert)
+	return nil
+}
+
+// fakeAnnotationRepo keeps annotations in memory. ListByUser looks up the
+// annotated transactions in transactions.
+type fakeAnnotationRepo struct {
+	annotations  []*entities.Annotation
+	transactions *fakeTransactionRepo
+}
+
+func (r *fakeAnnotationRepo) Create(ctx context.Context, annotation *entities.Annotation) error {
+	annotation.ID = uint64(len(r.annotations) + 1)
+	r.annotations = append(r.annotations, annotation)
+	return nil
+}
+
+func (r *fakeAnnotationRepo) ListByTarget(
+	ctx context.Context,
+	targetType entities.AnnotationTarget,
+	targetID string,
+) ([]*entities.Annotation, error) {
+	var result []*entities.Annotation
+	for _, annotation := range r.annotations {
+		if annotation.TargetType == targetType && annotation.TargetID == targetID {
+			result = append(result, annotation)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeAnnotationRepo) ListByUser(ctx context.Context, userID uint64) ([]*entities.Annotation, error) {
+	var result []*entities.Annotation
+	for _, annotation := range r.annotations {
+		switch annotation.TargetType {
+		case entities.AnnotationTargetUser:
+			if annotation.TargetID == strconv.FormatUint(userID, 10) {
+				result = append(result, annotation)
+			}
+		case entities.AnnotationTargetTransaction:
+			transaction, err := r.transactions.GetByTransactionID(ctx, annotation.TargetID)
+			if err == nil && transaction.UserID == userID {
+				result = append(result, annotation)
+			}
+		}
+	}
+	return result, nil
+}
+
+// fakeOutboxRepo keeps outbox events in memory
+type fakeOutboxRepo struct {
+	events    []*entities.OutboxEvent
+	appendErr error
+}
+
+func (r *fakeOutboxRepo) Append(ctx context.Context, event *entities.OutboxEvent) error {
+	if r.appendErr != nil {
+		return r.appendErr
+	}
+	event.ID = uint64(len(r.events) + 1)
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *fakeOutboxRepo) LockUnpublished(ctx context.Context, limit int) ([]*entities.OutboxEvent, error) {
+	var result []*entities.OutboxEvent
+	for _, event := range r.events {
+		if event.PublishedAt == nil && len(result) < limit {
+			copied := *event
+			result = append(result, &copied)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeOutboxRepo) MarkPublished(ctx context.Context, ids []uint64, publishedAt time.Time) error {
+	for _, id := range ids {
+		r.events[id-1].PublishedAt = &publishedAt
+	}
+	return nil
+}
+
+// fakeWebhookRepo keeps webhooks and their deliveries in memory
+type fakeWebhookRepo struct {
+	mu         sync.Mutex
+	webhooks   []*entities.Webhook
+	deliveries []*entities.WebhookDelivery
+}
+
+func (r *fakeWebhookRepo) Create(ctx context.Context, webhook *entities.Webhook) error {
+	webhook.ID = uint64(len(r.webhooks) + 1)
+	webhook.Active = true
+	copied := *webhook
+	r.webhooks = append(r.webhooks, &copied)
+	return nil
+}
+
+func (r *fakeWebhookRepo) GetByID(ctx context.Context, id uint64) (*entities.Webhook, error) {
+	if id == 0 || id > uint64(len(r.webhooks)) || !r.webhooks[id-1].Active {
+		return nil, repositories.ErrNotFound
+	}
+	copied := *r.webhooks[id-1]
+	copied.Secret = ""
+	return &copied, nil
+}
+
+func (r *fakeWebhookRepo) List(ctx context.Context) ([]*entities.Webhook, error) {
+	var result []*entities.Webhook
+	for _, webhook := range r.webhooks {
+		if webhook.Active {
+			copied := *webhook
+			copied.Secret = ""
+			result = append(result, &copied)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeWebhookRepo) Deactivate(ctx context.Context, id uint64) error {
+	if _, err := r.GetByID(ctx, id); err != nil {
+		return err
+	}
+	r.webhooks[id-1].Active = false
+	for _, delivery := range r.deliveries {
+		if delivery.WebhookID == id && delivery.Status == entities.WebhookDeliveryPending {
+			delivery.Status = entities.WebhookDeliveryFailed
+			delivery.NextAttemptAt = nil
+		}
+	}
+	return nil
+}
+
+func (r *fakeWebhookRepo) EnqueueDeliveries(ctx context.Context, event repositories.WebhookEvent) (int, error) {
+	queued := 0
+	for _, webhook := range r.webhooks {
+		filter := webhook.Filter
+		if !webhook.Active ||
+			(len(webhook.Events) > 0 && !slices.Contains(webhook.Events, event.Type)) ||
+			(filter.UserID != 0 && filter.UserID != event.UserID) ||
+			(filter.SourceType != "" && filter.SourceType != event.SourceType) ||
+			(filter.State != "" && filter.State != event.State) {
+			continue
+		}
+		createdAt := event.CreatedAt
+		r.deliveries = append(r.deliveries, &entities.WebhookDelivery{
+			ID:            uint64(len(r.deliveries) + 1),
+			WebhookID:     webhook.ID,
+			EventType:     event.Type,
+			Payload:       event.Payload,
+			Status:        entities.WebhookDeliveryPending,
+			NextAttemptAt: &createdAt,
+			CreatedAt:     createdAt,
+		})
+		queued++
+	}
+	return queued, nil
+}
+
+func (r *fakeWebhookRepo) ClaimDueDeliveries(
+	ctx context.Context,
+	now, leaseUntil time.Time,
+	limit int,
+) ([]*entities.WebhookDelivery, error) {
+	var result []*entities.WebhookDelivery
+	for _, delivery := range r.deliveries {
+		if delivery.Status != entities.WebhookDeliveryPending || delivery.NextAttemptAt.After(now) || len(result) == limit {
+			continue
+		}
+		lease := leaseUntil
+		delivery.NextAttemptAt = &lease
+		copied := *delivery
+		webhook := r.webhooks[delivery.WebhookID-1]
+		copied.URL = webhook.URL
+		copied.Secret = webhook.Secret
+		result = append(result, &copied)
+	}
+	return result, nil
+}
+
+func (r *fakeWebhookRepo) UpdateDelivery(ctx context.Context, delivery *entities.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	copied := *delivery
+	copied.URL, copied.Secret = "", ""
+	r.deliveries[delivery.ID-1] = &copied
+	return nil
+}
+
+func (r *fakeWebhookRepo) ListDeliveries(
+	ctx context.Context,
+	webhookID uint64,
+	status entities.WebhookDeliveryStatus,
+	limit, offset int,
+) ([]*entities.WebhookDelivery, int, error) {
+	var matches []*entities.WebhookDelivery
+	for i := len(r.deliveries) - 1; i >= 0; i-- {
+		delivery := r.deliveries[i]
+		if delivery.WebhookID == webhookID && (status == "" || delivery.Status == status) {
+			matches = append(matches, delivery)
+		}
+	}
+	total := len(matches)
+	if offset > total {
+		offset = total
+	}
+	matches = matches[offset:]
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, total, nil
+}
+
+func (r *fakeWebhookRepo) ListDeliveryIDs(ctx context.Context, webhookID uint64, from, to time.Time) ([]uint64, error) {
+	var ids []uint64
+	for _, delivery := range r.deliveries {
+		if !r.webhooks[delivery.WebhookID-1].Active || (webhookID != 0 && delivery.WebhookID != webhookID) {
+			continue
+		}
+		if !delivery.CreatedAt.Before(from) && delivery.CreatedAt.Before(to) {
+			ids = append(ids, delivery.ID)
+		}
+	}
+	return ids, nil
+}
+
+func (r *fakeWebhookRepo) RequeueDeliveries(ctx context.Context, ids []uint64, now time.Time) (int, error) {
+	for _, id := range ids {
+		delivery := r.deliveries[id-1]
+		delivery.Status = entities.WebhookDeliveryPending
+		delivery.Attempts = 0
+		delivery.NextAttemptAt = &now
+		delivery.DeliveredAt = nil
+	}
+	return len(ids), nil
+}
+
+// fakeRestoreRepo is an in-memory RestoreRepository whose database contents
+// are given by version and fingerprint
+type fakeRestoreRepo struct {
+	version     int
+	fingerprint entities.DatabaseFingerprint
+	markers     []*entities.RestoreMarker
+}
+
+func (r *fakeRestoreRepo) SchemaVersion(ctx context.Context) (int, error) {
+	return r.version, nil
+}
+
+func (r *fakeRestoreRepo) Fingerprint(ctx context.Context) (*entities.DatabaseFingerprint, error) {
+	fingerprint := r.fingerprint
+	fingerprint.Tables = slices.Clone(r.fingerprint.Tables)
+	return &fingerprint, nil
+}
+
+func (r *fakeRestoreRepo) CreateMarker(ctx context.Context, marker *entities.RestoreMarker) error {
+	for _, existing := range r.markers {
+		if existing.Name == marker.Name {
+			return repositories.ErrConflict
+		}
+	}
+	marker.WALPosition = "0/" + strconv.Itoa(len(r.markers)+1)
+	stored := *marker
+	r.markers = append(r.markers, &stored)
+	return nil
+}
+
+func (r *fakeRestoreRepo) GetMarker(ctx context.Context, name string) (*entities.RestoreMarker, error) {
+	for _, marker := range r.markers {
+		if marker.Name == name {
+			return marker, nil
+		}
+	}
+	return nil, repositories.ErrNotFound
+}
+
+func (r *fakeRestoreRepo) ListMarkers(ctx context.Context) ([]*entities.RestoreMarker, error) {
+	markers := slices.Clone(r.markers)
+	slices.Reverse(markers)
+	return markers, nil
+}
+
+// fakeRejectionRepo keeps rejections in memory; accepted stands in for the
+// accepted transactions per source type
+type fakeRejectionRepo struct {
+	rejections []*entities.Rejection
+	accepted   map[entities.SourceType]int
+	createErr  error
+}
+
+func (r *fakeRejectionRepo) Create(ctx context.Context, rejection *entities.Rejection) error {
+	if r.createErr != nil {
+		return r.createErr
+	}
+	rejection.ID = uint64(len(r.rejections) + 1)
+	copied := *rejection
+	r.rejections = append(r.rejections, &copied)
+	return nil
+}
+
+func (r *fakeRejectionRepo) CountByReason(ctx context.Context, from, to time.Time) ([]repositories.RejectionCount, error) {
+	var counts []repositories.RejectionCount
+	for _, rejection := range r.rejections {
+		if rejection.CreatedAt.Before(from) || !rejection.CreatedAt.Before(to) {
+			continue
+		}
+		counts = append(counts, repositories.RejectionCount{
+			SourceType: rejection.SourceType, Reason: rejection.Reason, Count: 1,
+		})
+	}
+	return counts, nil
+}
+
+func (r *fakeRejectionRepo) CountAccepted(ctx context.Context, from, to time.Time) (map[entities.SourceType]int, error) {
+	return r.accepted, nil
+}
+
+func (r *fakeRejectionRepo) List(ctx context.Context, filter repositories.RejectionFilter) ([]*entities.Rejection, int, error) {
+	var matches []*entities.Rejection
+	for i := len(r.rejections) - 1; i >= 0; i-- {
+		rejection := r.rejections[i]
+		if (filter.SourceType == "" || rejection.SourceType == filter.SourceType) &&
+			(filter.Reason == "" || rejection.Reason == filter.Reason) {
+			matches = append(matches, rejection)
+		}
+	}
+	total := len(matches)
+	return matches[min(filter.Offset, total):min(filter.Offset+filter.Limit, total)], total, nil
+}
+
+// fakeSyncRepo is an in-memory SyncRepository over changes ordered by cursor
+type fakeSyncRepo struct {
+	changes []repositories.TransactionChange
+}
+
+func (r *fakeSyncRepo) ChangesSince(ctx context.Context, cursor repositories.SyncCursor, limit int) ([]repositories.TransactionChange, error) {
+	var changes []repositories.TransactionChange
+	for _, change := range r.changes {
+		after := change.Cursor.Version > cursor.Version ||
+			change.Cursor.Version == cursor.Version && change.Cursor.ID > cursor.ID
+		if after && len(changes) < limit {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+// fakeFeeRuleRepo keeps fee rules in memory, keyed by source type and state
+type fakeFeeRuleRepo struct {
+	rules map[string]*entities.FeeRule
+}
+
+func newFakeFeeRuleRepo(rules ...*entities.FeeRule) *fakeFeeRuleRepo {
+	r := &fakeFeeRuleRepo{rules: make(map[string]*entities.FeeRule)}
+	for _, rule := range rules {
+		r.rules[string(rule.SourceType)+"/"+string(rule.State)] = rule
+	}
+	return r
+}
+
+func (r *fakeFeeRuleRepo) Get(ctx context.Context, sourceType entities.SourceType, state entities.TransactionState) (*entities.FeeRule, error) {
+	rule, ok := r.rules[string(sourceType)+"/"+string(state)]
+	if !ok {
+		return nil, repositories.ErrNotFound
+	}
+	copied := *rule
+	return &copied, nil
+}
+
+func (r *fakeFeeRuleRepo) List(ctx context.Context) ([]*entities.FeeRule, error) {
+	var rules []*entities.FeeRule
+	for _, key := range slices.Sorted(maps.Keys(r.rules)) {
+		rules = append(rules, r.rules[key])
+	}
+	return rules, nil
+}
+
+func (r *fakeFeeRuleRepo) Set(ctx context.Context, rule *entities.FeeRule) error {
+	copied := *rule
+	r.rules[string(rule.SourceType)+"/"+string(rule.State)] = &copied
+	return nil
+}
+
+func (r *fakeFeeRuleRepo) Delete(ctx context.Context, sourceType entities.SourceType, state entities.TransactionState) error {
+	key := string(sourceType) + "/" + string(state)
+	if _, ok := r.rules[key]; !ok {
+		return repositories.ErrNotFound
+	}
+	delete(r.rules, key)
+	return nil
+}
+
+// fakeBalanceAdjustmentRepo keeps the audit records of adjustments in memory
+type fakeBalanceAdjustmentRepo struct {
+	adjustments []*entities.BalanceAdjustment
+}
+
+func (r *fakeBalanceAdjustmentRepo) Create(ctx context.Context, adjustment *entities.BalanceAdjustment) error {
+	for _, existing := range r.adjustments {
+		if existing.AdjustmentID == adjustment.AdjustmentID {
+			return repositories.ErrConflict
+		}
+	}
+	adjustment.ID = uint64(len(r.adjustments) + 1)
+	copied := *adjustment
+	r.adjustments = append(r.adjustments, &copied)
+	return nil
+}
+
+func (r *fakeBalanceAdjustmentRepo) GetByAdjustmentID(ctx context.Context, adjustmentID string) (*entities.BalanceAdjustment, error) {
+	for _, adjustment := range r.adjustments {
+		if adjustment.AdjustmentID == adjustmentID {
+			copied := *adjustment
+			return &copied, nil
+		}
+	}
+	return nil, repositories.ErrNotFound
+}
+
+func (r *fakeBalanceAdjustmentRepo) ListByUser(ctx context.Context, userID uint64) ([]*entities.BalanceAdjustment, error) {
+	var adjustments []*entities.BalanceAdjustment
+	for i := len(r.adjustments) - 1; i >= 0; i-- {
+		if r.adjustments[i].UserID == userID {
+			adjustments = append(adjustments, r.adjustments[i])
+		}
+	}
+	return adjustments, nil
+}
+
+// fakeAuditLogRepo keeps the audit log in memory
+type fakeAuditLogRepo struct {
+	entries []*entities.AuditEntry
+}
+
+func (r *fakeAuditLogRepo) Append(ctx context.Context, entry *entities.AuditEntry) error {
+	entry.ID = uint64(len(r.entries) + 1)
+	copied := *entry
+	r.entries = append(r.entries, &copied)
+	return nil
+}
+
+func (r *fakeAuditLogRepo) List(ctx context.Context, filter repositories.AuditLogFilter) ([]*entities.AuditEntry, int, error) {
+	var matches []*entities.AuditEntry
+	for i := len(r.entries) - 1; i >= 0; i-- {
+		entry := r.entries[i]
+		if (filter.UserID == 0 || entry.UserID == filter.UserID) &&
+			(filter.From == nil || !entry.CreatedAt.Before(*filter.From)) &&
+			(filter.To == nil || entry.CreatedAt.Before(*filter.To)) {
+			matches = append(matches, entry)
+		}
+	}
+	total := len(matches)
+	return matches[min(filter.Offset, total):min(filter.Offset+filter.Limit, total)], total, nil
+}
+
+// fakeBalanceCheckRepo recomputes the balances of the fake repositories the
+// way the database does
+type fakeBalanceCheckRepo struct {
+	users        *fakeUserRepo
+	wallets      *fakeWalletRepo
+	transactions *fakeTransactionRepo
+}
+
+func (r *fakeBalanceCheckRepo) Recompute(ctx context.Context, afterID uint64, limit int) ([]repositories.BalanceRecomputation, error) {
+	r.users.mu.Lock()
+	userIDs := slices.Sorted(maps.Keys(r.users.users))
+	stored := make(map[uint64]decimal.Decimal, len(userIDs))
+	for id, user := range r.users.users {
+		stored[id] = user.Balance
+	}
+	r.users.mu.Unlock()
+
+	r.wallets.mu.Lock()
+	walletKeys := slices.Sorted(maps.Keys(r.wallets.wallets))
+	wallets := make([]entities.Wallet, 0, len(walletKeys))
+	for _, key := range walletKeys {
+		wallets = append(wallets, *r.wallets.wallets[key])
+	}
+	r.wallets.mu.Unlock()
+
+	r.transactions.mu.Lock()
+	defer r.transactions.mu.Unlock()
+	net := func(userID uint64, currency string) decimal.Decimal {
+		sum := decimal.Zero
+		for _, transaction := range r.transactions.transactions {
+			if transaction.UserID == userID && transaction.Currency == currency && !transaction.Cancelled {
+				sum = sum.Add(transaction.SignedAmount())
+			}
+		}
+		return sum
+	}
+
+	var balances []repositories.BalanceRecomputation
+	for _, userID := range userIDs {
+		if userID <= afterID {
+			continue
+		}
+		if limit == 0 {
+			break
+		}
+		limit--
+
+		balance := repositories.BalanceRecomputation{UserID: userID, Stored: stored[userID]}
+		recomputed := balance.Stored
+		balance.Recomputed = &recomputed
+		for _, transaction := range r.transactions.transactions {
+			if transaction.UserID == userID && transaction.Currency == "" {
+				balance.Recomputed = nil
+				if transaction.BalanceAfter != nil {
+					recomputed = transaction.BalanceAfter.Sub(transaction.SignedAmount()).Add(net(userID, ""))
+					balance.Recomputed = &recomputed
+				}
+				break
+			}
+		}
+		balances = append(balances, balance)
+
+		for _, wallet := range wallets {
+			if wallet.UserID == userID {
+				recomputed := net(userID, wallet.Currency)
+				balances = append(balances, repositories.BalanceRecomputation{
+					UserID: userID, Currency: wallet.Currency, Stored: wallet.Balance, Recomputed: &recomputed,
+				})
+			}
+		}
+	}
+	return balances, nil
+}
+
+// fakeSnapshotRepo is an in-memory BalanceSnapshotRepository for service
+// tests over the users userIDs
+type fakeSnapshotRepo struct {
+	mu        sync.Mutex
+	userIDs   []uint64
+	snapshots []*entities.BalanceSnapshot
+}
+
+func (r *fakeSnapshotRepo) Create(ctx context.Context, snapshot *entities.BalanceSnapshot) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.find(snapshot.UserID, snapshot.Day) == nil {
+		copied := *snapshot
+		r.snapshots = append(r.snapshots, &copied)
+	}
+	return nil
+}
+
+func (r *fakeSnapshotRepo) ListUnsnapshotted(ctx context.Context, day time.Time, limit int) ([]uint64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var userIDs []uint64
+	for _, userID := range r.userIDs {
+		if r.find(userID, day) == nil && len(userIDs) < limit {
+			userIDs = append(userIDs, userID)
+		}
+	}
+	return userIDs, nil
+}
+
+func (r *fakeSnapshotRepo) ListByUser(
+	ctx context.Context,
+	userID uint64,
+	from, to time.Time,
+) ([]*entities.BalanceSnapshot, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var snapshots []*entities.BalanceSnapshot
+	for _, snapshot := range r.snapshots {
+		if snapshot.UserID == userID && !snapshot.Day.Before(from) && snapshot.Day.Before(to) {
+			copied := *snapshot
+			snapshots = append(snapshots, &copied)
+		}
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Day.Before(snapshots[j].Day) })
+	return snapshots, nil
+}
+
+func (r *fakeSnapshotRepo) GetLatestBefore(
+	ctx context.Context,
+	userID uint64,
+	before time.Time,
+) (*entities.BalanceSnapshot, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var latest *entities.BalanceSnapshot
+	for _, snapshot := range r.snapshots {
+		if snapshot.UserID == userID && snapshot.Day.Before(before) && (latest == nil || snapshot.Day.After(latest.Day)) {
+			latest = snapshot
+		}
+	}
+	if latest == nil {
+		return nil, repositories.ErrNotFound
+	}
+	copied := *latest
+	return &copied, nil
+}
+
+func (r *fakeSnapshotRepo) find(userID uint64, day time.Time) *entities.BalanceSnapshot {
+	for _, snapshot := range r.snapshots {
+		if snapshot.UserID == userID && snapshot.Day.Equal(day) {
+			return snapshot
+		}
+	}
+	return nil
+}
+
+// ledgerFixture is a transaction service on fake repositories, with a clock
+// the tests move by setting now
+type ledgerFixture struct {
+	uow             *fakeUnitOfWork
+	userRepo        *fakeUserRepo
+	transactionRepo *fakeTransactionRepo
+	transactions    *TransactionService
+	now             time.Time
+}
+
+func newLedgerFixture(now time.Time, users []*entities.User, opts ...TransactionServiceOption) *ledgerFixture {
+	f := &ledgerFixture{
+		uow:             &fakeUnitOfWork{},
+		userRepo:        newFakeUserRepo(users...),
+		transactionRepo: newFakeTransactionRepo(),
+		now:             now,
+	}
+	opts = append(opts, WithClock(func() time.Time { return f.now }))
+	f.transactions = NewTransactionService(f.uow, f.userRepo, f.transactionRepo, opts...)
+	return f
+}
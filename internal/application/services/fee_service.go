@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrInvalidFeeRule  = errors.New("invalid fee rule")
+	ErrFeeRuleNotFound = errors.New("fee rule not found")
+)
+
+// MaxFeeTiers bounds the tiers of a tiered fee rule
+const MaxFeeTiers = 20
+
+// FeeCalculator decides the fee charged for a transaction. It runs in the
+// unit of work recording the transaction; a zero fee charges none.
+type FeeCalculator interface {
+	Fee(ctx context.Context, transaction *entities.Transaction) (decimal.Decimal, error)
+}
+
+// WithFees charges the fees decided by calculator for base currency
+// transactions. Each fee is recorded as a fee transaction linked to the
+// transaction it was charged for, in the same unit of work.
+func WithFees(calculator FeeCalculator) TransactionServiceOption {
+	return func(s *TransactionService) {
+		s.fees = calculator
+	}
+}
+
+// FeeTransactionID returns the transaction ID of the fee charged for the
+// transaction with the given key
+func FeeTransactionID(id uint64) string {
+	return "fee:" + strconv.FormatUint(id, 10)
+}
+
+// FeeService manages the fee rules and calculates the fees they charge, one
+// rule per source type and state
+type FeeService struct {
+	repo repositories.FeeRuleRepository
+	now  func() time.Time
+}
+
+// NewFeeService creates a new FeeService
+func NewFeeService(repo repositories.FeeRuleRepository) *FeeService {
+	return &FeeService{
+		repo: repo,
+		now:  time.Now,
+	}
+}
+
+// Fee returns the fee the rule of the transaction's source type and state
+// charges for it; zero when no rule is set
+func (s *FeeService) Fee(ctx context.Context, transaction *entities.Transaction) (decimal.Decimal, error) {
+	rule, err := s.repo.Get(ctx, transaction.SourceType, transaction.State)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return decimal.Zero, nil
+		}
+		return decimal.Zero, fmt.Errorf("failed to get fee rule: %w", err)
+	}
+	return rule.Fee(transaction.Amount), nil
+}
+
+// SetRule creates or replaces the fee rule of a source type and state. It
+// applies to the transactions processed from then on.
+func (s *FeeService) SetRule(
+	ctx context.Context,
+	sourceType entities.SourceType,
+	state entities.TransactionState,
+	req entities.FeeRuleRequest,
+) (*entities.FeeRule, error) {
+	if !sourceType.IsValid() {
+		return nil, ErrInvalidSourceType
+	}
+	if !state.IsValid() {
+		return nil, ErrInvalidTransactionState
+	}
+	if err := validateFeeRule(req); err != nil {
+		return nil, err
+	}
+
+	rule := &entities.FeeRule{
+		SourceType: sourceType,
+		State:      state,
+		Kind:       req.Kind,
+		Rate:       req.Rate,
+		Amount:     req.Amount,
+		Tiers:      req.Tiers,
+		UpdatedAt:  s.now().UTC(),
+	}
+	if err := s.repo.Set(ctx, rule); err != nil {
+		return nil, fmt.Errorf("failed to set fee rule: %w", err)
+	}
+	return rule, nil
+}
+
+// ListRules returns the fee rules ordered by source type and state
+func (s *FeeService) ListRules(ctx context.Context) ([]*entities.FeeRule, error) {
+	rules, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list fee rules: %w", err)
+	}
+	if rules == nil {
+		rules = []*entities.FeeRule{}
+	}
+	return rules, nil
+}
+
+// DeleteRule removes the fee rule of a source type and state, so that their
+// transactions are no longer charged a fee
+func (s *FeeService) DeleteRule(ctx context.Context, sourceType entities.SourceType, state entities.TransactionState) error {
+	if !sourceType.IsValid() {
+		return ErrInvalidSourceType
+	}
+	if !state.IsValid() {
+		return ErrInvalidTransactionState
+	}
+
+	if err := s.repo.Delete(ctx, sourceType, state); err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrFeeRuleNotFound
+		}
+		return fmt.Errorf("failed to delete fee rule: %w", err)
+	}
+	return nil
+}
+
+// validateFeeRule checks that a rule sets the fields of its kind, and only
+// those. Rates are percentages with up to 4 decimals and flat amounts have
+// up to 2.
+func validateFeeRule(req entities.FeeRuleRequest) error {
+	zero := func(values ...decimal.Decimal) bool {
+		for _, value := range values {
+			if !value.IsZero() {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch req.Kind {
+	case entities.FeeKindPercentage:
+		if !req.Rate.IsPositive() || !validFeeRate(req.Rate) || !zero(req.Amount) || len(req.Tiers) > 0 {
+			return ErrInvalidFeeRule
+		}
+
+	case entities.FeeKindFlat:
+		if !req.Amount.IsPositive() || !validFeeAmount(req.Amount) || !zero(req.Rate) || len(req.Tiers) > 0 {
+			return ErrInvalidFeeRule
+		}
+
+	case entities.FeeKindTiered:
+		if len(req.Tiers) == 0 || len(req.Tiers) > MaxFeeTiers || !zero(req.Rate, req.Amount) {
+			return ErrInvalidFeeRule
+		}
+		for i, tier := range req.Tiers {
+			if tier.From.IsNegative() || !validFeeAmount(tier.From) ||
+				tier.Rate.IsNegative() || !validFeeRate(tier.Rate) ||
+				tier.Amount.IsNegative() || !validFeeAmount(tier.Amount) {
+				return ErrInvalidFeeRule
+			}
+			if i > 0 && !tier.From.GreaterThan(req.Tiers[i-1].From) {
+				return ErrInvalidFeeRule
+			}
+		}
+
+	default:
+		return ErrInvalidFeeRule
+	}
+	return nil
+}
+
+var maxFeeRate = decimal.NewFromInt(100)
+
+func validFeeRate(rate decimal.Decimal) bool {
+	return rate.LessThanOrEqual(maxFeeRate) && rate.Exponent() >= -4
+}
+
+// maxFeeAmount is the largest amount the DECIMAL(15,2) columns hold
+var maxFeeAmount = decimal.RequireFromString("9999999999999.99")
+
+func validFeeAmount(amount decimal.Decimal) bool {
+	return amount.LessThanOrEqual(maxFeeAmount) && amount.Exponent() >= -2
+}
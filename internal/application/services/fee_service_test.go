@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeeService_SetRule(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	d := decimal.RequireFromString
+
+	t.Run("rules are stored per source type and state", func(t *testing.T) {
+		repo := newFakeFeeRuleRepo()
+		service := NewFeeService(repo)
+		service.now = func() time.Time { return now }
+
+		rule, err := service.SetRule(ctx, entities.SourceTypeGame, entities.StateWin, entities.FeeRuleRequest{
+			Kind: entities.FeeKindPercentage, Rate: d("2.5"),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, now, rule.UpdatedAt)
+
+		rules, err := service.ListRules(ctx)
+		require.NoError(t, err)
+		require.Len(t, rules, 1)
+		assert.Equal(t, entities.SourceTypeGame, rules[0].SourceType)
+		assert.Equal(t, entities.StateWin, rules[0].State)
+	})
+
+	t.Run("invalid rules are rejected", func(t *testing.T) {
+		service := NewFeeService(newFakeFeeRuleRepo())
+		tests := []struct {
+			name string
+			req  entities.FeeRuleRequest
+		}{
+			{"unknown kind", entities.FeeRuleRequest{Kind: "progressive", Rate: d("1")}},
+			{"percentage without rate", entities.FeeRuleRequest{Kind: entities.FeeKindPercentage}},
+			{"percentage above 100", entities.FeeRuleRequest{Kind: entities.FeeKindPercentage, Rate: d("100.01")}},
+			{"percentage with 5 decimals", entities.FeeRuleRequest{Kind: entities.FeeKindPercentage, Rate: d("0.00001")}},
+			{"percentage with amount", entities.FeeRuleRequest{Kind: entities.FeeKindPercentage, Rate: d("1"), Amount: d("1")}},
+			{"flat with sub-cent amount", entities.FeeRuleRequest{Kind: entities.FeeKindFlat, Amount: d("0.001")}},
+			{"negative flat", entities.FeeRuleRequest{Kind: entities.FeeKindFlat, Amount: d("-1")}},
+			{"tiered without tiers", entities.FeeRuleRequest{Kind: entities.FeeKindTiered}},
+			{"tiers out of order", entities.FeeRuleRequest{Kind: entities.FeeKindTiered, Tiers: []entities.FeeTier{
+				{From: d("100"), Rate: d("1")}, {From: d("100"), Rate: d("2")},
+			}}},
+			{"tiers with top-level rate", entities.FeeRuleRequest{Kind: entities.FeeKindTiered, Rate: d("1"), Tiers: []entities.FeeTier{
+				{From: d("0"), Rate: d("1")},
+			}}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := service.SetRule(ctx, entities.SourceTypeGame, entities.StateWin, tt.req)
+				assert.ErrorIs(t, err, ErrInvalidFeeRule)
+			})
+		}
+
+		_, err := service.SetRule(ctx, "casino", entities.StateWin, entities.FeeRuleRequest{Kind: entities.FeeKindFlat, Amount: d("1")})
+		assert.ErrorIs(t, err, ErrInvalidSourceType)
+		_, err = service.SetRule(ctx, entities.SourceTypeGame, "draw", entities.FeeRuleRequest{Kind: entities.FeeKindFlat, Amount: d("1")})
+		assert.ErrorIs(t, err, ErrInvalidTransactionState)
+	})
+
+	t.Run("deleting a missing rule fails", func(t *testing.T) {
+		service := NewFeeService(newFakeFeeRuleRepo())
+
+		err := service.DeleteRule(ctx, entities.SourceTypeGame, entities.StateWin)
+		assert.ErrorIs(t, err, ErrFeeRuleNotFound)
+	})
+}
+
+func TestTransactionService_Fees(t *testing.T) {
+	ctx := context.Background()
+
+	newService := func(balance int64) (*TransactionService, *fakeTransactionRepo) {
+		userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(balance)})
+		transactionRepo := newFakeTransactionRepo()
+		fees := NewFeeService(newFakeFeeRuleRepo(
+			&entities.FeeRule{SourceType: entities.SourceTypeGame, State: entities.StateWin, Kind: entities.FeeKindPercentage, Rate: decimal.NewFromInt(10)},
+			&entities.FeeRule{SourceType: entities.SourceTypeGame, State: entities.StateLose, Kind: entities.FeeKindFlat, Amount: decimal.NewFromInt(1)},
+		))
+		return NewTransactionService(&fakeUnitOfWork{}, userRepo, transactionRepo, WithFees(fees)), transactionRepo
+	}
+
+	t.Run("fees are recorded with the transaction", func(t *testing.T) {
+		service, transactionRepo := newService(100)
+		req := entities.TransactionRequest{State: "win", Amount: "20.00", TransactionID: "tx-1", RoundID: "round-1"}
+
+		result, err := service.ProcessTransaction(ctx, 1, req, entities.SourceTypeGame)
+		require.NoError(t, err)
+		assert.Equal(t, "118.00", result.Balance)
+		assert.Equal(t, "2.00", result.Fee)
+
+		fee, err := transactionRepo.GetByTransactionID(ctx, FeeTransactionID(1))
+		require.NoError(t, err)
+		assert.Equal(t, entities.TransactionTypeFee, fee.Type)
+		assert.Equal(t, "tx-1", fee.ChargedFor)
+		assert.Equal(t, "round-1", fee.RoundID)
+		assert.True(t, fee.Amount.Equal(decimal.NewFromInt(2)))
+
+		// Replays return the balance left after the fee
+		replayed, err := service.ProcessTransaction(ctx, 1, req, entities.SourceTypeGame)
+		require.NoError(t, err)
+		assert.True(t, replayed.Replayed)
+		assert.Equal(t, "118.00", replayed.Balance)
+		assert.Equal(t, "2.00", replayed.Fee)
+
+		summary, err := service.GetRoundSummary(ctx, 1, "round-1", "")
+		require.NoError(t, err)
+		assert.Equal(t, 1, summary.Transactions)
+		assert.Equal(t, "2.00", summary.TotalFees)
+		assert.Equal(t, "18.00", summary.Net)
+	})
+
+	t.Run("fees are capped at the available balance", func(t *testing.T) {
+		service, _ := newService(5)
+
+		result, err := service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "lose", Amount: "4.50", TransactionID: "tx-1",
+		}, entities.SourceTypeGame)
+		require.NoError(t, err)
+		assert.Equal(t, "0.00", result.Balance)
+		assert.Equal(t, "0.50", result.Fee)
+	})
+
+	t.Run("transactions without a rule are charged no fee", func(t *testing.T) {
+		service, transactionRepo := newService(100)
+
+		result, err := service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "win", Amount: "20.00", TransactionID: "tx-1",
+		}, entities.SourceTypePayment)
+		require.NoError(t, err)
+		assert.Equal(t, "120.00", result.Balance)
+		assert.Empty(t, result.Fee)
+		assert.Len(t, transactionRepo.transactions, 1)
+	})
+}
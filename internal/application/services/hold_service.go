@@ -0,0 +1,326 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrHoldNotFound      = errors.New("hold not found")
+	ErrHoldNotActive     = errors.New("hold is no longer active")
+	ErrDuplicateHold     = errors.New("hold ID already used for a different hold")
+	ErrHoldSourceType    = errors.New("holds are only available to the payment source type")
+	ErrInvalidHoldExpiry = errors.New("invalid hold expiry")
+)
+
+// HoldPolicy bounds how long holds reserve their amount
+type HoldPolicy struct {
+	// DefaultTTL applies to holds placed without an expiry
+	DefaultTTL time.Duration
+	MaxTTL     time.Duration
+}
+
+// HoldService reserves parts of user balances for payments and settles them
+// in two phases: a hold is placed first, then captured as a payment
+// transaction or released. Holds apply to the base currency only.
+type HoldService struct {
+	uow          repositories.UnitOfWork
+	userRepo     repositories.UserRepository
+	holdRepo     repositories.HoldRepository
+	transactions *TransactionService
+	policy       HoldPolicy
+}
+
+// NewHoldService creates a new HoldService. Captures are processed by
+// transactions, which must be configured WithHolds on the same repository and
+// whose clock times the holds.
+func NewHoldService(
+	uow repositories.UnitOfWork,
+	userRepo repositories.UserRepository,
+	holdRepo repositories.HoldRepository,
+	transactions *TransactionService,
+	policy HoldPolicy,
+) *HoldService {
+	return &HoldService{
+		uow:          uow,
+		userRepo:     userRepo,
+		holdRepo:     holdRepo,
+		transactions: transactions,
+		policy:       policy,
+	}
+}
+
+// CreateHold reserves an amount of the user's available balance until the
+// hold is settled or expires. Placing the same hold again returns it with
+// Replayed set, while reusing a hold ID for a different hold fails with
+// ErrDuplicateHold.
+func (s *HoldService) CreateHold(
+	ctx context.Context,
+	userID uint64,
+	req entities.HoldRequest,
+	sourceType entities.SourceType,
+) (*entities.HoldResponse, error) {
+	if sourceType != entities.SourceTypePayment {
+		return nil, ErrHoldSourceType
+	}
+
+	amount, err := parseHoldAmount(req.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := s.policy.DefaultTTL
+	if req.ExpiresIn != "" {
+		if ttl, err = time.ParseDuration(req.ExpiresIn); err != nil {
+			return nil, ErrInvalidHoldExpiry
+		}
+	}
+	if ttl <= 0 || ttl > s.policy.MaxTTL {
+		return nil, ErrInvalidHoldExpiry
+	}
+
+	var response *entities.HoldResponse
+	err = s.uow.WithinTransaction(ctx, func(ctx context.Context) error {
+		// Holds are placed under the user lock, like transactions, so the
+		// available balance cannot be reserved twice
+		user, err := s.userRepo.GetByIDForUpdate(ctx, userID)
+		if err != nil {
+			if errors.Is(err, repositories.ErrNotFound) {
+				return ErrUserNotFound
+			}
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+
+		existing, err := s.holdRepo.GetByHoldIDForUpdate(ctx, req.HoldID)
+		if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+			return fmt.Errorf("failed to check hold existence: %w", err)
+		}
+		if existing != nil {
+			if existing.UserID != userID || !existing.Amount.Equal(amount) {
+				return ErrDuplicateHold
+			}
+			response = newHoldResponse(existing)
+			response.Replayed = true
+			return nil
+		}
+
+		if user.Status == entities.UserStatusFrozen {
+			return ErrAccountFrozen
+		}
+
+		now := s.transactions.now()
+		held, err := s.holdRepo.SumActive(ctx, userID, now)
+		if err != nil {
+			return err
+		}
+		if user.Balance.Add(user.CreditLimit).Sub(held).Sub(amount).IsNegative() {
+			return ErrInsufficientFunds
+		}
+
+		hold := &entities.Hold{
+			HoldID:    req.HoldID,
+			UserID:    userID,
+			Amount:    amount,
+			Status:    entities.HoldStatusHeld,
+			CreatedAt: now,
+			ExpiresAt: now.Add(ttl),
+		}
+		if err := s.holdRepo.Create(ctx, hold); err != nil {
+			// A concurrent request of another user placed a hold with the ID
+			if errors.Is(err, repositories.ErrConflict) {
+				return ErrDuplicateHold
+			}
+			return fmt.Errorf("failed to create hold: %w", err)
+		}
+		response = newHoldResponse(hold)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// CaptureHold settles an active hold by moving the captured amount, by
+// default the full hold, as a payment transaction whose transaction ID is the
+// hold ID. Capturing a captured hold again returns it with Replayed set.
+func (s *HoldService) CaptureHold(
+	ctx context.Context,
+	userID uint64,
+	holdID string,
+	req entities.CaptureRequest,
+) (*entities.HoldResponse, error) {
+	var amount *decimal.Decimal
+	if req.Amount != "" {
+		parsed, err := parseHoldAmount(req.Amount)
+		if err != nil {
+			return nil, err
+		}
+		amount = &parsed
+	}
+
+	var response *entities.HoldResponse
+	err := s.uow.WithinTransaction(ctx, func(ctx context.Context) error {
+		// Lock the user before the hold, in the order the capture's
+		// transaction locks them
+		if _, err := s.userRepo.GetByIDForUpdate(ctx, userID); err != nil {
+			if errors.Is(err, repositories.ErrNotFound) {
+				return ErrUserNotFound
+			}
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+
+		hold, err := s.getHold(ctx, userID, holdID)
+		if err != nil {
+			return err
+		}
+		if amount != nil && amount.GreaterThan(hold.Amount) {
+			return ErrInvalidAmount
+		}
+
+		if hold.Status == entities.HoldStatusCaptured {
+			if amount != nil && !amount.Equal(*hold.CapturedAmount) {
+				return ErrHoldNotActive
+			}
+			response = newHoldResponse(hold)
+			response.Replayed = true
+			return nil
+		}
+
+		now := s.transactions.now()
+		if !hold.IsActive(now) {
+			return ErrHoldNotActive
+		}
+
+		captured := hold.Amount
+		if amount != nil {
+			captured = *amount
+		}
+
+		// Settle the hold first so that its amount is no longer reserved
+		// when the payment checks the available balance
+		if err := s.holdRepo.Settle(ctx, hold.ID, entities.HoldStatusCaptured, &captured, now); err != nil {
+			return err
+		}
+		result, err := s.transactions.ProcessTransaction(ctx, userID, entities.TransactionRequest{
+			State:         string(entities.StateLose),
+			Amount:        captured.String(),
+			TransactionID: hold.HoldID,
+		}, entities.SourceTypePayment)
+		if err != nil {
+			return err
+		}
+		// A transaction recorded earlier under the hold ID must not settle it
+		if result.Replayed {
+			return ErrDuplicateTransaction
+		}
+
+		hold.Status = entities.HoldStatusCaptured
+		hold.CapturedAmount = &captured
+		hold.SettledAt = &now
+		response = newHoldResponse(hold)
+		response.Receipt = result.Receipt
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// ReleaseHold cancels a hold, making its amount available again. Releasing a
+// released or expired hold returns it with Replayed set.
+func (s *HoldService) ReleaseHold(ctx context.Context, userID uint64, holdID string) (*entities.HoldResponse, error) {
+	var response *entities.HoldResponse
+	err := s.uow.WithinTransaction(ctx, func(ctx context.Context) error {
+		hold, err := s.getHold(ctx, userID, holdID)
+		if err != nil {
+			return err
+		}
+
+		switch hold.Status {
+		case entities.HoldStatusReleased, entities.HoldStatusExpired:
+			response = newHoldResponse(hold)
+			response.Replayed = true
+			return nil
+		case entities.HoldStatusCaptured:
+			return ErrHoldNotActive
+		}
+
+		now := s.transactions.now()
+		if err := s.holdRepo.Settle(ctx, hold.ID, entities.HoldStatusReleased, nil, now); err != nil {
+			return err
+		}
+
+		hold.Status = entities.HoldStatusReleased
+		hold.SettledAt = &now
+		response = newHoldResponse(hold)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// ExpireHolds marks up to limit holds whose expiry has passed as expired and
+// returns their hold IDs. Expired holds stop reserving their amount as soon
+// as they expire; this only records it.
+func (s *HoldService) ExpireHolds(ctx context.Context, limit int) ([]string, error) {
+	holdIDs, err := s.holdRepo.Expire(ctx, s.transactions.now(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire holds: %w", err)
+	}
+	return holdIDs, nil
+}
+
+// getHold locks the user's hold. Holds of other users are reported as not
+// found.
+func (s *HoldService) getHold(ctx context.Context, userID uint64, holdID string) (*entities.Hold, error) {
+	hold, err := s.holdRepo.GetByHoldIDForUpdate(ctx, holdID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, ErrHoldNotFound
+		}
+		return nil, fmt.Errorf("failed to get hold: %w", err)
+	}
+	if hold.UserID != userID {
+		return nil, ErrHoldNotFound
+	}
+	return hold, nil
+}
+
+// parseHoldAmount parses a positive amount with at most two decimal places
+func parseHoldAmount(value string) (decimal.Decimal, error) {
+	amount, err := decimal.NewFromString(value)
+	if err != nil || !amount.IsPositive() || !amount.Equal(amount.Round(2)) {
+		return decimal.Zero, ErrInvalidAmount
+	}
+	return amount, nil
+}
+
+func newHoldResponse(hold *entities.Hold) *entities.HoldResponse {
+	response := &entities.HoldResponse{
+		HoldID:    hold.HoldID,
+		UserID:    hold.UserID,
+		Amount:    hold.Amount.StringFixed(2),
+		Status:    hold.Status,
+		CreatedAt: hold.CreatedAt,
+		ExpiresAt: hold.ExpiresAt,
+		SettledAt: hold.SettledAt,
+	}
+	if hold.CapturedAmount != nil {
+		response.CapturedAmount = hold.CapturedAmount.StringFixed(2)
+	}
+	return response
+}
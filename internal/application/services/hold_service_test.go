@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type holdFixture struct {
+	*ledgerFixture
+	service  *HoldService
+	holdRepo *fakeHoldRepo
+}
+
+func newHoldFixture(balance int64) *holdFixture {
+	holdRepo := &fakeHoldRepo{}
+	f := &holdFixture{
+		ledgerFixture: newLedgerFixture(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+			[]*entities.User{{ID: 1, Balance: decimal.NewFromInt(balance)}}, WithHolds(holdRepo)),
+		holdRepo: holdRepo,
+	}
+	f.service = NewHoldService(f.uow, f.userRepo, f.holdRepo, f.transactions, HoldPolicy{
+		DefaultTTL: 15 * time.Minute,
+		MaxTTL:     24 * time.Hour,
+	})
+	return f
+}
+
+func (f *holdFixture) hold(t *testing.T, holdID, amount string) *entities.HoldResponse {
+	t.Helper()
+	hold, err := f.service.CreateHold(context.Background(), 1, entities.HoldRequest{
+		HoldID: holdID,
+		Amount: amount,
+	}, entities.SourceTypePayment)
+	require.NoError(t, err)
+	return hold
+}
+
+func TestHoldService_CreateHold(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		sourceType entities.SourceType
+		req        entities.HoldRequest
+		wantErr    error
+	}{
+		{name: "places the hold", sourceType: entities.SourceTypePayment, req: entities.HoldRequest{HoldID: "h-2", Amount: "40.00"}},
+		{name: "game sources cannot hold", sourceType: entities.SourceTypeGame, req: entities.HoldRequest{HoldID: "h-2", Amount: "10.00"}, wantErr: ErrHoldSourceType},
+		{name: "held amounts are not available", sourceType: entities.SourceTypePayment, req: entities.HoldRequest{HoldID: "h-2", Amount: "40.01"}, wantErr: ErrInsufficientFunds},
+		{name: "amounts must be positive", sourceType: entities.SourceTypePayment, req: entities.HoldRequest{HoldID: "h-2", Amount: "0"}, wantErr: ErrInvalidAmount},
+		{name: "expiry beyond the maximum", sourceType: entities.SourceTypePayment, req: entities.HoldRequest{HoldID: "h-2", Amount: "10.00", ExpiresIn: "48h"}, wantErr: ErrInvalidHoldExpiry},
+		{name: "malformed expiry", sourceType: entities.SourceTypePayment, req: entities.HoldRequest{HoldID: "h-2", Amount: "10.00", ExpiresIn: "soon"}, wantErr: ErrInvalidHoldExpiry},
+		{name: "hold IDs are unique", sourceType: entities.SourceTypePayment, req: entities.HoldRequest{HoldID: "h-1", Amount: "10.00"}, wantErr: ErrDuplicateHold},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newHoldFixture(100)
+			f.hold(t, "h-1", "60.00")
+
+			hold, err := f.service.CreateHold(ctx, 1, tt.req, tt.sourceType)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, entities.HoldStatusHeld, hold.Status)
+			assert.Equal(t, f.now.Add(15*time.Minute), hold.ExpiresAt)
+		})
+	}
+}
+
+func TestHoldService_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("replaying a hold returns it", func(t *testing.T) {
+		f := newHoldFixture(100)
+		f.hold(t, "h-1", "60.00")
+
+		hold := f.hold(t, "h-1", "60.00")
+		assert.True(t, hold.Replayed)
+	})
+
+	t.Run("holds reduce the available balance", func(t *testing.T) {
+		f := newHoldFixture(100)
+		f.hold(t, "h-1", "60.00")
+
+		balance, err := f.transactions.GetUserBalance(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, "100.00", balance.Balance)
+		assert.Equal(t, "40.00", balance.Available)
+
+		_, err = f.transactions.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "lose", Amount: "50.00", TransactionID: "tx-1",
+		}, entities.SourceTypeGame)
+		assert.ErrorIs(t, err, ErrInsufficientFunds)
+
+		// Credits are unaffected
+		_, err = f.transactions.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "win", Amount: "10.00", TransactionID: "tx-2",
+		}, entities.SourceTypeGame)
+		assert.NoError(t, err)
+	})
+
+	t.Run("captures move the captured amount", func(t *testing.T) {
+		f := newHoldFixture(100)
+		f.hold(t, "h-1", "60.00")
+
+		hold, err := f.service.CaptureHold(ctx, 1, "h-1", entities.CaptureRequest{Amount: "45.00"})
+		require.NoError(t, err)
+		assert.Equal(t, entities.HoldStatusCaptured, hold.Status)
+		assert.Equal(t, "45.00", hold.CapturedAmount)
+		assert.NotEmpty(t, hold.Receipt)
+
+		balance, err := f.transactions.GetUserBalance(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, "55.00", balance.Balance)
+		assert.Equal(t, "55.00", balance.Available)
+
+		hold, err = f.service.CaptureHold(ctx, 1, "h-1", entities.CaptureRequest{})
+		require.NoError(t, err)
+		assert.True(t, hold.Replayed)
+
+		_, err = f.service.ReleaseHold(ctx, 1, "h-1")
+		assert.ErrorIs(t, err, ErrHoldNotActive)
+	})
+
+	t.Run("captures cannot exceed the hold", func(t *testing.T) {
+		f := newHoldFixture(100)
+		f.hold(t, "h-1", "60.00")
+
+		_, err := f.service.CaptureHold(ctx, 1, "h-1", entities.CaptureRequest{Amount: "60.01"})
+		assert.ErrorIs(t, err, ErrInvalidAmount)
+	})
+
+	t.Run("released holds free their amount", func(t *testing.T) {
+		f := newHoldFixture(100)
+		f.hold(t, "h-1", "60.00")
+
+		hold, err := f.service.ReleaseHold(ctx, 1, "h-1")
+		require.NoError(t, err)
+		assert.Equal(t, entities.HoldStatusReleased, hold.Status)
+
+		_, err = f.service.CaptureHold(ctx, 1, "h-1", entities.CaptureRequest{})
+		assert.ErrorIs(t, err, ErrHoldNotActive)
+
+		f.hold(t, "h-2", "100.00")
+	})
+
+	t.Run("holds of other users are not found", func(t *testing.T) {
+		f := newHoldFixture(100)
+		f.hold(t, "h-1", "60.00")
+
+		_, err := f.service.ReleaseHold(ctx, 2, "h-1")
+		assert.ErrorIs(t, err, ErrHoldNotFound)
+	})
+
+	t.Run("expired holds cannot be captured", func(t *testing.T) {
+		f := newHoldFixture(100)
+		f.hold(t, "h-1", "60.00")
+		f.hold(t, "h-2", "10.00")
+
+		f.now = f.now.Add(15 * time.Minute)
+		_, err := f.service.CaptureHold(ctx, 1, "h-1", entities.CaptureRequest{})
+		assert.ErrorIs(t, err, ErrHoldNotActive)
+
+		expired, err := f.service.ExpireHolds(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"h-1"}, expired)
+
+		hold, err := f.service.ReleaseHold(ctx, 1, "h-1")
+		require.NoError(t, err)
+		assert.Equal(t, entities.HoldStatusExpired, hold.Status)
+		assert.True(t, hold.Replayed)
+	})
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"context"
+
+	"transaction-service/internal/domain/entities"
+
+	"github.com/shopspring/decimal"
+)
+
+// TransactionEvent describes a transaction being processed
+type TransactionEvent struct {
+	User        *entities.User
+	Transaction *entities.Transaction
+	NewBalance  decimal.Decimal
+}
+
+// TransactionHook extends transaction processing, e.g. loyalty accrual.
+//
+// Hooks run inside the unit of work: the context they receive carries the
+// ambient database transaction, so anything they write through it commits or
+// rolls back together with the transaction itself. Returning an error aborts
+// processing and rolls everything back.
+type TransactionHook interface {
+	// BeforeProcess runs after validation, before anything is written
+	BeforeProcess(ctx context.Context, event *TransactionEvent) error
+	// AfterProcess runs after the transaction and balance have been written
+	AfterProcess(ctx context.Context, event *TransactionEvent) error
+}
+
+// WithTransactionHooks registers hooks, run in registration order
+func WithTransactionHooks(hooks ...TransactionHook) TransactionServiceOption {
+	return func(s *TransactionService) {
+		s.hooks = append(s.hooks, hooks...)
+	}
+}
+
+func (s *TransactionService) runBeforeHooks(ctx context.Context, event *TransactionEvent) error {
+	for _, hook := range s.hooks {
+		if err := hook.BeforeProcess(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runAfterHooks runs the hooks once a transaction was written, and then
+// records it in the audit log
+func (s *TransactionService) runAfterHooks(ctx context.Context, event *TransactionEvent) error {
+	for _, hook := range s.hooks {
+		if err := hook.AfterProcess(ctx, event); err != nil {
+			return err
+		}
+	}
+	return s.recordAudit(ctx, event)
+}
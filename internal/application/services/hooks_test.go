@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"transaction-service/internal/domain/entities"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingHook struct {
+	calls     []string
+	beforeErr error
+	afterErr  error
+}
+
+func (h *recordingHook) BeforeProcess(ctx context.Context, event *TransactionEvent) error {
+	h.calls = append(h.calls, "before:"+event.Transaction.TransactionID)
+	return h.beforeErr
+}
+
+func (h *recordingHook) AfterProcess(ctx context.Context, event *TransactionEvent) error {
+	h.calls = append(h.calls, "after:"+event.NewBalance.StringFixed(2))
+	return h.afterErr
+}
+
+func TestTransactionService_Hooks(t *testing.T) {
+	ctx := context.Background()
+	req := entities.TransactionRequest{State: "win", Amount: "10.00", TransactionID: "tx-1"}
+
+	t.Run("hooks run inside the unit of work", func(t *testing.T) {
+		uow := &fakeUnitOfWork{}
+		hook := &recordingHook{}
+		userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(100)})
+		service := NewTransactionService(uow, userRepo, newFakeTransactionRepo(), WithTransactionHooks(hook))
+
+		_, err := service.ProcessTransaction(ctx, 1, req, entities.SourceTypeGame)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"before:tx-1", "after:110.00"}, hook.calls)
+		assert.Equal(t, 1, uow.commits)
+	})
+
+	t.Run("a failing hook rolls back the unit of work", func(t *testing.T) {
+		uow := &fakeUnitOfWork{}
+		hookErr := errors.New("loyalty service rejected accrual")
+		hook := &recordingHook{afterErr: hookErr}
+		userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(100)})
+		service := NewTransactionService(uow, userRepo, newFakeTransactionRepo(), WithTransactionHooks(hook))
+
+		_, err := service.ProcessTransaction(ctx, 1, req, entities.SourceTypeGame)
+		assert.ErrorIs(t, err, hookErr)
+		assert.Equal(t, 0, uow.commits)
+		assert.Equal(t, 1, uow.rollbacks)
+	})
+
+	t.Run("hooks do not run for rejected transactions", func(t *testing.T) {
+		hook := &recordingHook{}
+		userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(5)})
+		service := NewTransactionService(&fakeUnitOfWork{}, userRepo, newFakeTransactionRepo(), WithTransactionHooks(hook))
+
+		_, err := service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "lose", Amount: "10.00", TransactionID: "tx-1",
+		}, entities.SourceTypeGame)
+		assert.ErrorIs(t, err, ErrInsufficientFunds)
+		assert.Empty(t, hook.calls)
+	})
+}
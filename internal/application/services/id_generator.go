@@ -0,0 +1,16 @@
+package services
+
+// TransactionIDs are the identifiers allocated to a new transaction
+type TransactionIDs struct {
+	// Key is the surrogate key; zero leaves allocation to the database sequence
+	Key uint64
+	// Receipt is the receipt number returned to the client; empty means the
+	// decimal surrogate key is used
+	Receipt string
+}
+
+// IDGenerator allocates identifiers for new transactions. Implementations
+// must be safe for concurrent use.
+type IDGenerator interface {
+	NextIDs() (TransactionIDs, error)
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+)
+
+var ErrInvalidIngestionRange = errors.New("invalid ingestion range")
+
+const (
+	// MaxIngestionRange is the longest range verified at once
+	MaxIngestionRange = 31 * 24 * time.Hour
+
+	// maxReportedDuplicates bounds the duplicate transaction IDs reported
+	maxReportedDuplicates = 100
+	// duplicateBucketWidth is the period duplicate submissions are counted by
+	duplicateBucketWidth = time.Minute
+	// duplicateRetention is how long duplicate submissions are counted for
+	duplicateRetention = 24 * time.Hour
+)
+
+// DuplicateTracker counts the duplicate submissions of every source type per
+// minute over the last day. It implements TransactionMetrics to see every
+// outcome. Counts live in memory: they cover the transactions processed by
+// this instance only and are lost on restart.
+type DuplicateTracker struct {
+	mu      sync.Mutex
+	buckets map[duplicateBucketKey]*entities.DuplicateSubmissions
+	now     func() time.Time
+}
+
+type duplicateBucketKey struct {
+	start      time.Time
+	sourceType entities.SourceType
+}
+
+// NewDuplicateTracker creates a new DuplicateTracker
+func NewDuplicateTracker() *DuplicateTracker {
+	return &DuplicateTracker{
+		buckets: make(map[duplicateBucketKey]*entities.DuplicateSubmissions),
+		now:     time.Now,
+	}
+}
+
+// TransactionProcessed implements TransactionMetrics
+func (t *DuplicateTracker) TransactionProcessed(sourceType entities.SourceType, state entities.TransactionState) {
+}
+
+// TransactionFailed implements TransactionMetrics
+func (t *DuplicateTracker) TransactionFailed(sourceType entities.SourceType, state entities.TransactionState, reason string) {
+}
+
+// TransactionDuplicate counts a duplicate submission in the current minute
+func (t *DuplicateTracker) TransactionDuplicate(sourceType entities.SourceType, state entities.TransactionState, replayed bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	key := duplicateBucketKey{start: now.Truncate(duplicateBucketWidth).UTC(), sourceType: sourceType}
+	bucket, ok := t.buckets[key]
+	if !ok {
+		t.prune(now)
+		bucket = &entities.DuplicateSubmissions{Start: key.start, SourceType: sourceType}
+		t.buckets[key] = bucket
+	}
+	if replayed {
+		bucket.Replayed++
+	} else {
+		bucket.Rejected++
+	}
+}
+
+// prune forgets the buckets beyond the retention; t.mu must be held
+func (t *DuplicateTracker) prune(now time.Time) {
+	for key := range t.buckets {
+		if now.Sub(key.start) > duplicateRetention {
+			delete(t.buckets, key)
+		}
+	}
+}
+
+// Between returns the counts of the minutes starting in [from, to), oldest
+// first and by source type within a minute
+func (t *DuplicateTracker) Between(from, to time.Time) []entities.DuplicateSubmissions {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	buckets := []entities.DuplicateSubmissions{}
+	for key, bucket := range t.buckets {
+		if !key.start.Before(from) && key.start.Before(to) {
+			buckets = append(buckets, *bucket)
+		}
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		if !buckets[i].Start.Equal(buckets[j].Start) {
+			return buckets[i].Start.Before(buckets[j].Start)
+		}
+		return buckets[i].SourceType < buckets[j].SourceType
+	})
+	return buckets
+}
+
+// IngestionService verifies that transactions were ingested exactly once, so
+// that it can be demonstrated to partners after incidents
+type IngestionService struct {
+	transactionRepo repositories.TransactionRepository
+	tracker         *DuplicateTracker
+}
+
+// NewIngestionService creates a new IngestionService reporting the duplicate
+// submissions counted by tracker
+func NewIngestionService(transactionRepo repositories.TransactionRepository, tracker *DuplicateTracker) *IngestionService {
+	return &IngestionService{
+		transactionRepo: transactionRepo,
+		tracker:         tracker,
+	}
+}
+
+// Verify checks that no transaction created in [from, to) shares its
+// transaction ID with another transaction, and that the database enforces
+// unique transaction IDs. The report also lists the duplicate submissions
+// this instance replayed or rejected within the range.
+func (s *IngestionService) Verify(ctx context.Context, from, to time.Time) (*entities.IngestionReport, error) {
+	if !from.Before(to) || to.Sub(from) > MaxIngestionRange {
+		return nil, fmt.Errorf("%w: from must be before to, at most %s apart", ErrInvalidIngestionRange, MaxIngestionRange)
+	}
+
+	check, err := s.transactionRepo.CheckUniqueIDs(ctx, from, to, maxReportedDuplicates)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &entities.IngestionReport{
+		From:                   from,
+		To:                     to,
+		UniqueConstraint:       check.Enforced,
+		Transactions:           check.Transactions,
+		DistinctTransactionIDs: check.DistinctIDs,
+		Duplicates:             check.Duplicates,
+		Rejections:             s.tracker.Between(from, to),
+	}
+	if report.Duplicates == nil {
+		report.Duplicates = []entities.DuplicateTransactionID{}
+	}
+	report.Valid = report.UniqueConstraint && len(report.Duplicates) == 0 &&
+		report.Transactions == report.DistinctTransactionIDs
+
+	return report, nil
+}
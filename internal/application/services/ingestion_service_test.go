@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDuplicateTracker_Between(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 30, 0, time.UTC)
+	tracker := NewDuplicateTracker()
+	tracker.now = func() time.Time { return now }
+
+	tracker.TransactionDuplicate(entities.SourceTypeGame, entities.StateWin, true)
+	tracker.TransactionDuplicate(entities.SourceTypeGame, entities.StateWin, false)
+	tracker.TransactionDuplicate(entities.SourceTypePayment, entities.StateLose, true)
+	now = now.Add(time.Minute)
+	tracker.TransactionDuplicate(entities.SourceTypeGame, entities.StateWin, false)
+
+	minute := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	buckets := tracker.Between(minute, minute.Add(time.Hour))
+	assert.Equal(t, []entities.DuplicateSubmissions{
+		{Start: minute, SourceType: entities.SourceTypeGame, Replayed: 1, Rejected: 1},
+		{Start: minute, SourceType: entities.SourceTypePayment, Replayed: 1},
+		{Start: minute.Add(time.Minute), SourceType: entities.SourceTypeGame, Rejected: 1},
+	}, buckets)
+
+	assert.Len(t, tracker.Between(minute.Add(time.Minute), minute.Add(time.Hour)), 1)
+
+	// Buckets beyond the retention are forgotten
+	now = now.Add(25 * time.Hour)
+	tracker.TransactionDuplicate(entities.SourceTypeGame, entities.StateWin, true)
+	assert.Len(t, tracker.Between(minute, now.Add(time.Minute)), 1)
+}
+
+func TestIngestionService_Verify(t *testing.T) {
+	ctx := context.Background()
+	from := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+
+	transactionRepo := newFakeTransactionRepo()
+	for _, id := range []string{"tx-1", "tx-2"} {
+		require.NoError(t, transactionRepo.Create(ctx, &entities.Transaction{
+			UserID: 1, TransactionID: id, CreatedAt: from.Add(time.Hour),
+		}))
+	}
+	tracker := NewDuplicateTracker()
+	service := NewIngestionService(transactionRepo, tracker)
+
+	report, err := service.Verify(ctx, from, to)
+	require.NoError(t, err)
+	assert.True(t, report.Valid)
+	assert.True(t, report.UniqueConstraint)
+	assert.Equal(t, 2, report.Transactions)
+	assert.Equal(t, 2, report.DistinctTransactionIDs)
+	assert.Empty(t, report.Duplicates)
+	assert.NotNil(t, report.Duplicates)
+
+	t.Run("duplicated transaction IDs fail verification", func(t *testing.T) {
+		transactionRepo.transactions = append(transactionRepo.transactions, &entities.Transaction{
+			UserID: 2, TransactionID: "tx-2", CreatedAt: from.Add(2 * time.Hour),
+		})
+
+		report, err := service.Verify(ctx, from, to)
+		require.NoError(t, err)
+		assert.False(t, report.Valid)
+		assert.Equal(t, 3, report.Transactions)
+		assert.Equal(t, 2, report.DistinctTransactionIDs)
+		assert.Equal(t, []entities.DuplicateTransactionID{{TransactionID: "tx-2", Count: 2}}, report.Duplicates)
+	})
+
+	t.Run("invalid ranges are rejected", func(t *testing.T) {
+		_, err := service.Verify(ctx, to, from)
+		assert.ErrorIs(t, err, ErrInvalidIngestionRange)
+
+		_, err = service.Verify(ctx, from, from.Add(MaxIngestionRange+time.Hour))
+		assert.ErrorIs(t, err, ErrInvalidIngestionRange)
+	})
+}
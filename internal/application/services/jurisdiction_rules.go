@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrSourceTypeNotAllowed = errors.New("source type is not allowed in the user's jurisdiction")
+	ErrLossLimitExceeded    = errors.New("jurisdiction loss limit exceeded")
+)
+
+// JurisdictionRule overlays market-specific restrictions on top of the
+// global rules. A zero LossLimit disables the loss limit.
+type JurisdictionRule struct {
+	// DisabledSources lists source types that are rejected for users in the jurisdiction
+	DisabledSources []entities.SourceType
+	// LossLimit is the maximum net loss allowed within LossWindow
+	LossLimit  decimal.Decimal
+	LossWindow time.Duration
+	// DormancyFee is charged once to users flagged as dormant; zero charges
+	// no fee
+	DormancyFee decimal.Decimal
+	// FreezeDormant freezes the accounts of users flagged as dormant
+	FreezeDormant bool
+}
+
+// JurisdictionRules maps an ISO 3166-1 alpha-2 country code to its overlay
+type JurisdictionRules map[string]JurisdictionRule
+
+// WithJurisdictionRules enables per-jurisdiction overlays for users that have
+// a jurisdiction set
+func WithJurisdictionRules(rules JurisdictionRules) TransactionServiceOption {
+	return func(s *TransactionService) {
+		s.jurisdictionRules = rules
+	}
+}
+
+// checkJurisdiction enforces the overlay for the user's jurisdiction, if any,
+// against a prospective balance change
+func (s *TransactionService) checkJurisdiction(
+	ctx context.Context,
+	user *entities.User,
+	sourceType entities.SourceType,
+	currency string,
+	delta decimal.Decimal,
+	now time.Time,
+) error {
+	rule, ok := s.jurisdictionRules[user.Jurisdiction]
+	if user.Jurisdiction == "" || !ok {
+		return nil
+	}
+
+	if slices.Contains(rule.DisabledSources, sourceType) {
+		return ErrSourceTypeNotAllowed
+	}
+
+	// Only losses in the base currency count towards the loss limit
+	if rule.LossLimit.IsPositive() && delta.IsNegative() && currency == "" {
+		netChange, err := s.transactionRepo.NetChangeSince(ctx, user.ID, now.Add(-rule.LossWindow))
+		if err != nil {
+			return fmt.Errorf("failed to evaluate loss limit: %w", err)
+		}
+		if netChange.Add(delta).Neg().GreaterThan(rule.LossLimit) {
+			return ErrLossLimitExceeded
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionService_JurisdictionRules(t *testing.T) {
+	ctx := context.Background()
+	rules := JurisdictionRules{
+		"DE": {
+			DisabledSources: []entities.SourceType{entities.SourceTypePayment},
+			LossLimit:       decimal.NewFromInt(50),
+			LossWindow:      24 * time.Hour,
+		},
+	}
+
+	newService := func(jurisdiction string) *TransactionService {
+		userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(100), Jurisdiction: jurisdiction})
+		return NewTransactionService(&fakeUnitOfWork{}, userRepo, newFakeTransactionRepo(), WithJurisdictionRules(rules))
+	}
+
+	t.Run("disabled sources are rejected", func(t *testing.T) {
+		service := newService("DE")
+
+		_, err := service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "win", Amount: "10.00", TransactionID: "tx-1",
+		}, entities.SourceTypePayment)
+		assert.ErrorIs(t, err, ErrSourceTypeNotAllowed)
+	})
+
+	t.Run("losses beyond the limit are rejected", func(t *testing.T) {
+		service := newService("DE")
+
+		_, err := service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "lose", Amount: "40.00", TransactionID: "tx-1",
+		}, entities.SourceTypeGame)
+		require.NoError(t, err)
+
+		_, err = service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "lose", Amount: "20.00", TransactionID: "tx-2",
+		}, entities.SourceTypeGame)
+		assert.ErrorIs(t, err, ErrLossLimitExceeded)
+
+		// Wins are never blocked by the loss limit
+		_, err = service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "win", Amount: "20.00", TransactionID: "tx-3",
+		}, entities.SourceTypeGame)
+		require.NoError(t, err)
+	})
+
+	t.Run("wins offset earlier losses", func(t *testing.T) {
+		service := newService("DE")
+
+		for i, req := range []entities.TransactionRequest{
+			{State: "lose", Amount: "40.00", TransactionID: "tx-1"},
+			{State: "win", Amount: "30.00", TransactionID: "tx-2"},
+			{State: "lose", Amount: "30.00", TransactionID: "tx-3"},
+		} {
+			_, err := service.ProcessTransaction(ctx, 1, req, entities.SourceTypeGame)
+			require.NoError(t, err, "request %d", i)
+		}
+	})
+
+	t.Run("the loss window follows the service clock", func(t *testing.T) {
+		now := time.Now()
+		userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(100), Jurisdiction: "DE"})
+		service := NewTransactionService(&fakeUnitOfWork{}, userRepo, newFakeTransactionRepo(),
+			WithJurisdictionRules(rules), WithClock(func() time.Time { return now }))
+
+		_, err := service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "lose", Amount: "40.00", TransactionID: "tx-1",
+		}, entities.SourceTypeGame)
+		require.NoError(t, err)
+
+		// Once the clock has moved past the window the earlier loss no longer counts
+		now = now.Add(25 * time.Hour)
+		_, err = service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "lose", Amount: "40.00", TransactionID: "tx-2",
+		}, entities.SourceTypeGame)
+		require.NoError(t, err)
+
+		transactions, err := service.transactionRepo.GetByUserID(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, transactions, 2)
+		assert.True(t, now.Equal(transactions[0].CreatedAt))
+	})
+
+	t.Run("users without a configured jurisdiction are unaffected", func(t *testing.T) {
+		for _, jurisdiction := range []string{"", "FR"} {
+			service := newService(jurisdiction)
+
+			_, err := service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+				State: "lose", Amount: "90.00", TransactionID: "tx-1",
+			}, entities.SourceTypePayment)
+			assert.NoError(t, err)
+		}
+	})
+}
@@ -0,0 +1,45 @@
+package services
+
+import "errors"
+
+// ErrInvalidMetadata is returned for transaction metadata exceeding the limits
+// below or with keys other than letters, digits, '_', '-' and '.'
+var ErrInvalidMetadata = errors.New("invalid metadata")
+
+const (
+	// MaxMetadataKeys is the largest number of metadata keys of a transaction
+	MaxMetadataKeys = 20
+	// MaxMetadataKeyLength is the longest accepted metadata key
+	MaxMetadataKeyLength = 64
+	// MaxMetadataValueLength is the longest accepted metadata value
+	MaxMetadataValueLength = 255
+)
+
+// validateMetadata checks the metadata attached to a transaction
+func validateMetadata(metadata map[string]string) error {
+	if len(metadata) > MaxMetadataKeys {
+		return ErrInvalidMetadata
+	}
+	for key, value := range metadata {
+		if !ValidMetadataKey(key) || len(value) > MaxMetadataValueLength {
+			return ErrInvalidMetadata
+		}
+	}
+	return nil
+}
+
+// ValidMetadataKey reports whether key may name transaction metadata. Keys
+// are restricted so that they can be spelled in a JSON path as they are.
+func ValidMetadataKey(key string) bool {
+	if key == "" || len(key) > MaxMetadataKeyLength {
+		return false
+	}
+	for _, r := range key {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"errors"
+
+	"transaction-service/internal/domain/entities"
+)
+
+// TransactionMetrics records the outcome of every ProcessTransaction call.
+// Labels are only taken from validated values so they stay low-cardinality.
+type TransactionMetrics interface {
+	TransactionProcessed(sourceType entities.SourceType, state entities.TransactionState)
+	// TransactionDuplicate counts replays, which are served the original
+	// result, and reused transaction IDs, which are rejected
+	TransactionDuplicate(sourceType entities.SourceType, state entities.TransactionState, replayed bool)
+	TransactionFailed(sourceType entities.SourceType, state entities.TransactionState, reason string)
+}
+
+// WithMetrics records transaction outcomes in every metrics, in registration
+// order
+func WithMetrics(metrics ...TransactionMetrics) TransactionServiceOption {
+	return func(s *TransactionService) {
+		s.metrics = append(s.metrics, metrics...)
+	}
+}
+
+// recordOutcome reports the result of processing a transaction
+func (s *TransactionService) recordOutcome(
+	sourceType entities.SourceType,
+	state entities.TransactionState,
+	result *entities.TransactionResult,
+	err error,
+) {
+	if len(s.metrics) == 0 {
+		return
+	}
+
+	if !sourceType.IsValid() {
+		sourceType = "unknown"
+	}
+	if !state.IsValid() {
+		state = "unknown"
+	}
+
+	for _, metrics := range s.metrics {
+		switch {
+		case err == nil && result.Replayed:
+			metrics.TransactionDuplicate(sourceType, state, true)
+		case errors.Is(err, ErrDuplicateTransaction):
+			metrics.TransactionDuplicate(sourceType, state, false)
+		case err == nil:
+			metrics.TransactionProcessed(sourceType, state)
+		default:
+			metrics.TransactionFailed(sourceType, state, failureReason(err))
+		}
+	}
+}
+
+// failureReasons maps service errors to metric labels
+var failureReasons = []struct {
+	err    error
+	reason string
+}{
+	{ErrUserNotFound, "user_not_found"},
+	{ErrInsufficientFunds, "insufficient_funds"},
+	{ErrInvalidAmount, "invalid_amount"},
+	{ErrAmountBelowMinimum, "amount_below_minimum"},
+	{ErrAmountAboveMaximum, "amount_above_maximum"},
+	{ErrAmountPrecision, "invalid_amount_precision"},
+	{ErrInvalidTransactionState, "invalid_state"},
+	{ErrInvalidSourceType, "invalid_source_type"},
+	{ErrInvalidOccurredAt, "invalid_occurred_at"},
+	{ErrAccountFrozen, "account_frozen"},
+	{ErrBalanceChangeLimitExceeded, "balance_change_limit"},
+	{ErrSourceTypeNotAllowed, "source_type_not_allowed"},
+	{ErrLossLimitExceeded, "loss_limit"},
+	{ErrVelocityLimitExceeded, "velocity_limit"},
+	{ErrRegionStandby, "region_standby"},
+	{ErrInvalidCurrency, "invalid_currency"},
+	{ErrUnsupportedCurrency, "unsupported_currency"},
+	{ErrInvalidRoundID, "invalid_round_id"},
+	{ErrInvalidMetadata, "invalid_metadata"},
+	{ErrSystemAccount, "system_account"},
+	{ErrUnavailable, "unavailable"},
+}
+
+func failureReason(err error) string {
+	for _, r := range failureReasons {
+		if errors.Is(err, r.err) {
+			return r.reason
+		}
+	}
+	return "internal"
+}
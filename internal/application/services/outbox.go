@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/shopspring/decimal"
+)
+
+// Outbox records balance change events in the unit of work that changes the
+// balance, so an event exists exactly when its change was committed. The
+// OutboxRelay publishes them afterwards.
+type Outbox struct {
+	repo repositories.OutboxRepository
+	now  func() time.Time
+}
+
+// NewOutbox creates a new Outbox
+func NewOutbox(repo repositories.OutboxRepository) *Outbox {
+	return &Outbox{repo: repo, now: time.Now}
+}
+
+// BeforeProcess implements TransactionHook
+func (o *Outbox) BeforeProcess(ctx context.Context, event *TransactionEvent) error {
+	return nil
+}
+
+// AfterProcess records a transaction.processed event
+func (o *Outbox) AfterProcess(ctx context.Context, event *TransactionEvent) error {
+	return o.record(ctx, entities.EventTransactionProcessed, event.Transaction, event.NewBalance)
+}
+
+// RecordCancellation records a transaction.cancelled event for a transaction
+// whose reversal left the balance at balance
+func (o *Outbox) RecordCancellation(ctx context.Context, transaction *entities.Transaction, balance decimal.Decimal) error {
+	return o.record(ctx, entities.EventTransactionCancelled, transaction, balance)
+}
+
+// RecordSettlement records a transaction.settled event for a transaction
+// whose settlement left the balance at balance
+func (o *Outbox) RecordSettlement(ctx context.Context, transaction *entities.Transaction, balance decimal.Decimal) error {
+	return o.record(ctx, entities.EventTransactionSettled, transaction, balance)
+}
+
+// RecordDormancy records a user.dormant event
+func (o *Outbox) RecordDormancy(ctx context.Context, event *entities.UserDormantEvent) error {
+	return o.append(ctx, entities.EventUserDormant, event.UserID, event)
+}
+
+func (o *Outbox) record(
+	ctx context.Context,
+	eventType string,
+	transaction *entities.Transaction,
+	balance decimal.Decimal,
+) error {
+	return o.append(ctx, eventType, transaction.UserID, newBalanceChangeEvent(transaction, balance))
+}
+
+// append records an event of the user with the payload encoded as JSON
+func (o *Outbox) append(ctx context.Context, eventType string, userID uint64, payload any) error {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+
+	err = o.repo.Append(ctx, &entities.OutboxEvent{
+		Type: eventType,
+		// Events of a user keep their order on partitioned brokers
+		Key:       strconv.FormatUint(userID, 10),
+		Payload:   encoded,
+		CreatedAt: o.now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record %s event: %w", eventType, err)
+	}
+	return nil
+}
+
+// newBalanceChangeEvent describes the change of balance made by transaction
+func newBalanceChangeEvent(transaction *entities.Transaction, balance decimal.Decimal) entities.BalanceChangeEvent {
+	return entities.BalanceChangeEvent{
+		UserID:        transaction.UserID,
+		TransactionID: transaction.TransactionID,
+		Receipt:       transaction.Receipt,
+		State:         transaction.State,
+		Amount:        transaction.Amount.StringFixed(2),
+		SourceType:    transaction.SourceType,
+		Currency:      transaction.Currency,
+		Balance:       balance.StringFixed(2),
+		RoundID:       transaction.RoundID,
+		Metadata:      transaction.Metadata,
+		OccurredAt:    transaction.OccurredAt,
+		CreatedAt:     transaction.CreatedAt,
+		Pending:       transaction.Pending,
+		CancelledAt:   transaction.CancelledAt,
+		SettledAt:     transaction.SettledAt,
+		Reverses:      transaction.Reverses,
+		TransferID:    transaction.TransferID,
+	}
+}
+
+// EventPublisher publishes outbox events to a broker. Publish returns nil only
+// once the broker has acknowledged every event. On error some of the events
+// may have been published anyway; they are published again on the next
+// attempt, so consumers must deduplicate by event ID.
+type EventPublisher interface {
+	Publish(ctx context.Context, events []*entities.OutboxEvent) error
+}
+
+// OutboxRelay publishes the events recorded by the Outbox at least once and
+// in the order they were recorded
+type OutboxRelay struct {
+	uow       repositories.UnitOfWork
+	repo      repositories.OutboxRepository
+	publisher EventPublisher
+}
+
+// NewOutboxRelay creates a new OutboxRelay
+func NewOutboxRelay(
+	uow repositories.UnitOfWork,
+	repo repositories.OutboxRepository,
+	publisher EventPublisher,
+) *OutboxRelay {
+	return &OutboxRelay{
+		uow:       uow,
+		repo:      repo,
+		publisher: publisher,
+	}
+}
+
+// Relay publishes up to limit of the oldest unpublished events and marks them
+// published, returning how many were published. The events stay locked while
+// they are published, so concurrent relays never publish them out of order.
+// Events are only marked published after the broker acknowledged them; if
+// publishing or marking fails they are all published again by the next run.
+func (r *OutboxRelay) Relay(ctx context.Context, limit int) (int, error) {
+	var published int
+
+	err := r.uow.WithinTransaction(ctx, func(ctx context.Context) error {
+		events, err := r.repo.LockUnpublished(ctx, limit)
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+
+		if err := r.publisher.Publish(ctx, events); err != nil {
+			return fmt.Errorf("failed to publish events: %w", err)
+		}
+
+		ids := make([]uint64, len(events))
+		for i, event := range events {
+			ids[i] = event.ID
+		}
+		if err := r.repo.MarkPublished(ctx, ids, time.Now()); err != nil {
+			return err
+		}
+
+		published = len(events)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to relay outbox events: %w", err)
+	}
+
+	return published, nil
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingPublisher records the IDs of the events it published and fails
+// with its queued errors first
+type recordingPublisher struct {
+	published []uint64
+	errs      []error
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, events []*entities.OutboxEvent) error {
+	if len(p.errs) > 0 {
+		err := p.errs[0]
+		p.errs = p.errs[1:]
+		return err
+	}
+	for _, event := range events {
+		p.published = append(p.published, event.ID)
+	}
+	return nil
+}
+
+func decodeBalanceChange(t *testing.T, event *entities.OutboxEvent) entities.BalanceChangeEvent {
+	t.Helper()
+	var payload entities.BalanceChangeEvent
+	require.NoError(t, json.Unmarshal(event.Payload, &payload))
+	return payload
+}
+
+func TestOutbox(t *testing.T) {
+	ctx := context.Background()
+	req := entities.TransactionRequest{State: "win", Amount: "10.00", TransactionID: "tx-1"}
+
+	t.Run("processed transactions record an event", func(t *testing.T) {
+		outboxRepo := &fakeOutboxRepo{}
+		userRepo := newFakeUserRepo(&entities.User{ID: 7, Balance: decimal.NewFromInt(100)})
+		service := NewTransactionService(&fakeUnitOfWork{}, userRepo, newFakeTransactionRepo(),
+			WithTransactionHooks(NewOutbox(outboxRepo)))
+
+		_, err := service.ProcessTransaction(ctx, 7, req, entities.SourceTypeGame)
+		require.NoError(t, err)
+
+		require.Len(t, outboxRepo.events, 1)
+		event := outboxRepo.events[0]
+		assert.Equal(t, entities.EventTransactionProcessed, event.Type)
+		assert.Equal(t, "7", event.Key)
+		payload := decodeBalanceChange(t, event)
+		assert.Equal(t, "tx-1", payload.TransactionID)
+		assert.Equal(t, "10.00", payload.Amount)
+		assert.Equal(t, "110.00", payload.Balance)
+		assert.Nil(t, payload.CancelledAt)
+	})
+
+	t.Run("failing to record the event rolls back the transaction", func(t *testing.T) {
+		uow := &fakeUnitOfWork{}
+		outboxRepo := &fakeOutboxRepo{appendErr: errors.New("connection reset")}
+		userRepo := newFakeUserRepo(&entities.User{ID: 7, Balance: decimal.NewFromInt(100)})
+		service := NewTransactionService(uow, userRepo, newFakeTransactionRepo(),
+			WithTransactionHooks(NewOutbox(outboxRepo)))
+
+		_, err := service.ProcessTransaction(ctx, 7, req, entities.SourceTypeGame)
+		assert.ErrorIs(t, err, outboxRepo.appendErr)
+		assert.Equal(t, 1, uow.rollbacks)
+	})
+
+	t.Run("cancellations record an event", func(t *testing.T) {
+		outboxRepo := &fakeOutboxRepo{}
+		userRepo := newFakeUserRepo(&entities.User{ID: 7, Balance: decimal.NewFromInt(100)})
+		transactionRepo := newFakeTransactionRepo()
+		_, err := NewTransactionService(&fakeUnitOfWork{}, userRepo, transactionRepo).
+			ProcessTransaction(ctx, 7, req, entities.SourceTypeGame)
+		require.NoError(t, err)
+
+		service := NewCancellationService(&fakeUnitOfWork{}, userRepo, newFakeWalletRepo(), transactionRepo,
+			WithCancellationRecorders(NewOutbox(outboxRepo)))
+		result, err := service.CancelLatestOddTransactions(ctx, 10)
+		require.NoError(t, err)
+		require.Equal(t, []string{"tx-1"}, result.Cancelled)
+
+		require.Len(t, outboxRepo.events, 1)
+		event := outboxRepo.events[0]
+		assert.Equal(t, entities.EventTransactionCancelled, event.Type)
+		payload := decodeBalanceChange(t, event)
+		assert.Equal(t, "tx-1", payload.TransactionID)
+		assert.Equal(t, "100.00", payload.Balance)
+		assert.NotNil(t, payload.CancelledAt)
+	})
+
+	t.Run("settlements record an event", func(t *testing.T) {
+		outboxRepo := &fakeOutboxRepo{}
+		outbox := NewOutbox(outboxRepo)
+		userRepo := newFakeUserRepo(&entities.User{ID: 7, Balance: decimal.NewFromInt(100)})
+		service := NewTransactionService(&fakeUnitOfWork{}, userRepo, newFakeTransactionRepo(),
+			WithSettlement(time.Hour), WithTransactionHooks(outbox), WithSettlementRecorders(outbox))
+
+		_, err := service.ProcessTransaction(ctx, 7, req, entities.SourceTypePayment)
+		require.NoError(t, err)
+		_, err = service.SettleTransaction(ctx, "tx-1")
+		require.NoError(t, err)
+
+		require.Len(t, outboxRepo.events, 2)
+		processed := decodeBalanceChange(t, outboxRepo.events[0])
+		assert.True(t, processed.Pending)
+		assert.Equal(t, "100.00", processed.Balance)
+		assert.Nil(t, processed.SettledAt)
+
+		assert.Equal(t, entities.EventTransactionSettled, outboxRepo.events[1].Type)
+		settled := decodeBalanceChange(t, outboxRepo.events[1])
+		assert.False(t, settled.Pending)
+		assert.Equal(t, "110.00", settled.Balance)
+		assert.NotNil(t, settled.SettledAt)
+	})
+
+	t.Run("failing to record the settlement rolls it back", func(t *testing.T) {
+		uow := &fakeUnitOfWork{}
+		outboxRepo := &fakeOutboxRepo{}
+		userRepo := newFakeUserRepo(&entities.User{ID: 7, Balance: decimal.NewFromInt(100)})
+		service := NewTransactionService(uow, userRepo, newFakeTransactionRepo(),
+			WithSettlement(time.Hour), WithSettlementRecorders(NewOutbox(outboxRepo)))
+		_, err := service.ProcessTransaction(ctx, 7, req, entities.SourceTypePayment)
+		require.NoError(t, err)
+
+		outboxRepo.appendErr = errors.New("connection reset")
+		_, err = service.SettleTransaction(ctx, "tx-1")
+		assert.ErrorIs(t, err, outboxRepo.appendErr)
+		assert.Equal(t, 1, uow.rollbacks)
+	})
+}
+
+func TestOutboxRelay_Relay(t *testing.T) {
+	ctx := context.Background()
+	outboxRepo := &fakeOutboxRepo{}
+	for range 3 {
+		require.NoError(t, outboxRepo.Append(ctx, &entities.OutboxEvent{Type: entities.EventTransactionProcessed}))
+	}
+	publisher := &recordingPublisher{errs: []error{errors.New("broker unreachable")}}
+	relay := NewOutboxRelay(&fakeUnitOfWork{}, outboxRepo, publisher)
+
+	// Events stay unpublished until the broker acknowledges them
+	_, err := relay.Relay(ctx, 2)
+	require.Error(t, err)
+	for _, event := range outboxRepo.events {
+		assert.Nil(t, event.PublishedAt)
+	}
+
+	published, err := relay.Relay(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, published)
+
+	published, err = relay.Relay(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 1, published)
+
+	published, err = relay.Relay(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 0, published)
+
+	assert.Equal(t, []uint64{1, 2, 3}, publisher.published, "events are published once, in order")
+	for _, event := range outboxRepo.events {
+		assert.NotNil(t, event.PublishedAt)
+	}
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+)
+
+// RateLimitRule is a token bucket refilled at Rate tokens per second that
+// holds at most Burst tokens
+type RateLimitRule struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimitDecision is the outcome of taking a token from a bucket
+type RateLimitDecision struct {
+	Allowed bool
+	// RetryAfter is how long until a token is available when not allowed
+	RetryAfter time.Duration
+	// Remaining is the number of whole tokens left in the bucket
+	Remaining int
+	// ResetAfter is how long until the bucket is full again
+	ResetAfter time.Duration
+}
+
+// RateLimiter takes tokens from named token buckets
+type RateLimiter interface {
+	Allow(ctx context.Context, key string, rule RateLimitRule) (RateLimitDecision, error)
+}
+
+// RateLimitPolicy limits how fast transactions may be submitted per user and
+// per source type. A zero rule leaves the scope unlimited.
+type RateLimitPolicy struct {
+	PerUser   RateLimitRule
+	PerSource map[entities.SourceType]RateLimitRule
+}
+
+// IsZero reports whether the rule leaves its scope unlimited
+func (r RateLimitRule) IsZero() bool {
+	return r.Rate <= 0 || r.Burst <= 0
+}
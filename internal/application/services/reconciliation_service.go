@@ -0,0 +1,269 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrInvalidStatement           = errors.New("invalid statement")
+	ErrInvalidReconciliationRange = errors.New("invalid reconciliation range")
+)
+
+const (
+	// MaxReconciliationRange is the longest range reconciled at once
+	MaxReconciliationRange = 31 * 24 * time.Hour
+	// MaxStatementEntries is the largest number of entries in a statement
+	MaxStatementEntries = 10000
+
+	// maxReportedExtras bounds the extra transactions listed in a report
+	maxReportedExtras = 1000
+)
+
+// StatementFormat is the file format of a statement
+type StatementFormat string
+
+const (
+	// StatementFormatCSV is a header row naming the transactionId, amount
+	// and optional state columns, followed by a row per entry
+	StatementFormatCSV StatementFormat = "csv"
+	// StatementFormatJSON is an array of entries
+	StatementFormatJSON StatementFormat = "json"
+)
+
+// ParseStatement reads the entries of a statement. Entries need a
+// transaction ID, listed once, and a positive amount; a state, when listed,
+// must be win or lose.
+func ParseStatement(r io.Reader, format StatementFormat) ([]entities.StatementEntry, error) {
+	var entries []entities.StatementEntry
+	var err error
+	switch format {
+	case StatementFormatCSV:
+		entries, err = parseCSVStatement(r)
+	case StatementFormatJSON:
+		if decodeErr := json.NewDecoder(r).Decode(&entries); decodeErr != nil {
+			err = fmt.Errorf("%w: %v", ErrInvalidStatement, decodeErr)
+		}
+	default:
+		err = fmt.Errorf("%w: unsupported format %q", ErrInvalidStatement, format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(entries) > MaxStatementEntries {
+		return nil, fmt.Errorf("%w: more than %d entries", ErrInvalidStatement, MaxStatementEntries)
+	}
+	seen := make(map[string]bool, len(entries))
+	for i, entry := range entries {
+		switch {
+		case entry.TransactionID == "":
+			return nil, fmt.Errorf("%w: entry %d has no transactionId", ErrInvalidStatement, i+1)
+		case seen[entry.TransactionID]:
+			return nil, fmt.Errorf("%w: transactionId %q is listed more than once", ErrInvalidStatement, entry.TransactionID)
+		case !entry.Amount.IsPositive():
+			return nil, fmt.Errorf("%w: entry %d has no positive amount", ErrInvalidStatement, i+1)
+		case entry.State != "" && !entry.State.IsValid():
+			return nil, fmt.Errorf("%w: entry %d has an invalid state", ErrInvalidStatement, i+1)
+		}
+		seen[entry.TransactionID] = true
+	}
+
+	return entries, nil
+}
+
+// parseCSVStatement reads the entries of a CSV statement. Column names are
+// matched case-insensitively and unknown columns are ignored.
+func parseCSVStatement(r io.Reader) ([]entities.StatementEntry, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: missing header row", ErrInvalidStatement)
+	}
+	columns := map[string]int{"state": -1}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	idColumn, hasID := columns["transactionid"]
+	amountColumn, hasAmount := columns["amount"]
+	if !hasID || !hasAmount {
+		return nil, fmt.Errorf("%w: the header must name the transactionId and amount columns", ErrInvalidStatement)
+	}
+
+	var entries []entities.StatementEntry
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidStatement, err)
+		}
+		if len(entries) == MaxStatementEntries {
+			return nil, fmt.Errorf("%w: more than %d entries", ErrInvalidStatement, MaxStatementEntries)
+		}
+
+		line, _ := reader.FieldPos(0)
+		amount, err := decimal.NewFromString(record[amountColumn])
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d has an invalid amount", ErrInvalidStatement, line)
+		}
+		entry := entities.StatementEntry{
+			TransactionID: record[idColumn],
+			Amount:        amount,
+		}
+		if stateColumn := columns["state"]; stateColumn >= 0 {
+			entry.State = entities.TransactionState(strings.ToLower(record[stateColumn]))
+		}
+		entries = append(entries, entry)
+	}
+}
+
+// ReconciliationService matches the statements of source systems against the
+// ledger
+type ReconciliationService struct {
+	transactionRepo repositories.TransactionRepository
+}
+
+// NewReconciliationService creates a new ReconciliationService
+func NewReconciliationService(transactionRepo repositories.TransactionRepository) *ReconciliationService {
+	return &ReconciliationService{
+		transactionRepo: transactionRepo,
+	}
+}
+
+// Reconcile matches the statement entries of a source system against the
+// ledger by transaction ID. An entry matches when its transaction has the
+// same amount, state when listed and source type, and is not cancelled; it
+// is missing when the ledger has no transaction with its ID. The standard,
+// uncancelled transactions of the source type created in [from, to) that
+// the statement does not list are extra.
+func (s *ReconciliationService) Reconcile(
+	ctx context.Context,
+	sourceType entities.SourceType,
+	from, to time.Time,
+	entries []entities.StatementEntry,
+) (*entities.ReconciliationReport, error) {
+	if !sourceType.IsValid() {
+		return nil, ErrInvalidSourceType
+	}
+	if !from.Before(to) || to.Sub(from) > MaxReconciliationRange {
+		return nil, fmt.Errorf("%w: from must be before to, at most %s apart", ErrInvalidReconciliationRange, MaxReconciliationRange)
+	}
+
+	report := &entities.ReconciliationReport{
+		SourceType: sourceType,
+		From:       from,
+		To:         to,
+		Entries:    len(entries),
+		Missing:    []entities.StatementEntry{},
+		Mismatched: []entities.ReconciliationMismatch{},
+		Extra:      []*entities.Transaction{},
+	}
+
+	listed := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		listed[entry.TransactionID] = true
+	}
+
+	// The transactions of the range are read once, keeping the listed ones
+	ledger := make(map[string]*entities.Transaction, len(entries))
+	uncancelled := false
+	filter := repositories.TransactionFilter{
+		SourceType: sourceType,
+		From:       &from,
+		To:         &to,
+		Cancelled:  &uncancelled,
+		Limit:      MaxPageSize,
+	}
+	for {
+		transactions, _, err := s.transactionRepo.Search(ctx, filter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search transactions: %w", err)
+		}
+		for _, transaction := range transactions {
+			if transaction.Type != "" && transaction.Type != entities.TransactionTypeStandard {
+				continue
+			}
+			if listed[transaction.TransactionID] {
+				ledger[transaction.TransactionID] = transaction
+				continue
+			}
+			report.ExtraCount++
+			if len(report.Extra) < maxReportedExtras {
+				report.Extra = append(report.Extra, transaction)
+			}
+		}
+		if len(transactions) < filter.Limit {
+			break
+		}
+		last := transactions[len(transactions)-1]
+		filter.After = &repositories.TransactionCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+
+	for _, entry := range entries {
+		transaction, ok := ledger[entry.TransactionID]
+		if !ok {
+			// Listed transactions may be cancelled, of another source type
+			// or created outside the range
+			found, err := s.transactionRepo.GetByTransactionID(ctx, entry.TransactionID)
+			if errors.Is(err, repositories.ErrNotFound) {
+				report.Missing = append(report.Missing, entry)
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to get transaction: %w", err)
+			}
+			transaction = found
+		}
+
+		if differences := statementDifferences(entry, transaction, sourceType); len(differences) > 0 {
+			report.Mismatched = append(report.Mismatched, entities.ReconciliationMismatch{
+				Statement:   entry,
+				Ledger:      transaction,
+				Differences: differences,
+			})
+			continue
+		}
+		report.Matched++
+	}
+
+	report.Reconciled = len(report.Missing) == 0 && len(report.Mismatched) == 0 && report.ExtraCount == 0
+	return report, nil
+}
+
+// statementDifferences names what the ledger recorded differently from the
+// statement entry
+func statementDifferences(
+	entry entities.StatementEntry,
+	transaction *entities.Transaction,
+	sourceType entities.SourceType,
+) []string {
+	var differences []string
+	if !entry.Amount.Equal(transaction.Amount) {
+		differences = append(differences, "amount")
+	}
+	if entry.State != "" && entry.State != transaction.State {
+		differences = append(differences, "state")
+	}
+	if transaction.SourceType != sourceType {
+		differences = append(differences, "sourceType")
+	}
+	if transaction.Cancelled {
+		differences = append(differences, "cancelled")
+	}
+	return differences
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStatement(t *testing.T) {
+	t.Run("csv columns are found by name", func(t *testing.T) {
+		entries, err := ParseStatement(strings.NewReader(
+			"Amount,TransactionId,State,Note\n10.50,tx-1,WIN,first\n5,tx-2,,\n",
+		), StatementFormatCSV)
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, "tx-1", entries[0].TransactionID)
+		assert.Equal(t, "10.5", entries[0].Amount.String())
+		assert.Equal(t, entities.StateWin, entries[0].State)
+		assert.Empty(t, entries[1].State)
+	})
+
+	t.Run("json is an array of entries", func(t *testing.T) {
+		entries, err := ParseStatement(strings.NewReader(
+			`[{"transactionId": "tx-1", "amount": "10.50", "state": "lose"}, {"transactionId": "tx-2", "amount": 5}]`,
+		), StatementFormatJSON)
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, entities.StateLose, entries[0].State)
+		assert.Equal(t, "5", entries[1].Amount.String())
+	})
+
+	for name, tc := range map[string]struct {
+		format    StatementFormat
+		statement string
+	}{
+		"csv without an amount column":      {StatementFormatCSV, "transactionId\ntx-1\n"},
+		"csv with an invalid amount":        {StatementFormatCSV, "transactionId,amount\ntx-1,ten\n"},
+		"json that is not an array":         {StatementFormatJSON, `{"transactionId": "tx-1"}`},
+		"entries without an ID":             {StatementFormatJSON, `[{"amount": "1"}]`},
+		"entries without a positive amount": {StatementFormatJSON, `[{"transactionId": "tx-1", "amount": "0"}]`},
+		"entries with an invalid state":     {StatementFormatJSON, `[{"transactionId": "tx-1", "amount": "1", "state": "draw"}]`},
+		"entries listed twice":              {StatementFormatCSV, "transactionId,amount\ntx-1,1\ntx-1,1\n"},
+		"unsupported formats":               {"xml", "<statement/>"},
+	} {
+		t.Run(name+" are rejected", func(t *testing.T) {
+			_, err := ParseStatement(strings.NewReader(tc.statement), tc.format)
+			assert.ErrorIs(t, err, ErrInvalidStatement)
+		})
+	}
+}
+
+func TestReconciliationService_Reconcile(t *testing.T) {
+	ctx := context.Background()
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+
+	transactionRepo := newFakeTransactionRepo()
+	for i, transaction := range []*entities.Transaction{
+		{TransactionID: "tx-1", State: entities.StateWin, Amount: decimal.NewFromInt(10), SourceType: entities.SourceTypePayment},
+		{TransactionID: "tx-2", State: entities.StateLose, Amount: decimal.NewFromInt(20), SourceType: entities.SourceTypePayment},
+		{TransactionID: "tx-3", State: entities.StateWin, Amount: decimal.NewFromInt(30), SourceType: entities.SourceTypePayment, Cancelled: true},
+		{TransactionID: "tx-4", State: entities.StateWin, Amount: decimal.NewFromInt(40), SourceType: entities.SourceTypeGame},
+		{TransactionID: "tx-5", State: entities.StateWin, Amount: decimal.NewFromInt(50), SourceType: entities.SourceTypePayment},
+		{TransactionID: "fee:6", State: entities.StateLose, Amount: decimal.NewFromInt(1), SourceType: entities.SourceTypePayment,
+			Type: entities.TransactionTypeFee},
+	} {
+		transaction.UserID = 1
+		transaction.CreatedAt = from.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, transactionRepo.Create(ctx, transaction))
+	}
+	service := NewReconciliationService(transactionRepo)
+
+	entry := func(id, amount string, state entities.TransactionState) entities.StatementEntry {
+		return entities.StatementEntry{TransactionID: id, Amount: decimal.RequireFromString(amount), State: state}
+	}
+
+	t.Run("entries are matched, mismatched, missing or extra", func(t *testing.T) {
+		report, err := service.Reconcile(ctx, entities.SourceTypePayment, from, to, []entities.StatementEntry{
+			entry("tx-1", "10.00", entities.StateWin),
+			entry("tx-2", "25", ""),
+			entry("tx-3", "30", ""),
+			entry("tx-4", "40", entities.StateLose),
+			entry("tx-9", "90", ""),
+		})
+		require.NoError(t, err)
+		assert.False(t, report.Reconciled)
+		assert.Equal(t, 5, report.Entries)
+		assert.Equal(t, 1, report.Matched)
+
+		require.Len(t, report.Missing, 1)
+		assert.Equal(t, "tx-9", report.Missing[0].TransactionID)
+
+		differences := map[string][]string{}
+		for _, mismatch := range report.Mismatched {
+			differences[mismatch.Statement.TransactionID] = mismatch.Differences
+		}
+		assert.Equal(t, map[string][]string{
+			"tx-2": {"amount"},
+			"tx-3": {"cancelled"},
+			"tx-4": {"state", "sourceType"},
+		}, differences)
+
+		// Fees are the service's own
+		assert.Equal(t, 1, report.ExtraCount)
+		require.Len(t, report.Extra, 1)
+		assert.Equal(t, "tx-5", report.Extra[0].TransactionID)
+	})
+
+	t.Run("a complete statement reconciles", func(t *testing.T) {
+		report, err := service.Reconcile(ctx, entities.SourceTypePayment, from, to, []entities.StatementEntry{
+			entry("tx-1", "10", ""), entry("tx-2", "20", ""), entry("tx-5", "50", ""),
+		})
+		require.NoError(t, err)
+		assert.True(t, report.Reconciled)
+		assert.Equal(t, 3, report.Matched)
+	})
+
+	t.Run("ranges and source types are validated", func(t *testing.T) {
+		_, err := service.Reconcile(ctx, entities.SourceTypePayment, to, from, nil)
+		assert.ErrorIs(t, err, ErrInvalidReconciliationRange)
+
+		_, err = service.Reconcile(ctx, entities.SourceTypePayment, from, from.Add(MaxReconciliationRange+time.Hour), nil)
+		assert.ErrorIs(t, err, ErrInvalidReconciliationRange)
+
+		_, err = service.Reconcile(ctx, "casino", from, to, nil)
+		assert.ErrorIs(t, err, ErrInvalidSourceType)
+	})
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+	"transaction-service/internal/logging"
+)
+
+var ErrInvalidRejectionRange = errors.New("invalid rejection range")
+
+// MaxRejectionRange is the longest range a rejection report covers
+const MaxRejectionRange = 31 * 24 * time.Hour
+
+// RejectionRecorder records the transaction attempts ProcessTransaction
+// rejected. It runs once the attempt was rejected, outside the unit of work;
+// errors are logged rather than returned.
+type RejectionRecorder interface {
+	RecordRejection(ctx context.Context, rejection *entities.Rejection) error
+}
+
+// WithRejectionRecorders registers recorders, run in registration order for
+// every rejected transaction attempt
+func WithRejectionRecorders(recorders ...RejectionRecorder) TransactionServiceOption {
+	return func(s *TransactionService) {
+		s.rejectionRecorders = append(s.rejectionRecorders, recorders...)
+	}
+}
+
+// rejectionReason returns the reason code of a rejected attempt, and false
+// for errors that are not the client's doing, such as an unavailable
+// database or a standby region
+func rejectionReason(err error) (string, bool) {
+	if errors.Is(err, ErrDuplicateTransaction) {
+		return "duplicate_transaction_id", true
+	}
+	switch reason := failureReason(err); reason {
+	case "internal", "unavailable", "region_standby":
+		return "", false
+	default:
+		return reason, true
+	}
+}
+
+// Lengths the submitted values of a rejection are cut to
+const (
+	maxRejectedTransactionIDLength = 255
+	maxRejectedStateLength         = 20
+	maxRejectedAmountLength        = 40
+	maxRejectedCurrencyLength      = 16
+)
+
+// recordRejection tells the rejection recorders about an attempt rejected
+// with err. Recording outlives the request, which may have been cancelled.
+func (s *TransactionService) recordRejection(
+	ctx context.Context,
+	userID uint64,
+	req entities.TransactionRequest,
+	sourceType entities.SourceType,
+	err error,
+) {
+	if len(s.rejectionRecorders) == 0 || err == nil {
+		return
+	}
+	reason, ok := rejectionReason(err)
+	if !ok {
+		return
+	}
+
+	if !sourceType.IsValid() {
+		sourceType = "unknown"
+	}
+	rejection := &entities.Rejection{
+		UserID:        userID,
+		TransactionID: truncate(req.TransactionID, maxRejectedTransactionIDLength),
+		SourceType:    sourceType,
+		State:         truncate(req.State, maxRejectedStateLength),
+		Amount:        truncate(req.Amount, maxRejectedAmountLength),
+		Currency:      truncate(req.Currency, maxRejectedCurrencyLength),
+		Reason:        reason,
+		CreatedAt:     s.now(),
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	for _, recorder := range s.rejectionRecorders {
+		if err := recorder.RecordRejection(ctx, rejection); err != nil {
+			logging.FromContext(ctx, &s.logger).Warn().Err(err).
+				Uint64("user_id", userID).
+				Str("reason", reason).
+				Msg("failed to record rejected transaction")
+		}
+	}
+}
+
+// truncate cuts value to at most n bytes without splitting a character
+func truncate(value string, n int) string {
+	if len(value) <= n {
+		return value
+	}
+	for n > 0 && !utf8.RuneStart(value[n]) {
+		n--
+	}
+	return value[:n]
+}
+
+// RejectionService records rejected transaction attempts and reports the
+// rejection rates of the source types, so that integration problems surface
+// rather than being rejected silently
+type RejectionService struct {
+	rejectionRepo repositories.RejectionRepository
+}
+
+// NewRejectionService creates a new RejectionService
+func NewRejectionService(rejectionRepo repositories.RejectionRepository) *RejectionService {
+	return &RejectionService{
+		rejectionRepo: rejectionRepo,
+	}
+}
+
+// RecordRejection implements RejectionRecorder
+func (s *RejectionService) RecordRejection(ctx context.Context, rejection *entities.Rejection) error {
+	return s.rejectionRepo.Create(ctx, rejection)
+}
+
+// Report returns the rejection rate of every source type with attempts in
+// [from, to), ordered by source type
+func (s *RejectionService) Report(ctx context.Context, from, to time.Time) (*entities.RejectionReport, error) {
+	if !from.Before(to) || to.Sub(from) > MaxRejectionRange {
+		return nil, fmt.Errorf("%w: from must be before to, at most %s apart", ErrInvalidRejectionRange, MaxRejectionRange)
+	}
+
+	counts, err := s.rejectionRepo.CountByReason(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	accepted, err := s.rejectionRepo.CountAccepted(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	bySource := make(map[entities.SourceType]*entities.SourceRejections)
+	source := func(sourceType entities.SourceType) *entities.SourceRejections {
+		if _, ok := bySource[sourceType]; !ok {
+			bySource[sourceType] = &entities.SourceRejections{SourceType: sourceType, Reasons: map[string]int{}}
+		}
+		return bySource[sourceType]
+	}
+	for sourceType, count := range accepted {
+		source(sourceType).Attempts += count
+	}
+	for _, count := range counts {
+		rejections := source(count.SourceType)
+		rejections.Attempts += count.Count
+		rejections.Rejected += count.Count
+		rejections.Reasons[count.Reason] += count.Count
+	}
+
+	report := &entities.RejectionReport{From: from, To: to, Sources: []entities.SourceRejections{}}
+	for _, rejections := range bySource {
+		if rejections.Attempts > 0 {
+			rejections.RejectionRate = float64(rejections.Rejected) / float64(rejections.Attempts)
+		}
+		report.Sources = append(report.Sources, *rejections)
+	}
+	sort.Slice(report.Sources, func(i, j int) bool {
+		return report.Sources[i].SourceType < report.Sources[j].SourceType
+	})
+
+	return report, nil
+}
+
+// ListRejections returns a page of the rejections matching the filter, newest
+// first
+func (s *RejectionService) ListRejections(
+	ctx context.Context,
+	filter repositories.RejectionFilter,
+) (*entities.RejectionPage, error) {
+	if filter.Limit < 0 || filter.Offset < 0 || filter.Limit > MaxPageSize {
+		return nil, ErrInvalidFilter
+	}
+	if filter.Limit == 0 {
+		filter.Limit = DefaultPageSize
+	}
+
+	rejections, total, err := s.rejectionRepo.List(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rejections: %w", err)
+	}
+
+	return &entities.RejectionPage{
+		Rejections: rejections,
+		Total:      total,
+		Limit:      filter.Limit,
+		Offset:     filter.Offset,
+	}, nil
+}
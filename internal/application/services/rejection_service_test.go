@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRejectionService(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	newService := func() (*TransactionService, *RejectionService, *fakeRejectionRepo) {
+		userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(10)})
+		rejectionRepo := &fakeRejectionRepo{}
+		rejectionService := NewRejectionService(rejectionRepo)
+		transactionService := NewTransactionService(&fakeUnitOfWork{}, userRepo, newFakeTransactionRepo(),
+			WithClock(func() time.Time { return now }), WithRejectionRecorders(rejectionService))
+		return transactionService, rejectionService, rejectionRepo
+	}
+
+	t.Run("rejected attempts are recorded with their reason", func(t *testing.T) {
+		transactionService, _, rejectionRepo := newService()
+
+		_, err := transactionService.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "lose", Amount: "50.00", TransactionID: "tx-1",
+		}, entities.SourceTypeGame)
+		require.ErrorIs(t, err, ErrInsufficientFunds)
+		_, err = transactionService.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "win", Amount: "1.00", TransactionID: "tx-2",
+		}, entities.SourceType("casino"))
+		require.ErrorIs(t, err, ErrInvalidSourceType)
+
+		require.Len(t, rejectionRepo.rejections, 2)
+		assert.Equal(t, entities.Rejection{
+			ID: 1, UserID: 1, TransactionID: "tx-1", SourceType: entities.SourceTypeGame,
+			State: "lose", Amount: "50.00", Reason: "insufficient_funds", CreatedAt: now,
+		}, *rejectionRepo.rejections[0])
+		assert.Equal(t, entities.SourceType("unknown"), rejectionRepo.rejections[1].SourceType)
+		assert.Equal(t, "invalid_source_type", rejectionRepo.rejections[1].Reason)
+	})
+
+	t.Run("replays are not rejections but reused transaction IDs are", func(t *testing.T) {
+		transactionService, _, rejectionRepo := newService()
+		req := entities.TransactionRequest{State: "win", Amount: "1.00", TransactionID: "tx-1"}
+
+		_, err := transactionService.ProcessTransaction(ctx, 1, req, entities.SourceTypeGame)
+		require.NoError(t, err)
+		_, err = transactionService.ProcessTransaction(ctx, 1, req, entities.SourceTypeGame)
+		require.NoError(t, err)
+		assert.Empty(t, rejectionRepo.rejections)
+
+		req.Amount = "2.00"
+		_, err = transactionService.ProcessTransaction(ctx, 1, req, entities.SourceTypeGame)
+		require.ErrorIs(t, err, ErrDuplicateTransaction)
+		require.Len(t, rejectionRepo.rejections, 1)
+		assert.Equal(t, "duplicate_transaction_id", rejectionRepo.rejections[0].Reason)
+	})
+
+	t.Run("oversized values are truncated", func(t *testing.T) {
+		transactionService, _, rejectionRepo := newService()
+
+		_, err := transactionService.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: strings.Repeat("é", 15), Amount: "1.00", TransactionID: "tx-1",
+		}, entities.SourceTypeGame)
+		require.ErrorIs(t, err, ErrInvalidTransactionState)
+
+		require.Len(t, rejectionRepo.rejections, 1)
+		assert.Equal(t, strings.Repeat("é", 10), rejectionRepo.rejections[0].State)
+	})
+
+	t.Run("recording failures do not change the outcome", func(t *testing.T) {
+		transactionService, _, rejectionRepo := newService()
+		rejectionRepo.createErr = errors.New("database down")
+
+		_, err := transactionService.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "lose", Amount: "50.00", TransactionID: "tx-1",
+		}, entities.SourceTypeGame)
+		assert.ErrorIs(t, err, ErrInsufficientFunds)
+	})
+
+	t.Run("reports rejection rates per source type", func(t *testing.T) {
+		transactionService, rejectionService, rejectionRepo := newService()
+		rejectionRepo.accepted = map[entities.SourceType]int{entities.SourceTypeGame: 2, entities.SourceTypeServer: 3}
+		for _, req := range []entities.TransactionRequest{
+			{State: "lose", Amount: "50.00", TransactionID: "tx-1"},
+			{State: "lose", Amount: "-1", TransactionID: "tx-2"},
+		} {
+			_, err := transactionService.ProcessTransaction(ctx, 1, req, entities.SourceTypeGame)
+			require.Error(t, err)
+		}
+
+		report, err := rejectionService.Report(ctx, now.Add(-time.Hour), now.Add(time.Hour))
+		require.NoError(t, err)
+		require.Len(t, report.Sources, 2)
+		assert.Equal(t, entities.SourceRejections{
+			SourceType:    entities.SourceTypeGame,
+			Attempts:      4,
+			Rejected:      2,
+			RejectionRate: 0.5,
+			Reasons:       map[string]int{"insufficient_funds": 1, "invalid_amount": 1},
+		}, report.Sources[0])
+		assert.Equal(t, entities.SourceTypeServer, report.Sources[1].SourceType)
+		assert.Equal(t, 0, report.Sources[1].Rejected)
+		assert.Zero(t, report.Sources[1].RejectionRate)
+
+		page, err := rejectionService.ListRejections(ctx, repositories.RejectionFilter{Reason: "invalid_amount"})
+		require.NoError(t, err)
+		assert.Equal(t, 1, page.Total)
+		assert.Equal(t, DefaultPageSize, page.Limit)
+		require.Len(t, page.Rejections, 1)
+		assert.Equal(t, "tx-2", page.Rejections[0].TransactionID)
+
+		_, err = rejectionService.ListRejections(ctx, repositories.RejectionFilter{Limit: MaxPageSize + 1})
+		assert.ErrorIs(t, err, ErrInvalidFilter)
+	})
+
+	t.Run("invalid report ranges are rejected", func(t *testing.T) {
+		_, rejectionService, _ := newService()
+
+		_, err := rejectionService.Report(ctx, now, now)
+		assert.ErrorIs(t, err, ErrInvalidRejectionRange)
+		_, err = rejectionService.Report(ctx, now, now.Add(MaxRejectionRange+time.Second))
+		assert.ErrorIs(t, err, ErrInvalidRejectionRange)
+	})
+}
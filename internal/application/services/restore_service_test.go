@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"transaction-service/internal/domain/entities"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRestoreRepo() *fakeRestoreRepo {
+	return &fakeRestoreRepo{
+		version: 18,
+		fingerprint: entities.DatabaseFingerprint{
+			Tables: []entities.TableFingerprint{
+				{Table: "users", Rows: 3, Checksum: "1234"},
+				{Table: "transactions", Rows: 10, Checksum: "5678"},
+			},
+			Balances: entities.BalanceConsistency{LedgerMismatches: 1},
+		},
+	}
+}
+
+func TestRestoreService_CreateMarker(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRestoreRepo()
+	service := NewRestoreService(repo)
+
+	marker, err := service.CreateMarker(ctx, "nightly-2024.05.01")
+	require.NoError(t, err)
+	assert.Equal(t, 18, marker.SchemaVersion)
+	assert.Equal(t, "0/1", marker.WALPosition)
+	assert.Equal(t, repo.fingerprint, marker.Fingerprint)
+
+	_, err = service.CreateMarker(ctx, "nightly-2024.05.01")
+	assert.ErrorIs(t, err, ErrRestoreMarkerExists)
+
+	for _, name := range []string{"", "nightly 1", "../nightly", strings.Repeat("a", 256)} {
+		_, err = service.CreateMarker(ctx, name)
+		assert.ErrorIs(t, err, ErrInvalidRestoreMarker, name)
+	}
+
+	markers, err := service.ListMarkers(ctx)
+	require.NoError(t, err)
+	require.Len(t, markers, 1)
+}
+
+func TestRestoreService_Verify(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name         string
+		restore      func(repo *fakeRestoreRepo)
+		wantValid    bool
+		wantTables   []bool
+		wantBalances bool
+	}{
+		{
+			name:         "identical restore",
+			restore:      func(repo *fakeRestoreRepo) {},
+			wantValid:    true,
+			wantTables:   []bool{true, true},
+			wantBalances: true,
+		},
+		{
+			name:         "missing rows",
+			restore:      func(repo *fakeRestoreRepo) { repo.fingerprint.Tables[1].Rows = 9 },
+			wantTables:   []bool{true, false},
+			wantBalances: true,
+		},
+		{
+			name:         "changed rows",
+			restore:      func(repo *fakeRestoreRepo) { repo.fingerprint.Tables[0].Checksum = "4321" },
+			wantTables:   []bool{false, true},
+			wantBalances: true,
+		},
+		{
+			name:         "missing table",
+			restore:      func(repo *fakeRestoreRepo) { repo.fingerprint.Tables = repo.fingerprint.Tables[:1] },
+			wantTables:   []bool{true, false},
+			wantBalances: true,
+		},
+		{
+			name:       "inconsistent balances",
+			restore:    func(repo *fakeRestoreRepo) { repo.fingerprint.Balances.Negative = 1 },
+			wantTables: []bool{true, true},
+		},
+		{
+			name:    "different schema version",
+			restore: func(repo *fakeRestoreRepo) { repo.version = 17 },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newTestRestoreRepo()
+			service := NewRestoreService(repo)
+			_, err := service.CreateMarker(ctx, "backup")
+			require.NoError(t, err)
+
+			tt.restore(repo)
+			report, err := service.Verify(ctx, "backup")
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantValid, report.Valid)
+			var tables []bool
+			for _, table := range report.Tables {
+				tables = append(tables, table.Valid)
+			}
+			assert.Equal(t, tt.wantTables, tables)
+			if tt.wantTables == nil {
+				assert.False(t, report.SchemaVersion.Valid)
+				assert.Nil(t, report.Balances)
+				return
+			}
+			assert.Equal(t, tt.wantBalances, report.Balances.Valid)
+		})
+	}
+
+	_, err := NewRestoreService(newTestRestoreRepo()).Verify(ctx, "missing")
+	assert.ErrorIs(t, err, ErrRestoreMarkerNotFound)
+}
@@ -0,0 +1,424 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"transaction-service/internal/cron"
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrScheduleNotFound  = errors.New("scheduled transaction not found")
+	ErrScheduleNotActive = errors.New("scheduled transaction is no longer active")
+	ErrDuplicateSchedule = errors.New("schedule ID already used for a different scheduled transaction")
+	ErrInvalidSchedule   = errors.New("invalid schedule")
+)
+
+// MaxScheduleIDLength is the longest accepted schedule ID. It leaves room for
+// the run number the transaction IDs of the runs are suffixed with.
+const MaxScheduleIDLength = 200
+
+// ScheduleService accepts transactions processed at a future time, once or
+// on a recurring cron schedule, and runs them when they are due. Every run is
+// processed as a transaction whose ID is derived from the schedule ID and the
+// run number, so a run is applied at most once however often it is retried.
+type ScheduleService struct {
+	uow          repositories.UnitOfWork
+	userRepo     repositories.UserRepository
+	scheduleRepo repositories.ScheduledTransactionRepository
+	transactions *TransactionService
+}
+
+// NewScheduleService creates a new ScheduleService. Runs are processed by
+// transactions, whose clock also decides when schedules are due.
+func NewScheduleService(
+	uow repositories.UnitOfWork,
+	userRepo repositories.UserRepository,
+	scheduleRepo repositories.ScheduledTransactionRepository,
+	transactions *TransactionService,
+) *ScheduleService {
+	return &ScheduleService{
+		uow:          uow,
+		userRepo:     userRepo,
+		scheduleRepo: scheduleRepo,
+		transactions: transactions,
+	}
+}
+
+// CreateSchedule schedules a transaction for the user, at RunAt or on the
+// Cron schedule of the request. The transaction is validated up front, but
+// the balance is only checked by its runs. Scheduling the same transaction
+// again returns it with Replayed set, while reusing a schedule ID for a
+// different transaction fails with ErrDuplicateSchedule.
+func (s *ScheduleService) CreateSchedule(
+	ctx context.Context,
+	userID uint64,
+	req entities.ScheduleRequest,
+	sourceType entities.SourceType,
+) (*entities.ScheduledTransactionResponse, error) {
+	schedule, err := s.newSchedule(userID, req, sourceType)
+	if err != nil {
+		return nil, err
+	}
+
+	var response *entities.ScheduledTransactionResponse
+	err = s.uow.WithinTransaction(ctx, func(ctx context.Context) error {
+		// The user is not locked: runs lock the schedule before the user, so
+		// locking both here could deadlock
+		user, err := s.userRepo.GetByID(ctx, userID)
+		if err != nil {
+			if errors.Is(err, repositories.ErrNotFound) {
+				return ErrUserNotFound
+			}
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+
+		existing, err := s.scheduleRepo.GetByScheduleIDForUpdate(ctx, req.ScheduleID)
+		if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+			return fmt.Errorf("failed to check scheduled transaction existence: %w", err)
+		}
+		if existing != nil {
+			if !isScheduleReplay(existing, schedule) {
+				return ErrDuplicateSchedule
+			}
+			response = newScheduledTransactionResponse(existing)
+			response.Replayed = true
+			return nil
+		}
+
+		if user.Status == entities.UserStatusFrozen {
+			return ErrAccountFrozen
+		}
+
+		if err := s.scheduleRepo.Create(ctx, schedule); err != nil {
+			// A concurrent request scheduled a transaction with the ID
+			if errors.Is(err, repositories.ErrConflict) {
+				return ErrDuplicateSchedule
+			}
+			return fmt.Errorf("failed to create scheduled transaction: %w", err)
+		}
+		response = newScheduledTransactionResponse(schedule)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// newSchedule validates a request and returns the schedule it creates
+func (s *ScheduleService) newSchedule(
+	userID uint64,
+	req entities.ScheduleRequest,
+	sourceType entities.SourceType,
+) (*entities.ScheduledTransaction, error) {
+	if !sourceType.IsValid() {
+		return nil, ErrInvalidSourceType
+	}
+	if req.ScheduleID == "" || len(req.ScheduleID) > MaxScheduleIDLength {
+		return nil, ErrInvalidSchedule
+	}
+
+	state := entities.TransactionState(req.State)
+	if !state.IsValid() {
+		return nil, ErrInvalidTransactionState
+	}
+	amount, err := decimal.NewFromString(req.Amount)
+	// Amounts are stored with two decimal places
+	if err != nil || !amount.IsPositive() || !amount.Equal(amount.Round(2)) {
+		return nil, ErrInvalidAmount
+	}
+	if s.transactions.amountPolicy != nil {
+		if err := s.transactions.amountPolicy.Validate(amount, sourceType); err != nil {
+			return nil, err
+		}
+	}
+	currency, err := s.transactions.walletCurrency(req.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateMetadata(req.Metadata); err != nil {
+		return nil, err
+	}
+	if s.transactions.isSystemAccount(userID) {
+		return nil, ErrSystemAccount
+	}
+
+	now := s.transactions.now()
+	var nextRunAt *time.Time
+	switch {
+	case req.RunAt != nil && req.Cron == "":
+		if !req.RunAt.After(now) || req.MaxRuns != 0 {
+			return nil, ErrInvalidSchedule
+		}
+		runAt := req.RunAt.UTC()
+		nextRunAt = &runAt
+	case req.RunAt == nil && req.Cron != "":
+		expr, err := cron.Parse(req.Cron)
+		if err != nil || req.MaxRuns < 0 {
+			return nil, ErrInvalidSchedule
+		}
+		next := expr.Next(now)
+		if next.IsZero() {
+			return nil, ErrInvalidSchedule
+		}
+		nextRunAt = &next
+	default:
+		return nil, ErrInvalidSchedule
+	}
+
+	return &entities.ScheduledTransaction{
+		ScheduleID: req.ScheduleID,
+		UserID:     userID,
+		State:      state,
+		Amount:     amount,
+		SourceType: sourceType,
+		Currency:   currency,
+		Metadata:   req.Metadata,
+		Cron:       req.Cron,
+		MaxRuns:    req.MaxRuns,
+		Status:     entities.ScheduleStatusActive,
+		NextRunAt:  nextRunAt,
+		CreatedAt:  now,
+	}, nil
+}
+
+// ListSchedules returns the scheduled transactions of a user, newest first
+func (s *ScheduleService) ListSchedules(ctx context.Context, userID uint64) ([]*entities.ScheduledTransactionResponse, error) {
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	schedules, err := s.scheduleRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scheduled transactions: %w", err)
+	}
+
+	responses := make([]*entities.ScheduledTransactionResponse, 0, len(schedules))
+	for _, schedule := range schedules {
+		responses = append(responses, newScheduledTransactionResponse(schedule))
+	}
+	return responses, nil
+}
+
+// CancelSchedule stops a scheduled transaction from running again. The runs
+// already processed are kept. Cancelling a cancelled schedule returns it with
+// Replayed set.
+func (s *ScheduleService) CancelSchedule(
+	ctx context.Context,
+	userID uint64,
+	scheduleID string,
+) (*entities.ScheduledTransactionResponse, error) {
+	var response *entities.ScheduledTransactionResponse
+	err := s.uow.WithinTransaction(ctx, func(ctx context.Context) error {
+		schedule, err := s.scheduleRepo.GetByScheduleIDForUpdate(ctx, scheduleID)
+		if err != nil {
+			if errors.Is(err, repositories.ErrNotFound) {
+				return ErrScheduleNotFound
+			}
+			return fmt.Errorf("failed to get scheduled transaction: %w", err)
+		}
+		// Schedules of other users are reported as not found
+		if schedule.UserID != userID {
+			return ErrScheduleNotFound
+		}
+
+		switch schedule.Status {
+		case entities.ScheduleStatusCancelled:
+			response = newScheduledTransactionResponse(schedule)
+			response.Replayed = true
+			return nil
+		case entities.ScheduleStatusCompleted:
+			return ErrScheduleNotActive
+		}
+
+		schedule.Status = entities.ScheduleStatusCancelled
+		schedule.NextRunAt = nil
+		if err := s.scheduleRepo.Update(ctx, schedule); err != nil {
+			return fmt.Errorf("failed to cancel scheduled transaction: %w", err)
+		}
+		response = newScheduledTransactionResponse(schedule)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// RunDue runs up to limit of the scheduled transactions that are due and
+// returns their schedule IDs. Runs rejected like a request would be, e.g. for
+// insufficient funds, count as runs and are recorded on the schedule;
+// storage failures stop the batch and leave the schedule due.
+func (s *ScheduleService) RunDue(ctx context.Context, limit int) ([]string, error) {
+	now := s.transactions.now()
+	scheduleIDs, err := s.scheduleRepo.ListDue(ctx, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list due scheduled transactions: %w", err)
+	}
+
+	ran := make([]string, 0, len(scheduleIDs))
+	for _, scheduleID := range scheduleIDs {
+		done, err := s.run(ctx, scheduleID, now)
+		if err != nil {
+			return ran, fmt.Errorf("failed to run scheduled transaction %s: %w", scheduleID, err)
+		}
+		if done {
+			ran = append(ran, scheduleID)
+		}
+	}
+	return ran, nil
+}
+
+// run processes the next run of a schedule, reporting false when it was no
+// longer due, e.g. because another instance ran or cancelled it first
+func (s *ScheduleService) run(ctx context.Context, scheduleID string, now time.Time) (bool, error) {
+	due := true
+	var rejected error
+
+	err := s.uow.WithinTransaction(ctx, func(ctx context.Context) error {
+		// The schedule is locked before its user, which the transaction locks
+		schedule, err := s.lockDue(ctx, scheduleID, now)
+		if err != nil || schedule == nil {
+			due = schedule != nil
+			return err
+		}
+
+		transactionID := runTransactionID(schedule)
+		result, err := s.transactions.ProcessTransaction(ctx, schedule.UserID, entities.TransactionRequest{
+			State:         string(schedule.State),
+			Amount:        schedule.Amount.String(),
+			TransactionID: transactionID,
+			Currency:      schedule.Currency,
+			Metadata:      schedule.Metadata,
+		}, schedule.SourceType)
+		if err == nil && result.Replayed {
+			// A transaction recorded earlier under the run's ID must not
+			// stand in for it
+			err = ErrDuplicateTransaction
+		}
+		if err != nil {
+			if _, ok := rejectionReason(err); ok {
+				rejected = err
+			}
+			return err
+		}
+
+		return s.recordRun(ctx, schedule, now, transactionID, "")
+	})
+	if err == nil || rejected == nil {
+		return due, err
+	}
+
+	// The rejected run was rolled back; record it on its own, unless another
+	// instance got to the schedule in the meantime
+	reason, _ := rejectionReason(rejected)
+	err = s.uow.WithinTransaction(ctx, func(ctx context.Context) error {
+		schedule, err := s.lockDue(ctx, scheduleID, now)
+		if err != nil || schedule == nil {
+			due = schedule != nil
+			return err
+		}
+		return s.recordRun(ctx, schedule, now, "", reason)
+	})
+	return due, err
+}
+
+// lockDue locks a schedule, returning nil when it is no longer due at now
+func (s *ScheduleService) lockDue(ctx context.Context, scheduleID string, now time.Time) (*entities.ScheduledTransaction, error) {
+	schedule, err := s.scheduleRepo.GetByScheduleIDForUpdate(ctx, scheduleID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get scheduled transaction: %w", err)
+	}
+	if schedule.Status != entities.ScheduleStatusActive || schedule.NextRunAt == nil || schedule.NextRunAt.After(now) {
+		return nil, nil
+	}
+	return schedule, nil
+}
+
+// recordRun counts a run of a schedule at now and moves it to its next run.
+// Recurring schedules resume from now, so runs missed while no worker was
+// running are skipped rather than caught up on.
+func (s *ScheduleService) recordRun(
+	ctx context.Context,
+	schedule *entities.ScheduledTransaction,
+	now time.Time,
+	transactionID, lastError string,
+) error {
+	schedule.Runs++
+	schedule.LastRunAt = &now
+	schedule.LastTransactionID = transactionID
+	schedule.LastError = lastError
+	schedule.NextRunAt = nil
+	schedule.Status = entities.ScheduleStatusCompleted
+
+	if schedule.Cron != "" && (schedule.MaxRuns == 0 || schedule.Runs < schedule.MaxRuns) {
+		expr, err := cron.Parse(schedule.Cron)
+		if err != nil {
+			return fmt.Errorf("failed to parse the cron expression of %s: %w", schedule.ScheduleID, err)
+		}
+		if next := expr.Next(now); !next.IsZero() {
+			schedule.NextRunAt = &next
+			schedule.Status = entities.ScheduleStatusActive
+		}
+	}
+
+	if err := s.scheduleRepo.Update(ctx, schedule); err != nil {
+		return fmt.Errorf("failed to record scheduled transaction run: %w", err)
+	}
+	return nil
+}
+
+// runTransactionID returns the transaction ID of the next run of a schedule
+func runTransactionID(schedule *entities.ScheduledTransaction) string {
+	return schedule.ScheduleID + ":" + strconv.Itoa(schedule.Runs+1)
+}
+
+// isScheduleReplay reports whether a schedule requested again matches the
+// existing one. The first run is left out, as it moves on as the schedule
+// runs.
+func isScheduleReplay(existing, requested *entities.ScheduledTransaction) bool {
+	return existing.UserID == requested.UserID &&
+		existing.State == requested.State &&
+		existing.Amount.Equal(requested.Amount) &&
+		existing.SourceType == requested.SourceType &&
+		existing.Currency == requested.Currency &&
+		existing.Cron == requested.Cron &&
+		existing.MaxRuns == requested.MaxRuns
+}
+
+func newScheduledTransactionResponse(schedule *entities.ScheduledTransaction) *entities.ScheduledTransactionResponse {
+	return &entities.ScheduledTransactionResponse{
+		ScheduleID:        schedule.ScheduleID,
+		UserID:            schedule.UserID,
+		State:             schedule.State,
+		Amount:            schedule.Amount.StringFixed(2),
+		SourceType:        schedule.SourceType,
+		Currency:          schedule.Currency,
+		Metadata:          schedule.Metadata,
+		Cron:              schedule.Cron,
+		MaxRuns:           schedule.MaxRuns,
+		Runs:              schedule.Runs,
+		Status:            schedule.Status,
+		NextRunAt:         schedule.NextRunAt,
+		LastRunAt:         schedule.LastRunAt,
+		LastTransactionID: schedule.LastTransactionID,
+		LastError:         schedule.LastError,
+		CreatedAt:         schedule.CreatedAt,
+	}
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type scheduleFixture struct {
+	*ledgerFixture
+	service      *ScheduleService
+	scheduleRepo *fakeScheduleRepo
+}
+
+func newScheduleFixture(balance int64) *scheduleFixture {
+	f := &scheduleFixture{
+		// A Wednesday
+		ledgerFixture: newLedgerFixture(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+			[]*entities.User{{ID: 1, Balance: decimal.NewFromInt(balance)}}),
+		scheduleRepo: &fakeScheduleRepo{},
+	}
+	f.service = NewScheduleService(f.uow, f.userRepo, f.scheduleRepo, f.transactions)
+	return f
+}
+
+func (f *scheduleFixture) schedule(t *testing.T, req entities.ScheduleRequest) *entities.ScheduledTransactionResponse {
+	t.Helper()
+	schedule, err := f.service.CreateSchedule(context.Background(), 1, req, entities.SourceTypeServer)
+	require.NoError(t, err)
+	return schedule
+}
+
+func (f *scheduleFixture) balance(t *testing.T) string {
+	t.Helper()
+	user, err := f.userRepo.GetByID(context.Background(), 1)
+	require.NoError(t, err)
+	return user.Balance.StringFixed(2)
+}
+
+func TestScheduleService_CreateSchedule(t *testing.T) {
+	ctx := context.Background()
+	tomorrow := time.Date(2025, 1, 2, 12, 0, 0, 0, time.UTC)
+	past := time.Date(2024, 12, 31, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		req     entities.ScheduleRequest
+		wantErr error
+		wantRun time.Time
+	}{
+		{name: "one-off", req: entities.ScheduleRequest{ScheduleID: "s-2", State: "win", Amount: "10.00", RunAt: &tomorrow}, wantRun: tomorrow},
+		{name: "recurring", req: entities.ScheduleRequest{ScheduleID: "s-2", State: "win", Amount: "10.00", Cron: "0 9 * * 1"},
+			wantRun: time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)},
+		{name: "runAt in the past", req: entities.ScheduleRequest{ScheduleID: "s-2", State: "win", Amount: "10.00", RunAt: &past}, wantErr: ErrInvalidSchedule},
+		{name: "both runAt and cron", req: entities.ScheduleRequest{ScheduleID: "s-2", State: "win", Amount: "10.00", RunAt: &tomorrow, Cron: "* * * * *"},
+			wantErr: ErrInvalidSchedule},
+		{name: "neither runAt nor cron", req: entities.ScheduleRequest{ScheduleID: "s-2", State: "win", Amount: "10.00"}, wantErr: ErrInvalidSchedule},
+		{name: "malformed cron", req: entities.ScheduleRequest{ScheduleID: "s-2", State: "win", Amount: "10.00", Cron: "daily"}, wantErr: ErrInvalidSchedule},
+		{name: "maxRuns of a one-off", req: entities.ScheduleRequest{ScheduleID: "s-2", State: "win", Amount: "10.00", RunAt: &tomorrow, MaxRuns: 2},
+			wantErr: ErrInvalidSchedule},
+		{name: "invalid state", req: entities.ScheduleRequest{ScheduleID: "s-2", State: "draw", Amount: "10.00", RunAt: &tomorrow},
+			wantErr: ErrInvalidTransactionState},
+		{name: "three decimal places", req: entities.ScheduleRequest{ScheduleID: "s-2", State: "win", Amount: "10.001", RunAt: &tomorrow},
+			wantErr: ErrInvalidAmount},
+		{name: "schedule IDs are unique", req: entities.ScheduleRequest{ScheduleID: "s-1", State: "win", Amount: "20.00", RunAt: &tomorrow},
+			wantErr: ErrDuplicateSchedule},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newScheduleFixture(100)
+			f.schedule(t, entities.ScheduleRequest{ScheduleID: "s-1", State: "win", Amount: "10.00", RunAt: &tomorrow})
+
+			schedule, err := f.service.CreateSchedule(ctx, 1, tt.req, entities.SourceTypeServer)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, entities.ScheduleStatusActive, schedule.Status)
+			require.NotNil(t, schedule.NextRunAt)
+			assert.Equal(t, tt.wantRun, *schedule.NextRunAt)
+		})
+	}
+}
+
+func TestScheduleService_CreateSchedule_Replay(t *testing.T) {
+	f := newScheduleFixture(100)
+	req := entities.ScheduleRequest{ScheduleID: "s-1", State: "lose", Amount: "10.00", Cron: "0 * * * *"}
+	f.schedule(t, req)
+
+	replayed := f.schedule(t, req)
+	assert.True(t, replayed.Replayed)
+}
+
+func TestScheduleService_RunDue_OneOff(t *testing.T) {
+	ctx := context.Background()
+	f := newScheduleFixture(100)
+	runAt := f.now.Add(time.Hour)
+	f.schedule(t, entities.ScheduleRequest{ScheduleID: "s-1", State: "win", Amount: "25.00", RunAt: &runAt})
+
+	ran, err := f.service.RunDue(ctx, 10)
+	require.NoError(t, err)
+	assert.Empty(t, ran, "not due yet")
+
+	f.now = runAt
+	ran, err = f.service.RunDue(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"s-1"}, ran)
+	assert.Equal(t, "125.00", f.balance(t))
+
+	schedules, err := f.service.ListSchedules(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, schedules, 1)
+	assert.Equal(t, entities.ScheduleStatusCompleted, schedules[0].Status)
+	assert.Equal(t, "s-1:1", schedules[0].LastTransactionID)
+	assert.Nil(t, schedules[0].NextRunAt)
+
+	// Completed schedules never run again
+	f.now = f.now.Add(time.Hour)
+	ran, err = f.service.RunDue(ctx, 10)
+	require.NoError(t, err)
+	assert.Empty(t, ran)
+	assert.Equal(t, "125.00", f.balance(t))
+}
+
+func TestScheduleService_RunDue_Recurring(t *testing.T) {
+	ctx := context.Background()
+	f := newScheduleFixture(100)
+	f.schedule(t, entities.ScheduleRequest{ScheduleID: "s-1", State: "lose", Amount: "40.00", Cron: "0 * * * *", MaxRuns: 3})
+
+	for hour := 1; hour <= 3; hour++ {
+		f.now = time.Date(2025, 1, 1, 12+hour, 0, 0, 0, time.UTC)
+		ran, err := f.service.RunDue(ctx, 10)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"s-1"}, ran)
+	}
+
+	// The third run overdraws the balance: it is recorded as rejected and
+	// completes the schedule
+	assert.Equal(t, "20.00", f.balance(t))
+	schedules, err := f.service.ListSchedules(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, schedules, 1)
+	schedule := schedules[0]
+	assert.Equal(t, 3, schedule.Runs)
+	assert.Equal(t, entities.ScheduleStatusCompleted, schedule.Status)
+	assert.Equal(t, "insufficient_funds", schedule.LastError)
+	assert.Empty(t, schedule.LastTransactionID)
+
+	transactions, err := f.transactionRepo.GetByUserID(ctx, 1)
+	require.NoError(t, err)
+	var transactionIDs []string
+	for _, transaction := range transactions {
+		transactionIDs = append(transactionIDs, transaction.TransactionID)
+	}
+	assert.ElementsMatch(t, []string{"s-1:1", "s-1:2"}, transactionIDs)
+}
+
+func TestScheduleService_CancelSchedule(t *testing.T) {
+	ctx := context.Background()
+	f := newScheduleFixture(100)
+	f.schedule(t, entities.ScheduleRequest{ScheduleID: "s-1", State: "win", Amount: "10.00", Cron: "* * * * *"})
+
+	_, err := f.service.CancelSchedule(ctx, 2, "s-1")
+	assert.ErrorIs(t, err, ErrScheduleNotFound, "schedules of other users are not found")
+
+	cancelled, err := f.service.CancelSchedule(ctx, 1, "s-1")
+	require.NoError(t, err)
+	assert.Equal(t, entities.ScheduleStatusCancelled, cancelled.Status)
+	assert.False(t, cancelled.Replayed)
+
+	again, err := f.service.CancelSchedule(ctx, 1, "s-1")
+	require.NoError(t, err)
+	assert.True(t, again.Replayed)
+
+	f.now = f.now.Add(time.Hour)
+	ran, err := f.service.RunDue(ctx, 10)
+	require.NoError(t, err)
+	assert.Empty(t, ran)
+	assert.Equal(t, "100.00", f.balance(t))
+}
@@ -0,0 +1,304 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/shopspring/decimal"
+)
+
+// ErrNotPending is returned when settling a transaction that was not recorded
+// as pending
+var ErrNotPending = errors.New("transaction is not pending settlement")
+
+// WithSettlement records the base currency credits of payments as pending.
+// They move the balance only once settled, either on request or by SettleDue
+// once delay has passed. Payment debits are applied immediately, so that the
+// money cannot be spent twice.
+func WithSettlement(delay time.Duration) TransactionServiceOption {
+	return func(s *TransactionService) {
+		s.settlement = true
+		s.settlementDelay = delay
+	}
+}
+
+// SettlementRecorder records settlements, e.g. as events. It runs in the unit
+// of work that settles the transaction; returning an error rolls the
+// settlement back.
+type SettlementRecorder interface {
+	// RecordSettlement records the settlement of transaction, which left the
+	// balance at balance
+	RecordSettlement(ctx context.Context, transaction *entities.Transaction, balance decimal.Decimal) error
+}
+
+// WithSettlementRecorders registers recorders, run in registration order for
+// every settlement
+func WithSettlementRecorders(recorders ...SettlementRecorder) TransactionServiceOption {
+	return func(s *TransactionService) {
+		s.settlementRecorders = append(s.settlementRecorders, recorders...)
+	}
+}
+
+// awaitsSettlement reports whether a transaction moving the balance in
+// currency by delta is recorded as pending
+func (s *TransactionService) awaitsSettlement(sourceType entities.SourceType, currency string, delta decimal.Decimal) bool {
+	return s.settlement && sourceType == entities.SourceTypePayment && currency == "" && delta.IsPositive()
+}
+
+// SettleTransaction moves the amount of a pending transaction to the balance
+// of its user. Settlement is idempotent: settling a settled transaction
+// returns the current balance with Replayed set. Settlement completes what the
+// user was already credited for, so it applies to frozen accounts too.
+func (s *TransactionService) SettleTransaction(ctx context.Context, transactionID string) (*entities.TransactionResult, error) {
+	// Writes belong to the active region
+	if s.region != nil && !s.region.AcceptsWrites() {
+		return nil, ErrRegionStandby
+	}
+
+	now := s.now()
+	var newBalance decimal.Decimal
+	var result *entities.TransactionResult
+
+	err := s.uow.WithinTransaction(ctx, func(ctx context.Context) error {
+		// Lock the transaction before its user, in the same order as refunds
+		transaction, err := s.transactionRepo.GetByTransactionIDForUpdate(ctx, transactionID)
+		if err != nil {
+			if errors.Is(err, repositories.ErrNotFound) {
+				return ErrTransactionNotFound
+			}
+			return fmt.Errorf("failed to get transaction: %w", err)
+		}
+
+		if transaction.SettledAt != nil {
+			user, err := s.userRepo.GetByID(ctx, transaction.UserID)
+			if err != nil {
+				return fmt.Errorf("failed to get user: %w", err)
+			}
+			result = s.settlementResult(transaction, user.Balance)
+			result.Replayed = true
+			return nil
+		}
+		if !transaction.Pending || transaction.Cancelled {
+			return ErrNotPending
+		}
+
+		newBalance, err = s.settle(ctx, transaction, now)
+		if err != nil {
+			return err
+		}
+		result = s.settlementResult(transaction, newBalance)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !result.Replayed {
+		s.cacheBalance(ctx, result.UserID, newBalance, time.Now())
+		s.invalidateBalance(ctx, result.UserID)
+	}
+
+	return result, nil
+}
+
+// SettleDue settles up to limit of the oldest pending transactions recorded
+// longer than the settlement delay ago, returning their transaction IDs
+func (s *TransactionService) SettleDue(ctx context.Context, limit int) ([]string, error) {
+	now := s.now()
+	var settled []*entities.Transaction
+
+	err := s.uow.WithinTransaction(ctx, func(ctx context.Context) error {
+		transactions, err := s.transactionRepo.LockPendingBefore(ctx, now.Add(-s.settlementDelay), limit)
+		if err != nil {
+			return err
+		}
+		for _, transaction := range transactions {
+			if _, err := s.settle(ctx, transaction, now); err != nil {
+				return err
+			}
+		}
+		settled = transactions
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to settle transactions: %w", err)
+	}
+
+	transactionIDs := make([]string, 0, len(settled))
+	for _, transaction := range settled {
+		s.invalidateBalance(ctx, transaction.UserID)
+		transactionIDs = append(transactionIDs, transaction.TransactionID)
+	}
+	return transactionIDs, nil
+}
+
+// PreviewSettlement reports what settling up to limit of the oldest pending
+// transactions recorded longer than the settlement delay ago would do: the
+// batch SettleDue settles next, unless transactions are recorded or settled
+// meanwhile. Nothing is locked nor settled.
+func (s *TransactionService) PreviewSettlement(ctx context.Context, limit int) (*entities.SettlementPreview, error) {
+	if limit <= 0 || limit > MaxPageSize {
+		return nil, ErrInvalidFilter
+	}
+
+	// One more transaction tells whether more are due
+	before := s.now().Add(-s.settlementDelay)
+	transactions, err := s.transactionRepo.ListPendingBefore(ctx, before, limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending transactions: %w", err)
+	}
+	hasMore := len(transactions) > limit
+	transactions = transactions[:min(limit, len(transactions))]
+
+	type totals struct {
+		transactions int
+		amount, fees decimal.Decimal
+	}
+	bySourceType := make(map[entities.SourceType]*totals)
+	users := make(map[uint64]bool)
+	amount, fees, credited := decimal.Zero, decimal.Zero, decimal.Zero
+	for _, transaction := range transactions {
+		sourceTotals, ok := bySourceType[transaction.SourceType]
+		if !ok {
+			sourceTotals = &totals{amount: decimal.Zero, fees: decimal.Zero}
+			bySourceType[transaction.SourceType] = sourceTotals
+		}
+		sourceTotals.transactions++
+		sourceTotals.amount = sourceTotals.amount.Add(transaction.Amount)
+		amount = amount.Add(transaction.Amount)
+		users[transaction.UserID] = true
+
+		fee, err := s.batchFee(ctx, transaction)
+		if err != nil {
+			return nil, err
+		}
+		if fee == nil {
+			continue
+		}
+		sourceTotals.fees = sourceTotals.fees.Add(fee.Amount)
+		fees = fees.Add(fee.Amount)
+
+		// The house is credited with the fee by its contra leg
+		contra, err := s.transactionRepo.GetByTransactionID(ctx, ContraTransactionID(fee.TransactionID))
+		if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+			return nil, fmt.Errorf("failed to get fee contra leg: %w", err)
+		}
+		if contra != nil && !contra.Cancelled {
+			credited = credited.Add(contra.Amount)
+		}
+	}
+
+	preview := &entities.SettlementPreview{
+		Before:       before,
+		Transactions: len(transactions),
+		Users:        len(users),
+		Amount:       amount.StringFixed(2),
+		Fees:         fees.StringFixed(2),
+		Net:          amount.Sub(fees).StringFixed(2),
+		BySourceType: make([]entities.SettlementTotals, 0, len(bySourceType)),
+		HasMore:      hasMore,
+	}
+	for sourceType, sourceTotals := range bySourceType {
+		preview.BySourceType = append(preview.BySourceType, entities.SettlementTotals{
+			SourceType:   sourceType,
+			Transactions: sourceTotals.transactions,
+			Amount:       sourceTotals.amount.StringFixed(2),
+			Fees:         sourceTotals.fees.StringFixed(2),
+		})
+	}
+	slices.SortFunc(preview.BySourceType, func(a, b entities.SettlementTotals) int {
+		return cmp.Compare(a.SourceType, b.SourceType)
+	})
+
+	if houseID, ok := s.systemAccount(entities.SystemAccountHouse); ok {
+		house, err := s.userRepo.GetByID(ctx, houseID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get house account: %w", err)
+		}
+		preview.House = &entities.HouseMovement{
+			UserID:  houseID,
+			Amount:  credited.StringFixed(2),
+			Balance: house.Balance.StringFixed(2),
+		}
+	}
+
+	return preview, nil
+}
+
+// batchFee returns the uncancelled fee charged for a pending transaction, if
+// any
+func (s *TransactionService) batchFee(ctx context.Context, transaction *entities.Transaction) (*entities.Transaction, error) {
+	fee, err := s.transactionRepo.GetByTransactionID(ctx, FeeTransactionID(transaction.ID))
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get fee: %w", err)
+	}
+	if fee.ChargedFor != transaction.TransactionID || fee.Cancelled {
+		return nil, nil
+	}
+	return fee, nil
+}
+
+// settle credits the amount of a locked pending transaction to the balance of
+// its user and returns the new balance
+func (s *TransactionService) settle(ctx context.Context, transaction *entities.Transaction, now time.Time) (decimal.Decimal, error) {
+	user, err := s.userRepo.GetByIDForUpdate(ctx, transaction.UserID)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("failed to get user %d: %w", transaction.UserID, err)
+	}
+	newBalance := user.Balance.Add(transaction.Amount)
+
+	if err := s.transactionRepo.MarkSettled(ctx, transaction.ID, now); err != nil {
+		return decimal.Zero, fmt.Errorf("failed to settle transaction: %w", err)
+	}
+	if err := s.userRepo.UpdateBalance(ctx, user.ID, newBalance); err != nil {
+		return decimal.Zero, fmt.Errorf("failed to update user balance: %w", err)
+	}
+	transaction.Pending = false
+	transaction.SettledAt = &now
+
+	for _, recorder := range s.settlementRecorders {
+		if err := recorder.RecordSettlement(ctx, transaction, newBalance); err != nil {
+			return decimal.Zero, err
+		}
+	}
+	if s.audit != nil {
+		err := s.audit.RecordAudit(ctx, &entities.AuditEntry{
+			UserID:        user.ID,
+			Operation:     entities.AuditOperationSettlement,
+			TransactionID: transaction.TransactionID,
+			Change:        transaction.Amount,
+			BalanceBefore: user.Balance,
+			BalanceAfter:  newBalance,
+			Actor:         auditActor(ctx),
+			CreatedAt:     now,
+		})
+		if err != nil {
+			return decimal.Zero, err
+		}
+	}
+
+	return newBalance, nil
+}
+
+// settlementResult describes a settled transaction with the balance of its
+// user
+func (s *TransactionService) settlementResult(transaction *entities.Transaction, balance decimal.Decimal) *entities.TransactionResult {
+	return &entities.TransactionResult{
+		UserID:        transaction.UserID,
+		ID:            transaction.ID,
+		TransactionID: transaction.TransactionID,
+		Receipt:       transaction.Receipt,
+		Balance:       balance.StringFixed(2),
+		Currency:      s.currencyCode(transaction.Currency),
+	}
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionService_Settlement(t *testing.T) {
+	ctx := context.Background()
+
+	type fixture struct {
+		service   *TransactionService
+		userRepo  *fakeUserRepo
+		auditRepo *fakeAuditLogRepo
+		now       *time.Time
+	}
+	newFixture := func() fixture {
+		now := time.Now()
+		userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(100)})
+		auditRepo := &fakeAuditLogRepo{}
+		service := NewTransactionService(&fakeUnitOfWork{}, userRepo, newFakeTransactionRepo(),
+			WithSettlement(time.Hour), WithAuditRecorder(NewAuditService(auditRepo)),
+			WithClock(func() time.Time { return now }))
+		return fixture{service: service, userRepo: userRepo, auditRepo: auditRepo, now: &now}
+	}
+	deposit := func(t *testing.T, f fixture, transactionID string) *entities.TransactionResult {
+		result, err := f.service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "win", Amount: "25.00", TransactionID: transactionID,
+		}, entities.SourceTypePayment)
+		require.NoError(t, err)
+		return result
+	}
+
+	t.Run("payment credits are pending until settled", func(t *testing.T) {
+		f := newFixture()
+
+		result := deposit(t, f, "deposit-1")
+		assert.True(t, result.Pending)
+		assert.Equal(t, "100.00", result.Balance)
+
+		balance, err := f.service.GetUserBalance(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, "100.00", balance.Balance)
+		assert.Equal(t, "25.00", balance.Pending)
+		assert.True(t, f.auditRepo.entries[0].Change.IsZero())
+
+		settled, err := f.service.SettleTransaction(ctx, "deposit-1")
+		require.NoError(t, err)
+		assert.Equal(t, "125.00", settled.Balance)
+		assert.False(t, settled.Replayed)
+
+		balance, err = f.service.GetUserBalance(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, "125.00", balance.Balance)
+		assert.Equal(t, "0.00", balance.Pending)
+
+		entry := f.auditRepo.entries[len(f.auditRepo.entries)-1]
+		assert.Equal(t, entities.AuditOperationSettlement, entry.Operation)
+		assert.Equal(t, "25", entry.Change.String())
+		assert.Equal(t, "125", entry.BalanceAfter.String())
+	})
+
+	t.Run("settling twice is replayed", func(t *testing.T) {
+		f := newFixture()
+		deposit(t, f, "deposit-1")
+
+		_, err := f.service.SettleTransaction(ctx, "deposit-1")
+		require.NoError(t, err)
+		replayed, err := f.service.SettleTransaction(ctx, "deposit-1")
+		require.NoError(t, err)
+		assert.True(t, replayed.Replayed)
+		assert.Equal(t, "125.00", replayed.Balance)
+		assert.Equal(t, "125", f.userRepo.users[1].Balance.String())
+	})
+
+	t.Run("other transactions are applied immediately and cannot be settled", func(t *testing.T) {
+		f := newFixture()
+
+		result, err := f.service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "lose", Amount: "10.00", TransactionID: "withdrawal-1",
+		}, entities.SourceTypePayment)
+		require.NoError(t, err)
+		assert.False(t, result.Pending)
+		assert.Equal(t, "90.00", result.Balance)
+
+		_, err = f.service.SettleTransaction(ctx, "withdrawal-1")
+		assert.ErrorIs(t, err, ErrNotPending)
+		_, err = f.service.SettleTransaction(ctx, "missing")
+		assert.ErrorIs(t, err, ErrTransactionNotFound)
+	})
+
+	t.Run("pending transactions cannot be refunded", func(t *testing.T) {
+		f := newFixture()
+		deposit(t, f, "deposit-1")
+
+		_, err := f.service.RefundTransaction(ctx, "deposit-1")
+		assert.ErrorIs(t, err, ErrNotRefundable)
+	})
+
+	t.Run("due transactions are settled after the delay", func(t *testing.T) {
+		f := newFixture()
+		deposit(t, f, "deposit-1")
+
+		settled, err := f.service.SettleDue(ctx, 10)
+		require.NoError(t, err)
+		assert.Empty(t, settled)
+
+		*f.now = f.now.Add(2 * time.Hour)
+		deposit(t, f, "deposit-2")
+		settled, err = f.service.SettleDue(ctx, 10)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"deposit-1"}, settled)
+		assert.Equal(t, "125", f.userRepo.users[1].Balance.String())
+	})
+}
+
+func TestTransactionService_PreviewSettlement(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	userRepo := newSystemAccountUserRepo(
+		&entities.User{ID: 1, Balance: decimal.NewFromInt(100)},
+		&entities.User{ID: 2, Balance: decimal.NewFromInt(50)},
+	)
+	fees := NewFeeService(newFakeFeeRuleRepo(&entities.FeeRule{
+		SourceType: entities.SourceTypePayment, State: entities.StateWin, Kind: entities.FeeKindFlat, Amount: decimal.NewFromInt(1),
+	}))
+	service := NewTransactionService(&fakeUnitOfWork{}, userRepo, newFakeTransactionRepo(),
+		WithSettlement(time.Hour), WithFees(fees), WithSystemAccounts(testSystemAccounts),
+		WithClock(func() time.Time { return now }))
+	deposit := func(userID uint64, amount, transactionID string) {
+		_, err := service.ProcessTransaction(ctx, userID, entities.TransactionRequest{
+			State: "win", Amount: amount, TransactionID: transactionID,
+		}, entities.SourceTypePayment)
+		require.NoError(t, err)
+	}
+
+	deposit(1, "25.00", "deposit-1")
+	deposit(2, "40.00", "deposit-2")
+	now = now.Add(2 * time.Hour)
+	deposit(1, "10.00", "deposit-3")
+
+	preview, err := service.PreviewSettlement(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(-time.Hour), preview.Before)
+	assert.Equal(t, 2, preview.Transactions)
+	assert.Equal(t, 2, preview.Users)
+	assert.Equal(t, "65.00", preview.Amount)
+	assert.Equal(t, "2.00", preview.Fees)
+	assert.Equal(t, "63.00", preview.Net)
+	assert.Equal(t, []entities.SettlementTotals{
+		{SourceType: entities.SourceTypePayment, Transactions: 2, Amount: "65.00", Fees: "2.00"},
+	}, preview.BySourceType)
+	assert.Equal(t, &entities.HouseMovement{UserID: 101, Amount: "2.00", Balance: "3.00"}, preview.House)
+	assert.False(t, preview.HasMore)
+
+	t.Run("the batch is limited", func(t *testing.T) {
+		preview, err := service.PreviewSettlement(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 1, preview.Transactions)
+		assert.Equal(t, "25.00", preview.Amount)
+		assert.True(t, preview.HasMore)
+
+		_, err = service.PreviewSettlement(ctx, 0)
+		assert.ErrorIs(t, err, ErrInvalidFilter)
+		_, err = service.PreviewSettlement(ctx, MaxPageSize+1)
+		assert.ErrorIs(t, err, ErrInvalidFilter)
+	})
+
+	t.Run("nothing is settled until the batch is", func(t *testing.T) {
+		assert.Equal(t, "98", userRepo.users[1].Balance.String())
+		assert.Equal(t, "49", userRepo.users[2].Balance.String())
+
+		settled, err := service.SettleDue(ctx, 10)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"deposit-1", "deposit-2"}, settled)
+		assert.Equal(t, "123", userRepo.users[1].Balance.String())
+		assert.Equal(t, "89", userRepo.users[2].Balance.String())
+	})
+}
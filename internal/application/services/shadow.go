@@ -0,0 +1,253 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/logging"
+)
+
+// ShadowCandidate is a new implementation of the transaction engine, such as
+// a new locking strategy or storage engine, run in shadow of the current one
+// so that its results can be compared before it takes over. Its results are
+// never returned. Its writes run in a unit of work of the current engine that
+// is always rolled back, so candidates must write through the same storage,
+// and skip their side effects outside of it when IsShadowRun.
+type ShadowCandidate interface {
+	ProcessTransaction(
+		ctx context.Context,
+		userID uint64,
+		req entities.TransactionRequest,
+		sourceType entities.SourceType,
+	) (*entities.TransactionResult, error)
+	GetUserBalance(ctx context.Context, userID uint64) (*entities.BalanceResponse, error)
+}
+
+// ShadowOutcome is how a shadow run compared with the current engine
+type ShadowOutcome string
+
+const (
+	ShadowMatch    ShadowOutcome = "match"
+	ShadowMismatch ShadowOutcome = "mismatch"
+	// ShadowError is a candidate run that panicked or timed out, or whose
+	// unit of work could not start
+	ShadowError ShadowOutcome = "error"
+	// ShadowSkipped is a sampled read dropped because too many candidate
+	// reads were in flight
+	ShadowSkipped ShadowOutcome = "skipped"
+)
+
+// The operations run in shadow
+const (
+	ShadowOperationProcess = "process_transaction"
+	ShadowOperationBalance = "get_balance"
+)
+
+// ShadowMetrics records the outcome of every shadow run
+type ShadowMetrics interface {
+	ShadowCompared(operation string, outcome ShadowOutcome)
+}
+
+// ShadowPolicy decides which requests run in shadow
+type ShadowPolicy struct {
+	// Percent of the transactions and balance reads also run through the
+	// candidate
+	Percent float64
+	// Timeout bounds each candidate run
+	Timeout time.Duration
+	// MaxConcurrent bounds the candidate reads running in the background
+	MaxConcurrent int
+}
+
+type shadowRunner struct {
+	candidate ShadowCandidate
+	policy    ShadowPolicy
+	metrics   []ShadowMetrics
+	// slots holds a token per candidate read in flight
+	slots  chan struct{}
+	sample func() float64
+}
+
+// WithShadow runs a sample of the transactions and balance reads through
+// candidate as well, logging and counting the results that differ.
+//
+// Sampled transactions are first run by the candidate in a unit of work that
+// is rolled back, then processed for real, so they take longer and may
+// mismatch if the user's balance changed in between. Sampled balance reads are
+// repeated by the candidate in the background.
+func WithShadow(candidate ShadowCandidate, policy ShadowPolicy, metrics ...ShadowMetrics) TransactionServiceOption {
+	return func(s *TransactionService) {
+		s.shadow = &shadowRunner{
+			candidate: candidate,
+			policy:    policy,
+			metrics:   metrics,
+			slots:     make(chan struct{}, max(policy.MaxConcurrent, 1)),
+			sample:    func() float64 { return rand.Float64() * 100 },
+		}
+	}
+}
+
+type shadowRunKey struct{}
+
+// IsShadowRun reports whether ctx belongs to a shadow run, whose results and
+// writes are discarded
+func IsShadowRun(ctx context.Context) bool {
+	shadow, _ := ctx.Value(shadowRunKey{}).(bool)
+	return shadow
+}
+
+var (
+	// errShadowRollback rolls back the unit of work of a shadow run
+	errShadowRollback = errors.New("shadow run rolled back")
+	errShadowPanic    = errors.New("shadow candidate panicked")
+)
+
+// sampled decides whether a request runs in shadow
+func (r *shadowRunner) sampled() bool {
+	return r != nil && r.policy.Percent > 0 && r.sample() < r.policy.Percent
+}
+
+func (r *shadowRunner) record(operation string, outcome ShadowOutcome) {
+	for _, metrics := range r.metrics {
+		metrics.ShadowCompared(operation, outcome)
+	}
+}
+
+// callCandidate calls run, turning a panic of the candidate into an error
+func callCandidate[T any](run func() (T, error)) (result T, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("%w: %v", errShadowPanic, p)
+		}
+	}()
+	return run()
+}
+
+// shadowProcess runs a sampled transaction through the candidate in a unit of
+// work that is rolled back. It returns the comparison to make with the result
+// of the current engine, or nil if the transaction is not sampled.
+func (s *TransactionService) shadowProcess(
+	ctx context.Context,
+	userID uint64,
+	req entities.TransactionRequest,
+	sourceType entities.SourceType,
+) func(*entities.TransactionResult, error) {
+	if !s.shadow.sampled() {
+		return nil
+	}
+
+	runCtx, cancel := context.WithTimeout(context.WithValue(ctx, shadowRunKey{}, true), s.shadow.policy.Timeout)
+	defer cancel()
+	var result *entities.TransactionResult
+	var err error
+	ran := false
+	uowErr := s.uow.WithinTransaction(runCtx, func(ctx context.Context) error {
+		ran = true
+		result, err = callCandidate(func() (*entities.TransactionResult, error) {
+			return s.shadow.candidate.ProcessTransaction(ctx, userID, req, sourceType)
+		})
+		return errShadowRollback
+	})
+	if !ran || !errors.Is(uowErr, errShadowRollback) {
+		err = fmt.Errorf("failed to run shadow unit of work: %w", uowErr)
+	}
+	failed := !ran || errors.Is(err, errShadowPanic) || runCtx.Err() != nil
+
+	return func(primary *entities.TransactionResult, primaryErr error) {
+		if failed {
+			s.shadowFailed(ctx, ShadowOperationProcess, userID, err)
+			return
+		}
+		s.compareShadow(ctx, ShadowOperationProcess, userID,
+			describeTransactionOutcome(primary, primaryErr), describeTransactionOutcome(result, err))
+	}
+}
+
+// shadowBalance repeats a sampled balance read with the candidate in the
+// background. Stale balances are not compared.
+func (s *TransactionService) shadowBalance(
+	ctx context.Context,
+	userID uint64,
+	primary *entities.BalanceResponse,
+	primaryErr error,
+) {
+	if (primary != nil && primary.Stale) || !s.shadow.sampled() {
+		return
+	}
+	select {
+	case s.shadow.slots <- struct{}{}:
+	default:
+		s.shadow.record(ShadowOperationBalance, ShadowSkipped)
+		return
+	}
+
+	// The read outlives the request, which may have been cancelled
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		defer func() { <-s.shadow.slots }()
+
+		runCtx, cancel := context.WithTimeout(context.WithValue(ctx, shadowRunKey{}, true), s.shadow.policy.Timeout)
+		defer cancel()
+		result, err := callCandidate(func() (*entities.BalanceResponse, error) {
+			return s.shadow.candidate.GetUserBalance(runCtx, userID)
+		})
+		if errors.Is(err, errShadowPanic) || runCtx.Err() != nil {
+			s.shadowFailed(ctx, ShadowOperationBalance, userID, err)
+			return
+		}
+		s.compareShadow(ctx, ShadowOperationBalance, userID,
+			describeBalanceOutcome(primary, primaryErr), describeBalanceOutcome(result, err))
+	}()
+}
+
+// compareShadow records whether the candidate got the same outcome as the
+// current engine, logging both if it did not
+func (s *TransactionService) compareShadow(ctx context.Context, operation string, userID uint64, primary, candidate string) {
+	if primary == candidate {
+		s.shadow.record(operation, ShadowMatch)
+		return
+	}
+	s.shadow.record(operation, ShadowMismatch)
+	logging.FromContext(ctx, &s.logger).Warn().
+		Str("operation", operation).
+		Uint64("user_id", userID).
+		Str("primary", primary).
+		Str("candidate", candidate).
+		Msg("shadow run mismatch")
+}
+
+func (s *TransactionService) shadowFailed(ctx context.Context, operation string, userID uint64, err error) {
+	s.shadow.record(operation, ShadowError)
+	logging.FromContext(ctx, &s.logger).Warn().Err(err).
+		Str("operation", operation).
+		Uint64("user_id", userID).
+		Msg("shadow run failed")
+}
+
+// describeTransactionOutcome summarizes the parts of a transaction outcome
+// both engines must agree on. IDs and receipts differ between runs.
+func describeTransactionOutcome(result *entities.TransactionResult, err error) string {
+	switch {
+	case errors.Is(err, ErrDuplicateTransaction):
+		return "error duplicate_transaction"
+	case err != nil:
+		return "error " + failureReason(err)
+	}
+	return fmt.Sprintf("balance %s fee %q currency %q replayed %t",
+		result.Balance, result.Fee, result.Currency, result.Replayed)
+}
+
+// describeBalanceOutcome summarizes the parts of a balance both engines must
+// agree on
+func describeBalanceOutcome(balance *entities.BalanceResponse, err error) string {
+	if err != nil {
+		return "error " + failureReason(err)
+	}
+	// Maps print sorted by key
+	return fmt.Sprintf("balance %s available %q currency %q balances %v",
+		balance.Balance, balance.Available, balance.Currency, balance.Balances)
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingShadowMetrics captures shadow outcomes as "operation:outcome"
+type recordingShadowMetrics struct {
+	mu       sync.Mutex
+	outcomes []string
+}
+
+func (m *recordingShadowMetrics) ShadowCompared(operation string, outcome ShadowOutcome) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.outcomes = append(m.outcomes, operation+":"+string(outcome))
+}
+
+func (m *recordingShadowMetrics) recorded() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.outcomes...)
+}
+
+// stubCandidate answers with fixed results, remembering whether it was called
+// in a shadow run
+type stubCandidate struct {
+	result    *entities.TransactionResult
+	err       error
+	balance   *entities.BalanceResponse
+	panics    bool
+	shadowRun bool
+}
+
+func (c *stubCandidate) ProcessTransaction(
+	ctx context.Context,
+	_ uint64,
+	_ entities.TransactionRequest,
+	_ entities.SourceType,
+) (*entities.TransactionResult, error) {
+	c.shadowRun = IsShadowRun(ctx)
+	if c.panics {
+		panic("candidate bug")
+	}
+	return c.result, c.err
+}
+
+func (c *stubCandidate) GetUserBalance(ctx context.Context, _ uint64) (*entities.BalanceResponse, error) {
+	if c.panics {
+		panic("candidate bug")
+	}
+	return c.balance, c.err
+}
+
+func alwaysShadow(candidate ShadowCandidate, metrics ShadowMetrics) TransactionServiceOption {
+	return WithShadow(candidate, ShadowPolicy{Percent: 100, Timeout: time.Second, MaxConcurrent: 1}, metrics)
+}
+
+func TestTransactionService_ShadowProcess(t *testing.T) {
+	ctx := context.Background()
+	req := entities.TransactionRequest{State: "win", Amount: "5.00", TransactionID: "tx-1"}
+
+	t.Run("matching candidate", func(t *testing.T) {
+		// The candidate is the current engine over the same storage, which the
+		// shadow run must leave untouched
+		userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(10)})
+		transactionRepo := newFakeTransactionRepo()
+		uow := &shadowUnitOfWork{users: userRepo, transactions: transactionRepo}
+		recorder := &recordingMetrics{}
+		candidate := NewTransactionService(uow, userRepo, transactionRepo, WithMetrics(recorder))
+		shadowMetrics := &recordingShadowMetrics{}
+		service := NewTransactionService(uow, userRepo, transactionRepo, alwaysShadow(candidate, shadowMetrics))
+
+		result, err := service.ProcessTransaction(ctx, 1, req, entities.SourceTypeGame)
+		require.NoError(t, err)
+		assert.Equal(t, "15.00", result.Balance)
+		assert.False(t, result.Replayed)
+		assert.Equal(t, []string{"process_transaction:match"}, shadowMetrics.recorded())
+		// Shadow runs are not counted by the candidate
+		assert.Empty(t, recorder.outcomes)
+	})
+
+	t.Run("mismatching candidate", func(t *testing.T) {
+		candidate := &stubCandidate{result: &entities.TransactionResult{Balance: "16.00"}}
+		shadowMetrics := &recordingShadowMetrics{}
+		uow := &fakeUnitOfWork{}
+		userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(10)})
+		service := NewTransactionService(uow, userRepo, newFakeTransactionRepo(), alwaysShadow(candidate, shadowMetrics))
+
+		result, err := service.ProcessTransaction(ctx, 1, req, entities.SourceTypeGame)
+		require.NoError(t, err)
+		assert.Equal(t, "15.00", result.Balance)
+		assert.True(t, candidate.shadowRun)
+		assert.Equal(t, 1, uow.rollbacks)
+		assert.Equal(t, []string{"process_transaction:mismatch"}, shadowMetrics.recorded())
+	})
+
+	t.Run("errors compare by reason", func(t *testing.T) {
+		candidate := &stubCandidate{err: ErrInsufficientFunds}
+		shadowMetrics := &recordingShadowMetrics{}
+		userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(1)})
+		service := NewTransactionService(&fakeUnitOfWork{}, userRepo, newFakeTransactionRepo(), alwaysShadow(candidate, shadowMetrics))
+
+		_, err := service.ProcessTransaction(ctx, 1,
+			entities.TransactionRequest{State: "lose", Amount: "5.00", TransactionID: "tx-1"}, entities.SourceTypeGame)
+		assert.ErrorIs(t, err, ErrInsufficientFunds)
+		assert.Equal(t, []string{"process_transaction:match"}, shadowMetrics.recorded())
+	})
+
+	t.Run("panicking candidate", func(t *testing.T) {
+		candidate := &stubCandidate{panics: true}
+		shadowMetrics := &recordingShadowMetrics{}
+		userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(10)})
+		service := NewTransactionService(&fakeUnitOfWork{}, userRepo, newFakeTransactionRepo(), alwaysShadow(candidate, shadowMetrics))
+
+		result, err := service.ProcessTransaction(ctx, 1, req, entities.SourceTypeGame)
+		require.NoError(t, err)
+		assert.Equal(t, "15.00", result.Balance)
+		assert.Equal(t, []string{"process_transaction:error"}, shadowMetrics.recorded())
+	})
+
+	t.Run("not sampled", func(t *testing.T) {
+		candidate := &stubCandidate{}
+		shadowMetrics := &recordingShadowMetrics{}
+		userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(10)})
+		service := NewTransactionService(&fakeUnitOfWork{}, userRepo, newFakeTransactionRepo(),
+			WithShadow(candidate, ShadowPolicy{Percent: 0, Timeout: time.Second, MaxConcurrent: 1}, shadowMetrics))
+
+		_, err := service.ProcessTransaction(ctx, 1, req, entities.SourceTypeGame)
+		require.NoError(t, err)
+		assert.Empty(t, shadowMetrics.recorded())
+	})
+}
+
+func TestTransactionService_ShadowBalance(t *testing.T) {
+	ctx := context.Background()
+	userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(10)})
+
+	candidate := &stubCandidate{balance: &entities.BalanceResponse{UserID: 1, Balance: "10.00"}}
+	shadowMetrics := &recordingShadowMetrics{}
+	service := NewTransactionService(&fakeUnitOfWork{}, userRepo, newFakeTransactionRepo(), alwaysShadow(candidate, shadowMetrics))
+
+	balance, err := service.GetUserBalance(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "10.00", balance.Balance)
+	require.Eventually(t, func() bool { return len(shadowMetrics.recorded()) == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, []string{"get_balance:match"}, shadowMetrics.recorded())
+
+	candidate.balance = &entities.BalanceResponse{UserID: 1, Balance: "9.00"}
+	_, err = service.GetUserBalance(ctx, 1)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return len(shadowMetrics.recorded()) == 2 }, time.Second, time.Millisecond)
+	assert.Equal(t, "get_balance:mismatch", shadowMetrics.recorded()[1])
+}
+
+// shadowUnitOfWork restores the balances of the users and drops the new
+// transactions when a unit of work rolls back, like a database would
+type shadowUnitOfWork struct {
+	users        *fakeUserRepo
+	transactions *fakeTransactionRepo
+}
+
+func (u *shadowUnitOfWork) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	balances := make(map[uint64]decimal.Decimal, len(u.users.users))
+	for id, user := range u.users.users {
+		balances[id] = user.Balance
+	}
+	recorded := len(u.transactions.transactions)
+	if err := fn(ctx); err != nil {
+		for id, balance := range balances {
+			u.users.users[id].Balance = balance
+		}
+		u.transactions.transactions = u.transactions.transactions[:recorded]
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/shopspring/decimal"
+)
+
+// ErrInvalidStatsRange is returned for statistics ranges that are empty or
+// longer than MaxStatsRange
+var ErrInvalidStatsRange = errors.New("invalid statistics range")
+
+const (
+	// DefaultStatsRange is the range statistics cover when from is omitted
+	DefaultStatsRange = 30 * 24 * time.Hour
+	// MaxStatsRange is the longest range statistics are computed over
+	MaxStatsRange = 366 * 24 * time.Hour
+)
+
+// GetUserStats counts and totals the user's wins and losses in a currency,
+// the base currency when empty, that occurred in [from, to), overall, by
+// source type and by UTC day of their business time. to defaults to now and
+// from to DefaultStatsRange before to. The totals are aggregated by the
+// database on every call.
+func (s *TransactionService) GetUserStats(
+	ctx context.Context,
+	userID uint64,
+	from, to *time.Time,
+	currencyCode string,
+) (*entities.UserStats, error) {
+	end := s.now()
+	if to != nil {
+		end = *to
+	}
+	start := end.Add(-DefaultStatsRange)
+	if from != nil {
+		start = *from
+	}
+	if !start.Before(end) || end.Sub(start) > MaxStatsRange {
+		return nil, fmt.Errorf("%w: from must be before to, at most %s apart", ErrInvalidStatsRange, MaxStatsRange)
+	}
+	currency, err := s.walletCurrency(currencyCode)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	days, err := s.transactionRepo.SummarizeDays(ctx, userID, currency, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize days: %w", err)
+	}
+
+	var overall statsTally
+	bySourceType := make(map[entities.SourceType]*statsTally)
+	var sourceTypes []entities.SourceType
+	var byDay []*statsTally
+	var dayNames []string
+	for _, day := range days {
+		if bySourceType[day.SourceType] == nil {
+			bySourceType[day.SourceType] = &statsTally{}
+			sourceTypes = append(sourceTypes, day.SourceType)
+		}
+		// Days arrive in order, so a new day starts a new tally
+		name := day.Day.UTC().Format(time.DateOnly)
+		if len(dayNames) == 0 || dayNames[len(dayNames)-1] != name {
+			byDay = append(byDay, &statsTally{})
+			dayNames = append(dayNames, name)
+		}
+
+		overall.add(day)
+		bySourceType[day.SourceType].add(day)
+		byDay[len(byDay)-1].add(day)
+	}
+
+	stats := &entities.UserStats{
+		UserID:       userID,
+		Currency:     s.currencyCode(currency),
+		From:         start.UTC(),
+		To:           end.UTC(),
+		StatsTotals:  overall.totals(),
+		BySourceType: make([]entities.SourceTypeStats, 0, len(sourceTypes)),
+		Days:         make([]entities.DayStats, 0, len(byDay)),
+	}
+	for _, sourceType := range sourceTypes {
+		stats.BySourceType = append(stats.BySourceType, entities.SourceTypeStats{
+			SourceType:  sourceType,
+			StatsTotals: bySourceType[sourceType].totals(),
+		})
+	}
+	for i, tally := range byDay {
+		stats.Days = append(stats.Days, entities.DayStats{Day: dayNames[i], StatsTotals: tally.totals()})
+	}
+	return stats, nil
+}
+
+// statsTally accumulates the day totals of a statistics bucket
+type statsTally struct {
+	wins, losses           int
+	totalWins, totalLosses decimal.Decimal
+}
+
+func (t *statsTally) add(day repositories.DayTotals) {
+	switch day.State {
+	case entities.StateWin:
+		t.wins += day.Count
+		t.totalWins = t.totalWins.Add(day.Amount)
+	case entities.StateLose:
+		t.losses += day.Count
+		t.totalLosses = t.totalLosses.Add(day.Amount)
+	}
+}
+
+func (t *statsTally) totals() entities.StatsTotals {
+	return entities.StatsTotals{
+		Wins:        t.wins,
+		Losses:      t.losses,
+		TotalWins:   t.totalWins.StringFixed(2),
+		TotalLosses: t.totalLosses.StringFixed(2),
+		Net:         t.totalWins.Sub(t.totalLosses).StringFixed(2),
+	}
+}
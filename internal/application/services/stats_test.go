@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionService_GetUserStats(t *testing.T) {
+	ctx := context.Background()
+	userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(100)})
+	transactionRepo := newFakeTransactionRepo()
+	now := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	service := NewTransactionService(&fakeUnitOfWork{}, userRepo, transactionRepo,
+		WithClock(func() time.Time { return now }),
+		WithCurrencies(newFakeWalletRepo(), "EUR", "USD"))
+
+	process := func(req entities.TransactionRequest, sourceType entities.SourceType) {
+		t.Helper()
+		_, err := service.ProcessTransaction(ctx, 1, req, sourceType)
+		require.NoError(t, err)
+	}
+	process(entities.TransactionRequest{State: "lose", Amount: "10.00", TransactionID: "bet-1"}, entities.SourceTypeGame)
+	process(entities.TransactionRequest{State: "win", Amount: "25.00", TransactionID: "win-1"}, entities.SourceTypeGame)
+	process(entities.TransactionRequest{State: "win", Amount: "5.00", TransactionID: "deposit-1"}, entities.SourceTypePayment)
+	process(entities.TransactionRequest{State: "lose", Amount: "7.00", TransactionID: "cancelled-1"}, entities.SourceTypeGame)
+	process(entities.TransactionRequest{State: "win", Amount: "3.00", TransactionID: "win-usd", Currency: "USD"}, entities.SourceTypeGame)
+	require.NoError(t, transactionRepo.MarkCancelled(ctx, 4, now))
+	now = now.Add(24 * time.Hour)
+	process(entities.TransactionRequest{State: "lose", Amount: "2.50", TransactionID: "bet-2"}, entities.SourceTypeServer)
+	now = now.Add(time.Hour)
+
+	t.Run("totals by source type and day", func(t *testing.T) {
+		stats, err := service.GetUserStats(ctx, 1, nil, nil, "")
+		require.NoError(t, err)
+		assert.Equal(t, "EUR", stats.Currency)
+		assert.Equal(t, now, stats.To)
+		assert.Equal(t, now.Add(-DefaultStatsRange), stats.From)
+		assert.Equal(t, entities.StatsTotals{Wins: 2, Losses: 2, TotalWins: "30.00", TotalLosses: "12.50", Net: "17.50"}, stats.StatsTotals)
+		assert.Equal(t, []entities.SourceTypeStats{
+			{SourceType: entities.SourceTypeGame, StatsTotals: entities.StatsTotals{Wins: 1, Losses: 1, TotalWins: "25.00", TotalLosses: "10.00", Net: "15.00"}},
+			{SourceType: entities.SourceTypePayment, StatsTotals: entities.StatsTotals{Wins: 1, TotalWins: "5.00", TotalLosses: "0.00", Net: "5.00"}},
+			{SourceType: entities.SourceTypeServer, StatsTotals: entities.StatsTotals{Losses: 1, TotalWins: "0.00", TotalLosses: "2.50", Net: "-2.50"}},
+		}, stats.BySourceType)
+		assert.Equal(t, []entities.DayStats{
+			{Day: "2025-03-01", StatsTotals: entities.StatsTotals{Wins: 2, Losses: 1, TotalWins: "30.00", TotalLosses: "10.00", Net: "20.00"}},
+			{Day: "2025-03-02", StatsTotals: entities.StatsTotals{Losses: 1, TotalWins: "0.00", TotalLosses: "2.50", Net: "-2.50"}},
+		}, stats.Days)
+	})
+
+	t.Run("the range and currency narrow the totals", func(t *testing.T) {
+		from := time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC)
+		stats, err := service.GetUserStats(ctx, 1, &from, nil, "")
+		require.NoError(t, err)
+		assert.Len(t, stats.Days, 1)
+		assert.Equal(t, "-2.50", stats.Net)
+
+		stats, err = service.GetUserStats(ctx, 1, nil, nil, "usd")
+		require.NoError(t, err)
+		assert.Equal(t, "USD", stats.Currency)
+		assert.Equal(t, "3.00", stats.Net)
+	})
+
+	t.Run("no transactions", func(t *testing.T) {
+		to := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		stats, err := service.GetUserStats(ctx, 1, nil, &to, "")
+		require.NoError(t, err)
+		assert.Equal(t, "0.00", stats.Net)
+		assert.Empty(t, stats.BySourceType)
+		assert.Empty(t, stats.Days)
+	})
+
+	t.Run("invalid ranges and unknown users are reported", func(t *testing.T) {
+		from := now.Add(-367 * 24 * time.Hour)
+		_, err := service.GetUserStats(ctx, 1, &from, nil, "")
+		assert.ErrorIs(t, err, ErrInvalidStatsRange)
+		_, err = service.GetUserStats(ctx, 1, &now, &now, "")
+		assert.ErrorIs(t, err, ErrInvalidStatsRange)
+		_, err = service.GetUserStats(ctx, 99, nil, nil, "")
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+)
+
+var ErrInvalidSyncCursor = errors.New("invalid sync cursor")
+
+// SyncService serves the change feed of the transactions to downstream
+// copies, such as data warehouses, so that they can sync incrementally. A
+// transaction appears again whenever it changes, e.g. when it is cancelled
+// or refunded.
+type SyncService struct {
+	repo repositories.SyncRepository
+}
+
+// NewSyncService creates a new SyncService
+func NewSyncService(repo repositories.SyncRepository) *SyncService {
+	return &SyncService{repo: repo}
+}
+
+// Changes returns up to limit transactions changed after the cursor since,
+// empty for the start of the feed. Limit defaults to DefaultPageSize.
+func (s *SyncService) Changes(ctx context.Context, since string, limit int) (*entities.SyncPage, error) {
+	if limit < 0 || limit > MaxPageSize {
+		return nil, ErrInvalidFilter
+	}
+	if limit == 0 {
+		limit = DefaultPageSize
+	}
+	cursor, err := ParseSyncCursor(since)
+	if err != nil {
+		return nil, err
+	}
+
+	// One more change tells whether the feed goes on
+	changes, err := s.repo.ChangesSince(ctx, cursor, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	hasMore := len(changes) > limit
+	if hasMore {
+		changes = changes[:limit]
+	}
+
+	transactions := make([]*entities.Transaction, 0, len(changes))
+	for _, change := range changes {
+		transactions = append(transactions, change.Transaction)
+		cursor = change.Cursor
+	}
+
+	return &entities.SyncPage{
+		Transactions: transactions,
+		Cursor:       FormatSyncCursor(cursor),
+		HasMore:      hasMore,
+	}, nil
+}
+
+// FormatSyncCursor returns the opaque form of cursor handed to clients
+func FormatSyncCursor(cursor repositories.SyncCursor) string {
+	return fmt.Sprintf("%d.%d", cursor.Version, cursor.ID)
+}
+
+// ParseSyncCursor parses a cursor formatted by FormatSyncCursor, or the
+// empty string for the start of the feed
+func ParseSyncCursor(value string) (repositories.SyncCursor, error) {
+	if value == "" {
+		return repositories.SyncCursor{}, nil
+	}
+	versionPart, idPart, ok := strings.Cut(value, ".")
+	if !ok {
+		return repositories.SyncCursor{}, ErrInvalidSyncCursor
+	}
+	version, err := strconv.ParseUint(versionPart, 10, 64)
+	if err != nil {
+		return repositories.SyncCursor{}, ErrInvalidSyncCursor
+	}
+	id, err := strconv.ParseUint(idPart, 10, 64)
+	if err != nil {
+		return repositories.SyncCursor{}, ErrInvalidSyncCursor
+	}
+	return repositories.SyncCursor{Version: version, ID: id}, nil
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncService_Changes(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeSyncRepo{}
+	for i, version := range []uint64{7, 7, 9} {
+		id := uint64(i + 1)
+		repo.changes = append(repo.changes, repositories.TransactionChange{
+			Transaction: &entities.Transaction{ID: id},
+			Cursor:      repositories.SyncCursor{Version: version, ID: id},
+		})
+	}
+	service := NewSyncService(repo)
+
+	page, err := service.Changes(ctx, "", 2)
+	require.NoError(t, err)
+	require.Len(t, page.Transactions, 2)
+	assert.Equal(t, uint64(1), page.Transactions[0].ID)
+	assert.Equal(t, "7.2", page.Cursor)
+	assert.True(t, page.HasMore)
+
+	page, err = service.Changes(ctx, page.Cursor, 2)
+	require.NoError(t, err)
+	require.Len(t, page.Transactions, 1)
+	assert.Equal(t, uint64(3), page.Transactions[0].ID)
+	assert.Equal(t, "9.3", page.Cursor)
+	assert.False(t, page.HasMore)
+
+	// Without changes the cursor stays where it was
+	page, err = service.Changes(ctx, page.Cursor, 0)
+	require.NoError(t, err)
+	assert.Empty(t, page.Transactions)
+	assert.Equal(t, "9.3", page.Cursor)
+
+	for _, since := range []string{"7", "7.x", "-1.2", "7.2.1"} {
+		_, err = service.Changes(ctx, since, 0)
+		assert.ErrorIs(t, err, ErrInvalidSyncCursor, since)
+	}
+	_, err = service.Changes(ctx, "", MaxPageSize+1)
+	assert.ErrorIs(t, err, ErrInvalidFilter)
+}
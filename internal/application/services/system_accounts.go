@@ -0,0 +1,139 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+)
+
+var ErrSystemAccount = errors.New("operation not allowed on a system account")
+
+// WithSystemAccounts makes the system accounts the counterparty of the
+// balance movements the operator makes: fees are credited to the house, and
+// system accounts are refused as the user of a transaction.
+func WithSystemAccounts(accounts []*entities.SystemAccount) TransactionServiceOption {
+	return func(s *TransactionService) {
+		s.systemAccounts = make(map[entities.SystemAccountKind]uint64, len(accounts))
+		for _, account := range accounts {
+			s.systemAccounts[account.Kind] = account.UserID
+		}
+	}
+}
+
+// ContraTransactionID returns the transaction ID of the leg crediting a
+// system account with the transaction with the given ID
+func ContraTransactionID(transactionID string) string {
+	return transactionID + ":contra"
+}
+
+// systemAccount returns the user ID of a system account, and false without
+// system accounts
+func (s *TransactionService) systemAccount(kind entities.SystemAccountKind) (uint64, bool) {
+	userID, ok := s.systemAccounts[kind]
+	return userID, ok
+}
+
+// isSystemAccount reports whether the user is a system account
+func (s *TransactionService) isSystemAccount(userID uint64) bool {
+	for _, id := range s.systemAccounts {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// lockHouse locks the house account in a unit of work that may move it, and
+// does nothing without system accounts. The house is always locked before
+// any user, so units of work crediting it cannot deadlock with each other
+// nor with transfers from it, which lock the house first as well.
+func (s *TransactionService) lockHouse(ctx context.Context) error {
+	houseID, ok := s.systemAccount(entities.SystemAccountHouse)
+	if !ok {
+		return nil
+	}
+	if _, err := s.userRepo.GetByIDForUpdate(ctx, houseID); err != nil {
+		return fmt.Errorf("failed to lock house account: %w", err)
+	}
+	return nil
+}
+
+// lockOrder sorts the IDs of users about to be locked into the order they
+// are locked in: the house first, then ascending IDs
+func (s *TransactionService) lockOrder(userIDs []uint64) {
+	houseID, hasHouse := s.systemAccount(entities.SystemAccountHouse)
+	slices.SortFunc(userIDs, func(a, b uint64) int {
+		switch {
+		case hasHouse && a == houseID && b != houseID:
+			return -1
+		case hasHouse && b == houseID && a != houseID:
+			return 1
+		}
+		return cmp.Compare(a, b)
+	})
+}
+
+// creditHouse records the contra leg of a fee, crediting the house with
+// what the fee took from the user. It runs in the unit of work of the fee,
+// which locked the house with lockHouse before the user, and does nothing
+// without system accounts or when nothing was charged.
+func (s *TransactionService) creditHouse(ctx context.Context, fee *entities.Transaction) error {
+	houseID, ok := s.systemAccount(entities.SystemAccountHouse)
+	if !ok || fee == nil {
+		return nil
+	}
+
+	house, err := s.userRepo.GetByIDForUpdate(ctx, houseID)
+	if err != nil {
+		return fmt.Errorf("failed to get house account: %w", err)
+	}
+
+	balance := house.Balance.Add(fee.Amount)
+	contra := &entities.Transaction{
+		UserID:        houseID,
+		TransactionID: ContraTransactionID(fee.TransactionID),
+		State:         entities.StateWin,
+		Amount:        fee.Amount,
+		SourceType:    entities.SourceTypeServer,
+		CreatedAt:     fee.CreatedAt,
+		BalanceAfter:  &balance,
+		Type:          entities.TransactionTypeFee,
+		ChargedFor:    fee.ChargedFor,
+		RoundID:       fee.RoundID,
+	}
+	return s.recordLeg(ctx, house, contra, ErrDuplicateTransaction)
+}
+
+// SystemAccountService reports the system accounts and their balances
+type SystemAccountService struct {
+	accounts []*entities.SystemAccount
+	userRepo repositories.UserRepository
+}
+
+// NewSystemAccountService creates a new SystemAccountService
+func NewSystemAccountService(accounts []*entities.SystemAccount, userRepo repositories.UserRepository) *SystemAccountService {
+	return &SystemAccountService{
+		accounts: accounts,
+		userRepo: userRepo,
+	}
+}
+
+// List returns the system accounts with their current base currency balance
+func (s *SystemAccountService) List(ctx context.Context) ([]*entities.SystemAccount, error) {
+	accounts := make([]*entities.SystemAccount, 0, len(s.accounts))
+	for _, account := range s.accounts {
+		user, err := s.userRepo.GetByID(ctx, account.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s account: %w", account.Kind, err)
+		}
+		listed := *account
+		listed.Balance = user.Balance
+		accounts = append(accounts, &listed)
+	}
+	return accounts, nil
+}
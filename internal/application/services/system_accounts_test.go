@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"transaction-service/internal/domain/entities"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testSystemAccounts = []*entities.SystemAccount{
+	{Kind: entities.SystemAccountHouse, UserID: 101},
+	{Kind: entities.SystemAccountPromo, UserID: 102},
+	{Kind: entities.SystemAccountEscheat, UserID: 103},
+}
+
+func newSystemAccountUserRepo(users ...*entities.User) *fakeUserRepo {
+	for _, account := range testSystemAccounts {
+		users = append(users, &entities.User{ID: account.UserID, Balance: decimal.Zero})
+	}
+	return newFakeUserRepo(users...)
+}
+
+func TestTransactionService_SystemAccounts(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("fees are credited to the house", func(t *testing.T) {
+		userRepo := newSystemAccountUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(100)})
+		transactionRepo := newFakeTransactionRepo()
+		fees := NewFeeService(newFakeFeeRuleRepo(&entities.FeeRule{
+			SourceType: entities.SourceTypeGame, State: entities.StateWin, Kind: entities.FeeKindFlat, Amount: decimal.NewFromInt(2),
+		}))
+		service := NewTransactionService(&fakeUnitOfWork{}, userRepo, transactionRepo,
+			WithFees(fees), WithSystemAccounts(testSystemAccounts))
+
+		result, err := service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "win", Amount: "20.00", TransactionID: "tx-1",
+		}, entities.SourceTypeGame)
+		require.NoError(t, err)
+		assert.Equal(t, "118.00", result.Balance)
+		assert.Equal(t, "2", userRepo.users[101].Balance.String())
+
+		contra, err := transactionRepo.GetByTransactionID(ctx, ContraTransactionID(FeeTransactionID(1)))
+		require.NoError(t, err)
+		assert.Equal(t, uint64(101), contra.UserID)
+		assert.Equal(t, entities.StateWin, contra.State)
+		assert.Equal(t, entities.TransactionTypeFee, contra.Type)
+		assert.Equal(t, "tx-1", contra.ChargedFor)
+
+		// Dormancy fees too
+		_, err = service.ChargeFee(ctx, userRepo.users[1], "dormancy-fee:1", decimal.NewFromInt(3))
+		require.NoError(t, err)
+		assert.Equal(t, "5", userRepo.users[101].Balance.String())
+	})
+
+	t.Run("system accounts cannot transact", func(t *testing.T) {
+		service := NewTransactionService(&fakeUnitOfWork{}, newSystemAccountUserRepo(), newFakeTransactionRepo(),
+			WithSystemAccounts(testSystemAccounts))
+
+		_, err := service.ProcessTransaction(ctx, 101, entities.TransactionRequest{
+			State: "win", Amount: "1.00", TransactionID: "tx-1",
+		}, entities.SourceTypeServer)
+		assert.ErrorIs(t, err, ErrSystemAccount)
+	})
+
+	t.Run("system accounts may overdraw in transfers", func(t *testing.T) {
+		userRepo := newSystemAccountUserRepo(&entities.User{ID: 1, Balance: decimal.Zero})
+		service := NewTransactionService(&fakeUnitOfWork{}, userRepo, newFakeTransactionRepo(),
+			WithSystemAccounts(testSystemAccounts))
+
+		result, err := service.Transfer(ctx, entities.TransferRequest{
+			TransferID: "bonus-1", FromUserID: 102, ToUserID: 1, Amount: "10.00",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "-10.00", result.From.Balance)
+
+		_, err = service.Transfer(ctx, entities.TransferRequest{
+			TransferID: "bonus-2", FromUserID: 1, ToUserID: 102, Amount: "20.00",
+		})
+		assert.ErrorIs(t, err, ErrInsufficientFunds)
+	})
+}
+
+func TestTransactionService_HouseIsLockedFirst(t *testing.T) {
+	ctx := context.Background()
+	userRepo := &lockRecordingUserRepo{fakeUserRepo: newSystemAccountUserRepo(
+		&entities.User{ID: 1, Balance: decimal.NewFromInt(100)},
+		&entities.User{ID: 200, Balance: decimal.NewFromInt(100)},
+	)}
+	fees := NewFeeService(newFakeFeeRuleRepo(&entities.FeeRule{
+		SourceType: entities.SourceTypeGame, State: entities.StateWin, Kind: entities.FeeKindFlat, Amount: decimal.NewFromInt(2),
+	}))
+	service := NewTransactionService(&fakeUnitOfWork{}, userRepo, newFakeTransactionRepo(),
+		WithFees(fees), WithBalanceAdjustments(&fakeBalanceAdjustmentRepo{}), WithSystemAccounts(testSystemAccounts))
+
+	// Users with a lower ID than the house as well as with a higher one, so
+	// that transfers from the house lock in the same order as fees
+	for _, userID := range []uint64{1, 200} {
+		userRepo.locked = nil
+		_, err := service.ProcessTransaction(ctx, userID, entities.TransactionRequest{
+			State: "win", Amount: "20.00", TransactionID: fmt.Sprintf("tx-%d", userID),
+		}, entities.SourceTypeGame)
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{101, userID, 101}, userRepo.locked, "fee for user %d", userID)
+
+		userRepo.locked = nil
+		_, err = service.Transfer(ctx, entities.TransferRequest{
+			TransferID: fmt.Sprintf("bonus-%d", userID), FromUserID: 101, ToUserID: userID, Amount: "1.00",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{101, userID}, userRepo.locked, "transfer to user %d", userID)
+
+		userRepo.locked = nil
+		_, err = service.AdjustBalance(ctx, userID, entities.BalanceAdjustmentRequest{
+			AdjustmentID: fmt.Sprintf("fix-%d", userID), Direction: entities.AdjustmentCredit,
+			Amount: "1.00", Reason: "incident", Actor: "ops",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{101, userID, 101}, userRepo.locked, "adjustment of user %d", userID)
+	}
+}
+
+func TestBulkJobService_AdjustBalancesWithCounterparty(t *testing.T) {
+	ctx := context.Background()
+	userRepo := newSystemAccountUserRepo(
+		&entities.User{ID: 1, Balance: decimal.NewFromInt(100)},
+		&entities.User{ID: 2, Balance: decimal.NewFromInt(5)},
+	)
+	transactionRepo := newFakeTransactionRepo()
+	transactions := NewTransactionService(&fakeUnitOfWork{}, userRepo, transactionRepo,
+		WithSystemAccounts(testSystemAccounts))
+	service := NewBulkJobService(NewAccountService(userRepo), transactions, nil)
+
+	job, err := service.AdjustBalances(ctx, entities.BulkAdjustmentRequest{
+		AdjustmentID: "incident-42", UserIDs: []uint64{1, 2}, State: "win", Amount: "10.00",
+	})
+	require.NoError(t, err)
+	runQueued(ctx, service)
+	job, err = service.GetJob(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, job.Succeeded)
+
+	// The house paid for the corrections
+	assert.Equal(t, "-20", userRepo.users[101].Balance.String())
+	assert.Equal(t, "110", userRepo.users[1].Balance.String())
+	debit, credit := TransferTransactionIDs("incident-42:1")
+	_, err = transactionRepo.GetByTransactionID(ctx, debit)
+	require.NoError(t, err)
+	_, err = transactionRepo.GetByTransactionID(ctx, credit)
+	require.NoError(t, err)
+
+	job, err = service.AdjustBalances(ctx, entities.BulkAdjustmentRequest{
+		AdjustmentID: "promo-7", UserIDs: []uint64{2}, State: "lose", Amount: "5.00", Counterparty: entities.SystemAccountPromo,
+	})
+	require.NoError(t, err)
+	runQueued(ctx, service)
+	assert.Equal(t, "5", userRepo.users[102].Balance.String())
+	assert.Equal(t, "10", userRepo.users[2].Balance.String())
+
+	_, err = service.AdjustBalances(ctx, entities.BulkAdjustmentRequest{
+		AdjustmentID: "a", UserIDs: []uint64{1}, State: "win", Amount: "1", Counterparty: "bank",
+	})
+	assert.ErrorIs(t, err, ErrInvalidBulkJob)
+}
+
+func TestSystemAccountService_List(t *testing.T) {
+	userRepo := newSystemAccountUserRepo()
+	userRepo.users[101].Balance = decimal.RequireFromString("-12.50")
+	service := NewSystemAccountService(testSystemAccounts, userRepo)
+
+	accounts, err := service.List(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, accounts, 3)
+	assert.Equal(t, entities.SystemAccountHouse, accounts[0].Kind)
+	assert.Equal(t, "-12.5", accounts[0].Balance.String())
+	assert.True(t, testSystemAccounts[0].Balance.IsZero())
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+)
+
+// MaxBatchSize is the largest number of transactions submitted at once
+const MaxBatchSize = 1000
+
+var ErrInvalidBatch = errors.New("invalid transaction batch")
+
+// BatchOutcome is the outcome of a transaction of a batch: its result when it
+// was processed, the reason it was not otherwise
+type BatchOutcome struct {
+	Result *entities.TransactionResult
+	Err    error
+}
+
+// ProcessTransactionBatch processes the transactions of a batch for the user,
+// in order. Each transaction is processed on its own, as by
+// ProcessTransaction, so that a rejected transaction does not hold back the
+// others; the outcomes are in the order of reqs. Retrying a batch replays the
+// transactions that were already processed.
+//
+// The batch as a whole fails only when it is empty or larger than
+// MaxBatchSize, the source type is invalid, the region is in standby or the
+// user does not exist.
+func (s *TransactionService) ProcessTransactionBatch(
+	ctx context.Context,
+	userID uint64,
+	reqs []entities.TransactionRequest,
+	sourceType entities.SourceType,
+) ([]BatchOutcome, error) {
+	if len(reqs) == 0 || len(reqs) > MaxBatchSize {
+		return nil, fmt.Errorf("%w: a batch holds 1 to %d transactions", ErrInvalidBatch, MaxBatchSize)
+	}
+	if !sourceType.IsValid() {
+		return nil, ErrInvalidSourceType
+	}
+	if s.region != nil && !s.region.AcceptsWrites() {
+		return nil, ErrRegionStandby
+	}
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	outcomes := make([]BatchOutcome, len(reqs))
+	for i, req := range reqs {
+		// The transactions left once the caller has gone are not processed
+		if err := ctx.Err(); err != nil {
+			outcomes[i].Err = err
+			continue
+		}
+		outcomes[i].Result, outcomes[i].Err = s.ProcessTransaction(ctx, userID, req, sourceType)
+	}
+	return outcomes, nil
+}
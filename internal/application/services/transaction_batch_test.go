@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"transaction-service/internal/domain/entities"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionService_ProcessTransactionBatch(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("processes every transaction on its own", func(t *testing.T) {
+		userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(10)})
+		transactionRepo := newFakeTransactionRepo()
+		service := NewTransactionService(&fakeUnitOfWork{}, userRepo, transactionRepo)
+
+		outcomes, err := service.ProcessTransactionBatch(ctx, 1, []entities.TransactionRequest{
+			{State: "win", Amount: "5", TransactionID: "tx-1"},
+			{State: "lose", Amount: "100", TransactionID: "tx-2"},
+			{State: "lose", Amount: "15", TransactionID: "tx-3"},
+			{State: "draw", Amount: "1", TransactionID: "tx-4"},
+		}, entities.SourceTypeGame)
+		require.NoError(t, err)
+		require.Len(t, outcomes, 4)
+
+		require.NoError(t, outcomes[0].Err)
+		assert.Equal(t, "15.00", outcomes[0].Result.Balance)
+		assert.ErrorIs(t, outcomes[1].Err, ErrInsufficientFunds)
+		require.NoError(t, outcomes[2].Err)
+		assert.Equal(t, "0.00", outcomes[2].Result.Balance)
+		assert.ErrorIs(t, outcomes[3].Err, ErrInvalidTransactionState)
+
+		// Rejected transactions do not roll back the others
+		assert.Len(t, transactionRepo.transactions, 2)
+		assert.True(t, userRepo.users[1].Balance.IsZero())
+	})
+
+	t.Run("resubmitted batches are replayed", func(t *testing.T) {
+		userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(10)})
+		transactionRepo := newFakeTransactionRepo()
+		service := NewTransactionService(&fakeUnitOfWork{}, userRepo, transactionRepo)
+		batch := []entities.TransactionRequest{
+			{State: "win", Amount: "5", TransactionID: "tx-1"},
+			{State: "win", Amount: "5", TransactionID: "tx-2"},
+		}
+
+		_, err := service.ProcessTransactionBatch(ctx, 1, batch, entities.SourceTypeGame)
+		require.NoError(t, err)
+		outcomes, err := service.ProcessTransactionBatch(ctx, 1, batch, entities.SourceTypeGame)
+		require.NoError(t, err)
+		for _, outcome := range outcomes {
+			require.NoError(t, outcome.Err)
+			assert.True(t, outcome.Result.Replayed)
+		}
+		assert.Len(t, transactionRepo.transactions, 2)
+		assert.Equal(t, "20", userRepo.users[1].Balance.String())
+	})
+
+	t.Run("batches that cannot be processed are rejected", func(t *testing.T) {
+		userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(10)})
+		transactionRepo := newFakeTransactionRepo()
+		service := NewTransactionService(&fakeUnitOfWork{}, userRepo, transactionRepo)
+		one := []entities.TransactionRequest{{State: "win", Amount: "5", TransactionID: "tx-1"}}
+
+		_, err := service.ProcessTransactionBatch(ctx, 1, nil, entities.SourceTypeGame)
+		assert.ErrorIs(t, err, ErrInvalidBatch)
+		_, err = service.ProcessTransactionBatch(ctx, 1, make([]entities.TransactionRequest, MaxBatchSize+1), entities.SourceTypeGame)
+		assert.ErrorIs(t, err, ErrInvalidBatch)
+		_, err = service.ProcessTransactionBatch(ctx, 1, one, "casino")
+		assert.ErrorIs(t, err, ErrInvalidSourceType)
+		_, err = service.ProcessTransactionBatch(ctx, 99, one, entities.SourceTypeGame)
+		assert.ErrorIs(t, err, ErrUserNotFound)
+
+		standby := NewTransactionService(&fakeUnitOfWork{}, userRepo, transactionRepo, WithRegionGate(fixedRegionGate(false)))
+		_, err = standby.ProcessTransactionBatch(ctx, 1, one, entities.SourceTypeGame)
+		assert.ErrorIs(t, err, ErrRegionStandby)
+
+		assert.Empty(t, transactionRepo.transactions)
+	})
+
+	t.Run("transactions left once the caller has gone are not processed", func(t *testing.T) {
+		userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(10)})
+		transactionRepo := newFakeTransactionRepo()
+		service := NewTransactionService(&fakeUnitOfWork{}, userRepo, transactionRepo)
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		outcomes, err := service.ProcessTransactionBatch(cancelled, 1, []entities.TransactionRequest{
+			{State: "win", Amount: "5", TransactionID: "tx-1"},
+		}, entities.SourceTypeGame)
+		require.NoError(t, err)
+		assert.ErrorIs(t, outcomes[0].Err, context.Canceled)
+		assert.Empty(t, transactionRepo.transactions)
+	})
+}
@@ -4,129 +4,1558 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"transaction-service/internal/domain/entities"
 	"transaction-service/internal/domain/repositories"
+	"transaction-service/internal/logging"
 
+	"github.com/rs/zerolog"
 	"github.com/shopspring/decimal"
 )
 
 var (
 	ErrUserNotFound            = errors.New("user not found")
 	ErrInsufficientFunds       = errors.New("insufficient funds")
-	ErrDuplicateTransaction    = errors.New("transaction already processed")
+	ErrDuplicateTransaction    = errors.New("transaction ID already used for a different transaction")
 	ErrInvalidAmount           = errors.New("invalid amount")
 	ErrInvalidTransactionState = errors.New("invalid transaction state")
 	ErrInvalidSourceType       = errors.New("invalid source type")
+	ErrAccountFrozen           = errors.New("account is frozen")
+	ErrInvalidOccurredAt       = errors.New("occurredAt is outside the accepted clock skew")
+	ErrInvalidFilter           = errors.New("invalid filter")
+	ErrInvalidCursor           = errors.New("invalid cursor")
+	ErrRegionStandby           = errors.New("region is in standby and does not accept writes")
+	ErrInvalidCurrency         = errors.New("invalid currency")
+	ErrUnsupportedCurrency     = errors.New("unsupported currency")
+	ErrInvalidRoundID          = errors.New("round ID must be at most 255 characters")
+	ErrRoundNotFound           = errors.New("round not found")
+	ErrTransactionNotFound     = errors.New("transaction not found")
+	ErrAlreadyRefunded         = errors.New("transaction has already been refunded")
+	ErrNotRefundable           = errors.New("refunds, transfer legs, adjustments, cancelled and pending transactions cannot be refunded")
+	ErrInvalidTransfer         = errors.New("invalid transfer")
+	ErrDuplicateTransfer       = errors.New("transfer ID already used for a different transfer")
+
+	ErrBalanceChangeLimitExceeded = errors.New("balance change limit exceeded")
+
+	// ErrUnavailable wraps storage failures that may pass, such as a lost
+	// database connection; retrying the request later may succeed
+	ErrUnavailable = repositories.ErrUnavailable
 )
 
+// errReplayedOnConflict rolls back a unit of work that found its transaction
+// recorded by a concurrent request only when inserting it
+var errReplayedOnConflict = errors.New("transaction recorded concurrently")
+
 // TransactionService handles transaction business logic
 type TransactionService struct {
+	uow             repositories.UnitOfWork
 	userRepo        repositories.UserRepository
 	transactionRepo repositories.TransactionRepository
+	balanceGuard    *BalanceGuard
+	clockSkew       ClockSkewPolicy
+	// amountPolicy bounds the amounts of transactions; only positive amounts
+	// are checked when it is nil
+	amountPolicy *AmountPolicy
+	hooks        []TransactionHook
+	idGenerator  IDGenerator
+	region       RegionGate
+	logger       zerolog.Logger
+
+	// now is the business clock stamping transactions and placing limit
+	// windows; sandbox services run on a virtual clock
+	now func() time.Time
+
+	// Balances in currencies other than baseCurrency live in wallets; only
+	// walletCurrencies may be used
+	walletRepo       repositories.WalletRepository
+	baseCurrency     string
+	walletCurrencies map[string]bool
+
+	// Active holds reduce the available base currency balance; disabled when
+	// holdRepo is nil
+	holdRepo repositories.HoldRepository
+
+	// Per-jurisdiction overlays, keyed by country code
+	jurisdictionRules JurisdictionRules
+
+	// Limits of the transactions of every user within a rolling window
+	velocity VelocityPolicy
+
+	// Payment credits await settlement for settlementDelay before they move
+	// the balance; disabled when settlement is false
+	settlement      bool
+	settlementDelay time.Duration
+	// settlementRecorders are told about every settlement, e.g. the outbox
+	settlementRecorders []SettlementRecorder
+
+	// Fees charged for base currency transactions; disabled when fees is nil
+	fees FeeCalculator
+
+	// User IDs of the system accounts by kind; none when empty
+	systemAccounts map[entities.SystemAccountKind]uint64
+
+	// Audit records of the manual balance adjustments; disabled when
+	// adjustments is nil
+	adjustments repositories.BalanceAdjustmentRepository
+
+	// Every written transaction is recorded in the audit log; disabled when
+	// audit is nil
+	audit AuditRecorder
+
+	metrics []TransactionMetrics
+
+	// Rejected attempts are reported to rejectionRecorders
+	rejectionRecorders []RejectionRecorder
+
+	// Daily closing balances bound the replay of point-in-time balances;
+	// they are replayed from the current balance when snapshotRepo is nil
+	snapshotRepo repositories.BalanceSnapshotRepository
+
+	// Stale balance fallback; disabled when balanceCache is nil
+	balanceCache BalanceCache
+	maxStaleness time.Duration
+	invalidator  BalanceInvalidator
+
+	// A sample of the requests also run through a candidate engine; disabled
+	// when shadow is nil
+	shadow *shadowRunner
+
+	// ProcessTransaction runs its unit of work under serializable isolation
+	serializable bool
+}
+
+// creditLimit returns how far below zero the user's balance in currency may
+// go; credit lines only extend to the base currency
+func creditLimit(user *entities.User, currency string) decimal.Decimal {
+	if currency != "" {
+		return decimal.Zero
+	}
+	return user.CreditLimit
+}
+
+// RegionGate reports whether this deployment currently accepts writes
+type RegionGate interface {
+	AcceptsWrites() bool
+}
+
+// ClockSkewPolicy bounds how far a client-supplied occurredAt may drift from
+// the server clock, optionally per source type
+type ClockSkewPolicy struct {
+	Default   time.Duration
+	PerSource map[entities.SourceType]time.Duration
+}
+
+// Tolerance returns the accepted skew for the given source type
+func (p ClockSkewPolicy) Tolerance(sourceType entities.SourceType) time.Duration {
+	if tolerance, ok := p.PerSource[sourceType]; ok {
+		return tolerance
+	}
+	return p.Default
+}
+
+const (
+	// DefaultPageSize is the number of records returned when no limit is given
+	DefaultPageSize = 50
+	// MaxPageSize is the largest page of records that can be requested
+	MaxPageSize = 500
+)
+
+// MaxRoundIDLength is the longest accepted round ID
+const MaxRoundIDLength = 255
+
+// MaxTransferIDLength leaves room for the leg suffix in the transaction IDs of
+// a transfer's legs
+const MaxTransferIDLength = 200
+
+// DefaultClockSkewTolerance is the accepted skew for occurredAt when no policy is configured
+const DefaultClockSkewTolerance = 5 * time.Minute
+
+// TransactionServiceOption configures optional TransactionService behaviour
+type TransactionServiceOption func(*TransactionService)
+
+// WithLogger sets the logger used outside of a request-scoped context
+func WithLogger(logger zerolog.Logger) TransactionServiceOption {
+	return func(s *TransactionService) {
+		s.logger = logger
+	}
+}
+
+// WithBalanceGuard enables the balance rate-of-change guard
+func WithBalanceGuard(guard *BalanceGuard) TransactionServiceOption {
+	return func(s *TransactionService) {
+		s.balanceGuard = guard
+	}
+}
+
+// WithClockSkewPolicy sets the accepted skew for client-supplied timestamps
+func WithClockSkewPolicy(policy ClockSkewPolicy) TransactionServiceOption {
+	return func(s *TransactionService) {
+		s.clockSkew = policy
+	}
+}
+
+// WithAmountPolicy bounds the amounts of transactions
+func WithAmountPolicy(policy AmountPolicy) TransactionServiceOption {
+	return func(s *TransactionService) {
+		s.amountPolicy = &policy
+	}
+}
+
+// WithIDGenerator sets the strategy used to allocate transaction IDs and
+// receipts. Without it the database sequence allocates both.
+func WithIDGenerator(generator IDGenerator) TransactionServiceOption {
+	return func(s *TransactionService) {
+		s.idGenerator = generator
+	}
+}
+
+// WithRegionGate refuses transactions with ErrRegionStandby while the gate
+// does not accept writes
+func WithRegionGate(gate RegionGate) TransactionServiceOption {
+	return func(s *TransactionService) {
+		s.region = gate
+	}
+}
+
+// WithStaleBalanceFallback serves cached balances no older than maxStaleness
+// when the user repository is unavailable. Writes never use the cache.
+func WithStaleBalanceFallback(cache BalanceCache, maxStaleness time.Duration) TransactionServiceOption {
+	return func(s *TransactionService) {
+		s.balanceCache = cache
+		s.maxStaleness = maxStaleness
+	}
+}
+
+// WithBalanceInvalidator announces every balance update through invalidator
+func WithBalanceInvalidator(invalidator BalanceInvalidator) TransactionServiceOption {
+	return func(s *TransactionService) {
+		s.invalidator = invalidator
+	}
+}
+
+// WithCurrencies names the base currency user balances are held in and lets
+// users hold balances in the other currencies, each in its own wallet
+func WithCurrencies(wallets repositories.WalletRepository, base string, others ...string) TransactionServiceOption {
+	return func(s *TransactionService) {
+		s.walletRepo = wallets
+		s.baseCurrency = base
+		s.walletCurrencies = make(map[string]bool, len(others))
+		for _, currency := range others {
+			if currency != base {
+				s.walletCurrencies[currency] = true
+			}
+		}
+	}
+}
+
+// WithHolds keeps the amounts reserved by active holds from being spent by
+// other transactions
+func WithHolds(holds repositories.HoldRepository) TransactionServiceOption {
+	return func(s *TransactionService) {
+		s.holdRepo = holds
+	}
+}
+
+// WithClock sets the clock used for transaction timestamps and limit windows.
+// Client-supplied timestamps are still checked against the wall clock.
+func WithClock(now func() time.Time) TransactionServiceOption {
+	return func(s *TransactionService) {
+		s.now = now
+	}
+}
+
+// WithSerializableProcessing runs the unit of work of ProcessTransaction under
+// serializable isolation (see repositories.WithSerializable); the unit of work
+// is expected to retry serialization failures
+func WithSerializableProcessing() TransactionServiceOption {
+	return func(s *TransactionService) {
+		s.serializable = true
+	}
 }
 
 // NewTransactionService creates a new TransactionService
 func NewTransactionService(
+	uow repositories.UnitOfWork,
 	userRepo repositories.UserRepository,
 	transactionRepo repositories.TransactionRepository,
+	opts ...TransactionServiceOption,
 ) *TransactionService {
-	return &TransactionService{
+	s := &TransactionService{
+		uow:             uow,
 		userRepo:        userRepo,
 		transactionRepo: transactionRepo,
+		clockSkew:       ClockSkewPolicy{Default: DefaultClockSkewTolerance},
+		logger:          zerolog.Nop(),
+		now:             time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
-// ProcessTransaction processes a new transaction
+// ProcessTransaction processes a new transaction. The duplicate check, the
+// transaction insert, the balance update and all hooks run in a single unit
+// of work and commit or roll back together.
+//
+// Processing is idempotent: replaying a transaction that was already
+// processed returns the original result with Replayed set, while reusing a
+// transaction ID for a different transaction fails with ErrDuplicateTransaction.
 func (s *TransactionService) ProcessTransaction(
 	ctx context.Context,
 	userID uint64,
 	req entities.TransactionRequest,
 	sourceType entities.SourceType,
-) error {
-	// Validating a source type
-	if !sourceType.IsValid() {
-		return ErrInvalidSourceType
+) (*entities.TransactionResult, error) {
+	compareShadow := s.shadowProcess(ctx, userID, req, sourceType)
+
+	result, err := s.processTransaction(ctx, userID, req, sourceType)
+	// Shadow runs of a candidate engine built from this service are not
+	// counted
+	if !IsShadowRun(ctx) {
+		s.recordOutcome(sourceType, entities.TransactionState(req.State), result, err)
+		s.recordRejection(ctx, userID, req, sourceType, err)
 	}
 
-	// Checking for duplicate transactions
-	exists, err := s.transactionRepo.ExistsByTransactionID(ctx, req.TransactionID)
-	if err != nil {
-		return fmt.Errorf("failed to check transaction existence: %w", err)
+	if compareShadow != nil {
+		compareShadow(result, err)
 	}
-	if exists {
-		return ErrDuplicateTransaction
+	return result, err
+}
+
+func (s *TransactionService) processTransaction(
+	ctx context.Context,
+	userID uint64,
+	req entities.TransactionRequest,
+	sourceType entities.SourceType,
+) (*entities.TransactionResult, error) {
+	// Writes belong to the active region
+	if s.region != nil && !s.region.AcceptsWrites() {
+		return nil, ErrRegionStandby
+	}
+
+	// Validating a source type
+	if !sourceType.IsValid() {
+		return nil, ErrInvalidSourceType
 	}
 
 	// Parse and validate the amount
 	amount, err := decimal.NewFromString(req.Amount)
 	if err != nil {
-		return ErrInvalidAmount
+		return nil, ErrInvalidAmount
 	}
 	if amount.IsNegative() || amount.IsZero() {
-		return ErrInvalidAmount
+		return nil, ErrInvalidAmount
+	}
+	if s.amountPolicy != nil {
+		if err := s.amountPolicy.Validate(amount, sourceType); err != nil {
+			return nil, err
+		}
 	}
 
 	// Validating transaction state
 	state := entities.TransactionState(req.State)
 	if !state.IsValid() {
-		return ErrInvalidTransactionState
+		return nil, ErrInvalidTransactionState
 	}
 
-	// Get current user
-	user, err := s.userRepo.GetByID(ctx, userID)
+	// Resolve the wallet to move; empty for the base currency
+	currency, err := s.walletCurrency(req.Currency)
 	if err != nil {
-		return ErrUserNotFound
+		return nil, err
+	}
+
+	if len(req.RoundID) > MaxRoundIDLength {
+		return nil, ErrInvalidRoundID
+	}
+	if err := validateMetadata(req.Metadata); err != nil {
+		return nil, err
+	}
+
+	// System accounts only move through fees, transfers and corrections
+	if s.isSystemAccount(userID) {
+		return nil, ErrSystemAccount
 	}
 
-	// Calculate new balance
+	// Validating the client-side timestamp against the accepted skew
+	if req.OccurredAt != nil {
+		skew := time.Since(*req.OccurredAt).Abs()
+		if skew > s.clockSkew.Tolerance(sourceType) {
+			return nil, ErrInvalidOccurredAt
+		}
+	}
+
+	now := s.now()
 	var newBalance decimal.Decimal
-	switch state {
-	case entities.StateWin:
-		newBalance = user.Balance.Add(amount)
-	case entities.StateLose:
-		newBalance = user.Balance.Sub(amount)
-		if newBalance.IsNegative() {
+	var transaction, fee *entities.Transaction
+	var alert *BalanceAlert
+	var replayed *entities.TransactionResult
+
+	uowCtx := ctx
+	if s.serializable {
+		uowCtx = repositories.WithSerializable(ctx)
+	}
+	err = s.uow.WithinTransaction(uowCtx, func(ctx context.Context) error {
+		// Fees credit the house, which is locked before the user
+		if s.fees != nil && currency == "" {
+			if err := s.lockHouse(ctx); err != nil {
+				return err
+			}
+		}
+
+		// Get current user, locking it so concurrent transactions for the
+		// same user cannot compute their new balance from a stale value. The
+		// lock also serializes retries of the same transaction.
+		user, err := s.userRepo.GetByIDForUpdate(ctx, userID)
+		if err != nil {
+			if errors.Is(err, repositories.ErrNotFound) {
+				return ErrUserNotFound
+			}
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+
+		// Replay the original result of a transaction that was already processed
+		existing, err := s.transactionRepo.GetByTransactionID(ctx, req.TransactionID)
+		if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+			return fmt.Errorf("failed to check transaction existence: %w", err)
+		}
+		if existing != nil {
+			if !isReplay(existing, userID, state, amount, sourceType, currency) {
+				return ErrDuplicateTransaction
+			}
+			replayed, err = s.originalResult(ctx, existing)
+			if err != nil {
+				return err
+			}
+			replayed.Replayed = true
+			return nil
+		}
+
+		if user.Status == entities.UserStatusFrozen {
+			return ErrAccountFrozen
+		}
+
+		// Wallets are locked on top of the user, which already serializes
+		// transactions for the same user
+		balance := user.Balance
+		if currency != "" {
+			wallet, err := s.walletRepo.GetForUpdate(ctx, userID, currency)
+			if err != nil {
+				return fmt.Errorf("failed to get wallet: %w", err)
+			}
+			balance = wallet.Balance
+		}
+
+		// Calculate new balance
+		var delta decimal.Decimal
+		switch state {
+		case entities.StateWin:
+			delta = amount
+		case entities.StateLose:
+			delta = amount.Neg()
+		}
+		// Credits are always accepted, so that balances left below a lowered
+		// credit limit can be paid back
+		newBalance = balance.Add(delta)
+		if delta.IsNegative() && newBalance.Add(creditLimit(user, currency)).IsNegative() {
 			return ErrInsufficientFunds
 		}
+
+		// Debits may not spend the amounts reserved by holds
+		if s.holdRepo != nil && currency == "" && delta.IsNegative() {
+			held, err := s.holdRepo.SumActive(ctx, userID, now)
+			if err != nil {
+				return err
+			}
+			if newBalance.Add(user.CreditLimit).Sub(held).IsNegative() {
+				return ErrInsufficientFunds
+			}
+		}
+
+		// Payment credits only move the balance once settled
+		pending := s.awaitsSettlement(sourceType, currency, delta)
+		if pending {
+			newBalance = balance
+		}
+
+		// Apply the rules of the user's jurisdiction
+		if err := s.checkJurisdiction(ctx, user, sourceType, currency, delta, now); err != nil {
+			return err
+		}
+
+		// Limit how much and how fast users transact
+		if err := s.checkVelocity(ctx, userID, currency, delta, now); err != nil {
+			return err
+		}
+
+		// Guard against unusually fast movements of the base currency balance
+		if s.balanceGuard != nil && currency == "" {
+			alert, err = s.balanceGuard.Evaluate(ctx, user, req.TransactionID, delta)
+			if err != nil {
+				return err
+			}
+			if alert != nil && s.balanceGuard.Blocks() {
+				return ErrBalanceChangeLimitExceeded
+			}
+		}
+
+		// Allocate the transaction's IDs
+		var ids TransactionIDs
+		if s.idGenerator != nil {
+			if ids, err = s.idGenerator.NextIDs(); err != nil {
+				return fmt.Errorf("failed to allocate transaction ID: %w", err)
+			}
+		}
+
+		// Create transaction record
+		transaction = &entities.Transaction{
+			ID:            ids.Key,
+			UserID:        userID,
+			TransactionID: req.TransactionID,
+			Receipt:       ids.Receipt,
+			State:         state,
+			Amount:        amount,
+			SourceType:    sourceType,
+			Currency:      currency,
+			OccurredAt:    req.OccurredAt,
+			CreatedAt:     now,
+			BalanceAfter:  &newBalance,
+			RoundID:       req.RoundID,
+			Metadata:      req.Metadata,
+			Type:          entities.TransactionTypeStandard,
+			Pending:       pending,
+		}
+		event := &TransactionEvent{User: user, Transaction: transaction, NewBalance: newBalance}
+
+		if err := s.runBeforeHooks(ctx, event); err != nil {
+			return err
+		}
+
+		// Save the transaction
+		if err := s.transactionRepo.Create(ctx, transaction); err != nil {
+			if !errors.Is(err, repositories.ErrConflict) {
+				return fmt.Errorf("failed to create transaction: %w", err)
+			}
+			// A concurrent request recorded the transaction ID since it was
+			// checked. The insert did nothing, so the original can still be
+			// read and replayed if it is the same transaction.
+			existing, err := s.transactionRepo.GetByTransactionID(ctx, req.TransactionID)
+			if err != nil || !isReplay(existing, userID, state, amount, sourceType, currency) {
+				return ErrDuplicateTransaction
+			}
+			if replayed, err = s.originalResult(ctx, existing); err != nil {
+				return err
+			}
+			replayed.Replayed = true
+			// The writes of the before hooks are rolled back
+			return errReplayedOnConflict
+		}
+
+		// Update the balance
+		if currency != "" {
+			if err := s.walletRepo.UpdateBalance(ctx, userID, currency, newBalance); err != nil {
+				return fmt.Errorf("failed to update wallet balance: %w", err)
+			}
+		} else if err := s.userRepo.UpdateBalance(ctx, userID, newBalance); err != nil {
+			return fmt.Errorf("failed to update user balance: %w", err)
+		}
+
+		// Transacting again ends the user's dormancy
+		if user.DormantAt != nil {
+			if err := s.userRepo.UpdateDormancy(ctx, userID, nil); err != nil {
+				return fmt.Errorf("failed to clear dormancy: %w", err)
+			}
+		}
+
+		if err := s.runAfterHooks(ctx, event); err != nil {
+			return err
+		}
+
+		// Charge the fee of the transaction in the same unit of work
+		if s.fees != nil && currency == "" {
+			user.Balance = newBalance
+			if fee, err = s.chargeTransactionFee(ctx, user, transaction); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	// Freezing and alerting happen outside the unit of work so that they
+	// survive the rollback of a blocked transaction; shadow runs only report
+	// whether the transaction was blocked
+	if alert != nil && (err == nil || errors.Is(err, ErrBalanceChangeLimitExceeded)) && !IsShadowRun(ctx) {
+		if enforceErr := s.balanceGuard.Enforce(ctx, alert); enforceErr != nil {
+			logging.FromContext(ctx, &s.logger).Error().Err(enforceErr).
+				Uint64("user_id", userID).
+				Str("transaction_id", req.TransactionID).
+				Msg("failed to enforce balance guard")
+		}
+	}
+	if errors.Is(err, errReplayedOnConflict) {
+		return replayed, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if replayed != nil {
+		return replayed, nil
 	}
 
-	// Create transaction record
-	transaction := &entities.Transaction{
+	result := &entities.TransactionResult{
 		UserID:        userID,
+		ID:            transaction.ID,
 		TransactionID: req.TransactionID,
-		State:         state,
-		Amount:        amount,
-		SourceType:    sourceType,
-		CreatedAt:     time.Now(),
+		Receipt:       transaction.Receipt,
+		Currency:      s.currencyCode(currency),
+		Pending:       transaction.Pending,
+	}
+	if fee != nil {
+		newBalance = *fee.BalanceAfter
+		result.Fee = fee.Amount.StringFixed(2)
+	}
+	result.Balance = newBalance.StringFixed(2)
+
+	// Only the base currency balance is cached
+	if currency == "" {
+		s.cacheBalance(ctx, userID, newBalance, time.Now())
+		s.invalidateBalance(ctx, userID)
+	}
+
+	return result, nil
+}
+
+// isReplay reports whether an already processed transaction matches the
+// incoming request, so its original result can be returned. Transactions
+// recorded before the resulting balance was stored, refunds, transfer legs,
+// fees and adjustments cannot be replayed.
+func isReplay(
+	existing *entities.Transaction,
+	userID uint64,
+	state entities.TransactionState,
+	amount decimal.Decimal,
+	sourceType entities.SourceType,
+	currency string,
+) bool {
+	return existing.BalanceAfter != nil &&
+		existing.Type != entities.TransactionTypeRefund &&
+		existing.Type != entities.TransactionTypeFee &&
+		existing.Type != entities.TransactionTypeAdjustment &&
+		existing.TransferID == "" &&
+		existing.UserID == userID &&
+		existing.State == state &&
+		existing.Amount.Equal(amount) &&
+		existing.SourceType == sourceType &&
+		existing.Currency == currency
+}
+
+// RefundTransaction reverses a processed transaction. The refund is recorded
+// as a compensating transaction of the opposite state, linked to the original
+// through Reverses and ReversedBy, and moves the amount back on the balance
+// the original moved. A transaction is refunded at most once; refunds,
+// transfer legs, adjustments, cancelled and pending transactions cannot be
+// refunded.
+// Refunds are operator corrections, so they are applied to frozen accounts
+// and skip the balance guard and the jurisdiction rules.
+func (s *TransactionService) RefundTransaction(ctx context.Context, transactionID string) (*entities.TransactionResult, error) {
+	// Writes belong to the active region
+	if s.region != nil && !s.region.AcceptsWrites() {
+		return nil, ErrRegionStandby
+	}
+
+	now := s.now()
+	var newBalance decimal.Decimal
+	var refund *entities.Transaction
+
+	err := s.uow.WithinTransaction(ctx, func(ctx context.Context) error {
+		// Lock the transaction before its user, in the same order as
+		// cancellations. The lock serializes refunds of the same transaction.
+		original, err := s.transactionRepo.GetByTransactionIDForUpdate(ctx, transactionID)
+		if err != nil {
+			if errors.Is(err, repositories.ErrNotFound) {
+				return ErrTransactionNotFound
+			}
+			return fmt.Errorf("failed to get transaction: %w", err)
+		}
+		switch {
+		case original.ReversedBy != "":
+			return ErrAlreadyRefunded
+		case original.Type == entities.TransactionTypeRefund || original.Type == entities.TransactionTypeAdjustment ||
+			original.TransferID != "" || original.Cancelled || original.Pending:
+			return ErrNotRefundable
+		}
+
+		user, err := s.userRepo.GetByIDForUpdate(ctx, original.UserID)
+		if err != nil {
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+		currency := original.Currency
+		balance := user.Balance
+		if currency != "" {
+			wallet, err := s.walletRepo.GetForUpdate(ctx, user.ID, currency)
+			if err != nil {
+				return fmt.Errorf("failed to get wallet: %w", err)
+			}
+			balance = wallet.Balance
+		}
+
+		delta := original.SignedAmount().Neg()
+		newBalance = balance.Add(delta)
+		if delta.IsNegative() && newBalance.Add(creditLimit(user, currency)).IsNegative() {
+			return ErrInsufficientFunds
+		}
+
+		// Debits may not spend the amounts reserved by holds
+		if s.holdRepo != nil && currency == "" && delta.IsNegative() {
+			held, err := s.holdRepo.SumActive(ctx, user.ID, now)
+			if err != nil {
+				return err
+			}
+			if newBalance.Add(user.CreditLimit).Sub(held).IsNegative() {
+				return ErrInsufficientFunds
+			}
+		}
+
+		var ids TransactionIDs
+		if s.idGenerator != nil {
+			if ids, err = s.idGenerator.NextIDs(); err != nil {
+				return fmt.Errorf("failed to allocate transaction ID: %w", err)
+			}
+		}
+
+		refund = &entities.Transaction{
+			ID:            ids.Key,
+			UserID:        user.ID,
+			TransactionID: RefundTransactionID(original),
+			Receipt:       ids.Receipt,
+			State:         original.State.Opposite(),
+			Amount:        original.Amount,
+			SourceType:    original.SourceType,
+			Currency:      currency,
+			CreatedAt:     now,
+			BalanceAfter:  &newBalance,
+			Type:          entities.TransactionTypeRefund,
+			Reverses:      original.TransactionID,
+		}
+		event := &TransactionEvent{User: user, Transaction: refund, NewBalance: newBalance}
+
+		if err := s.runBeforeHooks(ctx, event); err != nil {
+			return err
+		}
+
+		if err := s.transactionRepo.Create(ctx, refund); err != nil {
+			if errors.Is(err, repositories.ErrConflict) {
+				return ErrDuplicateTransaction
+			}
+			return fmt.Errorf("failed to create refund: %w", err)
+		}
+		if err := s.transactionRepo.MarkReversed(ctx, original.ID, refund.TransactionID); err != nil {
+			return fmt.Errorf("failed to link refund: %w", err)
+		}
+
+		if currency != "" {
+			if err := s.walletRepo.UpdateBalance(ctx, user.ID, currency, newBalance); err != nil {
+				return fmt.Errorf("failed to update wallet balance: %w", err)
+			}
+		} else if err := s.userRepo.UpdateBalance(ctx, user.ID, newBalance); err != nil {
+			return fmt.Errorf("failed to update user balance: %w", err)
+		}
+
+		return s.runAfterHooks(ctx, event)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if refund.Currency == "" {
+		s.cacheBalance(ctx, refund.UserID, newBalance, time.Now())
+		s.invalidateBalance(ctx, refund.UserID)
+	}
+
+	return &entities.TransactionResult{
+		UserID:        refund.UserID,
+		ID:            refund.ID,
+		TransactionID: refund.TransactionID,
+		Receipt:       refund.Receipt,
+		Balance:       newBalance.StringFixed(2),
+		Currency:      s.currencyCode(refund.Currency),
+	}, nil
+}
+
+// RefundTransactionID returns the transaction ID of the refund of a
+// transaction. It is derived from the internal ID so that it stays within the
+// length of a transaction ID.
+func RefundTransactionID(original *entities.Transaction) string {
+	return "refund:" + strconv.FormatUint(original.ID, 10)
+}
+
+// Transfer moves an amount from one user to another. Both legs, a loss for
+// the sender and a win for the recipient, are recorded under the transfer ID
+// and applied in a single unit of work, so a transfer is applied entirely or
+// not at all. Transfers are operator actions: they are recorded with the
+// server source type and skip the balance guard and the jurisdiction rules,
+// but refuse frozen accounts and may not spend held amounts.
+//
+// Like transactions, transfers are idempotent: replaying a processed transfer
+// returns the original result with Replayed set, while reusing a transfer ID
+// for a different transfer fails with ErrDuplicateTransfer.
+//
+// System accounts may send more than their base currency balance, which
+// then goes negative.
+func (s *TransactionService) Transfer(ctx context.Context, req entities.TransferRequest) (*entities.TransferResult, error) {
+	if req.TransferID == "" || len(req.TransferID) > MaxTransferIDLength {
+		return nil, fmt.Errorf("%w: transferId must be 1 to %d characters", ErrInvalidTransfer, MaxTransferIDLength)
+	}
+	return s.transfer(ctx, req)
+}
+
+// transfer is Transfer without the length limit of the transfer ID, for the
+// transfers whose ID is derived from a shorter one
+func (s *TransactionService) transfer(ctx context.Context, req entities.TransferRequest) (*entities.TransferResult, error) {
+	// Writes belong to the active region
+	if s.region != nil && !s.region.AcceptsWrites() {
+		return nil, ErrRegionStandby
+	}
+
+	if req.TransferID == "" {
+		return nil, fmt.Errorf("%w: transferId must not be empty", ErrInvalidTransfer)
+	}
+	if req.FromUserID == 0 || req.ToUserID == 0 {
+		return nil, fmt.Errorf("%w: user IDs must be positive", ErrInvalidTransfer)
+	}
+	if req.FromUserID == req.ToUserID {
+		return nil, fmt.Errorf("%w: a user cannot transfer to themselves", ErrInvalidTransfer)
+	}
+	amount, err := decimal.NewFromString(req.Amount)
+	if err != nil || !amount.IsPositive() {
+		return nil, ErrInvalidAmount
+	}
+	currency, err := s.walletCurrency(req.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	debitID, creditID := TransferTransactionIDs(req.TransferID)
+	var debit, credit *entities.Transaction
+	var replayed *entities.TransferResult
+
+	err = s.uow.WithinTransaction(ctx, func(ctx context.Context) error {
+		// Lock both users, and then their wallets, in ascending ID order, so
+		// that concurrent transfers between the same users in opposite
+		// directions cannot deadlock. The house goes first, as in the units
+		// of work crediting it with fees. The locks also serialize retries of
+		// the same transfer.
+		userIDs := []uint64{req.FromUserID, req.ToUserID}
+		s.lockOrder(userIDs)
+
+		users := make(map[uint64]*entities.User, len(userIDs))
+		for _, userID := range userIDs {
+			user, err := s.userRepo.GetByIDForUpdate(ctx, userID)
+			if err != nil {
+				if errors.Is(err, repositories.ErrNotFound) {
+					return ErrUserNotFound
+				}
+				return fmt.Errorf("failed to get user: %w", err)
+			}
+			users[userID] = user
+		}
+
+		// Replay the original result of a transfer that was already processed
+		existingDebit, err := s.transactionRepo.GetByTransactionID(ctx, debitID)
+		if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+			return fmt.Errorf("failed to check transfer existence: %w", err)
+		}
+		if existingDebit != nil {
+			existingCredit, err := s.transactionRepo.GetByTransactionID(ctx, creditID)
+			if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+				return fmt.Errorf("failed to check transfer existence: %w", err)
+			}
+			if existingCredit == nil || !isTransferReplay(existingDebit, existingCredit, req, amount, currency) {
+				return ErrDuplicateTransfer
+			}
+			replayed = s.newTransferResult(existingDebit, existingCredit)
+			replayed.Replayed = true
+			return nil
+		}
+
+		for _, user := range users {
+			if user.Status == entities.UserStatusFrozen {
+				return ErrAccountFrozen
+			}
+		}
+
+		balances := make(map[uint64]decimal.Decimal, len(userIDs))
+		for _, userID := range userIDs {
+			balances[userID] = users[userID].Balance
+			if currency != "" {
+				wallet, err := s.walletRepo.GetForUpdate(ctx, userID, currency)
+				if err != nil {
+					return fmt.Errorf("failed to get wallet: %w", err)
+				}
+				balances[userID] = wallet.Balance
+			}
+		}
+
+		// Wallets never go negative, base currency balances of system
+		// accounts do
+		overdraft := currency == "" && s.isSystemAccount(req.FromUserID)
+		senderBalance := balances[req.FromUserID].Sub(amount)
+		if senderBalance.Add(creditLimit(users[req.FromUserID], currency)).IsNegative() && !overdraft {
+			return ErrInsufficientFunds
+		}
+
+		// Transfers may not spend the amounts reserved by holds
+		if s.holdRepo != nil && currency == "" && !overdraft {
+			held, err := s.holdRepo.SumActive(ctx, req.FromUserID, now)
+			if err != nil {
+				return err
+			}
+			if senderBalance.Add(users[req.FromUserID].CreditLimit).Sub(held).IsNegative() {
+				return ErrInsufficientFunds
+			}
+		}
+		recipientBalance := balances[req.ToUserID].Add(amount)
+
+		debit = &entities.Transaction{
+			UserID:        req.FromUserID,
+			TransactionID: debitID,
+			State:         entities.StateLose,
+			Amount:        amount,
+			SourceType:    entities.SourceTypeServer,
+			Currency:      currency,
+			CreatedAt:     now,
+			BalanceAfter:  &senderBalance,
+			Type:          entities.TransactionTypeTransfer,
+			TransferID:    req.TransferID,
+		}
+		if err := s.recordLeg(ctx, users[req.FromUserID], debit, ErrDuplicateTransfer); err != nil {
+			return err
+		}
+
+		credit = &entities.Transaction{
+			UserID:        req.ToUserID,
+			TransactionID: creditID,
+			State:         entities.StateWin,
+			Amount:        amount,
+			SourceType:    entities.SourceTypeServer,
+			Currency:      currency,
+			CreatedAt:     now,
+			BalanceAfter:  &recipientBalance,
+			Type:          entities.TransactionTypeTransfer,
+			TransferID:    req.TransferID,
+		}
+		return s.recordLeg(ctx, users[req.ToUserID], credit, ErrDuplicateTransfer)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if replayed != nil {
+		return replayed, nil
 	}
 
-	// Save the transaction
-	if err := s.transactionRepo.Create(ctx, transaction); err != nil {
-		return fmt.Errorf("failed to create transaction: %w", err)
+	// Only the base currency balance is cached
+	if currency == "" {
+		for _, leg := range []*entities.Transaction{debit, credit} {
+			s.cacheBalance(ctx, leg.UserID, *leg.BalanceAfter, time.Now())
+			s.invalidateBalance(ctx, leg.UserID)
+		}
 	}
 
-	// Update user balance
-	if err := s.userRepo.UpdateBalance(ctx, userID, newBalance); err != nil {
+	return s.newTransferResult(debit, credit), nil
+}
+
+// recordLeg records a leg of a transfer, an adjustment, or the contra leg of
+// a fee or an adjustment, and applies it to the balance it moves, failing with
+// duplicate when its transaction ID is already used. The user must be locked.
+func (s *TransactionService) recordLeg(ctx context.Context, user *entities.User, leg *entities.Transaction, duplicate error) error {
+	if s.idGenerator != nil {
+		ids, err := s.idGenerator.NextIDs()
+		if err != nil {
+			return fmt.Errorf("failed to allocate transaction ID: %w", err)
+		}
+		leg.ID, leg.Receipt = ids.Key, ids.Receipt
+	}
+	event := &TransactionEvent{User: user, Transaction: leg, NewBalance: *leg.BalanceAfter}
+
+	if err := s.runBeforeHooks(ctx, event); err != nil {
+		return err
+	}
+
+	if err := s.transactionRepo.Create(ctx, leg); err != nil {
+		// The transaction ID of the leg is already used by a transaction
+		if errors.Is(err, repositories.ErrConflict) {
+			return duplicate
+		}
+		return fmt.Errorf("failed to create leg: %w", err)
+	}
+
+	if leg.Currency != "" {
+		if err := s.walletRepo.UpdateBalance(ctx, user.ID, leg.Currency, *leg.BalanceAfter); err != nil {
+			return fmt.Errorf("failed to update wallet balance: %w", err)
+		}
+	} else if err := s.userRepo.UpdateBalance(ctx, user.ID, *leg.BalanceAfter); err != nil {
 		return fmt.Errorf("failed to update user balance: %w", err)
 	}
 
-	return nil
+	return s.runAfterHooks(ctx, event)
+}
+
+// isTransferReplay reports whether the legs of an already processed transfer
+// match the incoming request, so its original result can be returned
+func isTransferReplay(
+	debit, credit *entities.Transaction,
+	req entities.TransferRequest,
+	amount decimal.Decimal,
+	currency string,
+) bool {
+	return debit.TransferID == req.TransferID &&
+		credit.TransferID == req.TransferID &&
+		debit.BalanceAfter != nil &&
+		credit.BalanceAfter != nil &&
+		debit.UserID == req.FromUserID &&
+		credit.UserID == req.ToUserID &&
+		debit.Amount.Equal(amount) &&
+		debit.Currency == currency
+}
+
+// newTransferResult describes the transfer recorded by its legs
+func (s *TransactionService) newTransferResult(debit, credit *entities.Transaction) *entities.TransferResult {
+	return &entities.TransferResult{
+		TransferID: debit.TransferID,
+		Amount:     debit.Amount.StringFixed(2),
+		Currency:   s.currencyCode(debit.Currency),
+		From: entities.TransferLeg{
+			UserID:        debit.UserID,
+			TransactionID: debit.TransactionID,
+			Receipt:       debit.Receipt,
+			Balance:       debit.BalanceAfter.StringFixed(2),
+		},
+		To: entities.TransferLeg{
+			UserID:        credit.UserID,
+			TransactionID: credit.TransactionID,
+			Receipt:       credit.Receipt,
+			Balance:       credit.BalanceAfter.StringFixed(2),
+		},
+	}
+}
+
+// TransferTransactionIDs returns the transaction IDs of the debit and the
+// credit leg of a transfer
+func TransferTransactionIDs(transferID string) (debit, credit string) {
+	return "transfer:" + transferID + ":debit", "transfer:" + transferID + ":credit"
+}
+
+// ChargeFee charges a fee of up to amount to the user's base currency
+// balance, recorded as a fee transaction under feeID. It must run in a unit of
+// work that has locked the house account, when there are system accounts,
+// and then the user. Fees never spend held amounts nor make the
+// balance negative: the fee is capped at the available balance, and nothing is
+// charged, returning a nil transaction, when nothing is available. Fees are
+// charged by the service, so they are applied to frozen accounts and skip the
+// balance guard and the jurisdiction rules.
+//
+// The caller caches the resulting balance once its unit of work committed.
+func (s *TransactionService) ChargeFee(
+	ctx context.Context,
+	user *entities.User,
+	feeID string,
+	amount decimal.Decimal,
+) (*entities.Transaction, error) {
+	return s.chargeFee(ctx, user, feeID, amount, nil)
+}
+
+// chargeTransactionFee charges the fee decided by the fee calculator for a
+// transaction just recorded for the locked user. It returns nil when no fee
+// applies or nothing is available to pay it.
+func (s *TransactionService) chargeTransactionFee(
+	ctx context.Context,
+	user *entities.User,
+	transaction *entities.Transaction,
+) (*entities.Transaction, error) {
+	amount, err := s.fees.Fee(ctx, transaction)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate fee: %w", err)
+	}
+	if !amount.IsPositive() {
+		return nil, nil
+	}
+	return s.chargeFee(ctx, user, FeeTransactionID(transaction.ID), amount, transaction)
+}
+
+// chargeFee is ChargeFee linking the fee to the transaction it is charged
+// for, when not nil, and to its round
+func (s *TransactionService) chargeFee(
+	ctx context.Context,
+	user *entities.User,
+	feeID string,
+	amount decimal.Decimal,
+	chargedFor *entities.Transaction,
+) (*entities.Transaction, error) {
+	// Writes belong to the active region
+	if s.region != nil && !s.region.AcceptsWrites() {
+		return nil, ErrRegionStandby
+	}
+	if !amount.IsPositive() {
+		return nil, ErrInvalidAmount
+	}
+
+	now := s.now()
+	var fee *entities.Transaction
+
+	err := s.uow.WithinTransaction(ctx, func(ctx context.Context) error {
+		available := user.Balance
+		if s.holdRepo != nil {
+			held, err := s.holdRepo.SumActive(ctx, user.ID, now)
+			if err != nil {
+				return err
+			}
+			available = available.Sub(held)
+		}
+		charged := decimal.Min(amount, available)
+		if !charged.IsPositive() {
+			return nil
+		}
+
+		var ids TransactionIDs
+		if s.idGenerator != nil {
+			var err error
+			if ids, err = s.idGenerator.NextIDs(); err != nil {
+				return fmt.Errorf("failed to allocate transaction ID: %w", err)
+			}
+		}
+
+		newBalance := user.Balance.Sub(charged)
+		fee = &entities.Transaction{
+			ID:            ids.Key,
+			UserID:        user.ID,
+			TransactionID: feeID,
+			Receipt:       ids.Receipt,
+			State:         entities.StateLose,
+			Amount:        charged,
+			SourceType:    entities.SourceTypeServer,
+			CreatedAt:     now,
+			BalanceAfter:  &newBalance,
+			Type:          entities.TransactionTypeFee,
+		}
+		if chargedFor != nil {
+			fee.ChargedFor = chargedFor.TransactionID
+			fee.RoundID = chargedFor.RoundID
+		}
+		event := &TransactionEvent{User: user, Transaction: fee, NewBalance: newBalance}
+
+		if err := s.runBeforeHooks(ctx, event); err != nil {
+			return err
+		}
+
+		if err := s.transactionRepo.Create(ctx, fee); err != nil {
+			if errors.Is(err, repositories.ErrConflict) {
+				return ErrDuplicateTransaction
+			}
+			return fmt.Errorf("failed to create fee: %w", err)
+		}
+		if err := s.userRepo.UpdateBalance(ctx, user.ID, newBalance); err != nil {
+			return fmt.Errorf("failed to update user balance: %w", err)
+		}
+		user.Balance = newBalance
+
+		if err := s.runAfterHooks(ctx, event); err != nil {
+			return err
+		}
+		return s.creditHouse(ctx, fee)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if house, ok := s.systemAccount(entities.SystemAccountHouse); ok && fee != nil {
+		s.invalidateBalance(ctx, house)
+	}
+	return fee, nil
+}
+
+// walletCurrency validates the requested ISO 4217 currency and returns the
+// wallet it selects, or an empty string for the base currency
+func (s *TransactionService) walletCurrency(code string) (string, error) {
+	if code == "" {
+		return "", nil
+	}
+	currency, ok := entities.LookupCurrency(code)
+	if !ok {
+		return "", ErrInvalidCurrency
+	}
+
+	switch {
+	case currency.Code == s.baseCurrency:
+		return "", nil
+	case s.walletCurrencies[currency.Code]:
+		return currency.Code, nil
+	default:
+		return "", ErrUnsupportedCurrency
+	}
+}
+
+// currencyCode returns the ISO 4217 code of a wallet currency, resolving the
+// empty base currency
+func (s *TransactionService) currencyCode(currency string) string {
+	if currency == "" {
+		return s.baseCurrency
+	}
+	return currency
 }
 
 // GetUserBalance retrieves the current user balance
 func (s *TransactionService) GetUserBalance(
 	ctx context.Context,
 	userID uint64,
+) (*entities.BalanceResponse, error) {
+	balance, err := s.getUserBalance(ctx, userID)
+	s.shadowBalance(ctx, userID, balance, err)
+	return balance, err
+}
+
+func (s *TransactionService) getUserBalance(
+	ctx context.Context,
+	userID uint64,
 ) (*entities.BalanceResponse, error) {
 	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
-		return nil, ErrUserNotFound
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, ErrUserNotFound
+		}
+		if stale, ok := s.staleBalance(ctx, userID); ok {
+			return stale, nil
+		}
+		return nil, fmt.Errorf("failed to get user balance: %w", err)
 	}
 
-	return &entities.BalanceResponse{
-		UserID:  user.ID,
-		Balance: user.Balance.StringFixed(2),
+	s.cacheBalance(ctx, userID, user.Balance, time.Now())
+
+	response := &entities.BalanceResponse{
+		UserID:   user.ID,
+		Balance:  user.Balance.StringFixed(2),
+		Currency: s.baseCurrency,
+	}
+
+	if s.holdRepo != nil {
+		held, err := s.holdRepo.SumActive(ctx, userID, s.now())
+		if err != nil {
+			return nil, err
+		}
+		response.Available = user.Balance.Sub(held).StringFixed(2)
+	}
+
+	if s.settlement {
+		pending, err := s.transactionRepo.SumPending(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pending balance: %w", err)
+		}
+		response.Pending = pending.StringFixed(2)
+	}
+
+	if s.walletRepo != nil {
+		if user.Wallets, err = s.walletRepo.ListByUser(ctx, userID); err != nil {
+			return nil, fmt.Errorf("failed to get wallets: %w", err)
+		}
+		response.Balances = map[string]string{s.baseCurrency: response.Balance}
+		for _, wallet := range user.Wallets {
+			response.Balances[wallet.Currency] = wallet.Balance.StringFixed(2)
+		}
+	}
+
+	return response, nil
+}
+
+// GetUserTransactions returns a page of the user's transactions matching
+// filter, newest first. The user ID of the filter is ignored.
+func (s *TransactionService) GetUserTransactions(
+	ctx context.Context,
+	userID uint64,
+	filter repositories.TransactionFilter,
+) (*entities.TransactionPage, error) {
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	filter.UserID = userID
+	return s.SearchTransactions(ctx, filter)
+}
+
+// ExportUserTransactions passes the user's transactions matching filter to
+// fn, newest first. They are read a page at a time, so that histories of any
+// length are exported in bounded memory. The user ID, limit, offset and
+// cursor of the filter are ignored. It stops at the first error of fn.
+func (s *TransactionService) ExportUserTransactions(
+	ctx context.Context,
+	userID uint64,
+	filter repositories.TransactionFilter,
+	fn func(*entities.Transaction) error,
+) error {
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	filter.UserID = userID
+	filter.Limit = MaxPageSize
+	filter.Offset = 0
+	filter.After = nil
+	for {
+		page, err := s.SearchTransactions(ctx, filter)
+		if err != nil {
+			return err
+		}
+		for _, transaction := range page.Transactions {
+			if err := fn(transaction); err != nil {
+				return err
+			}
+		}
+		if page.NextCursor == "" {
+			return nil
+		}
+		if filter.After, err = ParseTransactionCursor(page.NextCursor); err != nil {
+			return err
+		}
+	}
+}
+
+// originalResult returns the result a processed transaction was answered
+// with
+func (s *TransactionService) originalResult(
+	ctx context.Context,
+	transaction *entities.Transaction,
+) (*entities.TransactionResult, error) {
+	result := &entities.TransactionResult{
+		UserID:        transaction.UserID,
+		ID:            transaction.ID,
+		TransactionID: transaction.TransactionID,
+		Receipt:       transaction.Receipt,
+		Currency:      s.currencyCode(transaction.Currency),
+		Pending:       transaction.Pending || transaction.SettledAt != nil,
+	}
+	if transaction.BalanceAfter != nil {
+		result.Balance = transaction.BalanceAfter.StringFixed(2)
+	}
+
+	// The balance of the result is the one left after the fee
+	charged, err := s.transactionRepo.GetByTransactionID(ctx, FeeTransactionID(transaction.ID))
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		return nil, fmt.Errorf("failed to get fee: %w", err)
+	}
+	if charged != nil && charged.ChargedFor == transaction.TransactionID && charged.BalanceAfter != nil {
+		result.Balance = charged.BalanceAfter.StringFixed(2)
+		result.Fee = charged.Amount.StringFixed(2)
+	}
+	return result, nil
+}
+
+// TransactionStatus reports a transaction of the ledger as processed, with
+// the result it was answered with
+func (s *TransactionService) TransactionStatus(ctx context.Context, transactionID string) (*entities.AsyncTransaction, error) {
+	transaction, err := s.GetTransaction(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.originalResult(ctx, transaction)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entities.AsyncTransaction{
+		TransactionID: transaction.TransactionID,
+		UserID:        transaction.UserID,
+		Status:        entities.AsyncTransactionProcessed,
+		Result:        result,
+	}, nil
+}
+
+// GetTransaction returns a transaction of any user by its external ID
+func (s *TransactionService) GetTransaction(ctx context.Context, transactionID string) (*entities.Transaction, error) {
+	transaction, err := s.transactionRepo.GetByTransactionID(ctx, transactionID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, ErrTransactionNotFound
+		}
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	}
+	return transaction, nil
+}
+
+// GetRoundSummary totals the user's transactions of a game round in a
+// currency, the base currency when empty, so that providers can verify the
+// settlement of a round without paging through the history
+func (s *TransactionService) GetRoundSummary(
+	ctx context.Context,
+	userID uint64,
+	roundID string,
+	currencyCode string,
+) (*entities.RoundSummary, error) {
+	if roundID == "" || len(roundID) > MaxRoundIDLength {
+		return nil, ErrInvalidRoundID
+	}
+	currency, err := s.walletCurrency(currencyCode)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	totals, err := s.transactionRepo.SummarizeRound(ctx, userID, roundID, currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize round: %w", err)
+	}
+	if totals.Transactions == 0 && totals.Cancelled == 0 {
+		return nil, ErrRoundNotFound
+	}
+
+	return &entities.RoundSummary{
+		UserID:       userID,
+		RoundID:      roundID,
+		Currency:     s.currencyCode(currency),
+		Transactions: totals.Transactions,
+		Cancelled:    totals.Cancelled,
+		TotalBets:    totals.Bets.StringFixed(2),
+		TotalWins:    totals.Wins.StringFixed(2),
+		TotalFees:    totals.Fees.StringFixed(2),
+		Net:          totals.Wins.Sub(totals.Bets).Sub(totals.Fees).StringFixed(2),
 	}, nil
 }
+
+// SearchTransactions returns a page of transactions across all users
+func (s *TransactionService) SearchTransactions(
+	ctx context.Context,
+	filter repositories.TransactionFilter,
+) (*entities.TransactionPage, error) {
+	if filter.Limit < 0 || filter.Offset < 0 || filter.Limit > MaxPageSize {
+		return nil, ErrInvalidFilter
+	}
+	if filter.After != nil && filter.Offset != 0 {
+		return nil, ErrInvalidFilter
+	}
+	if filter.Limit == 0 {
+		filter.Limit = DefaultPageSize
+	}
+	if filter.SourceType != "" && !filter.SourceType.IsValid() {
+		return nil, ErrInvalidSourceType
+	}
+	if filter.State != "" && !filter.State.IsValid() {
+		return nil, ErrInvalidTransactionState
+	}
+	if filter.MinAmount != nil && filter.MaxAmount != nil && filter.MinAmount.GreaterThan(*filter.MaxAmount) {
+		return nil, ErrInvalidFilter
+	}
+	if filter.From != nil && filter.To != nil && filter.From.After(*filter.To) {
+		return nil, ErrInvalidFilter
+	}
+	if filter.MetadataKey != "" && !ValidMetadataKey(filter.MetadataKey) ||
+		filter.MetadataKey == "" && filter.MetadataValue != nil {
+		return nil, ErrInvalidFilter
+	}
+
+	// One more transaction tells whether there is a next page
+	limit := filter.Limit
+	filter.Limit++
+	transactions, total, err := s.transactionRepo.Search(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search transactions: %w", err)
+	}
+	var nextCursor string
+	if len(transactions) > limit {
+		transactions = transactions[:limit]
+		last := transactions[limit-1]
+		nextCursor = FormatTransactionCursor(repositories.TransactionCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	if transactions == nil {
+		transactions = []*entities.Transaction{}
+	}
+	for _, transaction := range transactions {
+		transaction.Currency = s.currencyCode(transaction.Currency)
+	}
+
+	return &entities.TransactionPage{
+		Transactions: transactions,
+		Total:        total,
+		Limit:        limit,
+		Offset:       filter.Offset,
+		NextCursor:   nextCursor,
+	}, nil
+}
+
+// FormatTransactionCursor returns the opaque form of cursor handed to clients
+func FormatTransactionCursor(cursor repositories.TransactionCursor) string {
+	return fmt.Sprintf("%d.%d", cursor.CreatedAt.UnixNano(), cursor.ID)
+}
+
+// ParseTransactionCursor parses a cursor formatted by FormatTransactionCursor
+func ParseTransactionCursor(value string) (*repositories.TransactionCursor, error) {
+	createdPart, idPart, ok := strings.Cut(value, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	created, err := strconv.ParseInt(createdPart, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	id, err := strconv.ParseUint(idPart, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &repositories.TransactionCursor{CreatedAt: time.Unix(0, created).UTC(), ID: id}, nil
+}
+
+// cacheBalance remembers a balance known to be current at the given time
+func (s *TransactionService) cacheBalance(ctx context.Context, userID uint64, balance decimal.Decimal, at time.Time) {
+	if s.balanceCache == nil || IsShadowRun(ctx) {
+		return
+	}
+	s.balanceCache.Set(ctx, userID, CachedBalance{Balance: balance, CachedAt: at})
+}
+
+// invalidateBalance tells other replicas that their cached balance for the
+// user is outdated. Failures only delay invalidation until the entry ages out,
+// so they are logged rather than returned.
+func (s *TransactionService) invalidateBalance(ctx context.Context, userID uint64) {
+	if s.invalidator == nil || IsShadowRun(ctx) {
+		return
+	}
+	if err := s.invalidator.Invalidate(ctx, userID); err != nil {
+		logging.FromContext(ctx, &s.logger).Warn().Err(err).
+			Uint64("user_id", userID).
+			Msg("failed to publish balance invalidation")
+	}
+}
+
+// staleBalance returns the cached balance if the fallback is enabled and the
+// cached value is recent enough
+func (s *TransactionService) staleBalance(ctx context.Context, userID uint64) (*entities.BalanceResponse, bool) {
+	if s.balanceCache == nil {
+		return nil, false
+	}
+
+	cached, ok := s.balanceCache.Get(ctx, userID)
+	if !ok || time.Since(cached.CachedAt) > s.maxStaleness {
+		return nil, false
+	}
+
+	asOf := cached.CachedAt
+	return &entities.BalanceResponse{
+		UserID:   userID,
+		Balance:  cached.Balance.StringFixed(2),
+		Currency: s.baseCurrency,
+		Stale:    true,
+		AsOf:     &asOf,
+	}, true
+}
@@ -0,0 +1,1103 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionService_ProcessTransaction_OccurredAt(t *testing.T) {
+	policy := ClockSkewPolicy{
+		Default: time.Minute,
+		PerSource: map[entities.SourceType]time.Duration{
+			entities.SourceTypePayment: time.Hour,
+		},
+	}
+
+	tests := []struct {
+		name       string
+		sourceType entities.SourceType
+		offset     time.Duration
+		wantErr    error
+	}{
+		{name: "within default tolerance", sourceType: entities.SourceTypeGame, offset: -30 * time.Second},
+		{name: "future within default tolerance", sourceType: entities.SourceTypeGame, offset: 30 * time.Second},
+		{name: "outside default tolerance", sourceType: entities.SourceTypeGame, offset: -2 * time.Minute, wantErr: ErrInvalidOccurredAt},
+		{name: "future outside default tolerance", sourceType: entities.SourceTypeServer, offset: 2 * time.Minute, wantErr: ErrInvalidOccurredAt},
+		{name: "within per-source tolerance", sourceType: entities.SourceTypePayment, offset: -30 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(100)})
+			transactionRepo := newFakeTransactionRepo()
+			service := NewTransactionService(&fakeUnitOfWork{}, userRepo, transactionRepo, WithClockSkewPolicy(policy))
+
+			occurredAt := time.Now().Add(tt.offset)
+			_, err := service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+				State:         "win",
+				Amount:        "10.00",
+				TransactionID: "tx-1",
+				OccurredAt:    &occurredAt,
+			}, tt.sourceType)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			transactions, err := transactionRepo.GetByUserID(ctx, 1)
+			require.NoError(t, err)
+			require.Len(t, transactions, 1)
+			assert.True(t, occurredAt.Equal(transactions[0].BusinessTime()))
+		})
+	}
+}
+
+type mapBalanceCache map[uint64]CachedBalance
+
+func (c mapBalanceCache) Get(ctx context.Context, userID uint64) (CachedBalance, bool) {
+	balance, ok := c[userID]
+	return balance, ok
+}
+
+func (c mapBalanceCache) Set(ctx context.Context, userID uint64, balance CachedBalance) {
+	c[userID] = balance
+}
+
+func TestTransactionService_GetUserBalance_StaleFallback(t *testing.T) {
+	ctx := context.Background()
+	outage := errors.New("connection refused")
+
+	t.Run("serves the cached balance during an outage", func(t *testing.T) {
+		userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(100)})
+		service := NewTransactionService(&fakeUnitOfWork{}, userRepo, newFakeTransactionRepo(),
+			WithStaleBalanceFallback(mapBalanceCache{}, time.Minute))
+
+		_, err := service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "win", Amount: "5.00", TransactionID: "tx-1",
+		}, entities.SourceTypeGame)
+		require.NoError(t, err)
+
+		userRepo.getErr = outage
+		balance, err := service.GetUserBalance(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, "105.00", balance.Balance)
+		assert.True(t, balance.Stale)
+		assert.NotNil(t, balance.AsOf)
+	})
+
+	t.Run("expired cache entries are not served", func(t *testing.T) {
+		cache := mapBalanceCache{1: {Balance: decimal.NewFromInt(100), CachedAt: time.Now().Add(-time.Hour)}}
+		userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(100)})
+		userRepo.getErr = outage
+		service := NewTransactionService(&fakeUnitOfWork{}, userRepo, newFakeTransactionRepo(),
+			WithStaleBalanceFallback(cache, time.Minute))
+
+		_, err := service.GetUserBalance(ctx, 1)
+		assert.ErrorIs(t, err, outage)
+	})
+
+	t.Run("writes fail fast during an outage", func(t *testing.T) {
+		cache := mapBalanceCache{1: {Balance: decimal.NewFromInt(100), CachedAt: time.Now()}}
+		userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(100)})
+		userRepo.getErr = outage
+		service := NewTransactionService(&fakeUnitOfWork{}, userRepo, newFakeTransactionRepo(),
+			WithStaleBalanceFallback(cache, time.Minute))
+
+		_, err := service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "win", Amount: "5.00", TransactionID: "tx-1",
+		}, entities.SourceTypeGame)
+		assert.Error(t, err)
+	})
+
+	t.Run("unknown users are not served from cache", func(t *testing.T) {
+		cache := mapBalanceCache{2: {Balance: decimal.NewFromInt(100), CachedAt: time.Now()}}
+		service := NewTransactionService(&fakeUnitOfWork{}, newFakeUserRepo(), newFakeTransactionRepo(),
+			WithStaleBalanceFallback(cache, time.Minute))
+
+		_, err := service.GetUserBalance(ctx, 2)
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+}
+
+func TestTransactionService_ProcessTransaction_Atomicity(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("successful processing commits once", func(t *testing.T) {
+		uow := &fakeUnitOfWork{}
+		userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(100)})
+		service := NewTransactionService(uow, userRepo, newFakeTransactionRepo())
+
+		_, err := service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "lose", Amount: "40.00", TransactionID: "tx-1",
+		}, entities.SourceTypeGame)
+		require.NoError(t, err)
+		assert.Equal(t, 1, uow.commits)
+		assert.Equal(t, 0, uow.rollbacks)
+	})
+
+	t.Run("a failing balance update rolls back the insert", func(t *testing.T) {
+		uow := &fakeUnitOfWork{}
+		userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(100)})
+		userRepo.updateErr = errors.New("connection reset")
+		service := NewTransactionService(uow, userRepo, newFakeTransactionRepo())
+
+		_, err := service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "lose", Amount: "40.00", TransactionID: "tx-1",
+		}, entities.SourceTypeGame)
+		assert.ErrorIs(t, err, userRepo.updateErr)
+		assert.Equal(t, 0, uow.commits)
+		assert.Equal(t, 1, uow.rollbacks)
+	})
+
+	t.Run("duplicates are detected inside the unit of work", func(t *testing.T) {
+		uow := &fakeUnitOfWork{}
+		userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(100)})
+		service := NewTransactionService(uow, userRepo, newFakeTransactionRepo())
+
+		_, err := service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "win", Amount: "1.00", TransactionID: "tx-1",
+		}, entities.SourceTypeGame)
+		require.NoError(t, err)
+
+		_, err = service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "win", Amount: "2.00", TransactionID: "tx-1",
+		}, entities.SourceTypeGame)
+		assert.ErrorIs(t, err, ErrDuplicateTransaction)
+		assert.Equal(t, 1, uow.rollbacks)
+	})
+}
+
+func TestTransactionService_ProcessTransaction_RepositoryErrors(t *testing.T) {
+	ctx := context.Background()
+	unavailable := fmt.Errorf("failed to get user: %w", repositories.ErrUnavailable)
+
+	tests := []struct {
+		name      string
+		getErr    error
+		createErr error
+		wantErr   error
+		notErr    error
+	}{
+		{
+			name:    "unavailable storage is not reported as a missing user",
+			getErr:  unavailable,
+			wantErr: ErrUnavailable,
+			notErr:  ErrUserNotFound,
+		},
+		{
+			name:      "conflicting inserts are duplicates",
+			createErr: fmt.Errorf("failed to create transaction: %w", repositories.ErrConflict),
+			wantErr:   ErrDuplicateTransaction,
+		},
+		{
+			name:      "other insert failures are kept",
+			createErr: unavailable,
+			wantErr:   ErrUnavailable,
+			notErr:    ErrDuplicateTransaction,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(100)})
+			userRepo.getErr = tt.getErr
+			transactionRepo := newFakeTransactionRepo()
+			transactionRepo.createErr = tt.createErr
+			service := NewTransactionService(&fakeUnitOfWork{}, userRepo, transactionRepo)
+
+			_, err := service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+				State: "win", Amount: "10.00", TransactionID: "tx-1",
+			}, entities.SourceTypeGame)
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.notErr != nil {
+				assert.NotErrorIs(t, err, tt.notErr)
+			}
+		})
+	}
+}
+
+func TestTransactionService_ProcessTransaction_Idempotency(t *testing.T) {
+	ctx := context.Background()
+	req := entities.TransactionRequest{State: "win", Amount: "10.00", TransactionID: "tx-1"}
+
+	t.Run("a replay returns the original result", func(t *testing.T) {
+		userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(100)})
+		transactionRepo := newFakeTransactionRepo()
+		service := NewTransactionService(&fakeUnitOfWork{}, userRepo, transactionRepo)
+
+		first, err := service.ProcessTransaction(ctx, 1, req, entities.SourceTypeGame)
+		require.NoError(t, err)
+		assert.Equal(t, "110.00", first.Balance)
+		assert.False(t, first.Replayed)
+
+		// A later transaction moves the balance on
+		_, err = service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "lose", Amount: "5.00", TransactionID: "tx-2",
+		}, entities.SourceTypeGame)
+		require.NoError(t, err)
+
+		replay, err := service.ProcessTransaction(ctx, 1, req, entities.SourceTypeGame)
+		require.NoError(t, err)
+		assert.True(t, replay.Replayed)
+		assert.Equal(t, "110.00", replay.Balance)
+
+		user, err := userRepo.GetByID(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, "105.00", user.Balance.StringFixed(2))
+		assert.Len(t, transactionRepo.transactions, 2)
+	})
+
+	t.Run("reusing an ID for another user or payload is rejected", func(t *testing.T) {
+		userRepo := newFakeUserRepo(
+			&entities.User{ID: 1, Balance: decimal.NewFromInt(100)},
+			&entities.User{ID: 2, Balance: decimal.NewFromInt(100)},
+		)
+		service := NewTransactionService(&fakeUnitOfWork{}, userRepo, newFakeTransactionRepo())
+
+		_, err := service.ProcessTransaction(ctx, 1, req, entities.SourceTypeGame)
+		require.NoError(t, err)
+
+		_, err = service.ProcessTransaction(ctx, 2, req, entities.SourceTypeGame)
+		assert.ErrorIs(t, err, ErrDuplicateTransaction)
+
+		_, err = service.ProcessTransaction(ctx, 1, req, entities.SourceTypePayment)
+		assert.ErrorIs(t, err, ErrDuplicateTransaction)
+	})
+
+	t.Run("replays of legacy transactions without a stored result are rejected", func(t *testing.T) {
+		userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(100)})
+		transactionRepo := newFakeTransactionRepo()
+		require.NoError(t, transactionRepo.Create(ctx, &entities.Transaction{
+			UserID: 1, TransactionID: "tx-1", State: entities.StateWin,
+			Amount: decimal.NewFromInt(10), SourceType: entities.SourceTypeGame,
+		}))
+		service := NewTransactionService(&fakeUnitOfWork{}, userRepo, transactionRepo)
+
+		_, err := service.ProcessTransaction(ctx, 1, req, entities.SourceTypeGame)
+		assert.ErrorIs(t, err, ErrDuplicateTransaction)
+	})
+
+	// The unique index rejects the insert of a transaction that a concurrent
+	// request recorded after the duplicate check
+	concurrent := func() *racingTransactionRepo {
+		balanceAfter := decimal.NewFromInt(110)
+		return &racingTransactionRepo{
+			fakeTransactionRepo: newFakeTransactionRepo(),
+			concurrent: &entities.Transaction{
+				ID: 41, UserID: 1, TransactionID: "tx-1", Receipt: "r-41", State: entities.StateWin,
+				Amount: decimal.NewFromInt(10), SourceType: entities.SourceTypeGame, BalanceAfter: &balanceAfter,
+			},
+		}
+	}
+
+	t.Run("a replay recorded concurrently returns the original result", func(t *testing.T) {
+		uow := &fakeUnitOfWork{}
+		userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(110)})
+		transactionRepo := concurrent()
+		service := NewTransactionService(uow, userRepo, transactionRepo)
+
+		replay, err := service.ProcessTransaction(ctx, 1, req, entities.SourceTypeGame)
+		require.NoError(t, err)
+		assert.True(t, replay.Replayed)
+		assert.Equal(t, uint64(41), replay.ID)
+		assert.Equal(t, "r-41", replay.Receipt)
+		assert.Equal(t, "110.00", replay.Balance)
+		assert.Equal(t, 1, uow.rollbacks)
+		assert.Equal(t, "110", userRepo.users[1].Balance.String())
+		assert.Len(t, transactionRepo.transactions, 1)
+	})
+
+	t.Run("another transaction recorded concurrently is rejected", func(t *testing.T) {
+		userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(110)})
+		service := NewTransactionService(&fakeUnitOfWork{}, userRepo, concurrent())
+
+		_, err := service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "win", Amount: "20.00", TransactionID: "tx-1",
+		}, entities.SourceTypeGame)
+		assert.ErrorIs(t, err, ErrDuplicateTransaction)
+	})
+}
+
+// racingTransactionRepo records the transaction of a concurrent request right
+// after the first lookup missed it
+type racingTransactionRepo struct {
+	*fakeTransactionRepo
+	concurrent *entities.Transaction
+}
+
+func (r *racingTransactionRepo) GetByTransactionID(ctx context.Context, transactionID string) (*entities.Transaction, error) {
+	if concurrent := r.concurrent; concurrent != nil {
+		r.concurrent = nil
+		if err := r.fakeTransactionRepo.Create(ctx, concurrent); err != nil {
+			return nil, err
+		}
+		return nil, repositories.ErrNotFound
+	}
+	return r.fakeTransactionRepo.GetByTransactionID(ctx, transactionID)
+}
+
+type fixedIDGenerator struct {
+	ids TransactionIDs
+	err error
+}
+
+func (g fixedIDGenerator) NextIDs() (TransactionIDs, error) {
+	return g.ids, g.err
+}
+
+func TestTransactionService_ProcessTransaction_IDGenerator(t *testing.T) {
+	ctx := context.Background()
+	req := entities.TransactionRequest{State: "win", Amount: "10.00", TransactionID: "tx-1"}
+
+	t.Run("generated IDs are stored and replayed", func(t *testing.T) {
+		userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(100)})
+		transactionRepo := newFakeTransactionRepo()
+		generator := fixedIDGenerator{ids: TransactionIDs{Key: 7340032, Receipt: "0190b1c2-receipt"}}
+		service := NewTransactionService(&fakeUnitOfWork{}, userRepo, transactionRepo, WithIDGenerator(generator))
+
+		result, err := service.ProcessTransaction(ctx, 1, req, entities.SourceTypeGame)
+		require.NoError(t, err)
+		assert.Equal(t, "0190b1c2-receipt", result.Receipt)
+		assert.Equal(t, uint64(7340032), result.ID)
+		require.Len(t, transactionRepo.transactions, 1)
+		assert.Equal(t, uint64(7340032), transactionRepo.transactions[0].ID)
+
+		replay, err := service.ProcessTransaction(ctx, 1, req, entities.SourceTypeGame)
+		require.NoError(t, err)
+		assert.Equal(t, "0190b1c2-receipt", replay.Receipt)
+		assert.Equal(t, uint64(7340032), replay.ID)
+	})
+
+	t.Run("the receipt defaults to the sequence ID", func(t *testing.T) {
+		userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(100)})
+		service := NewTransactionService(&fakeUnitOfWork{}, userRepo, newFakeTransactionRepo())
+
+		result, err := service.ProcessTransaction(ctx, 1, req, entities.SourceTypeGame)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(1), result.ID)
+		assert.Equal(t, "1", result.Receipt)
+	})
+
+	t.Run("allocation failures roll back", func(t *testing.T) {
+		userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(100)})
+		uow := &fakeUnitOfWork{}
+		generator := fixedIDGenerator{err: errors.New("clock moved backwards")}
+		service := NewTransactionService(uow, userRepo, newFakeTransactionRepo(), WithIDGenerator(generator))
+
+		_, err := service.ProcessTransaction(ctx, 1, req, entities.SourceTypeGame)
+		assert.Error(t, err)
+		assert.Equal(t, 1, uow.rollbacks)
+	})
+}
+
+type fixedRegionGate bool
+
+func (g fixedRegionGate) AcceptsWrites() bool {
+	return bool(g)
+}
+
+func TestTransactionService_ProcessTransaction_RegionStandby(t *testing.T) {
+	ctx := context.Background()
+	userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(100)})
+	transactionRepo := newFakeTransactionRepo()
+	service := NewTransactionService(&fakeUnitOfWork{}, userRepo, transactionRepo, WithRegionGate(fixedRegionGate(false)))
+
+	_, err := service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+		State: "win", Amount: "10.00", TransactionID: "tx-1",
+	}, entities.SourceTypeGame)
+	assert.ErrorIs(t, err, ErrRegionStandby)
+	assert.Empty(t, transactionRepo.transactions)
+
+	// Reads keep being served from the replica
+	balance, err := service.GetUserBalance(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "100.00", balance.Balance)
+}
+
+func TestTransactionService_ProcessTransaction_Metadata(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("metadata is recorded with the transaction", func(t *testing.T) {
+		userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(100)})
+		transactionRepo := newFakeTransactionRepo()
+		service := NewTransactionService(&fakeUnitOfWork{}, userRepo, transactionRepo)
+
+		metadata := map[string]string{"table.id": "t-7", "provider_ref": "p-123"}
+		_, err := service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "win", Amount: "10.00", TransactionID: "tx-1", Metadata: metadata,
+		}, entities.SourceTypeGame)
+		require.NoError(t, err)
+		require.Len(t, transactionRepo.transactions, 1)
+		assert.Equal(t, metadata, transactionRepo.transactions[0].Metadata)
+	})
+
+	tooMany := make(map[string]string)
+	for i := range MaxMetadataKeys + 1 {
+		tooMany[strconv.Itoa(i)] = "x"
+	}
+	for name, metadata := range map[string]map[string]string{
+		"too many keys":    tooMany,
+		"empty key":        {"": "x"},
+		"key too long":     {strings.Repeat("k", MaxMetadataKeyLength+1): "x"},
+		"key with a quote": {`table"id`: "x"},
+		"value too long":   {"table.id": strings.Repeat("v", MaxMetadataValueLength+1)},
+	} {
+		t.Run(name+" is rejected", func(t *testing.T) {
+			userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(100)})
+			transactionRepo := newFakeTransactionRepo()
+			service := NewTransactionService(&fakeUnitOfWork{}, userRepo, transactionRepo)
+
+			_, err := service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+				State: "win", Amount: "10.00", TransactionID: "tx-1", Metadata: metadata,
+			}, entities.SourceTypeGame)
+			assert.ErrorIs(t, err, ErrInvalidMetadata)
+			assert.Empty(t, transactionRepo.transactions)
+		})
+	}
+}
+
+// isolationRecordingUnitOfWork records whether its units of work asked for
+// serializable isolation or snapshot reads
+type isolationRecordingUnitOfWork struct {
+	fakeUnitOfWork
+	serializable []bool
+	snapshotRead []bool
+}
+
+func (u *isolationRecordingUnitOfWork) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	u.serializable = append(u.serializable, repositories.Serializable(ctx))
+	u.snapshotRead = append(u.snapshotRead, repositories.SnapshotRead(ctx))
+	return u.fakeUnitOfWork.WithinTransaction(ctx, fn)
+}
+
+func TestTransactionService_ProcessTransaction_Serializable(t *testing.T) {
+	ctx := context.Background()
+	for name, tt := range map[string]struct {
+		opts []TransactionServiceOption
+		want bool
+	}{
+		"serializable processing": {opts: []TransactionServiceOption{WithSerializableProcessing()}, want: true},
+		"default isolation":       {want: false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			uow := &isolationRecordingUnitOfWork{}
+			userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(100)})
+			service := NewTransactionService(uow, userRepo, newFakeTransactionRepo(), tt.opts...)
+
+			_, err := service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+				State: "win", Amount: "10.00", TransactionID: "tx-1",
+			}, entities.SourceTypeGame)
+			require.NoError(t, err)
+			assert.Equal(t, []bool{tt.want}, uow.serializable)
+		})
+	}
+}
+
+func TestTransactionService_GetUserTransactions(t *testing.T) {
+	ctx := context.Background()
+	userRepo := newFakeUserRepo(
+		&entities.User{ID: 1, Balance: decimal.NewFromInt(100)},
+		&entities.User{ID: 2, Balance: decimal.NewFromInt(100)},
+	)
+	service := NewTransactionService(&fakeUnitOfWork{}, userRepo, newFakeTransactionRepo())
+
+	for _, txID := range []string{"tx-1", "tx-2", "tx-3"} {
+		_, err := service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "win", Amount: "1.00", TransactionID: txID,
+		}, entities.SourceTypeGame)
+		require.NoError(t, err)
+	}
+	_, err := service.ProcessTransaction(ctx, 2, entities.TransactionRequest{
+		State: "win", Amount: "1.00", TransactionID: "tx-other",
+	}, entities.SourceTypeGame)
+	require.NoError(t, err)
+
+	t.Run("returns the user's transactions newest first", func(t *testing.T) {
+		page, err := service.GetUserTransactions(ctx, 1, repositories.TransactionFilter{Limit: 2})
+		require.NoError(t, err)
+		assert.Equal(t, 3, page.Total)
+		require.Len(t, page.Transactions, 2)
+		assert.Equal(t, "tx-3", page.Transactions[0].TransactionID)
+		assert.Equal(t, "tx-2", page.Transactions[1].TransactionID)
+	})
+
+	t.Run("offset pages through the history", func(t *testing.T) {
+		page, err := service.GetUserTransactions(ctx, 1, repositories.TransactionFilter{Limit: 2, Offset: 2})
+		require.NoError(t, err)
+		require.Len(t, page.Transactions, 1)
+		assert.Equal(t, "tx-1", page.Transactions[0].TransactionID)
+	})
+
+	t.Run("cursor pages through the history", func(t *testing.T) {
+		page, err := service.GetUserTransactions(ctx, 1, repositories.TransactionFilter{Limit: 2})
+		require.NoError(t, err)
+		require.NotEmpty(t, page.NextCursor)
+
+		after, err := ParseTransactionCursor(page.NextCursor)
+		require.NoError(t, err)
+		page, err = service.GetUserTransactions(ctx, 1, repositories.TransactionFilter{Limit: 2, After: after})
+		require.NoError(t, err)
+		assert.Equal(t, 3, page.Total)
+		require.Len(t, page.Transactions, 1)
+		assert.Equal(t, "tx-1", page.Transactions[0].TransactionID)
+		assert.Empty(t, page.NextCursor)
+
+		_, err = service.GetUserTransactions(ctx, 1, repositories.TransactionFilter{Limit: 2, Offset: 1, After: after})
+		assert.ErrorIs(t, err, ErrInvalidFilter)
+	})
+
+	t.Run("filters the history", func(t *testing.T) {
+		// The user of the filter is the one of the history
+		page, err := service.GetUserTransactions(ctx, 1, repositories.TransactionFilter{
+			UserID: 2, State: entities.StateWin, SourceType: entities.SourceTypeGame,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 3, page.Total)
+
+		page, err = service.GetUserTransactions(ctx, 1, repositories.TransactionFilter{State: entities.StateLose})
+		require.NoError(t, err)
+		assert.Equal(t, 0, page.Total)
+
+		_, err = service.GetUserTransactions(ctx, 1, repositories.TransactionFilter{State: "draw"})
+		assert.ErrorIs(t, err, ErrInvalidTransactionState)
+	})
+
+	t.Run("uses the default page size", func(t *testing.T) {
+		page, err := service.GetUserTransactions(ctx, 1, repositories.TransactionFilter{})
+		require.NoError(t, err)
+		assert.Equal(t, DefaultPageSize, page.Limit)
+	})
+
+	t.Run("unknown users are reported", func(t *testing.T) {
+		_, err := service.GetUserTransactions(ctx, 99, repositories.TransactionFilter{})
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+
+	t.Run("invalid pagination is rejected", func(t *testing.T) {
+		_, err := service.GetUserTransactions(ctx, 1, repositories.TransactionFilter{Limit: MaxPageSize + 1})
+		assert.ErrorIs(t, err, ErrInvalidFilter)
+	})
+}
+
+func TestTransactionService_ExportUserTransactions(t *testing.T) {
+	ctx := context.Background()
+	userRepo := newFakeUserRepo(
+		&entities.User{ID: 1, Balance: decimal.NewFromInt(1000)},
+		&entities.User{ID: 2, Balance: decimal.NewFromInt(100)},
+	)
+	service := NewTransactionService(&fakeUnitOfWork{}, userRepo, newFakeTransactionRepo())
+
+	// More than a page, so the export follows the cursor
+	count := MaxPageSize + 2
+	for i := 1; i <= count; i++ {
+		_, err := service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "win", Amount: "1.00", TransactionID: fmt.Sprintf("tx-%d", i),
+		}, entities.SourceTypeGame)
+		require.NoError(t, err)
+	}
+	_, err := service.ProcessTransaction(ctx, 2, entities.TransactionRequest{
+		State: "win", Amount: "1.00", TransactionID: "tx-other",
+	}, entities.SourceTypeGame)
+	require.NoError(t, err)
+
+	t.Run("passes every transaction of the user newest first", func(t *testing.T) {
+		var exported []string
+		err := service.ExportUserTransactions(ctx, 1, repositories.TransactionFilter{Limit: 1, Offset: 3},
+			func(transaction *entities.Transaction) error {
+				exported = append(exported, transaction.TransactionID)
+				return nil
+			})
+		require.NoError(t, err)
+		require.Len(t, exported, count)
+		assert.Equal(t, fmt.Sprintf("tx-%d", count), exported[0])
+		assert.Equal(t, "tx-1", exported[count-1])
+	})
+
+	t.Run("stops at the first error", func(t *testing.T) {
+		failure := errors.New("client went away")
+		calls := 0
+		err := service.ExportUserTransactions(ctx, 1, repositories.TransactionFilter{},
+			func(*entities.Transaction) error {
+				calls++
+				return failure
+			})
+		assert.ErrorIs(t, err, failure)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("invalid filters are rejected", func(t *testing.T) {
+		err := service.ExportUserTransactions(ctx, 1, repositories.TransactionFilter{State: "draw"},
+			func(*entities.Transaction) error { return nil })
+		assert.ErrorIs(t, err, ErrInvalidTransactionState)
+	})
+
+	t.Run("unknown users are reported", func(t *testing.T) {
+		err := service.ExportUserTransactions(ctx, 99, repositories.TransactionFilter{},
+			func(*entities.Transaction) error { return nil })
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+}
+
+func TestTransactionService_GetTransaction(t *testing.T) {
+	ctx := context.Background()
+	userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(100)})
+	service := NewTransactionService(&fakeUnitOfWork{}, userRepo, newFakeTransactionRepo())
+
+	_, err := service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+		State: "lose", Amount: "30.00", TransactionID: "tx-1",
+	}, entities.SourceTypePayment)
+	require.NoError(t, err)
+
+	transaction, err := service.GetTransaction(ctx, "tx-1")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), transaction.UserID)
+	assert.False(t, transaction.Cancelled)
+	require.NotNil(t, transaction.BalanceAfter)
+	assert.Equal(t, "70", transaction.BalanceAfter.String())
+
+	_, err = service.GetTransaction(ctx, "tx-missing")
+	assert.ErrorIs(t, err, ErrTransactionNotFound)
+}
+
+func TestTransactionService_GetRoundSummary(t *testing.T) {
+	ctx := context.Background()
+	userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(100)})
+	transactionRepo := newFakeTransactionRepo()
+	service := NewTransactionService(&fakeUnitOfWork{}, userRepo, transactionRepo,
+		WithCurrencies(newFakeWalletRepo(), "EUR", "USD"))
+
+	requests := []entities.TransactionRequest{
+		{State: "lose", Amount: "10.00", TransactionID: "bet-1", RoundID: "round-1"},
+		{State: "lose", Amount: "5.00", TransactionID: "bet-2", RoundID: "round-1"},
+		{State: "win", Amount: "12.50", TransactionID: "win-1", RoundID: "round-1"},
+		{State: "lose", Amount: "1.00", TransactionID: "bet-3", RoundID: "round-2"},
+		{State: "win", Amount: "3.00", TransactionID: "win-usd", RoundID: "round-1", Currency: "USD"},
+	}
+	for _, req := range requests {
+		_, err := service.ProcessTransaction(ctx, 1, req, entities.SourceTypeGame)
+		require.NoError(t, err)
+	}
+	require.NoError(t, transactionRepo.MarkCancelled(ctx, 2, time.Now()))
+
+	t.Run("totals the round's uncancelled transactions", func(t *testing.T) {
+		summary, err := service.GetRoundSummary(ctx, 1, "round-1", "")
+		require.NoError(t, err)
+		assert.Equal(t, &entities.RoundSummary{
+			UserID:       1,
+			RoundID:      "round-1",
+			Currency:     "EUR",
+			Transactions: 2,
+			Cancelled:    1,
+			TotalBets:    "10.00",
+			TotalWins:    "12.50",
+			TotalFees:    "0.00",
+			Net:          "2.50",
+		}, summary)
+	})
+
+	t.Run("totals are per currency", func(t *testing.T) {
+		summary, err := service.GetRoundSummary(ctx, 1, "round-1", "usd")
+		require.NoError(t, err)
+		assert.Equal(t, "USD", summary.Currency)
+		assert.Equal(t, 1, summary.Transactions)
+		assert.Equal(t, "3.00", summary.Net)
+	})
+
+	t.Run("unknown rounds and users are reported", func(t *testing.T) {
+		_, err := service.GetRoundSummary(ctx, 1, "round-3", "")
+		assert.ErrorIs(t, err, ErrRoundNotFound)
+		_, err = service.GetRoundSummary(ctx, 99, "round-1", "")
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+
+	t.Run("invalid round IDs are rejected", func(t *testing.T) {
+		_, err := service.GetRoundSummary(ctx, 1, strings.Repeat("r", MaxRoundIDLength+1), "")
+		assert.ErrorIs(t, err, ErrInvalidRoundID)
+		_, err = service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "win", Amount: "1.00", TransactionID: "tx-long", RoundID: strings.Repeat("r", MaxRoundIDLength+1),
+		}, entities.SourceTypeGame)
+		assert.ErrorIs(t, err, ErrInvalidRoundID)
+	})
+}
+
+// recordingMetrics captures transaction outcomes as "outcome:source:state[:reason]"
+type recordingMetrics struct {
+	outcomes []string
+}
+
+func (m *recordingMetrics) TransactionProcessed(sourceType entities.SourceType, state entities.TransactionState) {
+	m.outcomes = append(m.outcomes, "processed:"+string(sourceType)+":"+string(state))
+}
+
+func (m *recordingMetrics) TransactionDuplicate(sourceType entities.SourceType, state entities.TransactionState, replayed bool) {
+	outcome := "rejected"
+	if replayed {
+		outcome = "replayed"
+	}
+	m.outcomes = append(m.outcomes, "duplicate:"+string(sourceType)+":"+string(state)+":"+outcome)
+}
+
+func (m *recordingMetrics) TransactionFailed(sourceType entities.SourceType, state entities.TransactionState, reason string) {
+	m.outcomes = append(m.outcomes, "failed:"+string(sourceType)+":"+string(state)+":"+reason)
+}
+
+func TestTransactionService_ProcessTransaction_Metrics(t *testing.T) {
+	ctx := context.Background()
+	recorder := &recordingMetrics{}
+	userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(10)})
+	service := NewTransactionService(&fakeUnitOfWork{}, userRepo, newFakeTransactionRepo(), WithMetrics(recorder))
+
+	requests := []struct {
+		req        entities.TransactionRequest
+		sourceType entities.SourceType
+	}{
+		{entities.TransactionRequest{State: "win", Amount: "5.00", TransactionID: "tx-1"}, entities.SourceTypeGame},
+		{entities.TransactionRequest{State: "win", Amount: "5.00", TransactionID: "tx-1"}, entities.SourceTypeGame},
+		{entities.TransactionRequest{State: "win", Amount: "6.00", TransactionID: "tx-1"}, entities.SourceTypeGame},
+		{entities.TransactionRequest{State: "lose", Amount: "50.00", TransactionID: "tx-2"}, entities.SourceTypePayment},
+		{entities.TransactionRequest{State: "draw", Amount: "1.00", TransactionID: "tx-3"}, "casino"},
+	}
+	for _, r := range requests {
+		_, _ = service.ProcessTransaction(ctx, 1, r.req, r.sourceType)
+	}
+
+	assert.Equal(t, []string{
+		"processed:game:win",
+		"duplicate:game:win:replayed",
+		"duplicate:game:win:rejected",
+		"failed:payment:lose:insufficient_funds",
+		"failed:unknown:unknown:invalid_source_type",
+	}, recorder.outcomes)
+}
+
+func TestTransactionService_ProcessTransaction_Currencies(t *testing.T) {
+	ctx := context.Background()
+	userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(100)})
+	walletRepo := newFakeWalletRepo()
+	transactionRepo := newFakeTransactionRepo()
+	service := NewTransactionService(&fakeUnitOfWork{}, userRepo, transactionRepo,
+		WithCurrencies(walletRepo, "EUR", "EUR", "USD", "GBP"))
+
+	process := func(transactionID, state, amount, currency string) (*entities.TransactionResult, error) {
+		return service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: state, Amount: amount, Currency: currency, TransactionID: transactionID,
+		}, entities.SourceTypeGame)
+	}
+
+	t.Run("other currencies move their own wallet", func(t *testing.T) {
+		result, err := process("tx-usd", "win", "25.00", "usd")
+		require.NoError(t, err)
+		assert.Equal(t, "25.00", result.Balance)
+		assert.Equal(t, "USD", result.Currency)
+
+		_, err = process("tx-gbp", "lose", "1.00", "GBP")
+		assert.ErrorIs(t, err, ErrInsufficientFunds)
+	})
+
+	t.Run("the base currency moves the user's balance", func(t *testing.T) {
+		result, err := process("tx-eur", "lose", "10.00", "EUR")
+		require.NoError(t, err)
+		assert.Equal(t, "90.00", result.Balance)
+		assert.Equal(t, "EUR", result.Currency)
+
+		result, err = process("tx-default", "win", "5.00", "")
+		require.NoError(t, err)
+		assert.Equal(t, "95.00", result.Balance)
+	})
+
+	t.Run("replays must use the same currency", func(t *testing.T) {
+		result, err := process("tx-usd", "win", "25.00", "USD")
+		require.NoError(t, err)
+		assert.True(t, result.Replayed)
+
+		_, err = process("tx-usd", "win", "25.00", "EUR")
+		assert.ErrorIs(t, err, ErrDuplicateTransaction)
+	})
+
+	t.Run("currencies are validated", func(t *testing.T) {
+		_, err := process("tx-bad", "win", "1.00", "EURO")
+		assert.ErrorIs(t, err, ErrInvalidCurrency)
+
+		_, err = process("tx-chf", "win", "1.00", "CHF")
+		assert.ErrorIs(t, err, ErrUnsupportedCurrency)
+	})
+
+	t.Run("balances are returned per currency", func(t *testing.T) {
+		balance, err := service.GetUserBalance(ctx, 1)
+		require.NoError(t, err)
+
+		assert.Equal(t, "95.00", balance.Balance)
+		assert.Equal(t, "EUR", balance.Currency)
+		assert.Equal(t, "95.00", balance.Balances["EUR"])
+		assert.Equal(t, "25.00", balance.Balances["USD"])
+	})
+
+	t.Run("transactions carry their currency", func(t *testing.T) {
+		page, err := service.GetUserTransactions(ctx, 1, repositories.TransactionFilter{})
+		require.NoError(t, err)
+
+		currencies := make(map[string]string)
+		for _, transaction := range page.Transactions {
+			currencies[transaction.TransactionID] = transaction.Currency
+		}
+		assert.Equal(t, map[string]string{"tx-usd": "USD", "tx-eur": "EUR", "tx-default": "EUR"}, currencies)
+	})
+}
+
+func TestTransactionService_RefundTransaction(t *testing.T) {
+	ctx := context.Background()
+	userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(100)})
+	walletRepo := newFakeWalletRepo()
+	transactionRepo := newFakeTransactionRepo()
+	service := NewTransactionService(&fakeUnitOfWork{}, userRepo, transactionRepo,
+		WithCurrencies(walletRepo, "EUR", "EUR", "USD"))
+
+	process := func(transactionID, state, amount, currency string) {
+		_, err := service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: state, Amount: amount, Currency: currency, TransactionID: transactionID,
+		}, entities.SourceTypeGame)
+		require.NoError(t, err)
+	}
+	process("tx-bet", "lose", "30.00", "")
+	process("tx-usd", "win", "20.00", "USD")
+	process("tx-big-win", "win", "50.00", "")
+
+	t.Run("refunds move the amount back", func(t *testing.T) {
+		result, err := service.RefundTransaction(ctx, "tx-bet")
+		require.NoError(t, err)
+		assert.Equal(t, "refund:1", result.TransactionID)
+		assert.Equal(t, "150.00", result.Balance)
+		assert.Equal(t, "EUR", result.Currency)
+
+		refund, err := transactionRepo.GetByTransactionID(ctx, "refund:1")
+		require.NoError(t, err)
+		assert.Equal(t, entities.TransactionTypeRefund, refund.Type)
+		assert.Equal(t, entities.StateWin, refund.State)
+		assert.Equal(t, "tx-bet", refund.Reverses)
+		original, err := transactionRepo.GetByTransactionID(ctx, "tx-bet")
+		require.NoError(t, err)
+		assert.Equal(t, "refund:1", original.ReversedBy)
+	})
+
+	t.Run("refunds use the wallet of the original", func(t *testing.T) {
+		result, err := service.RefundTransaction(ctx, "tx-usd")
+		require.NoError(t, err)
+		assert.Equal(t, "0.00", result.Balance)
+		assert.Equal(t, "USD", result.Currency)
+		assert.Equal(t, "150.00", userRepo.users[1].Balance.StringFixed(2))
+	})
+
+	t.Run("transactions are refunded once", func(t *testing.T) {
+		_, err := service.RefundTransaction(ctx, "tx-bet")
+		assert.ErrorIs(t, err, ErrAlreadyRefunded)
+		_, err = service.RefundTransaction(ctx, "refund:1")
+		assert.ErrorIs(t, err, ErrNotRefundable)
+		assert.Equal(t, "150.00", userRepo.users[1].Balance.StringFixed(2))
+	})
+
+	t.Run("refunds cannot be replayed as transactions", func(t *testing.T) {
+		_, err := service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "win", Amount: "30.00", TransactionID: "refund:1",
+		}, entities.SourceTypeGame)
+		assert.ErrorIs(t, err, ErrDuplicateTransaction)
+	})
+
+	t.Run("refunds of wins need the funds", func(t *testing.T) {
+		process("tx-bet-2", "lose", "120.00", "")
+		_, err := service.RefundTransaction(ctx, "tx-big-win")
+		assert.ErrorIs(t, err, ErrInsufficientFunds)
+		original, err := transactionRepo.GetByTransactionID(ctx, "tx-big-win")
+		require.NoError(t, err)
+		assert.Empty(t, original.ReversedBy)
+	})
+
+	t.Run("cancelled and unknown transactions", func(t *testing.T) {
+		cancelled, err := transactionRepo.GetByTransactionID(ctx, "tx-bet-2")
+		require.NoError(t, err)
+		require.NoError(t, transactionRepo.MarkCancelled(ctx, cancelled.ID, time.Now()))
+		_, err = service.RefundTransaction(ctx, "tx-bet-2")
+		assert.ErrorIs(t, err, ErrNotRefundable)
+		_, err = service.RefundTransaction(ctx, "tx-missing")
+		assert.ErrorIs(t, err, ErrTransactionNotFound)
+	})
+}
+
+// lockRecordingUserRepo records the order users are locked in
+type lockRecordingUserRepo struct {
+	*fakeUserRepo
+	locked []uint64
+}
+
+func (r *lockRecordingUserRepo) GetByIDForUpdate(ctx context.Context, userID uint64) (*entities.User, error) {
+	r.locked = append(r.locked, userID)
+	return r.fakeUserRepo.GetByIDForUpdate(ctx, userID)
+}
+
+func TestTransactionService_Transfer(t *testing.T) {
+	ctx := context.Background()
+	userRepo := &lockRecordingUserRepo{fakeUserRepo: newFakeUserRepo(
+		&entities.User{ID: 1, Balance: decimal.NewFromInt(100)},
+		&entities.User{ID: 2, Balance: decimal.NewFromInt(10)},
+		&entities.User{ID: 3, Balance: decimal.NewFromInt(10), Status: entities.UserStatusFrozen},
+	)}
+	walletRepo := newFakeWalletRepo(&entities.Wallet{UserID: 2, Currency: "USD", Balance: decimal.NewFromInt(40)})
+	transactionRepo := newFakeTransactionRepo()
+	service := NewTransactionService(&fakeUnitOfWork{}, userRepo, transactionRepo,
+		WithCurrencies(walletRepo, "EUR", "EUR", "USD"))
+
+	t.Run("transfers debit the sender and credit the recipient", func(t *testing.T) {
+		userRepo.locked = nil
+		result, err := service.Transfer(ctx, entities.TransferRequest{
+			TransferID: "tr-1", FromUserID: 2, ToUserID: 1, Amount: "4.50",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "tr-1", result.TransferID)
+		assert.Equal(t, "4.50", result.Amount)
+		assert.Equal(t, "EUR", result.Currency)
+		assert.Equal(t, entities.TransferLeg{UserID: 2, TransactionID: "transfer:tr-1:debit", Receipt: result.From.Receipt, Balance: "5.50"}, result.From)
+		assert.Equal(t, entities.TransferLeg{UserID: 1, TransactionID: "transfer:tr-1:credit", Receipt: result.To.Receipt, Balance: "104.50"}, result.To)
+		assert.False(t, result.Replayed)
+		assert.Equal(t, "5.50", userRepo.users[2].Balance.StringFixed(2))
+		assert.Equal(t, "104.50", userRepo.users[1].Balance.StringFixed(2))
+
+		// Users are locked in ascending ID order whatever the direction
+		assert.Equal(t, []uint64{1, 2}, userRepo.locked)
+
+		for _, leg := range []struct {
+			transactionID string
+			state         entities.TransactionState
+		}{
+			{"transfer:tr-1:debit", entities.StateLose},
+			{"transfer:tr-1:credit", entities.StateWin},
+		} {
+			transaction, err := transactionRepo.GetByTransactionID(ctx, leg.transactionID)
+			require.NoError(t, err)
+			assert.Equal(t, entities.TransactionTypeTransfer, transaction.Type)
+			assert.Equal(t, leg.state, transaction.State)
+			assert.Equal(t, "tr-1", transaction.TransferID)
+		}
+	})
+
+	t.Run("transfers are idempotent", func(t *testing.T) {
+		result, err := service.Transfer(ctx, entities.TransferRequest{
+			TransferID: "tr-1", FromUserID: 2, ToUserID: 1, Amount: "4.5",
+		})
+		require.NoError(t, err)
+		assert.True(t, result.Replayed)
+		assert.Equal(t, "5.50", result.From.Balance)
+		assert.Equal(t, "5.50", userRepo.users[2].Balance.StringFixed(2))
+
+		_, err = service.Transfer(ctx, entities.TransferRequest{
+			TransferID: "tr-1", FromUserID: 1, ToUserID: 2, Amount: "4.50",
+		})
+		assert.ErrorIs(t, err, ErrDuplicateTransfer)
+	})
+
+	t.Run("transfers move wallets", func(t *testing.T) {
+		result, err := service.Transfer(ctx, entities.TransferRequest{
+			TransferID: "tr-usd", FromUserID: 2, ToUserID: 1, Amount: "40", Currency: "USD",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "USD", result.Currency)
+		assert.Equal(t, "0.00", result.From.Balance)
+		assert.Equal(t, "40.00", result.To.Balance)
+		assert.Equal(t, "5.50", userRepo.users[2].Balance.StringFixed(2))
+	})
+
+	t.Run("insufficient funds", func(t *testing.T) {
+		_, err := service.Transfer(ctx, entities.TransferRequest{
+			TransferID: "tr-2", FromUserID: 2, ToUserID: 1, Amount: "5.51",
+		})
+		assert.ErrorIs(t, err, ErrInsufficientFunds)
+		assert.Equal(t, "104.50", userRepo.users[1].Balance.StringFixed(2))
+		_, err = transactionRepo.GetByTransactionID(ctx, "transfer:tr-2:credit")
+		assert.ErrorIs(t, err, repositories.ErrNotFound)
+	})
+
+	t.Run("invalid transfers", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			req     entities.TransferRequest
+			wantErr error
+		}{
+			{"same user", entities.TransferRequest{TransferID: "tr-3", FromUserID: 1, ToUserID: 1, Amount: "1"}, ErrInvalidTransfer},
+			{"long transfer ID", entities.TransferRequest{TransferID: strings.Repeat("x", MaxTransferIDLength+1), FromUserID: 1, ToUserID: 2, Amount: "1"}, ErrInvalidTransfer},
+			{"negative amount", entities.TransferRequest{TransferID: "tr-3", FromUserID: 1, ToUserID: 2, Amount: "-1"}, ErrInvalidAmount},
+			{"unsupported currency", entities.TransferRequest{TransferID: "tr-3", FromUserID: 1, ToUserID: 2, Amount: "1", Currency: "GBP"}, ErrUnsupportedCurrency},
+			{"unknown recipient", entities.TransferRequest{TransferID: "tr-3", FromUserID: 1, ToUserID: 9, Amount: "1"}, ErrUserNotFound},
+			{"frozen recipient", entities.TransferRequest{TransferID: "tr-3", FromUserID: 1, ToUserID: 3, Amount: "1"}, ErrAccountFrozen},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := service.Transfer(ctx, tt.req)
+				assert.ErrorIs(t, err, tt.wantErr)
+			})
+		}
+	})
+
+	t.Run("transfer legs cannot be refunded", func(t *testing.T) {
+		_, err := service.RefundTransaction(ctx, "transfer:tr-1:debit")
+		assert.ErrorIs(t, err, ErrNotRefundable)
+	})
+}
+
+func TestParseTransactionCursor(t *testing.T) {
+	cursor := repositories.TransactionCursor{
+		CreatedAt: time.Date(2025, 1, 31, 12, 0, 0, 123456000, time.UTC),
+		ID:        42,
+	}
+	parsed, err := ParseTransactionCursor(FormatTransactionCursor(cursor))
+	require.NoError(t, err)
+	assert.Equal(t, cursor, *parsed)
+
+	for _, value := range []string{"", "42", "x.42", "1.-1", "1.2.3"} {
+		_, err := ParseTransactionCursor(value)
+		assert.ErrorIs(t, err, ErrInvalidCursor, value)
+	}
+}
+
+func TestTransactionService_ProcessTransaction_CreditLimit(t *testing.T) {
+	ctx := context.Background()
+	userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(10), CreditLimit: decimal.NewFromInt(50)})
+	service := NewTransactionService(&fakeUnitOfWork{}, userRepo, newFakeTransactionRepo())
+
+	result, err := service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+		State: "lose", Amount: "60.00", TransactionID: "tx-1",
+	}, entities.SourceTypeGame)
+	require.NoError(t, err)
+	assert.Equal(t, "-50.00", result.Balance)
+
+	_, err = service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+		State: "lose", Amount: "0.01", TransactionID: "tx-2",
+	}, entities.SourceTypeGame)
+	assert.ErrorIs(t, err, ErrInsufficientFunds)
+
+	// Lowering the credit limit leaves the balance below it, but credits are
+	// still accepted
+	userRepo.users[1].CreditLimit = decimal.Zero
+	result, err = service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+		State: "win", Amount: "20.00", TransactionID: "tx-3",
+	}, entities.SourceTypeGame)
+	require.NoError(t, err)
+	assert.Equal(t, "-30.00", result.Balance)
+
+	_, err = service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+		State: "lose", Amount: "1.00", TransactionID: "tx-4",
+	}, entities.SourceTypeGame)
+	assert.ErrorIs(t, err, ErrInsufficientFunds)
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ErrVelocityLimitExceeded is returned for transactions beyond a user's
+// velocity limits; the transaction may succeed once older transactions leave
+// the window
+var ErrVelocityLimitExceeded = errors.New("velocity limit exceeded")
+
+// VelocityPolicy limits the standard transactions of a user within a rolling
+// window. Zero limits are disabled.
+type VelocityPolicy struct {
+	// MaxTransactions is the largest number of transactions within Window
+	MaxTransactions int
+	// MaxLoss is the largest sum of base currency losses within Window
+	MaxLoss decimal.Decimal
+	Window  time.Duration
+}
+
+// enabled reports whether the policy limits anything
+func (p VelocityPolicy) enabled() bool {
+	return p.MaxTransactions > 0 || p.MaxLoss.IsPositive()
+}
+
+// WithVelocityLimits limits the transactions of every user within a rolling
+// window
+func WithVelocityLimits(policy VelocityPolicy) TransactionServiceOption {
+	return func(s *TransactionService) {
+		s.velocity = policy
+	}
+}
+
+// checkVelocity enforces the velocity limits against a prospective
+// transaction of the user. The user is locked, so its transactions cannot
+// change in between.
+func (s *TransactionService) checkVelocity(
+	ctx context.Context,
+	userID uint64,
+	currency string,
+	delta decimal.Decimal,
+	now time.Time,
+) error {
+	if !s.velocity.enabled() {
+		return nil
+	}
+
+	velocity, err := s.transactionRepo.VelocitySince(ctx, userID, now.Add(-s.velocity.Window))
+	if err != nil {
+		return fmt.Errorf("failed to evaluate velocity limits: %w", err)
+	}
+	if s.velocity.MaxTransactions > 0 && velocity.Transactions >= s.velocity.MaxTransactions {
+		return ErrVelocityLimitExceeded
+	}
+	// Only losses in the base currency count towards the loss limit
+	if s.velocity.MaxLoss.IsPositive() && delta.IsNegative() && currency == "" &&
+		velocity.Losses.Sub(delta).GreaterThan(s.velocity.MaxLoss) {
+		return ErrVelocityLimitExceeded
+	}
+
+	return nil
+}
+
+// VelocityUsage is what is left of a user's velocity limits in the current
+// window. The remaining values of disabled limits are zero.
+type VelocityUsage struct {
+	MaxTransactions       int
+	RemainingTransactions int
+	MaxLoss               decimal.Decimal
+	RemainingLoss         decimal.Decimal
+}
+
+// GetVelocityUsage returns what is left of the user's velocity limits, or nil
+// when there are none
+func (s *TransactionService) GetVelocityUsage(ctx context.Context, userID uint64) (*VelocityUsage, error) {
+	if !s.velocity.enabled() {
+		return nil, nil
+	}
+
+	velocity, err := s.transactionRepo.VelocitySince(ctx, userID, s.now().Add(-s.velocity.Window))
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate velocity limits: %w", err)
+	}
+
+	usage := &VelocityUsage{
+		MaxTransactions: s.velocity.MaxTransactions,
+		MaxLoss:         s.velocity.MaxLoss,
+	}
+	if s.velocity.MaxTransactions > 0 {
+		usage.RemainingTransactions = max(s.velocity.MaxTransactions-velocity.Transactions, 0)
+	}
+	if s.velocity.MaxLoss.IsPositive() {
+		usage.RemainingLoss = decimal.Max(s.velocity.MaxLoss.Sub(velocity.Losses), decimal.Zero)
+	}
+	return usage, nil
+}
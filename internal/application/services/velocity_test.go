@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionService_VelocityLimits(t *testing.T) {
+	ctx := context.Background()
+
+	newService := func(policy VelocityPolicy, now *time.Time) *TransactionService {
+		userRepo := newFakeUserRepo(&entities.User{ID: 1, Balance: decimal.NewFromInt(100)})
+		return NewTransactionService(&fakeUnitOfWork{}, userRepo, newFakeTransactionRepo(),
+			WithVelocityLimits(policy), WithClock(func() time.Time { return *now }))
+	}
+
+	t.Run("transactions beyond the count are rejected", func(t *testing.T) {
+		now := time.Now()
+		service := newService(VelocityPolicy{MaxTransactions: 2, Window: 24 * time.Hour}, &now)
+
+		for _, id := range []string{"tx-1", "tx-2"} {
+			_, err := service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+				State: "win", Amount: "1.00", TransactionID: id,
+			}, entities.SourceTypeGame)
+			require.NoError(t, err)
+		}
+
+		_, err := service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "win", Amount: "1.00", TransactionID: "tx-3",
+		}, entities.SourceTypeGame)
+		assert.ErrorIs(t, err, ErrVelocityLimitExceeded)
+
+		// Retries of processed transactions are replayed
+		_, err = service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "win", Amount: "1.00", TransactionID: "tx-2",
+		}, entities.SourceTypeGame)
+		require.NoError(t, err)
+
+		// The window rolls with the service clock
+		now = now.Add(25 * time.Hour)
+		_, err = service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "win", Amount: "1.00", TransactionID: "tx-3",
+		}, entities.SourceTypeGame)
+		require.NoError(t, err)
+	})
+
+	t.Run("losses beyond the limit are rejected", func(t *testing.T) {
+		now := time.Now()
+		service := newService(VelocityPolicy{MaxLoss: decimal.NewFromInt(50), Window: 24 * time.Hour}, &now)
+
+		_, err := service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "lose", Amount: "40.00", TransactionID: "tx-1",
+		}, entities.SourceTypeGame)
+		require.NoError(t, err)
+
+		// Wins neither offset losses nor are blocked by the limit
+		_, err = service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "win", Amount: "30.00", TransactionID: "tx-2",
+		}, entities.SourceTypeGame)
+		require.NoError(t, err)
+
+		_, err = service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "lose", Amount: "20.00", TransactionID: "tx-3",
+		}, entities.SourceTypeGame)
+		assert.ErrorIs(t, err, ErrVelocityLimitExceeded)
+
+		_, err = service.ProcessTransaction(ctx, 1, entities.TransactionRequest{
+			State: "lose", Amount: "10.00", TransactionID: "tx-4",
+		}, entities.SourceTypeGame)
+		require.NoError(t, err)
+	})
+
+	t.Run("usage reports what is left of the limits", func(t *testing.T) {
+		now := time.Now()
+		service := newService(VelocityPolicy{MaxTransactions: 5, MaxLoss: decimal.NewFromInt(50), Window: 24 * time.Hour}, &now)
+
+		for _, req := range []entities.TransactionRequest{
+			{State: "lose", Amount: "15.00", TransactionID: "tx-1"},
+			{State: "win", Amount: "30.00", TransactionID: "tx-2"},
+		} {
+			_, err := service.ProcessTransaction(ctx, 1, req, entities.SourceTypeGame)
+			require.NoError(t, err)
+		}
+
+		usage, err := service.GetVelocityUsage(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 5, usage.MaxTransactions)
+		assert.Equal(t, 3, usage.RemainingTransactions)
+		assert.Equal(t, "35", usage.RemainingLoss.String())
+
+		usage, err = newService(VelocityPolicy{}, &now).GetVelocityUsage(ctx, 1)
+		require.NoError(t, err)
+		assert.Nil(t, usage, "without limits")
+	})
+}
@@ -0,0 +1,35 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// PrimeBalances reads the balances of up to limit users with the most
+// transactions in the last window, as their balance requests would, so that
+// the queries and the stale balance cache are warm before the first requests
+// arrive. Users deleted since are skipped. It returns how many balances were
+// read.
+func (s *TransactionService) PrimeBalances(ctx context.Context, window time.Duration, limit int) (int, error) {
+	if limit <= 0 {
+		return 0, nil
+	}
+	userIDs, err := s.transactionRepo.MostActiveUsers(ctx, s.now().Add(-window), limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get most active users: %w", err)
+	}
+
+	primed := 0
+	for _, userID := range userIDs {
+		if _, err := s.GetUserBalance(ctx, userID); err != nil {
+			if errors.Is(err, ErrUserNotFound) {
+				continue
+			}
+			return primed, err
+		}
+		primed++
+	}
+	return primed, nil
+}
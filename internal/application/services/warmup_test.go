@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionService_PrimeBalances(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	newService := func(cache mapBalanceCache) (*TransactionService, *fakeUserRepo, *fakeTransactionRepo) {
+		userRepo := newFakeUserRepo(
+			&entities.User{ID: 1, Balance: decimal.NewFromInt(10)},
+			&entities.User{ID: 2, Balance: decimal.NewFromInt(20)},
+			&entities.User{ID: 3, Balance: decimal.NewFromInt(30)},
+		)
+		transactionRepo := newFakeTransactionRepo()
+		for i, userID := range []uint64{1, 2, 2, 3, 3, 3, 4, 4, 4, 4} {
+			transactionRepo.transactions = append(transactionRepo.transactions, &entities.Transaction{
+				ID: uint64(i + 1), UserID: userID, CreatedAt: now.Add(-time.Duration(userID) * time.Hour),
+			})
+		}
+		service := NewTransactionService(&fakeUnitOfWork{}, userRepo, transactionRepo,
+			WithClock(func() time.Time { return now }), WithStaleBalanceFallback(cache, time.Minute))
+		return service, userRepo, transactionRepo
+	}
+
+	t.Run("caches the balances of the most active users", func(t *testing.T) {
+		cache := mapBalanceCache{}
+		service, _, _ := newService(cache)
+
+		// User 4 has the most transactions but no longer exists
+		primed, err := service.PrimeBalances(ctx, 5*time.Hour, 3)
+		require.NoError(t, err)
+		assert.Equal(t, 2, primed)
+		assert.Len(t, cache, 2)
+		assert.True(t, decimal.NewFromInt(30).Equal(cache[3].Balance))
+		assert.True(t, decimal.NewFromInt(20).Equal(cache[2].Balance))
+	})
+
+	t.Run("only counts transactions within the window", func(t *testing.T) {
+		cache := mapBalanceCache{}
+		service, _, _ := newService(cache)
+
+		primed, err := service.PrimeBalances(ctx, 90*time.Minute, 10)
+		require.NoError(t, err)
+		assert.Equal(t, 1, primed)
+		assert.Contains(t, cache, uint64(1))
+	})
+
+	t.Run("stops at the first failure", func(t *testing.T) {
+		service, userRepo, _ := newService(mapBalanceCache{})
+		outage := errors.New("connection refused")
+		userRepo.getErr = outage
+
+		_, err := service.PrimeBalances(ctx, 5*time.Hour, 3)
+		assert.ErrorIs(t, err, outage)
+	})
+}
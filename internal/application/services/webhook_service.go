@@ -0,0 +1,388 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+	"transaction-service/internal/domain/repositories"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrInvalidWebhook  = errors.New("invalid webhook")
+	ErrWebhookNotFound = errors.New("webhook not found")
+)
+
+// Headers of webhook deliveries
+const (
+	// WebhookSignatureHeader carries "sha256=" and the hex HMAC-SHA256 of the
+	// timestamp, a dot and the body, keyed with the webhook's secret
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	// WebhookTimestampHeader carries the Unix time the attempt was signed at,
+	// letting receivers reject replayed deliveries
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+)
+
+// maxWebhookErrorLength bounds the error recorded for a failed attempt
+const maxWebhookErrorLength = 500
+
+// WebhookPolicy bounds the attempts of webhook deliveries
+type WebhookPolicy struct {
+	// Timeout bounds each attempt; claimed deliveries are leased for twice
+	// as long before another worker may retry them
+	Timeout     time.Duration
+	MaxAttempts int
+	// BaseDelay doubles after every failed attempt up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// WebhookSender posts a delivery to a webhook URL and returns the response
+// status code. Errors are reserved for requests that got no response.
+type WebhookSender interface {
+	Send(ctx context.Context, url string, headers map[string]string, body []byte) (int, error)
+}
+
+// WebhookService registers webhooks and delivers the balance change events
+// matching their filters. Deliveries are queued in the unit of work that
+// changes the balance and sent afterwards by Deliver, so a webhook is only
+// called for committed changes.
+type WebhookService struct {
+	repo   repositories.WebhookRepository
+	sender WebhookSender
+	policy WebhookPolicy
+	now    func() time.Time
+}
+
+// NewWebhookService creates a new WebhookService
+func NewWebhookService(repo repositories.WebhookRepository, sender WebhookSender, policy WebhookPolicy) *WebhookService {
+	return &WebhookService{
+		repo:   repo,
+		sender: sender,
+		policy: policy,
+		now:    time.Now,
+	}
+}
+
+// Register creates a webhook and returns it with the secret its deliveries
+// are signed with. The secret is not returned again.
+func (s *WebhookService) Register(ctx context.Context, req entities.WebhookRequest) (*entities.Webhook, error) {
+	if err := validateWebhookRequest(req); err != nil {
+		return nil, err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+
+	webhook := &entities.Webhook{
+		URL:       req.URL,
+		Secret:    hex.EncodeToString(secret),
+		Events:    req.Events,
+		Filter:    req.Filter,
+		CreatedAt: s.now(),
+	}
+	if err := s.repo.Create(ctx, webhook); err != nil {
+		return nil, fmt.Errorf("failed to register webhook: %w", err)
+	}
+
+	return webhook, nil
+}
+
+func validateWebhookRequest(req entities.WebhookRequest) error {
+	target, err := url.Parse(req.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidWebhook)
+	}
+	for _, event := range req.Events {
+		if event != entities.EventTransactionProcessed && event != entities.EventTransactionCancelled &&
+			event != entities.EventTransactionSettled && event != entities.EventUserDormant {
+			return fmt.Errorf("%w: unknown event %q", ErrInvalidWebhook, event)
+		}
+	}
+	if req.Filter.SourceType != "" && !req.Filter.SourceType.IsValid() {
+		return fmt.Errorf("%w: %w", ErrInvalidWebhook, ErrInvalidSourceType)
+	}
+	if req.Filter.State != "" && !req.Filter.State.IsValid() {
+		return fmt.Errorf("%w: %w", ErrInvalidWebhook, ErrInvalidTransactionState)
+	}
+	return nil
+}
+
+// ListWebhooks returns the registered webhooks
+func (s *WebhookService) ListWebhooks(ctx context.Context) ([]*entities.Webhook, error) {
+	webhooks, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	return webhooks, nil
+}
+
+// GetWebhook returns a registered webhook
+func (s *WebhookService) GetWebhook(ctx context.Context, id uint64) (*entities.Webhook, error) {
+	webhook, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+// DeleteWebhook stops deliveries to a webhook. Its pending deliveries fail.
+func (s *WebhookService) DeleteWebhook(ctx context.Context, id uint64) error {
+	if err := s.repo.Deactivate(ctx, id); err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrWebhookNotFound
+		}
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	return nil
+}
+
+// ListDeliveries returns a page of a registered webhook's deliveries, newest
+// first, optionally with the given status
+func (s *WebhookService) ListDeliveries(
+	ctx context.Context,
+	webhookID uint64,
+	status entities.WebhookDeliveryStatus,
+	limit, offset int,
+) (*entities.WebhookDeliveryPage, error) {
+	if limit < 0 || offset < 0 || limit > MaxPageSize || (status != "" && !status.IsValid()) {
+		return nil, ErrInvalidFilter
+	}
+	if limit == 0 {
+		limit = DefaultPageSize
+	}
+
+	if _, err := s.GetWebhook(ctx, webhookID); err != nil {
+		return nil, err
+	}
+
+	deliveries, total, err := s.repo.ListDeliveries(ctx, webhookID, status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+
+	return &entities.WebhookDeliveryPage{
+		Deliveries: deliveries,
+		Total:      total,
+		Limit:      limit,
+		Offset:     offset,
+	}, nil
+}
+
+// DeliveriesCreatedBetween returns the IDs of the deliveries created in
+// [from, to) for a registered webhook, or for every webhook when webhookID is
+// zero
+func (s *WebhookService) DeliveriesCreatedBetween(ctx context.Context, webhookID uint64, from, to time.Time) ([]uint64, error) {
+	if webhookID != 0 {
+		if _, err := s.GetWebhook(ctx, webhookID); err != nil {
+			return nil, err
+		}
+	}
+
+	ids, err := s.repo.ListDeliveryIDs(ctx, webhookID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	return ids, nil
+}
+
+// Redeliver makes the deliveries pending again, whatever their outcome, so
+// they are delivered as soon as possible with a fresh set of attempts
+func (s *WebhookService) Redeliver(ctx context.Context, ids []uint64) error {
+	if _, err := s.repo.RequeueDeliveries(ctx, ids, s.now()); err != nil {
+		return fmt.Errorf("failed to requeue webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+// BeforeProcess implements TransactionHook
+func (s *WebhookService) BeforeProcess(ctx context.Context, event *TransactionEvent) error {
+	return nil
+}
+
+// AfterProcess queues a transaction.processed delivery for the matching
+// webhooks
+func (s *WebhookService) AfterProcess(ctx context.Context, event *TransactionEvent) error {
+	return s.enqueue(ctx, entities.EventTransactionProcessed, event.Transaction, event.NewBalance)
+}
+
+// RecordCancellation queues a transaction.cancelled delivery for the matching
+// webhooks
+func (s *WebhookService) RecordCancellation(ctx context.Context, transaction *entities.Transaction, balance decimal.Decimal) error {
+	return s.enqueue(ctx, entities.EventTransactionCancelled, transaction, balance)
+}
+
+// RecordSettlement queues a transaction.settled delivery for the matching
+// webhooks
+func (s *WebhookService) RecordSettlement(ctx context.Context, transaction *entities.Transaction, balance decimal.Decimal) error {
+	return s.enqueue(ctx, entities.EventTransactionSettled, transaction, balance)
+}
+
+// RecordDormancy queues a user.dormant delivery for the matching webhooks.
+// Webhooks filtering on a source type or state never match it.
+func (s *WebhookService) RecordDormancy(ctx context.Context, event *entities.UserDormantEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", entities.EventUserDormant, err)
+	}
+
+	_, err = s.repo.EnqueueDeliveries(ctx, repositories.WebhookEvent{
+		Type:      entities.EventUserDormant,
+		UserID:    event.UserID,
+		Payload:   payload,
+		CreatedAt: s.now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to queue %s webhook deliveries: %w", entities.EventUserDormant, err)
+	}
+	return nil
+}
+
+func (s *WebhookService) enqueue(
+	ctx context.Context,
+	eventType string,
+	transaction *entities.Transaction,
+	balance decimal.Decimal,
+) error {
+	payload, err := json.Marshal(newBalanceChangeEvent(transaction, balance))
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+
+	_, err = s.repo.EnqueueDeliveries(ctx, repositories.WebhookEvent{
+		Type:       eventType,
+		UserID:     transaction.UserID,
+		SourceType: transaction.SourceType,
+		State:      transaction.State,
+		Payload:    payload,
+		CreatedAt:  s.now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to queue %s webhook deliveries: %w", eventType, err)
+	}
+	return nil
+}
+
+// Deliver sends up to limit of the due deliveries concurrently and records
+// their outcome, returning how many were attempted. Failed deliveries are
+// retried with exponential backoff until MaxAttempts is reached.
+func (s *WebhookService) Deliver(ctx context.Context, limit int) (int, error) {
+	now := s.now()
+	deliveries, err := s.repo.ClaimDueDeliveries(ctx, now, now.Add(2*s.policy.Timeout), limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(deliveries))
+	for i, delivery := range deliveries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.attempt(ctx, delivery)
+			errs[i] = s.repo.UpdateDelivery(ctx, delivery)
+		}()
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return len(deliveries), fmt.Errorf("failed to record webhook deliveries: %w", err)
+	}
+	return len(deliveries), nil
+}
+
+// attempt sends delivery once and updates it with the outcome
+func (s *WebhookService) attempt(ctx context.Context, delivery *entities.WebhookDelivery) {
+	body, err := json.Marshal(entities.WebhookPayload{
+		ID:        delivery.ID,
+		Type:      delivery.EventType,
+		CreatedAt: delivery.CreatedAt,
+		Data:      delivery.Payload,
+	})
+	if err != nil {
+		s.fail(delivery, 0, fmt.Errorf("failed to encode delivery: %w", err))
+		return
+	}
+
+	timestamp := s.now()
+	headers := map[string]string{
+		"Content-Type":         "application/json",
+		WebhookSignatureHeader: SignWebhook(delivery.Secret, timestamp, body),
+		WebhookTimestampHeader: strconv.FormatInt(timestamp.Unix(), 10),
+		WebhookEventHeader:     delivery.EventType,
+		WebhookDeliveryHeader:  strconv.FormatUint(delivery.ID, 10),
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, s.policy.Timeout)
+	defer cancel()
+	statusCode, err := s.sender.Send(sendCtx, delivery.URL, headers, body)
+	delivery.Attempts++
+	switch {
+	case err != nil:
+		s.fail(delivery, 0, err)
+	case statusCode < 200 || statusCode > 299:
+		s.fail(delivery, statusCode, fmt.Errorf("unexpected status %d", statusCode))
+	default:
+		deliveredAt := s.now()
+		delivery.Status = entities.WebhookDeliverySucceeded
+		delivery.NextAttemptAt = nil
+		delivery.LastStatusCode = statusCode
+		delivery.LastError = ""
+		delivery.DeliveredAt = &deliveredAt
+	}
+}
+
+// fail records a failed attempt and schedules the next one, if any
+func (s *WebhookService) fail(delivery *entities.WebhookDelivery, statusCode int, cause error) {
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = cause.Error()
+	if len(delivery.LastError) > maxWebhookErrorLength {
+		delivery.LastError = delivery.LastError[:maxWebhookErrorLength]
+	}
+
+	if delivery.Attempts >= s.policy.MaxAttempts {
+		delivery.Status = entities.WebhookDeliveryFailed
+		delivery.NextAttemptAt = nil
+		return
+	}
+	next := s.now().Add(s.backoff(delivery.Attempts))
+	delivery.NextAttemptAt = &next
+}
+
+// backoff returns the delay after the given failed attempt
+func (s *WebhookService) backoff(attempt int) time.Duration {
+	delay := s.policy.BaseDelay << (attempt - 1)
+	if delay <= 0 || delay > s.policy.MaxDelay {
+		delay = s.policy.MaxDelay
+	}
+	return delay
+}
+
+// SignWebhook returns the signature header value of a delivery body signed
+// at timestamp with secret. Receivers recompute it to authenticate deliveries.
+func SignWebhook(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,279 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedSender answers each URL with its queued status codes, then 200
+type scriptedSender struct {
+	mu       sync.Mutex
+	statuses map[string][]int
+	sent     []map[string]string
+	bodies   [][]byte
+}
+
+func (s *scriptedSender) Send(ctx context.Context, url string, headers map[string]string, body []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sent = append(s.sent, headers)
+	s.bodies = append(s.bodies, body)
+	if statuses := s.statuses[url]; len(statuses) > 0 {
+		s.statuses[url] = statuses[1:]
+		if statuses[0] == 0 {
+			return 0, errors.New("connection refused")
+		}
+		return statuses[0], nil
+	}
+	return 200, nil
+}
+
+var testWebhookPolicy = WebhookPolicy{
+	Timeout:     time.Second,
+	MaxAttempts: 3,
+	BaseDelay:   time.Minute,
+	MaxDelay:    90 * time.Second,
+}
+
+func TestWebhookService_Register(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		req     entities.WebhookRequest
+		wantErr bool
+	}{
+		{name: "valid", req: entities.WebhookRequest{URL: "https://example.com/hook"}},
+		{
+			name: "with events and filter",
+			req: entities.WebhookRequest{
+				URL:    "http://example.com/hook",
+				Events: []string{entities.EventTransactionCancelled},
+				Filter: entities.WebhookFilter{UserID: 7, SourceType: entities.SourceTypeGame, State: entities.StateWin},
+			},
+		},
+		{name: "relative url", req: entities.WebhookRequest{URL: "/hook"}, wantErr: true},
+		{name: "unsupported scheme", req: entities.WebhookRequest{URL: "ftp://example.com/hook"}, wantErr: true},
+		{
+			name:    "unknown event",
+			req:     entities.WebhookRequest{URL: "https://example.com/hook", Events: []string{"user.created"}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown source type",
+			req:     entities.WebhookRequest{URL: "https://example.com/hook", Filter: entities.WebhookFilter{SourceType: "casino"}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown state",
+			req:     entities.WebhookRequest{URL: "https://example.com/hook", Filter: entities.WebhookFilter{State: "draw"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeWebhookRepo{}
+			service := NewWebhookService(repo, &scriptedSender{}, testWebhookPolicy)
+
+			webhook, err := service.Register(ctx, tt.req)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidWebhook)
+				assert.Empty(t, repo.webhooks)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, uint64(1), webhook.ID)
+			assert.Len(t, webhook.Secret, 64)
+
+			stored, err := service.GetWebhook(ctx, webhook.ID)
+			require.NoError(t, err)
+			assert.Empty(t, stored.Secret, "the secret is only returned on registration")
+			assert.Equal(t, tt.req.Filter, stored.Filter)
+		})
+	}
+}
+
+func TestWebhookService_QueuesMatchingDeliveries(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeWebhookRepo{}
+	webhooks := NewWebhookService(repo, &scriptedSender{}, testWebhookPolicy)
+	for _, req := range []entities.WebhookRequest{
+		{URL: "https://example.com/all"},
+		{URL: "https://example.com/user-7", Filter: entities.WebhookFilter{UserID: 7}},
+		{URL: "https://example.com/user-8", Filter: entities.WebhookFilter{UserID: 8}},
+		{URL: "https://example.com/payments", Filter: entities.WebhookFilter{SourceType: entities.SourceTypePayment}},
+		{URL: "https://example.com/cancellations", Events: []string{entities.EventTransactionCancelled}},
+		{URL: "https://example.com/settlements", Events: []string{entities.EventTransactionSettled}},
+	} {
+		_, err := webhooks.Register(ctx, req)
+		require.NoError(t, err)
+	}
+
+	userRepo := newFakeUserRepo(&entities.User{ID: 7, Balance: decimal.NewFromInt(100)})
+	transactionRepo := newFakeTransactionRepo()
+	service := NewTransactionService(&fakeUnitOfWork{}, userRepo, transactionRepo, WithTransactionHooks(webhooks))
+	_, err := service.ProcessTransaction(ctx, 7, entities.TransactionRequest{
+		State: "win", Amount: "10.00", TransactionID: "tx-1",
+	}, entities.SourceTypeGame)
+	require.NoError(t, err)
+
+	require.Len(t, repo.deliveries, 2)
+	assert.Equal(t, uint64(1), repo.deliveries[0].WebhookID)
+	assert.Equal(t, uint64(2), repo.deliveries[1].WebhookID)
+	var payload entities.BalanceChangeEvent
+	require.NoError(t, json.Unmarshal(repo.deliveries[0].Payload, &payload))
+	assert.Equal(t, "tx-1", payload.TransactionID)
+	assert.Equal(t, "110.00", payload.Balance)
+
+	cancellations := NewCancellationService(&fakeUnitOfWork{}, userRepo, newFakeWalletRepo(), transactionRepo,
+		WithCancellationRecorders(webhooks))
+	_, err = cancellations.CancelLatestOddTransactions(ctx, 10)
+	require.NoError(t, err)
+
+	require.Len(t, repo.deliveries, 5)
+	for i, want := range []uint64{1, 2, 5} {
+		delivery := repo.deliveries[2+i]
+		assert.Equal(t, want, delivery.WebhookID)
+		assert.Equal(t, entities.EventTransactionCancelled, delivery.EventType)
+	}
+
+	settling := NewTransactionService(&fakeUnitOfWork{}, userRepo, transactionRepo, WithSettlement(time.Hour),
+		WithTransactionHooks(webhooks), WithSettlementRecorders(webhooks))
+	_, err = settling.ProcessTransaction(ctx, 7, entities.TransactionRequest{
+		State: "win", Amount: "5.00", TransactionID: "deposit-1",
+	}, entities.SourceTypePayment)
+	require.NoError(t, err)
+	_, err = settling.SettleTransaction(ctx, "deposit-1")
+	require.NoError(t, err)
+
+	require.Len(t, repo.deliveries, 12)
+	for i, want := range []uint64{1, 2, 4, 6} {
+		delivery := repo.deliveries[8+i]
+		assert.Equal(t, want, delivery.WebhookID)
+		assert.Equal(t, entities.EventTransactionSettled, delivery.EventType)
+	}
+}
+
+func TestWebhookService_Deliver(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	repo := &fakeWebhookRepo{}
+	sender := &scriptedSender{statuses: map[string][]int{
+		"https://example.com/flaky": {503, 0},
+		"https://example.com/down":  {500, 500, 500},
+	}}
+	service := NewWebhookService(repo, sender, testWebhookPolicy)
+	service.now = func() time.Time { return now }
+
+	secrets := make(map[string]string)
+	for _, target := range []string{"https://example.com/ok", "https://example.com/flaky", "https://example.com/down"} {
+		webhook, err := service.Register(ctx, entities.WebhookRequest{URL: target})
+		require.NoError(t, err)
+		secrets[strconv.FormatUint(webhook.ID, 10)] = webhook.Secret
+	}
+	require.NoError(t, service.RecordCancellation(ctx, &entities.Transaction{
+		UserID: 7, TransactionID: "tx-1", State: entities.StateWin, Amount: decimal.NewFromInt(10),
+	}, decimal.NewFromInt(90)))
+	require.Len(t, repo.deliveries, 3)
+
+	attempted, err := service.Deliver(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 3, attempted)
+
+	// Every attempt is signed with the secret of its webhook
+	for i, headers := range sender.sent {
+		var body entities.WebhookPayload
+		require.NoError(t, json.Unmarshal(sender.bodies[i], &body))
+		assert.Equal(t, entities.EventTransactionCancelled, body.Type)
+		assert.Equal(t, entities.EventTransactionCancelled, headers[WebhookEventHeader])
+		deliveryID := headers[WebhookDeliveryHeader]
+		assert.Equal(t, strconv.FormatUint(body.ID, 10), deliveryID)
+		webhookID := strconv.FormatUint(repo.deliveries[body.ID-1].WebhookID, 10)
+		assert.Equal(t, SignWebhook(secrets[webhookID], now, sender.bodies[i]), headers[WebhookSignatureHeader])
+		assert.Equal(t, strconv.FormatInt(now.Unix(), 10), headers[WebhookTimestampHeader])
+	}
+
+	succeeded, flaky := repo.deliveries[0], repo.deliveries[1]
+	assert.Equal(t, entities.WebhookDeliverySucceeded, succeeded.Status)
+	assert.Equal(t, 200, succeeded.LastStatusCode)
+	assert.Equal(t, now, *succeeded.DeliveredAt)
+	assert.Equal(t, entities.WebhookDeliveryPending, flaky.Status)
+	assert.Equal(t, 503, flaky.LastStatusCode)
+	assert.Equal(t, now.Add(time.Minute), *flaky.NextAttemptAt)
+
+	// Nothing is due until the backoff has passed
+	attempted, err = service.Deliver(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 0, attempted)
+
+	// The backoff doubles up to its cap
+	now = now.Add(time.Minute)
+	_, err = service.Deliver(ctx, 10)
+	require.NoError(t, err)
+	flaky = repo.deliveries[1]
+	assert.Equal(t, 2, flaky.Attempts)
+	assert.Equal(t, "connection refused", flaky.LastError)
+	assert.Equal(t, now.Add(90*time.Second), *flaky.NextAttemptAt)
+
+	now = now.Add(90 * time.Second)
+	_, err = service.Deliver(ctx, 10)
+	require.NoError(t, err)
+
+	assert.Equal(t, entities.WebhookDeliverySucceeded, repo.deliveries[1].Status)
+	assert.Equal(t, 3, repo.deliveries[1].Attempts)
+	down := repo.deliveries[2]
+	assert.Equal(t, entities.WebhookDeliveryFailed, down.Status, "deliveries fail after the last attempt")
+	assert.Equal(t, 3, down.Attempts)
+	assert.Nil(t, down.NextAttemptAt)
+}
+
+func TestWebhookService_ListDeliveries(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeWebhookRepo{}
+	service := NewWebhookService(repo, &scriptedSender{}, testWebhookPolicy)
+	webhook, err := service.Register(ctx, entities.WebhookRequest{URL: "https://example.com/hook"})
+	require.NoError(t, err)
+	for _, transactionID := range []string{"tx-1", "tx-2"} {
+		require.NoError(t, service.RecordCancellation(ctx, &entities.Transaction{UserID: 7, TransactionID: transactionID}, decimal.Zero))
+	}
+
+	page, err := service.ListDeliveries(ctx, webhook.ID, entities.WebhookDeliveryPending, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 2, page.Total)
+	assert.Equal(t, DefaultPageSize, page.Limit)
+	assert.Equal(t, uint64(2), page.Deliveries[0].ID, "newest first")
+
+	_, err = service.ListDeliveries(ctx, webhook.ID, "lost", 0, 0)
+	assert.ErrorIs(t, err, ErrInvalidFilter)
+
+	require.NoError(t, service.DeleteWebhook(ctx, webhook.ID))
+	assert.Equal(t, entities.WebhookDeliveryFailed, repo.deliveries[0].Status)
+	_, err = service.ListDeliveries(ctx, webhook.ID, "", 0, 0)
+	assert.ErrorIs(t, err, ErrWebhookNotFound)
+	assert.ErrorIs(t, service.DeleteWebhook(ctx, webhook.ID), ErrWebhookNotFound)
+}
+
+func TestSignWebhook(t *testing.T) {
+	timestamp := time.Unix(1700000000, 0)
+	signature := SignWebhook("secret", timestamp, []byte(`{"id":1}`))
+
+	assert.Equal(t, "sha256=", signature[:7])
+	assert.Len(t, signature, 7+64)
+	assert.Equal(t, signature, SignWebhook("secret", timestamp, []byte(`{"id":1}`)))
+	assert.NotEqual(t, signature, SignWebhook("other", timestamp, []byte(`{"id":1}`)))
+	assert.NotEqual(t, signature, SignWebhook("secret", timestamp.Add(time.Second), []byte(`{"id":1}`)))
+}
@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"slices"
+
+	"transaction-service/internal/domain/entities"
+)
+
+// ErrUnknownAPIKey is returned for API keys that are not in the store
+var ErrUnknownAPIKey = errors.New("unknown API key")
+
+// APIKey is an integrator's credential and the source types it is bound to
+type APIKey struct {
+	// ID identifies the key in logs without revealing it
+	ID          string
+	SourceTypes []entities.SourceType
+}
+
+// Allows reports whether the key may submit transactions of sourceType
+func (k *APIKey) Allows(sourceType entities.SourceType) bool {
+	return slices.Contains(k.SourceTypes, sourceType)
+}
+
+// APIKeyStore resolves API keys to the integrator they were issued to
+type APIKeyStore interface {
+	Lookup(ctx context.Context, key string) (*APIKey, error)
+}
+
+// StaticAPIKeyStore holds a fixed set of API keys. Only their hashes are kept
+// in memory.
+type StaticAPIKeyStore struct {
+	keys map[[sha256.Size]byte]*APIKey
+}
+
+// NewStaticAPIKeyStore creates a store from API keys and their source types
+func NewStaticAPIKeyStore(keys map[string][]entities.SourceType) *StaticAPIKeyStore {
+	store := &StaticAPIKeyStore{keys: make(map[[sha256.Size]byte]*APIKey, len(keys))}
+	for key, sourceTypes := range keys {
+		hash := sha256.Sum256([]byte(key))
+		store.keys[hash] = &APIKey{
+			ID:          hex.EncodeToString(hash[:4]),
+			SourceTypes: slices.Clone(sourceTypes),
+		}
+	}
+	return store
+}
+
+// Lookup implements APIKeyStore
+func (s *StaticAPIKeyStore) Lookup(_ context.Context, key string) (*APIKey, error) {
+	apiKey, ok := s.keys[sha256.Sum256([]byte(key))]
+	if !ok {
+		return nil, ErrUnknownAPIKey
+	}
+	return apiKey, nil
+}
@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"transaction-service/internal/domain/entities"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticAPIKeyStore(t *testing.T) {
+	store := NewStaticAPIKeyStore(map[string][]entities.SourceType{
+		"game-backend-key": {entities.SourceTypeGame},
+		"psp-key":          {entities.SourceTypePayment, entities.SourceTypeServer},
+	})
+
+	key, err := store.Lookup(context.Background(), "game-backend-key")
+	require.NoError(t, err)
+	assert.True(t, key.Allows(entities.SourceTypeGame))
+	assert.False(t, key.Allows(entities.SourceTypePayment))
+	assert.Len(t, key.ID, 8)
+	assert.NotContains(t, key.ID, "game")
+
+	key, err = store.Lookup(context.Background(), "psp-key")
+	require.NoError(t, err)
+	assert.True(t, key.Allows(entities.SourceTypePayment))
+	assert.True(t, key.Allows(entities.SourceTypeServer))
+
+	_, err = store.Lookup(context.Background(), "unknown")
+	assert.ErrorIs(t, err, ErrUnknownAPIKey)
+}
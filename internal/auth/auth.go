@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrInvalidToken is returned for bearer tokens that are malformed, expired,
+// wrongly signed or issued by someone else
+var ErrInvalidToken = errors.New("invalid token")
+
+// Claims are the JWT claims the service understands. The subject is the ID of
+// the user the caller acts for and scope is a space-separated scope list.
+type Claims struct {
+	Scope string `json:"scope,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// HasScope reports whether the claims grant scope
+func (c *Claims) HasScope(scope string) bool {
+	return scope != "" && slices.Contains(strings.Fields(c.Scope), scope)
+}
+
+// UserID returns the user ID carried in the subject, if any
+func (c *Claims) UserID() (uint64, bool) {
+	userID, err := strconv.ParseUint(c.Subject, 10, 64)
+	if err != nil || userID == 0 {
+		return 0, false
+	}
+	return userID, true
+}
+
+// Verifier validates HS256-signed bearer tokens and decides what their
+// holders may access
+type Verifier struct {
+	key        []byte
+	issuer     string
+	adminScope string
+}
+
+// NewVerifier creates a verifier for tokens signed with signingKey. When issuer
+// is not empty tokens must carry it as their issuer. Holders of adminScope may
+// operate on any user.
+func NewVerifier(signingKey, issuer, adminScope string) *Verifier {
+	return &Verifier{
+		key:        []byte(signingKey),
+		issuer:     issuer,
+		adminScope: adminScope,
+	}
+}
+
+// Verify parses a bearer token and validates its signature, expiry and issuer
+func (v *Verifier) Verify(token string) (*Claims, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithExpirationRequired(),
+	}
+	if v.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(v.issuer))
+	}
+
+	claims := &Claims{}
+	if _, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (any, error) {
+		return v.key, nil
+	}, opts...); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	return claims, nil
+}
+
+// IsAdmin reports whether the claims carry the admin scope
+func (v *Verifier) IsAdmin(claims *Claims) bool {
+	return claims.HasScope(v.adminScope)
+}
+
+// CanAccessUser reports whether the holder of claims may operate on userID:
+// admins may operate on anyone, everybody else only on themselves
+func (v *Verifier) CanAccessUser(claims *Claims, userID uint64) bool {
+	if v.IsAdmin(claims) {
+		return true
+	}
+	subject, ok := claims.UserID()
+	return ok && subject == userID
+}
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testKey = "test-signing-key"
+
+func signToken(t *testing.T, method jwt.SigningMethod, key any, claims Claims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	require.NoError(t, err)
+	return token
+}
+
+func userClaims(subject, scope string) Claims {
+	return Claims{
+		Scope: scope,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject,
+			Issuer:    "wallet-auth",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	verifier := NewVerifier(testKey, "wallet-auth", "admin")
+
+	expired := userClaims("1", "")
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+
+	noExpiry := userClaims("1", "")
+	noExpiry.ExpiresAt = nil
+
+	otherIssuer := userClaims("1", "")
+	otherIssuer.Issuer = "someone-else"
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "valid token", token: signToken(t, jwt.SigningMethodHS256, []byte(testKey), userClaims("1", ""))},
+		{name: "wrong key", token: signToken(t, jwt.SigningMethodHS256, []byte("other-key"), userClaims("1", "")), wantErr: true},
+		{name: "disallowed algorithm", token: signToken(t, jwt.SigningMethodHS512, []byte(testKey), userClaims("1", "")), wantErr: true},
+		{name: "unsigned token", token: signToken(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, userClaims("1", "")), wantErr: true},
+		{name: "expired", token: signToken(t, jwt.SigningMethodHS256, []byte(testKey), expired), wantErr: true},
+		{name: "missing expiry", token: signToken(t, jwt.SigningMethodHS256, []byte(testKey), noExpiry), wantErr: true},
+		{name: "wrong issuer", token: signToken(t, jwt.SigningMethodHS256, []byte(testKey), otherIssuer), wantErr: true},
+		{name: "garbage", token: "not-a-token", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := verifier.Verify(tt.token)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidToken)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "1", claims.Subject)
+		})
+	}
+}
+
+func TestVerifier_CanAccessUser(t *testing.T) {
+	verifier := NewVerifier(testKey, "", "admin")
+
+	user := userClaims("7", "read write")
+	admin := userClaims("", "read admin")
+	anonymous := userClaims("", "")
+
+	assert.True(t, verifier.CanAccessUser(&user, 7))
+	assert.False(t, verifier.CanAccessUser(&user, 8))
+	assert.True(t, verifier.CanAccessUser(&admin, 8))
+	assert.False(t, verifier.CanAccessUser(&anonymous, 7))
+	assert.False(t, verifier.IsAdmin(&user))
+	assert.True(t, verifier.IsAdmin(&admin))
+}
@@ -0,0 +1,58 @@
+package clock
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNonPositiveDuration is returned when advancing by zero or a negative duration
+var ErrNonPositiveDuration = errors.New("duration must be positive")
+
+// Virtual is a clock running at wall-clock speed but offset into the future.
+// Sandbox tenants advance it to exercise time-dependent behavior, such as
+// limit windows, without waiting. It is safe for concurrent use.
+type Virtual struct {
+	mu     sync.RWMutex
+	offset time.Duration
+	now    func() time.Time
+}
+
+// NewVirtual creates a virtual clock showing the current time
+func NewVirtual() *Virtual {
+	return &Virtual{now: time.Now}
+}
+
+// Now returns the virtual time
+func (v *Virtual) Now() time.Time {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.now().Add(v.offset)
+}
+
+// Offset returns how far the clock is ahead of the wall clock
+func (v *Virtual) Offset() time.Duration {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.offset
+}
+
+// Advance moves the clock forward by d. It never moves backwards, so that
+// times recorded earlier are never in the future.
+func (v *Virtual) Advance(d time.Duration) error {
+	if d <= 0 {
+		return ErrNonPositiveDuration
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.offset += d
+	return nil
+}
+
+// Reset brings the clock back to the wall clock
+func (v *Virtual) Reset() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.offset = 0
+}
@@ -0,0 +1,29 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVirtual(t *testing.T) {
+	wall := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	v := NewVirtual()
+	v.now = func() time.Time { return wall }
+
+	assert.Equal(t, wall, v.Now())
+
+	require.NoError(t, v.Advance(2*time.Hour))
+	require.NoError(t, v.Advance(30*time.Minute))
+	assert.Equal(t, wall.Add(150*time.Minute), v.Now())
+	assert.Equal(t, 150*time.Minute, v.Offset())
+
+	assert.ErrorIs(t, v.Advance(0), ErrNonPositiveDuration)
+	assert.ErrorIs(t, v.Advance(-time.Hour), ErrNonPositiveDuration)
+	assert.Equal(t, 150*time.Minute, v.Offset())
+
+	v.Reset()
+	assert.Equal(t, wall, v.Now())
+}
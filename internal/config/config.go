@@ -0,0 +1,2555 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Config holds the application configuration loaded from the environment
+type Config struct {
+	Port     string `json:"port"`
+	GRPCPort string `json:"grpcPort"`
+	// ShutdownTimeout bounds how long in-flight requests are drained on shutdown
+	ShutdownTimeout time.Duration  `json:"shutdownTimeout"`
+	Log             LogConfig      `json:"log"`
+	Auth            AuthConfig     `json:"auth"`
+	Database        DatabaseConfig `json:"database"`
+	// Storage is "postgres" or "memory", which keeps users, wallets and
+	// transactions in the process for tests and demos
+	Storage string `json:"storage"`
+	// MigrateOnStart migrates the schema when the active region starts;
+	// deployments running cmd/migrate on their own turn it off
+	MigrateOnStart bool `json:"migrateOnStart"`
+	// DatabaseRetry bounds the retries of transient database errors
+	DatabaseRetry DatabaseRetryConfig `json:"databaseRetry"`
+	// StorageMigration configures writing to a second storage backend
+	StorageMigration StorageMigrationConfig `json:"storageMigration"`
+	// ReplicaReads configures serving reads from a read replica
+	ReplicaReads ReplicaReadsConfig `json:"replicaReads"`
+	Readiness    ReadinessConfig    `json:"readiness"`
+	Liveness     LivenessConfig     `json:"liveness"`
+	Warmup       WarmupConfig       `json:"warmup"`
+	Guard        GuardConfig        `json:"balanceGuard"`
+	ClockSkew    ClockSkewConfig    `json:"clockSkew"`
+	Amounts      AmountConfig       `json:"amounts"`
+	Velocity     VelocityConfig     `json:"velocity"`
+	Seed         SeedConfig         `json:"seed"`
+	IDs          IDConfig           `json:"ids"`
+	Region       RegionConfig       `json:"region"`
+	// StaleBalance configures serving cached balances during database outages
+	StaleBalance StaleBalanceConfig `json:"staleBalance"`
+	// Redis configures cross-replica cache invalidation
+	Redis        RedisConfig        `json:"redis"`
+	Cancellation CancellationConfig `json:"cancellationWorker"`
+	Dormancy     DormancyConfig     `json:"dormancy"`
+	Holds        HoldConfig         `json:"holds"`
+	Settlement   SettlementConfig   `json:"settlement"`
+	Quota        QuotaConfig        `json:"quotaWarnings"`
+	RateLimit    RateLimitConfig    `json:"rateLimit"`
+	// ScheduledTransactions configures future-dated and recurring
+	// transactions
+	ScheduledTransactions ScheduledTransactionConfig `json:"scheduledTransactions"`
+	// BalanceSnapshots configures the daily closing balance snapshots
+	BalanceSnapshots BalanceSnapshotConfig `json:"balanceSnapshots"`
+	// Routes configures the middleware run by each route group
+	Routes RoutesConfig `json:"routes"`
+	SLO    SLOConfig    `json:"slo"`
+	// Shadow runs a sample of the requests through a candidate engine
+	Shadow   ShadowConfig  `json:"shadow"`
+	Outbox   OutboxConfig  `json:"outbox"`
+	CDC      CDCConfig     `json:"cdc"`
+	Webhooks WebhookConfig `json:"webhooks"`
+	// Ingest bounds the retries of the transactions consumed from brokers
+	Ingest IngestConfig `json:"ingest"`
+	// Kafka configures the consumer of the transactions game servers publish
+	Kafka KafkaConfig `json:"kafka"`
+	// NATS configures the consumer of the transaction commands game servers
+	// publish to JetStream
+	NATS NATSConfig `json:"nats"`
+	// RabbitMQ configures the consumer of the transactions the legacy wallet
+	// integration publishes
+	RabbitMQ RabbitMQConfig `json:"rabbitmq"`
+	// Processor sizes the worker pool processing transactions off the
+	// request path
+	Processor ProcessorConfig `json:"processor"`
+	// AsyncProcessing honours Prefer: respond-async, processing the
+	// transactions of the callers sending it on the processor pool
+	AsyncProcessing bool `json:"asyncProcessing"`
+	// HTTPClient configures the outbound HTTP clients per destination
+	HTTPClient HTTPClientConfig `json:"httpClient"`
+	// RejectionAnalytics records rejected transaction attempts for the
+	// rejection rate reports
+	RejectionAnalytics bool `json:"rejectionAnalytics"`
+	// Fees charges the fees of the admin-managed fee rules
+	Fees bool `json:"fees"`
+	// SystemAccounts provisions the house, promo and escheat accounts as the
+	// counterparty of fees, sweeps and corrections
+	SystemAccounts bool `json:"systemAccounts"`
+	// BalanceAdjustments serves the admin endpoints crediting and debiting
+	// users by hand, audited in the balance adjustments table
+	BalanceAdjustments bool `json:"balanceAdjustments"`
+	// AuditLog records every balance-affecting operation in the append-only
+	// audit log
+	AuditLog bool `json:"auditLog"`
+	// BalanceChecks serves the admin endpoints recomputing the balances from
+	// the transactions
+	BalanceChecks bool `json:"balanceChecks"`
+	// UserAdvisoryLocks takes an advisory lock per user in every unit of work
+	// updating a balance
+	UserAdvisoryLocks bool `json:"userAdvisoryLocks"`
+	// SerializableIsolation runs the unit of work of every transaction under
+	// SERIALIZABLE isolation, retrying its serialization failures
+	SerializableIsolation bool `json:"serializableIsolation"`
+	// Jurisdictions maps a country code to its rule overlay
+	Jurisdictions map[string]JurisdictionConfig `json:"jurisdictions"`
+	// ExportDir is where export jobs write their files and manifests
+	ExportDir string `json:"exportDir"`
+	// EnvelopeAPIKeys are the API keys served in the legacy response envelope mode
+	EnvelopeAPIKeys []string `json:"envelopeApiKeys" redact:"true"`
+	// Currency is the ISO 4217 code of the base currency balances are held in
+	Currency string `json:"currency"`
+	// Currencies are the ISO 4217 codes users may hold balances in, each
+	// besides the base currency in its own wallet
+	Currencies []string `json:"currencies"`
+	// APIKeys maps integrator API keys to the source types they are bound to
+	APIKeys map[string][]string `json:"apiKeys" redact:"true"`
+	// MinorUnitsAPIKeys are the API keys exchanging amounts in integer minor units
+	MinorUnitsAPIKeys []string      `json:"minorUnitsApiKeys" redact:"true"`
+	Sandbox           SandboxConfig `json:"sandbox"`
+}
+
+// SandboxConfig routes integration-test traffic to isolated test users
+type SandboxConfig struct {
+	// APIKeys are the API keys whose traffic is served from the sandbox
+	APIKeys []string `json:"apiKeys" redact:"true"`
+	// Schema is the PostgreSQL schema holding the sandbox tables
+	Schema string `json:"schema"`
+	// Users are the deterministic test users restored on every reset
+	Users []SeedUserConfig `json:"users"`
+}
+
+// LogConfig holds the structured logger settings
+type LogConfig struct {
+	Level string `json:"level"`
+	// Format is either "json" or "console"
+	Format string `json:"format"`
+}
+
+// AuthConfig holds the JWT bearer token settings
+type AuthConfig struct {
+	Enabled    bool   `json:"enabled"`
+	SigningKey string `json:"signingKey" redact:"true"`
+	// Issuer, when set, must match the tokens' iss claim
+	Issuer string `json:"issuer"`
+	// AdminScope lets callers operate on any user and use the admin routes
+	AdminScope string `json:"adminScope"`
+}
+
+// DatabaseConfig holds the PostgreSQL connection settings
+type DatabaseConfig struct {
+	// Driver is "postgres", "mysql" or "sqlite"
+	Driver   string `json:"driver"`
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	User     string `json:"user"`
+	Password string `json:"password" redact:"true"`
+	Name     string `json:"name"`
+	SSLMode  string `json:"sslMode"`
+	// Schema, when set, is used as the connection's search_path
+	Schema string `json:"schema,omitempty"`
+	// Path is the database file of the sqlite driver, which ignores the
+	// other settings
+	Path string `json:"path,omitempty"`
+	// Pool sizes the connection pool of the postgres driver
+	Pool DatabasePoolConfig `json:"pool"`
+	// StatementTimeout is the statement_timeout of the postgres driver's
+	// sessions, after which the server cancels a statement
+	StatementTimeout time.Duration `json:"statementTimeout"`
+	// QueryTimeout bounds the statements of the postgres driver on the
+	// client, for when the server cannot cancel them in time
+	QueryTimeout time.Duration `json:"queryTimeout"`
+}
+
+// DatabasePoolConfig holds the settings of a PostgreSQL connection pool
+type DatabasePoolConfig struct {
+	MaxConns int32 `json:"maxConns"`
+	// MinConns connections are kept open even while the pool is idle
+	MinConns        int32         `json:"minConns"`
+	MaxConnLifetime time.Duration `json:"maxConnLifetime"`
+	MaxConnIdleTime time.Duration `json:"maxConnIdleTime"`
+}
+
+// StorageMigrationConfig holds the settings for migrating to a new storage
+// backend by writing to both the current and the new one
+type StorageMigrationConfig struct {
+	// Phase is "off", "dual_write" or "cutover"
+	Phase string `json:"phase"`
+	// Target is the database being migrated to
+	Target DatabaseConfig `json:"target"`
+	// QueueSize bounds the writes waiting to be applied to the shadow store
+	QueueSize int `json:"queueSize"`
+}
+
+// ReplicaReadsConfig holds the settings for serving reads from a replica of
+// the database, with read-your-writes consistency tokens
+type ReplicaReadsConfig struct {
+	Enabled bool `json:"enabled"`
+	// Replica is the read replica of the database
+	Replica DatabaseConfig `json:"replica"`
+}
+
+// DatabaseRetryConfig holds the retry policy for transient database errors
+type DatabaseRetryConfig struct {
+	// MaxAttempts includes the first attempt; 1 disables retries
+	MaxAttempts int `json:"maxAttempts"`
+	// SerializationMaxAttempts bounds the attempts of units of work failing
+	// to serialize under serializable isolation
+	SerializationMaxAttempts int           `json:"serializationMaxAttempts"`
+	BaseDelay                time.Duration `json:"baseDelay"`
+	MaxDelay                 time.Duration `json:"maxDelay"`
+}
+
+// ReadinessConfig holds the settings for the readiness endpoint
+type ReadinessConfig struct {
+	CheckTimeout time.Duration `json:"checkTimeout"`
+	// Policies maps a dependency name to its readiness policy
+	Policies map[string]string `json:"policies"`
+}
+
+// LivenessConfig holds the settings for the liveness endpoint
+type LivenessConfig struct {
+	// StallTimeout is how long a periodic worker may make no progress beyond
+	// its interval before the service is reported as stalled
+	StallTimeout time.Duration `json:"stallTimeout"`
+}
+
+// GuardConfig holds the settings for the balance rate-of-change guard
+type GuardConfig struct {
+	Enabled          bool            `json:"enabled"`
+	Window           time.Duration   `json:"window"`
+	MaxChange        decimal.Decimal `json:"maxChange"`
+	MaxChangePercent decimal.Decimal `json:"maxChangePercent"`
+	// Action is either "flag" or "block"
+	Action       string `json:"action"`
+	FreezeOnTrip bool   `json:"freezeOnTrip"`
+}
+
+// ClockSkewConfig holds the accepted skew for client-supplied timestamps
+type ClockSkewConfig struct {
+	Default time.Duration `json:"default"`
+	// PerSource maps a source type to its tolerance
+	PerSource map[string]time.Duration `json:"perSource"`
+}
+
+// AmountConfig holds the validation policy of transaction amounts; zero
+// bounds are disabled
+type AmountConfig struct {
+	Min              decimal.Decimal `json:"min"`
+	Max              decimal.Decimal `json:"max"`
+	MaxDecimalPlaces int             `json:"maxDecimalPlaces"`
+	// MinPerSource and MaxPerSource map a source type to the bound replacing
+	// Min or Max
+	MinPerSource map[string]decimal.Decimal `json:"minPerSource"`
+	MaxPerSource map[string]decimal.Decimal `json:"maxPerSource"`
+}
+
+// VelocityConfig holds the limits of the transactions of every user within a
+// rolling window; zero limits are disabled
+type VelocityConfig struct {
+	MaxTransactions int             `json:"maxTransactions"`
+	MaxLoss         decimal.Decimal `json:"maxLoss"`
+	Window          time.Duration   `json:"window"`
+}
+
+// SeedConfig holds the users that are seeded on startup
+type SeedConfig struct {
+	Users []SeedUserConfig `json:"users"`
+}
+
+// SeedUserConfig describes a single seeded user
+type SeedUserConfig struct {
+	ID      uint64          `json:"id"`
+	Balance decimal.Decimal `json:"balance"`
+}
+
+// IDConfig selects how transaction IDs and receipts are allocated
+type IDConfig struct {
+	// Strategy is one of "sequence", "uuidv7" or "snowflake"
+	Strategy string `json:"strategy"`
+	// NodeID distinguishes instances under the snowflake strategy
+	NodeID uint16 `json:"nodeId"`
+}
+
+// RegionConfig holds the active-passive replication settings
+type RegionConfig struct {
+	Name string `json:"name"`
+	// Mode is either "active" or "standby"
+	Mode string `json:"mode"`
+	// ActiveURL is the base URL standby regions send writers to
+	ActiveURL string `json:"activeUrl"`
+}
+
+// StaleBalanceConfig holds the settings for the stale balance fallback
+type StaleBalanceConfig struct {
+	Enabled      bool          `json:"enabled"`
+	MaxStaleness time.Duration `json:"maxStaleness"`
+}
+
+// RedisConfig holds the Redis connection used for cache invalidation. An
+// empty Addr disables it.
+type RedisConfig struct {
+	Addr     string `json:"addr"`
+	Password string `json:"password" redact:"true"`
+	DB       int    `json:"db"`
+	// InvalidationChannel is the pub/sub channel balance updates are announced on
+	InvalidationChannel string `json:"invalidationChannel"`
+}
+
+// CancellationConfig holds the settings for the cancellation worker
+type CancellationConfig struct {
+	Enabled   bool          `json:"enabled"`
+	Interval  time.Duration `json:"interval"`
+	BatchSize int           `json:"batchSize"`
+}
+
+// WarmupConfig holds the settings for the startup warm-up, which keeps the
+// service unready until it is done
+type WarmupConfig struct {
+	Enabled bool `json:"enabled"`
+	// Connections are opened to the database up front
+	Connections int `json:"connections"`
+	// Users is how many of the users with the most transactions in the last
+	// Window have their balances cached
+	Users  int           `json:"users"`
+	Window time.Duration `json:"window"`
+	// Timeout bounds the warm-up, after which the service is ready anyway
+	Timeout time.Duration `json:"timeout"`
+}
+
+// DormancyConfig holds the settings for the dormancy sweep
+type DormancyConfig struct {
+	Enabled bool `json:"enabled"`
+	// Period is how long users must go without transactions to be dormant
+	Period    time.Duration `json:"period"`
+	Interval  time.Duration `json:"interval"`
+	BatchSize int           `json:"batchSize"`
+}
+
+// ProcessorConfig holds the settings for the transaction processor pool
+type ProcessorConfig struct {
+	// Workers process the transactions queued, up to QueueSize at a time
+	Workers   int `json:"workers"`
+	QueueSize int `json:"queueSize"`
+}
+
+// HoldConfig holds the settings for payment holds and their expiry worker
+type HoldConfig struct {
+	Enabled    bool          `json:"enabled"`
+	DefaultTTL time.Duration `json:"defaultTtl"`
+	MaxTTL     time.Duration `json:"maxTtl"`
+	// ExpiryInterval and ExpiryBatchSize pace the expiry worker
+	ExpiryInterval  time.Duration `json:"expiryInterval"`
+	ExpiryBatchSize int           `json:"expiryBatchSize"`
+}
+
+// SettlementConfig holds the settings for pending payment credits and their
+// settlement worker
+type SettlementConfig struct {
+	Enabled bool `json:"enabled"`
+	// Delay is how long payment credits stay pending before the worker
+	// settles them
+	Delay     time.Duration `json:"delay"`
+	Interval  time.Duration `json:"interval"`
+	BatchSize int           `json:"batchSize"`
+}
+
+// ScheduledTransactionConfig holds the settings for scheduled transactions and
+// the worker running them
+type ScheduledTransactionConfig struct {
+	Enabled   bool          `json:"enabled"`
+	Interval  time.Duration `json:"interval"`
+	BatchSize int           `json:"batchSize"`
+}
+
+// BalanceSnapshotConfig holds the settings for the worker snapshotting the
+// daily closing balances. Every run snapshots up to BatchSize users.
+type BalanceSnapshotConfig struct {
+	Enabled   bool          `json:"enabled"`
+	Interval  time.Duration `json:"interval"`
+	BatchSize int           `json:"batchSize"`
+}
+
+// QuotaConfig holds when the velocity limits left to a user are reported
+// through response headers
+type QuotaConfig struct {
+	Enabled   bool    `json:"enabled"`
+	WarnRatio float64 `json:"warnRatio"`
+}
+
+// RateLimitConfig holds the transaction submission rate limits
+type RateLimitConfig struct {
+	Enabled bool `json:"enabled"`
+	// Backend is either "memory" or "redis"
+	Backend   string  `json:"backend"`
+	UserRate  float64 `json:"userRate"`
+	UserBurst int     `json:"userBurst"`
+	// SourceRates maps a source type to its rate per second
+	SourceRates map[string]float64 `json:"sourceRates"`
+}
+
+// SLOConfig holds the service level objectives of the routes, tracked from
+// the request metrics
+type SLOConfig struct {
+	// Window is the period the error budgets are spent over
+	Window     time.Duration  `json:"window"`
+	Objectives []SLOObjective `json:"objectives"`
+}
+
+// SLOObjective is the availability and latency a route must meet
+type SLOObjective struct {
+	// Route is the method and route template, e.g. "GET /user/:userId/balance"
+	Route string `json:"route"`
+	// Availability is the percentage of requests that must not fail with a
+	// 5xx status
+	Availability float64 `json:"availability"`
+	// LatencyTarget is the percentage of requests that must be served within
+	// Latency
+	Latency       time.Duration `json:"latency"`
+	LatencyTarget float64       `json:"latencyTarget"`
+}
+
+// ShadowConfig holds the share of the transactions and balance reads also run
+// through the candidate engine, whose results are compared but never returned
+type ShadowConfig struct {
+	// Percent of the requests run in shadow; 0 disables shadow runs
+	Percent float64 `json:"percent"`
+	// Timeout bounds each candidate run
+	Timeout time.Duration `json:"timeout"`
+	// MaxConcurrent bounds the candidate reads running in the background
+	MaxConcurrent int `json:"maxConcurrent"`
+}
+
+// RouteMiddleware are the middleware route groups may run, in the order they
+// run in: authentication, the request body limit, HMAC request signatures
+// and rate limiting
+var RouteMiddleware = []string{"auth", "body_limit", "signing", "rate_limit"}
+
+// RoutesConfig holds the middleware chains of the route groups
+type RoutesConfig struct {
+	// Groups maps the path prefix of a route group to the RouteMiddleware its
+	// routes run. A route belongs to the group with the longest prefix of its
+	// path; the "/" group holds the routes outside every other group.
+	Groups map[string][]string `json:"groups"`
+	// BodyLimit is the largest request body accepted by body_limit, in bytes
+	BodyLimit int64 `json:"bodyLimit"`
+	// SigningSecret keys the HMAC-SHA256 request signatures checked by signing
+	SigningSecret string `json:"signingSecret" redact:"true"`
+	// SigningTolerance bounds how far a signature's timestamp may be from the
+	// server clock, limiting replays
+	SigningTolerance time.Duration `json:"signingTolerance"`
+	// Legacy serves the deprecated unversioned routes next to /api/v1
+	Legacy bool `json:"legacy"`
+	// LegacySunset is when the unversioned routes will be removed, announced
+	// in their Sunset header; zero while unknown
+	LegacySunset time.Time `json:"legacySunset"`
+}
+
+// OutboxConfig holds the settings for publishing balance change events
+type OutboxConfig struct {
+	Enabled bool `json:"enabled"`
+	// Publisher is "log", "redis", "kafka" or "nats"
+	Publisher string `json:"publisher"`
+	// Topic is the topic or stream the events are published to, or the
+	// prefix of their NATS subjects
+	Topic string `json:"topic"`
+	// StreamMaxLen caps the Redis stream approximately; zero keeps every event
+	StreamMaxLen int64 `json:"streamMaxLen"`
+	// RelayInterval and RelayBatchSize pace the relay worker
+	RelayInterval  time.Duration `json:"relayInterval"`
+	RelayBatchSize int           `json:"relayBatchSize"`
+}
+
+// CDCConfig holds the settings for publishing the changes of the transactions
+// and users tables read from a PostgreSQL logical replication slot
+type CDCConfig struct {
+	Enabled bool `json:"enabled"`
+	// Slot and Publication name the replication slot and the publication of
+	// the tables; both are created when missing
+	Slot        string `json:"slot"`
+	Publication string `json:"publication"`
+	// Publisher is "log", "redis", "kafka" or "nats"
+	Publisher string `json:"publisher"`
+	// Topic is the topic or stream the events are published to, or the
+	// prefix of their NATS subjects
+	Topic string `json:"topic"`
+	// StreamMaxLen caps the Redis stream approximately; zero keeps every event
+	StreamMaxLen int64 `json:"streamMaxLen"`
+	// StatusInterval is how often the published position is reported to the
+	// server, which releases the WAL before it
+	StatusInterval time.Duration `json:"statusInterval"`
+}
+
+// IngestConfig holds the retry policy of the broker consumers
+type IngestConfig struct {
+	// MaxAttempts bounds the attempts of messages failing with unexpected
+	// errors before they are dead-lettered. Messages failing because the
+	// database is unavailable are retried until it recovers.
+	MaxAttempts    int           `json:"maxAttempts"`
+	RetryBaseDelay time.Duration `json:"retryBaseDelay"`
+	RetryMaxDelay  time.Duration `json:"retryMaxDelay"`
+}
+
+// KafkaConfig holds the settings for ingesting the transactions published to
+// a Kafka topic
+type KafkaConfig struct {
+	Enabled bool `json:"enabled"`
+	// Brokers are the addresses of the bootstrap brokers
+	Brokers []string `json:"brokers"`
+	// Topic is consumed by the members of the consumer group GroupID. The
+	// messages that can never be applied are written to DLQTopic.
+	Topic    string `json:"topic"`
+	GroupID  string `json:"groupId"`
+	DLQTopic string `json:"dlqTopic"`
+	// SourceType is the source type the transactions of the topic are
+	// applied as
+	SourceType string `json:"sourceType"`
+}
+
+// NATSConfig holds the settings for ingesting the transaction commands
+// published to a NATS JetStream stream
+type NATSConfig struct {
+	Enabled bool `json:"enabled"`
+	// URL is the server URL, or a comma-separated list of them
+	URL string `json:"url"`
+	// Stream is the stream the durable consumer Durable reads Subjects from.
+	// The stream must exist; the consumer is created on it.
+	Stream  string `json:"stream"`
+	Durable string `json:"durable"`
+	// Subjects route the subjects of the stream, which may hold the * and >
+	// wildcards, to the source type their transactions are applied as. The
+	// first match wins.
+	Subjects []NATSSubject `json:"subjects"`
+	// DLQSubject receives the messages that can never be applied. It must
+	// belong to a stream and match none of Subjects.
+	DLQSubject string `json:"dlqSubject"`
+	// AckWait is how long a delivered message may go unacknowledged before
+	// it is redelivered; it is reset between the retries of a message
+	AckWait time.Duration `json:"ackWait"`
+	// MaxAckPending bounds the messages delivered but not yet acknowledged,
+	// which the processor pool handles concurrently
+	MaxAckPending int `json:"maxAckPending"`
+}
+
+// RabbitMQConfig holds the settings for ingesting the transactions published
+// to a RabbitMQ queue
+type RabbitMQConfig struct {
+	Enabled bool `json:"enabled"`
+	// URL is the AMQP URL of the broker, which may hold its credentials
+	URL string `json:"url" redact:"true"`
+	// Queue is the durable queue the transactions are consumed from. It is
+	// bound to Exchange of ExchangeKind with each of BindingKeys, unless
+	// Exchange is empty and the transactions are published to the queue.
+	Queue        string   `json:"queue"`
+	Exchange     string   `json:"exchange"`
+	ExchangeKind string   `json:"exchangeKind"`
+	BindingKeys  []string `json:"bindingKeys"`
+	// DeadLetterExchange receives the messages that can never be applied,
+	// and routes them to DeadLetterQueue
+	DeadLetterExchange string `json:"deadLetterExchange"`
+	DeadLetterQueue    string `json:"deadLetterQueue"`
+	// Prefetch bounds the messages delivered but not yet acked, which the
+	// processor pool handles concurrently
+	Prefetch int `json:"prefetch"`
+	// SourceType is the source type the transactions of the queue are
+	// applied as
+	SourceType string `json:"sourceType"`
+}
+
+// NATSSubject routes the subjects matching Subject to SourceType
+type NATSSubject struct {
+	Subject    string `json:"subject"`
+	SourceType string `json:"sourceType"`
+}
+
+// WebhookConfig holds the settings for delivering events to webhooks
+type WebhookConfig struct {
+	Enabled bool `json:"enabled"`
+	// DeliveryInterval and DeliveryBatchSize pace the delivery worker
+	DeliveryInterval  time.Duration `json:"deliveryInterval"`
+	DeliveryBatchSize int           `json:"deliveryBatchSize"`
+	// Timeout bounds each delivery attempt
+	Timeout     time.Duration `json:"timeout"`
+	MaxAttempts int           `json:"maxAttempts"`
+	// RetryBaseDelay doubles after every failed attempt up to RetryMaxDelay
+	RetryBaseDelay time.Duration `json:"retryBaseDelay"`
+	RetryMaxDelay  time.Duration `json:"retryMaxDelay"`
+}
+
+// HTTPClientConfig holds the policies of the outbound HTTP clients
+type HTTPClientConfig struct {
+	// Default applies to destinations without a policy of their own
+	Default HTTPClientPolicy `json:"default"`
+	// Destinations are the policies of the known destinations, such as
+	// "webhooks", each defaulting to Default
+	Destinations map[string]HTTPClientPolicy `json:"destinations"`
+}
+
+// HTTPClientPolicy holds the connection, timeout and retry settings of a
+// destination
+type HTTPClientPolicy struct {
+	// Timeout bounds each attempt, until the response body is read
+	Timeout             time.Duration `json:"timeout"`
+	MaxIdleConnsPerHost int           `json:"maxIdleConnsPerHost"`
+	// MaxConnsPerHost limits the connections per host; zero is unlimited
+	MaxConnsPerHost int           `json:"maxConnsPerHost"`
+	IdleConnTimeout time.Duration `json:"idleConnTimeout"`
+	// Retries is how often a failed attempt is retried, while RetryBudget,
+	// the ratio of retries to requests, allows
+	Retries     int     `json:"retries"`
+	RetryBudget float64 `json:"retryBudget"`
+	// Proxy is the URL of the proxy to use; empty uses the HTTP_PROXY,
+	// HTTPS_PROXY and NO_PROXY environment variables
+	Proxy string `json:"proxy" redact:"true"`
+}
+
+// JurisdictionConfig holds the rule overlay for a single jurisdiction
+type JurisdictionConfig struct {
+	DisabledSources []string        `json:"disabledSources"`
+	LossLimit       decimal.Decimal `json:"lossLimit"`
+	LossWindow      time.Duration   `json:"lossWindow"`
+	// DormancyFee is charged to users flagged as dormant; zero charges no fee
+	DormancyFee decimal.Decimal `json:"dormancyFee"`
+	// FreezeDormant freezes the accounts of users flagged as dormant
+	FreezeDormant bool `json:"freezeDormant"`
+}
+
+// Load reads the configuration from environment variables
+func Load() (*Config, error) {
+	checkTimeout, err := getDurationOrDefault("READINESS_CHECK_TIMEOUT", 2*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	stallTimeout, err := getDurationOrDefault("LIVENESS_STALL_TIMEOUT", 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	policies, err := parseKeyValueList(os.Getenv("READINESS_POLICIES"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid READINESS_POLICIES: %w", err)
+	}
+
+	shutdownTimeout, err := getDurationOrDefault("SHUTDOWN_TIMEOUT", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if shutdownTimeout <= 0 {
+		return nil, fmt.Errorf("invalid SHUTDOWN_TIMEOUT: must be positive")
+	}
+
+	migrateOnStart, err := getBoolOrDefault("MIGRATE_ON_START", true)
+	if err != nil {
+		return nil, err
+	}
+
+	rejectionAnalytics, err := getBoolOrDefault("REJECTION_ANALYTICS_ENABLED", false)
+	if err != nil {
+		return nil, err
+	}
+
+	fees, err := getBoolOrDefault("FEES_ENABLED", false)
+	if err != nil {
+		return nil, err
+	}
+
+	systemAccounts, err := getBoolOrDefault("SYSTEM_ACCOUNTS_ENABLED", false)
+	if err != nil {
+		return nil, err
+	}
+
+	balanceAdjustments, err := getBoolOrDefault("BALANCE_ADJUSTMENTS_ENABLED", false)
+	if err != nil {
+		return nil, err
+	}
+
+	auditLog, err := getBoolOrDefault("AUDIT_LOG_ENABLED", false)
+	if err != nil {
+		return nil, err
+	}
+
+	balanceChecks, err := getBoolOrDefault("BALANCE_CHECKS_ENABLED", false)
+	if err != nil {
+		return nil, err
+	}
+
+	userAdvisoryLocks, err := getBoolOrDefault("USER_ADVISORY_LOCKS_ENABLED", false)
+	if err != nil {
+		return nil, err
+	}
+
+	serializableIsolation, err := getBoolOrDefault("SERIALIZABLE_ISOLATION_ENABLED", false)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKeys, err := loadAPIKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	authConfig, err := loadAuthConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	guard, err := loadGuardConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	clockSkew, err := loadClockSkewConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	amounts, err := loadAmountConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	velocity, err := loadVelocityConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	seed, err := loadSeedConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := loadIDConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	staleBalanceEnabled, err := getBoolOrDefault("STALE_BALANCE_FALLBACK_ENABLED", false)
+	if err != nil {
+		return nil, err
+	}
+	maxStaleness, err := getDurationOrDefault("STALE_BALANCE_MAX_AGE", 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	redisDB, err := getUintOrDefault("REDIS_DB", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	cancellation, err := loadCancellationConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	dormancy, err := loadDormancyConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	processor, err := loadProcessorConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	asyncProcessing, err := getBoolOrDefault("ASYNC_PROCESSING_ENABLED", false)
+	if err != nil {
+		return nil, err
+	}
+
+	warmup, err := loadWarmupConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	holds, err := loadHoldConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	settlement, err := loadSettlementConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	scheduledTransactions, err := loadScheduledTransactionConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	balanceSnapshots, err := loadBalanceSnapshotConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	quota, err := loadQuotaConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	rateLimit, err := loadRateLimitConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	routes, err := loadRoutesConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	slo, err := loadSLOConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	shadow, err := loadShadowConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	outbox, err := loadOutboxConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	cdc, err := loadCDCConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	webhooks, err := loadWebhookConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	ingest, err := loadIngestConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	kafka, err := loadKafkaConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	nats, err := loadNATSConfig(ingest)
+	if err != nil {
+		return nil, err
+	}
+
+	rabbitMQ, err := loadRabbitMQConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient, err := loadHTTPClientConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	jurisdictions, err := loadJurisdictionConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	sandbox, err := loadSandboxConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	databaseRetry, err := loadDatabaseRetryConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	database, err := loadDatabaseConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	storageMigration, err := loadStorageMigrationConfig(database)
+
+	if err != nil {
+		return nil, err
+	}
+
+	replicaReads, err := loadReplicaReadsConfig(database, storageMigration)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{
+		Port:            getEnvOrDefault("PORT", "8080"),
+		GRPCPort:        getEnvOrDefault("GRPC_PORT", "9090"),
+		ShutdownTimeout: shutdownTimeout,
+		Log: LogConfig{
+			Level:  getEnvOrDefault("LOG_LEVEL", "info"),
+			Format: getEnvOrDefault("LOG_FORMAT", "json"),
+		},
+		Auth:             authConfig,
+		Database:         database,
+		Storage:          getEnvOrDefault("STORAGE_BACKEND", "postgres"),
+		MigrateOnStart:   migrateOnStart,
+		DatabaseRetry:    databaseRetry,
+		StorageMigration: storageMigration,
+		ReplicaReads:     replicaReads,
+		Readiness: ReadinessConfig{
+			CheckTimeout: checkTimeout,
+			Policies:     policies,
+		},
+		Liveness: LivenessConfig{
+			StallTimeout: stallTimeout,
+		},
+		Guard:     guard,
+		ClockSkew: clockSkew,
+		Amounts:   amounts,
+		Velocity:  velocity,
+		Seed:      seed,
+		IDs:       ids,
+		Region: RegionConfig{
+			Name:      os.Getenv("REGION_NAME"),
+			Mode:      getEnvOrDefault("REGION_MODE", "active"),
+			ActiveURL: os.Getenv("REGION_ACTIVE_URL"),
+		},
+		StaleBalance: StaleBalanceConfig{
+			Enabled:      staleBalanceEnabled,
+			MaxStaleness: maxStaleness,
+		},
+		Redis: RedisConfig{
+			Addr:                os.Getenv("REDIS_ADDR"),
+			Password:            os.Getenv("REDIS_PASSWORD"),
+			DB:                  int(redisDB),
+			InvalidationChannel: getEnvOrDefault("REDIS_INVALIDATION_CHANNEL", "balance-invalidations"),
+		},
+		Cancellation:          cancellation,
+		Dormancy:              dormancy,
+		Processor:             processor,
+		AsyncProcessing:       asyncProcessing,
+		Warmup:                warmup,
+		Holds:                 holds,
+		Settlement:            settlement,
+		ScheduledTransactions: scheduledTransactions,
+		BalanceSnapshots:      balanceSnapshots,
+		Quota:                 quota,
+		RateLimit:             rateLimit,
+		Routes:                routes,
+		SLO:                   slo,
+		Shadow:                shadow,
+		Outbox:                outbox,
+		CDC:                   cdc,
+		Webhooks:              webhooks,
+		Ingest:                ingest,
+		Kafka:                 kafka,
+		NATS:                  nats,
+		RabbitMQ:              rabbitMQ,
+		HTTPClient:            httpClient,
+		RejectionAnalytics:    rejectionAnalytics,
+		Fees:                  fees,
+		SystemAccounts:        systemAccounts,
+		BalanceAdjustments:    balanceAdjustments,
+		AuditLog:              auditLog,
+		BalanceChecks:         balanceChecks,
+		UserAdvisoryLocks:     userAdvisoryLocks,
+		SerializableIsolation: serializableIsolation,
+		Jurisdictions:         jurisdictions,
+		ExportDir:             getEnvOrDefault("EXPORT_DIR", "exports"),
+		EnvelopeAPIKeys:       parseList(os.Getenv("ENVELOPE_API_KEYS")),
+		APIKeys:               apiKeys,
+		Currency:              getEnvOrDefault("CURRENCY", "EUR"),
+		Currencies:            parseList(getEnvOrDefault("CURRENCIES", "EUR,USD,GBP")),
+		MinorUnitsAPIKeys:     parseList(os.Getenv("MINOR_UNITS_API_KEYS")),
+		Sandbox:               sandbox,
+	}
+	if err := validateStorage(cfg); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// validateStorage checks the storage backend. The memory backend and the
+// MySQL and SQLite drivers hold users, wallets, transactions and annotations
+// only, so they rule out the features storing anything else or relying on
+// PostgreSQL. Standby regions need a replicated database.
+func validateStorage(cfg *Config) error {
+	var backend string
+	switch {
+	case cfg.Storage == "memory":
+		backend = "the memory storage backend"
+	case cfg.Storage != "postgres":
+		return fmt.Errorf("invalid STORAGE_BACKEND %q: must be postgres or memory", cfg.Storage)
+	case cfg.Database.Driver != "postgres":
+		backend = "the " + cfg.Database.Driver + " database driver"
+	default:
+		return nil
+	}
+
+	unsupported := []struct {
+		name    string
+		enabled bool
+	}{
+		{"STORAGE_MIGRATION_PHASE", cfg.StorageMigration.Phase != "off"},
+		{"REPLICA_READS_ENABLED", cfg.ReplicaReads.Enabled},
+		{"REGION_MODE", cfg.Region.Mode != "active" && (cfg.Storage == "memory" || cfg.Database.Driver == "sqlite")},
+		{"SANDBOX_API_KEYS", len(cfg.Sandbox.APIKeys) > 0},
+		{"HOLDS_ENABLED", cfg.Holds.Enabled},
+		{"SCHEDULED_TRANSACTIONS_ENABLED", cfg.ScheduledTransactions.Enabled},
+		{"BALANCE_SNAPSHOTS_ENABLED", cfg.BalanceSnapshots.Enabled},
+		{"OUTBOX_ENABLED", cfg.Outbox.Enabled},
+		{"CDC_ENABLED", cfg.CDC.Enabled},
+		{"WEBHOOKS_ENABLED", cfg.Webhooks.Enabled},
+		{"REJECTION_ANALYTICS_ENABLED", cfg.RejectionAnalytics},
+		{"FEES_ENABLED", cfg.Fees},
+		{"SYSTEM_ACCOUNTS_ENABLED", cfg.SystemAccounts},
+		{"BALANCE_ADJUSTMENTS_ENABLED", cfg.BalanceAdjustments},
+		{"AUDIT_LOG_ENABLED", cfg.AuditLog},
+		{"BALANCE_CHECKS_ENABLED", cfg.BalanceChecks},
+		{"USER_ADVISORY_LOCKS_ENABLED", cfg.UserAdvisoryLocks},
+		{"SERIALIZABLE_ISOLATION_ENABLED", cfg.SerializableIsolation},
+	}
+	for _, setting := range unsupported {
+		if setting.enabled {
+			return fmt.Errorf("invalid %s: not supported by %s", setting.name, backend)
+		}
+	}
+	return nil
+}
+
+// loadDatabaseConfig reads the primary database settings. DB_PORT defaults to
+// the standard port of the driver, and DB_PATH names the file of the sqlite
+// driver.
+func loadDatabaseConfig() (DatabaseConfig, error) {
+	driver := getEnvOrDefault("DB_DRIVER", "postgres")
+	var defaultPort, path string
+	switch driver {
+	case "postgres":
+		defaultPort = "5432"
+	case "mysql":
+		defaultPort = "3306"
+	case "sqlite":
+		path = getEnvOrDefault("DB_PATH", "transaction.db")
+	default:
+		return DatabaseConfig{}, fmt.Errorf("invalid DB_DRIVER %q: must be postgres, mysql or sqlite", driver)
+	}
+	pool, err := loadDatabasePoolConfig()
+	if err != nil {
+		return DatabaseConfig{}, err
+	}
+	statementTimeout, err := getDurationOrDefault("DB_STATEMENT_TIMEOUT", 30*time.Second)
+	if err != nil {
+		return DatabaseConfig{}, err
+	}
+	if statementTimeout < 0 {
+		return DatabaseConfig{}, fmt.Errorf("invalid DB_STATEMENT_TIMEOUT: must not be negative")
+	}
+	queryTimeout, err := getDurationOrDefault("DB_QUERY_TIMEOUT", 35*time.Second)
+	if err != nil {
+		return DatabaseConfig{}, err
+	}
+	if queryTimeout < 0 {
+		return DatabaseConfig{}, fmt.Errorf("invalid DB_QUERY_TIMEOUT: must not be negative")
+	}
+	// The server should cancel a slow statement first, reporting it as such
+	if queryTimeout > 0 && queryTimeout <= statementTimeout {
+		return DatabaseConfig{}, fmt.Errorf("invalid DB_QUERY_TIMEOUT: must be longer than DB_STATEMENT_TIMEOUT")
+	}
+
+	return DatabaseConfig{
+		Driver:           driver,
+		Host:             getEnvOrDefault("DB_HOST", "localhost"),
+		Port:             getEnvOrDefault("DB_PORT", defaultPort),
+		User:             getEnvOrDefault("DB_USER", "postgres"),
+		Password:         getEnvOrDefault("DB_PASSWORD", "password"),
+		Name:             getEnvOrDefault("DB_NAME", "transaction_db"),
+		SSLMode:          getEnvOrDefault("DB_SSLMODE", "disable"),
+		Path:             path,
+		Pool:             pool,
+		StatementTimeout: statementTimeout,
+		QueryTimeout:     queryTimeout,
+	}, nil
+}
+
+// loadDatabasePoolConfig reads the DB_POOL_* settings of the PostgreSQL
+// connection pools
+func loadDatabasePoolConfig() (DatabasePoolConfig, error) {
+	maxConns, err := getUintOrDefault("DB_POOL_MAX_CONNS", 25)
+	if err != nil {
+		return DatabasePoolConfig{}, err
+	}
+	if maxConns == 0 || maxConns > math.MaxInt32 {
+		return DatabasePoolConfig{}, fmt.Errorf("invalid DB_POOL_MAX_CONNS: must be between 1 and %d", math.MaxInt32)
+	}
+	minConns, err := getUintOrDefault("DB_POOL_MIN_CONNS", 0)
+	if err != nil {
+		return DatabasePoolConfig{}, err
+	}
+	if minConns > maxConns {
+		return DatabasePoolConfig{}, fmt.Errorf("invalid DB_POOL_MIN_CONNS: must not exceed DB_POOL_MAX_CONNS")
+	}
+	maxConnLifetime, err := getDurationOrDefault("DB_POOL_MAX_CONN_LIFETIME", time.Hour)
+	if err != nil {
+		return DatabasePoolConfig{}, err
+	}
+	if maxConnLifetime <= 0 {
+		return DatabasePoolConfig{}, fmt.Errorf("invalid DB_POOL_MAX_CONN_LIFETIME: must be positive")
+	}
+	maxConnIdleTime, err := getDurationOrDefault("DB_POOL_MAX_CONN_IDLE_TIME", 30*time.Minute)
+	if err != nil {
+		return DatabasePoolConfig{}, err
+	}
+	if maxConnIdleTime <= 0 {
+		return DatabasePoolConfig{}, fmt.Errorf("invalid DB_POOL_MAX_CONN_IDLE_TIME: must be positive")
+	}
+
+	return DatabasePoolConfig{
+		MaxConns:        int32(maxConns),
+		MinConns:        int32(minConns),
+		MaxConnLifetime: maxConnLifetime,
+		MaxConnIdleTime: maxConnIdleTime,
+	}, nil
+}
+
+// loadAPIKeys reads the integrator API keys, given as "key:game|server,key2:payment"
+func loadAPIKeys() (map[string][]string, error) {
+	entries, err := parseKeyValueList(os.Getenv("API_KEYS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid API_KEYS: %w", err)
+	}
+
+	apiKeys := make(map[string][]string, len(entries))
+	for key, sources := range entries {
+		for _, source := range strings.Split(sources, "|") {
+			if source = strings.TrimSpace(source); source != "" {
+				apiKeys[key] = append(apiKeys[key], source)
+			}
+		}
+	}
+	return apiKeys, nil
+}
+
+func loadAuthConfig() (AuthConfig, error) {
+	enabled, err := getBoolOrDefault("AUTH_ENABLED", false)
+	if err != nil {
+		return AuthConfig{}, err
+	}
+
+	cfg := AuthConfig{
+		Enabled:    enabled,
+		SigningKey: os.Getenv("AUTH_JWT_SIGNING_KEY"),
+		Issuer:     os.Getenv("AUTH_JWT_ISSUER"),
+		AdminScope: getEnvOrDefault("AUTH_ADMIN_SCOPE", "admin"),
+	}
+	if cfg.Enabled && len(cfg.SigningKey) < 32 {
+		return AuthConfig{}, fmt.Errorf("invalid AUTH_JWT_SIGNING_KEY: must be at least 32 bytes when AUTH_ENABLED is set")
+	}
+	return cfg, nil
+}
+
+func loadIDConfig() (IDConfig, error) {
+	nodeID, err := getUintOrDefault("ID_NODE_ID", 0)
+	if err != nil {
+		return IDConfig{}, err
+	}
+	if nodeID > 1023 {
+		return IDConfig{}, fmt.Errorf("invalid ID_NODE_ID: must not exceed 1023")
+	}
+
+	return IDConfig{
+		Strategy: getEnvOrDefault("ID_STRATEGY", "sequence"),
+		NodeID:   uint16(nodeID),
+	}, nil
+}
+
+// loadJurisdictionConfig reads the per-jurisdiction overlays. Disabled sources
+// are given as "DE:payment|server,NL:payment", loss limits and dormancy fees
+// as "DE:1000" and the jurisdictions freezing dormant accounts as "DE,NL".
+func loadJurisdictionConfig() (map[string]JurisdictionConfig, error) {
+	lossWindow, err := getDurationOrDefault("JURISDICTION_LOSS_WINDOW", 24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	if lossWindow <= 0 {
+		return nil, fmt.Errorf("invalid JURISDICTION_LOSS_WINDOW: must be positive")
+	}
+
+	disabled, err := parseKeyValueList(os.Getenv("JURISDICTION_DISABLED_SOURCES"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid JURISDICTION_DISABLED_SOURCES: %w", err)
+	}
+	lossLimits, err := parseKeyValueList(os.Getenv("JURISDICTION_LOSS_LIMITS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid JURISDICTION_LOSS_LIMITS: %w", err)
+	}
+	dormancyFees, err := parseKeyValueList(os.Getenv("JURISDICTION_DORMANCY_FEES"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid JURISDICTION_DORMANCY_FEES: %w", err)
+	}
+
+	jurisdictions := make(map[string]JurisdictionConfig)
+	ruleFor := func(code string) JurisdictionConfig {
+		rule, ok := jurisdictions[code]
+		if !ok {
+			rule = JurisdictionConfig{LossLimit: decimal.Zero, LossWindow: lossWindow, DormancyFee: decimal.Zero}
+		}
+		return rule
+	}
+
+	for code, sources := range disabled {
+		code = strings.ToUpper(code)
+		rule := ruleFor(code)
+		for _, source := range strings.Split(sources, "|") {
+			if source = strings.TrimSpace(source); source != "" {
+				rule.DisabledSources = append(rule.DisabledSources, source)
+			}
+		}
+		jurisdictions[code] = rule
+	}
+
+	for code, value := range lossLimits {
+		code = strings.ToUpper(code)
+		limit, err := decimal.NewFromString(value)
+		if err != nil || limit.IsNegative() {
+			return nil, fmt.Errorf("invalid JURISDICTION_LOSS_LIMITS for %s: %q", code, value)
+		}
+		rule := ruleFor(code)
+		rule.LossLimit = limit
+		jurisdictions[code] = rule
+	}
+
+	for code, value := range dormancyFees {
+		code = strings.ToUpper(code)
+		fee, err := decimal.NewFromString(value)
+		if err != nil || fee.IsNegative() {
+			return nil, fmt.Errorf("invalid JURISDICTION_DORMANCY_FEES for %s: %q", code, value)
+		}
+		rule := ruleFor(code)
+		rule.DormancyFee = fee
+		jurisdictions[code] = rule
+	}
+
+	for _, code := range parseList(os.Getenv("JURISDICTION_DORMANCY_FREEZE")) {
+		code = strings.ToUpper(code)
+		rule := ruleFor(code)
+		rule.FreezeDormant = true
+		jurisdictions[code] = rule
+	}
+
+	return jurisdictions, nil
+}
+
+func loadQuotaConfig() (QuotaConfig, error) {
+	enabled, err := getBoolOrDefault("QUOTA_WARNINGS_ENABLED", false)
+	if err != nil {
+		return QuotaConfig{}, err
+	}
+	warnRatio, err := getFloatOrDefault("QUOTA_WARN_RATIO", 0.8)
+	if err != nil {
+		return QuotaConfig{}, err
+	}
+	if warnRatio <= 0 || warnRatio > 1 {
+		return QuotaConfig{}, fmt.Errorf("invalid QUOTA_WARN_RATIO: must be in (0, 1]")
+	}
+
+	return QuotaConfig{
+		Enabled:   enabled,
+		WarnRatio: warnRatio,
+	}, nil
+}
+
+// loadRateLimitConfig reads the rate limits. Source rates are given as
+// "game:500,payment:50" in transactions per second.
+func loadRateLimitConfig() (RateLimitConfig, error) {
+	enabled, err := getBoolOrDefault("RATE_LIMIT_ENABLED", false)
+	if err != nil {
+		return RateLimitConfig{}, err
+	}
+	userRate, err := getFloatOrDefault("RATE_LIMIT_USER_RATE", 10)
+	if err != nil {
+		return RateLimitConfig{}, err
+	}
+	userBurst, err := getUintOrDefault("RATE_LIMIT_USER_BURST", 20)
+	if err != nil {
+		return RateLimitConfig{}, err
+	}
+	if userRate < 0 {
+		return RateLimitConfig{}, fmt.Errorf("invalid RATE_LIMIT_USER_RATE: must not be negative")
+	}
+
+	entries, err := parseKeyValueList(os.Getenv("RATE_LIMIT_SOURCE_RATES"))
+	if err != nil {
+		return RateLimitConfig{}, fmt.Errorf("invalid RATE_LIMIT_SOURCE_RATES: %w", err)
+	}
+	sourceRates := make(map[string]float64, len(entries))
+	for source, value := range entries {
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || rate <= 0 {
+			return RateLimitConfig{}, fmt.Errorf("invalid RATE_LIMIT_SOURCE_RATES for %s: %q", source, value)
+		}
+		sourceRates[source] = rate
+	}
+
+	return RateLimitConfig{
+		Enabled:     enabled,
+		Backend:     getEnvOrDefault("RATE_LIMIT_BACKEND", "memory"),
+		UserRate:    userRate,
+		UserBurst:   int(userBurst),
+		SourceRates: sourceRates,
+	}, nil
+}
+
+// defaultRouteMiddleware authenticates every route and rate limits
+// transaction submissions
+const defaultRouteMiddleware = "/=auth,/user/:userId/transaction=auth|rate_limit"
+
+// loadRoutesConfig reads the route group middleware, given as
+// "/=auth,/user=auth|signing|rate_limit". Route patterns may contain colons,
+// so prefixes are separated from their middleware by an equals sign.
+func loadRoutesConfig() (RoutesConfig, error) {
+	groups := make(map[string][]string)
+	signing := false
+	for _, entry := range parseList(getEnvOrDefault("ROUTE_MIDDLEWARE", defaultRouteMiddleware)) {
+		prefix, names, ok := strings.Cut(entry, "=")
+		prefix = strings.TrimSpace(prefix)
+		if !ok || !strings.HasPrefix(prefix, "/") {
+			return RoutesConfig{}, fmt.Errorf("invalid ROUTE_MIDDLEWARE: malformed entry %q", entry)
+		}
+		if _, ok := groups[prefix]; ok {
+			return RoutesConfig{}, fmt.Errorf("invalid ROUTE_MIDDLEWARE: group %s given twice", prefix)
+		}
+
+		middleware := []string{}
+		for _, name := range strings.Split(names, "|") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			if !slices.Contains(RouteMiddleware, name) {
+				return RoutesConfig{}, fmt.Errorf("invalid ROUTE_MIDDLEWARE: unknown middleware %q for %s, must be one of %s",
+					name, prefix, strings.Join(RouteMiddleware, ", "))
+			}
+			signing = signing || name == "signing"
+			middleware = append(middleware, name)
+		}
+		groups[prefix] = middleware
+	}
+	// Routes outside every group would otherwise run no middleware at all
+	if _, ok := groups["/"]; !ok {
+		return RoutesConfig{}, fmt.Errorf("invalid ROUTE_MIDDLEWARE: the / group is required")
+	}
+
+	bodyLimit, err := getUintOrDefault("REQUEST_BODY_LIMIT", 1<<20)
+	if err != nil {
+		return RoutesConfig{}, err
+	}
+	if bodyLimit == 0 {
+		return RoutesConfig{}, fmt.Errorf("invalid REQUEST_BODY_LIMIT: must be positive")
+	}
+
+	secret := os.Getenv("REQUEST_SIGNING_SECRET")
+	if signing && secret == "" {
+		return RoutesConfig{}, fmt.Errorf("REQUEST_SIGNING_SECRET is required when a route group runs signing")
+	}
+	tolerance, err := getDurationOrDefault("REQUEST_SIGNING_TOLERANCE", 5*time.Minute)
+	if err != nil {
+		return RoutesConfig{}, err
+	}
+	if tolerance <= 0 {
+		return RoutesConfig{}, fmt.Errorf("invalid REQUEST_SIGNING_TOLERANCE: must be positive")
+	}
+
+	legacy, err := getBoolOrDefault("LEGACY_ROUTES_ENABLED", true)
+	if err != nil {
+		return RoutesConfig{}, err
+	}
+	var sunset time.Time
+	if value := os.Getenv("LEGACY_ROUTES_SUNSET"); value != "" {
+		if sunset, err = time.Parse(time.DateOnly, value); err != nil {
+			return RoutesConfig{}, fmt.Errorf("invalid LEGACY_ROUTES_SUNSET: %w", err)
+		}
+	}
+
+	return RoutesConfig{
+		Groups:           groups,
+		BodyLimit:        int64(bodyLimit),
+		SigningSecret:    secret,
+		SigningTolerance: tolerance,
+		Legacy:           legacy,
+		LegacySunset:     sunset,
+	}, nil
+}
+
+const defaultSLOObjectives = "POST /user/:userId/transaction=99.9|250ms|99,GET /user/:userId/balance=99.9|100ms|99"
+
+// loadSLOConfig reads the objectives, given as
+// "POST /user/:userId/transaction=99.9|250ms|99": the availability, the
+// latency threshold and the percentage of requests that must meet it
+func loadSLOConfig() (SLOConfig, error) {
+	window, err := getDurationOrDefault("SLO_WINDOW", 30*24*time.Hour)
+	if err != nil {
+		return SLOConfig{}, err
+	}
+	if window < time.Hour {
+		return SLOConfig{}, fmt.Errorf("invalid SLO_WINDOW: must be at least 1h")
+	}
+
+	objectives := []SLOObjective{}
+	for _, entry := range parseList(getEnvOrDefault("SLO_OBJECTIVES", defaultSLOObjectives)) {
+		route, targets, ok := strings.Cut(entry, "=")
+		method, path, routeOK := strings.Cut(strings.TrimSpace(route), " ")
+		parts := strings.Split(targets, "|")
+		if !ok || !routeOK || !strings.HasPrefix(path, "/") || len(parts) != 3 {
+			return SLOConfig{}, fmt.Errorf("invalid SLO_OBJECTIVES: malformed entry %q", entry)
+		}
+		objective := SLOObjective{Route: strings.ToUpper(method) + " " + strings.TrimSpace(path)}
+		if slices.ContainsFunc(objectives, func(o SLOObjective) bool { return o.Route == objective.Route }) {
+			return SLOConfig{}, fmt.Errorf("invalid SLO_OBJECTIVES: route %s given twice", objective.Route)
+		}
+
+		objective.Availability, err = strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+		if err != nil || objective.Availability <= 0 || objective.Availability >= 100 {
+			return SLOConfig{}, fmt.Errorf("invalid SLO_OBJECTIVES: availability of %s must be between 0 and 100", objective.Route)
+		}
+		objective.Latency, err = time.ParseDuration(strings.TrimSpace(parts[1]))
+		if err != nil || objective.Latency <= 0 {
+			return SLOConfig{}, fmt.Errorf("invalid SLO_OBJECTIVES: latency of %s must be a positive duration", objective.Route)
+		}
+		objective.LatencyTarget, err = strconv.ParseFloat(strings.TrimSpace(parts[2]), 64)
+		if err != nil || objective.LatencyTarget <= 0 || objective.LatencyTarget >= 100 {
+			return SLOConfig{}, fmt.Errorf("invalid SLO_OBJECTIVES: latency target of %s must be between 0 and 100", objective.Route)
+		}
+		objectives = append(objectives, objective)
+	}
+
+	return SLOConfig{Window: window, Objectives: objectives}, nil
+}
+
+func loadShadowConfig() (ShadowConfig, error) {
+	percent, err := getFloatOrDefault("SHADOW_PERCENT", 0)
+	if err != nil {
+		return ShadowConfig{}, err
+	}
+	if percent < 0 || percent > 100 {
+		return ShadowConfig{}, fmt.Errorf("invalid SHADOW_PERCENT: must be between 0 and 100")
+	}
+	timeout, err := getDurationOrDefault("SHADOW_TIMEOUT", 2*time.Second)
+	if err != nil {
+		return ShadowConfig{}, err
+	}
+	if timeout <= 0 {
+		return ShadowConfig{}, fmt.Errorf("invalid SHADOW_TIMEOUT: must be positive")
+	}
+	maxConcurrent, err := getUintOrDefault("SHADOW_MAX_CONCURRENT", 16)
+	if err != nil {
+		return ShadowConfig{}, err
+	}
+	if maxConcurrent == 0 {
+		return ShadowConfig{}, fmt.Errorf("invalid SHADOW_MAX_CONCURRENT: must be positive")
+	}
+
+	return ShadowConfig{Percent: percent, Timeout: timeout, MaxConcurrent: int(maxConcurrent)}, nil
+}
+
+// eventPublishers are the publishers of the outbox and change events. The
+// kafka publisher writes to KAFKA_BROKERS, the nats publisher to the streams
+// of NATS_URL.
+var eventPublishers = []string{"log", "redis", "kafka", "nats"}
+
+func loadOutboxConfig() (OutboxConfig, error) {
+	enabled, err := getBoolOrDefault("OUTBOX_ENABLED", false)
+	if err != nil {
+		return OutboxConfig{}, err
+	}
+	streamMaxLen, err := getUintOrDefault("OUTBOX_STREAM_MAX_LEN", 0)
+	if err != nil {
+		return OutboxConfig{}, err
+	}
+	interval, err := getDurationOrDefault("OUTBOX_RELAY_INTERVAL", time.Second)
+	if err != nil {
+		return OutboxConfig{}, err
+	}
+	if interval <= 0 {
+		return OutboxConfig{}, fmt.Errorf("invalid OUTBOX_RELAY_INTERVAL: must be positive")
+	}
+	batchSize, err := getUintOrDefault("OUTBOX_RELAY_BATCH_SIZE", 100)
+	if err != nil {
+		return OutboxConfig{}, err
+	}
+	if batchSize == 0 {
+		return OutboxConfig{}, fmt.Errorf("invalid OUTBOX_RELAY_BATCH_SIZE: must be positive")
+	}
+
+	publisher := getEnvOrDefault("OUTBOX_PUBLISHER", "log")
+	if !slices.Contains(eventPublishers, publisher) {
+		return OutboxConfig{}, fmt.Errorf("invalid OUTBOX_PUBLISHER: must be one of %s", strings.Join(eventPublishers, ", "))
+	}
+
+	return OutboxConfig{
+		Enabled:        enabled,
+		Publisher:      publisher,
+		Topic:          getEnvOrDefault("OUTBOX_TOPIC", "balance-events"),
+		StreamMaxLen:   int64(streamMaxLen),
+		RelayInterval:  interval,
+		RelayBatchSize: int(batchSize),
+	}, nil
+}
+
+// replicationNamePattern keeps replication slot and publication names valid
+// slot names, which are plain identifiers as well
+var replicationNamePattern = regexp.MustCompile(`^[a-z0-9_]{1,63}$`)
+
+func loadCDCConfig() (CDCConfig, error) {
+	enabled, err := getBoolOrDefault("CDC_ENABLED", false)
+	if err != nil {
+		return CDCConfig{}, err
+	}
+	slot := getEnvOrDefault("CDC_SLOT", "transaction_service_cdc")
+	if !replicationNamePattern.MatchString(slot) {
+		return CDCConfig{}, fmt.Errorf("invalid CDC_SLOT: must be 1 to 63 lowercase letters, digits or underscores")
+	}
+	publication := getEnvOrDefault("CDC_PUBLICATION", "transaction_service_cdc")
+	if !replicationNamePattern.MatchString(publication) {
+		return CDCConfig{}, fmt.Errorf("invalid CDC_PUBLICATION: must be 1 to 63 lowercase letters, digits or underscores")
+	}
+	publisher := getEnvOrDefault("CDC_PUBLISHER", "log")
+	if !slices.Contains(eventPublishers, publisher) {
+		return CDCConfig{}, fmt.Errorf("invalid CDC_PUBLISHER: must be one of %s", strings.Join(eventPublishers, ", "))
+	}
+	streamMaxLen, err := getUintOrDefault("CDC_STREAM_MAX_LEN", 0)
+	if err != nil {
+		return CDCConfig{}, err
+	}
+	statusInterval, err := getDurationOrDefault("CDC_STATUS_INTERVAL", 10*time.Second)
+	if err != nil {
+		return CDCConfig{}, err
+	}
+	if statusInterval <= 0 {
+		return CDCConfig{}, fmt.Errorf("invalid CDC_STATUS_INTERVAL: must be positive")
+	}
+
+	return CDCConfig{
+		Enabled:        enabled,
+		Slot:           slot,
+		Publication:    publication,
+		Publisher:      publisher,
+		Topic:          getEnvOrDefault("CDC_TOPIC", "change-events"),
+		StreamMaxLen:   int64(streamMaxLen),
+		StatusInterval: statusInterval,
+	}, nil
+}
+
+func loadWebhookConfig() (WebhookConfig, error) {
+	enabled, err := getBoolOrDefault("WEBHOOKS_ENABLED", false)
+	if err != nil {
+		return WebhookConfig{}, err
+	}
+	interval, err := getDurationOrDefault("WEBHOOK_DELIVERY_INTERVAL", time.Second)
+	if err != nil {
+		return WebhookConfig{}, err
+	}
+	if interval <= 0 {
+		return WebhookConfig{}, fmt.Errorf("invalid WEBHOOK_DELIVERY_INTERVAL: must be positive")
+	}
+	batchSize, err := getUintOrDefault("WEBHOOK_DELIVERY_BATCH_SIZE", 50)
+	if err != nil {
+		return WebhookConfig{}, err
+	}
+	if batchSize == 0 {
+		return WebhookConfig{}, fmt.Errorf("invalid WEBHOOK_DELIVERY_BATCH_SIZE: must be positive")
+	}
+	timeout, err := getDurationOrDefault("WEBHOOK_TIMEOUT", 5*time.Second)
+	if err != nil {
+		return WebhookConfig{}, err
+	}
+	if timeout <= 0 {
+		return WebhookConfig{}, fmt.Errorf("invalid WEBHOOK_TIMEOUT: must be positive")
+	}
+	maxAttempts, err := getUintOrDefault("WEBHOOK_MAX_ATTEMPTS", 8)
+	if err != nil {
+		return WebhookConfig{}, err
+	}
+	if maxAttempts == 0 {
+		return WebhookConfig{}, fmt.Errorf("invalid WEBHOOK_MAX_ATTEMPTS: must be positive")
+	}
+	baseDelay, err := getDurationOrDefault("WEBHOOK_RETRY_BASE_DELAY", 10*time.Second)
+	if err != nil {
+		return WebhookConfig{}, err
+	}
+	maxDelay, err := getDurationOrDefault("WEBHOOK_RETRY_MAX_DELAY", time.Hour)
+	if err != nil {
+		return WebhookConfig{}, err
+	}
+	if baseDelay <= 0 || maxDelay < baseDelay {
+		return WebhookConfig{}, fmt.Errorf("invalid WEBHOOK_RETRY_MAX_DELAY: must be at least a positive WEBHOOK_RETRY_BASE_DELAY")
+	}
+
+	return WebhookConfig{
+		Enabled:           enabled,
+		DeliveryInterval:  interval,
+		DeliveryBatchSize: int(batchSize),
+		Timeout:           timeout,
+		MaxAttempts:       int(maxAttempts),
+		RetryBaseDelay:    baseDelay,
+		RetryMaxDelay:     maxDelay,
+	}, nil
+}
+
+func loadIngestConfig() (IngestConfig, error) {
+	maxAttempts, err := getUintOrDefault("INGEST_MAX_ATTEMPTS", 5)
+	if err != nil {
+		return IngestConfig{}, err
+	}
+	if maxAttempts == 0 {
+		return IngestConfig{}, fmt.Errorf("invalid INGEST_MAX_ATTEMPTS: must be positive")
+	}
+	baseDelay, err := getDurationOrDefault("INGEST_RETRY_BASE_DELAY", 100*time.Millisecond)
+	if err != nil {
+		return IngestConfig{}, err
+	}
+	maxDelay, err := getDurationOrDefault("INGEST_RETRY_MAX_DELAY", 30*time.Second)
+	if err != nil {
+		return IngestConfig{}, err
+	}
+	if baseDelay <= 0 || maxDelay < baseDelay {
+		return IngestConfig{}, fmt.Errorf("invalid INGEST_RETRY_MAX_DELAY: must be at least a positive INGEST_RETRY_BASE_DELAY")
+	}
+
+	return IngestConfig{
+		MaxAttempts:    int(maxAttempts),
+		RetryBaseDelay: baseDelay,
+		RetryMaxDelay:  maxDelay,
+	}, nil
+}
+
+func loadKafkaConfig() (KafkaConfig, error) {
+	enabled, err := getBoolOrDefault("KAFKA_ENABLED", false)
+	if err != nil {
+		return KafkaConfig{}, err
+	}
+	brokers := parseList(os.Getenv("KAFKA_BROKERS"))
+	if enabled && len(brokers) == 0 {
+		return KafkaConfig{}, fmt.Errorf("invalid KAFKA_BROKERS: required when KAFKA_ENABLED is set")
+	}
+	topic := getEnvOrDefault("KAFKA_TOPIC", "transactions")
+	dlqTopic := getEnvOrDefault("KAFKA_DLQ_TOPIC", topic+"-dlq")
+	if dlqTopic == topic {
+		return KafkaConfig{}, fmt.Errorf("invalid KAFKA_DLQ_TOPIC: must differ from KAFKA_TOPIC")
+	}
+
+	return KafkaConfig{
+		Enabled:    enabled,
+		Brokers:    brokers,
+		Topic:      topic,
+		GroupID:    getEnvOrDefault("KAFKA_GROUP_ID", "transaction-service"),
+		DLQTopic:   dlqTopic,
+		SourceType: getEnvOrDefault("KAFKA_SOURCE_TYPE", "game"),
+	}, nil
+}
+
+func loadNATSConfig(ingest IngestConfig) (NATSConfig, error) {
+	enabled, err := getBoolOrDefault("NATS_ENABLED", false)
+	if err != nil {
+		return NATSConfig{}, err
+	}
+	serverURL := os.Getenv("NATS_URL")
+	if enabled && serverURL == "" {
+		return NATSConfig{}, fmt.Errorf("invalid NATS_URL: required when NATS_ENABLED is set")
+	}
+
+	var subjects []NATSSubject
+	for _, entry := range parseList(getEnvOrDefault("NATS_SUBJECTS", "transactions.game.>:game,transactions.payment.>:payment")) {
+		subject, sourceType, ok := strings.Cut(entry, ":")
+		if !ok || subject == "" || sourceType == "" {
+			return NATSConfig{}, fmt.Errorf("invalid NATS_SUBJECTS: malformed entry %q, want subject:sourceType", entry)
+		}
+		subjects = append(subjects, NATSSubject{Subject: subject, SourceType: sourceType})
+	}
+	if len(subjects) == 0 {
+		return NATSConfig{}, fmt.Errorf("invalid NATS_SUBJECTS: at least one subject is required")
+	}
+
+	// A message is retried in place while its ack wait runs, which is reset
+	// after each backoff
+	ackWait, err := getDurationOrDefault("NATS_ACK_WAIT", time.Minute)
+	if err != nil {
+		return NATSConfig{}, err
+	}
+	if ackWait <= ingest.RetryMaxDelay {
+		return NATSConfig{}, fmt.Errorf("invalid NATS_ACK_WAIT: must exceed INGEST_RETRY_MAX_DELAY")
+	}
+	maxAckPending, err := getUintOrDefault("NATS_MAX_ACK_PENDING", 100)
+	if err != nil {
+		return NATSConfig{}, err
+	}
+	if maxAckPending == 0 {
+		return NATSConfig{}, fmt.Errorf("invalid NATS_MAX_ACK_PENDING: must be positive")
+	}
+
+	return NATSConfig{
+		Enabled:       enabled,
+		URL:           serverURL,
+		Stream:        getEnvOrDefault("NATS_STREAM", "TRANSACTIONS"),
+		Durable:       getEnvOrDefault("NATS_DURABLE", "transaction-service"),
+		Subjects:      subjects,
+		DLQSubject:    getEnvOrDefault("NATS_DLQ_SUBJECT", "transactions.dlq"),
+		AckWait:       ackWait,
+		MaxAckPending: int(maxAckPending),
+	}, nil
+}
+
+// exchangeKinds are the kinds of exchanges the RabbitMQ queue can be bound to
+var exchangeKinds = []string{"direct", "fanout", "topic", "headers"}
+
+func loadRabbitMQConfig() (RabbitMQConfig, error) {
+	enabled, err := getBoolOrDefault("RABBITMQ_ENABLED", false)
+	if err != nil {
+		return RabbitMQConfig{}, err
+	}
+	serverURL := os.Getenv("RABBITMQ_URL")
+	if enabled && serverURL == "" {
+		return RabbitMQConfig{}, fmt.Errorf("invalid RABBITMQ_URL: required when RABBITMQ_ENABLED is set")
+	}
+	queue := getEnvOrDefault("RABBITMQ_QUEUE", "wallet-transactions")
+	exchangeKind := getEnvOrDefault("RABBITMQ_EXCHANGE_KIND", "topic")
+	if !slices.Contains(exchangeKinds, exchangeKind) {
+		return RabbitMQConfig{}, fmt.Errorf("invalid RABBITMQ_EXCHANGE_KIND: must be one of %s", strings.Join(exchangeKinds, ", "))
+	}
+	deadLetterQueue := getEnvOrDefault("RABBITMQ_DEAD_LETTER_QUEUE", queue+"-dlq")
+	if deadLetterQueue == queue {
+		return RabbitMQConfig{}, fmt.Errorf("invalid RABBITMQ_DEAD_LETTER_QUEUE: must differ from RABBITMQ_QUEUE")
+	}
+	exchange := os.Getenv("RABBITMQ_EXCHANGE")
+	deadLetterExchange := getEnvOrDefault("RABBITMQ_DEAD_LETTER_EXCHANGE", queue+"-dlx")
+	if deadLetterExchange == exchange {
+		return RabbitMQConfig{}, fmt.Errorf("invalid RABBITMQ_DEAD_LETTER_EXCHANGE: must differ from RABBITMQ_EXCHANGE")
+	}
+	prefetch, err := getUintOrDefault("RABBITMQ_PREFETCH", 100)
+	if err != nil {
+		return RabbitMQConfig{}, err
+	}
+	if prefetch == 0 {
+		return RabbitMQConfig{}, fmt.Errorf("invalid RABBITMQ_PREFETCH: must be positive")
+	}
+
+	return RabbitMQConfig{
+		Enabled:            enabled,
+		URL:                serverURL,
+		Queue:              queue,
+		Exchange:           exchange,
+		ExchangeKind:       exchangeKind,
+		BindingKeys:        parseList(getEnvOrDefault("RABBITMQ_BINDING_KEYS", "#")),
+		DeadLetterExchange: deadLetterExchange,
+		DeadLetterQueue:    deadLetterQueue,
+		Prefetch:           int(prefetch),
+		SourceType:         getEnvOrDefault("RABBITMQ_SOURCE_TYPE", "payment"),
+	}, nil
+}
+
+// httpClientDestinations are the destinations of outbound HTTP requests. The
+// HTTP_CLIENT_<DESTINATION>_* variables override the HTTP_CLIENT_* defaults
+// for a destination.
+var httpClientDestinations = []string{"webhooks"}
+
+func loadHTTPClientConfig() (HTTPClientConfig, error) {
+	defaultPolicy, err := loadHTTPClientPolicy("HTTP_CLIENT_", HTTPClientPolicy{
+		Timeout:             10 * time.Second,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     90 * time.Second,
+		Retries:             2,
+		RetryBudget:         0.1,
+	})
+	if err != nil {
+		return HTTPClientConfig{}, err
+	}
+
+	destinations := make(map[string]HTTPClientPolicy, len(httpClientDestinations))
+	for _, destination := range httpClientDestinations {
+		prefix := "HTTP_CLIENT_" + strings.ToUpper(destination) + "_"
+		if destinations[destination], err = loadHTTPClientPolicy(prefix, defaultPolicy); err != nil {
+			return HTTPClientConfig{}, err
+		}
+	}
+
+	return HTTPClientConfig{Default: defaultPolicy, Destinations: destinations}, nil
+}
+
+// loadHTTPClientPolicy reads the policy variables starting with prefix,
+// falling back to defaults
+func loadHTTPClientPolicy(prefix string, defaults HTTPClientPolicy) (HTTPClientPolicy, error) {
+	timeout, err := getDurationOrDefault(prefix+"TIMEOUT", defaults.Timeout)
+	if err != nil {
+		return HTTPClientPolicy{}, err
+	}
+	if timeout <= 0 {
+		return HTTPClientPolicy{}, fmt.Errorf("invalid %sTIMEOUT: must be positive", prefix)
+	}
+	maxIdleConns, err := getUintOrDefault(prefix+"MAX_IDLE_CONNS_PER_HOST", uint64(defaults.MaxIdleConnsPerHost))
+	if err != nil {
+		return HTTPClientPolicy{}, err
+	}
+	maxConns, err := getUintOrDefault(prefix+"MAX_CONNS_PER_HOST", uint64(defaults.MaxConnsPerHost))
+	if err != nil {
+		return HTTPClientPolicy{}, err
+	}
+	idleTimeout, err := getDurationOrDefault(prefix+"IDLE_CONN_TIMEOUT", defaults.IdleConnTimeout)
+	if err != nil {
+		return HTTPClientPolicy{}, err
+	}
+	if idleTimeout <= 0 {
+		return HTTPClientPolicy{}, fmt.Errorf("invalid %sIDLE_CONN_TIMEOUT: must be positive", prefix)
+	}
+	retries, err := getUintOrDefault(prefix+"RETRIES", uint64(defaults.Retries))
+	if err != nil {
+		return HTTPClientPolicy{}, err
+	}
+	budget, err := getFloatOrDefault(prefix+"RETRY_BUDGET", defaults.RetryBudget)
+	if err != nil {
+		return HTTPClientPolicy{}, err
+	}
+	if budget < 0 {
+		return HTTPClientPolicy{}, fmt.Errorf("invalid %sRETRY_BUDGET: must not be negative", prefix)
+	}
+	proxy := getEnvOrDefault(prefix+"PROXY", defaults.Proxy)
+	if proxy != "" {
+		// The URL may hold credentials, so it is left out of the error
+		if u, err := url.Parse(proxy); err != nil || u.Scheme == "" || u.Host == "" {
+			return HTTPClientPolicy{}, fmt.Errorf("invalid %sPROXY: must be an absolute URL", prefix)
+		}
+	}
+
+	return HTTPClientPolicy{
+		Timeout:             timeout,
+		MaxIdleConnsPerHost: int(maxIdleConns),
+		MaxConnsPerHost:     int(maxConns),
+		IdleConnTimeout:     idleTimeout,
+		Retries:             int(retries),
+		RetryBudget:         budget,
+		Proxy:               proxy,
+	}, nil
+}
+
+func loadCancellationConfig() (CancellationConfig, error) {
+	enabled, err := getBoolOrDefault("CANCELLATION_WORKER_ENABLED", false)
+	if err != nil {
+		return CancellationConfig{}, err
+	}
+	interval, err := getDurationOrDefault("CANCELLATION_WORKER_INTERVAL", 10*time.Minute)
+	if err != nil {
+		return CancellationConfig{}, err
+	}
+	if interval <= 0 {
+		return CancellationConfig{}, fmt.Errorf("invalid CANCELLATION_WORKER_INTERVAL: must be positive")
+	}
+	batchSize, err := getUintOrDefault("CANCELLATION_WORKER_BATCH_SIZE", 10)
+	if err != nil {
+		return CancellationConfig{}, err
+	}
+	if batchSize == 0 {
+		return CancellationConfig{}, fmt.Errorf("invalid CANCELLATION_WORKER_BATCH_SIZE: must be positive")
+	}
+
+	return CancellationConfig{
+		Enabled:   enabled,
+		Interval:  interval,
+		BatchSize: int(batchSize),
+	}, nil
+}
+
+func loadWarmupConfig() (WarmupConfig, error) {
+	enabled, err := getBoolOrDefault("WARMUP_ENABLED", false)
+	if err != nil {
+		return WarmupConfig{}, err
+	}
+	connections, err := getUintOrDefault("WARMUP_CONNECTIONS", 5)
+	if err != nil {
+		return WarmupConfig{}, err
+	}
+	users, err := getUintOrDefault("WARMUP_USERS", 100)
+	if err != nil {
+		return WarmupConfig{}, err
+	}
+	window, err := getDurationOrDefault("WARMUP_WINDOW", 24*time.Hour)
+	if err != nil {
+		return WarmupConfig{}, err
+	}
+	if window <= 0 {
+		return WarmupConfig{}, fmt.Errorf("invalid WARMUP_WINDOW: must be positive")
+	}
+	timeout, err := getDurationOrDefault("WARMUP_TIMEOUT", 30*time.Second)
+	if err != nil {
+		return WarmupConfig{}, err
+	}
+	if timeout <= 0 {
+		return WarmupConfig{}, fmt.Errorf("invalid WARMUP_TIMEOUT: must be positive")
+	}
+
+	return WarmupConfig{
+		Enabled:     enabled,
+		Connections: int(connections),
+		Users:       int(users),
+		Window:      window,
+		Timeout:     timeout,
+	}, nil
+}
+
+func loadDormancyConfig() (DormancyConfig, error) {
+	enabled, err := getBoolOrDefault("DORMANCY_ENABLED", false)
+	if err != nil {
+		return DormancyConfig{}, err
+	}
+	period, err := getDurationOrDefault("DORMANCY_PERIOD", 365*24*time.Hour)
+	if err != nil {
+		return DormancyConfig{}, err
+	}
+	if period <= 0 {
+		return DormancyConfig{}, fmt.Errorf("invalid DORMANCY_PERIOD: must be positive")
+	}
+	interval, err := getDurationOrDefault("DORMANCY_INTERVAL", time.Hour)
+	if err != nil {
+		return DormancyConfig{}, err
+	}
+	if interval <= 0 {
+		return DormancyConfig{}, fmt.Errorf("invalid DORMANCY_INTERVAL: must be positive")
+	}
+	batchSize, err := getUintOrDefault("DORMANCY_BATCH_SIZE", 100)
+	if err != nil {
+		return DormancyConfig{}, err
+	}
+	if batchSize == 0 {
+		return DormancyConfig{}, fmt.Errorf("invalid DORMANCY_BATCH_SIZE: must be positive")
+	}
+
+	return DormancyConfig{
+		Enabled:   enabled,
+		Period:    period,
+		Interval:  interval,
+		BatchSize: int(batchSize),
+	}, nil
+}
+
+func loadProcessorConfig() (ProcessorConfig, error) {
+	workers, err := getUintOrDefault("PROCESSOR_WORKERS", 4)
+	if err != nil {
+		return ProcessorConfig{}, err
+	}
+	if workers == 0 {
+		return ProcessorConfig{}, fmt.Errorf("invalid PROCESSOR_WORKERS: must be positive")
+	}
+	queueSize, err := getUintOrDefault("PROCESSOR_QUEUE_SIZE", 1000)
+	if err != nil {
+		return ProcessorConfig{}, err
+	}
+	if queueSize < workers {
+		return ProcessorConfig{}, fmt.Errorf("invalid PROCESSOR_QUEUE_SIZE: must be at least PROCESSOR_WORKERS")
+	}
+
+	return ProcessorConfig{
+		Workers:   int(workers),
+		QueueSize: int(queueSize),
+	}, nil
+}
+
+func loadSettlementConfig() (SettlementConfig, error) {
+	enabled, err := getBoolOrDefault("SETTLEMENT_ENABLED", false)
+	if err != nil {
+		return SettlementConfig{}, err
+	}
+	delay, err := getDurationOrDefault("SETTLEMENT_DELAY", 24*time.Hour)
+	if err != nil {
+		return SettlementConfig{}, err
+	}
+	if delay < 0 {
+		return SettlementConfig{}, fmt.Errorf("invalid SETTLEMENT_DELAY: must not be negative")
+	}
+	interval, err := getDurationOrDefault("SETTLEMENT_INTERVAL", time.Minute)
+	if err != nil {
+		return SettlementConfig{}, err
+	}
+	if interval <= 0 {
+		return SettlementConfig{}, fmt.Errorf("invalid SETTLEMENT_INTERVAL: must be positive")
+	}
+	batchSize, err := getUintOrDefault("SETTLEMENT_BATCH_SIZE", 100)
+	if err != nil {
+		return SettlementConfig{}, err
+	}
+	if batchSize == 0 {
+		return SettlementConfig{}, fmt.Errorf("invalid SETTLEMENT_BATCH_SIZE: must be positive")
+	}
+
+	return SettlementConfig{
+		Enabled:   enabled,
+		Delay:     delay,
+		Interval:  interval,
+		BatchSize: int(batchSize),
+	}, nil
+}
+
+func loadScheduledTransactionConfig() (ScheduledTransactionConfig, error) {
+	enabled, err := getBoolOrDefault("SCHEDULED_TRANSACTIONS_ENABLED", false)
+	if err != nil {
+		return ScheduledTransactionConfig{}, err
+	}
+	interval, err := getDurationOrDefault("SCHEDULED_TRANSACTIONS_INTERVAL", 30*time.Second)
+	if err != nil {
+		return ScheduledTransactionConfig{}, err
+	}
+	if interval <= 0 {
+		return ScheduledTransactionConfig{}, fmt.Errorf("invalid SCHEDULED_TRANSACTIONS_INTERVAL: must be positive")
+	}
+	batchSize, err := getUintOrDefault("SCHEDULED_TRANSACTIONS_BATCH_SIZE", 100)
+	if err != nil {
+		return ScheduledTransactionConfig{}, err
+	}
+	if batchSize == 0 {
+		return ScheduledTransactionConfig{}, fmt.Errorf("invalid SCHEDULED_TRANSACTIONS_BATCH_SIZE: must be positive")
+	}
+
+	return ScheduledTransactionConfig{
+		Enabled:   enabled,
+		Interval:  interval,
+		BatchSize: int(batchSize),
+	}, nil
+}
+
+func loadBalanceSnapshotConfig() (BalanceSnapshotConfig, error) {
+	enabled, err := getBoolOrDefault("BALANCE_SNAPSHOTS_ENABLED", false)
+	if err != nil {
+		return BalanceSnapshotConfig{}, err
+	}
+	interval, err := getDurationOrDefault("BALANCE_SNAPSHOTS_INTERVAL", 5*time.Minute)
+	if err != nil {
+		return BalanceSnapshotConfig{}, err
+	}
+	if interval <= 0 {
+		return BalanceSnapshotConfig{}, fmt.Errorf("invalid BALANCE_SNAPSHOTS_INTERVAL: must be positive")
+	}
+	batchSize, err := getUintOrDefault("BALANCE_SNAPSHOTS_BATCH_SIZE", 1000)
+	if err != nil {
+		return BalanceSnapshotConfig{}, err
+	}
+	if batchSize == 0 {
+		return BalanceSnapshotConfig{}, fmt.Errorf("invalid BALANCE_SNAPSHOTS_BATCH_SIZE: must be positive")
+	}
+
+	return BalanceSnapshotConfig{
+		Enabled:   enabled,
+		Interval:  interval,
+		BatchSize: int(batchSize),
+	}, nil
+}
+
+func loadHoldConfig() (HoldConfig, error) {
+	enabled, err := getBoolOrDefault("HOLDS_ENABLED", false)
+	if err != nil {
+		return HoldConfig{}, err
+	}
+	defaultTTL, err := getDurationOrDefault("HOLD_DEFAULT_TTL", 15*time.Minute)
+	if err != nil {
+		return HoldConfig{}, err
+	}
+	maxTTL, err := getDurationOrDefault("HOLD_MAX_TTL", 7*24*time.Hour)
+	if err != nil {
+		return HoldConfig{}, err
+	}
+	if defaultTTL <= 0 || maxTTL < defaultTTL {
+		return HoldConfig{}, fmt.Errorf("invalid HOLD_DEFAULT_TTL: must be positive and at most HOLD_MAX_TTL")
+	}
+	interval, err := getDurationOrDefault("HOLD_EXPIRY_INTERVAL", time.Minute)
+	if err != nil {
+		return HoldConfig{}, err
+	}
+	if interval <= 0 {
+		return HoldConfig{}, fmt.Errorf("invalid HOLD_EXPIRY_INTERVAL: must be positive")
+	}
+	batchSize, err := getUintOrDefault("HOLD_EXPIRY_BATCH_SIZE", 100)
+	if err != nil {
+		return HoldConfig{}, err
+	}
+	if batchSize == 0 {
+		return HoldConfig{}, fmt.Errorf("invalid HOLD_EXPIRY_BATCH_SIZE: must be positive")
+	}
+
+	return HoldConfig{
+		Enabled:         enabled,
+		DefaultTTL:      defaultTTL,
+		MaxTTL:          maxTTL,
+		ExpiryInterval:  interval,
+		ExpiryBatchSize: int(batchSize),
+	}, nil
+}
+
+// loadStorageMigrationConfig reads the storage migration settings. The target
+// database defaults to the settings of source.
+func loadStorageMigrationConfig(source DatabaseConfig) (StorageMigrationConfig, error) {
+	phase := getEnvOrDefault("STORAGE_MIGRATION_PHASE", "off")
+	switch phase {
+	case "off", "dual_write", "cutover":
+	default:
+		return StorageMigrationConfig{}, fmt.Errorf("invalid STORAGE_MIGRATION_PHASE %q: must be off, dual_write or cutover", phase)
+	}
+
+	queueSize, err := getUintOrDefault("STORAGE_MIGRATION_QUEUE_SIZE", 10000)
+	if err != nil {
+		return StorageMigrationConfig{}, err
+	}
+	if queueSize == 0 {
+		return StorageMigrationConfig{}, fmt.Errorf("invalid STORAGE_MIGRATION_QUEUE_SIZE: must be positive")
+	}
+
+	target := DatabaseConfig{
+		Driver:           source.Driver,
+		Host:             getEnvOrDefault("STORAGE_MIGRATION_DB_HOST", source.Host),
+		Port:             getEnvOrDefault("STORAGE_MIGRATION_DB_PORT", source.Port),
+		User:             getEnvOrDefault("STORAGE_MIGRATION_DB_USER", source.User),
+		Password:         getEnvOrDefault("STORAGE_MIGRATION_DB_PASSWORD", source.Password),
+		Name:             getEnvOrDefault("STORAGE_MIGRATION_DB_NAME", source.Name),
+		SSLMode:          getEnvOrDefault("STORAGE_MIGRATION_DB_SSLMODE", source.SSLMode),
+		Schema:           os.Getenv("STORAGE_MIGRATION_DB_SCHEMA"),
+		Pool:             source.Pool,
+		StatementTimeout: source.StatementTimeout,
+		QueryTimeout:     source.QueryTimeout,
+	}
+	if phase != "off" && target.Host == source.Host && target.Port == source.Port &&
+		target.Name == source.Name && target.Schema == source.Schema {
+		return StorageMigrationConfig{}, fmt.Errorf("invalid STORAGE_MIGRATION_DB_NAME: the target database must differ from the source")
+	}
+
+	return StorageMigrationConfig{
+		Phase:     phase,
+		Target:    target,
+		QueueSize: int(queueSize),
+	}, nil
+}
+
+// loadReplicaReadsConfig reads the replica read settings. The replica
+// defaults to the settings of primary.
+func loadReplicaReadsConfig(primary DatabaseConfig, storageMigration StorageMigrationConfig) (ReplicaReadsConfig, error) {
+	enabled, err := getBoolOrDefault("REPLICA_READS_ENABLED", false)
+	if err != nil {
+		return ReplicaReadsConfig{}, err
+	}
+
+	replica := DatabaseConfig{
+		Driver:           primary.Driver,
+		Host:             getEnvOrDefault("REPLICA_DB_HOST", primary.Host),
+		Port:             getEnvOrDefault("REPLICA_DB_PORT", primary.Port),
+		User:             getEnvOrDefault("REPLICA_DB_USER", primary.User),
+		Password:         getEnvOrDefault("REPLICA_DB_PASSWORD", primary.Password),
+		Name:             getEnvOrDefault("REPLICA_DB_NAME", primary.Name),
+		SSLMode:          getEnvOrDefault("REPLICA_DB_SSLMODE", primary.SSLMode),
+		Pool:             primary.Pool,
+		StatementTimeout: primary.StatementTimeout,
+		QueryTimeout:     primary.QueryTimeout,
+	}
+	if enabled && replica.Host == primary.Host && replica.Port == primary.Port {
+		return ReplicaReadsConfig{}, fmt.Errorf("invalid REPLICA_DB_HOST: the replica must differ from the primary")
+	}
+	// The replica follows the source database, which stops receiving writes
+	// at the cutover
+	if enabled && storageMigration.Phase != "off" {
+		return ReplicaReadsConfig{}, fmt.Errorf("invalid REPLICA_READS_ENABLED: replica reads cannot be combined with a storage migration")
+	}
+
+	return ReplicaReadsConfig{
+		Enabled: enabled,
+		Replica: replica,
+	}, nil
+}
+
+func loadDatabaseRetryConfig() (DatabaseRetryConfig, error) {
+	maxAttempts, err := getUintOrDefault("DB_RETRY_MAX_ATTEMPTS", 3)
+	if err != nil {
+		return DatabaseRetryConfig{}, err
+	}
+	if maxAttempts == 0 {
+		return DatabaseRetryConfig{}, fmt.Errorf("invalid DB_RETRY_MAX_ATTEMPTS: must be positive")
+	}
+	serializationMaxAttempts, err := getUintOrDefault("DB_RETRY_SERIALIZATION_MAX_ATTEMPTS", 10)
+	if err != nil {
+		return DatabaseRetryConfig{}, err
+	}
+	if serializationMaxAttempts == 0 {
+		return DatabaseRetryConfig{}, fmt.Errorf("invalid DB_RETRY_SERIALIZATION_MAX_ATTEMPTS: must be positive")
+	}
+	baseDelay, err := getDurationOrDefault("DB_RETRY_BASE_DELAY", 50*time.Millisecond)
+	if err != nil {
+		return DatabaseRetryConfig{}, err
+	}
+	maxDelay, err := getDurationOrDefault("DB_RETRY_MAX_DELAY", time.Second)
+	if err != nil {
+		return DatabaseRetryConfig{}, err
+	}
+	if baseDelay < 0 || maxDelay < baseDelay {
+		return DatabaseRetryConfig{}, fmt.Errorf("invalid DB_RETRY_MAX_DELAY: must be at least DB_RETRY_BASE_DELAY")
+	}
+
+	return DatabaseRetryConfig{
+		MaxAttempts:              int(maxAttempts),
+		SerializationMaxAttempts: int(serializationMaxAttempts),
+		BaseDelay:                baseDelay,
+		MaxDelay:                 maxDelay,
+	}, nil
+}
+
+// loadSeedConfig reads either an explicit SEED_USERS list ("id:balance,...")
+// or generates SEED_USER_COUNT users with IDs 1..N and SEED_USER_BALANCE each
+func loadSeedConfig() (SeedConfig, error) {
+	if explicit := os.Getenv("SEED_USERS"); explicit != "" {
+		users, err := parseSeedUsers("SEED_USERS", explicit)
+		if err != nil {
+			return SeedConfig{}, err
+		}
+		return SeedConfig{Users: users}, nil
+	}
+
+	count, err := getUintOrDefault("SEED_USER_COUNT", 3)
+	if err != nil {
+		return SeedConfig{}, err
+	}
+	balance, err := getDecimalOrDefault("SEED_USER_BALANCE", decimal.NewFromInt(100))
+	if err != nil {
+		return SeedConfig{}, err
+	}
+	if balance.IsNegative() {
+		return SeedConfig{}, fmt.Errorf("invalid SEED_USER_BALANCE: must not be negative")
+	}
+
+	users := make([]SeedUserConfig, 0, count)
+	for id := uint64(1); id <= count; id++ {
+		users = append(users, SeedUserConfig{ID: id, Balance: balance})
+	}
+
+	return SeedConfig{Users: users}, nil
+}
+
+// parseSeedUsers reads users given as "id:balance,id2:balance2" from the
+// environment variable name, sorted by ID
+func parseSeedUsers(name, value string) ([]SeedUserConfig, error) {
+	entries, err := parseKeyValueList(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", name, err)
+	}
+
+	users := make([]SeedUserConfig, 0, len(entries))
+	for idStr, balanceStr := range entries {
+		id, err := strconv.ParseUint(idStr, 10, 64)
+		if err != nil || id == 0 {
+			return nil, fmt.Errorf("invalid %s user ID %q", name, idStr)
+		}
+		balance, err := decimal.NewFromString(balanceStr)
+		if err != nil || balance.IsNegative() {
+			return nil, fmt.Errorf("invalid %s balance %q for user %d", name, balanceStr, id)
+		}
+		users = append(users, SeedUserConfig{ID: id, Balance: balance})
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+
+	return users, nil
+}
+
+// sandboxSchemaPattern keeps the sandbox schema a plain, unquoted identifier
+var sandboxSchemaPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+func loadSandboxConfig() (SandboxConfig, error) {
+	schema := getEnvOrDefault("SANDBOX_SCHEMA", "sandbox")
+	if !sandboxSchemaPattern.MatchString(schema) || schema == "public" {
+		return SandboxConfig{}, fmt.Errorf("invalid SANDBOX_SCHEMA %q: must be a lowercase identifier other than public", schema)
+	}
+
+	users, err := parseSeedUsers("SANDBOX_USERS", getEnvOrDefault("SANDBOX_USERS", "1:100.00,2:100.00,3:0.00"))
+	if err != nil {
+		return SandboxConfig{}, err
+	}
+
+	return SandboxConfig{
+		APIKeys: parseList(os.Getenv("SANDBOX_API_KEYS")),
+		Schema:  schema,
+		Users:   users,
+	}, nil
+}
+
+func loadClockSkewConfig() (ClockSkewConfig, error) {
+	defaultTolerance, err := getDurationOrDefault("CLOCK_SKEW_TOLERANCE", 5*time.Minute)
+	if err != nil {
+		return ClockSkewConfig{}, err
+	}
+
+	entries, err := parseKeyValueList(os.Getenv("CLOCK_SKEW_TOLERANCE_PER_SOURCE"))
+	if err != nil {
+		return ClockSkewConfig{}, fmt.Errorf("invalid CLOCK_SKEW_TOLERANCE_PER_SOURCE: %w", err)
+	}
+
+	perSource := make(map[string]time.Duration, len(entries))
+	for source, value := range entries {
+		tolerance, err := time.ParseDuration(value)
+		if err != nil {
+			return ClockSkewConfig{}, fmt.Errorf("invalid CLOCK_SKEW_TOLERANCE_PER_SOURCE for %s: %w", source, err)
+		}
+		perSource[source] = tolerance
+	}
+
+	return ClockSkewConfig{
+		Default:   defaultTolerance,
+		PerSource: perSource,
+	}, nil
+}
+
+// loadAmountConfig reads the amount validation policy. Per-source bounds are
+// given as "game:10000,payment:50000".
+func loadAmountConfig() (AmountConfig, error) {
+	minAmount, err := getDecimalOrDefault("AMOUNT_MIN", decimal.Zero)
+	if err != nil {
+		return AmountConfig{}, err
+	}
+	maxAmount, err := getDecimalOrDefault("AMOUNT_MAX", decimal.Zero)
+	if err != nil {
+		return AmountConfig{}, err
+	}
+	// Amounts are stored with two decimal places
+	maxDecimalPlaces, err := getUintOrDefault("AMOUNT_MAX_DECIMAL_PLACES", 2)
+	if err != nil {
+		return AmountConfig{}, err
+	}
+	if maxDecimalPlaces > 2 {
+		return AmountConfig{}, fmt.Errorf("invalid AMOUNT_MAX_DECIMAL_PLACES: must be at most 2")
+	}
+	minPerSource, err := loadAmountsPerSource("AMOUNT_MIN_PER_SOURCE")
+	if err != nil {
+		return AmountConfig{}, err
+	}
+	maxPerSource, err := loadAmountsPerSource("AMOUNT_MAX_PER_SOURCE")
+	if err != nil {
+		return AmountConfig{}, err
+	}
+
+	if minAmount.IsNegative() || maxAmount.IsNegative() {
+		return AmountConfig{}, fmt.Errorf("invalid AMOUNT_MIN or AMOUNT_MAX: must not be negative")
+	}
+	// The bounds of every source type must leave room for an amount
+	sources := []string{""}
+	for source := range minPerSource {
+		sources = append(sources, source)
+	}
+	for source := range maxPerSource {
+		sources = append(sources, source)
+	}
+	for _, source := range sources {
+		lower, ok := minPerSource[source]
+		if !ok {
+			lower = minAmount
+		}
+		upper, ok := maxPerSource[source]
+		if !ok {
+			upper = maxAmount
+		}
+		if upper.IsPositive() && lower.GreaterThan(upper) {
+			return AmountConfig{}, fmt.Errorf("invalid AMOUNT_MAX: below the minimum amount of %q", source)
+		}
+	}
+
+	return AmountConfig{
+		Min:              minAmount,
+		Max:              maxAmount,
+		MaxDecimalPlaces: int(maxDecimalPlaces),
+		MinPerSource:     minPerSource,
+		MaxPerSource:     maxPerSource,
+	}, nil
+}
+
+// loadVelocityConfig reads the per-user velocity limits
+func loadVelocityConfig() (VelocityConfig, error) {
+	maxTransactions, err := getUintOrDefault("VELOCITY_MAX_TRANSACTIONS", 0)
+	if err != nil {
+		return VelocityConfig{}, err
+	}
+	maxLoss, err := getDecimalOrDefault("VELOCITY_MAX_LOSS", decimal.Zero)
+	if err != nil {
+		return VelocityConfig{}, err
+	}
+	if maxLoss.IsNegative() {
+		return VelocityConfig{}, fmt.Errorf("invalid VELOCITY_MAX_LOSS: must not be negative")
+	}
+	window, err := getDurationOrDefault("VELOCITY_WINDOW", 24*time.Hour)
+	if err != nil {
+		return VelocityConfig{}, err
+	}
+	if window <= 0 {
+		return VelocityConfig{}, fmt.Errorf("invalid VELOCITY_WINDOW: must be positive")
+	}
+
+	return VelocityConfig{
+		MaxTransactions: int(maxTransactions),
+		MaxLoss:         maxLoss,
+		Window:          window,
+	}, nil
+}
+
+// loadAmountsPerSource reads non-negative amounts per source type
+func loadAmountsPerSource(key string) (map[string]decimal.Decimal, error) {
+	entries, err := parseKeyValueList(os.Getenv(key))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", key, err)
+	}
+
+	amounts := make(map[string]decimal.Decimal, len(entries))
+	for source, value := range entries {
+		amount, err := decimal.NewFromString(value)
+		if err != nil || amount.IsNegative() {
+			return nil, fmt.Errorf("invalid %s for %s: %q", key, source, value)
+		}
+		amounts[source] = amount
+	}
+	return amounts, nil
+}
+
+func loadGuardConfig() (GuardConfig, error) {
+	enabled, err := getBoolOrDefault("BALANCE_GUARD_ENABLED", false)
+	if err != nil {
+		return GuardConfig{}, err
+	}
+	window, err := getDurationOrDefault("BALANCE_GUARD_WINDOW", time.Hour)
+	if err != nil {
+		return GuardConfig{}, err
+	}
+	maxChange, err := getDecimalOrDefault("BALANCE_GUARD_MAX_CHANGE", decimal.Zero)
+	if err != nil {
+		return GuardConfig{}, err
+	}
+	maxChangePercent, err := getDecimalOrDefault("BALANCE_GUARD_MAX_CHANGE_PERCENT", decimal.Zero)
+	if err != nil {
+		return GuardConfig{}, err
+	}
+	freeze, err := getBoolOrDefault("BALANCE_GUARD_FREEZE", false)
+	if err != nil {
+		return GuardConfig{}, err
+	}
+
+	return GuardConfig{
+		Enabled:          enabled,
+		Window:           window,
+		MaxChange:        maxChange,
+		MaxChangePercent: maxChangePercent,
+		Action:           getEnvOrDefault("BALANCE_GUARD_ACTION", "flag"),
+		FreezeOnTrip:     freeze,
+	}, nil
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getDurationOrDefault(key string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return duration, nil
+}
+
+func getBoolOrDefault(key string, defaultValue bool) (bool, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return parsed, nil
+}
+
+func getUintOrDefault(key string, defaultValue uint64) (uint64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	parsed, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return parsed, nil
+}
+
+func getFloatOrDefault(key string, defaultValue float64) (float64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return parsed, nil
+}
+
+func getDecimalOrDefault(key string, defaultValue decimal.Decimal) (decimal.Decimal, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	parsed, err := decimal.NewFromString(value)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return parsed, nil
+}
+
+// parseList parses comma-separated values, dropping empty entries
+func parseList(value string) []string {
+	var result []string
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+// parseKeyValueList parses values of the form "key1:value1,key2:value2"
+func parseKeyValueList(value string) (map[string]string, error) {
+	result := make(map[string]string)
+	if strings.TrimSpace(value) == "" {
+		return result, nil
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		key, val, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || key == "" || val == "" {
+			return nil, fmt.Errorf("malformed entry %q", pair)
+		}
+		result[strings.TrimSpace(key)] = strings.TrimSpace(val)
+	}
+
+	return result, nil
+}
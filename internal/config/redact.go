@@ -0,0 +1,83 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const redactedValue = "[REDACTED]"
+
+// Redacted returns the effective configuration as a nested map with every
+// field tagged `redact:"true"` masked, suitable for logging and admin output
+func (c *Config) Redacted() map[string]any {
+	return redactStruct(reflect.ValueOf(*c))
+}
+
+func redactStruct(v reflect.Value) map[string]any {
+	result := make(map[string]any, v.NumField())
+	t := v.Type()
+
+	for i := 0; i < v.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := field.Name
+		if tag, _, _ := strings.Cut(field.Tag.Get("json"), ","); tag != "" {
+			if tag == "-" {
+				continue
+			}
+			name = tag
+		}
+
+		if field.Tag.Get("redact") == "true" {
+			if v.Field(i).IsZero() {
+				result[name] = ""
+			} else {
+				result[name] = redactedValue
+			}
+			continue
+		}
+
+		result[name] = redactValue(v.Field(i))
+	}
+
+	return result
+}
+
+func redactValue(v reflect.Value) any {
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		return time.Duration(v.Int()).String()
+	}
+
+	// Types with their own JSON form (e.g. decimals) are kept as-is
+	if marshaler, ok := v.Interface().(json.Marshaler); ok {
+		return marshaler
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		return redactStruct(v)
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		result := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			result[fmt.Sprint(iter.Key().Interface())] = redactValue(iter.Value())
+		}
+		return result
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		return redactValue(v.Elem())
+	default:
+		return v.Interface()
+	}
+}
@@ -0,0 +1,60 @@
+// Package consistency carries read-your-writes consistency tokens. A token is
+// a position in the primary database's write-ahead log, handed out after a
+// write. A client echoing it on a later read is served by a replica only once
+// the replica has replayed the log up to that position.
+package consistency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Header carries tokens in write responses and in the reads echoing them
+const Header = "X-Consistency-Token"
+
+// ErrInvalidToken is returned for tokens that are not a log position
+var ErrInvalidToken = errors.New("invalid consistency token")
+
+// Token is a write-ahead log position. The zero Token precedes every write.
+type Token uint64
+
+// ParseToken parses a log position in the Postgres notation, two hexadecimal
+// numbers separated by a slash, e.g. "0/16B3748"
+func ParseToken(value string) (Token, error) {
+	high, low, ok := strings.Cut(value, "/")
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidToken, value)
+	}
+	h, err := strconv.ParseUint(high, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidToken, value)
+	}
+	l, err := strconv.ParseUint(low, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidToken, value)
+	}
+	return Token(h<<32 | l), nil
+}
+
+// String formats the token in the Postgres notation
+func (t Token) String() string {
+	return fmt.Sprintf("%X/%X", uint64(t)>>32, uint64(t)&0xFFFFFFFF)
+}
+
+type replicaReadKey struct{}
+
+// WithReplicaRead marks reads made with the returned context as allowed to be
+// served by a replica that has replayed the log up to after
+func WithReplicaRead(ctx context.Context, after Token) context.Context {
+	return context.WithValue(ctx, replicaReadKey{}, after)
+}
+
+// ReplicaRead returns the position a replica must have replayed to serve
+// reads made with ctx, and false when they must be served by the primary
+func ReplicaRead(ctx context.Context) (Token, bool) {
+	after, ok := ctx.Value(replicaReadKey{}).(Token)
+	return after, ok
+}
@@ -0,0 +1,35 @@
+package consistency
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseToken(t *testing.T) {
+	token, err := ParseToken("1/16B3748")
+	require.NoError(t, err)
+	assert.Equal(t, Token(1<<32|0x16B3748), token)
+	assert.Equal(t, "1/16B3748", token.String())
+
+	later, err := ParseToken("1/16B3750")
+	require.NoError(t, err)
+	assert.Greater(t, later, token)
+
+	for _, value := range []string{"", "16B3748", "x/1", "1/", "100000000/0"} {
+		_, err := ParseToken(value)
+		assert.ErrorIs(t, err, ErrInvalidToken, value)
+	}
+}
+
+func TestReplicaRead(t *testing.T) {
+	ctx := context.Background()
+	_, ok := ReplicaRead(ctx)
+	assert.False(t, ok)
+
+	after, ok := ReplicaRead(WithReplicaRead(ctx, 42))
+	assert.True(t, ok)
+	assert.Equal(t, Token(42), after)
+}
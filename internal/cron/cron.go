@@ -0,0 +1,168 @@
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidExpression is returned for expressions that are not five valid
+// cron fields
+var ErrInvalidExpression = errors.New("invalid cron expression")
+
+// horizon bounds the search for the next activation, so that expressions
+// matching no date, such as February 30th, end it
+const horizon = 5 * 366 * 24 * time.Hour
+
+// field describes the values one of the five fields ranges over
+type field struct {
+	name     string
+	min, max int
+}
+
+var fields = [5]field{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	// 7 is Sunday as well as 0
+	{"day of week", 0, 7},
+}
+
+// Schedule is a parsed cron expression of the five standard fields: minute,
+// hour, day of month, month and day of week. Each field is `*`, a value, a
+// range `a-b` or a comma-separated list of them, optionally stepped with
+// `/n`. Like in cron, a day matches when either restricted day field matches.
+// Schedules are evaluated in UTC.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	// domAny and dowAny are set for the day fields left as `*`
+	domAny, dowAny bool
+}
+
+// Parse parses a cron expression of five fields
+func Parse(expr string) (*Schedule, error) {
+	parts := strings.Fields(expr)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("%w: want 5 fields, got %d", ErrInvalidExpression, len(parts))
+	}
+
+	var sets [5]uint64
+	for i, part := range parts {
+		set, err := parseField(part, fields[i])
+		if err != nil {
+			return nil, err
+		}
+		sets[i] = set
+	}
+	// Sunday is both 0 and 7
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1
+	}
+
+	return &Schedule{
+		minute: sets[0],
+		hour:   sets[1],
+		dom:    sets[2],
+		month:  sets[3],
+		dow:    sets[4],
+		domAny: parts[2] == "*",
+		dowAny: parts[4] == "*",
+	}, nil
+}
+
+// parseField returns the set of values a field matches
+func parseField(value string, f field) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(value, ",") {
+		rangePart, stepPart, stepped := strings.Cut(item, "/")
+		step := 1
+		if stepped {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+				return 0, fmt.Errorf("%w: invalid step %q in the %s field", ErrInvalidExpression, stepPart, f.name)
+			}
+		}
+
+		low, high := f.min, f.max
+		if rangePart != "*" {
+			lowPart, highPart, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = parseValue(lowPart, f); err != nil {
+				return 0, err
+			}
+			high = low
+			if isRange {
+				if high, err = parseValue(highPart, f); err != nil {
+					return 0, err
+				}
+			} else if stepped {
+				// A stepped value, such as 5/15, runs to the end of the range
+				high = f.max
+			}
+			if low > high {
+				return 0, fmt.Errorf("%w: invalid range %q in the %s field", ErrInvalidExpression, rangePart, f.name)
+			}
+		}
+
+		for v := low; v <= high; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+func parseValue(value string, f field) (int, error) {
+	v, err := strconv.Atoi(value)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("%w: %q is not a %s from %d to %d", ErrInvalidExpression, value, f.name, f.min, f.max)
+	}
+	return v, nil
+}
+
+// Next returns the first activation strictly after after, in UTC. It returns
+// the zero time when the schedule never activates.
+func (s *Schedule) Next(after time.Time) time.Time {
+	t := after.UTC().Truncate(time.Minute).Add(time.Minute)
+	end := t.Add(horizon)
+
+	for t.Before(end) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// matchesDay reports whether the day of t matches. When both day fields are
+// restricted, matching either is enough.
+func (s *Schedule) matchesDay(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dow
+	case s.dowAny:
+		return dom
+	default:
+		return dom || dow
+	}
+}
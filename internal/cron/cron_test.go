@@ -0,0 +1,49 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedule_Next(t *testing.T) {
+	// A Wednesday
+	after := time.Date(2025, 1, 1, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		expr string
+		want time.Time
+	}{
+		{name: "every minute", expr: "* * * * *", want: time.Date(2025, 1, 1, 10, 31, 0, 0, time.UTC)},
+		{name: "later the same day", expr: "0 12 * * *", want: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{name: "the next day", expr: "0 9 * * *", want: time.Date(2025, 1, 2, 9, 0, 0, 0, time.UTC)},
+		{name: "steps", expr: "*/20 * * * *", want: time.Date(2025, 1, 1, 10, 40, 0, 0, time.UTC)},
+		{name: "lists and ranges", expr: "15,45 8-9,11 * * *", want: time.Date(2025, 1, 1, 11, 15, 0, 0, time.UTC)},
+		{name: "day of month", expr: "0 0 15 * *", want: time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{name: "day of week", expr: "0 0 * * 1", want: time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)},
+		{name: "sunday is 7 too", expr: "0 0 * * 7", want: time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{name: "either restricted day matches", expr: "0 0 15 * 5", want: time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC)},
+		{name: "month", expr: "0 0 1 3 *", want: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "leap day", expr: "0 0 29 2 *", want: time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{name: "never", expr: "0 0 30 2 *", want: time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := Parse(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, schedule.Next(after))
+		})
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "* * * * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *",
+		"* * * * 8", "5-1 * * * *", "*/0 * * * *", "a * * * *", "1,,2 * * * *"} {
+		_, err := Parse(expr)
+		assert.ErrorIs(t, err, ErrInvalidExpression, expr)
+	}
+}
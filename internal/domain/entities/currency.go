@@ -0,0 +1,60 @@
+package entities
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// ErrNotRepresentable is returned when an amount has more fractional digits
+// than its currency's minor unit
+var ErrNotRepresentable = errors.New("amount is not representable in minor units")
+
+// Currency is an ISO 4217 currency with the number of digits of its minor unit
+type Currency struct {
+	Code     string `json:"code"`
+	Exponent int32  `json:"exponent"`
+}
+
+// currencies are the supported ISO 4217 currencies. Amounts are stored with
+// two decimal places, so currencies with a finer minor unit are not listed.
+var currencies = map[string]Currency{
+	"AUD": {Code: "AUD", Exponent: 2},
+	"CAD": {Code: "CAD", Exponent: 2},
+	"CHF": {Code: "CHF", Exponent: 2},
+	"CZK": {Code: "CZK", Exponent: 2},
+	"DKK": {Code: "DKK", Exponent: 2},
+	"EUR": {Code: "EUR", Exponent: 2},
+	"GBP": {Code: "GBP", Exponent: 2},
+	"JPY": {Code: "JPY", Exponent: 0},
+	"KRW": {Code: "KRW", Exponent: 0},
+	"NOK": {Code: "NOK", Exponent: 2},
+	"PLN": {Code: "PLN", Exponent: 2},
+	"SEK": {Code: "SEK", Exponent: 2},
+	"USD": {Code: "USD", Exponent: 2},
+}
+
+// LookupCurrency returns the supported currency with the given ISO 4217 code
+func LookupCurrency(code string) (Currency, bool) {
+	currency, ok := currencies[strings.ToUpper(code)]
+	return currency, ok
+}
+
+// maxSafeMinorUnits is the largest integer a JSON decoder using doubles reads exactly
+var maxSafeMinorUnits = decimal.NewFromInt(1 << 53)
+
+// ToMinorUnits converts an amount to an integer number of minor units, e.g.
+// 10.50 EUR to 1050
+func (c Currency) ToMinorUnits(amount decimal.Decimal) (int64, error) {
+	units := amount.Shift(c.Exponent)
+	if !units.IsInteger() || units.Abs().GreaterThan(maxSafeMinorUnits) {
+		return 0, ErrNotRepresentable
+	}
+	return units.IntPart(), nil
+}
+
+// FromMinorUnits converts an integer number of minor units to an amount
+func (c Currency) FromMinorUnits(units int64) decimal.Decimal {
+	return decimal.New(units, -c.Exponent)
+}
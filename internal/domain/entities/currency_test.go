@@ -0,0 +1,53 @@
+package entities
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupCurrency(t *testing.T) {
+	eur, ok := LookupCurrency("eur")
+	require.True(t, ok)
+	assert.Equal(t, Currency{Code: "EUR", Exponent: 2}, eur)
+
+	_, ok = LookupCurrency("XYZ")
+	assert.False(t, ok)
+}
+
+func TestCurrency_MinorUnits(t *testing.T) {
+	tests := []struct {
+		name     string
+		currency string
+		amount   string
+		units    int64
+		wantErr  bool
+	}{
+		{name: "cents", currency: "EUR", amount: "10.50", units: 1050},
+		{name: "whole amount", currency: "USD", amount: "3", units: 300},
+		{name: "zero exponent", currency: "JPY", amount: "1200", units: 1200},
+		{name: "negative", currency: "GBP", amount: "-0.01", units: -1},
+		{name: "sub-minor fraction", currency: "EUR", amount: "0.005", wantErr: true},
+		{name: "fraction of a yen", currency: "JPY", amount: "1.5", wantErr: true},
+		{name: "beyond safe integers", currency: "EUR", amount: "100000000000000", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			currency, ok := LookupCurrency(tt.currency)
+			require.True(t, ok)
+			amount := decimal.RequireFromString(tt.amount)
+
+			units, err := currency.ToMinorUnits(amount)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrNotRepresentable)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.units, units)
+			assert.True(t, amount.Equal(currency.FromMinorUnits(units)))
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package entities
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/shopspring/decimal"
@@ -12,6 +13,39 @@ type SourceType string
 type User struct {
 	ID      uint64          `json:"id" db:"id"`
 	Balance decimal.Decimal `json:"balance" db:"balance"`
+	Status  UserStatus      `json:"status" db:"status"`
+	// CreditLimit is how far below zero the base currency balance may go;
+	// zero for users without a credit line
+	CreditLimit decimal.Decimal `json:"creditLimit" db:"credit_limit"`
+	// Jurisdiction is the ISO 3166-1 alpha-2 country code whose rules apply
+	// to the user; empty when no overlay applies
+	Jurisdiction string `json:"jurisdiction,omitempty" db:"jurisdiction"`
+	// DormantAt is when the dormancy sweep flagged the user as dormant; nil
+	// for users that have transacted since
+	DormantAt *time.Time `json:"dormantAt,omitempty" db:"dormant_at"`
+	// Wallets hold the user's balances in currencies other than the base
+	// currency, which Balance is held in. Only loaded where needed.
+	Wallets []*Wallet `json:"wallets,omitempty" db:"-"`
+}
+
+// Wallet is a user's balance in a currency other than the base currency
+type Wallet struct {
+	UserID   uint64          `json:"userId" db:"user_id"`
+	Currency string          `json:"currency" db:"currency"`
+	Balance  decimal.Decimal `json:"balance" db:"balance"`
+}
+
+// UserStatus represents the lifecycle status of a user account
+type UserStatus string
+
+const (
+	UserStatusActive UserStatus = "active"
+	UserStatusFrozen UserStatus = "frozen"
+)
+
+// IsValid checks if the user status is valid
+func (us UserStatus) IsValid() bool {
+	return us == UserStatusActive || us == UserStatusFrozen
 }
 
 // Transaction represents a transaction in the system
@@ -19,10 +53,93 @@ type Transaction struct {
 	ID            uint64           `json:"id" db:"id"`
 	UserID        uint64           `json:"userId" db:"user_id"`
 	TransactionID string           `json:"transactionId" db:"transaction_id"`
+	Receipt       string           `json:"receipt" db:"receipt"`
 	State         TransactionState `json:"state" db:"state"`
 	Amount        decimal.Decimal  `json:"amount" db:"amount"`
 	SourceType    SourceType       `json:"sourceType" db:"source_type"`
-	CreatedAt     time.Time        `json:"createdAt" db:"created_at"`
+	// Currency is the ISO 4217 code of the balance the transaction moved. It
+	// is stored empty for the base currency.
+	Currency    string     `json:"currency,omitempty" db:"currency"`
+	OccurredAt  *time.Time `json:"occurredAt,omitempty" db:"occurred_at"`
+	CreatedAt   time.Time  `json:"createdAt" db:"created_at"`
+	Cancelled   bool       `json:"cancelled" db:"cancelled"`
+	CancelledAt *time.Time `json:"cancelledAt,omitempty" db:"cancelled_at"`
+	// BalanceAfter is the user's balance right after the transaction was
+	// applied; nil for transactions recorded before it was stored
+	BalanceAfter *decimal.Decimal `json:"balanceAfter,omitempty" db:"balance_after"`
+	// RoundID is the game round or session the transaction belongs to; empty
+	// when the client did not send one
+	RoundID string `json:"roundId,omitempty" db:"round_id"`
+	// Type is refund for the records compensating a transaction, transfer
+	// for the legs of a transfer between users, fee for the fees charged by
+	// the service and adjustment for the manual balance adjustments
+	Type TransactionType `json:"type" db:"type"`
+	// Reverses is the transaction ID of the transaction a refund compensates
+	Reverses string `json:"reverses,omitempty" db:"reverses"`
+	// ReversedBy is the transaction ID of the refund compensating the
+	// transaction; empty while it has not been refunded
+	ReversedBy string `json:"reversedBy,omitempty" db:"reversed_by"`
+	// TransferID is the transfer both legs of a transfer were recorded for
+	TransferID string `json:"transferId,omitempty" db:"transfer_id"`
+	// ChargedFor is the transaction ID of the transaction a fee was charged
+	// for; empty for the other transactions and for dormancy fees
+	ChargedFor string `json:"chargedFor,omitempty" db:"charged_for"`
+	// Metadata holds the references the client attached to the transaction,
+	// e.g. a table ID or the provider's reference
+	Metadata map[string]string `json:"metadata,omitempty" db:"metadata"`
+	// Pending is set while a payment credit awaits settlement; it moves the
+	// balance only once SettledAt is set
+	Pending   bool       `json:"pending,omitempty" db:"pending"`
+	SettledAt *time.Time `json:"settledAt,omitempty" db:"settled_at"`
+}
+
+// BusinessTime returns when the transaction happened according to the source
+// system, falling back to the time it arrived at the service
+func (t *Transaction) BusinessTime() time.Time {
+	if t.OccurredAt != nil {
+		return *t.OccurredAt
+	}
+	return t.CreatedAt
+}
+
+// BalanceChangeSince returns how much the transaction moved the balance at or
+// after since: its signed amount if it was applied then, less the same again
+// if it was cancelled then. Transactions apply when created, or when settled
+// if they were pending. Pending transactions have not moved the balance yet.
+func (t *Transaction) BalanceChangeSince(since time.Time) decimal.Decimal {
+	if t.Pending {
+		return decimal.Zero
+	}
+	appliedAt := t.CreatedAt
+	if t.SettledAt != nil {
+		appliedAt = *t.SettledAt
+	}
+
+	change := decimal.Zero
+	if !appliedAt.Before(since) {
+		change = t.SignedAmount()
+	}
+	if t.Cancelled {
+		// Cancellations recorded without a time take effect with the
+		// transaction
+		cancelledAt := appliedAt
+		if t.CancelledAt != nil {
+			cancelledAt = *t.CancelledAt
+		}
+		if !cancelledAt.Before(since) {
+			change = change.Sub(t.SignedAmount())
+		}
+	}
+	return change
+}
+
+// SignedAmount returns the amount as it affected the balance: positive for
+// wins and negative for losses
+func (t *Transaction) SignedAmount() decimal.Decimal {
+	if t.State == StateLose {
+		return t.Amount.Neg()
+	}
+	return t.Amount
 }
 
 // TransactionState represents the state of a transaction
@@ -38,6 +155,27 @@ func (ts TransactionState) IsValid() bool {
 	return ts == StateWin || ts == StateLose
 }
 
+// TransactionType tells the transactions submitted by clients apart from the
+// records compensating them, the legs of transfers, the fees charged by the
+// service and the balance adjustments made by operators
+type TransactionType string
+
+const (
+	TransactionTypeStandard   TransactionType = "transaction"
+	TransactionTypeRefund     TransactionType = "refund"
+	TransactionTypeTransfer   TransactionType = "transfer"
+	TransactionTypeFee        TransactionType = "fee"
+	TransactionTypeAdjustment TransactionType = "adjustment"
+)
+
+// Opposite returns the state reverting the effect of the state on the balance
+func (ts TransactionState) Opposite() TransactionState {
+	if ts == StateWin {
+		return StateLose
+	}
+	return StateWin
+}
+
 const (
 	SourceTypeGame    SourceType = "game"
 	SourceTypeServer  SourceType = "server"
@@ -54,9 +192,1145 @@ type TransactionRequest struct {
 	State         string `json:"state" binding:"required"`
 	Amount        string `json:"amount" binding:"required"`
 	TransactionID string `json:"transactionId" binding:"required"`
+	// Currency is the optional ISO 4217 code of the wallet to move; the base
+	// currency when empty
+	Currency string `json:"currency,omitempty"`
+	// OccurredAt is the optional client-side time the transaction happened
+	OccurredAt *time.Time `json:"occurredAt,omitempty"`
+	// RoundID optionally groups the transactions of a game round or session
+	RoundID string `json:"roundId,omitempty"`
+	// Metadata optionally attaches references to the transaction, e.g. a
+	// table ID or the provider's reference
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// TransactionResult is the outcome of a processed transaction
+type TransactionResult struct {
+	UserID uint64 `json:"userId"`
+	// ID is the internal ID of the recorded transaction
+	ID            uint64 `json:"id"`
+	TransactionID string `json:"transactionId"`
+	Receipt       string `json:"receipt"`
+	Balance       string `json:"balance"`
+	// Currency is the currency of Balance
+	Currency string `json:"currency,omitempty"`
+	// Fee is the fee charged for the transaction, already taken from
+	// Balance; empty when no fee was charged
+	Fee string `json:"fee,omitempty"`
+	// Pending is set when the transaction awaits settlement, so Balance does
+	// not include it yet
+	Pending bool `json:"pending,omitempty"`
+	// Replayed is set when the transaction had already been processed and the
+	// original result is returned
+	Replayed bool `json:"replayed"`
 }
 
+// TransactionBatchRequest represents a batch of transactions of a user
+// submitted at once
+type TransactionBatchRequest struct {
+	Transactions []TransactionRequest `json:"transactions" binding:"required"`
+}
+
+// TransferRequest represents the incoming request to move an amount from one
+// user to another
+type TransferRequest struct {
+	TransferID string `json:"transferId" binding:"required"`
+	FromUserID uint64 `json:"fromUserId" binding:"required"`
+	ToUserID   uint64 `json:"toUserId" binding:"required"`
+	Amount     string `json:"amount" binding:"required"`
+	// Currency is the optional ISO 4217 code of the wallets to move; the base
+	// currency when empty
+	Currency string `json:"currency,omitempty"`
+}
+
+// TransferResult is the outcome of a processed transfer
+type TransferResult struct {
+	TransferID string `json:"transferId"`
+	Amount     string `json:"amount"`
+	Currency   string `json:"currency,omitempty"`
+	// From is the debit of the sender and To the credit of the recipient
+	From TransferLeg `json:"from"`
+	To   TransferLeg `json:"to"`
+	// Replayed is set when the transfer had already been processed and the
+	// original result is returned
+	Replayed bool `json:"replayed"`
+}
+
+// TransferLeg is the transaction a transfer recorded for one of its users
+type TransferLeg struct {
+	UserID        uint64 `json:"userId"`
+	TransactionID string `json:"transactionId"`
+	Receipt       string `json:"receipt"`
+	// Balance is the user's balance right after the leg was applied
+	Balance string `json:"balance"`
+}
+
+// RoundSummary totals the transactions of a game round in one currency.
+// Bets are the lost amounts and wins the won amounts; cancelled transactions
+// are left out of the totals.
+type RoundSummary struct {
+	UserID       uint64 `json:"userId"`
+	RoundID      string `json:"roundId"`
+	Currency     string `json:"currency"`
+	Transactions int    `json:"transactions"`
+	Cancelled    int    `json:"cancelled"`
+	TotalBets    string `json:"totalBets"`
+	TotalWins    string `json:"totalWins"`
+	// TotalFees are the fees charged for the round's transactions, which
+	// are not counted as transactions nor bets
+	TotalFees string `json:"totalFees"`
+	// Net is the wins minus the bets and fees, i.e. the round's effect on
+	// the balance
+	Net string `json:"net"`
+}
+
+// SettlementPreview is the effect that settling a batch of pending
+// transactions would have, computed without settling them
+type SettlementPreview struct {
+	// Before is the cutoff of the batch, which holds the oldest pending
+	// transactions recorded before it
+	Before       time.Time `json:"before"`
+	Transactions int       `json:"transactions"`
+	Users        int       `json:"users"`
+	// Amount is what settling moves to the balances of the users
+	Amount string `json:"amount"`
+	// Fees are the fees charged for the batch's transactions when they were
+	// recorded
+	Fees string `json:"fees"`
+	// Net is the amount minus the fees, i.e. what the users are left with
+	Net string `json:"net"`
+	// BySourceType totals the batch by source system, i.e. by provider
+	BySourceType []SettlementTotals `json:"bySourceType"`
+	// House is left out without a house account
+	House *HouseMovement `json:"house,omitempty"`
+	// HasMore is set when more pending transactions are due than the batch
+	// holds
+	HasMore bool `json:"hasMore"`
+}
+
+// SettlementTotals totals the transactions of a settlement batch recorded
+// with one source type
+type SettlementTotals struct {
+	SourceType   SourceType `json:"sourceType"`
+	Transactions int        `json:"transactions"`
+	Amount       string     `json:"amount"`
+	Fees         string     `json:"fees"`
+}
+
+// HouseMovement is what a settlement batch moves on the house account: the
+// contra legs of the batch's fees, which credited the house when the fees
+// were charged. Settling moves nothing more on the house.
+type HouseMovement struct {
+	UserID uint64 `json:"userId"`
+	Amount string `json:"amount"`
+	// Balance is the current balance of the house, the fees included
+	Balance string `json:"balance"`
+}
+
+// UserStats totals a user's uncancelled transactions in one currency created
+// in [From, To), overall, by source type and by UTC day. Fees are left out.
+type UserStats struct {
+	UserID   uint64    `json:"userId"`
+	Currency string    `json:"currency"`
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	StatsTotals
+	BySourceType []SourceTypeStats `json:"bySourceType"`
+	// Days lists the days with transactions, oldest first
+	Days []DayStats `json:"days"`
+}
+
+// StatsTotals count and sum the wins and losses of a set of transactions
+type StatsTotals struct {
+	Wins        int    `json:"wins"`
+	Losses      int    `json:"losses"`
+	TotalWins   string `json:"totalWins"`
+	TotalLosses string `json:"totalLosses"`
+	// Net is the wins minus the losses
+	Net string `json:"net"`
+}
+
+// SourceTypeStats totals the transactions of one source type
+type SourceTypeStats struct {
+	SourceType SourceType `json:"sourceType"`
+	StatsTotals
+}
+
+// DayStats totals the transactions created on a UTC day
+type DayStats struct {
+	// Day is formatted as YYYY-MM-DD
+	Day string `json:"day"`
+	StatsTotals
+}
+
+// BalanceResponse represents a user's balance as returned by the API
 type BalanceResponse struct {
 	UserID  uint64 `json:"userId"`
 	Balance string `json:"balance"`
+	// Currency is the base currency Balance is held in
+	Currency string `json:"currency,omitempty"`
+	// Balances maps every currency the user holds, including the base
+	// currency, to its balance
+	Balances map[string]string `json:"balances,omitempty"`
+	// Available is Balance less the active holds; empty when holds are not
+	// enabled
+	Available string `json:"available,omitempty"`
+	// Pending is the sum of the base currency credits awaiting settlement,
+	// not yet included in Balance; empty when settlement is not enabled
+	Pending string `json:"pending,omitempty"`
+	// Stale is set when the balance was served from cache because the
+	// database was unavailable; AsOf is when the cached value was read, or
+	// the past moment a point-in-time balance was requested for
+	Stale bool       `json:"stale,omitempty"`
+	AsOf  *time.Time `json:"asOf,omitempty"`
+}
+
+// CreateUserRequest represents the incoming user creation request
+type CreateUserRequest struct {
+	// Balance is the optional initial balance; users start at zero without it
+	Balance string `json:"balance,omitempty"`
+}
+
+// UserResponse represents a user as returned by the API
+type UserResponse struct {
+	ID           uint64     `json:"id"`
+	Balance      string     `json:"balance"`
+	Status       UserStatus `json:"status"`
+	CreditLimit  string     `json:"creditLimit,omitempty"`
+	Jurisdiction string     `json:"jurisdiction,omitempty"`
+	DormantAt    *time.Time `json:"dormantAt,omitempty"`
+}
+
+// UserPage is a page of users with the total number of users
+type UserPage struct {
+	Users  []*UserResponse `json:"users"`
+	Total  int             `json:"total"`
+	Limit  int             `json:"limit"`
+	Offset int             `json:"offset"`
+}
+
+// TransactionPage is a page of transactions with the total number of matches.
+// NextCursor continues after the page, and is empty on the last page.
+type TransactionPage struct {
+	Transactions []*Transaction `json:"transactions"`
+	Total        int            `json:"total"`
+	Limit        int            `json:"limit"`
+	Offset       int            `json:"offset"`
+	NextCursor   string         `json:"nextCursor,omitempty"`
+}
+
+// SyncPage is a batch of the transaction change feed. Cursor is the position
+// after the batch, to be passed as since for the next one.
+type SyncPage struct {
+	Transactions []*Transaction `json:"transactions"`
+	Cursor       string         `json:"cursor"`
+	HasMore      bool           `json:"hasMore"`
+}
+
+// AnnotationTarget identifies what kind of record an annotation is attached to
+type AnnotationTarget string
+
+const (
+	AnnotationTargetUser        AnnotationTarget = "user"
+	AnnotationTargetTransaction AnnotationTarget = "transaction"
+)
+
+// IsValid checks if the annotation target is valid
+func (at AnnotationTarget) IsValid() bool {
+	return at == AnnotationTargetUser || at == AnnotationTargetTransaction
+}
+
+// Annotation is a support note attached to a user or a transaction. TargetID
+// is the user ID or the external transaction ID.
+type Annotation struct {
+	ID         uint64           `json:"id" db:"id"`
+	TargetType AnnotationTarget `json:"targetType" db:"target_type"`
+	TargetID   string           `json:"targetId" db:"target_id"`
+	Author     string           `json:"author" db:"author"`
+	Note       string           `json:"note" db:"note"`
+	CreatedAt  time.Time        `json:"createdAt" db:"created_at"`
+}
+
+// AnnotationRequest represents an incoming request to annotate a record
+type AnnotationRequest struct {
+	Author string `json:"author" binding:"required"`
+	Note   string `json:"note" binding:"required"`
+}
+
+// Hold reserves part of a user's base currency balance for a payment until it
+// is captured, released or expires
+type Hold struct {
+	ID     uint64          `json:"id" db:"id"`
+	HoldID string          `json:"holdId" db:"hold_id"`
+	UserID uint64          `json:"userId" db:"user_id"`
+	Amount decimal.Decimal `json:"amount" db:"amount"`
+	Status HoldStatus      `json:"status" db:"status"`
+	// CapturedAmount is the amount moved by the capture, at most Amount
+	CapturedAmount *decimal.Decimal `json:"capturedAmount,omitempty" db:"captured_amount"`
+	CreatedAt      time.Time        `json:"createdAt" db:"created_at"`
+	ExpiresAt      time.Time        `json:"expiresAt" db:"expires_at"`
+	// SettledAt is when the hold was captured, released or expired
+	SettledAt *time.Time `json:"settledAt,omitempty" db:"settled_at"`
+}
+
+// IsActive reports whether the hold still reserves its amount at now
+func (h *Hold) IsActive(now time.Time) bool {
+	return h.Status == HoldStatusHeld && now.Before(h.ExpiresAt)
+}
+
+// HoldStatus represents the lifecycle status of a hold
+type HoldStatus string
+
+const (
+	HoldStatusHeld     HoldStatus = "held"
+	HoldStatusCaptured HoldStatus = "captured"
+	HoldStatusReleased HoldStatus = "released"
+	HoldStatusExpired  HoldStatus = "expired"
+)
+
+// HoldRequest represents an incoming request to place a hold
+type HoldRequest struct {
+	HoldID string `json:"holdId" binding:"required"`
+	Amount string `json:"amount" binding:"required"`
+	// ExpiresIn is an optional Go duration after which the hold expires
+	ExpiresIn string `json:"expiresIn,omitempty"`
+}
+
+// CaptureRequest represents an incoming request to capture a hold
+type CaptureRequest struct {
+	// Amount is the optional amount to capture; the full hold when empty
+	Amount string `json:"amount,omitempty"`
+}
+
+// HoldResponse represents a hold as returned by the API
+type HoldResponse struct {
+	HoldID         string     `json:"holdId"`
+	UserID         uint64     `json:"userId"`
+	Amount         string     `json:"amount"`
+	CapturedAmount string     `json:"capturedAmount,omitempty"`
+	Status         HoldStatus `json:"status"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	ExpiresAt      time.Time  `json:"expiresAt"`
+	SettledAt      *time.Time `json:"settledAt,omitempty"`
+	// Receipt identifies the payment transaction recorded by the capture
+	Receipt string `json:"receipt,omitempty"`
+	// Replayed is set when the request had already been applied and the
+	// hold is returned unchanged
+	Replayed bool `json:"replayed"`
+}
+
+// ScheduledTransaction is a transaction processed by the service at a future
+// time, once or on a recurring cron schedule
+type ScheduledTransaction struct {
+	ID         uint64           `json:"id" db:"id"`
+	ScheduleID string           `json:"scheduleId" db:"schedule_id"`
+	UserID     uint64           `json:"userId" db:"user_id"`
+	State      TransactionState `json:"state" db:"state"`
+	Amount     decimal.Decimal  `json:"amount" db:"amount"`
+	SourceType SourceType       `json:"sourceType" db:"source_type"`
+	// Currency is the wallet to move; the base currency when empty
+	Currency string            `json:"currency,omitempty" db:"currency"`
+	Metadata map[string]string `json:"metadata,omitempty" db:"metadata"`
+	// Cron is the recurrence of the schedule; empty for one-off schedules
+	Cron string `json:"cron,omitempty" db:"cron"`
+	// MaxRuns bounds the runs of a recurring schedule; zero is unbounded
+	MaxRuns int `json:"maxRuns,omitempty" db:"max_runs"`
+	// Runs counts the runs so far, including the failed ones
+	Runs   int            `json:"runs" db:"runs"`
+	Status ScheduleStatus `json:"status" db:"status"`
+	// NextRunAt is when the schedule runs next; nil once it is no longer
+	// active
+	NextRunAt         *time.Time `json:"nextRunAt,omitempty" db:"next_run_at"`
+	LastRunAt         *time.Time `json:"lastRunAt,omitempty" db:"last_run_at"`
+	LastTransactionID string     `json:"lastTransactionId,omitempty" db:"last_transaction_id"`
+	// LastError is the reason the last run was rejected; empty when it
+	// succeeded
+	LastError string    `json:"lastError,omitempty" db:"last_error"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
+
+// ScheduleStatus represents the lifecycle status of a scheduled transaction
+type ScheduleStatus string
+
+const (
+	ScheduleStatusActive    ScheduleStatus = "active"
+	ScheduleStatusCompleted ScheduleStatus = "completed"
+	ScheduleStatusCancelled ScheduleStatus = "cancelled"
+)
+
+// ScheduleRequest represents an incoming request to schedule a transaction.
+// Exactly one of RunAt and Cron is set.
+type ScheduleRequest struct {
+	ScheduleID string `json:"scheduleId" binding:"required"`
+	State      string `json:"state" binding:"required"`
+	Amount     string `json:"amount" binding:"required"`
+	// Currency is the optional ISO 4217 code of the wallet to move; the base
+	// currency when empty
+	Currency string            `json:"currency,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// RunAt is the future time a one-off transaction is processed at
+	RunAt *time.Time `json:"runAt,omitempty"`
+	// Cron is the five-field cron expression, evaluated in UTC, of a
+	// recurring transaction
+	Cron string `json:"cron,omitempty"`
+	// MaxRuns optionally bounds the runs of a recurring transaction
+	MaxRuns int `json:"maxRuns,omitempty"`
+}
+
+// ScheduledTransactionResponse represents a scheduled transaction as returned
+// by the API
+type ScheduledTransactionResponse struct {
+	ScheduleID        string            `json:"scheduleId"`
+	UserID            uint64            `json:"userId"`
+	State             TransactionState  `json:"state"`
+	Amount            string            `json:"amount"`
+	SourceType        SourceType        `json:"sourceType"`
+	Currency          string            `json:"currency,omitempty"`
+	Metadata          map[string]string `json:"metadata,omitempty"`
+	Cron              string            `json:"cron,omitempty"`
+	MaxRuns           int               `json:"maxRuns,omitempty"`
+	Runs              int               `json:"runs"`
+	Status            ScheduleStatus    `json:"status"`
+	NextRunAt         *time.Time        `json:"nextRunAt,omitempty"`
+	LastRunAt         *time.Time        `json:"lastRunAt,omitempty"`
+	LastTransactionID string            `json:"lastTransactionId,omitempty"`
+	LastError         string            `json:"lastError,omitempty"`
+	CreatedAt         time.Time         `json:"createdAt"`
+	// Replayed is set when the request had already been applied and the
+	// schedule is returned unchanged
+	Replayed bool `json:"replayed"`
+}
+
+// BalanceSnapshot records a user's base currency balance at the end of a UTC
+// day
+type BalanceSnapshot struct {
+	UserID uint64 `json:"userId" db:"user_id"`
+	// Day is midnight UTC of the day the balance closed
+	Day       time.Time       `json:"day" db:"day"`
+	Balance   decimal.Decimal `json:"balance" db:"balance"`
+	CreatedAt time.Time       `json:"createdAt" db:"created_at"`
+}
+
+// BalanceHistory lists a user's closing balances over a range, oldest first
+type BalanceHistory struct {
+	UserID uint64 `json:"userId"`
+	// Currency is the base currency the balances are held in
+	Currency    string    `json:"currency"`
+	Granularity string    `json:"granularity"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	// Balances only lists the days with a snapshot
+	Balances []ClosingBalance `json:"balances"`
+}
+
+// ClosingBalance is a user's balance at the end of a UTC day
+type ClosingBalance struct {
+	// Day is formatted as YYYY-MM-DD
+	Day     string `json:"day"`
+	Balance string `json:"balance"`
+}
+
+// IngestionReport is the outcome of verifying that the transactions created
+// in [From, To) were ingested exactly once
+type IngestionReport struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	// Valid is set when the database enforces unique transaction IDs and no
+	// transaction ID was recorded more than once
+	Valid            bool `json:"valid"`
+	UniqueConstraint bool `json:"uniqueConstraint"`
+	Transactions     int  `json:"transactions"`
+	// DistinctTransactionIDs equals Transactions when every transaction ID
+	// was recorded once
+	DistinctTransactionIDs int                      `json:"distinctTransactionIds"`
+	Duplicates             []DuplicateTransactionID `json:"duplicates"`
+	// Rejections are the duplicate submissions the reporting instance saw
+	// within the range and its retention
+	Rejections []DuplicateSubmissions `json:"rejections"`
+}
+
+// DuplicateTransactionID is a transaction ID recorded Count times
+type DuplicateTransactionID struct {
+	TransactionID string `json:"transactionId"`
+	Count         int    `json:"count"`
+}
+
+// DuplicateSubmissions counts the duplicate submissions of a source type
+// within the minute starting at Start
+type DuplicateSubmissions struct {
+	Start      time.Time  `json:"start"`
+	SourceType SourceType `json:"sourceType"`
+	// Replayed counts the retries served their original result and Rejected
+	// the transaction IDs refused for being reused by a different transaction
+	Replayed int `json:"replayed"`
+	Rejected int `json:"rejected"`
+}
+
+// Rejection is a transaction attempt that was rejected, e.g. for failing
+// validation, insufficient funds or hitting a limit. The submitted values are
+// kept as they were sent.
+type Rejection struct {
+	ID            uint64     `json:"id" db:"id"`
+	UserID        uint64     `json:"userId" db:"user_id"`
+	TransactionID string     `json:"transactionId" db:"transaction_id"`
+	SourceType    SourceType `json:"sourceType" db:"source_type"`
+	State         string     `json:"state" db:"state"`
+	Amount        string     `json:"amount" db:"amount"`
+	Currency      string     `json:"currency,omitempty" db:"currency"`
+	// Reason is the code of the error the attempt was rejected with, e.g.
+	// insufficient_funds
+	Reason    string    `json:"reason" db:"reason"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
+
+// RejectionPage is a page of rejections with the total number of matches
+type RejectionPage struct {
+	Rejections []*Rejection `json:"rejections"`
+	Total      int          `json:"total"`
+	Limit      int          `json:"limit"`
+	Offset     int          `json:"offset"`
+}
+
+// RejectionReport is the rejection rate of every source type with attempts
+// in [From, To)
+type RejectionReport struct {
+	From    time.Time          `json:"from"`
+	To      time.Time          `json:"to"`
+	Sources []SourceRejections `json:"sources"`
+}
+
+// SourceRejections are the attempts of a source type and the rejections
+// among them. Attempts count the transactions recorded and the rejections;
+// replays are not attempts.
+type SourceRejections struct {
+	SourceType SourceType `json:"sourceType"`
+	Attempts   int        `json:"attempts"`
+	Rejected   int        `json:"rejected"`
+	// RejectionRate is Rejected over Attempts
+	RejectionRate float64 `json:"rejectionRate"`
+	// Reasons counts the rejections by reason code
+	Reasons map[string]int `json:"reasons"`
+}
+
+// Event types recorded in the outbox
+const (
+	EventTransactionProcessed = "transaction.processed"
+	EventTransactionCancelled = "transaction.cancelled"
+	EventTransactionSettled   = "transaction.settled"
+	EventUserDormant          = "user.dormant"
+)
+
+// OutboxEvent is an event recorded in the same database transaction as the
+// change it describes, waiting to be published
+type OutboxEvent struct {
+	ID   uint64 `json:"id" db:"id"`
+	Type string `json:"type" db:"event_type"`
+	// Key partitions the events; events with the same key are published in
+	// the order they were recorded
+	Key         string     `json:"key" db:"event_key"`
+	Payload     []byte     `json:"payload" db:"payload"`
+	CreatedAt   time.Time  `json:"createdAt" db:"created_at"`
+	PublishedAt *time.Time `json:"publishedAt,omitempty" db:"published_at"`
+}
+
+// BalanceChangeEvent is the payload of the events published when a
+// transaction changes a balance
+type BalanceChangeEvent struct {
+	UserID        uint64           `json:"userId"`
+	TransactionID string           `json:"transactionId"`
+	Receipt       string           `json:"receipt"`
+	State         TransactionState `json:"state"`
+	Amount        string           `json:"amount"`
+	SourceType    SourceType       `json:"sourceType"`
+	Currency      string           `json:"currency,omitempty"`
+	// Balance is the balance the transaction moved, right after the change
+	Balance string `json:"balance"`
+	RoundID string `json:"roundId,omitempty"`
+	// Metadata holds the references the client attached to the transaction
+	Metadata   map[string]string `json:"metadata,omitempty"`
+	OccurredAt *time.Time        `json:"occurredAt,omitempty"`
+	CreatedAt  time.Time         `json:"createdAt"`
+	// Pending is set on the transaction.processed events of credits awaiting
+	// settlement, which have not moved Balance yet
+	Pending bool `json:"pending,omitempty"`
+	// CancelledAt is set on transaction.cancelled events
+	CancelledAt *time.Time `json:"cancelledAt,omitempty"`
+	// SettledAt is set on transaction.settled events
+	SettledAt *time.Time `json:"settledAt,omitempty"`
+	// Reverses is set on the events of refunds to the transaction ID of the
+	// refunded transaction
+	Reverses string `json:"reverses,omitempty"`
+	// TransferID is set on the events of transfer legs
+	TransferID string `json:"transferId,omitempty"`
+}
+
+// ChangeEvent is the payload of the events published for every row a
+// committed database transaction wrote to the transactions or users table.
+// Row maps the columns to their values in PostgreSQL text format, nil for
+// NULL; deletes only carry the primary key.
+type ChangeEvent struct {
+	Table       string             `json:"table"`
+	Operation   string             `json:"operation"`
+	Row         map[string]*string `json:"row"`
+	LSN         string             `json:"lsn"`
+	CommittedAt time.Time          `json:"committedAt"`
+}
+
+// UserDormantEvent is the payload of the events published when the dormancy
+// sweep flags a user as dormant
+type UserDormantEvent struct {
+	UserID       uint64    `json:"userId"`
+	Jurisdiction string    `json:"jurisdiction,omitempty"`
+	DormantAt    time.Time `json:"dormantAt"`
+	// Fee is the dormancy fee charged, and FeeTransactionID the transaction
+	// recording it; both empty when no fee was charged
+	Fee              string `json:"fee,omitempty"`
+	FeeTransactionID string `json:"feeTransactionId,omitempty"`
+	// Balance is the base currency balance after the fee
+	Balance string `json:"balance"`
+	// Frozen is set when the account was frozen for being dormant
+	Frozen bool `json:"frozen"`
+}
+
+// Webhook is a callback URL that receives the balance change events matching
+// its filter
+type Webhook struct {
+	ID  uint64 `json:"id" db:"id"`
+	URL string `json:"url" db:"url"`
+	// Secret signs the deliveries; it is only returned when the webhook is
+	// registered
+	Secret string `json:"secret,omitempty" db:"secret"`
+	// Events lists the event types delivered; every type when empty
+	Events    []string      `json:"events" db:"event_types"`
+	Filter    WebhookFilter `json:"filter"`
+	Active    bool          `json:"active" db:"active"`
+	CreatedAt time.Time     `json:"createdAt" db:"created_at"`
+}
+
+// WebhookFilter narrows the events delivered to a webhook. Zero fields match
+// every event.
+type WebhookFilter struct {
+	UserID     uint64           `json:"userId,omitempty" db:"user_id"`
+	SourceType SourceType       `json:"sourceType,omitempty" db:"source_type"`
+	State      TransactionState `json:"state,omitempty" db:"state"`
+}
+
+// WebhookRequest represents an incoming request to register a webhook
+type WebhookRequest struct {
+	URL    string        `json:"url" binding:"required"`
+	Events []string      `json:"events,omitempty"`
+	Filter WebhookFilter `json:"filter"`
+}
+
+// WebhookDelivery is an event queued for, or delivered to, a webhook
+type WebhookDelivery struct {
+	ID        uint64                `json:"id" db:"id"`
+	WebhookID uint64                `json:"webhookId" db:"webhook_id"`
+	EventType string                `json:"eventType" db:"event_type"`
+	Payload   json.RawMessage       `json:"payload" db:"payload"`
+	Status    WebhookDeliveryStatus `json:"status" db:"status"`
+	Attempts  int                   `json:"attempts" db:"attempts"`
+	// NextAttemptAt is when a pending delivery is attempted next
+	NextAttemptAt *time.Time `json:"nextAttemptAt,omitempty" db:"next_attempt_at"`
+	// LastStatusCode and LastError describe the outcome of the last attempt
+	LastStatusCode int        `json:"lastStatusCode,omitempty" db:"last_status_code"`
+	LastError      string     `json:"lastError,omitempty" db:"last_error"`
+	CreatedAt      time.Time  `json:"createdAt" db:"created_at"`
+	DeliveredAt    *time.Time `json:"deliveredAt,omitempty" db:"delivered_at"`
+	// URL and Secret are those of the webhook when the delivery is claimed
+	URL    string `json:"-"`
+	Secret string `json:"-"`
+}
+
+// WebhookPayload is the body posted to a webhook. Retries of a delivery
+// share its ID, so receivers can deduplicate by it.
+type WebhookPayload struct {
+	ID        uint64          `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// WebhookDeliveryStatus represents the lifecycle status of a delivery
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "failed"
+)
+
+// IsValid checks if the delivery status is valid
+func (ds WebhookDeliveryStatus) IsValid() bool {
+	return ds == WebhookDeliveryPending || ds == WebhookDeliverySucceeded || ds == WebhookDeliveryFailed
+}
+
+// WebhookDeliveryPage is a page of a webhook's deliveries, newest first, with
+// the total number of matches
+type WebhookDeliveryPage struct {
+	Deliveries []*WebhookDelivery `json:"deliveries"`
+	Total      int                `json:"total"`
+	Limit      int                `json:"limit"`
+	Offset     int                `json:"offset"`
+}
+
+// BulkJobType identifies the operation of a bulk admin job
+type BulkJobType string
+
+const (
+	BulkJobFreezeUsers        BulkJobType = "freeze_users"
+	BulkJobAdjustBalances     BulkJobType = "adjust_balances"
+	BulkJobRedeliverWebhooks  BulkJobType = "redeliver_webhooks"
+	BulkJobExportTransactions BulkJobType = "export_transactions"
+)
+
+// BulkJobStatus represents the lifecycle status of a bulk admin job
+type BulkJobStatus string
+
+const (
+	BulkJobQueued    BulkJobStatus = "queued"
+	BulkJobRunning   BulkJobStatus = "running"
+	BulkJobCompleted BulkJobStatus = "completed"
+	// BulkJobCancelled jobs were interrupted by a shutdown; their processed
+	// items stay applied
+	BulkJobCancelled BulkJobStatus = "cancelled"
+)
+
+// BulkJob is an admin operation applied to many items in the background
+type BulkJob struct {
+	ID     string        `json:"id"`
+	Type   BulkJobType   `json:"type"`
+	Status BulkJobStatus `json:"status"`
+	// Total is the number of items; Processed of them were handled so far,
+	// either successfully or not
+	Total     int `json:"total"`
+	Processed int `json:"processed"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	// Errors lists the first failed items
+	Errors     []BulkJobError `json:"errors"`
+	CreatedAt  time.Time      `json:"createdAt"`
+	StartedAt  *time.Time     `json:"startedAt,omitempty"`
+	FinishedAt *time.Time     `json:"finishedAt,omitempty"`
+}
+
+// BulkJobError describes an item a bulk job failed to process
+type BulkJobError struct {
+	Item  string `json:"item"`
+	Error string `json:"error"`
+}
+
+// AsyncTransactionStatus is the processing status of a transaction
+type AsyncTransactionStatus string
+
+const (
+	// AsyncTransactionPending transactions are queued or being processed
+	AsyncTransactionPending   AsyncTransactionStatus = "pending"
+	AsyncTransactionProcessed AsyncTransactionStatus = "processed"
+	AsyncTransactionFailed    AsyncTransactionStatus = "failed"
+)
+
+// AsyncTransaction is the processing status of a transaction submitted for
+// asynchronous processing, or found in the ledger
+type AsyncTransaction struct {
+	TransactionID string                 `json:"transactionId"`
+	UserID        uint64                 `json:"userId"`
+	Status        AsyncTransactionStatus `json:"status"`
+	// Result is set once the transaction was processed
+	Result *TransactionResult `json:"result,omitempty"`
+	// Err is the reason a failed transaction was rejected
+	Err error `json:"-"`
+	// SubmittedAt is not set for the transactions found in the ledger only
+	SubmittedAt *time.Time `json:"submittedAt,omitempty"`
+	FinishedAt  *time.Time `json:"finishedAt,omitempty"`
+}
+
+// BulkFreezeRequest represents a request to freeze a list of users
+type BulkFreezeRequest struct {
+	UserIDs []uint64 `json:"userIds" binding:"required"`
+}
+
+// BulkAdjustmentRequest represents a request to apply the same balance
+// adjustment to a list of users. Each user's adjustment is recorded as a
+// server transaction with the ID "<adjustmentId>:<userId>", so resubmitting
+// the request does not apply it twice. With system accounts it is recorded
+// instead as a transfer with the ID "<adjustmentId>:<userId>" between the
+// user and Counterparty, the house by default.
+type BulkAdjustmentRequest struct {
+	AdjustmentID string            `json:"adjustmentId" binding:"required"`
+	UserIDs      []uint64          `json:"userIds" binding:"required"`
+	State        string            `json:"state" binding:"required"`
+	Amount       string            `json:"amount" binding:"required"`
+	Currency     string            `json:"currency,omitempty"`
+	Counterparty SystemAccountKind `json:"counterparty,omitempty"`
+}
+
+// BulkRedeliveryRequest represents a request to deliver again the webhook
+// deliveries created in [From, To), for one webhook or, when WebhookID is
+// zero, for every active webhook
+type BulkRedeliveryRequest struct {
+	WebhookID uint64    `json:"webhookId,omitempty"`
+	From      time.Time `json:"from" binding:"required"`
+	To        time.Time `json:"to" binding:"required"`
+}
+
+// BulkExportRequest represents a request to export the transaction
+// histories of a list of users, created in [From, To) when given, as the
+// export Name
+type BulkExportRequest struct {
+	Name    string     `json:"name" binding:"required"`
+	UserIDs []uint64   `json:"userIds" binding:"required"`
+	From    *time.Time `json:"from,omitempty"`
+	To      *time.Time `json:"to,omitempty"`
+}
+
+// RestoreMarker records the contents of the database when a backup is taken,
+// so that a restore of the backup can be verified against it. The marker is
+// stored in the database itself and therefore travels with the backup.
+type RestoreMarker struct {
+	Name string `json:"name"`
+	// SchemaVersion is the schema version the database had been migrated to
+	SchemaVersion int `json:"schemaVersion"`
+	// WALPosition is the write-ahead log position the marker was recorded at;
+	// a point-in-time restore up to it includes the marker
+	WALPosition string              `json:"walPosition"`
+	Fingerprint DatabaseFingerprint `json:"fingerprint"`
+	CreatedAt   time.Time           `json:"createdAt"`
+}
+
+// DatabaseFingerprint summarizes the contents of the database
+type DatabaseFingerprint struct {
+	Tables   []TableFingerprint `json:"tables"`
+	Balances BalanceConsistency `json:"balances"`
+}
+
+// TableFingerprint is the row count of a table and an order-independent
+// checksum of its rows
+type TableFingerprint struct {
+	Table    string `json:"table"`
+	Rows     int64  `json:"rows"`
+	Checksum string `json:"checksum"`
+}
+
+// BalanceConsistency counts the balances breaking the ledger invariants
+type BalanceConsistency struct {
+	// Negative counts the user and wallet balances below zero
+	Negative int64 `json:"negative"`
+	// LedgerMismatches counts the user and wallet balances that differ from
+	// the balance recorded by their latest transaction. Balances with a
+	// cancelled transaction are skipped, since cancellations move the balance
+	// without recording a transaction.
+	LedgerMismatches int64 `json:"ledgerMismatches"`
+}
+
+// RestoreMarkerRequest represents a request to record a restore marker
+type RestoreMarkerRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// RestoreVerificationReport is the outcome of verifying a restored database
+// against a restore marker
+type RestoreVerificationReport struct {
+	Name          string              `json:"name"`
+	CreatedAt     time.Time           `json:"createdAt"`
+	Valid         bool                `json:"valid"`
+	SchemaVersion SchemaVersionResult `json:"schemaVersion"`
+	// Tables and Balances are only compared when the schema versions match
+	Tables   []TableResult  `json:"tables,omitempty"`
+	Balances *BalanceResult `json:"balances,omitempty"`
+}
+
+// SchemaVersionResult compares the schema version of the restored database
+// with the marker's
+type SchemaVersionResult struct {
+	Valid    bool `json:"valid"`
+	Expected int  `json:"expected"`
+	Actual   int  `json:"actual"`
+}
+
+// TableResult compares the fingerprint of a restored table with the marker's
+type TableResult struct {
+	Table    string            `json:"table"`
+	Valid    bool              `json:"valid"`
+	Expected TableFingerprint  `json:"expected"`
+	Actual   *TableFingerprint `json:"actual,omitempty"`
+}
+
+// BalanceResult compares the balance consistency of the restored database
+// with the marker's
+type BalanceResult struct {
+	Valid    bool               `json:"valid"`
+	Expected BalanceConsistency `json:"expected"`
+	Actual   BalanceConsistency `json:"actual"`
+}
+
+// FeeKind is how a fee rule computes the fee of a transaction
+type FeeKind string
+
+const (
+	// FeeKindPercentage charges Rate percent of the amount
+	FeeKindPercentage FeeKind = "percentage"
+	// FeeKindFlat charges Amount whatever the amount
+	FeeKindFlat FeeKind = "flat"
+	// FeeKindTiered charges the rate and flat amount of the tier the amount
+	// falls in
+	FeeKindTiered FeeKind = "tiered"
+)
+
+// IsValid checks if the fee kind is valid
+func (fk FeeKind) IsValid() bool {
+	return fk == FeeKindPercentage || fk == FeeKindFlat || fk == FeeKindTiered
+}
+
+// FeeRule is the fee charged for the transactions of a source type and state
+type FeeRule struct {
+	SourceType SourceType       `json:"sourceType" db:"source_type"`
+	State      TransactionState `json:"state" db:"state"`
+	Kind       FeeKind          `json:"kind" db:"kind"`
+	// Rate is the percentage of the amount charged by percentage rules
+	Rate decimal.Decimal `json:"rate" db:"rate"`
+	// Amount is the fee charged by flat rules
+	Amount decimal.Decimal `json:"amount" db:"amount"`
+	// Tiers are the tiers of tiered rules, in ascending order of From
+	Tiers     []FeeTier `json:"tiers,omitempty" db:"tiers"`
+	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+// FeeTier applies to the amounts from From, inclusive, up to the From of the
+// next tier. Amounts below the first tier are charged no fee.
+type FeeTier struct {
+	From decimal.Decimal `json:"from"`
+	// Rate is a percentage of the amount, charged on top of Amount
+	Rate   decimal.Decimal `json:"rate"`
+	Amount decimal.Decimal `json:"amount"`
+}
+
+// Fee returns the fee the rule charges for amount, rounded to cents
+func (r *FeeRule) Fee(amount decimal.Decimal) decimal.Decimal {
+	var rate, flat decimal.Decimal
+	switch r.Kind {
+	case FeeKindPercentage:
+		rate = r.Rate
+	case FeeKindFlat:
+		flat = r.Amount
+	case FeeKindTiered:
+		for _, tier := range r.Tiers {
+			if amount.LessThan(tier.From) {
+				break
+			}
+			rate, flat = tier.Rate, tier.Amount
+		}
+	}
+	return amount.Mul(rate).Div(decimal.NewFromInt(100)).Add(flat).Round(2)
+}
+
+// FeeRuleRequest represents an incoming request to set the fee rule of a
+// source type and state
+type FeeRuleRequest struct {
+	Kind   FeeKind         `json:"kind" binding:"required"`
+	Rate   decimal.Decimal `json:"rate"`
+	Amount decimal.Decimal `json:"amount"`
+	Tiers  []FeeTier       `json:"tiers,omitempty"`
+}
+
+// SystemAccountKind names a system account
+type SystemAccountKind string
+
+const (
+	// SystemAccountHouse collects fees and is the counterparty of corrections
+	SystemAccountHouse SystemAccountKind = "house"
+	// SystemAccountPromo funds promotional credits
+	SystemAccountPromo SystemAccountKind = "promo"
+	// SystemAccountEscheat holds the balances swept from dormant accounts
+	SystemAccountEscheat SystemAccountKind = "escheat"
+)
+
+// SystemAccountKinds lists the system accounts every deployment has
+var SystemAccountKinds = []SystemAccountKind{SystemAccountHouse, SystemAccountPromo, SystemAccountEscheat}
+
+// IsValid checks if the system account kind is valid
+func (k SystemAccountKind) IsValid() bool {
+	return k == SystemAccountHouse || k == SystemAccountPromo || k == SystemAccountEscheat
+}
+
+// SystemAccount is a user owned by the operator rather than a player. It is
+// the explicit counterparty of the balance movements the operator makes, so
+// that they sum to zero across accounts.
+type SystemAccount struct {
+	Kind   SystemAccountKind `json:"kind" db:"kind"`
+	UserID uint64            `json:"userId" db:"user_id"`
+	// Balance is only loaded where needed; system accounts may go negative
+	Balance   decimal.Decimal `json:"balance" db:"-"`
+	CreatedAt time.Time       `json:"createdAt" db:"created_at"`
+}
+
+// AdjustmentDirection tells credits apart from debits of a balance adjustment
+type AdjustmentDirection string
+
+const (
+	AdjustmentCredit AdjustmentDirection = "credit"
+	AdjustmentDebit  AdjustmentDirection = "debit"
+)
+
+// IsValid checks if the adjustment direction is valid
+func (d AdjustmentDirection) IsValid() bool {
+	return d == AdjustmentCredit || d == AdjustmentDebit
+}
+
+// State returns the transaction state moving the balance in the direction
+func (d AdjustmentDirection) State() TransactionState {
+	if d == AdjustmentDebit {
+		return StateLose
+	}
+	return StateWin
+}
+
+// BalanceAdjustmentRequest represents an operator's request to credit or
+// debit a user by hand
+type BalanceAdjustmentRequest struct {
+	// AdjustmentID identifies the adjustment for replays
+	AdjustmentID string              `json:"adjustmentId" binding:"required"`
+	Direction    AdjustmentDirection `json:"direction" binding:"required"`
+	Amount       string              `json:"amount" binding:"required"`
+	// Currency is the optional ISO 4217 code of the wallet to adjust; the
+	// base currency when empty
+	Currency string `json:"currency,omitempty"`
+	Reason   string `json:"reason" binding:"required"`
+	// Actor names the operator making the adjustment when the request is not
+	// authenticated with a token
+	Actor string `json:"actor,omitempty"`
+}
+
+// BalanceAdjustment is the audit record of a manual balance adjustment. The
+// adjustment itself is recorded as a transaction of the user under
+// TransactionID.
+type BalanceAdjustment struct {
+	ID            uint64              `json:"id" db:"id"`
+	AdjustmentID  string              `json:"adjustmentId" db:"adjustment_id"`
+	UserID        uint64              `json:"userId" db:"user_id"`
+	TransactionID string              `json:"transactionId" db:"transaction_id"`
+	Direction     AdjustmentDirection `json:"direction" db:"direction"`
+	Amount        decimal.Decimal     `json:"amount" db:"amount"`
+	// Currency is empty for the base currency
+	Currency string `json:"currency,omitempty" db:"currency"`
+	Reason   string `json:"reason" db:"reason"`
+	// Actor is the operator who made the adjustment
+	Actor         string          `json:"actor" db:"actor"`
+	BalanceBefore decimal.Decimal `json:"balanceBefore" db:"balance_before"`
+	BalanceAfter  decimal.Decimal `json:"balanceAfter" db:"balance_after"`
+	CreatedAt     time.Time       `json:"createdAt" db:"created_at"`
+	// Replayed is set when the adjustment had already been made and the
+	// original record is returned
+	Replayed bool `json:"replayed,omitempty" db:"-"`
+}
+
+// AuditOperation is the kind of balance-affecting operation an audit entry
+// records. The operations writing a transaction are named after its type.
+type AuditOperation string
+
+const (
+	AuditOperationTransaction  AuditOperation = "transaction"
+	AuditOperationRefund       AuditOperation = "refund"
+	AuditOperationTransfer     AuditOperation = "transfer"
+	AuditOperationFee          AuditOperation = "fee"
+	AuditOperationAdjustment   AuditOperation = "adjustment"
+	AuditOperationCancellation AuditOperation = "cancellation"
+	AuditOperationSettlement   AuditOperation = "settlement"
+	// AuditOperationRepair sets a drifted balance to the one recomputed from
+	// the transactions
+	AuditOperationRepair AuditOperation = "repair"
+)
+
+// AuditEntry is an entry of the append-only audit log, recording how an
+// operation moved a balance
+type AuditEntry struct {
+	ID        uint64         `json:"id" db:"id"`
+	UserID    uint64         `json:"userId" db:"user_id"`
+	Operation AuditOperation `json:"operation" db:"operation"`
+	// TransactionID is the transaction written or, for cancellations,
+	// cancelled by the operation
+	TransactionID string `json:"transactionId" db:"transaction_id"`
+	// Change is the signed amount the operation moved the balance by
+	Change decimal.Decimal `json:"change" db:"change"`
+	// Currency is empty for the base currency
+	Currency      string          `json:"currency,omitempty" db:"currency"`
+	BalanceBefore decimal.Decimal `json:"balanceBefore" db:"balance_before"`
+	BalanceAfter  decimal.Decimal `json:"balanceAfter" db:"balance_after"`
+	// Actor is the operator behind the operation, when known
+	Actor     string    `json:"actor,omitempty" db:"actor"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
+
+// AuditLogPage is a page of audit entries
+type AuditLogPage struct {
+	Entries []*AuditEntry `json:"entries"`
+	Total   int           `json:"total"`
+	Limit   int           `json:"limit"`
+	Offset  int           `json:"offset"`
+}
+
+// BalanceDrift is a balance that differs from the one recomputed from the
+// user's transactions
+type BalanceDrift struct {
+	UserID uint64 `json:"userId"`
+	// Currency is empty for the base currency
+	Currency          string          `json:"currency,omitempty"`
+	StoredBalance     decimal.Decimal `json:"storedBalance"`
+	RecomputedBalance decimal.Decimal `json:"recomputedBalance"`
+	// Drift is the stored balance minus the recomputed one
+	Drift decimal.Decimal `json:"drift"`
+	// Repaired is set when the stored balance was set to the recomputed one
+	Repaired bool `json:"repaired"`
+}
+
+// BalanceCheckReport is the outcome of recomputing the balances of every
+// user from their transactions
+type BalanceCheckReport struct {
+	CheckedAt time.Time `json:"checkedAt"`
+	// Checked is the number of balances compared, wallets included
+	Checked int `json:"checked"`
+	// Unverifiable counts the base currency balances that cannot be
+	// recomputed, because the user's first transaction was recorded without
+	// its balance after
+	Unverifiable int             `json:"unverifiable"`
+	Drifts       []*BalanceDrift `json:"drifts"`
+	Repaired     int             `json:"repaired"`
+}
+
+// StatementEntry is a transaction as listed in the statement of a source
+// system, e.g. a payment provider or a game platform
+type StatementEntry struct {
+	TransactionID string          `json:"transactionId"`
+	Amount        decimal.Decimal `json:"amount"`
+	// State is compared with the ledger's when the statement lists it
+	State TransactionState `json:"state,omitempty"`
+}
+
+// ReconciliationMismatch is a statement entry whose transaction the ledger
+// recorded differently
+type ReconciliationMismatch struct {
+	Statement StatementEntry `json:"statement"`
+	Ledger    *Transaction   `json:"ledger"`
+	// Differences names what differs: amount, state, sourceType or
+	// cancelled
+	Differences []string `json:"differences"`
+}
+
+// ReconciliationReport is the outcome of matching the statement of a source
+// system with the transactions of the source type created in [From, To)
+type ReconciliationReport struct {
+	SourceType SourceType `json:"sourceType"`
+	From       time.Time  `json:"from"`
+	To         time.Time  `json:"to"`
+	// Reconciled is set when every entry matched and nothing is extra
+	Reconciled bool `json:"reconciled"`
+	Entries    int  `json:"entries"`
+	Matched    int  `json:"matched"`
+	// Missing are the statement entries the ledger has no transaction for
+	Missing    []StatementEntry         `json:"missing"`
+	Mismatched []ReconciliationMismatch `json:"mismatched"`
+	// ExtraCount is the number of transactions in the range the statement
+	// does not list, of which Extra holds the first ones, newest first
+	ExtraCount int            `json:"extraCount"`
+	Extra      []*Transaction `json:"extra"`
 }
@@ -2,7 +2,9 @@ package entities
 
 import (
 	"testing"
+	"time"
 
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -135,3 +137,60 @@ func TestBalanceResponse_Structure(t *testing.T) {
 	assert.Equal(t, uint64(123), response.UserID)
 	assert.Equal(t, "100.50", response.Balance)
 }
+
+func TestFeeRule_Fee(t *testing.T) {
+	tiered := &FeeRule{Kind: FeeKindTiered, Tiers: []FeeTier{
+		{From: decimal.NewFromInt(10), Rate: decimal.NewFromInt(2)},
+		{From: decimal.NewFromInt(100), Rate: decimal.NewFromInt(1), Amount: decimal.RequireFromString("0.50")},
+	}}
+
+	tests := []struct {
+		name   string
+		rule   *FeeRule
+		amount string
+		want   string
+	}{
+		{"percentage", &FeeRule{Kind: FeeKindPercentage, Rate: decimal.RequireFromString("2.5")}, "40.00", "1"},
+		{"percentage rounded to cents", &FeeRule{Kind: FeeKindPercentage, Rate: decimal.RequireFromString("1.5")}, "0.99", "0.01"},
+		{"flat", &FeeRule{Kind: FeeKindFlat, Amount: decimal.RequireFromString("0.25")}, "1000.00", "0.25"},
+		{"below the first tier", tiered, "9.99", "0"},
+		{"first tier", tiered, "50.00", "1"},
+		{"tier bounds are inclusive", tiered, "100.00", "1.5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fee := tt.rule.Fee(decimal.RequireFromString(tt.amount))
+			assert.True(t, fee.Equal(decimal.RequireFromString(tt.want)), "got %s", fee)
+		})
+	}
+}
+
+func TestTransaction_BalanceChangeSince(t *testing.T) {
+	midnight := time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC)
+	before, after := midnight.Add(-time.Hour), midnight.Add(time.Hour)
+
+	tests := []struct {
+		name        string
+		transaction Transaction
+		want        string
+	}{
+		{"applied before", Transaction{State: StateWin, CreatedAt: before}, "0"},
+		{"applied since", Transaction{State: StateWin, CreatedAt: after}, "10"},
+		{"losses take from the balance", Transaction{State: StateLose, CreatedAt: after}, "-10"},
+		{"pending", Transaction{State: StateWin, CreatedAt: after, Pending: true}, "0"},
+		{"settled since", Transaction{State: StateWin, CreatedAt: before, SettledAt: &after}, "10"},
+		{"settled before", Transaction{State: StateWin, CreatedAt: before, SettledAt: &before}, "0"},
+		{"cancelled since", Transaction{State: StateWin, CreatedAt: before, Cancelled: true, CancelledAt: &after}, "-10"},
+		{"applied and cancelled since", Transaction{State: StateLose, CreatedAt: after, Cancelled: true, CancelledAt: &after}, "0"},
+		{"cancelled before", Transaction{State: StateWin, CreatedAt: before, Cancelled: true, CancelledAt: &before}, "0"},
+		{"cancelled at an unknown time", Transaction{State: StateWin, CreatedAt: after, Cancelled: true}, "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.transaction.Amount = decimal.NewFromInt(10)
+			assert.Equal(t, tt.want, tt.transaction.BalanceChangeSince(midnight).String())
+		})
+	}
+}
@@ -2,22 +2,488 @@ package repositories
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"transaction-service/internal/domain/entities"
 
 	"github.com/shopspring/decimal"
 )
 
+var (
+	// ErrNotFound is returned when the requested record does not exist
+	ErrNotFound = errors.New("record not found")
+	// ErrConflict is returned when a write collides with an existing record
+	ErrConflict = errors.New("record already exists")
+	// ErrUnavailable is returned when the storage cannot be reached or
+	// refused the operation for a reason that may pass, such as a lost
+	// connection or a deadlock
+	ErrUnavailable = errors.New("storage unavailable")
+)
+
+// UnitOfWork runs a function within a single database transaction. Repository
+// calls made with the context passed to fn take part in that transaction, and
+// it is committed only if fn returns nil. Nested calls join the outer
+// transaction.
+type UnitOfWork interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type serializableKey struct{}
+
+// WithSerializable asks the units of work started with the returned context
+// to run under SERIALIZABLE isolation. Storages that run units of work one at
+// a time ignore it.
+func WithSerializable(ctx context.Context) context.Context {
+	return context.WithValue(ctx, serializableKey{}, true)
+}
+
+// Serializable reports whether units of work started with ctx must run under
+// SERIALIZABLE isolation
+func Serializable(ctx context.Context) bool {
+	serializable, _ := ctx.Value(serializableKey{}).(bool)
+	return serializable
+}
+
+type snapshotReadKey struct{}
+
+// WithSnapshotRead asks the units of work started with the returned context
+// to run read-only under REPEATABLE READ isolation, so that their reads see
+// one snapshot of the database without locking rows against writers.
+// Storages that run units of work one at a time ignore it.
+func WithSnapshotRead(ctx context.Context) context.Context {
+	return context.WithValue(ctx, snapshotReadKey{}, true)
+}
+
+// SnapshotRead reports whether units of work started with ctx must run
+// read-only under REPEATABLE READ isolation
+func SnapshotRead(ctx context.Context) bool {
+	snapshotRead, _ := ctx.Value(snapshotReadKey{}).(bool)
+	return snapshotRead
+}
+
 // UserRepository defines the interface for user data operations
 type UserRepository interface {
 	GetByID(ctx context.Context, userID uint64) (*entities.User, error)
+	// GetByIDForUpdate locks the user until the ambient unit of work ends
+	GetByIDForUpdate(ctx context.Context, userID uint64) (*entities.User, error)
 	UpdateBalance(ctx context.Context, userID uint64, newBalance decimal.Decimal) error
 	Create(ctx context.Context, user *entities.User) error
+	// List returns a page of users ordered by ID, along with the total number
+	// of users
+	List(ctx context.Context, limit, offset int) ([]*entities.User, int, error)
+	UpdateStatus(ctx context.Context, userID uint64, status entities.UserStatus) error
+	UpdateJurisdiction(ctx context.Context, userID uint64, jurisdiction string) error
+	UpdateCreditLimit(ctx context.Context, userID uint64, creditLimit decimal.Decimal) error
+	// LockIdleSince locks and returns up to limit active users that are not
+	// dormant, were created before since and have no transaction created at
+	// or after since, skipping users locked by others. The users stay locked
+	// until the ambient unit of work ends.
+	LockIdleSince(ctx context.Context, since time.Time, limit int) ([]*entities.User, error)
+	// UpdateDormancy flags the user as dormant at dormantAt; nil clears the flag
+	UpdateDormancy(ctx context.Context, userID uint64, dormantAt *time.Time) error
+}
+
+// WalletRepository defines the interface for the balances users hold in
+// currencies other than the base currency
+type WalletRepository interface {
+	// GetForUpdate returns the user's wallet in currency, creating an empty
+	// one first if needed, and locks it until the ambient unit of work ends
+	GetForUpdate(ctx context.Context, userID uint64, currency string) (*entities.Wallet, error)
+	UpdateBalance(ctx context.Context, userID uint64, currency string, newBalance decimal.Decimal) error
+	// ListByUser returns the user's wallets ordered by currency
+	ListByUser(ctx context.Context, userID uint64) ([]*entities.Wallet, error)
 }
 
 // TransactionRepository defines the interface for transaction data operations
 type TransactionRepository interface {
+	// Create returns ErrConflict if the transaction ID is already used,
+	// which is how concurrent requests with the same ID are told apart
 	Create(ctx context.Context, transaction *entities.Transaction) error
 	ExistsByTransactionID(ctx context.Context, transactionID string) (bool, error)
+	// GetByTransactionID returns ErrNotFound if no transaction has the external ID
+	GetByTransactionID(ctx context.Context, transactionID string) (*entities.Transaction, error)
+	// GetByTransactionIDForUpdate is GetByTransactionID locking the
+	// transaction until the ambient unit of work ends
+	GetByTransactionIDForUpdate(ctx context.Context, transactionID string) (*entities.Transaction, error)
 	GetByUserID(ctx context.Context, userID uint64) ([]*entities.Transaction, error)
+	// NetChangeSince returns how much the user's base currency transactions
+	// moved the balance at or after since, as summed by
+	// Transaction.BalanceChangeSince: transactions count from when they were
+	// settled, if they were pending, and their cancellations count from
+	// when they were cancelled
+	NetChangeSince(ctx context.Context, userID uint64, since time.Time) (decimal.Decimal, error)
+	// VelocitySince totals the user's uncancelled standard transactions
+	// created at or after since
+	VelocitySince(ctx context.Context, userID uint64, since time.Time) (Velocity, error)
+	// MostActiveUsers returns the IDs of up to limit users with the most
+	// transactions created at or after since, most active first
+	MostActiveUsers(ctx context.Context, since time.Time, limit int) ([]uint64, error)
+	// Search returns a page of transactions matching the filter, newest first,
+	// along with the total number of matches
+	Search(ctx context.Context, filter TransactionFilter) ([]*entities.Transaction, int, error)
+	// SummarizeRound totals the user's transactions of a round in a wallet
+	// currency, empty for the base currency
+	SummarizeRound(ctx context.Context, userID uint64, roundID, currency string) (RoundTotals, error)
+	// SummarizeDays totals the user's uncancelled transactions in a wallet
+	// currency, empty for the base currency, whose business time is in
+	// [from, to) by UTC day, source type and state, in that order. Fees are
+	// left out.
+	SummarizeDays(ctx context.Context, userID uint64, currency string, from, to time.Time) ([]DayTotals, error)
+	// LockLatestOddUncancelled locks and returns the newest uncancelled
+	// transactions with an odd ID, skipping rows locked by other workers.
+	// Refunds, refunded transactions, transfer legs, fees, adjustments and
+	// pending transactions are left out.
+	LockLatestOddUncancelled(ctx context.Context, limit int) ([]*entities.Transaction, error)
+	MarkCancelled(ctx context.Context, id uint64, cancelledAt time.Time) error
+	// MarkReversed links a transaction to the refund reversing it. It
+	// returns ErrNotFound if the transaction is missing or already reversed.
+	MarkReversed(ctx context.Context, id uint64, reversedBy string) error
+	// CheckUniqueIDs checks the transactions created in [from, to) for
+	// transaction IDs recorded more than once, returning up to limit of them
+	CheckUniqueIDs(ctx context.Context, from, to time.Time, limit int) (*UniquenessCheck, error)
+	// SumPending returns the total of the user's uncancelled base currency
+	// transactions awaiting settlement
+	SumPending(ctx context.Context, userID uint64) (decimal.Decimal, error)
+	// ListPendingBefore returns up to limit of the oldest pending
+	// transactions created before before, without locking them
+	ListPendingBefore(ctx context.Context, before time.Time, limit int) ([]*entities.Transaction, error)
+	// LockPendingBefore locks and returns up to limit of the oldest pending
+	// transactions created before before, skipping rows locked by other
+	// workers
+	LockPendingBefore(ctx context.Context, before time.Time, limit int) ([]*entities.Transaction, error)
+	// MarkSettled records the settlement of a pending transaction. It returns
+	// ErrNotFound if the transaction is missing or not pending.
+	MarkSettled(ctx context.Context, id uint64, settledAt time.Time) error
+}
+
+// HoldRepository defines the interface for balance hold operations
+type HoldRepository interface {
+	Create(ctx context.Context, hold *entities.Hold) error
+	// GetByHoldIDForUpdate returns ErrNotFound if no hold has the external ID,
+	// and otherwise locks the hold until the ambient unit of work ends
+	GetByHoldIDForUpdate(ctx context.Context, holdID string) (*entities.Hold, error)
+	// SumActive returns the total of the user's holds that are held and have
+	// not expired at now
+	SumActive(ctx context.Context, userID uint64, now time.Time) (decimal.Decimal, error)
+	// Settle records the final status of a held hold
+	Settle(ctx context.Context, id uint64, status entities.HoldStatus, capturedAmount *decimal.Decimal, settledAt time.Time) error
+	// Expire marks up to limit holds that are held but expired at now as
+	// expired, skipping holds locked by others, and returns their external IDs
+	Expire(ctx context.Context, now time.Time, limit int) ([]string, error)
+}
+
+// ScheduledTransactionRepository defines the interface for scheduled
+// transaction operations
+type ScheduledTransactionRepository interface {
+	// Create returns ErrConflict if the schedule ID is taken
+	Create(ctx context.Context, schedule *entities.ScheduledTransaction) error
+	// GetByScheduleIDForUpdate returns ErrNotFound if no schedule has the
+	// external ID, and otherwise locks the schedule until the ambient unit of
+	// work ends
+	GetByScheduleIDForUpdate(ctx context.Context, scheduleID string) (*entities.ScheduledTransaction, error)
+	// ListByUser returns the schedules of a user, newest first
+	ListByUser(ctx context.Context, userID uint64) ([]*entities.ScheduledTransaction, error)
+	// ListDue returns the external IDs of up to limit active schedules due at
+	// now, the longest due first
+	ListDue(ctx context.Context, now time.Time, limit int) ([]string, error)
+	// Update records the runs, the next run, the outcome of the last run and
+	// the status of a schedule
+	Update(ctx context.Context, schedule *entities.ScheduledTransaction) error
+}
+
+// BalanceSnapshotRepository defines the interface for the daily closing
+// balances of users
+type BalanceSnapshotRepository interface {
+	// Create keeps the existing snapshot when the user's day is already
+	// snapshotted
+	Create(ctx context.Context, snapshot *entities.BalanceSnapshot) error
+	// ListUnsnapshotted returns the IDs of up to limit users without a
+	// snapshot of day, lowest first
+	ListUnsnapshotted(ctx context.Context, day time.Time, limit int) ([]uint64, error)
+	// ListByUser returns the user's snapshots of the days in [from, to),
+	// oldest first. from and to are midnight UTC.
+	ListByUser(ctx context.Context, userID uint64, from, to time.Time) ([]*entities.BalanceSnapshot, error)
+	// GetLatestBefore returns the user's snapshot of the latest day before
+	// before, which is midnight UTC, or ErrNotFound if there is none
+	GetLatestBefore(ctx context.Context, userID uint64, before time.Time) (*entities.BalanceSnapshot, error)
+}
+
+// AnnotationRepository defines the interface for support annotation operations
+type AnnotationRepository interface {
+	Create(ctx context.Context, annotation *entities.Annotation) error
+	// ListByTarget returns the annotations on a record, oldest first
+	ListByTarget(ctx context.Context, targetType entities.AnnotationTarget, targetID string) ([]*entities.Annotation, error)
+	// ListByUser returns the annotations on a user and on the user's
+	// transactions, oldest first
+	ListByUser(ctx context.Context, userID uint64) ([]*entities.Annotation, error)
+}
+
+// OutboxRepository defines the interface for the events waiting to be published
+type OutboxRepository interface {
+	// Append records an event in the ambient unit of work
+	Append(ctx context.Context, event *entities.OutboxEvent) error
+	// LockUnpublished locks and returns up to limit of the oldest unpublished
+	// events until the ambient unit of work ends. Concurrent callers wait for
+	// each other, so events are handed out in order.
+	LockUnpublished(ctx context.Context, limit int) ([]*entities.OutboxEvent, error)
+	MarkPublished(ctx context.Context, ids []uint64, publishedAt time.Time) error
+}
+
+// RejectionRepository defines the interface for recording rejected
+// transaction attempts and reporting on them
+type RejectionRepository interface {
+	Create(ctx context.Context, rejection *entities.Rejection) error
+	// CountByReason counts the rejections created in [from, to) by source
+	// type and reason
+	CountByReason(ctx context.Context, from, to time.Time) ([]RejectionCount, error)
+	// CountAccepted counts the standard transactions created in [from, to)
+	// by source type
+	CountAccepted(ctx context.Context, from, to time.Time) (map[entities.SourceType]int, error)
+	// List returns a page of the rejections matching the filter, newest
+	// first, along with the total number of matches
+	List(ctx context.Context, filter RejectionFilter) ([]*entities.Rejection, int, error)
+}
+
+// AuditLogRepository defines the interface for the append-only audit log of
+// the balance-affecting operations. Entries are never updated nor deleted.
+type AuditLogRepository interface {
+	Append(ctx context.Context, entry *entities.AuditEntry) error
+	// List returns a page of the entries matching the filter, newest first,
+	// along with the total number of matches
+	List(ctx context.Context, filter AuditLogFilter) ([]*entities.AuditEntry, int, error)
+}
+
+// AuditLogFilter selects audit entries. A zero UserID matches every user.
+type AuditLogFilter struct {
+	UserID uint64
+	From   *time.Time
+	To     *time.Time
+	Limit  int
+	Offset int
+}
+
+// BalanceRecomputation is a stored balance along with the balance recomputed
+// from the user's transactions
+type BalanceRecomputation struct {
+	UserID uint64
+	// Currency is empty for the base currency
+	Currency string
+	Stored   decimal.Decimal
+	// Recomputed is nil when the balance cannot be recomputed
+	Recomputed *decimal.Decimal
+}
+
+// BalanceCheckRepository defines the interface for recomputing balances from
+// the transactions. A base currency balance is the opening balance implied
+// by the balance after the user's first transaction plus the uncancelled
+// transactions; a wallet starts empty.
+type BalanceCheckRepository interface {
+	// Recompute returns the balances of up to limit users with an ID above
+	// afterID, in user ID order, each base currency balance followed by the
+	// user's wallets
+	Recompute(ctx context.Context, afterID uint64, limit int) ([]BalanceRecomputation, error)
+}
+
+// RejectionCount is the number of rejections of a source type for a reason
+type RejectionCount struct {
+	SourceType entities.SourceType
+	Reason     string
+	Count      int
+}
+
+// RejectionFilter describes criteria for listing rejections. Zero values mean
+// the criterion is not applied.
+type RejectionFilter struct {
+	SourceType entities.SourceType
+	Reason     string
+	From       *time.Time
+	To         *time.Time
+	Limit      int
+	Offset     int
+}
+
+// RoundTotals are the sums of a round's transactions. Transactions, Bets and
+// Wins cover the uncancelled transactions; Cancelled counts the others. Fees
+// sums the fees charged for them, which are left out of the other totals.
+type RoundTotals struct {
+	Transactions int
+	Cancelled    int
+	Bets         decimal.Decimal
+	Wins         decimal.Decimal
+	Fees         decimal.Decimal
+}
+
+// DayTotals count and sum the transactions of one source type and state
+// created on a UTC day
+type DayTotals struct {
+	Day        time.Time
+	SourceType entities.SourceType
+	State      entities.TransactionState
+	Count      int
+	Amount     decimal.Decimal
+}
+
+// Velocity totals a user's recent activity. Transactions counts the standard
+// transactions in every currency; Losses sums the base currency ones that are
+// losses. Refunds, transfers, fees and adjustments are left out.
+type Velocity struct {
+	Transactions int
+	Losses       decimal.Decimal
+}
+
+// UniquenessCheck is the outcome of checking a range of transactions for
+// duplicate transaction IDs. Duplicates counts every record of the duplicated
+// IDs, including those created outside the range.
+type UniquenessCheck struct {
+	Transactions int
+	DistinctIDs  int
+	Duplicates   []entities.DuplicateTransactionID
+	// Enforced is set when a valid unique index covers the transaction IDs
+	Enforced bool
+}
+
+// TransactionFilter describes criteria for searching transactions. Zero
+// values mean the criterion is not applied. From and To bound the arrival
+// time rather than the business time, which keeps histories and exports in
+// the order of their cursors.
+type TransactionFilter struct {
+	UserID              uint64
+	TransactionIDPrefix string
+	MinAmount           *decimal.Decimal
+	MaxAmount           *decimal.Decimal
+	SourceType          entities.SourceType
+	State               entities.TransactionState
+	From                *time.Time
+	To                  *time.Time
+	Cancelled           *bool
+	// MetadataKey only matches transactions with the metadata key, whose
+	// value is MetadataValue unless it is nil
+	MetadataKey   string
+	MetadataValue *string
+	Limit         int
+	Offset        int
+	// After continues a search after a transaction, in place of Offset
+	After *TransactionCursor
+}
+
+// TransactionCursor is the position of a transaction in the newest first
+// order of a search
+type TransactionCursor struct {
+	CreatedAt time.Time
+	ID        uint64
+}
+
+// WebhookEvent is an event to deliver to the webhooks whose filter matches it
+type WebhookEvent struct {
+	Type       string
+	UserID     uint64
+	SourceType entities.SourceType
+	State      entities.TransactionState
+	Payload    []byte
+	CreatedAt  time.Time
+}
+
+// WebhookRepository defines the interface for webhooks and their deliveries
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *entities.Webhook) error
+	// GetByID returns ErrNotFound if no active webhook has the ID. The secret
+	// is not returned.
+	GetByID(ctx context.Context, id uint64) (*entities.Webhook, error)
+	// List returns the active webhooks ordered by ID, without their secrets
+	List(ctx context.Context) ([]*entities.Webhook, error)
+	// Deactivate stops deliveries to the webhook and fails its pending ones.
+	// It returns ErrNotFound if no active webhook has the ID.
+	Deactivate(ctx context.Context, id uint64) error
+	// EnqueueDeliveries queues event for every active webhook matching it in
+	// the ambient unit of work and returns how many were queued
+	EnqueueDeliveries(ctx context.Context, event WebhookEvent) (int, error)
+	// ClaimDueDeliveries returns up to limit of the pending deliveries due at
+	// now, with the URL and secret of their webhook, and postpones them to
+	// leaseUntil so concurrent callers do not claim them too
+	ClaimDueDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*entities.WebhookDelivery, error)
+	// UpdateDelivery records the outcome of an attempt
+	UpdateDelivery(ctx context.Context, delivery *entities.WebhookDelivery) error
+	// ListDeliveries returns a page of the webhook's deliveries, newest first,
+	// optionally with the given status, and the total number of matches
+	ListDeliveries(
+		ctx context.Context,
+		webhookID uint64,
+		status entities.WebhookDeliveryStatus,
+		limit, offset int,
+	) ([]*entities.WebhookDelivery, int, error)
+	// ListDeliveryIDs returns the IDs of the deliveries created in [from, to)
+	// for the webhook, or for every active webhook when webhookID is zero,
+	// in ascending order. Deliveries of inactive webhooks are left out.
+	ListDeliveryIDs(ctx context.Context, webhookID uint64, from, to time.Time) ([]uint64, error)
+	// RequeueDeliveries makes the deliveries pending again, due at now with
+	// no attempts, and returns how many were requeued
+	RequeueDeliveries(ctx context.Context, ids []uint64, now time.Time) (int, error)
+}
+
+// FeeRuleRepository defines the interface for the fee rules, one per source
+// type and state
+type FeeRuleRepository interface {
+	// Get returns ErrNotFound if no rule is set for the source type and state
+	Get(ctx context.Context, sourceType entities.SourceType, state entities.TransactionState) (*entities.FeeRule, error)
+	// List returns the rules ordered by source type and state
+	List(ctx context.Context) ([]*entities.FeeRule, error)
+	// Set creates the rule of its source type and state, or replaces it
+	Set(ctx context.Context, rule *entities.FeeRule) error
+	// Delete returns ErrNotFound if no rule is set for the source type and
+	// state
+	Delete(ctx context.Context, sourceType entities.SourceType, state entities.TransactionState) error
+}
+
+// BalanceAdjustmentRepository defines the interface for the audit records of
+// the manual balance adjustments
+type BalanceAdjustmentRepository interface {
+	// Create returns ErrConflict if the adjustment ID is already used
+	Create(ctx context.Context, adjustment *entities.BalanceAdjustment) error
+	// GetByAdjustmentID returns ErrNotFound if no adjustment has the ID
+	GetByAdjustmentID(ctx context.Context, adjustmentID string) (*entities.BalanceAdjustment, error)
+	// ListByUser returns the adjustments of the user, newest first
+	ListByUser(ctx context.Context, userID uint64) ([]*entities.BalanceAdjustment, error)
+}
+
+// RestoreRepository defines the interface for recording and reading the
+// restore markers and the database contents they are compared with
+type RestoreRepository interface {
+	// SchemaVersion returns the schema version the database was migrated to
+	SchemaVersion(ctx context.Context) (int, error)
+	// Fingerprint summarizes the current contents of the database from a
+	// single snapshot
+	Fingerprint(ctx context.Context) (*entities.DatabaseFingerprint, error)
+	// CreateMarker stores a marker, filling in its WAL position. It returns
+	// ErrConflict if a marker with the same name exists.
+	CreateMarker(ctx context.Context, marker *entities.RestoreMarker) error
+	// GetMarker returns ErrNotFound if no marker has the name
+	GetMarker(ctx context.Context, name string) (*entities.RestoreMarker, error)
+	// ListMarkers returns the markers, newest first
+	ListMarkers(ctx context.Context) ([]*entities.RestoreMarker, error)
+}
+
+// SyncCursor is a position in the change feed of the transactions, which is
+// ordered by the database transaction that last changed a transaction, then
+// by its ID
+type SyncCursor struct {
+	Version uint64
+	ID      uint64
+}
+
+// TransactionChange is a transaction as it was last changed, at its position
+// in the change feed
+type TransactionChange struct {
+	Transaction *entities.Transaction
+	Cursor      SyncCursor
+}
+
+// SyncRepository defines the interface for reading the change feed of the
+// transactions
+type SyncRepository interface {
+	// ChangesSince returns up to limit transactions created or changed after
+	// cursor, in feed order. Changes are left out while a database
+	// transaction that could still add changes before them is in progress,
+	// so that a reader following the cursor never skips one.
+	ChangesSince(ctx context.Context, cursor SyncCursor, limit int) ([]TransactionChange, error)
 }
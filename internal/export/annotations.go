@@ -0,0 +1,22 @@
+package export
+
+import (
+	"time"
+
+	"transaction-service/internal/domain/entities"
+)
+
+// AnnotationColumns is the header row of an annotation export
+var AnnotationColumns = []string{"createdAt", "targetType", "targetId", "author", "note"}
+
+// AnnotationRow returns the columns of an annotation in the order of
+// AnnotationColumns
+func AnnotationRow(annotation *entities.Annotation) []string {
+	return []string{
+		annotation.CreatedAt.UTC().Format(time.RFC3339Nano),
+		string(annotation.TargetType),
+		CSVText(annotation.TargetID),
+		CSVText(annotation.Author),
+		CSVText(annotation.Note),
+	}
+}
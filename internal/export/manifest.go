@@ -0,0 +1,287 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ManifestFileName is the name of the manifest written next to the exported files
+const ManifestFileName = "manifest.json"
+
+// ErrExportNotFound is returned when an export or its manifest does not exist
+var ErrExportNotFound = errors.New("export not found")
+
+// ErrInvalidExportName is returned for names that are not a single path element
+var ErrInvalidExportName = errors.New("invalid export name")
+
+// ErrExportExists is returned when creating an export under a name already
+// used, so that a verified export is never overwritten
+var ErrExportExists = errors.New("export already exists")
+
+// Manifest lists the files of an export with their row counts and checksums
+type Manifest struct {
+	Name      string      `json:"name"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Files     []FileEntry `json:"files"`
+}
+
+// FileEntry describes a single exported file. Rows is the number of lines in
+// the file; for CSV files this includes the header.
+type FileEntry struct {
+	Path   string `json:"path"`
+	Rows   int64  `json:"rows"`
+	Bytes  int64  `json:"bytes"`
+	SHA256 string `json:"sha256"`
+}
+
+// Writer produces an export directory and its manifest. Every export job
+// writes its files through a Writer so that the manifest is always complete.
+type Writer struct {
+	dir      string
+	manifest Manifest
+}
+
+// NewWriter creates the directory for the named export under root. It fails
+// with ErrExportExists when the directory already exists.
+func NewWriter(root, name string) (*Writer, error) {
+	if !isValidName(name) {
+		return nil, ErrInvalidExportName
+	}
+
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create export root: %w", err)
+	}
+	dir := filepath.Join(root, name)
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return nil, ErrExportExists
+		}
+		return nil, fmt.Errorf("failed to create export directory: %w", err)
+	}
+
+	return &Writer{
+		dir:      dir,
+		manifest: Manifest{Name: name, Files: []FileEntry{}},
+	}, nil
+}
+
+// Create opens a new file in the export. The file is added to the manifest
+// when it is closed.
+func (w *Writer) Create(name string) (*File, error) {
+	if !isValidName(name) || name == ManifestFileName {
+		return nil, ErrInvalidExportName
+	}
+
+	f, err := os.Create(filepath.Join(w.dir, name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create export file: %w", err)
+	}
+
+	return &File{
+		writer:  w,
+		name:    name,
+		file:    f,
+		counter: newCounter(),
+	}, nil
+}
+
+// Finish writes the manifest. No files may be added afterwards.
+func (w *Writer) Finish() (*Manifest, error) {
+	w.manifest.CreatedAt = time.Now().UTC()
+
+	data, err := json.MarshalIndent(w.manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(w.dir, ManifestFileName), data, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	manifest := w.manifest
+	return &manifest, nil
+}
+
+// File is an export file that counts and hashes everything written to it
+type File struct {
+	writer  *Writer
+	name    string
+	file    *os.File
+	counter *counter
+}
+
+// Write implements io.Writer
+func (f *File) Write(p []byte) (int, error) {
+	n, err := f.file.Write(p)
+	f.counter.Write(p[:n])
+	return n, err
+}
+
+// Close closes the file and records it in the manifest
+func (f *File) Close() error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("failed to close export file: %w", err)
+	}
+	f.writer.manifest.Files = append(f.writer.manifest.Files, f.counter.entry(f.name))
+	return nil
+}
+
+// Abort closes and removes the file, leaving it out of the manifest
+func (f *File) Abort() error {
+	_ = f.file.Close()
+	if err := os.Remove(f.file.Name()); err != nil {
+		return fmt.Errorf("failed to remove export file: %w", err)
+	}
+	return nil
+}
+
+// Exists reports whether the named export exists under root
+func Exists(root, name string) (bool, error) {
+	if !isValidName(name) {
+		return false, ErrInvalidExportName
+	}
+	if _, err := os.Stat(filepath.Join(root, name)); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check export: %w", err)
+	}
+	return true, nil
+}
+
+// FileResult is the verification outcome for a single file
+type FileResult struct {
+	Path     string     `json:"path"`
+	Valid    bool       `json:"valid"`
+	Expected FileEntry  `json:"expected"`
+	Actual   *FileEntry `json:"actual,omitempty"`
+	Error    string     `json:"error,omitempty"`
+}
+
+// VerificationReport is the outcome of verifying an export against its manifest
+type VerificationReport struct {
+	Name      string       `json:"name"`
+	CreatedAt time.Time    `json:"createdAt"`
+	Valid     bool         `json:"valid"`
+	Files     []FileResult `json:"files"`
+}
+
+// Verify recomputes the row counts and checksums of the named export under
+// root and compares them with its manifest
+func Verify(root, name string) (*VerificationReport, error) {
+	if !isValidName(name) {
+		return nil, ErrInvalidExportName
+	}
+
+	dir := filepath.Join(root, name)
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFileName))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrExportNotFound
+		}
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+
+	report := &VerificationReport{
+		Name:      manifest.Name,
+		CreatedAt: manifest.CreatedAt,
+		Valid:     true,
+		Files:     make([]FileResult, 0, len(manifest.Files)),
+	}
+
+	for _, expected := range manifest.Files {
+		result := verifyFile(dir, expected)
+		if !result.Valid {
+			report.Valid = false
+		}
+		report.Files = append(report.Files, result)
+	}
+
+	return report, nil
+}
+
+func verifyFile(dir string, expected FileEntry) FileResult {
+	result := FileResult{Path: expected.Path, Expected: expected}
+	if !isValidName(expected.Path) {
+		result.Error = "invalid path in manifest"
+		return result
+	}
+
+	f, err := os.Open(filepath.Join(dir, expected.Path))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer f.Close()
+
+	c := newCounter()
+	if _, err := io.Copy(c, bufio.NewReader(f)); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	actual := c.entry(expected.Path)
+	result.Actual = &actual
+	result.Valid = actual == expected
+	return result
+}
+
+// counter tracks the size, line count and SHA-256 of a byte stream
+type counter struct {
+	hash  hash.Hash
+	bytes int64
+	rows  int64
+	// partial is set when the last byte written was not a newline
+	partial bool
+}
+
+func newCounter() *counter {
+	return &counter{hash: sha256.New()}
+}
+
+func (c *counter) Write(p []byte) (int, error) {
+	c.hash.Write(p)
+	c.bytes += int64(len(p))
+	c.rows += int64(bytes.Count(p, []byte{'\n'}))
+	if len(p) > 0 {
+		c.partial = p[len(p)-1] != '\n'
+	}
+	return len(p), nil
+}
+
+func (c *counter) entry(path string) FileEntry {
+	rows := c.rows
+	// A final line without a trailing newline still counts as a row
+	if c.partial {
+		rows++
+	}
+	return FileEntry{
+		Path:   path,
+		Rows:   rows,
+		Bytes:  c.bytes,
+		SHA256: hex.EncodeToString(c.hash.Sum(nil)),
+	}
+}
+
+// isValidName accepts a single, non-hidden path element
+func isValidName(name string) bool {
+	return name != "" &&
+		!strings.HasPrefix(name, ".") &&
+		!strings.ContainsAny(name, `/\`) &&
+		filepath.Base(name) == name
+}
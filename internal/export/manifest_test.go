@@ -0,0 +1,167 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeExport(t *testing.T, root string) *Manifest {
+	t.Helper()
+
+	w, err := NewWriter(root, "transactions-2025-01")
+	require.NoError(t, err)
+
+	f, err := w.Create("transactions.csv")
+	require.NoError(t, err)
+	csvWriter := csv.NewWriter(f)
+	require.NoError(t, csvWriter.WriteAll([][]string{
+		{"id", "amount"},
+		{"1", "10.00"},
+		{"2", "5.50"},
+	}))
+	require.NoError(t, f.Close())
+
+	manifest, err := w.Finish()
+	require.NoError(t, err)
+	return manifest
+}
+
+func TestWriter(t *testing.T) {
+	manifest := writeExport(t, t.TempDir())
+
+	require.Len(t, manifest.Files, 1)
+	entry := manifest.Files[0]
+	assert.Equal(t, "transactions.csv", entry.Path)
+	assert.Equal(t, int64(3), entry.Rows)
+	assert.Equal(t, int64(len("id,amount\n1,10.00\n2,5.50\n")), entry.Bytes)
+	assert.Len(t, entry.SHA256, 64)
+}
+
+func TestWriter_Abort(t *testing.T) {
+	root := t.TempDir()
+	w, err := NewWriter(root, "partial")
+	require.NoError(t, err)
+
+	f, err := w.Create("aborted.csv")
+	require.NoError(t, err)
+	_, err = f.Write([]byte("id\n"))
+	require.NoError(t, err)
+	require.NoError(t, f.Abort())
+
+	manifest, err := w.Finish()
+	require.NoError(t, err)
+	assert.Empty(t, manifest.Files)
+	assert.NoFileExists(t, filepath.Join(root, "partial", "aborted.csv"))
+
+	exists, err := Exists(root, "partial")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	_, err = NewWriter(root, "partial")
+	assert.ErrorIs(t, err, ErrExportExists)
+}
+
+func TestVerify(t *testing.T) {
+	t.Run("an untouched export is valid", func(t *testing.T) {
+		root := t.TempDir()
+		writeExport(t, root)
+
+		report, err := Verify(root, "transactions-2025-01")
+		require.NoError(t, err)
+		assert.True(t, report.Valid)
+		require.Len(t, report.Files, 1)
+		assert.True(t, report.Files[0].Valid)
+	})
+
+	t.Run("a modified file is detected", func(t *testing.T) {
+		root := t.TempDir()
+		writeExport(t, root)
+		path := filepath.Join(root, "transactions-2025-01", "transactions.csv")
+		require.NoError(t, os.WriteFile(path, []byte("id,amount\n1,10.00\n2,9.50\n"), 0o644))
+
+		report, err := Verify(root, "transactions-2025-01")
+		require.NoError(t, err)
+		assert.False(t, report.Valid)
+		assert.NotEqual(t, report.Files[0].Expected.SHA256, report.Files[0].Actual.SHA256)
+	})
+
+	t.Run("a missing file is detected", func(t *testing.T) {
+		root := t.TempDir()
+		writeExport(t, root)
+		require.NoError(t, os.Remove(filepath.Join(root, "transactions-2025-01", "transactions.csv")))
+
+		report, err := Verify(root, "transactions-2025-01")
+		require.NoError(t, err)
+		assert.False(t, report.Valid)
+		assert.NotEmpty(t, report.Files[0].Error)
+	})
+
+	t.Run("unknown exports and unsafe names are rejected", func(t *testing.T) {
+		root := t.TempDir()
+
+		_, err := Verify(root, "missing")
+		assert.ErrorIs(t, err, ErrExportNotFound)
+
+		_, err = Verify(root, "../etc")
+		assert.ErrorIs(t, err, ErrInvalidExportName)
+	})
+}
+
+func TestTransactionCSV(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewTransactionCSV(&buf)
+	require.NoError(t, writer.WriteHeader())
+	require.NoError(t, writer.Write(&entities.Transaction{
+		TransactionID: "=1+1",
+		State:         entities.StateWin,
+		Amount:        decimal.NewFromInt(10),
+		CreatedAt:     time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+	}))
+	require.NoError(t, writer.Flush())
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, TransactionColumns, records[0])
+	assert.Equal(t, "'=1+1", records[1][2])
+	assert.Equal(t, "10.00", records[1][6])
+
+	// The entry matches what Verify computes for the same bytes
+	root := t.TempDir()
+	w, err := NewWriter(root, "statement")
+	require.NoError(t, err)
+	f, err := w.Create("user-1.csv")
+	require.NoError(t, err)
+	streamed := NewTransactionCSV(f)
+	require.NoError(t, streamed.WriteHeader())
+	require.NoError(t, streamed.Flush())
+	require.NoError(t, f.Close())
+	manifest, err := w.Finish()
+	require.NoError(t, err)
+	assert.Equal(t, manifest.Files[0], streamed.Entry("user-1.csv"))
+}
+
+func TestCSVText(t *testing.T) {
+	for value, want := range map[string]string{
+		"":            "",
+		"tx-1":        "tx-1",
+		"=1+1":        "'=1+1",
+		"+1":          "'+1",
+		"-1":          "'-1",
+		"@SUM(A1)":    "'@SUM(A1)",
+		"\tcmd":       "'\tcmd",
+		"a=b,c":       "a=b,c",
+		"round \"1\"": "round \"1\"",
+	} {
+		assert.Equal(t, want, CSVText(value), value)
+	}
+}
@@ -0,0 +1,102 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"transaction-service/internal/domain/entities"
+)
+
+// TransactionColumns is the header row of a transaction export
+var TransactionColumns = []string{
+	"createdAt", "occurredAt", "transactionId", "type", "sourceType", "state", "amount", "currency",
+	"balanceAfter", "roundId", "cancelled", "cancelledAt", "reverses", "reversedBy", "transferId", "chargedFor",
+}
+
+// TransactionCSV writes transactions as CSV rows under the TransactionColumns
+// header. Like File, it counts and hashes everything written, so a file
+// streamed rather than kept, e.g. as an HTTP response, still has an entry to
+// check it against.
+type TransactionCSV struct {
+	writer  *csv.Writer
+	counter *counter
+}
+
+// NewTransactionCSV creates a TransactionCSV writing to w. Nothing is written
+// before WriteHeader.
+func NewTransactionCSV(w io.Writer) *TransactionCSV {
+	c := newCounter()
+	return &TransactionCSV{writer: csv.NewWriter(io.MultiWriter(w, c)), counter: c}
+}
+
+// WriteHeader writes the header row
+func (t *TransactionCSV) WriteHeader() error {
+	return t.writer.Write(TransactionColumns)
+}
+
+// Write writes the row of a transaction
+func (t *TransactionCSV) Write(transaction *entities.Transaction) error {
+	return t.writer.Write(TransactionRow(transaction))
+}
+
+// Flush writes the buffered rows, and returns the first error of any write
+func (t *TransactionCSV) Flush() error {
+	t.writer.Flush()
+	return t.writer.Error()
+}
+
+// Entry returns the row count, size and checksum of what was flushed so far,
+// recorded under path
+func (t *TransactionCSV) Entry(path string) FileEntry {
+	return t.counter.entry(path)
+}
+
+// TransactionRow returns the columns of a transaction in the order of
+// TransactionColumns
+func TransactionRow(transaction *entities.Transaction) []string {
+	var occurredAt, balanceAfter, cancelledAt string
+	if transaction.OccurredAt != nil {
+		occurredAt = transaction.OccurredAt.UTC().Format(time.RFC3339Nano)
+	}
+	if transaction.BalanceAfter != nil {
+		balanceAfter = transaction.BalanceAfter.StringFixed(2)
+	}
+	if transaction.CancelledAt != nil {
+		cancelledAt = transaction.CancelledAt.UTC().Format(time.RFC3339Nano)
+	}
+	transactionType := transaction.Type
+	if transactionType == "" {
+		transactionType = entities.TransactionTypeStandard
+	}
+
+	return []string{
+		transaction.CreatedAt.UTC().Format(time.RFC3339Nano),
+		occurredAt,
+		CSVText(transaction.TransactionID),
+		string(transactionType),
+		string(transaction.SourceType),
+		string(transaction.State),
+		transaction.Amount.StringFixed(2),
+		transaction.Currency,
+		balanceAfter,
+		CSVText(transaction.RoundID),
+		strconv.FormatBool(transaction.Cancelled),
+		cancelledAt,
+		CSVText(transaction.Reverses),
+		CSVText(transaction.ReversedBy),
+		CSVText(transaction.TransferID),
+		CSVText(transaction.ChargedFor),
+	}
+}
+
+// CSVText keeps spreadsheets from evaluating client-supplied text as a
+// formula, by prefixing the text a formula starts with by a quote
+func CSVText(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
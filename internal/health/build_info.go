@@ -0,0 +1,47 @@
+package health
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Version is the release of the service. Release builds set it with
+// -ldflags "-X transaction-service/internal/health.Version=v1.2.3".
+var Version = "dev"
+
+// BuildInfo describes the binary serving requests
+type BuildInfo struct {
+	Version string `json:"version"`
+	// Commit is the VCS revision the binary was built from, and CommitTime
+	// when it was committed; both are empty when the build did not record them
+	Commit     string `json:"commit,omitempty"`
+	CommitTime string `json:"commitTime,omitempty"`
+	// Modified is set when the working tree had uncommitted changes
+	Modified  bool   `json:"modified"`
+	GoVersion string `json:"goVersion"`
+}
+
+// ReadBuildInfo returns the build information of the running binary. The
+// revision is recorded by the go command when building inside a repository.
+func ReadBuildInfo() BuildInfo {
+	info := BuildInfo{
+		Version:   Version,
+		GoVersion: runtime.Version(),
+	}
+
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	for _, setting := range build.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			info.Commit = setting.Value
+		case "vcs.time":
+			info.CommitTime = setting.Value
+		case "vcs.modified":
+			info.Modified = setting.Value == "true"
+		}
+	}
+	return info
+}
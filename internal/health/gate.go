@@ -0,0 +1,33 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// Gate holds readiness back until a startup phase, such as the warm-up, is
+// done. Check fails with the reason the gate was created with until Open is
+// called.
+type Gate struct {
+	reason string
+	open   atomic.Bool
+}
+
+// NewGate creates a new closed Gate
+func NewGate(reason string) *Gate {
+	return &Gate{reason: reason}
+}
+
+// Open lets Check pass from now on
+func (g *Gate) Open() {
+	g.open.Store(true)
+}
+
+// Check fails while the gate is closed
+func (g *Gate) Check(ctx context.Context) error {
+	if !g.open.Load() {
+		return errors.New(g.reason)
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Policy determines how a failing dependency affects readiness
+type Policy string
+
+const (
+	// PolicyRequired makes the service unready when the dependency is down
+	PolicyRequired Policy = "required"
+	// PolicyOptional keeps the service ready but reports it as degraded
+	PolicyOptional Policy = "optional"
+)
+
+// IsValid checks if the policy is valid
+func (p Policy) IsValid() bool {
+	return p == PolicyRequired || p == PolicyOptional
+}
+
+// ParsePolicy converts a configuration value into a Policy
+func ParsePolicy(value string) (Policy, error) {
+	policy := Policy(value)
+	if !policy.IsValid() {
+		return "", fmt.Errorf("invalid readiness policy %q", value)
+	}
+	return policy, nil
+}
+
+// Status represents the aggregated readiness or liveness of the service
+type Status string
+
+const (
+	StatusReady    Status = "ready"
+	StatusDegraded Status = "degraded"
+	StatusUnready  Status = "unready"
+)
+
+// Liveness statuses, reported for the checks of a liveness Checker
+const (
+	StatusAlive   Status = "alive"
+	StatusStalled Status = "stalled"
+)
+
+// CheckFunc verifies that a dependency is reachable
+type CheckFunc func(ctx context.Context) error
+
+// DependencyReport describes the result of a single dependency check
+type DependencyReport struct {
+	Name      string `json:"name"`
+	Policy    Policy `json:"policy"`
+	Up        bool   `json:"up"`
+	Error     string `json:"error,omitempty"`
+	LatencyMs int64  `json:"latencyMs"`
+}
+
+// Report is the aggregated readiness of all registered dependencies
+type Report struct {
+	Status       Status             `json:"status"`
+	Dependencies []DependencyReport `json:"dependencies"`
+}
+
+type dependency struct {
+	name   string
+	policy Policy
+	check  CheckFunc
+}
+
+// Checker evaluates registered dependencies and weights them by policy
+type Checker struct {
+	mu        sync.RWMutex
+	deps      []dependency
+	overrides map[string]Policy
+	timeout   time.Duration
+}
+
+// NewChecker creates a new Checker. Overrides replace the default policy a
+// dependency is registered with, keyed by dependency name.
+func NewChecker(timeout time.Duration, overrides map[string]Policy) *Checker {
+	if overrides == nil {
+		overrides = make(map[string]Policy)
+	}
+	return &Checker{
+		overrides: overrides,
+		timeout:   timeout,
+	}
+}
+
+// Register adds a dependency check with its default policy
+func (c *Checker) Register(name string, policy Policy, check CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if override, ok := c.overrides[name]; ok {
+		policy = override
+	}
+
+	c.deps = append(c.deps, dependency{name: name, policy: policy, check: check})
+}
+
+// Check runs all dependency checks concurrently and aggregates the result
+func (c *Checker) Check(ctx context.Context) Report {
+	c.mu.RLock()
+	deps := make([]dependency, len(c.deps))
+	copy(deps, c.deps)
+	c.mu.RUnlock()
+
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	reports := make([]DependencyReport, len(deps))
+	var wg sync.WaitGroup
+	for i, dep := range deps {
+		wg.Add(1)
+		go func(i int, dep dependency) {
+			defer wg.Done()
+			reports[i] = runCheck(ctx, dep)
+		}(i, dep)
+	}
+	wg.Wait()
+
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].Name < reports[j].Name
+	})
+
+	return Report{
+		Status:       aggregate(reports),
+		Dependencies: reports,
+	}
+}
+
+// Live runs all checks like Check, for the Checker of a liveness probe. The
+// service is stalled when any check fails, whatever its policy.
+func (c *Checker) Live(ctx context.Context) Report {
+	report := c.Check(ctx)
+
+	report.Status = StatusAlive
+	for _, dep := range report.Dependencies {
+		if !dep.Up {
+			report.Status = StatusStalled
+			break
+		}
+	}
+	return report
+}
+
+func runCheck(ctx context.Context, dep dependency) DependencyReport {
+	start := time.Now()
+	err := dep.check(ctx)
+
+	report := DependencyReport{
+		Name:      dep.name,
+		Policy:    dep.policy,
+		Up:        err == nil,
+		LatencyMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		report.Error = err.Error()
+	}
+	return report
+}
+
+func aggregate(reports []DependencyReport) Status {
+	status := StatusReady
+	for _, report := range reports {
+		if report.Up {
+			continue
+		}
+		if report.Policy == PolicyRequired {
+			return StatusUnready
+		}
+		status = StatusDegraded
+	}
+	return status
+}
@@ -0,0 +1,129 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func up(ctx context.Context) error { return nil }
+
+func down(ctx context.Context) error { return errors.New("connection refused") }
+
+func TestChecker_Check(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string]Policy
+		postgres  CheckFunc
+		cache     CheckFunc
+		want      Status
+	}{
+		{
+			name:     "all dependencies up",
+			postgres: up,
+			cache:    up,
+			want:     StatusReady,
+		},
+		{
+			name:     "optional dependency down",
+			postgres: up,
+			cache:    down,
+			want:     StatusDegraded,
+		},
+		{
+			name:     "required dependency down",
+			postgres: down,
+			cache:    up,
+			want:     StatusUnready,
+		},
+		{
+			name:      "required dependency overridden to optional",
+			overrides: map[string]Policy{"postgres": PolicyOptional},
+			postgres:  down,
+			cache:     up,
+			want:      StatusDegraded,
+		},
+		{
+			name:      "optional dependency overridden to required",
+			overrides: map[string]Policy{"cache": PolicyRequired},
+			postgres:  up,
+			cache:     down,
+			want:      StatusUnready,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := NewChecker(time.Second, tt.overrides)
+			checker.Register("postgres", PolicyRequired, tt.postgres)
+			checker.Register("cache", PolicyOptional, tt.cache)
+
+			report := checker.Check(context.Background())
+			assert.Equal(t, tt.want, report.Status)
+			assert.Len(t, report.Dependencies, 2)
+		})
+	}
+}
+
+func TestChecker_CheckTimeout(t *testing.T) {
+	checker := NewChecker(10*time.Millisecond, nil)
+	checker.Register("slow", PolicyRequired, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	report := checker.Check(context.Background())
+	assert.Equal(t, StatusUnready, report.Status)
+	assert.False(t, report.Dependencies[0].Up)
+	assert.NotEmpty(t, report.Dependencies[0].Error)
+}
+
+func TestParsePolicy(t *testing.T) {
+	policy, err := ParsePolicy("optional")
+	assert.NoError(t, err)
+	assert.Equal(t, PolicyOptional, policy)
+
+	_, err = ParsePolicy("sometimes")
+	assert.Error(t, err)
+}
+
+func TestChecker_Live(t *testing.T) {
+	checker := NewChecker(time.Second, nil)
+	assert.Equal(t, StatusAlive, checker.Live(context.Background()).Status)
+
+	checker.Register("worker", PolicyOptional, down)
+	report := checker.Live(context.Background())
+	assert.Equal(t, StatusStalled, report.Status)
+	assert.Len(t, report.Dependencies, 1)
+}
+
+func TestHeartbeat(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	heartbeat := NewHeartbeat(time.Minute)
+	heartbeat.now = func() time.Time { return now }
+	heartbeat.Beat()
+
+	now = now.Add(time.Minute)
+	assert.NoError(t, heartbeat.Check(context.Background()))
+
+	now = now.Add(time.Second)
+	assert.EqualError(t, heartbeat.Check(context.Background()), "no progress for 1m1s")
+
+	heartbeat.Beat()
+	assert.NoError(t, heartbeat.Check(context.Background()))
+
+	// Loops may run without a heartbeat
+	var missing *Heartbeat
+	missing.Beat()
+}
+
+func TestGate(t *testing.T) {
+	gate := NewGate("warming up")
+	assert.EqualError(t, gate.Check(context.Background()), "warming up")
+
+	gate.Open()
+	assert.NoError(t, gate.Check(context.Background()))
+}
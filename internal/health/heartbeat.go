@@ -0,0 +1,47 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Heartbeat tracks the progress of a background loop, such as a worker. The
+// loop beats after every iteration and Check fails once no beat has been seen
+// for longer than maxAge, so that liveness probes notice loops that are stuck.
+type Heartbeat struct {
+	maxAge time.Duration
+	now    func() time.Time
+	// last is the time of the last beat in Unix nanoseconds
+	last atomic.Int64
+}
+
+// NewHeartbeat creates a new Heartbeat. The loop counts as alive for maxAge
+// after the heartbeat is created, until its first beat.
+func NewHeartbeat(maxAge time.Duration) *Heartbeat {
+	h := &Heartbeat{
+		maxAge: maxAge,
+		now:    time.Now,
+	}
+	h.Beat()
+	return h
+}
+
+// Beat records that the loop made progress. Beating a nil Heartbeat does
+// nothing, so loops may be run without one.
+func (h *Heartbeat) Beat() {
+	if h == nil {
+		return
+	}
+	h.last.Store(h.now().UnixNano())
+}
+
+// Check fails when the last beat is older than maxAge
+func (h *Heartbeat) Check(ctx context.Context) error {
+	age := h.now().Sub(time.Unix(0, h.last.Load()))
+	if age > h.maxAge {
+		return fmt.Errorf("no progress for %s", age.Round(time.Second))
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// Supported output formats
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
+// New creates the application logger writing to stdout
+func New(level, format string) (zerolog.Logger, error) {
+	return newLogger(os.Stdout, level, format)
+}
+
+func newLogger(out io.Writer, level, format string) (zerolog.Logger, error) {
+	parsedLevel, err := zerolog.ParseLevel(level)
+	if err != nil || parsedLevel == zerolog.NoLevel {
+		return zerolog.Nop(), fmt.Errorf("invalid log level %q", level)
+	}
+
+	switch format {
+	case FormatJSON:
+	case FormatConsole:
+		out = zerolog.ConsoleWriter{Out: out, TimeFormat: time.RFC3339}
+	default:
+		return zerolog.Nop(), fmt.Errorf("invalid log format %q", format)
+	}
+
+	zerolog.TimeFieldFormat = time.RFC3339Nano
+	return zerolog.New(out).Level(parsedLevel).With().Timestamp().Logger(), nil
+}
+
+// FromContext returns the request-scoped logger carried by ctx, falling back
+// to the given logger outside of a request
+func FromContext(ctx context.Context, fallback *zerolog.Logger) *zerolog.Logger {
+	if logger := zerolog.Ctx(ctx); logger.GetLevel() != zerolog.Disabled {
+		return logger
+	}
+	return fallback
+}
+
+// RequestIDHeader carries the request ID between services
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds client-supplied request IDs
+const maxRequestIDLength = 128
+
+// RequestID returns the client-supplied request ID if it is usable, otherwise
+// a newly generated one
+func RequestID(supplied string) string {
+	if isValidRequestID(supplied) {
+		return supplied
+	}
+	return uuid.NewString()
+}
+
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
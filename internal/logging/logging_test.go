@@ -0,0 +1,55 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_InvalidSettings(t *testing.T) {
+	_, err := New("loud", FormatJSON)
+	assert.Error(t, err)
+	_, err = New("info", "xml")
+	assert.Error(t, err)
+}
+
+func TestNewLogger_WritesJSON(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := newLogger(&buf, "info", FormatJSON)
+	require.NoError(t, err)
+
+	logger.Debug().Msg("hidden")
+	logger.Info().Uint64("user_id", 1).Msg("processed")
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "info", entry["level"])
+	assert.Equal(t, "processed", entry["message"])
+	assert.Equal(t, float64(1), entry["user_id"])
+	assert.Contains(t, entry, "time")
+}
+
+func TestFromContext(t *testing.T) {
+	fallback := zerolog.Nop()
+	assert.Same(t, &fallback, FromContext(context.Background(), &fallback))
+
+	requestLogger := zerolog.New(&bytes.Buffer{})
+	ctx := requestLogger.WithContext(context.Background())
+	assert.NotSame(t, &fallback, FromContext(ctx, &fallback))
+}
+
+func TestRequestID(t *testing.T) {
+	assert.Equal(t, "abc-123", RequestID("abc-123"))
+
+	for _, supplied := range []string{"", "has space", "line\nbreak", strings.Repeat("a", 129)} {
+		generated := RequestID(supplied)
+		assert.NotEqual(t, supplied, generated)
+		assert.Len(t, generated, 36)
+	}
+}
@@ -0,0 +1,124 @@
+// Package processor runs transaction processing on a bounded pool of workers.
+//
+// Tasks are keyed by user: the tasks of a user always run on the same worker,
+// in the order they were submitted, so the transactions of a user never
+// interleave while those of different users run concurrently.
+package processor
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	// ErrQueueFull is returned by TrySubmit when the user's worker has no
+	// room left
+	ErrQueueFull = errors.New("processor queue is full")
+	// ErrClosed is returned for tasks submitted once the pool is shutting
+	// down
+	ErrClosed = errors.New("processor is shut down")
+)
+
+// Task is a unit of work for a user
+type Task struct {
+	UserID uint64
+	// Run is called with the context of the pool, which is cancelled once it
+	// shuts down. Tasks queued by then still run, so they must check the
+	// context, or detach from it, to decide whether to do their work.
+	Run func(ctx context.Context)
+}
+
+// Pool runs tasks on a fixed number of workers. Each worker has its own
+// queue, taking the tasks of the users assigned to it. A slow task only holds
+// back the users of its worker.
+type Pool struct {
+	queues []chan Task
+
+	// mu guards closed against the queues being closed while tasks are
+	// submitted
+	mu     sync.RWMutex
+	closed bool
+}
+
+// New creates a new Pool running workers workers and queueing up to
+// queueSize tasks, shared evenly between the workers
+func New(workers, queueSize int) *Pool {
+	workers = max(workers, 1)
+	perWorker := max((queueSize+workers-1)/workers, 1)
+
+	queues := make([]chan Task, workers)
+	for i := range queues {
+		queues[i] = make(chan Task, perWorker)
+	}
+	return &Pool{queues: queues}
+}
+
+// Workers returns the number of workers of the pool
+func (p *Pool) Workers() int {
+	return len(p.queues)
+}
+
+// queue returns the queue of the worker running the tasks of userID
+func (p *Pool) queue(userID uint64) chan Task {
+	return p.queues[userID%uint64(len(p.queues))]
+}
+
+// Submit queues task, waiting for room in its worker's queue. It fails with
+// the context's error when ctx is done first, and with ErrClosed once the pool
+// is shutting down.
+func (p *Pool) Submit(ctx context.Context, task Task) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return ErrClosed
+	}
+	select {
+	case p.queue(task.UserID) <- task:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// TrySubmit queues task without waiting, failing with ErrQueueFull when its
+// worker's queue is full and with ErrClosed once the pool is shutting down
+func (p *Pool) TrySubmit(task Task) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return ErrClosed
+	}
+	select {
+	case p.queue(task.UserID) <- task:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Run runs the queued tasks until the context is cancelled. The tasks queued
+// by then still run before it returns, while new ones are refused.
+func (p *Pool) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, queue := range p.queues {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range queue {
+				task.Run(ctx)
+			}
+		}()
+	}
+
+	<-ctx.Done()
+	p.mu.Lock()
+	p.closed = true
+	for _, queue := range p.queues {
+		close(queue)
+	}
+	p.mu.Unlock()
+	wg.Wait()
+}
@@ -0,0 +1,116 @@
+package processor
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder records the tasks run, per user
+type recorder struct {
+	mu  sync.Mutex
+	ran map[uint64][]int
+}
+
+func (r *recorder) task(userID uint64, n int) Task {
+	return Task{UserID: userID, Run: func(ctx context.Context) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.ran[userID] = append(r.ran[userID], n)
+	}}
+}
+
+func (r *recorder) runs(userID uint64) []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]int(nil), r.ran[userID]...)
+}
+
+func start(t *testing.T, pool *Pool) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pool.Run(ctx)
+	}()
+	stop = func() {
+		cancel()
+		<-done
+	}
+	t.Cleanup(stop)
+	return stop
+}
+
+func TestPool(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("runs the tasks of a user in order", func(t *testing.T) {
+		pool := New(4, 400)
+		rec := &recorder{ran: make(map[uint64][]int)}
+		stop := start(t, pool)
+
+		for n := range 100 {
+			for userID := range uint64(3) {
+				require.NoError(t, pool.Submit(ctx, rec.task(userID, n)))
+			}
+		}
+		stop()
+
+		for userID := range uint64(3) {
+			runs := rec.runs(userID)
+			assert.Len(t, runs, 100)
+			assert.IsIncreasing(t, runs)
+		}
+	})
+
+	t.Run("a slow user does not hold back the users of other workers", func(t *testing.T) {
+		pool := New(2, 10)
+		start(t, pool)
+		release := make(chan struct{})
+		defer close(release)
+		ran := make(chan uint64, 1)
+
+		require.NoError(t, pool.Submit(ctx, Task{UserID: 1, Run: func(ctx context.Context) { <-release }}))
+		require.NoError(t, pool.Submit(ctx, Task{UserID: 2, Run: func(ctx context.Context) { ran <- 2 }}))
+
+		select {
+		case userID := <-ran:
+			assert.Equal(t, uint64(2), userID)
+		case <-time.After(time.Second):
+			t.Fatal("the task of user 2 did not run")
+		}
+	})
+
+	t.Run("queues are bounded", func(t *testing.T) {
+		pool := New(2, 2)
+		noop := func(ctx context.Context) {}
+
+		require.NoError(t, pool.TrySubmit(Task{UserID: 1, Run: noop}))
+		assert.ErrorIs(t, pool.TrySubmit(Task{UserID: 3, Run: noop}), ErrQueueFull, "users 1 and 3 share a worker")
+		assert.NoError(t, pool.TrySubmit(Task{UserID: 2, Run: noop}))
+
+		timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, pool.Submit(timeout, Task{UserID: 1, Run: noop}), context.DeadlineExceeded)
+	})
+
+	t.Run("queued tasks run before shutting down", func(t *testing.T) {
+		pool := New(2, 10)
+		rec := &recorder{ran: make(map[uint64][]int)}
+		for n := range 5 {
+			require.NoError(t, pool.Submit(ctx, rec.task(1, n)))
+		}
+
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		pool.Run(cancelled)
+
+		assert.Equal(t, []int{0, 1, 2, 3, 4}, rec.runs(1))
+		assert.ErrorIs(t, pool.Submit(ctx, rec.task(1, 5)), ErrClosed)
+		assert.ErrorIs(t, pool.TrySubmit(rec.task(1, 5)), ErrClosed)
+	})
+}
@@ -0,0 +1,139 @@
+package region
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Mode determines whether this deployment accepts writes
+type Mode string
+
+const (
+	// ModeActive accepts reads and writes
+	ModeActive Mode = "active"
+	// ModeStandby serves reads from a replica and refuses writes
+	ModeStandby Mode = "standby"
+)
+
+// IsValid checks if the mode is valid
+func (m Mode) IsValid() bool {
+	return m == ModeActive || m == ModeStandby
+}
+
+// ParseMode converts a configuration value into a Mode
+func ParseMode(value string) (Mode, error) {
+	mode := Mode(value)
+	if !mode.IsValid() {
+		return "", fmt.Errorf("invalid region mode %q", value)
+	}
+	return mode, nil
+}
+
+var (
+	// ErrActiveRegionRequired is returned when demoting without naming the active region
+	ErrActiveRegionRequired = errors.New("active region URL is required")
+	// ErrNotWritable is returned when promoting while the local database is still read-only
+	ErrNotWritable = errors.New("database is not writable")
+)
+
+// WritableCheck verifies that the local database accepts writes
+type WritableCheck func(ctx context.Context) error
+
+// Status describes the current mode of this region
+type Status struct {
+	Name string `json:"name,omitempty"`
+	Mode Mode   `json:"mode"`
+	// ActiveRegionURL is where writes must be sent while in standby
+	ActiveRegionURL string    `json:"activeRegionUrl,omitempty"`
+	ChangedAt       time.Time `json:"changedAt"`
+}
+
+// State holds the region mode and lets the admin API flip it at runtime
+type State struct {
+	mu        sync.RWMutex
+	status    Status
+	writable  WritableCheck
+	checkTime time.Duration
+}
+
+// NewState creates a new State. writable is consulted before a promotion and
+// may be nil.
+func NewState(name string, mode Mode, activeRegionURL string, writable WritableCheck) *State {
+	return &State{
+		status: Status{
+			Name:            name,
+			Mode:            mode,
+			ActiveRegionURL: activeRegionURL,
+			ChangedAt:       time.Now(),
+		},
+		writable:  writable,
+		checkTime: 5 * time.Second,
+	}
+}
+
+// Status returns the current status
+func (s *State) Status() Status {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.status
+}
+
+// AcceptsWrites reports whether this region is active
+func (s *State) AcceptsWrites() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.status.Mode == ModeActive
+}
+
+// ActiveRegionURL returns where writes must be sent while in standby
+func (s *State) ActiveRegionURL() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.status.ActiveRegionURL
+}
+
+// Promote makes this region active. The local database must already have
+// been promoted, otherwise ErrNotWritable is returned and the mode is kept.
+func (s *State) Promote(ctx context.Context) (Status, error) {
+	if s.writable != nil {
+		checkCtx, cancel := context.WithTimeout(ctx, s.checkTime)
+		defer cancel()
+
+		if err := s.writable(checkCtx); err != nil {
+			return s.Status(), fmt.Errorf("%w: %v", ErrNotWritable, err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.status.Mode != ModeActive {
+		s.status.Mode = ModeActive
+		s.status.ActiveRegionURL = ""
+		s.status.ChangedAt = time.Now()
+	}
+	return s.status, nil
+}
+
+// Demote puts this region in standby, pointing writers at activeRegionURL
+func (s *State) Demote(activeRegionURL string) (Status, error) {
+	if activeRegionURL == "" {
+		return s.Status(), ErrActiveRegionRequired
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.status.Mode != ModeStandby || s.status.ActiveRegionURL != activeRegionURL {
+		s.status.Mode = ModeStandby
+		s.status.ActiveRegionURL = activeRegionURL
+		s.status.ChangedAt = time.Now()
+	}
+	return s.status, nil
+}
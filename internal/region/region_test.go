@@ -0,0 +1,52 @@
+package region
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMode(t *testing.T) {
+	mode, err := ParseMode("standby")
+	require.NoError(t, err)
+	assert.Equal(t, ModeStandby, mode)
+
+	_, err = ParseMode("passive")
+	assert.Error(t, err)
+}
+
+func TestState_PromoteAndDemote(t *testing.T) {
+	ctx := context.Background()
+	var replicaErr error
+	state := NewState("eu-west", ModeStandby, "https://us.example.com", func(ctx context.Context) error {
+		return replicaErr
+	})
+	assert.False(t, state.AcceptsWrites())
+	assert.Equal(t, "https://us.example.com", state.ActiveRegionURL())
+
+	// Promotion is refused while the database is still a replica
+	replicaErr = errors.New("database is in recovery")
+	status, err := state.Promote(ctx)
+	assert.ErrorIs(t, err, ErrNotWritable)
+	assert.Equal(t, ModeStandby, status.Mode)
+	assert.False(t, state.AcceptsWrites())
+
+	replicaErr = nil
+	status, err = state.Promote(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, ModeActive, status.Mode)
+	assert.Empty(t, status.ActiveRegionURL)
+	assert.True(t, state.AcceptsWrites())
+
+	_, err = state.Demote("")
+	assert.ErrorIs(t, err, ErrActiveRegionRequired)
+	assert.True(t, state.AcceptsWrites())
+
+	status, err = state.Demote("https://ap.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, ModeStandby, status.Mode)
+	assert.Equal(t, "https://ap.example.com", state.ActiveRegionURL())
+}
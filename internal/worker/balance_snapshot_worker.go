@@ -0,0 +1,81 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/health"
+
+	"github.com/rs/zerolog"
+)
+
+// BalanceSnapshotWorker periodically snapshots the closing balances of the day
+// that ended last
+type BalanceSnapshotWorker struct {
+	service   *services.BalanceSnapshotService
+	interval  time.Duration
+	batchSize int
+	// gate pauses the worker while the region does not accept writes; may be nil
+	gate services.RegionGate
+	// heartbeat beats after every run so that liveness probes notice a stuck
+	// worker; may be nil
+	heartbeat *health.Heartbeat
+	logger    zerolog.Logger
+}
+
+// NewBalanceSnapshotWorker creates a new BalanceSnapshotWorker
+func NewBalanceSnapshotWorker(
+	service *services.BalanceSnapshotService,
+	interval time.Duration,
+	batchSize int,
+	gate services.RegionGate,
+	heartbeat *health.Heartbeat,
+	logger zerolog.Logger,
+) *BalanceSnapshotWorker {
+	return &BalanceSnapshotWorker{
+		service:   service,
+		interval:  interval,
+		batchSize: batchSize,
+		gate:      gate,
+		heartbeat: heartbeat,
+		logger:    logger.With().Str("worker", "balance_snapshots").Logger(),
+	}
+}
+
+// Run runs a batch every interval until the context is cancelled
+func (w *BalanceSnapshotWorker) Run(ctx context.Context) {
+	w.logger.Info().Dur("interval", w.interval).Int("batch_size", w.batchSize).Msg("worker started")
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.logger.Info().Msg("worker stopped")
+			return
+		case <-ticker.C:
+			w.runOnce(ctx)
+			w.heartbeat.Beat()
+		}
+	}
+}
+
+func (w *BalanceSnapshotWorker) runOnce(ctx context.Context) {
+	// Only the active region writes
+	if w.gate != nil && !w.gate.AcceptsWrites() {
+		return
+	}
+
+	userIDs, err := w.service.SnapshotPreviousDay(ctx, w.batchSize)
+	if err != nil {
+		// The users snapshotted before the failure stay snapshotted
+		w.logger.Error().Err(err).Int("users", len(userIDs)).Msg("worker run failed")
+		return
+	}
+
+	if len(userIDs) > 0 {
+		w.logger.Info().Int("users", len(userIDs)).Msg("worker run completed")
+	}
+}
@@ -0,0 +1,80 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/health"
+
+	"github.com/rs/zerolog"
+)
+
+// CancellationWorker periodically cancels the latest odd-ID transactions
+type CancellationWorker struct {
+	service   *services.CancellationService
+	interval  time.Duration
+	batchSize int
+	// gate pauses the worker while the region does not accept writes; may be nil
+	gate services.RegionGate
+	// heartbeat beats after every run so that liveness probes notice a stuck
+	// worker; may be nil
+	heartbeat *health.Heartbeat
+	logger    zerolog.Logger
+}
+
+// NewCancellationWorker creates a new CancellationWorker
+func NewCancellationWorker(
+	service *services.CancellationService,
+	interval time.Duration,
+	batchSize int,
+	gate services.RegionGate,
+	heartbeat *health.Heartbeat,
+	logger zerolog.Logger,
+) *CancellationWorker {
+	return &CancellationWorker{
+		service:   service,
+		interval:  interval,
+		batchSize: batchSize,
+		gate:      gate,
+		heartbeat: heartbeat,
+		logger:    logger.With().Str("worker", "cancellation").Logger(),
+	}
+}
+
+// Run processes a batch every interval until the context is cancelled
+func (w *CancellationWorker) Run(ctx context.Context) {
+	w.logger.Info().Dur("interval", w.interval).Int("batch_size", w.batchSize).Msg("worker started")
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.logger.Info().Msg("worker stopped")
+			return
+		case <-ticker.C:
+			w.runOnce(ctx)
+			w.heartbeat.Beat()
+		}
+	}
+}
+
+func (w *CancellationWorker) runOnce(ctx context.Context) {
+	// Only the active region writes
+	if w.gate != nil && !w.gate.AcceptsWrites() {
+		return
+	}
+
+	result, err := w.service.CancelLatestOddTransactions(ctx, w.batchSize)
+	if err != nil {
+		w.logger.Error().Err(err).Msg("worker run failed")
+		return
+	}
+
+	w.logger.Info().Int("cancelled", len(result.Cancelled)).Int("skipped", len(result.Skipped)).Msg("worker run completed")
+	for _, transactionID := range result.Skipped {
+		w.logger.Warn().Str("transaction_id", transactionID).Msg("cancellation skipped: balance would become negative")
+	}
+}
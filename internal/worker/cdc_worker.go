@@ -0,0 +1,72 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/health"
+
+	"github.com/rs/zerolog"
+)
+
+// ChangeStream publishes database changes until its context is cancelled or
+// it fails, calling progress whenever it made progress
+type ChangeStream interface {
+	Run(ctx context.Context, progress func()) error
+}
+
+// CDCWorker keeps a change stream running, restarting it after failures
+type CDCWorker struct {
+	stream ChangeStream
+	// retryDelay is the pause before restarting the stream
+	retryDelay time.Duration
+	// gate pauses the worker while the region does not accept writes; may be nil
+	gate services.RegionGate
+	// heartbeat beats while the stream makes progress and between restarts;
+	// may be nil
+	heartbeat *health.Heartbeat
+	logger    zerolog.Logger
+}
+
+// NewCDCWorker creates a new CDCWorker
+func NewCDCWorker(
+	stream ChangeStream,
+	retryDelay time.Duration,
+	gate services.RegionGate,
+	heartbeat *health.Heartbeat,
+	logger zerolog.Logger,
+) *CDCWorker {
+	return &CDCWorker{
+		stream:     stream,
+		retryDelay: retryDelay,
+		gate:       gate,
+		heartbeat:  heartbeat,
+		logger:     logger.With().Str("worker", "cdc").Logger(),
+	}
+}
+
+// Run streams changes until the context is cancelled
+func (w *CDCWorker) Run(ctx context.Context) {
+	w.logger.Info().Dur("retry_delay", w.retryDelay).Msg("worker started")
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.logger.Info().Msg("worker stopped")
+			return
+		case <-timer.C:
+			// Only the active region writes
+			if w.gate == nil || w.gate.AcceptsWrites() {
+				if err := w.stream.Run(ctx, w.heartbeat.Beat); err != nil && ctx.Err() == nil {
+					w.logger.Error().Err(err).Msg("change stream failed")
+				}
+			}
+			w.heartbeat.Beat()
+			timer.Reset(w.retryDelay)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/health"
+
+	"github.com/rs/zerolog"
+)
+
+// DormancyWorker periodically flags the users that stopped transacting as
+// dormant
+type DormancyWorker struct {
+	service   *services.DormancyService
+	interval  time.Duration
+	batchSize int
+	// gate pauses the worker while the region does not accept writes; may be nil
+	gate services.RegionGate
+	// heartbeat beats after every run so that liveness probes notice a stuck
+	// worker; may be nil
+	heartbeat *health.Heartbeat
+	logger    zerolog.Logger
+}
+
+// NewDormancyWorker creates a new DormancyWorker
+func NewDormancyWorker(
+	service *services.DormancyService,
+	interval time.Duration,
+	batchSize int,
+	gate services.RegionGate,
+	heartbeat *health.Heartbeat,
+	logger zerolog.Logger,
+) *DormancyWorker {
+	return &DormancyWorker{
+		service:   service,
+		interval:  interval,
+		batchSize: batchSize,
+		gate:      gate,
+		heartbeat: heartbeat,
+		logger:    logger.With().Str("worker", "dormancy").Logger(),
+	}
+}
+
+// Run flags a batch of users every interval until the context is cancelled
+func (w *DormancyWorker) Run(ctx context.Context) {
+	w.logger.Info().Dur("interval", w.interval).Int("batch_size", w.batchSize).Msg("worker started")
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.logger.Info().Msg("worker stopped")
+			return
+		case <-ticker.C:
+			w.runOnce(ctx)
+			w.heartbeat.Beat()
+		}
+	}
+}
+
+func (w *DormancyWorker) runOnce(ctx context.Context) {
+	// Only the active region writes
+	if w.gate != nil && !w.gate.AcceptsWrites() {
+		return
+	}
+
+	result, err := w.service.FlagIdleUsers(ctx, w.batchSize)
+	if err != nil {
+		w.logger.Error().Err(err).Msg("worker run failed")
+		return
+	}
+
+	w.logger.Info().
+		Int("flagged", len(result.Flagged)).
+		Int("charged", len(result.Charged)).
+		Int("frozen", len(result.Frozen)).
+		Msg("worker run completed")
+}
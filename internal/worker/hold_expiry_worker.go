@@ -0,0 +1,79 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/health"
+
+	"github.com/rs/zerolog"
+)
+
+// HoldExpiryWorker periodically marks holds whose expiry has passed as expired
+type HoldExpiryWorker struct {
+	service   *services.HoldService
+	interval  time.Duration
+	batchSize int
+	// gate pauses the worker while the region does not accept writes; may be nil
+	gate services.RegionGate
+	// heartbeat beats after every run so that liveness probes notice a stuck
+	// worker; may be nil
+	heartbeat *health.Heartbeat
+	logger    zerolog.Logger
+}
+
+// NewHoldExpiryWorker creates a new HoldExpiryWorker
+func NewHoldExpiryWorker(
+	service *services.HoldService,
+	interval time.Duration,
+	batchSize int,
+	gate services.RegionGate,
+	heartbeat *health.Heartbeat,
+	logger zerolog.Logger,
+) *HoldExpiryWorker {
+	return &HoldExpiryWorker{
+		service:   service,
+		interval:  interval,
+		batchSize: batchSize,
+		gate:      gate,
+		heartbeat: heartbeat,
+		logger:    logger.With().Str("worker", "hold_expiry").Logger(),
+	}
+}
+
+// Run expires a batch every interval until the context is cancelled
+func (w *HoldExpiryWorker) Run(ctx context.Context) {
+	w.logger.Info().Dur("interval", w.interval).Int("batch_size", w.batchSize).Msg("worker started")
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.logger.Info().Msg("worker stopped")
+			return
+		case <-ticker.C:
+			w.runOnce(ctx)
+			w.heartbeat.Beat()
+		}
+	}
+}
+
+func (w *HoldExpiryWorker) runOnce(ctx context.Context) {
+	// Only the active region writes
+	if w.gate != nil && !w.gate.AcceptsWrites() {
+		return
+	}
+
+	expired, err := w.service.ExpireHolds(ctx, w.batchSize)
+	if err != nil {
+		w.logger.Error().Err(err).Msg("worker run failed")
+		return
+	}
+
+	if len(expired) > 0 {
+		w.logger.Info().Strs("hold_ids", expired).Msg("worker run completed")
+	}
+}
@@ -0,0 +1,85 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/health"
+
+	"github.com/rs/zerolog"
+)
+
+// OutboxRelayWorker periodically publishes the events recorded in the outbox
+type OutboxRelayWorker struct {
+	relay     *services.OutboxRelay
+	interval  time.Duration
+	batchSize int
+	// gate pauses the worker while the region does not accept writes; may be nil
+	gate services.RegionGate
+	// heartbeat beats after every run so that liveness probes notice a stuck
+	// worker; may be nil
+	heartbeat *health.Heartbeat
+	logger    zerolog.Logger
+}
+
+// NewOutboxRelayWorker creates a new OutboxRelayWorker
+func NewOutboxRelayWorker(
+	relay *services.OutboxRelay,
+	interval time.Duration,
+	batchSize int,
+	gate services.RegionGate,
+	heartbeat *health.Heartbeat,
+	logger zerolog.Logger,
+) *OutboxRelayWorker {
+	return &OutboxRelayWorker{
+		relay:     relay,
+		interval:  interval,
+		batchSize: batchSize,
+		gate:      gate,
+		heartbeat: heartbeat,
+		logger:    logger.With().Str("worker", "outbox_relay").Logger(),
+	}
+}
+
+// Run relays events every interval until the context is cancelled
+func (w *OutboxRelayWorker) Run(ctx context.Context) {
+	w.logger.Info().Dur("interval", w.interval).Int("batch_size", w.batchSize).Msg("worker started")
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.logger.Info().Msg("worker stopped")
+			return
+		case <-ticker.C:
+			w.runOnce(ctx)
+			w.heartbeat.Beat()
+		}
+	}
+}
+
+// runOnce relays full batches until the backlog is drained, so a burst of
+// events does not wait several intervals
+func (w *OutboxRelayWorker) runOnce(ctx context.Context) {
+	// Only the active region writes
+	if w.gate != nil && !w.gate.AcceptsWrites() {
+		return
+	}
+
+	for ctx.Err() == nil {
+		published, err := w.relay.Relay(ctx, w.batchSize)
+		if err != nil {
+			w.logger.Error().Err(err).Msg("worker run failed")
+			return
+		}
+		if published > 0 {
+			w.logger.Debug().Int("published", published).Msg("outbox events published")
+		}
+		if published < w.batchSize {
+			return
+		}
+	}
+}
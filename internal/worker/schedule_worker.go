@@ -0,0 +1,80 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/health"
+
+	"github.com/rs/zerolog"
+)
+
+// ScheduleWorker periodically runs the scheduled transactions that are due
+type ScheduleWorker struct {
+	service   *services.ScheduleService
+	interval  time.Duration
+	batchSize int
+	// gate pauses the worker while the region does not accept writes; may be nil
+	gate services.RegionGate
+	// heartbeat beats after every run so that liveness probes notice a stuck
+	// worker; may be nil
+	heartbeat *health.Heartbeat
+	logger    zerolog.Logger
+}
+
+// NewScheduleWorker creates a new ScheduleWorker
+func NewScheduleWorker(
+	service *services.ScheduleService,
+	interval time.Duration,
+	batchSize int,
+	gate services.RegionGate,
+	heartbeat *health.Heartbeat,
+	logger zerolog.Logger,
+) *ScheduleWorker {
+	return &ScheduleWorker{
+		service:   service,
+		interval:  interval,
+		batchSize: batchSize,
+		gate:      gate,
+		heartbeat: heartbeat,
+		logger:    logger.With().Str("worker", "scheduler").Logger(),
+	}
+}
+
+// Run runs a batch every interval until the context is cancelled
+func (w *ScheduleWorker) Run(ctx context.Context) {
+	w.logger.Info().Dur("interval", w.interval).Int("batch_size", w.batchSize).Msg("worker started")
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.logger.Info().Msg("worker stopped")
+			return
+		case <-ticker.C:
+			w.runOnce(ctx)
+			w.heartbeat.Beat()
+		}
+	}
+}
+
+func (w *ScheduleWorker) runOnce(ctx context.Context) {
+	// Only the active region writes
+	if w.gate != nil && !w.gate.AcceptsWrites() {
+		return
+	}
+
+	ran, err := w.service.RunDue(ctx, w.batchSize)
+	if err != nil {
+		// The schedules run before the failure stay run
+		w.logger.Error().Err(err).Strs("schedule_ids", ran).Msg("worker run failed")
+		return
+	}
+
+	if len(ran) > 0 {
+		w.logger.Info().Strs("schedule_ids", ran).Msg("worker run completed")
+	}
+}
@@ -0,0 +1,80 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/health"
+
+	"github.com/rs/zerolog"
+)
+
+// SettlementWorker periodically settles the pending transactions whose
+// settlement delay has passed
+type SettlementWorker struct {
+	service   *services.TransactionService
+	interval  time.Duration
+	batchSize int
+	// gate pauses the worker while the region does not accept writes; may be nil
+	gate services.RegionGate
+	// heartbeat beats after every run so that liveness probes notice a stuck
+	// worker; may be nil
+	heartbeat *health.Heartbeat
+	logger    zerolog.Logger
+}
+
+// NewSettlementWorker creates a new SettlementWorker
+func NewSettlementWorker(
+	service *services.TransactionService,
+	interval time.Duration,
+	batchSize int,
+	gate services.RegionGate,
+	heartbeat *health.Heartbeat,
+	logger zerolog.Logger,
+) *SettlementWorker {
+	return &SettlementWorker{
+		service:   service,
+		interval:  interval,
+		batchSize: batchSize,
+		gate:      gate,
+		heartbeat: heartbeat,
+		logger:    logger.With().Str("worker", "settlement").Logger(),
+	}
+}
+
+// Run settles a batch every interval until the context is cancelled
+func (w *SettlementWorker) Run(ctx context.Context) {
+	w.logger.Info().Dur("interval", w.interval).Int("batch_size", w.batchSize).Msg("worker started")
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.logger.Info().Msg("worker stopped")
+			return
+		case <-ticker.C:
+			w.runOnce(ctx)
+			w.heartbeat.Beat()
+		}
+	}
+}
+
+func (w *SettlementWorker) runOnce(ctx context.Context) {
+	// Only the active region writes
+	if w.gate != nil && !w.gate.AcceptsWrites() {
+		return
+	}
+
+	settled, err := w.service.SettleDue(ctx, w.batchSize)
+	if err != nil {
+		w.logger.Error().Err(err).Msg("worker run failed")
+		return
+	}
+
+	if len(settled) > 0 {
+		w.logger.Info().Strs("transaction_ids", settled).Msg("worker run completed")
+	}
+}
@@ -0,0 +1,85 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"transaction-service/internal/application/services"
+	"transaction-service/internal/health"
+
+	"github.com/rs/zerolog"
+)
+
+// WebhookDeliveryWorker periodically sends the due webhook deliveries
+type WebhookDeliveryWorker struct {
+	webhooks  *services.WebhookService
+	interval  time.Duration
+	batchSize int
+	// gate pauses the worker while the region does not accept writes; may be nil
+	gate services.RegionGate
+	// heartbeat beats after every run so that liveness probes notice a stuck
+	// worker; may be nil
+	heartbeat *health.Heartbeat
+	logger    zerolog.Logger
+}
+
+// NewWebhookDeliveryWorker creates a new WebhookDeliveryWorker
+func NewWebhookDeliveryWorker(
+	webhooks *services.WebhookService,
+	interval time.Duration,
+	batchSize int,
+	gate services.RegionGate,
+	heartbeat *health.Heartbeat,
+	logger zerolog.Logger,
+) *WebhookDeliveryWorker {
+	return &WebhookDeliveryWorker{
+		webhooks:  webhooks,
+		interval:  interval,
+		batchSize: batchSize,
+		gate:      gate,
+		heartbeat: heartbeat,
+		logger:    logger.With().Str("worker", "webhook_delivery").Logger(),
+	}
+}
+
+// Run sends deliveries every interval until the context is cancelled
+func (w *WebhookDeliveryWorker) Run(ctx context.Context) {
+	w.logger.Info().Dur("interval", w.interval).Int("batch_size", w.batchSize).Msg("worker started")
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.logger.Info().Msg("worker stopped")
+			return
+		case <-ticker.C:
+			w.runOnce(ctx)
+			w.heartbeat.Beat()
+		}
+	}
+}
+
+// runOnce sends full batches until no delivery is due, so a burst of events
+// does not wait several intervals
+func (w *WebhookDeliveryWorker) runOnce(ctx context.Context) {
+	// Only the active region writes
+	if w.gate != nil && !w.gate.AcceptsWrites() {
+		return
+	}
+
+	for ctx.Err() == nil {
+		attempted, err := w.webhooks.Deliver(ctx, w.batchSize)
+		if err != nil {
+			w.logger.Error().Err(err).Msg("worker run failed")
+			return
+		}
+		if attempted > 0 {
+			w.logger.Debug().Int("attempted", attempted).Msg("webhook deliveries attempted")
+		}
+		if attempted < w.batchSize {
+			return
+		}
+	}
+}
@@ -0,0 +1,663 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Config handles GET /admin/config, returning the service's redacted
+// effective configuration
+func (c *Client) Config(ctx context.Context) (map[string]any, error) {
+	var config map[string]any
+	if err := c.do(ctx, request{
+		method:    http.MethodGet,
+		path:      "/admin/config",
+		retriable: true,
+	}, &config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// SearchTransactions handles GET /admin/transactions
+func (c *Client) SearchTransactions(ctx context.Context, filter TransactionFilter) (*TransactionPage, error) {
+	query := url.Values{}
+	if filter.UserID != 0 {
+		query.Set("userId", strconv.FormatUint(filter.UserID, 10))
+	}
+	if filter.TransactionIDPrefix != "" {
+		query.Set("transactionIdPrefix", filter.TransactionIDPrefix)
+	}
+	if filter.SourceType != "" {
+		query.Set("sourceType", string(filter.SourceType))
+	}
+	if filter.State != "" {
+		query.Set("state", string(filter.State))
+	}
+	if filter.MinAmount != nil {
+		query.Set("minAmount", filter.MinAmount.String())
+	}
+	if filter.MaxAmount != nil {
+		query.Set("maxAmount", filter.MaxAmount.String())
+	}
+	if filter.MetadataKey != "" {
+		query.Set("metadataKey", filter.MetadataKey)
+	}
+	if filter.MetadataValue != nil {
+		query.Set("metadataValue", *filter.MetadataValue)
+	}
+	if filter.From != nil {
+		query.Set("from", filter.From.Format(time.RFC3339Nano))
+	}
+	if filter.To != nil {
+		query.Set("to", filter.To.Format(time.RFC3339Nano))
+	}
+	if filter.Cancelled != nil {
+		query.Set("cancelled", strconv.FormatBool(*filter.Cancelled))
+	}
+
+	var result TransactionPage
+	if err := c.do(ctx, request{
+		method:    http.MethodGet,
+		path:      "/admin/transactions",
+		query:     filter.Page.values(query),
+		retriable: true,
+	}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// VerifyExport handles GET /admin/exports/{name}/verify. A tampered or
+// incomplete export is reported through the report's Valid flag rather than
+// an error.
+func (c *Client) VerifyExport(ctx context.Context, name string) (*ExportReport, error) {
+	var report ExportReport
+	if err := c.do(ctx, request{
+		method:    http.MethodGet,
+		path:      "/admin/exports/" + url.PathEscape(name) + "/verify",
+		accept:    []int{http.StatusUnprocessableEntity},
+		retriable: true,
+	}, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// VerifyIngestion handles GET /admin/ingestion/verify, checking that the
+// transactions created in [from, to) were ingested exactly once. Duplicated
+// transaction IDs are reported through the report's Valid flag rather than an
+// error.
+func (c *Client) VerifyIngestion(ctx context.Context, from, to time.Time) (*IngestionReport, error) {
+	query := url.Values{}
+	query.Set("from", from.Format(time.RFC3339Nano))
+	query.Set("to", to.Format(time.RFC3339Nano))
+
+	var report IngestionReport
+	if err := c.do(ctx, request{
+		method:    http.MethodGet,
+		path:      "/admin/ingestion/verify",
+		query:     query,
+		accept:    []int{http.StatusUnprocessableEntity},
+		retriable: true,
+	}, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// RejectionReport handles GET /admin/rejections/report, returning the
+// rejection rate of every source type with attempts in [from, to)
+func (c *Client) RejectionReport(ctx context.Context, from, to time.Time) (*RejectionReport, error) {
+	query := url.Values{}
+	query.Set("from", from.Format(time.RFC3339Nano))
+	query.Set("to", to.Format(time.RFC3339Nano))
+
+	var report RejectionReport
+	if err := c.do(ctx, request{
+		method:    http.MethodGet,
+		path:      "/admin/rejections/report",
+		query:     query,
+		retriable: true,
+	}, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// ListRejections handles GET /admin/rejections, returning a page of the
+// rejected transaction attempts matching the filter, newest first
+func (c *Client) ListRejections(ctx context.Context, filter RejectionFilter) (*RejectionPage, error) {
+	query := url.Values{}
+	if filter.SourceType != "" {
+		query.Set("sourceType", string(filter.SourceType))
+	}
+	if filter.Reason != "" {
+		query.Set("reason", filter.Reason)
+	}
+	if filter.From != nil {
+		query.Set("from", filter.From.Format(time.RFC3339Nano))
+	}
+	if filter.To != nil {
+		query.Set("to", filter.To.Format(time.RFC3339Nano))
+	}
+
+	var result RejectionPage
+	if err := c.do(ctx, request{
+		method:    http.MethodGet,
+		path:      "/admin/rejections",
+		query:     filter.Page.values(query),
+		retriable: true,
+	}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// SetUserJurisdiction handles PUT /admin/users/{userId}/jurisdiction. An
+// empty jurisdiction clears it.
+func (c *Client) SetUserJurisdiction(ctx context.Context, userID uint64, jurisdiction string) error {
+	err := c.do(ctx, request{
+		method:    http.MethodPut,
+		path:      "/admin/users/" + strconv.FormatUint(userID, 10) + "/jurisdiction",
+		body:      map[string]string{"jurisdiction": jurisdiction},
+		retriable: true,
+	}, nil)
+	return err
+}
+
+// SetUserCreditLimit handles PUT /admin/users/{userId}/credit-limit. A zero
+// credit limit removes the credit line.
+func (c *Client) SetUserCreditLimit(ctx context.Context, userID uint64, creditLimit decimal.Decimal) error {
+	err := c.do(ctx, request{
+		method:    http.MethodPut,
+		path:      "/admin/users/" + strconv.FormatUint(userID, 10) + "/credit-limit",
+		body:      map[string]string{"creditLimit": creditLimit.StringFixed(2)},
+		retriable: true,
+	}, nil)
+	return err
+}
+
+// ListFeeRules handles GET /admin/fees
+func (c *Client) ListFeeRules(ctx context.Context) ([]FeeRule, error) {
+	var result struct {
+		Rules []FeeRule `json:"rules"`
+	}
+	if err := c.do(ctx, request{
+		method:    http.MethodGet,
+		path:      "/admin/fees",
+		retriable: true,
+	}, &result); err != nil {
+		return nil, err
+	}
+	return result.Rules, nil
+}
+
+// SetFeeRule handles PUT /admin/fees/{sourceType}/{state}, replacing the fee
+// rule of the source type and state. Only the kind, rate, amount and tiers of
+// rule are sent.
+func (c *Client) SetFeeRule(ctx context.Context, sourceType SourceType, state State, rule FeeRule) (*FeeRule, error) {
+	var result FeeRule
+	if err := c.do(ctx, request{
+		method: http.MethodPut,
+		path:   feeRulePath(sourceType, state),
+		body: FeeRule{
+			Kind:   rule.Kind,
+			Rate:   rule.Rate,
+			Amount: rule.Amount,
+			Tiers:  rule.Tiers,
+		},
+		retriable: true,
+	}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DeleteFeeRule handles DELETE /admin/fees/{sourceType}/{state}. Deleting a
+// rule that does not exist fails with ErrNotFound.
+func (c *Client) DeleteFeeRule(ctx context.Context, sourceType SourceType, state State) error {
+	return c.do(ctx, request{
+		method: http.MethodDelete,
+		path:   feeRulePath(sourceType, state),
+	}, nil)
+}
+
+// SystemAccounts handles GET /admin/system-accounts, returning the house,
+// promo and escheat accounts with their balances
+func (c *Client) SystemAccounts(ctx context.Context) ([]SystemAccount, error) {
+	var result struct {
+		Accounts []SystemAccount `json:"accounts"`
+	}
+	if err := c.do(ctx, request{
+		method:    http.MethodGet,
+		path:      "/admin/system-accounts",
+		retriable: true,
+	}, &result); err != nil {
+		return nil, err
+	}
+	return result.Accounts, nil
+}
+
+// AdjustBalance handles POST /admin/users/{userId}/adjustments. The
+// adjustment ID doubles as idempotency key: the request is retried on
+// transient failures and a retry of an already made adjustment returns the
+// original record with Replayed set.
+func (c *Client) AdjustBalance(ctx context.Context, userID uint64, req BalanceAdjustmentRequest) (*BalanceAdjustment, error) {
+	if req.AdjustmentID == "" {
+		req.AdjustmentID = uuid.NewString()
+	}
+
+	var adjustment BalanceAdjustment
+	if err := c.do(ctx, request{
+		method:    http.MethodPost,
+		path:      balanceAdjustmentsPath(userID),
+		body:      req,
+		retriable: true,
+	}, &adjustment); err != nil {
+		return nil, err
+	}
+	return &adjustment, nil
+}
+
+// ListBalanceAdjustments handles GET /admin/users/{userId}/adjustments,
+// returning the user's adjustments newest first
+func (c *Client) ListBalanceAdjustments(ctx context.Context, userID uint64) ([]BalanceAdjustment, error) {
+	var result struct {
+		Adjustments []BalanceAdjustment `json:"adjustments"`
+	}
+	if err := c.do(ctx, request{
+		method:    http.MethodGet,
+		path:      balanceAdjustmentsPath(userID),
+		retriable: true,
+	}, &result); err != nil {
+		return nil, err
+	}
+	return result.Adjustments, nil
+}
+
+// AuditLog handles GET /admin/audit-log, returning a page of the audit
+// entries matching the filter, newest first
+func (c *Client) AuditLog(ctx context.Context, filter AuditLogFilter) (*AuditLogPage, error) {
+	query := url.Values{}
+	if filter.UserID != 0 {
+		query.Set("userId", strconv.FormatUint(filter.UserID, 10))
+	}
+	if filter.From != nil {
+		query.Set("from", filter.From.Format(time.RFC3339Nano))
+	}
+	if filter.To != nil {
+		query.Set("to", filter.To.Format(time.RFC3339Nano))
+	}
+
+	var result AuditLogPage
+	if err := c.do(ctx, request{
+		method:    http.MethodGet,
+		path:      "/admin/audit-log",
+		query:     filter.Page.values(query),
+		retriable: true,
+	}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// CheckBalances handles GET /admin/balances/check, recomputing every balance
+// from the transactions. Drifted balances are reported in the report's Drifts
+// rather than as an error.
+func (c *Client) CheckBalances(ctx context.Context) (*BalanceCheckReport, error) {
+	var report BalanceCheckReport
+	if err := c.do(ctx, request{
+		method:    http.MethodGet,
+		path:      "/admin/balances/check",
+		accept:    []int{http.StatusUnprocessableEntity},
+		retriable: true,
+	}, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// RepairBalances handles POST /admin/balances/repair, setting every drifted
+// balance to the one recomputed from the transactions
+func (c *Client) RepairBalances(ctx context.Context) (*BalanceCheckReport, error) {
+	var report BalanceCheckReport
+	if err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/admin/balances/repair",
+	}, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Reconcile handles POST /admin/reconciliation, matching the statement of
+// the source system with its transactions created in [from, to). Missing,
+// mismatched and extra records are reported through the report's Reconciled
+// flag rather than an error.
+func (c *Client) Reconcile(ctx context.Context, sourceType SourceType, from, to time.Time, statement []StatementEntry) (*ReconciliationReport, error) {
+	query := url.Values{}
+	query.Set("sourceType", string(sourceType))
+	query.Set("from", from.Format(time.RFC3339Nano))
+	query.Set("to", to.Format(time.RFC3339Nano))
+
+	var report ReconciliationReport
+	if err := c.do(ctx, request{
+		method:    http.MethodPost,
+		path:      "/admin/reconciliation",
+		query:     query,
+		body:      statement,
+		accept:    []int{http.StatusUnprocessableEntity},
+		retriable: true,
+	}, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// PreviewSettlement handles GET /admin/settlement/preview, totalling up to
+// limit of the pending transactions the settlement worker settles next, or
+// its batch size when limit is zero. Nothing is settled.
+func (c *Client) PreviewSettlement(ctx context.Context, limit int) (*SettlementPreview, error) {
+	var preview SettlementPreview
+	if err := c.do(ctx, request{
+		method:    http.MethodGet,
+		path:      "/admin/settlement/preview",
+		query:     Page{Limit: limit}.values(url.Values{}),
+		retriable: true,
+	}, &preview); err != nil {
+		return nil, err
+	}
+	return &preview, nil
+}
+
+// CreateRestoreMarker handles POST /admin/restore-markers, recording the
+// contents of the database under name before a backup is taken
+func (c *Client) CreateRestoreMarker(ctx context.Context, name string) (*RestoreMarker, error) {
+	var marker RestoreMarker
+	if err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/admin/restore-markers",
+		body:   map[string]string{"name": name},
+	}, &marker); err != nil {
+		return nil, err
+	}
+	return &marker, nil
+}
+
+// ListRestoreMarkers handles GET /admin/restore-markers
+func (c *Client) ListRestoreMarkers(ctx context.Context) ([]RestoreMarker, error) {
+	var result struct {
+		Markers []RestoreMarker `json:"markers"`
+	}
+	if err := c.do(ctx, request{
+		method:    http.MethodGet,
+		path:      "/admin/restore-markers",
+		retriable: true,
+	}, &result); err != nil {
+		return nil, err
+	}
+	return result.Markers, nil
+}
+
+// VerifyRestore handles GET /admin/restore-markers/{name}/verify. A restore
+// differing from the marker is reported through the report's Valid flag
+// rather than an error.
+func (c *Client) VerifyRestore(ctx context.Context, name string) (*RestoreVerificationReport, error) {
+	var report RestoreVerificationReport
+	if err := c.do(ctx, request{
+		method:    http.MethodGet,
+		path:      "/admin/restore-markers/" + url.PathEscape(name) + "/verify",
+		accept:    []int{http.StatusUnprocessableEntity},
+		retriable: true,
+	}, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// StorageMigration handles GET /admin/storage-migration
+func (c *Client) StorageMigration(ctx context.Context) (*StorageMigrationStatus, error) {
+	return c.storageMigration(ctx, http.MethodGet, "/admin/storage-migration", true)
+}
+
+// CutoverStorageMigration handles POST /admin/storage-migration/cutover. It
+// fails with ErrConflict when the shadow store did not catch up in time,
+// and is not retried, so that the caller decides when to pause writes again.
+func (c *Client) CutoverStorageMigration(ctx context.Context) (*StorageMigrationStatus, error) {
+	return c.storageMigration(ctx, http.MethodPost, "/admin/storage-migration/cutover", false)
+}
+
+// RollbackStorageMigration handles POST /admin/storage-migration/rollback,
+// which is not retried for the same reason as a cutover
+func (c *Client) RollbackStorageMigration(ctx context.Context) (*StorageMigrationStatus, error) {
+	return c.storageMigration(ctx, http.MethodPost, "/admin/storage-migration/rollback", false)
+}
+
+func (c *Client) storageMigration(ctx context.Context, method, path string, retriable bool) (*StorageMigrationStatus, error) {
+	var status StorageMigrationStatus
+	if err := c.do(ctx, request{
+		method:    method,
+		path:      path,
+		retriable: retriable,
+	}, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// SyncTransactions handles GET /sync/transactions, returning up to limit of
+// the transactions changed after the cursor since, or from the start when
+// empty. Pass the page's cursor to read the next page.
+func (c *Client) SyncTransactions(ctx context.Context, since string, limit int) (*SyncPage, error) {
+	query := url.Values{}
+	if since != "" {
+		query.Set("since", since)
+	}
+
+	var page SyncPage
+	if err := c.do(ctx, request{
+		method:    http.MethodGet,
+		path:      "/sync/transactions",
+		query:     Page{Limit: limit}.values(query),
+		retriable: true,
+	}, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+func balanceAdjustmentsPath(userID uint64) string {
+	return "/admin/users/" + strconv.FormatUint(userID, 10) + "/adjustments"
+}
+
+func feeRulePath(sourceType SourceType, state State) string {
+	return "/admin/fees/" + url.PathEscape(string(sourceType)) + "/" + url.PathEscape(string(state))
+}
+
+// GetTransaction handles GET /transaction/{transactionId}
+func (c *Client) GetTransaction(ctx context.Context, transactionID string) (*Transaction, error) {
+	var transaction Transaction
+	if err := c.do(ctx, request{
+		method:    http.MethodGet,
+		path:      "/transaction/" + url.PathEscape(transactionID),
+		retriable: true,
+	}, &transaction); err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+// TransactionStatus handles GET /transaction/{transactionId}/status, reporting
+// whether a transaction submitted for asynchronous processing is pending,
+// processed or failed
+func (c *Client) TransactionStatus(ctx context.Context, transactionID string) (*AsyncTransaction, error) {
+	var response struct {
+		AsyncTransaction
+		Problem json.RawMessage `json:"problem"`
+	}
+	if err := c.do(ctx, request{
+		method:    http.MethodGet,
+		path:      "/transaction/" + url.PathEscape(transactionID) + "/status",
+		retriable: true,
+	}, &response); err != nil {
+		return nil, err
+	}
+
+	status := response.AsyncTransaction
+	if len(response.Problem) > 0 {
+		var problem struct {
+			Status int `json:"status"`
+		}
+		_ = json.Unmarshal(response.Problem, &problem)
+		status.Err = problemError(problem.Status, response.Problem)
+	}
+	return &status, nil
+}
+
+// RefundTransaction handles POST /transaction/{transactionId}/refund. A
+// transaction is refunded once and a second refund fails with ErrConflict, so
+// the request is never retried.
+func (c *Client) RefundTransaction(ctx context.Context, transactionID string) (*TransactionResult, error) {
+	var result TransactionResult
+	if err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/transaction/" + url.PathEscape(transactionID) + "/refund",
+	}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// SettleTransaction handles POST /transaction/{transactionId}/settle.
+// Settling a settled transaction returns the current balance with Replayed
+// set, so the request is retried on transient failures.
+func (c *Client) SettleTransaction(ctx context.Context, transactionID string) (*TransactionResult, error) {
+	var result TransactionResult
+	if err := c.do(ctx, request{
+		method:    http.MethodPost,
+		path:      "/transaction/" + url.PathEscape(transactionID) + "/settle",
+		retriable: true,
+	}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Transfer handles POST /transfers. The transfer ID doubles as idempotency
+// key: the request is retried on transient failures and a retry of an already
+// processed transfer returns the original result with Replayed set.
+func (c *Client) Transfer(ctx context.Context, req TransferRequest) (*TransferResult, error) {
+	if req.TransferID == "" {
+		req.TransferID = uuid.NewString()
+	}
+
+	var result TransferResult
+	if err := c.do(ctx, request{
+		method:    http.MethodPost,
+		path:      "/transfers",
+		body:      req,
+		retriable: true,
+	}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// AnnotateUser handles POST /admin/users/{userId}/annotations. Creating an
+// annotation is not idempotent, so it is never retried.
+func (c *Client) AnnotateUser(ctx context.Context, userID uint64, author, note string) (*Annotation, error) {
+	return c.annotate(ctx, "/admin/users/"+strconv.FormatUint(userID, 10)+"/annotations", author, note)
+}
+
+// AnnotateTransaction handles POST /admin/transactions/{transactionId}/annotations.
+// Creating an annotation is not idempotent, so it is never retried.
+func (c *Client) AnnotateTransaction(ctx context.Context, transactionID, author, note string) (*Annotation, error) {
+	return c.annotate(ctx, "/admin/transactions/"+url.PathEscape(transactionID)+"/annotations", author, note)
+}
+
+// ListUserAnnotations handles GET /admin/users/{userId}/annotations
+func (c *Client) ListUserAnnotations(ctx context.Context, userID uint64) ([]Annotation, error) {
+	return c.listAnnotations(ctx, "/admin/users/"+strconv.FormatUint(userID, 10)+"/annotations")
+}
+
+// ListTransactionAnnotations handles GET /admin/transactions/{transactionId}/annotations
+func (c *Client) ListTransactionAnnotations(ctx context.Context, transactionID string) ([]Annotation, error) {
+	return c.listAnnotations(ctx, "/admin/transactions/"+url.PathEscape(transactionID)+"/annotations")
+}
+
+func (c *Client) annotate(ctx context.Context, path, author, note string) (*Annotation, error) {
+	var annotation Annotation
+	if err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   path,
+		body:   map[string]string{"author": author, "note": note},
+	}, &annotation); err != nil {
+		return nil, err
+	}
+	return &annotation, nil
+}
+
+func (c *Client) listAnnotations(ctx context.Context, path string) ([]Annotation, error) {
+	var response struct {
+		Annotations []Annotation `json:"annotations"`
+	}
+	if err := c.do(ctx, request{
+		method:    http.MethodGet,
+		path:      path,
+		retriable: true,
+	}, &response); err != nil {
+		return nil, err
+	}
+	return response.Annotations, nil
+}
+
+// RegionStatus handles GET /admin/region
+func (c *Client) RegionStatus(ctx context.Context) (*RegionStatus, error) {
+	return c.region(ctx, http.MethodGet, "/admin/region", nil)
+}
+
+// PromoteRegion handles POST /admin/region/promote
+func (c *Client) PromoteRegion(ctx context.Context) (*RegionStatus, error) {
+	return c.region(ctx, http.MethodPost, "/admin/region/promote", nil)
+}
+
+// DemoteRegion handles POST /admin/region/demote, pointing writers at activeRegionURL
+func (c *Client) DemoteRegion(ctx context.Context, activeRegionURL string) (*RegionStatus, error) {
+	return c.region(ctx, http.MethodPost, "/admin/region/demote", map[string]string{"activeRegionUrl": activeRegionURL})
+}
+
+// region calls a region endpoint; promoting and demoting are idempotent
+func (c *Client) region(ctx context.Context, method, path string, body any) (*RegionStatus, error) {
+	var status RegionStatus
+	if err := c.do(ctx, request{
+		method:    method,
+		path:      path,
+		body:      body,
+		retriable: true,
+	}, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// SLO handles GET /admin/slo, returning the error budgets and burn rates of
+// the objectives tracked by the instance that served the request
+func (c *Client) SLO(ctx context.Context) (*SLOReport, error) {
+	var report SLOReport
+	if err := c.do(ctx, request{
+		method:    http.MethodGet,
+		path:      "/admin/slo",
+		retriable: true,
+	}, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
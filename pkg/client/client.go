@@ -0,0 +1,386 @@
+// Package client is the Go SDK for the transaction service REST API. It offers
+// typed methods for every endpoint, retries transient failures and makes
+// transaction processing safe to retry by always sending a transaction ID.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// apiPath is the root of the version of the API the client speaks
+const apiPath = "/api/v1"
+
+// ConsistencyTokenHeader carries the read-your-writes consistency tokens of
+// services serving reads from a replica
+const ConsistencyTokenHeader = "X-Consistency-Token"
+
+// Headers of signed requests
+const (
+	SignatureHeader          = "X-Signature"
+	SignatureTimestampHeader = "X-Signature-Timestamp"
+)
+
+// Default retry settings
+const (
+	DefaultMaxAttempts = 3
+	DefaultBaseDelay   = 100 * time.Millisecond
+	DefaultMaxDelay    = 2 * time.Second
+)
+
+// Client calls the transaction service REST API
+type Client struct {
+	baseURL     *url.URL
+	httpClient  *http.Client
+	bearerToken string
+	apiKey      string
+	// signingSecret, when set, signs every request
+	signingSecret string
+	userAgent     string
+	maxAttempts   int
+	baseDelay     time.Duration
+	maxDelay      time.Duration
+
+	// consistencyToken is the latest write-ahead log position the service
+	// returned, echoed on reads so that they observe the client's writes
+	mu               sync.Mutex
+	consistencyToken string
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used for requests
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithBearerToken authenticates requests with a JWT bearer token
+func WithBearerToken(token string) Option {
+	return func(c *Client) {
+		c.bearerToken = token
+	}
+}
+
+// WithAPIKey sends the integrator's API key in the X-API-Key header
+func WithAPIKey(apiKey string) Option {
+	return func(c *Client) {
+		c.apiKey = apiKey
+	}
+}
+
+// WithRequestSigning signs every request with the HMAC-SHA256 secret shared
+// with the service, for the routes requiring signed requests
+func WithRequestSigning(secret string) Option {
+	return func(c *Client) {
+		c.signingSecret = secret
+	}
+}
+
+// WithUserAgent sets the User-Agent header
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithRetries sets how many times a request is attempted in total and the
+// exponential backoff between attempts. maxAttempts of 1 disables retries.
+func WithRetries(maxAttempts int, baseDelay, maxDelay time.Duration) Option {
+	return func(c *Client) {
+		c.maxAttempts = max(maxAttempts, 1)
+		c.baseDelay = baseDelay
+		c.maxDelay = maxDelay
+	}
+}
+
+// New creates a client for the service at baseURL, e.g. "http://localhost:8080"
+func New(baseURL string, opts ...Option) (*Client, error) {
+	parsed, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q", baseURL)
+	}
+
+	c := &Client{
+		baseURL:     parsed,
+		httpClient:  http.DefaultClient,
+		userAgent:   "transaction-service-go-client",
+		maxAttempts: DefaultMaxAttempts,
+		baseDelay:   DefaultBaseDelay,
+		maxDelay:    DefaultMaxDelay,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// request describes a single API call
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	header http.Header
+	body   any
+	// retriable marks requests that are safe to send more than once
+	retriable bool
+	// accept lists non-2xx statuses whose body is still decoded into out
+	accept []int
+	// unversioned marks the operational routes served outside the API
+	// version
+	unversioned bool
+	// download receives the body of a successful response in place of out,
+	// and trailer its trailers once the body was read
+	download io.Writer
+	trailer  *http.Header
+}
+
+// do sends req, retrying transient failures of retriable requests, and
+// decodes the response into out when it is not nil
+func (c *Client) do(ctx context.Context, req request, out any) error {
+	var body []byte
+	if req.body != nil {
+		var err error
+		if body, err = json.Marshal(req.body); err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+	}
+
+	attempts := 1
+	if req.retriable {
+		attempts = c.maxAttempts
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if err := sleep(ctx, c.backoff(attempt, lastErr)); err != nil {
+				return err
+			}
+		}
+
+		err := c.send(ctx, req, body, out)
+		if err == nil || !isRetriable(err) {
+			return err
+		}
+		lastErr = err
+	}
+	return lastErr
+}
+
+// signRequest returns "sha256=" and the hex HMAC-SHA256 of the timestamp,
+// method, request URI and body, separated by dots
+func signRequest(secret, timestamp, method, requestURI string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + method + "." + requestURI + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// send performs a single attempt
+func (c *Client) send(ctx context.Context, req request, body []byte, out any) error {
+	target := c.baseURL.JoinPath(apiPath, req.path)
+	if req.unversioned {
+		target = c.baseURL.JoinPath(req.path)
+	}
+	target.RawQuery = req.query.Encode()
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, target.String(), reader)
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	for key, values := range req.header {
+		httpReq.Header[key] = values
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	httpReq.Header.Set("User-Agent", c.userAgent)
+	if c.bearerToken != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.bearerToken)
+	}
+	if c.apiKey != "" {
+		httpReq.Header.Set("X-API-Key", c.apiKey)
+	}
+	if token := c.ConsistencyToken(); token != "" && req.method == http.MethodGet {
+		httpReq.Header.Set(ConsistencyTokenHeader, token)
+	}
+	if c.signingSecret != "" {
+		// Every attempt is signed afresh, so retries are not rejected as stale
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		httpReq.Header.Set(SignatureTimestampHeader, timestamp)
+		httpReq.Header.Set(SignatureHeader, signRequest(c.signingSecret, timestamp, req.method, httpReq.URL.RequestURI(), body))
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return &transportError{err: err}
+	}
+	defer resp.Body.Close()
+	c.observeConsistencyToken(resp.Header.Get(ConsistencyTokenHeader))
+
+	if req.download != nil && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		if _, err := io.Copy(req.download, resp.Body); err != nil {
+			return fmt.Errorf("failed to download response: %w", err)
+		}
+		if req.trailer != nil {
+			*req.trailer = resp.Trailer
+		}
+		return nil
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return &transportError{err: err}
+	}
+
+	accepted := resp.StatusCode >= 200 && resp.StatusCode < 300
+	for _, status := range req.accept {
+		accepted = accepted || resp.StatusCode == status
+	}
+	if !accepted {
+		return newAPIError(resp, data)
+	}
+
+	if out != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return nil
+}
+
+// ConsistencyToken returns the token of the latest write the client saw, empty
+// when the service does not serve reads from a replica. Reads echo it
+// automatically; other clients can be handed it to observe the same writes.
+func (c *Client) ConsistencyToken() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.consistencyToken
+}
+
+// observeConsistencyToken keeps token when it is after the current one
+func (c *Client) observeConsistencyToken(token string) {
+	position, ok := parseLogPosition(token)
+	if !ok {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if current, ok := parseLogPosition(c.consistencyToken); !ok || position > current {
+		c.consistencyToken = token
+	}
+}
+
+// parseLogPosition parses a token, a write-ahead log position written as two
+// hexadecimal numbers separated by a slash, e.g. "0/16B3748"
+func parseLogPosition(token string) (uint64, bool) {
+	high, low, ok := strings.Cut(token, "/")
+	if !ok {
+		return 0, false
+	}
+	h, err := strconv.ParseUint(high, 16, 32)
+	if err != nil {
+		return 0, false
+	}
+	l, err := strconv.ParseUint(low, 16, 32)
+	if err != nil {
+		return 0, false
+	}
+	return h<<32 | l, true
+}
+
+// transportError wraps failures to reach the service
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string {
+	return "request failed: " + e.err.Error()
+}
+
+func (e *transportError) Unwrap() error {
+	return e.err
+}
+
+// isRetriable reports whether a failed attempt may succeed when repeated
+func isRetriable(err error) bool {
+	var transportErr *transportError
+	if errors.As(err, &transportErr) {
+		return true
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the delay before the given retry, honouring Retry-After
+func (c *Client) backoff(attempt int, lastErr error) time.Duration {
+	var apiErr *APIError
+	if errors.As(lastErr, &apiErr) && apiErr.RetryAfter > 0 {
+		return apiErr.RetryAfter
+	}
+
+	delay := c.baseDelay << (attempt - 1)
+	if delay <= 0 || delay > c.maxDelay {
+		delay = c.maxDelay
+	}
+	// Full jitter keeps many clients from retrying in lockstep
+	if delay > 0 {
+		delay = time.Duration(rand.Int64N(int64(delay))) + 1
+	}
+	return delay
+}
+
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,777 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc, opts ...Option) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	opts = append([]Option{WithRetries(3, time.Millisecond, time.Millisecond)}, opts...)
+	c, err := New(server.URL, opts...)
+	require.NoError(t, err)
+	return c
+}
+
+func TestClient_ProcessTransaction_RetriesWithSameTransactionID(t *testing.T) {
+	var attempts atomic.Int32
+	transactionIDs := make(chan string, 3)
+
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/user/1/transaction", r.URL.Path)
+		assert.Equal(t, "game", r.Header.Get("Source-Type"))
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "10.5", body["amount"])
+		transactionIDs <- body["transactionId"].(string)
+
+		if attempts.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"transactionId":"` + body["transactionId"].(string) + `","receipt":"7","balance":"110.50","replayed":true}`))
+	}, WithBearerToken("token"))
+
+	result, err := c.ProcessTransaction(context.Background(), 1, SourceGame, TransactionRequest{
+		State:  StateWin,
+		Amount: decimal.RequireFromString("10.50"),
+	})
+	require.NoError(t, err)
+
+	assert.EqualValues(t, 3, attempts.Load())
+	first := <-transactionIDs
+	assert.NotEmpty(t, first)
+	assert.Equal(t, first, <-transactionIDs)
+	assert.Equal(t, first, <-transactionIDs)
+	assert.Equal(t, first, result.TransactionID)
+	assert.True(t, result.Balance.Equal(decimal.RequireFromString("110.50")))
+	assert.True(t, result.Replayed)
+}
+
+func TestClient_ProcessTransactionBatch(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/user/1/transactions/batch", r.URL.Path)
+		assert.Equal(t, "game", r.Header.Get("Source-Type"))
+
+		var body struct {
+			Transactions []map[string]any `json:"transactions"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		require.Len(t, body.Transactions, 2)
+		assert.Equal(t, "tx-1", body.Transactions[0]["transactionId"])
+		assert.NotEmpty(t, body.Transactions[1]["transactionId"])
+
+		_, _ = w.Write([]byte(`{"processed":1,"failed":1,"results":[
+			{"index":0,"transactionId":"tx-1","status":200,"result":{"transactionId":"tx-1","balance":"110.00","replayed":false}},
+			{"index":1,"transactionId":"` + body.Transactions[1]["transactionId"].(string) + `","status":400,
+				"problem":{"code":"insufficient_funds","title":"Insufficient funds","status":400,"detail":"Insufficient funds","requestId":"req-1"}}
+		]}`))
+	})
+
+	outcomes, err := c.ProcessTransactionBatch(context.Background(), 1, SourceGame, []TransactionRequest{
+		{State: StateWin, Amount: decimal.NewFromInt(10), TransactionID: "tx-1"},
+		{State: StateLose, Amount: decimal.NewFromInt(1000)},
+	})
+	require.NoError(t, err)
+	require.Len(t, outcomes, 2)
+
+	require.NoError(t, outcomes[0].Err)
+	assert.Equal(t, "tx-1", outcomes[0].TransactionID)
+	assert.True(t, outcomes[0].Result.Balance.Equal(decimal.NewFromInt(110)))
+
+	assert.Nil(t, outcomes[1].Result)
+	assert.NotEmpty(t, outcomes[1].TransactionID)
+	assert.ErrorIs(t, outcomes[1].Err, ErrBadRequest)
+	var apiErr *APIError
+	require.True(t, errors.As(outcomes[1].Err, &apiErr))
+	assert.Equal(t, CodeInsufficientFunds, apiErr.Code)
+	assert.Equal(t, "req-1", apiErr.RequestID)
+}
+
+func TestClient_Errors(t *testing.T) {
+	var attempts atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.Header().Set("X-Request-ID", "req-1")
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"type":"/problems/user_not_found","code":"user_not_found","title":"User not found","status":404,"detail":"User not found","requestId":"req-1"}`))
+	})
+
+	_, err := c.GetBalance(context.Background(), 42)
+
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrNotFound)
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, CodeUserNotFound, apiErr.Code)
+	assert.Equal(t, "User not found", apiErr.Message)
+	assert.Equal(t, "req-1", apiErr.RequestID)
+	// Client errors are not retried
+	assert.EqualValues(t, 1, attempts.Load())
+}
+
+func TestClient_NonIdempotentRequestsAreNotRetried(t *testing.T) {
+	var attempts atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	_, err := c.AnnotateUser(context.Background(), 1, "support", "called in")
+
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.EqualValues(t, 1, attempts.Load())
+}
+
+func TestClient_GetTransactions(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/user/1/transactions", r.URL.Path)
+		assert.Equal(t, "20", r.URL.Query().Get("limit"))
+		_, _ = w.Write([]byte(`{"transactions":[{"id":7,"userId":1,"transactionId":"tx-1","state":"win","amount":"25.5","sourceType":"game","createdAt":"2025-01-01T12:00:00Z"}],"total":1,"limit":20,"offset":0}`))
+	})
+
+	page, err := c.GetTransactions(context.Background(), 1, Page{Limit: 20})
+	require.NoError(t, err)
+
+	require.Len(t, page.Transactions, 1)
+	assert.Equal(t, "tx-1", page.Transactions[0].TransactionID)
+	assert.Equal(t, StateWin, page.Transactions[0].State)
+	assert.True(t, page.Transactions[0].Amount.Equal(decimal.RequireFromString("25.50")))
+}
+
+func TestClient_GetTransaction(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/transaction/tx 1", r.URL.Path)
+		_, _ = w.Write([]byte(`{"id":7,"userId":1,"transactionId":"tx 1","state":"lose","amount":"5","sourceType":"payment","createdAt":"2025-01-01T12:00:00Z","cancelled":true,"balanceAfter":"20.00"}`))
+	})
+
+	transaction, err := c.GetTransaction(context.Background(), "tx 1")
+	require.NoError(t, err)
+
+	assert.True(t, transaction.Cancelled)
+	require.NotNil(t, transaction.BalanceAfter)
+	assert.True(t, transaction.BalanceAfter.Equal(decimal.RequireFromString("20")))
+}
+
+func TestClient_TransactionStatus(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/transaction/tx-1/status":
+			_, _ = w.Write([]byte(`{"transactionId":"tx-1","userId":1,"status":"processed","statusUrl":"/api/v1/transaction/tx-1/status",
+				"result":{"transactionId":"tx-1","balance":"110.00","replayed":false}}`))
+		case "/api/v1/transaction/tx-2/status":
+			_, _ = w.Write([]byte(`{"transactionId":"tx-2","userId":1,"status":"failed","statusUrl":"/api/v1/transaction/tx-2/status",
+				"problem":{"code":"insufficient_funds","title":"Insufficient funds","status":400,"detail":"Insufficient funds"}}`))
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	})
+
+	status, err := c.TransactionStatus(context.Background(), "tx-1")
+	require.NoError(t, err)
+	assert.Equal(t, AsyncProcessed, status.Status)
+	require.NotNil(t, status.Result)
+	assert.True(t, status.Result.Balance.Equal(decimal.NewFromInt(110)))
+	assert.NoError(t, status.Err)
+
+	status, err = c.TransactionStatus(context.Background(), "tx-2")
+	require.NoError(t, err)
+	assert.Equal(t, AsyncFailed, status.Status)
+	assert.Nil(t, status.Result)
+	assert.ErrorIs(t, status.Err, ErrBadRequest)
+	var apiErr *APIError
+	require.True(t, errors.As(status.Err, &apiErr))
+	assert.Equal(t, CodeInsufficientFunds, apiErr.Code)
+}
+
+func TestClient_GetRoundSummary(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/user/1/rounds/round-7", r.URL.Path)
+		assert.Equal(t, "USD", r.URL.Query().Get("currency"))
+		_, _ = w.Write([]byte(`{"userId":1,"roundId":"round-7","currency":"USD","transactions":2,"cancelled":0,"totalBets":"10.00","totalWins":"12.50","net":"2.50"}`))
+	})
+
+	summary, err := c.GetRoundSummary(context.Background(), 1, "round-7", "USD")
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, summary.Transactions)
+	assert.True(t, summary.Net.Equal(decimal.RequireFromString("2.50")))
+}
+
+func TestClient_GetBalanceAt(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/user/1/balance", r.URL.Path)
+		assert.Equal(t, "2026-05-01T12:00:00Z", r.URL.Query().Get("at"))
+		_, _ = w.Write([]byte(`{"userId":1,"balance":{"userId":1,"balance":"42.50","currency":"EUR","asOf":"2026-05-01T12:00:00Z"}}`))
+	})
+
+	balance, err := c.GetBalanceAt(context.Background(), 1, time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	assert.True(t, balance.Balance.Equal(decimal.RequireFromString("42.50")))
+	require.NotNil(t, balance.AsOf)
+	assert.True(t, balance.AsOf.Equal(time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)))
+}
+
+func TestClient_GetBalanceHistory(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/user/1/balance/history", r.URL.Path)
+		assert.Equal(t, "day", r.URL.Query().Get("granularity"))
+		assert.Equal(t, "2026-05-01T00:00:00Z", r.URL.Query().Get("from"))
+		assert.False(t, r.URL.Query().Has("to"))
+		_, _ = w.Write([]byte(`{"userId":1,"currency":"EUR","granularity":"day","from":"2026-05-01T00:00:00Z","to":"2026-05-03T00:00:00Z","balances":[{"day":"2026-05-01","balance":"10.00"},{"day":"2026-05-02","balance":"12.50"}]}`))
+	})
+
+	history, err := c.GetBalanceHistory(context.Background(), 1, time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC), time.Time{})
+	require.NoError(t, err)
+
+	require.Len(t, history.Balances, 2)
+	assert.Equal(t, "2026-05-02", history.Balances[1].Day)
+	assert.True(t, history.Balances[1].Balance.Equal(decimal.RequireFromString("12.50")))
+}
+
+func TestClient_GetUserStats(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/user/1/stats", r.URL.Path)
+		assert.Equal(t, "USD", r.URL.Query().Get("currency"))
+		assert.Equal(t, "2026-05-01T00:00:00Z", r.URL.Query().Get("from"))
+		assert.Equal(t, "2026-05-08T00:00:00Z", r.URL.Query().Get("to"))
+		_, _ = w.Write([]byte(`{"userId":1,"currency":"USD","from":"2026-05-01T00:00:00Z","to":"2026-05-08T00:00:00Z",` +
+			`"wins":1,"losses":2,"totalWins":"15.00","totalLosses":"20.00","net":"-5.00",` +
+			`"bySourceType":[{"sourceType":"game","wins":1,"losses":2,"totalWins":"15.00","totalLosses":"20.00","net":"-5.00"}],` +
+			`"days":[{"day":"2026-05-02","wins":1,"losses":2,"totalWins":"15.00","totalLosses":"20.00","net":"-5.00"}]}`))
+	})
+
+	stats, err := c.GetUserStats(context.Background(), 1, "USD",
+		time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 5, 8, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, stats.Losses)
+	assert.True(t, stats.Net.Equal(decimal.RequireFromString("-5.00")))
+	require.Len(t, stats.BySourceType, 1)
+	assert.Equal(t, SourceGame, stats.BySourceType[0].SourceType)
+	require.Len(t, stats.Days, 1)
+	assert.True(t, stats.Days[0].TotalWins.Equal(decimal.RequireFromString("15.00")))
+}
+
+func TestClient_CreateSchedule_RetriesWithSameScheduleID(t *testing.T) {
+	var calls atomic.Int32
+	var scheduleIDs []string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/user/1/scheduled-transactions", r.URL.Path)
+		assert.Equal(t, "payment", r.Header.Get("Source-Type"))
+
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "10", body["amount"])
+		assert.Equal(t, "0 9 * * 1", body["cron"])
+		scheduleIDs = append(scheduleIDs, body["scheduleId"].(string))
+
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Idempotent-Replayed", "true")
+		_, _ = w.Write([]byte(`{"scheduleId":"` + scheduleIDs[0] + `","userId":1,"state":"win","amount":"10.00","sourceType":"payment","cron":"0 9 * * 1","runs":0,"status":"active","nextRunAt":"2026-05-04T09:00:00Z","replayed":true}`))
+	})
+
+	schedule, err := c.CreateSchedule(context.Background(), 1, SourcePayment, ScheduleRequest{
+		State:  StateWin,
+		Amount: decimal.RequireFromString("10.00"),
+		Cron:   "0 9 * * 1",
+	})
+	require.NoError(t, err)
+
+	require.Len(t, scheduleIDs, 2)
+	assert.NotEmpty(t, scheduleIDs[0])
+	assert.Equal(t, scheduleIDs[0], scheduleIDs[1])
+	assert.Equal(t, scheduleIDs[0], schedule.ScheduleID)
+	assert.Equal(t, ScheduleActive, schedule.Status)
+	assert.True(t, schedule.Replayed)
+}
+
+func TestClient_ListAndCancelSchedules(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/v1/user/1/scheduled-transactions":
+			_, _ = w.Write([]byte(`{"userId":1,"scheduledTransactions":[{"scheduleId":"weekly","userId":1,"state":"win","amount":"10.00","sourceType":"payment","runs":3,"status":"active"}]}`))
+		case "POST /api/v1/user/1/scheduled-transactions/weekly/cancel":
+			_, _ = w.Write([]byte(`{"scheduleId":"weekly","userId":1,"state":"win","amount":"10.00","sourceType":"payment","runs":3,"status":"cancelled","replayed":false}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	})
+
+	schedules, err := c.ListSchedules(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, schedules, 1)
+	assert.Equal(t, 3, schedules[0].Runs)
+
+	schedule, err := c.CancelSchedule(context.Background(), 1, "weekly")
+	require.NoError(t, err)
+	assert.Equal(t, ScheduleCancelled, schedule.Status)
+}
+
+func TestClient_Holds(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "payment", r.Header.Get("Source-Type"))
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		switch r.URL.Path {
+		case "/api/v1/user/1/holds":
+			assert.JSONEq(t, `{"holdId":"hold-1","amount":"25","expiresIn":"15m0s"}`, string(body))
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"holdId":"hold-1","userId":1,"amount":"25.00","status":"held","expiresAt":"2026-05-01T12:15:00Z","replayed":false}`))
+		case "/api/v1/user/1/holds/hold-1/capture":
+			assert.JSONEq(t, `{"amount":"20"}`, string(body))
+			_, _ = w.Write([]byte(`{"holdId":"hold-1","userId":1,"amount":"25.00","capturedAmount":"20.00","status":"captured","receipt":"rcpt-1","replayed":false}`))
+		case "/api/v1/user/1/holds/hold-1/release":
+			assert.Empty(t, body)
+			w.Header().Set("Idempotent-Replayed", "true")
+			_, _ = w.Write([]byte(`{"holdId":"hold-1","userId":1,"amount":"25.00","status":"released","replayed":true}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	})
+
+	hold, err := c.CreateHold(context.Background(), 1, HoldRequest{
+		HoldID:    "hold-1",
+		Amount:    decimal.NewFromInt(25),
+		ExpiresIn: 15 * time.Minute,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, HoldHeld, hold.Status)
+
+	amount := decimal.NewFromInt(20)
+	hold, err = c.CaptureHold(context.Background(), 1, "hold-1", &amount)
+	require.NoError(t, err)
+	require.NotNil(t, hold.CapturedAmount)
+	assert.True(t, hold.CapturedAmount.Equal(amount))
+	assert.Equal(t, "rcpt-1", hold.Receipt)
+
+	hold, err = c.ReleaseHold(context.Background(), 1, "hold-1")
+	require.NoError(t, err)
+	assert.True(t, hold.Replayed)
+}
+
+func TestClient_SetFeeRule(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		assert.Equal(t, "/api/v1/admin/fees/game/win", r.URL.Path)
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "percentage", body["kind"])
+		assert.Equal(t, "2.5", body["rate"])
+		assert.NotContains(t, body, "sourceType")
+		_, _ = w.Write([]byte(`{"sourceType":"game","state":"win","kind":"percentage","rate":"2.5","amount":"0","updatedAt":"2025-01-01T12:00:00Z"}`))
+	})
+
+	rule, err := c.SetFeeRule(context.Background(), SourceGame, StateWin, FeeRule{
+		SourceType: SourceServer,
+		Kind:       "percentage",
+		Rate:       decimal.RequireFromString("2.5"),
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, SourceGame, rule.SourceType)
+	assert.True(t, rule.Rate.Equal(decimal.RequireFromString("2.5")))
+}
+
+func TestClient_DeleteFeeRule(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodDelete, r.Method)
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":"Fee rule not found"}`))
+	})
+
+	err := c.DeleteFeeRule(context.Background(), SourcePayment, StateLose)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestClient_SystemAccounts(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/admin/system-accounts", r.URL.Path)
+		_, _ = w.Write([]byte(`{"accounts":[{"kind":"house","userId":4,"balance":"-12.50","createdAt":"2025-01-01T12:00:00Z"}]}`))
+	})
+
+	accounts, err := c.SystemAccounts(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, accounts, 1)
+	assert.Equal(t, "house", accounts[0].Kind)
+	assert.True(t, accounts[0].Balance.Equal(decimal.RequireFromString("-12.50")))
+}
+
+func TestClient_AdjustBalance(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/api/v1/admin/users/7/adjustments", r.URL.Path)
+		var req BalanceAdjustmentRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.NotEmpty(t, req.AdjustmentID)
+		assert.Equal(t, "credit", req.Direction)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":1,"adjustmentId":"` + req.AdjustmentID + `","userId":7,"direction":"credit","amount":"5",` +
+			`"reason":"goodwill","actor":"ops","balanceBefore":"10","balanceAfter":"15","createdAt":"2025-01-01T12:00:00Z"}`))
+	})
+
+	adjustment, err := c.AdjustBalance(context.Background(), 7, BalanceAdjustmentRequest{
+		Direction: "credit", Amount: decimal.NewFromInt(5), Reason: "goodwill",
+	})
+	require.NoError(t, err)
+	assert.True(t, adjustment.BalanceAfter.Equal(decimal.NewFromInt(15)))
+	assert.Equal(t, "ops", adjustment.Actor)
+}
+
+func TestClient_AuditLog(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/admin/audit-log", r.URL.Path)
+		assert.Equal(t, "7", r.URL.Query().Get("userId"))
+		assert.Equal(t, "10", r.URL.Query().Get("limit"))
+		_, _ = w.Write([]byte(`{"entries":[{"id":1,"userId":7,"operation":"adjustment","transactionId":"adjustment:a",` +
+			`"change":"-5","balanceBefore":"15","balanceAfter":"10","actor":"ops","createdAt":"2025-01-01T12:00:00Z"}],` +
+			`"total":1,"limit":10,"offset":0}`))
+	})
+
+	page, err := c.AuditLog(context.Background(), AuditLogFilter{UserID: 7, Page: Page{Limit: 10}})
+	require.NoError(t, err)
+	require.Len(t, page.Entries, 1)
+	assert.Equal(t, "adjustment", page.Entries[0].Operation)
+	assert.True(t, page.Entries[0].Change.Equal(decimal.NewFromInt(-5)))
+}
+
+func TestClient_CheckBalances(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/admin/balances/check", r.URL.Path)
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = w.Write([]byte(`{"checkedAt":"2025-01-01T12:00:00Z","checked":3,"unverifiable":0,"repaired":0,` +
+			`"drifts":[{"userId":7,"storedBalance":"20","recomputedBalance":"15","drift":"5","repaired":false}]}`))
+	})
+
+	report, err := c.CheckBalances(context.Background())
+	require.NoError(t, err)
+	require.Len(t, report.Drifts, 1)
+	assert.True(t, report.Drifts[0].Drift.Equal(decimal.NewFromInt(5)))
+}
+
+func TestClient_SLO(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/admin/slo", r.URL.Path)
+		_, _ = w.Write([]byte(`{"window":"720h0m0s","objectives":[{"route":"GET /ping","sli":"availability","target":99.9,"budgetRemaining":-0.5,"burnRates":{"5m":14.4},"withinBudget":false}]}`))
+	})
+
+	report, err := c.SLO(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, report.Objectives, 1)
+	assert.False(t, report.Objectives[0].WithinBudget)
+	assert.Equal(t, 14.4, report.Objectives[0].BurnRates["5m"])
+}
+
+func TestClient_ContextCancellationStopsRetries(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := c.GetBalance(ctx, 1)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestClient_CreateUser(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/api/v1/user", r.URL.Path)
+
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "25.5", body["balance"])
+
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":4,"balance":"25.50","status":"active"}`))
+	})
+
+	user, err := c.CreateUser(context.Background(), decimal.RequireFromString("25.50"))
+	require.NoError(t, err)
+
+	assert.Equal(t, uint64(4), user.ID)
+	assert.Equal(t, "active", user.Status)
+	assert.True(t, user.Balance.Equal(decimal.RequireFromString("25.50")))
+}
+
+func TestClient_EchoesConsistencyTokens(t *testing.T) {
+	tokens := []string{"0/20", "0/10"}
+	var echoed []string
+
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			echoed = append(echoed, r.Header.Get(ConsistencyTokenHeader))
+			_, _ = w.Write([]byte(`{"id": 1, "balance": "10.00"}`))
+			return
+		}
+		assert.Empty(t, r.Header.Get(ConsistencyTokenHeader), "writes go to the primary anyway")
+		w.Header().Set(ConsistencyTokenHeader, tokens[0])
+		tokens = tokens[1:]
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id": 1, "balance": "10.00"}`))
+	})
+
+	ctx := context.Background()
+	_, err := c.GetUser(ctx, 1)
+	require.NoError(t, err)
+
+	_, err = c.CreateUser(ctx, decimal.NewFromInt(10))
+	require.NoError(t, err)
+	_, err = c.GetUser(ctx, 1)
+	require.NoError(t, err)
+
+	// A response that raced an earlier write does not move the token back
+	_, err = c.CreateUser(ctx, decimal.NewFromInt(10))
+	require.NoError(t, err)
+	_, err = c.GetUser(ctx, 1)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"", "0/20", "0/20"}, echoed)
+	assert.Equal(t, "0/20", c.ConsistencyToken())
+}
+
+func TestClient_SignsRequests(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		timestamp := r.Header.Get(SignatureTimestampHeader)
+		assert.Equal(t, signRequest("secret", timestamp, r.Method, r.URL.RequestURI(), body), r.Header.Get(SignatureHeader))
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id": 1, "balance": "10.00"}`))
+	}, WithRequestSigning("secret"))
+
+	_, err := c.CreateUser(context.Background(), decimal.NewFromInt(10))
+	require.NoError(t, err)
+
+	// The service computes the same signature
+	assert.Equal(t,
+		"sha256=a2cc2973eb286b250069b69036570543f705d5b1b6a582e29412f25ac7c16c5d",
+		signRequest("secret", "1748779200", http.MethodPost, "/user/1/transaction?x=1", []byte(`{"a":1}`)),
+	)
+}
+
+func TestClient_HealthRoutesAreUnversioned(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/healthz":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"status":"stalled","dependencies":[{"name":"settlement_worker","policy":"critical","up":false}]}`))
+		case "/readyz":
+			_, _ = w.Write([]byte(`{"status":"ready","dependencies":[]}`))
+		case "/version":
+			_, _ = w.Write([]byte(`{"version":"v1.2.3","modified":false,"goVersion":"go1.24"}`))
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	})
+
+	liveness, err := c.Liveness(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "stalled", liveness.Status)
+
+	readiness, err := c.Readiness(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "ready", readiness.Status)
+
+	info, err := c.Version(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "v1.2.3", info.Version)
+}
+
+func TestClient_ExportTransactions(t *testing.T) {
+	const csv = "id,transactionId\n7,tx-1\n"
+	var fail atomic.Bool
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/user/1/transactions/export", r.URL.Path)
+		assert.Equal(t, "payment", r.URL.Query().Get("sourceType"))
+		w.Header().Set("Trailer", "X-Export-Rows, X-Export-SHA256, X-Export-Error")
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		_, _ = w.Write([]byte(csv))
+		if fail.Load() {
+			w.Header().Set("X-Export-Error", "database unavailable")
+			return
+		}
+		w.Header().Set("X-Export-Rows", "2")
+		w.Header().Set("X-Export-SHA256", "abc123")
+	})
+
+	var buf bytes.Buffer
+	summary, err := c.ExportTransactions(context.Background(), 1, HistoryFilter{SourceType: SourcePayment}, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, csv, buf.String())
+	assert.Equal(t, &ExportSummary{Rows: 2, SHA256: "abc123"}, summary)
+
+	fail.Store(true)
+	buf.Reset()
+	_, err = c.ExportTransactions(context.Background(), 1, HistoryFilter{SourceType: SourcePayment}, &buf)
+	assert.ErrorIs(t, err, ErrIncompleteExport)
+	assert.Equal(t, csv, buf.String())
+}
+
+func TestClient_Webhooks(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/v1/webhooks":
+			var req WebhookRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, SourcePayment, req.Filter.SourceType)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id":3,"url":"` + req.URL + `","secret":"s3cret","events":[],"filter":{"sourceType":"payment"},"active":true,"createdAt":"2025-01-01T12:00:00Z"}`))
+		case "GET /api/v1/webhooks/3/deliveries":
+			assert.Equal(t, "failed", r.URL.Query().Get("status"))
+			assert.Equal(t, "10", r.URL.Query().Get("limit"))
+			_, _ = w.Write([]byte(`{"deliveries":[{"id":9,"webhookId":3,"eventType":"transaction.processed","payload":{"id":9},"status":"failed","attempts":5,"createdAt":"2025-01-01T12:00:00Z"}],"total":1,"limit":10,"offset":0}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	})
+
+	webhook, err := c.RegisterWebhook(context.Background(), WebhookRequest{
+		URL: "https://example.com/hook", Filter: WebhookFilter{SourceType: SourcePayment},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", webhook.Secret)
+
+	page, err := c.ListWebhookDeliveries(context.Background(), webhook.ID, DeliveryFailed, Page{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, page.Deliveries, 1)
+	assert.Equal(t, 5, page.Deliveries[0].Attempts)
+	assert.JSONEq(t, `{"id":9}`, string(page.Deliveries[0].Payload))
+}
+
+func TestClient_Jobs(t *testing.T) {
+	var submissions atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/v1/admin/jobs/export-transactions":
+			submissions.Add(1)
+			var req BulkExportRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, "march", req.Name)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "GET /api/v1/admin/jobs/job-1":
+			_, _ = w.Write([]byte(`{"id":"job-1","type":"export_transactions","status":"completed","total":2,"processed":2,"succeeded":2,"failed":0,"errors":[],"createdAt":"2025-01-01T12:00:00Z"}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	})
+
+	_, err := c.ExportTransactionsJob(context.Background(), BulkExportRequest{Name: "march", UserIDs: []uint64{1, 2}})
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.EqualValues(t, 1, submissions.Load())
+
+	job, err := c.GetJob(context.Background(), "job-1")
+	require.NoError(t, err)
+	assert.Equal(t, BulkJobCompleted, job.Status)
+	assert.Equal(t, 2, job.Succeeded)
+}
+
+func TestClient_Reconcile(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/admin/reconciliation", r.URL.Path)
+		assert.Equal(t, "payment", r.URL.Query().Get("sourceType"))
+		var statement []StatementEntry
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&statement))
+		require.Len(t, statement, 1)
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = w.Write([]byte(`{"sourceType":"payment","reconciled":false,"entries":1,"matched":0,` +
+			`"missing":[{"transactionId":"dep-1","amount":"10"}],"mismatched":[],"extraCount":0,"extra":[]}`))
+	})
+
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	report, err := c.Reconcile(context.Background(), SourcePayment, from, from.AddDate(0, 0, 1), []StatementEntry{
+		{TransactionID: "dep-1", Amount: decimal.NewFromInt(10)},
+	})
+	require.NoError(t, err)
+	assert.False(t, report.Reconciled)
+	require.Len(t, report.Missing, 1)
+	assert.Equal(t, "dep-1", report.Missing[0].TransactionID)
+}
+
+func TestClient_PreviewSettlement(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/admin/settlement/preview", r.URL.Path)
+		assert.Empty(t, r.URL.Query().Get("limit"))
+		_, _ = w.Write([]byte(`{"before":"2025-01-01T12:00:00Z","transactions":2,"users":2,"amount":"65.00","fees":"2.00","net":"63.00",` +
+			`"bySourceType":[{"sourceType":"payment","transactions":2,"amount":"65.00","fees":"2.00"}],` +
+			`"house":{"userId":101,"amount":"2.00","balance":"3.00"},"hasMore":false}`))
+	})
+
+	preview, err := c.PreviewSettlement(context.Background(), 0)
+	require.NoError(t, err)
+	assert.True(t, preview.Net.Equal(decimal.NewFromInt(63)))
+	require.Len(t, preview.BySourceType, 1)
+	assert.Equal(t, SourcePayment, preview.BySourceType[0].SourceType)
+	require.NotNil(t, preview.House)
+	assert.True(t, preview.House.Amount.Equal(decimal.NewFromInt(2)))
+}
+
+func TestClient_StorageMigrationCutoverIsNotRetried(t *testing.T) {
+	var attempts atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/admin/storage-migration/cutover", r.URL.Path)
+		attempts.Add(1)
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte(`{"code":"shadow_backlog","detail":"The shadow store did not catch up in time"}`))
+	})
+
+	_, err := c.CutoverStorageMigration(context.Background())
+	assert.ErrorIs(t, err, ErrConflict)
+	assert.EqualValues(t, 1, attempts.Load())
+}
+
+func TestClient_SyncTransactions(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/sync/transactions", r.URL.Path)
+		assert.Equal(t, "cursor-1", r.URL.Query().Get("since"))
+		assert.Equal(t, "100", r.URL.Query().Get("limit"))
+		_, _ = w.Write([]byte(`{"transactions":[{"id":7,"userId":1,"transactionId":"tx-1","state":"win","amount":"5","sourceType":"game","createdAt":"2025-01-01T12:00:00Z"}],"cursor":"cursor-2","hasMore":true}`))
+	})
+
+	page, err := c.SyncTransactions(context.Background(), "cursor-1", 100)
+	require.NoError(t, err)
+	require.Len(t, page.Transactions, 1)
+	assert.Equal(t, "cursor-2", page.Cursor)
+	assert.True(t, page.HasMore)
+}
+
+func TestClient_AdvanceSandboxClock(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/sandbox/clock/advance", r.URL.Path)
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "24h0m0s", body["duration"])
+		_, _ = w.Write([]byte(`{"now":"2025-01-02T12:00:00Z","offset":"24h0m0s"}`))
+	}, WithAPIKey("sandbox-key"))
+
+	clock, err := c.AdvanceSandboxClock(context.Background(), 24*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, "24h0m0s", clock.Offset)
+}
@@ -0,0 +1,124 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Errors matched by APIError through errors.Is
+var (
+	ErrBadRequest   = errors.New("bad request")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	// ErrStandby is returned by a region in standby; the error's Location
+	// names the same endpoint in the active region
+	ErrStandby = errors.New("region is in standby")
+	// ErrLimitExceeded is returned when a balance guard or loss limit trips
+	ErrLimitExceeded = errors.New("limit exceeded")
+	ErrRateLimited   = errors.New("rate limited")
+	ErrUnavailable   = errors.New("service unavailable")
+)
+
+// ErrIncompleteExport is returned when a transaction export failed after it
+// started streaming, or ended without its trailers
+var ErrIncompleteExport = errors.New("export incomplete")
+
+// Codes of the problems the service reports that callers commonly branch
+// on; see APIError.Code. Codes are stable, unlike the messages.
+const (
+	CodeUserNotFound         = "user_not_found"
+	CodeInsufficientFunds    = "insufficient_funds"
+	CodeDuplicateTransaction = "duplicate_transaction"
+	CodeInvalidAmount        = "invalid_amount"
+	CodeUnsupportedCurrency  = "unsupported_currency"
+	CodeAccountFrozen        = "account_frozen"
+	CodeSourceTypeNotAllowed = "source_type_not_allowed"
+	CodeBalanceChangeLimit   = "balance_change_limit"
+	CodeLossLimit            = "loss_limit"
+	CodeHoldNotActive        = "hold_not_active"
+	CodeRegionStandby        = "region_standby"
+	CodeRateLimited          = "rate_limited"
+	CodeUnavailable          = "unavailable"
+)
+
+// statusErrors maps response statuses to the errors APIError matches
+var statusErrors = map[int]error{
+	http.StatusBadRequest:          ErrBadRequest,
+	http.StatusUnauthorized:        ErrUnauthorized,
+	http.StatusForbidden:           ErrForbidden,
+	http.StatusNotFound:            ErrNotFound,
+	http.StatusConflict:            ErrConflict,
+	http.StatusMisdirectedRequest:  ErrStandby,
+	http.StatusUnprocessableEntity: ErrLimitExceeded,
+	http.StatusTooManyRequests:     ErrRateLimited,
+	http.StatusServiceUnavailable:  ErrUnavailable,
+}
+
+// APIError is a non-successful response from the service
+type APIError struct {
+	StatusCode int
+	// Code is the machine-readable code of the problem, e.g.
+	// CodeInsufficientFunds
+	Code string
+	// Message is the human-readable detail of the problem
+	Message   string
+	RequestID string
+	// Location is set on standby redirects
+	Location   string
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("transaction service returned %d", e.StatusCode)
+	}
+	return fmt.Sprintf("transaction service returned %d: %s", e.StatusCode, e.Message)
+}
+
+// Is lets errors.Is match an APIError against the package's errors by status
+func (e *APIError) Is(target error) bool {
+	return statusErrors[e.StatusCode] == target
+}
+
+// newAPIError reads the problem details (RFC 7807) of a failed response.
+// Deployments predating them report only an "error" message.
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	err := problemError(resp.StatusCode, body)
+	if requestID := resp.Header.Get("X-Request-ID"); requestID != "" {
+		err.RequestID = requestID
+	}
+	err.Location = resp.Header.Get("Location")
+	err.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+	return err
+}
+
+// problemError reads problem details reported with status
+func problemError(status int, body []byte) *APIError {
+	var payload struct {
+		Code      string `json:"code"`
+		Title     string `json:"title"`
+		Detail    string `json:"detail"`
+		Error     string `json:"error"`
+		RequestID string `json:"requestId"`
+	}
+	_ = json.Unmarshal(body, &payload)
+
+	message := payload.Detail
+	for _, fallback := range []string{payload.Title, payload.Error} {
+		if message == "" {
+			message = fallback
+		}
+	}
+
+	return &APIError{
+		StatusCode: status,
+		Code:       payload.Code,
+		Message:    message,
+		RequestID:  payload.RequestID,
+	}
+}
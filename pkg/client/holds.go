@@ -0,0 +1,83 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// holdHeader is the Source-Type every hold route requires
+var holdHeader = http.Header{"Source-Type": []string{string(SourcePayment)}}
+
+// CreateHold handles POST /user/{userId}/holds, reserving an amount of the
+// user's base currency balance until it is captured, released or expires.
+// The hold ID doubles as idempotency key: the request is retried on transient
+// failures and a retry of an already placed hold returns it unchanged with
+// Replayed set.
+func (c *Client) CreateHold(ctx context.Context, userID uint64, req HoldRequest) (*Hold, error) {
+	if req.HoldID == "" {
+		req.HoldID = uuid.NewString()
+	}
+	body := map[string]any{"holdId": req.HoldID, "amount": req.Amount}
+	if req.ExpiresIn > 0 {
+		body["expiresIn"] = req.ExpiresIn.String()
+	}
+
+	var hold Hold
+	if err := c.do(ctx, request{
+		method:    http.MethodPost,
+		path:      userPath(userID, "holds"),
+		header:    holdHeader,
+		body:      body,
+		retriable: true,
+	}, &hold); err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+// CaptureHold handles POST /user/{userId}/holds/{holdId}/capture, debiting
+// amount, or the full hold when nil, and releasing the rest. Capturing is
+// idempotent, so the request is retried; a hold that was already captured
+// with the same amount is returned with Replayed set.
+func (c *Client) CaptureHold(ctx context.Context, userID uint64, holdID string, amount *decimal.Decimal) (*Hold, error) {
+	var body any
+	if amount != nil {
+		body = map[string]any{"amount": amount}
+	}
+
+	var hold Hold
+	if err := c.do(ctx, request{
+		method:    http.MethodPost,
+		path:      holdPath(userID, holdID, "capture"),
+		header:    holdHeader,
+		body:      body,
+		retriable: true,
+	}, &hold); err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+// ReleaseHold handles POST /user/{userId}/holds/{holdId}/release. Releasing
+// is idempotent, so the request is retried; a hold that was already released
+// or expired is returned with Replayed set.
+func (c *Client) ReleaseHold(ctx context.Context, userID uint64, holdID string) (*Hold, error) {
+	var hold Hold
+	if err := c.do(ctx, request{
+		method:    http.MethodPost,
+		path:      holdPath(userID, holdID, "release"),
+		header:    holdHeader,
+		retriable: true,
+	}, &hold); err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+func holdPath(userID uint64, holdID, action string) string {
+	return userPath(userID, "holds/"+url.PathEscape(holdID)+"/"+action)
+}
@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// FreezeUsersJob handles POST /admin/jobs/freeze-users, submitting a job
+// freezing the users. Every submission starts a new job, so it is never
+// retried; freezing a frozen user again is harmless.
+func (c *Client) FreezeUsersJob(ctx context.Context, userIDs []uint64) (*BulkJob, error) {
+	return c.submitJob(ctx, "freeze-users", map[string][]uint64{"userIds": userIDs})
+}
+
+// AdjustBalancesJob handles POST /admin/jobs/adjust-balances. Every
+// submission starts a new job, so it is never retried; submitting the same
+// adjustment ID again does not apply it twice.
+func (c *Client) AdjustBalancesJob(ctx context.Context, req BulkAdjustmentRequest) (*BulkJob, error) {
+	return c.submitJob(ctx, "adjust-balances", req)
+}
+
+// RedeliverWebhooksJob handles POST /admin/jobs/redeliver-webhooks. Every
+// submission starts a new job, so it is never retried.
+func (c *Client) RedeliverWebhooksJob(ctx context.Context, req BulkRedeliveryRequest) (*BulkJob, error) {
+	return c.submitJob(ctx, "redeliver-webhooks", req)
+}
+
+// ExportTransactionsJob handles POST /admin/jobs/export-transactions. Every
+// submission starts a new job, so it is never retried; the finished export is
+// checked with VerifyExport.
+func (c *Client) ExportTransactionsJob(ctx context.Context, req BulkExportRequest) (*BulkJob, error) {
+	return c.submitJob(ctx, "export-transactions", req)
+}
+
+// ListJobs handles GET /admin/jobs
+func (c *Client) ListJobs(ctx context.Context) ([]BulkJob, error) {
+	var result struct {
+		Jobs []BulkJob `json:"jobs"`
+	}
+	if err := c.do(ctx, request{
+		method:    http.MethodGet,
+		path:      "/admin/jobs",
+		retriable: true,
+	}, &result); err != nil {
+		return nil, err
+	}
+	return result.Jobs, nil
+}
+
+// GetJob handles GET /admin/jobs/{jobId}, reporting the progress of a job
+func (c *Client) GetJob(ctx context.Context, jobID string) (*BulkJob, error) {
+	var job BulkJob
+	if err := c.do(ctx, request{
+		method:    http.MethodGet,
+		path:      "/admin/jobs/" + url.PathEscape(jobID),
+		retriable: true,
+	}, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (c *Client) submitJob(ctx context.Context, operation string, body any) (*BulkJob, error) {
+	var job BulkJob
+	if err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/admin/jobs/" + operation,
+		body:   body,
+	}, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
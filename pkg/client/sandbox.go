@@ -0,0 +1,45 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// ResetSandbox handles POST /sandbox/reset, restoring the sandbox users and
+// bringing the sandbox clock back to the wall clock. Like the other sandbox
+// routes, it requires the client's API key to be a sandbox key.
+func (c *Client) ResetSandbox(ctx context.Context) error {
+	return c.do(ctx, request{
+		method:    http.MethodPost,
+		path:      "/sandbox/reset",
+		retriable: true,
+	}, nil)
+}
+
+// SandboxClock handles GET /sandbox/clock
+func (c *Client) SandboxClock(ctx context.Context) (*SandboxClock, error) {
+	var clock SandboxClock
+	if err := c.do(ctx, request{
+		method:    http.MethodGet,
+		path:      "/sandbox/clock",
+		retriable: true,
+	}, &clock); err != nil {
+		return nil, err
+	}
+	return &clock, nil
+}
+
+// AdvanceSandboxClock handles POST /sandbox/clock/advance. The clock only
+// moves forward, and a retry would move it twice, so it is never retried.
+func (c *Client) AdvanceSandboxClock(ctx context.Context, duration time.Duration) (*SandboxClock, error) {
+	var clock SandboxClock
+	if err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/sandbox/clock/advance",
+		body:   map[string]string{"duration": duration.String()},
+	}, &clock); err != nil {
+		return nil, err
+	}
+	return &clock, nil
+}
@@ -0,0 +1,66 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/google/uuid"
+)
+
+// CreateSchedule handles POST /user/{userId}/scheduled-transactions. Runs are
+// processed with sourceType. The schedule ID doubles as idempotency key: the
+// request is retried on transient failures and a retry of an already created
+// schedule returns it unchanged with Replayed set.
+func (c *Client) CreateSchedule(
+	ctx context.Context,
+	userID uint64,
+	sourceType SourceType,
+	req ScheduleRequest,
+) (*ScheduledTransaction, error) {
+	if req.ScheduleID == "" {
+		req.ScheduleID = uuid.NewString()
+	}
+
+	var schedule ScheduledTransaction
+	if err := c.do(ctx, request{
+		method:    http.MethodPost,
+		path:      userPath(userID, "scheduled-transactions"),
+		header:    http.Header{"Source-Type": []string{string(sourceType)}},
+		body:      req,
+		retriable: true,
+	}, &schedule); err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// ListSchedules handles GET /user/{userId}/scheduled-transactions
+func (c *Client) ListSchedules(ctx context.Context, userID uint64) ([]ScheduledTransaction, error) {
+	var response struct {
+		ScheduledTransactions []ScheduledTransaction `json:"scheduledTransactions"`
+	}
+	if err := c.do(ctx, request{
+		method:    http.MethodGet,
+		path:      userPath(userID, "scheduled-transactions"),
+		retriable: true,
+	}, &response); err != nil {
+		return nil, err
+	}
+	return response.ScheduledTransactions, nil
+}
+
+// CancelSchedule handles POST /user/{userId}/scheduled-transactions/{scheduleId}/cancel.
+// Cancelling is idempotent, so the request is retried; a schedule that was
+// already cancelled is returned with Replayed set.
+func (c *Client) CancelSchedule(ctx context.Context, userID uint64, scheduleID string) (*ScheduledTransaction, error) {
+	var schedule ScheduledTransaction
+	if err := c.do(ctx, request{
+		method:    http.MethodPost,
+		path:      userPath(userID, "scheduled-transactions/"+url.PathEscape(scheduleID)+"/cancel"),
+		retriable: true,
+	}, &schedule); err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
@@ -0,0 +1,315 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ProcessTransaction handles POST /user/{userId}/transaction. The transaction
+// ID doubles as idempotency key: the request is retried on transient failures
+// and a retry of an already processed transaction returns the original result
+// with Replayed set.
+func (c *Client) ProcessTransaction(
+	ctx context.Context,
+	userID uint64,
+	sourceType SourceType,
+	req TransactionRequest,
+) (*TransactionResult, error) {
+	if req.TransactionID == "" {
+		req.TransactionID = uuid.NewString()
+	}
+
+	var result TransactionResult
+	if err := c.do(ctx, request{
+		method:    http.MethodPost,
+		path:      userPath(userID, "transaction"),
+		header:    http.Header{"Source-Type": []string{string(sourceType)}},
+		body:      req,
+		retriable: true,
+	}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ProcessTransactionBatch handles POST /user/{userId}/transactions/batch,
+// processing up to 1000 transactions of the user in order, each on its own.
+// Transactions without an ID are given one. The outcomes are in the order of
+// reqs; the error is only set when the batch as a whole failed. Like single
+// transactions, batches are retried on transient failures, replaying the
+// transactions already processed.
+func (c *Client) ProcessTransactionBatch(
+	ctx context.Context,
+	userID uint64,
+	sourceType SourceType,
+	reqs []TransactionRequest,
+) ([]BatchOutcome, error) {
+	transactions := make([]TransactionRequest, len(reqs))
+	for i, req := range reqs {
+		if req.TransactionID == "" {
+			req.TransactionID = uuid.NewString()
+		}
+		transactions[i] = req
+	}
+
+	var response struct {
+		Results []struct {
+			TransactionID string             `json:"transactionId"`
+			Status        int                `json:"status"`
+			Result        *TransactionResult `json:"result"`
+			Problem       json.RawMessage    `json:"problem"`
+		} `json:"results"`
+	}
+	if err := c.do(ctx, request{
+		method:    http.MethodPost,
+		path:      userPath(userID, "transactions/batch"),
+		header:    http.Header{"Source-Type": []string{string(sourceType)}},
+		body:      map[string]any{"transactions": transactions},
+		retriable: true,
+	}, &response); err != nil {
+		return nil, err
+	}
+
+	outcomes := make([]BatchOutcome, len(response.Results))
+	for i, item := range response.Results {
+		outcomes[i].TransactionID = item.TransactionID
+		if item.Result != nil {
+			outcomes[i].Result = item.Result
+			continue
+		}
+		outcomes[i].Err = problemError(item.Status, item.Problem)
+	}
+	return outcomes, nil
+}
+
+// GetBalance handles GET /user/{userId}/balance
+func (c *Client) GetBalance(ctx context.Context, userID uint64) (*Balance, error) {
+	return c.getBalance(ctx, userID, url.Values{})
+}
+
+// GetBalanceAt handles GET /user/{userId}/balance?at=, reconstructing the
+// user's base currency balance as of the past moment at
+func (c *Client) GetBalanceAt(ctx context.Context, userID uint64, at time.Time) (*Balance, error) {
+	query := url.Values{}
+	query.Set("at", at.Format(time.RFC3339Nano))
+	return c.getBalance(ctx, userID, query)
+}
+
+func (c *Client) getBalance(ctx context.Context, userID uint64, query url.Values) (*Balance, error) {
+	var response struct {
+		Balance Balance `json:"balance"`
+	}
+	if err := c.do(ctx, request{
+		method:    http.MethodGet,
+		path:      userPath(userID, "balance"),
+		query:     query,
+		retriable: true,
+	}, &response); err != nil {
+		return nil, err
+	}
+	return &response.Balance, nil
+}
+
+// GetBalanceHistory handles GET /user/{userId}/balance/history, listing the
+// user's daily closing balances from the UTC day of from up to the UTC day of
+// to. Zero times use the service defaults: to is now and from a range before
+// it.
+func (c *Client) GetBalanceHistory(ctx context.Context, userID uint64, from, to time.Time) (*BalanceHistory, error) {
+	query := url.Values{}
+	query.Set("granularity", "day")
+	setTimeRange(query, from, to)
+
+	var history BalanceHistory
+	if err := c.do(ctx, request{
+		method:    http.MethodGet,
+		path:      userPath(userID, "balance/history"),
+		query:     query,
+		retriable: true,
+	}, &history); err != nil {
+		return nil, err
+	}
+	return &history, nil
+}
+
+// GetUserStats handles GET /user/{userId}/stats, totalling the user's wins
+// and losses in currency, or the service's base currency when empty, that
+// occurred in [from, to). Zero times use the service defaults: to is now and
+// from a range before it.
+func (c *Client) GetUserStats(ctx context.Context, userID uint64, currency string, from, to time.Time) (*UserStats, error) {
+	query := url.Values{}
+	if currency != "" {
+		query.Set("currency", currency)
+	}
+	setTimeRange(query, from, to)
+
+	var stats UserStats
+	if err := c.do(ctx, request{
+		method:    http.MethodGet,
+		path:      userPath(userID, "stats"),
+		query:     query,
+		retriable: true,
+	}, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// GetTransactions handles GET /user/{userId}/transactions, newest first
+func (c *Client) GetTransactions(ctx context.Context, userID uint64, page Page) (*TransactionPage, error) {
+	var result TransactionPage
+	if err := c.do(ctx, request{
+		method:    http.MethodGet,
+		path:      userPath(userID, "transactions"),
+		query:     page.values(url.Values{}),
+		retriable: true,
+	}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ExportTransactions handles GET /user/{userId}/transactions/export,
+// streaming the user's history matching filter into w as CSV, newest first.
+// The export is written as it arrives, so it is never retried. An export that
+// failed after its first row returns ErrIncompleteExport, with the rows
+// written so far left in w.
+func (c *Client) ExportTransactions(ctx context.Context, userID uint64, filter HistoryFilter, w io.Writer) (*ExportSummary, error) {
+	query := url.Values{}
+	if filter.SourceType != "" {
+		query.Set("sourceType", string(filter.SourceType))
+	}
+	if filter.State != "" {
+		query.Set("state", string(filter.State))
+	}
+	if filter.From != nil {
+		query.Set("from", filter.From.Format(time.RFC3339Nano))
+	}
+	if filter.To != nil {
+		query.Set("to", filter.To.Format(time.RFC3339Nano))
+	}
+	if filter.Cancelled != nil {
+		query.Set("cancelled", strconv.FormatBool(*filter.Cancelled))
+	}
+
+	var trailer http.Header
+	if err := c.do(ctx, request{
+		method:   http.MethodGet,
+		path:     userPath(userID, "transactions/export"),
+		query:    query,
+		download: w,
+		trailer:  &trailer,
+	}, nil); err != nil {
+		return nil, err
+	}
+
+	if message := trailer.Get("X-Export-Error"); message != "" {
+		return nil, fmt.Errorf("%w: %s", ErrIncompleteExport, message)
+	}
+	rows, err := strconv.ParseInt(trailer.Get("X-Export-Rows"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: missing row count", ErrIncompleteExport)
+	}
+	return &ExportSummary{Rows: rows, SHA256: trailer.Get("X-Export-SHA256")}, nil
+}
+
+// GetRoundSummary handles GET /user/{userId}/rounds/{roundId}. The round is
+// totalled in currency, or the service's base currency when empty.
+func (c *Client) GetRoundSummary(ctx context.Context, userID uint64, roundID, currency string) (*RoundSummary, error) {
+	query := url.Values{}
+	if currency != "" {
+		query.Set("currency", currency)
+	}
+
+	var summary RoundSummary
+	if err := c.do(ctx, request{
+		method:    http.MethodGet,
+		path:      userPath(userID, "rounds/"+url.PathEscape(roundID)),
+		query:     query,
+		retriable: true,
+	}, &summary); err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// Readiness handles GET /readyz. An unready service is reported through the
+// report's status rather than an error.
+func (c *Client) Readiness(ctx context.Context) (*ReadinessReport, error) {
+	var report ReadinessReport
+	if err := c.do(ctx, request{
+		method:      http.MethodGet,
+		path:        "/readyz",
+		accept:      []int{http.StatusServiceUnavailable},
+		unversioned: true,
+		retriable:   true,
+	}, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Liveness handles GET /healthz. A stalled worker is reported through the
+// report's status, "alive" or "stalled", rather than an error.
+func (c *Client) Liveness(ctx context.Context) (*ReadinessReport, error) {
+	var report ReadinessReport
+	if err := c.do(ctx, request{
+		method:      http.MethodGet,
+		path:        "/healthz",
+		accept:      []int{http.StatusServiceUnavailable},
+		unversioned: true,
+		retriable:   true,
+	}, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Version handles GET /version
+func (c *Client) Version(ctx context.Context) (*BuildInfo, error) {
+	var info BuildInfo
+	if err := c.do(ctx, request{
+		method:      http.MethodGet,
+		path:        "/version",
+		unversioned: true,
+		retriable:   true,
+	}, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// setTimeRange adds from and to to query unless they are zero
+func setTimeRange(query url.Values, from, to time.Time) {
+	if !from.IsZero() {
+		query.Set("from", from.Format(time.RFC3339Nano))
+	}
+	if !to.IsZero() {
+		query.Set("to", to.Format(time.RFC3339Nano))
+	}
+}
+
+func userPath(userID uint64, resource string) string {
+	return "/user/" + strconv.FormatUint(userID, 10) + "/" + resource
+}
+
+// values adds the page parameters to query
+func (p Page) values(query url.Values) url.Values {
+	if p.Limit != 0 {
+		query.Set("limit", strconv.Itoa(p.Limit))
+	}
+	if p.Offset != 0 {
+		query.Set("offset", strconv.Itoa(p.Offset))
+	}
+	if p.Cursor != "" {
+		query.Set("cursor", p.Cursor)
+	}
+	return query
+}
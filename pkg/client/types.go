@@ -0,0 +1,995 @@
+package client
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// SourceType identifies the system a transaction comes from
+type SourceType string
+
+// Source types accepted by the service
+const (
+	SourceGame    SourceType = "game"
+	SourceServer  SourceType = "server"
+	SourcePayment SourceType = "payment"
+)
+
+// State is the direction of a transaction
+type State string
+
+// Transaction states accepted by the service
+const (
+	StateWin  State = "win"
+	StateLose State = "lose"
+)
+
+// TransactionRequest is the body of a transaction. When TransactionID is
+// empty the client generates one, so retries are recognised as replays.
+type TransactionRequest struct {
+	State         State           `json:"state"`
+	Amount        decimal.Decimal `json:"amount"`
+	TransactionID string          `json:"transactionId"`
+	// Currency is the ISO 4217 code of the balance to move; the service's
+	// base currency when empty
+	Currency   string     `json:"currency,omitempty"`
+	OccurredAt *time.Time `json:"occurredAt,omitempty"`
+	// RoundID optionally groups the transactions of a game round
+	RoundID string `json:"roundId,omitempty"`
+	// Metadata optionally attaches references such as a table ID or the
+	// provider's reference; keys are letters, digits, '_', '-' and '.'
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// TransactionResult is the outcome of processing a transaction
+type TransactionResult struct {
+	// ID is the internal ID of the recorded transaction
+	ID            uint64          `json:"id"`
+	TransactionID string          `json:"transactionId"`
+	Receipt       string          `json:"receipt"`
+	Balance       decimal.Decimal `json:"balance"`
+	Currency      string          `json:"currency,omitempty"`
+	// Fee is the fee charged for the transaction, already deducted from
+	// Balance; zero when none was charged
+	Fee decimal.Decimal `json:"fee"`
+	// Pending is set when the transaction awaits settlement, so Balance does
+	// not include it yet
+	Pending bool `json:"pending,omitempty"`
+	// Replayed is set when the transaction had already been processed
+	Replayed bool `json:"replayed"`
+}
+
+// BatchOutcome is the outcome of a transaction of a batch: its result when it
+// was processed, the *APIError it was rejected with otherwise
+type BatchOutcome struct {
+	TransactionID string
+	Result        *TransactionResult
+	Err           error
+}
+
+// AsyncStatus is the status of a transaction submitted for asynchronous
+// processing
+type AsyncStatus string
+
+const (
+	AsyncPending   AsyncStatus = "pending"
+	AsyncProcessed AsyncStatus = "processed"
+	AsyncFailed    AsyncStatus = "failed"
+)
+
+// AsyncTransaction reports a transaction submitted for asynchronous
+// processing: its result once processed, the *APIError it was rejected with
+// once failed
+type AsyncTransaction struct {
+	TransactionID string             `json:"transactionId"`
+	UserID        uint64             `json:"userId"`
+	Status        AsyncStatus        `json:"status"`
+	SubmittedAt   *time.Time         `json:"submittedAt,omitempty"`
+	FinishedAt    *time.Time         `json:"finishedAt,omitempty"`
+	Result        *TransactionResult `json:"result,omitempty"`
+	Err           error              `json:"-"`
+}
+
+// Balance is a user's current balance
+type Balance struct {
+	UserID  uint64          `json:"userId"`
+	Balance decimal.Decimal `json:"balance"`
+	// Currency is the base currency Balance is held in
+	Currency string `json:"currency,omitempty"`
+	// Balances maps every currency the user holds to its balance
+	Balances map[string]decimal.Decimal `json:"balances,omitempty"`
+	// Available is Balance less the active holds; nil when holds are not
+	// enabled
+	Available *decimal.Decimal `json:"available,omitempty"`
+	// Pending is the total of the credits awaiting settlement, not yet
+	// included in Balance; zero when settlement is not enabled
+	Pending decimal.Decimal `json:"pending"`
+	// Stale is set when the balance was served from cache during a database
+	// outage; AsOf is when the cached value was read, or the past moment a
+	// point-in-time balance was requested for
+	Stale bool       `json:"stale,omitempty"`
+	AsOf  *time.Time `json:"asOf,omitempty"`
+}
+
+// HoldRequest is the body of a hold. When HoldID is empty the client
+// generates one, so retries are recognised as replays. ExpiresIn is the
+// service default when zero.
+type HoldRequest struct {
+	HoldID    string
+	Amount    decimal.Decimal
+	ExpiresIn time.Duration
+}
+
+// HoldStatus is the lifecycle status of a hold
+type HoldStatus string
+
+const (
+	HoldHeld     HoldStatus = "held"
+	HoldCaptured HoldStatus = "captured"
+	HoldReleased HoldStatus = "released"
+	HoldExpired  HoldStatus = "expired"
+)
+
+// Hold is an amount reserved from a user's base currency balance
+type Hold struct {
+	HoldID string          `json:"holdId"`
+	UserID uint64          `json:"userId"`
+	Amount decimal.Decimal `json:"amount"`
+	// CapturedAmount is the amount moved by the capture, at most Amount
+	CapturedAmount *decimal.Decimal `json:"capturedAmount,omitempty"`
+	Status         HoldStatus       `json:"status"`
+	CreatedAt      time.Time        `json:"createdAt"`
+	ExpiresAt      time.Time        `json:"expiresAt"`
+	// SettledAt is when the hold was captured, released or expired
+	SettledAt *time.Time `json:"settledAt,omitempty"`
+	// Receipt identifies the payment transaction recorded by the capture
+	Receipt string `json:"receipt,omitempty"`
+	// Replayed is set when the request had already been applied
+	Replayed bool `json:"replayed"`
+}
+
+// BalanceHistory lists a user's closing balances from the UTC day of From up
+// to the UTC day of To, which is left out
+type BalanceHistory struct {
+	UserID uint64 `json:"userId"`
+	// Currency is the base currency the balances are held in
+	Currency    string    `json:"currency"`
+	Granularity string    `json:"granularity"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	// Balances only lists the days with a snapshot
+	Balances []ClosingBalance `json:"balances"`
+}
+
+// ClosingBalance is a user's balance at the end of a UTC day
+type ClosingBalance struct {
+	// Day is formatted as YYYY-MM-DD
+	Day     string          `json:"day"`
+	Balance decimal.Decimal `json:"balance"`
+}
+
+// UserStats counts and totals a user's wins and losses in one currency that
+// occurred in [From, To), overall, by source type and by UTC day
+type UserStats struct {
+	UserID   uint64    `json:"userId"`
+	Currency string    `json:"currency"`
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	StatsTotals
+	BySourceType []SourceTypeStats `json:"bySourceType"`
+	// Days lists the days with transactions, oldest first
+	Days []DayStats `json:"days"`
+}
+
+// StatsTotals count and sum the wins and losses of a set of transactions.
+// Net is the wins minus the losses.
+type StatsTotals struct {
+	Wins        int             `json:"wins"`
+	Losses      int             `json:"losses"`
+	TotalWins   decimal.Decimal `json:"totalWins"`
+	TotalLosses decimal.Decimal `json:"totalLosses"`
+	Net         decimal.Decimal `json:"net"`
+}
+
+// SourceTypeStats totals the transactions of one source type
+type SourceTypeStats struct {
+	SourceType SourceType `json:"sourceType"`
+	StatsTotals
+}
+
+// DayStats totals the transactions of a UTC day, formatted as YYYY-MM-DD
+type DayStats struct {
+	Day string `json:"day"`
+	StatsTotals
+}
+
+// ScheduleRequest is the body of a scheduled transaction: a one-off
+// transaction processed at RunAt, or a recurring one following Cron. When
+// ScheduleID is empty the client generates one, so retries are recognised as
+// replays.
+type ScheduleRequest struct {
+	ScheduleID string          `json:"scheduleId"`
+	State      State           `json:"state"`
+	Amount     decimal.Decimal `json:"amount"`
+	// Currency is the ISO 4217 code of the balance to move; the service's
+	// base currency when empty
+	Currency string            `json:"currency,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	RunAt    *time.Time        `json:"runAt,omitempty"`
+	// Cron is a five-field cron expression, evaluated in UTC
+	Cron string `json:"cron,omitempty"`
+	// MaxRuns optionally bounds the runs of a recurring transaction
+	MaxRuns int `json:"maxRuns,omitempty"`
+}
+
+// ScheduleStatus is the lifecycle status of a scheduled transaction
+type ScheduleStatus string
+
+const (
+	ScheduleActive    ScheduleStatus = "active"
+	ScheduleCompleted ScheduleStatus = "completed"
+	ScheduleCancelled ScheduleStatus = "cancelled"
+)
+
+// ScheduledTransaction is a transaction scheduled for later or recurring
+// processing
+type ScheduledTransaction struct {
+	ScheduleID string            `json:"scheduleId"`
+	UserID     uint64            `json:"userId"`
+	State      State             `json:"state"`
+	Amount     decimal.Decimal   `json:"amount"`
+	SourceType SourceType        `json:"sourceType"`
+	Currency   string            `json:"currency,omitempty"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+	Cron       string            `json:"cron,omitempty"`
+	MaxRuns    int               `json:"maxRuns,omitempty"`
+	// Runs counts the runs so far, including the failed ones
+	Runs   int            `json:"runs"`
+	Status ScheduleStatus `json:"status"`
+	// NextRunAt is nil once the schedule is no longer active
+	NextRunAt         *time.Time `json:"nextRunAt,omitempty"`
+	LastRunAt         *time.Time `json:"lastRunAt,omitempty"`
+	LastTransactionID string     `json:"lastTransactionId,omitempty"`
+	// LastError is the reason the last run was rejected; empty when it
+	// succeeded
+	LastError string    `json:"lastError,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Replayed is set when the request had already been applied
+	Replayed bool `json:"replayed"`
+}
+
+// User is a user account
+type User struct {
+	ID           uint64          `json:"id"`
+	Balance      decimal.Decimal `json:"balance"`
+	Status       string          `json:"status"`
+	CreditLimit  decimal.Decimal `json:"creditLimit"`
+	Jurisdiction string          `json:"jurisdiction,omitempty"`
+	// DormantAt is when the user was flagged as dormant for not transacting
+	DormantAt *time.Time `json:"dormantAt,omitempty"`
+}
+
+// UserPage is a page of users ordered by ID
+type UserPage struct {
+	Users  []User `json:"users"`
+	Total  int    `json:"total"`
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+}
+
+// Transaction is a recorded transaction
+type Transaction struct {
+	ID            uint64           `json:"id"`
+	UserID        uint64           `json:"userId"`
+	TransactionID string           `json:"transactionId"`
+	Receipt       string           `json:"receipt"`
+	State         State            `json:"state"`
+	Amount        decimal.Decimal  `json:"amount"`
+	SourceType    SourceType       `json:"sourceType"`
+	Currency      string           `json:"currency,omitempty"`
+	OccurredAt    *time.Time       `json:"occurredAt,omitempty"`
+	CreatedAt     time.Time        `json:"createdAt"`
+	Cancelled     bool             `json:"cancelled"`
+	CancelledAt   *time.Time       `json:"cancelledAt,omitempty"`
+	BalanceAfter  *decimal.Decimal `json:"balanceAfter,omitempty"`
+	RoundID       string           `json:"roundId,omitempty"`
+	// Type is "refund" for the records compensating a transaction,
+	// "transfer" for the legs of a transfer and "fee" for charged fees
+	Type string `json:"type"`
+	// Reverses is the transaction ID a refund compensates, and ReversedBy the
+	// transaction ID of the refund of a refunded transaction
+	Reverses   string `json:"reverses,omitempty"`
+	ReversedBy string `json:"reversedBy,omitempty"`
+	// TransferID is the transfer a transfer leg was recorded for
+	TransferID string `json:"transferId,omitempty"`
+	// ChargedFor is the transaction ID a fee was charged for
+	ChargedFor string `json:"chargedFor,omitempty"`
+	// Metadata holds the references attached to the transaction
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Pending is set while a payment credit awaits settlement, and SettledAt
+	// once it was settled
+	Pending   bool       `json:"pending,omitempty"`
+	SettledAt *time.Time `json:"settledAt,omitempty"`
+}
+
+// TransferRequest is the body of a transfer. When TransferID is empty the
+// client generates one, so retries are recognised as replays.
+type TransferRequest struct {
+	TransferID string          `json:"transferId"`
+	FromUserID uint64          `json:"fromUserId"`
+	ToUserID   uint64          `json:"toUserId"`
+	Amount     decimal.Decimal `json:"amount"`
+	// Currency is the ISO 4217 code of the balances to move; the service's
+	// base currency when empty
+	Currency string `json:"currency,omitempty"`
+}
+
+// TransferResult is the outcome of processing a transfer
+type TransferResult struct {
+	TransferID string          `json:"transferId"`
+	Amount     decimal.Decimal `json:"amount"`
+	Currency   string          `json:"currency,omitempty"`
+	// From is the debit of the sender and To the credit of the recipient
+	From TransferLeg `json:"from"`
+	To   TransferLeg `json:"to"`
+	// Replayed is set when the transfer had already been processed
+	Replayed bool `json:"replayed"`
+}
+
+// TransferLeg is the transaction a transfer recorded for one of its users
+type TransferLeg struct {
+	UserID        uint64          `json:"userId"`
+	TransactionID string          `json:"transactionId"`
+	Receipt       string          `json:"receipt"`
+	Balance       decimal.Decimal `json:"balance"`
+}
+
+// RoundSummary totals the transactions of a game round in one currency.
+// Bets are the lost amounts; cancelled transactions are left out. Net is the
+// wins less the bets and the fees charged for the round.
+type RoundSummary struct {
+	UserID       uint64          `json:"userId"`
+	RoundID      string          `json:"roundId"`
+	Currency     string          `json:"currency"`
+	Transactions int             `json:"transactions"`
+	Cancelled    int             `json:"cancelled"`
+	TotalBets    decimal.Decimal `json:"totalBets"`
+	TotalWins    decimal.Decimal `json:"totalWins"`
+	TotalFees    decimal.Decimal `json:"totalFees"`
+	Net          decimal.Decimal `json:"net"`
+}
+
+// TransactionPage is a page of transactions with the total number of matches.
+// NextCursor continues after the page, and is empty on the last page.
+type TransactionPage struct {
+	Transactions []Transaction `json:"transactions"`
+	Total        int           `json:"total"`
+	Limit        int           `json:"limit"`
+	Offset       int           `json:"offset"`
+	NextCursor   string        `json:"nextCursor,omitempty"`
+}
+
+// Page selects a page of results; zero values use the service defaults.
+// Cursor, the NextCursor of the previous page, replaces Offset when paging
+// through transactions.
+type Page struct {
+	Limit  int
+	Offset int
+	Cursor string
+}
+
+// TransactionFilter narrows the admin transaction search; zero values match
+// everything
+type TransactionFilter struct {
+	UserID              uint64
+	TransactionIDPrefix string
+	SourceType          SourceType
+	State               State
+	MinAmount           *decimal.Decimal
+	MaxAmount           *decimal.Decimal
+	From                *time.Time
+	To                  *time.Time
+	Cancelled           *bool
+	// MetadataKey only matches transactions with the metadata key, whose
+	// value is MetadataValue unless it is nil
+	MetadataKey   string
+	MetadataValue *string
+	Page
+}
+
+// DependencyReport is the readiness of a single dependency
+type DependencyReport struct {
+	Name      string `json:"name"`
+	Policy    string `json:"policy"`
+	Up        bool   `json:"up"`
+	Error     string `json:"error,omitempty"`
+	LatencyMs int64  `json:"latencyMs"`
+}
+
+// ReadinessReport is the aggregated readiness of the service: "ready",
+// "degraded" or "unready"
+type ReadinessReport struct {
+	Status       string             `json:"status"`
+	Dependencies []DependencyReport `json:"dependencies"`
+}
+
+// ExportFile describes an exported file as recorded in its manifest
+type ExportFile struct {
+	Path   string `json:"path"`
+	Rows   int64  `json:"rows"`
+	Bytes  int64  `json:"bytes"`
+	SHA256 string `json:"sha256"`
+}
+
+// ExportFileResult is the verification outcome of a single exported file
+type ExportFileResult struct {
+	Path     string      `json:"path"`
+	Valid    bool        `json:"valid"`
+	Expected ExportFile  `json:"expected"`
+	Actual   *ExportFile `json:"actual,omitempty"`
+	Error    string      `json:"error,omitempty"`
+}
+
+// ExportReport is the outcome of verifying an export against its manifest
+type ExportReport struct {
+	Name      string             `json:"name"`
+	CreatedAt time.Time          `json:"createdAt"`
+	Valid     bool               `json:"valid"`
+	Files     []ExportFileResult `json:"files"`
+}
+
+// Annotation is a support note attached to a user or transaction
+type Annotation struct {
+	ID         uint64    `json:"id"`
+	TargetType string    `json:"targetType"`
+	TargetID   string    `json:"targetId"`
+	Author     string    `json:"author"`
+	Note       string    `json:"note"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// RegionStatus describes the active-passive mode of a region
+type RegionStatus struct {
+	Name            string    `json:"name,omitempty"`
+	Mode            string    `json:"mode"`
+	ActiveRegionURL string    `json:"activeRegionUrl,omitempty"`
+	ChangedAt       time.Time `json:"changedAt"`
+}
+
+// IngestionReport is the outcome of verifying that the transactions created
+// in [From, To) were ingested exactly once
+type IngestionReport struct {
+	From                   time.Time                `json:"from"`
+	To                     time.Time                `json:"to"`
+	Valid                  bool                     `json:"valid"`
+	UniqueConstraint       bool                     `json:"uniqueConstraint"`
+	Transactions           int                      `json:"transactions"`
+	DistinctTransactionIDs int                      `json:"distinctTransactionIds"`
+	Duplicates             []DuplicateTransactionID `json:"duplicates"`
+	Rejections             []DuplicateSubmissions   `json:"rejections"`
+}
+
+// DuplicateTransactionID is a transaction ID recorded Count times
+type DuplicateTransactionID struct {
+	TransactionID string `json:"transactionId"`
+	Count         int    `json:"count"`
+}
+
+// DuplicateSubmissions counts the duplicate submissions of a source type
+// within the minute starting at Start
+type DuplicateSubmissions struct {
+	Start      time.Time  `json:"start"`
+	SourceType SourceType `json:"sourceType"`
+	Replayed   int        `json:"replayed"`
+	Rejected   int        `json:"rejected"`
+}
+
+// Rejection is a rejected transaction attempt, with the values as they were
+// submitted
+type Rejection struct {
+	ID            uint64     `json:"id"`
+	UserID        uint64     `json:"userId"`
+	TransactionID string     `json:"transactionId"`
+	SourceType    SourceType `json:"sourceType"`
+	State         string     `json:"state"`
+	Amount        string     `json:"amount"`
+	Currency      string     `json:"currency,omitempty"`
+	// Reason is the code of the error the attempt was rejected with
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// RejectionPage is a page of rejections with the total number of matches
+type RejectionPage struct {
+	Rejections []Rejection `json:"rejections"`
+	Total      int         `json:"total"`
+	Limit      int         `json:"limit"`
+	Offset     int         `json:"offset"`
+}
+
+// RejectionFilter narrows the rejection listing; zero values match everything
+type RejectionFilter struct {
+	SourceType SourceType
+	Reason     string
+	From       *time.Time
+	To         *time.Time
+	Page
+}
+
+// RejectionReport is the rejection rate of every source type with attempts
+// in [From, To)
+type RejectionReport struct {
+	From    time.Time          `json:"from"`
+	To      time.Time          `json:"to"`
+	Sources []SourceRejections `json:"sources"`
+}
+
+// SourceRejections are the attempts of a source type and the rejections
+// among them, counted by reason
+type SourceRejections struct {
+	SourceType    SourceType     `json:"sourceType"`
+	Attempts      int            `json:"attempts"`
+	Rejected      int            `json:"rejected"`
+	RejectionRate float64        `json:"rejectionRate"`
+	Reasons       map[string]int `json:"reasons"`
+}
+
+// FeeRule is the fee charged for the transactions of a source type and
+// state. Kind is "percentage", charging Rate percent of the amount, "flat",
+// charging Amount, or "tiered", charging the rate and amount of the last tier
+// whose From the amount reaches.
+type FeeRule struct {
+	SourceType SourceType      `json:"sourceType,omitempty"`
+	State      State           `json:"state,omitempty"`
+	Kind       string          `json:"kind"`
+	Rate       decimal.Decimal `json:"rate"`
+	Amount     decimal.Decimal `json:"amount"`
+	Tiers      []FeeTier       `json:"tiers,omitempty"`
+	UpdatedAt  time.Time       `json:"updatedAt"`
+}
+
+// FeeTier is a tier of a tiered fee rule
+type FeeTier struct {
+	From   decimal.Decimal `json:"from"`
+	Rate   decimal.Decimal `json:"rate"`
+	Amount decimal.Decimal `json:"amount"`
+}
+
+// SystemAccount is a user owned by the operator, the counterparty of fees,
+// sweeps and corrections. Kind is "house", "promo" or "escheat". Its Balance
+// may be negative.
+type SystemAccount struct {
+	Kind      string          `json:"kind"`
+	UserID    uint64          `json:"userId"`
+	Balance   decimal.Decimal `json:"balance"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+// BalanceAdjustmentRequest asks to credit or debit a user by hand.
+// Direction is "credit" or "debit".
+type BalanceAdjustmentRequest struct {
+	// AdjustmentID identifies the adjustment for retries; generated when empty
+	AdjustmentID string          `json:"adjustmentId"`
+	Direction    string          `json:"direction"`
+	Amount       decimal.Decimal `json:"amount"`
+	// Currency is the ISO 4217 code of the balance to adjust; the service's
+	// base currency when empty
+	Currency string `json:"currency,omitempty"`
+	Reason   string `json:"reason"`
+	// Actor names the operator making the adjustment; the subject of the
+	// bearer token takes precedence
+	Actor string `json:"actor,omitempty"`
+}
+
+// BalanceAdjustment is the audit record of a manual balance adjustment,
+// recorded in the user's history under TransactionID
+type BalanceAdjustment struct {
+	ID            uint64          `json:"id"`
+	AdjustmentID  string          `json:"adjustmentId"`
+	UserID        uint64          `json:"userId"`
+	TransactionID string          `json:"transactionId"`
+	Direction     string          `json:"direction"`
+	Amount        decimal.Decimal `json:"amount"`
+	Currency      string          `json:"currency,omitempty"`
+	Reason        string          `json:"reason"`
+	Actor         string          `json:"actor"`
+	BalanceBefore decimal.Decimal `json:"balanceBefore"`
+	BalanceAfter  decimal.Decimal `json:"balanceAfter"`
+	CreatedAt     time.Time       `json:"createdAt"`
+	// Replayed is set when the adjustment had already been made
+	Replayed bool `json:"replayed,omitempty"`
+}
+
+// AuditEntry records a balance-affecting operation in the audit log
+type AuditEntry struct {
+	ID        uint64 `json:"id"`
+	UserID    uint64 `json:"userId"`
+	Operation string `json:"operation"`
+	// TransactionID is the transaction written or, for cancellations,
+	// cancelled by the operation
+	TransactionID string          `json:"transactionId"`
+	Change        decimal.Decimal `json:"change"`
+	Currency      string          `json:"currency,omitempty"`
+	BalanceBefore decimal.Decimal `json:"balanceBefore"`
+	BalanceAfter  decimal.Decimal `json:"balanceAfter"`
+	Actor         string          `json:"actor,omitempty"`
+	CreatedAt     time.Time       `json:"createdAt"`
+}
+
+// AuditLogPage is a page of audit entries with the total number of matches
+type AuditLogPage struct {
+	Entries []AuditEntry `json:"entries"`
+	Total   int          `json:"total"`
+	Limit   int          `json:"limit"`
+	Offset  int          `json:"offset"`
+}
+
+// AuditLogFilter narrows the audit log; zero values match everything
+type AuditLogFilter struct {
+	UserID uint64
+	From   *time.Time
+	To     *time.Time
+	Page
+}
+
+// BalanceDrift is a balance that differs from the one recomputed from the
+// user's transactions
+type BalanceDrift struct {
+	UserID            uint64          `json:"userId"`
+	Currency          string          `json:"currency,omitempty"`
+	StoredBalance     decimal.Decimal `json:"storedBalance"`
+	RecomputedBalance decimal.Decimal `json:"recomputedBalance"`
+	Drift             decimal.Decimal `json:"drift"`
+	Repaired          bool            `json:"repaired"`
+}
+
+// BalanceCheckReport is the outcome of recomputing every balance from the
+// transactions
+type BalanceCheckReport struct {
+	CheckedAt    time.Time      `json:"checkedAt"`
+	Checked      int            `json:"checked"`
+	Unverifiable int            `json:"unverifiable"`
+	Drifts       []BalanceDrift `json:"drifts"`
+	Repaired     int            `json:"repaired"`
+}
+
+// SLOReport is the state of the SLOs tracked by an instance
+type SLOReport struct {
+	Window     string      `json:"window"`
+	Objectives []SLOStatus `json:"objectives"`
+}
+
+// SLOStatus is how the availability or latency of a route fares against its
+// target
+type SLOStatus struct {
+	Route           string             `json:"route"`
+	SLI             string             `json:"sli"`
+	Target          float64            `json:"target"`
+	Threshold       string             `json:"threshold,omitempty"`
+	Requests        uint64             `json:"requests"`
+	Bad             uint64             `json:"bad"`
+	Attainment      float64            `json:"attainment"`
+	BudgetRemaining float64            `json:"budgetRemaining"`
+	BurnRates       map[string]float64 `json:"burnRates"`
+	WithinBudget    bool               `json:"withinBudget"`
+}
+
+// HistoryFilter narrows a user's transaction history; zero values match
+// everything
+type HistoryFilter struct {
+	SourceType SourceType
+	State      State
+	From       *time.Time
+	To         *time.Time
+	Cancelled  *bool
+}
+
+// ExportSummary describes a complete transaction export as its export
+// manifest would: its row count, including the header, and SHA-256 checksum
+type ExportSummary struct {
+	Rows   int64
+	SHA256 string
+}
+
+// WebhookFilter narrows the events delivered to a webhook; zero values match
+// every event
+type WebhookFilter struct {
+	UserID     uint64     `json:"userId,omitempty"`
+	SourceType SourceType `json:"sourceType,omitempty"`
+	State      State      `json:"state,omitempty"`
+}
+
+// WebhookRequest is the body of a webhook registration. Events lists the
+// event types to deliver; every type when empty.
+type WebhookRequest struct {
+	URL    string        `json:"url"`
+	Events []string      `json:"events,omitempty"`
+	Filter WebhookFilter `json:"filter"`
+}
+
+// Webhook is a registered webhook
+type Webhook struct {
+	ID  uint64 `json:"id"`
+	URL string `json:"url"`
+	// Secret signs the deliveries; it is only returned on registration
+	Secret    string        `json:"secret,omitempty"`
+	Events    []string      `json:"events"`
+	Filter    WebhookFilter `json:"filter"`
+	Active    bool          `json:"active"`
+	CreatedAt time.Time     `json:"createdAt"`
+}
+
+// DeliveryStatus is the status of a webhook delivery
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// WebhookDelivery is an event queued for, or delivered to, a webhook
+type WebhookDelivery struct {
+	ID             uint64          `json:"id"`
+	WebhookID      uint64          `json:"webhookId"`
+	EventType      string          `json:"eventType"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         DeliveryStatus  `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"nextAttemptAt,omitempty"`
+	LastStatusCode int             `json:"lastStatusCode,omitempty"`
+	LastError      string          `json:"lastError,omitempty"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	DeliveredAt    *time.Time      `json:"deliveredAt,omitempty"`
+}
+
+// WebhookDeliveryPage is a page of a webhook's deliveries, newest first, with
+// the total number of matches
+type WebhookDeliveryPage struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+	Total      int               `json:"total"`
+	Limit      int               `json:"limit"`
+	Offset     int               `json:"offset"`
+}
+
+// BulkJobStatus is the lifecycle status of a bulk job
+type BulkJobStatus string
+
+const (
+	BulkJobQueued    BulkJobStatus = "queued"
+	BulkJobRunning   BulkJobStatus = "running"
+	BulkJobCompleted BulkJobStatus = "completed"
+	// BulkJobCancelled jobs were interrupted by a shutdown; their processed
+	// items stay applied
+	BulkJobCancelled BulkJobStatus = "cancelled"
+)
+
+// BulkJob is an admin operation applied to many items in the background
+type BulkJob struct {
+	ID     string        `json:"id"`
+	Type   string        `json:"type"`
+	Status BulkJobStatus `json:"status"`
+	// Total is the number of items; Processed of them were handled so far,
+	// either successfully or not
+	Total     int `json:"total"`
+	Processed int `json:"processed"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	// Errors lists the first failed items
+	Errors     []BulkJobError `json:"errors"`
+	CreatedAt  time.Time      `json:"createdAt"`
+	StartedAt  *time.Time     `json:"startedAt,omitempty"`
+	FinishedAt *time.Time     `json:"finishedAt,omitempty"`
+}
+
+// BulkJobError describes an item a bulk job failed to process
+type BulkJobError struct {
+	Item  string `json:"item"`
+	Error string `json:"error"`
+}
+
+// BulkAdjustmentRequest applies the same adjustment to every listed user, as
+// the transaction or, with system accounts, the transfer with the ID
+// "{adjustmentId}:{userId}". Counterparty names the system account of the
+// transfers, the house when empty.
+type BulkAdjustmentRequest struct {
+	AdjustmentID string          `json:"adjustmentId"`
+	UserIDs      []uint64        `json:"userIds"`
+	State        State           `json:"state"`
+	Amount       decimal.Decimal `json:"amount"`
+	Currency     string          `json:"currency,omitempty"`
+	Counterparty string          `json:"counterparty,omitempty"`
+}
+
+// BulkRedeliveryRequest delivers again the webhook deliveries created in
+// [From, To), for one webhook or, when WebhookID is zero, for every active
+// webhook
+type BulkRedeliveryRequest struct {
+	WebhookID uint64    `json:"webhookId,omitempty"`
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+}
+
+// BulkExportRequest exports the transaction histories of the listed users,
+// created in [From, To) when given, as the export Name
+type BulkExportRequest struct {
+	Name    string     `json:"name"`
+	UserIDs []uint64   `json:"userIds"`
+	From    *time.Time `json:"from,omitempty"`
+	To      *time.Time `json:"to,omitempty"`
+}
+
+// StatementEntry is a transaction as listed in the statement of a source
+// system. State is only compared when set.
+type StatementEntry struct {
+	TransactionID string          `json:"transactionId"`
+	Amount        decimal.Decimal `json:"amount"`
+	State         State           `json:"state,omitempty"`
+}
+
+// ReconciliationMismatch is a statement entry whose transaction the ledger
+// recorded differently
+type ReconciliationMismatch struct {
+	Statement StatementEntry `json:"statement"`
+	Ledger    *Transaction   `json:"ledger"`
+	// Differences names what differs: amount, state, sourceType or
+	// cancelled
+	Differences []string `json:"differences"`
+}
+
+// ReconciliationReport is the outcome of matching the statement of a source
+// system with the transactions of the source type created in [From, To)
+type ReconciliationReport struct {
+	SourceType SourceType `json:"sourceType"`
+	From       time.Time  `json:"from"`
+	To         time.Time  `json:"to"`
+	// Reconciled is set when every entry matched and nothing is extra
+	Reconciled bool                     `json:"reconciled"`
+	Entries    int                      `json:"entries"`
+	Matched    int                      `json:"matched"`
+	Missing    []StatementEntry         `json:"missing"`
+	Mismatched []ReconciliationMismatch `json:"mismatched"`
+	// ExtraCount is the number of transactions the statement does not list,
+	// of which Extra holds the first ones, newest first
+	ExtraCount int           `json:"extraCount"`
+	Extra      []Transaction `json:"extra"`
+}
+
+// SettlementPreview is the effect that settling the next batch of pending
+// transactions would have
+type SettlementPreview struct {
+	// Before is the cutoff of the batch, which holds the oldest pending
+	// transactions recorded before it
+	Before       time.Time       `json:"before"`
+	Transactions int             `json:"transactions"`
+	Users        int             `json:"users"`
+	Amount       decimal.Decimal `json:"amount"`
+	Fees         decimal.Decimal `json:"fees"`
+	Net          decimal.Decimal `json:"net"`
+	// BySourceType totals the batch by source system, i.e. by provider
+	BySourceType []SettlementTotals `json:"bySourceType"`
+	// House is nil without system accounts
+	House   *HouseMovement `json:"house,omitempty"`
+	HasMore bool           `json:"hasMore"`
+}
+
+// SettlementTotals totals the transactions of a settlement batch recorded
+// with one source type
+type SettlementTotals struct {
+	SourceType   SourceType      `json:"sourceType"`
+	Transactions int             `json:"transactions"`
+	Amount       decimal.Decimal `json:"amount"`
+	Fees         decimal.Decimal `json:"fees"`
+}
+
+// HouseMovement is the fees of a settlement batch credited to the house
+// account, along with its current balance
+type HouseMovement struct {
+	UserID  uint64          `json:"userId"`
+	Amount  decimal.Decimal `json:"amount"`
+	Balance decimal.Decimal `json:"balance"`
+}
+
+// RestoreMarker records the contents of the database when a backup is taken
+type RestoreMarker struct {
+	Name          string              `json:"name"`
+	SchemaVersion int                 `json:"schemaVersion"`
+	WALPosition   string              `json:"walPosition"`
+	Fingerprint   DatabaseFingerprint `json:"fingerprint"`
+	CreatedAt     time.Time           `json:"createdAt"`
+}
+
+// DatabaseFingerprint summarizes the contents of the database
+type DatabaseFingerprint struct {
+	Tables   []TableFingerprint `json:"tables"`
+	Balances BalanceConsistency `json:"balances"`
+}
+
+// TableFingerprint is the row count and checksum of a table
+type TableFingerprint struct {
+	Table    string `json:"table"`
+	Rows     int64  `json:"rows"`
+	Checksum string `json:"checksum"`
+}
+
+// BalanceConsistency counts the balances breaking the ledger invariants
+type BalanceConsistency struct {
+	Negative         int64 `json:"negative"`
+	LedgerMismatches int64 `json:"ledgerMismatches"`
+}
+
+// RestoreVerificationReport is the outcome of verifying a restored database
+// against a restore marker
+type RestoreVerificationReport struct {
+	Name          string              `json:"name"`
+	CreatedAt     time.Time           `json:"createdAt"`
+	Valid         bool                `json:"valid"`
+	SchemaVersion SchemaVersionResult `json:"schemaVersion"`
+	// Tables and Balances are only compared when the schema versions match
+	Tables   []TableResult  `json:"tables,omitempty"`
+	Balances *BalanceResult `json:"balances,omitempty"`
+}
+
+// SchemaVersionResult compares the schema version of the restored database
+// with the marker's
+type SchemaVersionResult struct {
+	Valid    bool `json:"valid"`
+	Expected int  `json:"expected"`
+	Actual   int  `json:"actual"`
+}
+
+// TableResult compares the fingerprint of a restored table with the marker's
+type TableResult struct {
+	Table    string            `json:"table"`
+	Valid    bool              `json:"valid"`
+	Expected TableFingerprint  `json:"expected"`
+	Actual   *TableFingerprint `json:"actual,omitempty"`
+}
+
+// BalanceResult compares the balance consistency of the restored database
+// with the marker's
+type BalanceResult struct {
+	Valid    bool               `json:"valid"`
+	Expected BalanceConsistency `json:"expected"`
+	Actual   BalanceConsistency `json:"actual"`
+}
+
+// StorageMigrationStatus reports a storage migration: the phase, "dual_write"
+// or "cutover", and the progress of the shadow store
+type StorageMigrationStatus struct {
+	Phase string `json:"phase"`
+	// Pending is the number of committed writes not yet applied to the shadow
+	Pending    int       `json:"pending"`
+	Applied    uint64    `json:"applied"`
+	Failed     uint64    `json:"failed"`
+	Dropped    uint64    `json:"dropped"`
+	Compared   uint64    `json:"compared"`
+	Mismatches uint64    `json:"mismatches"`
+	ChangedAt  time.Time `json:"changedAt"`
+}
+
+// SyncPage is a page of the change feed. Cursor is passed as since to read
+// the next page.
+type SyncPage struct {
+	Transactions []Transaction `json:"transactions"`
+	Cursor       string        `json:"cursor"`
+	HasMore      bool          `json:"hasMore"`
+}
+
+// SandboxClock is the virtual clock of the sandbox, Offset ahead of the wall
+// clock
+type SandboxClock struct {
+	Now    time.Time `json:"now"`
+	Offset string    `json:"offset"`
+}
+
+// BuildInfo describes the build of the running service
+type BuildInfo struct {
+	Version    string `json:"version"`
+	Commit     string `json:"commit,omitempty"`
+	CommitTime string `json:"commitTime,omitempty"`
+	Modified   bool   `json:"modified"`
+	GoVersion  string `json:"goVersion"`
+}
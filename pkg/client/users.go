@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/shopspring/decimal"
+)
+
+// CreateUser handles POST /user. Retrying could create a second user, so the
+// request is never retried.
+func (c *Client) CreateUser(ctx context.Context, balance decimal.Decimal) (*User, error) {
+	var user User
+	if err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/user",
+		body:   map[string]string{"balance": balance.String()},
+	}, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUser handles GET /user/{userId}
+func (c *Client) GetUser(ctx context.Context, userID uint64) (*User, error) {
+	var user User
+	if err := c.do(ctx, request{
+		method:    http.MethodGet,
+		path:      "/user/" + strconv.FormatUint(userID, 10),
+		retriable: true,
+	}, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ListUsers handles GET /users
+func (c *Client) ListUsers(ctx context.Context, page Page) (*UserPage, error) {
+	var result UserPage
+	if err := c.do(ctx, request{
+		method:    http.MethodGet,
+		path:      "/users",
+		query:     page.values(url.Values{}),
+		retriable: true,
+	}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// FreezeUser handles POST /user/{userId}/freeze. Freezing is idempotent, so
+// the request is retried.
+func (c *Client) FreezeUser(ctx context.Context, userID uint64) (*User, error) {
+	return c.setUserStatus(ctx, userID, "freeze")
+}
+
+// UnfreezeUser handles POST /user/{userId}/unfreeze
+func (c *Client) UnfreezeUser(ctx context.Context, userID uint64) (*User, error) {
+	return c.setUserStatus(ctx, userID, "unfreeze")
+}
+
+func (c *Client) setUserStatus(ctx context.Context, userID uint64, action string) (*User, error) {
+	var user User
+	if err := c.do(ctx, request{
+		method:    http.MethodPost,
+		path:      "/user/" + strconv.FormatUint(userID, 10) + "/" + action,
+		retriable: true,
+	}, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
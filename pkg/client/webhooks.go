@@ -0,0 +1,85 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// RegisterWebhook handles POST /webhooks. The returned webhook carries the
+// secret signing its deliveries, which is not returned again. Registering is
+// not idempotent, so it is never retried.
+func (c *Client) RegisterWebhook(ctx context.Context, req WebhookRequest) (*Webhook, error) {
+	var webhook Webhook
+	if err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/webhooks",
+		body:   req,
+	}, &webhook); err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// ListWebhooks handles GET /webhooks
+func (c *Client) ListWebhooks(ctx context.Context) ([]Webhook, error) {
+	var result struct {
+		Webhooks []Webhook `json:"webhooks"`
+	}
+	if err := c.do(ctx, request{
+		method:    http.MethodGet,
+		path:      "/webhooks",
+		retriable: true,
+	}, &result); err != nil {
+		return nil, err
+	}
+	return result.Webhooks, nil
+}
+
+// GetWebhook handles GET /webhooks/{webhookId}
+func (c *Client) GetWebhook(ctx context.Context, webhookID uint64) (*Webhook, error) {
+	var webhook Webhook
+	if err := c.do(ctx, request{
+		method:    http.MethodGet,
+		path:      webhookPath(webhookID),
+		retriable: true,
+	}, &webhook); err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// DeleteWebhook handles DELETE /webhooks/{webhookId}. Deleting a webhook
+// that does not exist fails with ErrNotFound.
+func (c *Client) DeleteWebhook(ctx context.Context, webhookID uint64) error {
+	return c.do(ctx, request{
+		method: http.MethodDelete,
+		path:   webhookPath(webhookID),
+	}, nil)
+}
+
+// ListWebhookDeliveries handles GET /webhooks/{webhookId}/deliveries,
+// returning a page of the webhook's deliveries with the status, or of any
+// status when empty, newest first
+func (c *Client) ListWebhookDeliveries(ctx context.Context, webhookID uint64, status DeliveryStatus, page Page) (*WebhookDeliveryPage, error) {
+	query := url.Values{}
+	if status != "" {
+		query.Set("status", string(status))
+	}
+
+	var result WebhookDeliveryPage
+	if err := c.do(ctx, request{
+		method:    http.MethodGet,
+		path:      webhookPath(webhookID) + "/deliveries",
+		query:     page.values(query),
+		retriable: true,
+	}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func webhookPath(webhookID uint64) string {
+	return "/webhooks/" + strconv.FormatUint(webhookID, 10)
+}
@@ -0,0 +1,229 @@
+package contract
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/shopspring/decimal"
+)
+
+// contractAmount is the amount the checks move; it is won and lost again so
+// the test user ends where it started
+var contractAmount = decimal.RequireFromString("1.25")
+
+// Checks returns the documented behaviors in the order they are verified
+func Checks() []Check {
+	return []Check{
+		{Name: "missing Source-Type header is rejected with 400", Run: checkMissingSourceType},
+		{Name: "unknown Source-Type is rejected with 400", Run: checkInvalidSourceType},
+		{Name: "malformed user ID is rejected with 400", Run: checkInvalidUserID},
+		{Name: "malformed body is rejected with 400", Run: checkInvalidBody},
+		{Name: "invalid state is rejected with 400", Run: checkInvalidState},
+		{Name: "non-positive amount is rejected with 400", Run: checkInvalidAmount},
+		{Name: "unknown user is rejected with 404", Run: checkUnknownUser},
+		{Name: "request ID is echoed", Run: checkRequestID},
+		{Name: "transaction is applied and replays are idempotent", Run: checkDuplicateHandling},
+		{Name: "transaction ID reuse for a different transaction is rejected with 409", Run: checkConflictingDuplicate},
+		{Name: "overdraft is rejected with 400 and leaves the balance unchanged", Run: checkInsufficientFunds},
+	}
+}
+
+func transactionBody(state, amount, transactionID string) map[string]string {
+	return map[string]string{
+		"state":         state,
+		"amount":        amount,
+		"transactionId": transactionID,
+	}
+}
+
+func checkMissingSourceType(ctx context.Context, v *Verifier) error {
+	resp, err := v.call(ctx, http.MethodPost, transactionPath(v.cfg.UserID), map[string]string{},
+		transactionBody("win", contractAmount.String(), v.transactionID("no-source")))
+	if err != nil {
+		return err
+	}
+	return resp.expectStatus(http.StatusBadRequest)
+}
+
+func checkInvalidSourceType(ctx context.Context, v *Verifier) error {
+	resp, err := v.call(ctx, http.MethodPost, transactionPath(v.cfg.UserID), map[string]string{"Source-Type": "casino"},
+		transactionBody("win", contractAmount.String(), v.transactionID("bad-source")))
+	if err != nil {
+		return err
+	}
+	return resp.expectStatus(http.StatusBadRequest)
+}
+
+func checkInvalidUserID(ctx context.Context, v *Verifier) error {
+	resp, err := v.call(ctx, http.MethodPost, "/user/abc/transaction", nil,
+		transactionBody("win", contractAmount.String(), v.transactionID("bad-user")))
+	if err != nil {
+		return err
+	}
+	return resp.expectStatus(http.StatusBadRequest)
+}
+
+func checkInvalidBody(ctx context.Context, v *Verifier) error {
+	resp, err := v.call(ctx, http.MethodPost, transactionPath(v.cfg.UserID), nil, `{"state":`)
+	if err != nil {
+		return err
+	}
+	return resp.expectStatus(http.StatusBadRequest)
+}
+
+func checkInvalidState(ctx context.Context, v *Verifier) error {
+	resp, err := v.call(ctx, http.MethodPost, transactionPath(v.cfg.UserID), nil,
+		transactionBody("draw", contractAmount.String(), v.transactionID("bad-state")))
+	if err != nil {
+		return err
+	}
+	return resp.expectStatus(http.StatusBadRequest)
+}
+
+func checkInvalidAmount(ctx context.Context, v *Verifier) error {
+	for _, amount := range []string{"0", "-1.00", "ten"} {
+		resp, err := v.call(ctx, http.MethodPost, transactionPath(v.cfg.UserID), nil,
+			transactionBody("win", amount, v.transactionID("bad-amount")))
+		if err != nil {
+			return err
+		}
+		if err := resp.expectStatus(http.StatusBadRequest); err != nil {
+			return fmt.Errorf("amount %q: %w", amount, err)
+		}
+	}
+	return nil
+}
+
+func checkUnknownUser(ctx context.Context, v *Verifier) error {
+	resp, err := v.call(ctx, http.MethodPost, transactionPath(v.cfg.UnknownUserID), nil,
+		transactionBody("win", contractAmount.String(), v.transactionID("unknown-user")))
+	if err != nil {
+		return err
+	}
+	return resp.expectStatus(http.StatusNotFound)
+}
+
+func checkRequestID(ctx context.Context, v *Verifier) error {
+	requestID := v.transactionID("request-id")
+	resp, err := v.call(ctx, http.MethodGet, "/user/"+strconv.FormatUint(v.cfg.UserID, 10)+"/balance",
+		map[string]string{"X-Request-ID": requestID}, nil)
+	if err != nil {
+		return err
+	}
+	if got := resp.header.Get("X-Request-ID"); got != requestID {
+		return fmt.Errorf("expected X-Request-ID %q, got %q", requestID, got)
+	}
+	return nil
+}
+
+func checkDuplicateHandling(ctx context.Context, v *Verifier) error {
+	before, err := v.balance(ctx)
+	if err != nil {
+		return err
+	}
+
+	transactionID := v.transactionID("win")
+	body := transactionBody("win", contractAmount.String(), transactionID)
+	first, err := v.call(ctx, http.MethodPost, transactionPath(v.cfg.UserID), nil, body)
+	if err != nil {
+		return err
+	}
+	if err := first.expectStatus(http.StatusOK); err != nil {
+		return err
+	}
+	if first.field("transactionId") != transactionID {
+		return fmt.Errorf("expected transactionId %q, got %q", transactionID, first.field("transactionId"))
+	}
+	balance, err := decimal.NewFromString(first.field("balance"))
+	if err != nil || !balance.Equal(before.Add(contractAmount)) {
+		return fmt.Errorf("expected balance %s, got %q", before.Add(contractAmount), first.field("balance"))
+	}
+
+	replay, err := v.call(ctx, http.MethodPost, transactionPath(v.cfg.UserID), nil, body)
+	if err != nil {
+		return err
+	}
+	if err := replay.expectStatus(http.StatusOK); err != nil {
+		return fmt.Errorf("replay: %w", err)
+	}
+	if replay.field("replayed") != "true" || replay.header.Get("Idempotent-Replayed") != "true" {
+		return fmt.Errorf("replay is not marked as replayed: %v", replay.body)
+	}
+	if replay.field("balance") != first.field("balance") {
+		return fmt.Errorf("replay returned balance %q instead of the original %q", replay.field("balance"), first.field("balance"))
+	}
+
+	after, err := v.balance(ctx)
+	if err != nil {
+		return err
+	}
+	if !after.Equal(before.Add(contractAmount)) {
+		return fmt.Errorf("replay changed the balance: expected %s, got %s", before.Add(contractAmount), after)
+	}
+
+	// Restore the test user's balance
+	restore, err := v.call(ctx, http.MethodPost, transactionPath(v.cfg.UserID), nil,
+		transactionBody("lose", contractAmount.String(), v.transactionID("win-restore")))
+	if err != nil {
+		return err
+	}
+	return restore.expectStatus(http.StatusOK)
+}
+
+func checkConflictingDuplicate(ctx context.Context, v *Verifier) error {
+	transactionID := v.transactionID("conflict")
+	first, err := v.call(ctx, http.MethodPost, transactionPath(v.cfg.UserID), nil,
+		transactionBody("win", contractAmount.String(), transactionID))
+	if err != nil {
+		return err
+	}
+	if err := first.expectStatus(http.StatusOK); err != nil {
+		return err
+	}
+
+	conflict, err := v.call(ctx, http.MethodPost, transactionPath(v.cfg.UserID), nil,
+		transactionBody("win", contractAmount.Add(contractAmount).String(), transactionID))
+	if err != nil {
+		return err
+	}
+	conflictErr := conflict.expectStatus(http.StatusConflict)
+
+	// Restore the test user's balance
+	restore, err := v.call(ctx, http.MethodPost, transactionPath(v.cfg.UserID), nil,
+		transactionBody("lose", contractAmount.String(), v.transactionID("conflict-restore")))
+	if err != nil {
+		return err
+	}
+	if conflictErr != nil {
+		return conflictErr
+	}
+	return restore.expectStatus(http.StatusOK)
+}
+
+func checkInsufficientFunds(ctx context.Context, v *Verifier) error {
+	before, err := v.balance(ctx)
+	if err != nil {
+		return err
+	}
+
+	overdraft := before.Add(contractAmount)
+	resp, err := v.call(ctx, http.MethodPost, transactionPath(v.cfg.UserID), nil,
+		transactionBody("lose", overdraft.StringFixed(2), v.transactionID("overdraft")))
+	if err != nil {
+		return err
+	}
+	if err := resp.expectStatus(http.StatusBadRequest); err != nil {
+		return err
+	}
+
+	after, err := v.balance(ctx)
+	if err != nil {
+		return err
+	}
+	if !after.Equal(before) {
+		return fmt.Errorf("rejected transaction changed the balance from %s to %s", before, after)
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+// Package contract verifies that a deployment of the transaction service, or
+// a stand-in used by an integrator's tests, behaves as documented: header
+// requirements, error codes and duplicate handling. It talks plain HTTP so
+// that it checks the wire contract rather than any client library.
+package contract
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Config describes the deployment under test
+type Config struct {
+	// BaseURL of the REST API, e.g. "https://sandbox.example.com"
+	BaseURL string
+	// UserID is an existing test user. Checks move its balance but leave it
+	// where it started.
+	UserID uint64
+	// UnknownUserID must not exist; defaults to the largest possible ID
+	UnknownUserID uint64
+	// SourceType the checks submit transactions with; defaults to "game"
+	SourceType string
+	// APIKey and BearerToken authenticate the checks when set
+	APIKey      string
+	BearerToken string
+	HTTPClient  *http.Client
+}
+
+// Result is the outcome of a single check
+type Result struct {
+	Name     string        `json:"name"`
+	Passed   bool          `json:"passed"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Report is the outcome of a verification run
+type Report struct {
+	Passed  bool     `json:"passed"`
+	Results []Result `json:"results"`
+}
+
+// Check is a single documented behavior
+type Check struct {
+	Name string
+	Run  func(ctx context.Context, v *Verifier) error
+}
+
+// Verifier runs checks against a deployment
+type Verifier struct {
+	cfg    Config
+	client *http.Client
+	// runID keeps transaction IDs of separate runs apart
+	runID string
+}
+
+// NewVerifier creates a verifier for the deployment described by cfg
+func NewVerifier(cfg Config) (*Verifier, error) {
+	if cfg.BaseURL == "" {
+		return nil, fmt.Errorf("base URL is required")
+	}
+	if cfg.UserID == 0 {
+		return nil, fmt.Errorf("test user ID is required")
+	}
+	if cfg.UnknownUserID == 0 {
+		cfg.UnknownUserID = 1<<63 - 1
+	}
+	if cfg.SourceType == "" {
+		cfg.SourceType = "game"
+	}
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &Verifier{
+		cfg:    cfg,
+		client: client,
+		runID:  uuid.NewString()[:8],
+	}, nil
+}
+
+// Run executes every check in order. Checks keep running after a failure so
+// the report lists everything that deviates from the contract.
+func (v *Verifier) Run(ctx context.Context) Report {
+	report := Report{Passed: true}
+	for _, check := range Checks() {
+		start := time.Now()
+		err := check.Run(ctx, v)
+
+		result := Result{Name: check.Name, Passed: err == nil, Duration: time.Since(start)}
+		if err != nil {
+			result.Error = err.Error()
+			report.Passed = false
+		}
+		report.Results = append(report.Results, result)
+	}
+	return report
+}
+
+// response is a decoded API response
+type response struct {
+	status int
+	header http.Header
+	body   map[string]any
+}
+
+// field returns a top-level string or number field as a string
+func (r *response) field(name string) string {
+	switch value := r.body[name].(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(value)
+	default:
+		return ""
+	}
+}
+
+// expectStatus fails unless the response has the given status
+func (r *response) expectStatus(status int) error {
+	if r.status != status {
+		return fmt.Errorf("expected status %d, got %d: %v", status, r.status, r.body)
+	}
+	return nil
+}
+
+// call sends a request to the deployment. A nil header map sends the
+// configured Source-Type.
+func (v *Verifier) call(ctx context.Context, method, path string, header map[string]string, body any) (*response, error) {
+	var reader io.Reader
+	if body != nil {
+		var data []byte
+		switch b := body.(type) {
+		case string:
+			data = []byte(b)
+		default:
+			var err error
+			if data, err = json.Marshal(b); err != nil {
+				return nil, err
+			}
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, v.cfg.BaseURL+apiPath+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if v.cfg.APIKey != "" {
+		req.Header.Set("X-API-Key", v.cfg.APIKey)
+	}
+	if v.cfg.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+v.cfg.BearerToken)
+	}
+	if header == nil {
+		header = map[string]string{"Source-Type": v.cfg.SourceType}
+	}
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	result := &response{status: resp.StatusCode, header: resp.Header, body: map[string]any{}}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &result.body); err != nil {
+			return nil, fmt.Errorf("response is not a JSON object: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// apiPath is the root of the version of the API the contract covers
+const apiPath = "/api/v1"
+
+// transactionPath is the transaction endpoint of a user
+func transactionPath(userID uint64) string {
+	return "/user/" + strconv.FormatUint(userID, 10) + "/transaction"
+}
+
+// transactionID returns a transaction ID unique to this run
+func (v *Verifier) transactionID(name string) string {
+	return "contract-" + v.runID + "-" + name
+}
+
+// balance reads the test user's balance
+func (v *Verifier) balance(ctx context.Context) (decimal.Decimal, error) {
+	resp, err := v.call(ctx, http.MethodGet, "/user/"+strconv.FormatUint(v.cfg.UserID, 10)+"/balance", map[string]string{}, nil)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if err := resp.expectStatus(http.StatusOK); err != nil {
+		return decimal.Zero, err
+	}
+	nested, ok := resp.body["balance"].(map[string]any)
+	if !ok {
+		return decimal.Zero, fmt.Errorf("balance response has no balance object: %v", resp.body)
+	}
+	raw, _ := nested["balance"].(string)
+	return decimal.NewFromString(raw)
+}
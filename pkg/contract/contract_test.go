@@ -0,0 +1,149 @@
+package contract
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubService is a minimal in-memory implementation of the documented REST
+// contract for user 1
+type stubService struct {
+	mu           sync.Mutex
+	balance      decimal.Decimal
+	transactions map[string]map[string]string
+	// skipReplayHeader breaks the contract to check that it is detected
+	skipReplayHeader bool
+}
+
+func (s *stubService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if id := r.Header.Get("X-Request-ID"); id != "" {
+		w.Header().Set("X-Request-ID", id)
+	}
+	reply := func(status int, body map[string]any) {
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(body)
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1"), "/"), "/")
+	if len(parts) != 3 || parts[0] != "user" {
+		reply(http.StatusNotFound, map[string]any{"error": "Not found"})
+		return
+	}
+	if parts[1] != "1" && parts[1] != "9223372036854775807" {
+		reply(http.StatusBadRequest, map[string]any{"error": "Invalid user ID"})
+		return
+	}
+
+	if parts[2] == "balance" {
+		reply(http.StatusOK, map[string]any{"userId": 1, "balance": map[string]any{"userId": 1, "balance": s.balance.StringFixed(2)}})
+		return
+	}
+
+	switch r.Header.Get("Source-Type") {
+	case "game", "server", "payment":
+	default:
+		reply(http.StatusBadRequest, map[string]any{"error": "Invalid Source-Type header"})
+		return
+	}
+	var req map[string]string
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		reply(http.StatusBadRequest, map[string]any{"error": "Invalid request body"})
+		return
+	}
+	amount, err := decimal.NewFromString(req["amount"])
+	if err != nil || !amount.IsPositive() || (req["state"] != "win" && req["state"] != "lose") {
+		reply(http.StatusBadRequest, map[string]any{"error": "Invalid request"})
+		return
+	}
+	if parts[1] != "1" {
+		reply(http.StatusNotFound, map[string]any{"error": "User not found"})
+		return
+	}
+
+	if previous, ok := s.transactions[req["transactionId"]]; ok {
+		if previous["state"] != req["state"] || previous["amount"] != req["amount"] {
+			reply(http.StatusConflict, map[string]any{"error": "Transaction ID already used for a different transaction"})
+			return
+		}
+		if !s.skipReplayHeader {
+			w.Header().Set("Idempotent-Replayed", "true")
+		}
+		reply(http.StatusOK, map[string]any{"transactionId": req["transactionId"], "balance": previous["balance"], "replayed": true})
+		return
+	}
+
+	if req["state"] == "lose" {
+		if s.balance.LessThan(amount) {
+			reply(http.StatusBadRequest, map[string]any{"error": "Insufficient funds"})
+			return
+		}
+		amount = amount.Neg()
+	}
+	s.balance = s.balance.Add(amount)
+	req["balance"] = s.balance.StringFixed(2)
+	s.transactions[req["transactionId"]] = req
+	reply(http.StatusOK, map[string]any{"transactionId": req["transactionId"], "balance": req["balance"], "replayed": false})
+}
+
+func runAgainst(t *testing.T, stub *stubService) Report {
+	t.Helper()
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+
+	verifier, err := NewVerifier(Config{BaseURL: server.URL, UserID: 1})
+	require.NoError(t, err)
+	return verifier.Run(context.Background())
+}
+
+func TestVerifier_Run(t *testing.T) {
+	stub := &stubService{balance: decimal.NewFromInt(100), transactions: map[string]map[string]string{}}
+
+	report := runAgainst(t, stub)
+
+	for _, result := range report.Results {
+		assert.True(t, result.Passed, "%s: %s", result.Name, result.Error)
+	}
+	assert.True(t, report.Passed)
+	assert.Len(t, report.Results, len(Checks()))
+	assert.True(t, stub.balance.Equal(decimal.NewFromInt(100)), "checks must leave the balance unchanged")
+}
+
+func TestVerifier_Run_DetectsDeviations(t *testing.T) {
+	stub := &stubService{
+		balance:          decimal.NewFromInt(100),
+		transactions:     map[string]map[string]string{},
+		skipReplayHeader: true,
+	}
+
+	report := runAgainst(t, stub)
+
+	assert.False(t, report.Passed)
+	var failed []string
+	for _, result := range report.Results {
+		if !result.Passed {
+			failed = append(failed, result.Name)
+		}
+	}
+	assert.Equal(t, []string{"transaction is applied and replays are idempotent"}, failed)
+}
+
+func TestNewVerifier_RequiresConfig(t *testing.T) {
+	_, err := NewVerifier(Config{UserID: 1})
+	assert.Error(t, err)
+
+	_, err = NewVerifier(Config{BaseURL: "http://localhost:8080"})
+	assert.Error(t, err)
+}